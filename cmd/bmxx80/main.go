@@ -73,6 +73,7 @@ func mainImpl() error {
 	i2cID := flag.String("i2c", "", "I²C bus to use (default, uses the first I²C found)")
 	i2cAddr := flag.Uint("ia", 0x76, "I²C bus address to use; either 0x76 (BMx280, the default) or 0x77 (BMP180)")
 	spiID := flag.String("spi", "", "SPI port to use")
+	spi3w := flag.Bool("spi3w", false, "use 3-wire SPI, with SDI and SDO shared")
 	hz := flag.Int("hz", 0, "I²C bus/SPI port speed")
 	sample1x := flag.Bool("s1", false, "sample at 1x")
 	sample2x := flag.Bool("s2", false, "sample at 2x")
@@ -148,7 +149,11 @@ func mainImpl() error {
 				return err
 			}
 		}
-		if dev, err = bmxx80.NewSPI(s, &opts); err != nil {
+		if *spi3w {
+			if dev, err = bmxx80.NewSPI3Wire(s, &opts); err != nil {
+				return err
+			}
+		} else if dev, err = bmxx80.NewSPI(s, &opts); err != nil {
 			return err
 		}
 	} else {
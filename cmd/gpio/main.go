@@ -0,0 +1,369 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// gpio reads, writes, watches, blinks and generates PWM on a GPIO pin.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pwm"
+	"periph.io/x/periph/host"
+	"periph.io/x/periph/host/bcm283x"
+	"periph.io/x/periph/host/sysfs"
+)
+
+// command is a subcommand.
+type command struct {
+	name string
+	args string
+	desc string
+	run  func(f *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"read", "<pin>", "prints the level of a pin", cmdRead},
+	{"write", "<pin> <0|1>", "sets a pin as output at the level", cmdWrite},
+	{"toggle", "<pin>", "inverts the level of a pin and sets it as output", cmdToggle},
+	{"pull", "<pin> <up|down|float>", "sets a pin as input with the pull resistor and prints its level", cmdPull},
+	{"watch", "<pin>", "prints the edges of an input pin with timestamps", cmdWatch},
+	{"blink", "<pin>", "toggles a pin at a frequency", cmdBlink},
+	{"pwm", "<pin> <duty> <frequency>", "generates a PWM signal, e.g. 25% 1kHz", cmdPWM},
+}
+
+func usage(fs *flag.FlagSet) {
+	io.WriteString(os.Stderr, "Usage: gpio <args> <command> <command args> ...\n\n")
+	fs.PrintDefaults()
+	io.WriteString(os.Stderr, "\nCommands available:\n")
+	l := 0
+	for _, c := range commands {
+		if n := len(c.name) + len(c.args) + 1; n > l {
+			l = n
+		}
+	}
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", l, c.name+" "+c.args, c.desc)
+	}
+	io.WriteString(os.Stderr, "\nUse \"gpio <command> -help\" for the command specific args.\n")
+}
+
+func cmdRead(f *flag.FlagSet, args []string) error {
+	p, err := parsePin(f, args, 1)
+	if err != nil {
+		return err
+	}
+	return printLevel(p.Read())
+}
+
+func cmdWrite(f *flag.FlagSet, args []string) error {
+	p, err := parsePin(f, args, 2)
+	if err != nil {
+		return err
+	}
+	switch f.Arg(1) {
+	case "0":
+		return p.Out(gpio.Low)
+	case "1":
+		return p.Out(gpio.High)
+	default:
+		return errors.New("specify level as 0 or 1")
+	}
+}
+
+func cmdToggle(f *flag.FlagSet, args []string) error {
+	p, err := parsePin(f, args, 1)
+	if err != nil {
+		return err
+	}
+	return p.Out(!p.Read())
+}
+
+func cmdPull(f *flag.FlagSet, args []string) error {
+	p, err := parsePin(f, args, 2)
+	if err != nil {
+		return err
+	}
+	pull, err := parsePull(f.Arg(1))
+	if err != nil {
+		return err
+	}
+	if err := p.In(pull, gpio.NoEdge); err != nil {
+		return err
+	}
+	return printLevel(p.Read())
+}
+
+func cmdWatch(f *flag.FlagSet, args []string) error {
+	edgeName := f.String("edge", "both", "edge to watch: both, rising or falling")
+	pullName := f.String("pull", "", "pull resistor to set: up, down or float")
+	count := f.Int("n", 0, "stop after this number of edges; 0 means forever")
+	p, err := parsePin(f, args, 1)
+	if err != nil {
+		return err
+	}
+	edge := gpio.BothEdges
+	switch *edgeName {
+	case "both":
+	case "rising":
+		edge = gpio.RisingEdge
+	case "falling":
+		edge = gpio.FallingEdge
+	default:
+		return fmt.Errorf("invalid edge %q", *edgeName)
+	}
+	pull := gpio.PullNoChange
+	if *pullName != "" {
+		if pull, err = parsePull(*pullName); err != nil {
+			return err
+		}
+	}
+	if err := p.In(pull, edge); err != nil {
+		return err
+	}
+	defer p.In(gpio.PullNoChange, gpio.NoEdge)
+	stop := ctrlC()
+	edges := make(chan time.Time)
+	go func() {
+		for {
+			if p.WaitForEdge(-1) {
+				edges <- time.Now()
+			}
+		}
+	}()
+	fmt.Printf("%s %s\n", time.Now().Format("15:04:05.000000"), p.Read())
+	last := time.Now()
+	for i := 0; *count == 0 || i < *count; i++ {
+		select {
+		case <-stop:
+			return nil
+		case t := <-edges:
+			fmt.Printf("%s %s +%s\n", t.Format("15:04:05.000000"), p.Read(), t.Sub(last))
+			last = t
+		}
+	}
+	return nil
+}
+
+func cmdBlink(f *flag.FlagSet, args []string) error {
+	freq := f.String("f", "1Hz", "blinking frequency")
+	count := f.Int("n", 0, "stop after this number of periods; 0 means forever")
+	p, err := parsePin(f, args, 1)
+	if err != nil {
+		return err
+	}
+	hz, err := parseFrequency(*freq)
+	if err != nil {
+		return err
+	}
+	stop := ctrlC()
+	t := time.NewTicker(time.Duration(float64(time.Second) / hz / 2))
+	defer t.Stop()
+	l := gpio.High
+	for i := 0; *count == 0 || i < 2**count; i++ {
+		if err := p.Out(l); err != nil {
+			return err
+		}
+		l = !l
+		select {
+		case <-stop:
+			return p.Out(gpio.Low)
+		case <-t.C:
+		}
+	}
+	return p.Out(gpio.Low)
+}
+
+func cmdPWM(f *flag.FlagSet, args []string) error {
+	inversed := f.Bool("i", false, "use the inversed polarity")
+	d := f.Duration("d", 0, "stop after this duration; 0 means until Ctrl-C")
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if f.NArg() != 3 {
+		return errors.New("specify the pin, the duty cycle and the frequency")
+	}
+	duty, err := gpio.ParseDuty(f.Arg(1))
+	if err != nil {
+		return err
+	}
+	hz, err := parseFrequency(f.Arg(2))
+	if err != nil {
+		return err
+	}
+	c, err := openChannel(f.Arg(0))
+	if err != nil {
+		return err
+	}
+	s := pwm.State{Period: time.Duration(float64(time.Second) / hz), Duty: duty, Enabled: true}
+	if *inversed {
+		s.Polarity = pwm.Inversed
+	}
+	if err := c.Apply(s); err != nil {
+		return err
+	}
+	defer c.Halt()
+	fmt.Printf("%s: %s\n", c, c.State())
+	var timeout <-chan time.Time
+	if *d != 0 {
+		timeout = time.After(*d)
+	}
+	select {
+	case <-ctrlC():
+	case <-timeout:
+	}
+	return nil
+}
+
+// parsePin parses the command args and returns the pin of the first argument.
+func parsePin(f *flag.FlagSet, args []string, nargs int) (gpio.PinIO, error) {
+	if err := f.Parse(args); err != nil {
+		return nil, err
+	}
+	if f.NArg() != nargs {
+		return nil, fmt.Errorf("expected %d arguments, got %d", nargs, f.NArg())
+	}
+	p := gpioreg.ByName(f.Arg(0))
+	if p == nil {
+		return nil, fmt.Errorf("invalid pin %q", f.Arg(0))
+	}
+	return p, nil
+}
+
+func parsePull(s string) (gpio.Pull, error) {
+	switch s {
+	case "up":
+		return gpio.PullUp, nil
+	case "down":
+		return gpio.PullDown, nil
+	case "float":
+		return gpio.Float, nil
+	default:
+		return gpio.PullNoChange, fmt.Errorf("invalid pull %q; use up, down or float", s)
+	}
+}
+
+// parseFrequency parses a frequency like "50Hz", "1.5kHz" or "2MHz".
+func parseFrequency(s string) (float64, error) {
+	v := s
+	m := 1.
+	for _, u := range []struct {
+		suffix string
+		mul    float64
+	}{{"MHz", 1000000}, {"kHz", 1000}, {"Hz", 1}} {
+		if strings.HasSuffix(v, u.suffix) {
+			v = v[:len(v)-len(u.suffix)]
+			m = u.mul
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("invalid frequency %q", s)
+	}
+	return f * m, nil
+}
+
+// openChannel returns the PWM channel of a pin or of a sysfs PWM channel, e.g.
+// "pwmchip0/pwm1".
+//
+// The native PWM channel is preferred over gpio.PinPWM as it supports the
+// polarity.
+func openChannel(name string) (pwm.Channel, error) {
+	if c, err := sysfs.PWMByName(name); err == nil {
+		return c, nil
+	}
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("invalid pin or PWM channel %q", name)
+	}
+	if r, ok := p.(gpio.RealPin); ok {
+		p = r.Real()
+	}
+	if c, ok := p.(pwm.Channel); ok {
+		return c, nil
+	}
+	if b, ok := p.(*bcm283x.Pin); ok {
+		if c, err := bcm283x.NewPWMChannel(b); err == nil {
+			return c, nil
+		}
+	}
+	if pp, ok := p.(gpio.PinPWM); ok {
+		return pwm.FromPin(pp), nil
+	}
+	return nil, fmt.Errorf("%s doesn't support PWM", p)
+}
+
+func printLevel(l gpio.Level) error {
+	if l == gpio.Low {
+		_, err := os.Stdout.Write([]byte{'0', '\n'})
+		return err
+	}
+	_, err := os.Stdout.Write([]byte{'1', '\n'})
+	return err
+}
+
+// ctrlC returns a channel that is signaled on Ctrl-C.
+func ctrlC() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	return c
+}
+
+func mainImpl() error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "enable verbose logs")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(os.Args[1:]); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("please specify a command or use -help")
+	}
+	name := fs.Arg(0)
+	if name == "help" {
+		usage(fs)
+		return nil
+	}
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+
+	for _, c := range commands {
+		if c.name == name {
+			if _, err := host.Init(); err != nil {
+				return err
+			}
+			f := flag.NewFlagSet("gpio "+c.name, flag.ContinueOnError)
+			if err := c.run(f, fs.Args()[1:]); err != flag.ErrHelp {
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown command %q; use -help", name)
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "gpio: %s.\n", err)
+		os.Exit(1)
+	}
+}
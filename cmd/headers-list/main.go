@@ -14,11 +14,13 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strings"
 
 	"periph.io/x/periph"
-	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/conn/pin/pinreg"
 	"periph.io/x/periph/host"
+	"periph.io/x/periph/host/distro"
 )
 
 func printFailures(state *periph.State) {
@@ -33,7 +35,7 @@ func printFailures(state *periph.State) {
 	}
 }
 
-func printHardware(invalid bool, all map[string][][]pin.Pin) {
+func printHardware(invalid bool, all map[string]pinreg.Header) {
 	names := make([]string, 0, len(all))
 	for name := range all {
 		names = append(names, name)
@@ -42,10 +44,10 @@ func printHardware(invalid bool, all map[string][][]pin.Pin) {
 	maxName := 0
 	maxFn := 0
 	for _, header := range all {
-		if len(header) == 0 || len(header[0]) != 2 {
+		if len(header.Pins) == 0 || len(header.Pins[0]) != 2 {
 			continue
 		}
-		for _, line := range header {
+		for _, line := range header.Pins {
 			for _, p := range line {
 				if l := len(p.String()); l > maxName {
 					maxName = l
@@ -61,36 +63,60 @@ func printHardware(invalid bool, all map[string][][]pin.Pin) {
 			fmt.Print("\n")
 		}
 		header := all[name]
-		if len(header) == 0 {
+		if len(header.Pins) == 0 {
 			fmt.Printf("%s: No pin connected\n", name)
 			continue
 		}
 		sum := 0
-		for _, line := range header {
+		for _, line := range header.Pins {
 			sum += len(line)
 		}
-		fmt.Printf("%s: %d pins\n", name, sum)
-		if len(header[0]) == 2 {
+		if header.Voltage != 0 {
+			fmt.Printf("%s: %d pins, %d.%dV logic\n", name, sum, header.Voltage/1000, (header.Voltage%1000)/100)
+		} else {
+			fmt.Printf("%s: %d pins\n", name, sum)
+		}
+		if len(header.Pins[0]) == 2 {
 			fmt.Printf("  %*s  %*s  Pos  Pos  %-*s Func\n", maxFn, "Func", maxName, "Name", maxName, "Name")
-			for i, line := range header {
-				fmt.Printf("  %*s  %*s  %3d  %-3d  %-*s %s\n", maxFn, line[0].Function(), maxName, line[0], 2*i+1, 2*i+2, maxName, line[1], line[1].Function())
+			for i, line := range header.Pins {
+				fmt.Printf("  %*s  %*s  %3d  %-3d  %-*s %s\n", maxFn, line[0].Function(), maxName, line[0], header.Number(i, 0), header.Number(i, 1), maxName, line[1], line[1].Function())
 			}
 			continue
 		}
 		fmt.Printf("  Pos  %-*s  Func\n", maxName, "Name")
-		pos := 1
-		for _, line := range header {
-			for _, item := range line {
-				fmt.Printf("  %-3d  %-*s  %s\n", pos, maxName, item, item.Function())
-				pos++
+		for i, line := range header.Pins {
+			for j, item := range line {
+				fmt.Printf("  %-3d  %-*s  %s\n", header.Number(i, j), maxName, item, item.Function())
 			}
 		}
 	}
 }
 
+// printLocations prints the header positions of the pin named name.
+func printLocations(name string) error {
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return fmt.Errorf("pin %q is not registered", name)
+	}
+	l := pinreg.Locations(p)
+	if len(l) == 0 {
+		fmt.Printf("%s: not connected\n", p)
+		return nil
+	}
+	s := make([]string, 0, len(l))
+	for _, item := range l {
+		s = append(s, item.String())
+	}
+	fmt.Printf("%s: %s\n", p, strings.Join(s, ", "))
+	return nil
+}
+
 func mainImpl() error {
 	invalid := flag.Bool("n", false, "show not connected/INVALID pins")
 	verbose := flag.Bool("v", false, "enable verbose logs")
+	pinName := flag.String("p", "", "print the header positions of this pin, e.g. GPIO17")
+	asJSON := flag.Bool("json", false, "print the layout of all the headers as JSON")
+	asYAML := flag.Bool("yaml", false, "print the layout of all the headers as YAML")
 	flag.Parse()
 
 	if !*verbose {
@@ -101,12 +127,32 @@ func mainImpl() error {
 	if err != nil {
 		return err
 	}
-	all := pinreg.All()
+	all := pinreg.Headers()
 	if len(all) == 0 && len(state.Failed) != 0 {
 		fmt.Fprintf(os.Stderr, "Got the following driver failures:\n")
 		printFailures(state)
 		return errors.New("no header found")
 	}
+	if *asJSON || *asYAML {
+		if *asJSON && *asYAML {
+			return errors.New("-json and -yaml cannot be used together")
+		}
+		if *pinName != "" || flag.NArg() != 0 {
+			return errors.New("-json and -yaml cannot be used with -p or header names")
+		}
+		l := pinreg.Export()
+		l.Board = distro.DTModel()
+		if *asJSON {
+			return l.WriteJSON(os.Stdout)
+		}
+		return l.WriteYAML(os.Stdout)
+	}
+	if *pinName != "" {
+		if flag.NArg() != 0 {
+			return errors.New("-p cannot be used with header names")
+		}
+		return printLocations(*pinName)
+	}
 	if flag.NArg() == 0 {
 		printHardware(*invalid, all)
 	} else {
@@ -115,7 +161,7 @@ func mainImpl() error {
 			if !ok {
 				return fmt.Errorf("header %q is not registered", name)
 			}
-			printHardware(*invalid, map[string][][]pin.Pin{name: hdr})
+			printHardware(*invalid, map[string]pinreg.Header{name: hdr})
 		}
 	}
 	return nil
@@ -0,0 +1,130 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// i2c-scan prints the addresses of the devices found on the I²C buses.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/host"
+)
+
+// printGrid prints the addresses in the classic i2cdetect grid.
+func printGrid(w io.Writer, addrs []uint16) error {
+	found := map[uint16]bool{}
+	for _, a := range addrs {
+		found[a] = true
+	}
+	if _, err := io.WriteString(w, "     0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f\n"); err != nil {
+		return err
+	}
+	for row := uint16(0); row < 0x80; row += 0x10 {
+		line := fmt.Sprintf("%02x:", row)
+		for a := row; a < row+0x10; a++ {
+			switch {
+			case a < i2c.ScanFirst || a > i2c.ScanLast:
+				line += "   "
+			case found[a]:
+				line += fmt.Sprintf(" %02x", a)
+			default:
+				line += " --"
+			}
+		}
+		if _, err := io.WriteString(w, strings.TrimRight(line, " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scan(name string, p i2c.Probe, hz int64) error {
+	bus, err := i2creg.Open(name)
+	if err != nil {
+		return err
+	}
+	defer bus.Close()
+	if hz != 0 {
+		if err := bus.SetSpeed(hz); err != nil {
+			return err
+		}
+	}
+	if p, ok := bus.(i2c.Pins); ok {
+		log.Printf("Using pins SCL: %s  SDA: %s", p.SCL(), p.SDA())
+	}
+	addrs, err := i2c.Scan(bus, p)
+	if err != nil {
+		return err
+	}
+	return printGrid(os.Stdout, addrs)
+}
+
+func mainImpl() error {
+	busName := flag.String("b", "", "I²C bus to scan; all buses by default")
+	probe := flag.String("p", "auto", "probe method: auto, read or write; write may corrupt an EEPROM, read may lock up some write-only devices")
+	hz := flag.Int("hz", 0, "I²C bus speed (may require root)")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+
+	var p i2c.Probe
+	switch *probe {
+	case "auto":
+		p = i2c.ProbeAuto
+	case "read":
+		p = i2c.ProbeRead
+	case "write":
+		p = i2c.ProbeWrite
+	default:
+		return fmt.Errorf("invalid probe method %q", *probe)
+	}
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+
+	var names []string
+	if *busName != "" {
+		names = []string{*busName}
+	} else {
+		for _, ref := range i2creg.All() {
+			names = append(names, ref.Name)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no I²C bus found")
+	}
+	for i, name := range names {
+		if i != 0 {
+			fmt.Print("\n")
+		}
+		fmt.Printf("%s:\n", name)
+		if err := scan(name, p, int64(*hz)); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "i2c-scan: %s.\n", err)
+		os.Exit(1)
+	}
+}
@@ -371,6 +371,9 @@ func grabFrame(dev *lepton.Dev, path string, meta bool) error {
 		fmt.Printf("FFCSince:     %s\n", frame.Metadata.FFCSince)
 		fmt.Printf("FFCDesired:   %t\n", frame.Metadata.FFCDesired)
 		fmt.Printf("Overtemp:     %t\n", frame.Metadata.Overtemp)
+		fmt.Printf("FFCState:     %s\n", frame.Metadata.FFCState)
+		fmt.Printf("AGCEnabled:   %t\n", frame.Metadata.AGCEnabled)
+		fmt.Printf("AGCROI:       %s\n", frame.Metadata.AGCROI)
 	}
 	f, err := os.Create(path)
 	if err != nil {
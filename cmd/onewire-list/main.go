@@ -0,0 +1,190 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// onewire-list searches the 1-wire buses and prints the devices found, with
+// the temperature of the sensors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/ds18b20"
+	"periph.io/x/periph/devices/ds248x"
+	"periph.io/x/periph/host"
+)
+
+// family describes a 1-wire device family code, the lowest byte of the
+// address.
+type family struct {
+	name string
+	desc string
+	temp bool // Compatible with the ds18b20 driver
+}
+
+var families = map[byte]family{
+	0x01: {"DS2401", "silicon serial number", false},
+	0x05: {"DS2405", "addressable switch", false},
+	0x10: {"DS18S20", "temperature sensor", false},
+	0x12: {"DS2406", "dual addressable switch", false},
+	0x1D: {"DS2423", "RAM with counters", false},
+	0x20: {"DS2450", "quad ADC", false},
+	0x22: {"DS1822", "temperature sensor", true},
+	0x23: {"DS2433", "4kbit EEPROM", false},
+	0x26: {"DS2438", "battery monitor", false},
+	0x28: {"DS18B20", "temperature sensor", true},
+	0x29: {"DS2408", "8 channel addressable switch", false},
+	0x2D: {"DS2431", "1kbit EEPROM", false},
+	0x3A: {"DS2413", "dual channel addressable switch", false},
+	0x3B: {"DS1825", "temperature sensor", true},
+	0x42: {"DS28EA00", "temperature sensor", true},
+}
+
+func list(bus onewire.Bus, temp bool, bits int) error {
+	// Search returns the devices found before an error, print them anyway.
+	addrs, err := bus.Search(false)
+	if err != nil {
+		if e, ok := err.(onewire.NoDevicesError); ok && e.NoDevices() {
+			err = nil
+		} else if len(addrs) == 0 {
+			return err
+		}
+	}
+	if len(addrs) == 0 {
+		fmt.Print("  <none>\n")
+		return err
+	}
+	if temp {
+		for _, a := range addrs {
+			if families[byte(a)].temp {
+				if err2 := ds18b20.ConvertAll(bus, bits); err2 != nil {
+					return err2
+				}
+				break
+			}
+		}
+	}
+	for _, a := range addrs {
+		fmt.Printf("- 0x%016x", uint64(a))
+		if !validAddr(a) {
+			fmt.Print(": invalid CRC\n")
+			continue
+		}
+		f, ok := families[byte(a)]
+		if !ok {
+			fmt.Printf(": unknown family 0x%02X\n", byte(a))
+			continue
+		}
+		fmt.Printf(": %s %s", f.name, f.desc)
+		if temp && f.temp {
+			if t, err := readTemp(bus, a, bits); err != nil {
+				fmt.Printf(": %v", err)
+			} else {
+				fmt.Printf(": %s", t)
+			}
+		}
+		fmt.Print("\n")
+	}
+	return err
+}
+
+// readTemp returns the temperature of the last conversion done by
+// ds18b20.ConvertAll().
+func readTemp(bus onewire.Bus, a onewire.Address, bits int) (devices.Celsius, error) {
+	d, err := ds18b20.New(bus, a, bits)
+	if err != nil {
+		return 0, err
+	}
+	return d.LastTemp()
+}
+
+// validAddr returns true if the CRC of the address, stored in the highest
+// byte, is valid.
+func validAddr(a onewire.Address) bool {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(a >> uint(8*i))
+	}
+	return onewire.CheckCRC(b[:])
+}
+
+func mainImpl() error {
+	busName := flag.String("b", "", "1-wire bus to search; all buses by default")
+	i2cName := flag.String("i2cbus", "", "search the 1-wire bus of a DS248x on this I²C bus instead; use - for the default I²C bus")
+	temp := flag.Bool("t", true, "read the temperature sensors")
+	bits := flag.Int("r", 10, "temperature resolution in bits, between 9 and 12")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+	if *bits < 9 || *bits > 12 {
+		return errors.New("-r must be between 9 and 12")
+	}
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+
+	if *i2cName != "" {
+		if *i2cName == "-" {
+			*i2cName = ""
+		}
+		i, err := i2creg.Open(*i2cName)
+		if err != nil {
+			return err
+		}
+		defer i.Close()
+		bus, err := ds248x.New(i, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", bus)
+		return list(bus, *temp, *bits)
+	}
+
+	var names []string
+	if *busName != "" {
+		names = []string{*busName}
+	} else {
+		for _, ref := range onewirereg.All() {
+			names = append(names, ref.Name)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no 1-wire bus found; use -i2cbus for a DS248x")
+	}
+	for _, name := range names {
+		fmt.Printf("%s:\n", name)
+		bus, err := onewirereg.Open(name)
+		if err != nil {
+			return err
+		}
+		err = list(bus, *temp, *bits)
+		bus.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "onewire-list: %s.\n", err)
+		os.Exit(1)
+	}
+}
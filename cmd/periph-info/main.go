@@ -2,15 +2,28 @@
 // Use of this source code is governed under the Apache License, Version 2.0
 // that can be found in the LICENSE file.
 
-// periph-info prints out information about the loaded periph drivers.
+// periph-info prints out information about the loaded periph drivers, the
+// host, the buses and the pins.
+//
+// Use -json to get a machine readable dump, e.g. to attach to a bug report.
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"os"
+	"strings"
 
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn/connreg"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin/pinreg"
 	"periph.io/x/periph/host"
+	"periph.io/x/periph/host/soc"
 )
 
 func printDrivers(drivers []periph.DriverFailure) {
@@ -29,11 +42,196 @@ func printDrivers(drivers []periph.DriverFailure) {
 	}
 }
 
+func printReport(r soc.Report) {
+	fmt.Printf("Host:\n")
+	if r.Board != "" {
+		fmt.Printf("- Board: %s\n", r.Board)
+	}
+	if r.SoC.Model != "" {
+		fmt.Printf("- SoC: %s\n", r.SoC.Model)
+	}
+	for _, p := range r.SoC.Peripherals {
+		fmt.Printf("  - %s: 0x%X\n", p.Name, p.Base)
+	}
+	printList("I²C", r.I2C)
+	printList("SPI", r.SPI)
+	printList("PWM", r.PWM)
+	var avail, missing []string
+	for _, k := range r.Kernel {
+		if k.Available {
+			avail = append(avail, k.Name)
+		} else {
+			missing = append(missing, k.Name)
+		}
+	}
+	printList("Kernel drivers available", avail)
+	printList("Kernel drivers missing", missing)
+}
+
+func printList(name string, l []string) {
+	if len(l) == 0 {
+		fmt.Printf("- %s: <none>\n", name)
+		return
+	}
+	fmt.Printf("- %s: %s\n", name, strings.Join(l, ", "))
+}
+
+func printBuses(buses []busInfo) {
+	fmt.Printf("Buses:\n")
+	if len(buses) == 0 {
+		fmt.Print("  <none>\n")
+		return
+	}
+	for _, b := range buses {
+		fmt.Printf("- %s %s", b.Type, b.Name)
+		if len(b.Aliases) != 0 {
+			fmt.Printf(" (%s)", strings.Join(b.Aliases, ", "))
+		}
+		if b.Driver != "" {
+			fmt.Printf(": %s", b.Driver)
+		}
+		if b.Path != "" && b.Path != b.Name {
+			fmt.Printf(" %s", b.Path)
+		}
+		if b.MaxSpeed != 0 {
+			fmt.Printf(", max %dHz", b.MaxSpeed)
+		}
+		if len(b.Features) != 0 {
+			fmt.Printf(", %s", strings.Join(b.Features, ", "))
+		}
+		fmt.Print("\n")
+	}
+}
+
+func printPins(pins []pinInfo) {
+	fmt.Printf("Pins:\n")
+	if len(pins) == 0 {
+		fmt.Print("  <none>\n")
+		return
+	}
+	max := 0
+	for _, p := range pins {
+		if m := len(p.Name); m > max {
+			max = m
+		}
+	}
+	for _, p := range pins {
+		if len(p.Locations) != 0 {
+			fmt.Printf("- %-*s: %s (%s)\n", max, p.Name, p.Function, strings.Join(p.Locations, ", "))
+		} else {
+			fmt.Printf("- %-*s: %s\n", max, p.Name, p.Function)
+		}
+	}
+}
+
+// dump is the JSON representation of the whole state.
+type dump struct {
+	Loaded  []driverInfo `json:"loaded"`
+	Skipped []driverInfo `json:"skipped"`
+	Failed  []driverInfo `json:"failed"`
+	Host    soc.Report   `json:"host"`
+	Buses   []busInfo    `json:"buses"`
+	Pins    []pinInfo    `json:"pins"`
+}
+
+type driverInfo struct {
+	Name          string   `json:"name"`
+	Prerequisites []string `json:"prerequisites,omitempty"`
+	Err           string   `json:"error,omitempty"`
+}
+
+type busInfo struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Number   int      `json:"number"`
+	Driver   string   `json:"driver,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	MaxSpeed int64    `json:"max_speed,omitempty"`
+	Features []string `json:"features,omitempty"`
+}
+
+type pinInfo struct {
+	Name        string   `json:"name"`
+	Number      int      `json:"number"`
+	Function    string   `json:"function"`
+	DefaultPull string   `json:"default_pull,omitempty"`
+	Locations   []string `json:"locations,omitempty"`
+}
+
+func makeDrivers(d []periph.DriverFailure) []driverInfo {
+	out := make([]driverInfo, 0, len(d))
+	for _, f := range d {
+		out = append(out, driverInfo{Name: f.D.String(), Err: f.Err.Error()})
+	}
+	return out
+}
+
+func makeBuses() []busInfo {
+	all := connreg.All()
+	out := make([]busInfo, 0, len(all))
+	for _, b := range all {
+		out = append(out, busInfo{string(b.Type), b.Name, b.Aliases, b.Number, b.Driver, b.Path, b.MaxSpeed, b.Features})
+	}
+	return out
+}
+
+func makePins() []pinInfo {
+	all := gpioreg.All()
+	out := make([]pinInfo, 0, len(all))
+	for _, p := range all {
+		i := pinInfo{Name: p.Name(), Number: p.Number(), Function: p.Function()}
+		if d, ok := p.(gpio.PinDefaultPull); ok {
+			i.DefaultPull = d.DefaultPull().String()
+		}
+		for _, l := range pinreg.Locations(p) {
+			i.Locations = append(i.Locations, l.String())
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+func makeDump(state *periph.State) *dump {
+	d := &dump{
+		Loaded:  make([]driverInfo, 0, len(state.Loaded)),
+		Skipped: makeDrivers(state.Skipped),
+		Failed:  makeDrivers(state.Failed),
+		Host:    soc.Get(),
+		Buses:   makeBuses(),
+		Pins:    makePins(),
+	}
+	for _, l := range state.Loaded {
+		d.Loaded = append(d.Loaded, driverInfo{Name: l.String(), Prerequisites: l.Prerequisites()})
+	}
+	return d
+}
+
 func mainImpl() error {
+	asJSON := flag.Bool("json", false, "print everything as JSON")
+	verbose := flag.Bool("v", false, "enable verbose logs")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		return fmt.Errorf("unexpected argument: %s", flag.Args())
+	}
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(0)
 	state, err := host.Init()
 	if err != nil {
 		return err
 	}
+	d := makeDump(state)
+	if *asJSON {
+		b, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(append(b, '\n'))
+		return err
+	}
 
 	fmt.Printf("Drivers loaded and their dependencies, if any:\n")
 	if len(state.Loaded) == 0 {
@@ -58,6 +256,9 @@ func mainImpl() error {
 	printDrivers(state.Skipped)
 	fmt.Printf("Drivers failed to load and the error:\n")
 	printDrivers(state.Failed)
+	printReport(d.Host)
+	printBuses(d.Buses)
+	printPins(d.Pins)
 	return err
 }
 
@@ -0,0 +1,427 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// periph-mqtt publishes the measurements of the environmental sensors and the
+// edges of GPIO pins to a MQTT broker, optionally with Home Assistant MQTT
+// discovery configs.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/bmxx80"
+	"periph.io/x/periph/devices/ds18b20"
+	"periph.io/x/periph/experimental/devices/sht3x"
+	"periph.io/x/periph/host"
+)
+
+// sensor is a discovered environmental sensor.
+type sensor struct {
+	name    string
+	id      string // Used in the topics
+	dev     devices.Environmental
+	metrics devices.Metric
+}
+
+// metrics are the measurements published, each to its own topic.
+var metrics = []struct {
+	m     devices.Metric
+	name  string
+	unit  string
+	class string // Home Assistant device class
+	get   func(e *devices.Environment) float64
+}{
+	{devices.MetricTemperature, "temperature", "°C", "temperature", func(e *devices.Environment) float64 { return e.Temperature.Float64() }},
+	{devices.MetricPressure, "pressure", "kPa", "pressure", func(e *devices.Environment) float64 { return e.Pressure.Float64() }},
+	{devices.MetricHumidity, "humidity", "%", "humidity", func(e *devices.Environment) float64 { return e.Humidity.Float64() }},
+}
+
+// message is a MQTT message to publish.
+type message struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// bridge maps the sensors and the pins to topics.
+type bridge struct {
+	node   string // Node ID, unique per host
+	prefix string // Topic prefix of the node
+	retain bool   // Retain the state messages
+	msgs   chan message
+}
+
+func (b *bridge) status() string {
+	return b.prefix + "/status"
+}
+
+func (b *bridge) sensorTopic(s *sensor, metric string) string {
+	return b.prefix + "/" + s.id + "/" + metric
+}
+
+func (b *bridge) pinTopic(p gpio.PinIO) string {
+	return b.prefix + "/gpio/" + topicID(p.Name())
+}
+
+// runSensor publishes the measurements of a sensor until its channel is
+// closed by Halt().
+func (b *bridge) runSensor(s *sensor, c <-chan devices.Environment) {
+	for e := range c {
+		for _, m := range metrics {
+			if s.metrics&m.m != 0 {
+				v := strconv.FormatFloat(m.get(&e), 'f', -1, 64)
+				b.msgs <- message{b.sensorTopic(s, m.name), []byte(v), b.retain}
+			}
+		}
+	}
+}
+
+// runPin publishes the level of a pin, then the level after each edge.
+func (b *bridge) runPin(p gpio.PinIO) {
+	t := b.pinTopic(p)
+	for {
+		b.msgs <- message{t, []byte(p.Read().String()), b.retain}
+		if !p.WaitForEdge(-1) {
+			return
+		}
+	}
+}
+
+// haDevice groups the entities of a node in Home Assistant.
+type haDevice struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+}
+
+// haConfig is a Home Assistant MQTT discovery config as described at
+// https://home-assistant.io/docs/mqtt/discovery/.
+type haConfig struct {
+	Name              string   `json:"name"`
+	UniqueID          string   `json:"unique_id"`
+	StateTopic        string   `json:"state_topic"`
+	AvailabilityTopic string   `json:"availability_topic"`
+	DeviceClass       string   `json:"device_class,omitempty"`
+	Unit              string   `json:"unit_of_measurement,omitempty"`
+	PayloadOn         string   `json:"payload_on,omitempty"`
+	PayloadOff        string   `json:"payload_off,omitempty"`
+	Device            haDevice `json:"device"`
+}
+
+// discovery returns the retained Home Assistant discovery configs: a sensor
+// per metric of each sensor and a binary_sensor per pin.
+func (b *bridge) discovery(haPrefix string, sensors []*sensor, pins []gpio.PinIO) ([]message, error) {
+	dev := haDevice{[]string{b.node}, b.node}
+	var out []message
+	add := func(component, object string, c *haConfig) error {
+		c.UniqueID = b.node + "_" + object
+		c.AvailabilityTopic = b.status()
+		c.Device = dev
+		p, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		out = append(out, message{haPrefix + "/" + component + "/" + b.node + "/" + object + "/config", p, true})
+		return nil
+	}
+	for _, s := range sensors {
+		for _, m := range metrics {
+			if s.metrics&m.m == 0 {
+				continue
+			}
+			c := &haConfig{
+				Name:        s.name + " " + m.name,
+				StateTopic:  b.sensorTopic(s, m.name),
+				DeviceClass: m.class,
+				Unit:        m.unit,
+			}
+			if err := add("sensor", s.id+"_"+m.name, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, p := range pins {
+		c := &haConfig{
+			Name:       p.Name(),
+			StateTopic: b.pinTopic(p),
+			PayloadOn:  gpio.High.String(),
+			PayloadOff: gpio.Low.String(),
+		}
+		if err := add("binary_sensor", "gpio_"+topicID(p.Name()), c); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// topicID converts a name like "BME280{I2C1(118)}" to a topic level and
+// Home Assistant object ID like "bme280_i2c1_118".
+func topicID(s string) string {
+	out := make([]byte, 0, len(s))
+	sep := false
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if sep && len(out) != 0 {
+				out = append(out, '_')
+			}
+			out = append(out, byte(c))
+			sep = false
+		} else {
+			sep = true
+		}
+	}
+	return string(out)
+}
+
+// discover returns the environmental sensors found on all the buses.
+//
+// The I²C buses are probed at the addresses of the BME280/BMP280/BMP180 and
+// the SHT3x. The 1-wire buses are searched for DS18B20 compatible sensors.
+func discover(bits int) ([]*sensor, []io.Closer) {
+	var out []*sensor
+	var closers []io.Closer
+	add := func(d devices.Environmental) {
+		m := devices.MetricsOf(d)
+		if m == 0 {
+			m = devices.MetricTemperature
+		}
+		n := fmt.Sprint(d)
+		out = append(out, &sensor{name: n, id: topicID(n), dev: d, metrics: m})
+		log.Printf("Found %s: %s", d, m)
+	}
+	for _, ref := range i2creg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		n := len(out)
+		for _, addr := range []uint16{0x76, 0x77} {
+			if d, err := bmxx80.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+		for _, addr := range []uint16{0x44, 0x45} {
+			if d, err := sht3x.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+		if len(out) == n {
+			bus.Close()
+		} else {
+			closers = append(closers, bus)
+		}
+	}
+	for _, ref := range onewirereg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		n := len(out)
+		addrs, err := bus.Search(false)
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+		}
+		for _, a := range addrs {
+			switch byte(a) {
+			case 0x22, 0x28, 0x3B, 0x42:
+				if d, err := ds18b20.New(bus, a, bits); err == nil {
+					add(d)
+				} else {
+					log.Printf("%s: 0x%016x: %v", ref.Name, uint64(a), err)
+				}
+			}
+		}
+		if len(out) == n {
+			bus.Close()
+		} else {
+			closers = append(closers, bus)
+		}
+	}
+	return out, closers
+}
+
+// openPins sets the pins listed as input with edge detection.
+func openPins(names string, pull gpio.Pull) ([]gpio.PinIO, error) {
+	var out []gpio.PinIO
+	if names == "" {
+		return out, nil
+	}
+	for _, n := range strings.Split(names, ",") {
+		p := gpioreg.ByName(strings.TrimSpace(n))
+		if p == nil {
+			return out, fmt.Errorf("invalid pin %q", n)
+		}
+		if err := p.In(pull, gpio.BothEdges); err != nil {
+			return out, fmt.Errorf("%s: %v", p, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func parsePull(s string) (gpio.Pull, error) {
+	switch s {
+	case "":
+		return gpio.PullNoChange, nil
+	case "up":
+		return gpio.PullUp, nil
+	case "down":
+		return gpio.PullDown, nil
+	case "float":
+		return gpio.Float, nil
+	default:
+		return gpio.PullNoChange, fmt.Errorf("invalid pull %q; use up, down or float", s)
+	}
+}
+
+func mainImpl() error {
+	broker := flag.String("broker", "localhost:1883", "MQTT broker address")
+	node := flag.String("id", "", "client ID and node name; defaults to periph_<hostname>")
+	user := flag.String("user", "", "MQTT user name")
+	password := flag.String("password", "", "MQTT password")
+	prefix := flag.String("prefix", "periph", "topic prefix, followed by the node name")
+	retain := flag.Bool("retain", false, "retain the sensor and pin messages")
+	interval := flag.Duration("i", 30*time.Second, "sensor sampling interval")
+	noSensors := flag.Bool("no-sensors", false, "do not discover the sensors")
+	bits := flag.Int("r", 10, "DS18B20 resolution in bits, between 9 and 12")
+	pinNames := flag.String("pins", "", "comma separated GPIO pins to publish the edges of")
+	pullName := flag.String("pull", "", "pull resistor of the pins: up, down or float")
+	ha := flag.Bool("ha", false, "publish Home Assistant MQTT discovery configs")
+	haPrefix := flag.String("ha-prefix", "homeassistant", "Home Assistant discovery prefix")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+	if *interval <= 0 {
+		return errors.New("-i must be positive")
+	}
+	if *bits < 9 || *bits > 12 {
+		return errors.New("-r must be between 9 and 12")
+	}
+	pull, err := parsePull(*pullName)
+	if err != nil {
+		return err
+	}
+	if *node == "" {
+		h, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		*node = "periph_" + h
+	}
+	b := &bridge{node: topicID(*node), retain: *retain, msgs: make(chan message, 16)}
+	b.prefix = *prefix + "/" + b.node
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+	pins, err := openPins(*pinNames, pull)
+	defer func() {
+		for _, p := range pins {
+			p.In(gpio.PullNoChange, gpio.NoEdge)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	var sensors []*sensor
+	if !*noSensors {
+		var closers []io.Closer
+		sensors, closers = discover(*bits)
+		defer func() {
+			for _, c := range closers {
+				c.Close()
+			}
+		}()
+	}
+	if len(sensors) == 0 && len(pins) == 0 {
+		return errors.New("no sensor found and no pin specified")
+	}
+
+	o := &mqttOpts{
+		clientID:    b.node,
+		user:        *user,
+		password:    *password,
+		keepAlive:   time.Minute,
+		willTopic:   b.status(),
+		willPayload: "offline",
+	}
+	m, err := dialMQTT(*broker, o)
+	if err != nil {
+		return err
+	}
+	defer m.close()
+	log.Printf("Connected to %s as %s", *broker, b.node)
+	if *ha {
+		msgs, err := b.discovery(*haPrefix, sensors, pins)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if err := m.publish(msg.topic, msg.payload, msg.retain); err != nil {
+				return err
+			}
+		}
+	}
+	if err := m.publish(b.status(), []byte("online"), true); err != nil {
+		return err
+	}
+
+	for _, s := range sensors {
+		c, err := s.dev.SenseContinuous(*interval)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s.name, err)
+		}
+		defer s.dev.Halt()
+		go b.runSensor(s, c)
+	}
+	for _, p := range pins {
+		go b.runPin(p)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	for {
+		select {
+		case <-c:
+			return m.publish(b.status(), []byte("offline"), true)
+		case err := <-m.done:
+			return fmt.Errorf("connection lost: %v", err)
+		case msg := <-b.msgs:
+			log.Printf("%s: %s", msg.topic, msg.payload)
+			if err := m.publish(msg.topic, msg.payload, msg.retain); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "periph-mqtt: %s.\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// mqttOpts are the connection options.
+type mqttOpts struct {
+	clientID  string
+	user      string
+	password  string
+	keepAlive time.Duration
+	// The will is published by the broker when the connection is lost.
+	willTopic   string
+	willPayload string
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client that only publishes at QoS 0, as
+// described at
+// http://docs.oasis-open.org/mqtt/mqtt/v3.1.1/os/mqtt-v3.1.1-os.html.
+type mqttClient struct {
+	c    net.Conn
+	done chan error // Receives the error that closed the connection
+
+	mu sync.Mutex // Serializes the packets written
+}
+
+// dialMQTT connects to a broker.
+func dialMQTT(addr string, o *mqttOpts) (*mqttClient, error) {
+	c, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	m := &mqttClient{c: c, done: make(chan error, 1)}
+	if err := m.connect(o); err != nil {
+		c.Close()
+		return nil, err
+	}
+	go m.readLoop()
+	go m.pingLoop(o.keepAlive / 2)
+	return m, nil
+}
+
+// publish sends a message at QoS 0.
+func (m *mqttClient) publish(topic string, payload []byte, retain bool) error {
+	h := byte(pktPublish)
+	if retain {
+		h |= 0x01
+	}
+	b := appendString(nil, topic)
+	return m.write(h, append(b, payload...))
+}
+
+// close disconnects cleanly, so the will is not published.
+func (m *mqttClient) close() error {
+	err := m.write(pktDisconnect, nil)
+	if err2 := m.c.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+//
+
+// Packet types, shifted in the fixed header.
+const (
+	pktConnect    = 1 << 4
+	pktConnAck    = 2 << 4
+	pktPublish    = 3 << 4
+	pktPingReq    = 12 << 4
+	pktDisconnect = 14 << 4
+)
+
+var connAckErrors = [...]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+func (m *mqttClient) connect(o *mqttOpts) error {
+	flags := byte(0x02) // Clean session
+	b := appendString(nil, "MQTT")
+	b = append(b, 4) // Protocol level 3.1.1
+	flagsIdx := len(b)
+	k := int(o.keepAlive / time.Second)
+	b = append(b, 0, byte(k>>8), byte(k))
+	b = appendString(b, o.clientID)
+	if o.willTopic != "" {
+		flags |= 0x04 | 0x20 // Will, retained
+		b = appendString(b, o.willTopic)
+		b = appendString(b, o.willPayload)
+	}
+	if o.user != "" {
+		flags |= 0x80
+		b = appendString(b, o.user)
+		if o.password != "" {
+			flags |= 0x40
+			b = appendString(b, o.password)
+		}
+	}
+	b[flagsIdx] = flags
+	if err := m.write(pktConnect, b); err != nil {
+		return err
+	}
+	m.c.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer m.c.SetReadDeadline(time.Time{})
+	var r [4]byte
+	if _, err := io.ReadFull(m.c, r[:]); err != nil {
+		return fmt.Errorf("mqtt: failed to read CONNACK: %v", err)
+	}
+	if r[0] != pktConnAck || r[1] != 2 {
+		return errors.New("mqtt: unexpected reply to CONNECT")
+	}
+	if rc := int(r[3]); rc != 0 {
+		if rc < len(connAckErrors) {
+			return fmt.Errorf("mqtt: connection refused: %s", connAckErrors[rc])
+		}
+		return fmt.Errorf("mqtt: connection refused: %d", rc)
+	}
+	return nil
+}
+
+func (m *mqttClient) write(header byte, body []byte) error {
+	b := append([]byte{header}, appendLength(nil, len(body))...)
+	b = append(b, body...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.c.Write(b)
+	return err
+}
+
+// readLoop discards the packets received, i.e. PINGRESP, until the
+// connection is closed.
+func (m *mqttClient) readLoop() {
+	r := bufio.NewReader(m.c)
+	for {
+		if _, err := r.ReadByte(); err != nil {
+			m.done <- err
+			return
+		}
+		l, err := readLength(r)
+		if err != nil {
+			m.done <- err
+			return
+		}
+		if _, err := r.Discard(l); err != nil {
+			m.done <- err
+			return
+		}
+	}
+}
+
+func (m *mqttClient) pingLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for range t.C {
+		if err := m.write(pktPingReq, nil); err != nil {
+			return
+		}
+	}
+}
+
+// appendString appends a length prefixed UTF-8 string.
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+// appendLength appends the variable length encoding of the remaining length.
+func appendLength(b []byte, l int) []byte {
+	for {
+		d := byte(l % 128)
+		l /= 128
+		if l > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if l == 0 {
+			return b
+		}
+	}
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	l, mul := 0, 1
+	for i := 0; i < 4; i++ {
+		d, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		l += int(d&0x7F) * mul
+		if d&0x80 == 0 {
+			return l, nil
+		}
+		mul *= 128
+	}
+	return 0, errors.New("mqtt: invalid remaining length")
+}
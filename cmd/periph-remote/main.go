@@ -0,0 +1,51 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// periph-remote serves the buses and pins of the host over the network, for
+// use with the remote package on another computer.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+
+	"periph.io/x/periph/experimental/conn/remote"
+	"periph.io/x/periph/host"
+)
+
+func mainImpl() error {
+	addr := flag.String("addr", "localhost:7081", "address to listen on; use :7081 to listen on all interfaces")
+	write := flag.Bool("w", false, "allow opening the buses and changing the pins")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Serving on %s\n", l.Addr())
+	return remote.Serve(l, &remote.Opts{Write: *write})
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "periph-remote: %s.\n", err)
+		os.Exit(1)
+	}
+}
@@ -54,6 +54,7 @@ var tests = []SmokeTest{
 	&gpiosmoketest.SmokeTest{},
 	&i2csmoketest.SmokeTest{},
 	&odroidc1smoketest.SmokeTest{},
+	&onewiresmoketest.DS18B20{},
 	&onewiresmoketest.SmokeTest{},
 	&spismoketest.SmokeTest{},
 	&ssd1306smoketest.SmokeTest{},
@@ -0,0 +1,449 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// periph-web serves a web page and a JSON API to inspect the pins, the buses
+// and the environmental sensors of a headless host.
+//
+// Changing the pins is only allowed with -w.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/connreg"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/bmxx80"
+	"periph.io/x/periph/devices/ds18b20"
+	"periph.io/x/periph/experimental/devices/sht3x"
+	"periph.io/x/periph/host"
+)
+
+type pinInfo struct {
+	Name     string  `json:"name"`
+	Number   int     `json:"number"`
+	Function string  `json:"function"`
+	Edges    *uint64 `json:"edges,omitempty"`
+}
+
+type busInfo struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Number   int      `json:"number"`
+	Driver   string   `json:"driver,omitempty"`
+	MaxSpeed int64    `json:"max_speed,omitempty"`
+}
+
+type reading struct {
+	Time        time.Time `json:"time"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Pressure    *float64  `json:"pressure,omitempty"`
+	Humidity    *float64  `json:"humidity,omitempty"`
+}
+
+type sensorInfo struct {
+	Name     string    `json:"name"`
+	Readings []reading `json:"readings"`
+}
+
+// sensor is a discovered environmental sensor and its recent readings.
+type sensor struct {
+	name     string
+	dev      devices.Environmental
+	metrics  devices.Metric
+	readings []reading
+}
+
+// server serves the web page and the API.
+type server struct {
+	write   bool
+	history int
+
+	mu      sync.Mutex
+	edges   map[string]*uint64 // Edges counted per watched pin
+	sensors []*sensor
+}
+
+func (s *server) pins() []pinInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := gpioreg.All()
+	out := make([]pinInfo, 0, len(all))
+	for _, p := range all {
+		i := pinInfo{Name: p.Name(), Number: p.Number(), Function: p.Function()}
+		if e, ok := s.edges[p.Name()]; ok {
+			n := *e
+			i.Edges = &n
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+func (s *server) buses() []busInfo {
+	all := connreg.All()
+	out := make([]busInfo, 0, len(all))
+	for _, b := range all {
+		out = append(out, busInfo{string(b.Type), b.Name, b.Aliases, b.Number, b.Driver, b.MaxSpeed})
+	}
+	return out
+}
+
+func (s *server) sensorInfos() []sensorInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]sensorInfo, 0, len(s.sensors))
+	for _, se := range s.sensors {
+		out = append(out, sensorInfo{se.name, append([]reading{}, se.readings...)})
+	}
+	return out
+}
+
+// watch configures the pin as input and counts its edges in the background.
+func (s *server) watch(p gpio.PinIO, pull gpio.Pull, edge gpio.Edge) error {
+	if err := p.In(pull, edge); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.edges[p.Name()]; ok {
+		// Already watched.
+		return nil
+	}
+	n := new(uint64)
+	s.edges[p.Name()] = n
+	go func() {
+		// WaitForEdge(-1) only returns false once the edge detection is
+		// disabled, e.g. when the pin is set as output.
+		for p.WaitForEdge(-1) {
+			s.mu.Lock()
+			*n++
+			s.mu.Unlock()
+		}
+		s.mu.Lock()
+		delete(s.edges, p.Name())
+		s.mu.Unlock()
+	}()
+	return nil
+}
+
+// sample reads all the sensors at each interval and keeps the last readings.
+func (s *server) sample(interval time.Duration) {
+	for t := time.NewTicker(interval); ; <-t.C {
+		for _, se := range s.sensors {
+			var e devices.Environment
+			if err := se.dev.Sense(&e); err != nil {
+				log.Printf("%s: %v", se.name, err)
+				continue
+			}
+			r := reading{Time: e.Time}
+			if r.Time.IsZero() {
+				r.Time = time.Now()
+			}
+			if se.metrics&devices.MetricTemperature != 0 {
+				v := e.Temperature.Float64()
+				r.Temperature = &v
+			}
+			if se.metrics&devices.MetricPressure != 0 {
+				v := e.Pressure.Float64()
+				r.Pressure = &v
+			}
+			if se.metrics&devices.MetricHumidity != 0 {
+				v := e.Humidity.Float64()
+				r.Humidity = &v
+			}
+			s.mu.Lock()
+			se.readings = append(se.readings, r)
+			if len(se.readings) > s.history {
+				se.readings = se.readings[len(se.readings)-s.history:]
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	d := struct {
+		Write   bool
+		Pins    []pinInfo
+		Buses   []busInfo
+		Sensors []sensorInfo
+	}{s.write, s.pins(), s.buses(), s.sensorInfos()}
+	if err := rootTmpl.Execute(w, d); err != nil {
+		log.Printf("template: %v", err)
+	}
+}
+
+func (s *server) handlePins(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.pins())
+}
+
+func (s *server) handleBuses(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.buses())
+}
+
+func (s *server) handleSensors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.sensorInfos())
+}
+
+// handleOut sets a pin as output. The form values are pin and level (0 or 1).
+func (s *server) handleOut(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.writablePin(w, r)
+	if !ok {
+		return
+	}
+	var l gpio.Level
+	switch r.FormValue("level") {
+	case "0":
+	case "1":
+		l = gpio.High
+	default:
+		http.Error(w, "level must be 0 or 1", http.StatusBadRequest)
+		return
+	}
+	s.reply(w, r, p.Out(l))
+}
+
+// handleWatch starts counting the edges of a pin. The form values are pin,
+// edge (both, rising or falling) and pull (up, down, float or empty to keep
+// it).
+func (s *server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.writablePin(w, r)
+	if !ok {
+		return
+	}
+	edge := gpio.BothEdges
+	switch r.FormValue("edge") {
+	case "", "both":
+	case "rising":
+		edge = gpio.RisingEdge
+	case "falling":
+		edge = gpio.FallingEdge
+	default:
+		http.Error(w, "edge must be both, rising or falling", http.StatusBadRequest)
+		return
+	}
+	pull := gpio.PullNoChange
+	switch r.FormValue("pull") {
+	case "":
+	case "up":
+		pull = gpio.PullUp
+	case "down":
+		pull = gpio.PullDown
+	case "float":
+		pull = gpio.Float
+	default:
+		http.Error(w, "pull must be up, down or float", http.StatusBadRequest)
+		return
+	}
+	s.reply(w, r, s.watch(p, pull, edge))
+}
+
+// writablePin returns the pin of a request changing a pin, or replies with an
+// error.
+func (s *server) writablePin(w http.ResponseWriter, r *http.Request) (gpio.PinIO, bool) {
+	if r.Method != "POST" {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	if !s.write {
+		http.Error(w, "write access is disabled; restart with -w", http.StatusForbidden)
+		return nil, false
+	}
+	p := gpioreg.ByName(r.FormValue("pin"))
+	if p == nil {
+		http.Error(w, fmt.Sprintf("invalid pin %q", r.FormValue("pin")), http.StatusNotFound)
+		return nil, false
+	}
+	return p, true
+}
+
+// reply sends the result of a change. The web page forms are redirected back
+// to the page.
+func (s *server) reply(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.FormValue("redirect") != "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+// discover returns the environmental sensors found on the I²C and 1-wire
+// buses.
+func discover() []*sensor {
+	var out []*sensor
+	add := func(d devices.Environmental) {
+		m := devices.MetricsOf(d)
+		if m == 0 {
+			m = devices.MetricTemperature
+		}
+		out = append(out, &sensor{name: fmt.Sprint(d), dev: d, metrics: m})
+		log.Printf("Found %s: %s", d, m)
+	}
+	// The buses are kept open for the lifetime of the process.
+	for _, ref := range i2creg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		for _, addr := range []uint16{0x76, 0x77} {
+			if d, err := bmxx80.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+		for _, addr := range []uint16{0x44, 0x45} {
+			if d, err := sht3x.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+	}
+	for _, ref := range onewirereg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		addrs, _ := bus.Search(false)
+		for _, a := range addrs {
+			switch byte(a) {
+			case 0x22, 0x28, 0x3B, 0x42:
+				if d, err := ds18b20.New(bus, a, 10); err == nil {
+					add(d)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func mainImpl() error {
+	addr := flag.String("http", ":7080", "address to listen on")
+	write := flag.Bool("w", false, "allow changing the pins")
+	sensors := flag.Bool("sensors", false, "probe the I²C and 1-wire buses for environmental sensors and sample them")
+	interval := flag.Duration("i", 10*time.Second, "sensors sampling interval")
+	history := flag.Int("history", 60, "number of readings kept per sensor")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+	if *interval <= 0 || *history <= 0 {
+		return errors.New("-i and -history must be positive")
+	}
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+	s := &server{write: *write, history: *history, edges: map[string]*uint64{}}
+	if *sensors {
+		s.sensors = discover()
+		go s.sample(*interval)
+	}
+
+	m := http.NewServeMux()
+	m.HandleFunc("/", s.handleRoot)
+	m.HandleFunc("/api/pins", s.handlePins)
+	m.HandleFunc("/api/buses", s.handleBuses)
+	m.HandleFunc("/api/sensors", s.handleSensors)
+	m.HandleFunc("/api/pin/out", s.handleOut)
+	m.HandleFunc("/api/pin/watch", s.handleWatch)
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Serving on http://%s\n", strings.Replace(l.Addr().String(), "[::]", "localhost", 1))
+	return http.Serve(l, m)
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "periph-web: %s.\n", err)
+		os.Exit(1)
+	}
+}
+
+var rootTmpl = template.Must(template.New("root").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>periph-web</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 2px 6px; text-align: left; }
+form { display: inline; }
+</style>
+</head>
+<body>
+<h1>periph-web</h1>
+<p>JSON API: <a href="/api/pins">/api/pins</a>, <a href="/api/buses">/api/buses</a>, <a href="/api/sensors">/api/sensors</a>.
+{{if not .Write}}Read only; restart with -w to change the pins.{{end}}</p>
+
+<h2>Pins</h2>
+<table>
+<tr><th>Name</th><th>Number</th><th>Function</th><th>Edges</th>{{if .Write}}<th>Change</th>{{end}}</tr>
+{{range .Pins}}<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{.Function}}</td><td>{{if .Edges}}{{.Edges}}{{end}}</td>
+{{- if $.Write}}<td>
+<form method="post" action="/api/pin/out"><input type="hidden" name="pin" value="{{.Name}}"><input type="hidden" name="redirect" value="1"><button name="level" value="0">Low</button><button name="level" value="1">High</button></form>
+<form method="post" action="/api/pin/watch"><input type="hidden" name="pin" value="{{.Name}}"><input type="hidden" name="redirect" value="1"><button>Count edges</button></form>
+</td>{{end}}</tr>
+{{end}}</table>
+
+<h2>Buses</h2>
+{{if .Buses}}<table>
+<tr><th>Type</th><th>Name</th><th>Aliases</th><th>Driver</th><th>Max speed</th></tr>
+{{range .Buses}}<tr><td>{{.Type}}</td><td>{{.Name}}</td><td>{{range $i, $a := .Aliases}}{{if $i}}, {{end}}{{$a}}{{end}}</td><td>{{.Driver}}</td><td>{{if .MaxSpeed}}{{.MaxSpeed}}Hz{{end}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+
+<h2>Sensors</h2>
+{{range .Sensors}}<h3>{{.Name}}</h3>
+<table>
+<tr><th>Time</th><th>Temperature (°C)</th><th>Pressure (kPa)</th><th>Humidity (%rH)</th></tr>
+{{range .Readings}}<tr><td>{{.Time.Format "15:04:05"}}</td><td>{{with .Temperature}}{{.}}{{end}}</td><td>{{with .Pressure}}{{.}}{{end}}</td><td>{{with .Humidity}}{{.}}{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p>None; restart with -sensors to probe the buses.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,303 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// sensor-log discovers the environmental sensors on the I²C and 1-wire buses
+// and logs their measurements as CSV or InfluxDB line protocol.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/bmxx80"
+	"periph.io/x/periph/devices/ds18b20"
+	"periph.io/x/periph/experimental/devices/sht3x"
+	"periph.io/x/periph/host"
+)
+
+// sensor is a discovered environmental sensor.
+type sensor struct {
+	name    string
+	dev     devices.Environmental
+	metrics devices.Metric
+}
+
+// columns are the metrics logged, in order.
+var columns = []struct {
+	m    devices.Metric
+	name string
+	get  func(e *devices.Environment) float64
+}{
+	{devices.MetricTemperature, "temperature", func(e *devices.Environment) float64 { return e.Temperature.Float64() }},
+	{devices.MetricPressure, "pressure", func(e *devices.Environment) float64 { return e.Pressure.Float64() }},
+	{devices.MetricHumidity, "humidity", func(e *devices.Environment) float64 { return e.Humidity.Float64() }},
+}
+
+// writer writes a measurement in a specific format.
+type writer interface {
+	write(s *sensor, e *devices.Environment) error
+	flush() error
+}
+
+// csvWriter writes one row per measurement. The metrics not measured by a
+// sensor are left empty.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, header bool) (*csvWriter, error) {
+	c := &csvWriter{csv.NewWriter(w)}
+	if !header {
+		return c, nil
+	}
+	h := []string{"time", "sensor"}
+	for _, col := range columns {
+		h = append(h, col.name)
+	}
+	if err := c.w.Write(h); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) write(s *sensor, e *devices.Environment) error {
+	r := []string{e.Time.Format(time.RFC3339Nano), s.name}
+	for _, col := range columns {
+		v := ""
+		if s.metrics&col.m != 0 {
+			v = strconv.FormatFloat(col.get(e), 'f', -1, 64)
+		}
+		r = append(r, v)
+	}
+	return c.w.Write(r)
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// influxWriter writes the InfluxDB line protocol, one line per measurement
+// with the sensor as a tag.
+type influxWriter struct {
+	w           *bufio.Writer
+	measurement string
+}
+
+func (i *influxWriter) write(s *sensor, e *devices.Environment) error {
+	var fields []string
+	for _, col := range columns {
+		if s.metrics&col.m != 0 {
+			fields = append(fields, col.name+"="+strconv.FormatFloat(col.get(e), 'f', -1, 64))
+		}
+	}
+	_, err := fmt.Fprintf(i.w, "%s,sensor=%s %s %d\n", influxEscape(i.measurement), influxEscape(s.name), strings.Join(fields, ","), e.Time.UnixNano())
+	return err
+}
+
+func (i *influxWriter) flush() error {
+	return i.w.Flush()
+}
+
+var influxReplacer = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxEscape escapes a measurement name or a tag value.
+func influxEscape(s string) string {
+	return influxReplacer.Replace(s)
+}
+
+// discover returns the environmental sensors found on all the buses.
+//
+// The I²C buses are probed at the addresses of the BME280/BMP280/BMP180 and
+// the SHT3x. The 1-wire buses are searched for DS18B20 compatible sensors.
+func discover(bits int) ([]*sensor, []io.Closer) {
+	var out []*sensor
+	var closers []io.Closer
+	add := func(d devices.Environmental) {
+		m := devices.MetricsOf(d)
+		if m == 0 {
+			m = devices.MetricTemperature
+		}
+		out = append(out, &sensor{name: fmt.Sprint(d), dev: d, metrics: m})
+		log.Printf("Found %s: %s", d, m)
+	}
+	for _, ref := range i2creg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		n := len(out)
+		for _, addr := range []uint16{0x76, 0x77} {
+			if d, err := bmxx80.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+		for _, addr := range []uint16{0x44, 0x45} {
+			if d, err := sht3x.NewI2C(bus, addr, nil); err == nil {
+				add(d)
+			}
+		}
+		if len(out) == n {
+			bus.Close()
+		} else {
+			closers = append(closers, bus)
+		}
+	}
+	for _, ref := range onewirereg.All() {
+		bus, err := ref.Open()
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+			continue
+		}
+		n := len(out)
+		addrs, err := bus.Search(false)
+		if err != nil {
+			log.Printf("%s: %v", ref.Name, err)
+		}
+		for _, a := range addrs {
+			switch byte(a) {
+			case 0x22, 0x28, 0x3B, 0x42:
+				if d, err := ds18b20.New(bus, a, bits); err == nil {
+					add(d)
+				} else {
+					log.Printf("%s: 0x%016x: %v", ref.Name, uint64(a), err)
+				}
+			}
+		}
+		if len(out) == n {
+			bus.Close()
+		} else {
+			closers = append(closers, bus)
+		}
+	}
+	return out, closers
+}
+
+func sample(sensors []*sensor, w writer) error {
+	for _, s := range sensors {
+		var e devices.Environment
+		if err := s.dev.Sense(&e); err != nil {
+			// A transient error on a sensor shouldn't stop the logging.
+			fmt.Fprintf(os.Stderr, "sensor-log: %s: %v\n", s.name, err)
+			continue
+		}
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if err := w.write(s, &e); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+func mainImpl() error {
+	interval := flag.Duration("i", 10*time.Second, "sampling interval")
+	count := flag.Int("n", 0, "number of samples to log; 0 means until Ctrl-C")
+	format := flag.String("f", "csv", "output format: csv or influx")
+	measurement := flag.String("m", "environment", "InfluxDB measurement name")
+	out := flag.String("o", "", "file to append to; stdout by default")
+	bits := flag.Int("r", 10, "DS18B20 resolution in bits, between 9 and 12")
+	verbose := flag.Bool("v", false, "verbose mode")
+	flag.Parse()
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+	if flag.NArg() != 0 {
+		return errors.New("unexpected argument, try -help")
+	}
+	if *interval <= 0 {
+		return errors.New("-i must be positive")
+	}
+	if *bits < 9 || *bits > 12 {
+		return errors.New("-r must be between 9 and 12")
+	}
+
+	var f io.Writer = os.Stdout
+	header := true
+	if *out != "" {
+		o, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer o.Close()
+		f = o
+		// Do not repeat the CSV header when appending.
+		if i, err := o.Stat(); err == nil && i.Size() != 0 {
+			header = false
+		}
+	}
+	var w writer
+	switch *format {
+	case "csv":
+		c, err := newCSVWriter(f, header)
+		if err != nil {
+			return err
+		}
+		w = c
+	case "influx":
+		w = &influxWriter{bufio.NewWriter(f), *measurement}
+	default:
+		return fmt.Errorf("invalid format %q", *format)
+	}
+
+	if _, err := host.Init(); err != nil {
+		return err
+	}
+	sensors, closers := discover(*bits)
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	if len(sensors) == 0 {
+		return errors.New("no sensor found")
+	}
+	defer func() {
+		for _, s := range sensors {
+			s.dev.Halt()
+		}
+	}()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	t := time.NewTicker(*interval)
+	defer t.Stop()
+	for i := 0; *count == 0 || i < *count; i++ {
+		if i != 0 {
+			select {
+			case <-c:
+				return nil
+			case <-t.C:
+			}
+		}
+		if err := sample(sensors, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "sensor-log: %s.\n", err)
+		os.Exit(1)
+	}
+}
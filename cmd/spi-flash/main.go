@@ -0,0 +1,320 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// spi-flash identifies, reads, erases, writes and verifies a SPI NOR flash
+// memory.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/experimental/devices/w25qxx"
+	"periph.io/x/periph/host"
+)
+
+// chunk is the size of each operation between progress updates.
+const chunk = w25qxx.BlockSize
+
+// command is a subcommand.
+type command struct {
+	name string
+	args string
+	desc string
+	run  func(d *w25qxx.Dev, f *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"id", "", "prints the chip identification", cmdID},
+	{"read", "<file>", "dumps the memory to a file", cmdRead},
+	{"erase", "", "erases the memory", cmdErase},
+	{"write", "<file>", "erases, programs and verifies the memory with a file", cmdWrite},
+	{"verify", "<file>", "compares the memory with a file", cmdVerify},
+}
+
+func usage(fs *flag.FlagSet) {
+	io.WriteString(os.Stderr, "Usage: spi-flash <args> <command> <command args> ...\n\n")
+	fs.PrintDefaults()
+	io.WriteString(os.Stderr, "\nCommands available:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", c.name+" "+c.args, c.desc)
+	}
+	io.WriteString(os.Stderr, "\nUse \"spi-flash <command> -help\" for the command specific args.\n")
+}
+
+func cmdID(d *w25qxx.Dev, f *flag.FlagSet, args []string) error {
+	if err := parseArgs(f, args, 0); err != nil {
+		return err
+	}
+	id, err := d.UniqueID()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\nUnique ID: %X\n", d.ID(), id)
+	return nil
+}
+
+func cmdRead(d *w25qxx.Dev, f *flag.FlagSet, args []string) error {
+	off := f.Int64("o", 0, "offset to start reading at")
+	n := f.Int64("n", 0, "number of bytes to read; 0 means up to the end")
+	if err := parseArgs(f, args, 1); err != nil {
+		return err
+	}
+	l, err := area(d, *off, *n, 1)
+	if err != nil {
+		return err
+	}
+	b, err := read(d, *off, l)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.Arg(0), b, 0644)
+}
+
+func cmdErase(d *w25qxx.Dev, f *flag.FlagSet, args []string) error {
+	off := f.Int64("o", 0, "offset to start erasing at, in multiple of 4096 bytes")
+	n := f.Int64("n", 0, "number of bytes to erase, in multiple of 4096 bytes; 0 means up to the end")
+	if err := parseArgs(f, args, 0); err != nil {
+		return err
+	}
+	l, err := area(d, *off, *n, w25qxx.SectorSize)
+	if err != nil {
+		return err
+	}
+	if *off == 0 && l == d.Size() {
+		// Much faster than erasing each block.
+		log.Printf("Erasing the whole chip")
+		return d.EraseChip()
+	}
+	return erase(d, *off, l)
+}
+
+func cmdWrite(d *w25qxx.Dev, f *flag.FlagSet, args []string) error {
+	off := f.Int64("o", 0, "offset to start writing at, in multiple of 4096 bytes")
+	noErase := f.Bool("no-erase", false, "skip erasing the area before programming")
+	noVerify := f.Bool("no-verify", false, "skip verifying the area after programming")
+	if err := parseArgs(f, args, 1); err != nil {
+		return err
+	}
+	b, err := readFile(d, f.Arg(0), *off)
+	if err != nil {
+		return err
+	}
+	if !*noErase {
+		// Round up to the sector size.
+		l := (int64(len(b)) + w25qxx.SectorSize - 1) &^ (w25qxx.SectorSize - 1)
+		if _, err := area(d, *off, l, w25qxx.SectorSize); err != nil {
+			return err
+		}
+		if err := erase(d, *off, l); err != nil {
+			return err
+		}
+	}
+	p := newProgress("Writing", int64(len(b)))
+	for i := 0; i < len(b); i += chunk {
+		end := i + chunk
+		if end > len(b) {
+			end = len(b)
+		}
+		if _, err := d.WriteAt(b[i:end], *off+int64(i)); err != nil {
+			return err
+		}
+		p.update(int64(end))
+	}
+	if *noVerify {
+		return nil
+	}
+	return verify(d, b, *off)
+}
+
+func cmdVerify(d *w25qxx.Dev, f *flag.FlagSet, args []string) error {
+	off := f.Int64("o", 0, "offset to start comparing at")
+	if err := parseArgs(f, args, 1); err != nil {
+		return err
+	}
+	b, err := readFile(d, f.Arg(0), *off)
+	if err != nil {
+		return err
+	}
+	return verify(d, b, *off)
+}
+
+//
+
+// progress prints the progress of an operation to stderr.
+type progress struct {
+	op    string
+	total int64
+}
+
+func newProgress(op string, total int64) *progress {
+	p := &progress{op, total}
+	p.update(0)
+	return p
+}
+
+func (p *progress) update(done int64) {
+	pct := int64(100)
+	if p.total != 0 {
+		pct = 100 * done / p.total
+	}
+	fmt.Fprintf(os.Stderr, "\r%s: %3d%% (%d/%d KiB)", p.op, pct, done>>10, p.total>>10)
+	if done == p.total {
+		io.WriteString(os.Stderr, "\n")
+	}
+}
+
+func parseArgs(f *flag.FlagSet, args []string, nargs int) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if f.NArg() != nargs {
+		return fmt.Errorf("expected %d arguments, got %d", nargs, f.NArg())
+	}
+	return nil
+}
+
+// area validates an area of the memory and returns its size, resolving a size
+// of 0 as up to the end of the memory.
+func area(d *w25qxx.Dev, off, n, align int64) (int64, error) {
+	if n == 0 {
+		n = d.Size() - off
+	}
+	if off < 0 || n <= 0 || off+n > d.Size() {
+		return 0, fmt.Errorf("area [%d, %d) is out of the memory of %d bytes", off, off+n, d.Size())
+	}
+	if off%align != 0 || n%align != 0 {
+		return 0, fmt.Errorf("offset and size must be multiple of %d", align)
+	}
+	return n, nil
+}
+
+func readFile(d *w25qxx.Dev, name string, off int64) ([]byte, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+	if _, err := area(d, off, int64(len(b)), 1); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func read(d *w25qxx.Dev, off, n int64) ([]byte, error) {
+	b := make([]byte, n)
+	p := newProgress("Reading", n)
+	for i := int64(0); i < n; i += chunk {
+		end := i + chunk
+		if end > n {
+			end = n
+		}
+		if _, err := d.ReadAt(b[i:end], off+i); err != nil {
+			return nil, err
+		}
+		p.update(end)
+	}
+	return b, nil
+}
+
+func erase(d *w25qxx.Dev, off, n int64) error {
+	p := newProgress("Erasing", n)
+	for i := int64(0); i < n; {
+		// Keep the chunks aligned on blocks so the driver can erase whole
+		// blocks.
+		end := (off + i + chunk) &^ (chunk - 1)
+		if end > off+n {
+			end = off + n
+		}
+		if err := d.Erase(off+i, end-off-i); err != nil {
+			return err
+		}
+		i = end - off
+		p.update(i)
+	}
+	return nil
+}
+
+func verify(d *w25qxx.Dev, b []byte, off int64) error {
+	got, err := read(d, off, int64(len(b)))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, b) {
+		for i := range b {
+			if got[i] != b[i] {
+				return fmt.Errorf("verification failed at offset 0x%X: got 0x%02X, expected 0x%02X", off+int64(i), got[i], b[i])
+			}
+		}
+	}
+	log.Printf("Verified %d bytes", len(b))
+	return nil
+}
+
+func mainImpl() error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	spiName := fs.String("b", "", "SPI port to use")
+	hz := fs.Int("hz", 0, "SPI port speed; the maximum speed supported by the chip by default")
+	verbose := fs.Bool("v", false, "enable verbose logs")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(os.Args[1:]); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("please specify a command or use -help")
+	}
+	name := fs.Arg(0)
+	if name == "help" {
+		usage(fs)
+		return nil
+	}
+
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	log.SetFlags(log.Lmicroseconds)
+
+	for _, c := range commands {
+		if c.name == name {
+			if _, err := host.Init(); err != nil {
+				return err
+			}
+			p, err := spireg.Open(*spiName)
+			if err != nil {
+				return err
+			}
+			defer p.Close()
+			d, err := w25qxx.NewSPI(p, int64(*hz))
+			if err != nil {
+				return err
+			}
+			log.Printf("Found %s", d.ID())
+			f := flag.NewFlagSet("spi-flash "+c.name, flag.ContinueOnError)
+			if err := c.run(d, f, fs.Args()[1:]); err != flag.ErrHelp {
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown command %q; use -help", name)
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "spi-flash: %s.\n", err)
+		os.Exit(1)
+	}
+}
@@ -130,6 +130,7 @@ func mainImpl() error {
 	i2cID := flag.String("i2c", "", "I²C bus to use")
 	spiID := flag.String("spi", "", "SPI port to use")
 	dcName := flag.String("dc", "", "DC pin to use in 4-wire SPI mode")
+	rstName := flag.String("rst", "", "RES pin to use to reset the display in SPI mode")
 	hz := flag.Int("hz", 0, "I²C bus/SPI port speed")
 
 	h := flag.Int("h", 64, "display height")
@@ -174,7 +175,15 @@ func mainImpl() error {
 		if len(*dcName) != 0 {
 			dc = gpioreg.ByName(*dcName)
 		}
-		s, err = ssd1306.NewSPI(c, dc, *w, *h, *rotated)
+		if len(*rstName) != 0 {
+			rst := gpioreg.ByName(*rstName)
+			if rst == nil {
+				return fmt.Errorf("invalid pin %q", *rstName)
+			}
+			s, err = ssd1306.NewSPIReset(c, dc, rst, *w, *h, *rotated)
+		} else {
+			s, err = ssd1306.NewSPI(c, dc, *w, *h, *rotated)
+		}
 		if err != nil {
 			return err
 		}
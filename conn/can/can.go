@@ -0,0 +1,172 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package can defines the CAN bus protocol.
+//
+// CAN is a multi-master broadcast bus where each frame is identified by an 11
+// bits (standard) or 29 bits (extended) ID instead of being addressed to a
+// device. CAN FD extends the payload from 8 to up to 64 bytes.
+//
+// Unlike I²C or SPI, there is no transaction; a Bus sends and receives frames
+// independently.
+package can
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Maximum ID values.
+const (
+	MaxStandardID = 0x7FF
+	MaxExtendedID = 0x1FFFFFFF
+)
+
+// Maximum payload length.
+const (
+	MaxLen   = 8
+	MaxFDLen = 64
+)
+
+// Frame is a CAN frame.
+type Frame struct {
+	// ID is the frame identifier; it must not be higher than MaxStandardID
+	// unless Extended is set.
+	ID uint32
+	// Extended specifies a 29 bits identifier.
+	Extended bool
+	// RTR marks a remote transmission request. Data is not sent; its length is
+	// the requested length.
+	RTR bool
+	// FD marks a CAN FD frame.
+	FD bool
+	// BRS requests the bit rate switch for the data phase of a CAN FD frame.
+	BRS bool
+	// Data is the payload, up to MaxLen bytes, or MaxFDLen bytes for a CAN FD
+	// frame.
+	Data []byte
+}
+
+func (f *Frame) String() string {
+	var out []string
+	if f.Extended {
+		out = append(out, fmt.Sprintf("%08X", f.ID))
+	} else {
+		out = append(out, fmt.Sprintf("%03X", f.ID))
+	}
+	if f.FD {
+		out = append(out, "FD")
+		if f.BRS {
+			out = append(out, "BRS")
+		}
+	}
+	if f.RTR {
+		out = append(out, fmt.Sprintf("RTR[%d]", len(f.Data)))
+	} else {
+		out = append(out, fmt.Sprintf("[%d] % X", len(f.Data), f.Data))
+	}
+	return strings.TrimSpace(strings.Join(out, " "))
+}
+
+// Validate returns an error if the frame cannot be sent.
+func (f *Frame) Validate() error {
+	if f.Extended {
+		if f.ID > MaxExtendedID {
+			return fmt.Errorf("can: invalid extended ID 0x%X", f.ID)
+		}
+	} else if f.ID > MaxStandardID {
+		return fmt.Errorf("can: invalid standard ID 0x%X", f.ID)
+	}
+	if f.FD {
+		if f.RTR {
+			return errors.New("can: CAN FD doesn't support remote frames")
+		}
+		if !isFDLen(len(f.Data)) {
+			return fmt.Errorf("can: invalid CAN FD payload length %d", len(f.Data))
+		}
+		return nil
+	}
+	if f.BRS {
+		return errors.New("can: BRS requires a CAN FD frame")
+	}
+	if len(f.Data) > MaxLen {
+		return fmt.Errorf("can: invalid payload length %d", len(f.Data))
+	}
+	return nil
+}
+
+// Filter selects the frames to receive.
+//
+// A frame is accepted when its ID has the same bits as ID for all the bits set
+// in Mask. Standard and extended frames are filtered separately.
+type Filter struct {
+	ID   uint32
+	Mask uint32
+	// Extended selects extended frames instead of standard frames.
+	Extended bool
+	// Invert accepts the frames that do not match.
+	Invert bool
+}
+
+// Match returns true if the frame is accepted by the filter.
+func (l *Filter) Match(f *Frame) bool {
+	return (f.Extended == l.Extended && f.ID&l.Mask == l.ID&l.Mask) != l.Invert
+}
+
+// Bus defines the interface a concrete CAN driver must implement.
+//
+// This interface is consummed by a device driver for a device sitting on a
+// bus.
+//
+// This interface doesn't implement conn.Conn since a device on a CAN bus is
+// not addressed; a device driver sends and receives the frames with the IDs
+// it is interested in.
+type Bus interface {
+	fmt.Stringer
+	// Send sends a frame on the bus.
+	//
+	// It returns once the frame was queued for transmission.
+	Send(f *Frame) error
+	// Receive blocks until a frame is received and stores it into f.
+	//
+	// Only the frames accepted by the filters set with SetFilters are
+	// returned.
+	Receive(f *Frame) error
+	// SetFilters sets the filters for Receive. A frame is accepted if it
+	// matches any filter. An empty list accepts all the frames.
+	SetFilters(filters []Filter) error
+}
+
+// BusCloser is a CAN bus that can be closed.
+//
+// This interface is meant to be handled by the application and not the device
+// driver. A device driver doesn't "own" a bus, hence it must operate on a Bus,
+// not a BusCloser.
+type BusCloser interface {
+	io.Closer
+	Bus
+}
+
+// FDBus is implemented by the buses that support CAN FD frames.
+type FDBus interface {
+	Bus
+	// EnableFD enables sending and receiving CAN FD frames. It is disabled by
+	// default.
+	EnableFD(enable bool) error
+}
+
+//
+
+// isFDLen returns true if l is a payload length that can be encoded in a CAN
+// FD frame.
+func isFDLen(l int) bool {
+	switch l {
+	case 0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package can
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleFilter() {
+	//b, err := canreg.Open("")
+	//defer b.Close()
+	var b Bus
+
+	// Only receive the OBD-II responses.
+	if err := b.SetFilters([]Filter{{ID: 0x7E8, Mask: 0x7F8}}); err != nil {
+		fmt.Printf("Failed to set filters: %v", err)
+		return
+	}
+	var f Frame
+	if err := b.Receive(&f); err != nil {
+		fmt.Printf("Failed to receive: %v", err)
+		return
+	}
+	fmt.Printf("%s\n", &f)
+}
+
+//
+
+func TestFrame_String(t *testing.T) {
+	data := []struct {
+		f Frame
+		s string
+	}{
+		{Frame{ID: 0x123, Data: []byte{1, 0xAB}}, "123 [2] 01 AB"},
+		{Frame{ID: 0x12345, Extended: true}, "00012345 [0]"},
+		{Frame{ID: 0x7DF, RTR: true, Data: make([]byte, 8)}, "7DF RTR[8]"},
+		{Frame{ID: 1, FD: true, BRS: true, Data: []byte{2}}, "001 FD BRS [1] 02"},
+	}
+	for i, line := range data {
+		if s := line.f.String(); s != line.s {
+			t.Fatalf("#%d: %q != %q", i, s, line.s)
+		}
+	}
+}
+
+func TestFrame_Validate(t *testing.T) {
+	valid := []Frame{
+		{ID: MaxStandardID, Data: make([]byte, MaxLen)},
+		{ID: MaxExtendedID, Extended: true},
+		{ID: 1, RTR: true},
+		{ID: 1, FD: true, BRS: true, Data: make([]byte, 12)},
+		{ID: 1, FD: true, Data: make([]byte, MaxFDLen)},
+	}
+	for i, f := range valid {
+		if err := f.Validate(); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+	invalid := []Frame{
+		{ID: MaxStandardID + 1},
+		{ID: MaxExtendedID + 1, Extended: true},
+		{ID: 1, Data: make([]byte, MaxLen+1)},
+		{ID: 1, BRS: true},
+		{ID: 1, FD: true, RTR: true},
+		{ID: 1, FD: true, Data: make([]byte, 9)},
+	}
+	for i, f := range invalid {
+		if f.Validate() == nil {
+			t.Fatalf("#%d: expected error", i)
+		}
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	f := Filter{ID: 0x7E8, Mask: 0x7F8}
+	if !f.Match(&Frame{ID: 0x7EF}) {
+		t.Fatal("expected match")
+	}
+	if f.Match(&Frame{ID: 0x7E0}) {
+		t.Fatal("unexpected match")
+	}
+	if f.Match(&Frame{ID: 0x7E8, Extended: true}) {
+		t.Fatal("extended frames are filtered separately")
+	}
+	f.Invert = true
+	if !f.Match(&Frame{ID: 0x7E0}) {
+		t.Fatal("expected inverted match")
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package canreg defines CAN bus registry to list buses present on the host.
+package canreg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/can"
+)
+
+// Opener opens an handle to a bus.
+//
+// It is provided by the actual bus driver.
+type Opener func() (can.BusCloser, error)
+
+// Ref references a CAN bus.
+//
+// It is returned by All() to enumerate all registered buses.
+type Ref struct {
+	// Name of the bus.
+	//
+	// It must not be a sole number. It must be unique across the host.
+	Name string
+	// Aliases are the alternative names that can be used to reference this bus.
+	Aliases []string
+	// Number of the bus or -1 if the bus doesn't have any "native" number.
+	//
+	// Buses provided by the CPU normally have a 0 based number. Buses provided
+	// via an addon (like over USB) generally are not numbered.
+	Number int
+	// Info is the metadata provided by the driver, if any.
+	Info conn.Info
+	// Open is the factory to open an handle to this CAN bus.
+	Open Opener
+}
+
+// Open opens a CAN bus by its name, an alias or its number and returns an
+// handle to it.
+//
+// Specify the empty string "" to get the first available bus. This is the
+// recommended default value unless an application knows the exact bus to use.
+//
+// Each bus can register multiple aliases, each leading to the same bus handle.
+//
+// "Bus number" is a generic concept that is highly dependent on the platform
+// and OS. On linux, the SocketCAN interface can0 has the number 0.
+//
+// When the CAN bus is provided by an off board plug and play bus like USB,
+// there can be no associated number.
+func Open(name string) (can.BusCloser, error) {
+	var r *Ref
+	var err error
+	func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(byName) == 0 {
+			err = wrapf("no bus found; did you forget to call Init()?")
+			return
+		}
+		if len(name) == 0 {
+			r = getDefault()
+			return
+		}
+		// Try by name, by alias, by number.
+		if r = byName[name]; r == nil {
+			if r = byAlias[name]; r == nil {
+				if i, err2 := strconv.Atoi(name); err2 == nil {
+					r = byNumber[i]
+				}
+			}
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, wrapf("can't open unknown bus: %q", name)
+	}
+	return r.Open()
+}
+
+// All returns a copy of all the registered references to all know CAN buses
+// available on this host.
+//
+// The list is sorted by the bus name.
+func All() []*Ref {
+	var out refList
+	func() {
+		mu.Lock()
+		defer mu.Unlock()
+		out = make(refList, 0, len(byName))
+		for _, v := range byName {
+			r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Info: v.Info, Open: v.Open}
+			r.Info.Features = append([]string(nil), v.Info.Features...)
+			copy(r.Aliases, v.Aliases)
+			out = append(out, r)
+		}
+	}()
+	sort.Sort(out)
+	return out
+}
+
+// Register registers a CAN bus.
+//
+// Registering the same bus name twice is an error, e.g. o.Name(). o.Number()
+// can be -1 to signify that the bus doesn't have an inherent "bus number". A
+// good example is a bus provided by an USB adapter. In this case, the bus name
+// should be created from the serial number of the device for unique
+// identification.
+func Register(name string, aliases []string, number int, o Opener) error {
+	return RegisterWithInfo(name, aliases, number, conn.Info{}, o)
+}
+
+// RegisterWithInfo is like Register and also records the metadata of the
+// bus, returned in Ref.Info.
+func RegisterWithInfo(name string, aliases []string, number int, info conn.Info, o Opener) error {
+	if len(name) == 0 {
+		return wrapf("can't register a bus with no name")
+	}
+	if o == nil {
+		return wrapf("can't register bus %q with nil Opener", name)
+	}
+	if number < -1 {
+		return wrapf("can't register bus %q with invalid bus number %d", name, number)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return wrapf("can't register bus %q with name being only a number", name)
+	}
+	if strings.Contains(name, ":") {
+		return wrapf("can't register bus %q with name containing ':'", name)
+	}
+	for _, alias := range aliases {
+		if len(alias) == 0 {
+			return wrapf("can't register bus %q with an empty alias", name)
+		}
+		if name == alias {
+			return wrapf("can't register bus %q with an alias the same as the bus name", name)
+		}
+		if _, err := strconv.Atoi(alias); err == nil {
+			return wrapf("can't register bus %q with an alias that is a number: %q", name, alias)
+		}
+		if strings.Contains(alias, ":") {
+			return wrapf("can't register bus %q with an alias containing ':': %q", name, alias)
+		}
+	}
+
+	r, err := register(name, aliases, number, info, o)
+	if err != nil {
+		return err
+	}
+	notify(r, true)
+	return nil
+}
+
+// Unregister removes a previously registered CAN bus.
+//
+// This can happen when a CAN bus is exposed via an USB device and the device
+// is unplugged.
+func Unregister(name string) error {
+	mu.Lock()
+	r := byName[name]
+	if r != nil {
+		delete(byName, name)
+		delete(byNumber, r.Number)
+		for _, alias := range r.Aliases {
+			delete(byAlias, alias)
+		}
+	}
+	mu.Unlock()
+	if r == nil {
+		return wrapf("can't unregister unknown bus name %q", name)
+	}
+	notify(r, false)
+	return nil
+}
+
+// Notify registers f to be called after a bus is registered or unregistered.
+//
+// f is called synchronously, outside of the registry lock. Call the returned
+// function to stop the notifications.
+func Notify(f func(r *Ref, registered bool)) func() {
+	w := &watcher{f: f}
+	mu.Lock()
+	defer mu.Unlock()
+	watchers = append(watchers, w)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range watchers {
+			if watchers[i] == w {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+//
+
+var (
+	mu     sync.Mutex
+	byName = map[string]*Ref{}
+	// Caches
+	byNumber = map[int]*Ref{}
+	byAlias  = map[string]*Ref{}
+	watchers []*watcher
+)
+
+type watcher struct {
+	f func(r *Ref, registered bool)
+}
+
+// notify calls the watchers registered with Notify().
+func notify(r *Ref, registered bool) {
+	mu.Lock()
+	w := append([]*watcher(nil), watchers...)
+	mu.Unlock()
+	for _, x := range w {
+		x.f(r, registered)
+	}
+}
+
+// register adds the bus to the registry.
+func register(name string, aliases []string, number int, info conn.Info, o Opener) (*Ref, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byName[name]; ok {
+		return nil, wrapf("can't register bus %q twice", name)
+	}
+	if _, ok := byAlias[name]; ok {
+		return nil, wrapf("can't register bus %q twice; it is already an alias", name)
+	}
+	if number != -1 {
+		if _, ok := byNumber[number]; ok {
+			return nil, wrapf("can't register bus %q; bus number %d is already registered", name, number)
+		}
+	}
+	for _, alias := range aliases {
+		if _, ok := byName[alias]; ok {
+			return nil, wrapf("can't register bus %q twice; alias %q is already a bus", name, alias)
+		}
+		if _, ok := byAlias[alias]; ok {
+			return nil, wrapf("can't register bus %q twice; alias %q is already an alias", name, alias)
+		}
+	}
+
+	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Info: info, Open: o}
+	r.Info.Features = append([]string(nil), info.Features...)
+	copy(r.Aliases, aliases)
+	byName[name] = r
+	if number != -1 {
+		byNumber[number] = r
+	}
+	for _, alias := range aliases {
+		byAlias[alias] = r
+	}
+	return r, nil
+}
+
+// getDefault returns the Ref that should be used as the default bus.
+func getDefault() *Ref {
+	var o *Ref
+	if len(byNumber) == 0 {
+		// Fallback to use byName using a lexical sort.
+		name := ""
+		for n, o2 := range byName {
+			if len(name) == 0 || n < name {
+				o = o2
+				name = n
+			}
+		}
+		return o
+	}
+	number := int((^uint(0)) >> 1)
+	for n, o2 := range byNumber {
+		if number > n {
+			number = n
+			o = o2
+		}
+	}
+	return o
+}
+
+// wrapf returns an error that is wrapped with the package name.
+func wrapf(format string, a ...interface{}) error {
+	return fmt.Errorf("canreg: "+format, a...)
+}
+
+type refList []*Ref
+
+func (r refList) Len() int           { return len(r) }
+func (r refList) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r refList) Less(i, j int) bool { return r[i].Name < r[j].Name }
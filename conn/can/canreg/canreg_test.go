@@ -0,0 +1,269 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package canreg
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/can"
+)
+
+func ExampleAll() {
+	// Enumerate all CAN buses available and the corresponding pins.
+	fmt.Print("CAN buses available:\n")
+	for _, ref := range All() {
+		fmt.Printf("- %s\n", ref.Name)
+		if ref.Number != -1 {
+			fmt.Printf("  %d\n", ref.Number)
+		}
+		if len(ref.Aliases) != 0 {
+			fmt.Printf("  %s\n", strings.Join(ref.Aliases, " "))
+		}
+
+		b, err := ref.Open()
+		if err != nil {
+			fmt.Printf("  Failed to open: %v", err)
+		}
+		if err := b.Close(); err != nil {
+			fmt.Printf("  Failed to close: %v", err)
+		}
+	}
+}
+
+func ExampleOpen() {
+	// On linux, the following calls will likely open the same bus.
+	Open("can0")
+	Open("0")
+
+	// How a command line tool may let the user choose a CAN bus, yet default to
+	// the first bus known.
+	name := flag.String("can", "", "CAN bus to use")
+	flag.Parse()
+	b, err := Open(*name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	// Use b...
+	b.Send(&can.Frame{ID: 0x123, Data: []byte("cmd")})
+}
+
+//
+
+func TestOpen(t *testing.T) {
+	defer reset()
+	if _, err := Open(""); err == nil {
+		t.Fatal("no bus registered")
+	}
+	if err := Register("a", []string{"x"}, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if o, err := Open(""); o == nil || err != nil {
+		t.Fatal(o, err)
+	}
+	if o, err := Open("1"); o == nil || err != nil {
+		t.Fatal(o, err)
+	}
+	if o, err := Open("x"); o == nil || err != nil {
+		t.Fatal(o, err)
+	}
+	if o, err := Open("y"); o != nil || err == nil {
+		t.Fatal(o, err)
+	}
+}
+
+func TestDefault_NoNumber(t *testing.T) {
+	defer reset()
+	if err := Register("a", nil, -1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if o, err := Open(""); o == nil || err != nil {
+		t.Fatal(o, err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	defer reset()
+	if a := All(); len(a) != 0 {
+		t.Fatal(a)
+	}
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("b", nil, 2, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if a := All(); len(a) != 2 {
+		t.Fatal(a)
+	}
+}
+
+func TestRefList(t *testing.T) {
+	l := refList{&Ref{Name: "b"}, &Ref{Name: "a"}}
+	sort.Sort(l)
+	if l[0].Name != "a" || l[1].Name != "b" {
+		t.Fatal(l)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer reset()
+	if err := Register("a", []string{"b"}, 42, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if Register("a", nil, -1, fakeBuser) == nil {
+		t.Fatal("same bus name")
+	}
+	if Register("b", nil, -1, fakeBuser) == nil {
+		t.Fatal("same bus alias name")
+	}
+	if Register("c", nil, 42, fakeBuser) == nil {
+		t.Fatal("same bus number")
+	}
+	if Register("c", []string{"a"}, -1, fakeBuser) == nil {
+		t.Fatal("same bus alias")
+	}
+	if Register("c", []string{"b"}, -1, fakeBuser) == nil {
+		t.Fatal("same bus alias")
+	}
+}
+
+func TestRegister_fail(t *testing.T) {
+	defer reset()
+	if Register("a", nil, -1, nil) == nil {
+		t.Fatal("missing Opener")
+	}
+	if Register("a", nil, -2, fakeBuser) == nil {
+		t.Fatal("bad bus number")
+	}
+	if Register("", nil, 42, fakeBuser) == nil {
+		t.Fatal("missing name")
+	}
+	if Register("1", nil, 42, fakeBuser) == nil {
+		t.Fatal("numeric name")
+	}
+	if Register("a:b", nil, 42, fakeBuser) == nil {
+		t.Fatal("':' in name")
+	}
+	if Register("a", []string{"a"}, 0, fakeBuser) == nil {
+		t.Fatal("\"a\" is already registered")
+	}
+	if Register("a", []string{""}, 0, fakeBuser) == nil {
+		t.Fatal("empty alias")
+	}
+	if Register("a", []string{"1"}, 0, fakeBuser) == nil {
+		t.Fatal("numeric alias")
+	}
+	if Register("a", []string{"a:b"}, 0, fakeBuser) == nil {
+		t.Fatal("':' in alias")
+	}
+	if a := All(); len(a) != 0 {
+		t.Fatal(a)
+	}
+}
+
+func TestRegisterWithInfo(t *testing.T) {
+	defer reset()
+	info := conn.Info{Driver: "fake", Path: "/dev/fake", MaxSpeed: 100, Features: []string{"x"}}
+	if err := RegisterWithInfo("a", nil, -1, info, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	// The registry keeps its own copy.
+	info.Features[0] = "y"
+	a := All()
+	if len(a) != 1 || a[0].Info.Driver != "fake" || a[0].Info.Path != "/dev/fake" || a[0].Info.MaxSpeed != 100 || a[0].Info.Features[0] != "x" {
+		t.Fatal(a)
+	}
+	a[0].Info.Features[0] = "z"
+	if f := All()[0].Info.Features[0]; f != "x" {
+		t.Fatal(f)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	defer reset()
+	if Unregister("") == nil {
+		t.Fatal("unregister empty")
+	}
+	if Unregister("a") == nil {
+		t.Fatal("unregister non-existing")
+	}
+	if err := Register("a", []string{"b"}, 0, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	defer reset()
+	var events []string
+	stop := Notify(func(r *Ref, registered bool) {
+		events = append(events, fmt.Sprintf("%s:%t", r.Name, registered))
+	})
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("a", nil, 1, fakeBuser); err == nil {
+		t.Fatal("registering twice must not notify")
+	}
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if err := Register("b", nil, 2, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(events); s != "[a:true a:false]" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func fakeBuser() (can.BusCloser, error) {
+	return &fakeBus{}, nil
+}
+
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	byName = map[string]*Ref{}
+	byNumber = map[int]*Ref{}
+	byAlias = map[string]*Ref{}
+	watchers = nil
+}
+
+type fakeBus struct {
+	frames []can.Frame
+}
+
+func (f *fakeBus) Close() error {
+	return nil
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Send(fr *can.Frame) error {
+	f.frames = append(f.frames, *fr)
+	return nil
+}
+
+func (f *fakeBus) Receive(fr *can.Frame) error {
+	return nil
+}
+
+func (f *fakeBus) SetFilters(filters []can.Filter) error {
+	return nil
+}
@@ -4,7 +4,10 @@
 
 package conn
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Resource is a basic resource (like a gpio pin) or a device.
 //
@@ -93,6 +96,34 @@ type Conn interface {
 	Duplex() Duplex
 }
 
+// ConnCtx is an optional interface a Conn may implement to support
+// cancellation and deadlines via a context.Context.
+//
+// Use TxCtx() to use it when available.
+type ConnCtx interface {
+	// TxCtx is like Conn.Tx but aborts the transaction when ctx is done.
+	//
+	// Depending on the hardware, a transaction may not be interruptible once
+	// started. The driver maps the context deadline to the hardware timeout
+	// when supported.
+	TxCtx(ctx context.Context, w, r []byte) error
+}
+
+// TxCtx does a single transaction on c, honoring ctx.
+//
+// When c implements ConnCtx, the call is forwarded to it. Otherwise ctx is
+// only checked before the transaction is started, as Conn.Tx cannot be
+// interrupted.
+func TxCtx(ctx context.Context, c Conn, w, r []byte) error {
+	if cc, ok := c.(ConnCtx); ok {
+		return cc.TxCtx(ctx, w, r)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Tx(w, r)
+}
+
 // Limits returns information about the connection's limits.
 type Limits interface {
 	// MaxTxSize returns the maximum allowed data size to be sent as a single
@@ -101,3 +132,20 @@ type Limits interface {
 	// Returns 0 if undefined.
 	MaxTxSize() int
 }
+
+// Info is the metadata of a connection provided by its driver.
+//
+// It is returned by the bus registries, e.g. i2creg.Ref, so tools can present
+// the buses consistently.
+type Info struct {
+	// Driver is the name of the driver providing the bus, e.g. "sysfs-i2c".
+	Driver string
+	// Path is the OS specific path of the device, if any, e.g. "/dev/i2c-1".
+	Path string
+	// MaxSpeed is the maximum bus speed in Hz supported by the controller, or 0
+	// if unknown.
+	MaxSpeed int64
+	// Features lists the optional capabilities of the bus, e.g. "fd" for a CAN
+	// FD bus.
+	Features []string
+}
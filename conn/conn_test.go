@@ -5,6 +5,8 @@
 package conn
 
 import (
+	"context"
+	"errors"
 	"log"
 	"testing"
 )
@@ -27,3 +29,42 @@ func TestDuplex(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestTxCtx(t *testing.T) {
+	c := &fakeConn{}
+	if err := TxCtx(context.Background(), c, []byte{1}, nil); err != nil || c.tx != 1 {
+		t.Fatal(err, c.tx)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TxCtx(ctx, c, []byte{1}, nil); err != context.Canceled || c.tx != 1 {
+		t.Fatal(err, c.tx)
+	}
+	cc := &fakeConnCtx{}
+	if err := TxCtx(ctx, cc, []byte{1}, nil); err == nil || err.Error() != "ctx" || cc.tx != 0 {
+		t.Fatal(err, cc.tx)
+	}
+}
+
+//
+
+type fakeConn struct {
+	tx int
+}
+
+func (f *fakeConn) Tx(w, r []byte) error {
+	f.tx++
+	return nil
+}
+
+func (f *fakeConn) Duplex() Duplex {
+	return Half
+}
+
+type fakeConnCtx struct {
+	fakeConn
+}
+
+func (f *fakeConnCtx) TxCtx(ctx context.Context, w, r []byte) error {
+	return errors.New("ctx")
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package connreg lists the buses of all the bus registries.
+//
+// It presents the I²C, SPI, 1-wire, CAN buses and UART ports registered in
+// i2creg, spireg, onewirereg, canreg and uartreg with their metadata in a
+// single list, so tools can present them consistently.
+package connreg
+
+import (
+	"sort"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/can/canreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+// Type is the type of a bus.
+type Type string
+
+// Bus types.
+const (
+	I2C     Type = "I²C"
+	SPI     Type = "SPI"
+	OneWire Type = "1-wire"
+	CAN     Type = "CAN"
+	UART    Type = "UART"
+)
+
+// Bus describes a registered bus.
+type Bus struct {
+	// Type is the registry the bus is registered in.
+	Type Type
+	// Name is the bus name in its registry.
+	Name string
+	// Aliases are the alternative names of the bus.
+	Aliases []string
+	// Number is the bus number or -1.
+	Number int
+	// Info is the metadata provided by the driver.
+	conn.Info
+}
+
+// All returns all the registered buses.
+//
+// The list is sorted by type then by name. Use the corresponding registry to
+// open a bus.
+func All() []Bus {
+	var out busList
+	for _, r := range i2creg.All() {
+		out = append(out, Bus{I2C, r.Name, r.Aliases, r.Number, r.Info})
+	}
+	for _, r := range spireg.All() {
+		out = append(out, Bus{SPI, r.Name, r.Aliases, r.Number, r.Info})
+	}
+	for _, r := range onewirereg.All() {
+		out = append(out, Bus{OneWire, r.Name, r.Aliases, r.Number, r.Info})
+	}
+	for _, r := range canreg.All() {
+		out = append(out, Bus{CAN, r.Name, r.Aliases, r.Number, r.Info})
+	}
+	for _, r := range uartreg.All() {
+		out = append(out, Bus{UART, r.Name, r.Aliases, r.Number, r.Info})
+	}
+	// The registries return sorted lists, so this only sorts by type.
+	sort.Stable(out)
+	return out
+}
+
+// ByType returns the registered buses of a type, sorted by name.
+func ByType(t Type) []Bus {
+	var out []Bus
+	for _, b := range All() {
+		if b.Type == t {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+//
+
+type busList []Bus
+
+func (b busList) Len() int           { return len(b) }
+func (b busList) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b busList) Less(i, j int) bool { return b[i].Type < b[j].Type }
@@ -0,0 +1,112 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package connreg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/can"
+	"periph.io/x/periph/conn/can/canreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+func ExampleAll() {
+	// Enumerate all the buses available with their metadata.
+	for _, b := range All() {
+		fmt.Printf("- %s %s", b.Type, b.Name)
+		if len(b.Aliases) != 0 {
+			fmt.Printf(" (%s)", strings.Join(b.Aliases, ", "))
+		}
+		if b.Driver != "" {
+			fmt.Printf(" via %s", b.Driver)
+		}
+		if b.MaxSpeed != 0 {
+			fmt.Printf(" up to %dHz", b.MaxSpeed)
+		}
+		if len(b.Features) != 0 {
+			fmt.Printf(" [%s]", strings.Join(b.Features, ", "))
+		}
+		fmt.Print("\n")
+	}
+}
+
+//
+
+func TestAll(t *testing.T) {
+	if err := spireg.RegisterWithInfo("spi-a", []string{"SPI9.0"}, 9, conn.Info{Driver: "fake-spi", Path: "/dev/spidev9.0", MaxSpeed: 1000}, openSPI); err != nil {
+		t.Fatal(err)
+	}
+	defer spireg.Unregister("spi-a")
+	if err := i2creg.RegisterWithInfo("i2c-b", nil, -1, conn.Info{Driver: "fake-i2c"}, openI2C); err != nil {
+		t.Fatal(err)
+	}
+	defer i2creg.Unregister("i2c-b")
+	if err := i2creg.Register("i2c-a", nil, -1, openI2C); err != nil {
+		t.Fatal(err)
+	}
+	defer i2creg.Unregister("i2c-a")
+	features := []string{"fd"}
+	if err := canreg.RegisterWithInfo("can-a", nil, -1, conn.Info{Driver: "fake-can", Features: features}, openCAN); err != nil {
+		t.Fatal(err)
+	}
+	defer canreg.Unregister("can-a")
+	if err := uartreg.RegisterWithInfo("uart-a", nil, 3, conn.Info{Driver: "fake-uart", Path: "/dev/ttyS3"}, openUART); err != nil {
+		t.Fatal(err)
+	}
+	defer uartreg.Unregister("uart-a")
+	// The registry keeps its own copy.
+	features[0] = "x"
+
+	all := All()
+	var got []string
+	for _, b := range all {
+		got = append(got, fmt.Sprintf("%s:%s:%s:%s:%d:%v", b.Type, b.Name, b.Driver, b.Path, b.MaxSpeed, b.Features))
+	}
+	want := []string{
+		"CAN:can-a:fake-can::0:[fd]",
+		"I²C:i2c-a:::0:[]",
+		"I²C:i2c-b:fake-i2c::0:[]",
+		"SPI:spi-a:fake-spi:/dev/spidev9.0:1000:[]",
+		"UART:uart-a:fake-uart:/dev/ttyS3:0:[]",
+	}
+	if s, w := strings.Join(got, "\n"), strings.Join(want, "\n"); s != w {
+		t.Fatalf("got:\n%s\nwant:\n%s", s, w)
+	}
+	if b := ByType(SPI); len(b) != 1 || b[0].Number != 9 || b[0].Aliases[0] != "SPI9.0" {
+		t.Fatal(b)
+	}
+	if b := ByType(UART); len(b) != 1 || b[0].Number != 3 {
+		t.Fatal(b)
+	}
+	if b := ByType(OneWire); len(b) != 0 {
+		t.Fatal(b)
+	}
+}
+
+//
+
+func openI2C() (i2c.BusCloser, error) {
+	return nil, nil
+}
+
+func openSPI() (spi.PortCloser, error) {
+	return nil, nil
+}
+
+func openCAN() (can.BusCloser, error) {
+	return nil, nil
+}
+
+func openUART() (uart.ConnCloser, error) {
+	return nil, nil
+}
@@ -3,6 +3,13 @@
 // that can be found in the LICENSE file.
 
 // Package conntest implements fakes for package conn.
+//
+// Session records a complete flow of operations on I²C, SPI, 1-wire buses and
+// GPIO pins on the real hardware, so it can be played back in the unit tests
+// of a device driver.
+//
+// Injector injects faults like NACKs, timeouts, partial reads or corrupted
+// data in the transactions, to exercise the error handling of device drivers.
 package conntest
 
 import (
@@ -0,0 +1,220 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Fault is a fault injected in a transaction by an Injector.
+type Fault int
+
+const (
+	// NoFault lets the transaction through unmodified.
+	NoFault Fault = iota
+	// NACK fails the transaction as if the device didn't acknowledge it. The
+	// transaction is not forwarded.
+	NACK
+	// Timeout fails the transaction as if it timed out. The transaction is not
+	// forwarded.
+	Timeout
+	// PartialRead forwards the transaction, then overwrites the second half of
+	// the data read with 0xFF as if the bus was idle, without returning an
+	// error.
+	PartialRead
+	// Corrupt forwards the transaction, then flips one bit of the data read
+	// without returning an error, like a CRC error on the wire.
+	Corrupt
+)
+
+const faultName = "NoFaultNACKTimeoutPartialReadCorrupt"
+
+var faultIndex = [...]uint8{0, 7, 11, 18, 29, 36}
+
+func (f Fault) String() string {
+	if f < 0 || f >= Fault(len(faultIndex)-1) {
+		return fmt.Sprintf("Fault(%d)", f)
+	}
+	return faultName[faultIndex[f]:faultIndex[f+1]]
+}
+
+// FaultError is the error returned by a transaction failed by an Injector.
+type FaultError struct {
+	Fault Fault
+}
+
+func (f *FaultError) Error() string {
+	return "conntest: injected " + f.Fault.String()
+}
+
+// Timeout returns true for a Timeout fault.
+func (f *FaultError) Timeout() bool {
+	return f.Fault == Timeout
+}
+
+// Injector injects faults in the transactions of buses and connections to
+// exercise the error handling of device drivers.
+//
+// Faults are first taken from Schedule, one per transaction. Once the
+// schedule is exhausted, a fault chosen randomly from Random is injected with
+// Probability.
+//
+// Use the interceptors with the registries or the Intercept function of the
+// bus packages, or Conn() to wrap a conn.Conn.
+type Injector struct {
+	sync.Mutex
+	// Schedule is the faults to inject in the first transactions, in order.
+	Schedule []Fault
+	// Probability is the probability, from 0 to 1, to inject a fault after
+	// Schedule is exhausted.
+	Probability float64
+	// Random is the faults to choose from once Schedule is exhausted.
+	Random []Fault
+	// Rand is the source of randomness. A fixed seed is used when nil so that
+	// the tests are reproducible.
+	Rand *rand.Rand
+
+	// Count is the number of transactions seen.
+	Count int
+	// Injected is the number of times each fault was injected.
+	Injected map[Fault]int
+}
+
+// I2C returns an interceptor that injects faults in the transactions on an
+// I²C bus.
+func (f *Injector) I2C() i2c.Interceptor {
+	return func(addr uint16, w, r []byte, next i2c.TxFunc) error {
+		return f.inject([][]byte{r}, func() error { return next(addr, w, r) })
+	}
+}
+
+// SPI returns an interceptor that injects faults in the transactions on a SPI
+// connection.
+func (f *Injector) SPI() spi.Interceptor {
+	return func(p []spi.Packet, next spi.TxFunc) error {
+		r := make([][]byte, len(p))
+		for i := range p {
+			r[i] = p[i].R
+		}
+		return f.inject(r, func() error { return next(p) })
+	}
+}
+
+// OneWire returns an interceptor that injects faults in the transactions on a
+// 1-wire bus.
+func (f *Injector) OneWire() onewire.Interceptor {
+	return func(w, r []byte, power onewire.Pullup, next onewire.TxFunc) error {
+		return f.inject([][]byte{r}, func() error { return next(w, r, power) })
+	}
+}
+
+// Conn returns c wrapped to inject faults in its transactions.
+func (f *Injector) Conn(c conn.Conn) conn.Conn {
+	return &faultConn{c: c, f: f}
+}
+
+//
+
+// next returns the fault to inject in the next transaction.
+func (f *Injector) next(n int) Fault {
+	f.Lock()
+	defer f.Unlock()
+	f.Count++
+	fault := NoFault
+	if len(f.Schedule) != 0 {
+		fault = f.Schedule[0]
+		f.Schedule = f.Schedule[1:]
+	} else if len(f.Random) != 0 && f.Probability > 0 {
+		if f.Rand == nil {
+			f.Rand = rand.New(rand.NewSource(0))
+		}
+		if f.Rand.Float64() < f.Probability {
+			fault = f.Random[f.Rand.Intn(len(f.Random))]
+		}
+	}
+	if (fault == PartialRead || fault == Corrupt) && n == 0 {
+		// There's nothing to alter.
+		fault = NoFault
+	}
+	if fault != NoFault {
+		if f.Injected == nil {
+			f.Injected = map[Fault]int{}
+		}
+		f.Injected[fault]++
+	}
+	return fault
+}
+
+// inject runs tx with the next fault. r is the read buffers of the
+// transaction.
+func (f *Injector) inject(r [][]byte, tx func() error) error {
+	n := 0
+	for _, b := range r {
+		n += len(b)
+	}
+	switch fault := f.next(n); fault {
+	case NACK, Timeout:
+		return &FaultError{fault}
+	case PartialRead:
+		if err := tx(); err != nil {
+			return err
+		}
+		for i := n / 2; i < n; i++ {
+			*at(r, i) = 0xFF
+		}
+	case Corrupt:
+		if err := tx(); err != nil {
+			return err
+		}
+		f.Lock()
+		if f.Rand == nil {
+			f.Rand = rand.New(rand.NewSource(0))
+		}
+		bit := f.Rand.Intn(n * 8)
+		f.Unlock()
+		*at(r, bit/8) ^= 1 << uint(bit%8)
+	default:
+		return tx()
+	}
+	return nil
+}
+
+// at returns the byte at offset i in the concatenation of r.
+func at(r [][]byte, i int) *byte {
+	for _, b := range r {
+		if i < len(b) {
+			return &b[i]
+		}
+		i -= len(b)
+	}
+	panic("unreachable")
+}
+
+// faultConn implements conn.Conn for Injector.Conn().
+type faultConn struct {
+	c conn.Conn
+	f *Injector
+}
+
+func (f *faultConn) String() string {
+	return fmt.Sprintf("%s", f.c)
+}
+
+func (f *faultConn) Tx(w, r []byte) error {
+	return f.f.inject([][]byte{r}, func() error { return f.c.Tx(w, r) })
+}
+
+func (f *faultConn) Duplex() conn.Duplex {
+	return f.c.Duplex()
+}
+
+var _ conn.Conn = &faultConn{}
@@ -0,0 +1,147 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+func ExampleInjector() {
+	// The first transaction is not acknowledged, the second one times out.
+	f := &Injector{Schedule: []Fault{NACK, Timeout}}
+	c := f.Conn(&Discard{D: conn.Half})
+	for i := 0; i < 3; i++ {
+		fmt.Println(c.Tx([]byte{1}, nil))
+	}
+	// Output:
+	// conntest: injected NACK
+	// conntest: injected Timeout
+	// <nil>
+}
+
+//
+
+func TestFault_String(t *testing.T) {
+	if s := Corrupt.String(); s != "Corrupt" {
+		t.Fatal(s)
+	}
+	if s := Fault(-1).String(); s != "Fault(-1)" {
+		t.Fatal(s)
+	}
+}
+
+func TestInjector_Conn(t *testing.T) {
+	f := &Injector{Schedule: []Fault{NACK, Timeout, PartialRead, Corrupt, PartialRead}}
+	c := f.Conn(&Playback{Ops: []IO{{R: []byte{1, 2, 3, 4}}, {R: []byte{1, 2}}, {W: []byte{1}}}, D: conn.Half})
+	if s := fmt.Sprintf("%s", c); s != "playback" {
+		t.Fatal(s)
+	}
+	if d := c.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	r := make([]byte, 4)
+	err := c.Tx(nil, r)
+	if e, ok := err.(*FaultError); !ok || e.Fault != NACK || e.Timeout() {
+		t.Fatal(err)
+	}
+	err = c.Tx(nil, r)
+	if e, ok := err.(*FaultError); !ok || !e.Timeout() {
+		t.Fatal(err)
+	}
+	if err := c.Tx(nil, r); err != nil || !bytes.Equal(r, []byte{1, 2, 0xFF, 0xFF}) {
+		t.Fatal(err, r)
+	}
+	r = make([]byte, 2)
+	if err := c.Tx(nil, r); err != nil || bytes.Equal(r, []byte{1, 2}) {
+		t.Fatal(err, r)
+	}
+	// Nothing to read, the fault is not injected.
+	if err := c.Tx([]byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.Count != 5 || f.Injected[NACK] != 1 || f.Injected[PartialRead] != 1 || f.Injected[Corrupt] != 1 {
+		t.Fatal(f.Count, f.Injected)
+	}
+}
+
+func TestInjector_Random(t *testing.T) {
+	f := &Injector{Probability: 0.5, Random: []Fault{NACK}, Rand: rand.New(rand.NewSource(1))}
+	c := f.Conn(&Discard{})
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if c.Tx(nil, nil) != nil {
+			failed++
+		}
+	}
+	if failed < 400 || failed > 600 || f.Injected[NACK] != failed {
+		t.Fatal(failed, f.Injected)
+	}
+	f = &Injector{Probability: 1, Random: []Fault{Corrupt}}
+	r := []byte{0, 0}
+	if err := f.Conn(&Discard{}).Tx(nil, r); err != nil || (r[0] == 0 && r[1] == 0) {
+		t.Fatal(err, r)
+	}
+}
+
+func TestInjector_I2C(t *testing.T) {
+	f := &Injector{Schedule: []Fault{NACK, PartialRead}}
+	b := i2c.Intercept(&fakeI2C{r: []byte{1, 2}}, f.I2C())
+	if err := b.Tx(1, nil, nil); err == nil {
+		t.Fatal("expected NACK")
+	}
+	r := make([]byte, 2)
+	if err := b.Tx(1, nil, r); err != nil || !bytes.Equal(r, []byte{1, 0xFF}) {
+		t.Fatal(err, r)
+	}
+}
+
+func TestInjector_SPI(t *testing.T) {
+	f := &Injector{Schedule: []Fault{PartialRead, Timeout}}
+	p := spi.Intercept(&fakeSPI{}, f.SPI())
+	c, err := p.Connect(0, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := []byte{1, 1}
+	b := []byte{1, 1}
+	if err := c.TxPackets([]spi.Packet{{R: a}, {R: b}}); err != nil || !bytes.Equal(a, []byte{1, 1}) || !bytes.Equal(b, []byte{0xFF, 0xFF}) {
+		t.Fatal(err, a, b)
+	}
+	if err := c.Tx(nil, a); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
+
+func TestInjector_OneWire(t *testing.T) {
+	f := &Injector{Schedule: []Fault{Corrupt, NACK}, Rand: rand.New(rand.NewSource(2))}
+	b := onewire.Intercept(&fakeOneWire{r: []byte{0x55}}, f.OneWire())
+	r := make([]byte, 1)
+	if err := b.Tx(nil, r, onewire.WeakPullup); err != nil || r[0] == 0x55 {
+		t.Fatal(err, r)
+	}
+	if err := b.Tx(nil, r, onewire.WeakPullup); err == nil {
+		t.Fatal("expected NACK")
+	}
+}
+
+func TestInjector_error(t *testing.T) {
+	f := &Injector{Schedule: []Fault{PartialRead, Corrupt}}
+	c := f.Conn(&Playback{DontPanic: true})
+	r := make([]byte, 1)
+	if err := c.Tx(nil, r); !IsErr(err) {
+		t.Fatal(err)
+	}
+	if err := c.Tx(nil, r); !IsErr(err) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,565 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Kinds of operation recorded in a Session.
+const (
+	KindI2C           = "i2c"            // i2c.Bus.Tx()
+	KindSPI           = "spi"            // spi.Conn.Tx() or TxPackets()
+	KindOneWire       = "onewire"        // onewire.Bus.Tx()
+	KindOneWireSearch = "onewire.search" // onewire.Bus.Search()
+	KindPinIn         = "gpio.in"        // gpio.PinIn.In()
+	KindPinRead       = "gpio.read"      // gpio.PinIn.Read()
+	KindPinEdge       = "gpio.edge"      // gpio.PinIn.WaitForEdge()
+	KindPinOut        = "gpio.out"       // gpio.PinOut.Out()
+	KindPinPWM        = "gpio.pwm"       // gpio.PinPWM.PWM()
+)
+
+// Bytes is a byte slice encoded as an hexadecimal string in JSON.
+type Bytes []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Bytes) UnmarshalJSON(d []byte) error {
+	var s string
+	if err := json.Unmarshal(d, &s); err != nil {
+		return err
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// Packet is a spi.Packet as recorded in a Session.
+type Packet struct {
+	W           Bytes `json:",omitempty"`
+	R           Bytes `json:",omitempty"`
+	BitsPerWord uint8 `json:",omitempty"`
+	KeepCS      bool  `json:",omitempty"`
+}
+
+// Op is one operation on a bus or a pin recorded in a Session.
+//
+// Only the fields relevant to Kind are set.
+type Op struct {
+	Kind      string
+	Name      string            // Name of the bus or the pin
+	Addr      uint16            `json:",omitempty"` // I²C device address
+	W         Bytes             `json:",omitempty"` // I²C and 1-wire
+	R         Bytes             `json:",omitempty"` // I²C and 1-wire
+	Packets   []Packet          `json:",omitempty"` // SPI
+	Power     onewire.Pullup    `json:",omitempty"` // 1-wire pull-up at the end of Tx()
+	AlarmOnly bool              `json:",omitempty"` // 1-wire search argument
+	Addresses []onewire.Address `json:",omitempty"` // 1-wire search result
+	Pull      gpio.Pull         `json:",omitempty"`
+	Edge      gpio.Edge         `json:",omitempty"`
+	Level     gpio.Level        `json:",omitempty"` // Read() result or Out() argument
+	OK        bool              `json:",omitempty"` // WaitForEdge() result
+	Duty      gpio.Duty         `json:",omitempty"`
+	Period    time.Duration     `json:",omitempty"`
+	Err       string            `json:",omitempty"` // Error returned, if any
+}
+
+// Session records or plays back a sequence of mixed operations on I²C, SPI,
+// 1-wire buses and GPIO pins.
+//
+// Use the Record methods to wrap the real buses and pins, exercise the device
+// driver on the hardware, then Save() the session to a file. In unit tests,
+// LoadSession() the file and use the Playback methods to get fake buses and
+// pins to pass to the device driver; the operations must happen in the
+// exact same order as they were recorded.
+//
+// Set DontPanic to true to return an error instead of panicking on unexpected
+// operations during playback, which is the default.
+type Session struct {
+	sync.Mutex
+	Ops       []Op
+	Count     int
+	DontPanic bool
+}
+
+// LoadSession loads a session previously written with Save().
+func LoadSession(r io.Reader) (*Session, error) {
+	s := &Session{}
+	if err := json.NewDecoder(r).Decode(&s.Ops); err != nil {
+		return nil, fmt.Errorf("conntest: failed to load session: %v", err)
+	}
+	return s, nil
+}
+
+// Save writes the recorded operations as JSON to w.
+func (s *Session) Save(w io.Writer) error {
+	s.Lock()
+	defer s.Unlock()
+	b, err := json.MarshalIndent(s.Ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Close verifies that all the recorded operations have been played back.
+func (s *Session) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.Ops) != s.Count {
+		return errorf(s.DontPanic, "conntest: expected session to be empty: I/O count %d; expected %d", s.Count, len(s.Ops))
+	}
+	return nil
+}
+
+// RecordI2C returns b wrapped to record its transactions as the bus name.
+func (s *Session) RecordI2C(name string, b i2c.BusCloser) i2c.BusCloser {
+	return i2c.Intercept(b, func(addr uint16, w, r []byte, next i2c.TxFunc) error {
+		err := next(addr, w, r)
+		s.add(Op{Kind: KindI2C, Name: name, Addr: addr, W: clone(w), R: clone(r), Err: errString(err)})
+		return err
+	})
+}
+
+// RecordSPI returns p wrapped to record the transactions on its connections
+// as the port name.
+func (s *Session) RecordSPI(name string, p spi.PortCloser) spi.PortCloser {
+	return spi.Intercept(p, func(pkts []spi.Packet, next spi.TxFunc) error {
+		err := next(pkts)
+		op := Op{Kind: KindSPI, Name: name, Packets: make([]Packet, len(pkts)), Err: errString(err)}
+		for i, p := range pkts {
+			op.Packets[i] = Packet{W: clone(p.W), R: clone(p.R), BitsPerWord: p.BitsPerWord, KeepCS: p.KeepCS}
+		}
+		s.add(op)
+		return err
+	})
+}
+
+// RecordOneWire returns b wrapped to record its transactions and searches as
+// the bus name.
+func (s *Session) RecordOneWire(name string, b onewire.BusCloser) onewire.BusCloser {
+	return &sessionOneWire{s: s, name: name, b: b}
+}
+
+// RecordPin returns p wrapped to record its operations.
+func (s *Session) RecordPin(p gpio.PinIO) gpio.PinIO {
+	return &sessionPin{s: s, name: p.Name(), p: p}
+}
+
+// PlaybackI2C returns a fake bus name that plays back the recorded
+// transactions.
+func (s *Session) PlaybackI2C(name string) i2c.BusCloser {
+	return &sessionI2C{s: s, name: name}
+}
+
+// PlaybackSPI returns a fake port name that plays back the recorded
+// transactions.
+func (s *Session) PlaybackSPI(name string) spi.PortCloser {
+	return &sessionSPI{s: s, name: name}
+}
+
+// PlaybackOneWire returns a fake bus name that plays back the recorded
+// transactions and searches.
+func (s *Session) PlaybackOneWire(name string) onewire.BusCloser {
+	return &sessionOneWire{s: s, name: name}
+}
+
+// PlaybackPin returns a fake pin name that plays back the recorded
+// operations.
+func (s *Session) PlaybackPin(name string) gpio.PinIO {
+	return &sessionPin{s: s, name: name}
+}
+
+//
+
+func (s *Session) add(op Op) {
+	s.Lock()
+	defer s.Unlock()
+	s.Ops = append(s.Ops, op)
+}
+
+// next returns the next operation if it matches kind and name.
+//
+// lock must be held.
+func (s *Session) next(kind, name string) (*Op, error) {
+	if len(s.Ops) <= s.Count {
+		return nil, errorf(s.DontPanic, "conntest: unexpected %s on %q (count #%d)", kind, name, s.Count)
+	}
+	op := &s.Ops[s.Count]
+	if op.Kind != kind || op.Name != name {
+		return nil, errorf(s.DontPanic, "conntest: unexpected %s on %q (count #%d); expected %s on %q", kind, name, s.Count, op.Kind, op.Name)
+	}
+	return op, nil
+}
+
+// mismatch returns an error about an unexpected argument of the current
+// operation.
+//
+// lock must be held.
+func (s *Session) mismatch(what string, got, expected interface{}) error {
+	op := &s.Ops[s.Count]
+	return errorf(s.DontPanic, "conntest: unexpected %s for %s on %q (count #%d) %v != %v", what, op.Kind, op.Name, s.Count, got, expected)
+}
+
+// done consumes the current operation and returns its recorded error.
+//
+// lock must be held.
+func (s *Session) done(op *Op) error {
+	s.Count++
+	if op.Err != "" {
+		return errors.New(op.Err)
+	}
+	return nil
+}
+
+// sessionI2C implements i2c.BusCloser for Session.PlaybackI2C().
+type sessionI2C struct {
+	s    *Session
+	name string
+}
+
+func (b *sessionI2C) String() string {
+	return b.name
+}
+
+func (b *sessionI2C) Close() error {
+	return nil
+}
+
+func (b *sessionI2C) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (b *sessionI2C) Tx(addr uint16, w, r []byte) error {
+	b.s.Lock()
+	defer b.s.Unlock()
+	op, err := b.s.next(KindI2C, b.name)
+	if err != nil {
+		return err
+	}
+	if addr != op.Addr {
+		return b.s.mismatch("addr", addr, op.Addr)
+	}
+	if !bytes.Equal(w, op.W) {
+		return b.s.mismatch("write", fmt.Sprintf("%#v", w), fmt.Sprintf("%#v", []byte(op.W)))
+	}
+	if len(r) != len(op.R) {
+		return b.s.mismatch("read buffer length", len(r), len(op.R))
+	}
+	copy(r, op.R)
+	return b.s.done(op)
+}
+
+// sessionSPI implements spi.PortCloser for Session.PlaybackSPI().
+type sessionSPI struct {
+	s    *Session
+	name string
+}
+
+func (p *sessionSPI) String() string {
+	return p.name
+}
+
+func (p *sessionSPI) Close() error {
+	return nil
+}
+
+func (p *sessionSPI) LimitSpeed(maxHz int64) error {
+	return nil
+}
+
+func (p *sessionSPI) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	d := conn.Full
+	if mode&spi.HalfDuplex != 0 {
+		d = conn.Half
+	}
+	return &sessionSPIConn{p: p, d: d}, nil
+}
+
+type sessionSPIConn struct {
+	p *sessionSPI
+	d conn.Duplex
+}
+
+func (c *sessionSPIConn) String() string {
+	return c.p.name
+}
+
+func (c *sessionSPIConn) Duplex() conn.Duplex {
+	return c.d
+}
+
+func (c *sessionSPIConn) Tx(w, r []byte) error {
+	return c.TxPackets([]spi.Packet{{W: w, R: r}})
+}
+
+func (c *sessionSPIConn) TxPackets(pkts []spi.Packet) error {
+	s := c.p.s
+	s.Lock()
+	defer s.Unlock()
+	op, err := s.next(KindSPI, c.p.name)
+	if err != nil {
+		return err
+	}
+	if len(pkts) != len(op.Packets) {
+		return s.mismatch("packet count", len(pkts), len(op.Packets))
+	}
+	for i, p := range pkts {
+		e := op.Packets[i]
+		if !bytes.Equal(p.W, e.W) {
+			return s.mismatch(fmt.Sprintf("write in packet #%d", i), fmt.Sprintf("%#v", p.W), fmt.Sprintf("%#v", []byte(e.W)))
+		}
+		if len(p.R) != len(e.R) {
+			return s.mismatch(fmt.Sprintf("read buffer length in packet #%d", i), len(p.R), len(e.R))
+		}
+		if p.BitsPerWord != e.BitsPerWord || p.KeepCS != e.KeepCS {
+			return s.mismatch(fmt.Sprintf("settings in packet #%d", i), p, e)
+		}
+	}
+	for i, p := range pkts {
+		copy(p.R, op.Packets[i].R)
+	}
+	return s.done(op)
+}
+
+// sessionOneWire implements onewire.BusCloser for Session.RecordOneWire()
+// when b is set and Session.PlaybackOneWire() otherwise.
+type sessionOneWire struct {
+	s    *Session
+	name string
+	b    onewire.BusCloser
+}
+
+func (o *sessionOneWire) String() string {
+	return o.name
+}
+
+func (o *sessionOneWire) Close() error {
+	if o.b != nil {
+		return o.b.Close()
+	}
+	return nil
+}
+
+func (o *sessionOneWire) Tx(w, r []byte, power onewire.Pullup) error {
+	if o.b != nil {
+		err := o.b.Tx(w, r, power)
+		o.s.add(Op{Kind: KindOneWire, Name: o.name, W: clone(w), R: clone(r), Power: power, Err: errString(err)})
+		return err
+	}
+	o.s.Lock()
+	defer o.s.Unlock()
+	op, err := o.s.next(KindOneWire, o.name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(w, op.W) {
+		return o.s.mismatch("write", fmt.Sprintf("%#v", w), fmt.Sprintf("%#v", []byte(op.W)))
+	}
+	if len(r) != len(op.R) {
+		return o.s.mismatch("read buffer length", len(r), len(op.R))
+	}
+	if power != op.Power {
+		return o.s.mismatch("pull-up", power, op.Power)
+	}
+	copy(r, op.R)
+	return o.s.done(op)
+}
+
+func (o *sessionOneWire) Search(alarmOnly bool) ([]onewire.Address, error) {
+	if o.b != nil {
+		addrs, err := o.b.Search(alarmOnly)
+		o.s.add(Op{Kind: KindOneWireSearch, Name: o.name, AlarmOnly: alarmOnly, Addresses: append([]onewire.Address(nil), addrs...), Err: errString(err)})
+		return addrs, err
+	}
+	o.s.Lock()
+	defer o.s.Unlock()
+	op, err := o.s.next(KindOneWireSearch, o.name)
+	if err != nil {
+		return nil, err
+	}
+	if alarmOnly != op.AlarmOnly {
+		return nil, o.s.mismatch("alarmOnly", alarmOnly, op.AlarmOnly)
+	}
+	return append([]onewire.Address(nil), op.Addresses...), o.s.done(op)
+}
+
+// sessionPin implements gpio.PinIO for Session.RecordPin() when p is set and
+// Session.PlaybackPin() otherwise.
+type sessionPin struct {
+	s    *Session
+	name string
+	p    gpio.PinIO
+}
+
+func (p *sessionPin) String() string {
+	if p.p != nil {
+		return p.p.String()
+	}
+	return p.name
+}
+
+func (p *sessionPin) Name() string {
+	return p.name
+}
+
+func (p *sessionPin) Number() int {
+	if p.p != nil {
+		return p.p.Number()
+	}
+	return -1
+}
+
+func (p *sessionPin) Function() string {
+	if p.p != nil {
+		return p.p.Function()
+	}
+	return ""
+}
+
+func (p *sessionPin) Pull() gpio.Pull {
+	if p.p != nil {
+		return p.p.Pull()
+	}
+	return gpio.PullNoChange
+}
+
+func (p *sessionPin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if p.p != nil {
+		err := p.p.In(pull, edge)
+		p.s.add(Op{Kind: KindPinIn, Name: p.name, Pull: pull, Edge: edge, Err: errString(err)})
+		return err
+	}
+	p.s.Lock()
+	defer p.s.Unlock()
+	op, err := p.s.next(KindPinIn, p.name)
+	if err != nil {
+		return err
+	}
+	if pull != op.Pull {
+		return p.s.mismatch("pull", pull, op.Pull)
+	}
+	if edge != op.Edge {
+		return p.s.mismatch("edge", edge, op.Edge)
+	}
+	return p.s.done(op)
+}
+
+func (p *sessionPin) Read() gpio.Level {
+	if p.p != nil {
+		l := p.p.Read()
+		p.s.add(Op{Kind: KindPinRead, Name: p.name, Level: l})
+		return l
+	}
+	p.s.Lock()
+	defer p.s.Unlock()
+	op, err := p.s.next(KindPinRead, p.name)
+	if err != nil {
+		return gpio.Low
+	}
+	p.s.done(op)
+	return op.Level
+}
+
+// WaitForEdge records whether an edge was detected. The timeout is not
+// verified on playback as it is generally not deterministic.
+func (p *sessionPin) WaitForEdge(timeout time.Duration) bool {
+	if p.p != nil {
+		ok := p.p.WaitForEdge(timeout)
+		p.s.add(Op{Kind: KindPinEdge, Name: p.name, OK: ok})
+		return ok
+	}
+	p.s.Lock()
+	defer p.s.Unlock()
+	op, err := p.s.next(KindPinEdge, p.name)
+	if err != nil {
+		return false
+	}
+	p.s.done(op)
+	return op.OK
+}
+
+func (p *sessionPin) Out(l gpio.Level) error {
+	if p.p != nil {
+		err := p.p.Out(l)
+		p.s.add(Op{Kind: KindPinOut, Name: p.name, Level: l, Err: errString(err)})
+		return err
+	}
+	p.s.Lock()
+	defer p.s.Unlock()
+	op, err := p.s.next(KindPinOut, p.name)
+	if err != nil {
+		return err
+	}
+	if l != op.Level {
+		return p.s.mismatch("level", l, op.Level)
+	}
+	return p.s.done(op)
+}
+
+func (p *sessionPin) PWM(duty gpio.Duty, period time.Duration) error {
+	if p.p != nil {
+		var err error
+		if pwm, ok := p.p.(gpio.PinPWM); ok {
+			err = pwm.PWM(duty, period)
+		} else {
+			err = errors.New("conntest: pin doesn't support PWM")
+		}
+		p.s.add(Op{Kind: KindPinPWM, Name: p.name, Duty: duty, Period: period, Err: errString(err)})
+		return err
+	}
+	p.s.Lock()
+	defer p.s.Unlock()
+	op, err := p.s.next(KindPinPWM, p.name)
+	if err != nil {
+		return err
+	}
+	if duty != op.Duty || period != op.Period {
+		return p.s.mismatch("PWM", fmt.Sprintf("%s@%s", duty, period), fmt.Sprintf("%s@%s", op.Duty, op.Period))
+	}
+	return p.s.done(op)
+}
+
+func clone(b []byte) Bytes {
+	if len(b) == 0 {
+		return nil
+	}
+	return append(Bytes(nil), b...)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+var _ i2c.BusCloser = &sessionI2C{}
+var _ spi.PortCloser = &sessionSPI{}
+var _ spi.Conn = &sessionSPIConn{}
+var _ onewire.BusCloser = &sessionOneWire{}
+var _ gpio.PinIO = &sessionPin{}
+var _ gpio.PinPWM = &sessionPin{}
@@ -0,0 +1,309 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+func ExampleSession() {
+	// On the hardware, record the session and save it to a file:
+	//   s := &conntest.Session{}
+	//   b, err := i2creg.Open("")
+	//   d := &i2c.Dev{Bus: s.RecordI2C("I2C1", b), Addr: 0x76}
+	//   ... use d ...
+	//   s.Save(f)
+	//
+	// In the unit test, load the file and play it back:
+	s, err := LoadSession(strings.NewReader(`[{"Kind": "i2c", "Name": "I2C1", "Addr": 118, "W": "d0", "R": "60"}]`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	d := &i2c.Dev{Bus: s.PlaybackI2C("I2C1"), Addr: 0x76}
+	id := make([]byte, 1)
+	if err := d.Tx([]byte{0xD0}, id); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Chip ID: 0x%02X\n", id[0])
+	if err := s.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// Chip ID: 0x60
+}
+
+//
+
+func TestSession_Record_Playback(t *testing.T) {
+	rec := &Session{}
+	ib := rec.RecordI2C("I2C1", &fakeI2C{r: []byte{0x60}})
+	sp := rec.RecordSPI("SPI0.0", &fakeSPI{})
+	ow := rec.RecordOneWire("onewire0", &fakeOneWire{r: []byte{1, 2}, addrs: []onewire.Address{0x28}})
+	pin := rec.RecordPin(&gpiotest.Pin{N: "GPIO4", EdgesChan: make(chan gpio.Level, 1)})
+	if err := run(ib, sp, ow, pin); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := rec.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"W": "d0"`) {
+		t.Fatal(buf.String())
+	}
+	play, err := LoadSession(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(play.Ops) != len(rec.Ops) {
+		t.Fatal(play.Ops)
+	}
+	if err := run(play.PlaybackI2C("I2C1"), play.PlaybackSPI("SPI0.0"), play.PlaybackOneWire("onewire0"), play.PlaybackPin("GPIO4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := play.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession_Playback_fail(t *testing.T) {
+	s := &Session{DontPanic: true, Ops: []Op{
+		{Kind: KindI2C, Name: "I2C1", Addr: 1, W: Bytes{1}},
+		{Kind: KindSPI, Name: "SPI0.0", Packets: []Packet{{W: Bytes{1}}}},
+		{Kind: KindOneWire, Name: "ow", W: Bytes{1}, Power: onewire.StrongPullup},
+		{Kind: KindOneWireSearch, Name: "ow", AlarmOnly: true},
+		{Kind: KindPinIn, Name: "P", Pull: gpio.PullUp, Edge: gpio.RisingEdge},
+		{Kind: KindPinOut, Name: "P", Level: gpio.High},
+		{Kind: KindPinPWM, Name: "P", Duty: gpio.DutyHalf, Period: time.Second},
+	}}
+	b := s.PlaybackI2C("I2C1")
+	if err := b.Tx(1, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx(1, nil, nil); !IsErr(err) {
+		t.Fatal("expected mismatch on kind")
+	}
+	if err := s.Close(); !IsErr(err) {
+		t.Fatal("expected remaining operations")
+	}
+	s.Count = 0
+	for i, f := range []func() error{
+		func() error { return b.Tx(2, []byte{1}, nil) },
+		func() error { return b.Tx(1, []byte{2}, nil) },
+		func() error { return b.Tx(1, []byte{1}, []byte{0}) },
+	} {
+		if err := f(); !IsErr(err) {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+	s.Count = 1
+	c, _ := s.PlaybackSPI("SPI0.0").Connect(0, spi.Mode0|spi.HalfDuplex, 8)
+	if d := c.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	for i, f := range []func() error{
+		func() error { return c.Tx([]byte{2}, nil) },
+		func() error { return c.Tx([]byte{1}, []byte{0}) },
+		func() error { return c.TxPackets([]spi.Packet{{W: []byte{1}, KeepCS: true}}) },
+		func() error { return c.TxPackets(nil) },
+	} {
+		if err := f(); !IsErr(err) {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+	s.Count = 2
+	o := s.PlaybackOneWire("ow")
+	for i, f := range []func() error{
+		func() error { return o.Tx([]byte{2}, nil, onewire.StrongPullup) },
+		func() error { return o.Tx([]byte{1}, []byte{0}, onewire.StrongPullup) },
+		func() error { return o.Tx([]byte{1}, nil, onewire.WeakPullup) },
+	} {
+		if err := f(); !IsErr(err) {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+	s.Count = 3
+	if _, err := o.Search(false); !IsErr(err) {
+		t.Fatal(err)
+	}
+	s.Count = 4
+	p := s.PlaybackPin("P")
+	for i, f := range []func() error{
+		func() error { return p.In(gpio.PullDown, gpio.RisingEdge) },
+		func() error { return p.In(gpio.PullUp, gpio.NoEdge) },
+		func() error { return p.Out(gpio.Low) },
+	} {
+		if err := f(); !IsErr(err) {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+	s.Count = 5
+	if err := p.Out(gpio.Low); !IsErr(err) {
+		t.Fatal(err)
+	}
+	s.Count = 6
+	if err := p.(gpio.PinPWM).PWM(gpio.DutyMax, time.Second); !IsErr(err) {
+		t.Fatal(err)
+	}
+	s.Count = 7
+	if p.Read() != gpio.Low || p.WaitForEdge(0) {
+		t.Fatal("expected defaults when no operation is left")
+	}
+}
+
+func TestSession_Playback_panic(t *testing.T) {
+	defer func() {
+		if v := recover(); !IsErr(v.(error)) {
+			t.Fatal(v)
+		}
+	}()
+	(&Session{}).PlaybackI2C("I2C1").Tx(1, nil, nil)
+	t.Fatal("expected panic")
+}
+
+func TestLoadSession_fail(t *testing.T) {
+	if _, err := LoadSession(strings.NewReader(`[{"W": "zz"}]`)); err == nil {
+		t.Fatal("invalid hex")
+	}
+	if _, err := LoadSession(strings.NewReader(`[{"W": 1}]`)); err == nil {
+		t.Fatal("invalid type")
+	}
+}
+
+//
+
+// run runs a session mixing all the kind of operations.
+func run(b i2c.BusCloser, p spi.PortCloser, o onewire.BusCloser, pin gpio.PinIO) error {
+	r := make([]byte, 1)
+	if err := b.Tx(0x76, []byte{0xD0}, r); err != nil || r[0] != 0x60 {
+		return errors.New("i2c")
+	}
+	if err := pin.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+		return err
+	}
+	c, err := p.Connect(1000, spi.Mode0, 8)
+	if err != nil {
+		return err
+	}
+	if err := c.Tx([]byte{1, 2}, make([]byte, 2)); err != nil {
+		return err
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{3}, KeepCS: true}, {R: make([]byte, 1), BitsPerWord: 9}}); err != nil {
+		return err
+	}
+	addrs, err := o.Search(false)
+	if err != nil || len(addrs) != 1 || addrs[0] != 0x28 {
+		return errors.New("onewire search")
+	}
+	r = make([]byte, 2)
+	if err := o.Tx([]byte{0x44}, r, onewire.StrongPullup); err != nil || r[1] != 2 {
+		return errors.New("onewire tx")
+	}
+	if err := pin.Out(gpio.High); err != nil {
+		return err
+	}
+	if l := pin.Read(); l != gpio.High {
+		return errors.New("read")
+	}
+	if pin.WaitForEdge(0) {
+		return errors.New("edge")
+	}
+	if err := pin.(gpio.PinPWM).PWM(gpio.DutyHalf, time.Millisecond); err == nil || err.Error() != "conntest: pin doesn't support PWM" {
+		return errors.New("pwm")
+	}
+	if b.SetSpeed(100000) != nil || p.LimitSpeed(1000) != nil || b.Close() != nil || p.Close() != nil || o.Close() != nil {
+		return errors.New("close")
+	}
+	if s := pin.Name(); s != "GPIO4" {
+		return errors.New(s)
+	}
+	return nil
+}
+
+type fakeI2C struct {
+	r []byte
+}
+
+func (f *fakeI2C) String() string {
+	return "fakeI2C"
+}
+
+func (f *fakeI2C) Close() error {
+	return nil
+}
+
+func (f *fakeI2C) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeI2C) Tx(addr uint16, w, r []byte) error {
+	copy(r, f.r)
+	return nil
+}
+
+type fakeSPI struct{}
+
+func (f *fakeSPI) String() string {
+	return "fakeSPI"
+}
+
+func (f *fakeSPI) Close() error {
+	return nil
+}
+
+func (f *fakeSPI) LimitSpeed(maxHz int64) error {
+	return nil
+}
+
+func (f *fakeSPI) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	return f, nil
+}
+
+func (f *fakeSPI) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeSPI) Tx(w, r []byte) error {
+	return nil
+}
+
+func (f *fakeSPI) TxPackets(p []spi.Packet) error {
+	return nil
+}
+
+type fakeOneWire struct {
+	r     []byte
+	addrs []onewire.Address
+}
+
+func (f *fakeOneWire) String() string {
+	return "fakeOneWire"
+}
+
+func (f *fakeOneWire) Close() error {
+	return nil
+}
+
+func (f *fakeOneWire) Tx(w, r []byte, power onewire.Pullup) error {
+	copy(r, f.r)
+	return nil
+}
+
+func (f *fakeOneWire) Search(alarmOnly bool) ([]onewire.Address, error) {
+	return f.addrs, nil
+}
@@ -51,6 +51,14 @@
 // is point-to-point, only between two devices. It is ready to use like any
 // readable and/or writable pipe.
 //
+// Interceptors
+//
+// The I²C, SPI and 1-wire packages each define an Interceptor that wraps the
+// transactions on a bus or a port, like an HTTP middleware. The registries
+// accept interceptors when opening a bus or a port, so an application can add
+// logging, metrics, retries or fault injection without modifying the device
+// drivers.
+//
 // Subpackages
 //
 // Most connection-type specific subpackages include subpackages:
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"sync"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn/gpio"
 )
 
@@ -106,28 +107,68 @@ func Register(p gpio.PinIO, preferred bool) error {
 		other = 0
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if orig, ok := byNumber[i][number]; ok {
-		return wrapf("can't register pin %q twice with the same number %d; already registered as %s", name, number, orig)
-	}
-	if orig, ok := byName[i][name]; ok {
-		return wrapf("can't register pin %q twice; already registered as %s", name, orig)
-	}
-	if r, ok := p.(gpio.RealPin); ok {
-		return wrapf("can't register pin %q, it is already an alias: %s; use RegisterAlias() instead", name, r)
+	if err := register(p, name, number, i, other); err != nil {
+		return err
 	}
-	if alias, ok := byAlias[name]; ok {
-		return wrapf("can't register pin %q; an alias already exist: %s", name, alias)
-	}
-	if orig, ok := byName[other][name]; ok && number != orig.Number() {
-		return wrapf("can't register pin %q twice with different number; already registered as %s", name, orig)
+	notify(p, true)
+	return nil
+}
+
+// Unregister removes a previously registered GPIO pin by its name.
+//
+// This can happen when the pin is exposed via an USB device that is unplugged
+// or when the driver exposing it is unloaded. The aliases are kept and will
+// resolve to a pin registered later under the same name.
+func Unregister(name string) error {
+	return unregister(name, nil)
+}
+
+// UnregisterPin is like Unregister but only removes p, keeping a pin
+// registered under the same name by another driver.
+//
+// It is meant for drivers registering their pins with preferred set to false,
+// which often share their names with the pins of a preferred driver.
+func UnregisterPin(p gpio.PinIO) error {
+	return unregister(p.Name(), p)
+}
+
+// SetSafeState sets the level the pin named name is driven to by
+// periph.Shutdown(), e.g. to turn off a motor or a heater on a clean exit.
+//
+// The pins are set before the resources claimed by the host drivers are
+// released. Use periph.Track() for more complex sequences.
+func SetSafeState(name string, l gpio.Level) error {
+	p := ByName(name)
+	if p == nil {
+		return wrapf("can't set the safe state of unknown pin %q", name)
 	}
-	byNumber[i][number] = p
-	byName[i][name] = p
+	periph.Track(periph.CloserFunc(func() error {
+		return p.Out(l)
+	}))
 	return nil
 }
 
+// Notify registers f to be called after a pin is registered or unregistered.
+//
+// f is called synchronously, outside of the registry lock. Aliases are not
+// notified. Call the returned function to stop the notifications.
+func Notify(f func(p gpio.PinIO, registered bool)) func() {
+	w := &watcher{f: f}
+	mu.Lock()
+	defer mu.Unlock()
+	watchers = append(watchers, w)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range watchers {
+			if watchers[i] == w {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 // RegisterAlias registers an alias for a GPIO pin.
 //
 // It is possible to register an alias for a pin that itself has not been
@@ -168,6 +209,7 @@ var (
 	byNumber = [2]map[int]gpio.PinIO{{}, {}}
 	byName   = [2]map[string]gpio.PinIO{{}, {}}
 	byAlias  = map[string]*pinAlias{}
+	watchers []*watcher
 )
 
 // pinAlias implements an alias for a PinIO.
@@ -199,6 +241,70 @@ func (a *pinAlias) Real() gpio.PinIO {
 	return a.PinIO
 }
 
+type watcher struct {
+	f func(p gpio.PinIO, registered bool)
+}
+
+// notify calls the watchers registered with Notify().
+func notify(p gpio.PinIO, registered bool) {
+	mu.Lock()
+	w := append([]*watcher(nil), watchers...)
+	mu.Unlock()
+	for _, x := range w {
+		x.f(p, registered)
+	}
+}
+
+// unregister removes the pins named name. If match is not nil, only match is
+// removed.
+func unregister(name string, match gpio.PinIO) error {
+	mu.Lock()
+	var p gpio.PinIO
+	for i := range byName {
+		if q, ok := byName[i][name]; ok && (match == nil || q == match) {
+			p = q
+			delete(byName[i], name)
+			delete(byNumber[i], q.Number())
+		}
+	}
+	if p != nil {
+		// Reset the resolved aliases, since they may point to the pin.
+		for _, a := range byAlias {
+			a.PinIO = nil
+		}
+	}
+	mu.Unlock()
+	if p == nil {
+		return wrapf("can't unregister unknown pin name %q", name)
+	}
+	notify(p, false)
+	return nil
+}
+
+// register adds the pin to the registry.
+func register(p gpio.PinIO, name string, number, i, other int) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if orig, ok := byNumber[i][number]; ok {
+		return wrapf("can't register pin %q twice with the same number %d; already registered as %s", name, number, orig)
+	}
+	if orig, ok := byName[i][name]; ok {
+		return wrapf("can't register pin %q twice; already registered as %s", name, orig)
+	}
+	if r, ok := p.(gpio.RealPin); ok {
+		return wrapf("can't register pin %q, it is already an alias: %s; use RegisterAlias() instead", name, r)
+	}
+	if alias, ok := byAlias[name]; ok {
+		return wrapf("can't register pin %q; an alias already exist: %s", name, alias)
+	}
+	if orig, ok := byName[other][name]; ok && number != orig.Number() {
+		return wrapf("can't register pin %q twice with different number; already registered as %s", name, orig)
+	}
+	byNumber[i][number] = p
+	byName[i][name] = p
+	return nil
+}
+
 func getByNumber(number int) gpio.PinIO {
 	if p, ok := byNumber[0][number]; ok {
 		return p
@@ -10,7 +10,9 @@ import (
 	"sort"
 	"testing"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
 )
 
 func ExampleAll() {
@@ -175,6 +177,92 @@ func TestRegisterAlias_fail(t *testing.T) {
 	}
 }
 
+func TestUnregister(t *testing.T) {
+	defer reset()
+	var events []string
+	stop := Notify(func(p gpio.PinIO, registered bool) {
+		events = append(events, fmt.Sprintf("%s:%t", p, registered))
+	})
+	if err := Register(&basicPin{PinIO: gpio.INVALID, name: "a", num: 0}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterAlias("alias0", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if p := ByName("alias0"); p == nil {
+		t.Fatal("alias must resolve")
+	}
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unregister("a"); err == nil {
+		t.Fatal("already unregistered")
+	}
+	if p := ByName("alias0"); p != nil {
+		t.Fatal(p)
+	}
+	// Registering it again, e.g. after a driver reload.
+	b := &basicPin{PinIO: gpio.INVALID, name: "a", num: 0}
+	if err := Register(b, true); err != nil {
+		t.Fatal(err)
+	}
+	if p := ByName("alias0"); p.(gpio.RealPin).Real() != b {
+		t.Fatal(p)
+	}
+	stop()
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(events); s != "[a:true a:false a:true]" {
+		t.Fatal(s)
+	}
+}
+
+func TestUnregisterPin(t *testing.T) {
+	defer reset()
+	a := &basicPin{PinIO: gpio.INVALID, name: "a", num: 0}
+	b := &basicPin{PinIO: gpio.INVALID, name: "a", num: 0}
+	if err := Register(a, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(b, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnregisterPin(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnregisterPin(b); err == nil {
+		t.Fatal("already unregistered")
+	}
+	if p := ByName("a"); p != a {
+		t.Fatal(p)
+	}
+	// It can be registered again.
+	if err := Register(b, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetSafeState(t *testing.T) {
+	defer reset()
+	p := &gpiotest.Pin{N: "SAFE", Num: 5, L: gpio.High}
+	if err := Register(p, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSafeState("SAFE", gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSafeState("UNKNOWN", gpio.Low); err == nil {
+		t.Fatal("unknown pin")
+	}
+	if err := periph.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if p.L != gpio.Low {
+		t.Fatal("pin must be set to its safe state")
+	}
+}
+
 func TestPinList(t *testing.T) {
 	l := pinList{&basicPin{PinIO: gpio.INVALID, num: 1}, &basicPin{PinIO: gpio.INVALID}}
 	sort.Sort(l)
@@ -210,4 +298,5 @@ func reset() {
 	byNumber = [2]map[int]gpio.PinIO{{}, {}}
 	byName = [2]map[string]gpio.PinIO{{}, {}}
 	byAlias = map[string]*pinAlias{}
+	watchers = nil
 }
@@ -0,0 +1,127 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2c
+
+import (
+	"errors"
+	"sync"
+)
+
+// Async runs transactions on a Bus in the background, in the order they were
+// submitted.
+//
+// It lets a high rate consumer, like a display refresh or an IMU stream,
+// prepare the next transaction while the previous one is in flight instead of
+// blocking on each one.
+type Async struct {
+	b   Bus
+	ops chan asyncOp
+	wg  sync.WaitGroup
+
+	mu     sync.RWMutex // Protects closed, held while queueing
+	closed bool
+	errMu  sync.Mutex
+	err    error // First error of a transaction submitted without done channel
+}
+
+// NewAsync returns an Async running the transactions on b.
+//
+// depth is the number of transactions that can be queued before Submit()
+// blocks.
+func NewAsync(b Bus, depth int) *Async {
+	a := &Async{b: b, ops: make(chan asyncOp, depth)}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+// Submit queues a transaction on the bus.
+//
+// The result of the transaction is sent to done once completed; done must be
+// buffered or drained as the transactions are run sequentially. When done is
+// nil, the first error is returned by Flush() instead.
+//
+// w and r must not be accessed until the transaction is completed.
+func (a *Async) Submit(addr uint16, w, r []byte, done chan<- error) {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		a.report(errClosed, done)
+		return
+	}
+	a.ops <- asyncOp{addr: addr, w: w, r: r, done: done}
+	a.mu.RUnlock()
+}
+
+// Flush waits for all the submitted transactions to complete.
+//
+// It returns the first error of the transactions submitted without done
+// channel since the last call to Flush().
+func (a *Async) Flush() error {
+	done := make(chan error, 1)
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return errClosed
+	}
+	a.ops <- asyncOp{done: done, barrier: true}
+	a.mu.RUnlock()
+	<-done
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Close flushes the pending transactions and stops the background goroutine.
+//
+// It doesn't close the underlying bus.
+func (a *Async) Close() error {
+	err := a.Flush()
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.ops)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+	return err
+}
+
+//
+
+var errClosed = errors.New("i2c: Async is closed")
+
+type asyncOp struct {
+	addr    uint16
+	w, r    []byte
+	done    chan<- error
+	barrier bool
+}
+
+func (a *Async) run() {
+	defer a.wg.Done()
+	for op := range a.ops {
+		if op.barrier {
+			op.done <- nil
+			continue
+		}
+		a.report(a.b.Tx(op.addr, op.w, op.r), op.done)
+	}
+}
+
+// report sends err to done, or saves it if done is nil.
+func (a *Async) report(err error, done chan<- error) {
+	if done != nil {
+		done <- err
+		return
+	}
+	a.errMu.Lock()
+	if a.err == nil {
+		a.err = err
+	}
+	a.errMu.Unlock()
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2c
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+func ExampleAsync() {
+	//b, err := i2creg.Open("")
+	//defer b.Close()
+	var b Bus
+
+	a := NewAsync(b, 4)
+	defer a.Close()
+	done := make(chan error, 2)
+	frames := [2][16]byte{}
+	// Send the first frame while the second one is being prepared.
+	a.Submit(0x3C, frames[0][:], nil, done)
+	a.Submit(0x3C, frames[1][:], nil, done)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+//
+
+func TestAsync(t *testing.T) {
+	f := &fakeBus{r: []byte{1, 2}}
+	a := NewAsync(f, 1)
+	done := make(chan error, 1)
+	r := make([]byte, 2)
+	a.Submit(12, []byte{3}, r, done)
+	if err := <-done; err != nil || !bytes.Equal(r, []byte{1, 2}) {
+		t.Fatal(err, r)
+	}
+	a.Submit(13, []byte{4}, nil, nil)
+	a.Submit(14, []byte{5}, nil, nil)
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if f.addr != 14 || !bytes.Equal(f.w, []byte{3, 4, 5}) {
+		t.Fatal(f.addr, f.w)
+	}
+	f.err = errors.New("nack")
+	a.Submit(15, nil, nil, nil)
+	a.Submit(16, nil, nil, nil)
+	if err := a.Close(); err == nil || err.Error() != "nack" {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != errClosed {
+		t.Fatal(err)
+	}
+	a.Submit(17, nil, nil, done)
+	if err := <-done; err != errClosed {
+		t.Fatal(err)
+	}
+	if f.addr != 16 {
+		t.Fatal(f.addr)
+	}
+}
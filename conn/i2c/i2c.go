@@ -10,9 +10,11 @@
 package i2c
 
 import (
+	"context"
 	"fmt"
 	"io"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 )
@@ -33,6 +35,32 @@ type Bus interface {
 	SetSpeed(hz int64) error
 }
 
+// BusCtx is an optional interface a Bus may implement to support cancellation
+// and deadlines via a context.Context.
+//
+// Use TxCtx() to use it when available.
+type BusCtx interface {
+	// TxCtx is like Bus.Tx but aborts the transaction when ctx is done.
+	//
+	// An I²C transaction generally cannot be interrupted once started; the
+	// driver maps the context deadline to the bus timeout when supported.
+	TxCtx(ctx context.Context, addr uint16, w, r []byte) error
+}
+
+// TxCtx does a transaction on b, honoring ctx.
+//
+// When b implements BusCtx, the call is forwarded to it. Otherwise ctx is only
+// checked before the transaction is started.
+func TxCtx(ctx context.Context, b Bus, addr uint16, w, r []byte) error {
+	if bc, ok := b.(BusCtx); ok {
+		return bc.TxCtx(ctx, addr, w, r)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Tx(addr, w, r)
+}
+
 // BusCloser is an I²C bus that can be closed.
 //
 // This interface is meant to be handled by the application and not the device
@@ -54,6 +82,43 @@ type Pins interface {
 	SDA() gpio.PinIO
 }
 
+// TxFunc is the signature of Bus.Tx.
+type TxFunc func(addr uint16, w, r []byte) error
+
+// Interceptor intercepts the transactions on a bus, like a middleware.
+//
+// It must call next to forward the transaction to the bus. It can log or
+// measure the transaction, retry it when next fails, or return an error
+// without calling next to inject a fault.
+type Interceptor func(addr uint16, w, r []byte, next TxFunc) error
+
+// Intercept returns a BusCloser passing all the transactions on b through i.
+//
+// The returned bus forwards SetSpeed() and Close() to b, and implements BusCtx
+// and Pins only if b does. Its method Unwrap() BusCloser returns b, to access
+// the other interfaces b implements. Use i2creg.Open() to wrap a registered
+// bus when it is opened.
+func Intercept(b BusCloser, i Interceptor) BusCloser {
+	ib := &interceptBus{b: b, i: i}
+	p, isPins := b.(Pins)
+	if _, ok := b.(BusCtx); ok {
+		if isPins {
+			return &struct {
+				*interceptBusCtx
+				Pins
+			}{&interceptBusCtx{ib}, p}
+		}
+		return &interceptBusCtx{ib}
+	}
+	if isPins {
+		return &struct {
+			*interceptBus
+			Pins
+		}{ib, p}
+	}
+	return ib
+}
+
 // Dev is a device on a I²C bus.
 //
 // It implements conn.Conn.
@@ -72,7 +137,14 @@ func (d *Dev) String() string {
 //
 // It's a wrapper for Bus.Tx().
 func (d *Dev) Tx(w, r []byte) error {
-	return d.Bus.Tx(d.Addr, w, r)
+	return d.logErr(d.Bus.Tx(d.Addr, w, r))
+}
+
+// TxCtx is like Tx but honors ctx, implementing conn.ConnCtx.
+//
+// It's a wrapper for TxCtx().
+func (d *Dev) TxCtx(ctx context.Context, w, r []byte) error {
+	return d.logErr(TxCtx(ctx, d.Bus, d.Addr, w, r))
 }
 
 // Write writes to the I²C bus without reading, implementing io.Writer.
@@ -90,6 +162,56 @@ func (d *Dev) Duplex() conn.Duplex {
 	return conn.Half
 }
 
+// logErr emits err to periph.Log(), if any.
+func (d *Dev) logErr(err error) error {
+	if err != nil {
+		periph.Log().Debug("i2c: transaction failed", "dev", d.String(), "err", err)
+	}
+	return err
+}
+
 //
 
 var _ conn.Conn = &Dev{}
+var _ conn.ConnCtx = &Dev{}
+
+// interceptBus implements BusCloser for Intercept().
+type interceptBus struct {
+	b BusCloser
+	i Interceptor
+}
+
+func (i *interceptBus) String() string {
+	return fmt.Sprintf("%s", i.b)
+}
+
+func (i *interceptBus) Close() error {
+	return i.b.Close()
+}
+
+func (i *interceptBus) Tx(addr uint16, w, r []byte) error {
+	return i.i(addr, w, r, i.b.Tx)
+}
+
+func (i *interceptBus) SetSpeed(hz int64) error {
+	return i.b.SetSpeed(hz)
+}
+
+// Unwrap returns the intercepted bus.
+func (i *interceptBus) Unwrap() BusCloser {
+	return i.b
+}
+
+// interceptBusCtx implements BusCtx for Intercept() when the bus does.
+type interceptBusCtx struct {
+	*interceptBus
+}
+
+func (i *interceptBusCtx) TxCtx(ctx context.Context, addr uint16, w, r []byte) error {
+	return i.i(addr, w, r, func(addr uint16, w, r []byte) error {
+		return i.b.(BusCtx).TxCtx(ctx, addr, w, r)
+	})
+}
+
+var _ BusCloser = &interceptBus{}
+var _ BusCtx = &interceptBusCtx{}
@@ -6,12 +6,15 @@ package i2c
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"testing"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
 )
 
 func ExampleDev() {
@@ -42,6 +45,19 @@ func ExamplePins() {
 	}
 }
 
+func ExampleIntercept() {
+	//b, err := i2creg.Open("")
+	//defer b.Close()
+	var b BusCloser
+
+	// Logs all the transactions.
+	b = Intercept(b, func(addr uint16, w, r []byte, next TxFunc) error {
+		err := next(addr, w, r)
+		log.Printf("Tx(%d, %#v, %#v) = %v", addr, w, r, err)
+		return err
+	})
+}
+
 //
 
 func TestDevString(t *testing.T) {
@@ -106,8 +122,156 @@ func TestDevWriteErr(t *testing.T) {
 	}
 }
 
+func TestDevTxCtx(t *testing.T) {
+	b := &fakeBus{r: []byte{1}}
+	d := Dev{Bus: b, Addr: 12}
+	r := make([]byte, 1)
+	if err := d.TxCtx(context.Background(), []byte{2}, r); err != nil {
+		t.Fatal(err)
+	}
+	if b.addr != 12 || !bytes.Equal(b.w, []byte{2}) || r[0] != 1 {
+		t.Fatal(b.addr, b.w, r)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.TxCtx(ctx, []byte{3}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, []byte{2}) {
+		t.Fatal("Tx must not be called")
+	}
+	bc := &fakeBusCtx{}
+	if err := TxCtx(ctx, bc, 12, nil, nil); err != nil || bc.ctx != ctx {
+		t.Fatal(err)
+	}
+}
+
+func TestDevTx_log(t *testing.T) {
+	defer periph.SetLogger(nil)
+	l := &fakeLogger{}
+	periph.SetLogger(l)
+	d := Dev{Bus: &fakeBus{err: errors.New("nack")}, Addr: 12}
+	if err := d.Tx(nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := d.TxCtx(context.Background(), nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(l.msgs) != 2 || l.msgs[0] != "i2c: transaction failed [dev fake(12) err nack]" {
+		t.Fatal(l.msgs)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	f := &fakeBus{r: []byte{1, 2}}
+	var calls []uint16
+	b := Intercept(f, func(addr uint16, w, r []byte, next TxFunc) error {
+		calls = append(calls, addr)
+		if addr == 13 {
+			return errors.New("injected")
+		}
+		return next(addr, w, r)
+	})
+	if s := fmt.Sprintf("%s", b); s != "fake" {
+		t.Fatal(s)
+	}
+	r := make([]byte, 1)
+	if err := b.Tx(12, []byte{3}, r); err != nil || r[0] != 1 || f.addr != 12 {
+		t.Fatal(err, r, f.addr)
+	}
+	if err := b.Tx(13, []byte{4}, nil); err == nil || f.addr != 12 {
+		t.Fatal("expected injected error")
+	}
+	if err := TxCtx(context.Background(), b, 14, nil, r); err != nil || r[0] != 2 || f.addr != 14 {
+		t.Fatal(err, r, f.addr)
+	}
+	if len(calls) != 3 {
+		t.Fatal(calls)
+	}
+	if err := b.SetSpeed(100000); err != nil || f.speed != 100000 {
+		t.Fatal(err, f.speed)
+	}
+	// Only the optional interfaces implemented by the bus are exposed.
+	if _, ok := b.(Pins); ok {
+		t.Fatal("fakeBus doesn't implement Pins")
+	}
+	if _, ok := b.(BusCtx); ok {
+		t.Fatal("fakeBus doesn't implement BusCtx")
+	}
+	if u := b.(interface{ Unwrap() BusCloser }).Unwrap(); u != f {
+		t.Fatal(u)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntercept_optional(t *testing.T) {
+	f := &fakeBusPins{}
+	calls := 0
+	b := Intercept(f, func(addr uint16, w, r []byte, next TxFunc) error {
+		calls++
+		return next(addr, w, r)
+	})
+	if p := b.(Pins).SCL(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if _, ok := b.(BusCtx); !ok {
+		t.Fatal("fakeBusPins implements BusCtx")
+	}
+	ctx := context.Background()
+	if err := TxCtx(ctx, b, 12, nil, nil); err != nil || f.ctx != ctx || calls != 1 {
+		t.Fatal(err, calls)
+	}
+	// With only Pins.
+	b = Intercept(&struct {
+		*fakeBus
+		Pins
+	}{&fakeBus{}, f}, func(addr uint16, w, r []byte, next TxFunc) error {
+		return next(addr, w, r)
+	})
+	if _, ok := b.(Pins); !ok {
+		t.Fatal("Pins must be exposed")
+	}
+	if _, ok := b.(BusCtx); ok {
+		t.Fatal("BusCtx must not be exposed")
+	}
+	// With only BusCtx.
+	b = Intercept(&fakeBusCtx{}, func(addr uint16, w, r []byte, next TxFunc) error {
+		return next(addr, w, r)
+	})
+	if _, ok := b.(Pins); ok {
+		t.Fatal("Pins must not be exposed")
+	}
+	if _, ok := b.(BusCtx); !ok {
+		t.Fatal("BusCtx must be exposed")
+	}
+}
+
 //
 
+type fakeBusCtx struct {
+	fakeBus
+	ctx context.Context
+}
+
+func (f *fakeBusCtx) TxCtx(ctx context.Context, addr uint16, w, r []byte) error {
+	f.ctx = ctx
+	return nil
+}
+
+type fakeBusPins struct {
+	fakeBusCtx
+}
+
+func (f *fakeBusPins) SCL() gpio.PinIO {
+	return gpio.INVALID
+}
+
+func (f *fakeBusPins) SDA() gpio.PinIO {
+	return gpio.INVALID
+}
+
 type fakeBus struct {
 	speed int64
 	err   error
@@ -135,3 +299,15 @@ func (f *fakeBus) SetSpeed(hz int64) error {
 	f.speed = hz
 	return f.err
 }
+
+type fakeLogger struct {
+	msgs []string
+}
+
+func (f *fakeLogger) Debug(msg string, args ...interface{}) {
+	f.msgs = append(f.msgs, fmt.Sprintf("%s %v", msg, args))
+}
+
+func (f *fakeLogger) Info(msg string, args ...interface{})  {}
+func (f *fakeLogger) Warn(msg string, args ...interface{})  {}
+func (f *fakeLogger) Error(msg string, args ...interface{}) {}
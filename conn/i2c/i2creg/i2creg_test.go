@@ -5,6 +5,7 @@
 package i2creg
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"testing"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/i2c"
 )
 
@@ -84,6 +86,36 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpen_interceptors(t *testing.T) {
+	defer reset()
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	intercept := func(name string) i2c.Interceptor {
+		return func(addr uint16, w, r []byte, next i2c.TxFunc) error {
+			calls = append(calls, name)
+			return next(addr, w, r)
+		}
+	}
+	b, err := Open("a", intercept("outer"), intercept("inner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx(12, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, ","); s != "outer,inner" {
+		t.Fatal(s)
+	}
+	if err := Register("b", nil, 2, func() (i2c.BusCloser, error) { return nil, errors.New("fail") }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open("b", intercept("outer")); err == nil {
+		t.Fatal("open failed")
+	}
+}
+
 func TestDefault_NoNumber(t *testing.T) {
 	defer reset()
 	if err := Register("a", nil, -1, fakeBuser); err != nil {
@@ -174,6 +206,24 @@ func TestRegister_fail(t *testing.T) {
 	}
 }
 
+func TestRegisterWithInfo(t *testing.T) {
+	defer reset()
+	info := conn.Info{Driver: "fake", Path: "/dev/fake", MaxSpeed: 100, Features: []string{"x"}}
+	if err := RegisterWithInfo("a", nil, -1, info, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	// The registry keeps its own copy.
+	info.Features[0] = "y"
+	a := All()
+	if len(a) != 1 || a[0].Info.Driver != "fake" || a[0].Info.Path != "/dev/fake" || a[0].Info.MaxSpeed != 100 || a[0].Info.Features[0] != "x" {
+		t.Fatal(a)
+	}
+	a[0].Info.Features[0] = "z"
+	if f := All()[0].Info.Features[0]; f != "x" {
+		t.Fatal(f)
+	}
+}
+
 func TestUnregister(t *testing.T) {
 	defer reset()
 	if Unregister("") == nil {
@@ -190,6 +240,45 @@ func TestUnregister(t *testing.T) {
 	}
 }
 
+func TestNotify(t *testing.T) {
+	defer reset()
+	var events []string
+	stop := Notify(func(r *Ref, registered bool) {
+		events = append(events, fmt.Sprintf("%s:%t", r.Name, registered))
+	})
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("a", nil, 1, fakeBuser); err == nil {
+		t.Fatal("registering twice must not notify")
+	}
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if err := Register("b", nil, 2, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(events); s != "[a:true a:false]" {
+		t.Fatal(s)
+	}
+}
+
+func TestNotify_copy(t *testing.T) {
+	defer reset()
+	stop := Notify(func(r *Ref, registered bool) {
+		r.Name = "b"
+		r.Aliases[0] = "y"
+	})
+	defer stop()
+	if err := Register("a", []string{"x"}, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	if a := All(); len(a) != 1 || a[0].Name != "a" || a[0].Aliases[0] != "x" {
+		t.Fatal(a)
+	}
+}
+
 //
 
 func fakeBuser() (i2c.BusCloser, error) {
@@ -202,6 +291,7 @@ func reset() {
 	byName = map[string]*Ref{}
 	byNumber = map[int]*Ref{}
 	byAlias = map[string]*Ref{}
+	watchers = nil
 }
 
 type fakeBus struct {
@@ -0,0 +1,81 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2c
+
+import "fmt"
+
+// Probe is the transaction used by Scan() to detect a device.
+type Probe int
+
+const (
+	// ProbeAuto reads a byte from the addresses commonly used by EEPROMs and
+	// writes a byte everywhere else, like i2cdetect does.
+	//
+	// Writing to an EEPROM could corrupt it, while reading from some write-only
+	// devices could lock up the bus.
+	ProbeAuto Probe = 0
+	// ProbeRead reads a byte from the device.
+	ProbeRead Probe = 1
+	// ProbeWrite writes a zero byte to the device, which generally sets its
+	// register pointer.
+	ProbeWrite Probe = 2
+)
+
+func (p Probe) String() string {
+	switch p {
+	case ProbeAuto:
+		return "Auto"
+	case ProbeRead:
+		return "Read"
+	case ProbeWrite:
+		return "Write"
+	default:
+		return fmt.Sprintf("Probe(%d)", int(p))
+	}
+}
+
+const (
+	// ScanFirst is the first address probed by Scan(). The lower addresses are
+	// reserved.
+	ScanFirst = 0x08
+	// ScanLast is the last address probed by Scan(). The higher addresses are
+	// reserved.
+	ScanLast = 0x77
+)
+
+// Scan probes the 7 bit addresses from ScanFirst to ScanLast on b and returns
+// the addresses that acknowledged the probe, in increasing order.
+//
+// A device that is busy or doesn't support the probe transaction is not
+// detected. Scanning is meant for wiring verification; a device driver must
+// not rely on it.
+func Scan(b Bus, p Probe) ([]uint16, error) {
+	if p != ProbeAuto && p != ProbeRead && p != ProbeWrite {
+		return nil, fmt.Errorf("i2c: invalid probe %d", p)
+	}
+	var out []uint16
+	var buf [1]byte
+	for addr := uint16(ScanFirst); addr <= ScanLast; addr++ {
+		var err error
+		if p == ProbeRead || (p == ProbeAuto && isEEPROMAddr(addr)) {
+			err = b.Tx(addr, nil, buf[:])
+		} else {
+			buf[0] = 0
+			err = b.Tx(addr, buf[:], nil)
+		}
+		if err == nil {
+			out = append(out, addr)
+		}
+	}
+	return out, nil
+}
+
+//
+
+// isEEPROMAddr returns true for the addresses i2cdetect reads from instead of
+// writing to.
+func isEEPROMAddr(addr uint16) bool {
+	return (addr >= 0x30 && addr <= 0x37) || (addr >= 0x50 && addr <= 0x5F)
+}
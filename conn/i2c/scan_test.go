@@ -0,0 +1,99 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func ExampleScan() {
+	//b, err := i2creg.Open("")
+	//defer b.Close()
+	var b Bus
+
+	addrs, err := Scan(b, ProbeAuto)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, a := range addrs {
+		fmt.Printf("Found device at 0x%02X\n", a)
+	}
+}
+
+//
+
+func TestScan(t *testing.T) {
+	data := []struct {
+		p     Probe
+		reads []uint16
+	}{
+		{ProbeAuto, []uint16{0x50}},
+		{ProbeRead, []uint16{0x10, 0x50, 0x76}},
+		{ProbeWrite, nil},
+	}
+	for _, line := range data {
+		b := &scanBus{devices: map[uint16]bool{0x10: true, 0x50: true, 0x76: true}}
+		got, err := Scan(b, line.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []uint16{0x10, 0x50, 0x76}; !reflect.DeepEqual(got, want) {
+			t.Fatal(line.p, got)
+		}
+		if !reflect.DeepEqual(b.reads, line.reads) {
+			t.Fatal(line.p, b.reads)
+		}
+		if b.first != ScanFirst || b.last != ScanLast {
+			t.Fatal(b.first, b.last)
+		}
+	}
+}
+
+func TestScan_invalid(t *testing.T) {
+	if _, err := Scan(&scanBus{}, Probe(3)); err == nil {
+		t.Fatal("invalid probe")
+	}
+}
+
+func TestProbe_String(t *testing.T) {
+	if s := ProbeWrite.String(); s != "Write" {
+		t.Fatal(s)
+	}
+	if s := Probe(3).String(); s != "Probe(3)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+// scanBus acknowledges the transactions on the addresses in devices and
+// records the devices that were read from.
+type scanBus struct {
+	fakeBus
+	devices     map[uint16]bool
+	reads       []uint16
+	first, last uint16
+}
+
+func (s *scanBus) Tx(addr uint16, w, r []byte) error {
+	if s.first == 0 {
+		s.first = addr
+	}
+	s.last = addr
+	if len(w)+len(r) != 1 || (len(w) == 1 && w[0] != 0) {
+		return fmt.Errorf("unexpected transaction %#v %#v", w, r)
+	}
+	if !s.devices[addr] {
+		return errors.New("nack")
+	}
+	if len(r) != 0 {
+		s.reads = append(s.reads, addr)
+	}
+	return nil
+}
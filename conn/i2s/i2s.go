@@ -0,0 +1,133 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package i2s defines the I²S digital audio protocol.
+//
+// I²S carries a continuous stream of PCM audio samples over three wires: the
+// bit clock (BCLK), the frame sync, also named word select (LRCLK), and the
+// data line. It is used by DACs and amplifiers like the MAX98357 and by MEMS
+// microphones like the INMP441.
+//
+// Unlike SPI or I²C, there is no transaction; a Conn is a stream that is read
+// from or written to with io.Reader and io.Writer.
+package i2s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Format describes the samples layout in a stream.
+//
+// The samples are signed little endian integers, each stored in the smallest
+// number of bytes that can hold BitsPerSample bits. When Channels is 2, the
+// samples are interleaved, left channel first.
+type Format struct {
+	// SampleRate is the number of frames per second, e.g. 48000.
+	SampleRate int
+	// BitsPerSample is the number of significant bits per sample, between 8
+	// and 32.
+	BitsPerSample int
+	// Channels is 1 for mono or 2 for stereo.
+	Channels int
+}
+
+func (f Format) String() string {
+	c := "stereo"
+	if f.Channels == 1 {
+		c = "mono"
+	} else if f.Channels != 2 {
+		c = fmt.Sprintf("%dch", f.Channels)
+	}
+	return fmt.Sprintf("%dHz %dbits %s", f.SampleRate, f.BitsPerSample, c)
+}
+
+// Validate returns an error if the format is not supported.
+func (f Format) Validate() error {
+	if f.SampleRate <= 0 {
+		return errors.New("i2s: invalid sample rate")
+	}
+	if f.BitsPerSample < 8 || f.BitsPerSample > 32 {
+		return errors.New("i2s: bits per sample must be between 8 and 32")
+	}
+	if f.Channels != 1 && f.Channels != 2 {
+		return errors.New("i2s: channels must be 1 or 2")
+	}
+	return nil
+}
+
+// SampleSize returns the number of bytes used to store one sample.
+func (f Format) SampleSize() int {
+	return (f.BitsPerSample + 7) / 8
+}
+
+// FrameSize returns the number of bytes used to store one sample for each
+// channel.
+func (f Format) FrameSize() int {
+	return f.SampleSize() * f.Channels
+}
+
+// Duration returns the play time of n bytes of samples.
+func (f Format) Duration(n int) time.Duration {
+	s := f.FrameSize()
+	if s == 0 || f.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(int64(n/s) * int64(time.Second) / int64(f.SampleRate))
+}
+
+// Conn is a configured I²S stream.
+//
+// Write plays the samples and blocks until they were all shifted out. Read
+// records samples until the buffer is filled. The buffers must contain a whole
+// number of frames.
+//
+// The stream only runs while Read or Write is called; use large buffers to
+// limit the gaps between calls.
+type Conn interface {
+	fmt.Stringer
+	io.Reader
+	io.Writer
+	// Format returns the format negotiated in Connect.
+	Format() Format
+}
+
+// Port is the interface to be provided to device drivers.
+//
+// The device driver, that is the driver for the DAC or the microphone
+// connected over this port, calls Connect() to retrieve a configured
+// connection as Conn.
+type Port interface {
+	// Connect sets the stream format.
+	//
+	// The device driver must call this function exactly once.
+	Connect(f Format) (Conn, error)
+}
+
+// PortCloser is an I²S port that can be closed.
+//
+// This interface is meant to be handled by the application.
+type PortCloser interface {
+	io.Closer
+	Port
+}
+
+// Pins defines the pins that an I²S port interconnect is using on the host.
+//
+// It is expected that a implementer of Conn also implement Pins but this is
+// not a requirement.
+type Pins interface {
+	// CLK returns the BCLK (bit clock) pin.
+	CLK() gpio.PinOut
+	// FS returns the LRCLK (frame sync, also named word select) pin.
+	FS() gpio.PinOut
+	// DIN returns the SDIN (data in) pin.
+	DIN() gpio.PinIn
+	// DOUT returns the SDOUT (data out) pin.
+	DOUT() gpio.PinOut
+}
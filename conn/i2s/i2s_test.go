@@ -0,0 +1,75 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2s
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func ExamplePins() {
+	//p, err := bcm283x.NewPCM()
+	//c, err := p.Connect(Format{SampleRate: 48000, BitsPerSample: 16, Channels: 2})
+	var c Conn
+
+	// Prints out the gpio pin used.
+	if p, ok := c.(Pins); ok {
+		fmt.Printf("  CLK : %s", p.CLK())
+		fmt.Printf("  FS  : %s", p.FS())
+		fmt.Printf("  DIN : %s", p.DIN())
+		fmt.Printf("  DOUT: %s", p.DOUT())
+	}
+}
+
+//
+
+func TestFormat(t *testing.T) {
+	data := []struct {
+		f        Format
+		s        string
+		frame    int
+		duration time.Duration
+	}{
+		{Format{48000, 16, 2}, "48000Hz 16bits stereo", 4, time.Second},
+		{Format{16000, 24, 1}, "16000Hz 24bits mono", 3, 4 * time.Second},
+		{Format{8000, 32, 2}, "8000Hz 32bits stereo", 8, 3 * time.Second},
+	}
+	for i, line := range data {
+		if err := line.f.Validate(); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if s := line.f.String(); s != line.s {
+			t.Fatalf("#%d: %q != %q", i, s, line.s)
+		}
+		if s := line.f.FrameSize(); s != line.frame {
+			t.Fatalf("#%d: %d != %d", i, s, line.frame)
+		}
+		if d := line.f.Duration(48000 * 4); d != line.duration {
+			t.Fatalf("#%d: %s != %s", i, d, line.duration)
+		}
+	}
+}
+
+func TestFormat_invalid(t *testing.T) {
+	data := []Format{
+		{0, 16, 2},
+		{48000, 7, 2},
+		{48000, 33, 2},
+		{48000, 16, 0},
+		{48000, 16, 3},
+	}
+	for i, f := range data {
+		if f.Validate() == nil {
+			t.Fatalf("#%d: expected error", i)
+		}
+	}
+	if s := data[4].String(); s != "48000Hz 16bits 3ch" {
+		t.Fatal(s)
+	}
+	if d := (Format{}).Duration(10); d != 0 {
+		t.Fatal(d)
+	}
+}
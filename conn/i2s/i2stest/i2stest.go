@@ -0,0 +1,187 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package i2stest is meant to be used to test drivers over a fake I²S port.
+package i2stest
+
+import (
+	"io"
+	"sync"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2s"
+)
+
+// Record implements i2s.PortCloser and records the played samples.
+//
+// Read returns silence.
+type Record struct {
+	sync.Mutex
+	// W is the samples written so far.
+	W []byte
+	// F is the format set by Connect.
+	F           i2s.Format
+	Initialized bool
+}
+
+func (r *Record) String() string {
+	return "record"
+}
+
+// Close is a no-op.
+func (r *Record) Close() error {
+	return nil
+}
+
+// Connect implements i2s.Port.
+func (r *Record) Connect(f i2s.Format) (i2s.Conn, error) {
+	r.Lock()
+	defer r.Unlock()
+	if err := connect(&r.Initialized, f); err != nil {
+		return nil, err
+	}
+	r.F = f
+	return &recordConn{r}, nil
+}
+
+type recordConn struct {
+	r *Record
+}
+
+func (r *recordConn) String() string {
+	return r.r.String()
+}
+
+func (r *recordConn) Format() i2s.Format {
+	r.r.Lock()
+	defer r.r.Unlock()
+	return r.r.F
+}
+
+func (r *recordConn) Write(b []byte) (int, error) {
+	r.r.Lock()
+	defer r.r.Unlock()
+	if err := checkFrames(r.r.F, b); err != nil {
+		return 0, err
+	}
+	r.r.W = append(r.r.W, b...)
+	return len(b), nil
+}
+
+func (r *recordConn) Read(b []byte) (int, error) {
+	r.r.Lock()
+	defer r.r.Unlock()
+	if err := checkFrames(r.r.F, b); err != nil {
+		return 0, err
+	}
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+// Playback implements i2s.PortCloser and plays back recorded samples, like
+// the ones captured from a microphone.
+//
+// Read returns io.EOF once R is consumed. Write is not supported.
+type Playback struct {
+	sync.Mutex
+	// R is the samples to be returned by Read.
+	R []byte
+	// F is the format set by Connect.
+	F           i2s.Format
+	Initialized bool
+	// DontPanic changes the behavior of Close so it doesn't panic when R is
+	// not fully consumed.
+	DontPanic bool
+}
+
+func (p *Playback) String() string {
+	return "playback"
+}
+
+// Close verifies that all the samples were read.
+func (p *Playback) Close() error {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.R) != 0 {
+		return errorf(p.DontPanic, "i2stest: expected %d more bytes to be read", len(p.R))
+	}
+	return nil
+}
+
+// Connect implements i2s.Port.
+func (p *Playback) Connect(f i2s.Format) (i2s.Conn, error) {
+	p.Lock()
+	defer p.Unlock()
+	if err := connect(&p.Initialized, f); err != nil {
+		return nil, err
+	}
+	p.F = f
+	return &playbackConn{p}, nil
+}
+
+type playbackConn struct {
+	p *Playback
+}
+
+func (p *playbackConn) String() string {
+	return p.p.String()
+}
+
+func (p *playbackConn) Format() i2s.Format {
+	p.p.Lock()
+	defer p.p.Unlock()
+	return p.p.F
+}
+
+func (p *playbackConn) Write(b []byte) (int, error) {
+	return 0, conntest.Errorf("i2stest: Write is not implemented")
+}
+
+func (p *playbackConn) Read(b []byte) (int, error) {
+	p.p.Lock()
+	defer p.p.Unlock()
+	if err := checkFrames(p.p.F, b); err != nil {
+		return 0, err
+	}
+	if len(p.p.R) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, p.p.R)
+	p.p.R = p.p.R[n:]
+	return n, nil
+}
+
+//
+
+func connect(initialized *bool, f i2s.Format) error {
+	if *initialized {
+		return conntest.Errorf("i2stest: Connect cannot be called twice")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	*initialized = true
+	return nil
+}
+
+// checkFrames returns an error if b doesn't contain a whole number of frames.
+func checkFrames(f i2s.Format, b []byte) error {
+	if len(b)%f.FrameSize() != 0 {
+		return conntest.Errorf("i2stest: buffer of %d bytes is not a multiple of the frame size %d", len(b), f.FrameSize())
+	}
+	return nil
+}
+
+func errorf(dontPanic bool, format string, a ...interface{}) error {
+	err := conntest.Errorf(format, a...)
+	if !dontPanic {
+		panic(err)
+	}
+	return err
+}
+
+var _ i2s.PortCloser = &Record{}
+var _ i2s.PortCloser = &Playback{}
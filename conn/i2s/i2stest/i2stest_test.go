@@ -0,0 +1,103 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2stest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2s"
+)
+
+func TestRecord(t *testing.T) {
+	r := &Record{}
+	if _, err := r.Connect(i2s.Format{}); err == nil {
+		t.Fatal("invalid format")
+	}
+	f := i2s.Format{SampleRate: 8000, BitsPerSample: 16, Channels: 2}
+	c, err := r.Connect(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Connect(f); err == nil {
+		t.Fatal("second Connect")
+	}
+	if s := c.String(); s != "record" {
+		t.Fatal(s)
+	}
+	if c.Format() != f {
+		t.Fatal(c.Format())
+	}
+	if n, err := c.Write([]byte{1, 2, 3, 4}); n != 4 || err != nil {
+		t.Fatal(n, err)
+	}
+	if _, err := c.Write([]byte{1, 2}); !conntest.IsErr(err) {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.W, []byte{1, 2, 3, 4}) {
+		t.Fatal(r.W)
+	}
+	b := []byte{1, 2, 3, 4}
+	if n, err := c.Read(b); n != 4 || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(b, []byte{0, 0, 0, 0}) {
+		t.Fatal(b)
+	}
+	if _, err := c.Read(b[:3]); err == nil {
+		t.Fatal("partial frame")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	p := &Playback{R: []byte{1, 2, 3, 4, 5, 6}, DontPanic: true}
+	c, err := p.Connect(i2s.Format{SampleRate: 8000, BitsPerSample: 16, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "playback" {
+		t.Fatal(s)
+	}
+	if c.Format().Channels != 1 {
+		t.Fatal(c.Format())
+	}
+	if _, err := c.Write([]byte{1, 2}); err == nil {
+		t.Fatal("Write is not supported")
+	}
+	b := make([]byte, 4)
+	if n, err := c.Read(b); n != 4 || err != nil {
+		t.Fatal(n, err)
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("R is not consumed")
+	}
+	if _, err := c.Read(b[:1]); err == nil {
+		t.Fatal("partial frame")
+	}
+	if n, err := c.Read(b); n != 2 || err != nil || !bytes.Equal(b[:2], []byte{5, 6}) {
+		t.Fatal(n, err, b)
+	}
+	if _, err := c.Read(b); err != io.EOF {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayback_panic(t *testing.T) {
+	defer func() {
+		if v := recover(); v == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	p := &Playback{R: []byte{1, 2}}
+	p.Close()
+}
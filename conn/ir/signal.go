@@ -0,0 +1,206 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signal is a raw IR signal modulated on a carrier.
+type Signal struct {
+	// Carrier is the carrier frequency in Hz, usually 36000 or 38000.
+	Carrier int64
+	// Timings are the alternating mark (carrier on) and space (carrier off)
+	// durations, starting with a mark.
+	//
+	// When there is an even number of timings, the last space is the silence
+	// to respect after the signal, e.g. before it can be repeated.
+	Timings []time.Duration
+}
+
+func (s *Signal) String() string {
+	return fmt.Sprintf("Signal{%dHz, %d timings}", s.Carrier, len(s.Timings))
+}
+
+// Validate returns an error if the signal can't be transmitted.
+func (s *Signal) Validate() error {
+	if s.Carrier <= 0 {
+		return fmt.Errorf("ir: invalid carrier %dHz", s.Carrier)
+	}
+	if len(s.Timings) == 0 {
+		return errors.New("ir: empty signal")
+	}
+	for i, t := range s.Timings {
+		if t <= 0 {
+			return fmt.Errorf("ir: invalid timing #%d: %s", i, t)
+		}
+	}
+	return nil
+}
+
+// Transmitter defines an infrared emitter of raw signals.
+type Transmitter interface {
+	// Transmit emits the signal and returns once it was completely sent,
+	// including the trailing space, if any.
+	Transmit(s *Signal) error
+}
+
+// NEC returns the NEC protocol signal for a 8 bits address and command.
+//
+// The address and command are each followed by their logical inverse. The
+// signal is followed by the silence to complete the 108ms frame.
+func NEC(address, command uint8) *Signal {
+	return NECExtended(uint16(^address)<<8|uint16(address), command)
+}
+
+// NECExtended returns the NEC protocol signal for a 16 bits address and a 8
+// bits command, as used by most remotes nowadays.
+func NECExtended(address uint16, command uint8) *Signal {
+	s := &Signal{Carrier: 38000, Timings: make([]time.Duration, 0, 68)}
+	s.Timings = append(s.Timings, necLeaderMark, necLeaderSpace)
+	v := uint32(address) | uint32(command)<<16 | uint32(^command)<<24
+	for i := uint(0); i < 32; i++ {
+		// Least significant bit first.
+		if v&(1<<i) != 0 {
+			s.Timings = append(s.Timings, necBit, 3*necBit)
+		} else {
+			s.Timings = append(s.Timings, necBit, necBit)
+		}
+	}
+	s.Timings = append(s.Timings, necBit)
+	s.appendGap(necFrame)
+	return s
+}
+
+// NECRepeat returns the NEC repeat code, which is sent every 108ms while the
+// key is held after the initial NEC frame.
+func NECRepeat() *Signal {
+	s := &Signal{Carrier: 38000, Timings: []time.Duration{necLeaderMark, necLeaderSpace / 2, necBit}}
+	s.appendGap(necFrame)
+	return s
+}
+
+// RC5 returns the Philips RC-5 protocol signal.
+//
+// address is 5 bits and command is 7 bits; the 7th bit is sent as the
+// inverted field bit as defined in the extended RC-5 protocol. toggle must be
+// flipped on each new key press. The signal is followed by the silence to
+// complete the 113.778ms frame.
+func RC5(address, command uint8, toggle bool) (*Signal, error) {
+	if address >= 32 {
+		return nil, fmt.Errorf("ir: invalid RC-5 address %d", address)
+	}
+	if command >= 128 {
+		return nil, fmt.Errorf("ir: invalid RC-5 command %d", command)
+	}
+	// Start bit, field bit, toggle bit, 5 bits address then 6 bits command,
+	// most significant bit first.
+	v := uint16(1)<<13 | uint16(address)<<6 | uint16(command&0x3F)
+	if command&0x40 == 0 {
+		v |= 1 << 12
+	}
+	if toggle {
+		v |= 1 << 11
+	}
+	s := &Signal{Carrier: 36000}
+	// Manchester encoding; a 1 is a space followed by a mark, a 0 is a mark
+	// followed by a space. Adjacent halves of the same level are merged.
+	mark := false
+	for i := 13; i >= 0; i-- {
+		one := v&(1<<uint(i)) != 0
+		s.appendHalf(&mark, !one)
+		s.appendHalf(&mark, one)
+	}
+	if !mark {
+		// Drop the trailing space, it is replaced with the frame gap.
+		s.Timings = s.Timings[:len(s.Timings)-1]
+	}
+	s.appendGap(rc5Frame)
+	return s, nil
+}
+
+// ParsePronto parses a Pronto Hex code of learned type (0000) as commonly
+// found in IR code databases.
+//
+// The once sequence is returned, or the repeat sequence if there is no once
+// sequence.
+func ParsePronto(code string) (*Signal, error) {
+	fields := strings.Fields(code)
+	if len(fields) < 4 {
+		return nil, errors.New("ir: pronto code too short")
+	}
+	words := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("ir: invalid pronto word %q", f)
+		}
+		words[i] = int(v)
+	}
+	if words[0] != 0 {
+		return nil, fmt.Errorf("ir: unsupported pronto format %04X", words[0])
+	}
+	if words[1] == 0 {
+		return nil, errors.New("ir: invalid pronto frequency")
+	}
+	once := words[2]
+	repeat := words[3]
+	if len(words) != 4+2*(once+repeat) || once+repeat == 0 {
+		return nil, errors.New("ir: invalid pronto length")
+	}
+	// The carrier period is expressed in units of 0.241246µs; the timings are
+	// expressed in carrier periods.
+	period := float64(words[1]) * prontoClock
+	s := &Signal{Carrier: int64(1e9/period + 0.5)}
+	seq := words[4 : 4+2*once]
+	if once == 0 {
+		seq = words[4:]
+	}
+	s.Timings = make([]time.Duration, len(seq))
+	for i, w := range seq {
+		s.Timings[i] = time.Duration(float64(w)*period + 0.5)
+	}
+	return s, s.Validate()
+}
+
+//
+
+const (
+	necLeaderMark  = 9 * time.Millisecond
+	necLeaderSpace = 4500 * time.Microsecond
+	necBit         = 562500 * time.Nanosecond
+	necFrame       = 108 * time.Millisecond
+	rc5Half        = 889 * time.Microsecond
+	rc5Frame       = 113778 * time.Microsecond
+	// prontoClock is the Pronto clock period in ns.
+	prontoClock = 241.246
+)
+
+// appendHalf appends a RC-5 half bit.
+func (s *Signal) appendHalf(mark *bool, on bool) {
+	if on == *mark && len(s.Timings) != 0 {
+		s.Timings[len(s.Timings)-1] += rc5Half
+		return
+	}
+	*mark = on
+	if len(s.Timings) == 0 && !on {
+		// Never start with a space.
+		return
+	}
+	s.Timings = append(s.Timings, rc5Half)
+}
+
+// appendGap appends a space so the whole signal lasts frame.
+func (s *Signal) appendGap(frame time.Duration) {
+	var total time.Duration
+	for _, t := range s.Timings {
+		total += t
+	}
+	s.Timings = append(s.Timings, frame-total)
+}
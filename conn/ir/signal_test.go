@@ -0,0 +1,157 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSignal_Validate(t *testing.T) {
+	data := []Signal{
+		{},
+		{Carrier: 38000},
+		{Carrier: 38000, Timings: []time.Duration{time.Millisecond, 0}},
+		{Timings: []time.Duration{time.Millisecond}},
+	}
+	for i, s := range data {
+		if s.Validate() == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	s := Signal{Carrier: 38000, Timings: []time.Duration{time.Millisecond}}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if str := s.String(); str != "Signal{38000Hz, 1 timings}" {
+		t.Fatal(str)
+	}
+}
+
+func TestNEC(t *testing.T) {
+	s := NEC(0x04, 0x08)
+	if s.Carrier != 38000 {
+		t.Fatal(s.Carrier)
+	}
+	if len(s.Timings) != 68 {
+		t.Fatal(len(s.Timings))
+	}
+	if s.Timings[0] != 9*time.Millisecond || s.Timings[1] != 4500*time.Microsecond {
+		t.Fatal(s.Timings[:2])
+	}
+	if d := decodeNEC(s); d != 0xF708FB04 {
+		t.Fatalf("%#x", d)
+	}
+	if d := total(s.Timings); d != 108*time.Millisecond {
+		t.Fatal(d)
+	}
+	if d := decodeNEC(NECExtended(0x1234, 0x56)); d != 0xA9561234 {
+		t.Fatalf("%#x", d)
+	}
+}
+
+func TestNECRepeat(t *testing.T) {
+	s := NECRepeat()
+	expected := []time.Duration{9 * time.Millisecond, 2250 * time.Microsecond, 562500 * time.Nanosecond, 96187500 * time.Nanosecond}
+	if !reflect.DeepEqual(s.Timings, expected) {
+		t.Fatal(s.Timings)
+	}
+}
+
+func TestRC5(t *testing.T) {
+	// Address 5, command 0x35, toggle off: 1 1 0 00101 110101.
+	s, err := RC5(5, 0x35, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Carrier != 36000 {
+		t.Fatal(s.Carrier)
+	}
+	h := 889 * time.Microsecond
+	expected := []time.Duration{
+		h, h, 2 * h, h, h, h, h, 2 * h, 2 * h, 2 * h, h, h, h, h, 2 * h, 2 * h, 2 * h, 2 * h, h,
+	}
+	if !reflect.DeepEqual(s.Timings[:len(s.Timings)-1], expected) {
+		t.Fatalf("%v\n%v", s.Timings, expected)
+	}
+	if d := total(s.Timings); d != 113778*time.Microsecond {
+		t.Fatal(d)
+	}
+	// The extended command bit inverts the field bit, toggle sets its bit.
+	s, err = RC5(0, 0x40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Timings[0] != 2*h || s.Timings[1] != 2*h || s.Timings[2] != 2*h || s.Timings[3] != h {
+		t.Fatal(s.Timings)
+	}
+	if _, err := RC5(32, 0, false); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := RC5(0, 128, false); err == nil {
+		t.Fatal("invalid command")
+	}
+}
+
+func TestParsePronto(t *testing.T) {
+	s, err := ParsePronto("0000 006D 0002 0001 0157 00AC 0015 05D9 0157 0055")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Carrier != 38029 {
+		t.Fatal(s.Carrier)
+	}
+	if len(s.Timings) != 4 {
+		t.Fatal(s.Timings)
+	}
+	if s.Timings[0] < 9010*time.Microsecond || s.Timings[0] > 9030*time.Microsecond {
+		t.Fatal(s.Timings[0])
+	}
+	// Only the repeat sequence.
+	s, err = ParsePronto("0000 006D 0000 0001 0157 0055")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Timings) != 2 {
+		t.Fatal(s.Timings)
+	}
+	data := []string{
+		"",
+		"0000 006D 0001",
+		"0000 006D 0001 0000 0157 XXXX",
+		"0100 006D 0001 0000 0157 00AC",
+		"0000 0000 0001 0000 0157 00AC",
+		"0000 006D 0002 0000 0157 00AC",
+		"0000 006D 0000 0000",
+		"0000 006D 0001 0000 0157 0000",
+	}
+	for i, line := range data {
+		if _, err := ParsePronto(line); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+}
+
+//
+
+// decodeNEC returns the 32 bits sent in a NEC signal.
+func decodeNEC(s *Signal) uint32 {
+	var v uint32
+	for i := 0; i < 32; i++ {
+		if s.Timings[3+2*i] > 1000*time.Microsecond {
+			v |= 1 << uint(i)
+		}
+	}
+	return v
+}
+
+func total(t []time.Duration) time.Duration {
+	var d time.Duration
+	for _, v := range t {
+		d += v
+	}
+	return d
+}
@@ -16,10 +16,12 @@
 package onewire
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 )
@@ -50,6 +52,29 @@ type Bus interface {
 	Search(alarmOnly bool) ([]Address, error)
 }
 
+// BusCtx is an optional interface a Bus may implement to support
+// cancellation and deadlines via a context.Context.
+//
+// Use TxCtx() to use it when available.
+type BusCtx interface {
+	// TxCtx is like Bus.Tx but aborts the transaction when ctx is done.
+	TxCtx(ctx context.Context, w, r []byte, power Pullup) error
+}
+
+// TxCtx performs a bus transaction on b, honoring ctx.
+//
+// When b implements BusCtx, the call is forwarded to it. Otherwise ctx is only
+// checked before the transaction is started.
+func TxCtx(ctx context.Context, b Bus, w, r []byte, power Pullup) error {
+	if bc, ok := b.(BusCtx); ok {
+		return bc.TxCtx(ctx, w, r, power)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Tx(w, r, power)
+}
+
 // Address represents a 1-wire device address in little-endian format. This means
 // that the family code ends up in the lower byte, the CRC in the top byte,
 // and the variable address part in the middle 6 bytes. E.g. a DS18B20 device,
@@ -91,6 +116,44 @@ type Pins interface {
 	Q() gpio.PinIO
 }
 
+// TxFunc is the signature of Bus.Tx.
+type TxFunc func(w, r []byte, power Pullup) error
+
+// Interceptor intercepts the transactions on a bus, like a middleware.
+//
+// It must call next to forward the transaction to the bus. It can log or
+// measure the transaction, retry it when next fails, or return an error
+// without calling next to inject a fault.
+type Interceptor func(w, r []byte, power Pullup, next TxFunc) error
+
+// Intercept returns a BusCloser passing all the transactions on b through i.
+//
+// The returned bus forwards Search() and Close() to b; the transactions done
+// by Search() are not intercepted. It implements BusCtx and Pins only if b
+// does. Its method Unwrap() BusCloser returns b, to access the other
+// interfaces b implements. Use onewirereg.Open() to wrap a registered bus when
+// it is opened.
+func Intercept(b BusCloser, i Interceptor) BusCloser {
+	ib := &interceptBus{b: b, i: i}
+	p, isPins := b.(Pins)
+	if _, ok := b.(BusCtx); ok {
+		if isPins {
+			return &struct {
+				*interceptBusCtx
+				Pins
+			}{&interceptBusCtx{ib}, p}
+		}
+		return &interceptBusCtx{ib}
+	}
+	if isPins {
+		return &struct {
+			*interceptBus
+			Pins
+		}{ib, p}
+	}
+	return ib
+}
+
 // NoDevicesError is an interface that should be implemented by errors that
 // indicate that no devices responded with a presence pulse after a reset.
 type NoDevicesError interface {
@@ -158,13 +221,14 @@ func (d *Dev) String() string {
 //
 // It's a wrapper for Dev.Bus.Tx().
 func (d *Dev) Tx(w, r []byte) error {
-	// Issue ROM match command to select the device followed by the
-	// bytes being written.
-	ww := make([]byte, 9, len(w)+9)
-	ww[0] = 0x55 // Match ROM
-	binary.LittleEndian.PutUint64(ww[1:], uint64(d.Addr))
-	ww = append(ww, w...)
-	return d.Bus.Tx(ww, r, WeakPullup)
+	return d.logErr(d.Bus.Tx(d.matchROM(w), r, WeakPullup))
+}
+
+// TxCtx is like Tx but honors ctx, implementing conn.ConnCtx.
+//
+// It's a wrapper for TxCtx().
+func (d *Dev) TxCtx(ctx context.Context, w, r []byte) error {
+	return d.logErr(TxCtx(ctx, d.Bus, d.matchROM(w), r, WeakPullup))
 }
 
 // Duplex always return conn.Half for 1-wire.
@@ -179,17 +243,69 @@ func (d *Dev) Duplex() conn.Duplex {
 //
 // It's a wrapper for Dev.Bus.Tx().
 func (d *Dev) TxPower(w, r []byte) error {
-	// Issue ROM match command to select the device followed by the
-	// bytes being written.
+	return d.logErr(d.Bus.Tx(d.matchROM(w), r, StrongPullup))
+}
+
+// logErr emits err to periph.Log(), if any.
+func (d *Dev) logErr(err error) error {
+	if err != nil {
+		periph.Log().Debug("onewire: transaction failed", "dev", d.String(), "err", err)
+	}
+	return err
+}
+
+// matchROM returns the "match ROM" command to select the device followed by
+// the bytes being written.
+func (d *Dev) matchROM(w []byte) []byte {
 	ww := make([]byte, 9, len(w)+9)
 	ww[0] = 0x55 // Match ROM
 	binary.LittleEndian.PutUint64(ww[1:], uint64(d.Addr))
-	ww = append(ww, w...)
-	return d.Bus.Tx(ww, r, StrongPullup)
+	return append(ww, w...)
+}
+
+// interceptBus implements BusCloser for Intercept().
+type interceptBus struct {
+	b BusCloser
+	i Interceptor
+}
+
+func (i *interceptBus) String() string {
+	return fmt.Sprintf("%s", i.b)
+}
+
+func (i *interceptBus) Close() error {
+	return i.b.Close()
+}
+
+func (i *interceptBus) Tx(w, r []byte, power Pullup) error {
+	return i.i(w, r, power, i.b.Tx)
+}
+
+func (i *interceptBus) Search(alarmOnly bool) ([]Address, error) {
+	return i.b.Search(alarmOnly)
+}
+
+// Unwrap returns the intercepted bus.
+func (i *interceptBus) Unwrap() BusCloser {
+	return i.b
+}
+
+// interceptBusCtx implements BusCtx for Intercept() when the bus does.
+type interceptBusCtx struct {
+	*interceptBus
+}
+
+func (i *interceptBusCtx) TxCtx(ctx context.Context, w, r []byte, power Pullup) error {
+	return i.i(w, r, power, func(w, r []byte, power Pullup) error {
+		return i.b.(BusCtx).TxCtx(ctx, w, r, power)
+	})
 }
 
 // Ensure that the appropriate interfaces are implemented.
 var _ conn.Conn = &Dev{}
+var _ conn.ConnCtx = &Dev{}
 var _ NoDevicesError = noDevicesError("")
 var _ ShortedBusError = shortedBusError("")
 var _ BusError = busError("")
+var _ BusCloser = &interceptBus{}
+var _ BusCtx = &interceptBusCtx{}
@@ -6,12 +6,14 @@ package onewire
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"testing"
 
 	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
 )
 
 func ExampleDev() {
@@ -41,6 +43,19 @@ func ExamplePins() {
 	}
 }
 
+func ExampleIntercept() {
+	//b, err := onewirereg.Open("")
+	//defer b.Close()
+	var b BusCloser
+
+	// Logs all the transactions.
+	b = Intercept(b, func(w, r []byte, power Pullup, next TxFunc) error {
+		err := next(w, r, power)
+		log.Printf("Tx(%#v, %#v, %s) = %v", w, r, power, err)
+		return err
+	})
+}
+
 //
 
 func TestPullUp(t *testing.T) {
@@ -117,8 +132,137 @@ func TestDevTxPower(t *testing.T) {
 	}
 }
 
+func TestDevTxCtx(t *testing.T) {
+	b := &fakeBus{power: StrongPullup}
+	d := Dev{b, 12}
+	if err := d.TxCtx(context.Background(), []byte{3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{85, 12, 0, 0, 0, 0, 0, 0, 0, 3}
+	if !bytes.Equal(b.w, expected) || b.power != WeakPullup {
+		t.Fatal(b.w, b.power)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.TxCtx(ctx, []byte{3}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, expected) {
+		t.Fatal("Tx must not be called")
+	}
+	bc := &fakeBusCtx{}
+	if err := TxCtx(ctx, bc, nil, nil, StrongPullup); err != nil || bc.ctx != ctx || bc.power != StrongPullup {
+		t.Fatal(err)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	f := &fakeBus{r: []byte{1}}
+	var calls int
+	b := Intercept(f, func(w, r []byte, power Pullup, next TxFunc) error {
+		calls++
+		if len(w) == 0 {
+			return errors.New("injected")
+		}
+		return next(w, r, power)
+	})
+	if s := fmt.Sprintf("%s", b); s != "fake" {
+		t.Fatal(s)
+	}
+	r := make([]byte, 1)
+	if err := b.Tx([]byte{2}, r, StrongPullup); err != nil || r[0] != 1 || f.power != StrongPullup {
+		t.Fatal(err, r, f.power)
+	}
+	if err := b.Tx(nil, nil, WeakPullup); err == nil || f.power != StrongPullup {
+		t.Fatal("expected injected error")
+	}
+	if err := TxCtx(context.Background(), b, []byte{3}, nil, WeakPullup); err != nil || !bytes.Equal(f.w, []byte{2, 3}) {
+		t.Fatal(err, f.w)
+	}
+	if calls != 3 {
+		t.Fatal(calls)
+	}
+	if _, err := b.Search(false); err == nil {
+		t.Fatal("Search must be forwarded")
+	}
+	// Only the optional interfaces implemented by the bus are exposed.
+	if _, ok := b.(Pins); ok {
+		t.Fatal("fakeBus doesn't implement Pins")
+	}
+	if _, ok := b.(BusCtx); ok {
+		t.Fatal("fakeBus doesn't implement BusCtx")
+	}
+	if u := b.(interface{ Unwrap() BusCloser }).Unwrap(); u != f {
+		t.Fatal(u)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntercept_optional(t *testing.T) {
+	f := &fakeBusPins{}
+	calls := 0
+	b := Intercept(f, func(w, r []byte, power Pullup, next TxFunc) error {
+		calls++
+		return next(w, r, power)
+	})
+	if p := b.(Pins).Q(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if _, ok := b.(BusCtx); !ok {
+		t.Fatal("fakeBusPins implements BusCtx")
+	}
+	ctx := context.Background()
+	if err := TxCtx(ctx, b, nil, nil, StrongPullup); err != nil || f.ctx != ctx || f.power != StrongPullup || calls != 1 {
+		t.Fatal(err, calls)
+	}
+	// With only Pins.
+	b = Intercept(&struct {
+		*fakeBus
+		Pins
+	}{&fakeBus{}, f}, func(w, r []byte, power Pullup, next TxFunc) error {
+		return next(w, r, power)
+	})
+	if _, ok := b.(Pins); !ok {
+		t.Fatal("Pins must be exposed")
+	}
+	if _, ok := b.(BusCtx); ok {
+		t.Fatal("BusCtx must not be exposed")
+	}
+	// With only BusCtx.
+	b = Intercept(&fakeBusCtx{}, func(w, r []byte, power Pullup, next TxFunc) error {
+		return next(w, r, power)
+	})
+	if _, ok := b.(Pins); ok {
+		t.Fatal("Pins must not be exposed")
+	}
+	if _, ok := b.(BusCtx); !ok {
+		t.Fatal("BusCtx must be exposed")
+	}
+}
+
 //
 
+type fakeBusCtx struct {
+	fakeBus
+	ctx context.Context
+}
+
+func (f *fakeBusCtx) TxCtx(ctx context.Context, w, r []byte, power Pullup) error {
+	f.ctx = ctx
+	f.power = power
+	return nil
+}
+
+type fakeBusPins struct {
+	fakeBusCtx
+}
+
+func (f *fakeBusPins) Q() gpio.PinIO {
+	return gpio.INVALID
+}
+
 type fakeBus struct {
 	power Pullup
 	err   error
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/onewire"
 )
 
@@ -35,6 +36,8 @@ type Ref struct {
 	// Buses provided by the CPU normally have a 0 based number. Buses provided
 	// via an addon (like over USB) generally are not numbered.
 	Number int
+	// Info is the metadata provided by the driver, if any.
+	Info conn.Info
 	// Open is the factory to open an handle to this 1-wire bus.
 	Open Opener
 }
@@ -55,7 +58,11 @@ type Ref struct {
 //
 // When the 1-wire bus is provided by an off board plug and play bus like USB
 // via a FT232H USB device, there can be no associated number.
-func Open(name string) (onewire.BusCloser, error) {
+//
+// The interceptors, if any, wrap the bus in order, the first one being the
+// outermost. They can be used to log, measure, retry or inject faults in the
+// transactions without modifying the device drivers.
+func Open(name string, interceptors ...onewire.Interceptor) (onewire.BusCloser, error) {
 	var r *Ref
 	var err error
 	func() {
@@ -84,7 +91,14 @@ func Open(name string) (onewire.BusCloser, error) {
 	if r == nil {
 		return nil, wrapf("can't open unknown bus: %q", name)
 	}
-	return r.Open()
+	b, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		b = onewire.Intercept(b, interceptors[i])
+	}
+	return b, nil
 }
 
 // All returns a copy of all the registered references to all know 1-wire buses
@@ -98,9 +112,7 @@ func All() []*Ref {
 		defer mu.Unlock()
 		out = make(refList, 0, len(byName))
 		for _, v := range byName {
-			r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Open: v.Open}
-			copy(r.Aliases, v.Aliases)
-			out = append(out, r)
+			out = append(out, copyRef(v))
 		}
 	}()
 	sort.Sort(out)
@@ -115,6 +127,12 @@ func All() []*Ref {
 // In this case, the bus name should be created from the serial number of the
 // device for unique identification.
 func Register(name string, aliases []string, number int, o Opener) error {
+	return RegisterWithInfo(name, aliases, number, conn.Info{}, o)
+}
+
+// RegisterWithInfo is like Register and also records the metadata of the
+// bus, returned in Ref.Info.
+func RegisterWithInfo(name string, aliases []string, number int, info conn.Info, o Opener) error {
 	if len(name) == 0 {
 		return wrapf("can't register a bus with no name")
 	}
@@ -145,37 +163,11 @@ func Register(name string, aliases []string, number int, o Opener) error {
 		}
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if _, ok := byName[name]; ok {
-		return wrapf("can't register bus %q twice", name)
-	}
-	if _, ok := byAlias[name]; ok {
-		return wrapf("can't register bus %q twice; it is already an alias", name)
-	}
-	if number != -1 {
-		if _, ok := byNumber[number]; ok {
-			return wrapf("can't register bus %q; bus number %d is already registered", name, number)
-		}
-	}
-	for _, alias := range aliases {
-		if _, ok := byName[alias]; ok {
-			return wrapf("can't register bus %q twice; alias %q is already a bus", name, alias)
-		}
-		if _, ok := byAlias[alias]; ok {
-			return wrapf("can't register bus %q twice; alias %q is already an alias", name, alias)
-		}
-	}
-
-	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Open: o}
-	copy(r.Aliases, aliases)
-	byName[name] = r
-	if number != -1 {
-		byNumber[number] = r
-	}
-	for _, alias := range aliases {
-		byAlias[alias] = r
+	r, err := register(name, aliases, number, info, o)
+	if err != nil {
+		return err
 	}
+	notify(r, true)
 	return nil
 }
 
@@ -185,19 +177,43 @@ func Register(name string, aliases []string, number int, o Opener) error {
 // device is unplugged.
 func Unregister(name string) error {
 	mu.Lock()
-	defer mu.Unlock()
 	r := byName[name]
+	if r != nil {
+		delete(byName, name)
+		delete(byNumber, r.Number)
+		for _, alias := range r.Aliases {
+			delete(byAlias, alias)
+		}
+	}
+	mu.Unlock()
 	if r == nil {
 		return wrapf("can't unregister unknown bus name %q", name)
 	}
-	delete(byName, name)
-	delete(byNumber, r.Number)
-	for _, alias := range r.Aliases {
-		delete(byAlias, alias)
-	}
+	notify(r, false)
 	return nil
 }
 
+// Notify registers f to be called after a bus is registered or unregistered.
+//
+// f is called synchronously, outside of the registry lock. Call the returned
+// function to stop the notifications.
+func Notify(f func(r *Ref, registered bool)) func() {
+	w := &watcher{f: f}
+	mu.Lock()
+	defer mu.Unlock()
+	watchers = append(watchers, w)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range watchers {
+			if watchers[i] == w {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 //
 
 var (
@@ -206,8 +222,69 @@ var (
 	// Caches
 	byNumber = map[int]*Ref{}
 	byAlias  = map[string]*Ref{}
+	watchers []*watcher
 )
 
+type watcher struct {
+	f func(r *Ref, registered bool)
+}
+
+// notify calls the watchers registered with Notify().
+func notify(r *Ref, registered bool) {
+	mu.Lock()
+	w := append([]*watcher(nil), watchers...)
+	mu.Unlock()
+	for _, x := range w {
+		x.f(copyRef(r), registered)
+	}
+}
+
+// copyRef returns a deep copy of r, so the registry can't be modified through
+// it.
+func copyRef(v *Ref) *Ref {
+	r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Info: v.Info, Open: v.Open}
+	r.Info.Features = append([]string(nil), v.Info.Features...)
+	copy(r.Aliases, v.Aliases)
+	return r
+}
+
+// register adds the bus to the registry.
+func register(name string, aliases []string, number int, info conn.Info, o Opener) (*Ref, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byName[name]; ok {
+		return nil, wrapf("can't register bus %q twice", name)
+	}
+	if _, ok := byAlias[name]; ok {
+		return nil, wrapf("can't register bus %q twice; it is already an alias", name)
+	}
+	if number != -1 {
+		if _, ok := byNumber[number]; ok {
+			return nil, wrapf("can't register bus %q; bus number %d is already registered", name, number)
+		}
+	}
+	for _, alias := range aliases {
+		if _, ok := byName[alias]; ok {
+			return nil, wrapf("can't register bus %q twice; alias %q is already a bus", name, alias)
+		}
+		if _, ok := byAlias[alias]; ok {
+			return nil, wrapf("can't register bus %q twice; alias %q is already an alias", name, alias)
+		}
+	}
+
+	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Info: info, Open: o}
+	r.Info.Features = append([]string(nil), info.Features...)
+	copy(r.Aliases, aliases)
+	byName[name] = r
+	if number != -1 {
+		byNumber[number] = r
+	}
+	for _, alias := range aliases {
+		byAlias[alias] = r
+	}
+	return r, nil
+}
+
 // getDefault returns the Ref that should be used as the default bus.
 func getDefault() *Ref {
 	var o *Ref
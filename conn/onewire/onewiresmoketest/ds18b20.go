@@ -0,0 +1,256 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiresmoketest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/ds18b20"
+	"periph.io/x/periph/devices/ds248x"
+)
+
+// DS18B20 is imported by periph-smoketest.
+//
+// Unlike SmokeTest, it works with any 1-wire bus with at least one DS18B20
+// and talks to the sensor with raw transactions, so a regression in the bus
+// driver or in package onewire isn't hidden by the ds18b20 driver.
+type DS18B20 struct {
+}
+
+func (s *DS18B20) String() string {
+	return s.Name()
+}
+
+// Name implements the SmokeTest interface.
+func (s *DS18B20) Name() string {
+	return "onewire-ds18b20"
+}
+
+// Description implements the SmokeTest interface.
+func (s *DS18B20) Description() string {
+	return "Tests search, match ROM, strong pull-up and CRC of a 1-wire bus with a DS18B20"
+}
+
+// Run implements the SmokeTest interface.
+func (s *DS18B20) Run(f *flag.FlagSet, args []string) error {
+	busName := f.String("bus", "", "1-wire bus name, default is the first registered bus")
+	i2cName := f.String("i2cbus", "", "use the 1-wire bus of a DS248x on this I²C bus instead; use - for the default I²C bus")
+	addrHex := f.String("addr", "", "64 bits address of the DS18B20, default is the first one found")
+	f.Parse(args)
+	if f.NArg() != 0 {
+		f.Usage()
+		return errors.New("unrecognized arguments")
+	}
+	var want onewire.Address
+	if *addrHex != "" {
+		a, err := strconv.ParseUint(*addrHex, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid -addr: %v", err)
+		}
+		want = onewire.Address(a)
+	}
+
+	var bus onewire.Bus
+	if *i2cName != "" {
+		if *i2cName == "-" {
+			*i2cName = ""
+		}
+		i2cBus, err := i2creg.Open(*i2cName)
+		if err != nil {
+			return fmt.Errorf("cannot open I²C bus %s: %v", *i2cName, err)
+		}
+		defer i2cBus.Close()
+		b, err := ds248x.New(i2cBus, nil)
+		if err != nil {
+			return fmt.Errorf("cannot open DS248x: %v", err)
+		}
+		bus = b
+	} else {
+		b, err := onewirereg.Open(*busName)
+		if err != nil {
+			return fmt.Errorf("cannot open 1-wire bus %s: %v", *busName, err)
+		}
+		defer b.Close()
+		bus = b
+	}
+	log.Printf("%s: using %s", s, bus)
+
+	addr, err := s.search(bus, want)
+	if err != nil {
+		return err
+	}
+	if err := s.matchROM(bus, addr); err != nil {
+		return err
+	}
+	t, err := s.convert(bus, addr)
+	if err != nil {
+		return err
+	}
+	return s.driver(bus, addr, t)
+}
+
+// search verifies the CRC of all the addresses found and returns the DS18B20
+// to test. It also verifies that an alarm search doesn't fail.
+func (s *DS18B20) search(bus onewire.Bus, want onewire.Address) (onewire.Address, error) {
+	addrs, err := bus.Search(false)
+	if err != nil {
+		return 0, fmt.Errorf("search failed: %v", err)
+	}
+	log.Printf("%s: found %d devices", s, len(addrs))
+	var found onewire.Address
+	for _, a := range addrs {
+		if !onewire.CheckCRC(addrBytes(a)) {
+			return 0, fmt.Errorf("search returned address %#016x with an invalid CRC", uint64(a))
+		}
+		if found == 0 && ((want == 0 && isDS18B20(a)) || a == want) {
+			found = a
+		}
+	}
+	if found == 0 {
+		if want != 0 {
+			return 0, fmt.Errorf("search didn't find %#016x", uint64(want))
+		}
+		return 0, errors.New("search didn't find a DS18B20")
+	}
+	if !isDS18B20(found) {
+		return 0, fmt.Errorf("%#016x is not a DS18B20", uint64(found))
+	}
+	log.Printf("%s: using DS18B20 %#016x", s, uint64(found))
+
+	// The sensor doesn't have alarm thresholds set by this test, so the result
+	// can't be verified, only that it doesn't fail.
+	alarms, err := bus.Search(true)
+	if err != nil {
+		return 0, fmt.Errorf("alarm search failed: %v", err)
+	}
+	log.Printf("%s: found %d devices in alarm state", s, len(alarms))
+	return found, nil
+}
+
+// matchROM reads the scratchpad of the sensor and verifies its CRC, then
+// verifies that a device not on the bus doesn't reply.
+func (s *DS18B20) matchROM(bus onewire.Bus, addr onewire.Address) error {
+	if _, err := readScratchpad(bus, addr); err != nil {
+		return err
+	}
+	// Change the serial number and recompute the CRC so the address is valid.
+	b := addrBytes(addr ^ 0x0000FFFFFFFFFF00)
+	b[7] = onewire.CalcCRC(b[:7])
+	var other onewire.Address
+	for i := range b {
+		other |= onewire.Address(b[i]) << uint(8*i)
+	}
+	d := onewire.Dev{Bus: bus, Addr: other}
+	var spad [9]byte
+	if err := d.Tx([]byte{0xbe}, spad[:]); err != nil {
+		return fmt.Errorf("match ROM of missing device %#016x failed: %v", uint64(other), err)
+	}
+	for _, c := range spad {
+		if c != 0xff {
+			return fmt.Errorf("missing device %#016x replied % x", uint64(other), spad[:])
+		}
+	}
+	log.Printf("%s: match ROM successful", s)
+	return nil
+}
+
+// convert starts a conversion with the strong pull-up enabled, then reads
+// the temperature out of the scratchpad.
+//
+// A sensor with parasitic power resets itself during the conversion when the
+// strong pull-up doesn't work, and then reports its power-on value of 85°C.
+func (s *DS18B20) convert(bus onewire.Bus, addr onewire.Address) (devices.Celsius, error) {
+	spad, err := readScratchpad(bus, addr)
+	if err != nil {
+		return 0, err
+	}
+	bits := int(spad[4]>>5) + 9
+	d := onewire.Dev{Bus: bus, Addr: addr}
+	if err := d.TxPower([]byte{0x44}, nil); err != nil {
+		return 0, fmt.Errorf("conversion failed: %v", err)
+	}
+	// 750ms at 12 bits, halved for each bit less.
+	time.Sleep((750 * time.Millisecond) >> uint(12-bits))
+	if spad, err = readScratchpad(bus, addr); err != nil {
+		return 0, err
+	}
+	raw := int16(spad[0]) | int16(spad[1])<<8
+	if raw == 0x0550 {
+		return 0, errors.New("conversion returned the power-on value of 85°C; is the strong pull-up working?")
+	}
+	t := devices.Celsius(int32(raw) * 1000 / 16)
+	if t > 50*1000 || t <= 0 {
+		return 0, fmt.Errorf("expected temperature in the 0°C..50°C range, got %s", t)
+	}
+	log.Printf("%s: %d bits conversion is %s", s, bits, t)
+	return t, nil
+}
+
+// driver verifies that the ds18b20 driver agrees with the raw reading.
+func (s *DS18B20) driver(bus onewire.Bus, addr onewire.Address, t devices.Celsius) error {
+	// Keep the current resolution so the configuration isn't written to the
+	// EEPROM.
+	spad, err := readScratchpad(bus, addr)
+	if err != nil {
+		return err
+	}
+	dev, err := ds18b20.New(bus, addr, int(spad[4]>>5)+9)
+	if err != nil {
+		return err
+	}
+	t2, err := dev.Temperature()
+	if err != nil {
+		return err
+	}
+	if d := t2 - t; d > 1000 || d < -1000 {
+		return fmt.Errorf("ds18b20 driver returned %s, raw reading was %s", t2, t)
+	}
+	log.Printf("%s: ds18b20 driver returned %s", s, t2)
+	return nil
+}
+
+//
+
+// readScratchpad reads the 9 bytes of scratchpad and verifies the CRC.
+func readScratchpad(bus onewire.Bus, addr onewire.Address) ([]byte, error) {
+	d := onewire.Dev{Bus: bus, Addr: addr}
+	var spad [9]byte
+	if err := d.Tx([]byte{0xbe}, spad[:]); err != nil {
+		return nil, fmt.Errorf("reading scratchpad failed: %v", err)
+	}
+	if !onewire.CheckCRC(spad[:]) {
+		return nil, fmt.Errorf("invalid scratchpad CRC: % x", spad[:])
+	}
+	return spad[:], nil
+}
+
+// addrBytes returns the address in the order it is sent on the bus, family
+// code first and CRC last.
+func addrBytes(a onewire.Address) []byte {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = byte(a >> uint(8*i))
+	}
+	return b
+}
+
+func isDS18B20(a onewire.Address) bool {
+	switch byte(a) {
+	case 0x22, 0x28, 0x3B, 0x42:
+		return true
+	}
+	return false
+}
+
+var _ fmt.Stringer = &DS18B20{}
@@ -4,4 +4,13 @@
 
 // Package pinreg is a registry for the physical headers (made up of pins) on
 // a host.
+//
+// A host may have multiple headers, e.g. the Raspberry Pi P1 header, a debug
+// connector or the BeagleBone cape connectors P8 and P9. Each header can
+// describe its physical pin numbering and its logic voltage level, and the
+// position of a pin can be queried with Position() or Locations().
+//
+// Export() returns a snapshot of all the headers, including the current
+// function of each pin, that can be written as JSON or YAML for external
+// tooling.
 package pinreg
@@ -0,0 +1,143 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pinreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Layout is a serializable snapshot of the headers on a micro computer,
+// including what each pin is currently configured to do.
+//
+// It is meant for external tooling and user interfaces. Use Export() to
+// create one.
+type Layout struct {
+	// Board is the name of the board, e.g. "Raspberry Pi 3 Model B Rev 1.2".
+	// It is left empty by Export() as pinreg doesn't know about the host; the
+	// caller is expected to fill it.
+	Board   string         `json:"board,omitempty"`
+	Headers []HeaderLayout `json:"headers"`
+}
+
+// HeaderLayout is the layout of one header.
+type HeaderLayout struct {
+	Name string `json:"name"`
+	// Voltage is the logic level in millivolts, 0 when unknown.
+	Voltage int `json:"voltage,omitempty"`
+	// Rows is the pins in the same layout as Header.Pins.
+	Rows [][]PinLayout `json:"rows"`
+}
+
+// PinLayout is the state of one pin on a header.
+type PinLayout struct {
+	// Number is the physical pin number on the header.
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	// GPIO is the logical pin number, or -1 if the pin is not a GPIO.
+	GPIO int `json:"gpio"`
+	// Function is what the pin is configured to do, e.g. "In/High" or
+	// "I2C1_SDA".
+	Function string `json:"function"`
+	// DefaultPull is the pull resistor at boot, only set for GPIOs that report
+	// it.
+	DefaultPull string `json:"default_pull,omitempty"`
+}
+
+// Export returns a snapshot of all the registered headers, sorted by name.
+func Export() *Layout {
+	all := Headers()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	l := &Layout{Headers: make([]HeaderLayout, 0, len(names))}
+	for _, name := range names {
+		h := all[name]
+		hl := HeaderLayout{Name: name, Voltage: h.Voltage, Rows: make([][]PinLayout, len(h.Pins))}
+		for i, line := range h.Pins {
+			hl.Rows[i] = make([]PinLayout, len(line))
+			for j, p := range line {
+				pl := PinLayout{Number: h.Number(i, j), Name: p.Name(), GPIO: p.Number(), Function: p.Function()}
+				if d, ok := p.(gpio.PinDefaultPull); ok {
+					pl.DefaultPull = d.DefaultPull().String()
+				}
+				hl.Rows[i][j] = pl
+			}
+		}
+		l.Headers = append(l.Headers, hl)
+	}
+	return l
+}
+
+// WriteJSON writes the layout as indented JSON.
+func (l *Layout) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteYAML writes the layout as YAML, with the same field names as the JSON
+// encoding.
+func (l *Layout) WriteYAML(w io.Writer) error {
+	y := yamlWriter{w: w}
+	if l.Board != "" {
+		y.printf("board: %s\n", strconv.Quote(l.Board))
+	}
+	if len(l.Headers) == 0 {
+		y.printf("headers: []\n")
+		return y.err
+	}
+	y.printf("headers:\n")
+	for _, h := range l.Headers {
+		y.printf("- name: %s\n", strconv.Quote(h.Name))
+		if h.Voltage != 0 {
+			y.printf("  voltage: %d\n", h.Voltage)
+		}
+		y.printf("  rows:\n")
+		for _, row := range h.Rows {
+			if len(row) == 0 {
+				y.printf("  - []\n")
+			}
+			for j, p := range row {
+				prefix := "    "
+				if j == 0 {
+					prefix = "  - "
+				}
+				y.printf("%s- number: %d\n", prefix, p.Number)
+				y.printf("      name: %s\n", strconv.Quote(p.Name))
+				y.printf("      gpio: %d\n", p.GPIO)
+				y.printf("      function: %s\n", strconv.Quote(p.Function))
+				if p.DefaultPull != "" {
+					y.printf("      default_pull: %s\n", strconv.Quote(p.DefaultPull))
+				}
+			}
+		}
+	}
+	return y.err
+}
+
+//
+
+// yamlWriter keeps the first write error.
+type yamlWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (y *yamlWriter) printf(format string, a ...interface{}) {
+	if y.err == nil {
+		_, y.err = fmt.Fprintf(y.w, format, a...)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pinreg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/pin"
+)
+
+func TestExport(t *testing.T) {
+	defer reset()
+	gpio2 := &pullPin{Pin: gpiotest.Pin{N: "GPIO2", Num: 2, Fn: "I2C1_SDA"}}
+	h := Header{
+		Pins:    [][]pin.Pin{{pin.GROUND, gpio2}},
+		Numbers: [][]int{{2, 1}},
+		Voltage: 3300,
+	}
+	if err := RegisterHeader("EXPORT", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("EMPTY", [][]pin.Pin{{}}); err != nil {
+		t.Fatal(err)
+	}
+	l := Export()
+	l.Board = "Board"
+	expected := &Layout{
+		Board: "Board",
+		Headers: []HeaderLayout{
+			{Name: "EMPTY", Rows: [][]PinLayout{{}}},
+			{
+				Name:    "EXPORT",
+				Voltage: 3300,
+				Rows: [][]PinLayout{{
+					{Number: 2, Name: "GROUND", GPIO: -1, Function: ""},
+					{Number: 1, Name: "GPIO2", GPIO: 2, Function: "I2C1_SDA", DefaultPull: "PullUp"},
+				}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(l, expected) {
+		t.Fatalf("%#v", l)
+	}
+
+	buf := bytes.Buffer{}
+	if err := l.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := &Layout{}
+	if err := json.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := l.WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	yaml := `board: "Board"
+headers:
+- name: "EMPTY"
+  rows:
+  - []
+- name: "EXPORT"
+  voltage: 3300
+  rows:
+  - - number: 2
+      name: "GROUND"
+      gpio: -1
+      function: ""
+    - number: 1
+      name: "GPIO2"
+      gpio: 2
+      function: "I2C1_SDA"
+      default_pull: "PullUp"
+`
+	if s := buf.String(); s != yaml {
+		t.Fatal(s)
+	}
+}
+
+func TestExport_empty(t *testing.T) {
+	defer reset()
+	buf := bytes.Buffer{}
+	if err := Export().WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "headers: []\n" {
+		t.Fatal(s)
+	}
+	buf.Reset()
+	if err := Export().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "{\n  \"headers\": []\n}\n" {
+		t.Fatal(s)
+	}
+}
+
+func TestExport_write_error(t *testing.T) {
+	l := &Layout{Headers: []HeaderLayout{{Name: "P1"}}}
+	if err := l.WriteJSON(&failWriter{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := l.WriteYAML(&failWriter{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+//
+
+type pullPin struct {
+	gpiotest.Pin
+}
+
+func (p *pullPin) DefaultPull() gpio.Pull {
+	return gpio.PullUp
+}
+
+type failWriter struct{}
+
+func (f *failWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("oops")
+}
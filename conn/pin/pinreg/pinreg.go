@@ -14,6 +14,43 @@ import (
 	"periph.io/x/periph/conn/pin"
 )
 
+// Header describes a physical header on a micro computer.
+type Header struct {
+	// Pins is the pins on the header. For a 2x20 header, it's going to be a
+	// slice of [20][2]pin.Pin.
+	Pins [][]pin.Pin
+	// Numbers is the physical number of each pin in Pins, in the same layout.
+	//
+	// When nil, the pins are numbered from 1 in row order, which matches the
+	// usual odd/even numbering of 2 rows headers like the Raspberry Pi P1.
+	Numbers [][]int
+	// Voltage is the logic level of the GPIO pins on the header in millivolts,
+	// e.g. 3300. It is 0 when unknown.
+	Voltage int
+}
+
+// Number returns the physical number of the pin at Pins[i][j].
+func (h *Header) Number(i, j int) int {
+	if h.Numbers != nil {
+		return h.Numbers[i][j]
+	}
+	n := 0
+	for _, line := range h.Pins[:i] {
+		n += len(line)
+	}
+	return n + j + 1
+}
+
+// Location is the physical location of a pin.
+type Location struct {
+	Header string // Header name, e.g. "P1"
+	Number int    // Physical pin number, 1-based
+}
+
+func (l Location) String() string {
+	return l.Header + "_" + strconv.Itoa(l.Number)
+}
+
 // All contains all the on-board headers on a micro computer.
 //
 // The map key is the header name, e.g. "P1" or "EULER" and the value is a
@@ -24,27 +61,78 @@ func All() map[string][][]pin.Pin {
 	defer mu.Unlock()
 	out := make(map[string][][]pin.Pin, len(allHeaders))
 	for k, v := range allHeaders {
-		outV := make([][]pin.Pin, len(v))
-		for i, w := range v {
-			outW := make([]pin.Pin, len(w))
-			copy(outW, w)
-			outV[i] = outW
+		out[k] = copyPins(v.Pins)
+	}
+	return out
+}
+
+// Headers returns all the on-board headers on a micro computer, including
+// their physical numbering and voltage level.
+func Headers() map[string]Header {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make(map[string]Header, len(allHeaders))
+	for k, v := range allHeaders {
+		h := Header{Pins: copyPins(v.Pins), Voltage: v.Voltage}
+		if v.Numbers != nil {
+			h.Numbers = make([][]int, len(v.Numbers))
+			for i, w := range v.Numbers {
+				h.Numbers[i] = append([]int(nil), w...)
+			}
 		}
-		out[k] = outV
+		out[k] = h
 	}
 	return out
 }
 
 // Position returns the position on a pin if found.
 //
-// The header and the pin number. Pin numbers are 1-based.
+// The header and the pin number. Pin numbers are 1-based. When the pin is on
+// multiple headers or multiple times on a header, the first one registered is
+// returned.
 //
 // Returns "", 0 if not connected.
 func Position(p pin.Pin) (string, int) {
 	mu.Lock()
 	defer mu.Unlock()
-	pos, _ := byPin[realPin(p).Name()]
-	return pos.name, pos.number
+	if l := byPin[realPin(p).Name()]; len(l) != 0 {
+		return l[0].Header, l[0].Number
+	}
+	return "", 0
+}
+
+// Locations returns all the positions of a pin on the headers, e.g. GROUND is
+// found on multiple pins.
+//
+// Returns nil if not connected.
+func Locations(p pin.Pin) []Location {
+	mu.Lock()
+	defer mu.Unlock()
+	l := byPin[realPin(p).Name()]
+	if len(l) == 0 {
+		return nil
+	}
+	return append([]Location(nil), l...)
+}
+
+// ByLocation returns the pin at the physical number on a header.
+//
+// Returns nil if there is no such pin.
+func ByLocation(header string, number int) pin.Pin {
+	mu.Lock()
+	defer mu.Unlock()
+	h, ok := allHeaders[header]
+	if !ok {
+		return nil
+	}
+	for i, line := range h.Pins {
+		for j, p := range line {
+			if h.Number(i, j) == number {
+				return p
+			}
+		}
+	}
+	return nil
 }
 
 // IsConnected returns true if the pin is on a header.
@@ -55,35 +143,63 @@ func IsConnected(p pin.Pin) bool {
 
 // Register registers a physical header.
 //
+// The pins are numbered from 1 in row order. Use RegisterHeader to specify
+// the physical numbering or the voltage level.
+//
 // It automatically registers all gpio pins to gpioreg.
 func Register(name string, allPins [][]pin.Pin) error {
+	return RegisterHeader(name, Header{Pins: allPins})
+}
+
+// RegisterHeader registers a physical header.
+//
+// It automatically registers all gpio pins to gpioreg as an alias
+// "<header>_<number>", e.g. "P1_11".
+func RegisterHeader(name string, h Header) error {
 	mu.Lock()
 	defer mu.Unlock()
 	if _, ok := allHeaders[name]; ok {
 		return fmt.Errorf("pinreg: header %q was already registered", name)
 	}
-	for i, line := range allPins {
+	for i, line := range h.Pins {
 		for j, pin := range line {
 			if pin == nil || len(pin.Name()) == 0 {
 				return fmt.Errorf("pinreg: invalid pin on header %s[%d][%d]", name, i+1, j+1)
 			}
 		}
 	}
-	allHeaders[name] = allPins
-	number := 1
-	for _, line := range allPins {
-		for _, p := range line {
-			byPin[realPin(p).Name()] = position{name, number}
-			number++
+	if h.Numbers != nil {
+		if len(h.Numbers) != len(h.Pins) {
+			return fmt.Errorf("pinreg: header %s has %d rows of pins but %d rows of numbers", name, len(h.Pins), len(h.Numbers))
+		}
+		seen := map[int]bool{}
+		for i, line := range h.Numbers {
+			if len(line) != len(h.Pins[i]) {
+				return fmt.Errorf("pinreg: header %s row %d has %d pins but %d numbers", name, i+1, len(h.Pins[i]), len(line))
+			}
+			for _, n := range line {
+				if n <= 0 || seen[n] {
+					return fmt.Errorf("pinreg: invalid pin number %d on header %s", n, name)
+				}
+				seen[n] = true
+			}
+		}
+	}
+	if h.Voltage < 0 {
+		return fmt.Errorf("pinreg: invalid voltage %dmV on header %s", h.Voltage, name)
+	}
+	allHeaders[name] = h
+	for i, line := range h.Pins {
+		for j, p := range line {
+			n := realPin(p).Name()
+			byPin[n] = append(byPin[n], Location{name, h.Number(i, j)})
 		}
 	}
 
-	count := 0
-	for _, row := range allPins {
-		for _, p := range row {
-			count++
+	for i, line := range h.Pins {
+		for j, p := range line {
 			if _, ok := p.(gpio.PinIO); ok {
-				if err := gpioreg.RegisterAlias(name+"_"+strconv.Itoa(count), p.Name()); err != nil {
+				if err := gpioreg.RegisterAlias(name+"_"+strconv.Itoa(h.Number(i, j)), p.Name()); err != nil {
 					return fmt.Errorf("pinreg: %v", err)
 				}
 			}
@@ -95,17 +211,22 @@ func Register(name string, allPins [][]pin.Pin) error {
 
 //
 
-type position struct {
-	name   string // Header name
-	number int    // Pin number
-}
-
 var (
 	mu         sync.Mutex
-	allHeaders = map[string][][]pin.Pin{} // every known headers as per internal lookup table
-	byPin      = map[string]position{}    // GPIO pin name to position
+	allHeaders = map[string]Header{}     // every known headers as per internal lookup table
+	byPin      = map[string][]Location{} // GPIO pin name to positions
 )
 
+func copyPins(v [][]pin.Pin) [][]pin.Pin {
+	out := make([][]pin.Pin, len(v))
+	for i, w := range v {
+		outW := make([]pin.Pin, len(w))
+		copy(outW, w)
+		out[i] = outW
+	}
+	return out
+}
+
 // realPin returns the real pin from an alias.
 func realPin(p pin.Pin) pin.Pin {
 	for {
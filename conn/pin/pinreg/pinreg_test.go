@@ -5,6 +5,7 @@
 package pinreg
 
 import (
+	"reflect"
 	"testing"
 
 	"periph.io/x/periph/conn/gpio"
@@ -70,13 +71,89 @@ func TestIsConnected(t *testing.T) {
 	}
 }
 
+func TestRegisterHeader(t *testing.T) {
+	defer reset()
+	gpio2 := &gpiotest.Pin{N: "GPIO2", Num: 2, Fn: "I2C1_SDA"}
+	gpio3 := &gpiotest.Pin{N: "GPIO3", Num: 3, Fn: "I2C1_SCL"}
+	// A 2x2 header numbered by column.
+	h := Header{
+		Pins:    [][]pin.Pin{{pin.GROUND, gpio2}, {pin.GROUND, gpio3}},
+		Numbers: [][]int{{1, 3}, {2, 4}},
+		Voltage: 1800,
+	}
+	if err := RegisterHeader("DEBUG", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("J3", [][]pin.Pin{{gpio3}}); err != nil {
+		t.Fatal(err)
+	}
+	all := Headers()
+	if d := all["DEBUG"]; d.Voltage != 1800 || !reflect.DeepEqual(d.Numbers, h.Numbers) {
+		t.Fatal(d)
+	}
+	if p := all["J3"]; p.Voltage != 0 || p.Numbers != nil || p.Number(0, 0) != 1 {
+		t.Fatal(p)
+	}
+	if s, n := Position(gpio3); s != "DEBUG" || n != 4 {
+		t.Fatal(s, n)
+	}
+	expected := []Location{{"DEBUG", 4}, {"J3", 1}}
+	if l := Locations(gpio3); !reflect.DeepEqual(l, expected) {
+		t.Fatal(l)
+	}
+	if s := expected[0].String(); s != "DEBUG_4" {
+		t.Fatal(s)
+	}
+	if l := Locations(pin.GROUND); !reflect.DeepEqual(l, []Location{{"DEBUG", 1}, {"DEBUG", 2}}) {
+		t.Fatal(l)
+	}
+	if l := Locations(pin.V5); l != nil {
+		t.Fatal(l)
+	}
+	if p := ByLocation("DEBUG", 3); p != gpio2 {
+		t.Fatal(p)
+	}
+	if p := ByLocation("DEBUG", 5); p != nil {
+		t.Fatal(p)
+	}
+	if p := ByLocation("FOO", 1); p != nil {
+		t.Fatal(p)
+	}
+}
+
+func TestRegisterHeader_Header(t *testing.T) {
+	h := Header{Pins: [][]pin.Pin{{pin.GROUND, pin.V3_3}, {pin.V5, pin.GROUND}, {pin.V1_8}}}
+	if n := h.Number(1, 1); n != 4 {
+		t.Fatal(n)
+	}
+	if n := h.Number(2, 0); n != 5 {
+		t.Fatal(n)
+	}
+}
+
+func TestRegisterHeader_invalid(t *testing.T) {
+	defer reset()
+	data := []Header{
+		{Pins: [][]pin.Pin{{pin.GROUND}}, Numbers: [][]int{{1}, {2}}},
+		{Pins: [][]pin.Pin{{pin.GROUND}}, Numbers: [][]int{{1, 2}}},
+		{Pins: [][]pin.Pin{{pin.GROUND, pin.V5}}, Numbers: [][]int{{1, 1}}},
+		{Pins: [][]pin.Pin{{pin.GROUND}}, Numbers: [][]int{{0}}},
+		{Pins: [][]pin.Pin{{pin.GROUND}}, Voltage: -1},
+	}
+	for i, h := range data {
+		if err := RegisterHeader("P1", h); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+}
+
 //
 
 func reset() {
 	mu.Lock()
 	defer mu.Unlock()
-	allHeaders = map[string][][]pin.Pin{}
-	byPin = map[string]position{}
+	allHeaders = map[string]Header{}
+	byPin = map[string][]Location{}
 }
 
 type pinAlias struct {
@@ -0,0 +1,157 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package pwm defines PWM controller channels.
+//
+// gpio.PinPWM is a convenient way to generate a PWM signal on a pin but it
+// hides what the hardware supports. A Channel instead has explicit period,
+// duty cycle, polarity and enable states, and exposes the channels that share
+// their period with SharedPeriod.
+//
+// Use FromPin() to use a gpio.PinPWM as a Channel.
+package pwm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Polarity determines the output level during the active part of the period.
+type Polarity int
+
+const (
+	// Normal outputs high during the duty cycle, then low.
+	Normal Polarity = 0
+	// Inversed outputs low during the duty cycle, then high.
+	Inversed Polarity = 1
+)
+
+func (p Polarity) String() string {
+	switch p {
+	case Normal:
+		return "Normal"
+	case Inversed:
+		return "Inversed"
+	default:
+		return fmt.Sprintf("Polarity(%d)", int(p))
+	}
+}
+
+// State is the state of a PWM channel.
+type State struct {
+	// Period is the duration of a PWM cycle. 0 keeps the current period.
+	Period time.Duration
+	// Duty is the active fraction of the period.
+	Duty gpio.Duty
+	// Polarity is the output level of the active part of the period.
+	Polarity Polarity
+	// Enabled is false when the channel is stopped. A stopped channel outputs
+	// its inactive level: low for Normal, high for Inversed.
+	Enabled bool
+}
+
+func (s State) String() string {
+	if !s.Enabled {
+		return fmt.Sprintf("Disabled(%s)", s.Polarity)
+	}
+	return fmt.Sprintf("%s %s %s", s.Period, s.Duty, s.Polarity)
+}
+
+// Validate returns an error if the state is invalid.
+func (s State) Validate() error {
+	if s.Period < 0 {
+		return errors.New("pwm: period must be positive")
+	}
+	if !s.Duty.Valid() {
+		return fmt.Errorf("pwm: invalid duty %d", s.Duty)
+	}
+	if s.Polarity != Normal && s.Polarity != Inversed {
+		return fmt.Errorf("pwm: invalid polarity %d", s.Polarity)
+	}
+	return nil
+}
+
+// Channel is an output of a PWM controller.
+//
+// Halt() disables the channel.
+type Channel interface {
+	fmt.Stringer
+	conn.Resource
+	// Apply changes the channel state.
+	//
+	// The driver returns an error for a state not supported by the hardware,
+	// like an Inversed polarity or an unattainable period, instead of silently
+	// approximating it.
+	Apply(s State) error
+	// State returns the last state applied.
+	State() State
+}
+
+// SharedPeriod is implemented by channels that share their period with other
+// channels of the same controller, like the PCA9685.
+//
+// The duty cycle and the polarity of each channel are independent but the
+// channels start their period at the same time. Changing the period of a
+// channel may fail while another channel is enabled.
+type SharedPeriod interface {
+	Channel
+	// Siblings returns the channels sharing the period, including this one.
+	Siblings() []Channel
+}
+
+// FromPin returns a Channel for a pin that only supports gpio.PinPWM.
+//
+// The Inversed polarity is not supported. Disabling the channel sets a duty
+// cycle of 0%.
+func FromPin(p gpio.PinPWM) Channel {
+	return &pinChannel{p: p}
+}
+
+//
+
+// pinChannel adapts a gpio.PinPWM as a Channel.
+type pinChannel struct {
+	p gpio.PinPWM
+	s State
+}
+
+func (c *pinChannel) String() string {
+	if s, ok := c.p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "PWM"
+}
+
+func (c *pinChannel) Halt() error {
+	return c.Apply(State{Period: c.s.Period})
+}
+
+func (c *pinChannel) Apply(s State) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.Polarity != Normal {
+		return errors.New("pwm: polarity is not supported")
+	}
+	if s.Period == 0 {
+		s.Period = c.s.Period
+	}
+	d := s.Duty
+	if !s.Enabled {
+		d = 0
+	}
+	if err := c.p.PWM(d, s.Period); err != nil {
+		return err
+	}
+	c.s = s
+	return nil
+}
+
+func (c *pinChannel) State() State {
+	return c.s
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pwm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func ExampleChannel() {
+	//c, err := bcm283x.NewPWMChannel(bcm283x.GPIO18)
+	var c Channel
+
+	// Drive a low side MOSFET with an active low gate driver at 25kHz.
+	s := State{Period: 40 * time.Microsecond, Duty: gpio.DutyHalf, Polarity: Inversed, Enabled: true}
+	if err := c.Apply(s); err != nil {
+		fmt.Printf("%s doesn't support %s: %v\n", c, s, err)
+	}
+	if p, ok := c.(SharedPeriod); ok {
+		fmt.Printf("%s shares its period with %d channels\n", c, len(p.Siblings())-1)
+	}
+}
+
+//
+
+func TestPolarity_String(t *testing.T) {
+	if s := Normal.String(); s != "Normal" {
+		t.Fatal(s)
+	}
+	if s := Inversed.String(); s != "Inversed" {
+		t.Fatal(s)
+	}
+	if s := Polarity(2).String(); s != "Polarity(2)" {
+		t.Fatal(s)
+	}
+}
+
+func TestState(t *testing.T) {
+	s := State{Period: time.Millisecond, Duty: gpio.DutyHalf, Enabled: true}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if str := s.String(); str != "1ms 50% Normal" {
+		t.Fatal(str)
+	}
+	if str := (State{Polarity: Inversed}).String(); str != "Disabled(Inversed)" {
+		t.Fatal(str)
+	}
+	for i, s := range []State{{Period: -1}, {Duty: -1}, {Polarity: 2}} {
+		if s.Validate() == nil {
+			t.Fatalf("#%d: expected error", i)
+		}
+	}
+}
+
+func TestFromPin(t *testing.T) {
+	p := &fakePin{}
+	c := FromPin(p)
+	if s := c.String(); s != "fake" {
+		t.Fatal(s)
+	}
+	if err := c.Apply(State{Period: time.Millisecond, Duty: gpio.DutyHalf, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if p.duty != gpio.DutyHalf || p.period != time.Millisecond {
+		t.Fatal(p)
+	}
+	if err := c.Apply(State{Duty: gpio.DutyMax, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.State(); s.Period != time.Millisecond || s.Duty != gpio.DutyMax {
+		t.Fatal(s)
+	}
+	if err := c.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if p.duty != 0 || c.State().Enabled {
+		t.Fatal(p, c.State())
+	}
+	if c.Apply(State{Polarity: Inversed, Enabled: true}) == nil {
+		t.Fatal("polarity is not supported")
+	}
+	if c.Apply(State{Duty: -1}) == nil {
+		t.Fatal("invalid duty")
+	}
+	p.err = errors.New("oops")
+	if c.Apply(State{Enabled: true}) == nil {
+		t.Fatal("PWM failed")
+	}
+	if s := FromPin(&unnamedPin{}).String(); s != "PWM" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+type fakePin struct {
+	unnamedPin
+}
+
+func (f *fakePin) String() string {
+	return "fake"
+}
+
+type unnamedPin struct {
+	duty   gpio.Duty
+	period time.Duration
+	err    error
+}
+
+func (u *unnamedPin) PWM(duty gpio.Duty, period time.Duration) error {
+	if u.err != nil {
+		return u.err
+	}
+	u.duty = duty
+	u.period = period
+	return nil
+}
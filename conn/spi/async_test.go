@@ -0,0 +1,91 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spi
+
+import (
+	"errors"
+	"log"
+	"testing"
+
+	"periph.io/x/periph/conn"
+)
+
+func ExampleAsync() {
+	//p, err := spireg.Open("")
+	//defer p.Close()
+	//c, err := p.Connect(...)
+	var c Conn
+
+	a := NewAsync(c, 8)
+	defer a.Close()
+	// Queue the lines of a display; errors are reported by Flush().
+	line := [128]byte{}
+	for i := 0; i < 64; i++ {
+		a.Submit(line[:], nil, nil)
+	}
+	if err := a.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestAsync(t *testing.T) {
+	c := &fakeAsyncConn{}
+	a := NewAsync(c, 2)
+	done := make(chan error, 2)
+	a.Submit([]byte{1}, nil, done)
+	a.SubmitPackets([]Packet{{W: []byte{2}}}, done)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c.tx != 1 || c.packets != 1 {
+		t.Fatal(c.tx, c.packets)
+	}
+	c.err = errors.New("fail")
+	a.Submit(nil, nil, nil)
+	if err := a.Flush(); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal("error must be reset")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	a.SubmitPackets(nil, done)
+	if err := <-done; err != errClosed {
+		t.Fatal(err)
+	}
+}
+
+//
+
+type fakeAsyncConn struct {
+	tx      int
+	packets int
+	err     error
+}
+
+func (f *fakeAsyncConn) String() string {
+	return "fake"
+}
+
+func (f *fakeAsyncConn) Tx(w, r []byte) error {
+	f.tx++
+	return f.err
+}
+
+func (f *fakeAsyncConn) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeAsyncConn) TxPackets(p []Packet) error {
+	f.packets++
+	return f.err
+}
@@ -6,6 +6,8 @@
 package spi
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"strconv"
 
@@ -113,6 +115,30 @@ type Conn interface {
 	TxPackets(p []Packet) error
 }
 
+// ConnCtx is an optional interface a Conn may implement to support
+// cancellation and deadlines via a context.Context.
+//
+// Use conn.TxCtx() and TxPacketsCtx() to use it when available.
+type ConnCtx interface {
+	conn.ConnCtx
+	// TxPacketsCtx is like Conn.TxPackets but aborts when ctx is done.
+	TxPacketsCtx(ctx context.Context, p []Packet) error
+}
+
+// TxPacketsCtx does multiple operations over c, honoring ctx.
+//
+// When c implements ConnCtx, the call is forwarded to it. Otherwise ctx is only
+// checked before the packets are sent.
+func TxPacketsCtx(ctx context.Context, c Conn, p []Packet) error {
+	if cc, ok := c.(ConnCtx); ok {
+		return cc.TxPacketsCtx(ctx, p)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.TxPackets(p)
+}
+
 // Port is the interface to be provided to device drivers.
 //
 // The device driver, that is the driver for the peripheral connected over
@@ -166,3 +192,177 @@ type Pins interface {
 	// CS returns the CSN (chip select) pin.
 	CS() gpio.PinOut
 }
+
+// TxFunc is the signature of Conn.TxPackets.
+type TxFunc func(p []Packet) error
+
+// Interceptor intercepts the transactions on a connection, like a middleware.
+//
+// A call to Conn.Tx() is presented as a single packet. The interceptor must
+// call next to forward the packets to the connection. It can log or measure
+// the transaction, retry it when next fails, or return an error without
+// calling next to inject a fault.
+type Interceptor func(p []Packet, next TxFunc) error
+
+// Intercept returns a PortCloser passing all the transactions on the
+// connections returned by Connect() through i.
+//
+// The returned port forwards LimitSpeed() and Close() to p. It implements Pins
+// only if p does. Likewise, the connections it returns implement ConnCtx,
+// conn.Limits and Pins only if the connections returned by p do. The port's
+// method Unwrap() PortCloser returns p and the connections' method Unwrap()
+// Conn returns the wrapped connection, to access the other interfaces they
+// implement. Use spireg.Open() to wrap a registered port when it is opened.
+func Intercept(p PortCloser, i Interceptor) PortCloser {
+	ip := &interceptPort{p: p, i: i}
+	if pins, ok := p.(Pins); ok {
+		return &struct {
+			*interceptPort
+			Pins
+		}{ip, pins}
+	}
+	return ip
+}
+
+//
+
+// interceptPort implements PortCloser for Intercept().
+type interceptPort struct {
+	p PortCloser
+	i Interceptor
+}
+
+func (i *interceptPort) String() string {
+	return fmt.Sprintf("%s", i.p)
+}
+
+func (i *interceptPort) Close() error {
+	return i.p.Close()
+}
+
+func (i *interceptPort) LimitSpeed(maxHz int64) error {
+	return i.p.LimitSpeed(maxHz)
+}
+
+func (i *interceptPort) Connect(maxHz int64, mode Mode, bits int) (Conn, error) {
+	c, err := i.p.Connect(maxHz, mode, bits)
+	if err != nil {
+		return nil, err
+	}
+	return interceptConnOf(&interceptConn{c: c, i: i.i}), nil
+}
+
+// Unwrap returns the intercepted port.
+func (i *interceptPort) Unwrap() PortCloser {
+	return i.p
+}
+
+// interceptConnOf returns ic augmented with the optional interfaces
+// implemented by the wrapped connection.
+func interceptConnOf(ic *interceptConn) Conn {
+	l, isLimits := ic.c.(conn.Limits)
+	p, isPins := ic.c.(Pins)
+	if _, ok := ic.c.(ConnCtx); ok {
+		icc := &interceptConnCtx{ic}
+		switch {
+		case isLimits && isPins:
+			return &struct {
+				*interceptConnCtx
+				conn.Limits
+				Pins
+			}{icc, l, p}
+		case isLimits:
+			return &struct {
+				*interceptConnCtx
+				conn.Limits
+			}{icc, l}
+		case isPins:
+			return &struct {
+				*interceptConnCtx
+				Pins
+			}{icc, p}
+		}
+		return icc
+	}
+	switch {
+	case isLimits && isPins:
+		return &struct {
+			*interceptConn
+			conn.Limits
+			Pins
+		}{ic, l, p}
+	case isLimits:
+		return &struct {
+			*interceptConn
+			conn.Limits
+		}{ic, l}
+	case isPins:
+		return &struct {
+			*interceptConn
+			Pins
+		}{ic, p}
+	}
+	return ic
+}
+
+// interceptConn implements Conn for interceptPort.
+type interceptConn struct {
+	c Conn
+	i Interceptor
+}
+
+func (i *interceptConn) String() string {
+	return fmt.Sprintf("%s", i.c)
+}
+
+func (i *interceptConn) Duplex() conn.Duplex {
+	return i.c.Duplex()
+}
+
+func (i *interceptConn) Tx(w, r []byte) error {
+	return i.i([]Packet{{W: w, R: r}}, func(p []Packet) error {
+		if isSimple(p) {
+			return i.c.Tx(p[0].W, p[0].R)
+		}
+		return i.c.TxPackets(p)
+	})
+}
+
+func (i *interceptConn) TxPackets(p []Packet) error {
+	return i.i(p, i.c.TxPackets)
+}
+
+// Unwrap returns the intercepted connection.
+func (i *interceptConn) Unwrap() Conn {
+	return i.c
+}
+
+// interceptConnCtx implements ConnCtx for interceptPort when the connection
+// does.
+type interceptConnCtx struct {
+	*interceptConn
+}
+
+func (i *interceptConnCtx) TxCtx(ctx context.Context, w, r []byte) error {
+	return i.i([]Packet{{W: w, R: r}}, func(p []Packet) error {
+		if isSimple(p) {
+			return conn.TxCtx(ctx, i.c, p[0].W, p[0].R)
+		}
+		return TxPacketsCtx(ctx, i.c, p)
+	})
+}
+
+func (i *interceptConnCtx) TxPacketsCtx(ctx context.Context, p []Packet) error {
+	return i.i(p, func(p []Packet) error {
+		return TxPacketsCtx(ctx, i.c, p)
+	})
+}
+
+// isSimple returns true if p can be sent with Conn.Tx().
+func isSimple(p []Packet) bool {
+	return len(p) == 1 && p[0].BitsPerWord == 0 && !p[0].KeepCS
+}
+
+var _ PortCloser = &interceptPort{}
+var _ Conn = &interceptConn{}
+var _ ConnCtx = &interceptConnCtx{}
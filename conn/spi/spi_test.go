@@ -5,8 +5,14 @@
 package spi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
 )
 
 func ExamplePins() {
@@ -23,6 +29,19 @@ func ExamplePins() {
 	}
 }
 
+func ExampleIntercept() {
+	//p, err := spireg.Open("")
+	//defer p.Close()
+	var p PortCloser
+
+	// Logs all the transactions.
+	p = Intercept(p, func(pkts []Packet, next TxFunc) error {
+		err := next(pkts)
+		log.Printf("TxPackets(%d packets) = %v", len(pkts), err)
+		return err
+	})
+}
+
 //
 
 func TestMode_String(t *testing.T) {
@@ -39,3 +58,269 @@ func TestMode_String(t *testing.T) {
 		t.Fatal(s)
 	}
 }
+
+func TestTxPacketsCtx(t *testing.T) {
+	c := &fakeConn{}
+	p := []Packet{{W: []byte{1}}}
+	if err := TxPacketsCtx(context.Background(), c, p); err != nil || c.packets != 1 {
+		t.Fatal(err, c.packets)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TxPacketsCtx(ctx, c, p); err != context.Canceled || c.packets != 1 {
+		t.Fatal(err, c.packets)
+	}
+	cc := &fakeConnCtx{}
+	if err := TxPacketsCtx(ctx, cc, p); err == nil || err.Error() != "ctx" {
+		t.Fatal(err)
+	}
+	if err := conn.TxCtx(ctx, cc, nil, nil); err == nil || err.Error() != "ctx" {
+		t.Fatal(err)
+	}
+}
+
+func TestIntercept(t *testing.T) {
+	f := &fakePort{c: &fakeConn{}}
+	var calls []int
+	p := Intercept(f, func(pkts []Packet, next TxFunc) error {
+		calls = append(calls, len(pkts))
+		if len(pkts[0].W) == 0 {
+			return errors.New("injected")
+		}
+		return next(pkts)
+	})
+	if s := fmt.Sprintf("%s", p); s != "fakePort" {
+		t.Fatal(s)
+	}
+	if err := p.LimitSpeed(1000); err != nil || f.maxHz != 1000 {
+		t.Fatal(err, f.maxHz)
+	}
+	c, err := p.Connect(1000, Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprintf("%s", c); s != "fake" {
+		t.Fatal(s)
+	}
+	if d := c.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if err := c.Tx([]byte{1}, nil); err != nil || f.c.tx != 1 || f.c.packets != 0 {
+		t.Fatal(err, f.c.tx, f.c.packets)
+	}
+	if err := c.TxPackets([]Packet{{W: []byte{1}}, {W: []byte{2}}}); err != nil || f.c.packets != 1 {
+		t.Fatal(err, f.c.packets)
+	}
+	if err := c.Tx(nil, []byte{0}); err == nil || f.c.tx != 1 {
+		t.Fatal("expected injected error")
+	}
+	if err := conn.TxCtx(context.Background(), c, []byte{1}, nil); err != nil || f.c.tx != 2 {
+		t.Fatal(err, f.c.tx)
+	}
+	if err := TxPacketsCtx(context.Background(), c, []Packet{{W: []byte{1}, KeepCS: true}}); err != nil || f.c.packets != 2 {
+		t.Fatal(err, f.c.packets)
+	}
+	if len(calls) != 5 {
+		t.Fatal(calls)
+	}
+	// Only the optional interfaces implemented by the port and the connection
+	// are exposed.
+	if _, ok := c.(ConnCtx); ok {
+		t.Fatal("fakeConn doesn't implement ConnCtx")
+	}
+	if _, ok := c.(conn.Limits); ok {
+		t.Fatal("fakeConn doesn't implement conn.Limits")
+	}
+	if _, ok := c.(Pins); ok {
+		t.Fatal("fakeConn doesn't implement Pins")
+	}
+	if _, ok := p.(Pins); ok {
+		t.Fatal("fakePort doesn't implement Pins")
+	}
+	if u := c.(interface{ Unwrap() Conn }).Unwrap(); u != f.c {
+		t.Fatal(u)
+	}
+	if u := p.(interface{ Unwrap() PortCloser }).Unwrap(); u != f {
+		t.Fatal(u)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.err = errors.New("connect")
+	if _, err := p.Connect(1000, Mode0, 8); err == nil {
+		t.Fatal("expected Connect error")
+	}
+}
+
+func TestIntercept_optional(t *testing.T) {
+	next := func(pkts []Packet, next TxFunc) error {
+		return next(pkts)
+	}
+	data := []struct {
+		c                    Conn
+		isCtx, isLim, isPins bool
+	}{
+		{&fakeConn{}, false, false, false},
+		{&struct {
+			*fakeConn
+			conn.Limits
+		}{&fakeConn{}, &fakeLimits{}}, false, true, false},
+		{&struct {
+			*fakeConn
+			Pins
+		}{&fakeConn{}, &fakePins{}}, false, false, true},
+		{&struct {
+			*fakeConn
+			conn.Limits
+			Pins
+		}{&fakeConn{}, &fakeLimits{}, &fakePins{}}, false, true, true},
+		{&fakeConnCtx{}, true, false, false},
+		{&struct {
+			*fakeConnCtx
+			conn.Limits
+		}{&fakeConnCtx{}, &fakeLimits{}}, true, true, false},
+		{&struct {
+			*fakeConnCtx
+			Pins
+		}{&fakeConnCtx{}, &fakePins{}}, true, false, true},
+		{&struct {
+			*fakeConnCtx
+			conn.Limits
+			Pins
+		}{&fakeConnCtx{}, &fakeLimits{}, &fakePins{}}, true, true, true},
+	}
+	for i, line := range data {
+		p := Intercept(&struct {
+			*fakeConnPort
+			Pins
+		}{&fakeConnPort{c: line.c}, &fakePins{}}, next)
+		if _, ok := p.(Pins); !ok {
+			t.Fatal("Pins must be exposed")
+		}
+		c, err := p.Connect(1000, Mode0, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.(ConnCtx); ok != line.isCtx {
+			t.Fatal(i, "ConnCtx", ok)
+		}
+		if _, ok := c.(conn.Limits); ok != line.isLim {
+			t.Fatal(i, "conn.Limits", ok)
+		}
+		if _, ok := c.(Pins); ok != line.isPins {
+			t.Fatal(i, "Pins", ok)
+		}
+		if line.isCtx {
+			if err := conn.TxCtx(context.Background(), c, []byte{1}, nil); err == nil || err.Error() != "ctx" {
+				t.Fatal(i, err)
+			}
+		}
+		if line.isLim {
+			if l := c.(conn.Limits).MaxTxSize(); l != 42 {
+				t.Fatal(i, l)
+			}
+		}
+		if line.isPins {
+			if c.(Pins).CLK() != gpio.INVALID {
+				t.Fatal(i, "unexpected pin")
+			}
+		}
+	}
+}
+
+//
+
+type fakePort struct {
+	c     *fakeConn
+	maxHz int64
+	err   error
+}
+
+func (f *fakePort) String() string {
+	return "fakePort"
+}
+
+func (f *fakePort) Close() error {
+	return nil
+}
+
+func (f *fakePort) LimitSpeed(maxHz int64) error {
+	f.maxHz = maxHz
+	return nil
+}
+
+func (f *fakePort) Connect(maxHz int64, mode Mode, bits int) (Conn, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.c, nil
+}
+
+type fakeConn struct {
+	tx      int
+	packets int
+}
+
+func (f *fakeConn) String() string {
+	return "fake"
+}
+
+func (f *fakeConn) Tx(w, r []byte) error {
+	f.tx++
+	return nil
+}
+
+func (f *fakeConn) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeConn) TxPackets(p []Packet) error {
+	f.packets++
+	return nil
+}
+
+type fakeConnCtx struct {
+	fakeConn
+}
+
+func (f *fakeConnCtx) TxCtx(ctx context.Context, w, r []byte) error {
+	return errors.New("ctx")
+}
+
+func (f *fakeConnCtx) TxPacketsCtx(ctx context.Context, p []Packet) error {
+	return errors.New("ctx")
+}
+
+// fakeConnPort is a fakePort returning c on Connect().
+type fakeConnPort struct {
+	fakePort
+	c Conn
+}
+
+func (f *fakeConnPort) Connect(maxHz int64, mode Mode, bits int) (Conn, error) {
+	return f.c, nil
+}
+
+type fakeLimits struct{}
+
+func (f *fakeLimits) MaxTxSize() int {
+	return 42
+}
+
+type fakePins struct{}
+
+func (f *fakePins) CLK() gpio.PinOut {
+	return gpio.INVALID
+}
+
+func (f *fakePins) MOSI() gpio.PinOut {
+	return gpio.INVALID
+}
+
+func (f *fakePins) MISO() gpio.PinIn {
+	return gpio.INVALID
+}
+
+func (f *fakePins) CS() gpio.PinOut {
+	return gpio.INVALID
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/spi"
 )
 
@@ -37,6 +38,8 @@ type Ref struct {
 	//
 	// The port is a bus number plus a CS line.
 	Number int
+	// Info is the metadata provided by the driver, if any.
+	Info conn.Info
 	// Open is the factory to open an handle to this SPI port.
 	Open Opener
 }
@@ -60,7 +63,11 @@ type Ref struct {
 //
 // When the SPI port is provided by an off board plug and play bus like USB via
 // a FT232H USB device, there can be no associated number.
-func Open(name string) (spi.PortCloser, error) {
+//
+// The interceptors, if any, wrap the port in order, the first one being the
+// outermost. They can be used to log, measure, retry or inject faults in the
+// transactions without modifying the device drivers.
+func Open(name string, interceptors ...spi.Interceptor) (spi.PortCloser, error) {
 	var r *Ref
 	var err error
 	func() {
@@ -89,7 +96,14 @@ func Open(name string) (spi.PortCloser, error) {
 	if r == nil {
 		return nil, wrapf("can't open unknown port: %q", name)
 	}
-	return r.Open()
+	p, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		p = spi.Intercept(p, interceptors[i])
+	}
+	return p, nil
 }
 
 // All returns a copy of all the registered references to all know SPI ports
@@ -103,9 +117,7 @@ func All() []*Ref {
 		defer mu.Unlock()
 		out = make(refList, 0, len(byName))
 		for _, v := range byName {
-			r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Open: v.Open}
-			copy(r.Aliases, v.Aliases)
-			out = append(out, r)
+			out = append(out, copyRef(v))
 		}
 	}()
 	sort.Sort(out)
@@ -122,6 +134,12 @@ func All() []*Ref {
 //
 // Only ports with the CS #0 are registered with their number.
 func Register(name string, aliases []string, number int, o Opener) error {
+	return RegisterWithInfo(name, aliases, number, conn.Info{}, o)
+}
+
+// RegisterWithInfo is like Register and also records the metadata of the
+// bus, returned in Ref.Info.
+func RegisterWithInfo(name string, aliases []string, number int, info conn.Info, o Opener) error {
 	if len(name) == 0 {
 		return wrapf("can't register a port with no name")
 	}
@@ -152,37 +170,11 @@ func Register(name string, aliases []string, number int, o Opener) error {
 		}
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if _, ok := byName[name]; ok {
-		return wrapf("can't register port %q twice", name)
-	}
-	if _, ok := byAlias[name]; ok {
-		return wrapf("can't register port %q twice; it is already an alias", name)
-	}
-	if number != -1 {
-		if _, ok := byNumber[number]; ok {
-			return wrapf("can't register port %q; port number %d is already registered", name, number)
-		}
-	}
-	for _, alias := range aliases {
-		if _, ok := byName[alias]; ok {
-			return wrapf("can't register port %q twice; alias %q is already a port", name, alias)
-		}
-		if _, ok := byAlias[alias]; ok {
-			return wrapf("can't register port %q twice; alias %q is already an alias", name, alias)
-		}
-	}
-
-	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Open: o}
-	copy(r.Aliases, aliases)
-	byName[name] = r
-	if number != -1 {
-		byNumber[number] = r
-	}
-	for _, alias := range aliases {
-		byAlias[alias] = r
+	r, err := register(name, aliases, number, info, o)
+	if err != nil {
+		return err
 	}
+	notify(r, true)
 	return nil
 }
 
@@ -192,19 +184,43 @@ func Register(name string, aliases []string, number int, o Opener) error {
 // is unplugged.
 func Unregister(name string) error {
 	mu.Lock()
-	defer mu.Unlock()
 	r := byName[name]
+	if r != nil {
+		delete(byName, name)
+		delete(byNumber, r.Number)
+		for _, alias := range r.Aliases {
+			delete(byAlias, alias)
+		}
+	}
+	mu.Unlock()
 	if r == nil {
 		return wrapf("can't unregister unknown port name %q", name)
 	}
-	delete(byName, name)
-	delete(byNumber, r.Number)
-	for _, alias := range r.Aliases {
-		delete(byAlias, alias)
-	}
+	notify(r, false)
 	return nil
 }
 
+// Notify registers f to be called after a port is registered or unregistered.
+//
+// f is called synchronously, outside of the registry lock. Call the returned
+// function to stop the notifications.
+func Notify(f func(r *Ref, registered bool)) func() {
+	w := &watcher{f: f}
+	mu.Lock()
+	defer mu.Unlock()
+	watchers = append(watchers, w)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range watchers {
+			if watchers[i] == w {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 //
 
 var (
@@ -213,8 +229,69 @@ var (
 	// Caches
 	byNumber = map[int]*Ref{}
 	byAlias  = map[string]*Ref{}
+	watchers []*watcher
 )
 
+type watcher struct {
+	f func(r *Ref, registered bool)
+}
+
+// notify calls the watchers registered with Notify().
+func notify(r *Ref, registered bool) {
+	mu.Lock()
+	w := append([]*watcher(nil), watchers...)
+	mu.Unlock()
+	for _, x := range w {
+		x.f(copyRef(r), registered)
+	}
+}
+
+// copyRef returns a deep copy of r, so the registry can't be modified through
+// it.
+func copyRef(v *Ref) *Ref {
+	r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Info: v.Info, Open: v.Open}
+	r.Info.Features = append([]string(nil), v.Info.Features...)
+	copy(r.Aliases, v.Aliases)
+	return r
+}
+
+// register adds the port to the registry.
+func register(name string, aliases []string, number int, info conn.Info, o Opener) (*Ref, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byName[name]; ok {
+		return nil, wrapf("can't register port %q twice", name)
+	}
+	if _, ok := byAlias[name]; ok {
+		return nil, wrapf("can't register port %q twice; it is already an alias", name)
+	}
+	if number != -1 {
+		if _, ok := byNumber[number]; ok {
+			return nil, wrapf("can't register port %q; port number %d is already registered", name, number)
+		}
+	}
+	for _, alias := range aliases {
+		if _, ok := byName[alias]; ok {
+			return nil, wrapf("can't register port %q twice; alias %q is already a port", name, alias)
+		}
+		if _, ok := byAlias[alias]; ok {
+			return nil, wrapf("can't register port %q twice; alias %q is already an alias", name, alias)
+		}
+	}
+
+	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Info: info, Open: o}
+	r.Info.Features = append([]string(nil), info.Features...)
+	copy(r.Aliases, aliases)
+	byName[name] = r
+	if number != -1 {
+		byNumber[number] = r
+	}
+	for _, alias := range aliases {
+		byAlias[alias] = r
+	}
+	return r, nil
+}
+
 // getDefault returns the Ref that should be used as the default port.
 func getDefault() *Ref {
 	var o *Ref
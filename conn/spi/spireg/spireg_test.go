@@ -94,6 +94,40 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpen_interceptors(t *testing.T) {
+	defer reset()
+	if err := Register("a", nil, 1, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	intercept := func(name string) spi.Interceptor {
+		return func(p []spi.Packet, next spi.TxFunc) error {
+			calls = append(calls, name)
+			return next(p)
+		}
+	}
+	p, err := Open("a", intercept("outer"), intercept("inner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Connect(1000, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Tx([]byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, ","); s != "outer,inner" {
+		t.Fatal(s)
+	}
+	if err := Register("b", nil, 2, func() (spi.PortCloser, error) { return nil, errors.New("fail") }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open("b", intercept("outer")); err == nil {
+		t.Fatal("open failed")
+	}
+}
+
 func TestDefault_NoNumber(t *testing.T) {
 	defer reset()
 	if err := Register("a", nil, -1, getFakePort); err != nil {
@@ -184,6 +218,24 @@ func TestRegister_fail(t *testing.T) {
 	}
 }
 
+func TestRegisterWithInfo(t *testing.T) {
+	defer reset()
+	info := conn.Info{Driver: "fake", Path: "/dev/fake", MaxSpeed: 100, Features: []string{"x"}}
+	if err := RegisterWithInfo("a", nil, -1, info, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	// The registry keeps its own copy.
+	info.Features[0] = "y"
+	a := All()
+	if len(a) != 1 || a[0].Info.Driver != "fake" || a[0].Info.Path != "/dev/fake" || a[0].Info.MaxSpeed != 100 || a[0].Info.Features[0] != "x" {
+		t.Fatal(a)
+	}
+	a[0].Info.Features[0] = "z"
+	if f := All()[0].Info.Features[0]; f != "x" {
+		t.Fatal(f)
+	}
+}
+
 func TestUnregister(t *testing.T) {
 	defer reset()
 	if Unregister("") == nil {
@@ -200,6 +252,45 @@ func TestUnregister(t *testing.T) {
 	}
 }
 
+func TestNotify(t *testing.T) {
+	defer reset()
+	var events []string
+	stop := Notify(func(r *Ref, registered bool) {
+		events = append(events, fmt.Sprintf("%s:%t", r.Name, registered))
+	})
+	if err := Register("a", nil, 1, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("a", nil, 1, getFakePort); err == nil {
+		t.Fatal("registering twice must not notify")
+	}
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if err := Register("b", nil, 2, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(events); s != "[a:true a:false]" {
+		t.Fatal(s)
+	}
+}
+
+func TestNotify_copy(t *testing.T) {
+	defer reset()
+	stop := Notify(func(r *Ref, registered bool) {
+		r.Name = "b"
+		r.Aliases[0] = "y"
+	})
+	defer stop()
+	if err := Register("a", []string{"x"}, 1, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	if a := All(); len(a) != 1 || a[0].Name != "a" || a[0].Aliases[0] != "x" {
+		t.Fatal(a)
+	}
+}
+
 //
 
 func getFakePort() (spi.PortCloser, error) {
@@ -218,7 +309,7 @@ func (f *fakePort) Close() error {
 }
 
 func (f *fakePort) Tx(w, r []byte) error {
-	return errors.New("not implemented")
+	return nil
 }
 
 func (f *fakePort) Duplex() conn.Duplex {
@@ -230,7 +321,7 @@ func (f *fakePort) LimitSpeed(maxHz int64) error {
 }
 
 func (f *fakePort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
-	return f, errors.New("not implemented")
+	return f, nil
 }
 
 func (f *fakePort) TxPackets(p []spi.Packet) error {
@@ -243,4 +334,5 @@ func reset() {
 	byName = map[string]*Ref{}
 	byNumber = map[int]*Ref{}
 	byAlias = map[string]*Ref{}
+	watchers = nil
 }
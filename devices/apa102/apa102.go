@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/spi"
@@ -52,41 +53,54 @@ func ramp(l uint8, max uint16) uint16 {
 	return uint16((y*outRange+(offset*offset))/inRange/inRange + linearCutOff)
 }
 
+// rampGamma converts input from [0, 0xFF] as intensity to [0, max] using a
+// power curve with exponent gamma.
+func rampGamma(l uint8, max uint16, gamma float64) uint16 {
+	return uint16(float64(max)*math.Pow(float64(l)/255, gamma) + 0.5)
+}
+
 // lut is a lookup table that initializes itself on the fly.
 type lut struct {
-	intensity   uint8  // Set an intensity between 0 (off) and 255 (full brightness).
-	temperature uint16 // In Kelvin.
+	intensity   uint8   // Set an intensity between 0 (off) and 255 (full brightness).
+	temperature uint16  // In Kelvin.
+	gamma       float64 // 0 to use ramp().
 	r           [256]uint16
 	g           [256]uint16
 	b           [256]uint16
 }
 
-func (l *lut) init(i uint8, t uint16) {
-	if i != l.intensity || t != l.temperature {
+func (l *lut) init(i uint8, t uint16, gamma float64) {
+	if i != l.intensity || t != l.temperature || gamma != l.gamma {
 		l.intensity = i
 		l.temperature = t
+		l.gamma = gamma
 		tr, tg, tb := toRGBFast(l.temperature)
 		maxR := uint16((uint32(maxOut)*uint32(l.intensity)*uint32(tr) + 127*127) / 65025)
 		maxG := uint16((uint32(maxOut)*uint32(l.intensity)*uint32(tg) + 127*127) / 65025)
 		maxB := uint16((uint32(maxOut)*uint32(l.intensity)*uint32(tb) + 127*127) / 65025)
-		for i := range l.r {
-			l.r[i] = ramp(uint8(i), maxR)
-		}
+		l.fill(&l.r, maxR)
 		if maxG == maxR {
 			copy(l.g[:], l.r[:])
 		} else {
-			for i := range l.g {
-				l.g[i] = ramp(uint8(i), maxG)
-			}
+			l.fill(&l.g, maxG)
 		}
 		if maxB == maxR {
 			copy(l.b[:], l.r[:])
 		} else if maxB == maxG {
 			copy(l.b[:], l.g[:])
 		} else {
-			for i := range l.b {
-				l.b[i] = ramp(uint8(i), maxB)
-			}
+			l.fill(&l.b, maxB)
+		}
+	}
+}
+
+// fill initializes the lookup table of a channel with the current curve.
+func (l *lut) fill(c *[256]uint16, max uint16) {
+	for i := range c {
+		if l.gamma == 0 {
+			c[i] = ramp(uint8(i), max)
+		} else {
+			c[i] = rampGamma(uint8(i), max, l.gamma)
 		}
 	}
 }
@@ -147,59 +161,6 @@ func (l *lut) raster(dst []byte, src []byte) {
 	}
 }
 
-// rasterImg is the generic version of raster.
-func (l *lut) rasterImg(dst []byte, r image.Rectangle, src image.Image, srcR image.Rectangle) {
-	// Render directly into the buffer for maximum performance and to keep
-	// untouched sections intact.
-	deltaX4 := 4 * (r.Min.X - srcR.Min.X)
-	if img, ok := src.(*image.NRGBA); ok {
-		// Fast path for image.NRGBA.
-		pix := img.Pix[srcR.Min.Y*img.Stride:]
-		for sX := srcR.Min.X; sX < srcR.Max.X; sX++ {
-			sX4 := 4 * sX
-			r := l.r[pix[sX4]]
-			g := l.g[pix[sX4+1]]
-			b := l.b[pix[sX4+2]]
-			m := r | g | b
-			rX := sX4 + deltaX4
-			if m <= 1023 {
-				if m <= 255 {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+1), byte(b), byte(g), byte(r)
-				} else if m <= 511 {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+2), byte(b>>1), byte(g>>1), byte(r>>1)
-				} else {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+4), byte((b+2)>>2), byte((g+2)>>2), byte((r+2)>>2)
-				}
-			} else {
-				// In this case we need to use a ramp of 255-1 even for lower colors.
-				dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+31), byte((b+15)/31), byte((g+15)/31), byte((r+15)/31)
-			}
-		}
-	} else {
-		// Generic version.
-		for sX := srcR.Min.X; sX < srcR.Max.X; sX++ {
-			r16, g16, b16, _ := src.At(sX, srcR.Min.Y).RGBA()
-			r := l.r[byte(r16>>8)]
-			g := l.g[byte(g16>>8)]
-			b := l.b[byte(b16>>8)]
-			m := r | g | b
-			rX := sX*4 + deltaX4
-			if m <= 1023 {
-				if m <= 255 {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+1), byte(b), byte(g), byte(r)
-				} else if m <= 511 {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+2), byte(b>>1), byte(g>>1), byte(r>>1)
-				} else {
-					dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+4), byte((b+2)>>2), byte((g+2)>>2), byte((r+2)>>2)
-				}
-			} else {
-				// In this case we need to use a ramp of 255-1 even for lower colors.
-				dst[rX], dst[rX+1], dst[rX+2], dst[rX+3] = byte(0xE0+31), byte((b+15)/31), byte((g+15)/31), byte((r+15)/31)
-			}
-		}
-	}
-}
-
 // ToRGB converts a slice of color.NRGBA to a byte stream of RGB pixels.
 //
 // Ignores alpha.
@@ -215,15 +176,17 @@ func ToRGB(p []color.NRGBA) []byte {
 // It accepts a stream of raw RGB pixels and converts it to the full dynamic
 // range as supported by APA102 protocol (nearly 8000:1 contrast ratio).
 //
-// Includes intensity and temperature correction.
+// Includes intensity, temperature and gamma correction.
 type Dev struct {
 	Intensity   uint8    // Set an intensity between 0 (off) and 255 (full brightness).
 	Temperature uint16   // In Kelvin.
+	Gamma       float64  // Exponent of the power curve; 0 uses the default perceptual lightness curve.
 	s           spi.Conn //
 	l           lut      // Updated at each .Write() call.
 	numPixels   int      //
 	rawBuf      []byte   // Raw buffer sent over SPI. Cached to reduce heap fragmentation.
 	pixels      []byte   // Double buffer of pixels, to enable partial painting via Draw(). Effectively points inside rawBuf.
+	rgb         []byte   // RGB pixels last drawn, to apply a new correction without redrawing.
 }
 
 func (d *Dev) String() string {
@@ -255,8 +218,11 @@ func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
 	if dY := r.Dy(); dY < srcR.Dy() {
 		srcR.Max.Y = srcR.Min.Y + dY
 	}
-	d.l.init(d.Intensity, d.Temperature)
-	d.l.rasterImg(d.pixels, r, src, srcR)
+	d.copyImg(r, src, srcR)
+	d.l.init(d.Intensity, d.Temperature, d.Gamma)
+	// Only convert the pixels drawn.
+	x := r.Min.X
+	d.l.raster(d.pixels[4*x:], d.rgb[3*x:3*(x+srcR.Dx())])
 	_ = d.s.Tx(d.rawBuf, nil)
 }
 
@@ -266,13 +232,44 @@ func (d *Dev) Write(pixels []byte) (int, error) {
 	if len(pixels)%3 != 0 || len(pixels) > len(d.pixels) {
 		return 0, errors.New("apa102: invalid RGB stream length")
 	}
-	d.l.init(d.Intensity, d.Temperature)
+	copy(d.rgb, pixels)
+	d.l.init(d.Intensity, d.Temperature, d.Gamma)
 	// Do not touch header and footer.
 	d.l.raster(d.pixels, pixels)
 	err := d.s.Tx(d.rawBuf, nil)
 	return len(pixels), err
 }
 
+// SetIntensity changes the intensity between 0 (off) and 255 (full
+// brightness) and updates the LEDs with the pixels last drawn.
+//
+// Use it for smooth dimming.
+func (d *Dev) SetIntensity(i uint8) error {
+	d.Intensity = i
+	return d.refresh()
+}
+
+// SetTemperature changes the color temperature in Kelvin and updates the LEDs
+// with the pixels last drawn.
+func (d *Dev) SetTemperature(t uint16) error {
+	d.Temperature = t
+	return d.refresh()
+}
+
+// SetGamma changes the gamma curve and updates the LEDs with the pixels last
+// drawn.
+//
+// Each channel is corrected with out = in^gamma, where a value of about 2.2
+// to 2.8 is perceptually linear. 0 uses the default curve, which is based on
+// the CIE lightness and keeps more resolution in the dark values.
+func (d *Dev) SetGamma(gamma float64) error {
+	if !(gamma >= 0) || math.IsInf(gamma, 1) {
+		return fmt.Errorf("apa102: invalid gamma %g", gamma)
+	}
+	d.Gamma = gamma
+	return d.refresh()
+}
+
 // Halt turns off all the lights.
 func (d *Dev) Halt() error {
 	_, err := d.Write(make([]byte, d.numPixels*3))
@@ -310,11 +307,43 @@ func New(p spi.Port, numPixels int, intensity uint8, temperature uint16) (*Dev,
 		numPixels:   numPixels,
 		rawBuf:      buf,
 		pixels:      buf[4 : 4+4*numPixels],
+		rgb:         make([]byte, 3*numPixels),
 	}, nil
 }
 
 //
 
+// copyImg copies the pixels of src in srcR to the RGB buffer at r.
+//
+// Using something else than image.NRGBA is 10x slower.
+func (d *Dev) copyImg(r image.Rectangle, src image.Image, srcR image.Rectangle) {
+	deltaX3 := 3 * (r.Min.X - srcR.Min.X)
+	if img, ok := src.(*image.NRGBA); ok {
+		// Fast path for image.NRGBA.
+		pix := img.Pix[srcR.Min.Y*img.Stride:]
+		for sX := srcR.Min.X; sX < srcR.Max.X; sX++ {
+			sX4 := 4 * sX
+			rX := 3*sX + deltaX3
+			d.rgb[rX], d.rgb[rX+1], d.rgb[rX+2] = pix[sX4], pix[sX4+1], pix[sX4+2]
+		}
+		return
+	}
+	// Generic version.
+	for sX := srcR.Min.X; sX < srcR.Max.X; sX++ {
+		r16, g16, b16, _ := src.At(sX, srcR.Min.Y).RGBA()
+		rX := 3*sX + deltaX3
+		d.rgb[rX], d.rgb[rX+1], d.rgb[rX+2] = byte(r16>>8), byte(g16>>8), byte(b16>>8)
+	}
+}
+
+// refresh converts the pixels last drawn with the current correction and
+// sends them.
+func (d *Dev) refresh() error {
+	d.l.init(d.Intensity, d.Temperature, d.Gamma)
+	d.l.raster(d.pixels, d.rgb)
+	return d.s.Tx(d.rawBuf, nil)
+}
+
 var _ conn.Resource = &Dev{}
 var _ devices.Display = &Dev{}
 var _ fmt.Stringer = &Dev{}
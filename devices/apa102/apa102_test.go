@@ -12,6 +12,7 @@ import (
 	"image/color"
 	"io/ioutil"
 	"log"
+	"math"
 	"testing"
 
 	"periph.io/x/periph/conn/conntest"
@@ -568,10 +569,110 @@ func TestHalt(t *testing.T) {
 	}
 }
 
+func TestSetIntensity(t *testing.T) {
+	colors := []color.NRGBA{
+		{0xFF, 0xFF, 0xFF, 0x00},
+		{0x80, 0x00, 0x00, 0x00},
+		{0x00, 0x00, 0x01, 0x00},
+	}
+	buf := bytes.Buffer{}
+	d, _ := New(spitest.NewRecordRaw(&buf), len(colors), 255, 6500)
+	if _, err := d.Write(ToRGB(colors)); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	// The last pixels are sent again with the new intensity.
+	if err := d.SetIntensity(127); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0xFF, 0x7F, 0x7F, 0x7F,
+		0xE2, 0x00, 0x00, 0x9B,
+		0xE1, 0x01, 0x00, 0x00,
+		0xFF,
+	}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("%#v != %#v", expected, buf.Bytes())
+	}
+	if d.Intensity != 127 {
+		t.Fatal(d.Intensity)
+	}
+}
+
+func TestSetTemperature(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 1))
+	for i := 0; i < 16; i++ {
+		img.Pix[4*i] = uint8((3 * i) << 2)
+		img.Pix[4*i+1] = uint8((3*i + 1) << 2)
+		img.Pix[4*i+2] = uint8((3*i + 2) << 2)
+		img.Pix[4*i+3] = 0xFF
+	}
+	buf := bytes.Buffer{}
+	d, _ := New(spitest.NewRecordRaw(&buf), 16, 250, 6500)
+	d.Draw(d.Bounds(), img, image.Point{})
+	buf.Reset()
+	if err := d.SetTemperature(5000); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expectedi250t5000, buf.Bytes()) {
+		t.Fatalf("%#v != %#v", expectedi250t5000, buf.Bytes())
+	}
+}
+
+func TestSetGamma(t *testing.T) {
+	colors := []color.NRGBA{
+		{0xFF, 0xFF, 0xFF, 0x00},
+		{0x80, 0x00, 0x00, 0x00},
+		{0x00, 0x00, 0x00, 0x00},
+	}
+	buf := bytes.Buffer{}
+	d, _ := New(spitest.NewRecordRaw(&buf), len(colors), 255, 6500)
+	if _, err := d.Write(ToRGB(colors)); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	// Linear.
+	if err := d.SetGamma(1); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0xFF, 0x00, 0x00, 0x80,
+		0xE1, 0x00, 0x00, 0x00,
+		0xFF,
+	}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("%#v != %#v", expected, buf.Bytes())
+	}
+	for _, g := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if err := d.SetGamma(g); err == nil {
+			t.Fatalf("%g: invalid gamma", g)
+		}
+	}
+	if d.Gamma != 1 {
+		t.Fatal(d.Gamma)
+	}
+}
+
+func TestRampGamma(t *testing.T) {
+	if v := rampGamma(0, maxOut, 2.2); v != 0 {
+		t.Fatal(v)
+	}
+	if v := rampGamma(255, maxOut, 2.2); v != maxOut {
+		t.Fatal(v)
+	}
+	// 0.5^2 = 0.25
+	if v := rampGamma(0x80, 1000, 2); v != 252 {
+		t.Fatal(v)
+	}
+}
+
 func TestInit(t *testing.T) {
 	// Catch the "maxB == maxG" line.
 	l := lut{}
-	l.init(255, 6000)
+	l.init(255, 6000, 0)
 	if equalUint16(l.r[:], l.g[:]) || !equalUint16(l.g[:], l.b[:]) {
 		t.Fatal("test case is for only when maxG == maxB but maxR != maxG")
 	}
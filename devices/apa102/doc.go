@@ -8,8 +8,9 @@
 // of resolution and one per channel of 8 bits of resolution. This means that
 // the dynamic range is of 13 bits.
 //
-// This driver handles color intensity and temperature correction and uses the
-// full near 8000:1 dynamic range as supported by the device.
+// This driver handles color intensity, temperature and gamma correction and
+// uses the full near 8000:1 dynamic range as supported by the device. They can
+// be changed at runtime without redrawing the pixels.
 //
 // Datasheet
 //
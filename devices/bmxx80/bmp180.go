@@ -40,6 +40,7 @@ func (d *Dev) sense180(env *devices.Environment) error {
 	pressure := d.cal180.compensatePressure(up, int32(rawTemp), uint(d.os))
 	env.Temperature = devices.Celsius(temp * 100)
 	env.Pressure = devices.KPascal(pressure)
+	env.Time = time.Now()
 	return nil
 }
 
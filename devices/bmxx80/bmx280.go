@@ -45,6 +45,7 @@ func (d *Dev) sense280(env *devices.Environment) error {
 		h := d.cal280.compensateHumidityInt(hRaw, tFine)
 		env.Humidity = devices.RelativeHumidity((int32(h)*100 + 511) / 1024)
 	}
+	env.Time = time.Now()
 	return nil
 }
 
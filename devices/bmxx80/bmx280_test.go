@@ -109,6 +109,88 @@ func TestSPISenseBME280_success(t *testing.T) {
 	}
 }
 
+func TestSPI3WireSenseBME280_success(t *testing.T) {
+	s := spi3wPlayback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				// Enable 3-wire SPI.
+				{W: []byte{0x75, 0x01}},
+				// Chip ID detection.
+				{W: []byte{0xD0}, R: []byte{0x60}},
+				// Calibration data.
+				{
+					W: []byte{0x88},
+					R: []byte{0xC9, 0x6C, 0x63, 0x65, 0x32, 0x00, 0x77, 0x93, 0x98, 0xD5, 0xD0, 0x0B, 0x67, 0x23, 0xBA, 0x00, 0xF9, 0xFF, 0xAC, 0x26, 0x0A, 0xD8, 0xBD, 0x10, 0x00, 0x4B},
+				},
+				// Calibration data humidity.
+				{W: []byte{0xE1}, R: []byte{0x5C, 0x01, 0x00, 0x15, 0x0F, 0x00, 0x1E}},
+				// Config; spi3w_en is kept set.
+				{W: []byte{0x74, 0xB4, 0x72, 0x05, 0x75, 0xA1, 0x74, 0xB4}},
+				// Forced mode.
+				{W: []byte{0x74, 0xB5}},
+				// Check if idle.
+				{W: []byte{0xF3}, R: []byte{0}},
+				// Read measurement data.
+				{W: []byte{0xF7}, R: []byte{0x51, 0x9F, 0xC0, 0x9E, 0x3A, 0x50, 0x5E, 0x5B}},
+				// Halt.
+				{W: []byte{0x75, 0xA1, 0x74, 0xB4}},
+			},
+		},
+	}
+	opts := Opts{
+		Temperature: O16x,
+		Pressure:    O16x,
+		Humidity:    O16x,
+	}
+	dev, err := NewSPI3Wire(&s, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "BME280{playback}" {
+		t.Fatal(s)
+	}
+	if m := dev.Metrics(); m != devices.MetricTemperature|devices.MetricPressure|devices.MetricHumidity {
+		t.Fatal(m)
+	}
+	env := devices.Environment{}
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	// Same data as in TestSPISenseBME280_success.
+	if env.Temperature != 62680 || env.Pressure != 99576 || env.Humidity != 995 {
+		t.Fatal(env)
+	}
+	// Halt only writes when sensing continuously; force it.
+	dev.mu.Lock()
+	dev.stop = make(chan struct{})
+	dev.mu.Unlock()
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI3WireBME280_fail(t *testing.T) {
+	if dev, err := NewSPI3Wire(&spiFail{}, nil); dev != nil || err == nil {
+		t.Fatal("Connect failed")
+	}
+	s := spi3wPlayback{Playback: conntest.Playback{DontPanic: true}}
+	if dev, err := NewSPI3Wire(&s, nil); dev != nil || err == nil {
+		t.Fatal("write failed")
+	}
+	s = spi3wPlayback{
+		Playback: conntest.Playback{
+			Ops:       []conntest.IO{{W: []byte{0x75, 0x01}}},
+			DontPanic: true,
+		},
+	}
+	if dev, err := NewSPI3Wire(&s, nil); dev != nil || err == nil {
+		t.Fatal("read failed")
+	}
+}
+
 func TestNewSPIBME280_fail_Connect(t *testing.T) {
 	if dev, err := NewSPI(&spiFail{}, nil); dev != nil || err == nil {
 		t.Fatal("read failed")
@@ -329,6 +411,9 @@ func TestI2CSenseBMP280_success(t *testing.T) {
 	if s := dev.String(); s != "BMP280{playback(118)}" {
 		t.Fatal(s)
 	}
+	if m := devices.MetricsOf(dev); m != devices.MetricTemperature|devices.MetricPressure {
+		t.Fatal(m)
+	}
 	env := devices.Environment{}
 	if err := dev.Sense(&env); err != nil {
 		t.Fatal(err)
@@ -489,11 +574,11 @@ func TestI2CSenseContinuous280_success(t *testing.T) {
 			// Configuration.
 			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xf2, 0x3, 0xf5, 0xa0, 0xf4, 0x6c}, R: nil},
 			// Normal mode.
-			{Addr: 0x76, W: []byte{0xF5, 0xa0, 0xf4, 0x6f}},
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xF5, 0xa0, 0xf4, 0x6f}},
 			// Read.
 			{Addr: 0x76, W: []byte{0xf7}, R: []byte{0x4a, 0x52, 0xc0, 0x80, 0x96, 0xc0, 0x7a, 0x76}},
 			// Normal mode.
-			{Addr: 0x76, W: []byte{0xF5, 0, 0xf4, 0x6f}},
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xF5, 0, 0xf4, 0x6f}},
 			// Read.
 			{Addr: 0x76, W: []byte{0xf7}, R: []byte{0x4a, 0x52, 0xc0, 0x80, 0x96, 0xc0, 0x7a, 0x76}},
 			// Read.
@@ -631,7 +716,7 @@ func TestI2CSenseContinuous280_sense_fail(t *testing.T) {
 			// Configuration.
 			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xf2, 0x3, 0xf5, 0xa0, 0xf4, 0x6c}, R: nil},
 			// Normal mode.
-			{Addr: 0x76, W: []byte{0xF5, 0xa0, 0xf4, 0x6f}},
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xF5, 0xa0, 0xf4, 0x6f}},
 			// Read fail.
 		},
 		DontPanic: true,
@@ -654,6 +739,96 @@ func TestI2CSenseContinuous280_sense_fail(t *testing.T) {
 	}
 }
 
+func TestI2CSenseStream280_restart(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			// Chip ID detection.
+			{Addr: 0x76, W: []byte{0xd0}, R: []byte{0x60}},
+			// Calibration data.
+			{
+				Addr: 0x76,
+				W:    []byte{0x88},
+				R:    []byte{0x10, 0x6e, 0x6c, 0x66, 0x32, 0x0, 0x5d, 0x95, 0xb8, 0xd5, 0xd0, 0xb, 0x77, 0x1e, 0x9d, 0xff, 0xf9, 0xff, 0xac, 0x26, 0xa, 0xd8, 0xbd, 0x10, 0x0, 0x4b},
+			},
+			// Calibration data humidity.
+			{Addr: 0x76, W: []byte{0xe1}, R: []byte{0x6e, 0x1, 0x0, 0x13, 0x5, 0x0, 0x1e}},
+			// Configuration.
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xf2, 0x3, 0xf5, 0xa0, 0xf4, 0x6c}, R: nil},
+			// Normal mode, 125ms standby.
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xF5, 0x40, 0xf4, 0x6f}},
+			// Read.
+			{Addr: 0x76, W: []byte{0xf7}, R: []byte{0x4a, 0x52, 0xc0, 0x80, 0x96, 0xc0, 0x7a, 0x76}},
+			// Sleep mode.
+			{Addr: 0x76, W: []byte{0xF5, 0xa0, 0xf4, 0x6c}},
+			// Normal mode again, without reading the calibration data.
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xF5, 0x40, 0xf4, 0x6f}},
+			// Read.
+			{Addr: 0x76, W: []byte{0xf7}, R: []byte{0x4a, 0x52, 0xc0, 0x80, 0x96, 0xc0, 0x7a, 0x76}},
+			// Sleep mode.
+			{Addr: 0x76, W: []byte{0xF5, 0xa0, 0xf4, 0x6c}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x76, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last time.Time
+	for i := 0; i < 2; i++ {
+		c, err := dev.SenseStream(200 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r Reading
+		select {
+		case r = <-c:
+		case <-time.After(2 * time.Second):
+			t.Fatal("failed")
+		}
+		if r.Temperature != 23720 || r.Pressure != 100943 || r.Humidity != 6531 {
+			t.Fatalf("#%d: %v", i, r.Environment)
+		}
+		if r.Time.Before(last) || r.Time.IsZero() {
+			t.Fatalf("#%d: %s", i, r.Time)
+		}
+		last = r.Time
+		if err := dev.Halt(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := <-c; ok {
+			t.Fatal("c should be closed")
+		}
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestI2CSenseStream280_command_fail(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			// Chip ID detection.
+			{Addr: 0x76, W: []byte{0xd0}, R: []byte{0x58}},
+			// Calibration data.
+			{
+				Addr: 0x76,
+				W:    []byte{0x88},
+				R:    []byte{0x10, 0x6e, 0x6c, 0x66, 0x32, 0x0, 0x5d, 0x95, 0xb8, 0xd5, 0xd0, 0xb, 0x77, 0x1e, 0x9d, 0xff, 0xf9, 0xff, 0xac, 0x26, 0xa, 0xd8, 0xbd, 0x10, 0x0, 0x4b},
+			},
+			// Configuration.
+			{Addr: 0x76, W: []byte{0xf4, 0x6c, 0xf5, 0xa0, 0xf4, 0x6c}, R: nil},
+			// Normal mode fails.
+		},
+		DontPanic: true,
+	}
+	dev, err := NewI2C(&bus, 0x76, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseStream(time.Minute); err == nil {
+		t.Fatal("send command should have failed")
+	}
+}
+
 func TestCalibration280Float(t *testing.T) {
 	// Real data extracted from measurements from this device.
 	tRaw := int32(524112)
@@ -915,6 +1090,32 @@ func (c *calibration280) compensateHumidityFloat(raw, tFine int32) float32 {
 	return float32(h)
 }
 
+// spi3wPlayback is a half duplex spi.Port.
+type spi3wPlayback struct {
+	conntest.Playback
+}
+
+func (s *spi3wPlayback) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	if mode&spi.HalfDuplex == 0 {
+		return nil, errors.New("expected half duplex")
+	}
+	return s, nil
+}
+
+func (s *spi3wPlayback) Tx(w, r []byte) error {
+	if len(w) != 0 && len(r) != 0 {
+		return errors.New("can only specify one of w or r when in half duplex")
+	}
+	return s.Playback.Tx(w, r)
+}
+
+func (s *spi3wPlayback) TxPackets(p []spi.Packet) error {
+	if len(p) != 2 || len(p[0].R) != 0 || len(p[1].W) != 0 || !p[0].KeepCS {
+		return fmt.Errorf("unexpected packets %v", p)
+	}
+	return s.Playback.Tx(p[0].W, p[1].R)
+}
+
 type spiFail struct {
 	spitest.Playback
 }
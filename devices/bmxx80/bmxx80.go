@@ -3,7 +3,7 @@
 // that can be found in the LICENSE file.
 
 // Package bmxx80 controls a Bosch BMP180/BME280/BMP280 device over I²C, or SPI
-// for the BMx280.
+// for the BMx280, either 4-wire or 3-wire.
 //
 // BMx280
 //
@@ -130,6 +130,7 @@ const (
 type Dev struct {
 	d         conn.Conn
 	isSPI     bool
+	spi3w     spi.Conn // Set in 3-wire SPI mode.
 	is280     bool
 	isBME     bool
 	opts      Opts
@@ -189,39 +190,83 @@ func (d *Dev) Sense(env *devices.Environment) error {
 func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if d.stop != nil {
-		// Don't send the stop command to the device.
-		close(d.stop)
-		d.stop = nil
-		d.wg.Wait()
+	if err := d.startSensing(interval); err != nil {
+		return nil, err
 	}
-
-	if d.is280 {
-		s := chooseStandby(d.isBME, interval-d.measDelay)
-		err := d.writeCommands([]byte{
-			// config
-			0xF5, byte(s)<<5 | byte(d.opts.Filter)<<2,
-			// ctrl_meas
-			0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(normal),
+	sensing := make(chan devices.Environment)
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, stop, func(r Reading) bool {
+			select {
+			case sensing <- r.Environment:
+				return true
+			case <-stop:
+				return false
+			}
 		})
-		if err != nil {
-			return nil, d.wrap(err)
-		}
-	}
+	}(d.stop)
+	return sensing, nil
+}
 
-	sensing := make(chan devices.Environment)
-	d.stop = make(chan struct{})
+// Reading is a measurement.
+//
+// Environment.Time is the time it was retrieved from the device.
+type Reading struct {
+	devices.Environment
+}
+
+// SenseStream is like SenseContinuous() but returns Reading values. Since
+// Environment.Time is the time of each measurement, SenseContinuous() is
+// equivalent.
+//
+// On BMx280, the device measures autonomously in normal mode, with a standby
+// period chosen so that a new measurement is available at each interval.
+//
+// Halt() stops the sensing and closes the channel. The sensing can be
+// restarted afterward without re-initializing the device.
+func (d *Dev) SenseStream(interval time.Duration) (<-chan Reading, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.startSensing(interval); err != nil {
+		return nil, err
+	}
+	sensing := make(chan Reading)
 	d.wg.Add(1)
-	go func() {
+	go func(stop <-chan struct{}) {
 		defer d.wg.Done()
 		defer close(sensing)
-		d.sensingContinuous(interval, sensing, d.stop)
-	}()
+		d.sensingContinuous(interval, stop, func(r Reading) bool {
+			select {
+			case sensing <- r:
+				return true
+			case <-stop:
+				return false
+			}
+		})
+	}(d.stop)
 	return sensing, nil
 }
 
+// Metrics implements devices.EnvironmentalMetrics.
+//
+// Humidity is only measured by the BME280.
+func (d *Dev) Metrics() devices.Metric {
+	m := devices.MetricTemperature
+	if !d.is280 || d.opts.Pressure != Off {
+		m |= devices.MetricPressure
+	}
+	if d.isBME && d.opts.Humidity != Off {
+		m |= devices.MetricHumidity
+	}
+	return m
+}
+
 // Halt stops the BMxx80 from acquiring measurements as initiated by
-// SenseContinuous().
+// SenseContinuous() or SenseStream().
+//
+// The calibration data is kept, so the sensing can be restarted right away.
 //
 // It is recommended to call this function before terminating the process to
 // reduce idle power usage and a goroutine leak.
@@ -239,7 +284,7 @@ func (d *Dev) Halt() error {
 		// Page 27 (for register) and 12~13 section 3.3.
 		return d.writeCommands([]byte{
 			// config
-			0xF5, byte(s1s)<<5 | byte(NoFilter)<<2,
+			0xF5, d.config(s1s, NoFilter),
 			// ctrl_meas
 			0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(sleep),
 		})
@@ -340,8 +385,37 @@ func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
 	return d, nil
 }
 
+// NewSPI3Wire returns an object that communicates over 3-wire SPI to either a
+// BME280 or BMP280 environmental sensor.
+//
+// In 3-wire mode, SDI is used for both input and output and SDO is not
+// connected. The port must support half duplex. The device is switched to
+// 3-wire mode by setting spi3w_en first thing, since writes work in both
+// modes.
+//
+// It is recommended to call Halt() when done with the device so it stops
+// sampling.
+func NewSPI3Wire(p spi.Port, opts *Opts) (*Dev, error) {
+	c, err := p.Connect(10000000, spi.Mode3|spi.HalfDuplex, 8)
+	if err != nil {
+		return nil, fmt.Errorf("bmxx80: %v", err)
+	}
+	d := &Dev{d: c, isSPI: true, spi3w: c}
+	// Page 29; the rest of config is written as part of the initialization.
+	if err := d.writeCommands([]byte{0xF5, spi3wEn}); err != nil {
+		return nil, err
+	}
+	if err := d.makeDev(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 //
 
+// spi3wEn is the bit in the config register that enables 3-wire SPI.
+const spi3wEn = 1
+
 func (d *Dev) makeDev(opts *Opts) error {
 	if opts == nil {
 		opts = &defaults
@@ -403,7 +477,7 @@ func (d *Dev) makeDev(opts *Opts) error {
 				// ctrl_hum
 				0xF2, byte(d.opts.Humidity),
 				// config
-				0xF5, byte(s1s)<<5 | byte(NoFilter)<<2,
+				0xF5, d.config(s1s, NoFilter),
 				// As per page 25, ctrl_meas must be re-written last.
 				0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(sleep),
 			}
@@ -415,7 +489,7 @@ func (d *Dev) makeDev(opts *Opts) error {
 				// into normal but was not Halt'ed.
 				0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(sleep),
 				// config
-				0xF5, byte(s1s)<<5 | byte(NoFilter)<<2,
+				0xF5, d.config(s1s, NoFilter),
 				// As per page 25, ctrl_meas must be re-written last.
 				0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(sleep),
 			}
@@ -436,28 +510,68 @@ func (d *Dev) makeDev(opts *Opts) error {
 	return nil
 }
 
-func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+// startSensing stops the current sensing, if any, and configures the device
+// for continuous sensing.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) startSensing(interval time.Duration) error {
+	if d.stop != nil {
+		// Don't send the stop command to the device.
+		close(d.stop)
+		d.stop = nil
+		d.wg.Wait()
+	}
+	if d.is280 {
+		// The cycle is the measurement followed by the standby period.
+		s := chooseStandby(d.isBME, interval-d.measDelay)
+		err := d.writeCommands([]byte{
+			// ctrl_meas; the config update may be ignored in normal mode, which
+			// happens when the sensing is restarted.
+			0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(sleep),
+			// config
+			0xF5, d.config(s, d.opts.Filter),
+			// ctrl_meas
+			0xF4, byte(d.opts.Temperature)<<5 | byte(d.opts.Pressure)<<2 | byte(normal),
+		})
+		if err != nil {
+			return d.wrap(err)
+		}
+	}
+	d.stop = make(chan struct{})
+	return nil
+}
+
+// sensingContinuous reads the measurements at each interval and calls send
+// with each of them, until send returns false.
+func (d *Dev) sensingContinuous(interval time.Duration, stop <-chan struct{}, send func(r Reading) bool) {
+	if d.is280 {
+		// Wait for the first measurement in normal mode to complete, otherwise
+		// the values of the previous sensing would be returned.
+		select {
+		case <-stop:
+			return
+		case <-time.After(d.measDelay):
+		}
+	}
 	t := time.NewTicker(interval)
 	defer t.Stop()
 
 	var err error
 	for {
 		// Do one initial sensing right away.
-		var e devices.Environment
+		var r Reading
 		d.mu.Lock()
 		if d.is280 {
-			err = d.sense280(&e)
+			err = d.sense280(&r.Environment)
 		} else {
-			err = d.sense180(&e)
+			err = d.sense180(&r.Environment)
 		}
 		d.mu.Unlock()
 		if err != nil {
 			log.Printf("%s: failed to sense: %v", d, err)
 			return
 		}
-		select {
-		case sensing <- e:
-		case <-stop:
+		if !send(r) {
 			return
 		}
 		select {
@@ -468,8 +582,27 @@ func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.E
 	}
 }
 
+// config returns the value of the config register, keeping 3-wire SPI
+// enabled if used.
+func (d *Dev) config(s standby, f Filter) byte {
+	c := byte(s)<<5 | byte(f)<<2
+	if d.spi3w != nil {
+		c |= spi3wEn
+	}
+	return c
+}
+
 func (d *Dev) readReg(reg uint8, b []byte) error {
 	// Page 32-33
+	if d.spi3w != nil {
+		// In half duplex, the register address is written then the data is read
+		// on the same line, while keeping CS asserted.
+		p := []spi.Packet{{W: []byte{reg}, KeepCS: true}, {R: b}}
+		if err := d.spi3w.TxPackets(p); err != nil {
+			return d.wrap(err)
+		}
+		return nil
+	}
 	if d.isSPI {
 		// MSB is 0 for write and 1 for read.
 		read := make([]byte, len(b)+1)
@@ -515,5 +648,5 @@ var defaults = Opts{
 }
 
 var _ conn.Resource = &Dev{}
-var _ devices.Environmental = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
 var _ fmt.Stringer = &Dev{}
@@ -5,9 +5,11 @@
 package devices
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -48,11 +50,118 @@ type Display interface {
 	Draw(r image.Rectangle, src image.Image, sp image.Point)
 }
 
+// TextDisplay represents a character based output device, like a character
+// LCD. It is a write-only interface.
+type TextDisplay interface {
+	Device
+
+	// Writer writes text at the current cursor position. Characters past the
+	// end of a row wrap to the next row.
+	io.Writer
+	// Cols returns the number of characters per row.
+	Cols() int
+	// Rows returns the number of rows.
+	Rows() int
+	// Clear clears the display and moves the cursor to the top left corner.
+	Clear() error
+	// MoveTo moves the cursor to the specified column and row, starting at 0.
+	MoveTo(col, row int) error
+}
+
+// AnalogOutput represents a voltage output, generally driven by a
+// digital-to-analog converter.
+type AnalogOutput interface {
+	Device
+
+	// Range returns the minimum and maximum voltage that can be output.
+	Range() (Volt, Volt)
+	// Out sets the output to the supported level closest to v.
+	//
+	// Halt() powers the output down; the next call to Out() powers it up again.
+	Out(v Volt) error
+}
+
 // Environment represents measurements from an environmental sensor.
 type Environment struct {
 	Temperature Celsius
 	Pressure    KPascal
 	Humidity    RelativeHumidity
+	Light       Lux
+	// Gas is the resistance of a metal oxide gas sensor, which decreases with
+	// the concentration of volatile organic compounds.
+	Gas Ohm
+
+	// Time is when the measurement was captured. It is the zero value if the
+	// driver doesn't record it.
+	Time time.Time
+	// Quality flags measurements that may not be reliable.
+	Quality Quality
+}
+
+// Quality is a bitmask of flags about the validity of an Environment.
+//
+// The zero value means the measurement is believed to be valid.
+type Quality uint8
+
+// Quality flags.
+const (
+	// QualityStale means the sensor didn't refresh the measurement since it was
+	// last read.
+	QualityStale Quality = 1 << iota
+	// QualityCorrected means the data failed an integrity check, like a CRC,
+	// and was recovered by reading it again.
+	QualityCorrected
+	// QualitySaturated means at least one measurement is at the limit of the
+	// range of the sensor, so the actual value may be beyond it.
+	QualitySaturated
+)
+
+var qualityNames = [...]string{"Stale", "Corrected", "Saturated"}
+
+func (q Quality) String() string {
+	if q == 0 {
+		return "Valid"
+	}
+	var out []string
+	for i, n := range qualityNames {
+		if q&(1<<uint(i)) != 0 {
+			out = append(out, n)
+		}
+	}
+	if r := q &^ (1<<uint(len(qualityNames)) - 1); r != 0 {
+		out = append(out, fmt.Sprintf("0x%x", uint8(r)))
+	}
+	return strings.Join(out, "|")
+}
+
+// Metric is a bitmask of the measurements in Environment.
+type Metric uint8
+
+// Metrics that can be measured by an environmental sensor.
+const (
+	MetricTemperature Metric = 1 << iota
+	MetricPressure
+	MetricHumidity
+	MetricLight
+	MetricGas
+)
+
+var metricNames = [...]string{"Temperature", "Pressure", "Humidity", "Light", "Gas"}
+
+func (m Metric) String() string {
+	if m == 0 {
+		return "0"
+	}
+	var out []string
+	for i, n := range metricNames {
+		if m&(1<<uint(i)) != 0 {
+			out = append(out, n)
+		}
+	}
+	if r := m &^ (1<<uint(len(metricNames)) - 1); r != 0 {
+		out = append(out, fmt.Sprintf("0x%x", uint8(r)))
+	}
+	return strings.Join(out, "|")
 }
 
 // Environmental represents an environmental sensor.
@@ -60,7 +169,7 @@ type Environmental interface {
 	Device
 
 	// Sense returns the value read from the sensor. Unsupported metrics are not
-	// modified.
+	// modified. Time and Quality are set when the driver supports them.
 	Sense(env *Environment) error
 	// SenseContinuous initiates a continuous sensing at the specified interval.
 	//
@@ -68,3 +177,26 @@ type Environmental interface {
 	// the device off and will close the channel.
 	SenseContinuous(interval time.Duration) (<-chan Environment, error)
 }
+
+// EnvironmentalMetrics is an environmental sensor that reports which
+// measurements it fills in Environment.
+//
+// It permits applications to handle heterogeneous sensors generically.
+type EnvironmentalMetrics interface {
+	Environmental
+
+	// Metrics returns the measurements filled by Sense() and
+	// SenseContinuous(). It may depend on the device model and configuration.
+	Metrics() Metric
+}
+
+// MetricsOf returns the measurements filled by e.
+//
+// It returns 0 when e doesn't implement EnvironmentalMetrics, in which case
+// the caller has to guess from the values.
+func MetricsOf(e Environmental) Metric {
+	if m, ok := e.(EnvironmentalMetrics); ok {
+		return m.Metrics()
+	}
+	return 0
+}
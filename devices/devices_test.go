@@ -0,0 +1,81 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetric_String(t *testing.T) {
+	data := []struct {
+		m        Metric
+		expected string
+	}{
+		{0, "0"},
+		{MetricTemperature, "Temperature"},
+		{MetricTemperature | MetricHumidity, "Temperature|Humidity"},
+		{MetricPressure | MetricLight | MetricGas, "Pressure|Light|Gas"},
+		{MetricGas | 0x80, "Gas|0x80"},
+	}
+	for i, line := range data {
+		if s := line.m.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestQuality_String(t *testing.T) {
+	data := []struct {
+		q        Quality
+		expected string
+	}{
+		{0, "Valid"},
+		{QualityStale, "Stale"},
+		{QualityCorrected | QualitySaturated, "Corrected|Saturated"},
+		{QualityStale | 0x80, "Stale|0x80"},
+	}
+	for i, line := range data {
+		if s := line.q.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestMetricsOf(t *testing.T) {
+	if m := MetricsOf(&env{}); m != 0 {
+		t.Fatal(m)
+	}
+	if m := MetricsOf(&envMetrics{}); m != MetricTemperature|MetricPressure {
+		t.Fatal(m)
+	}
+}
+
+//
+
+type env struct{}
+
+func (e *env) Halt() error {
+	return nil
+}
+
+func (e *env) Sense(env *Environment) error {
+	env.Temperature = 20000
+	return nil
+}
+
+func (e *env) SenseContinuous(interval time.Duration) (<-chan Environment, error) {
+	return nil, nil
+}
+
+type envMetrics struct {
+	env
+}
+
+func (e *envMetrics) Metrics() Metric {
+	return MetricTemperature | MetricPressure
+}
+
+var _ EnvironmentalMetrics = &envMetrics{}
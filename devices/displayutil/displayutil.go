@@ -0,0 +1,191 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package displayutil contains helpers to drive a devices.Display.
+package displayutil
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/devices"
+)
+
+// FrameFunc returns the image to draw for the frame n, t being the time
+// elapsed since the start of the animation.
+//
+// Returning nil ends the animation.
+type FrameFunc func(n int, t time.Duration) image.Image
+
+// Flusher is implemented by displays that can defer sending the pixels drawn
+// until Flush() is called, like ssd1306.Dev.
+type Flusher interface {
+	// SetAutoFlush enables or disables sending the pixels on each Draw().
+	SetAutoFlush(on bool) error
+	// Flush sends the pixels drawn since the last update.
+	Flush() error
+}
+
+// Animation draws the frames returned by a FrameFunc on a display at a
+// target frame rate.
+type Animation struct {
+	d      devices.Display
+	f      FrameFunc
+	period time.Duration
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	dropped int
+	err     error
+}
+
+// Animate starts drawing the frames returned by f on d at fps frames per
+// second, until f returns nil or Halt() is called.
+//
+// Frames are paced on the start time of the animation. When generating and
+// drawing a frame takes longer than the frame period, the frames that are
+// already late are skipped so the animation keeps its pace; n then jumps.
+//
+// When d implements Flusher, auto flush is disabled for the duration of the
+// animation and each frame is sent as a whole in a single update, which
+// reduces tearing. When d has an Err() error method, it is checked after each
+// frame and the animation stops on the first error.
+//
+// The display is not halted when the animation ends.
+func Animate(d devices.Display, fps float64, f FrameFunc) (*Animation, error) {
+	if fps <= 0 || fps > 1000 {
+		return nil, fmt.Errorf("displayutil: invalid frame rate %g", fps)
+	}
+	if f == nil {
+		return nil, errors.New("displayutil: FrameFunc is required")
+	}
+	a := &Animation{
+		d:      d,
+		f:      f,
+		period: time.Duration(float64(time.Second) / fps),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	fl, _ := d.(Flusher)
+	if fl != nil {
+		if err := fl.SetAutoFlush(false); err != nil {
+			return nil, fmt.Errorf("displayutil: %v", err)
+		}
+	}
+	go a.run(fl, a.stop)
+	return a, nil
+}
+
+func (a *Animation) String() string {
+	return fmt.Sprintf("Animation{%v}", a.d)
+}
+
+// Dropped returns the number of frames skipped so far to keep the pace.
+func (a *Animation) Dropped() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// Wait waits for the animation to end and returns the first error that
+// occurred, if any.
+func (a *Animation) Wait() error {
+	<-a.done
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// Halt stops the animation once the current frame is drawn and returns the
+// first error that occurred, if any.
+func (a *Animation) Halt() error {
+	a.mu.Lock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+	a.mu.Unlock()
+	return a.Wait()
+}
+
+//
+
+type errer interface {
+	Err() error
+}
+
+func (a *Animation) run(fl Flusher, stop <-chan struct{}) {
+	defer close(a.done)
+	if fl != nil {
+		defer func() {
+			if err := fl.SetAutoFlush(true); err != nil {
+				a.setErr(err)
+			}
+		}()
+	}
+	start := time.Now()
+	for n := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		img := a.f(n, time.Since(start))
+		if img == nil {
+			return
+		}
+		if err := a.draw(fl, img); err != nil {
+			a.setErr(err)
+			return
+		}
+		// Frame k is due at k*period after start.
+		next := n + 1
+		elapsed := time.Since(start)
+		if k := int(elapsed / a.period); k > next {
+			a.mu.Lock()
+			a.dropped += k - next
+			a.mu.Unlock()
+			next = k
+		}
+		if wait := time.Duration(next)*a.period - elapsed; wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-stop:
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+		n = next
+	}
+}
+
+func (a *Animation) draw(fl Flusher, img image.Image) error {
+	a.d.Draw(a.d.Bounds(), img, img.Bounds().Min)
+	if fl != nil {
+		if err := fl.Flush(); err != nil {
+			return err
+		}
+	}
+	if e, ok := a.d.(errer); ok {
+		return e.Err()
+	}
+	return nil
+}
+
+func (a *Animation) setErr(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = fmt.Errorf("displayutil: %v", err)
+	}
+}
+
+var _ conn.Resource = &Animation{}
+var _ fmt.Stringer = &Animation{}
@@ -0,0 +1,228 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package displayutil
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/devices/devicestest"
+	"periph.io/x/periph/devices/ssd1306"
+)
+
+func Example() {
+	d := &devicestest.Display{Img: image.NewNRGBA(image.Rect(0, 0, 128, 64))}
+	// Draw a bar moving across the display at 30fps for 2 seconds.
+	a, err := Animate(d, 30, func(n int, t time.Duration) image.Image {
+		if t > 2*time.Second {
+			return nil
+		}
+		img := image.NewGray(d.Bounds())
+		x := int(t/(10*time.Millisecond)) % img.Rect.Dx()
+		for y := 0; y < img.Rect.Dy(); y++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+		return img
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := a.Wait(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestAnimate(t *testing.T) {
+	d := &devicestest.Display{Img: image.NewNRGBA(image.Rect(0, 0, 4, 4))}
+	var frames []int
+	end := 0
+	a, err := Animate(d, 1000, func(n int, e time.Duration) image.Image {
+		// Late frames are skipped, so n may jump past 5.
+		if n >= 5 {
+			end = n
+			return nil
+		}
+		frames = append(frames, n)
+		img := image.NewGray(image.Rect(0, 0, 4, 4))
+		img.Pix[0] = byte(n + 1)
+		return img
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// Frames may be dropped on a slow machine. Every frame before the last one
+	// requested is either drawn or dropped.
+	if len(frames) == 0 || frames[0] != 0 || len(frames)+a.Dropped() != end {
+		t.Fatal(frames, a.Dropped(), end)
+	}
+	if d.Img.Pix[0] != byte(frames[len(frames)-1]+1) {
+		t.Fatal(d.Img.Pix[:4])
+	}
+	if s := a.String(); s != "Animation{Display}" {
+		t.Fatal(s)
+	}
+	// Halt after the end is fine.
+	if err := a.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnimate_dropped(t *testing.T) {
+	d := &devicestest.Display{Img: image.NewNRGBA(image.Rect(0, 0, 4, 4))}
+	var frames []int
+	a, err := Animate(d, 1000, func(n int, e time.Duration) image.Image {
+		if len(frames) == 2 {
+			return nil
+		}
+		frames = append(frames, n)
+		// Slower than the frame rate.
+		time.Sleep(10 * time.Millisecond)
+		return image.NewGray(image.Rect(0, 0, 4, 4))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if frames[0] != 0 || frames[1] < 10 || a.Dropped() < frames[1]-1 {
+		t.Fatal(frames, a.Dropped())
+	}
+}
+
+func TestAnimate_Halt(t *testing.T) {
+	d := &flushDisplay{Display: devicestest.Display{Img: image.NewNRGBA(image.Rect(0, 0, 4, 4))}}
+	started := make(chan struct{})
+	a, err := Animate(d, 100, func(n int, e time.Duration) image.Image {
+		if n == 0 {
+			close(started)
+		}
+		return image.NewGray(image.Rect(0, 0, 4, 4))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := a.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.auto || d.flushes == 0 || d.draws != d.flushes {
+		t.Fatal(d.auto, d.draws, d.flushes)
+	}
+}
+
+func TestAnimate_fail(t *testing.T) {
+	d := &devicestest.Display{Img: image.NewNRGBA(image.Rect(0, 0, 4, 4))}
+	f := func(n int, e time.Duration) image.Image { return nil }
+	for _, fps := range []float64{0, -1, 1001} {
+		if _, err := Animate(d, fps, f); err == nil {
+			t.Fatal("invalid frame rate")
+		}
+	}
+	if _, err := Animate(d, 1, nil); err == nil {
+		t.Fatal("nil FrameFunc")
+	}
+	fd := &flushDisplay{Display: *d, err: errors.New("injected")}
+	if _, err := Animate(fd, 1, f); err == nil {
+		t.Fatal("SetAutoFlush failed")
+	}
+	// Flush failure; the restoration failure is not reported.
+	fd.err = nil
+	fd.flushErr = errors.New("injected")
+	a, err := Animate(fd, 1, func(n int, e time.Duration) image.Image {
+		fd.mu.Lock()
+		fd.err = errors.New("restore")
+		fd.mu.Unlock()
+		return d.Img
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err == nil || err.Error() != "displayutil: injected" {
+		t.Fatal(err)
+	}
+	// Restoration failure.
+	fd.err = nil
+	fd.flushErr = nil
+	a, err = Animate(fd, 1, func(n int, e time.Duration) image.Image {
+		fd.mu.Lock()
+		fd.err = errors.New("restore")
+		fd.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err == nil || err.Error() != "displayutil: restore" {
+		t.Fatal(err)
+	}
+	// Display error.
+	ed := &errDisplay{Display: *d, err: errors.New("injected")}
+	if a, err = Animate(ed, 1, func(n int, e time.Duration) image.Image { return d.Img }); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err == nil {
+		t.Fatal("Draw failed")
+	}
+}
+
+//
+
+type flushDisplay struct {
+	devicestest.Display
+	mu       sync.Mutex
+	auto     bool
+	draws    int
+	flushes  int
+	err      error
+	flushErr error
+}
+
+func (f *flushDisplay) Draw(r image.Rectangle, src image.Image, sp image.Point) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.draws++
+	f.Display.Draw(r, src, sp)
+}
+
+func (f *flushDisplay) SetAutoFlush(on bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.auto = on
+	return nil
+}
+
+func (f *flushDisplay) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushes++
+	return f.flushErr
+}
+
+type errDisplay struct {
+	devicestest.Display
+	err error
+}
+
+func (e *errDisplay) Err() error {
+	return e.err
+}
+
+var _ Flusher = &ssd1306.Dev{}
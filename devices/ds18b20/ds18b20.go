@@ -24,6 +24,8 @@ package ds18b20
 import (
 	"errors"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
 	"periph.io/x/periph/conn"
@@ -93,14 +95,71 @@ func ConvertAll(o onewire.Bus, maxResolutionBits int) error {
 type Dev struct {
 	onewire    onewire.Dev // device on 1-wire bus
 	resolution int         // resolution in bits (9..12)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 func (d *Dev) String() string {
 	return fmt.Sprintf("DS18B20{%v}", d.onewire)
 }
 
-// Halt implements conn.Resource.
+// Sense implements devices.Environmental.
+//
+// It performs a conversion and sets env.Temperature. The scratchpad is read
+// again once if its CRC is incorrect, in which case env.Quality is
+// QualityCorrected.
+func (d *Dev) Sense(env *devices.Environment) error {
+	if err := d.onewire.TxPower([]byte{0x44}, nil); err != nil {
+		return err
+	}
+	conversionSleep(d.resolution)
+	q := devices.Quality(0)
+	t, err := d.LastTemp()
+	if err == errCRC {
+		q = devices.QualityCorrected
+		t, err = d.LastTemp()
+	}
+	if err != nil {
+		return err
+	}
+	env.Temperature = t
+	env.Time = time.Now()
+	env.Quality = q
+	return nil
+}
+
+// SenseContinuous implements devices.Environmental.
+//
+// The interval must be at least the conversion time at the resolution used.
+// It is important to call Halt() once done, which will close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if c := conversionTime(d.resolution); interval < c {
+		return nil, fmt.Errorf("ds18b20: interval must be at least %s", c)
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature
+}
+
+// Halt stops the continuous sensing, if any.
 func (d *Dev) Halt() error {
+	d.stopSensing()
 	return nil
 }
 
@@ -146,11 +205,51 @@ type busError string
 func (e busError) Error() string  { return string(e) }
 func (e busError) BusError() bool { return true }
 
-// conversionSleep sleeps for the time a conversion takes, which depends
-// on the resolution:
+const errCRC busError = "ds18b20: incorrect scratchpad CRC"
+
+// conversionTime returns the time a conversion takes, which depends on the
+// resolution:
 // 9bits:94ms, 10bits:188ms, 11bits:376ms, 12bits:752ms, datasheet p.6.
+func conversionTime(bits int) time.Duration {
+	return (94 << uint(bits-9)) * time.Millisecond
+}
+
+// conversionSleep sleeps for the time a conversion takes.
 func conversionSleep(bits int) {
-	time.Sleep((94 << uint(bits-9)) * time.Millisecond)
+	time.Sleep(conversionTime(bits))
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var e devices.Environment
+		if err := d.Sense(&e); err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
 }
 
 // readScratchpad reads the 9 bytes of scratchpad and checks the CRC.
@@ -166,7 +265,7 @@ func (d *Dev) readScratchpad() ([]byte, error) {
 	if !onewire.CheckCRC(spad[:]) {
 		for _, s := range spad {
 			if s != 0xff {
-				return nil, busError("ds18b20: incorrect scratchpad CRC")
+				return nil, errCRC
 			}
 		}
 		return nil, busError("ds18b20: device did not respond")
@@ -176,4 +275,5 @@ func (d *Dev) readScratchpad() ([]byte, error) {
 }
 
 var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
 var _ fmt.Stringer = &Dev{}
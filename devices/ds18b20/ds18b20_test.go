@@ -83,6 +83,89 @@ func TestTemperature(t *testing.T) {
 	}
 }
 
+func TestSenseContinuous(t *testing.T) {
+	readSpad := onewiretest.IO{
+		W: []uint8{0x55, 0x28, 0xac, 0x41, 0xe, 0x7, 0x0, 0x0, 0x74, 0xbe},
+		R: []uint8{0xe0, 0x1, 0x0, 0x0, 0x3f, 0xff, 0x10, 0x10, 0x3f},
+	}
+	convert := onewiretest.IO{
+		W:    []uint8{0x55, 0x28, 0xac, 0x41, 0xe, 0x7, 0x0, 0x0, 0x74, 0x44},
+		Pull: true,
+	}
+	bus := onewiretest.Playback{
+		Ops:       []onewiretest.IO{readSpad, convert, readSpad, convert, readSpad, convert, readSpad},
+		DontPanic: true,
+	}
+	dev, err := New(&bus, 0x740000070e41ac28, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := devices.MetricsOf(dev); m != devices.MetricTemperature {
+		t.Fatal(m)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval shorter than the conversion time")
+	}
+	env := devices.Environment{}
+	if err := dev.Sense(&env); err != nil || env.Temperature != 30000 {
+		t.Fatal(env, err)
+	}
+	c, err := dev.SenseContinuous(188 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if e := <-c; e.Temperature != 30000 {
+			t.Fatal(e)
+		}
+	}
+	// The playback is exhausted, the failure closes the channel.
+	if _, ok := <-c; ok {
+		t.Fatal("expected closed channel")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("expected failure")
+	}
+}
+
+func TestSense_corrected(t *testing.T) {
+	readSpad := onewiretest.IO{
+		W: []uint8{0x55, 0x28, 0xac, 0x41, 0xe, 0x7, 0x0, 0x0, 0x74, 0xbe},
+		R: []uint8{0xe0, 0x1, 0x0, 0x0, 0x3f, 0xff, 0x10, 0x10, 0x3f},
+	}
+	badSpad := onewiretest.IO{
+		W: readSpad.W,
+		R: []uint8{0xe1, 0x1, 0x0, 0x0, 0x3f, 0xff, 0x10, 0x10, 0x3f},
+	}
+	convert := onewiretest.IO{
+		W:    []uint8{0x55, 0x28, 0xac, 0x41, 0xe, 0x7, 0x0, 0x0, 0x74, 0x44},
+		Pull: true,
+	}
+	bus := onewiretest.Playback{
+		Ops: []onewiretest.IO{readSpad, convert, readSpad, convert, badSpad, readSpad, convert, badSpad, badSpad},
+	}
+	dev, err := New(&bus, 0x740000070e41ac28, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := devices.Environment{}
+	if err := dev.Sense(&env); err != nil || env.Quality != 0 || env.Time.IsZero() {
+		t.Fatal(env, err)
+	}
+	if err := dev.Sense(&env); err != nil || env.Temperature != 30000 || env.Quality != devices.QualityCorrected {
+		t.Fatal(env, err)
+	}
+	if err := dev.Sense(&env); err != errCRC {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestConvertAll tests a temperature conversion on all ds18b20 using
 // recorded bus transactions.
 func TestConvertAll(t *testing.T) {
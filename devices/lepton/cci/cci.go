@@ -28,6 +28,7 @@ import (
 	"sync"
 	"time"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/mmr"
@@ -162,12 +163,14 @@ func New(i i2c.Bus) (*Dev, error) {
 	}
 	// Wait for the device to be booted.
 	for {
-		if status, err := d.c.waitIdle(); err != nil {
+		status, err := d.c.waitIdle()
+		if err != nil {
 			return nil, err
-		} else if status == StatusBootNormal|StatusBooted {
+		}
+		if status == StatusBootNormal|StatusBooted {
 			return d, nil
 		}
-		//log.Printf("lepton not yet booted: 0x%02x", status)
+		periph.Log().Debug("cci: lepton not yet booted", "status", status)
 		// Polling rocks.
 		time.Sleep(5 * time.Millisecond)
 	}
@@ -389,7 +392,7 @@ func (c *cciConn) get(cmd command, data interface{}) error {
 			return fmt.Errorf("invalid crc; expected 0x%04X; got 0x%04X", expected, crc)
 		}
 	*/
-	//log.Printf("get(%s) = %v", cmd, data)
+	periph.Log().Debug("cci: get", "cmd", cmd, "data", data)
 	return nil
 }
 
@@ -23,9 +23,11 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"log"
 	"sync"
 	"time"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/i2c"
@@ -50,6 +52,10 @@ type Metadata struct {
 	FFCState       cci.FFCState    // Current calibration state.
 	FFCDesired     bool            // Asserted at start-up, after period (default 3m) or after temperature change (default 3°K). Indicates that a calibration should be triggered as soon as possible.
 	Overtemp       bool            // true 10s before self-shutdown.
+	AGCEnabled     bool            // Automatic gain control is enabled; the pixels are 8 bits.
+	AGCROI         image.Rectangle // Region of interest used by the AGC.
+	AGCClipHigh    uint16          // AGC histogram clip limit high.
+	AGCClipLow     uint16          // AGC histogram clip limit low.
 }
 
 // Frame is a FLIR Lepton frame, containing 14 bits resolution intensity stored
@@ -79,6 +85,10 @@ type Dev struct {
 	frameLines     int
 	maxTxSize      int
 	delay          time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 // New returns an initialized connection to the FLIR Lepton.
@@ -157,7 +167,7 @@ func (d *Dev) String() string {
 // ReadImg reads an image.
 //
 // It is ok to call other functions concurrently to send commands to the
-// camera.
+// camera. It must not be called while streaming.
 func (d *Dev) ReadImg() (*Frame, error) {
 	f := &Frame{Gray16: image.NewGray16(d.prevImg.Bounds())}
 	for {
@@ -178,8 +188,129 @@ func (d *Dev) ReadImg() (*Frame, error) {
 	return f, nil
 }
 
+// Stream starts reading the frames continuously and sends them on the returned
+// channel. Frames identical to the previous one are skipped, so the effective
+// rate is up to 9fps.
+//
+// When the synchronization with the VoSPI stream is lost, CS is deasserted
+// for more than 5 frames to resynchronize, as documented at page 42.
+//
+// It is important to call StopStream() or Halt() once done, which will close
+// the channel. The stream is stopped on a SPI or GPIO failure, which closes
+// the channel.
+func (d *Dev) Stream() <-chan *Frame {
+	d.StopStream()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	frames := make(chan *Frame)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(frames)
+		d.streaming(frames, stop)
+	}(d.stop)
+	return frames
+}
+
+// StopStream stops the streaming started with Stream().
+func (d *Dev) StopStream() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+// Halt stops the streaming, if any, and stops the camera.
+func (d *Dev) Halt() error {
+	d.StopStream()
+	return d.Dev.Halt()
+}
+
 // Private details.
 
+var (
+	// errSync is returned when no valid frame was received in time.
+	errSync = errors.New("lepton: failed to synchronize")
+	// errClosed is returned when the stream of lines stopped.
+	errClosed = errors.New("lepton: stream closed")
+	// errStopped is returned when the streaming is stopped.
+	errStopped = errors.New("lepton: stream stopped")
+)
+
+// resyncDelay is the time CS is deasserted to resynchronize, more than 5
+// frames. It is overridden in unit tests.
+var resyncDelay = 200 * time.Millisecond
+
+// streaming sends the frames on frames until stopped, resynchronizing as
+// needed.
+func (d *Dev) streaming(frames chan<- *Frame, stop <-chan struct{}) {
+	prev := image.NewGray16(d.prevImg.Bounds())
+	for {
+		err := d.streamFrames(frames, prev, stop)
+		if err == errStopped {
+			return
+		}
+		if err != errSync {
+			log.Printf("%s: failed to stream: %v", d, err)
+			return
+		}
+		// CS was deasserted by stream(); wait for the camera to reset its
+		// VoSPI interface.
+		select {
+		case <-stop:
+			return
+		case <-time.After(resyncDelay):
+		}
+	}
+}
+
+// streamFrames reads the frames until an error occurs.
+//
+// prev is the last frame sent, to skip duplicates.
+func (d *Dev) streamFrames(frames chan<- *Frame, prev *image.Gray16, stop <-chan struct{}) error {
+	done := make(chan struct{})
+	c := make(chan []byte, 1024)
+	var err error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(c)
+		err = d.stream(done, c)
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	for {
+		f := &Frame{Gray16: image.NewGray16(prev.Bounds())}
+		if err2 := d.assemble(f, c, stop); err2 != nil {
+			if err2 == errClosed {
+				wg.Wait()
+				if err == nil {
+					return errClosed
+				}
+				return err
+			}
+			return err2
+		}
+		if bytes.Equal(prev.Pix, f.Pix) {
+			continue
+		}
+		copy(prev.Pix, f.Pix)
+		select {
+		case frames <- f:
+		case <-stop:
+			return errStopped
+		}
+	}
+}
+
 // stream reads continuously from the SPI connection.
 func (d *Dev) stream(done <-chan struct{}, c chan<- []byte) error {
 	lines := 8
@@ -241,19 +372,35 @@ func (d *Dev) readFrame(f *Frame) error {
 	defer func() {
 		done <- struct{}{}
 	}()
+	if err2 := d.assemble(f, c, nil); err2 != nil {
+		if err2 == errClosed {
+			wg.Wait()
+			return err
+		}
+		return err2
+	}
+	return nil
+}
 
+// assemble decodes the lines read from c into f until a whole frame is
+// received.
+//
+// It returns errSync if no frame was received within d.delay, errClosed if c
+// is closed and errStopped if stop is closed.
+func (d *Dev) assemble(f *Frame, c <-chan []byte, stop <-chan struct{}) error {
 	timeout := time.After(d.delay)
 	w := f.Bounds().Dx()
 	sync := 0
 	discard := 0
 	for {
 		select {
+		case <-stop:
+			return errStopped
 		case <-timeout:
-			return fmt.Errorf("failed to synchronize after %s", d.delay)
+			return errSync
 		case l, ok := <-c:
 			if !ok {
-				wg.Wait()
-				return err
+				return errClosed
 			}
 			h := internal.Big16.Uint16(l)
 			if h&packetHeaderDiscard == packetHeaderDiscard {
@@ -263,24 +410,21 @@ func (d *Dev) readFrame(f *Frame) error {
 			}
 			headerID := h & packetHeaderMask
 			if discard != 0 {
-				//log.Printf("discarded %d", discard)
 				discard = 0
 				sync = 0
 			}
 			if int(headerID) == 0 && sync == 0 && !verifyCRC(l) {
-				//log.Printf("no crc")
 				sync = 0
 				continue
 			}
 			if int(headerID) != sync {
-				//log.Printf("%d != %d", headerID, sync)
 				sync = 0
 				continue
 			}
 			if sync == 0 {
 				// Parse the first row of telemetry data.
 				if err2 := f.Metadata.parseTelemetry(l[4:]); err2 != nil {
-					//log.Printf("Failed to parse telemetry line: %v", err2)
+					periph.Log().Debug("lepton: failed to parse telemetry line", "dev", d.String(), "err", err2)
 					continue
 				}
 			} else if sync >= 3 {
@@ -319,6 +463,10 @@ func (m *Metadata) parseTelemetry(data []byte) error {
 	}
 	m.FFCDesired = rowA.StatusBits&statusFFCDesired != 0
 	m.Overtemp = rowA.StatusBits&statusOvertemp != 0
+	m.AGCEnabled = rowA.StatusBits&statusAGCState != 0
+	m.AGCROI = image.Rect(int(rowA.AGCROILeft), int(rowA.AGCROITop), int(rowA.AGCROIRight)+1, int(rowA.AGCROIBottom)+1)
+	m.AGCClipHigh = rowA.AGCClipLimitHigh
+	m.AGCClipLow = rowA.AGCClipLimitLow
 	fccstate := rowA.StatusBits & statusFFCStateMask >> statusFFCStateShift
 	if rowA.TelemetryRevision == 8 {
 		switch fccstate {
@@ -11,7 +11,9 @@ import (
 	"errors"
 	"image"
 	"image/color"
+	"sync"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/conntest"
@@ -224,6 +226,100 @@ func TestReadImg_fail_OUt(t *testing.T) {
 	}
 }
 
+func TestStream(t *testing.T) {
+	i := i2ctest.Playback{
+		Ops: append(initSequence(),
+			[]i2ctest.IO{
+				{Addr: 42, W: []byte{0x0, 0x2}, R: []byte{0x0, 0x6}}, // waitIdle
+				{Addr: 42, W: []byte{0x0, 0x6, 0x0, 0x0}},
+				{Addr: 42, W: []byte{0x0, 0x4, 0x48, 0x2}},
+				{Addr: 42, W: []byte{0x0, 0x2}, R: []byte{0x0, 0x6}}, // waitIdle
+			}...),
+	}
+	// The same frame twice, then a different frame, then garbage that forces a
+	// resynchronization.
+	frame1 := prepareFrame(t)
+	data := append(append([]byte{}, frame1...), frame1...)
+	data = append(data, prepareFrameOffset(t, 1)...)
+	s := spiStream{data: data}
+	cs := &csPin{}
+	d, err := New(&s, &i, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.delay = 10 * time.Millisecond
+	c := d.Stream()
+	f := <-c
+	if !bytes.Equal(referenceFrame().Pix, f.Pix) {
+		t.Fatal("unexpected first frame")
+	}
+	f = <-c
+	if bytes.Equal(referenceFrame().Pix, f.Pix) {
+		t.Fatal("identical frames must be skipped")
+	}
+	if f.Metadata.TempHousing != devices.Celsius(2000) {
+		t.Fatal(f.Metadata.TempHousing)
+	}
+	// Wait for at least one resynchronization.
+	for cs.highs() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	// Safe to call multiple times.
+	d.StopStream()
+	if err := i.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStream_restart(t *testing.T) {
+	i := i2ctest.Playback{Ops: initSequence()}
+	s := spiStream{data: prepareFrame(t)}
+	d, err := New(&s, &i, &csPin{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1 := d.Stream()
+	c2 := d.Stream()
+	if _, ok := <-c1; ok {
+		t.Fatal("previous stream must be stopped")
+	}
+	d.StopStream()
+	if _, ok := <-c2; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := i.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStream_fail(t *testing.T) {
+	i := i2ctest.Playback{Ops: initSequence()}
+	s := spitest.Playback{Playback: conntest.Playback{DontPanic: true}}
+	d, err := New(&s, &i, &failPin{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-d.Stream(); ok {
+		t.Fatal("CS failed")
+	}
+	d.StopStream()
+	d.s = &spiStream{err: errors.New("injected")}
+	d.cs = &csPin{}
+	if _, ok := <-d.Stream(); ok {
+		t.Fatal("spi port Tx failed")
+	}
+	d.StopStream()
+	if err := i.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestParseTelemetry_fail(t *testing.T) {
 	l := telemetryLine(t)
 	m := Metadata{}
@@ -245,6 +341,30 @@ func TestParseTelemetry(t *testing.T) {
 	if err := m.parseTelemetry(telemetryLine(t)); err != nil {
 		t.Fatal(err)
 	}
+	if !m.FFCDesired || m.AGCEnabled {
+		t.Fatal(m)
+	}
+
+	buf := bytes.Buffer{}
+	rowA := telemetryRowA{
+		TelemetryRevision: 8,
+		StatusBits:        statusAGCState,
+		AGCROILeft:        1,
+		AGCROITop:         2,
+		AGCROIRight:       78,
+		AGCROIBottom:      57,
+		AGCClipLimitHigh:  4800,
+		AGCClipLimitLow:   512,
+	}
+	if err := binary.Write(&buf, internal.Big16, &rowA); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.parseTelemetry(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.AGCEnabled || m.AGCROI != image.Rect(1, 2, 79, 58) || m.AGCClipHigh != 4800 || m.AGCClipLow != 512 {
+		t.Fatal(m)
+	}
 
 	data := []struct {
 		rowA    telemetryRowA
@@ -341,6 +461,12 @@ func referenceFrame() *image.Gray16 {
 }
 
 func prepareFrame(t *testing.T) []byte {
+	return prepareFrameOffset(t, 0)
+}
+
+// prepareFrameOffset returns the reference frame with offset added to each
+// pixel.
+func prepareFrameOffset(t *testing.T, offset uint16) []byte {
 	buf := bytes.Buffer{}
 	tmp := make([]byte, 160)
 	buf.Write(appendHeader(t, 0, telemetryLine(t)))
@@ -350,7 +476,7 @@ func prepareFrame(t *testing.T) []byte {
 	r := img.Bounds()
 	for y := 0; y < r.Max.Y; y++ {
 		for x := 0; x < r.Max.X; x++ {
-			internal.Big16.PutUint16(tmp[x*2:], img.Gray16At(x, y).Y)
+			internal.Big16.PutUint16(tmp[x*2:], img.Gray16At(x, y).Y+offset)
 		}
 		buf.Write(appendHeader(t, y+3, tmp))
 	}
@@ -417,3 +543,29 @@ type failPin struct {
 func (f *failPin) Out(l gpio.Level) error {
 	return errors.New("injected")
 }
+
+// csPin counts the number of times CS is deasserted.
+type csPin struct {
+	gpiotest.Pin
+	mu sync.Mutex
+	n  int
+}
+
+func (c *csPin) Out(l gpio.Level) error {
+	if l == gpio.High {
+		c.mu.Lock()
+		c.n++
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *csPin) highs() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+func init() {
+	resyncDelay = time.Millisecond
+}
@@ -2,8 +2,8 @@
 // Use of this source code is governed under the Apache License, Version 2.0
 // that can be found in the LICENSE file.
 
-// Package lirc implements InfraRed receiver support through native linux app
-// lirc.
+// Package lirc implements InfraRed receiver and transmitter support through
+// native linux app lirc.
 //
 // Configuration
 //
@@ -27,6 +27,15 @@
 // Keys are listed at
 // http://www.lirc.org/api-docs/html/input__map_8inc_source.html
 //
+// Transmission
+//
+// Conn.Emit() sends keys known to lircd. To send raw signals without lircd,
+// e.g. to build a universal remote, use Device with the rc-core lirc character
+// device exposed by the kernel, like /dev/lirc0. When no such device is
+// available, PWM generates the carrier directly on a GPIO pin supporting
+// hardware PWM. Signals are generated with ir.NEC(), ir.RC5() or
+// ir.ParsePronto().
+//
 // Debugging
 //
 // Here's a quick recipe to train a remote:
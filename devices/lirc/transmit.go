@@ -0,0 +1,202 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package lirc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/ir"
+	"periph.io/x/periph/host/fs"
+)
+
+// Device is an IR transmitter through a rc-core lirc character device, e.g.
+// /dev/lirc0.
+//
+// Contrary to Conn, it doesn't require lircd and sends raw signals, which
+// can be generated with ir.NEC(), ir.RC5() or ir.ParsePronto().
+type Device struct {
+	path     string
+	features uint32
+
+	mu      sync.Mutex
+	f       ioctlWriteCloser
+	carrier int64
+}
+
+// OpenDevice opens a rc-core lirc character device for transmission.
+func OpenDevice(path string) (*Device, error) {
+	f, err := ioctlOpen(path, os.O_RDWR)
+	if err != nil {
+		return nil, fmt.Errorf("lirc: %v", err)
+	}
+	d := &Device{path: path, f: f}
+	if err := f.Ioctl(lircGetFeatures, uintptr(unsafe.Pointer(&d.features))); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lirc: failed to get features of %s: %v", path, err)
+	}
+	if d.features&lircCanSendPulse == 0 {
+		f.Close()
+		return nil, fmt.Errorf("lirc: %s can't transmit", path)
+	}
+	return d, nil
+}
+
+func (d *Device) String() string {
+	return d.path
+}
+
+// Close closes the device.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.f == nil {
+		return nil
+	}
+	err := d.f.Close()
+	d.f = nil
+	return err
+}
+
+// Transmit implements ir.Transmitter.
+//
+// The kernel driver returns once the signal was sent. The trailing space, if
+// any, is then waited for.
+func (d *Device) Transmit(s *ir.Signal) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.f == nil {
+		return errors.New("lirc: device is closed")
+	}
+	if s.Carrier != d.carrier && d.features&lircCanSetSendCarrier != 0 {
+		v := uint32(s.Carrier)
+		if err := d.f.Ioctl(lircSetSendCarrier, uintptr(unsafe.Pointer(&v))); err != nil {
+			return fmt.Errorf("lirc: failed to set carrier to %dHz: %v", s.Carrier, err)
+		}
+		d.carrier = s.Carrier
+	}
+	// The driver requires an odd number of values, ending with a mark. Values
+	// are in µs.
+	t, gap := splitGap(s.Timings)
+	buf := make([]byte, 4*len(t))
+	for i, v := range t {
+		binary.LittleEndian.PutUint32(buf[4*i:], uint32((v+time.Microsecond/2)/time.Microsecond))
+	}
+	if _, err := d.f.Write(buf); err != nil {
+		return fmt.Errorf("lirc: %v", err)
+	}
+	sleep(gap)
+	return nil
+}
+
+// PWM is an IR transmitter using a GPIO pin with hardware PWM to generate the
+// carrier, for when no lirc transmitter is available.
+//
+// The timings precision depends on the OS scheduler, so it is best effort.
+type PWM struct {
+	mu sync.Mutex
+	p  PinPWMOut
+}
+
+// PinPWMOut is a GPIO pin supporting hardware PWM.
+type PinPWMOut interface {
+	gpio.PinOut
+	gpio.PinPWM
+}
+
+// NewPWM returns a transmitter that generates the carrier on p.
+func NewPWM(p PinPWMOut) (*PWM, error) {
+	if err := p.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("lirc: %v", err)
+	}
+	return &PWM{p: p}, nil
+}
+
+func (p *PWM) String() string {
+	return fmt.Sprintf("PWM(%s)", p.p)
+}
+
+// Transmit implements ir.Transmitter.
+//
+// The carrier is generated with a 33% duty cycle.
+func (p *PWM) Transmit(s *ir.Signal) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	period := time.Second / time.Duration(s.Carrier)
+	for i, t := range s.Timings {
+		var err error
+		if i&1 == 0 {
+			err = p.p.PWM(gpio.DutyMax/3, period)
+		} else {
+			err = p.p.Out(gpio.Low)
+		}
+		if err != nil {
+			p.p.Out(gpio.Low)
+			return fmt.Errorf("lirc: %v", err)
+		}
+		sleep(t)
+	}
+	if len(s.Timings)&1 == 1 {
+		if err := p.p.Out(gpio.Low); err != nil {
+			return fmt.Errorf("lirc: %v", err)
+		}
+	}
+	return nil
+}
+
+//
+
+// See linux/lirc.h.
+const (
+	lircGetFeatures       = 0x80046900
+	lircSetSendCarrier    = 0x40046913
+	lircCanSendPulse      = 0x00000002
+	lircCanSetSendCarrier = 0x00000100
+)
+
+var sleep = time.Sleep
+
+var ioctlOpen = ioctlOpenDefault
+
+func ioctlOpenDefault(path string, flag int) (ioctlWriteCloser, error) {
+	f, err := fs.Open(path, flag)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type ioctlWriteCloser interface {
+	io.Closer
+	io.Writer
+	fs.Ioctler
+}
+
+// splitGap returns the timings ending with a mark and the trailing space, if
+// any.
+func splitGap(t []time.Duration) ([]time.Duration, time.Duration) {
+	if len(t)&1 == 0 {
+		return t[:len(t)-1], t[len(t)-1]
+	}
+	return t, 0
+}
+
+var _ ir.Transmitter = &Device{}
+var _ ir.Transmitter = &PWM{}
+var _ fmt.Stringer = &Device{}
+var _ fmt.Stringer = &PWM{}
@@ -0,0 +1,194 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package lirc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/ir"
+)
+
+func ExampleDevice() {
+	d, err := OpenDevice("/dev/lirc0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Close()
+	// Send the NEC code for address 0x04, command 0x08 followed by one repeat
+	// code, as if the key was held for a short while.
+	for _, s := range []*ir.Signal{ir.NEC(0x04, 0x08), ir.NECRepeat()} {
+		if err := d.Transmit(s); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+//
+
+func TestDevice(t *testing.T) {
+	f := &fakeLirc{}
+	d := &Device{path: "/dev/lirc0", features: lircCanSendPulse | lircCanSetSendCarrier, f: f}
+	if s := d.String(); s != "/dev/lirc0" {
+		t.Fatal(s)
+	}
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+	defer func() { sleep = time.Sleep }()
+	s := &ir.Signal{Carrier: 38000, Timings: []time.Duration{9 * time.Millisecond, 4500 * time.Microsecond, 562 * time.Microsecond, 50 * time.Millisecond}}
+	if err := d.Transmit(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.ops) != 1 || f.ops[0] != lircSetSendCarrier {
+		t.Fatal(f.ops)
+	}
+	expected := []byte{0x28, 0x23, 0, 0, 0x94, 0x11, 0, 0, 0x32, 0x02, 0, 0}
+	if !bytes.Equal(f.w.Bytes(), expected) {
+		t.Fatalf("%x", f.w.Bytes())
+	}
+	if slept != 50*time.Millisecond {
+		t.Fatal(slept)
+	}
+	// The carrier is only set when it changes.
+	if err := d.Transmit(&ir.Signal{Carrier: 38000, Timings: []time.Duration{time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.ops) != 1 {
+		t.Fatal(f.ops)
+	}
+	if err := d.Transmit(&ir.Signal{}); err == nil {
+		t.Fatal("invalid signal")
+	}
+	f.err = errors.New("injected")
+	if err := d.Transmit(&ir.Signal{Carrier: 36000, Timings: []time.Duration{time.Millisecond}}); err == nil {
+		t.Fatal("ioctl failed")
+	}
+	d.features = lircCanSendPulse
+	if err := d.Transmit(&ir.Signal{Carrier: 36000, Timings: []time.Duration{time.Millisecond}}); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Transmit(s); err == nil {
+		t.Fatal("closed")
+	}
+}
+
+func TestOpenDevice_fail(t *testing.T) {
+	defer func() { ioctlOpen = ioctlOpenDefault }()
+	ioctlOpen = func(path string, flag int) (ioctlWriteCloser, error) {
+		return nil, errors.New("injected")
+	}
+	if _, err := OpenDevice("/dev/lirc0"); err == nil {
+		t.Fatal("open failed")
+	}
+	f := &fakeLirc{err: errors.New("injected")}
+	ioctlOpen = func(path string, flag int) (ioctlWriteCloser, error) {
+		return f, nil
+	}
+	if _, err := OpenDevice("/dev/lirc0"); err == nil {
+		t.Fatal("ioctl failed")
+	}
+	// The fake reports no feature.
+	f.err = nil
+	if _, err := OpenDevice("/dev/lirc0"); err == nil {
+		t.Fatal("receive only")
+	}
+	if len(f.ops) != 1 || f.ops[0] != lircGetFeatures {
+		t.Fatal(f.ops)
+	}
+}
+
+func TestPWM(t *testing.T) {
+	p := &pwmPin{}
+	tx, err := NewPWM(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := tx.String(); s != "PWM(IR(0))" {
+		t.Fatal(s)
+	}
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+	s := &ir.Signal{Carrier: 40000, Timings: []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}}
+	if err := tx.Transmit(s); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Low", "PWM(33%, 25µs)", "Low", "PWM(33%, 25µs)", "Low"}
+	if fmt.Sprint(p.ops) != fmt.Sprint(expected) {
+		t.Fatal(p.ops)
+	}
+	if fmt.Sprint(slept) != fmt.Sprint(s.Timings) {
+		t.Fatal(slept)
+	}
+	if err := tx.Transmit(&ir.Signal{}); err == nil {
+		t.Fatal("invalid signal")
+	}
+	p.err = errors.New("injected")
+	if err := tx.Transmit(s); err == nil {
+		t.Fatal("PWM failed")
+	}
+	if _, err := NewPWM(p); err == nil {
+		t.Fatal("Out failed")
+	}
+}
+
+//
+
+type fakeLirc struct {
+	ops []uint
+	w   bytes.Buffer
+	err error
+}
+
+func (f *fakeLirc) Close() error {
+	return nil
+}
+
+func (f *fakeLirc) Write(b []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.w.Write(b)
+}
+
+func (f *fakeLirc) Ioctl(op uint, data uintptr) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.ops = append(f.ops, op)
+	return nil
+}
+
+type pwmPin struct {
+	gpiotest.Pin
+	ops []string
+	err error
+}
+
+func (p *pwmPin) String() string {
+	return "IR(0)"
+}
+
+func (p *pwmPin) Out(l gpio.Level) error {
+	p.ops = append(p.ops, l.String())
+	return p.err
+}
+
+func (p *pwmPin) PWM(duty gpio.Duty, period time.Duration) error {
+	p.ops = append(p.ops, fmt.Sprintf("PWM(%s, %s)", duty, period))
+	return p.err
+}
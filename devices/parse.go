@@ -0,0 +1,414 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseCelsius parses a temperature like "25.5°C". A value without unit is
+// in °C.
+func ParseCelsius(s string) (Celsius, error) {
+	v, err := parse(s, "°C", 0, 3)
+	return Celsius(v), err
+}
+
+// ParseFahrenheit parses a temperature like "77.9°F". A value without unit
+// is in °F.
+func ParseFahrenheit(s string) (Fahrenheit, error) {
+	v, err := parse(s, "°F", 0, 3)
+	return Fahrenheit(v), err
+}
+
+// ParseKPascal parses a pressure like "101.325kPa" or "1013hPa". A value
+// without unit is in KPa.
+func ParseKPascal(s string) (KPascal, error) {
+	v, err := parse(s, "Pa", 3, 0)
+	return KPascal(v), err
+}
+
+// ParseRelativeHumidity parses a humidity level like "50.1%rH". A value
+// without unit is in %rH.
+func ParseRelativeHumidity(s string) (RelativeHumidity, error) {
+	v, err := parse(s, "%rH", 0, 2)
+	return RelativeHumidity(v), err
+}
+
+// ParseVolt parses an electrical potential like "3.3V" or "500mV". A value
+// without unit is in V.
+func ParseVolt(s string) (Volt, error) {
+	v, err := parse(s, "V", 0, 3)
+	return Volt(v), err
+}
+
+// ParseAmpere parses an electrical current like "1.5A" or "20mA". A value
+// without unit is in A.
+func ParseAmpere(s string) (Ampere, error) {
+	v, err := parse(s, "A", 0, 3)
+	return Ampere(v), err
+}
+
+// ParseWatt parses a power like "2.5W" or "100mW". A value without unit is
+// in W.
+func ParseWatt(s string) (Watt, error) {
+	v, err := parse(s, "W", 0, 3)
+	return Watt(v), err
+}
+
+// ParseDistance parses a length like "1.2m" or "35mm". A value without unit
+// is in m.
+func ParseDistance(s string) (Distance, error) {
+	v, err := parse(s, "m", 0, 3)
+	return Distance(v), err
+}
+
+// ParseLux parses an illuminance like "300lx". A value without unit is in
+// lx.
+func ParseLux(s string) (Lux, error) {
+	v, err := parse(s, "lx", 0, 3)
+	return Lux(v), err
+}
+
+// ParseMass parses a mass like "1.5kg" or "20g". A value without unit is in
+// g.
+func ParseMass(s string) (Mass, error) {
+	v, err := parse(s, "g", 0, 3)
+	return Mass(v), err
+}
+
+// ParseNewton parses a force like "9.8N". A value without unit is in N.
+func ParseNewton(s string) (Newton, error) {
+	v, err := parse(s, "N", 0, 3)
+	return Newton(v), err
+}
+
+// ParseOhm parses an electrical resistance like "10kΩ" or "4.7MΩ". A value
+// without unit is in Ω.
+func ParseOhm(s string) (Ohm, error) {
+	v, err := parse(s, "Ω", 0, 0)
+	return Ohm(v), err
+}
+
+// ParseAngle parses an angle like "90°" or "1.5708rad". A value without unit
+// is in degrees.
+func ParseAngle(s string) (Angle, error) {
+	if strings.HasSuffix(strings.TrimSpace(s), "rad") {
+		// Parse as µrad to keep the precision.
+		v, err := parse(s, "rad", 0, 6)
+		return AngleFromRadians(float64(v) * .000001), err
+	}
+	v, err := parse(s, "°", 0, 3)
+	return Angle(v), err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (c Celsius) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseCelsius() or a number in °C.
+func (c *Celsius) UnmarshalJSON(b []byte) error {
+	v, err := ParseCelsius(unquote(b))
+	if err == nil {
+		*c = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (f Fahrenheit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseFahrenheit() or a number in °F.
+func (f *Fahrenheit) UnmarshalJSON(b []byte) error {
+	v, err := ParseFahrenheit(unquote(b))
+	if err == nil {
+		*f = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (k KPascal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseKPascal() or a number in KPa.
+func (k *KPascal) UnmarshalJSON(b []byte) error {
+	v, err := ParseKPascal(unquote(b))
+	if err == nil {
+		*k = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (r RelativeHumidity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseRelativeHumidity() or a number in %rH.
+func (r *RelativeHumidity) UnmarshalJSON(b []byte) error {
+	v, err := ParseRelativeHumidity(unquote(b))
+	if err == nil {
+		*r = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (v Volt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseVolt() or a number in V.
+func (v *Volt) UnmarshalJSON(b []byte) error {
+	i, err := ParseVolt(unquote(b))
+	if err == nil {
+		*v = i
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (a Ampere) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseAmpere() or a number in A.
+func (a *Ampere) UnmarshalJSON(b []byte) error {
+	v, err := ParseAmpere(unquote(b))
+	if err == nil {
+		*a = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (w Watt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseWatt() or a number in W.
+func (w *Watt) UnmarshalJSON(b []byte) error {
+	v, err := ParseWatt(unquote(b))
+	if err == nil {
+		*w = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (d Distance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseDistance() or a number in m.
+func (d *Distance) UnmarshalJSON(b []byte) error {
+	v, err := ParseDistance(unquote(b))
+	if err == nil {
+		*d = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (l Lux) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseLux() or a number in lx.
+func (l *Lux) UnmarshalJSON(b []byte) error {
+	v, err := ParseLux(unquote(b))
+	if err == nil {
+		*l = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (m Mass) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseMass() or a number in g.
+func (m *Mass) UnmarshalJSON(b []byte) error {
+	v, err := ParseMass(unquote(b))
+	if err == nil {
+		*m = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (n Newton) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseNewton() or a number in N.
+func (n *Newton) UnmarshalJSON(b []byte) error {
+	v, err := ParseNewton(unquote(b))
+	if err == nil {
+		*n = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation.
+func (o Ohm) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseOhm() or a number in Ω.
+func (o *Ohm) UnmarshalJSON(b []byte) error {
+	v, err := ParseOhm(unquote(b))
+	if err == nil {
+		*o = v
+	}
+	return err
+}
+
+// MarshalJSON implements json.Marshaler. The value is encoded as its string
+// representation in degrees.
+func (a Angle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a string as parsed by
+// ParseAngle() or a number in degrees.
+func (a *Angle) UnmarshalJSON(b []byte) error {
+	v, err := ParseAngle(unquote(b))
+	if err == nil {
+		*a = v
+	}
+	return err
+}
+
+//
+
+// prefixes is the SI prefixes accepted before the unit symbol, as power of
+// 10. "K" is accepted for compatibility with KPascal.String().
+var prefixes = map[rune]int{
+	'n': -9,
+	'µ': -6,
+	'u': -6,
+	'm': -3,
+	'c': -2,
+	'h': 2,
+	'k': 3,
+	'K': 3,
+	'M': 6,
+	'G': 9,
+}
+
+// parse parses a decimal number optionally followed by an SI prefix and
+// symbol.
+//
+// The returned value is in 10^-decimals of the unit. bare is the power of 10
+// of the unit when s has no symbol. The value is rounded to the nearest
+// representable value.
+func parse(s, symbol string, bare, decimals int) (int32, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	exp := bare
+	if strings.HasSuffix(s, symbol) {
+		s = strings.TrimSpace(s[:len(s)-len(symbol)])
+		exp = 0
+		if r, size := utf8.DecodeLastRuneInString(s); r != utf8.RuneError && (r < '0' || r > '9') && r != '.' {
+			p, ok := prefixes[r]
+			if !ok {
+				return 0, errors.New("devices: unknown prefix in " + quote(orig))
+			}
+			exp = p
+			s = strings.TrimSpace(s[:len(s)-size])
+		}
+	}
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" || s == "." {
+		return 0, errors.New("devices: invalid value " + quote(orig))
+	}
+	var v int64
+	dot := false
+	for _, c := range s {
+		switch {
+		case c == '.' && !dot:
+			dot = true
+		case c >= '0' && c <= '9':
+			if v > math.MaxInt64/100 {
+				return 0, errors.New("devices: value out of range " + quote(orig))
+			}
+			v = v*10 + int64(c-'0')
+			if dot {
+				exp--
+			}
+		default:
+			return 0, errors.New("devices: invalid value " + quote(orig))
+		}
+	}
+	for exp += decimals; exp > 0; exp-- {
+		if v > math.MaxInt32 {
+			return 0, errors.New("devices: value out of range " + quote(orig))
+		}
+		v *= 10
+	}
+	for ; exp < 0; exp++ {
+		v = (v + 5) / 10
+	}
+	if neg {
+		v = -v
+	}
+	if v > math.MaxInt32 || v < math.MinInt32 {
+		return 0, errors.New("devices: value out of range " + quote(orig))
+	}
+	return int32(v), nil
+}
+
+// unquote returns the content of a JSON string, or b as-is for a JSON number.
+func unquote(b []byte) string {
+	s := string(b)
+	if len(b) != 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return string(b)
+		}
+	}
+	return s
+}
+
+func quote(s string) string {
+	return "\"" + s + "\""
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package devices
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+)
+
+func ExampleParseVolt() {
+	v, err := ParseVolt("3.3V")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(v)
+	// Output:
+	// 3.300V
+}
+
+//
+
+func TestParse(t *testing.T) {
+	data := []struct {
+		f        func(s string) (fmt.Stringer, error)
+		in       string
+		expected string
+	}{
+		{func(s string) (fmt.Stringer, error) { return ParseCelsius(s) }, "25.5°C", "25.500°C"},
+		{func(s string) (fmt.Stringer, error) { return ParseCelsius(s) }, "-40", "-40.000°C"},
+		{func(s string) (fmt.Stringer, error) { return ParseCelsius(s) }, " 21.0005 °C ", "21.001°C"},
+		{func(s string) (fmt.Stringer, error) { return ParseFahrenheit(s) }, "77.9°F", "77.900°F"},
+		{func(s string) (fmt.Stringer, error) { return ParseKPascal(s) }, "101.325kPa", "101.325KPa"},
+		{func(s string) (fmt.Stringer, error) { return ParseKPascal(s) }, "10.010KPa", "10.010KPa"},
+		{func(s string) (fmt.Stringer, error) { return ParseKPascal(s) }, "1013.25hPa", "101.325KPa"},
+		{func(s string) (fmt.Stringer, error) { return ParseKPascal(s) }, "100", "100.000KPa"},
+		{func(s string) (fmt.Stringer, error) { return ParseRelativeHumidity(s) }, "50.1%rH", "50.10%rH"},
+		{func(s string) (fmt.Stringer, error) { return ParseVolt(s) }, "500mV", "0.500V"},
+		{func(s string) (fmt.Stringer, error) { return ParseAmpere(s) }, "-20mA", "-0.020A"},
+		{func(s string) (fmt.Stringer, error) { return ParseAmpere(s) }, "+1.5", "1.500A"},
+		{func(s string) (fmt.Stringer, error) { return ParseWatt(s) }, "2.5kW", "2500.000W"},
+		{func(s string) (fmt.Stringer, error) { return ParseDistance(s) }, "35mm", "0.035m"},
+		{func(s string) (fmt.Stringer, error) { return ParseDistance(s) }, "1.2m", "1.200m"},
+		{func(s string) (fmt.Stringer, error) { return ParseDistance(s) }, "12cm", "0.120m"},
+		{func(s string) (fmt.Stringer, error) { return ParseLux(s) }, ".5lx", "0.500lx"},
+		{func(s string) (fmt.Stringer, error) { return ParseMass(s) }, "1.5kg", "1500.000g"},
+		{func(s string) (fmt.Stringer, error) { return ParseNewton(s) }, "9.8N", "9.800N"},
+		{func(s string) (fmt.Stringer, error) { return ParseOhm(s) }, "4.7kΩ", "4700Ω"},
+		{func(s string) (fmt.Stringer, error) { return ParseOhm(s) }, "1MΩ", "1000000Ω"},
+		{func(s string) (fmt.Stringer, error) { return ParseOhm(s) }, "1500mΩ", "2Ω"},
+		{func(s string) (fmt.Stringer, error) { return ParseAngle(s) }, "90°", "90.000°"},
+		{func(s string) (fmt.Stringer, error) { return ParseAngle(s) }, "-45.5", "-45.500°"},
+		{func(s string) (fmt.Stringer, error) { return ParseAngle(s) }, "1.5708rad", "90.000°"},
+		{func(s string) (fmt.Stringer, error) { return ParseAngle(s) }, "3141.593 mrad", "180.000°"},
+	}
+	for i, line := range data {
+		v, err := line.f(line.in)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if s := v.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestParse_fail(t *testing.T) {
+	for i, s := range []string{"", "V", "-", ".", "1.2.3V", "3,3V", "3.3xV", "3.3mA", "1GV", "99999999999999999999V"} {
+		if v, err := ParseVolt(s); err == nil {
+			t.Fatalf("#%d: %q: expected error, got %s", i, s, v)
+		}
+	}
+	if v, err := ParseOhm("3GΩ"); err == nil {
+		t.Fatalf("expected overflow, got %s", v)
+	}
+	if v, err := ParseOhm("-3GΩ"); err == nil {
+		t.Fatalf("expected overflow, got %s", v)
+	}
+	if v, err := ParseAngle("1xrad"); err == nil {
+		t.Fatalf("expected error, got %s", v)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type config struct {
+		C  Celsius
+		F  Fahrenheit
+		P  KPascal
+		H  RelativeHumidity
+		V  Volt
+		A  Ampere
+		W  Watt
+		D  Distance
+		L  Lux
+		M  Mass
+		N  Newton
+		O  Ohm
+		An Angle
+	}
+	c := config{C: 25500, F: 77900, P: 101325, H: 5010, V: 3300, A: -20, W: 2500, D: 1234, L: 300000, M: 1500, N: 9800, O: 4700, An: 90000}
+	b, err := json.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"C":"25.500°C","F":"77.900°F","P":"101.325KPa","H":"50.10%rH","V":"3.300V","A":"-0.020A","W":"2.500W","D":"1.234m","L":"300.000lx","M":"1.500g","N":"9.800N","O":"4700Ω","An":"90.000°"}`
+	if s := string(b); s != expected {
+		t.Fatal(s)
+	}
+	d := config{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d != c {
+		t.Fatalf("%+v != %+v", d, c)
+	}
+	// Numbers are in the unit of the type.
+	if err := json.Unmarshal([]byte(`{"C":-12.5,"P":101.3,"H":40,"O":10,"An":"0.5rad"}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.C != -12500 || d.P != 101300 || d.H != 4000 || d.O != 10 || d.An != 28648 {
+		t.Fatalf("%+v", d)
+	}
+}
+
+func TestJSON_fail(t *testing.T) {
+	d := struct {
+		V Volt
+	}{V: 1}
+	for i, s := range []string{`{"V":"3.3A"}`, `{"V":true}`, `{"V":"\x"}`} {
+		if err := json.Unmarshal([]byte(s), &d); err == nil {
+			t.Fatalf("#%d: expected error", i)
+		}
+	}
+	if d.V != 1 {
+		t.Fatal("value must not be modified on error")
+	}
+}
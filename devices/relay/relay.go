@@ -0,0 +1,345 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package relay controls relay boards.
+//
+// Both banks of relays driven directly by GPIOs and I²C relay HATs are
+// supported. Most GPIO relay modules are active low, the relay being energized
+// when the input is pulled to ground.
+//
+// The relays are put in their safe state, by default all off, when the board
+// is opened, halted or closed.
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+)
+
+// Board is a model of I²C relay HAT.
+type Board uint8
+
+// Supported I²C relay boards.
+const (
+	// PCF8574 is the common 8 channels active low relay board driven by a
+	// PCF8574 I/O expander; the default address is 0x20.
+	PCF8574 Board = iota
+	// SeeedQuad is the Seeed Studio Grove 4 channels SPDT relay; the default
+	// address is 0x11.
+	SeeedQuad
+)
+
+const boardName = "PCF8574SeeedQuad"
+
+var boardIndex = [...]uint8{0, 7, 16}
+
+func (b Board) String() string {
+	if b >= Board(len(boardIndex)-1) {
+		return fmt.Sprintf("Board(%d)", b)
+	}
+	return boardName[boardIndex[b]:boardIndex[b+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Names are the channel names, used to look them up with Channel(). The
+	// channels are named "1", "2", ... by default.
+	Names []string
+	// SafeState is the bitmask of the channels that are on in the safe state.
+	// Bit 0 is the first channel. The default is all off.
+	SafeState uint32
+}
+
+// Channel is a single relay of a board.
+type Channel struct {
+	d    *Dev
+	n    int
+	name string
+}
+
+func (c *Channel) String() string {
+	return c.name
+}
+
+// Number returns the channel index on the board, starting at 0.
+func (c *Channel) Number() int {
+	return c.n
+}
+
+// Set energizes the relay when on is true.
+func (c *Channel) Set(on bool) error {
+	return c.d.Set(c.n, on)
+}
+
+// On energizes the relay.
+func (c *Channel) On() error {
+	return c.d.Set(c.n, true)
+}
+
+// Off releases the relay.
+func (c *Channel) Off() error {
+	return c.d.Set(c.n, false)
+}
+
+// Pulse energizes the relay for t then releases it.
+//
+// It is meant for momentary operation, like a door strike or the push
+// button of a garage door opener. It blocks for t.
+func (c *Channel) Pulse(t time.Duration) error {
+	return c.d.Pulse(c.n, t)
+}
+
+// IsOn returns true if the relay is energized.
+func (c *Channel) IsOn() bool {
+	return c.d.State()&(1<<uint(c.n)) != 0
+}
+
+// Dev is a relay board.
+type Dev struct {
+	w        writer
+	channels []Channel
+	safe     uint32
+
+	mu     sync.Mutex
+	state  uint32
+	closed bool
+}
+
+// NewGPIO returns a relay bank where each relay is driven by a pin.
+//
+// activeLow must be true for modules where the relay is energized when the
+// input is low, which is the most common.
+func NewGPIO(pins []gpio.PinOut, activeLow bool, opts *Opts) (*Dev, error) {
+	if len(pins) == 0 || len(pins) > 32 {
+		return nil, fmt.Errorf("relay: invalid number of pins %d", len(pins))
+	}
+	for i, p := range pins {
+		if p == nil || p == gpio.INVALID {
+			return nil, fmt.Errorf("relay: invalid pin #%d", i)
+		}
+	}
+	return newDev(&gpioWriter{pins: pins, activeLow: activeLow}, len(pins), opts)
+}
+
+// NewI2C returns a relay HAT of the model b over I²C.
+func NewI2C(bus i2c.Bus, addr uint16, b Board, opts *Opts) (*Dev, error) {
+	c := &i2c.Dev{Bus: bus, Addr: addr}
+	switch b {
+	case PCF8574:
+		return newDev(&pcf8574Writer{c: c}, 8, opts)
+	case SeeedQuad:
+		return newDev(&seeedWriter{c: c}, 4, opts)
+	default:
+		return nil, fmt.Errorf("relay: unsupported board %s", b)
+	}
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("Relay{%s}", d.w)
+}
+
+// Channels returns all the channels of the board.
+func (d *Dev) Channels() []*Channel {
+	out := make([]*Channel, len(d.channels))
+	for i := range d.channels {
+		out[i] = &d.channels[i]
+	}
+	return out
+}
+
+// Channel returns the channel with the given name or nil if not found.
+func (d *Dev) Channel(name string) *Channel {
+	for i := range d.channels {
+		if d.channels[i].name == name {
+			return &d.channels[i]
+		}
+	}
+	return nil
+}
+
+// State returns the bitmask of the relays that are energized.
+func (d *Dev) State() uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+// SetState sets all the relays at once. Bit 0 is the first channel.
+func (d *Dev) SetState(s uint32) error {
+	if s&^d.mask() != 0 {
+		return fmt.Errorf("relay: invalid state %#x", s)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(s)
+}
+
+// Set energizes the relay of the channel n when on is true.
+func (d *Dev) Set(n int, on bool) error {
+	if n < 0 || n >= len(d.channels) {
+		return fmt.Errorf("relay: invalid channel %d", n)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.state &^ (1 << uint(n))
+	if on {
+		s |= 1 << uint(n)
+	}
+	return d.write(s)
+}
+
+// Pulse energizes the relay of the channel n for t then releases it.
+//
+// It blocks for t. The other channels can be used concurrently.
+func (d *Dev) Pulse(n int, t time.Duration) error {
+	if t <= 0 {
+		return fmt.Errorf("relay: invalid pulse duration %s", t)
+	}
+	if err := d.Set(n, true); err != nil {
+		return err
+	}
+	sleep(t)
+	return d.Set(n, false)
+}
+
+// Halt puts all the relays in the safe state.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(d.safe)
+}
+
+// Close puts all the relays in the safe state. The board cannot be used
+// afterward.
+func (d *Dev) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	err := d.write(d.safe)
+	d.closed = true
+	return err
+}
+
+//
+
+var errClosed = errors.New("relay: board is closed")
+
+var defaults = Opts{}
+
+var sleep = time.Sleep
+
+// writer sets the relays on the physical board.
+type writer interface {
+	fmt.Stringer
+	write(s uint32) error
+}
+
+func newDev(w writer, n int, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if len(opts.Names) != 0 && len(opts.Names) != n {
+		return nil, fmt.Errorf("relay: expected %d names, got %d", n, len(opts.Names))
+	}
+	d := &Dev{w: w, channels: make([]Channel, n), safe: opts.SafeState}
+	if d.safe&^d.mask() != 0 {
+		return nil, fmt.Errorf("relay: invalid safe state %#x", d.safe)
+	}
+	names := map[string]bool{}
+	for i := range d.channels {
+		name := fmt.Sprintf("%d", i+1)
+		if len(opts.Names) != 0 {
+			name = opts.Names[i]
+		}
+		if name == "" || names[name] {
+			return nil, fmt.Errorf("relay: invalid channel name %q", name)
+		}
+		names[name] = true
+		d.channels[i] = Channel{d: d, n: i, name: name}
+	}
+	if err := d.w.write(d.safe); err != nil {
+		return nil, fmt.Errorf("relay: %v", err)
+	}
+	d.state = d.safe
+	return d, nil
+}
+
+func (d *Dev) mask() uint32 {
+	return uint32(1<<uint(len(d.channels)) - 1)
+}
+
+// write must be called with d.mu held.
+func (d *Dev) write(s uint32) error {
+	if d.closed {
+		return errClosed
+	}
+	if err := d.w.write(s); err != nil {
+		return fmt.Errorf("relay: %v", err)
+	}
+	d.state = s
+	return nil
+}
+
+type gpioWriter struct {
+	pins      []gpio.PinOut
+	activeLow bool
+}
+
+func (g *gpioWriter) String() string {
+	return fmt.Sprintf("%s", g.pins)
+}
+
+func (g *gpioWriter) write(s uint32) error {
+	for i, p := range g.pins {
+		l := gpio.Level(s&(1<<uint(i)) != 0)
+		if g.activeLow {
+			l = !l
+		}
+		if err := p.Out(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pcf8574Writer drives a PCF8574 board; a low output energizes the relay.
+type pcf8574Writer struct {
+	c *i2c.Dev
+}
+
+func (p *pcf8574Writer) String() string {
+	return fmt.Sprintf("%s %s", PCF8574, p.c)
+}
+
+func (p *pcf8574Writer) write(s uint32) error {
+	return p.c.Tx([]byte{^byte(s)}, nil)
+}
+
+// seeedWriter drives the Seeed Studio Grove 4 channels relay; the state is
+// written with the channel control command.
+type seeedWriter struct {
+	c *i2c.Dev
+}
+
+func (s *seeedWriter) String() string {
+	return fmt.Sprintf("%s %s", SeeedQuad, s.c)
+}
+
+func (s *seeedWriter) write(st uint32) error {
+	return s.c.Tx([]byte{seeedChannelCtrl, byte(st)}, nil)
+}
+
+const seeedChannelCtrl = 0x10
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ fmt.Stringer = &Channel{}
@@ -0,0 +1,323 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package relay
+
+import (
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+	var pins []gpio.PinOut
+	for _, name := range []string{"GPIO5", "GPIO6"} {
+		p := gpioreg.ByName(name)
+		if p == nil {
+			log.Fatalf("failed to find %s", name)
+		}
+		pins = append(pins, p)
+	}
+	d, err := NewGPIO(pins, true, &Opts{Names: []string{"pump", "door"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Close()
+	if err := d.Channel("pump").On(); err != nil {
+		log.Fatal(err)
+	}
+	// Unlock the door strike for a second.
+	if err := d.Channel("door").Pulse(time.Second); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Example_i2c() {
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+	b, err := i2creg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	d, err := NewI2C(b, 0x11, SeeedQuad, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Close()
+	for _, c := range d.Channels() {
+		if err := c.Pulse(500 * time.Millisecond); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+//
+
+func TestBoard_String(t *testing.T) {
+	if s := PCF8574.String(); s != "PCF8574" {
+		t.Fatal(s)
+	}
+	if s := SeeedQuad.String(); s != "SeeedQuad" {
+		t.Fatal(s)
+	}
+	if s := Board(2).String(); s != "Board(2)" {
+		t.Fatal(s)
+	}
+}
+
+func TestNewGPIO(t *testing.T) {
+	p := []*gpiotest.Pin{{N: "A"}, {N: "B"}, {N: "C"}}
+	d, err := NewGPIO([]gpio.PinOut{p[0], p[1], p[2]}, true, &Opts{SafeState: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "Relay{[A(0) B(0) C(0)]}" {
+		t.Fatal(s)
+	}
+	checkLevels(t, p, gpio.High, gpio.High, gpio.Low)
+	c := d.Channels()
+	if len(c) != 3 || c[0].String() != "1" || c[2].Number() != 2 || d.Channel("2") != c[1] {
+		t.Fatal(c)
+	}
+	if d.Channel("foo") != nil {
+		t.Fatal("unexpected channel")
+	}
+	if err := c[0].On(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c[2].Off(); err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.Low, gpio.High, gpio.High)
+	if !c[0].IsOn() || c[1].IsOn() || d.State() != 1 {
+		t.Fatal(d.State())
+	}
+	if err := c[1].Set(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetState(6); err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.High, gpio.Low, gpio.Low)
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.High, gpio.High, gpio.Low)
+	if d.State() != 4 {
+		t.Fatal(d.State())
+	}
+}
+
+func TestNewGPIO_activeHigh(t *testing.T) {
+	p := []*gpiotest.Pin{{N: "A"}, {N: "B"}}
+	d, err := NewGPIO([]gpio.PinOut{p[0], p[1]}, false, &Opts{Names: []string{"pump", "fan"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.Low, gpio.Low)
+	if err := d.Channel("fan").On(); err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.Low, gpio.High)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkLevels(t, p, gpio.Low, gpio.Low)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Channel("pump").On(); err != errClosed {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != errClosed {
+		t.Fatal(err)
+	}
+}
+
+func TestNewGPIO_fail(t *testing.T) {
+	if _, err := NewGPIO(nil, true, nil); err == nil {
+		t.Fatal("no pins")
+	}
+	if _, err := NewGPIO([]gpio.PinOut{gpio.INVALID}, true, nil); err == nil {
+		t.Fatal("invalid pin")
+	}
+	p := &gpiotest.Pin{N: "A"}
+	data := []Opts{
+		{Names: []string{"a", "b"}},
+		{Names: []string{""}},
+		{SafeState: 2},
+	}
+	for i, opts := range data {
+		if _, err := NewGPIO([]gpio.PinOut{p}, true, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := NewGPIO([]gpio.PinOut{p, p}, true, &Opts{Names: []string{"a", "a"}}); err == nil {
+		t.Fatal("duplicate names")
+	}
+	f := &failPin{Pin: gpiotest.Pin{N: "A"}, err: errors.New("injected")}
+	if _, err := NewGPIO([]gpio.PinOut{f}, true, nil); err == nil {
+		t.Fatal("Out() failure")
+	}
+}
+
+func TestDev_fail(t *testing.T) {
+	f := &failPin{Pin: gpiotest.Pin{N: "A"}}
+	d, err := NewGPIO([]gpio.PinOut{f}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set(1, true); err == nil {
+		t.Fatal("invalid channel")
+	}
+	if err := d.SetState(2); err == nil {
+		t.Fatal("invalid state")
+	}
+	if err := d.Pulse(0, 0); err == nil {
+		t.Fatal("invalid duration")
+	}
+	if err := d.Pulse(1, time.Second); err == nil {
+		t.Fatal("invalid channel")
+	}
+	f.err = errors.New("injected")
+	if err := d.Set(0, true); err == nil || err.Error() != "relay: injected" {
+		t.Fatal(err)
+	}
+	if d.State() != 0 {
+		t.Fatal("state must not change on failure")
+	}
+	if err := d.Close(); err == nil {
+		t.Fatal("Out() failure")
+	}
+}
+
+func TestPulse(t *testing.T) {
+	p := &gpiotest.Pin{N: "A"}
+	d, err := NewGPIO([]gpio.PinOut{p}, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept time.Duration
+	sleep = func(t time.Duration) {
+		slept = t
+		if p.Read() != gpio.Low {
+			panic("relay must be energized during the pulse")
+		}
+	}
+	defer func() {
+		sleep = time.Sleep
+	}()
+	if err := d.Channels()[0].Pulse(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if slept != time.Second || p.Read() != gpio.High {
+		t.Fatal(slept, p.Read())
+	}
+}
+
+func TestNewI2C_PCF8574(t *testing.T) {
+	b := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x20, W: []byte{0xFF}},
+			{Addr: 0x20, W: []byte{0xFE}},
+			{Addr: 0x20, W: []byte{0x7E}},
+			{Addr: 0x20, W: []byte{0xFF}},
+		},
+	}
+	d, err := NewI2C(&b, 0x20, PCF8574, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "Relay{PCF8574 playback(32)}" {
+		t.Fatal(s)
+	}
+	if len(d.Channels()) != 8 {
+		t.Fatal(d.Channels())
+	}
+	if err := d.Set(0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Channel("8").On(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_SeeedQuad(t *testing.T) {
+	b := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x11, W: []byte{0x10, 0x00}},
+			{Addr: 0x11, W: []byte{0x10, 0x0A}},
+			{Addr: 0x11, W: []byte{0x10, 0x00}},
+		},
+	}
+	d, err := NewI2C(&b, 0x11, SeeedQuad, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "Relay{SeeedQuad playback(17)}" {
+		t.Fatal(s)
+	}
+	if err := d.SetState(0x0A); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetState(0x10); err == nil {
+		t.Fatal("invalid state")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x20, Board(2), nil); err == nil {
+		t.Fatal("invalid board")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x20, PCF8574, nil); err == nil {
+		t.Fatal("I²C failure")
+	}
+}
+
+//
+
+type failPin struct {
+	gpiotest.Pin
+	err error
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.Pin.Out(l)
+}
+
+func checkLevels(t *testing.T, p []*gpiotest.Pin, l ...gpio.Level) {
+	for i := range p {
+		if r := p[i].Read(); r != l[i] {
+			t.Fatalf("pin %s: %s != %s", p[i], r, l[i])
+		}
+	}
+}
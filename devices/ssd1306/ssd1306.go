@@ -10,13 +10,18 @@
 // when using I²C as the bus default speed (often 100kHz) is slow enough to
 // saturate the bus at less than 10 frames per second.
 //
+// Use SetAutoFlush(false) to accumulate multiple Draw() calls in the back
+// buffer and send them all at once with Flush().
+//
 // The SSD1306 is a write-only device. It can be driven on either I²C or SPI
 // with 4 wires. Changing between protocol is likely done through resistor
 // soldering, for boards that support both.
 //
 // Some boards expose a RES / Reset pin. If present, it must be normally be
-// High. When set to Low (Ground), it enables the reset circuitry. It can be
-// used externally to this driver, if used, the driver must be reinstantiated.
+// High. When set to Low (Ground), it enables the reset circuitry. Pass it to
+// NewSPIReset() to have the driver reset the controller on startup and on
+// Reset(). If it is used externally to this driver, the driver must be
+// reinstantiated.
 //
 // Datasheets
 //
@@ -41,6 +46,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
@@ -82,7 +88,10 @@ type Dev struct {
 	// Communication
 	c   conn.Conn
 	dc  gpio.PinOut
+	rst gpio.PinOut
 	spi bool
+	// init is the initialization command sequence, sent again on Reset().
+	init []byte
 
 	// Display size controlled by the SSD1306.
 	rect image.Rectangle
@@ -101,7 +110,10 @@ type Dev struct {
 	startCol, endCol   int
 	scrolled           bool
 	halted             bool
-	err                error
+	// deferred is true when Draw() and Write() only update next, until
+	// Flush() is called.
+	deferred bool
+	err      error
 }
 
 // NewSPI returns a Dev object that communicates over SPI to a SSD1306 display
@@ -119,10 +131,26 @@ type Dev struct {
 // In 3-wire SPI mode, pass nil for 'dc'. In 4-wire SPI mode, pass a GPIO pin
 // to use.
 //
-// The RES (reset) pin can be used outside of this driver but is not supported
-// natively. In case of external reset via the RES pin, this device drive must
-// be reinstantiated.
+// The RES (reset) pin can be used outside of this driver; use NewSPIReset() to
+// have it managed by the driver. In case of external reset via the RES pin,
+// this device drive must be reinstantiated.
 func NewSPI(p spi.Port, dc gpio.PinOut, w, h int, rotated bool) (*Dev, error) {
+	return newSPI(p, dc, nil, w, h, rotated)
+}
+
+// NewSPIReset is like NewSPI but also manages the RES (reset) pin connected to
+// rst.
+//
+// The controller is hardware reset before being initialized, which recovers
+// it from any prior state. Use Reset() to reset it again afterward.
+func NewSPIReset(p spi.Port, dc, rst gpio.PinOut, w, h int, rotated bool) (*Dev, error) {
+	if rst == nil || rst == gpio.INVALID {
+		return nil, errors.New("ssd1306: a reset pin is required")
+	}
+	return newSPI(p, dc, rst, w, h, rotated)
+}
+
+func newSPI(p spi.Port, dc, rst gpio.PinOut, w, h int, rotated bool) (*Dev, error) {
 	if dc == gpio.INVALID {
 		return nil, errors.New("ssd1306: use nil for dc to use 3-wire mode, do not use gpio.INVALID")
 	}
@@ -137,7 +165,17 @@ func NewSPI(p spi.Port, dc gpio.PinOut, w, h int, rotated bool) (*Dev, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newDev(c, w, h, rotated, true, dc)
+	if rst != nil {
+		if err := resetPulse(rst); err != nil {
+			return nil, err
+		}
+	}
+	d, err := newDev(c, w, h, rotated, true, dc)
+	if err != nil {
+		return nil, err
+	}
+	d.rst = rst
+	return d, nil
 }
 
 // NewI2C returns a Dev object that communicates over I²C to a SSD1306 display
@@ -173,8 +211,9 @@ func newDev(c conn.Conn, w, h int, rotated, usingSPI bool, dc gpio.PinOut) (*Dev
 		endCol:    w,
 		// Signal that the screen must be redrawn on first draw().
 		scrolled: true,
+		init:     getInitCmd(w, h, rotated),
 	}
-	if err := d.sendCommand(getInitCmd(w, h, rotated)); err != nil {
+	if err := d.sendCommand(d.init); err != nil {
 		return nil, err
 	}
 	return d, nil
@@ -246,19 +285,23 @@ func (d *Dev) Bounds() image.Rectangle {
 // It means that on slow bus  (I²C), it may be preferable to defer Draw() calls
 // to a background goroutine.
 //
+// When auto flush is disabled, it only draws in the back buffer and the
+// display is updated on Flush().
+//
 // It discards any failure.
 func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
 	var next []byte
-	if img, ok := src.(*image1bit.VerticalLSB); ok && r == d.Bounds() && src.Bounds() == d.rect && sp.X == 0 && sp.Y == 0 {
+	if img, ok := src.(*image1bit.VerticalLSB); ok && !d.deferred && r == d.Bounds() && src.Bounds() == d.rect && sp.X == 0 && sp.Y == 0 {
 		// Exact size, full frame, image1bit encoding: fast path!
 		next = img.Pix
 	} else {
 		// Double buffering.
-		if d.next == nil {
-			d.next = image1bit.NewVerticalLSB(d.rect)
-		}
+		d.initNext()
 		next = d.next.Pix
 		draw.Src.Draw(d.next, r, src, sp)
+		if d.deferred {
+			return
+		}
 	}
 	d.err = d.drawInternal(next)
 }
@@ -278,6 +321,11 @@ func (d *Dev) Write(pixels []byte) (int, error) {
 	if len(pixels) != len(d.buffer) {
 		return 0, fmt.Errorf("ssd1306: invalid pixel stream length; expected %d bytes, got %d bytes", len(d.buffer), len(pixels))
 	}
+	if d.deferred {
+		d.initNext()
+		copy(d.next.Pix, pixels)
+		return len(pixels), nil
+	}
 	// Write() skips d.next so it saves 1kb of RAM.
 	if err := d.drawInternal(pixels); err != nil {
 		return 0, err
@@ -285,6 +333,40 @@ func (d *Dev) Write(pixels []byte) (int, error) {
 	return len(pixels), nil
 }
 
+// SetAutoFlush enables or disables sending the pixels to the display on each
+// call to Draw() and Write(). It is enabled by default.
+//
+// When disabled, the pixels are kept in a back buffer until Flush() is called.
+// Enabling it back flushes the pending pixels.
+func (d *Dev) SetAutoFlush(on bool) error {
+	if on {
+		if !d.deferred {
+			return nil
+		}
+		d.deferred = false
+		return d.Flush()
+	}
+	if !d.deferred {
+		d.deferred = true
+		if d.next != nil {
+			// Start from the display content.
+			copy(d.next.Pix, d.buffer)
+		}
+	}
+	return nil
+}
+
+// Flush sends the pixels drawn in the back buffer since the last update to
+// the display.
+//
+// Only the smallest rectangle of pages and columns that changed is sent.
+func (d *Dev) Flush() error {
+	if d.next == nil {
+		return nil
+	}
+	return d.drawInternal(d.next.Pix)
+}
+
 // Scroll scrolls an horizontal band.
 //
 // Only one scrolling operation can happen at a time.
@@ -345,6 +427,32 @@ func (d *Dev) Halt() error {
 	return err
 }
 
+// Reset hardware resets the controller via the RES pin, initializes it again
+// and sends back the last pixels drawn.
+//
+// It is useful to recover from glitches, like an electrical interference. It
+// is only supported when created with NewSPIReset(). Scrolling, contrast and
+// inversion are reset to their default.
+func (d *Dev) Reset() error {
+	if d.rst == nil {
+		return errors.New("ssd1306: Reset() requires a reset pin; use NewSPIReset()")
+	}
+	if err := resetPulse(d.rst); err != nil {
+		return err
+	}
+	d.halted = false
+	if err := d.sendCommand(d.init); err != nil {
+		return err
+	}
+	// The initialization sets the window to the whole display.
+	d.startPage = 0
+	d.endPage = d.rect.Dy() / 8
+	d.startCol = 0
+	d.endCol = d.rect.Dx()
+	d.scrolled = false
+	return d.sendData(d.buffer)
+}
+
 // Invert the display (black on white vs white on black).
 func (d *Dev) Invert(blackOnWhite bool) error {
 	b := []byte{0xA6}
@@ -391,30 +499,18 @@ func (d *Dev) calculateSubset(next []byte) (int, int, int, int, bool) {
 			// Early exit, the image is exactly the same.
 			return 0, 0, 0, 0, true
 		}
-		// TODO(maruel): This currently corrupts the screen. Likely a small error
-		// in the way the commands are sent.
-		/*
-				// Left.
-				for ; startCol < endCol; startCol++ {
-					for i := startPage; i < endPage; i++ {
-						x := i*pageSize + startCol
-						if d.buffer[x] != next[x] {
-							goto breakLeft
-						}
-					}
-				}
-			breakLeft:
-				// Right.
-				for ; endCol > startCol; endCol-- {
-					for i := startPage; i < endPage; i++ {
-						x := i*pageSize + endCol - 1
-						if d.buffer[x] != next[x] {
-							goto breakRight
-						}
-					}
-				}
-			breakRight:
-		*/
+		// Left.
+		for ; startCol < endCol; startCol++ {
+			if !d.isColumnEqual(next, startCol, startPage, endPage) {
+				break
+			}
+		}
+		// Right.
+		for ; endCol > startCol; endCol-- {
+			if !d.isColumnEqual(next, endCol-1, startPage, endPage) {
+				break
+			}
+		}
 	}
 	return startPage, endPage, startCol, endCol, false
 }
@@ -441,9 +537,56 @@ func (d *Dev) drawInternal(next []byte) error {
 		}
 	}
 
-	// Write the subset of the data as needed.
+	// Write the subset of the data as needed. The controller wraps to the next
+	// page at the end of the column window.
 	pageSize := d.rect.Dx()
-	return d.sendData(d.buffer[startPage*pageSize+startCol : (endPage-1)*pageSize+endCol])
+	if startCol == 0 && endCol == pageSize {
+		return d.sendData(d.buffer[startPage*pageSize : endPage*pageSize])
+	}
+	data := make([]byte, 0, (endPage-startPage)*(endCol-startCol))
+	for i := startPage; i < endPage; i++ {
+		data = append(data, d.buffer[i*pageSize+startCol:i*pageSize+endCol]...)
+	}
+	return d.sendData(data)
+}
+
+// isColumnEqual returns true if the column col is the same in the buffer and
+// next for the pages in [startPage, endPage).
+func (d *Dev) isColumnEqual(next []byte, col, startPage, endPage int) bool {
+	pageSize := d.rect.Dx()
+	for i := startPage; i < endPage; i++ {
+		if x := i*pageSize + col; d.buffer[x] != next[x] {
+			return false
+		}
+	}
+	return true
+}
+
+// initNext lazily initializes the back buffer.
+//
+// When auto flushing, the back buffer is synchronized with the display content
+// since Write() and the fast path of Draw() skip it.
+func (d *Dev) initNext() {
+	if d.next == nil {
+		d.next = image1bit.NewVerticalLSB(d.rect)
+		copy(d.next.Pix, d.buffer)
+	} else if !d.deferred {
+		copy(d.next.Pix, d.buffer)
+	}
+}
+
+// resetPulse pulls RES low long enough to reset the controller; page 27
+// requires at least 3µs. It then waits for the controller to be ready.
+func resetPulse(rst gpio.PinOut) error {
+	if err := rst.Out(gpio.Low); err != nil {
+		return err
+	}
+	sleep(10 * time.Microsecond)
+	if err := rst.Out(gpio.High); err != nil {
+		return err
+	}
+	sleep(time.Millisecond)
+	return nil
 }
 
 func (d *Dev) sendData(c []byte) error {
@@ -483,6 +626,8 @@ func (d *Dev) sendCommand(c []byte) error {
 	return d.c.Tx(append([]byte{i2cCmd}, c...), nil)
 }
 
+var sleep = time.Sleep
+
 const (
 	i2cCmd  = 0x00 // I²C transaction has stream of command bytes
 	i2cData = 0x40 // I²C transaction has stream of data bytes
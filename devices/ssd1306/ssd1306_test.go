@@ -10,6 +10,7 @@ import (
 	"image/color"
 	"log"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn/conntest"
 	"periph.io/x/periph/conn/gpio"
@@ -442,17 +443,14 @@ func TestSPI_4wire_String(t *testing.T) {
 func TestSPI_4wire_Write_differential(t *testing.T) {
 	buf1 := make([]byte, 1024)
 	buf1[130] = 1
-	buf2 := make([]byte, 128)
-	buf2[130-128] = 1
-	buf2[131-128] = 2
 	port := spitest.Playback{
 		Playback: conntest.Playback{
 			Ops: []conntest.IO{
 				{W: getInitCmd(128, 64, false)},
 				{W: buf1},
-				// Reset to write only to the first page.
-				{W: []byte{0x21, 0x0, 0x7f, 0x22, 0x1, 0x1}},
-				{W: buf2},
+				// Reset to write only to the modified column of the second page.
+				{W: []byte{0x21, 0x3, 0x3, 0x22, 0x1, 0x1}},
+				{W: []byte{2}},
 			},
 		},
 	}
@@ -474,6 +472,89 @@ func TestSPI_4wire_Write_differential(t *testing.T) {
 	}
 }
 
+func TestSPI_4wire_Draw_deferred(t *testing.T) {
+	page0 := make([]byte, 11)
+	page0[0] = 0xF0
+	page0[1] = 0xF0
+	page0[10] = 0x01
+	page1 := make([]byte, 11)
+	page1[0] = 0x0F
+	page1[1] = 0x0F
+	port := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: getInitCmd(128, 64, false)},
+				{W: make([]byte, 1024)},
+				// Both rectangles are sent at once, page by page.
+				{W: []byte{0x21, 10, 20, 0x22, 0x0, 0x1}},
+				{W: append(page0, page1...)},
+				// Write() in the back buffer.
+				{W: []byte{0x21, 64, 64, 0x22, 0x7, 0x7}},
+				{W: []byte{0x80}},
+				// Draw() after Write() keeps the pixels written.
+				{W: []byte{0x21, 0, 0, 0x22, 0x0, 0x0}},
+				{W: []byte{0xFF}},
+				{W: []byte{0x21, 127, 127, 0x22, 0x0, 0x0}},
+				{W: []byte{0x01}},
+			},
+		},
+	}
+	dev, err := NewSPI(&port, &gpiotest.Pin{N: "pin1", Num: 42}, 128, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Write(make([]byte, 1024)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetAutoFlush(false); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing drawn yet.
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	on := &image.Uniform{image1bit.On}
+	dev.Draw(image.Rect(10, 4, 12, 12), on, image.Point{})
+	dev.Draw(image.Rect(20, 0, 21, 1), on, image.Point{})
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// No change.
+	if err := dev.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	pix := make([]byte, 1024)
+	pix[10] = 0xF0
+	pix[11] = 0xF0
+	pix[20] = 0x01
+	pix[138] = 0x0F
+	pix[139] = 0x0F
+	pix[7*128+64] = 0x80
+	if n, err := dev.Write(pix); n != len(pix) || err != nil {
+		t.Fatal(n, err)
+	}
+	if err := dev.SetAutoFlush(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetAutoFlush(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetAutoFlush(true); err != nil {
+		t.Fatal(err)
+	}
+	pix[0] = 0xFF
+	if _, err := dev.Write(pix); err != nil {
+		t.Fatal(err)
+	}
+	dev.Draw(image.Rect(127, 0, 128, 1), on, image.Point{})
+	if err := dev.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSPI_4wire_Write_differential_fail(t *testing.T) {
 	buf1 := make([]byte, 1024)
 	buf1[130] = 1
@@ -528,8 +609,111 @@ func TestSPI_4wire_gpio_fail(t *testing.T) {
 	}
 }
 
+func TestNewSPIReset(t *testing.T) {
+	buf := make([]byte, 1024)
+	buf[3] = 0x80
+	port := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: getInitCmd(128, 64, true)},
+				{W: buf},
+				// Halt.
+				{W: []byte{0xAE}},
+				// Reset.
+				{W: getInitCmd(128, 64, true)},
+				{W: buf},
+			},
+		},
+	}
+	rst := &levelsPin{}
+	dev, err := NewSPIReset(&port, &gpiotest.Pin{N: "pin1", Num: 42}, rst, 128, 64, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rst.levels) != 2 || rst.levels[0] != gpio.Low || rst.levels[1] != gpio.High {
+		t.Fatal(rst.levels)
+	}
+	if n, err := dev.Write(buf); n != len(buf) || err != nil {
+		t.Fatal(n, err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	// Reset sends back the content; the display is no longer halted.
+	if err := dev.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rst.levels) != 4 {
+		t.Fatal(rst.levels)
+	}
+	// Unchanged.
+	if n, err := dev.Write(buf); n != len(buf) || err != nil {
+		t.Fatal(n, err)
+	}
+	if err := port.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPIReset_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "pin1", Num: 42}
+	if d, err := NewSPIReset(&spitest.Playback{}, dc, nil, 128, 64, false); d != nil || err == nil {
+		t.Fatal(d, err)
+	}
+	if d, err := NewSPIReset(&spitest.Playback{}, dc, gpio.INVALID, 128, 64, false); d != nil || err == nil {
+		t.Fatal(d, err)
+	}
+	if d, err := NewSPIReset(&spitest.Playback{}, dc, &failPin{fail: true}, 128, 64, false); d != nil || err == nil {
+		t.Fatal(d, err)
+	}
+	if d, err := NewSPIReset(&spitest.Playback{}, dc, &levelsPin{failHigh: true}, 128, 64, false); d != nil || err == nil {
+		t.Fatal(d, err)
+	}
+	if d, err := NewSPIReset(&spitest.Playback{}, dc, &levelsPin{}, 0, 64, false); d != nil || err == nil {
+		t.Fatal(d, err)
+	}
+}
+
+func TestReset_fail(t *testing.T) {
+	// I²C doesn't support reset.
+	bus := getI2CPlayback()
+	dev, err := NewI2C(bus, 128, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Reset(); err == nil {
+		t.Fatal("reset pin is required")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	port := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops:       []conntest.IO{{W: getInitCmd(128, 64, false)}},
+			DontPanic: true,
+		},
+	}
+	rst := &failPin{}
+	if dev, err = NewSPIReset(&port, &gpiotest.Pin{N: "pin1", Num: 42}, rst, 128, 64, false); err != nil {
+		t.Fatal(err)
+	}
+	rst.fail = true
+	if err := dev.Reset(); err == nil || err.Error() != "injected error" {
+		t.Fatal(err)
+	}
+	rst.fail = false
+	if err := dev.Reset(); err == nil {
+		t.Fatal("expected conntest error")
+	}
+}
+
 //
 
+func init() {
+	sleep = func(time.Duration) {}
+}
+
 func initCmdI2C() []byte {
 	return append([]byte{0}, getInitCmd(128, 64, false)...)
 }
@@ -591,3 +775,18 @@ func (f *failPin) Out(l gpio.Level) error {
 	}
 	return nil
 }
+
+// levelsPin records the levels set.
+type levelsPin struct {
+	gpiotest.Pin
+	levels   []gpio.Level
+	failHigh bool
+}
+
+func (l *levelsPin) Out(v gpio.Level) error {
+	if l.failHigh && v == gpio.High {
+		return errors.New("injected error")
+	}
+	l.levels = append(l.levels, v)
+	return nil
+}
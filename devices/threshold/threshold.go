@@ -0,0 +1,245 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package threshold raises alerts when environmental measurements cross
+// thresholds.
+//
+// It watches the stream returned by devices.Environmental.SenseContinuous()
+// of any driver, so alerting logic doesn't have to be implemented by each
+// driver. Hysteresis prevents a measurement hovering around a threshold
+// from raising a flood of alerts.
+package threshold
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"periph.io/x/periph/devices"
+)
+
+// Threshold is a limit on one measurement of devices.Environment.
+type Threshold struct {
+	// Metric is the measurement watched. Exactly one metric must be set.
+	Metric devices.Metric
+	// Level is in the unit of the devices.Environment field; for example
+	// 30000 is 30°C for devices.MetricTemperature.
+	Level int64
+	// Hysteresis is how far back from Level the measurement must go for the
+	// alert to be cleared. It must not be negative.
+	Hysteresis int64
+	// Below raises the alert when the measurement goes below Level instead of
+	// above.
+	Below bool
+}
+
+func (t *Threshold) String() string {
+	op := ">"
+	if t.Below {
+		op = "<"
+	}
+	return fmt.Sprintf("%s%s%s", t.Metric, op, format(t.Metric, t.Level))
+}
+
+// Event is sent when a threshold is crossed.
+type Event struct {
+	// Threshold is the index of the threshold crossed, as passed to Watch().
+	Threshold int
+	// Active is true when the alert is raised and false when it is cleared.
+	Active bool
+	// Environment is the measurement that crossed the threshold.
+	Environment devices.Environment
+}
+
+// Watcher watches a stream of measurements for threshold crossings.
+type Watcher struct {
+	thresholds []Threshold
+	active     []bool
+	events     chan Event
+	f          func(Event)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Watch returns a Watcher that sends an Event on Events() each time a
+// measurement received from in crosses one of the thresholds.
+//
+// A measurement already beyond a threshold raises an alert right away. The
+// watching ends, and Events() is closed, when in is closed, which happens
+// when Halt() is called on the device, or when Stop() is called.
+func Watch(in <-chan devices.Environment, thresholds []Threshold) (*Watcher, error) {
+	w, err := newWatcher(in, thresholds)
+	if err != nil {
+		return nil, err
+	}
+	w.events = make(chan Event)
+	go w.run(in, w.stop)
+	return w, nil
+}
+
+// WatchFunc is like Watch but calls f for each Event instead.
+//
+// f is called from a single goroutine; the measurements are not received
+// while it runs.
+func WatchFunc(in <-chan devices.Environment, thresholds []Threshold, f func(Event)) (*Watcher, error) {
+	if f == nil {
+		return nil, errors.New("threshold: f is required")
+	}
+	w, err := newWatcher(in, thresholds)
+	if err != nil {
+		return nil, err
+	}
+	w.f = f
+	go w.run(in, w.stop)
+	return w, nil
+}
+
+// Events returns the channel on which the events are sent.
+//
+// It returns nil when created with WatchFunc().
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Active returns true if the alert of the threshold i is raised.
+func (w *Watcher) Active(i int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active[i]
+}
+
+// Wait blocks until the watching ends.
+func (w *Watcher) Wait() {
+	<-w.done
+}
+
+// Stop stops watching. The measurements are not received anymore; the
+// device should be halted too.
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+	w.mu.Unlock()
+	w.Wait()
+}
+
+//
+
+func newWatcher(in <-chan devices.Environment, thresholds []Threshold) (*Watcher, error) {
+	if in == nil {
+		return nil, errors.New("threshold: a channel is required")
+	}
+	if len(thresholds) == 0 {
+		return nil, errors.New("threshold: at least one threshold is required")
+	}
+	for i := range thresholds {
+		t := &thresholds[i]
+		if t.Metric == 0 || t.Metric&(t.Metric-1) != 0 || t.Metric > devices.MetricGas {
+			return nil, fmt.Errorf("threshold: #%d: invalid metric %s", i, t.Metric)
+		}
+		if t.Hysteresis < 0 {
+			return nil, fmt.Errorf("threshold: #%d: invalid hysteresis %d", i, t.Hysteresis)
+		}
+	}
+	return &Watcher{
+		thresholds: append([]Threshold(nil), thresholds...),
+		active:     make([]bool, len(thresholds)),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+func (w *Watcher) run(in <-chan devices.Environment, stop <-chan struct{}) {
+	defer close(w.done)
+	if w.events != nil {
+		defer close(w.events)
+	}
+	for {
+		var e devices.Environment
+		var ok bool
+		select {
+		case <-stop:
+			return
+		case e, ok = <-in:
+			if !ok {
+				return
+			}
+		}
+		for i := range w.thresholds {
+			w.mu.Lock()
+			active := w.active[i]
+			changed := w.thresholds[i].crossed(&e, active)
+			if changed {
+				active = !active
+				w.active[i] = active
+			}
+			w.mu.Unlock()
+			if !changed {
+				continue
+			}
+			ev := Event{Threshold: i, Active: active, Environment: e}
+			if w.f != nil {
+				w.f(ev)
+				continue
+			}
+			select {
+			case w.events <- ev:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// crossed returns true if the measurement changes the state of the alert.
+func (t *Threshold) crossed(e *devices.Environment, active bool) bool {
+	v := value(e, t.Metric)
+	if t.Below {
+		v, level := -v, -t.Level
+		if active {
+			return v <= level-t.Hysteresis
+		}
+		return v > level
+	}
+	if active {
+		return v <= t.Level-t.Hysteresis
+	}
+	return v > t.Level
+}
+
+func value(e *devices.Environment, m devices.Metric) int64 {
+	switch m {
+	case devices.MetricTemperature:
+		return int64(e.Temperature)
+	case devices.MetricPressure:
+		return int64(e.Pressure)
+	case devices.MetricHumidity:
+		return int64(e.Humidity)
+	case devices.MetricLight:
+		return int64(e.Light)
+	default:
+		return int64(e.Gas)
+	}
+}
+
+func format(m devices.Metric, v int64) string {
+	switch m {
+	case devices.MetricTemperature:
+		return devices.Celsius(v).String()
+	case devices.MetricPressure:
+		return devices.KPascal(v).String()
+	case devices.MetricHumidity:
+		return devices.RelativeHumidity(v).String()
+	case devices.MetricLight:
+		return devices.Lux(v).String()
+	default:
+		return devices.Ohm(v).String()
+	}
+}
+
+var _ fmt.Stringer = &Threshold{}
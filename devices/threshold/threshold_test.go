@@ -0,0 +1,200 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package threshold
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/bmxx80"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+	b, err := i2creg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	dev, err := bmxx80.NewI2C(b, 0x76, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := dev.SenseContinuous(time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dev.Halt()
+	thresholds := []Threshold{
+		// Too hot above 30°C, cleared below 29°C.
+		{Metric: devices.MetricTemperature, Level: 30000, Hysteresis: 1000},
+		// Too dry below 25%rH, cleared above 27%rH.
+		{Metric: devices.MetricHumidity, Level: 2500, Hysteresis: 200, Below: true},
+	}
+	w, err := Watch(c, thresholds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for e := range w.Events() {
+		state := "cleared"
+		if e.Active {
+			state = "raised"
+		}
+		fmt.Printf("%s %s\n", &thresholds[e.Threshold], state)
+	}
+}
+
+//
+
+func TestThreshold_String(t *testing.T) {
+	th := Threshold{Metric: devices.MetricTemperature, Level: 30000}
+	if s := th.String(); s != "Temperature>30.000°C" {
+		t.Fatal(s)
+	}
+	data := []struct {
+		th       Threshold
+		expected string
+	}{
+		{Threshold{Metric: devices.MetricPressure, Level: 101325, Below: true}, "Pressure<101.325KPa"},
+		{Threshold{Metric: devices.MetricHumidity, Level: 6000}, "Humidity>60.00%rH"},
+		{Threshold{Metric: devices.MetricLight, Level: 500000}, "Light>500.000lx"},
+		{Threshold{Metric: devices.MetricGas, Level: 50000, Below: true}, "Gas<50000Ω"},
+	}
+	for i, line := range data {
+		if s := line.th.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	in := make(chan devices.Environment)
+	w, err := Watch(in, []Threshold{
+		{Metric: devices.MetricTemperature, Level: 30000, Hysteresis: 1000},
+		{Metric: devices.MetricHumidity, Level: 2500, Hysteresis: 200, Below: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer close(in)
+		for _, e := range []devices.Environment{
+			// Already too dry.
+			{Temperature: 25000, Humidity: 2000},
+			// Exactly at the level doesn't raise.
+			{Temperature: 30000, Humidity: 2400},
+			{Temperature: 30001, Humidity: 2600},
+			// Within the hysteresis.
+			{Temperature: 29500, Humidity: 2699},
+			{Temperature: 29000, Humidity: 2700},
+		} {
+			in <- e
+		}
+	}()
+	expected := []Event{
+		{1, true, devices.Environment{Temperature: 25000, Humidity: 2000}},
+		{0, true, devices.Environment{Temperature: 30001, Humidity: 2600}},
+		{0, false, devices.Environment{Temperature: 29000, Humidity: 2700}},
+		{1, false, devices.Environment{Temperature: 29000, Humidity: 2700}},
+	}
+	for i, exp := range expected {
+		if e := <-w.Events(); e != exp {
+			t.Fatalf("#%d: %#v != %#v", i, e, exp)
+		}
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Fatal("expected closed channel")
+	}
+	w.Wait()
+	if w.Active(0) || w.Active(1) {
+		t.Fatal("expected cleared")
+	}
+	// Stop after the end is fine.
+	w.Stop()
+}
+
+func TestWatch_Stop(t *testing.T) {
+	in := make(chan devices.Environment, 1)
+	w, err := Watch(in, []Threshold{{Metric: devices.MetricLight, Level: 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in <- devices.Environment{Light: 2000}
+	// The event is not read; Stop() unblocks the goroutine.
+	for !w.Active(0) {
+		time.Sleep(time.Millisecond)
+	}
+	w.Stop()
+	if _, ok := <-w.Events(); ok {
+		t.Fatal("expected closed channel")
+	}
+	w.Stop()
+
+	w, err = Watch(make(chan devices.Environment), []Threshold{{Metric: devices.MetricLight, Level: 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+}
+
+func TestWatchFunc(t *testing.T) {
+	in := make(chan devices.Environment)
+	var events []Event
+	w, err := WatchFunc(in, []Threshold{{Metric: devices.MetricGas, Level: 50000, Below: true}}, func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Events() != nil {
+		t.Fatal("expected no channel")
+	}
+	in <- devices.Environment{Gas: 40000}
+	in <- devices.Environment{Gas: 50000}
+	close(in)
+	w.Wait()
+	expected := []Event{
+		{0, true, devices.Environment{Gas: 40000}},
+		{0, false, devices.Environment{Gas: 50000}},
+	}
+	if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+		t.Fatal(events)
+	}
+}
+
+func TestWatch_fail(t *testing.T) {
+	in := make(chan devices.Environment)
+	valid := []Threshold{{Metric: devices.MetricPressure, Level: 100000}}
+	if _, err := Watch(nil, valid); err == nil {
+		t.Fatal("channel is required")
+	}
+	if _, err := Watch(in, nil); err == nil {
+		t.Fatal("threshold is required")
+	}
+	data := []Threshold{
+		{},
+		{Metric: devices.MetricTemperature | devices.MetricHumidity},
+		{Metric: devices.MetricGas << 1},
+		{Metric: devices.MetricTemperature, Hysteresis: -1},
+	}
+	for i, th := range data {
+		if _, err := Watch(in, []Threshold{th}); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+		if _, err := WatchFunc(in, []Threshold{th}, func(Event) {}); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	if _, err := WatchFunc(in, valid, nil); err == nil {
+		t.Fatal("f is required")
+	}
+}
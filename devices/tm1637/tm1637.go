@@ -13,8 +13,12 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strconv"
+	"sync"
 	"time"
 
+	"unicode"
+
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/host/cpu"
@@ -47,6 +51,47 @@ func Digits(n ...int) []byte {
 	return seg
 }
 
+// Text converts a string to a slice of bytes as segments.
+//
+// Digits, space, '-', '_', '=' and most letters are supported, using the
+// lowercase or uppercase glyph when only one of them can be displayed.
+// Unsupported characters are displayed as blank. A '.' or ':' lights the P
+// segment of the preceding character, which is the colon when following the
+// second digit of a 4 digits clock display.
+func Text(s string) []byte {
+	var seg []byte
+	for _, r := range s {
+		if r == '.' || r == ':' {
+			if l := len(seg) - 1; l >= 0 && seg[l]&0x80 == 0 {
+				seg[l] |= 0x80
+			} else {
+				seg = append(seg, 0x80)
+			}
+			continue
+		}
+		v, ok := charToSegment[r]
+		if !ok {
+			if v, ok = charToSegment[unicode.ToUpper(r)]; !ok {
+				v = charToSegment[unicode.ToLower(r)]
+			}
+		}
+		seg = append(seg, v)
+	}
+	return seg
+}
+
+// Number converts a decimal number to a slice of bytes as segments, right
+// aligned on 4 digits.
+//
+// Numbers outside the range [-999, 9999] return more than 4 segment groups.
+func Number(n int) []byte {
+	s := strconv.Itoa(n)
+	for len(s) < 4 {
+		s = " " + s
+	}
+	return Text(s)
+}
+
 // Brightness defines the screen brightness as controlled by the internal PWM.
 type Brightness uint8
 
@@ -67,6 +112,9 @@ const (
 type Dev struct {
 	clk  gpio.PinOut
 	data gpio.PinIO
+
+	mu  sync.Mutex
+	seg [6]byte // Segments currently displayed.
 }
 
 func (d *Dev) String() string {
@@ -75,6 +123,8 @@ func (d *Dev) String() string {
 
 // SetBrightness changes the brightness and/or turns the display on and off.
 func (d *Dev) SetBrightness(b Brightness) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	// This helps reduce jitter a little.
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
@@ -98,27 +148,31 @@ func (d *Dev) Write(seg []byte) (int, error) {
 	if len(seg) > 6 {
 		return 0, errors.New("tm1637: up to 6 segment groups are supported")
 	}
-	// This helps reduce jitter a little.
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
-	// Use auto-incrementing address. It is possible to write to a single
-	// segment but there isn't much point.
-	d.start()
-	d.writeByte(0x40)
-	d.stop()
-	d.start()
-	d.writeByte(0xC0)
-	for i := 0; i < 6; i++ {
-		if len(seg) <= i {
-			d.writeByte(0)
-		} else {
-			d.writeByte(seg[i])
-		}
-	}
-	d.stop()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seg = [6]byte{}
+	copy(d.seg[:], seg)
+	d.write()
 	return len(seg), nil
 }
 
+// SetColon turns the colon of a 4 digits clock display on or off, keeping the
+// digits currently displayed.
+//
+// The colon is the P segment of the second group, so this also controls the
+// dot following the second digit on displays with dots.
+func (d *Dev) SetColon(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if on {
+		d.seg[1] |= 0x80
+	} else {
+		d.seg[1] &^= 0x80
+	}
+	d.write()
+	return nil
+}
+
 // Halt turns the display off.
 func (d *Dev) Halt() error {
 	b := [6]byte{}
@@ -152,6 +206,38 @@ var digitToSegment = []byte{
 	0x3f, 0x06, 0x5b, 0x4f, 0x66, 0x6d, 0x7d, 0x07, 0x7f, 0x6f, 0x77, 0x7c, 0x39, 0x5e, 0x79, 0x71,
 }
 
+// charToSegment maps the characters supported by Text.
+var charToSegment = map[rune]byte{
+	'0': 0x3F, '1': 0x06, '2': 0x5B, '3': 0x4F, '4': 0x66,
+	'5': 0x6D, '6': 0x7D, '7': 0x07, '8': 0x7F, '9': 0x6F,
+	' ': 0x00, '-': 0x40, '_': 0x08, '=': 0x48, '\'': 0x20, '"': 0x22, '°': 0x63,
+	'A': 0x77, 'b': 0x7C, 'C': 0x39, 'c': 0x58, 'd': 0x5E, 'E': 0x79,
+	'F': 0x71, 'G': 0x3D, 'H': 0x76, 'h': 0x74, 'I': 0x30, 'i': 0x10,
+	'J': 0x1E, 'L': 0x38, 'n': 0x54, 'O': 0x3F, 'o': 0x5C, 'P': 0x73,
+	'q': 0x67, 'r': 0x50, 'S': 0x6D, 't': 0x78, 'U': 0x3E, 'u': 0x1C,
+	'y': 0x6E,
+}
+
+// write sends d.seg to the device.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) write() {
+	// This helps reduce jitter a little.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	// Use auto-incrementing address. It is possible to write to a single
+	// segment but there isn't much point.
+	d.start()
+	d.writeByte(0x40)
+	d.stop()
+	d.start()
+	d.writeByte(0xC0)
+	for _, b := range d.seg {
+		d.writeByte(b)
+	}
+	d.stop()
+}
+
 func (d *Dev) start() {
 	d.data.Out(gpio.Low)
 	d.sleepHalfCycle()
@@ -30,6 +30,12 @@ func Example() {
 	if _, err := dev.Write(Clock(12, 00, true)); err != nil {
 		log.Fatalf("failed to write to tm1637: %v", err)
 	}
+	if err := dev.SetColon(false); err != nil {
+		log.Fatalf("failed to write to tm1637: %v", err)
+	}
+	if _, err := dev.Write(Number(-42)); err != nil {
+		log.Fatalf("failed to write to tm1637: %v", err)
+	}
 }
 
 //
@@ -52,8 +58,44 @@ func TestNew(t *testing.T) {
 	if err := dev.Halt(); err != nil {
 		t.Fatal(err)
 	}
-	// TODO(maruel): Check the state of the pins. That's hard since it has to
-	// emulate the quasi-I²C protocol.
+}
+
+func TestWrite_protocol(t *testing.T) {
+	r := &recorder{}
+	dev, err := New(&clkPin{r: r}, &dataPin{r: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Write(Clock(12, 00, false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetColon(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetBrightness(Brightness14); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetColon(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{
+		{0x40}, {0xC0, 0x06, 0x5B, 0x3F, 0x3F, 0x00, 0x00},
+		{0x40}, {0xC0, 0x06, 0xDB, 0x3F, 0x3F, 0x00, 0x00},
+		{0x8F},
+		{0x40}, {0xC0, 0x06, 0x5B, 0x3F, 0x3F, 0x00, 0x00},
+		{0x40}, {0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}
+	if len(r.frames) != len(expected) {
+		t.Fatalf("%x != %x", r.frames, expected)
+	}
+	for i := range expected {
+		if !bytes.Equal(r.frames[i], expected[i]) {
+			t.Fatalf("#%d: %x != %x", i, r.frames[i], expected[i])
+		}
+	}
 }
 
 func TestDigits(t *testing.T) {
@@ -63,6 +105,44 @@ func TestDigits(t *testing.T) {
 	}
 }
 
+func TestText(t *testing.T) {
+	data := []struct {
+		s        string
+		expected []byte
+	}{
+		{"", nil},
+		{"12:34", []byte{0x06, 0xDB, 0x4F, 0x66}},
+		{"3.14", []byte{0xCF, 0x06, 0x66}},
+		{"..", []byte{0x80, 0x80}},
+		{"HELP", []byte{0x76, 0x79, 0x38, 0x73}},
+		{"done", []byte{0x5E, 0x5C, 0x54, 0x79}},
+		{"aBs-", []byte{0x77, 0x7C, 0x6D, 0x40}},
+		{"k?", []byte{0x00, 0x00}},
+	}
+	for i, line := range data {
+		if b := Text(line.s); !bytes.Equal(b, line.expected) {
+			t.Fatalf("#%d: %x != %x", i, b, line.expected)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	data := []struct {
+		n        int
+		expected []byte
+	}{
+		{0, []byte{0x00, 0x00, 0x00, 0x3F}},
+		{-42, []byte{0x00, 0x40, 0x66, 0x5B}},
+		{9999, []byte{0x6F, 0x6F, 0x6F, 0x6F}},
+		{12345, []byte{0x06, 0x5B, 0x4F, 0x66, 0x6D}},
+	}
+	for i, line := range data {
+		if b := Number(line.n); !bytes.Equal(b, line.expected) {
+			t.Fatalf("#%d: %x != %x", i, b, line.expected)
+		}
+	}
+}
+
 func TestNew_clk_fail(t *testing.T) {
 	clk := failPin{fail: true}
 	data := gpiotest.Pin{}
@@ -102,3 +182,70 @@ func (f *failPin) Out(l gpio.Level) error {
 	}
 	return nil
 }
+
+// recorder decodes the quasi-I²C protocol from the pin transitions.
+//
+// Each frame is the bytes sent between a start and a stop condition.
+type recorder struct {
+	clk     gpio.Level
+	data    gpio.Level
+	started bool
+	bits    []gpio.Level
+	cur     []byte
+	frames  [][]byte
+}
+
+func (r *recorder) setClk(l gpio.Level) {
+	if !r.clk && l {
+		r.bits = append(r.bits, r.data)
+		if len(r.bits) == 9 {
+			// LSB first, the 9th bit is the ACK.
+			var b byte
+			for i := 0; i < 8; i++ {
+				if r.bits[i] {
+					b |= 1 << uint(i)
+				}
+			}
+			r.cur = append(r.cur, b)
+			r.bits = nil
+		}
+	}
+	r.clk = l
+}
+
+func (r *recorder) setData(l gpio.Level) {
+	if r.clk {
+		if r.data && !l {
+			// Start.
+			r.started = true
+			r.cur = nil
+			r.bits = nil
+		} else if r.started && r.data == gpio.Low && l == gpio.High {
+			// Stop; the clock raised before the stop is not a bit.
+			r.started = false
+			r.frames = append(r.frames, r.cur)
+			r.bits = nil
+		}
+	}
+	r.data = l
+}
+
+type clkPin struct {
+	gpiotest.Pin
+	r *recorder
+}
+
+func (c *clkPin) Out(l gpio.Level) error {
+	c.r.setClk(l)
+	return nil
+}
+
+type dataPin struct {
+	gpiotest.Pin
+	r *recorder
+}
+
+func (d *dataPin) Out(l gpio.Level) error {
+	d.r.setData(l)
+	return nil
+}
@@ -6,6 +6,7 @@ package devices
 
 import (
 	"fmt"
+	"math"
 )
 
 // Milli is a fixed point value with 0.001 precision.
@@ -18,6 +19,9 @@ func (m Milli) Float64() float64 {
 
 // String returns the value formatted as a string.
 func (m Milli) String() string {
+	if m < 0 && m > -1000 {
+		return fmt.Sprintf("-0.%03d", -m)
+	}
 	d := m % 1000
 	if d < 0 {
 		d = -d
@@ -89,3 +93,162 @@ func (r RelativeHumidity) String() string {
 	}
 	return fmt.Sprintf("%d.%02d%%rH", r/100, m)
 }
+
+// Volt is an electrical potential at a precision of 1mV.
+type Volt Milli
+
+// Float64 returns the value as float64 with 0.001 precision.
+func (v Volt) Float64() float64 {
+	return Milli(v).Float64()
+}
+
+// String returns the electrical potential formatted as a string.
+func (v Volt) String() string {
+	return Milli(v).String() + "V"
+}
+
+// Ampere is an electrical current at a precision of 1mA.
+type Ampere Milli
+
+// Float64 returns the value as float64 with 0.001 precision.
+func (a Ampere) Float64() float64 {
+	return Milli(a).Float64()
+}
+
+// String returns the electrical current formatted as a string.
+func (a Ampere) String() string {
+	return Milli(a).String() + "A"
+}
+
+// Watt is a power at a precision of 1mW.
+type Watt Milli
+
+// Float64 returns the value as float64 with 0.001 precision.
+func (w Watt) Float64() float64 {
+	return Milli(w).Float64()
+}
+
+// String returns the power formatted as a string.
+func (w Watt) String() string {
+	return Milli(w).String() + "W"
+}
+
+// Distance is a length at a precision of 1mm.
+type Distance Milli
+
+// Common lengths.
+const (
+	Millimeter Distance = 1
+	Centimeter Distance = 10
+	Meter      Distance = 1000
+	Kilometer  Distance = 1000000
+)
+
+// Float64 returns the value in meters as float64 with 0.001 precision.
+func (d Distance) Float64() float64 {
+	return Milli(d).Float64()
+}
+
+// String returns the distance formatted as a string.
+func (d Distance) String() string {
+	return Milli(d).String() + "m"
+}
+
+// Millimeters returns the distance in mm.
+func (d Distance) Millimeters() int32 {
+	return int32(d)
+}
+
+// Inches returns the distance in inches, a unit used in the United States.
+func (d Distance) Inches() float64 {
+	return float64(d) / 25.4
+}
+
+// Angle is an angle at a precision of 0.001°.
+type Angle Milli
+
+// Common angles.
+const (
+	Degree Angle = 1000
+	Right  Angle = 90 * Degree
+	Flat   Angle = 180 * Degree
+	Turn   Angle = 360 * Degree
+)
+
+// AngleFromRadians returns the angle in radians rounded to the closest
+// 0.001°.
+func AngleFromRadians(r float64) Angle {
+	return Angle(math.Floor(r*180000/math.Pi + .5))
+}
+
+// Float64 returns the value in degrees as float64 with 0.001 precision.
+func (a Angle) Float64() float64 {
+	return Milli(a).Float64()
+}
+
+// Radians returns the value in radians.
+func (a Angle) Radians() float64 {
+	return float64(a) * math.Pi / 180000
+}
+
+// String returns the angle formatted as a string in degrees.
+func (a Angle) String() string {
+	return Milli(a).String() + "°"
+}
+
+// StringRadians returns the angle formatted as a string in radians.
+func (a Angle) StringRadians() string {
+	return fmt.Sprintf("%.4frad", a.Radians())
+}
+
+// Lux is an illuminance at a precision of 0.001lx.
+type Lux Milli
+
+// Float64 returns the value as float64 with 0.001 precision.
+func (l Lux) Float64() float64 {
+	return Milli(l).Float64()
+}
+
+// String returns the illuminance formatted as a string.
+func (l Lux) String() string {
+	return Milli(l).String() + "lx"
+}
+
+// Mass is a mass at a precision of 1mg.
+type Mass Milli
+
+// Float64 returns the value in grams as float64 with 0.001 precision.
+func (m Mass) Float64() float64 {
+	return Milli(m).Float64()
+}
+
+// String returns the mass formatted as a string.
+func (m Mass) String() string {
+	return Milli(m).String() + "g"
+}
+
+// Newton is a force at a precision of 1mN.
+type Newton Milli
+
+// Float64 returns the value as float64 with 0.001 precision.
+func (n Newton) Float64() float64 {
+	return Milli(n).Float64()
+}
+
+// String returns the force formatted as a string.
+func (n Newton) String() string {
+	return Milli(n).String() + "N"
+}
+
+// Ohm is an electrical resistance at a precision of 1Ω.
+type Ohm int32
+
+// Float64 returns the value as float64.
+func (o Ohm) Float64() float64 {
+	return float64(o)
+}
+
+// String returns the resistance formatted as a string.
+func (o Ohm) String() string {
+	return fmt.Sprintf("%dΩ", o)
+}
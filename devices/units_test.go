@@ -26,6 +26,13 @@ func TestMilli_neg(t *testing.T) {
 	}
 }
 
+func TestMilli_negFraction(t *testing.T) {
+	o := Milli(-10)
+	if s := o.String(); s != "-0.010" {
+		t.Fatalf("%#v", s)
+	}
+}
+
 func TestCelsius(t *testing.T) {
 	o := Celsius(10010)
 	if s := o.String(); s != "10.010°C" {
@@ -78,3 +85,115 @@ func TestRelativeHumidity_neg(t *testing.T) {
 		t.Fatalf("%f", f)
 	}
 }
+
+func TestVolt(t *testing.T) {
+	o := Volt(10010)
+	if s := o.String(); s != "10.010V" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 10.011 || f < 10.009 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestAmpere(t *testing.T) {
+	o := Ampere(-10010)
+	if s := o.String(); s != "-10.010A" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f < -10.011 || f > -10.009 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestWatt(t *testing.T) {
+	o := Watt(10010)
+	if s := o.String(); s != "10.010W" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 10.011 || f < 10.009 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	o := Distance(1234)
+	if s := o.String(); s != "1.234m" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 1.2341 || f < 1.2339 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestLux(t *testing.T) {
+	o := Lux(1234)
+	if s := o.String(); s != "1.234lx" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 1.2341 || f < 1.2339 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestMass(t *testing.T) {
+	o := Mass(1234)
+	if s := o.String(); s != "1.234g" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 1.2341 || f < 1.2339 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestNewton(t *testing.T) {
+	o := Newton(1234)
+	if s := o.String(); s != "1.234N" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 1.2341 || f < 1.2339 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestOhm(t *testing.T) {
+	o := Ohm(123456)
+	if s := o.String(); s != "123456Ω" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f != 123456 {
+		t.Fatalf("%f", f)
+	}
+}
+
+func TestDistance_helpers(t *testing.T) {
+	o := 2*Meter + 5*Centimeter + 4*Millimeter
+	if m := o.Millimeters(); m != 2054 {
+		t.Fatal(m)
+	}
+	if i := (254 * Millimeter).Inches(); i > 10.001 || i < 9.999 {
+		t.Fatalf("%f", i)
+	}
+	if Kilometer != 1000*Meter {
+		t.Fatal(Kilometer)
+	}
+}
+
+func TestAngle(t *testing.T) {
+	o := Right + 500
+	if s := o.String(); s != "90.500°" {
+		t.Fatalf("%#v", s)
+	}
+	if f := o.Float64(); f > 90.501 || f < 90.499 {
+		t.Fatalf("%f", f)
+	}
+	if s := Flat.StringRadians(); s != "3.1416rad" {
+		t.Fatalf("%#v", s)
+	}
+	if r := Turn.Radians(); r > 6.2832 || r < 6.2831 {
+		t.Fatalf("%f", r)
+	}
+	if a := AngleFromRadians(-1.5707963); a != -Right {
+		t.Fatal(a)
+	}
+}
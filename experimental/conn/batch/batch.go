@@ -0,0 +1,130 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package batch groups operations across buses and pins to execute them with
+// minimal gaps.
+//
+// Some devices require a tight sequencing of commands over multiple
+// interfaces, like a display controller that needs its D/C pin set before
+// each SPI command and cleared before the data. A Batch is built once, then
+// Run() as many times as needed.
+//
+// Consecutive SPI operations on the same connection are sent as a single
+// spi.Conn.TxPackets() call, which the Linux spidev driver executes in a
+// single ioctl. GPIO operations are as fast as the pin driver; the SoC drivers
+// (bcm283x, allwinner, etc) write to memory mapped registers.
+package batch
+
+import (
+	"fmt"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Batch is a sequence of operations on buses and pins.
+//
+// The methods append an operation and return the Batch so calls can be
+// chained. The buffers passed are used as-is when Run() is called, so they
+// can be updated between runs.
+type Batch struct {
+	ops []op
+}
+
+// Out appends setting the level of p.
+func (b *Batch) Out(p gpio.PinOut, l gpio.Level) *Batch {
+	b.ops = append(b.ops, op{pin: p, level: l})
+	return b
+}
+
+// SPI appends a transaction on c.
+//
+// It is merged with the previous operation when it is also a SPI transaction
+// on c, keeping CS asserted between them if keepCS is true.
+func (b *Batch) SPI(c spi.Conn, w, r []byte, keepCS bool) *Batch {
+	p := spi.Packet{W: w, R: r, KeepCS: keepCS}
+	if n := len(b.ops); n != 0 && b.ops[n-1].spi == c {
+		b.ops[n-1].packets = append(b.ops[n-1].packets, p)
+		return b
+	}
+	b.ops = append(b.ops, op{spi: c, packets: []spi.Packet{p}})
+	return b
+}
+
+// I2C appends a transaction with the device at addr on bus.
+func (b *Batch) I2C(bus i2c.Bus, addr uint16, w, r []byte) *Batch {
+	b.ops = append(b.ops, op{i2c: bus, addr: addr, w: w, r: r})
+	return b
+}
+
+// Tx appends a transaction on a generic connection.
+func (b *Batch) Tx(c conn.Conn, w, r []byte) *Batch {
+	b.ops = append(b.ops, op{conn: c, w: w, r: r})
+	return b
+}
+
+// Sleep appends a delay.
+func (b *Batch) Sleep(d time.Duration) *Batch {
+	b.ops = append(b.ops, op{sleep: d})
+	return b
+}
+
+// Len returns the number of operations, after merging.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Run executes the operations in order.
+//
+// It stops at the first error, which is returned with the index of the
+// failing operation.
+func (b *Batch) Run() error {
+	for i := range b.ops {
+		if err := b.ops[i].run(); err != nil {
+			return fmt.Errorf("batch: operation #%d failed: %v", i, err)
+		}
+	}
+	return nil
+}
+
+//
+
+// op is one operation. Only one of pin, spi, i2c, conn or sleep is set.
+type op struct {
+	pin   gpio.PinOut
+	level gpio.Level
+
+	spi     spi.Conn
+	packets []spi.Packet
+
+	i2c  i2c.Bus
+	addr uint16
+
+	conn conn.Conn
+	w, r []byte
+
+	sleep time.Duration
+}
+
+func (o *op) run() error {
+	switch {
+	case o.pin != nil:
+		return o.pin.Out(o.level)
+	case o.spi != nil:
+		if len(o.packets) == 1 && !o.packets[0].KeepCS {
+			return o.spi.Tx(o.packets[0].W, o.packets[0].R)
+		}
+		return o.spi.TxPackets(o.packets)
+	case o.i2c != nil:
+		return o.i2c.Tx(o.addr, o.w, o.r)
+	case o.conn != nil:
+		return o.conn.Tx(o.w, o.r)
+	default:
+		time.Sleep(o.sleep)
+		return nil
+	}
+}
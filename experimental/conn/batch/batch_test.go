@@ -0,0 +1,114 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi"
+)
+
+func ExampleBatch() {
+	// Send a command then its data to a display controller, toggling the D/C
+	// pin in between.
+	dc := &gpiotest.Pin{N: "DC"}
+	c := &conntest.Discard{D: conn.Full}
+	b := &Batch{}
+	b.Out(dc, gpio.Low).Tx(c, []byte{0x2C}, nil).Out(dc, gpio.High).Tx(c, make([]byte, 16), nil)
+	if err := b.Run(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(dc.L)
+	// Output:
+	// High
+}
+
+//
+
+func TestBatch_SPI_merge(t *testing.T) {
+	c := &fakeSPI{}
+	other := &fakeSPI{}
+	dc := &gpiotest.Pin{N: "DC"}
+	b := &Batch{}
+	b.SPI(c, []byte{1}, nil, false)
+	b.Out(dc, gpio.High)
+	b.SPI(c, []byte{2}, nil, true).SPI(c, []byte{3}, nil, false).SPI(other, []byte{4}, nil, false)
+	if n := b.Len(); n != 4 {
+		t.Fatal(n)
+	}
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c.tx != 1 || len(c.packets) != 1 || len(c.packets[0]) != 2 || !c.packets[0][0].KeepCS {
+		t.Fatal(c.tx, c.packets)
+	}
+	if other.tx != 1 || len(other.packets) != 0 {
+		t.Fatal(other.tx, other.packets)
+	}
+	if dc.L != gpio.High {
+		t.Fatal(dc.L)
+	}
+}
+
+func TestBatch_I2C(t *testing.T) {
+	bus := &i2ctest.Playback{Ops: []i2ctest.IO{{Addr: 0x76, W: []byte{0xD0}, R: []byte{0x60}}}}
+	r := make([]byte, 1)
+	b := (&Batch{}).I2C(bus, 0x76, []byte{0xD0}, r).Sleep(0)
+	if err := b.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0x60}) {
+		t.Fatal(r)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch_Run_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	b := (&Batch{}).Out(dc, gpio.High).SPI(&fakeSPI{err: errors.New("oops")}, nil, nil, false).Out(dc, gpio.Low)
+	if err := b.Run(); err == nil || err.Error() != "batch: operation #1 failed: oops" {
+		t.Fatal(err)
+	}
+	if dc.L != gpio.High {
+		t.Fatal("the operations after the failure must not be run")
+	}
+}
+
+//
+
+type fakeSPI struct {
+	tx      int
+	packets [][]spi.Packet
+	err     error
+}
+
+func (f *fakeSPI) String() string {
+	return "fakeSPI"
+}
+
+func (f *fakeSPI) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeSPI) Tx(w, r []byte) error {
+	f.tx++
+	return f.err
+}
+
+func (f *fakeSPI) TxPackets(p []spi.Packet) error {
+	f.packets = append(f.packets, p)
+	return f.err
+}
@@ -0,0 +1,317 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package metrics collects the I/O activity of buses and pins and exports it
+// in the Prometheus text exposition format.
+//
+// The buses are instrumented with the interceptors passed to the registries,
+// e.g. i2creg.Open("I2C1", m.I2C("I2C1")), and the pins are instrumented by
+// wrapping them with Pin(). A Registry implements http.Handler so it can be
+// served directly on the /metrics endpoint scraped by Prometheus.
+//
+// The package doesn't depend on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+// DefaultBuckets is the default upper bounds of the latency histogram
+// buckets, in seconds.
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// Registry collects the metrics of the instrumented buses and pins.
+//
+// The zero value is not usable; use New().
+type Registry struct {
+	mu      sync.Mutex
+	buckets []float64
+	buses   map[string]*busStats
+	pins    map[string]*pinStats
+}
+
+// New returns an empty Registry using buckets as the upper bounds of the
+// latency histogram, in seconds and in increasing order.
+//
+// DefaultBuckets is used when buckets is nil.
+func New(buckets []float64) (*Registry, error) {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return nil, fmt.Errorf("metrics: buckets must be in increasing order; %g <= %g", buckets[i], buckets[i-1])
+		}
+	}
+	return &Registry{
+		buckets: append([]float64(nil), buckets...),
+		buses:   map[string]*busStats{},
+		pins:    map[string]*pinStats{},
+	}, nil
+}
+
+// I2C returns an interceptor recording the transactions on the I²C bus name.
+func (r *Registry) I2C(name string) i2c.Interceptor {
+	s := r.bus(name, "i2c")
+	return func(addr uint16, w, rd []byte, next i2c.TxFunc) error {
+		start := now()
+		err := next(addr, w, rd)
+		r.record(s, len(w), len(rd), now().Sub(start), err)
+		return err
+	}
+}
+
+// SPI returns an interceptor recording the transactions on the SPI port name.
+//
+// Each call to Tx() or TxPackets() is recorded as one transaction.
+func (r *Registry) SPI(name string) spi.Interceptor {
+	s := r.bus(name, "spi")
+	return func(p []spi.Packet, next spi.TxFunc) error {
+		start := now()
+		err := next(p)
+		w, rd := 0, 0
+		for i := range p {
+			w += len(p[i].W)
+			rd += len(p[i].R)
+		}
+		r.record(s, w, rd, now().Sub(start), err)
+		return err
+	}
+}
+
+// OneWire returns an interceptor recording the transactions on the 1-wire bus
+// name.
+func (r *Registry) OneWire(name string) onewire.Interceptor {
+	s := r.bus(name, "onewire")
+	return func(w, rd []byte, power onewire.Pullup, next onewire.TxFunc) error {
+		start := now()
+		err := next(w, rd, power)
+		r.record(s, len(w), len(rd), now().Sub(start), err)
+		return err
+	}
+}
+
+// Pin returns p wrapped to count the edges detected by WaitForEdge() and the
+// level changes done by Out().
+func (r *Registry) Pin(p gpio.PinIO) gpio.PinIO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.pins[p.Name()]
+	if s == nil {
+		s = &pinStats{}
+		r.pins[p.Name()] = s
+	}
+	return &pin{PinIO: p, r: r, s: s}
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	c := &countWriter{w: bufio.NewWriter(w)}
+	r.mu.Lock()
+	r.write(c)
+	r.mu.Unlock()
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+	return c.n, c.err
+}
+
+// ServeHTTP implements http.Handler to be scraped by Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+//
+
+var now = time.Now
+
+type busStats struct {
+	kind         string
+	transactions uint64
+	errors       uint64
+	written      uint64
+	read         uint64
+	buckets      []uint64 // Non cumulative
+	sum          time.Duration
+}
+
+type pinStats struct {
+	edges   uint64
+	changes uint64
+}
+
+// bus returns the stats for the bus name, creating it as needed.
+func (r *Registry) bus(name, kind string) *busStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.buses[name]
+	if s == nil {
+		s = &busStats{kind: kind, buckets: make([]uint64, len(r.buckets))}
+		r.buses[name] = s
+	}
+	return s
+}
+
+func (r *Registry) record(s *busStats, w, rd int, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.transactions++
+	if err != nil {
+		s.errors++
+	}
+	s.written += uint64(w)
+	s.read += uint64(rd)
+	s.sum += d
+	secs := d.Seconds()
+	for i, b := range r.buckets {
+		if secs <= b {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+// write writes the metrics.
+//
+// lock must be held.
+func (r *Registry) write(w *countWriter) {
+	buses := make([]string, 0, len(r.buses))
+	for name := range r.buses {
+		buses = append(buses, name)
+	}
+	sort.Strings(buses)
+	pins := make([]string, 0, len(r.pins))
+	for name := range r.pins {
+		pins = append(pins, name)
+	}
+	sort.Strings(pins)
+
+	w.header("periph_bus_transactions_total", "counter", "Number of transactions on the bus.")
+	for _, name := range buses {
+		s := r.buses[name]
+		w.printf("periph_bus_transactions_total{bus=%s,type=%s} %d\n", quote(name), quote(s.kind), s.transactions)
+	}
+	w.header("periph_bus_errors_total", "counter", "Number of transactions on the bus that failed.")
+	for _, name := range buses {
+		w.printf("periph_bus_errors_total{bus=%s} %d\n", quote(name), r.buses[name].errors)
+	}
+	w.header("periph_bus_bytes_total", "counter", "Number of bytes transferred on the bus.")
+	for _, name := range buses {
+		s := r.buses[name]
+		w.printf("periph_bus_bytes_total{bus=%s,direction=\"write\"} %d\n", quote(name), s.written)
+		w.printf("periph_bus_bytes_total{bus=%s,direction=\"read\"} %d\n", quote(name), s.read)
+	}
+	w.header("periph_bus_latency_seconds", "histogram", "Duration of the transactions on the bus.")
+	for _, name := range buses {
+		s := r.buses[name]
+		var total uint64
+		for i, b := range r.buckets {
+			total += s.buckets[i]
+			w.printf("periph_bus_latency_seconds_bucket{bus=%s,le=\"%s\"} %d\n", quote(name), formatFloat(b), total)
+		}
+		w.printf("periph_bus_latency_seconds_bucket{bus=%s,le=\"+Inf\"} %d\n", quote(name), s.transactions)
+		w.printf("periph_bus_latency_seconds_sum{bus=%s} %s\n", quote(name), formatFloat(s.sum.Seconds()))
+		w.printf("periph_bus_latency_seconds_count{bus=%s} %d\n", quote(name), s.transactions)
+	}
+	w.header("periph_pin_edges_total", "counter", "Number of edges detected on the pin.")
+	for _, name := range pins {
+		w.printf("periph_pin_edges_total{pin=%s} %d\n", quote(name), r.pins[name].edges)
+	}
+	w.header("periph_pin_changes_total", "counter", "Number of level changes driven on the pin.")
+	for _, name := range pins {
+		w.printf("periph_pin_changes_total{pin=%s} %d\n", quote(name), r.pins[name].changes)
+	}
+}
+
+// pin implements gpio.PinIO for Registry.Pin().
+type pin struct {
+	gpio.PinIO
+	r *Registry
+	s *pinStats
+
+	mu    sync.Mutex
+	level gpio.Level
+	set   bool // level was set at least once
+}
+
+// Real implements gpio.RealPin.
+func (p *pin) Real() gpio.PinIO {
+	return p.PinIO
+}
+
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	if !p.PinIO.WaitForEdge(timeout) {
+		return false
+	}
+	p.r.mu.Lock()
+	p.s.edges++
+	p.r.mu.Unlock()
+	return true
+}
+
+func (p *pin) Out(l gpio.Level) error {
+	if err := p.PinIO.Out(l); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	changed := p.set && p.level != l
+	p.level = l
+	p.set = true
+	p.mu.Unlock()
+	if changed {
+		p.r.mu.Lock()
+		p.s.changes++
+		p.r.mu.Unlock()
+	}
+	return nil
+}
+
+// countWriter writes to w, remembering the first error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) printf(format string, a ...interface{}) {
+	if c.err == nil {
+		var n int
+		n, c.err = fmt.Fprintf(c.w, format, a...)
+		c.n += int64(n)
+	}
+}
+
+func (c *countWriter) header(name, kind, help string) {
+	c.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns s as a quoted label value.
+func quote(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var _ http.Handler = &Registry{}
+var _ io.WriterTo = &Registry{}
+var _ gpio.PinIO = &pin{}
+var _ gpio.RealPin = &pin{}
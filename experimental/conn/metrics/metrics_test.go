@@ -0,0 +1,183 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+func Example() {
+	m, err := New(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := i2creg.Open("I2C1", m.I2C("I2C1"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	// Use b...
+
+	http.Handle("/metrics", m)
+	log.Fatal(http.ListenAndServe(":9100", nil))
+}
+
+//
+
+func TestNew(t *testing.T) {
+	if _, err := New([]float64{1, 1}); err == nil {
+		t.Fatal("buckets must be increasing")
+	}
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.buckets) != len(DefaultBuckets) {
+		t.Fatal(m.buckets)
+	}
+}
+
+func TestBuses(t *testing.T) {
+	defer fakeNow(2 * time.Millisecond)()
+	m, err := New([]float64{0.001, 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := m.I2C("I2C1")
+	if err := i(0x76, []byte{1}, make([]byte, 2), func(addr uint16, w, r []byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := i(0x76, []byte{1}, nil, func(addr uint16, w, r []byte) error { return errors.New("nack") }); err == nil {
+		t.Fatal("error must be forwarded")
+	}
+	s := m.SPI("SPI0.0")
+	p := []spi.Packet{{W: []byte{1, 2}}, {R: make([]byte, 3)}}
+	if err := s(p, func(p []spi.Packet) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	o := m.OneWire(`1w"0`)
+	if err := o([]byte{1}, nil, onewire.WeakPullup, func(w, r []byte, power onewire.Pullup) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`periph_bus_transactions_total{bus="I2C1",type="i2c"} 2`,
+		`periph_bus_transactions_total{bus="SPI0.0",type="spi"} 1`,
+		`periph_bus_transactions_total{bus="1w\"0",type="onewire"} 1`,
+		`periph_bus_errors_total{bus="I2C1"} 1`,
+		`periph_bus_bytes_total{bus="I2C1",direction="write"} 2`,
+		`periph_bus_bytes_total{bus="I2C1",direction="read"} 2`,
+		`periph_bus_bytes_total{bus="SPI0.0",direction="write"} 2`,
+		`periph_bus_bytes_total{bus="SPI0.0",direction="read"} 3`,
+		`periph_bus_latency_seconds_bucket{bus="I2C1",le="0.001"} 0`,
+		`periph_bus_latency_seconds_bucket{bus="I2C1",le="0.01"} 2`,
+		`periph_bus_latency_seconds_bucket{bus="I2C1",le="+Inf"} 2`,
+		`periph_bus_latency_seconds_sum{bus="I2C1"} 0.004`,
+		`periph_bus_latency_seconds_count{bus="I2C1"} 2`,
+		`# TYPE periph_bus_latency_seconds histogram`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, out.String())
+		}
+	}
+	// The same bus is reused.
+	m.I2C("I2C1")
+	if len(m.buses) != 3 {
+		t.Fatal(m.buses)
+	}
+}
+
+func TestPin(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &gpiotest.Pin{N: "GPIO4", Num: 4, EdgesChan: make(chan gpio.Level, 2)}
+	p := m.Pin(f)
+	if r := p.(gpio.RealPin).Real(); r != f {
+		t.Fatal(r)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("no edge")
+	}
+	f.EdgesChan <- gpio.High
+	f.EdgesChan <- gpio.Low
+	if !p.WaitForEdge(-1) || !p.WaitForEdge(-1) {
+		t.Fatal("expected edges")
+	}
+	for _, l := range []gpio.Level{gpio.High, gpio.High, gpio.Low, gpio.High} {
+		if err := p.Out(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.Pin(f); len(m.pins) != 1 {
+		t.Fatal(m.pins)
+	}
+	if s := m.pins["GPIO4"]; s.edges != 2 || s.changes != 2 {
+		t.Fatal(s)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Pin(&gpiotest.Pin{N: "GPIO5"})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if c := w.Header().Get("Content-Type"); !strings.HasPrefix(c, "text/plain; version=0.0.4") {
+		t.Fatal(c)
+	}
+	if s := w.Body.String(); !strings.Contains(s, "periph_pin_edges_total{pin=\"GPIO5\"} 0\n") {
+		t.Fatal(s)
+	}
+}
+
+func TestWriteTo_fail(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.WriteTo(&failWriter{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+//
+
+// fakeNow makes each call to now() advance by d. Call the returned function
+// to restore it.
+func fakeNow(d time.Duration) func() {
+	t := time.Unix(0, 0)
+	now = func() time.Time {
+		t = t.Add(d)
+		return t
+	}
+	return func() { now = time.Now }
+}
+
+type failWriter struct{}
+
+func (f *failWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("fail")
+}
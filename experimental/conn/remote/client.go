@@ -0,0 +1,330 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Client is a connection to a server.
+//
+// It is safe for concurrent use.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects to a server, e.g. Dial("tcp", "raspberrypi:7081").
+func Dial(network, address string) (*Client, error) {
+	c, err := rpc.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("remote: %v", err)
+	}
+	return &Client{c: c}, nil
+}
+
+// NewClient returns a Client using an already established connection.
+func NewClient(c io.ReadWriteCloser) *Client {
+	return &Client{c: rpc.NewClient(c)}
+}
+
+// Close closes the connection. The server closes the buses opened by the
+// client.
+func (c *Client) Close() error {
+	return c.c.Close()
+}
+
+// List returns the names of the buses and pins available on the server.
+func (c *Client) List() (*List, error) {
+	var r Reply
+	if err := c.call("List", &Args{}, &r); err != nil {
+		return nil, err
+	}
+	return &r.List, nil
+}
+
+// I2C opens an I²C bus on the server by its name, alias or number as
+// accepted by i2creg.Open(). Use "" for the default bus.
+func (c *Client) I2C(name string) (i2c.BusCloser, error) {
+	var r Reply
+	if err := c.call("I2COpen", &Args{Name: name}, &r); err != nil {
+		return nil, err
+	}
+	return &i2cBus{c: c, name: name, h: r.Handle}, nil
+}
+
+// SPI opens a SPI port on the server by its name, alias or number as
+// accepted by spireg.Open(). Use "" for the default port.
+func (c *Client) SPI(name string) (spi.PortCloser, error) {
+	var r Reply
+	if err := c.call("SPIOpen", &Args{Name: name}, &r); err != nil {
+		return nil, err
+	}
+	return &spiPort{c: c, name: name, h: r.Handle}, nil
+}
+
+// OneWire opens a 1-wire bus on the server by its name, alias or number as
+// accepted by onewirereg.Open(). Use "" for the default bus.
+func (c *Client) OneWire(name string) (onewire.BusCloser, error) {
+	var r Reply
+	if err := c.call("OneWireOpen", &Args{Name: name}, &r); err != nil {
+		return nil, err
+	}
+	return &oneWireBus{c: c, name: name, h: r.Handle}, nil
+}
+
+// Pin returns a GPIO pin on the server by its name or number as accepted by
+// gpioreg.ByName().
+//
+// gpio.PinIO methods without an error return value, like Read(), return the
+// zero value on a network error, which is logged with periph.Log().
+func (c *Client) Pin(name string) (gpio.PinIO, error) {
+	var r Reply
+	if err := c.call("PinInfo", &Args{Name: name}, &r); err != nil {
+		return nil, err
+	}
+	return &pin{c: c, name: name, number: r.Number}, nil
+}
+
+//
+
+func (c *Client) call(method string, args *Args, reply *Reply) error {
+	if err := c.c.Call(serviceName+"."+method, args, reply); err != nil {
+		if _, ok := err.(rpc.ServerError); ok {
+			return errors.New(err.Error())
+		}
+		return fmt.Errorf("remote: %v", err)
+	}
+	return nil
+}
+
+// closeHandle releases a bus opened on the server.
+func (c *Client) closeHandle(h int) error {
+	return c.call("Close", &Args{Handle: h}, &Reply{})
+}
+
+// i2cBus implements i2c.BusCloser.
+type i2cBus struct {
+	c    *Client
+	name string
+	h    int
+}
+
+func (i *i2cBus) String() string {
+	return fmt.Sprintf("remote(%s)", i.name)
+}
+
+func (i *i2cBus) Close() error {
+	return i.c.closeHandle(i.h)
+}
+
+func (i *i2cBus) Tx(addr uint16, w, r []byte) error {
+	var reply Reply
+	if err := i.c.call("I2CTx", &Args{Handle: i.h, Addr: addr, W: w, RLen: len(r)}, &reply); err != nil {
+		return err
+	}
+	copy(r, reply.R)
+	return nil
+}
+
+func (i *i2cBus) SetSpeed(hz int64) error {
+	return i.c.call("I2CSetSpeed", &Args{Handle: i.h, Hz: hz}, &Reply{})
+}
+
+// spiPort implements spi.PortCloser.
+type spiPort struct {
+	c    *Client
+	name string
+	h    int
+
+	mu     sync.Mutex
+	duplex conn.Duplex
+}
+
+func (s *spiPort) String() string {
+	return fmt.Sprintf("remote(%s)", s.name)
+}
+
+func (s *spiPort) Close() error {
+	return s.c.closeHandle(s.h)
+}
+
+func (s *spiPort) LimitSpeed(maxHz int64) error {
+	return s.c.call("SPILimitSpeed", &Args{Handle: s.h, Hz: maxHz}, &Reply{})
+}
+
+func (s *spiPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	var r Reply
+	if err := s.c.call("SPIConnect", &Args{Handle: s.h, Hz: maxHz, Mode: mode, Bits: bits}, &r); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.duplex = r.Duplex
+	s.mu.Unlock()
+	return &spiConn{s}, nil
+}
+
+// spiConn implements spi.Conn.
+type spiConn struct {
+	p *spiPort
+}
+
+func (s *spiConn) String() string {
+	return s.p.String()
+}
+
+func (s *spiConn) Tx(w, r []byte) error {
+	var reply Reply
+	if err := s.p.c.call("SPITx", &Args{Handle: s.p.h, W: w, RLen: len(r)}, &reply); err != nil {
+		return err
+	}
+	copy(r, reply.R)
+	return nil
+}
+
+func (s *spiConn) TxPackets(p []spi.Packet) error {
+	args := Args{Handle: s.p.h, Packets: make([]Packet, len(p))}
+	for i := range p {
+		args.Packets[i] = Packet{W: p[i].W, RLen: len(p[i].R), BitsPerWord: p[i].BitsPerWord, KeepCS: p[i].KeepCS}
+	}
+	var reply Reply
+	if err := s.p.c.call("SPITxPackets", &args, &reply); err != nil {
+		return err
+	}
+	for i := range p {
+		if i < len(reply.Packets) {
+			copy(p[i].R, reply.Packets[i])
+		}
+	}
+	return nil
+}
+
+func (s *spiConn) Duplex() conn.Duplex {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	return s.p.duplex
+}
+
+// oneWireBus implements onewire.BusCloser.
+type oneWireBus struct {
+	c    *Client
+	name string
+	h    int
+}
+
+func (o *oneWireBus) String() string {
+	return fmt.Sprintf("remote(%s)", o.name)
+}
+
+func (o *oneWireBus) Close() error {
+	return o.c.closeHandle(o.h)
+}
+
+func (o *oneWireBus) Tx(w, r []byte, power onewire.Pullup) error {
+	var reply Reply
+	if err := o.c.call("OneWireTx", &Args{Handle: o.h, W: w, RLen: len(r), Power: power}, &reply); err != nil {
+		return err
+	}
+	copy(r, reply.R)
+	return nil
+}
+
+func (o *oneWireBus) Search(alarmOnly bool) ([]onewire.Address, error) {
+	var reply Reply
+	if err := o.c.call("OneWireSearch", &Args{Handle: o.h, Alarm: alarmOnly}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Addrs, nil
+}
+
+// pin implements gpio.PinIO and gpio.PinPWM.
+type pin struct {
+	c      *Client
+	name   string
+	number int
+}
+
+func (p *pin) String() string {
+	return fmt.Sprintf("remote(%s)", p.name)
+}
+
+func (p *pin) Name() string {
+	return p.name
+}
+
+func (p *pin) Number() int {
+	return p.number
+}
+
+func (p *pin) Function() string {
+	var r Reply
+	if err := p.c.call("PinInfo", &Args{Name: p.name}, &r); err != nil {
+		p.logErr(err)
+		return ""
+	}
+	return r.Function
+}
+
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	return p.c.call("PinIn", &Args{Name: p.name, Pull: pull, Edge: edge}, &Reply{})
+}
+
+func (p *pin) Read() gpio.Level {
+	var r Reply
+	if err := p.c.call("PinRead", &Args{Name: p.name}, &r); err != nil {
+		p.logErr(err)
+		return gpio.Low
+	}
+	return r.Level
+}
+
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	var r Reply
+	if err := p.c.call("PinWaitForEdge", &Args{Name: p.name, Period: timeout}, &r); err != nil {
+		p.logErr(err)
+		return false
+	}
+	return r.Edge
+}
+
+func (p *pin) Pull() gpio.Pull {
+	var r Reply
+	if err := p.c.call("PinInfo", &Args{Name: p.name}, &r); err != nil {
+		p.logErr(err)
+		return gpio.PullNoChange
+	}
+	return r.Pull
+}
+
+func (p *pin) Out(l gpio.Level) error {
+	return p.c.call("PinOut", &Args{Name: p.name, Level: l}, &Reply{})
+}
+
+func (p *pin) PWM(duty gpio.Duty, period time.Duration) error {
+	return p.c.call("PinPWM", &Args{Name: p.name, Duty: duty, Period: period}, &Reply{})
+}
+
+// logErr emits err to periph.Log().
+func (p *pin) logErr(err error) {
+	periph.Log().Debug("remote: pin call failed", "pin", p.name, "err", err)
+}
+
+var _ i2c.BusCloser = &i2cBus{}
+var _ spi.PortCloser = &spiPort{}
+var _ spi.Conn = &spiConn{}
+var _ onewire.BusCloser = &oneWireBus{}
+var _ gpio.PinIO = &pin{}
+var _ gpio.PinPWM = &pin{}
@@ -0,0 +1,94 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package remote serves the I²C buses, SPI ports, 1-wire buses and GPIO pins
+// of a host over the network, and provides clients implementing the conn
+// interfaces on top of them.
+//
+// It permits running device drivers on a workstation while the hardware is
+// connected to a remote board, e.g. a Raspberry Pi running Serve().
+//
+// The protocol is net/rpc with its default gob encoding, not gRPC, since
+// periph doesn't depend on packages outside of the standard library. It is
+// specific to Go. Each transaction is a round trip on the network, so it is
+// only suitable for low rate devices.
+//
+// The errors are returned as strings; the optional error interfaces, like
+// onewire.NoDevicesError, are not preserved.
+//
+// There is no authentication. The server is read only unless Opts.Write is
+// set; only permit writes on a trusted network.
+package remote
+
+import (
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/spi"
+)
+
+// List is the list of the buses and pins available on the server.
+type List struct {
+	I2C     []string
+	SPI     []string
+	OneWire []string
+	Pins    []string
+}
+
+// Args are the arguments of the remote calls.
+//
+// It is only exported for net/rpc; use Client instead.
+type Args struct {
+	Handle  int    // Handle of an opened bus or port
+	Name    string // Name of a bus, port or pin
+	Addr    uint16
+	W       []byte
+	RLen    int
+	Packets []Packet
+	Hz      int64
+	Mode    spi.Mode
+	Bits    int
+	Pull    gpio.Pull
+	Edge    gpio.Edge
+	Level   gpio.Level
+	Duty    gpio.Duty
+	Period  time.Duration // Also used as the timeout of WaitForEdge
+	Power   onewire.Pullup
+	Alarm   bool
+}
+
+// Packet is a spi.Packet sent with the length of its read buffer instead of
+// the buffer.
+//
+// It is only exported for net/rpc.
+type Packet struct {
+	W           []byte
+	RLen        int
+	BitsPerWord uint8
+	KeepCS      bool
+}
+
+// Reply is the result of the remote calls.
+//
+// It is only exported for net/rpc; use Client instead.
+type Reply struct {
+	Handle   int
+	R        []byte
+	Packets  [][]byte
+	Duplex   conn.Duplex
+	Level    gpio.Level
+	Pull     gpio.Pull
+	Edge     bool
+	Number   int
+	Function string
+	Addrs    []onewire.Address
+	List     List
+}
+
+//
+
+// serviceName is the name of the net/rpc service.
+const serviceName = "Periph"
@@ -0,0 +1,388 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/onewire/onewiretest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func Example() {
+	// On the board, after host.Init():
+	//   l, err := net.Listen("tcp", "localhost:7081")
+	//   log.Fatal(remote.Serve(l, &remote.Opts{Write: true}))
+	c, err := Dial("tcp", "raspberrypi:7081")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	b, err := c.I2C("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	// Use b with any I²C device driver.
+	d := i2c.Dev{Bus: b, Addr: 0x76}
+	id := [1]byte{}
+	if err := d.Tx([]byte{0xD0}, id[:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestList(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	l, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(l.I2C, "REMOTE_I2C") || !contains(l.SPI, "REMOTE_SPI") || !contains(l.OneWire, "REMOTE_1W") || !contains(l.Pins, "REMOTE_PIN") {
+		t.Fatal(l)
+	}
+}
+
+func TestI2C(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	if _, err := c.I2C("INVALID"); err == nil {
+		t.Fatal("invalid bus")
+	}
+	b, err := c.I2C("REMOTE_I2C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.(fmt.Stringer).String(); s != "remote(REMOTE_I2C)" {
+		t.Fatal(s)
+	}
+	r := make([]byte, 2)
+	if err := b.Tx(0x10, []byte{1}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{2, 3}) {
+		t.Fatal(r)
+	}
+	if err := b.Tx(0x10, []byte{4}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetSpeed(100000); err != nil {
+		t.Fatal(err)
+	}
+	// The error of the server is returned.
+	if err := b.Tx(0x10, []byte{5}, nil); err == nil {
+		t.Fatal("playback is empty")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx(0x10, []byte{1}, nil); err == nil {
+		t.Fatal("closed")
+	}
+}
+
+func TestSPI(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	p, err := c.SPI("REMOTE_SPI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LimitSpeed(1000000); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Connect(1000000, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := s.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	r := make([]byte, 2)
+	if err := s.Tx([]byte{1, 2}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{3, 4}) {
+		t.Fatal(r)
+	}
+	// spitest.Playback doesn't support TxPackets, the error is returned.
+	if err := s.TxPackets([]spi.Packet{{W: []byte{5}, R: r[:1]}}); err == nil {
+		t.Fatal("TxPackets is not implemented")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOneWire(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	b, err := c.OneWire("REMOTE_1W")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := make([]byte, 1)
+	if err := b.Tx([]byte{0xCC, 0x44}, r, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if r[0] != 1 {
+		t.Fatal(r)
+	}
+	if _, err := b.Search(false); err == nil {
+		t.Fatal("no devices")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPin(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	if _, err := c.Pin("INVALID"); err == nil {
+		t.Fatal("invalid pin")
+	}
+	p, err := c.Pin("REMOTE_PIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.String(); s != "remote(REMOTE_PIN)" {
+		t.Fatal(s)
+	}
+	if p.Name() != "REMOTE_PIN" || p.Number() != 1000 {
+		t.Fatal(p.Name(), p.Number())
+	}
+	if err := p.In(gpio.PullUp, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Pull(); l != gpio.PullUp {
+		t.Fatal(l)
+	}
+	if p.WaitForEdge(time.Millisecond) {
+		t.Fatal("no edge")
+	}
+	remotePin.EdgesChan <- gpio.High
+	if !p.WaitForEdge(-1) {
+		t.Fatal("edge")
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if l := remotePin.Read(); l != gpio.Low {
+		t.Fatal(l)
+	}
+	if s := p.Function(); s != "GPIO" {
+		t.Fatal(s)
+	}
+	if err := p.(gpio.PinPWM).PWM(gpio.DutyHalf, time.Millisecond); err == nil {
+		t.Fatal("gpiotest.Pin doesn't support PWM")
+	}
+}
+
+func TestPin_disconnected(t *testing.T) {
+	c := connect(t)
+	p, err := c.Pin("REMOTE_PIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if l := p.Read(); l != gpio.Low {
+		t.Fatal(l)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("disconnected")
+	}
+	if s := p.Function(); s != "" {
+		t.Fatal(s)
+	}
+	if err := p.Out(gpio.High); err == nil {
+		t.Fatal("disconnected")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	s, cc := net.Pipe()
+	go ServeConn(s, nil)
+	c := NewClient(cc)
+	defer c.Close()
+	if _, err := c.List(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.I2C("REMOTE_I2C"); err == nil {
+		t.Fatal("read only")
+	}
+	if _, err := c.SPI("REMOTE_SPI"); err == nil {
+		t.Fatal("read only")
+	}
+	if _, err := c.OneWire("REMOTE_1W"); err == nil {
+		t.Fatal("read only")
+	}
+	p, err := c.Pin("REMOTE_PIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remotePin.L = gpio.High
+	if l := p.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); err == nil {
+		t.Fatal("read only")
+	}
+	if err := p.Out(gpio.Low); err == nil {
+		t.Fatal("read only")
+	}
+	if err := p.(gpio.PinPWM).PWM(gpio.DutyHalf, time.Second); err == nil {
+		t.Fatal("read only")
+	}
+}
+
+func TestInvalidRLen(t *testing.T) {
+	c := connect(t)
+	defer c.Close()
+	var r Reply
+	if err := c.call("I2COpen", &Args{Name: "REMOTE_I2C"}, &r); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []int{-1, maxRLen + 1} {
+		if err := c.call("I2CTx", &Args{Handle: r.Handle, Addr: 0x10, RLen: l}, &Reply{}); err == nil {
+			t.Fatal(l)
+		}
+	}
+	if err := c.call("OneWireOpen", &Args{Name: "REMOTE_1W"}, &r); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("OneWireTx", &Args{Handle: r.Handle, RLen: -1}, &Reply{}); err == nil {
+		t.Fatal("negative")
+	}
+	if err := c.call("SPIOpen", &Args{Name: "REMOTE_SPI"}, &r); err != nil {
+		t.Fatal(err)
+	}
+	h := r.Handle
+	if err := c.call("SPIConnect", &Args{Handle: h, Hz: 1000, Bits: 8}, &r); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("SPITx", &Args{Handle: h, RLen: -1}, &Reply{}); err == nil {
+		t.Fatal("negative")
+	}
+	// Each packet is valid but the sum is too large.
+	p := []Packet{{RLen: maxRLen}, {RLen: 1}}
+	if err := c.call("SPITxPackets", &Args{Handle: h, Packets: p}, &Reply{}); err == nil {
+		t.Fatal("too large")
+	}
+	p = []Packet{{RLen: -1}}
+	if err := c.call("SPITxPackets", &Args{Handle: h, Packets: p}, &Reply{}); err == nil {
+		t.Fatal("negative")
+	}
+	// The server is still serving.
+	if _, err := c.List(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- Serve(l, nil)
+	}()
+	c, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.List(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	l.Close()
+	if err := <-done; err == nil {
+		t.Fatal("listener closed")
+	}
+	if _, err := Dial("tcp", l.Addr().String()); err == nil {
+		t.Fatal("listener closed")
+	}
+}
+
+//
+
+var remotePin = &gpiotest.Pin{N: "REMOTE_PIN", Num: 1000, Fn: "GPIO", EdgesChan: make(chan gpio.Level, 1)}
+
+func init() {
+	if err := gpioreg.Register(remotePin, false); err != nil {
+		panic(err)
+	}
+	if err := i2creg.Register("REMOTE_I2C", nil, -1, func() (i2c.BusCloser, error) {
+		return &i2ctest.Playback{
+			Ops: []i2ctest.IO{
+				{Addr: 0x10, W: []byte{1}, R: []byte{2, 3}},
+				{Addr: 0x10, W: []byte{4}},
+			},
+			DontPanic: true,
+		}, nil
+	}); err != nil {
+		panic(err)
+	}
+	if err := spireg.Register("REMOTE_SPI", nil, -1, func() (spi.PortCloser, error) {
+		return &spitest.Playback{
+			Playback: conntest.Playback{
+				Ops: []conntest.IO{
+					{W: []byte{1, 2}, R: []byte{3, 4}},
+				},
+				D:         conn.Full,
+				DontPanic: true,
+			},
+		}, nil
+	}); err != nil {
+		panic(err)
+	}
+	if err := onewirereg.Register("REMOTE_1W", nil, -1, func() (onewire.BusCloser, error) {
+		return &onewiretest.Playback{
+			Ops:       []onewiretest.IO{{W: []byte{0xCC, 0x44}, R: []byte{1}, Pull: onewire.StrongPullup}},
+			DontPanic: true,
+		}, nil
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// connect returns a Client connected to a server over a pipe.
+func connect(t *testing.T) *Client {
+	s, c := net.Pipe()
+	go ServeConn(s, &Opts{Write: true})
+	return NewClient(c)
+}
+
+func contains(l []string, s string) bool {
+	for _, i := range l {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,383 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+// Opts are the options of the server.
+type Opts struct {
+	// Write permits the clients to open the buses and to change the pins.
+	// Otherwise, the clients can only list the buses and the pins, and read
+	// the pins.
+	Write bool
+}
+
+// Serve serves the buses and pins registered in the registries to the
+// clients connecting on l.
+//
+// The buses opened by a client are closed when it disconnects. host.Init()
+// must be called beforehand. Serve returns when l is closed.
+//
+// opts can be nil, in which case the server is read only.
+func Serve(l net.Listener, opts *Opts) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go ServeConn(c, opts)
+	}
+}
+
+// ServeConn serves a single client on c until it disconnects.
+//
+// opts can be nil, in which case the server is read only.
+func ServeConn(c io.ReadWriteCloser, opts *Opts) {
+	s := &service{handles: map[int]io.Closer{}}
+	if opts != nil {
+		s.opts = *opts
+	}
+	r := rpc.NewServer()
+	if err := r.RegisterName(serviceName, s); err != nil {
+		// This is a programming error.
+		panic(err)
+	}
+	r.ServeConn(c)
+	s.close()
+}
+
+//
+
+// service implements the remote calls for a client connection.
+type service struct {
+	opts Opts
+
+	mu      sync.Mutex
+	handles map[int]io.Closer
+	next    int
+}
+
+// openedSPI is an opened SPI port, connected once Connect() is called.
+type openedSPI struct {
+	p spi.PortCloser
+	c spi.Conn
+}
+
+func (s *openedSPI) Close() error {
+	return s.p.Close()
+}
+
+func (s *service) List(args *Args, reply *Reply) error {
+	for _, r := range i2creg.All() {
+		reply.List.I2C = append(reply.List.I2C, r.Name)
+	}
+	for _, r := range spireg.All() {
+		reply.List.SPI = append(reply.List.SPI, r.Name)
+	}
+	for _, r := range onewirereg.All() {
+		reply.List.OneWire = append(reply.List.OneWire, r.Name)
+	}
+	for _, p := range gpioreg.All() {
+		reply.List.Pins = append(reply.List.Pins, p.Name())
+	}
+	return nil
+}
+
+func (s *service) Close(args *Args, reply *Reply) error {
+	s.mu.Lock()
+	h, ok := s.handles[args.Handle]
+	delete(s.handles, args.Handle)
+	s.mu.Unlock()
+	if !ok {
+		return errInvalidHandle
+	}
+	return h.Close()
+}
+
+func (s *service) I2COpen(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	b, err := i2creg.Open(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Handle = s.add(b)
+	return nil
+}
+
+func (s *service) I2CTx(args *Args, reply *Reply) error {
+	b, ok := s.get(args.Handle).(i2c.Bus)
+	if !ok {
+		return errInvalidHandle
+	}
+	var err error
+	if reply.R, err = makeR(args.RLen); err != nil {
+		return err
+	}
+	return b.Tx(args.Addr, args.W, reply.R)
+}
+
+func (s *service) I2CSetSpeed(args *Args, reply *Reply) error {
+	b, ok := s.get(args.Handle).(i2c.Bus)
+	if !ok {
+		return errInvalidHandle
+	}
+	return b.SetSpeed(args.Hz)
+}
+
+func (s *service) SPIOpen(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	p, err := spireg.Open(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Handle = s.add(&openedSPI{p: p})
+	return nil
+}
+
+func (s *service) SPILimitSpeed(args *Args, reply *Reply) error {
+	p, ok := s.get(args.Handle).(*openedSPI)
+	if !ok {
+		return errInvalidHandle
+	}
+	return p.p.LimitSpeed(args.Hz)
+}
+
+func (s *service) SPIConnect(args *Args, reply *Reply) error {
+	p, ok := s.get(args.Handle).(*openedSPI)
+	if !ok {
+		return errInvalidHandle
+	}
+	c, err := p.p.Connect(args.Hz, args.Mode, args.Bits)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	p.c = c
+	s.mu.Unlock()
+	reply.Duplex = c.Duplex()
+	return nil
+}
+
+func (s *service) SPITx(args *Args, reply *Reply) error {
+	c, err := s.spiConn(args.Handle)
+	if err != nil {
+		return err
+	}
+	if reply.R, err = makeR(args.RLen); err != nil {
+		return err
+	}
+	return c.Tx(args.W, reply.R)
+}
+
+func (s *service) SPITxPackets(args *Args, reply *Reply) error {
+	c, err := s.spiConn(args.Handle)
+	if err != nil {
+		return err
+	}
+	p := make([]spi.Packet, len(args.Packets))
+	reply.Packets = make([][]byte, len(args.Packets))
+	total := 0
+	for i, a := range args.Packets {
+		// Bound the sum, not only each packet.
+		if a.RLen > maxRLen-total {
+			return fmt.Errorf("remote: invalid read length %d", a.RLen)
+		}
+		if reply.Packets[i], err = makeR(a.RLen); err != nil {
+			return err
+		}
+		total += a.RLen
+		p[i] = spi.Packet{W: a.W, R: reply.Packets[i], BitsPerWord: a.BitsPerWord, KeepCS: a.KeepCS}
+	}
+	return c.TxPackets(p)
+}
+
+func (s *service) OneWireOpen(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	b, err := onewirereg.Open(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Handle = s.add(b)
+	return nil
+}
+
+func (s *service) OneWireTx(args *Args, reply *Reply) error {
+	b, ok := s.get(args.Handle).(onewire.Bus)
+	if !ok {
+		return errInvalidHandle
+	}
+	var err error
+	if reply.R, err = makeR(args.RLen); err != nil {
+		return err
+	}
+	return b.Tx(args.W, reply.R, args.Power)
+}
+
+func (s *service) OneWireSearch(args *Args, reply *Reply) error {
+	b, ok := s.get(args.Handle).(onewire.Bus)
+	if !ok {
+		return errInvalidHandle
+	}
+	var err error
+	reply.Addrs, err = b.Search(args.Alarm)
+	return err
+}
+
+func (s *service) PinInfo(args *Args, reply *Reply) error {
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Number = p.Number()
+	reply.Function = p.Function()
+	reply.Pull = p.Pull()
+	return nil
+}
+
+func (s *service) PinIn(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	return p.In(args.Pull, args.Edge)
+}
+
+func (s *service) PinRead(args *Args, reply *Reply) error {
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Level = p.Read()
+	return nil
+}
+
+func (s *service) PinWaitForEdge(args *Args, reply *Reply) error {
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	reply.Edge = p.WaitForEdge(args.Period)
+	return nil
+}
+
+func (s *service) PinOut(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	return p.Out(args.Level)
+}
+
+func (s *service) PinPWM(args *Args, reply *Reply) error {
+	if !s.opts.Write {
+		return errReadOnly
+	}
+	p, err := getPin(args.Name)
+	if err != nil {
+		return err
+	}
+	if r, ok := p.(gpio.RealPin); ok {
+		p = r.Real()
+	}
+	pwm, ok := p.(gpio.PinPWM)
+	if !ok {
+		return fmt.Errorf("remote: %s doesn't support PWM", p)
+	}
+	return pwm.PWM(args.Duty, args.Period)
+}
+
+var (
+	errInvalidHandle = errors.New("remote: invalid handle")
+	errReadOnly      = errors.New("remote: the server is read only")
+)
+
+// maxRLen is the maximum number of bytes read in a single call. The length
+// is set by the client, so it must be bounded.
+const maxRLen = 64 * 1024
+
+func (s *service) add(c io.Closer) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	s.handles[s.next] = c
+	return s.next
+}
+
+func (s *service) get(h int) io.Closer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handles[h]
+}
+
+func (s *service) spiConn(h int) (spi.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.handles[h].(*openedSPI)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+	if p.c == nil {
+		return nil, errors.New("remote: Connect() must be called first")
+	}
+	return p.c, nil
+}
+
+// close closes all the buses opened by the client.
+func (s *service) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for h, c := range s.handles {
+		c.Close()
+		delete(s.handles, h)
+	}
+}
+
+func getPin(name string) (gpio.PinIO, error) {
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("remote: invalid pin %q", name)
+	}
+	return p, nil
+}
+
+// makeR returns a read buffer of length l, nil for 0 as expected by the
+// drivers.
+func makeR(l int) ([]byte, error) {
+	if l < 0 || l > maxRLen {
+		return nil, fmt.Errorf("remote: invalid read length %d", l)
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	return make([]byte, l), nil
+}
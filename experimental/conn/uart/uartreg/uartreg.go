@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/experimental/conn/uart"
 )
 
@@ -36,6 +37,8 @@ type Ref struct {
 	// Buses provided by the CPU normally have a 0 based number. Buses provided
 	// via an addon (like over USB) generally are not numbered.
 	Number int
+	// Info is the metadata provided by the driver, if any.
+	Info conn.Info
 	// Open is the factory to open an handle to this UART port.
 	Open Opener
 }
@@ -100,7 +103,8 @@ func All() []*Ref {
 		defer mu.Unlock()
 		out = make(refList, 0, len(byName))
 		for _, v := range byName {
-			r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Open: v.Open}
+			r := &Ref{Name: v.Name, Aliases: make([]string, len(v.Aliases)), Number: v.Number, Info: v.Info, Open: v.Open}
+			r.Info.Features = append([]string(nil), v.Info.Features...)
 			copy(r.Aliases, v.Aliases)
 			out = append(out, r)
 		}
@@ -117,6 +121,12 @@ func All() []*Ref {
 // In this case, the port name should be created from the serial number of the
 // device for unique identification.
 func Register(name string, aliases []string, number int, o Opener) error {
+	return RegisterWithInfo(name, aliases, number, conn.Info{}, o)
+}
+
+// RegisterWithInfo is like Register and also records the metadata of the
+// port, returned in Ref.Info.
+func RegisterWithInfo(name string, aliases []string, number int, info conn.Info, o Opener) error {
 	if len(name) == 0 {
 		return wrapf("can't register a port with no name")
 	}
@@ -169,7 +179,8 @@ func Register(name string, aliases []string, number int, o Opener) error {
 		}
 	}
 
-	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Open: o}
+	r := &Ref{Name: name, Aliases: make([]string, len(aliases)), Number: number, Info: info, Open: o}
+	r.Info.Features = append([]string(nil), info.Features...)
 	copy(r.Aliases, aliases)
 	byName[name] = r
 	if number != -1 {
@@ -178,6 +178,24 @@ func TestRegister_fail(t *testing.T) {
 	}
 }
 
+func TestRegisterWithInfo(t *testing.T) {
+	defer reset()
+	info := conn.Info{Driver: "fake", Path: "/dev/ttyFAKE", MaxSpeed: 115200, Features: []string{"x"}}
+	if err := RegisterWithInfo("a", nil, -1, info, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	// The registry keeps its own copy.
+	info.Features[0] = "y"
+	a := All()
+	if len(a) != 1 || a[0].Info.Driver != "fake" || a[0].Info.Path != "/dev/ttyFAKE" || a[0].Info.MaxSpeed != 115200 || a[0].Info.Features[0] != "x" {
+		t.Fatal(a)
+	}
+	a[0].Info.Features[0] = "z"
+	if f := All()[0].Info.Features[0]; f != "x" {
+		t.Fatal(f)
+	}
+}
+
 func TestUnregister(t *testing.T) {
 	defer reset()
 	if Unregister("") == nil {
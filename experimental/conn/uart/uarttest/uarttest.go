@@ -0,0 +1,275 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package uarttest is meant to be used to test drivers over a fake UART port.
+//
+// Record captures the byte streams exchanged with a real device along with
+// the idle gaps between each I/O, so the timing can be reproduced by Playback
+// when testing a driver's timeout handling. Line errors can be simulated by
+// setting IO.Err.
+package uarttest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/conn/uart"
+)
+
+// IO registers the I/O that happened on either a real or fake UART port.
+type IO struct {
+	// Gap is the idle time on the line before this I/O started, measured from
+	// the end of the previous I/O.
+	Gap time.Duration
+	W   []byte
+	R   []byte
+	// Err is returned by Playback instead of doing the I/O. It can be used to
+	// simulate framing, parity or overrun errors. It is ignored by Record.
+	Err error
+}
+
+// Record implements uart.Conn that records everything written to it.
+//
+// This can then be used to feed to Playback to do "replay" based unit tests.
+type Record struct {
+	sync.Mutex
+	Conn uart.Conn // Conn can be nil if only writes are being recorded.
+	Ops  []IO
+
+	last time.Time
+}
+
+func (r *Record) String() string {
+	return "record"
+}
+
+// Tx implements uart.Conn.
+func (r *Record) Tx(w, read []byte) error {
+	io := IO{}
+	if len(w) != 0 {
+		io.W = make([]byte, len(w))
+		copy(io.W, w)
+	}
+	r.Lock()
+	defer r.Unlock()
+	now := time.Now()
+	if !r.last.IsZero() {
+		io.Gap = now.Sub(r.last)
+	}
+	if r.Conn == nil {
+		if len(read) != 0 {
+			return conntest.Errorf("uarttest: read unsupported when no port is connected")
+		}
+	} else {
+		if err := r.Conn.Tx(w, read); err != nil {
+			return err
+		}
+	}
+	if len(read) != 0 {
+		io.R = make([]byte, len(read))
+		copy(io.R, read)
+	}
+	r.Ops = append(r.Ops, io)
+	r.last = time.Now()
+	return nil
+}
+
+// Duplex implements uart.Conn.
+func (r *Record) Duplex() conn.Duplex {
+	if r.Conn != nil {
+		return r.Conn.Duplex()
+	}
+	return conn.DuplexUnknown
+}
+
+// Speed implements uart.Conn.
+func (r *Record) Speed(baud int) error {
+	if r.Conn != nil {
+		return r.Conn.Speed(baud)
+	}
+	return nil
+}
+
+// Configure implements uart.Conn.
+func (r *Record) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	if r.Conn != nil {
+		return r.Conn.Configure(stopBit, parity, bits)
+	}
+	return nil
+}
+
+// RX implements uart.Pins.
+func (r *Record) RX() gpio.PinIn {
+	if p, ok := r.Conn.(uart.Pins); ok {
+		return p.RX()
+	}
+	return gpio.INVALID
+}
+
+// TX implements uart.Pins.
+func (r *Record) TX() gpio.PinOut {
+	if p, ok := r.Conn.(uart.Pins); ok {
+		return p.TX()
+	}
+	return gpio.INVALID
+}
+
+// RTS implements uart.Pins.
+func (r *Record) RTS() gpio.PinIO {
+	if p, ok := r.Conn.(uart.Pins); ok {
+		return p.RTS()
+	}
+	return gpio.INVALID
+}
+
+// CTS implements uart.Pins.
+func (r *Record) CTS() gpio.PinIO {
+	if p, ok := r.Conn.(uart.Pins); ok {
+		return p.CTS()
+	}
+	return gpio.INVALID
+}
+
+// Playback implements uart.ConnCloser and plays back a recorded I/O flow.
+//
+// While "replay" type of unit tests are of limited value, they still present
+// an easy way to do basic code coverage.
+//
+// Each IO.Gap is slept before the I/O is processed, unless IgnoreGaps is set.
+//
+// Set DontPanic to true to return an error instead of panicking, which is the
+// default.
+type Playback struct {
+	sync.Mutex
+	Ops        []IO
+	Count      int
+	DontPanic  bool
+	IgnoreGaps bool
+	RXPin      gpio.PinIO
+	TXPin      gpio.PinIO
+	RTSPin     gpio.PinIO
+	CTSPin     gpio.PinIO
+
+	// Configuration as set by the driver under test.
+	Baud   int
+	Stop   uart.Stop
+	Parity uart.Parity
+	Bits   int
+}
+
+func (p *Playback) String() string {
+	return "playback"
+}
+
+// Close implements uart.ConnCloser.
+//
+// Close() verifies that all the expected Ops have been consumed.
+func (p *Playback) Close() error {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.Ops) != p.Count {
+		return errorf(p.DontPanic, "uarttest: expected playback to be empty: I/O count %d; expected %d", p.Count, len(p.Ops))
+	}
+	return nil
+}
+
+// Tx implements uart.Conn.
+func (p *Playback) Tx(w, r []byte) error {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.Ops) <= p.Count {
+		return errorf(p.DontPanic, "uarttest: unexpected Tx() (count #%d) expecting uarttest.IO{W:%#v, R:%#v}", p.Count, w, r)
+	}
+	op := &p.Ops[p.Count]
+	if !p.IgnoreGaps && op.Gap > 0 {
+		time.Sleep(op.Gap)
+	}
+	if op.Err != nil {
+		p.Count++
+		return op.Err
+	}
+	if !bytes.Equal(op.W, w) {
+		return errorf(p.DontPanic, "uarttest: unexpected write (count #%d) %#v != %#v", p.Count, w, op.W)
+	}
+	if len(op.R) != len(r) {
+		return errorf(p.DontPanic, "uarttest: unexpected read buffer length (count #%d) %d != %d", p.Count, len(r), len(op.R))
+	}
+	copy(r, op.R)
+	p.Count++
+	return nil
+}
+
+// Duplex implements uart.Conn.
+func (p *Playback) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+// Speed implements uart.Conn.
+func (p *Playback) Speed(baud int) error {
+	if baud <= 0 {
+		return conntest.Errorf("uarttest: invalid speed %d", baud)
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.Baud = baud
+	return nil
+}
+
+// Configure implements uart.Conn.
+func (p *Playback) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	if bits < 5 || bits > 9 {
+		return conntest.Errorf("uarttest: invalid number of bits %d", bits)
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.Stop = stopBit
+	p.Parity = parity
+	p.Bits = bits
+	return nil
+}
+
+// RX implements uart.Pins.
+func (p *Playback) RX() gpio.PinIn {
+	return p.RXPin
+}
+
+// TX implements uart.Pins.
+func (p *Playback) TX() gpio.PinOut {
+	return p.TXPin
+}
+
+// RTS implements uart.Pins.
+func (p *Playback) RTS() gpio.PinIO {
+	return p.RTSPin
+}
+
+// CTS implements uart.Pins.
+func (p *Playback) CTS() gpio.PinIO {
+	return p.CTSPin
+}
+
+//
+
+// errorf is the internal implementation that optionally panic.
+//
+// If dontPanic is false, it panics instead.
+func errorf(dontPanic bool, format string, a ...interface{}) error {
+	err := conntest.Errorf(format, a...)
+	if !dontPanic {
+		panic(err)
+	}
+	return err
+}
+
+var _ uart.Conn = &Record{}
+var _ uart.Pins = &Record{}
+var _ uart.ConnCloser = &Playback{}
+var _ uart.Pins = &Playback{}
+var _ fmt.Stringer = &Record{}
+var _ fmt.Stringer = &Playback{}
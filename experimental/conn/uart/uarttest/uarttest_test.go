@@ -0,0 +1,184 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package uarttest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/experimental/conn/uart"
+)
+
+func TestRecord_empty(t *testing.T) {
+	r := Record{}
+	if s := r.String(); s != "record" {
+		t.Fatal(s)
+	}
+	if d := r.Duplex(); d != conn.DuplexUnknown {
+		t.Fatal(d)
+	}
+	if err := r.Speed(9600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Configure(uart.One, uart.None, 8); err != nil {
+		t.Fatal(err)
+	}
+	if r.Tx(nil, []byte{'a'}) == nil {
+		t.Fatal("Conn is nil")
+	}
+	if p := r.RX(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if p := r.TX(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if p := r.RTS(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if p := r.CTS(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+}
+
+func TestRecord_Tx(t *testing.T) {
+	p := &Playback{Ops: []IO{{W: []byte("AT\r"), R: []byte("OK\r")}, {W: []byte("x")}}}
+	r := Record{Conn: p}
+	if d := r.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if err := r.Speed(9600); err != nil || p.Baud != 9600 {
+		t.Fatal(err, p.Baud)
+	}
+	if err := r.Configure(uart.Two, uart.Even, 7); err != nil || p.Bits != 7 {
+		t.Fatal(err, p.Bits)
+	}
+	var b [3]byte
+	if err := r.Tx([]byte("AT\r"), b[:]); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := r.Tx([]byte("x"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Ops) != 2 {
+		t.Fatal(r.Ops)
+	}
+	if !bytes.Equal(r.Ops[0].R, []byte("OK\r")) || r.Ops[0].Gap != 0 {
+		t.Fatalf("%#v", r.Ops[0])
+	}
+	if r.Ops[1].Gap < time.Millisecond {
+		t.Fatal(r.Ops[1].Gap)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	p := Playback{
+		RXPin:  &gpiotest.Pin{N: "RX"},
+		TXPin:  &gpiotest.Pin{N: "TX"},
+		RTSPin: &gpiotest.Pin{N: "RTS"},
+		CTSPin: &gpiotest.Pin{N: "CTS"},
+	}
+	if s := p.String(); s != "playback" {
+		t.Fatal(s)
+	}
+	if d := p.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if n := p.RX().Name(); n != "RX" {
+		t.Fatal(n)
+	}
+	if n := p.TX().Name(); n != "TX" {
+		t.Fatal(n)
+	}
+	if n := p.RTS().Name(); n != "RTS" {
+		t.Fatal(n)
+	}
+	if n := p.CTS().Name(); n != "CTS" {
+		t.Fatal(n)
+	}
+	if err := p.Speed(0); err == nil {
+		t.Fatal("invalid speed")
+	}
+	if err := p.Configure(uart.One, uart.None, 10); err == nil {
+		t.Fatal("invalid bits")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		v := recover()
+		err, ok := v.(error)
+		if !ok {
+			t.Fatal("expected error")
+		}
+		if !conntest.IsErr(err) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}()
+	p.Tx(nil, nil)
+	t.Fatal("shouldn't run")
+}
+
+func TestPlayback_Tx(t *testing.T) {
+	oops := errors.New("framing error")
+	p := Playback{
+		Ops: []IO{
+			{W: []byte{10}, R: []byte{12}},
+			{Gap: 5 * time.Millisecond, R: []byte{13}},
+			{Err: oops},
+		},
+		DontPanic: true,
+	}
+	v := [1]byte{}
+	if err := p.Tx([]byte{10}, v[:]); err != nil || v[0] != 12 {
+		t.Fatal(err, v)
+	}
+	start := time.Now()
+	if err := p.Tx(nil, v[:]); err != nil || v[0] != 13 {
+		t.Fatal(err, v)
+	}
+	if d := time.Since(start); d < 5*time.Millisecond {
+		t.Fatalf("gap wasn't respected: %s", d)
+	}
+	if err := p.Tx(nil, v[:]); err != oops {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Tx(nil, nil); err == nil {
+		t.Fatal("playback is empty")
+	}
+}
+
+func TestPlayback_Tx_err(t *testing.T) {
+	p := Playback{
+		Ops:        []IO{{Gap: time.Hour, W: []byte{10}, R: []byte{12}}},
+		DontPanic:  true,
+		IgnoreGaps: true,
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("Ops is not empty")
+	}
+	v := [1]byte{}
+	if err := p.Tx([]byte{11}, v[:]); err == nil {
+		t.Fatal("invalid write")
+	}
+	if err := p.Tx([]byte{10}, nil); err == nil {
+		t.Fatal("invalid read size")
+	}
+	if err := p.Tx([]byte{10}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package a4988 controls a bipolar stepper motor through an Allegro A4988 or
+// a Texas Instruments DRV8825 step/direction driver.
+//
+// The motion follows a trapezoidal profile. The position is tracked in
+// software, in microsteps relative to the position at initialization.
+//
+// Dev implements motor.Stepper.
+//
+// Current limit
+//
+// The current limit must be set with the trimmer potentiometer of the
+// carrier board before driving the motor, by measuring the reference voltage
+// Vref, to protect both the motor and the driver. With the sense resistors Rs
+// of the board:
+//
+//   A4988:   Imax = Vref / (8 × Rs); Rs is 0.068Ω on current Pololu boards.
+//   DRV8825: Imax = Vref / (5 × Rs); Rs is 0.1Ω on Pololu boards, so
+//            Imax = 2 × Vref.
+//
+// In full step mode, the current through each coil is limited to 70% of
+// Imax.
+//
+// Datasheet
+//
+// https://www.allegromicro.com/-/media/files/datasheets/a4988-datasheet.pdf
+//
+// http://www.ti.com/lit/ds/symlink/drv8825.pdf
+package a4988
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/devices/motor"
+	"periph.io/x/periph/host/cpu"
+)
+
+// Model is the driver chip.
+type Model int
+
+// Supported models.
+const (
+	A4988   Model = 0
+	DRV8825 Model = 1
+)
+
+const modelName = "A4988DRV8825"
+
+var modelIndex = [...]uint8{0, 5, 12}
+
+func (m Model) String() string {
+	if m < 0 || m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Model is the driver chip.
+	Model Model
+	// Microsteps is the number of microsteps per full step; 1, 2, 4, 8 or 16,
+	// and also 32 for the DRV8825. 0 is the same as 1.
+	Microsteps int
+	// MS are the optional pins connected to MS1 to MS3 on the A4988, or M0 to
+	// M2 on the DRV8825, to configure Microsteps.
+	//
+	// Leave them nil when the pins are hardwired to match Microsteps.
+	MS [3]gpio.PinOut
+	// Enable is the optional pin connected to ENABLE, active low. When
+	// specified, Halt() disables the outputs.
+	Enable gpio.PinOut
+	// Speed is the maximum speed in steps per second.
+	Speed int
+	// Acceleration is in steps per second², used both to accelerate and to
+	// decelerate. 0 disables the ramps.
+	Acceleration int
+}
+
+// Dev is a handle to a stepper motor driven by an A4988 or a DRV8825.
+type Dev struct {
+	step    gpio.PinOut
+	dir     gpio.PinOut
+	opts    Opts
+	profile motor.Profile
+	timing  timing
+
+	mu   sync.Mutex
+	pos  int
+	err  error         // Error of the last motion.
+	stop chan struct{} // Closed to abort the current motion.
+	done chan struct{} // Closed when the current motion ended.
+}
+
+// New returns an object that drives a stepper motor through the STEP and DIR
+// inputs of an A4988 or a DRV8825.
+func New(step, dir gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Model != A4988 && opts.Model != DRV8825 {
+		return nil, errors.New("a4988: invalid model")
+	}
+	ms := opts.Microsteps
+	if ms == 0 {
+		ms = 1
+	}
+	bits, ok := microsteps[opts.Model][ms]
+	if !ok {
+		return nil, fmt.Errorf("a4988: %s doesn't support %d microsteps", opts.Model, opts.Microsteps)
+	}
+	if opts.Speed <= 0 {
+		return nil, errors.New("a4988: speed must be positive")
+	}
+	if opts.Acceleration < 0 {
+		return nil, errors.New("a4988: acceleration must not be negative")
+	}
+	if !isSet(step) || !isSet(dir) {
+		return nil, errors.New("a4988: step and dir are required")
+	}
+	d := &Dev{
+		step:    step,
+		dir:     dir,
+		opts:    *opts,
+		profile: motor.Profile{Speed: opts.Speed, Acceleration: opts.Acceleration},
+		timing:  timings[opts.Model],
+	}
+	for i, p := range opts.MS {
+		if isSet(p) {
+			if err := p.Out(bits&(1<<uint(i)) != 0); err != nil {
+				return nil, fmt.Errorf("a4988: %v", err)
+			}
+		}
+	}
+	if err := step.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("a4988: %v", err)
+	}
+	if err := d.enable(gpio.Low); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s, %s}", d.opts.Model, d.step, d.dir)
+}
+
+// MoveTo starts moving the motor to the absolute position pos, in steps.
+//
+// It returns immediately; use Wait() to wait for the motion to complete. The
+// current motion, if any, is stopped first.
+func (d *Dev) MoveTo(pos int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.start(pos)
+}
+
+// Move starts moving the motor by the number of steps relative to the current
+// position. Negative values turn the motor backward.
+//
+// It returns immediately; use Wait() to wait for the motion to complete. The
+// current motion, if any, is stopped first.
+func (d *Dev) Move(steps int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.start(d.pos + steps)
+}
+
+// Wait blocks until the current motion completes or is stopped.
+//
+// It returns the error that aborted the motion, if any.
+func (d *Dev) Wait() error {
+	d.mu.Lock()
+	done := d.done
+	d.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Moving returns true if a motion is in progress.
+func (d *Dev) Moving() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done == nil {
+		return false
+	}
+	select {
+	case <-d.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Position returns the current position, in steps.
+func (d *Dev) Position() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pos
+}
+
+// SetPosition stops the current motion, if any, and defines the current
+// position as pos, e.g. after homing the motor against a limit switch.
+func (d *Dev) SetPosition(pos int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pos = pos
+	return nil
+}
+
+// Stop stops the current motion immediately, without deceleration.
+//
+// The motor holds its position.
+func (d *Dev) Stop() error {
+	d.stopMoving()
+	return nil
+}
+
+// Halt stops the current motion and, when Opts.Enable was specified, disables
+// the outputs so the motor doesn't draw current. The next motion enables the
+// outputs again.
+func (d *Dev) Halt() error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enable(gpio.High)
+}
+
+//
+
+var defaults = Opts{
+	Model:        A4988,
+	Microsteps:   1,
+	Speed:        200,
+	Acceleration: 400,
+}
+
+// microsteps maps the number of microsteps to the MS pins levels, bit 0 is
+// MS1 or M0.
+var microsteps = [...]map[int]byte{
+	A4988:   {1: 0x0, 2: 0x1, 4: 0x2, 8: 0x3, 16: 0x7},
+	DRV8825: {1: 0x0, 2: 0x1, 4: 0x2, 8: 0x3, 16: 0x4, 32: 0x5},
+}
+
+// timing is the minimum timing of the STEP and DIR inputs.
+type timing struct {
+	pulse time.Duration // STEP high time.
+	setup time.Duration // DIR setup time before the STEP rising edge.
+}
+
+var timings = [...]timing{
+	A4988:   {time.Microsecond, 200 * time.Nanosecond},
+	DRV8825: {1900 * time.Nanosecond, 650 * time.Nanosecond},
+}
+
+// after is overridden in unit tests.
+var after = time.After
+
+// start starts the motion to the target position.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) start(target int) error {
+	d.err = nil
+	n := target - d.pos
+	if n == 0 {
+		return nil
+	}
+	if err := d.enable(gpio.Low); err != nil {
+		return err
+	}
+	if err := d.dir.Out(n < 0); err != nil {
+		return fmt.Errorf("a4988: %v", err)
+	}
+	cpu.Nanospin(d.timing.setup)
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		d.moving(n, stop)
+	}(d.stop, d.done)
+	return nil
+}
+
+func (d *Dev) stopMoving() {
+	d.mu.Lock()
+	stop := d.stop
+	done := d.done
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (d *Dev) moving(n int, stop <-chan struct{}) {
+	dir := 1
+	if n < 0 {
+		dir = -1
+		n = -n
+	}
+	for i := 0; i < n; i++ {
+		d.mu.Lock()
+		err := d.pulse()
+		if err != nil {
+			d.err = err
+		} else {
+			d.pos += dir
+		}
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to step: %v", d, err)
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-after(d.profile.Delay(i, n)):
+		}
+	}
+}
+
+func (d *Dev) pulse() error {
+	if err := d.step.Out(gpio.High); err != nil {
+		return fmt.Errorf("a4988: %v", err)
+	}
+	cpu.Nanospin(d.timing.pulse)
+	if err := d.step.Out(gpio.Low); err != nil {
+		return fmt.Errorf("a4988: %v", err)
+	}
+	return nil
+}
+
+// enable sets the ENABLE pin, if specified.
+func (d *Dev) enable(l gpio.Level) error {
+	if isSet(d.opts.Enable) {
+		if err := d.opts.Enable.Out(l); err != nil {
+			return fmt.Errorf("a4988: %v", err)
+		}
+	}
+	return nil
+}
+
+func isSet(p gpio.PinOut) bool {
+	return p != nil && p != gpio.INVALID
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ motor.Stepper = &Dev{}
@@ -0,0 +1,302 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package a4988
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	opts := Opts{
+		Model:        DRV8825,
+		Microsteps:   16,
+		Enable:       gpioreg.ByName("GPIO22"),
+		Speed:        3200,
+		Acceleration: 6400,
+	}
+	dev, err := New(gpioreg.ByName("GPIO17"), gpioreg.ByName("GPIO27"), &opts)
+	if err != nil {
+		log.Fatalf("failed to initialize a4988: %v", err)
+	}
+	defer dev.Halt()
+	// One revolution of a 200 steps per revolution motor.
+	if err := dev.Move(200 * 16); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNew(t *testing.T) {
+	r := &recorder{}
+	dev, err := New(r.pin("step"), r.pin("dir"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "A4988{step(0), dir(0)}" {
+		t.Fatal(s)
+	}
+	r.check(t, []string{"step=Low"})
+	if err := dev.Move(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"dir=Low", "step=High", "step=Low", "step=High", "step=Low"})
+	if err := dev.MoveTo(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != -1 {
+		t.Fatal(p)
+	}
+	r.check(t, []string{"dir=High", "step=High", "step=Low", "step=High", "step=Low", "step=High", "step=Low"})
+	// No-op.
+	if err := dev.MoveTo(-1); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Moving() {
+		t.Fatal("not moving")
+	}
+	if err := dev.SetPosition(10); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != 10 {
+		t.Fatal(p)
+	}
+	// Without an enable pin, Halt only stops.
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, nil)
+}
+
+func TestNew_Enable_Microsteps(t *testing.T) {
+	r := &recorder{}
+	opts := Opts{
+		Model:      DRV8825,
+		Microsteps: 32,
+		MS:         [3]gpio.PinOut{r.pin("m0"), nil, r.pin("m2")},
+		Enable:     r.pin("en"),
+		Speed:      100,
+	}
+	dev, err := New(r.pin("step"), r.pin("dir"), &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "DRV8825{step(0), dir(0)}" {
+		t.Fatal(s)
+	}
+	r.check(t, []string{"m0=High", "m2=High", "step=Low", "en=Low"})
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"en=High"})
+	// Enabled again.
+	if err := dev.Move(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"en=Low", "dir=Low", "step=High", "step=Low"})
+}
+
+func TestStop(t *testing.T) {
+	defer func() {
+		after = afterNow
+	}()
+	tick := make(chan time.Time)
+	after = func(time.Duration) <-chan time.Time {
+		return tick
+	}
+	r := &recorder{}
+	dev, err := New(r.pin("step"), r.pin("dir"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(100); err != nil {
+		t.Fatal(err)
+	}
+	tick <- time.Time{}
+	if !dev.Moving() {
+		t.Fatal("moving")
+	}
+	if err := dev.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Moving() {
+		t.Fatal("stopped")
+	}
+	if p := dev.Position(); p != 2 {
+		t.Fatal(p)
+	}
+	// Starting a new motion aborts the current one.
+	if err := dev.Move(-100); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(2); err != nil {
+		t.Fatal(err)
+	}
+	tick <- time.Time{}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != 2 {
+		t.Fatal(p)
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	p := &gpiotest.Pin{}
+	data := []Opts{
+		{Model: 2, Speed: 100},
+		{Model: A4988, Microsteps: 32, Speed: 100},
+		{Model: DRV8825, Microsteps: 3, Speed: 100},
+		{Speed: 0},
+		{Speed: 100, Acceleration: -1},
+	}
+	for i, opts := range data {
+		if _, err := New(p, p, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := New(p, gpio.INVALID, nil); err == nil {
+		t.Fatal("invalid pin")
+	}
+	if _, err := New(&failPin{}, p, nil); err == nil {
+		t.Fatal("step failed")
+	}
+	if _, err := New(p, p, &Opts{Speed: 1, MS: [3]gpio.PinOut{&failPin{}}}); err == nil {
+		t.Fatal("ms failed")
+	}
+	if _, err := New(p, p, &Opts{Speed: 1, Enable: &failPin{}}); err == nil {
+		t.Fatal("enable failed")
+	}
+	dev, err := New(p, &failPin{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Move(1); err == nil {
+		t.Fatal("dir failed")
+	}
+	en := &failPin{after: 1}
+	if dev, err = New(p, p, &Opts{Speed: 1, Enable: en}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Move(1); err == nil {
+		t.Fatal("enable failed")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("enable failed")
+	}
+	for n := 1; n < 3; n++ {
+		step := &failPin{after: n}
+		if dev, err = New(step, p, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.Move(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.Wait(); err == nil {
+			t.Fatalf("#%d: step failed", n)
+		}
+		if p := dev.Position(); p != 0 {
+			t.Fatal(p)
+		}
+	}
+}
+
+func TestModel_String(t *testing.T) {
+	if s := DRV8825.String(); s != "DRV8825" {
+		t.Fatal(s)
+	}
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	after = afterNow
+}
+
+func afterNow(time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	c <- time.Time{}
+	return c
+}
+
+// recorder logs the pins written.
+type recorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *recorder) pin(name string) *recordPin {
+	return &recordPin{Pin: gpiotest.Pin{N: name}, r: r}
+}
+
+// check verifies the pins written since the last call.
+func (r *recorder) check(t *testing.T, expected []string) {
+	r.mu.Lock()
+	l := r.log
+	r.log = nil
+	r.mu.Unlock()
+	if len(l) != len(expected) {
+		t.Fatalf("%s != %s", l, expected)
+	}
+	for i := range l {
+		if l[i] != expected[i] {
+			t.Fatalf("%s != %s", l, expected)
+		}
+	}
+}
+
+type recordPin struct {
+	gpiotest.Pin
+	r *recorder
+}
+
+func (p *recordPin) Out(l gpio.Level) error {
+	p.r.mu.Lock()
+	defer p.r.mu.Unlock()
+	p.r.log = append(p.r.log, fmt.Sprintf("%s=%s", p.N, l))
+	return nil
+}
+
+// failPin fails after a number of successful writes.
+type failPin struct {
+	gpiotest.Pin
+	after int
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	if f.after == 0 {
+		return errors.New("injected error")
+	}
+	f.after--
+	return nil
+}
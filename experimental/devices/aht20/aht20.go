@@ -0,0 +1,310 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package aht20 controls an Aosong AHT10 or AHT20 humidity and temperature
+// sensor over I²C.
+//
+// The sensor only supports triggered measurements; SenseContinuous() polls the
+// device at the requested interval. The data read from the AHT20 is CRC
+// checked, the AHT10 doesn't provide a CRC.
+//
+// Datasheet
+//
+// http://www.aosong.com/userfiles/files/media/AHT10%20%E8%A7%84%E6%A0%BC%E4%B9%A6.pdf
+//
+// http://www.aosong.com/userfiles/files/media/AHT20%20%E8%8B%B1%E6%96%87%E7%89%88%E8%AF%B4%E6%98%8E%E4%B9%A6%20A0%202020-12-8.pdf
+package aht20
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Model is the sensor model, which determines the initialization command and
+// whether the measurements are CRC checked.
+type Model uint8
+
+// Supported models.
+const (
+	AHT20 Model = 0
+	AHT10 Model = 1
+)
+
+const modelName = "AHT20AHT10"
+
+var modelIndex = [...]uint8{0, 5, 10}
+
+func (m Model) String() string {
+	if m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Model Model
+}
+
+// Dev is a handle to an AHT10 or AHT20 device.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to an AHT10 or AHT20.
+//
+// The address is 0x38. The AHT10 can also use 0x39 when its ADDR pin is
+// pulled high.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	switch {
+	case opts.Model > AHT10:
+		return nil, fmt.Errorf("aht20: invalid model %d", opts.Model)
+	case addr == 0x38, addr == 0x39 && opts.Model == AHT10:
+	default:
+		return nil, errors.New("aht20: given address not supported by device")
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.opts.Model, d.c)
+}
+
+// Sense requests a one time measurement as °C and % of relative humidity.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("aht20: already sensing continuously")
+	}
+	return d.measure(env)
+}
+
+// SenseContinuous returns measurements as °C and % of relative humidity on a
+// continuous basis.
+//
+// The datasheet recommends to not measure more than once every 2 seconds to
+// limit self-heating.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < measDuration {
+		return nil, errors.New("aht20: interval is shorter than measurement duration")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Reset soft resets the device then loads the calibration again.
+func (d *Dev) Reset() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("aht20: already sensing continuously")
+	}
+	if err := d.write(cmdSoftReset); err != nil {
+		return err
+	}
+	sleep(20 * time.Millisecond)
+	return d.init()
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature | devices.MetricHumidity
+}
+
+// Halt stops the sensor from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+const (
+	statusBusy       = 0x80
+	statusCalibrated = 0x08
+
+	// measDuration is the typical measurement duration; page 8.
+	measDuration = 80 * time.Millisecond
+)
+
+var (
+	cmdStatus    = []byte{0x71}
+	cmdSoftReset = []byte{0xBA}
+	cmdMeasure   = []byte{0xAC, 0x33, 0x00}
+	// cmdInit is indexed by Model.
+	cmdInit = [...][]byte{{0xBE, 0x08, 0x00}, {0xE1, 0x08, 0x00}}
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// init loads the calibration coefficients when not already done by the
+// device.
+func (d *Dev) init() error {
+	// Power on time.
+	sleep(40 * time.Millisecond)
+	var s [1]byte
+	if err := d.c.Tx(cmdStatus, s[:]); err != nil {
+		return fmt.Errorf("aht20: %v", err)
+	}
+	if s[0]&statusCalibrated != 0 {
+		return nil
+	}
+	if err := d.write(cmdInit[d.opts.Model]); err != nil {
+		return err
+	}
+	sleep(10 * time.Millisecond)
+	if err := d.c.Tx(cmdStatus, s[:]); err != nil {
+		return fmt.Errorf("aht20: %v", err)
+	}
+	if s[0]&statusCalibrated == 0 {
+		return errors.New("aht20: calibration failed")
+	}
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.measure(&e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// measure triggers a measurement and waits for its completion.
+//
+// The reply is the status byte, 20 bits of humidity, 20 bits of temperature
+// and for the AHT20 the CRC.
+func (d *Dev) measure(env *devices.Environment) error {
+	if err := d.write(cmdMeasure); err != nil {
+		return err
+	}
+	sleep(measDuration)
+	b := make([]byte, 7)
+	if d.opts.Model == AHT10 {
+		b = b[:6]
+	}
+	for i := 0; ; i++ {
+		if err := d.c.Tx(nil, b); err != nil {
+			return fmt.Errorf("aht20: %v", err)
+		}
+		if b[0]&statusBusy == 0 {
+			break
+		}
+		if i == 10 {
+			return errors.New("aht20: timeout waiting for measurement")
+		}
+		sleep(5 * time.Millisecond)
+	}
+	if d.opts.Model == AHT20 && crc8(b[:6]) != b[6] {
+		return errors.New("aht20: invalid CRC")
+	}
+	env.Humidity = rawToHumidity(uint32(b[1])<<12 | uint32(b[2])<<4 | uint32(b[3])>>4)
+	env.Temperature = rawToTemp(uint32(b[3]&0x0F)<<16 | uint32(b[4])<<8 | uint32(b[5]))
+	env.Time = time.Now()
+	return nil
+}
+
+func (d *Dev) write(cmd []byte) error {
+	if err := d.c.Tx(cmd, nil); err != nil {
+		return fmt.Errorf("aht20: %v", err)
+	}
+	return nil
+}
+
+// rawToHumidity converts a 20 bits raw value to %rH.
+func rawToHumidity(raw uint32) devices.RelativeHumidity {
+	return devices.RelativeHumidity((int64(raw)*10000 + 1<<19) >> 20)
+}
+
+// rawToTemp converts a 20 bits raw value to °C.
+func rawToTemp(raw uint32) devices.Celsius {
+	return devices.Celsius((int64(raw)*200000+1<<19)>>20 - 50000)
+}
+
+// crc8 calculates the CRC with polynomial 0x31 and initialization 0xFF.
+func crc8(b []byte) byte {
+	crc := byte(0xFF)
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+var defaults = Opts{
+	Model: AHT20,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,267 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package aht20
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x38, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize aht20: %v", err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%8s %9s\n", env.Temperature, env.Humidity)
+}
+
+//
+
+// 25°C, 50%rH.
+var measurement = []byte{0x1C, 0x80, 0x00, 0x06, 0x00, 0x00, 0x4E}
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			// Not calibrated.
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x10}},
+			{Addr: 0x38, W: []byte{0xBE, 0x08, 0x00}},
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x18}},
+			{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}},
+			// Busy.
+			{Addr: 0x38, R: []byte{0x9C, 0, 0, 0, 0, 0, 0}},
+			{Addr: 0x38, R: measurement},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x38, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "AHT20{playback(56)}" {
+		t.Fatal(s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 25000, Humidity: 5000, Time: env.Time}); env != expected {
+		t.Fatalf("%#v != %#v", env, expected)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_AHT10(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x39, W: []byte{0x71}, R: []byte{0x00}},
+			{Addr: 0x39, W: []byte{0xE1, 0x08, 0x00}},
+			{Addr: 0x39, W: []byte{0x71}, R: []byte{0x08}},
+			{Addr: 0x39, W: []byte{0xAC, 0x33, 0x00}},
+			{Addr: 0x39, R: []byte{0x1C, 0x5A, 0x3C, 0x25, 0xE3, 0x54}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x39, &Opts{Model: AHT10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "AHT10{playback(57)}" {
+		t.Fatal(s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 23600, Humidity: 3525, Time: env.Time}); env != expected {
+		t.Fatalf("%#v != %#v", env, expected)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x39, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x38, &Opts{Model: 2}); err == nil {
+		t.Fatal("bad model")
+	}
+	bus := i2ctest.Playback{DontPanic: true}
+	if _, err := NewI2C(&bus, 0x38, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	bus = i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x38, W: []byte{0x71}, R: []byte{0x00}}},
+		DontPanic: true,
+	}
+	if _, err := NewI2C(&bus, 0x38, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	bus = i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x00}},
+			{Addr: 0x38, W: []byte{0xBE, 0x08, 0x00}},
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x00}},
+		},
+	}
+	if _, err := NewI2C(&bus, 0x38, nil); err == nil {
+		t.Fatal("calibration failed")
+	}
+}
+
+func TestSense_fail(t *testing.T) {
+	bad := make([]byte, len(measurement))
+	copy(bad, measurement)
+	bad[6] = 0
+	busy := []i2ctest.IO{{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}}}
+	for i := 0; i < 11; i++ {
+		busy = append(busy, i2ctest.IO{Addr: 0x38, R: []byte{0x80, 0, 0, 0, 0, 0, 0}})
+	}
+	data := [][]i2ctest.IO{
+		{{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}}, {Addr: 0x38, R: bad}},
+		busy,
+		{{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}}},
+		nil,
+	}
+	for i, ops := range data {
+		bus := i2ctest.Playback{
+			Ops:       append([]i2ctest.IO{{Addr: 0x38, W: []byte{0x71}, R: []byte{0x18}}}, ops...),
+			DontPanic: true,
+		}
+		dev, err := NewI2C(&bus, 0x38, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var env devices.Environment
+		if err := dev.Sense(&env); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x18}},
+			{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}},
+			{Addr: 0x38, R: measurement},
+			{Addr: 0x38, W: []byte{0xAC, 0x33, 0x00}},
+			{Addr: 0x38, R: measurement},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x38, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if env := <-c; env.Temperature != 25000 || env.Humidity != 5000 {
+			t.Fatal(env)
+		}
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Reset(); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x18}},
+			{Addr: 0x38, W: []byte{0xBA}},
+			{Addr: 0x38, W: []byte{0x71}, R: []byte{0x18}},
+		},
+		DontPanic: true,
+	}
+	dev, err := NewI2C(&bus, 0x38, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Reset(); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestRawConversion(t *testing.T) {
+	if h := rawToHumidity(0); h != 0 {
+		t.Fatal(h)
+	}
+	if h := rawToHumidity(0xFFFFF); h != 10000 {
+		t.Fatal(h)
+	}
+	if c := rawToTemp(0); c != -50000 {
+		t.Fatal(c)
+	}
+	if c := rawToTemp(0xFFFFF); c != 150000 {
+		t.Fatal(c)
+	}
+}
+
+func TestModel(t *testing.T) {
+	if s := AHT10.String(); s != "AHT10" {
+		t.Fatal(s)
+	}
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
@@ -0,0 +1,343 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package amg8833 controls a Panasonic AMG88xx Grid-EYE 8x8 thermal camera
+// over I²C.
+//
+// Datasheet
+//
+// https://industrial.panasonic.com/cdbs/www-data/pdf/ADI8000/ADI8000C66.pdf
+//
+// https://cdn.sparkfun.com/assets/4/1/c/0/1/Grid-EYE_Datasheet.pdf
+package amg8833
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// FrameRate is the rate at which the device updates the frames.
+type FrameRate uint8
+
+// Possible frame rates.
+const (
+	FPS10 FrameRate = 0
+	FPS1  FrameRate = 1
+)
+
+const frameRateName = "FPS10FPS1"
+
+var frameRateIndex = [...]uint8{0, 5, 9}
+
+func (f FrameRate) String() string {
+	if f >= FrameRate(len(frameRateIndex)-1) {
+		return fmt.Sprintf("FrameRate(%d)", f)
+	}
+	return frameRateName[frameRateIndex[f]:frameRateIndex[f+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	FrameRate FrameRate
+	// MovingAverage averages each pixel over the two last frames, halving the
+	// noise at the cost of latency.
+	MovingAverage bool
+}
+
+// Interrupt configures the pixel interrupts, which assert the INT pin.
+//
+// The temperatures have a resolution of 0.25°C.
+type Interrupt struct {
+	// Low and High are the thresholds outside which a pixel triggers an
+	// interrupt.
+	Low  devices.Celsius
+	High devices.Celsius
+	// Hysteresis is the margin a pixel has to go back inside the thresholds to
+	// clear its interrupt.
+	Hysteresis devices.Celsius
+	// Difference compares the thresholds with the difference between the
+	// current and the previous frame instead of the absolute temperature.
+	Difference bool
+}
+
+// Frame is an AMG8833 frame, containing 12 bits resolution intensity stored
+// as image.Gray16.
+//
+// The values are offset by 2048 so they are positive; 2048 is 0°C and each 1
+// increment is 0.25°C. Use Temperature() to decode the values.
+type Frame struct {
+	*image.Gray16
+	// Thermistor is the temperature of the device, with a resolution of
+	// 0.0625°C.
+	Thermistor devices.Celsius
+}
+
+// Temperature returns the temperature of a pixel.
+func (f *Frame) Temperature(x, y int) devices.Celsius {
+	return devices.Celsius((int32(f.Gray16At(x, y).Y) - 2048) * 250)
+}
+
+// Dev is a handle to an AMG8833.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+
+	mu     sync.Mutex
+	intr   *Interrupt
+	halted bool
+}
+
+// NewI2C returns an object that communicates over I²C to an AMG8833.
+//
+// The address is 0x69, or 0x68 when the AD_SELECT pin is grounded.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x68, 0x69:
+	default:
+		return nil, errors.New("amg8833: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.FrameRate > FPS1 {
+		return nil, fmt.Errorf("amg8833: invalid frame rate %d", opts.FrameRate)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("AMG8833{%s}", d.c)
+}
+
+// ReadFrame returns the latest frame.
+//
+// The first frame is available one frame period after the device is
+// initialized.
+func (d *Dev) ReadFrame() (*Frame, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wake(); err != nil {
+		return nil, err
+	}
+	var t [2]byte
+	if err := d.readReg(regThermistor, t[:]); err != nil {
+		return nil, err
+	}
+	var b [128]byte
+	if err := d.readReg(regPixels, b[:]); err != nil {
+		return nil, err
+	}
+	f := &Frame{Gray16: image.NewGray16(image.Rect(0, 0, 8, 8)), Thermistor: thermistorToTemp(t[:])}
+	for i := 0; i < 64; i++ {
+		// 12 bits two's complement.
+		raw := int16(uint16(b[2*i])<<4|uint16(b[2*i+1])<<12) >> 4
+		v := uint16(raw + 2048)
+		f.Pix[2*i] = byte(v >> 8)
+		f.Pix[2*i+1] = byte(v)
+	}
+	return f, nil
+}
+
+// SetInterrupt enables the pixel interrupts.
+//
+// Use nil to disable the interrupts.
+func (d *Dev) SetInterrupt(i *Interrupt) error {
+	if i != nil {
+		for _, c := range []devices.Celsius{i.Low, i.High, i.Hysteresis} {
+			if _, err := fromTemp(c); err != nil {
+				return err
+			}
+		}
+		if i.Low > i.High || i.Hysteresis < 0 {
+			return errors.New("amg8833: invalid interrupt thresholds")
+		}
+		c := *i
+		i = &c
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wake(); err != nil {
+		return err
+	}
+	d.intr = i
+	return d.writeInterrupt()
+}
+
+// Interrupted returns a bitmap of the pixels that triggered an interrupt,
+// with bit n being the pixel at x=n%8, y=n/8, then clears the interrupt.
+func (d *Dev) Interrupted() (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [8]byte
+	if err := d.readReg(regIntTable, b[:]); err != nil {
+		return 0, err
+	}
+	var p uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		p = p<<8 | uint64(b[i])
+	}
+	return p, d.write(regStatusClear, statusInt)
+}
+
+// Halt puts the device in sleep mode.
+//
+// The next call to ReadFrame() or SetInterrupt() wakes it up.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.write(regPowerCtl, modeSleep); err != nil {
+		return err
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	regPowerCtl    = 0x00
+	regReset       = 0x01
+	regFrameRate   = 0x02
+	regIntCtl      = 0x03
+	regStatusClear = 0x05
+	regAverage     = 0x07
+	regIntLevel    = 0x08
+	regThermistor  = 0x0E
+	regIntTable    = 0x10
+	regAvgUnlock   = 0x1F
+	regPixels      = 0x80
+
+	modeNormal = 0x00
+	modeSleep  = 0x10
+
+	resetInitial = 0x3F
+
+	intEnable   = 0x01
+	intAbsolute = 0x02
+
+	statusInt = 0x02
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// init resets the device and configures it.
+func (d *Dev) init() error {
+	if err := d.write(regPowerCtl, modeNormal); err != nil {
+		return err
+	}
+	sleep(50 * time.Millisecond)
+	if err := d.write(regReset, resetInitial); err != nil {
+		return err
+	}
+	sleep(2 * time.Millisecond)
+	if err := d.write(regFrameRate, byte(d.opts.FrameRate)); err != nil {
+		return err
+	}
+	if d.opts.MovingAverage {
+		// The register is protected by an undocumented unlock sequence.
+		b := []byte{regAvgUnlock, 0x50, regAvgUnlock, 0x45, regAvgUnlock, 0x57, regAverage, 0x20, regAvgUnlock, 0x00}
+		for i := 0; i < len(b); i += 2 {
+			if err := d.write(b[i], b[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return d.writeInterrupt()
+}
+
+// wake initializes the device again after Halt(), since the registers are
+// reset, then waits for the first frame.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) wake() error {
+	if !d.halted {
+		return nil
+	}
+	if err := d.init(); err != nil {
+		return err
+	}
+	d.halted = false
+	if d.opts.FrameRate == FPS1 {
+		sleep(time.Second)
+	} else {
+		sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// writeInterrupt writes the interrupt configuration.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) writeInterrupt() error {
+	if d.intr == nil {
+		return d.write(regIntCtl, 0)
+	}
+	b := []byte{regIntLevel}
+	for _, c := range []devices.Celsius{d.intr.High, d.intr.Low, d.intr.Hysteresis} {
+		v, _ := fromTemp(c)
+		b = append(b, byte(v), byte(v>>8)&0x0F)
+	}
+	if err := d.c.Tx(b, nil); err != nil {
+		return fmt.Errorf("amg8833: %v", err)
+	}
+	ctl := byte(intEnable)
+	if !d.intr.Difference {
+		ctl |= intAbsolute
+	}
+	return d.write(regIntCtl, ctl)
+}
+
+func (d *Dev) readReg(reg uint8, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("amg8833: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) write(reg, v byte) error {
+	if err := d.c.Tx([]byte{reg, v}, nil); err != nil {
+		return fmt.Errorf("amg8833: %v", err)
+	}
+	return nil
+}
+
+// fromTemp converts a temperature to the 12 bits two's complement interrupt
+// level, in 0.25°C.
+func fromTemp(c devices.Celsius) (uint16, error) {
+	v := int32(c) / 250
+	if v < -2048 || v > 2047 {
+		return 0, fmt.Errorf("amg8833: %s is out of range", c)
+	}
+	return uint16(v) & 0x0FFF, nil
+}
+
+// thermistorToTemp converts the 12 bits sign and magnitude thermistor value,
+// in 0.0625°C.
+func thermistorToTemp(b []byte) devices.Celsius {
+	v := int32(b[0]) | int32(b[1]&0x07)<<8
+	if b[1]&0x08 != 0 {
+		v = -v
+	}
+	return devices.Celsius(v * 625 / 10)
+}
+
+var defaults = Opts{
+	FrameRate: FPS10,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ image.Image = &Frame{}
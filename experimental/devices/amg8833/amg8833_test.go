@@ -0,0 +1,275 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package amg8833
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x69, &Opts{MovingAverage: true})
+	if err != nil {
+		log.Fatalf("failed to initialize amg8833: %v", err)
+	}
+	defer dev.Halt()
+	time.Sleep(200 * time.Millisecond)
+	f, err := dev.ReadFrame()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			fmt.Printf("%5.1f ", f.Temperature(x, y).Float64())
+		}
+		fmt.Println()
+	}
+}
+
+//
+
+func TestNewI2C_ReadFrame(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x69, &Opts{FrameRate: FPS1, MovingAverage: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "AMG8833{fake(105)}" {
+		t.Fatal(s)
+	}
+	expected := [][2]byte{
+		{0x00, 0x00}, {0x01, 0x3F}, {0x02, 0x01},
+		{0x1F, 0x50}, {0x1F, 0x45}, {0x1F, 0x57}, {0x07, 0x20}, {0x1F, 0x00},
+		{0x03, 0x00},
+	}
+	f.checkWrites(t, expected)
+	// 25°C, -5°C, ..., 30.5°C.
+	f.regs[0x80] = 0x64
+	f.regs[0x82] = 0xEC
+	f.regs[0x83] = 0xFF
+	f.regs[0xFE] = 0x7A
+	f.regs[0x0E] = 0xA8
+	f.regs[0x0F] = 0x01
+	fr, err := dev.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := fr.Temperature(0, 0); c != 25000 {
+		t.Fatal(c)
+	}
+	if c := fr.Temperature(1, 0); c != -5000 {
+		t.Fatal(c)
+	}
+	if c := fr.Temperature(2, 0); c != 0 {
+		t.Fatal(c)
+	}
+	if c := fr.Temperature(7, 7); c != 30500 {
+		t.Fatal(c)
+	}
+	if v := fr.Gray16At(0, 0).Y; v != 2148 {
+		t.Fatal(v)
+	}
+	if fr.Thermistor != 26500 {
+		t.Fatal(fr.Thermistor)
+	}
+	f.regs[0x0E] = 0x18
+	f.regs[0x0F] = 0x08
+	if fr, err = dev.ReadFrame(); err != nil || fr.Thermistor != -1500 {
+		t.Fatal(fr.Thermistor, err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x00, 0x10}})
+	// Woken up and configured again.
+	if _, err := dev.ReadFrame(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, expected)
+}
+
+func TestInterrupt(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x68, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x00, 0x00}, {0x01, 0x3F}, {0x02, 0x00}, {0x03, 0x00}})
+	if err := dev.SetInterrupt(&Interrupt{Low: -5000, High: 30000, Hysteresis: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x08, 0x78}, {0x09, 0x00}, {0x0A, 0xEC}, {0x0B, 0x0F}, {0x0C, 0x04}, {0x0D, 0x00}, {0x03, 0x03}})
+	if err := dev.SetInterrupt(&Interrupt{High: 2000, Difference: true}); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x08, 0x08}, {0x09, 0x00}, {0x0A, 0x00}, {0x0B, 0x00}, {0x0C, 0x00}, {0x0D, 0x00}, {0x03, 0x01}})
+	f.regs[0x10] = 0x01
+	f.regs[0x17] = 0x80
+	if p, err := dev.Interrupted(); p != 0x8000000000000001 || err != nil {
+		t.Fatalf("%#x %v", p, err)
+	}
+	f.checkWrites(t, [][2]byte{{0x05, 0x02}})
+	if err := dev.SetInterrupt(nil); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x03, 0x00}})
+	data := []Interrupt{
+		{Low: 2000, High: 1000},
+		{Hysteresis: -1000},
+		{High: 512000},
+		{Low: -513000},
+	}
+	for i, line := range data {
+		if err := dev.SetInterrupt(&line); err == nil {
+			t.Fatalf("#%d: invalid thresholds", i)
+		}
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetInterrupt(&Interrupt{High: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	// Woken up then configured.
+	f.checkWrites(t, [][2]byte{
+		{0x00, 0x10},
+		{0x00, 0x00}, {0x01, 0x3F}, {0x02, 0x00}, {0x03, 0x00},
+		{0x08, 0x04}, {0x09, 0x00}, {0x0A, 0x00}, {0x0B, 0x00}, {0x0C, 0x00}, {0x0D, 0x00}, {0x03, 0x03},
+	})
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x67, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x69, &Opts{FrameRate: 2}); err == nil {
+		t.Fatal("bad frame rate")
+	}
+	// Fail at each successive write of the initialization.
+	for n := 0; n < 9; n++ {
+		f := newFakeBus()
+		f.failAfter = n
+		if _, err := NewI2C(f, 0x69, &Opts{MovingAverage: true}); err == nil {
+			t.Fatalf("#%d: write failed", n)
+		}
+	}
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x69, &Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetInterrupt(&Interrupt{High: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	f.failAfter = 0
+	if _, err := dev.ReadFrame(); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Interrupted(); err == nil {
+		t.Fatal("read failed")
+	}
+	if err := dev.SetInterrupt(&Interrupt{High: 1000}); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("write failed")
+	}
+	dev.halted = true
+	if _, err := dev.ReadFrame(); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.SetInterrupt(nil); err == nil {
+		t.Fatal("write failed")
+	}
+	f.failAfter = 1
+	dev.halted = false
+	if _, err := dev.ReadFrame(); err == nil {
+		t.Fatal("read failed")
+	}
+}
+
+func TestFrameRate(t *testing.T) {
+	if s := FPS1.String(); s != "FPS1" {
+		t.Fatal(s)
+	}
+	if s := FrameRate(2).String(); s != "FrameRate(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+// fakeBus emulates the register map with the register address auto increment.
+//
+// Each register written is logged in writes.
+type fakeBus struct {
+	regs   [256]byte
+	writes [][2]byte
+	// failAfter fails the transactions after this number of successful ones,
+	// when not -1.
+	failAfter int
+}
+
+func newFakeBus() *fakeBus {
+	return &fakeBus{failAfter: -1}
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if f.failAfter == 0 {
+		return fmt.Errorf("failed")
+	}
+	if f.failAfter > 0 {
+		f.failAfter--
+	}
+	reg := int(w[0])
+	for i, v := range w[1:] {
+		f.regs[reg+i] = v
+		f.writes = append(f.writes, [2]byte{byte(reg + i), v})
+	}
+	for i := range r {
+		r[i] = f.regs[reg+i]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+// checkWrites verifies the registers written since the last call.
+func (f *fakeBus) checkWrites(t *testing.T, expected [][2]byte) {
+	w := f.writes
+	f.writes = nil
+	if len(w) != len(expected) {
+		t.Fatalf("%x != %x", w, expected)
+	}
+	for i := range w {
+		if w[i] != expected[i] {
+			t.Fatalf("%x != %x", w, expected)
+		}
+	}
+}
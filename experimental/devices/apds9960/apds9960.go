@@ -0,0 +1,539 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package apds9960 controls a Broadcom APDS-9960 gesture, proximity and color
+// sensor over I²C.
+//
+// Datasheet
+//
+// https://docs.broadcom.com/docs/AV02-4191EN
+package apds9960
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+)
+
+// Gesture is a hand motion over the sensor.
+//
+// The directions are relative to the photodiodes; Up is a motion from the D
+// to the U photodiode and Right a motion from the L to the R photodiode.
+type Gesture uint8
+
+// Detected gestures.
+const (
+	Up    Gesture = 0
+	Down  Gesture = 1
+	Left  Gesture = 2
+	Right Gesture = 3
+)
+
+const gestureName = "UpDownLeftRight"
+
+var gestureIndex = [...]uint8{0, 2, 6, 10, 15}
+
+func (g Gesture) String() string {
+	if g >= Gesture(len(gestureIndex)-1) {
+		return fmt.Sprintf("Gesture(%d)", g)
+	}
+	return gestureName[gestureIndex[g]:gestureIndex[g+1]]
+}
+
+// Color is a RGBC measurement.
+//
+// The values are relative and depend on Opts.ALSGain and
+// Opts.ALSIntegration.
+type Color struct {
+	Clear uint16
+	Red   uint16
+	Green uint16
+	Blue  uint16
+}
+
+func (c *Color) String() string {
+	return fmt.Sprintf("C:%d R:%d G:%d B:%d", c.Clear, c.Red, c.Green, c.Blue)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// ALSGain is the color measurement gain; 1, 4, 16 or 64.
+	ALSGain int
+	// ALSIntegration is the color measurement integration time, between
+	// 2.78ms and 712ms.
+	ALSIntegration time.Duration
+	// ProximityGain is the proximity measurement gain; 1, 2, 4 or 8.
+	ProximityGain int
+}
+
+// Dev is a handle to an APDS-9960.
+type Dev struct {
+	c    conn.Conn
+	intr gpio.PinIn
+	opts Opts
+
+	mu     sync.Mutex
+	enable byte
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to an APDS-9960.
+//
+// intr is optional and is the pin connected to INT, which must be pulled up.
+// It is needed for WaitForProximity() and recommended for Gestures().
+func NewI2C(b i2c.Bus, intr gpio.PinIn, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	again, ok := alsGain[opts.ALSGain]
+	if !ok {
+		return nil, fmt.Errorf("apds9960: invalid ALS gain %d", opts.ALSGain)
+	}
+	pgain, ok := proximityGain[opts.ProximityGain]
+	if !ok {
+		return nil, fmt.Errorf("apds9960: invalid proximity gain %d", opts.ProximityGain)
+	}
+	cycles := (opts.ALSIntegration + alsCycle/2) / alsCycle
+	if cycles < 1 || cycles > 256 {
+		return nil, fmt.Errorf("apds9960: invalid ALS integration time %s", opts.ALSIntegration)
+	}
+	if intr == gpio.INVALID {
+		intr = nil
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: 0x39}, intr: intr, opts: *opts}
+	var id [1]byte
+	if err := d.readReg(regID, id[:]); err != nil {
+		return nil, err
+	}
+	if id[0] != 0xAB && id[0] != 0xA8 {
+		return nil, fmt.Errorf("apds9960: unexpected chip id %x", id[0])
+	}
+	b2 := []byte{
+		regEnable, 0,
+		regATime, byte(256 - cycles),
+		regPPulse, 0x87, // 16µs, 8 pulses.
+		regControl, pgain<<2 | again, // 100mA LED drive.
+		regGPEnter, 40,
+		regGExit, 30,
+		regGConf1, 0x40, // Interrupt after 4 datasets.
+		regGConf2, 0x41, // 4x gain, 100mA, 2.8ms wait.
+		regGPulse, 0xC9, // 32µs, 10 pulses.
+	}
+	for i := 0; i < len(b2); i += 2 {
+		if err := d.write(b2[i], b2[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	if d.intr != nil {
+		if err := d.intr.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+			return nil, fmt.Errorf("apds9960: %v", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("APDS9960{%s}", d.c)
+}
+
+// Color returns the latest RGBC measurement.
+//
+// The first call waits for the integration time.
+func (d *Dev) Color() (Color, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.waitValid(enableALS, statusALSValid, d.opts.ALSIntegration); err != nil {
+		return Color{}, err
+	}
+	var b [8]byte
+	if err := d.readReg(regCData, b[:]); err != nil {
+		return Color{}, err
+	}
+	return Color{
+		Clear: uint16(b[0]) | uint16(b[1])<<8,
+		Red:   uint16(b[2]) | uint16(b[3])<<8,
+		Green: uint16(b[4]) | uint16(b[5])<<8,
+		Blue:  uint16(b[6]) | uint16(b[7])<<8,
+	}, nil
+}
+
+// Proximity returns the latest proximity measurement; larger is closer.
+func (d *Dev) Proximity() (uint8, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.waitValid(enableProximity, statusProximityValid, 5*time.Millisecond); err != nil {
+		return 0, err
+	}
+	var b [1]byte
+	if err := d.readReg(regPData, b[:]); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// SetProximityInterrupt enables the proximity interrupt, asserted when
+// persistence consecutive measurements are outside [low, high].
+//
+// persistence is between 0 and 15, where 0 asserts on every measurement.
+func (d *Dev) SetProximityInterrupt(low, high, persistence uint8) error {
+	if low > high {
+		return errors.New("apds9960: low threshold must be lower than high threshold")
+	}
+	if persistence > 15 {
+		return fmt.Errorf("apds9960: invalid persistence %d", persistence)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var p [1]byte
+	if err := d.readReg(regPers, p[:]); err != nil {
+		return err
+	}
+	b := []byte{regPILT, low, regPIHT, high, regPers, persistence<<4 | p[0]&0x0F}
+	for i := 0; i < len(b); i += 2 {
+		if err := d.write(b[i], b[i+1]); err != nil {
+			return err
+		}
+	}
+	if err := d.clear(regPIClear); err != nil {
+		return err
+	}
+	return d.setEnable(d.enable | enablePower | enableProximity | enableProximityInt)
+}
+
+// DisableProximityInterrupt disables the proximity interrupt.
+func (d *Dev) DisableProximityInterrupt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setEnable(d.enable &^ enableProximityInt)
+}
+
+// WaitForProximity waits for the proximity interrupt, then clears it.
+//
+// Returns false on timeout. Use -1 to wait indefinitely.
+func (d *Dev) WaitForProximity(timeout time.Duration) (bool, error) {
+	if d.intr == nil {
+		return false, errors.New("apds9960: INT pin is not connected")
+	}
+	d.mu.Lock()
+	if d.stop != nil {
+		d.mu.Unlock()
+		return false, errors.New("apds9960: already sensing gestures")
+	}
+	d.mu.Unlock()
+	// The interrupt may have been asserted before the edge detection was set
+	// up.
+	if d.intr.Read() == gpio.High && !d.intr.WaitForEdge(timeout) {
+		return false, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return true, d.clear(regPIClear)
+}
+
+// Gestures returns the gestures detected on a continuous basis.
+//
+// When the INT pin is not connected, the device is polled every 10ms.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) Gestures() (<-chan Gesture, error) {
+	d.stopGestures()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	conf4 := byte(0)
+	if d.intr != nil {
+		conf4 = gconf4Int
+	}
+	if err := d.write(regGConf4, conf4); err != nil {
+		return nil, err
+	}
+	if err := d.setEnable(d.enable | enablePower | enableProximity | enableGesture); err != nil {
+		return nil, err
+	}
+	c := make(chan Gesture, 4)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(c)
+		d.sensingGestures(c, stop)
+	}(d.stop)
+	return c, nil
+}
+
+// Halt stops the gesture sensing and powers the device down.
+func (d *Dev) Halt() error {
+	d.stopGestures()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setEnable(0)
+}
+
+//
+
+const (
+	regEnable  = 0x80
+	regATime   = 0x81
+	regPILT    = 0x89
+	regPIHT    = 0x8B
+	regPers    = 0x8C
+	regPPulse  = 0x8E
+	regControl = 0x8F
+	regID      = 0x92
+	regStatus  = 0x93
+	regCData   = 0x94
+	regPData   = 0x9C
+	regGPEnter = 0xA0
+	regGExit   = 0xA1
+	regGConf1  = 0xA2
+	regGConf2  = 0xA3
+	regGPulse  = 0xA6
+	regGConf4  = 0xAB
+	regGFLevel = 0xAE
+	regPIClear = 0xE5
+	regGFIFO   = 0xFC
+
+	enablePower        = 0x01
+	enableALS          = 0x02
+	enableProximity    = 0x04
+	enableProximityInt = 0x20
+	enableGesture      = 0x40
+
+	statusALSValid       = 0x01
+	statusProximityValid = 0x02
+
+	gconf4Mode = 0x01
+	gconf4Int  = 0x02
+
+	gstatusOverflow = 0x02
+
+	alsCycle = 2780 * time.Microsecond
+
+	// gestureThreshold is the minimum value of all the photodiodes for a
+	// dataset to be considered.
+	gestureThreshold = 10
+	// gestureSensitivity is the minimum change of the ratio in % between the
+	// photodiodes to detect a motion.
+	gestureSensitivity = 30
+)
+
+var alsGain = map[int]byte{1: 0, 4: 1, 16: 2, 64: 3}
+
+var proximityGain = map[int]byte{1: 0, 2: 1, 4: 2, 8: 3}
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// pollInterval is the gesture polling interval when the INT pin is not
+// connected.
+const pollInterval = 10 * time.Millisecond
+
+// waitValid enables a measurement and waits for its result.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) waitValid(en, valid byte, wait time.Duration) error {
+	if d.enable&en == 0 {
+		if err := d.setEnable(d.enable | enablePower | en); err != nil {
+			return err
+		}
+		sleep(wait)
+	}
+	for i := 0; ; i++ {
+		var s [1]byte
+		if err := d.readReg(regStatus, s[:]); err != nil {
+			return err
+		}
+		if s[0]&valid != 0 {
+			return nil
+		}
+		if i == 10 {
+			return errors.New("apds9960: timeout waiting for measurement")
+		}
+		sleep(wait / 10)
+	}
+}
+
+// setEnable writes the ENABLE register.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) setEnable(v byte) error {
+	if err := d.write(regEnable, v); err != nil {
+		return err
+	}
+	d.enable = v
+	return nil
+}
+
+func (d *Dev) stopGestures() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingGestures(c chan<- Gesture, stop <-chan struct{}) {
+	var t *time.Ticker
+	if d.intr == nil {
+		t = time.NewTicker(pollInterval)
+		defer t.Stop()
+	}
+	var data [][4]byte
+	for {
+		if t != nil {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+		} else {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Wake up regularly to check for stop. The end of a gesture leaving
+			// less datasets than the FIFO threshold doesn't assert INT so poll
+			// when a gesture is in progress.
+			if d.intr.Read() == gpio.High && !d.intr.WaitForEdge(100*time.Millisecond) && len(data) == 0 {
+				continue
+			}
+		}
+		d.mu.Lock()
+		g, ok, err := d.readGesture(&data)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		if ok {
+			select {
+			case c <- g:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// readGesture empties the gesture FIFO into data and decodes the gesture once
+// the device exited the gesture engine.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) readGesture(data *[][4]byte) (Gesture, bool, error) {
+	// GFLVL and GSTATUS.
+	var s [2]byte
+	if err := d.readReg(regGFLevel, s[:]); err != nil {
+		return 0, false, err
+	}
+	if s[1]&gstatusOverflow != 0 {
+		// Datasets were lost; the motion can't be trusted.
+		*data = (*data)[:0]
+	}
+	if n := int(s[0]); n != 0 {
+		b := make([]byte, 4*n)
+		if err := d.readReg(regGFIFO, b); err != nil {
+			return 0, false, err
+		}
+		for i := 0; i < n; i++ {
+			*data = append(*data, [4]byte{b[4*i], b[4*i+1], b[4*i+2], b[4*i+3]})
+		}
+	}
+	var c [1]byte
+	if err := d.readReg(regGConf4, c[:]); err != nil {
+		return 0, false, err
+	}
+	if c[0]&gconf4Mode != 0 || len(*data) == 0 {
+		// Still in the gesture engine.
+		return 0, false, nil
+	}
+	g, ok := decodeGesture(*data)
+	*data = (*data)[:0]
+	return g, ok, nil
+}
+
+// decodeGesture determines the motion from the change of the ratio between
+// opposite photodiodes from the first to the last dataset.
+//
+// Each dataset is U, D, L and R.
+func decodeGesture(data [][4]byte) (Gesture, bool) {
+	first, last := -1, -1
+	for i, v := range data {
+		if v[0] > gestureThreshold && v[1] > gestureThreshold && v[2] > gestureThreshold && v[3] > gestureThreshold {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 || first == last {
+		return 0, false
+	}
+	ratio := func(a, b byte) int {
+		return (int(a) - int(b)) * 100 / (int(a) + int(b))
+	}
+	f, l := data[first], data[last]
+	ud := ratio(l[0], l[1]) - ratio(f[0], f[1])
+	lr := ratio(l[3], l[2]) - ratio(f[3], f[2])
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	if abs(ud) < gestureSensitivity && abs(lr) < gestureSensitivity {
+		return 0, false
+	}
+	if abs(ud) > abs(lr) {
+		if ud > 0 {
+			return Up, true
+		}
+		return Down, true
+	}
+	if lr > 0 {
+		return Right, true
+	}
+	return Left, true
+}
+
+func (d *Dev) readReg(reg uint8, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("apds9960: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) write(reg, v byte) error {
+	if err := d.c.Tx([]byte{reg, v}, nil); err != nil {
+		return fmt.Errorf("apds9960: %v", err)
+	}
+	return nil
+}
+
+// clear accesses a special function register to clear an interrupt.
+func (d *Dev) clear(reg uint8) error {
+	if err := d.c.Tx([]byte{reg}, nil); err != nil {
+		return fmt.Errorf("apds9960: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	ALSGain:        4,
+	ALSIntegration: 100 * time.Millisecond,
+	ProximityGain:  4,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,472 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package apds9960
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, gpioreg.ByName("GPIO4"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize apds9960: %v", err)
+	}
+	defer dev.Halt()
+	c, err := dev.Color()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", &c)
+	gestures, err := dev.Gestures()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for g := range gestures {
+		fmt.Printf("%s\n", g)
+	}
+}
+
+//
+
+func TestNewI2C_Color_Proximity(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil, &Opts{ALSGain: 16, ALSIntegration: 200 * time.Millisecond, ProximityGain: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "APDS9960{fake(57)}" {
+		t.Fatal(s)
+	}
+	f.checkWrites(t, []string{
+		"80=00", "81=b8", "8e=87", "8f=0e", "a0=28", "a1=1e", "a2=40", "a3=41", "a6=c9",
+	})
+	copy(f.regs[0x94:], []byte{0x10, 0x27, 0xE8, 0x03, 0xD0, 0x07, 0xB8, 0x0B})
+	// Not valid yet.
+	f.status = []byte{0x00, 0x01}
+	c, err := dev.Color()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Color{Clear: 10000, Red: 1000, Green: 2000, Blue: 3000}); c != expected {
+		t.Fatal(c)
+	}
+	if s := c.String(); s != "C:10000 R:1000 G:2000 B:3000" {
+		t.Fatal(s)
+	}
+	f.checkWrites(t, []string{"80=03"})
+	// Already enabled.
+	if _, err := dev.Color(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, nil)
+	f.regs[0x9C] = 42
+	f.status = []byte{0x02}
+	if p, err := dev.Proximity(); p != 42 || err != nil {
+		t.Fatal(p, err)
+	}
+	f.checkWrites(t, []string{"80=07"})
+	f.status = []byte{0x00}
+	if _, err := dev.Proximity(); err == nil {
+		t.Fatal("timeout")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, []string{"80=00"})
+}
+
+func TestProximityInterrupt(t *testing.T) {
+	f := newFakeBus()
+	p := &gpiotest.Pin{EdgesChan: make(chan gpio.Level, 1)}
+	dev, err := NewI2C(f, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.writes = nil
+	f.regs[0x8C] = 0x11
+	if err := dev.SetProximityInterrupt(10, 200, 3); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, []string{"89=0a", "8b=c8", "8c=31", "e5", "80=25"})
+	if ok, err := dev.WaitForProximity(time.Millisecond); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	p.EdgesChan <- gpio.Low
+	if ok, err := dev.WaitForProximity(-1); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	f.checkWrites(t, []string{"e5"})
+	// Still asserted.
+	if ok, err := dev.WaitForProximity(time.Millisecond); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	f.checkWrites(t, []string{"e5"})
+	if err := dev.DisableProximityInterrupt(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, []string{"80=05"})
+	if err := dev.SetProximityInterrupt(10, 9, 0); err == nil {
+		t.Fatal("invalid thresholds")
+	}
+	if err := dev.SetProximityInterrupt(0, 255, 16); err == nil {
+		t.Fatal("invalid persistence")
+	}
+	dev, err = NewI2C(f, gpio.INVALID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.WaitForProximity(time.Millisecond); err == nil {
+		t.Fatal("no pin")
+	}
+}
+
+func TestGestures_poll(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.writes = nil
+	f.setFIFO([][4]byte{{50, 50, 100, 20}, {50, 50, 60, 60}, {50, 50, 20, 100}}, 0)
+	c, err := dev.Gestures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := <-c; g != Right {
+		t.Fatal(g)
+	}
+	f.setFIFO([][4]byte{{100, 20, 50, 50}}, 0x01)
+	f.setFIFO([][4]byte{{60, 60, 50, 50}, {20, 100, 50, 50}}, 0x00)
+	if g := <-c; g != Down {
+		t.Fatal(g)
+	}
+	if _, err := dev.WaitForProximity(time.Millisecond); err == nil {
+		t.Fatal("no pin")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	w := f.getWrites()
+	if w[0] != "ab=00" || w[1] != "80=45" || w[len(w)-1] != "80=00" {
+		t.Fatal(w)
+	}
+}
+
+func TestGestures_interrupt(t *testing.T) {
+	f := newFakeBus()
+	p := &gpiotest.Pin{EdgesChan: make(chan gpio.Level)}
+	dev, err := NewI2C(f, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.writes = nil
+	c, err := dev.Gestures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.WaitForProximity(time.Millisecond); err == nil {
+		t.Fatal("already sensing")
+	}
+	f.setFIFO([][4]byte{{20, 100, 50, 50}, {60, 60, 50, 50}, {100, 20, 50, 50}}, 0)
+	p.EdgesChan <- gpio.Low
+	if g := <-c; g != Up {
+		t.Fatal(g)
+	}
+	p.Out(gpio.High)
+	// Restarting the gestures stops the previous goroutine.
+	c2, err := dev.Gestures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	// The end of the gesture is detected without interrupt.
+	f.setFIFO([][4]byte{{50, 50, 20, 100}, {50, 50, 100, 20}}, 0x01)
+	p.EdgesChan <- gpio.Low
+	p.Out(gpio.High)
+	f.setFIFO(nil, 0)
+	if g := <-c2; g != Left {
+		t.Fatal(g)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if w := f.getWrites(); w[0] != "ab=02" || w[1] != "80=45" {
+		t.Fatal(w)
+	}
+}
+
+func TestGestures_fail(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setFIFO([][4]byte{{50, 50, 100, 20}}, 0x01)
+	f.mu.Lock()
+	f.failFIFO = true
+	f.mu.Unlock()
+	c, err := dev.Gestures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed on error")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadGesture(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data [][4]byte
+	// Overflow drops the previous datasets.
+	data = append(data, [4]byte{50, 50, 100, 20})
+	f.setFIFO([][4]byte{{50, 50, 20, 100}}, 0x01)
+	f.gstatus = gstatusOverflow
+	if _, ok, err := dev.readGesture(&data); ok || err != nil || len(data) != 1 {
+		t.Fatal(ok, err, data)
+	}
+	f.gstatus = 0
+	f.setFIFO(nil, 0)
+	if _, ok, err := dev.readGesture(&data); ok || err != nil || len(data) != 0 {
+		t.Fatal(ok, err, data)
+	}
+}
+
+func TestDecodeGesture(t *testing.T) {
+	data := []struct {
+		d  [][4]byte
+		g  Gesture
+		ok bool
+	}{
+		{[][4]byte{{20, 100, 50, 50}, {100, 20, 50, 50}}, Up, true},
+		{[][4]byte{{100, 20, 50, 50}, {100, 20, 50, 50}, {20, 100, 50, 50}}, Down, true},
+		{[][4]byte{{50, 50, 20, 100}, {50, 50, 100, 20}, {5, 5, 5, 5}}, Left, true},
+		{[][4]byte{{5, 5, 5, 5}, {50, 50, 100, 20}, {50, 50, 20, 100}}, Right, true},
+		// Not enough datasets above the threshold.
+		{[][4]byte{{5, 5, 5, 5}, {50, 50, 20, 100}}, 0, false},
+		{[][4]byte{{5, 5, 5, 5}}, 0, false},
+		// Not enough motion.
+		{[][4]byte{{50, 50, 50, 50}, {55, 50, 50, 55}}, 0, false},
+	}
+	for i, line := range data {
+		if g, ok := decodeGesture(line.d); g != line.g || ok != line.ok {
+			t.Fatalf("#%d: %s %t", i, g, ok)
+		}
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	data := []Opts{
+		{ALSGain: 2, ALSIntegration: time.Second, ProximityGain: 1},
+		{ALSGain: 1, ALSIntegration: time.Millisecond, ProximityGain: 1},
+		{ALSGain: 1, ALSIntegration: time.Second, ProximityGain: 1},
+		{ALSGain: 1, ALSIntegration: 100 * time.Millisecond, ProximityGain: 16},
+	}
+	for i, opts := range data {
+		if _, err := NewI2C(newFakeBus(), nil, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	f := newFakeBus()
+	f.regs[0x92] = 0x00
+	if _, err := NewI2C(f, nil, nil); err == nil {
+		t.Fatal("bad id")
+	}
+	bus := i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x39, W: []byte{0x92}, R: []byte{0xAB}}},
+		DontPanic: true,
+	}
+	if _, err := NewI2C(&bus, nil, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	if _, err := NewI2C(newFakeBus(), &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("no edge detection")
+	}
+	f = newFakeBus()
+	dev, err := NewI2C(f, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.fail = true
+	if _, err := dev.Color(); err == nil {
+		t.Fatal("write failed")
+	}
+	if _, err := dev.Proximity(); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.SetProximityInterrupt(0, 1, 0); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Gestures(); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestGesture_String(t *testing.T) {
+	if s := Right.String(); s != "Right" {
+		t.Fatal(s)
+	}
+	if s := Gesture(4).String(); s != "Gesture(4)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+// fakeBus emulates the register map, the gesture FIFO and the special
+// function registers.
+//
+// Each register written is logged in writes.
+type fakeBus struct {
+	mu   sync.Mutex
+	regs [256]byte
+	// status is the sequence of STATUS values returned, the last one being
+	// repeated.
+	status   []byte
+	fifo     [][4]byte
+	gstatus  byte
+	writes   []string
+	fail     bool
+	failFIFO bool
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{}
+	f.regs[0x92] = 0xAB
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("failed")
+	}
+	if addr != 0x39 {
+		return fmt.Errorf("no device at %#x", addr)
+	}
+	reg := int(w[0])
+	if reg >= 0xE4 && reg <= 0xE7 {
+		f.writes = append(f.writes, fmt.Sprintf("%02x", reg))
+		return nil
+	}
+	for i, v := range w[1:] {
+		f.regs[reg+i] = v
+		f.writes = append(f.writes, fmt.Sprintf("%02x=%02x", reg+i, v))
+	}
+	switch reg {
+	case 0x93:
+		if len(f.status) != 0 {
+			r[0] = f.status[0]
+			if len(f.status) > 1 {
+				f.status = f.status[1:]
+			}
+		}
+		return nil
+	case 0xAE:
+		r[0] = byte(len(f.fifo))
+		r[1] = f.gstatus
+		return nil
+	case 0xFC:
+		if f.failFIFO {
+			return errors.New("failed")
+		}
+		for i := 0; i < len(r)/4; i++ {
+			copy(r[4*i:], f.fifo[0][:])
+			f.fifo = f.fifo[1:]
+		}
+		return nil
+	}
+	for i := range r {
+		r[i] = f.regs[reg+i]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+// setFIFO waits for the FIFO to be emptied then sets its content and the
+// GCONF4 value.
+func (f *fakeBus) setFIFO(fifo [][4]byte, gconf4 byte) {
+	for {
+		f.mu.Lock()
+		if len(f.fifo) == 0 {
+			f.fifo = fifo
+			f.regs[0xAB] = f.regs[0xAB]&^0x01 | gconf4
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (f *fakeBus) getWrites() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.writes
+	f.writes = nil
+	return w
+}
+
+// checkWrites verifies the registers written since the last call.
+func (f *fakeBus) checkWrites(t *testing.T, expected []string) {
+	w := f.getWrites()
+	if len(w) != len(expected) {
+		t.Fatalf("%s != %s", w, expected)
+	}
+	for i := range w {
+		if w[i] != expected[i] {
+			t.Fatalf("%s != %s", w, expected)
+		}
+	}
+}
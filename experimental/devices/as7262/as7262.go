@@ -0,0 +1,469 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package as7262 controls an AMS AS7262 6 channels visible spectral sensor
+// over I²C.
+//
+// The device measures the violet (450nm), blue (500nm), green (550nm), yellow
+// (570nm), orange (600nm) and red (650nm) bands. It exposes both the raw ADC
+// counts and the factory calibrated values, in µW/cm².
+//
+// The registers are accessed through a virtual register interface, so each
+// register access is a handful of I²C transactions.
+//
+// Datasheet
+//
+// https://ams.com/documents/20143/36005/AS7262_DS000486_2-00.pdf
+package as7262
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Gain is the analog gain.
+type Gain uint8
+
+// Possible gain values.
+const (
+	G1x   Gain = 0 // 1x
+	G3_7x Gain = 1 // 3.7x
+	G16x  Gain = 2 // 16x
+	G64x  Gain = 3 // 64x
+)
+
+const gainName = "G1xG3_7xG16xG64x"
+
+var gainIndex = [...]uint8{0, 3, 8, 12, 16}
+
+func (g Gain) String() string {
+	if g >= Gain(len(gainIndex)-1) {
+		return fmt.Sprintf("Gain(%d)", g)
+	}
+	return gainName[gainIndex[g]:gainIndex[g+1]]
+}
+
+// LEDCurrent is the current limit of the illumination LED driver.
+type LEDCurrent uint8
+
+// Possible LED driver currents.
+const (
+	LED12_5mA LEDCurrent = 0
+	LED25mA   LEDCurrent = 1
+	LED50mA   LEDCurrent = 2
+	LED100mA  LEDCurrent = 3
+)
+
+const ledCurrentName = "LED12_5mALED25mALED50mALED100mA"
+
+var ledCurrentIndex = [...]uint8{0, 9, 16, 23, 31}
+
+func (l LEDCurrent) String() string {
+	if l >= LEDCurrent(len(ledCurrentIndex)-1) {
+		return fmt.Sprintf("LEDCurrent(%d)", l)
+	}
+	return ledCurrentName[ledCurrentIndex[l]:ledCurrentIndex[l+1]]
+}
+
+// Band is the measurement of one spectral band.
+type Band struct {
+	Wavelength int     // Center wavelength in nm.
+	Name       string  // Color name.
+	Counts     uint16  // Raw ADC counts.
+	Value      float64 // Calibrated irradiance in µW/cm², after Calibration is applied.
+}
+
+// Spectrum is a measurement of the 6 bands.
+type Spectrum struct {
+	Bands       [6]Band
+	Gain        Gain          // Gain used for the measurement.
+	Integration time.Duration // Integration time used for the measurement.
+	Temperature devices.Celsius
+}
+
+// Saturated returns true if at least one band is saturated. The values are
+// not reliable in this case.
+func (s *Spectrum) Saturated() bool {
+	return s.maxCounts() >= saturation
+}
+
+// Calibration is an additional correction applied on top of the factory
+// calibrated values, for example to compensate a diffuser.
+//
+// Value = factory*Scale + Offset. A zero Scale is treated as 1.
+type Calibration struct {
+	Scale  [6]float64
+	Offset [6]float64
+}
+
+// Opts holds the configuration options.
+type Opts struct {
+	Gain Gain
+	// Integration is the integration time per bank, between 2.8ms and 714ms by
+	// step of 2.8ms. A full measurement takes twice this time.
+	Integration time.Duration
+	// AutoExposure adjusts the gain and the integration time on each
+	// measurement to keep the counts within the dynamic range of the ADC,
+	// avoiding saturation.
+	AutoExposure bool
+}
+
+// Dev is a handle to an AS7262.
+type Dev struct {
+	c conn.Conn
+
+	mu    sync.Mutex
+	gain  Gain
+	intT  uint8
+	auto  bool
+	calib Calibration
+	led   byte
+}
+
+// NewI2C returns an object that communicates over I²C to an AS7262.
+//
+// The address is always 0x49.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Gain > G64x {
+		return nil, fmt.Errorf("as7262: invalid gain %d", opts.Gain)
+	}
+	intT, err := integrationSteps(opts.Integration)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: 0x49}, gain: opts.Gain, intT: intT, auto: opts.AutoExposure}
+	var id byte
+	if err := d.readVirtual(vregHWVersion, &id); err != nil {
+		return nil, err
+	}
+	if id != deviceType {
+		return nil, fmt.Errorf("as7262: unexpected device type %#x; is this an AS7262?", id)
+	}
+	if err := d.writeVirtual(vregIntT, d.intT); err != nil {
+		return nil, err
+	}
+	if err := d.writeVirtual(vregLEDControl, 0); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("AS7262{%s}", d.c)
+}
+
+// Sense does a one shot measurement of the 6 bands.
+//
+// With auto exposure, the measurement is repeated with a lower or higher
+// sensitivity until the counts are within range. The gain and integration
+// time found are kept for the next measurement.
+func (d *Dev) Sense(s *Spectrum) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; ; i++ {
+		if err := d.measure(s); err != nil {
+			return err
+		}
+		if !d.auto || i == maxAutoSteps || !d.adjustExposure(s.maxCounts()) {
+			return nil
+		}
+		if err := d.writeVirtual(vregIntT, d.intT); err != nil {
+			return err
+		}
+	}
+}
+
+// SetGain changes the analog gain.
+func (d *Dev) SetGain(g Gain) error {
+	if g > G64x {
+		return fmt.Errorf("as7262: invalid gain %d", g)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.gain = g
+	return nil
+}
+
+// SetIntegration changes the integration time per bank.
+func (d *Dev) SetIntegration(t time.Duration) error {
+	intT, err := integrationSteps(t)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeVirtual(vregIntT, intT); err != nil {
+		return err
+	}
+	d.intT = intT
+	return nil
+}
+
+// SetAutoExposure enables or disables the auto exposure.
+func (d *Dev) SetAutoExposure(on bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.auto = on
+}
+
+// Calibration returns the correction currently applied.
+func (d *Dev) Calibration() Calibration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calib
+}
+
+// SetCalibration sets the correction applied on the factory calibrated
+// values.
+func (d *Dev) SetCalibration(c *Calibration) error {
+	for i := range c.Scale {
+		if math.IsNaN(c.Scale[i]) || math.IsInf(c.Scale[i], 0) || math.IsNaN(c.Offset[i]) || math.IsInf(c.Offset[i], 0) {
+			return fmt.Errorf("as7262: invalid calibration for band %d", i)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calib = *c
+	return nil
+}
+
+// SetLED turns the illumination LED driver on or off with the specified
+// current limit.
+func (d *Dev) SetLED(on bool, current LEDCurrent) error {
+	if current > LED100mA {
+		return fmt.Errorf("as7262: invalid LED current %d", current)
+	}
+	v := byte(current) << 4
+	if on {
+		v |= ledDrvEnable
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeVirtual(vregLEDControl, v); err != nil {
+		return err
+	}
+	d.led = v
+	return nil
+}
+
+// Halt turns off the LED driver.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.led &^= ledDrvEnable
+	return d.writeVirtual(vregLEDControl, d.led)
+}
+
+//
+
+const (
+	deviceType = 0x40
+
+	// Physical registers.
+	regStatus = 0x00
+	regWrite  = 0x01
+	regRead   = 0x02
+
+	statusRxValid = 0x01
+	statusTxValid = 0x02
+
+	// Virtual registers.
+	vregHWVersion  = 0x00
+	vregControl    = 0x04
+	vregIntT       = 0x05
+	vregDeviceTemp = 0x06
+	vregLEDControl = 0x07
+	vregRaw        = 0x08
+	vregCalibrated = 0x14
+
+	controlDataReady = 0x02
+	// controlOneShot is bank mode 3; all 6 bands measured once.
+	controlOneShot = 0x0C
+	ledDrvEnable   = 0x08
+
+	intTStep = 2800 * time.Microsecond
+
+	// saturation is the count above which the gain or integration time is
+	// lowered.
+	saturation = 60000
+	// underExposed is the count below which the gain or integration time is
+	// raised.
+	underExposed = 1000
+	// maxAutoSteps is the number of measurements done to find the exposure.
+	maxAutoSteps = 8
+)
+
+var bandInfo = [6]struct {
+	wavelength int
+	name       string
+}{{450, "violet"}, {500, "blue"}, {550, "green"}, {570, "yellow"}, {600, "orange"}, {650, "red"}}
+
+var defaults = Opts{
+	Gain:        G16x,
+	Integration: 140 * time.Millisecond,
+}
+
+var sleep = time.Sleep
+
+func integrationSteps(t time.Duration) (uint8, error) {
+	n := (t + intTStep/2) / intTStep
+	if n < 1 || n > 255 {
+		return 0, fmt.Errorf("as7262: invalid integration time %s", t)
+	}
+	return uint8(n), nil
+}
+
+func (s *Spectrum) maxCounts() uint16 {
+	var m uint16
+	for _, b := range s.Bands {
+		if b.Counts > m {
+			m = b.Counts
+		}
+	}
+	return m
+}
+
+// adjustExposure changes the gain or the integration time according to the
+// highest count of the last measurement.
+//
+// Returns false if no change was done.
+func (d *Dev) adjustExposure(max uint16) bool {
+	switch {
+	case max >= saturation && d.gain > G1x:
+		d.gain--
+	case max >= saturation && d.intT > 1:
+		d.intT /= 2
+	case max < underExposed && d.gain < G64x:
+		d.gain++
+	case max < underExposed && d.intT < 255:
+		if d.intT > 127 {
+			d.intT = 255
+		} else {
+			d.intT *= 2
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// measure does one measurement of all the bands.
+func (d *Dev) measure(s *Spectrum) error {
+	if err := d.writeVirtual(vregControl, byte(d.gain)<<4|controlOneShot); err != nil {
+		return err
+	}
+	// Each bank is integrated successively.
+	integration := time.Duration(d.intT) * intTStep
+	sleep(2 * integration)
+	for i := 0; ; i++ {
+		var c byte
+		if err := d.readVirtual(vregControl, &c); err != nil {
+			return err
+		}
+		if c&controlDataReady != 0 {
+			break
+		}
+		if i == 100 {
+			return errors.New("as7262: timed out waiting for the measurement")
+		}
+		sleep(5 * time.Millisecond)
+	}
+	var raw [12]byte
+	var cal [24]byte
+	var temp byte
+	for i := range raw {
+		if err := d.readVirtual(vregRaw+byte(i), &raw[i]); err != nil {
+			return err
+		}
+	}
+	for i := range cal {
+		if err := d.readVirtual(vregCalibrated+byte(i), &cal[i]); err != nil {
+			return err
+		}
+	}
+	if err := d.readVirtual(vregDeviceTemp, &temp); err != nil {
+		return err
+	}
+	for i := range s.Bands {
+		v := float64(math.Float32frombits(uint32(cal[4*i])<<24 | uint32(cal[4*i+1])<<16 | uint32(cal[4*i+2])<<8 | uint32(cal[4*i+3])))
+		scale := d.calib.Scale[i]
+		if scale == 0 {
+			scale = 1
+		}
+		s.Bands[i] = Band{
+			Wavelength: bandInfo[i].wavelength,
+			Name:       bandInfo[i].name,
+			Counts:     uint16(raw[2*i])<<8 | uint16(raw[2*i+1]),
+			Value:      v*scale + d.calib.Offset[i],
+		}
+	}
+	s.Gain = d.gain
+	s.Integration = integration
+	s.Temperature = devices.Celsius(int32(int8(temp)) * 1000)
+	return nil
+}
+
+// waitStatus waits for the bit of the status register to be equal to set.
+func (d *Dev) waitStatus(bit byte, set bool) error {
+	for i := 0; ; i++ {
+		var s [1]byte
+		if err := d.c.Tx([]byte{regStatus}, s[:]); err != nil {
+			return fmt.Errorf("as7262: %v", err)
+		}
+		if (s[0]&bit != 0) == set {
+			return nil
+		}
+		if i == 100 {
+			return errors.New("as7262: timed out waiting for the virtual register interface")
+		}
+		sleep(time.Millisecond)
+	}
+}
+
+func (d *Dev) writeVirtual(reg, v byte) error {
+	if err := d.waitStatus(statusTxValid, false); err != nil {
+		return err
+	}
+	if err := d.c.Tx([]byte{regWrite, reg | 0x80}, nil); err != nil {
+		return fmt.Errorf("as7262: %v", err)
+	}
+	if err := d.waitStatus(statusTxValid, false); err != nil {
+		return err
+	}
+	if err := d.c.Tx([]byte{regWrite, v}, nil); err != nil {
+		return fmt.Errorf("as7262: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) readVirtual(reg byte, v *byte) error {
+	if err := d.waitStatus(statusTxValid, false); err != nil {
+		return err
+	}
+	if err := d.c.Tx([]byte{regWrite, reg}, nil); err != nil {
+		return fmt.Errorf("as7262: %v", err)
+	}
+	if err := d.waitStatus(statusRxValid, true); err != nil {
+		return err
+	}
+	var b [1]byte
+	if err := d.c.Tx([]byte{regRead}, b[:]); err != nil {
+		return fmt.Errorf("as7262: %v", err)
+	}
+	*v = b[0]
+	return nil
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,393 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package as7262
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, &Opts{Gain: G16x, Integration: 140 * time.Millisecond, AutoExposure: true})
+	if err != nil {
+		log.Fatalf("failed to initialize as7262: %v", err)
+	}
+	defer dev.Halt()
+	// Illuminate the sample.
+	if err := dev.SetLED(true, LED25mA); err != nil {
+		log.Fatal(err)
+	}
+	var s Spectrum
+	if err := dev.Sense(&s); err != nil {
+		log.Fatal(err)
+	}
+	for _, b := range s.Bands {
+		fmt.Printf("%3dnm %-6s: %.1fµW/cm²\n", b.Wavelength, b.Name, b.Value)
+	}
+}
+
+//
+
+func TestNewI2C_Sense(t *testing.T) {
+	f := newFakeBus()
+	f.counts = func(gain, intT byte) [6]uint16 {
+		return [6]uint16{1000, 2000, 3000, 4000, 5000, 0x1234}
+	}
+	d, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "AS7262{fake(73)}" {
+		t.Fatal(s)
+	}
+	if f.vregs[vregIntT] != 50 {
+		t.Fatal(f.vregs[vregIntT])
+	}
+	var s Spectrum
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if f.vregs[vregControl]&^controlDataReady != 0x2C {
+		t.Fatalf("%#x", f.vregs[vregControl])
+	}
+	expected := Spectrum{
+		Bands: [6]Band{
+			{450, "violet", 1000, 1500},
+			{500, "blue", 2000, 3000},
+			{550, "green", 3000, 4500},
+			{570, "yellow", 4000, 6000},
+			{600, "orange", 5000, 7500},
+			{650, "red", 0x1234, 0x1234 * 1.5},
+		},
+		Gain:        G16x,
+		Integration: 140 * time.Millisecond,
+		Temperature: devices.Celsius(25000),
+	}
+	if s != expected {
+		t.Fatalf("%+v\n%+v", s, expected)
+	}
+	if s.Saturated() {
+		t.Fatal("not saturated")
+	}
+	// Calibration.
+	c := Calibration{Scale: [6]float64{2}, Offset: [6]float64{0, 1}}
+	if err := d.SetCalibration(&c); err != nil {
+		t.Fatal(err)
+	}
+	if d.Calibration() != c {
+		t.Fatal(d.Calibration())
+	}
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Bands[0].Value != 3000 || s.Bands[1].Value != 3001 || s.Bands[2].Value != 4500 {
+		t.Fatal(s.Bands)
+	}
+	for _, v := range []float64{math.NaN(), math.Inf(1)} {
+		if err := d.SetCalibration(&Calibration{Scale: [6]float64{v}}); err == nil {
+			t.Fatal("invalid scale")
+		}
+		if err := d.SetCalibration(&Calibration{Offset: [6]float64{5: v}}); err == nil {
+			t.Fatal("invalid offset")
+		}
+	}
+	// Settings.
+	if err := d.SetGain(G64x); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetGain(4); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if err := d.SetIntegration(714 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if f.vregs[vregIntT] != 255 {
+		t.Fatal(f.vregs[vregIntT])
+	}
+	for _, i := range []time.Duration{time.Millisecond, time.Second} {
+		if err := d.SetIntegration(i); err == nil {
+			t.Fatal("invalid integration")
+		}
+	}
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Gain != G64x || s.Integration != 714*time.Millisecond {
+		t.Fatal(s.Gain, s.Integration)
+	}
+	// LED.
+	if err := d.SetLED(true, LED100mA); err != nil {
+		t.Fatal(err)
+	}
+	if f.vregs[vregLEDControl] != 0x38 {
+		t.Fatalf("%#x", f.vregs[vregLEDControl])
+	}
+	if err := d.SetLED(false, 4); err == nil {
+		t.Fatal("invalid current")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if f.vregs[vregLEDControl] != 0x30 {
+		t.Fatalf("%#x", f.vregs[vregLEDControl])
+	}
+}
+
+func TestSense_AutoExposure(t *testing.T) {
+	gains := [...]float64{1, 3.7, 16, 64}
+	f := newFakeBus()
+	var light float64
+	f.counts = func(gain, intT byte) [6]uint16 {
+		v := light * gains[gain] * float64(intT)
+		if v > 65535 {
+			v = 65535
+		}
+		return [6]uint16{0, uint16(v)}
+	}
+	d, err := NewI2C(f, &Opts{Gain: G16x, Integration: 140 * time.Millisecond, AutoExposure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Too bright; lower the gain.
+	light = 1000
+	var s Spectrum
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Saturated() || s.Gain != G1x || s.Integration != 50*intTStep {
+		t.Fatal(s.Gain, s.Integration, s.Bands[1].Counts)
+	}
+	// Too dark; raise the gain.
+	light = 0.5
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Gain != G64x || s.Integration != 50*intTStep {
+		t.Fatal(s.Gain, s.Integration, s.Bands[1].Counts)
+	}
+	// Very dark; raise the integration time to the maximum.
+	light = 0.01
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Gain != G64x || s.Integration != 255*intTStep {
+		t.Fatal(s.Gain, s.Integration, s.Bands[1].Counts)
+	}
+	// Way too bright; lower the gain then the integration time, up to
+	// maxAutoSteps times.
+	light = 1e6
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Saturated() || s.Gain != G1x || s.Integration != 7*intTStep {
+		t.Fatal(s.Gain, s.Integration, s.Bands[1].Counts)
+	}
+	d.SetAutoExposure(false)
+	light = 0.01
+	if err := d.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Gain != G1x {
+		t.Fatal(s.Gain)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(newFakeBus(), &Opts{Gain: 4, Integration: 140 * time.Millisecond}); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if _, err := NewI2C(newFakeBus(), &Opts{}); err == nil {
+		t.Fatal("invalid integration")
+	}
+	f := newFakeBus()
+	f.vregs[vregHWVersion] = 0x3F
+	if _, err := NewI2C(f, nil); err == nil {
+		t.Fatal("not an AS7262")
+	}
+	// Fail at each successive transaction of the initialization.
+	for n := 0; n < 12; n++ {
+		f := newFakeBus()
+		f.failAfter = n
+		if _, err := NewI2C(f, nil); err == nil {
+			t.Fatalf("#%d: I²C failed", n)
+		}
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("I²C failed")
+	}
+}
+
+func TestDev_fail(t *testing.T) {
+	f := newFakeBus()
+	d, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each measurement does 4+4+36*4+4 = 156 I²C transactions.
+	for _, n := range []int{0, 4, 8, 20, 60, 152} {
+		f.failAfter = n
+		var s Spectrum
+		if err := d.Sense(&s); err == nil {
+			t.Fatalf("#%d: I²C failed", n)
+		}
+	}
+	f.failAfter = 0
+	if err := d.SetIntegration(140 * time.Millisecond); err == nil {
+		t.Fatal("I²C failed")
+	}
+	if err := d.SetLED(true, LED12_5mA); err == nil {
+		t.Fatal("I²C failed")
+	}
+	if err := d.Halt(); err == nil {
+		t.Fatal("I²C failed")
+	}
+	// Auto exposure write failure.
+	f.failAfter = -1
+	f.counts = func(gain, intT byte) [6]uint16 {
+		return [6]uint16{65535}
+	}
+	d.SetAutoExposure(true)
+	f.failAfter = 156
+	var s Spectrum
+	if err := d.Sense(&s); err == nil {
+		t.Fatal("I²C failed")
+	}
+	// Stuck virtual register interface.
+	f.failAfter = -1
+	f.stuck = statusTxValid
+	if err := d.Halt(); err == nil {
+		t.Fatal("timed out")
+	}
+	f.stuck = 0
+	f.noData = true
+	if err := d.Sense(&s); err == nil {
+		t.Fatal("timed out")
+	}
+}
+
+func TestGain_String(t *testing.T) {
+	if s := G3_7x.String(); s != "G3_7x" {
+		t.Fatal(s)
+	}
+	if s := Gain(4).String(); s != "Gain(4)" {
+		t.Fatal(s)
+	}
+}
+
+func TestLEDCurrent_String(t *testing.T) {
+	if s := LED12_5mA.String(); s != "LED12_5mA" {
+		t.Fatal(s)
+	}
+	if s := LED100mA.String(); s != "LED100mA" {
+		t.Fatal(s)
+	}
+	if s := LEDCurrent(4).String(); s != "LEDCurrent(4)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+// fakeBus emulates the virtual register interface.
+type fakeBus struct {
+	vregs [256]byte
+	// counts returns the raw counts of a measurement.
+	counts func(gain, intT byte) [6]uint16
+	// failAfter fails the transactions after this number of successful ones,
+	// when not -1.
+	failAfter int
+	// stuck is the status bits always set.
+	stuck byte
+	// noData never completes a measurement.
+	noData bool
+
+	status  byte
+	pending int
+	rx      byte
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{failAfter: -1, pending: -1}
+	f.vregs[vregHWVersion] = deviceType
+	f.vregs[vregDeviceTemp] = 25
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if f.failAfter == 0 {
+		return errors.New("injected")
+	}
+	if f.failAfter > 0 {
+		f.failAfter--
+	}
+	switch w[0] {
+	case regStatus:
+		r[0] = f.status | f.stuck
+	case regRead:
+		r[0] = f.rx
+		f.status &^= statusRxValid
+	case regWrite:
+		v := w[1]
+		switch {
+		case f.pending != -1:
+			f.vregs[f.pending] = v
+			if f.pending == vregControl {
+				f.measure()
+			}
+			f.pending = -1
+		case v&0x80 != 0:
+			f.pending = int(v & 0x7F)
+		default:
+			f.rx = f.vregs[v]
+			f.status |= statusRxValid
+		}
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) measure() {
+	if f.noData || f.counts == nil {
+		return
+	}
+	c := f.counts((f.vregs[vregControl]>>4)&3, f.vregs[vregIntT])
+	for i, v := range c {
+		f.vregs[vregRaw+2*i] = byte(v >> 8)
+		f.vregs[vregRaw+2*i+1] = byte(v)
+		b := math.Float32bits(float32(v) * 1.5)
+		for j := 0; j < 4; j++ {
+			f.vregs[vregCalibrated+4*i+j] = byte(b >> uint(24-8*j))
+		}
+	}
+	f.vregs[vregControl] |= controlDataReady
+}
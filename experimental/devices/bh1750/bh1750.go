@@ -0,0 +1,259 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package bh1750 controls a ROHM BH1750 ambient light sensor over I²C.
+//
+// The device implements devices.Environmental; only the Light field is
+// set.
+//
+// Datasheet
+//
+// https://www.mouser.com/datasheet/2/348/bh1750fvi-e-186247.pdf
+package bh1750
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Resolution is the measurement resolution. Higher resolutions take longer.
+type Resolution uint8
+
+// Possible resolutions.
+const (
+	HighRes  Resolution = 0 // 1lx, 120ms
+	HighRes2 Resolution = 1 // 0.5lx, 120ms
+	LowRes   Resolution = 2 // 4lx, 16ms
+)
+
+const resolutionName = "HighResHighRes2LowRes"
+
+var resolutionIndex = [...]uint8{0, 7, 15, 21}
+
+func (r Resolution) String() string {
+	if r >= Resolution(len(resolutionIndex)-1) {
+		return fmt.Sprintf("Resolution(%d)", r)
+	}
+	return resolutionName[resolutionIndex[r]:resolutionIndex[r+1]]
+}
+
+// Duration returns the maximum measurement time.
+func (r Resolution) Duration() time.Duration {
+	if r > LowRes {
+		return 0
+	}
+	return measurementTime[r]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Resolution Resolution
+}
+
+// Dev is a handle to a BH1750.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a BH1750.
+//
+// The address is 0x23 when ADDR is low and 0x5C when ADDR is high.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x23, 0x5C:
+	default:
+		return nil, errors.New("bh1750: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Resolution > LowRes {
+		return nil, fmt.Errorf("bh1750: invalid resolution %d", opts.Resolution)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	// The device has no identification register; make sure it acknowledges.
+	if err := d.command(cmdPowerDown); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("BH1750{%s}", d.c)
+}
+
+// Sense does a one time measurement of the illuminance. Only env.Light,
+// env.Time and env.Quality are modified; env.Quality is QualitySaturated when
+// the illuminance is beyond the range of the resolution.
+//
+// The device powers down automatically after the measurement.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("bh1750: already sensing continuously")
+	}
+	if err := d.command(cmdOneTime | resolutionMode[d.opts.Resolution]); err != nil {
+		return err
+	}
+	time.Sleep(d.opts.Resolution.Duration())
+	return d.read(env)
+}
+
+// SenseContinuous puts the device in continuous measurement mode and returns
+// the illuminance at the requested interval.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < d.opts.Resolution.Duration() {
+		return nil, errors.New("bh1750: interval is shorter than the measurement time")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.command(cmdContinuous | resolutionMode[d.opts.Resolution]); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// SetResolution changes the measurement resolution.
+func (d *Dev) SetResolution(r Resolution) error {
+	if r > LowRes {
+		return fmt.Errorf("bh1750: invalid resolution %d", r)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("bh1750: already sensing continuously")
+	}
+	d.opts.Resolution = r
+	return nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricLight
+}
+
+// Halt stops the sensing as initiated by SenseContinuous() and powers down
+// the device.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.command(cmdPowerDown)
+}
+
+//
+
+const (
+	cmdPowerDown  = 0x00
+	cmdContinuous = 0x10
+	cmdOneTime    = 0x20
+)
+
+// resolutionMode is the mode bits, indexed by Resolution.
+var resolutionMode = [...]byte{0x00, 0x01, 0x03}
+
+// measurementTime is indexed by Resolution; page 2.
+var measurementTime = [...]time.Duration{180 * time.Millisecond, 180 * time.Millisecond, 24 * time.Millisecond}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.read(&e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// read reads the last measurement.
+func (d *Dev) read(env *devices.Environment) error {
+	var b [2]byte
+	if err := d.c.Tx(nil, b[:]); err != nil {
+		return fmt.Errorf("bh1750: %v", err)
+	}
+	v := uint16(b[0])<<8 | uint16(b[1])
+	env.Light = calculateLux(v, d.opts.Resolution)
+	env.Time = time.Now()
+	env.Quality = 0
+	if v == 0xFFFF {
+		env.Quality = devices.QualitySaturated
+	}
+	return nil
+}
+
+func (d *Dev) command(c byte) error {
+	if err := d.c.Tx([]byte{c}, nil); err != nil {
+		return fmt.Errorf("bh1750: %v", err)
+	}
+	return nil
+}
+
+// calculateLux converts the raw count to lux. One count is 1/1.2lx, or half
+// of it in HighRes2.
+func calculateLux(v uint16, r Resolution) devices.Lux {
+	l := int64(v) * 2500 / 3
+	if r == HighRes2 {
+		l /= 2
+	}
+	return devices.Lux(l)
+}
+
+var defaults = Opts{
+	Resolution: HighRes,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
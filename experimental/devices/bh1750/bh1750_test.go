@@ -0,0 +1,206 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bh1750
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x23, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize bh1750: %v", err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", env.Light)
+}
+
+//
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x23, W: []byte{0x00}},
+			{Addr: 0x23, W: []byte{0x23}},
+			{Addr: 0x23, R: []byte{0x01, 0x2C}},
+			{Addr: 0x23, W: []byte{0x21}},
+			{Addr: 0x23, R: []byte{0x01, 0x2C}},
+			{Addr: 0x23, W: []byte{0x00}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x23, &Opts{Resolution: LowRes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "BH1750{playback(35)}" {
+		t.Fatal(s)
+	}
+	env := devices.Environment{Temperature: 1}
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 1, Light: 250000, Time: env.Time}); env != expected {
+		t.Fatal(env)
+	}
+	if err := dev.SetResolution(HighRes2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetResolution(3); err == nil {
+		t.Fatal("invalid resolution")
+	}
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Light != 125000 {
+		t.Fatal(env.Light)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSense_saturated(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x23, W: []byte{0x00}},
+			{Addr: 0x23, W: []byte{0x23}},
+			{Addr: 0x23, R: []byte{0xFF, 0xFF}},
+			{Addr: 0x23, W: []byte{0x23}},
+			{Addr: 0x23, R: []byte{0x01, 0x2C}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x23, &Opts{Resolution: LowRes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := devices.Environment{}
+	if err := dev.Sense(&env); err != nil || env.Quality != devices.QualitySaturated {
+		t.Fatal(env, err)
+	}
+	// The flag is cleared by the next valid measurement.
+	if err := dev.Sense(&env); err != nil || env.Quality != 0 {
+		t.Fatal(env, err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x40, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x23, &Opts{Resolution: 3}); err == nil {
+		t.Fatal("invalid resolution")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x5C, nil); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x5C, W: []byte{0x00}},
+			{Addr: 0x5C, W: []byte{0x13}},
+			{Addr: 0x5C, R: []byte{0x00, 0x0C}},
+			{Addr: 0x5C, R: []byte{0x00, 0x0C}},
+			{Addr: 0x5C, R: []byte{0x00, 0x0C}},
+		},
+		DontPanic: true,
+	}
+	dev, err := NewI2C(&bus, 0x5C, &Opts{Resolution: LowRes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(30 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if e := <-c; e.Light != 10000 {
+			t.Fatal(e.Light)
+		}
+	}
+	if err := dev.SetResolution(HighRes); err == nil {
+		t.Fatal("already sensing")
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("already sensing")
+	}
+	// The third measurement may or may not have happened.
+	dev.stopSensing()
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	bus.Ops = []i2ctest.IO{{Addr: 0x5C, W: []byte{0x00}}}
+	bus.Count = 0
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCalculateLux(t *testing.T) {
+	data := []struct {
+		v        uint16
+		r        Resolution
+		expected devices.Lux
+	}{
+		{0, HighRes, 0},
+		{1, HighRes, 833},
+		{12, HighRes, 10000},
+		{12, HighRes2, 5000},
+		{65535, HighRes, 54612500},
+	}
+	for i, line := range data {
+		if l := calculateLux(line.v, line.r); l != line.expected {
+			t.Fatalf("#%d: %d != %d", i, l, line.expected)
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	if s := HighRes2.String(); s != "HighRes2" {
+		t.Fatal(s)
+	}
+	if s := Resolution(3).String(); s != "Resolution(3)" {
+		t.Fatal(s)
+	}
+	if d := LowRes.Duration(); d != 24*time.Millisecond {
+		t.Fatal(d)
+	}
+	if d := Resolution(3).Duration(); d != 0 {
+		t.Fatal(d)
+	}
+}
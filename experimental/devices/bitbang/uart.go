@@ -0,0 +1,336 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bitbang
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+// MaxBaud is the maximum speed accepted by the bit-banged UART.
+//
+// The achievable speed depends on the GPIO driver and on the CPU load. As a
+// rule of thumb, with a memory mapped GPIO driver like bcm283x or allwinner,
+// transmission is reliable up to 57600 baud and reception up to 19200 baud.
+// With the sysfs GPIO driver, do not expect more than 1200 baud.
+//
+// Reception is more sensitive than transmission because the start bit has to
+// be detected by polling the RX pin.
+const MaxBaud = 57600
+
+// oversampling is the number of times the RX pin is polled per bit while
+// waiting for the start bit, and the number of samples taken around the
+// center of each bit for majority voting.
+const oversampling = 3
+
+// UART represents an UART port implemented as bit-banging on 2 GPIO pins.
+//
+// It is half duplex: Tx() first writes w, then reads len(r) bytes.
+type UART struct {
+	tx gpio.PinOut
+	rx gpio.PinIn
+
+	mu          sync.Mutex
+	baud        int
+	stop        uart.Stop
+	parity      uart.Parity
+	bits        int
+	bitTime     time.Duration
+	readTimeout time.Duration
+}
+
+// NewUART returns an object that communicates UART over 2 pins.
+//
+// rx can be nil for a transmit-only port. The port is configured as 8N1 at
+// the specified speed.
+func NewUART(tx gpio.PinOut, rx gpio.PinIn, baud int) (*UART, error) {
+	// The line idles high.
+	if err := tx.Out(gpio.High); err != nil {
+		return nil, err
+	}
+	if rx != nil {
+		if err := rx.In(gpio.PullUp, gpio.NoEdge); err != nil {
+			return nil, err
+		}
+	}
+	u := &UART{
+		tx:          tx,
+		rx:          rx,
+		stop:        uart.One,
+		parity:      uart.None,
+		bits:        8,
+		readTimeout: time.Second,
+	}
+	if err := u.Speed(baud); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// RegisterUART registers an UART port bit-banged over tx and rx in uartreg.
+//
+// Each Open() call returns a new handle configured as 9600 8N1.
+func RegisterUART(name string, aliases []string, tx gpio.PinOut, rx gpio.PinIn) error {
+	return uartreg.RegisterWithInfo(name, aliases, -1, conn.Info{Driver: "bitbang", MaxSpeed: MaxBaud}, func() (uart.ConnCloser, error) {
+		return NewUART(tx, rx, 9600)
+	})
+}
+
+func (u *UART) String() string {
+	return fmt.Sprintf("bitbang/uart(%s, %s)", u.tx, u.rx)
+}
+
+// Close implements uart.ConnCloser.
+func (u *UART) Close() error {
+	return nil
+}
+
+// Duplex implements uart.Conn.
+func (u *UART) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+// Speed implements uart.Conn.
+func (u *UART) Speed(baud int) error {
+	if baud <= 0 || baud > MaxBaud {
+		return fmt.Errorf("bitbang-uart: invalid speed %d; max is %d", baud, MaxBaud)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.baud = baud
+	u.bitTime = time.Second / time.Duration(baud)
+	return nil
+}
+
+// Configure implements uart.Conn.
+func (u *UART) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	if bits < 5 || bits > 8 {
+		return fmt.Errorf("bitbang-uart: invalid number of bits %d", bits)
+	}
+	switch stopBit {
+	case uart.One, uart.OneHalf, uart.Two:
+	default:
+		return fmt.Errorf("bitbang-uart: invalid stop bit %d", stopBit)
+	}
+	switch parity {
+	case uart.None, uart.Odd, uart.Even, uart.Mark, uart.Space:
+	default:
+		return fmt.Errorf("bitbang-uart: invalid parity %q", parity)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.stop = stopBit
+	u.parity = parity
+	u.bits = bits
+	return nil
+}
+
+// SetReadTimeout sets the maximum time to wait for each start bit while
+// reading. The default is one second.
+func (u *UART) SetReadTimeout(d time.Duration) error {
+	if d <= 0 {
+		return errors.New("bitbang-uart: invalid timeout")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.readTimeout = d
+	return nil
+}
+
+// Tx implements uart.Conn.
+//
+// It writes w then reads len(r) bytes. Reading fails if the start bit of a
+// byte isn't received within the read timeout.
+func (u *UART) Tx(w, r []byte) error {
+	if len(r) != 0 && u.rx == nil {
+		return errors.New("bitbang-uart: no RX pin")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	// This helps reduce jitter a little.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for _, b := range w {
+		if err := u.writeByte(b); err != nil {
+			return err
+		}
+	}
+	for i := range r {
+		b, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		r[i] = b
+	}
+	return nil
+}
+
+// Write implements io.Writer.
+func (u *UART) Write(b []byte) (int, error) {
+	if err := u.Tx(b, nil); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// RX implements uart.Pins.
+func (u *UART) RX() gpio.PinIn {
+	if u.rx == nil {
+		return gpio.INVALID
+	}
+	return u.rx
+}
+
+// TX implements uart.Pins.
+func (u *UART) TX() gpio.PinOut {
+	return u.tx
+}
+
+// RTS implements uart.Pins.
+func (u *UART) RTS() gpio.PinIO {
+	return gpio.INVALID
+}
+
+// CTS implements uart.Pins.
+func (u *UART) CTS() gpio.PinIO {
+	return gpio.INVALID
+}
+
+//
+
+// writeByte sends one character.
+//
+// The bit edges are timed relative to the start of the character so that the
+// jitter doesn't accumulate over the frame.
+func (u *UART) writeByte(b byte) error {
+	start := now()
+	n := time.Duration(0)
+	if err := u.tx.Out(gpio.Low); err != nil {
+		return err
+	}
+	n++
+	spinUntil(start.Add(n * u.bitTime))
+	for i := 0; i < u.bits; i++ {
+		u.tx.Out(b&(1<<uint(i)) != 0)
+		n++
+		spinUntil(start.Add(n * u.bitTime))
+	}
+	if p, ok := u.parityBit(b); ok {
+		u.tx.Out(p)
+		n++
+		spinUntil(start.Add(n * u.bitTime))
+	}
+	u.tx.Out(gpio.High)
+	spinUntil(start.Add(n*u.bitTime + u.stopTime()))
+	return nil
+}
+
+// readByte waits for a start bit and samples one character.
+func (u *UART) readByte() (byte, error) {
+	poll := u.bitTime / oversampling
+	deadline := now().Add(u.readTimeout)
+	var edge time.Time
+	for {
+		if u.rx.Read() == gpio.Low {
+			// The edge happened at some point during the last polling period.
+			edge = now().Add(-poll / 2)
+			break
+		}
+		if now().After(deadline) {
+			return 0, errors.New("bitbang-uart: timeout waiting for start bit")
+		}
+		spinUntil(now().Add(poll))
+	}
+	center := edge.Add(u.bitTime / 2)
+	if u.sample(center) != gpio.Low {
+		return 0, errors.New("bitbang-uart: glitch on start bit")
+	}
+	var b byte
+	n := time.Duration(1)
+	for i := 0; i < u.bits; i++ {
+		if u.sample(center.Add(n*u.bitTime)) == gpio.High {
+			b |= 1 << uint(i)
+		}
+		n++
+	}
+	if p, ok := u.parityBit(b); ok {
+		if u.sample(center.Add(n*u.bitTime)) != p {
+			return 0, errors.New("bitbang-uart: parity error")
+		}
+		n++
+	}
+	if u.sample(center.Add(n*u.bitTime)) != gpio.High {
+		return 0, errors.New("bitbang-uart: framing error")
+	}
+	return b, nil
+}
+
+// sample does a majority vote of the samples taken around t.
+func (u *UART) sample(t time.Time) gpio.Level {
+	step := u.bitTime / (2 * oversampling)
+	t = t.Add(-step * (oversampling / 2))
+	high := 0
+	for i := 0; i < oversampling; i++ {
+		spinUntil(t.Add(time.Duration(i) * step))
+		if u.rx.Read() == gpio.High {
+			high++
+		}
+	}
+	return high*2 > oversampling
+}
+
+// parityBit returns the parity bit to use for b, if any.
+func (u *UART) parityBit(b byte) (gpio.Level, bool) {
+	ones := 0
+	for i := 0; i < u.bits; i++ {
+		if b&(1<<uint(i)) != 0 {
+			ones++
+		}
+	}
+	switch u.parity {
+	case uart.Odd:
+		return ones%2 == 0, true
+	case uart.Even:
+		return ones%2 != 0, true
+	case uart.Mark:
+		return gpio.High, true
+	case uart.Space:
+		return gpio.Low, true
+	default:
+		return gpio.Low, false
+	}
+}
+
+func (u *UART) stopTime() time.Duration {
+	switch u.stop {
+	case uart.OneHalf:
+		return u.bitTime + u.bitTime/2
+	case uart.Two:
+		return 2 * u.bitTime
+	default:
+		return u.bitTime
+	}
+}
+
+// spinUntil does a busy loop until t to act as precisely as possible.
+func spinUntil(t time.Time) {
+	for now().Before(t) {
+	}
+}
+
+// now is replaced in unit tests by a fake clock.
+var now = time.Now
+
+var _ uart.ConnCloser = &UART{}
+var _ uart.Pins = &UART{}
+var _ fmt.Stringer = &UART{}
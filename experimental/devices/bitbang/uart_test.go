@@ -0,0 +1,460 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bitbang
+
+import (
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+func ExampleNewUART() {
+	// Use the pins of your choice.
+	u, err := NewUART(gpioreg.ByName("GPIO4"), gpioreg.ByName("GPIO17"), 9600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer u.Close()
+	r := make([]byte, 2)
+	if err := u.Tx([]byte("AT\r\n"), r); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNewUART(t *testing.T) {
+	defer setClock()()
+	tx := newWire("TX")
+	rx := newWire("RX")
+	u, err := NewUART(tx, rx, 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The line idles high.
+	if l := tx.levels(); len(l) != 1 || l[0] != gpio.High {
+		t.Fatal(l)
+	}
+	if rx.pull != gpio.PullUp {
+		t.Fatal(rx.pull)
+	}
+	if s := u.String(); s != "bitbang/uart(TX(1), RX(1))" {
+		t.Fatal(s)
+	}
+	if d := u.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	if u.TX() != tx || u.RX() != rx || u.RTS() != gpio.INVALID || u.CTS() != gpio.INVALID {
+		t.Fatal("unexpected pins")
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewUART_fail(t *testing.T) {
+	defer setClock()()
+	if _, err := NewUART(newWire("TX"), newWire("RX"), 0); err == nil {
+		t.Fatal("invalid speed")
+	}
+	if _, err := NewUART(&failPin{Pin: gpiotest.Pin{N: "TX"}}, nil, 9600); err == nil {
+		t.Fatal("tx failed")
+	}
+	if _, err := NewUART(newWire("TX"), &failPin{Pin: gpiotest.Pin{N: "RX"}}, 9600); err == nil {
+		t.Fatal("rx failed")
+	}
+}
+
+func TestUART_Speed(t *testing.T) {
+	defer setClock()()
+	u, err := NewUART(newWire("TX"), nil, 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []int{-1, 0, MaxBaud + 1} {
+		if u.Speed(b) == nil {
+			t.Fatal(b)
+		}
+	}
+	if u.baud != 9600 {
+		t.Fatal(u.baud)
+	}
+	if err := u.Speed(MaxBaud); err != nil {
+		t.Fatal(err)
+	}
+	if u.bitTime != time.Second/MaxBaud {
+		t.Fatal(u.bitTime)
+	}
+}
+
+func TestUART_Configure(t *testing.T) {
+	defer setClock()()
+	u, err := NewUART(newWire("TX"), nil, 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []struct {
+		stop   uart.Stop
+		parity uart.Parity
+		bits   int
+	}{
+		{uart.One, uart.None, 4},
+		{uart.One, uart.None, 9},
+		{uart.Stop(3), uart.None, 8},
+		{uart.Stop(-1), uart.None, 8},
+		{uart.One, uart.Parity('X'), 8},
+	}
+	for i, l := range data {
+		if u.Configure(l.stop, l.parity, l.bits) == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	// The configuration is unchanged.
+	if u.stop != uart.One || u.parity != uart.None || u.bits != 8 {
+		t.Fatal(u.stop, u.parity, u.bits)
+	}
+	if err := u.Configure(uart.Two, uart.Even, 5); err != nil {
+		t.Fatal(err)
+	}
+	if u.stop != uart.Two || u.parity != uart.Even || u.bits != 5 {
+		t.Fatal(u.stop, u.parity, u.bits)
+	}
+	if u.SetReadTimeout(0) == nil {
+		t.Fatal("invalid timeout")
+	}
+	if err := u.SetReadTimeout(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUART_write(t *testing.T) {
+	const H, L = gpio.High, gpio.Low
+	data := []struct {
+		stop   uart.Stop
+		parity uart.Parity
+		bits   int
+		b      byte
+		want   []gpio.Level // Data bits LSB first, then the parity bit if any
+	}{
+		{uart.One, uart.None, 8, 0x55, []gpio.Level{H, L, H, L, H, L, H, L}},
+		{uart.One, uart.None, 8, 0x80, []gpio.Level{L, L, L, L, L, L, L, H}},
+		{uart.One, uart.None, 7, 0x41, []gpio.Level{H, L, L, L, L, L, H}},
+		{uart.One, uart.None, 5, 0xFF, []gpio.Level{H, H, H, H, H}},
+		// 0x07 has 3 bits set, 0x0F has 4.
+		{uart.One, uart.Odd, 8, 0x07, []gpio.Level{H, H, H, L, L, L, L, L, L}},
+		{uart.One, uart.Odd, 8, 0x0F, []gpio.Level{H, H, H, H, L, L, L, L, H}},
+		{uart.One, uart.Even, 8, 0x07, []gpio.Level{H, H, H, L, L, L, L, L, H}},
+		{uart.One, uart.Even, 8, 0x0F, []gpio.Level{H, H, H, H, L, L, L, L, L}},
+		{uart.One, uart.Mark, 8, 0x00, []gpio.Level{L, L, L, L, L, L, L, L, H}},
+		{uart.One, uart.Space, 8, 0xFF, []gpio.Level{H, H, H, H, H, H, H, H, L}},
+		// The bits above the word size don't count in the parity.
+		{uart.One, uart.Even, 5, 0xE1, []gpio.Level{H, L, L, L, L, H}},
+		{uart.OneHalf, uart.None, 8, 0x00, []gpio.Level{L, L, L, L, L, L, L, L}},
+		{uart.Two, uart.Even, 8, 0x01, []gpio.Level{H, L, L, L, L, L, L, L, H}},
+	}
+	for i, line := range data {
+		restore := setClock()
+		tx := newWire("TX")
+		u, err := NewUART(tx, nil, 9600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Configure(line.stop, line.parity, line.bits); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := u.Write([]byte{line.b}); n != 1 || err != nil {
+			t.Fatal(n, err)
+		}
+		end := clock.t
+		restore()
+
+		// The initial idle level, the start bit, the data and parity bits, then
+		// the stop bit.
+		want := append(append([]gpio.Level{H, L}, line.want...), H)
+		got := tx.levels()
+		if !equalLevels(got, want) {
+			t.Fatalf("#%d: got %v, want %v", i, got, want)
+		}
+		// Each bit is driven on time relative to the start bit.
+		start := tx.edges[1].t
+		for j, e := range tx.edges[1:] {
+			if d := e.t.Sub(start) - time.Duration(j)*u.bitTime; d < 0 || d > tolerance {
+				t.Fatalf("#%d: bit %d is %s late", i, j, d)
+			}
+		}
+		// The stop bit is held for its duration.
+		if d := end.Sub(tx.edges[len(tx.edges)-1].t) - u.stopTime(); d < -tolerance || d > tolerance {
+			t.Fatalf("#%d: stop bit is off by %s", i, d)
+		}
+	}
+}
+
+func TestUART_loopback(t *testing.T) {
+	w := []byte{0x00, 0xFF, 0x55, 0xA5, 0x0F}
+	data := []struct {
+		stop   uart.Stop
+		parity uart.Parity
+		bits   int
+		baud   int
+	}{
+		{uart.One, uart.None, 8, 9600},
+		{uart.One, uart.Odd, 8, 9600},
+		{uart.One, uart.Even, 8, 19200},
+		{uart.One, uart.Mark, 8, 1200},
+		{uart.One, uart.Space, 8, 9600},
+		{uart.OneHalf, uart.Even, 7, 9600},
+		{uart.Two, uart.Odd, 5, 9600},
+	}
+	for i, line := range data {
+		func() {
+			defer setClock()()
+			tx := newWire("TX")
+			rx := newWire("RX")
+			u, err := NewUART(tx, rx, line.baud)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := u.Configure(line.stop, line.parity, line.bits); err != nil {
+				t.Fatal(err)
+			}
+			if err := u.Tx(w, nil); err != nil {
+				t.Fatal(err)
+			}
+			// Replay the transmission on RX, starting a bit later.
+			rx.replay(tx, clock.t.Add(u.bitTime))
+			r := make([]byte, len(w))
+			if err := u.Tx(nil, r); err != nil {
+				t.Fatalf("#%d: %v", i, err)
+			}
+			mask := byte(1<<uint(line.bits) - 1)
+			for j := range w {
+				if r[j] != w[j]&mask {
+					t.Fatalf("#%d: got %#v, want %#v", i, r, w)
+				}
+			}
+		}()
+	}
+}
+
+func TestUART_read_errors(t *testing.T) {
+	const H, L = gpio.High, gpio.Low
+	data := []struct {
+		name   string
+		parity uart.Parity
+		frame  []gpio.Level // One level per bit time, starting with the start bit
+		err    string
+	}{
+		{
+			"framing",
+			uart.None,
+			[]gpio.Level{L, H, L, H, L, H, L, H, L, L},
+			"bitbang-uart: framing error",
+		},
+		{
+			"parity",
+			uart.Even,
+			// 0x55 has an even number of bits set.
+			[]gpio.Level{L, H, L, H, L, H, L, H, L, H, H},
+			"bitbang-uart: parity error",
+		},
+		{
+			"glitch",
+			uart.None,
+			nil,
+			"bitbang-uart: glitch on start bit",
+		},
+		{
+			"timeout",
+			uart.None,
+			[]gpio.Level{},
+			"bitbang-uart: timeout waiting for start bit",
+		},
+	}
+	for _, line := range data {
+		func() {
+			defer setClock()()
+			rx := newWire("RX")
+			u, err := NewUART(newWire("TX"), rx, 9600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := u.Configure(uart.One, line.parity, 8); err != nil {
+				t.Fatal(err)
+			}
+			if err := u.SetReadTimeout(10 * time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			start := clock.t.Add(u.bitTime)
+			if line.frame == nil {
+				// A pulse much shorter than a bit.
+				rx.edges = []edge{{start, L}, {start.Add(u.bitTime / 8), H}}
+			} else {
+				for i, l := range line.frame {
+					rx.edges = append(rx.edges, edge{start.Add(time.Duration(i) * u.bitTime), l})
+				}
+			}
+			var r [1]byte
+			if err := u.Tx(nil, r[:]); err == nil || err.Error() != line.err {
+				t.Fatalf("%s: %v", line.name, err)
+			}
+		}()
+	}
+}
+
+func TestUART_no_rx(t *testing.T) {
+	defer setClock()()
+	u, err := NewUART(newWire("TX"), nil, 9600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.RX() != gpio.INVALID {
+		t.Fatal(u.RX())
+	}
+	var r [1]byte
+	if u.Tx(nil, r[:]) == nil {
+		t.Fatal("no RX pin")
+	}
+	if err := u.Tx([]byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterUART(t *testing.T) {
+	defer setClock()()
+	if err := RegisterUART("bitbang-test", nil, newWire("TX"), newWire("RX")); err != nil {
+		t.Fatal(err)
+	}
+	defer uartreg.Unregister("bitbang-test")
+	p, err := uartreg.Open("bitbang-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := p.(*UART); u.baud != 9600 || u.bits != 8 || u.parity != uart.None || u.stop != uart.One {
+		t.Fatal(u.baud, u.bits, u.parity, u.stop)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//
+
+// tolerance is the timing error accepted. The fake clock advances by 1µs on
+// each call, so the busy loops overshoot by a few µs.
+const tolerance = 5 * time.Microsecond
+
+// fakeClock is a virtual clock advancing on each call, so the busy loops run
+// deterministically.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.t = c.t.Add(time.Microsecond)
+	return c.t
+}
+
+var clock *fakeClock
+
+// setClock replaces the clock with a fake one and returns a function to
+// restore it.
+func setClock() func() {
+	clock = &fakeClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	now = clock.now
+	return func() {
+		now = time.Now
+	}
+}
+
+type edge struct {
+	t time.Time
+	l gpio.Level
+}
+
+// wire is a fake line on the fake clock.
+//
+// As output, it records the edges. As input, it returns the level set by the
+// last edge at the current time, high before the first edge.
+type wire struct {
+	gpiotest.Pin
+	edges []edge
+	pull  gpio.Pull
+}
+
+func newWire(name string) *wire {
+	return &wire{Pin: gpiotest.Pin{N: name, Num: 1}}
+}
+
+func (w *wire) In(pull gpio.Pull, edge gpio.Edge) error {
+	w.pull = pull
+	return nil
+}
+
+func (w *wire) Read() gpio.Level {
+	l := gpio.High
+	for _, e := range w.edges {
+		if e.t.After(clock.t) {
+			break
+		}
+		l = e.l
+	}
+	return l
+}
+
+func (w *wire) Out(l gpio.Level) error {
+	w.edges = append(w.edges, edge{clock.t, l})
+	return nil
+}
+
+func (w *wire) levels() []gpio.Level {
+	out := make([]gpio.Level, len(w.edges))
+	for i, e := range w.edges {
+		out[i] = e.l
+	}
+	return out
+}
+
+// replay sets the edges recorded by src, shifted so the first one after the
+// initial idle level happens at start.
+func (w *wire) replay(src *wire, start time.Time) {
+	d := start.Sub(src.edges[1].t)
+	w.edges = nil
+	for _, e := range src.edges[1:] {
+		w.edges = append(w.edges, edge{e.t.Add(d), e.l})
+	}
+}
+
+// failPin fails In() and Out().
+type failPin struct {
+	gpiotest.Pin
+}
+
+func (f *failPin) In(pull gpio.Pull, edge gpio.Edge) error {
+	return errors.New("failed")
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	return errors.New("failed")
+}
+
+func equalLevels(a, b []gpio.Level) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
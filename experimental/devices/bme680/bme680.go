@@ -0,0 +1,521 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package bme680 controls a Bosch BME680 gas, pressure, temperature and
+// humidity sensor over I²C or SPI.
+//
+// The gas sensor is a metal oxide layer heated by a hot plate. Its resistance
+// decreases in presence of volatile organic compounds. The raw readings are
+// exposed in Measurement so an indoor air quality (IAQ) index can be computed
+// by an external algorithm, like Bosch's BSEC library.
+//
+// Datasheet
+//
+// https://ae-bst.resource.bosch.com/media/_tech/media/datasheets/BST-BME680-DS001.pdf
+package bme680
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+)
+
+// Oversampling affects how much time is taken to measure each of temperature,
+// pressure and humidity.
+type Oversampling uint8
+
+// Possible oversampling values.
+const (
+	Off  Oversampling = 0
+	O1x  Oversampling = 1
+	O2x  Oversampling = 2
+	O4x  Oversampling = 3
+	O8x  Oversampling = 4
+	O16x Oversampling = 5
+)
+
+const oversamplingName = "Off1x2x4x8x16x"
+
+var oversamplingIndex = [...]uint8{0, 3, 5, 7, 9, 11, 14}
+
+func (o Oversampling) String() string {
+	if o >= Oversampling(len(oversamplingIndex)-1) {
+		return fmt.Sprintf("Oversampling(%d)", o)
+	}
+	return oversamplingName[oversamplingIndex[o]:oversamplingIndex[o+1]]
+}
+
+func (o Oversampling) asValue() int {
+	if o == Off || o > O16x {
+		return 0
+	}
+	return 1 << (o - 1)
+}
+
+// Filter specifies the internal IIR filter to get steadier measurements.
+type Filter uint8
+
+// Possible filtering values.
+const (
+	NoFilter Filter = 0
+	F1       Filter = 1
+	F3       Filter = 2
+	F7       Filter = 3
+	F15      Filter = 4
+	F31      Filter = 5
+	F63      Filter = 6
+	F127     Filter = 7
+)
+
+// HeaterProfile is the target temperature of the gas sensor hot plate and the
+// time to keep it at this temperature before measuring the gas resistance.
+//
+// Typical values are 320°C for 150ms. Use a zero Duration to disable the gas
+// measurement.
+type HeaterProfile struct {
+	// Temperature is the target temperature in °C, between 200 and 400.
+	Temperature int
+	// Duration is the heating time, up to 4032ms.
+	Duration time.Duration
+}
+
+// Opts is optional options to pass to the constructor.
+//
+// Recommended (and default) values are O4x for oversampling and 320°C for
+// 150ms for the heater.
+type Opts struct {
+	// Temperature must be measured for pressure, humidity and gas to be
+	// measured.
+	Temperature Oversampling
+	Pressure    Oversampling
+	Humidity    Oversampling
+	Filter      Filter
+	Heater      HeaterProfile
+}
+
+// Measurement is a complete measurement, including the gas sensor reading and
+// the raw values.
+type Measurement struct {
+	devices.Environment
+	// GasResistance is the compensated gas sensor resistance in Ω.
+	GasResistance uint32
+	// GasValid is true when the gas measurement was done.
+	GasValid bool
+	// HeaterStable is true when the hot plate reached the target temperature
+	// before the gas measurement.
+	HeaterStable bool
+
+	// Raw values as read from the ADCs, for use by IAQ algorithms.
+	RawTemperature uint32
+	RawPressure    uint32
+	RawHumidity    uint16
+	RawGas         uint16
+	GasRange       uint8
+}
+
+// Dev is a handle to an initialized BME680 device.
+type Dev struct {
+	d     conn.Conn
+	isSPI bool
+	opts  Opts
+	cal   calibration
+
+	mu      sync.Mutex
+	page    int8 // SPI memory page, -1 if unknown.
+	ambient int32
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a BME680.
+//
+// The address must be 0x76 or 0x77, depending on the SDO pin.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x76, 0x77:
+	default:
+		return nil, errors.New("bme680: given address not supported by device")
+	}
+	d := &Dev{d: &i2c.Dev{Bus: b, Addr: addr}}
+	if err := d.makeDev(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewSPI returns an object that communicates over SPI to a BME680.
+//
+// When using SPI, the CS line must be used.
+func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
+	// It works both in Mode0 and Mode3.
+	c, err := p.Connect(10000000, spi.Mode3, 8)
+	if err != nil {
+		return nil, fmt.Errorf("bme680: %v", err)
+	}
+	d := &Dev{d: c, isSPI: true, page: -1}
+	if err := d.makeDev(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("BME680{%s}", d.d)
+}
+
+// Sense requests a one time measurement as °C, kPa and % of relative
+// humidity.
+//
+// The gas sensor is also measured if a heater profile is set and stored in
+// env.Gas; use SenseGas() to retrieve the details.
+func (d *Dev) Sense(env *devices.Environment) error {
+	var m Measurement
+	if err := d.SenseGas(&m); err != nil {
+		return err
+	}
+	*env = m.Environment
+	return nil
+}
+
+// SenseGas requests a one time measurement of all the sensors, including the
+// gas sensor.
+func (d *Dev) SenseGas(m *Measurement) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("bme680: already sensing continuously")
+	}
+	return d.sense(m)
+}
+
+// SenseContinuous returns measurements as °C, kPa and % of relative humidity
+// on a continuous basis.
+//
+// The BME680 has no continuous mode, so a forced measurement is triggered at
+// each interval. The interval must be larger than the measurement duration,
+// including the heater duration.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < d.measDuration() {
+		return nil, fmt.Errorf("bme680: interval must be larger than %s", d.measDuration())
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// SetHeaterProfile changes the heater profile used for the next measurements.
+func (d *Dev) SetHeaterProfile(h HeaterProfile) error {
+	if err := h.validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts.Heater = h
+	return nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+//
+// Gas is measured only when a heater profile is set.
+func (d *Dev) Metrics() devices.Metric {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	m := devices.MetricTemperature
+	if d.opts.Pressure != Off {
+		m |= devices.MetricPressure
+	}
+	if d.opts.Humidity != Off {
+		m |= devices.MetricHumidity
+	}
+	if d.opts.Heater.Duration != 0 {
+		m |= devices.MetricGas
+	}
+	return m
+}
+
+// Halt stops the BME680 from acquiring measurements as initiated by
+// SenseContinuous() and turns the heater off.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// ctrl_gas_0: heat_off.
+	return d.writeCommands([]byte{0x70, 0x08})
+}
+
+//
+
+func (h *HeaterProfile) validate() error {
+	if h.Duration == 0 {
+		return nil
+	}
+	if h.Temperature < 200 || h.Temperature > 400 {
+		return fmt.Errorf("bme680: invalid heater temperature %d°C", h.Temperature)
+	}
+	if h.Duration < time.Millisecond || h.Duration > 4032*time.Millisecond {
+		return fmt.Errorf("bme680: invalid heater duration %s", h.Duration)
+	}
+	return nil
+}
+
+func (d *Dev) makeDev(opts *Opts) error {
+	if opts == nil {
+		opts = &defaults
+	}
+	d.opts = *opts
+	d.ambient = 25
+	if d.opts.Temperature == Off {
+		return errors.New("bme680: temperature measurement is required, use at least O1x")
+	}
+	if err := d.opts.Heater.validate(); err != nil {
+		return err
+	}
+	var chipID [1]byte
+	if err := d.readReg(0xD0, chipID[:]); err != nil {
+		return err
+	}
+	if chipID[0] != 0x61 {
+		return fmt.Errorf("bme680: unexpected chip id %x", chipID[0])
+	}
+	var c1 [25]byte
+	if err := d.readReg(0x89, c1[:]); err != nil {
+		return err
+	}
+	var c2 [16]byte
+	if err := d.readReg(0xE1, c2[:]); err != nil {
+		return err
+	}
+	var c3 [5]byte
+	if err := d.readReg(0x00, c3[:]); err != nil {
+		return err
+	}
+	d.cal = newCalibration(c1[:], c2[:], c3[:])
+	return nil
+}
+
+// sense triggers a forced measurement and reads it.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) sense(m *Measurement) error {
+	gas := d.opts.Heater.Duration != 0
+	b := []byte{
+		// ctrl_hum
+		0x72, byte(d.opts.Humidity),
+		// config
+		0x75, byte(d.opts.Filter) << 2,
+	}
+	if gas {
+		b = append(b,
+			// res_heat_0
+			0x5A, d.cal.heaterResistance(d.opts.Heater.Temperature, d.ambient),
+			// gas_wait_0
+			0x64, heaterDuration(d.opts.Heater.Duration),
+			// ctrl_gas_1: run_gas, nb_conv=0
+			0x71, 0x10)
+	} else {
+		// ctrl_gas_1: run_gas disabled.
+		b = append(b, 0x71, 0x00)
+	}
+	// ctrl_meas; must be written last to trigger the forced measurement.
+	b = append(b, 0x74, byte(d.opts.Temperature)<<5|byte(d.opts.Pressure)<<2|1)
+	if err := d.writeCommands(b); err != nil {
+		return err
+	}
+	time.Sleep(d.measDuration())
+	// meas_status_0 to gas_r_lsb.
+	var buf [0x2C - 0x1D]byte
+	for i := 0; ; i++ {
+		if err := d.readReg(0x1D, buf[:]); err != nil {
+			return err
+		}
+		// new_data_0
+		if buf[0]&0x80 != 0 {
+			break
+		}
+		if i == 10 {
+			return errors.New("bme680: timeout waiting for measurement")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.RawPressure = uint32(buf[2])<<12 | uint32(buf[3])<<4 | uint32(buf[4])>>4
+	m.RawTemperature = uint32(buf[5])<<12 | uint32(buf[6])<<4 | uint32(buf[7])>>4
+	m.RawHumidity = uint16(buf[8])<<8 | uint16(buf[9])
+	m.RawGas = uint16(buf[13])<<2 | uint16(buf[14])>>6
+	m.GasRange = buf[14] & 0x0F
+	m.GasValid = gas && buf[14]&0x20 != 0
+	m.HeaterStable = gas && buf[14]&0x10 != 0
+
+	t, tFine := d.cal.compensateTemp(int32(m.RawTemperature))
+	m.Temperature = devices.Celsius(t * 10)
+	d.ambient = t / 100
+	if d.opts.Pressure != Off {
+		m.Pressure = devices.KPascal(d.cal.compensatePressure(int32(m.RawPressure), tFine))
+	}
+	if d.opts.Humidity != Off {
+		m.Humidity = devices.RelativeHumidity(d.cal.compensateHumidity(int32(m.RawHumidity), tFine) / 10)
+	}
+	if m.GasValid {
+		m.GasResistance = d.cal.compensateGas(m.RawGas, m.GasRange)
+	} else {
+		m.GasResistance = 0
+	}
+	m.Gas = devices.Ohm(m.GasResistance)
+	m.Time = time.Now()
+	return nil
+}
+
+// measDuration returns the time to wait after triggering a measurement.
+func (d *Dev) measDuration() time.Duration {
+	// Page 17; the conversion time is 1.963ms per oversampling unit, plus
+	// overheads, plus the heater duration.
+	cycles := d.opts.Temperature.asValue() + d.opts.Pressure.asValue() + d.opts.Humidity.asValue()
+	µs := cycles*1963 + 477*4 + 477*5 + 500 + 1000
+	return time.Duration(µs)*time.Microsecond + d.opts.Heater.Duration
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		// Do one initial sensing right away.
+		var m Measurement
+		d.mu.Lock()
+		err := d.sense(&m)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- m.Environment:
+		case <-stop:
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// setPage selects the SPI memory page for the register.
+//
+// Page 0 maps registers 0x80~0xFF, page 1 maps 0x00~0x7F.
+func (d *Dev) setPage(reg uint8) error {
+	page := int8(1)
+	if reg >= 0x80 {
+		page = 0
+	}
+	if page == d.page {
+		return nil
+	}
+	// status register 0x73 is accessible from both pages; spi_mem_page is bit 4.
+	if err := d.d.Tx([]byte{0x73, byte(page) << 4}, nil); err != nil {
+		return fmt.Errorf("bme680: %v", err)
+	}
+	d.page = page
+	return nil
+}
+
+func (d *Dev) readReg(reg uint8, b []byte) error {
+	if d.isSPI {
+		if err := d.setPage(reg); err != nil {
+			return err
+		}
+		// MSB is 0 for write and 1 for read.
+		read := make([]byte, len(b)+1)
+		write := make([]byte, len(read))
+		// Rest of the write buffer is ignored.
+		write[0] = reg | 0x80
+		if err := d.d.Tx(write, read); err != nil {
+			return fmt.Errorf("bme680: %v", err)
+		}
+		copy(b, read[1:])
+		return nil
+	}
+	if err := d.d.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("bme680: %v", err)
+	}
+	return nil
+}
+
+// writeCommands writes pairs of register and value to the device.
+//
+// All the registers written must be in the same SPI memory page.
+//
+// Warning: b may be modified!
+func (d *Dev) writeCommands(b []byte) error {
+	if d.isSPI {
+		if err := d.setPage(b[0]); err != nil {
+			return err
+		}
+		// Set RW bit 7 to 0.
+		for i := 0; i < len(b); i += 2 {
+			b[i] &^= 0x80
+		}
+	}
+	if err := d.d.Tx(b, nil); err != nil {
+		return fmt.Errorf("bme680: %v", err)
+	}
+	return nil
+}
+
+// heaterDuration converts a duration into the gas_wait_x register format.
+//
+// The 6 lower bits are the value in ms and the 2 upper bits a multiplication
+// factor of 1, 4, 16 or 64.
+func heaterDuration(t time.Duration) byte {
+	ms := int(t / time.Millisecond)
+	if ms >= 0xFC0 {
+		return 0xFF
+	}
+	factor := 0
+	for ms > 0x3F {
+		ms /= 4
+		factor++
+	}
+	return byte(ms + factor*64)
+}
+
+var defaults = Opts{
+	Temperature: O4x,
+	Pressure:    O4x,
+	Humidity:    O4x,
+	Heater:      HeaterProfile{Temperature: 320, Duration: 150 * time.Millisecond},
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
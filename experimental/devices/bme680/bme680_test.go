@@ -0,0 +1,284 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bme680
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x76, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize bme680: %v", err)
+	}
+	defer dev.Halt()
+	var m Measurement
+	if err := dev.SenseGas(&m); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%8s %10s %9s %dΩ\n", m.Temperature, m.Pressure, m.Humidity, m.GasResistance)
+}
+
+//
+
+// Calibration data; t1=26264 t2=26281 t3=3 p1=36813 p2=-10441 p3=88 p4=3015
+// p5=-121 p6=30 p7=44 p8=-2486 p9=-3234 p10=30 h1=842 h2=1005 h3=0 h4=45 h5=20
+// h6=120 h7=-100 gh1=-27 gh2=-11226 gh3=18 res_heat_range=1 res_heat_val=42.
+var (
+	calib1 = []byte{0x00, 0xA9, 0x66, 0x03, 0x00, 0xCD, 0x8F, 0x37, 0xD7, 0x58, 0x00, 0xC7, 0x0B, 0x87, 0xFF, 0x2C, 0x1E, 0x00, 0x00, 0x4A, 0xF6, 0x5E, 0xF3, 0x1E, 0x00}
+	calib2 = []byte{0x3E, 0xDA, 0x34, 0x00, 0x2D, 0x14, 0x78, 0x9C, 0x98, 0x66, 0x26, 0xD4, 0xE5, 0x12, 0x00, 0x00}
+	calib3 = []byte{0x2A, 0x00, 0x16, 0x00, 0x00}
+	// meas_status_0 to gas_r_lsb.
+	measurement = []byte{0x80, 0x00, 0x57, 0xA4, 0x00, 0x7E, 0x6A, 0x80, 0x5A, 0x00, 0x00, 0x00, 0x00, 0x69, 0x75}
+)
+
+func initOps(addr uint16) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: addr, W: []byte{0xD0}, R: []byte{0x61}},
+		{Addr: addr, W: []byte{0x89}, R: calib1},
+		{Addr: addr, W: []byte{0xE1}, R: calib2},
+		{Addr: addr, W: []byte{0x00}, R: calib3},
+	}
+}
+
+func TestNewI2C_SenseGas(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x77),
+			i2ctest.IO{Addr: 0x77, W: []byte{0x72, 0x01, 0x75, 0x08, 0x5A, 111, 0x64, 20, 0x71, 0x10, 0x74, 0x25}},
+			i2ctest.IO{Addr: 0x77, W: []byte{0x1D}, R: measurement},
+			// Halt.
+			i2ctest.IO{Addr: 0x77, W: []byte{0x70, 0x08}},
+		),
+	}
+	opts := Opts{
+		Temperature: O1x,
+		Pressure:    O1x,
+		Humidity:    O1x,
+		Filter:      F3,
+		Heater:      HeaterProfile{Temperature: 300, Duration: 20 * time.Millisecond},
+	}
+	dev, err := NewI2C(&bus, 0x77, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "BME680{playback(119)}" {
+		t.Fatal(s)
+	}
+	if m := dev.Metrics(); m != devices.MetricTemperature|devices.MetricPressure|devices.MetricHumidity|devices.MetricGas {
+		t.Fatal(m)
+	}
+	var m Measurement
+	if err := dev.SenseGas(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	expected := Measurement{
+		Environment:    devices.Environment{Temperature: 30580, Pressure: 107648, Humidity: 4998, Gas: 266546, Time: m.Time},
+		GasResistance:  266546,
+		GasValid:       true,
+		HeaterStable:   true,
+		RawTemperature: 0x7E6A8,
+		RawPressure:    0x57A40,
+		RawHumidity:    0x5A00,
+		RawGas:         0x1A5,
+		GasRange:       5,
+	}
+	if m != expected {
+		t.Fatalf("%#v != %#v", m, expected)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_Sense_noGas(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x72, 0x00, 0x75, 0x00, 0x71, 0x00, 0x74, 0x21}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1D}, R: measurement},
+		),
+	}
+	opts := Opts{Temperature: O1x}
+	dev, err := NewI2C(&bus, 0x76, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := dev.Metrics(); m != devices.MetricTemperature {
+		t.Fatal(m)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 30580, Time: env.Time}); env != expected {
+		t.Fatalf("%#v != %#v", env, expected)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				// Page 0.
+				{W: []byte{0x73, 0x00}},
+				{W: []byte{0xD0, 0x00}, R: []byte{0x00, 0x61}},
+				{W: append([]byte{0x89}, make([]byte, 25)...), R: append([]byte{0}, calib1...)},
+				{W: append([]byte{0xE1}, make([]byte, 16)...), R: append([]byte{0}, calib2...)},
+				// Page 1.
+				{W: []byte{0x73, 0x10}},
+				{W: []byte{0x80, 0, 0, 0, 0, 0}, R: append([]byte{0}, calib3...)},
+				// Halt.
+				{W: []byte{0x70, 0x08}},
+			},
+		},
+	}
+	dev, err := NewSPI(&s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.cal.h1 != 842 || dev.cal.h2 != 1005 || dev.cal.resHeatVal != 42 {
+		t.Fatalf("%#v", dev.cal)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 1, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x76, &Opts{}); err == nil {
+		t.Fatal("temperature is required")
+	}
+	opts := Opts{Temperature: O1x, Heater: HeaterProfile{Temperature: 500, Duration: time.Millisecond}}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x76, &opts); err == nil {
+		t.Fatal("bad heater")
+	}
+	bus := i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x76, W: []byte{0xD0}, R: []byte{0x60}}},
+		DontPanic: true,
+	}
+	if _, err := NewI2C(&bus, 0x76, nil); err == nil {
+		t.Fatal("bad chip id")
+	}
+	bus = i2ctest.Playback{DontPanic: true}
+	if _, err := NewI2C(&bus, 0x76, nil); err == nil {
+		t.Fatal("read failed")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x72, 0x00, 0x75, 0x00, 0x71, 0x00, 0x74, 0x21}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1D}, R: measurement},
+			// Halt.
+			i2ctest.IO{Addr: 0x76, W: []byte{0x70, 0x08}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x76, &Opts{Temperature: O1x})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Microsecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	// Use a long interval so only the initial measurement is done before
+	// Halt(); a shorter one is racy as a tick may be pending once the
+	// measurement is received.
+	c, err := dev.SenseContinuous(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env := <-c; env.Temperature != 30580 {
+		t.Fatal(env)
+	}
+	var m Measurement
+	if err := dev.SenseGas(&m); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetHeaterProfile(t *testing.T) {
+	d := Dev{}
+	if err := d.SetHeaterProfile(HeaterProfile{Temperature: 300, Duration: 5 * time.Second}); err == nil {
+		t.Fatal("duration too long")
+	}
+	if err := d.SetHeaterProfile(HeaterProfile{Temperature: 300, Duration: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeaterDuration(t *testing.T) {
+	data := []struct {
+		d        time.Duration
+		expected byte
+	}{
+		{20 * time.Millisecond, 20},
+		{63 * time.Millisecond, 63},
+		{150 * time.Millisecond, 0x40 | 37},
+		{1000 * time.Millisecond, 0x80 | 62},
+		{5000 * time.Millisecond, 0xFF},
+	}
+	for i, line := range data {
+		if v := heaterDuration(line.d); v != line.expected {
+			t.Fatalf("#%d: %d != %d", i, v, line.expected)
+		}
+	}
+}
+
+func TestOversampling(t *testing.T) {
+	if s := O16x.String(); s != "16x" {
+		t.Fatal(s)
+	}
+	if s := Oversampling(10).String(); s != "Oversampling(10)" {
+		t.Fatal(s)
+	}
+	if v := O8x.asValue(); v != 8 {
+		t.Fatal(v)
+	}
+}
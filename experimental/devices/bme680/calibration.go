@@ -0,0 +1,159 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bme680
+
+// calibration is the factory calibration data.
+type calibration struct {
+	t1                 uint16
+	t2                 int16
+	t3                 int8
+	p1                 uint16
+	p2, p4, p5, p8, p9 int16
+	p3, p6, p7         int8
+	p10                uint8
+	h1, h2             uint16
+	h3, h4, h5, h7     int8
+	h6                 uint8
+	gh1, gh3           int8
+	gh2                int16
+	resHeatRange       uint8
+	resHeatVal         int8
+	rangeSwErr         int8
+}
+
+// newCalibration parses the calibration data.
+//
+// c1 is read from 0x89~0xA1, c2 from 0xE1~0xF0 and c3 from 0x00~0x04.
+func newCalibration(c1, c2, c3 []byte) (c calibration) {
+	c.t2 = int16(c1[1]) | int16(c1[2])<<8
+	c.t3 = int8(c1[3])
+	c.p1 = uint16(c1[5]) | uint16(c1[6])<<8
+	c.p2 = int16(c1[7]) | int16(c1[8])<<8
+	c.p3 = int8(c1[9])
+	c.p4 = int16(c1[11]) | int16(c1[12])<<8
+	c.p5 = int16(c1[13]) | int16(c1[14])<<8
+	c.p7 = int8(c1[15])
+	c.p6 = int8(c1[16])
+	c.p8 = int16(c1[19]) | int16(c1[20])<<8
+	c.p9 = int16(c1[21]) | int16(c1[22])<<8
+	c.p10 = c1[23]
+
+	// h1 and h2 are 12 bits, sharing 0xE2.
+	c.h2 = uint16(c2[0])<<4 | uint16(c2[1])>>4
+	c.h1 = uint16(c2[2])<<4 | uint16(c2[1])&0x0F
+	c.h3 = int8(c2[3])
+	c.h4 = int8(c2[4])
+	c.h5 = int8(c2[5])
+	c.h6 = c2[6]
+	c.h7 = int8(c2[7])
+	c.t1 = uint16(c2[8]) | uint16(c2[9])<<8
+	c.gh2 = int16(c2[10]) | int16(c2[11])<<8
+	c.gh1 = int8(c2[12])
+	c.gh3 = int8(c2[13])
+
+	c.resHeatVal = int8(c3[0])
+	c.resHeatRange = (c3[2] & 0x30) >> 4
+	c.rangeSwErr = int8(c3[4]) >> 4
+	return c
+}
+
+// compensateTemp returns temperature in °C, resolution is 0.01 °C, and the
+// fine temperature used by the other compensation functions.
+//
+// raw has 20 bits of resolution.
+func (c *calibration) compensateTemp(raw int32) (int32, int32) {
+	x := raw>>3 - int32(c.t1)<<1
+	y := (x * int32(c.t2)) >> 11
+	z := ((((x >> 1) * (x >> 1)) >> 12) * (int32(c.t3) << 4)) >> 14
+	tFine := y + z
+	return (tFine*5 + 128) >> 8, tFine
+}
+
+// compensatePressure returns pressure in Pa.
+//
+// raw has 20 bits of resolution.
+func (c *calibration) compensatePressure(raw, tFine int32) int32 {
+	x := tFine>>1 - 64000
+	y := ((((x >> 2) * (x >> 2)) >> 11) * int32(c.p6)) >> 2
+	y += (x * int32(c.p5)) << 1
+	y = y>>2 + int32(c.p4)<<16
+	x = (((((x >> 2) * (x >> 2)) >> 13) * (int32(c.p3) << 5)) >> 3) + ((int32(c.p2) * x) >> 1)
+	x >>= 18
+	x = ((32768 + x) * int32(c.p1)) >> 15
+	if x == 0 {
+		return 0
+	}
+	p := 1048576 - raw
+	p = int32(uint32(p-y>>12) * 3125)
+	if p >= 0x40000000 {
+		p = (p / x) << 1
+	} else {
+		p = (p << 1) / x
+	}
+	x = (int32(c.p9) * (((p >> 3) * (p >> 3)) >> 13)) >> 12
+	y = ((p >> 2) * int32(c.p8)) >> 13
+	z := ((p >> 8) * (p >> 8) * (p >> 8) * int32(c.p10)) >> 17
+	return p + (x+y+z+int32(c.p7)<<7)>>4
+}
+
+// compensateHumidity returns humidity in %RH with 0.001% resolution.
+//
+// raw has 16 bits of resolution.
+func (c *calibration) compensateHumidity(raw, tFine int32) int32 {
+	t := (tFine*5 + 128) >> 8
+	x1 := raw - int32(c.h1)*16 - ((t*int32(c.h3))/100)>>1
+	x2 := (int32(c.h2) * ((t*int32(c.h4))/100 + ((t*((t*int32(c.h5))/100))>>6)/100 + 1<<14)) >> 10
+	x3 := x1 * x2
+	x4 := (int32(c.h6)<<7 + (t*int32(c.h7))/100) >> 4
+	x5 := ((x3 >> 14) * (x3 >> 14)) >> 10
+	x6 := (x4 * x5) >> 1
+	h := (((x3 + x6) >> 10) * 1000) >> 12
+	if h > 100000 {
+		return 100000
+	}
+	if h < 0 {
+		return 0
+	}
+	return h
+}
+
+// compensateGas returns the gas sensor resistance in Ω.
+//
+// raw has 10 bits of resolution, gasRange is the ADC range used.
+func (c *calibration) compensateGas(raw uint16, gasRange uint8) uint32 {
+	x := (int64(1340+5*int64(c.rangeSwErr)) * int64(gasLookup1[gasRange])) >> 16
+	y := int64(raw)<<15 - 16777216 + x
+	if y == 0 {
+		return 0
+	}
+	z := (int64(gasLookup2[gasRange]) * x) >> 9
+	return uint32((z + y>>1) / y)
+}
+
+// heaterResistance returns the res_heat_x register value to reach the target
+// temperature in °C, given the ambient temperature in °C.
+func (c *calibration) heaterResistance(target int, ambient int32) byte {
+	if target > 400 {
+		target = 400
+	}
+	x1 := ((ambient * int32(c.gh3)) / 1000) * 256
+	x2 := (int32(c.gh1) + 784) * (((((int32(c.gh2) + 154009) * int32(target) * 5) / 100) + 3276800) / 10)
+	x3 := x1 + x2/2
+	x4 := x3 / (int32(c.resHeatRange) + 4)
+	x5 := 131*int32(c.resHeatVal) + 65536
+	r := ((x4 / x5) - 250) * 34
+	return byte((r + 50) / 100)
+}
+
+// Gas range lookup tables, from the Bosch reference implementation.
+var gasLookup1 = [16]uint32{
+	2147483647, 2147483647, 2147483647, 2147483647, 2147483647, 2126008810, 2147483647, 2130303777,
+	2147483647, 2147483647, 2143188679, 2136746228, 2147483647, 2126008810, 2147483647, 2147483647,
+}
+
+var gasLookup2 = [16]uint32{
+	4096000000, 2048000000, 1024000000, 512000000, 255744255, 127110228, 64000000, 32258064,
+	16016016, 8000000, 4000000, 2000000, 1000000, 500000, 250000, 125000,
+}
@@ -0,0 +1,550 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package bmp388 controls a Bosch BMP388 or BMP390 pressure and temperature
+// sensor over I²C or SPI.
+//
+// The API is the same as the one of package bmxx80. In addition, the
+// measurements can be buffered in the device's FIFO with StartFIFO() and
+// retrieved in batch with ReadFIFO().
+//
+// Datasheet
+//
+// https://ae-bst.resource.bosch.com/media/_tech/media/datasheets/bst-bmp388-ds001.pdf
+//
+// https://ae-bst.resource.bosch.com/media/_tech/media/datasheets/bst-bmp390-ds002.pdf
+package bmp388
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+)
+
+// Oversampling affects how much time is taken to measure each of temperature
+// and pressure.
+type Oversampling uint8
+
+// Possible oversampling values.
+//
+// Off is only valid for pressure.
+const (
+	Off  Oversampling = 0
+	O1x  Oversampling = 1
+	O2x  Oversampling = 2
+	O4x  Oversampling = 3
+	O8x  Oversampling = 4
+	O16x Oversampling = 5
+	O32x Oversampling = 6
+)
+
+const oversamplingName = "Off1x2x4x8x16x32x"
+
+var oversamplingIndex = [...]uint8{0, 3, 5, 7, 9, 11, 14, 17}
+
+func (o Oversampling) String() string {
+	if o >= Oversampling(len(oversamplingIndex)-1) {
+		return fmt.Sprintf("Oversampling(%d)", o)
+	}
+	return oversamplingName[oversamplingIndex[o]:oversamplingIndex[o+1]]
+}
+
+func (o Oversampling) asValue() int {
+	if o == Off || o > O32x {
+		return 0
+	}
+	return 1 << (o - 1)
+}
+
+// Filter specifies the internal IIR filter coefficient to get steadier
+// measurements.
+type Filter uint8
+
+// Possible filtering values.
+const (
+	NoFilter Filter = 0
+	F1       Filter = 1
+	F3       Filter = 2
+	F7       Filter = 3
+	F15      Filter = 4
+	F31      Filter = 5
+	F63      Filter = 6
+	F127     Filter = 7
+)
+
+// Opts is optional options to pass to the constructor.
+//
+// Recommended (and default) values are O8x for pressure and O1x for
+// temperature, which is the datasheet's "standard resolution" setting.
+type Opts struct {
+	// Temperature must be measured for pressure to be compensated.
+	Temperature Oversampling
+	Pressure    Oversampling
+	// Filter is applied to the pressure and temperature in all modes.
+	Filter Filter
+}
+
+// Dev is a handle to an initialized BMP388 or BMP390 device.
+type Dev struct {
+	d     conn.Conn
+	isSPI bool
+	name  string
+	opts  Opts
+	cal   calibration
+
+	mu   sync.Mutex
+	fifo bool
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a BMP388 or BMP390.
+//
+// The address must be 0x76 or 0x77, depending on the SDO pin.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x76, 0x77:
+	default:
+		return nil, errors.New("bmp388: given address not supported by device")
+	}
+	d := &Dev{d: &i2c.Dev{Bus: b, Addr: addr}}
+	if err := d.makeDev(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewSPI returns an object that communicates over SPI to a BMP388 or BMP390.
+//
+// When using SPI, the CS line must be used.
+func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
+	// It works both in Mode0 and Mode3.
+	c, err := p.Connect(10000000, spi.Mode3, 8)
+	if err != nil {
+		return nil, fmt.Errorf("bmp388: %v", err)
+	}
+	d := &Dev{d: c, isSPI: true}
+	if err := d.makeDev(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.name, d.d)
+}
+
+// Sense requests a one time measurement as °C and kPa.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil || d.fifo {
+		return errors.New("bmp388: already sensing continuously")
+	}
+	if err := d.writeCommands(d.config(pwrForced)); err != nil {
+		return err
+	}
+	sleep(d.measDuration())
+	for i := 0; ; i++ {
+		var status [1]byte
+		if err := d.readReg(regStatus, status[:]); err != nil {
+			return err
+		}
+		if status[0]&d.drdy() == d.drdy() {
+			break
+		}
+		if i == 10 {
+			return errors.New("bmp388: timeout waiting for measurement")
+		}
+		sleep(time.Millisecond)
+	}
+	return d.sense(env)
+}
+
+// SenseContinuous returns measurements as °C and kPa on a continuous basis.
+//
+// The device is put in normal mode with the closest supported output data
+// rate below interval, which must be larger than the measurement duration.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.fifo {
+		return nil, errors.New("bmp388: FIFO is running")
+	}
+	if err := d.startNormal(interval); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// StartFIFO starts buffering measurements in the FIFO at the closest
+// supported output data rate below interval.
+//
+// The FIFO holds 512 bytes, which is 73 measurements of temperature and
+// pressure; the oldest measurements are overwritten when it is full. Use
+// ReadFIFO() to retrieve them and Halt() to stop.
+func (d *Dev) StartFIFO(interval time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("bmp388: already sensing continuously")
+	}
+	en := byte(fifoMode | fifoTempEn)
+	if d.opts.Pressure != Off {
+		en |= fifoPressEn
+	}
+	b := []byte{
+		regFIFOConfig1, en,
+		// Filtered data, no subsampling.
+		regFIFOConfig2, 0x08,
+		regCmd, cmdFIFOFlush,
+	}
+	if err := d.writeCommands(b); err != nil {
+		return err
+	}
+	if err := d.startNormal(interval); err != nil {
+		return err
+	}
+	d.fifo = true
+	return nil
+}
+
+// ReadFIFO returns all the measurements currently buffered in the FIFO, the
+// oldest first.
+//
+// StartFIFO() must have been called.
+func (d *Dev) ReadFIFO() ([]devices.Environment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.fifo {
+		return nil, errors.New("bmp388: FIFO is not running")
+	}
+	var l [2]byte
+	if err := d.readReg(regFIFOLength, l[:]); err != nil {
+		return nil, err
+	}
+	n := int(l[0]) | int(l[1]&0x01)<<8
+	if n == 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	if err := d.readReg(regFIFOData, b); err != nil {
+		return nil, err
+	}
+	var out []devices.Environment
+	for i := 0; i < len(b); {
+		size := 0
+		switch b[i] {
+		case frameTempPress:
+			size = 6
+		case frameTemp, framePress, frameTime:
+			size = 3
+		case frameConfigChange, frameError:
+		case frameEmpty:
+			return out, nil
+		default:
+			return out, fmt.Errorf("bmp388: invalid FIFO frame header %#x", b[i])
+		}
+		if i+1+size > len(b) {
+			// Partial frame.
+			break
+		}
+		if b[i] == frameTempPress || b[i] == frameTemp {
+			var e devices.Environment
+			f := b[i+1:]
+			t := d.cal.compensateTemp(uint24(f))
+			e.Temperature = devices.Celsius(math.Floor(t*1000 + 0.5))
+			if b[i] == frameTempPress {
+				e.Pressure = devices.KPascal(math.Floor(d.cal.compensatePressure(uint24(f[3:]), t) + 0.5))
+			}
+			out = append(out, e)
+		}
+		i += 1 + size
+	}
+	return out, nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	if d.opts.Pressure == Off {
+		return devices.MetricTemperature
+	}
+	return devices.MetricTemperature | devices.MetricPressure
+}
+
+// Halt stops the BMP388 from acquiring measurements as initiated by
+// SenseContinuous() or StartFIFO().
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b := []byte{regPwrCtrl, pwrSleep}
+	if d.fifo {
+		b = append(b, regFIFOConfig1, 0x00)
+		d.fifo = false
+	}
+	return d.writeCommands(b)
+}
+
+//
+
+const (
+	regChipID      = 0x00
+	regErr         = 0x02
+	regStatus      = 0x03
+	regData        = 0x04
+	regFIFOLength  = 0x12
+	regFIFOData    = 0x14
+	regFIFOConfig1 = 0x17
+	regFIFOConfig2 = 0x18
+	regPwrCtrl     = 0x1B
+	regOSR         = 0x1C
+	regODR         = 0x1D
+	regConfig      = 0x1F
+	regCalib       = 0x31
+	regCmd         = 0x7E
+
+	cmdFIFOFlush = 0xB0
+	cmdSoftReset = 0xB6
+
+	// PWR_CTRL mode; the sensors enable bits are added by config().
+	pwrSleep  = 0x00
+	pwrForced = 0x10
+	pwrNormal = 0x30
+
+	errConf = 0x04
+
+	statusDrdyPress = 0x20
+	statusDrdyTemp  = 0x40
+
+	fifoMode    = 0x01
+	fifoPressEn = 0x08
+	fifoTempEn  = 0x10
+
+	frameTempPress    = 0x94
+	frameTemp         = 0x90
+	framePress        = 0x84
+	frameTime         = 0xA0
+	frameConfigChange = 0x48
+	frameError        = 0x44
+	frameEmpty        = 0x80
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+func (d *Dev) makeDev(opts *Opts) error {
+	if opts == nil {
+		opts = &defaults
+	}
+	d.opts = *opts
+	if d.opts.Temperature == Off {
+		return errors.New("bmp388: temperature measurement is required, use at least O1x")
+	}
+	if d.opts.Temperature > O32x || d.opts.Pressure > O32x {
+		return errors.New("bmp388: invalid oversampling")
+	}
+	if d.opts.Filter > F127 {
+		return fmt.Errorf("bmp388: invalid filter %d", d.opts.Filter)
+	}
+	var chipID [1]byte
+	if err := d.readReg(regChipID, chipID[:]); err != nil {
+		return err
+	}
+	switch chipID[0] {
+	case 0x50:
+		d.name = "BMP388"
+	case 0x60:
+		d.name = "BMP390"
+	default:
+		return fmt.Errorf("bmp388: unexpected chip id %x", chipID[0])
+	}
+	if err := d.writeCommands([]byte{regCmd, cmdSoftReset}); err != nil {
+		return err
+	}
+	// Start-up time.
+	sleep(2 * time.Millisecond)
+	var c [0x46 - regCalib]byte
+	if err := d.readReg(regCalib, c[:]); err != nil {
+		return err
+	}
+	d.cal = newCalibration(c[:])
+	return nil
+}
+
+// config returns the commands to configure the measurement, with the power
+// mode written last.
+func (d *Dev) config(mode byte) []byte {
+	osr := byte(d.opts.Temperature-1) << 3
+	pwr := mode | 0x02
+	if d.opts.Pressure != Off {
+		osr |= byte(d.opts.Pressure - 1)
+		pwr |= 0x01
+	}
+	return []byte{
+		regOSR, osr,
+		regConfig, byte(d.opts.Filter) << 1,
+		regPwrCtrl, pwr,
+	}
+}
+
+// drdy returns the status bits set when a measurement is ready.
+func (d *Dev) drdy() byte {
+	if d.opts.Pressure == Off {
+		return statusDrdyTemp
+	}
+	return statusDrdyTemp | statusDrdyPress
+}
+
+// startNormal puts the device in normal mode at the output data rate closest
+// to interval.
+func (d *Dev) startNormal(interval time.Duration) error {
+	if interval < d.measDuration() {
+		return fmt.Errorf("bmp388: interval must be larger than %s", d.measDuration())
+	}
+	// The output data rate is 5ms * 2^odr_sel, up to 655.36s.
+	odr := byte(0)
+	for odr < 17 && 5*time.Millisecond<<(odr+1) <= interval {
+		odr++
+	}
+	b := append([]byte{regODR, odr}, d.config(pwrNormal)...)
+	if err := d.writeCommands(b); err != nil {
+		return err
+	}
+	var e [1]byte
+	if err := d.readReg(regErr, e[:]); err != nil {
+		return err
+	}
+	if e[0]&errConf != 0 {
+		return errors.New("bmp388: invalid configuration; reduce oversampling or increase interval")
+	}
+	return nil
+}
+
+// sense reads the latest measurement.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) sense(env *devices.Environment) error {
+	var b [6]byte
+	if err := d.readReg(regData, b[:]); err != nil {
+		return err
+	}
+	t := d.cal.compensateTemp(uint24(b[3:]))
+	env.Temperature = devices.Celsius(math.Floor(t*1000 + 0.5))
+	env.Pressure = 0
+	if d.opts.Pressure != Off {
+		env.Pressure = devices.KPascal(math.Floor(d.cal.compensatePressure(uint24(b[:]), t) + 0.5))
+	}
+	env.Humidity = 0
+	env.Time = time.Now()
+	return nil
+}
+
+// measDuration returns the maximum time to wait after triggering a
+// measurement.
+func (d *Dev) measDuration() time.Duration {
+	// Page 17.
+	µs := 234 + 163 + 2020*d.opts.Temperature.asValue()
+	if d.opts.Pressure != Off {
+		µs += 392 + 2020*d.opts.Pressure.asValue()
+	}
+	return time.Duration(µs) * time.Microsecond
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.sense(&e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) readReg(reg uint8, b []byte) error {
+	if d.isSPI {
+		// MSB is 1 for read; the first byte read after the address is a dummy
+		// byte.
+		read := make([]byte, len(b)+2)
+		write := make([]byte, len(read))
+		write[0] = reg | 0x80
+		if err := d.d.Tx(write, read); err != nil {
+			return fmt.Errorf("bmp388: %v", err)
+		}
+		copy(b, read[2:])
+		return nil
+	}
+	if err := d.d.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("bmp388: %v", err)
+	}
+	return nil
+}
+
+// writeCommands writes pairs of register and value to the device.
+func (d *Dev) writeCommands(b []byte) error {
+	if err := d.d.Tx(b, nil); err != nil {
+		return fmt.Errorf("bmp388: %v", err)
+	}
+	return nil
+}
+
+// uint24 decodes a 24 bits little endian value.
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+var defaults = Opts{
+	Temperature: O1x,
+	Pressure:    O8x,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,386 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmp388
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x77, &Opts{Temperature: O1x, Pressure: O4x, Filter: F3})
+	if err != nil {
+		log.Fatalf("failed to initialize bmp388: %v", err)
+	}
+	defer dev.Halt()
+	// Buffer a measurement every 80ms and retrieve them all once per second.
+	if err := dev.StartFIFO(80 * time.Millisecond); err != nil {
+		log.Fatal(err)
+	}
+	for range time.Tick(time.Second) {
+		envs, err := dev.ReadFIFO()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, e := range envs {
+			fmt.Printf("%8s %10s\n", e.Temperature, e.Pressure)
+		}
+	}
+}
+
+//
+
+// Calibration data; t1=28262 t2=18995 t3=-10 p1=-2206 p2=-3237 p3=35 p4=1
+// p5=25058 p6=30402 p7=3 p8=-6 p9=4286 p10=18 p11=-60.
+var calib = []byte{0x66, 0x6E, 0x33, 0x4A, 0xF6, 0x62, 0xF7, 0x5B, 0xF3, 0x23, 0x01, 0xE2, 0x61, 0xC2, 0x76, 0x03, 0xFA, 0xBE, 0x10, 0x12, 0xC4}
+
+// Pressure then temperature; 6000000 and 8600000, which is 100.535kPa and
+// 24.080°C.
+var measurement = []byte{0x80, 0x8D, 0x5B, 0xC0, 0x39, 0x83}
+
+func initOps(addr uint16, id byte) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: addr, W: []byte{0x00}, R: []byte{id}},
+		{Addr: addr, W: []byte{0x7E, 0xB6}},
+		{Addr: addr, W: []byte{0x31}, R: calib},
+	}
+}
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x77, 0x50),
+			i2ctest.IO{Addr: 0x77, W: []byte{0x1C, 0x03, 0x1F, 0x00, 0x1B, 0x13}},
+			// Not ready yet.
+			i2ctest.IO{Addr: 0x77, W: []byte{0x03}, R: []byte{0x40}},
+			i2ctest.IO{Addr: 0x77, W: []byte{0x03}, R: []byte{0x70}},
+			i2ctest.IO{Addr: 0x77, W: []byte{0x04}, R: measurement},
+			// Halt.
+			i2ctest.IO{Addr: 0x77, W: []byte{0x1B, 0x00}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x77, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "BMP388{playback(119)}" {
+		t.Fatal(s)
+	}
+	e := devices.Environment{Humidity: 1}
+	if err := dev.Sense(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if e != (devices.Environment{Temperature: 24080, Pressure: 100535, Time: e.Time}) {
+		t.Fatal(e)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_Sense_noPressure(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76, 0x60),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1C, 0x28, 0x1F, 0x04, 0x1B, 0x12}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x03}, R: []byte{0x40}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x04}, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x76, &Opts{Temperature: O32x, Filter: F3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "BMP390{playback(118)}" {
+		t.Fatal(s)
+	}
+	var e devices.Environment
+	if err := dev.Sense(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if e != (devices.Environment{Temperature: 24080, Time: e.Time}) {
+		t.Fatal(e)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{0x80, 0x00, 0x00}, R: []byte{0x00, 0x00, 0x50}},
+				{W: []byte{0x7E, 0xB6}},
+				{W: append([]byte{0xB1}, make([]byte, 22)...), R: append([]byte{0, 0}, calib...)},
+				// Halt.
+				{W: []byte{0x1B, 0x00}},
+			},
+		},
+	}
+	dev, err := NewSPI(&s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.cal.t1 != 28262*256 || dev.cal.p5 != 25058*8 {
+		t.Fatalf("%#v", dev.cal)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 1, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	data := []Opts{
+		{Pressure: O1x},
+		{Temperature: 7},
+		{Temperature: O1x, Pressure: 7},
+		{Temperature: O1x, Filter: 8},
+	}
+	for i, opts := range data {
+		if _, err := NewI2C(&i2ctest.Playback{}, 0x76, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	bus := i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x76, W: []byte{0x00}, R: []byte{0x58}}},
+		DontPanic: true,
+	}
+	if _, err := NewI2C(&bus, 0x76, nil); err == nil {
+		t.Fatal("bad chip id")
+	}
+	bus = i2ctest.Playback{DontPanic: true}
+	if _, err := NewI2C(&bus, 0x76, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	bus = i2ctest.Playback{
+		Ops:       initOps(0x76, 0x50),
+		DontPanic: true,
+	}
+	dev, err := NewI2C(&bus, 0x76, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e devices.Environment
+	if err := dev.Sense(&e); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestSense_timeout(t *testing.T) {
+	ops := append(initOps(0x76, 0x50), i2ctest.IO{Addr: 0x76, W: []byte{0x1C, 0x03, 0x1F, 0x00, 0x1B, 0x13}})
+	for i := 0; i < 11; i++ {
+		ops = append(ops, i2ctest.IO{Addr: 0x76, W: []byte{0x03}, R: []byte{0x10}})
+	}
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewI2C(&bus, 0x76, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e devices.Environment
+	if err := dev.Sense(&e); err == nil {
+		t.Fatal("timeout")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76, 0x50),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1D, 0x01, 0x1C, 0x00, 0x1F, 0x00, 0x1B, 0x33}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x02}, R: []byte{0x00}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x04}, R: measurement},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x04}, R: measurement},
+			// Halt.
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1B, 0x00}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x76, &Opts{Temperature: O1x, Pressure: O1x})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if env := <-c; env.Temperature != 24080 || env.Pressure != 100535 {
+			t.Fatal(env)
+		}
+	}
+	var e devices.Environment
+	if err := dev.Sense(&e); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.StartFIFO(10 * time.Millisecond); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSenseContinuous_confErr(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76, 0x50),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1D, 0x11, 0x1C, 0x00, 0x1F, 0x00, 0x1B, 0x33}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x02}, R: []byte{0x04}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x76, &Opts{Temperature: O1x, Pressure: O1x})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Hour); err == nil {
+		t.Fatal("configuration error")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFIFO(t *testing.T) {
+	fifo := []byte{
+		0x48,
+		0x94, 0xC0, 0x39, 0x83, 0x80, 0x8D, 0x5B,
+		0xA0, 0x01, 0x02, 0x03,
+		0x90, 0x10, 0xFD, 0x83,
+		0x80, 0x00,
+	}
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x76, 0x50),
+			i2ctest.IO{Addr: 0x76, W: []byte{0x17, 0x19, 0x18, 0x08, 0x7E, 0xB0}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1D, 0x02, 0x1C, 0x00, 0x1F, 0x00, 0x1B, 0x33}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x02}, R: []byte{0x00}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x12}, R: []byte{byte(len(fifo)), 0x00}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x14}, R: fifo},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x12}, R: []byte{0x00, 0x00}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x12}, R: []byte{0x02, 0x00}},
+			i2ctest.IO{Addr: 0x76, W: []byte{0x14}, R: []byte{0x12, 0x00}},
+			// Halt.
+			i2ctest.IO{Addr: 0x76, W: []byte{0x1B, 0x00, 0x17, 0x00}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x76, &Opts{Temperature: O1x, Pressure: O1x})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.ReadFIFO(); err == nil {
+		t.Fatal("FIFO not started")
+	}
+	if err := dev.StartFIFO(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	var e devices.Environment
+	if err := dev.Sense(&e); err == nil {
+		t.Fatal("FIFO running")
+	}
+	if _, err := dev.SenseContinuous(20 * time.Millisecond); err == nil {
+		t.Fatal("FIFO running")
+	}
+	envs, err := dev.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []devices.Environment{{Temperature: 24080, Pressure: 100535}, {Temperature: 24960}}
+	if len(envs) != len(expected) || envs[0] != expected[0] || envs[1] != expected[1] {
+		t.Fatal(envs)
+	}
+	if envs, err := dev.ReadFIFO(); err != nil || envs != nil {
+		t.Fatal(envs, err)
+	}
+	if _, err := dev.ReadFIFO(); err == nil {
+		t.Fatal("invalid frame header")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasDuration(t *testing.T) {
+	d := Dev{opts: defaults}
+	if v := d.measDuration(); v != 18969*time.Microsecond {
+		t.Fatal(v)
+	}
+	d.opts.Pressure = Off
+	if v := d.measDuration(); v != 2417*time.Microsecond {
+		t.Fatal(v)
+	}
+}
+
+func TestOversampling(t *testing.T) {
+	data := []struct {
+		o Oversampling
+		v int
+		s string
+	}{
+		{Off, 0, "Off"},
+		{O1x, 1, "1x"},
+		{O2x, 2, "2x"},
+		{O4x, 4, "4x"},
+		{O8x, 8, "8x"},
+		{O16x, 16, "16x"},
+		{O32x, 32, "32x"},
+		{Oversampling(7), 0, "Oversampling(7)"},
+	}
+	for i, line := range data {
+		if v := line.o.asValue(); v != line.v {
+			t.Fatalf("#%d %d != %d", i, v, line.v)
+		}
+		if s := line.o.String(); s != line.s {
+			t.Fatalf("#%d %s != %s", i, s, line.s)
+		}
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bmp388
+
+import "math"
+
+// calibration is the factory calibration data, converted to the floating
+// point coefficients of the datasheet section 9.1.
+type calibration struct {
+	t1, t2, t3                                   float64
+	p1, p2, p3, p4, p5, p6, p7, p8, p9, p10, p11 float64
+}
+
+// newCalibration parses the calibration data read from 0x31~0x45.
+func newCalibration(b []byte) (c calibration) {
+	u16 := func(i int) float64 {
+		return float64(uint16(b[i]) | uint16(b[i+1])<<8)
+	}
+	i16 := func(i int) float64 {
+		return float64(int16(uint16(b[i]) | uint16(b[i+1])<<8))
+	}
+	i8 := func(i int) float64 {
+		return float64(int8(b[i]))
+	}
+	c.t1 = u16(0) * math.Exp2(8)
+	c.t2 = u16(2) / math.Exp2(30)
+	c.t3 = i8(4) / math.Exp2(48)
+	c.p1 = (i16(5) - math.Exp2(14)) / math.Exp2(20)
+	c.p2 = (i16(7) - math.Exp2(14)) / math.Exp2(29)
+	c.p3 = i8(9) / math.Exp2(32)
+	c.p4 = i8(10) / math.Exp2(37)
+	c.p5 = u16(11) * math.Exp2(3)
+	c.p6 = u16(13) / math.Exp2(6)
+	c.p7 = i8(15) / math.Exp2(8)
+	c.p8 = i8(16) / math.Exp2(15)
+	c.p9 = i16(17) / math.Exp2(48)
+	c.p10 = i8(19) / math.Exp2(48)
+	c.p11 = i8(20) / math.Exp2(65)
+	return c
+}
+
+// compensateTemp returns the temperature in °C.
+//
+// raw has 24 bits of resolution.
+func (c *calibration) compensateTemp(raw uint32) float64 {
+	d := float64(raw) - c.t1
+	return d*c.t2 + d*d*c.t3
+}
+
+// compensatePressure returns the pressure in Pa.
+//
+// raw has 24 bits of resolution and t is the compensated temperature.
+func (c *calibration) compensatePressure(raw uint32, t float64) float64 {
+	p := float64(raw)
+	t2 := t * t
+	t3 := t2 * t
+	out1 := c.p5 + c.p6*t + c.p7*t2 + c.p8*t3
+	out2 := p * (c.p1 + c.p2*t + c.p3*t2 + c.p4*t3)
+	out3 := p*p*(c.p9+c.p10*t) + p*p*p*c.p11
+	return out1 + out2 + out3
+}
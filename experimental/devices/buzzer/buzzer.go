@@ -0,0 +1,280 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package buzzer plays tones on a piezo buzzer or a small speaker.
+//
+// The tones are generated with the hardware PWM of the pin when it implements
+// gpio.PinPWM. Otherwise the pin is toggled in software, which has audible
+// jitter and keeps a CPU core busy while playing.
+//
+// Passive buzzers only are supported; an active buzzer has its own oscillator
+// and is driven like a LED.
+package buzzer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Note is a tone to play for a duration.
+type Note struct {
+	// Frequency is in Hz. 0 is a rest.
+	Frequency float64
+	// Duration is how long the note is played.
+	Duration time.Duration
+}
+
+// MIDI returns the frequency in Hz of the MIDI note number n, where 69 is
+// A4 at 440Hz and 60 is the middle C.
+func MIDI(n int) float64 {
+	return 440 * math.Pow(2, float64(n-69)/12)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Volume is the duty cycle of the signal. The loudest is gpio.DutyHalf and
+	// 0 mutes the buzzer.
+	Volume gpio.Duty
+}
+
+// Dev is a handle to a buzzer.
+type Dev struct {
+	p   gpio.PinOut
+	pwm gpio.PinPWM // nil when toggling the pin in software.
+
+	mu     sync.Mutex
+	volume gpio.Duty
+	err    error         // Error of the last playback.
+	stop   chan struct{} // Closed to abort the current playback.
+	done   chan struct{} // Closed when the current playback ended.
+}
+
+// New returns an object that plays tones on a buzzer connected to p.
+func New(p gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if p == nil || p == gpio.INVALID {
+		return nil, errors.New("buzzer: a pin is required")
+	}
+	if !opts.Volume.Valid() {
+		return nil, fmt.Errorf("buzzer: invalid volume %s", opts.Volume)
+	}
+	d := &Dev{p: p, volume: opts.Volume}
+	d.pwm, _ = p.(gpio.PinPWM)
+	if err := d.silence(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("Buzzer{%s}", d.p)
+}
+
+// SetVolume sets the duty cycle of the signal, starting with the next note
+// played.
+func (d *Dev) SetVolume(v gpio.Duty) error {
+	if !v.Valid() {
+		return fmt.Errorf("buzzer: invalid volume %s", v)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.volume = v
+	return nil
+}
+
+// Tone starts playing a tone at the frequency f in Hz until Stop() or Halt()
+// is called.
+//
+// It returns immediately. The current playback, if any, is stopped first.
+func (d *Dev) Tone(f float64) error {
+	if !(f > 0) {
+		return fmt.Errorf("buzzer: invalid frequency %g", f)
+	}
+	return d.play([]Note{{Frequency: f, Duration: -1}})
+}
+
+// Play starts playing the notes in sequence.
+//
+// It returns immediately; use Wait() to wait for the playback to complete. The
+// current playback, if any, is stopped first.
+func (d *Dev) Play(notes []Note) error {
+	for _, n := range notes {
+		if !(n.Frequency >= 0) || math.IsInf(n.Frequency, 1) {
+			return fmt.Errorf("buzzer: invalid frequency %g", n.Frequency)
+		}
+		if n.Duration < 0 {
+			return fmt.Errorf("buzzer: invalid duration %s", n.Duration)
+		}
+	}
+	return d.play(append([]Note(nil), notes...))
+}
+
+// Wait blocks until the current playback completes or is stopped.
+//
+// It returns the error that aborted the playback, if any.
+func (d *Dev) Wait() error {
+	d.mu.Lock()
+	done := d.done
+	d.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Stop stops the current playback.
+func (d *Dev) Stop() error {
+	d.stopPlaying()
+	return nil
+}
+
+// Halt stops the current playback and silences the buzzer.
+func (d *Dev) Halt() error {
+	d.stopPlaying()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.silence()
+}
+
+//
+
+var defaults = Opts{
+	Volume: gpio.DutyHalf,
+}
+
+// after and sleep are overridden in unit tests.
+var (
+	after = time.After
+	sleep = time.Sleep
+)
+
+func (d *Dev) play(notes []Note) error {
+	d.stopPlaying()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = nil
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		d.playing(notes, stop)
+	}(d.stop, d.done)
+	return nil
+}
+
+func (d *Dev) stopPlaying() {
+	d.mu.Lock()
+	stop := d.stop
+	done := d.done
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (d *Dev) playing(notes []Note, stop <-chan struct{}) {
+	var err error
+	for _, n := range notes {
+		d.mu.Lock()
+		v := d.volume
+		d.mu.Unlock()
+		var stopped bool
+		if stopped, err = d.note(n, v, stop); stopped || err != nil {
+			break
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err2 := d.silence(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		d.err = err
+		log.Printf("%s: failed to play: %v", d, err)
+	}
+}
+
+// note plays a note at the volume v. A negative duration plays the note until
+// stopped.
+//
+// It returns true if the playback was stopped.
+func (d *Dev) note(n Note, v gpio.Duty, stop <-chan struct{}) (bool, error) {
+	if n.Frequency == 0 || v == 0 {
+		if err := d.silence(); err != nil {
+			return false, err
+		}
+		return wait(n.Duration, stop), nil
+	}
+	period := time.Duration(float64(time.Second) / n.Frequency)
+	if d.pwm != nil {
+		if err := d.pwm.PWM(v, period); err != nil {
+			return false, fmt.Errorf("buzzer: %v", err)
+		}
+		return wait(n.Duration, stop), nil
+	}
+	high := time.Duration(int64(period) * int64(v) / int64(gpio.DutyMax))
+	cycles := int(n.Duration.Seconds()*n.Frequency + 0.5)
+	for i := 0; n.Duration < 0 || i < cycles; i++ {
+		select {
+		case <-stop:
+			return true, nil
+		default:
+		}
+		if err := d.p.Out(gpio.High); err != nil {
+			return false, fmt.Errorf("buzzer: %v", err)
+		}
+		sleep(high)
+		if err := d.p.Out(gpio.Low); err != nil {
+			return false, fmt.Errorf("buzzer: %v", err)
+		}
+		sleep(period - high)
+	}
+	return false, nil
+}
+
+func (d *Dev) silence() error {
+	var err error
+	if d.pwm != nil {
+		err = d.pwm.PWM(0, 0)
+	} else {
+		err = d.p.Out(gpio.Low)
+	}
+	if err != nil {
+		return fmt.Errorf("buzzer: %v", err)
+	}
+	return nil
+}
+
+// wait waits for the duration or until stopped. A negative duration waits
+// until stopped.
+//
+// It returns true if stopped.
+func wait(t time.Duration, stop <-chan struct{}) bool {
+	if t < 0 {
+		<-stop
+		return true
+	}
+	select {
+	case <-stop:
+		return true
+	case <-after(t):
+		return false
+	}
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
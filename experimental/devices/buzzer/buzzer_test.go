@@ -0,0 +1,322 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package buzzer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	dev, err := New(gpioreg.ByName("GPIO18"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize buzzer: %v", err)
+	}
+	defer dev.Halt()
+	// C E G C, then a rest.
+	var notes []Note
+	for _, n := range []int{60, 64, 67, 72} {
+		notes = append(notes, Note{MIDI(n), 200 * time.Millisecond})
+	}
+	notes = append(notes, Note{0, 100 * time.Millisecond})
+	if err := dev.Play(notes); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNew_PWM(t *testing.T) {
+	r := &recorder{}
+	p := &pwmPin{recordPin{Pin: gpiotest.Pin{N: "pwm"}, r: r}}
+	dev, err := New(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "Buzzer{pwm(0)}" {
+		t.Fatal(s)
+	}
+	r.check(t, []string{"PWM(0%, 0s)"})
+	notes := []Note{{500, time.Second}, {0, time.Second}, {1000, time.Second}}
+	if err := dev.Play(notes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"PWM(50%, 2ms)", "PWM(0%, 0s)", "PWM(50%, 1ms)", "PWM(0%, 0s)"})
+	if err := dev.SetVolume(gpio.DutyMax / 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Play(notes[2:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"PWM(10%, 1ms)", "PWM(0%, 0s)"})
+	// Muted.
+	if err := dev.SetVolume(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Play(notes[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"PWM(0%, 0s)", "PWM(0%, 0s)"})
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"PWM(0%, 0s)"})
+}
+
+func TestTone_PWM(t *testing.T) {
+	r := &recorder{}
+	p := &pwmPin{recordPin{Pin: gpiotest.Pin{N: "pwm"}, r: r}}
+	dev, err := New(p, &Opts{Volume: gpio.DutyMax / 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Tone(500); err != nil {
+		t.Fatal(err)
+	}
+	// A new playback stops the tone.
+	if err := dev.Tone(1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"PWM(0%, 0s)", "PWM(25%, 2ms)", "PWM(0%, 0s)", "PWM(25%, 1ms)", "PWM(0%, 0s)"})
+}
+
+func TestPlay_software(t *testing.T) {
+	r := &recorder{}
+	p := &recordPin{Pin: gpiotest.Pin{N: "gpio"}, r: r}
+	dev, err := New(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"Low"})
+	if err := dev.Play([]Note{{1000, 2 * time.Millisecond}, {0, time.Second}, {2000, time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []string{"High", "Low", "High", "Low", "Low", "High", "Low", "High", "Low", "Low"})
+}
+
+func TestTone_software(t *testing.T) {
+	defer func() {
+		sleep = func(time.Duration) {}
+	}()
+	sleep = time.Sleep
+	r := &recorder{}
+	p := &recordPin{Pin: gpiotest.Pin{N: "gpio"}, r: r}
+	dev, err := New(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Tone(1000); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := r.log
+	if len(l) < 4 || l[1] != "High" || l[2] != "Low" || l[len(l)-1] != "Low" {
+		t.Fatal(l)
+	}
+}
+
+func TestMIDI(t *testing.T) {
+	if f := MIDI(69); f != 440 {
+		t.Fatal(f)
+	}
+	if f := MIDI(60); math.Abs(f-261.63) > 0.01 {
+		t.Fatal(f)
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	if _, err := New(nil, nil); err == nil {
+		t.Fatal("missing pin")
+	}
+	if _, err := New(gpio.INVALID, nil); err == nil {
+		t.Fatal("invalid pin")
+	}
+	if _, err := New(&gpiotest.Pin{}, &Opts{Volume: -1}); err == nil {
+		t.Fatal("invalid volume")
+	}
+	if _, err := New(&failPin{}, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	dev, err := New(&gpiotest.Pin{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetVolume(gpio.DutyMax + 1); err == nil {
+		t.Fatal("invalid volume")
+	}
+	if err := dev.Tone(0); err == nil {
+		t.Fatal("invalid frequency")
+	}
+	if err := dev.Tone(math.NaN()); err == nil {
+		t.Fatal("invalid frequency")
+	}
+	data := []Note{
+		{-1, time.Second},
+		{math.NaN(), time.Second},
+		{math.Inf(1), time.Second},
+		{440, -1},
+	}
+	for i, n := range data {
+		if err := dev.Play([]Note{n}); err == nil {
+			t.Fatalf("#%d: invalid note", i)
+		}
+	}
+}
+
+func TestPlay_fail(t *testing.T) {
+	notes := []Note{{1000, time.Millisecond}}
+	for n := 1; n < 3; n++ {
+		dev, err := New(&failPin{after: n}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.Play(notes); err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.Wait(); err == nil {
+			t.Fatalf("#%d: write failed", n)
+		}
+	}
+	// The rest fails to silence.
+	dev, err := New(&failPin{after: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Play([]Note{{0, time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("write failed")
+	}
+	r := &recorder{}
+	if dev, err = New(&pwmPin{recordPin{r: r}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	r.fail = true
+	if err := dev.Play(notes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err == nil {
+		t.Fatal("PWM failed")
+	}
+}
+
+//
+
+func init() {
+	after = func(time.Duration) <-chan time.Time {
+		c := make(chan time.Time, 1)
+		c <- time.Time{}
+		return c
+	}
+	sleep = func(time.Duration) {}
+}
+
+// recorder logs the pin writes.
+type recorder struct {
+	mu   sync.Mutex
+	log  []string
+	fail bool
+}
+
+func (r *recorder) add(s string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("injected error")
+	}
+	r.log = append(r.log, s)
+	return nil
+}
+
+// check verifies the pin writes since the last call.
+func (r *recorder) check(t *testing.T, expected []string) {
+	r.mu.Lock()
+	l := r.log
+	r.log = nil
+	r.mu.Unlock()
+	if len(l) != len(expected) {
+		t.Fatalf("%s != %s", l, expected)
+	}
+	for i := range l {
+		if l[i] != expected[i] {
+			t.Fatalf("%s != %s", l, expected)
+		}
+	}
+}
+
+type recordPin struct {
+	gpiotest.Pin
+	r *recorder
+}
+
+func (p *recordPin) Out(l gpio.Level) error {
+	return p.r.add(l.String())
+}
+
+// pwmPin is a recordPin implementing gpio.PinPWM.
+type pwmPin struct {
+	recordPin
+}
+
+func (p *pwmPin) PWM(duty gpio.Duty, period time.Duration) error {
+	return p.r.add(fmt.Sprintf("PWM(%s, %s)", duty, period))
+}
+
+// failPin fails after a number of successful writes.
+type failPin struct {
+	gpiotest.Pin
+	after int
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	if f.after == 0 {
+		return errors.New("injected error")
+	}
+	f.after--
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"periph.io/x/periph/conn"
@@ -57,6 +58,16 @@ const (
 	ReleasedStatus
 )
 
+// Event is a touch event on one of the inputs.
+type Event struct {
+	Input  int         // Input sensor index, between 0 and 7.
+	Status TouchStatus // PressedStatus, HeldStatus or ReleasedStatus.
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("#%d %s", e.Input, e.Status)
+}
+
 const (
 	nbrOfLEDs         = 8
 	strOffStatus      = "Off"
@@ -85,26 +96,64 @@ type Dev struct {
 	isSPI         bool
 	inputStatuses []TouchStatus
 	resetAt       time.Time
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 func (d *Dev) String() string {
 	return fmt.Sprintf("cap1188{%s}", d.regWrapper.Conn)
 }
 
-// Halt is a noop for the cap1188.
+// Halt stops the events, if any.
 func (d *Dev) Halt() error {
+	d.stopEvents()
 	return nil
 }
 
+// Events returns a channel on which the press, hold and release events are
+// sent, as signaled by the ALERT pin.
+//
+// Opts.AlertPin must be set; it is configured for falling edge detection.
+// HeldStatus events are only sent when Opts.RetriggerOnHold is set. The
+// inputs are polled while touched so the release is detected even when
+// Opts.InterruptOnRelease is not set.
+//
+// It is important to call Halt() once done, which will close the channel.
+func (d *Dev) Events() (<-chan Event, error) {
+	if d.AlertPin == nil {
+		return nil, wrap(errors.New("Events() requires Opts.AlertPin"))
+	}
+	d.stopEvents()
+	if err := d.AlertPin.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+		return nil, wrap(fmt.Errorf("failed to setup the alert pin - %s", err))
+	}
+	d.waitReady()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Deassert the ALERT pin so the next touch triggers an edge.
+	if err := d.clearBit(0x0, 0); err != nil {
+		return nil, wrap(fmt.Errorf("failed to clear the interrupt - %s", err))
+	}
+	c := make(chan Event, nbrOfLEDs)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(c)
+		d.sensingEvents(c, stop)
+	}(d.stop)
+	return c, nil
+}
+
 // InputStatus reads and returns the status of the 8 inputs as an array where
 // each entry indicates a touch event or not.
 func (d *Dev) InputStatus() ([]TouchStatus, error) {
 	// first check that we are ready
-	now := time.Now()
-	readyAt := d.resetAt.Add(200 * time.Millisecond)
-	if now.Before(readyAt) {
-		time.Sleep(readyAt.Sub(now))
-	}
+	d.waitReady()
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	// read inputs
 	status, err := d.regWrapper.ReadUint8(0x3)
 	if err != nil {
@@ -229,6 +278,8 @@ func (d *Dev) Reset() (err error) {
 
 // ClearInterrupt resets the interrupt flag
 func (d *Dev) ClearInterrupt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	// clear the main control bit
 	return d.clearBit(0x0, 0)
 }
@@ -493,6 +544,83 @@ func (d *Dev) makeDev(opts *Opts) error {
 	return nil
 }
 
+// pollInterval is the interval at which the inputs are read while touched, to
+// detect the release.
+const pollInterval = 50 * time.Millisecond
+
+// waitReady sleeps until the first conversion is done after reset.
+func (d *Dev) waitReady() {
+	now := time.Now()
+	readyAt := d.resetAt.Add(200 * time.Millisecond)
+	if now.Before(readyAt) {
+		time.Sleep(readyAt.Sub(now))
+	}
+}
+
+func (d *Dev) stopEvents() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingEvents(c chan<- Event, stop <-chan struct{}) {
+	var last byte
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		// Wake up regularly to check for stop, and poll while an input is
+		// touched to detect the release.
+		wait := 100 * time.Millisecond
+		if last != 0 {
+			wait = pollInterval
+		}
+		edge := d.AlertPin.WaitForEdge(wait)
+		if !edge && last == 0 {
+			continue
+		}
+		d.mu.Lock()
+		status, err := d.regWrapper.ReadUint8(0x3)
+		if err == nil {
+			// Clearing the interrupt also clears the status of the inputs that
+			// are not touched anymore.
+			err = d.clearBit(0x0, 0)
+		}
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		for i := 0; i < nbrOfLEDs; i++ {
+			m := byte(1) << uint(7-i)
+			var e Event
+			switch {
+			case status&m != 0 && last&m == 0:
+				e = Event{Input: i, Status: PressedStatus}
+			case status&m != 0 && edge && d.RetriggerOnHold:
+				e = Event{Input: i, Status: HeldStatus}
+			case status&m == 0 && last&m != 0:
+				e = Event{Input: i, Status: ReleasedStatus}
+			default:
+				continue
+			}
+			select {
+			case c <- e:
+			case <-stop:
+				return
+			}
+		}
+		last = status
+	}
+}
+
 // setBit sets a specific bit on a register
 // TODO(mattetti): avoid reading before writing, keep states in memory
 func (d *Dev) setBit(regID uint8, idx int) error {
@@ -520,3 +648,4 @@ func wrap(err error) error {
 }
 
 var _ devices.Device = &Dev{}
+var _ fmt.Stringer = &Event{}
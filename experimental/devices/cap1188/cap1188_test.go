@@ -5,14 +5,16 @@
 package cap1188_test
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"sync"
 	"testing"
-	"time"
 
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/i2c/i2creg"
 	"periph.io/x/periph/conn/i2c/i2ctest"
@@ -30,16 +32,12 @@ func Example() {
 	// We will configure the cap1188 by setting some options, we can start by the defaults.
 	opts := cap1188.DefaultOpts()
 
-	// We need to set an alert ping that will let us know when a touch event
+	// We need to set an alert pin that will let us know when a touch event
 	// occurs. The alert pin is the pin connected to the IRQ/interrupt pin.
 	alertPin := gpioreg.ByName("GPIO25")
 	if alertPin == nil {
 		log.Fatal("invalid alert GPIO pin number")
 	}
-	// We set the alert pin to monitor for interrupts
-	if err := alertPin.In(gpio.PullUp, gpio.BothEdges); err != nil {
-		log.Fatalf("Can't monitor the alert pin")
-	}
 
 	// Optionally but highly recommended, we can also set a reset pin to
 	// start/leave things in a clean state.
@@ -55,31 +53,22 @@ func Example() {
 	if err != nil {
 		log.Fatalf("couldn't open cap1188 - %s", err)
 	}
-	time.Sleep(200 * time.Millisecond)
+	defer dev.Halt()
 
 	fmt.Println("Monitoring for touch events")
+	events, err := dev.Events()
+	if err != nil {
+		log.Fatal(err)
+	}
 	maxTouches := 42 // stop the program after 42 touches
-	for maxTouches > 0 {
-		if alertPin.WaitForEdge(-1) {
-			maxTouches--
-			statuses, err := dev.InputStatus()
-			if err != nil {
-				fmt.Printf("Error reading inputs: %s\n", err)
-				continue
-			}
-			// print the status of each sensor
-			for i, st := range statuses {
-				fmt.Printf("#%d: %s\t", i, st)
-			}
-			fmt.Println()
-			// we need to clear the interrupt so it can be triggered again
-			if err := dev.ClearInterrupt(); err != nil {
-				fmt.Println(err, "while clearing the interrupt")
+	for e := range events {
+		fmt.Println(e)
+		if e.Status == cap1188.PressedStatus {
+			if maxTouches--; maxTouches == 0 {
+				break
 			}
 		}
 	}
-
-	fmt.Print("\n")
 }
 
 func TestNewI2C(t *testing.T) {
@@ -317,6 +306,108 @@ func TestDev_InputStatus(t *testing.T) {
 	})
 }
 
+func TestDev_Events(t *testing.T) {
+	bus := newFakeBus()
+	p := &gpiotest.Pin{N: "ALERT", EdgesChan: make(chan gpio.Level)}
+	opts := cap1188.DefaultOpts()
+	opts.AlertPin = p
+	opts.RetriggerOnHold = true
+	d, err := cap1188.NewI2C(bus, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.set(0x0, 0x01)
+	c, err := d.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := bus.get(0x0); v != 0 {
+		t.Fatalf("interrupt must be cleared: %#x", v)
+	}
+	if p.Pull() != gpio.PullUp {
+		t.Fatal(p.Pull())
+	}
+	// Press the first and fourth inputs.
+	bus.set(0x0, 0x01)
+	bus.set(0x3, 0x90)
+	p.EdgesChan <- gpio.Low
+	for _, e := range []cap1188.Event{{0, cap1188.PressedStatus}, {3, cap1188.PressedStatus}} {
+		if g := <-c; g != e {
+			t.Fatalf("%s != %s", g, e)
+		}
+	}
+	if v := bus.get(0x0); v != 0 {
+		t.Fatalf("interrupt must be cleared: %#x", v)
+	}
+	// Repeat rate interrupt while the first input is held.
+	bus.set(0x3, 0x80)
+	p.EdgesChan <- gpio.Low
+	for _, e := range []cap1188.Event{{0, cap1188.HeldStatus}, {3, cap1188.ReleasedStatus}} {
+		if g := <-c; g != e {
+			t.Fatalf("%s != %s", g, e)
+		}
+	}
+	// The release is detected without interrupt.
+	bus.set(0x3, 0x00)
+	if g := <-c; g != (cap1188.Event{0, cap1188.ReleasedStatus}) {
+		t.Fatal(g)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+}
+
+func TestDev_Events_fail(t *testing.T) {
+	bus := newFakeBus()
+	d, err := cap1188.NewI2C(bus, cap1188.DefaultOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Events(); err == nil {
+		t.Fatal("no alert pin")
+	}
+	opts := cap1188.DefaultOpts()
+	opts.AlertPin = &gpiotest.Pin{N: "ALERT"}
+	if d, err = cap1188.NewI2C(bus, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Events(); err == nil {
+		t.Fatal("no edge detection")
+	}
+	p := &gpiotest.Pin{N: "ALERT", EdgesChan: make(chan gpio.Level)}
+	opts.AlertPin = p
+	if d, err = cap1188.NewI2C(bus, opts); err != nil {
+		t.Fatal(err)
+	}
+	bus.fail(true)
+	if _, err := d.Events(); err == nil {
+		t.Fatal("I²C failed")
+	}
+	bus.fail(false)
+	c, err := d.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A failure stops the events.
+	bus.fail(true)
+	p.EdgesChan <- gpio.Low
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvent_String(t *testing.T) {
+	if s := (cap1188.Event{3, cap1188.HeldStatus}).String(); s != "#3 Held" {
+		t.Fatal(s)
+	}
+}
+
 func setupPlaybackIO() []i2ctest.IO {
 	return []i2ctest.IO{
 		// chip ID
@@ -346,3 +437,54 @@ func setupPlaybackIO() []i2ctest.IO {
 		{Addr: 40, W: []byte{0x44, 0x61}, R: nil},
 	}
 }
+
+// fakeBus emulates the register map.
+type fakeBus struct {
+	mu       sync.Mutex
+	regs     [256]byte
+	failures bool
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{}
+	f.regs[0xFD] = 0x50
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures {
+		return errors.New("injected")
+	}
+	reg := int(w[0])
+	copy(f.regs[reg:], w[1:])
+	copy(r, f.regs[reg:])
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) set(reg, v byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[reg] = v
+}
+
+func (f *fakeBus) get(reg byte) byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.regs[reg]
+}
+
+func (f *fakeBus) fail(on bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = on
+}
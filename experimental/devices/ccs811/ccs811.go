@@ -0,0 +1,438 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ccs811 controls an AMS CCS811 air quality sensor over I²C.
+//
+// The sensor estimates the equivalent CO2 (eCO2) and the total volatile
+// organic compounds (TVOC) at a rate determined by the drive mode. The
+// accuracy improves when the ambient temperature and humidity are provided
+// with SetEnvironment().
+//
+// The nWAKE pin must be tied to ground or driven low by the application.
+//
+// Datasheet
+//
+// https://ams.com/documents/20143/36005/CCS811_DS000459_7-00.pdf
+package ccs811
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// DriveMode determines how often a measurement is done.
+type DriveMode uint8
+
+// Possible drive modes.
+const (
+	Idle      DriveMode = 0 // No measurement
+	Mode1s    DriveMode = 1
+	Mode10s   DriveMode = 2
+	Mode60s   DriveMode = 3
+	Mode250ms DriveMode = 4 // Raw data only; eCO2 and TVOC are not calculated
+)
+
+const driveModeName = "IdleMode1sMode10sMode60sMode250ms"
+
+var driveModeIndex = [...]uint8{0, 4, 10, 17, 24, 33}
+
+func (d DriveMode) String() string {
+	if d >= DriveMode(len(driveModeIndex)-1) {
+		return fmt.Sprintf("DriveMode(%d)", d)
+	}
+	return driveModeName[driveModeIndex[d]:driveModeIndex[d+1]]
+}
+
+// Period returns the duration between measurements, 0 for Idle.
+func (d DriveMode) Period() time.Duration {
+	if d > Mode250ms {
+		return 0
+	}
+	return modePeriod[d]
+}
+
+// Reading is one measurement.
+type Reading struct {
+	ECO2 uint16 // Equivalent CO2 in ppm, from 400 to 8192
+	TVOC uint16 // Total volatile organic compounds in ppb, from 0 to 1187
+	// Raw sensor data; the current through the sensor in µA and the voltage
+	// across it in 1.65V/1023 units.
+	RawCurrent uint8
+	RawVoltage uint16
+}
+
+func (r *Reading) String() string {
+	return fmt.Sprintf("eCO2: %dppm, TVOC: %dppb", r.ECO2, r.TVOC)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Addr is 0x5A when ADDR is low and 0x5B when ADDR is high.
+	Addr uint16
+	// Mode is the initial drive mode. The zero value is Idle.
+	Mode DriveMode
+	// Interrupt is the GPIO pin connected to nINT. When set, the data ready
+	// interrupt is used instead of polling the status register.
+	Interrupt gpio.PinIn
+}
+
+// Dev is a handle to a CCS811.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a CCS811.
+//
+// The application firmware is started and the drive mode is set.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.Addr == 0 {
+		o.Addr = defaults.Addr
+	}
+	if o.Addr != 0x5A && o.Addr != 0x5B {
+		return nil, errors.New("ccs811: given address not supported by device")
+	}
+	if o.Mode > Mode250ms {
+		return nil, fmt.Errorf("ccs811: invalid drive mode %d", o.Mode)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: o.Addr}, opts: o}
+	var id [1]byte
+	if err := d.readReg(regHWID, id[:]); err != nil {
+		return nil, err
+	}
+	if id[0] != 0x81 {
+		return nil, fmt.Errorf("ccs811: unexpected hardware id %#x", id[0])
+	}
+	if err := d.start(); err != nil {
+		return nil, err
+	}
+	if o.Interrupt != nil {
+		// nINT is open drain, active low.
+		if err := o.Interrupt.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+			return nil, fmt.Errorf("ccs811: %v", err)
+		}
+	}
+	if err := d.setMode(o.Mode); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("CCS811{%s}", d.c)
+}
+
+// Sense waits for the next measurement and returns it.
+//
+// The drive mode must not be Idle.
+func (d *Dev) Sense(r *Reading) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("ccs811: already sensing continuously")
+	}
+	if d.opts.Mode == Idle {
+		return errors.New("ccs811: no measurement in idle mode")
+	}
+	return d.wait(r, nil)
+}
+
+// SenseContinuous returns measurements as they are produced by the device, at
+// the rate determined by the drive mode.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous() (<-chan Reading, error) {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.opts.Mode == Idle {
+		return nil, errors.New("ccs811: no measurement in idle mode")
+	}
+	sensing := make(chan Reading)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// SetMode changes the drive mode.
+//
+// When changing to a mode with a longer period, the device should be put in
+// Idle mode for 10 minutes first.
+func (d *Dev) SetMode(m DriveMode) error {
+	if m > Mode250ms {
+		return fmt.Errorf("ccs811: invalid drive mode %d", m)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("ccs811: already sensing continuously")
+	}
+	return d.setMode(m)
+}
+
+// SetEnvironment provides the ambient temperature and humidity to compensate
+// the measurements. The pressure is ignored.
+func (d *Dev) SetEnvironment(env *devices.Environment) error {
+	// Both are in 1/512 units and the temperature has an offset of 25°C.
+	h := int64(env.Humidity) * 512 / 100
+	t := (int64(env.Temperature) + 25000) * 512 / 1000
+	if h < 0 || h > 0xFFFF || t < 0 || t > 0xFFFF {
+		return errors.New("ccs811: environment out of range")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(regEnvData, byte(h>>8), byte(h), byte(t>>8), byte(t))
+}
+
+// Baseline returns the current baseline of the algorithm.
+//
+// The baseline can be saved and restored with SetBaseline() after a power
+// cycle to skip the conditioning period.
+func (d *Dev) Baseline() (uint16, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [2]byte
+	if err := d.readReg(regBaseline, b[:]); err != nil {
+		return 0, err
+	}
+	return uint16(b[0])<<8 | uint16(b[1]), nil
+}
+
+// SetBaseline restores a baseline previously retrieved with Baseline().
+func (d *Dev) SetBaseline(v uint16) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(regBaseline, byte(v>>8), byte(v))
+}
+
+// Halt stops the sensing as initiated by SenseContinuous() and puts the
+// device in Idle mode.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setMode(Idle)
+}
+
+//
+
+const (
+	regStatus    = 0x00
+	regMeasMode  = 0x01
+	regAlgResult = 0x02
+	regEnvData   = 0x05
+	regBaseline  = 0x11
+	regHWID      = 0x20
+	regErrorID   = 0xE0
+	regAppStart  = 0xF4
+
+	statusError     = 0x01
+	statusDataReady = 0x08
+	statusAppValid  = 0x10
+	statusFWMode    = 0x80
+
+	measIntDataReady = 0x08
+)
+
+// modePeriod is indexed by DriveMode.
+var modePeriod = [...]time.Duration{0, time.Second, 10 * time.Second, 60 * time.Second, 250 * time.Millisecond}
+
+// errorNames is indexed by the bit position in ERROR_ID.
+var errorNames = [...]string{
+	"invalid register write",
+	"invalid register read",
+	"invalid drive mode",
+	"maximum sensor resistance exceeded",
+	"heater current fault",
+	"heater voltage fault",
+}
+
+// start boots the application firmware.
+func (d *Dev) start() error {
+	var s [1]byte
+	if err := d.readReg(regStatus, s[:]); err != nil {
+		return err
+	}
+	if s[0]&statusFWMode != 0 {
+		// Already running.
+		return nil
+	}
+	if s[0]&statusAppValid == 0 {
+		return errors.New("ccs811: no valid application firmware")
+	}
+	if err := d.write(regAppStart); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond)
+	if err := d.status(s[:]); err != nil {
+		return err
+	}
+	if s[0]&statusFWMode == 0 {
+		return errors.New("ccs811: failed to start the application firmware")
+	}
+	return nil
+}
+
+func (d *Dev) setMode(m DriveMode) error {
+	v := byte(m) << 4
+	if d.opts.Interrupt != nil {
+		v |= measIntDataReady
+	}
+	if err := d.write(regMeasMode, v); err != nil {
+		return err
+	}
+	d.opts.Mode = m
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(sensing chan<- Reading, stop <-chan struct{}) {
+	for {
+		var r Reading
+		d.mu.Lock()
+		err := d.wait(&r, stop)
+		d.mu.Unlock()
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- r:
+		case <-stop:
+			return
+		}
+	}
+}
+
+var errStopped = errors.New("ccs811: stopped")
+
+// wait waits for the data ready signal then reads the measurement.
+//
+// It polls the status register at a tenth of the measurement period or waits
+// for the interrupt. It gives up after twice the measurement period or when
+// stop is closed.
+func (d *Dev) wait(r *Reading, stop <-chan struct{}) error {
+	period := d.opts.Mode.Period()
+	poll := period / 10
+	for end := time.Now().Add(2 * period); time.Now().Before(end); {
+		select {
+		case <-stop:
+			return errStopped
+		default:
+		}
+		if d.opts.Interrupt != nil {
+			// nINT stays asserted as long as the data is not read; check the
+			// level first.
+			if d.opts.Interrupt.Read() == gpio.High && !d.opts.Interrupt.WaitForEdge(poll) {
+				continue
+			}
+		}
+		var b [8]byte
+		if err := d.readReg(regAlgResult, b[:]); err != nil {
+			return err
+		}
+		if b[4]&statusError != 0 {
+			return d.readError()
+		}
+		if b[4]&statusDataReady != 0 {
+			r.ECO2 = uint16(b[0])<<8 | uint16(b[1])
+			r.TVOC = uint16(b[2])<<8 | uint16(b[3])
+			r.RawCurrent = b[6] >> 2
+			r.RawVoltage = uint16(b[6]&0x03)<<8 | uint16(b[7])
+			return nil
+		}
+		if d.opts.Interrupt == nil {
+			time.Sleep(poll)
+		} else {
+			d.opts.Interrupt.WaitForEdge(poll)
+		}
+	}
+	return errors.New("ccs811: timed out waiting for measurement")
+}
+
+// status reads the status register and returns the device error if any.
+func (d *Dev) status(s []byte) error {
+	if err := d.readReg(regStatus, s); err != nil {
+		return err
+	}
+	if s[0]&statusError != 0 {
+		return d.readError()
+	}
+	return nil
+}
+
+func (d *Dev) readError() error {
+	var e [1]byte
+	if err := d.readReg(regErrorID, e[:]); err != nil {
+		return err
+	}
+	var msgs []string
+	for i, n := range errorNames {
+		if e[0]&(1<<uint(i)) != 0 {
+			msgs = append(msgs, n)
+		}
+	}
+	if len(msgs) == 0 {
+		return fmt.Errorf("ccs811: device error %#x", e[0])
+	}
+	return errors.New("ccs811: " + strings.Join(msgs, ", "))
+}
+
+func (d *Dev) readReg(reg byte, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("ccs811: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) write(reg byte, v ...byte) error {
+	if err := d.c.Tx(append([]byte{reg}, v...), nil); err != nil {
+		return fmt.Errorf("ccs811: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	Addr: 0x5A,
+	Mode: Mode1s,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
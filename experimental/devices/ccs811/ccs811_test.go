@@ -0,0 +1,243 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ccs811
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize ccs811: %v", err)
+	}
+	defer dev.Halt()
+	env := devices.Environment{Temperature: 21000, Humidity: 4500}
+	if err := dev.SetEnvironment(&env); err != nil {
+		log.Fatal(err)
+	}
+	var r Reading
+	if err := dev.Sense(&r); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", &r)
+}
+
+//
+
+func initOps(meas byte) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x81}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x10}},
+		{Addr: 0x5A, W: []byte{0xF4}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x90}},
+		{Addr: 0x5A, W: []byte{0x01, meas}},
+	}
+}
+
+var readyOp = i2ctest.IO{Addr: 0x5A, W: []byte{0x02}, R: []byte{0x01, 0x90, 0x00, 0x0A, 0x98, 0x00, 0x14, 0x33}}
+
+func TestNewI2C_Sense(t *testing.T) {
+	ops := initOps(0x10)
+	ops = append(ops,
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x02}, R: []byte{0, 0, 0, 0, 0x90, 0, 0, 0}},
+		readyOp,
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x02}, R: []byte{0, 0, 0, 0, 0x91, 0, 0, 0}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0xE0}, R: []byte{0x04}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x05, 0x64, 0x00, 0x64, 0x00}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x11}, R: []byte{0x12, 0x34}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x11, 0x12, 0x34}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x01, 0x40}},
+		i2ctest.IO{Addr: 0x5A, W: []byte{0x01, 0x00}},
+	)
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "CCS811{playback(90)}" {
+		t.Fatal(s)
+	}
+	var r Reading
+	if err := dev.Sense(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r != (Reading{ECO2: 400, TVOC: 10, RawCurrent: 5, RawVoltage: 0x33}) {
+		t.Fatal(r)
+	}
+	if s := r.String(); s != "eCO2: 400ppm, TVOC: 10ppb" {
+		t.Fatal(s)
+	}
+	if err := dev.Sense(&r); err == nil || err.Error() != "ccs811: invalid drive mode" {
+		t.Fatal(err)
+	}
+	env := devices.Environment{Temperature: 25000, Humidity: 5000}
+	if err := dev.SetEnvironment(&env); err != nil {
+		t.Fatal(err)
+	}
+	env.Temperature = -30000
+	if err := dev.SetEnvironment(&env); err == nil {
+		t.Fatal("out of range")
+	}
+	if b, err := dev.Baseline(); err != nil || b != 0x1234 {
+		t.Fatal(b, err)
+	}
+	if err := dev.SetBaseline(0x1234); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetMode(Mode250ms); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetMode(5); err == nil {
+		t.Fatal("invalid mode")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Sense(&r); err == nil {
+		t.Fatal("idle")
+	}
+	if _, err := dev.SenseContinuous(); err == nil {
+		t.Fatal("idle")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, &Opts{Addr: 0x40}); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, &Opts{Mode: 5}); err == nil {
+		t.Fatal("invalid mode")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	bus := i2ctest.Playback{Ops: []i2ctest.IO{{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x55}}}}
+	if _, err := NewI2C(&bus, nil); err == nil {
+		t.Fatal("invalid hardware id")
+	}
+	bus = i2ctest.Playback{Ops: []i2ctest.IO{
+		{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x81}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x00}},
+	}}
+	if _, err := NewI2C(&bus, nil); err == nil {
+		t.Fatal("no valid application")
+	}
+	bus = i2ctest.Playback{Ops: []i2ctest.IO{
+		{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x81}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x10}},
+		{Addr: 0x5A, W: []byte{0xF4}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x10}},
+	}}
+	if _, err := NewI2C(&bus, nil); err == nil {
+		t.Fatal("application not started")
+	}
+	bus = i2ctest.Playback{Ops: []i2ctest.IO{
+		{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x81}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x10}},
+		{Addr: 0x5A, W: []byte{0xF4}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x11}},
+		{Addr: 0x5A, W: []byte{0xE0}, R: []byte{0x80}},
+	}}
+	if _, err := NewI2C(&bus, nil); err == nil || err.Error() != "ccs811: device error 0x80" {
+		t.Fatal(err)
+	}
+	bus = i2ctest.Playback{Ops: initOps(0x18)[:4]}
+	if _, err := NewI2C(&bus, &Opts{Interrupt: &gpiotest.Pin{N: "INT"}}); err == nil {
+		t.Fatal("pin without edge support")
+	}
+}
+
+func TestSenseContinuous_interrupt(t *testing.T) {
+	// Already in application mode.
+	ops := []i2ctest.IO{
+		{Addr: 0x5A, W: []byte{0x20}, R: []byte{0x81}},
+		{Addr: 0x5A, W: []byte{0x00}, R: []byte{0x90}},
+		{Addr: 0x5A, W: []byte{0x01, 0x18}},
+		readyOp,
+		readyOp,
+		{Addr: 0x5A, W: []byte{0x01, 0x08}},
+	}
+	bus := i2ctest.Playback{Ops: ops}
+	p := &intPin{Pin: gpiotest.Pin{N: "INT", EdgesChan: make(chan gpio.Level)}}
+	dev, err := NewI2C(&bus, &Opts{Mode: Mode1s, Interrupt: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.P != gpio.PullUp {
+		t.Fatal(p.P)
+	}
+	c, err := dev.SenseContinuous()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		p.EdgesChan <- gpio.Low
+		if r := <-c; r.ECO2 != 400 || r.TVOC != 10 {
+			t.Fatal(r)
+		}
+	}
+	var r Reading
+	if err := dev.Sense(&r); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.SetMode(Mode10s); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriveMode(t *testing.T) {
+	if s := Mode60s.String(); s != "Mode60s" {
+		t.Fatal(s)
+	}
+	if s := DriveMode(5).String(); s != "DriveMode(5)" {
+		t.Fatal(s)
+	}
+	if p := Mode250ms.Period(); p != 250000000 {
+		t.Fatal(p)
+	}
+	if p := DriveMode(5).Period(); p != 0 {
+		t.Fatal(p)
+	}
+}
+
+// intPin is a nINT pin that is deasserted until an edge is received, so each
+// edge is followed by exactly one read.
+type intPin struct {
+	gpiotest.Pin
+}
+
+func (i *intPin) Read() gpio.Level {
+	return gpio.High
+}
@@ -0,0 +1,301 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package dht controls an Aosong DHT11 or DHT22 (AM2302) humidity and
+// temperature sensor over a single GPIO pin.
+//
+// The single wire protocol encodes each bit as the duration of a high pulse,
+// 26~28µs for a 0 and 70µs for a 1. The pulses are captured by busy polling
+// the pin so the host must be able to read the pin at least every 10µs or so;
+// a failed capture or checksum is automatically retried.
+//
+// Datasheet
+//
+// https://akizukidenshi.com/download/ds/aosong/DHT11.pdf
+//
+// https://www.sparkfun.com/datasheets/Sensors/Temperature/DHT22.pdf
+package dht
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/devices"
+)
+
+// Model is the sensor model.
+type Model uint8
+
+// Supported models.
+const (
+	DHT22 Model = 0 // Also sold as AM2302
+	DHT11 Model = 1
+)
+
+const modelName = "DHT22DHT11"
+
+var modelIndex = [...]uint8{0, 5, 10}
+
+func (m Model) String() string {
+	if m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Model Model
+	// Retries is the number of additional attempts done when a read fails.
+	Retries int
+}
+
+// Dev is a handle to a DHT11 or DHT22 device.
+type Dev struct {
+	p    gpio.PinIO
+	opts Opts
+	last time.Time // Time of the last read attempt
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns an object that communicates over the specified pin to a DHT11
+// or DHT22.
+//
+// The pin must have a pull up resistor, either an external one or the one
+// of the CPU.
+func New(p gpio.PinIO, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Model > DHT11 {
+		return nil, fmt.Errorf("dht: invalid model %d", opts.Model)
+	}
+	if opts.Retries < 0 {
+		return nil, errors.New("dht: invalid number of retries")
+	}
+	// The line idles high.
+	if err := p.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		return nil, fmt.Errorf("dht: %v", err)
+	}
+	return &Dev{p: p, opts: *opts}, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.opts.Model, d.p)
+}
+
+// Sense reads the temperature in °C and the relative humidity.
+//
+// The sensor doesn't support being read more often than every 1s for the
+// DHT11 and 2s for the DHT22; Sense() sleeps as necessary.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("dht: already sensing continuously")
+	}
+	return d.sense(env)
+}
+
+// SenseContinuous returns measurements as °C and % of relative humidity on a
+// continuous basis.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < minInterval[d.opts.Model] {
+		return nil, fmt.Errorf("dht: interval must be at least %s", minInterval[d.opts.Model])
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature | devices.MetricHumidity
+}
+
+// Halt stops the DHT from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+// minInterval is indexed by Model.
+var minInterval = [...]time.Duration{2 * time.Second, time.Second}
+
+// startDuration is how long the line is held low to request a measurement,
+// indexed by Model.
+var startDuration = [...]time.Duration{1100 * time.Microsecond, 20 * time.Millisecond}
+
+// edgeTimeout is the maximum duration of any level during the reply; the
+// longest is 80µs.
+const edgeTimeout = 200 * time.Microsecond
+
+// now and sleep are overridden in unit tests.
+var now = time.Now
+var sleep = time.Sleep
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.sense(&e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sense(env *devices.Environment) error {
+	var err error
+	for i := 0; i <= d.opts.Retries; i++ {
+		if wait := minInterval[d.opts.Model] - now().Sub(d.last); !d.last.IsZero() && wait > 0 {
+			sleep(wait)
+		}
+		var b [5]byte
+		b, err = d.read()
+		d.last = now()
+		if err == nil {
+			if err = d.decode(b, env); err == nil {
+				env.Time = d.last
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// read requests a measurement and captures the 40 bits of the reply.
+func (d *Dev) read() ([5]byte, error) {
+	var b [5]byte
+	if err := d.p.Out(gpio.Low); err != nil {
+		return b, fmt.Errorf("dht: %v", err)
+	}
+	sleep(startDuration[d.opts.Model])
+	// The timing sensitive part starts here.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.p.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		return b, fmt.Errorf("dht: %v", err)
+	}
+	// The sensor replies by pulling the line low for 80µs then high for 80µs.
+	for _, l := range []gpio.Level{gpio.Low, gpio.High, gpio.Low} {
+		if _, ok := d.waitFor(l); !ok {
+			return b, errors.New("dht: no reply from sensor")
+		}
+	}
+	// Each bit is 50µs low followed by a variable length high pulse. Comparing
+	// to the low pulse makes the decoding independent of the polling latency.
+	for i := 0; i < 40; i++ {
+		low, ok := d.waitFor(gpio.High)
+		if !ok {
+			return b, fmt.Errorf("dht: timed out on bit %d", i)
+		}
+		high, ok := d.waitFor(gpio.Low)
+		if !ok {
+			return b, fmt.Errorf("dht: timed out on bit %d", i)
+		}
+		if high > low {
+			b[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return b, nil
+}
+
+// waitFor busy polls the pin until it reaches the level l and returns how
+// long it took.
+func (d *Dev) waitFor(l gpio.Level) (time.Duration, bool) {
+	start := now()
+	for {
+		if d.p.Read() == l {
+			return now().Sub(start), true
+		}
+		if now().Sub(start) > edgeTimeout {
+			return 0, false
+		}
+	}
+}
+
+// decode validates the checksum and converts the 5 bytes reply.
+func (d *Dev) decode(b [5]byte, env *devices.Environment) error {
+	if b[0]+b[1]+b[2]+b[3] != b[4] {
+		return errors.New("dht: invalid checksum")
+	}
+	if d.opts.Model == DHT11 {
+		// Integral and decimal parts; the sign bit is in the decimal part of the
+		// temperature.
+		env.Humidity = devices.RelativeHumidity(int32(b[0])*100 + int32(b[1])*10)
+		t := int32(b[2])*1000 + int32(b[3]&0x7F)*100
+		if b[3]&0x80 != 0 {
+			t = -t
+		}
+		env.Temperature = devices.Celsius(t)
+		return nil
+	}
+	// Values in 0.1 unit, the temperature is sign and magnitude.
+	env.Humidity = devices.RelativeHumidity((int32(b[0])<<8 | int32(b[1])) * 10)
+	t := (int32(b[2]&0x7F)<<8 | int32(b[3])) * 100
+	if b[2]&0x80 != 0 {
+		t = -t
+	}
+	env.Temperature = devices.Celsius(t)
+	return nil
+}
+
+var defaults = Opts{
+	Model:   DHT22,
+	Retries: 2,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
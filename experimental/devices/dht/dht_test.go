@@ -0,0 +1,262 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package dht
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p := gpioreg.ByName("GPIO4")
+	if p == nil {
+		log.Fatal("failed to find GPIO4")
+	}
+	dev, err := New(p, &Opts{Model: DHT22, Retries: 3})
+	if err != nil {
+		log.Fatalf("failed to initialize dht: %v", err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%8s %9s\n", env.Temperature, env.Humidity)
+}
+
+//
+
+func TestSense_DHT22(t *testing.T) {
+	p := newFakePin([][5]byte{{0x02, 0x8C, 0x01, 0x5F, 0xEE}})
+	dev, err := New(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "DHT22{fake}" {
+		t.Fatal(s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Humidity != 6520 || env.Temperature != 35100 {
+		t.Fatalf("%#v", env)
+	}
+	if p.start != 1100*time.Microsecond {
+		t.Fatal(p.start)
+	}
+	// Negative temperature; the minimum interval is enforced.
+	p.replies = [][5]byte{{0x02, 0x8C, 0x80, 0x65, 0x73}}
+	before := p.clock
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Temperature != -10100 {
+		t.Fatal(env.Temperature)
+	}
+	if d := p.clock.Sub(before); d < 2*time.Second {
+		t.Fatal(d)
+	}
+}
+
+func TestSense_DHT11(t *testing.T) {
+	p := newFakePin([][5]byte{{45, 0, 23, 4, 72}, {45, 0, 1, 0x85, 0xB3}})
+	dev, err := New(p, &Opts{Model: DHT11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "DHT11{fake}" {
+		t.Fatal(s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Humidity != 4500 || env.Temperature != 23400 {
+		t.Fatalf("%#v", env)
+	}
+	if p.start != 20*time.Millisecond {
+		t.Fatal(p.start)
+	}
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Temperature != -1500 {
+		t.Fatal(env.Temperature)
+	}
+}
+
+func TestSense_retry(t *testing.T) {
+	// The first reply has a bad checksum, the second one doesn't reply at all.
+	p := newFakePin([][5]byte{{0x02, 0x8C, 0x01, 0x5F, 0x00}, {}, {0x02, 0x8C, 0x01, 0x5F, 0xEE}})
+	p.silent = 1
+	dev, err := New(p, &Opts{Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Humidity != 6520 {
+		t.Fatal(env.Humidity)
+	}
+	p = newFakePin([][5]byte{{0x02, 0x8C, 0x01, 0x5F, 0x00}})
+	dev, err = New(p, &Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("invalid checksum")
+	}
+	p = newFakePin([][5]byte{{}})
+	p.silent = 0
+	dev, err = New(p, &Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("no reply")
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	if _, err := New(newFakePin(nil), &Opts{Model: 2}); err == nil {
+		t.Fatal("invalid model")
+	}
+	if _, err := New(newFakePin(nil), &Opts{Retries: -1}); err == nil {
+		t.Fatal("invalid retries")
+	}
+	p := newFakePin(nil)
+	p.err = errors.New("injected")
+	if _, err := New(p, nil); err == nil {
+		t.Fatal("pin failure")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	dev, err := New(newFakePin(nil), &Opts{Model: DHT11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(500 * time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+}
+
+func TestModel_String(t *testing.T) {
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+// fakePin simulates the sensor's replies on a virtual clock that advances on
+// each read of the pin.
+type fakePin struct {
+	gpio.PinIO
+	replies [][5]byte
+	silent  int // Index of the reply that is never sent; -1 for none
+	err     error
+
+	clock    time.Time
+	lowSince time.Time
+	start    time.Duration
+	replyAt  time.Time
+	wave     []level
+}
+
+type level struct {
+	l gpio.Level
+	d time.Duration
+}
+
+func newFakePin(replies [][5]byte) *fakePin {
+	f := &fakePin{replies: replies, silent: -1, clock: time.Unix(1000, 0)}
+	now = func() time.Time { return f.clock }
+	sleep = func(d time.Duration) { f.clock = f.clock.Add(d) }
+	return f
+}
+
+func (f *fakePin) String() string {
+	return "fake"
+}
+
+func (f *fakePin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.lowSince.IsZero() {
+		return nil
+	}
+	f.start = f.clock.Sub(f.lowSince)
+	f.lowSince = time.Time{}
+	f.replyAt = f.clock
+	f.wave = nil
+	if len(f.replies) == 0 {
+		return nil
+	}
+	b := f.replies[0]
+	f.replies = f.replies[1:]
+	if f.silent == 0 {
+		f.silent--
+		return nil
+	}
+	f.silent--
+	f.wave = []level{{gpio.High, 30 * time.Microsecond}, {gpio.Low, 80 * time.Microsecond}, {gpio.High, 80 * time.Microsecond}}
+	for i := 0; i < 40; i++ {
+		d := 27 * time.Microsecond
+		if b[i/8]&(0x80>>uint(i%8)) != 0 {
+			d = 70 * time.Microsecond
+		}
+		f.wave = append(f.wave, level{gpio.Low, 50 * time.Microsecond}, level{gpio.High, d})
+	}
+	f.wave = append(f.wave, level{gpio.Low, 50 * time.Microsecond})
+	return nil
+}
+
+func (f *fakePin) Out(l gpio.Level) error {
+	if l == gpio.Low {
+		f.lowSince = f.clock
+	}
+	return nil
+}
+
+func (f *fakePin) Read() gpio.Level {
+	f.clock = f.clock.Add(2 * time.Microsecond)
+	t := f.clock.Sub(f.replyAt)
+	for _, w := range f.wave {
+		if t < w.d {
+			return w.l
+		}
+		t -= w.d
+	}
+	return gpio.High
+}
@@ -0,0 +1,390 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package dmx512 transmits DMX512 universes over an UART connected to a RS-485
+// line driver.
+//
+// A DMX512 packet is a break (line held low for at least 88µs), a mark after
+// break (line held high for at least 8µs), then up to 513 slots sent at 250
+// kbaud 8N2. The first slot is the start code, 0 for dimmer data.
+//
+// The break is generated by temporarily lowering the UART speed and sending a
+// zero byte, so the start bit and the 8 data bits stretch over the requested
+// break duration. When the UART cannot change speed on the fly, a GPIO pin
+// gating the line driver can be specified in Opts.BreakPin instead.
+//
+// Alternatively, NewStream() rasterizes the whole packet, break included, as
+// a bit stream sent over a gpiostream.PinOut. On a Raspberry Pi, the stream
+// is paced by DMA so no UART is needed.
+//
+// Datasheet
+//
+// http://tsp.esta.org/tsp/documents/docs/ANSI-ESTA_E1-11_2008R2018.pdf
+package dmx512
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiostream"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/host/cpu"
+)
+
+// Baud is the DMX512 line speed.
+const Baud = 250000
+
+// MaxChannels is the maximum number of channels (slots after the start code)
+// in a universe.
+const MaxChannels = 512
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Channels is the number of channels to transmit, up to MaxChannels. Sending
+	// less channels increases the maximum refresh rate. Defaults to MaxChannels.
+	Channels int
+	// StartCode is the first slot of each packet. It is 0 for dimmer data.
+	StartCode byte
+	// Break is the duration of the break. It must be at least 88µs. Defaults to
+	// 92µs.
+	Break time.Duration
+	// MAB is the mark after break duration. It must be at least 8µs. Defaults to
+	// 12µs. It is only used when BreakPin is set or with NewStream(), otherwise
+	// the stop bits of the break byte are used.
+	MAB time.Duration
+	// BreakPin, when set, is driven low for the break and high otherwise,
+	// instead of generating the break by changing the UART speed. It is ignored
+	// by NewStream().
+	BreakPin gpio.PinOut
+}
+
+// Dev is a handle to a DMX512 universe.
+type Dev struct {
+	c    uart.Conn         // nil when using a gpiostream.PinOut
+	p    gpiostream.PinOut // nil when using an UART
+	opts Opts
+
+	mu   sync.Mutex
+	buf  []byte                  // Start code followed by the channels.
+	bits gpiostream.BitStreamMSB // Rasterized packet; only used with a gpiostream.PinOut
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New opens a handle to a DMX512 universe over an UART.
+//
+// The UART is configured as 250 kbaud 8N2.
+func New(c uart.Conn, opts *Opts) (*Dev, error) {
+	d, err := newDev(opts)
+	if err != nil {
+		return nil, err
+	}
+	d.c = c
+	if d.opts.BreakPin != nil {
+		if err := d.opts.BreakPin.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("dmx512: %v", err)
+		}
+	}
+	if err := c.Configure(uart.Two, uart.None, 8); err != nil {
+		return nil, fmt.Errorf("dmx512: %v", err)
+	}
+	if err := c.Speed(Baud); err != nil {
+		return nil, fmt.Errorf("dmx512: %v", err)
+	}
+	return d, nil
+}
+
+// NewStream opens a handle to a DMX512 universe over a pin connected to a
+// RS-485 line driver.
+//
+// Each packet is rasterized at 4µs per bit, the duration of one bit at 250
+// kbaud, and sent with a single StreamOut() call. The break and the mark after
+// break are rounded up to a multiple of 4µs. The pin must idle high between
+// packets.
+func NewStream(p gpiostream.PinOut, opts *Opts) (*Dev, error) {
+	d, err := newDev(opts)
+	if err != nil {
+		return nil, err
+	}
+	d.p = p
+	n := d.breakBits() + d.mabBits() + 11*len(d.buf)
+	d.bits = gpiostream.BitStreamMSB{
+		Bits: make(gpiostream.BitsMSB, (n+7)/8),
+		Res:  time.Second / Baud,
+	}
+	return d, nil
+}
+
+func newDev(opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	d := &Dev{opts: *opts}
+	if d.opts.Channels == 0 {
+		d.opts.Channels = MaxChannels
+	}
+	if d.opts.Channels < 0 || d.opts.Channels > MaxChannels {
+		return nil, fmt.Errorf("dmx512: invalid number of channels %d", d.opts.Channels)
+	}
+	if d.opts.Break == 0 {
+		d.opts.Break = defaults.Break
+	}
+	if d.opts.Break < 88*time.Microsecond {
+		return nil, errors.New("dmx512: break must be at least 88µs")
+	}
+	if d.opts.MAB == 0 {
+		d.opts.MAB = defaults.MAB
+	}
+	if d.opts.MAB < 8*time.Microsecond {
+		return nil, errors.New("dmx512: mark after break must be at least 8µs")
+	}
+	d.buf = make([]byte, d.opts.Channels+1)
+	d.buf[0] = d.opts.StartCode
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	if d.c != nil {
+		return fmt.Sprintf("dmx512{%s}", d.c)
+	}
+	return fmt.Sprintf("dmx512{%s}", d.p)
+}
+
+// Channels returns the number of channels in the universe.
+func (d *Dev) Channels() int {
+	return d.opts.Channels
+}
+
+// Set sets the value of a channel in the frame buffer.
+//
+// Channels are numbered from 1 as is customary in lighting control. The value
+// is sent on the next Flush() or on the next refresh when Refresh() was
+// called.
+func (d *Dev) Set(channel int, v byte) error {
+	if channel < 1 || channel > d.opts.Channels {
+		return fmt.Errorf("dmx512: invalid channel %d", channel)
+	}
+	d.mu.Lock()
+	d.buf[channel] = v
+	d.mu.Unlock()
+	return nil
+}
+
+// Get returns the value of a channel in the frame buffer.
+func (d *Dev) Get(channel int) (byte, error) {
+	if channel < 1 || channel > d.opts.Channels {
+		return 0, fmt.Errorf("dmx512: invalid channel %d", channel)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.buf[channel], nil
+}
+
+// Write replaces the frame buffer starting at channel 1, implementing
+// io.Writer.
+//
+// Channels beyond len(b) are not modified. The values are sent on the next
+// Flush() or on the next refresh when Refresh() was called.
+func (d *Dev) Write(b []byte) (int, error) {
+	if len(b) > d.opts.Channels {
+		return 0, fmt.Errorf("dmx512: up to %d channels are supported", d.opts.Channels)
+	}
+	d.mu.Lock()
+	copy(d.buf[1:], b)
+	d.mu.Unlock()
+	return len(b), nil
+}
+
+// Flush sends one packet with the current frame buffer.
+//
+// It must not be called while Refresh() is active.
+func (d *Dev) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("dmx512: already refreshing continuously")
+	}
+	return d.send()
+}
+
+// Refresh sends the frame buffer continuously at the specified interval.
+//
+// DMX512 receivers generally expect a new packet at least once per second.
+// The minimum interval for a full universe is about 23ms. Call Halt() to stop
+// the refresh.
+func (d *Dev) Refresh(interval time.Duration) error {
+	if min := d.packetDuration(); interval < min {
+		return fmt.Errorf("dmx512: interval %s is lower than the packet duration %s", interval, min)
+	}
+	d.stopRefresh()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		d.refreshContinuous(interval, stop)
+	}(d.stop)
+	return nil
+}
+
+// Halt stops the continuous refresh if it was started and sends a blackout
+// packet with all the channels at 0.
+//
+// The frame buffer is not affected.
+func (d *Dev) Halt() error {
+	d.stopRefresh()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	orig := d.buf
+	d.buf = make([]byte, len(orig))
+	d.buf[0] = orig[0]
+	err := d.send()
+	d.buf = orig
+	return err
+}
+
+//
+
+// stopRefresh stops the refresh goroutine, if any.
+//
+// d.mu must not be held, since the goroutine grabs it.
+func (d *Dev) stopRefresh() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+// send sends the break and the packet.
+//
+// d.mu must be held.
+func (d *Dev) send() error {
+	if d.p != nil {
+		d.raster()
+		if err := d.p.StreamOut(&d.bits); err != nil {
+			return fmt.Errorf("dmx512: %v", err)
+		}
+		return nil
+	}
+	if err := d.sendBreak(); err != nil {
+		return err
+	}
+	if err := d.c.Tx(d.buf, nil); err != nil {
+		return fmt.Errorf("dmx512: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) sendBreak() error {
+	if d.opts.BreakPin != nil {
+		if err := d.opts.BreakPin.Out(gpio.Low); err != nil {
+			return fmt.Errorf("dmx512: %v", err)
+		}
+		cpu.Nanospin(d.opts.Break)
+		if err := d.opts.BreakPin.Out(gpio.High); err != nil {
+			return fmt.Errorf("dmx512: %v", err)
+		}
+		cpu.Nanospin(d.opts.MAB)
+		return nil
+	}
+	// The start bit and the 8 data bits of 0x00 are low, the stop bits form the
+	// mark after break.
+	if err := d.c.Speed(int(9 * time.Second / d.opts.Break)); err != nil {
+		return fmt.Errorf("dmx512: %v", err)
+	}
+	if err := d.c.Tx([]byte{0}, nil); err != nil {
+		return fmt.Errorf("dmx512: %v", err)
+	}
+	if err := d.c.Speed(Baud); err != nil {
+		return fmt.Errorf("dmx512: %v", err)
+	}
+	return nil
+}
+
+// raster encodes the break, the mark after break and the slots in 8N2 format
+// into d.bits.
+//
+// The padding at the end is high, as the line idles high.
+func (d *Dev) raster() {
+	b := d.bits.Bits
+	for i := range b {
+		b[i] = 0
+	}
+	i := d.breakBits()
+	mark := func(n int) {
+		for ; n > 0; n-- {
+			b[i/8] |= 0x80 >> uint(i&7)
+			i++
+		}
+	}
+	mark(d.mabBits())
+	for _, v := range d.buf {
+		// Start bit.
+		i++
+		// Data bits are sent LSB first.
+		for j := uint(0); j < 8; j++ {
+			if v&(1<<j) != 0 {
+				b[i/8] |= 0x80 >> uint(i&7)
+			}
+			i++
+		}
+		// Stop bits.
+		mark(2)
+	}
+	mark(8*len(b) - i)
+}
+
+// breakBits returns the number of bits of the break when rasterized.
+func (d *Dev) breakBits() int {
+	return int((d.opts.Break*Baud + time.Second - 1) / time.Second)
+}
+
+// mabBits returns the number of bits of the mark after break when rasterized.
+func (d *Dev) mabBits() int {
+	return int((d.opts.MAB*Baud + time.Second - 1) / time.Second)
+}
+
+// packetDuration returns the minimum time it takes to send one packet.
+func (d *Dev) packetDuration() time.Duration {
+	// Each slot is 11 bits: start, 8 data bits, 2 stop bits.
+	return d.opts.Break + d.opts.MAB + time.Duration(11*(d.opts.Channels+1))*time.Second/Baud
+}
+
+func (d *Dev) refreshContinuous(interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		d.mu.Lock()
+		err := d.send()
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to refresh: %v", d, err)
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+var defaults = Opts{
+	Channels: MaxChannels,
+	Break:    92 * time.Microsecond,
+	MAB:      12 * time.Microsecond,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,327 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package dmx512
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiostream"
+	"periph.io/x/periph/conn/gpio/gpiostream/gpiostreamtest"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := uartreg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open UART: %v", err)
+	}
+	defer p.Close()
+	d, err := New(p, &Opts{Channels: 3})
+	if err != nil {
+		log.Fatalf("failed to initialize dmx512: %v", err)
+	}
+	defer d.Halt()
+	// Set a RGB fixture at address 1 to orange.
+	if _, err := d.Write([]byte{0xFF, 0x80, 0x00}); err != nil {
+		log.Fatalf("failed to write: %v", err)
+	}
+	if err := d.Refresh(25 * time.Millisecond); err != nil {
+		log.Fatalf("failed to start refresh: %v", err)
+	}
+	time.Sleep(10 * time.Second)
+}
+
+//
+
+func TestNew(t *testing.T) {
+	u := &fakeUART{}
+	d, err := New(u, &Opts{Channels: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "dmx512{fake}" {
+		t.Fatal(s)
+	}
+	if c := d.Channels(); c != 4 {
+		t.Fatal(c)
+	}
+	if u.stop != uart.Two || u.parity != uart.None || u.bits != 8 || u.baud != Baud {
+		t.Fatalf("%#v", u)
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	data := []Opts{
+		{Channels: -1},
+		{Channels: MaxChannels + 1},
+		{Break: 10 * time.Microsecond},
+		{MAB: time.Microsecond},
+	}
+	for i, opts := range data {
+		if _, err := New(&fakeUART{}, &opts); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	if _, err := New(&fakeUART{err: errors.New("oops")}, nil); err == nil {
+		t.Fatal("expected failure")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	u := &fakeUART{}
+	d, err := New(u, &Opts{Channels: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set(1, 0xFF); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set(4, 0x80); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set(0, 1); err == nil {
+		t.Fatal("channel 0 is invalid")
+	}
+	if err := d.Set(5, 1); err == nil {
+		t.Fatal("channel 5 is invalid")
+	}
+	if v, err := d.Get(4); err != nil || v != 0x80 {
+		t.Fatal(v, err)
+	}
+	if _, err := d.Get(5); err == nil {
+		t.Fatal("channel 5 is invalid")
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{{0}, {0, 0xFF, 0, 0, 0x80}}
+	if !equal(u.writes, expected) {
+		t.Fatalf("%#v != %#v", u.writes, expected)
+	}
+	// 9 bits at 97826 baud is 92µs.
+	if expectedBauds := []int{Baud, 97826, Baud}; !equalInts(u.bauds, expectedBauds) {
+		t.Fatalf("%v != %v", u.bauds, expectedBauds)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	u := &fakeUART{}
+	d, err := New(u, &Opts{Channels: 3, StartCode: 0xCC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.Write([]byte{1, 2}); n != 2 || err != nil {
+		t.Fatal(n, err)
+	}
+	if n, err := d.Write([]byte{1, 2, 3, 4}); n != 0 || err == nil {
+		t.Fatal(n, err)
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(u.writes[1], []byte{0xCC, 1, 2, 0}) {
+		t.Fatalf("%#v", u.writes[1])
+	}
+}
+
+func TestBreakPin(t *testing.T) {
+	u := &fakeUART{}
+	p := &gpiotest.Pin{N: "break"}
+	d, err := New(u, &Opts{Channels: 1, BreakPin: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.L != gpio.High {
+		t.Fatal("pin must idle high")
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]byte{{0, 0}}; !equal(u.writes, expected) {
+		t.Fatalf("%#v != %#v", u.writes, expected)
+	}
+	if p.L != gpio.High {
+		t.Fatal("pin must idle high")
+	}
+}
+
+func TestNewStream(t *testing.T) {
+	// 23 bits of break, 3 bits of MAB, then the start code and one channel.
+	g := gpiostreamtest.PinOutPlayback{
+		N: "P1",
+		Ops: []gpiostream.Stream{
+			&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0x00, 0x00, 0x01, 0xC0, 0x1B, 0xFF}, Res: 4 * time.Microsecond},
+			&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0x00, 0x00, 0x01, 0xC0, 0x18, 0x03}, Res: 4 * time.Microsecond},
+		},
+	}
+	d, err := NewStream(&g, &Opts{Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "dmx512{P1}" {
+		t.Fatal(s)
+	}
+	if err := d.Set(1, 0xFF); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewStream_fail(t *testing.T) {
+	if _, err := NewStream(&gpiostreamtest.PinOutPlayback{}, &Opts{Break: time.Microsecond}); err == nil {
+		t.Fatal("break too short")
+	}
+	g := gpiostreamtest.PinOutPlayback{DontPanic: true}
+	d, err := NewStream(&g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flush(); err == nil {
+		t.Fatal("unexpected StreamOut")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	u := &fakeUART{}
+	d, err := New(u, &Opts{Channels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Refresh(time.Microsecond); err == nil {
+		t.Fatal("interval is too short")
+	}
+	if err := d.Set(2, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Refresh(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flush(); err == nil {
+		t.Fatal("Flush() must fail while refreshing")
+	}
+	for {
+		u.Lock()
+		l := len(u.writes)
+		u.Unlock()
+		if l >= 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(u.writes[1], []byte{0, 0, 42}) {
+		t.Fatalf("%#v", u.writes[1])
+	}
+	if last := u.writes[len(u.writes)-1]; !bytes.Equal(last, []byte{0, 0, 0}) {
+		t.Fatalf("Halt() must send a blackout: %#v", last)
+	}
+	// The frame buffer is unaffected.
+	if v, err := d.Get(2); err != nil || v != 42 {
+		t.Fatal(v, err)
+	}
+}
+
+func TestFlush_fail(t *testing.T) {
+	u := &fakeUART{}
+	d, err := New(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.err = errors.New("oops")
+	if err := d.Flush(); err == nil {
+		t.Fatal("expected failure")
+	}
+}
+
+//
+
+type fakeUART struct {
+	sync.Mutex
+	err    error
+	baud   int
+	bauds  []int
+	stop   uart.Stop
+	parity uart.Parity
+	bits   int
+	writes [][]byte
+}
+
+func (f *fakeUART) String() string {
+	return "fake"
+}
+
+func (f *fakeUART) Tx(w, r []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.writes = append(f.writes, append([]byte(nil), w...))
+	return nil
+}
+
+func (f *fakeUART) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeUART) Speed(baud int) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.baud = baud
+	f.bauds = append(f.bauds, baud)
+	return nil
+}
+
+func (f *fakeUART) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	f.Lock()
+	defer f.Unlock()
+	f.stop = stopBit
+	f.parity = parity
+	f.bits = bits
+	return f.err
+}
+
+func equal(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalInts(a, b []int) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
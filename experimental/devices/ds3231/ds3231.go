@@ -0,0 +1,414 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ds3231 controls a Maxim DS3231 real time clock over I²C.
+//
+// The time is stored in UTC. The two alarms assert the INT/SQW pin which can
+// be connected to a GPIO to wait for them with WaitForAlarm().
+//
+// Datasheet
+//
+// https://datasheets.maximintegrated.com/en/ds/DS3231.pdf
+package ds3231
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Alarm is a set of alarms.
+type Alarm uint8
+
+// The two alarms of the device.
+const (
+	Alarm1 Alarm = 1
+	Alarm2 Alarm = 2
+)
+
+func (a Alarm) String() string {
+	switch a {
+	case 0:
+		return "0"
+	case Alarm1:
+		return "Alarm1"
+	case Alarm2:
+		return "Alarm2"
+	case Alarm1 | Alarm2:
+		return "Alarm1|Alarm2"
+	default:
+		return fmt.Sprintf("Alarm(%d)", a)
+	}
+}
+
+// AlarmRate determines which fields of the alarm time must match the current
+// time for the alarm to fire.
+type AlarmRate uint8
+
+// Possible alarm rates.
+const (
+	// EverySecond is only supported by Alarm1.
+	EverySecond AlarmRate = 0
+	// EveryMinute matches the seconds; Alarm2 fires when the seconds are 0.
+	EveryMinute AlarmRate = 1
+	// Hourly matches the minutes and seconds.
+	Hourly AlarmRate = 2
+	// Daily matches the hours, minutes and seconds.
+	Daily AlarmRate = 3
+	// Weekly matches the day of the week and the time.
+	Weekly AlarmRate = 4
+	// Monthly matches the day of the month and the time.
+	Monthly AlarmRate = 5
+)
+
+const alarmRateName = "EverySecondEveryMinuteHourlyDailyWeeklyMonthly"
+
+var alarmRateIndex = [...]uint8{0, 11, 22, 28, 33, 39, 46}
+
+func (a AlarmRate) String() string {
+	if a >= AlarmRate(len(alarmRateIndex)-1) {
+		return fmt.Sprintf("AlarmRate(%d)", a)
+	}
+	return alarmRateName[alarmRateIndex[a]:alarmRateIndex[a+1]]
+}
+
+// SquareWave is the frequency of the square wave output on the INT/SQW pin.
+type SquareWave uint8
+
+// Possible square wave frequencies.
+//
+// SquareWaveOff makes the pin usable for the alarms interrupts.
+const (
+	SquareWaveOff  SquareWave = 0
+	SquareWave1Hz  SquareWave = 1
+	SquareWave1kHz SquareWave = 2
+	SquareWave4kHz SquareWave = 3
+	SquareWave8kHz SquareWave = 4
+)
+
+// Dev is a handle to a DS3231.
+type Dev struct {
+	c    conn.Conn
+	intr gpio.PinIn
+
+	mu sync.Mutex
+}
+
+// NewI2C returns an object that communicates over I²C to a DS3231.
+//
+// intr is optional and is the pin connected to INT/SQW, which must be pulled
+// up. It is needed for WaitForAlarm().
+func NewI2C(b i2c.Bus, intr gpio.PinIn) (*Dev, error) {
+	if intr == gpio.INVALID {
+		intr = nil
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: 0x68}, intr: intr}
+	// Verifies the device is present.
+	var s [1]byte
+	if err := d.readReg(regStatus, s[:]); err != nil {
+		return nil, err
+	}
+	if d.intr != nil {
+		if err := d.intr.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+			return nil, fmt.Errorf("ds3231: %v", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("DS3231{%s}", d.c)
+}
+
+// Now returns the current time in UTC.
+//
+// It returns an error if the oscillator was stopped since the time was last
+// set, e.g. because the backup battery is depleted, as the time is then
+// invalid.
+func (d *Dev) Now() (time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [7]byte
+	if err := d.readReg(regTime, b[:]); err != nil {
+		return time.Time{}, err
+	}
+	var s [1]byte
+	if err := d.readReg(regStatus, s[:]); err != nil {
+		return time.Time{}, err
+	}
+	if s[0]&statusOSF != 0 {
+		return time.Time{}, errors.New("ds3231: oscillator was stopped, the time must be set")
+	}
+	year := 2000 + int(fromBCD(b[6]))
+	if b[5]&0x80 != 0 {
+		year += 100
+	}
+	t := time.Date(year, time.Month(fromBCD(b[5]&0x1F)), int(fromBCD(b[4])), fromHour(b[2]), int(fromBCD(b[1])), int(fromBCD(b[0])), 0, time.UTC)
+	return t, nil
+}
+
+// Set sets the time, which is converted to UTC.
+//
+// The year must be between 2000 and 2199. The fractional second is truncated.
+func (d *Dev) Set(t time.Time) error {
+	t = t.UTC()
+	if t.Year() < 2000 || t.Year() > 2199 {
+		return fmt.Errorf("ds3231: year %d is out of range [2000, 2199]", t.Year())
+	}
+	month := toBCD(uint8(t.Month()))
+	if t.Year() >= 2100 {
+		month |= 0x80
+	}
+	b := []byte{
+		regTime,
+		toBCD(uint8(t.Second())),
+		toBCD(uint8(t.Minute())),
+		toBCD(uint8(t.Hour())),
+		uint8(t.Weekday()) + 1,
+		toBCD(uint8(t.Day())),
+		month,
+		toBCD(uint8(t.Year() % 100)),
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.write(b...); err != nil {
+		return err
+	}
+	return d.update(regStatus, statusOSF, 0)
+}
+
+// SetSystemClock sets the operating system clock to the time of the device.
+//
+// It generally requires root privileges and is only supported on linux.
+func (d *Dev) SetSystemClock() error {
+	t, err := d.Now()
+	if err != nil {
+		return err
+	}
+	if err := setSystemTime(t); err != nil {
+		return fmt.Errorf("ds3231: %v", err)
+	}
+	return nil
+}
+
+// Temperature returns the temperature measured by the device to compensate
+// the oscillator, with a resolution of 0.25°C.
+//
+// The measurement is updated every 64 seconds.
+func (d *Dev) Temperature() (devices.Celsius, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [2]byte
+	if err := d.readReg(regTemp, b[:]); err != nil {
+		return 0, err
+	}
+	raw := int16(uint16(b[0])<<8|uint16(b[1])) >> 6
+	return devices.Celsius(int32(raw) * 250), nil
+}
+
+// AgingOffset returns the aging offset register value.
+func (d *Dev) AgingOffset() (int8, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [1]byte
+	if err := d.readReg(regAging, b[:]); err != nil {
+		return 0, err
+	}
+	return int8(b[0]), nil
+}
+
+// SetAgingOffset adjusts the oscillator frequency; a positive value slows it
+// down. One step is roughly 0.1ppm at 25°C.
+func (d *Dev) SetAgingOffset(v int8) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(regAging, byte(v))
+}
+
+// SetAlarm sets and enables an alarm.
+//
+// Only the fields of t selected by r are used. The time is converted to UTC.
+// Alarm2 has no seconds field and always fires at 0 seconds.
+//
+// Enabling an alarm disables the square wave output.
+func (d *Dev) SetAlarm(a Alarm, t time.Time, r AlarmRate) error {
+	if a != Alarm1 && a != Alarm2 {
+		return fmt.Errorf("ds3231: invalid alarm %s", a)
+	}
+	if r > Monthly {
+		return fmt.Errorf("ds3231: invalid alarm rate %d", r)
+	}
+	if a == Alarm2 && r == EverySecond {
+		return errors.New("ds3231: Alarm2 doesn't support EverySecond")
+	}
+	t = t.UTC()
+	b := []byte{
+		toBCD(uint8(t.Second())),
+		toBCD(uint8(t.Minute())),
+		toBCD(uint8(t.Hour())),
+		toBCD(uint8(t.Day())),
+	}
+	if r == Weekly {
+		b[3] = alarmDay | (uint8(t.Weekday()) + 1)
+	}
+	// The number of fields to match.
+	n := int(r)
+	if r == Monthly {
+		n = int(Weekly)
+	}
+	for i := n; i < len(b); i++ {
+		b[i] |= alarmMask
+	}
+	reg := byte(regAlarm1)
+	if a == Alarm2 {
+		reg = regAlarm2
+		b = b[1:]
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.write(append([]byte{reg}, b...)...); err != nil {
+		return err
+	}
+	if err := d.update(regStatus, byte(a), 0); err != nil {
+		return err
+	}
+	return d.update(regControl, byte(a), controlINTCN|byte(a))
+}
+
+// DisableAlarm disables an alarm.
+func (d *Dev) DisableAlarm(a Alarm) error {
+	if a == 0 || a > Alarm1|Alarm2 {
+		return fmt.Errorf("ds3231: invalid alarm %s", a)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.update(regControl, byte(a), 0)
+}
+
+// Fired returns the alarms that fired since the last call and clears them,
+// which releases the INT/SQW pin.
+func (d *Dev) Fired() (Alarm, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var s [1]byte
+	if err := d.readReg(regStatus, s[:]); err != nil {
+		return 0, err
+	}
+	a := Alarm(s[0] & 0x03)
+	if a == 0 {
+		return 0, nil
+	}
+	return a, d.write(regStatus, s[0]&^0x03)
+}
+
+// WaitForAlarm waits for an alarm to fire and returns the alarms that fired.
+//
+// Returns 0 on timeout. Use -1 to wait indefinitely.
+func (d *Dev) WaitForAlarm(timeout time.Duration) (Alarm, error) {
+	if d.intr == nil {
+		return 0, errors.New("ds3231: INT/SQW pin is not connected")
+	}
+	// An alarm may have fired before the edge detection was set up.
+	if a, err := d.Fired(); a != 0 || err != nil {
+		return a, err
+	}
+	if !d.intr.WaitForEdge(timeout) {
+		return 0, nil
+	}
+	return d.Fired()
+}
+
+// SetSquareWave outputs a square wave on the INT/SQW pin.
+//
+// Using SquareWaveOff makes the pin usable for the alarms again.
+func (d *Dev) SetSquareWave(s SquareWave) error {
+	if s > SquareWave8kHz {
+		return fmt.Errorf("ds3231: invalid square wave %d", s)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s == SquareWaveOff {
+		return d.update(regControl, controlINTCN, controlINTCN)
+	}
+	return d.update(regControl, controlINTCN|controlRS, (byte(s)-1)<<3)
+}
+
+// Halt is a no-op; the clock and the alarms keep running on their own.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+//
+
+const (
+	regTime    = 0x00
+	regAlarm1  = 0x07
+	regAlarm2  = 0x0B
+	regControl = 0x0E
+	regStatus  = 0x0F
+	regAging   = 0x10
+	regTemp    = 0x11
+
+	controlINTCN = 0x04
+	controlRS    = 0x18
+
+	statusOSF = 0x80
+
+	alarmMask = 0x80
+	alarmDay  = 0x40
+)
+
+func (d *Dev) readReg(reg uint8, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("ds3231: %v", err)
+	}
+	return nil
+}
+
+// write writes the register address followed by the values.
+func (d *Dev) write(b ...byte) error {
+	if err := d.c.Tx(b, nil); err != nil {
+		return fmt.Errorf("ds3231: %v", err)
+	}
+	return nil
+}
+
+// update replaces the bits in mask of a register with the ones of value.
+func (d *Dev) update(reg, mask, value byte) error {
+	var b [1]byte
+	if err := d.readReg(reg, b[:]); err != nil {
+		return err
+	}
+	return d.write(reg, b[0]&^mask|value&mask)
+}
+
+func toBCD(v uint8) uint8 {
+	return v/10<<4 | v%10
+}
+
+func fromBCD(v uint8) uint8 {
+	return v>>4*10 + v&0x0F
+}
+
+// fromHour decodes the hour register, which may be in 12 hours mode if set by
+// another software.
+func fromHour(v uint8) int {
+	if v&0x40 == 0 {
+		return int(fromBCD(v & 0x3F))
+	}
+	h := int(fromBCD(v&0x1F)) % 12
+	if v&0x20 != 0 {
+		h += 12
+	}
+	return h
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
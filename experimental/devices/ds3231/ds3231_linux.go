@@ -0,0 +1,16 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds3231
+
+import (
+	"syscall"
+	"time"
+)
+
+// setSystemTime is overridden in unit tests.
+var setSystemTime = func(t time.Time) error {
+	tv := syscall.NsecToTimeval(t.UnixNano())
+	return syscall.Settimeofday(&tv)
+}
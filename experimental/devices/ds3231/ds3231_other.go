@@ -0,0 +1,17 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !linux
+
+package ds3231
+
+import (
+	"errors"
+	"time"
+)
+
+// setSystemTime is overridden in unit tests.
+var setSystemTime = func(t time.Time) error {
+	return errors.New("setting the system clock is not supported on this OS")
+}
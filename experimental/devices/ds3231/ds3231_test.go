@@ -0,0 +1,415 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds3231
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, gpioreg.ByName("GPIO17"))
+	if err != nil {
+		log.Fatalf("failed to initialize ds3231: %v", err)
+	}
+	if err := dev.SetSystemClock(); err != nil {
+		log.Fatal(err)
+	}
+	// Wake up every day at 7:30 UTC.
+	if err := dev.SetAlarm(Alarm1, time.Date(2000, 1, 1, 7, 30, 0, 0, time.UTC), Daily); err != nil {
+		log.Fatal(err)
+	}
+	for {
+		if _, err := dev.WaitForAlarm(-1); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Good morning!")
+	}
+}
+
+//
+
+func TestNewI2C_Time(t *testing.T) {
+	f := newFakeBus()
+	// Lost power.
+	f.regs[0x0F] = 0x88
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "DS3231{fake(104)}" {
+		t.Fatal(s)
+	}
+	if _, err := dev.Now(); err == nil {
+		t.Fatal("oscillator stopped")
+	}
+	now := time.Date(2021, 3, 14, 15, 9, 26, 500, time.FixedZone("", 3600))
+	if err := dev.Set(now); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x00: 0x26, 0x01: 0x09, 0x02: 0x14, 0x03: 0x01, 0x04: 0x14, 0x05: 0x03, 0x06: 0x21, 0x0F: 0x08})
+	got, err := dev.Now()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2021, 3, 14, 14, 9, 26, 0, time.UTC); !got.Equal(expected) || got.Location() != time.UTC {
+		t.Fatal(got)
+	}
+	if err := dev.Set(time.Date(2150, 12, 31, 23, 59, 59, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x02: 0x23, 0x05: 0x92, 0x06: 0x50})
+	if got, err = dev.Now(); err != nil || got.Year() != 2150 {
+		t.Fatal(got, err)
+	}
+	// Set in 12 hours mode by another software.
+	for hour, reg := range map[int]byte{0: 0x52, 11: 0x51, 12: 0x72, 15: 0x63} {
+		f.regs[0x02] = reg
+		if got, err = dev.Now(); err != nil || got.Hour() != hour {
+			t.Fatal(hour, got, err)
+		}
+	}
+	if err := dev.Set(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)); err == nil {
+		t.Fatal("year out of range")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetSystemClock(t *testing.T) {
+	defer func() {
+		setSystemTime = setSystemTimeOrig
+	}()
+	var got time.Time
+	setSystemTime = func(t time.Time) error {
+		got = t
+		return nil
+	}
+	f := newFakeBus()
+	dev, err := NewI2C(f, gpio.INVALID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)
+	if err := dev.Set(now); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetSystemClock(); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(now) {
+		t.Fatal(got)
+	}
+	setSystemTime = func(t time.Time) error {
+		return errors.New("oops")
+	}
+	if err := dev.SetSystemClock(); err == nil {
+		t.Fatal("set failed")
+	}
+	f.regs[0x0F] = 0x80
+	if err := dev.SetSystemClock(); err == nil {
+		t.Fatal("oscillator stopped")
+	}
+}
+
+func TestTemperature_AgingOffset(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.regs[0x11] = 0x19
+	f.regs[0x12] = 0x40
+	if c, err := dev.Temperature(); c != 25250 || err != nil {
+		t.Fatal(c, err)
+	}
+	f.regs[0x11] = 0xF5
+	f.regs[0x12] = 0x80
+	if c, err := dev.Temperature(); c != -10500 || err != nil {
+		t.Fatal(c, err)
+	}
+	if err := dev.SetAgingOffset(-3); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x10: 0xFD})
+	if v, err := dev.AgingOffset(); v != -3 || err != nil {
+		t.Fatal(v, err)
+	}
+}
+
+func TestAlarms(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2021, 3, 14, 7, 30, 15, 0, time.UTC)
+	data := []struct {
+		a        Alarm
+		r        AlarmRate
+		expected map[byte]byte
+	}{
+		{Alarm1, EverySecond, map[byte]byte{0x07: 0x95, 0x08: 0xB0, 0x09: 0x87, 0x0A: 0x94, 0x0E: 0x1D}},
+		{Alarm1, EveryMinute, map[byte]byte{0x07: 0x15, 0x08: 0xB0, 0x09: 0x87, 0x0A: 0x94}},
+		{Alarm1, Daily, map[byte]byte{0x07: 0x15, 0x08: 0x30, 0x09: 0x07, 0x0A: 0x94}},
+		{Alarm1, Monthly, map[byte]byte{0x07: 0x15, 0x08: 0x30, 0x09: 0x07, 0x0A: 0x14}},
+		{Alarm2, EveryMinute, map[byte]byte{0x0B: 0xB0, 0x0C: 0x87, 0x0D: 0x94, 0x0E: 0x1F}},
+		{Alarm2, Hourly, map[byte]byte{0x0B: 0x30, 0x0C: 0x87, 0x0D: 0x94}},
+		{Alarm2, Weekly, map[byte]byte{0x0B: 0x30, 0x0C: 0x07, 0x0D: 0x41}},
+	}
+	for i, line := range data {
+		// The flag is cleared when the alarm is set.
+		f.regs[0x0F] = 0x03
+		if err := dev.SetAlarm(line.a, at, line.r); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if f.regs[0x0F]&byte(line.a) != 0 {
+			t.Fatalf("#%d: flag not cleared", i)
+		}
+		f.check(t, line.expected)
+	}
+	f.regs[0x0F] = 0x0A
+	if a, err := dev.Fired(); a != Alarm2 || err != nil {
+		t.Fatal(a, err)
+	}
+	f.check(t, map[byte]byte{0x0F: 0x08})
+	if a, err := dev.Fired(); a != 0 || err != nil {
+		t.Fatal(a, err)
+	}
+	if err := dev.DisableAlarm(Alarm1); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x0E: 0x1E})
+	if err := dev.SetAlarm(Alarm2, at, EverySecond); err == nil {
+		t.Fatal("Alarm2 doesn't support seconds")
+	}
+	if err := dev.SetAlarm(Alarm1|Alarm2, at, Daily); err == nil {
+		t.Fatal("invalid alarm")
+	}
+	if err := dev.SetAlarm(Alarm1, at, 6); err == nil {
+		t.Fatal("invalid rate")
+	}
+	if err := dev.DisableAlarm(0); err == nil {
+		t.Fatal("invalid alarm")
+	}
+	if _, err := dev.WaitForAlarm(time.Millisecond); err == nil {
+		t.Fatal("no pin")
+	}
+}
+
+func TestWaitForAlarm(t *testing.T) {
+	f := newFakeBus()
+	p := &alarmPin{Pin: gpiotest.Pin{EdgesChan: make(chan gpio.Level)}, f: f}
+	dev, err := NewI2C(f, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Pull() != gpio.PullUp {
+		t.Fatal(p.Pull())
+	}
+	if a, err := dev.WaitForAlarm(time.Millisecond); a != 0 || err != nil {
+		t.Fatal(a, err)
+	}
+	p.fire = byte(Alarm1)
+	if a, err := dev.WaitForAlarm(-1); a != Alarm1 || err != nil {
+		t.Fatal(a, err)
+	}
+	// Already fired.
+	p.fire = 0
+	f.regs[0x0F] = 0x03
+	if a, err := dev.WaitForAlarm(-1); a != Alarm1|Alarm2 || err != nil {
+		t.Fatal(a, err)
+	}
+	if f.regs[0x0F] != 0 {
+		t.Fatal("flags not cleared")
+	}
+}
+
+func TestSetSquareWave(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.regs[0x0E] = 0x1F
+	if err := dev.SetSquareWave(SquareWave1kHz); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x0E: 0x0B})
+	if err := dev.SetSquareWave(SquareWaveOff); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[byte]byte{0x0E: 0x0F})
+	if err := dev.SetSquareWave(5); err == nil {
+		t.Fatal("invalid frequency")
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := NewI2C(newFakeBus(), &gpiotest.Pin{}); err == nil {
+		t.Fatal("no edge detection")
+	}
+	bus := i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x68, W: []byte{0x0F}, R: []byte{0x00}}},
+		DontPanic: true,
+	}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2021, 3, 14, 7, 30, 15, 0, time.UTC)
+	if _, err := dev.Now(); err == nil {
+		t.Fatal("read failed")
+	}
+	if err := dev.Set(at); err == nil {
+		t.Fatal("write failed")
+	}
+	if _, err := dev.Temperature(); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.AgingOffset(); err == nil {
+		t.Fatal("read failed")
+	}
+	if err := dev.SetAlarm(Alarm1, at, Daily); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.SetSquareWave(SquareWaveOff); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Fired(); err == nil {
+		t.Fatal("read failed")
+	}
+	bus = i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x68, W: []byte{0x0F}, R: []byte{0x00}},
+			{Addr: 0x68, W: []byte{0x00}, R: make([]byte, 7)},
+		},
+		DontPanic: true,
+	}
+	if dev, err = NewI2C(&bus, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Now(); err == nil {
+		t.Fatal("read failed")
+	}
+	bus = i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x68, W: []byte{0x0F}, R: []byte{0x00}},
+			{Addr: 0x68, W: []byte{0x07, 0x15, 0x30, 0x07, 0x94}},
+		},
+		DontPanic: true,
+	}
+	if dev, err = NewI2C(&bus, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetAlarm(Alarm1, at, Daily); err == nil {
+		t.Fatal("read failed")
+	}
+}
+
+func TestStrings(t *testing.T) {
+	data := []struct {
+		s        fmt.Stringer
+		expected string
+	}{
+		{Alarm(0), "0"},
+		{Alarm1, "Alarm1"},
+		{Alarm2, "Alarm2"},
+		{Alarm1 | Alarm2, "Alarm1|Alarm2"},
+		{Alarm(4), "Alarm(4)"},
+		{EveryMinute, "EveryMinute"},
+		{Monthly, "Monthly"},
+		{AlarmRate(6), "AlarmRate(6)"},
+	}
+	for i, line := range data {
+		if s := line.s.String(); s != line.expected {
+			t.Fatalf("#%d: %s != %s", i, s, line.expected)
+		}
+	}
+}
+
+//
+
+var setSystemTimeOrig = setSystemTime
+
+// fakeBus emulates the register map with the register address auto increment.
+type fakeBus struct {
+	regs [0x13]byte
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{}
+	// Power on values.
+	f.regs[0x0E] = 0x1C
+	f.regs[0x0F] = 0x08
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	if addr != 0x68 {
+		return fmt.Errorf("no device at %#x", addr)
+	}
+	reg := int(w[0])
+	for i, v := range w[1:] {
+		f.regs[reg+i] = v
+	}
+	for i := range r {
+		r[i] = f.regs[reg+i]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) check(t *testing.T, expected map[byte]byte) {
+	for reg, v := range expected {
+		if r := f.regs[reg]; r != v {
+			t.Fatalf("%#x: %#x != %#x", reg, r, v)
+		}
+	}
+}
+
+// alarmPin fires the alarms in fire when WaitForEdge() is called.
+type alarmPin struct {
+	gpiotest.Pin
+	f    *fakeBus
+	fire byte
+}
+
+func (a *alarmPin) WaitForEdge(timeout time.Duration) bool {
+	if a.fire == 0 {
+		return false
+	}
+	a.f.regs[0x0F] |= a.fire
+	return true
+}
@@ -0,0 +1,451 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package hd44780 controls a Hitachi HD44780 compatible character LCD.
+//
+// The display can be wired directly to GPIO pins in 4 bits mode or through
+// the ubiquitous PCF8574 I²C backpack. The R/W pin is not used and must be
+// tied to ground; delays are used instead of polling the busy flag.
+//
+// Datasheet
+//
+// https://www.sparkfun.com/datasheets/LCD/HD44780.pdf
+//
+// http://www.ti.com/lit/ds/symlink/pcf8574.pdf
+package hd44780
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Cursor is the cursor appearance.
+type Cursor uint8
+
+// Possible cursor appearances.
+const (
+	CursorHidden    Cursor = 0
+	CursorUnderline Cursor = 2
+	CursorBlink     Cursor = 1
+	CursorBoth      Cursor = 3
+)
+
+// Opts is optional options to pass to the constructors.
+type Opts struct {
+	// Cols and Rows are the size of the display in characters. The default is
+	// 16x2.
+	Cols int
+	Rows int
+	// Backlight is the pin controlling the backlight. It is only used by
+	// NewGPIO() since the PCF8574 backpack controls the backlight itself.
+	Backlight gpio.PinOut
+}
+
+// Dev is a handle to a HD44780 character LCD.
+type Dev struct {
+	mu        sync.Mutex
+	w         writer
+	cols      int
+	rows      int
+	col       int
+	row       int
+	control   byte // Display on/off and cursor state
+	backlight gpio.PinOut
+}
+
+// NewGPIO returns an object that communicates with a HD44780 wired in 4 bits
+// mode to GPIO pins.
+//
+// rs is the register select pin, e is the enable pin and d4 to d7 are the
+// upper data lines.
+func NewGPIO(rs, e, d4, d5, d6, d7 gpio.PinOut, opts *Opts) (*Dev, error) {
+	w := &gpioWriter{rs: rs, e: e, d: [4]gpio.PinOut{d4, d5, d6, d7}}
+	for _, p := range []gpio.PinOut{rs, e, d4, d5, d6, d7} {
+		if p == nil {
+			return nil, errors.New("hd44780: all the pins must be specified")
+		}
+		if err := p.Out(gpio.Low); err != nil {
+			return nil, fmt.Errorf("hd44780: %v", err)
+		}
+	}
+	d, err := newDev(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.Backlight != nil {
+		d.backlight = opts.Backlight
+		if err := d.backlight.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("hd44780: %v", err)
+		}
+	}
+	return d, nil
+}
+
+// NewPCF8574 returns an object that communicates with a HD44780 through a
+// PCF8574 I²C backpack.
+//
+// The address is between 0x20 and 0x27 for the PCF8574 and between 0x38 and
+// 0x3F for the PCF8574A; 0x27 and 0x3F are the most common. The expected
+// wiring is P0: RS, P1: R/W, P2: E, P3: backlight, P4~P7: D4~D7.
+func NewPCF8574(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if (addr < 0x20 || addr > 0x27) && (addr < 0x38 || addr > 0x3F) {
+		return nil, errors.New("hd44780: given address not supported by device")
+	}
+	return newDev(&i2cWriter{c: &i2c.Dev{Bus: b, Addr: addr}, backlight: pcfBacklight}, opts)
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("HD44780{%s}", d.w)
+}
+
+// Cols implements devices.TextDisplay.
+func (d *Dev) Cols() int {
+	return d.cols
+}
+
+// Rows implements devices.TextDisplay.
+func (d *Dev) Rows() int {
+	return d.rows
+}
+
+// Write implements devices.TextDisplay.
+//
+// '\n' moves the cursor to the beginning of the next row. The bytes 0 to 7
+// display the custom characters defined with DefineChar().
+func (d *Dev) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, c := range p {
+		if c == '\n' {
+			if err := d.moveTo(0, (d.row+1)%d.rows); err != nil {
+				return i, err
+			}
+			continue
+		}
+		if err := d.w.send(c, true); err != nil {
+			return i, err
+		}
+		if d.col++; d.col == d.cols {
+			if err := d.moveTo(0, (d.row+1)%d.rows); err != nil {
+				return i + 1, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Clear implements devices.TextDisplay.
+func (d *Dev) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.col, d.row = 0, 0
+	return d.command(cmdClear, slowDelay)
+}
+
+// Home moves the cursor to the top left corner without clearing the display.
+func (d *Dev) Home() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.col, d.row = 0, 0
+	return d.command(cmdHome, slowDelay)
+}
+
+// MoveTo implements devices.TextDisplay.
+func (d *Dev) MoveTo(col, row int) error {
+	if col < 0 || col >= d.cols || row < 0 || row >= d.rows {
+		return fmt.Errorf("hd44780: position (%d, %d) is out of range", col, row)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.moveTo(col, row)
+}
+
+// SetCursor changes the cursor appearance.
+func (d *Dev) SetCursor(c Cursor) error {
+	if c > CursorBoth {
+		return fmt.Errorf("hd44780: invalid cursor %d", c)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setControl(d.control&^byte(CursorBoth) | byte(c))
+}
+
+// SetDisplay turns the display on or off. The content is kept while the
+// display is off.
+func (d *Dev) SetDisplay(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if on {
+		return d.setControl(d.control | ctrlDisplayOn)
+	}
+	return d.setControl(d.control &^ ctrlDisplayOn)
+}
+
+// SetBacklight turns the backlight on or off.
+//
+// When using NewGPIO(), Opts.Backlight must have been specified.
+func (d *Dev) SetBacklight(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.backlight != nil {
+		l := gpio.Low
+		if on {
+			l = gpio.High
+		}
+		if err := d.backlight.Out(l); err != nil {
+			return fmt.Errorf("hd44780: %v", err)
+		}
+		return nil
+	}
+	return d.w.setBacklight(on)
+}
+
+// DefineChar defines one of the 8 custom characters, identified by the bytes
+// 0 to 7.
+//
+// Each byte of bitmap is one row of 5 pixels, the least significant bit is
+// the rightmost pixel. The last row is normally left blank for the cursor.
+func (d *Dev) DefineChar(n int, bitmap [8]byte) error {
+	if n < 0 || n > 7 {
+		return fmt.Errorf("hd44780: invalid custom character %d", n)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.command(cmdSetCGRAM|byte(n<<3), fastDelay); err != nil {
+		return err
+	}
+	for _, b := range bitmap {
+		if err := d.w.send(b&0x1F, true); err != nil {
+			return err
+		}
+	}
+	// Return to DDRAM at the current cursor position.
+	return d.moveTo(d.col, d.row)
+}
+
+// Halt implements conn.Resource.
+//
+// It clears the display and turns it off.
+func (d *Dev) Halt() error {
+	if err := d.Clear(); err != nil {
+		return err
+	}
+	return d.SetDisplay(false)
+}
+
+//
+
+const (
+	cmdClear       = 0x01
+	cmdHome        = 0x02
+	cmdEntryMode   = 0x04
+	cmdControl     = 0x08
+	cmdFunctionSet = 0x20
+	cmdSetCGRAM    = 0x40
+	cmdSetDDRAM    = 0x80
+
+	entryIncrement = 0x02
+	ctrlDisplayOn  = 0x04
+	funcTwoLines   = 0x08
+
+	// Commands take 37µs except clear and home which take 1.52ms; page 24.
+	fastDelay = 50 * time.Microsecond
+	slowDelay = 2 * time.Millisecond
+)
+
+// writer abstracts how the nibbles are sent to the display.
+type writer interface {
+	fmt.Stringer
+	// send4 sends the 4 lower bits of v as a single nibble to the instruction
+	// register. It is only used during the initialization.
+	send4(v byte) error
+	// send sends a byte as two nibbles to the data register if rs is true,
+	// otherwise to the instruction register.
+	send(v byte, rs bool) error
+	setBacklight(on bool) error
+}
+
+func newDev(w writer, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	cols, rows := opts.Cols, opts.Rows
+	if cols == 0 && rows == 0 {
+		cols, rows = defaults.Cols, defaults.Rows
+	}
+	if cols < 1 || cols > 40 || rows < 1 || rows > 4 || cols*rows > 80 {
+		return nil, fmt.Errorf("hd44780: invalid size %dx%d", cols, rows)
+	}
+	d := &Dev{w: w, cols: cols, rows: rows}
+	// Initialization by instruction to set 4 bits mode whatever the current
+	// state is; figure 24.
+	time.Sleep(50 * time.Millisecond)
+	for _, v := range []byte{0x03, 0x03, 0x03, 0x02} {
+		if err := w.send4(v); err != nil {
+			return nil, err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	f := byte(cmdFunctionSet)
+	if rows > 1 {
+		f |= funcTwoLines
+	}
+	if err := d.command(f, fastDelay); err != nil {
+		return nil, err
+	}
+	if err := d.setControl(ctrlDisplayOn); err != nil {
+		return nil, err
+	}
+	if err := d.command(cmdClear, slowDelay); err != nil {
+		return nil, err
+	}
+	if err := d.command(cmdEntryMode|entryIncrement, fastDelay); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) command(c byte, wait time.Duration) error {
+	if err := d.w.send(c, false); err != nil {
+		return err
+	}
+	time.Sleep(wait)
+	return nil
+}
+
+func (d *Dev) setControl(c byte) error {
+	if err := d.command(cmdControl|c, fastDelay); err != nil {
+		return err
+	}
+	d.control = c
+	return nil
+}
+
+func (d *Dev) moveTo(col, row int) error {
+	// Rows 2 and 3 are the continuation of rows 0 and 1 in DDRAM.
+	offset := [...]int{0x00, 0x40, d.cols, 0x40 + d.cols}
+	if err := d.command(cmdSetDDRAM|byte(offset[row]+col), fastDelay); err != nil {
+		return err
+	}
+	d.col, d.row = col, row
+	return nil
+}
+
+// gpioWriter drives the display directly through GPIO pins.
+type gpioWriter struct {
+	rs gpio.PinOut
+	e  gpio.PinOut
+	d  [4]gpio.PinOut
+}
+
+func (g *gpioWriter) String() string {
+	return fmt.Sprintf("rs:%s, e:%s, d4:%s, d5:%s, d6:%s, d7:%s", g.rs, g.e, g.d[0], g.d[1], g.d[2], g.d[3])
+}
+
+func (g *gpioWriter) send4(v byte) error {
+	return g.nibble(v, gpio.Low)
+}
+
+func (g *gpioWriter) send(v byte, rs bool) error {
+	l := gpio.Low
+	if rs {
+		l = gpio.High
+	}
+	if err := g.nibble(v>>4, l); err != nil {
+		return err
+	}
+	return g.nibble(v, l)
+}
+
+func (g *gpioWriter) setBacklight(on bool) error {
+	return errors.New("hd44780: no backlight pin specified")
+}
+
+func (g *gpioWriter) nibble(v byte, rs gpio.Level) error {
+	if err := g.rs.Out(rs); err != nil {
+		return fmt.Errorf("hd44780: %v", err)
+	}
+	for i, p := range g.d {
+		if err := p.Out(gpio.Level(v&(1<<uint(i)) != 0)); err != nil {
+			return fmt.Errorf("hd44780: %v", err)
+		}
+	}
+	// The enable pulse must be at least 450ns wide and the data is latched on
+	// the falling edge.
+	if err := g.e.Out(gpio.High); err != nil {
+		return fmt.Errorf("hd44780: %v", err)
+	}
+	time.Sleep(time.Microsecond)
+	if err := g.e.Out(gpio.Low); err != nil {
+		return fmt.Errorf("hd44780: %v", err)
+	}
+	return nil
+}
+
+// PCF8574 backpack bits.
+const (
+	pcfRS        = 0x01
+	pcfE         = 0x04
+	pcfBacklight = 0x08
+)
+
+// i2cWriter drives the display through a PCF8574 I/O expander.
+type i2cWriter struct {
+	c         conn.Conn
+	backlight byte
+}
+
+func (i *i2cWriter) String() string {
+	return fmt.Sprintf("%s", i.c)
+}
+
+func (i *i2cWriter) send4(v byte) error {
+	return i.write(i.pulse(nil, v<<4|i.backlight))
+}
+
+func (i *i2cWriter) send(v byte, rs bool) error {
+	b := i.backlight
+	if rs {
+		b |= pcfRS
+	}
+	// Each byte written is latched on the output pins so both nibbles and
+	// their enable pulses are sent in a single transaction.
+	return i.write(i.pulse(i.pulse(nil, v&0xF0|b), v<<4|b))
+}
+
+func (i *i2cWriter) setBacklight(on bool) error {
+	if on {
+		i.backlight = pcfBacklight
+	} else {
+		i.backlight = 0
+	}
+	return i.write([]byte{i.backlight})
+}
+
+func (i *i2cWriter) pulse(b []byte, v byte) []byte {
+	return append(b, v|pcfE, v)
+}
+
+func (i *i2cWriter) write(b []byte) error {
+	if err := i.c.Tx(b, nil); err != nil {
+		return fmt.Errorf("hd44780: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	Cols: 16,
+	Rows: 2,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.TextDisplay = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,279 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hd44780
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewPCF8574(bus, 0x27, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize hd44780: %v", err)
+	}
+	// A heart.
+	if err := dev.DefineChar(0, [8]byte{0x00, 0x0A, 0x1F, 0x1F, 0x0E, 0x04, 0x00, 0x00}); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(dev, "Hello \x00 periph\nline 2")
+}
+
+//
+
+func TestNewPCF8574(t *testing.T) {
+	bus := i2ctest.Record{}
+	dev, err := NewPCF8574(&bus, 0x27, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "HD44780{record(39)}" {
+		t.Fatal(s)
+	}
+	if dev.Cols() != 16 || dev.Rows() != 2 {
+		t.Fatal(dev.Cols(), dev.Rows())
+	}
+	// The 4 initialization nibbles.
+	for i, n := range []byte{0x30, 0x30, 0x30, 0x20} {
+		if w := bus.Ops[i].W; !bytes.Equal(w, []byte{n | 0x0C, n | 0x08}) {
+			t.Fatalf("#%d: %#v", i, w)
+		}
+	}
+	expected := []cmd{{0x28, false}, {0x0C, false}, {0x01, false}, {0x06, false}}
+	checkCmds(t, decodeI2C(t, bus.Ops[4:]), expected)
+
+	bus.Ops = nil
+	if _, err := fmt.Fprintf(dev, "0123456789ABCDEFx\ny"); err != nil {
+		t.Fatal(err)
+	}
+	expected = nil
+	for _, c := range []byte("0123456789ABCDEF") {
+		expected = append(expected, cmd{c, true})
+	}
+	expected = append(expected, cmd{0xC0, false}, cmd{'x', true}, cmd{0x80, false}, cmd{'y', true})
+	checkCmds(t, decodeI2C(t, bus.Ops), expected)
+
+	bus.Ops = nil
+	if err := dev.DefineChar(1, [8]byte{0xFF, 1, 2, 3, 4, 5, 6, 7}); err != nil {
+		t.Fatal(err)
+	}
+	expected = []cmd{{0x48, false}, {0x1F, true}, {1, true}, {2, true}, {3, true}, {4, true}, {5, true}, {6, true}, {7, true}, {0x81, false}}
+	checkCmds(t, decodeI2C(t, bus.Ops), expected)
+
+	bus.Ops = nil
+	if err := dev.SetCursor(CursorBoth); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(15, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Home(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	expected = []cmd{{0x0F, false}, {0xCF, false}, {0x02, false}, {0x01, false}, {0x0B, false}}
+	checkCmds(t, decodeI2C(t, bus.Ops), expected)
+
+	bus.Ops = nil
+	if err := dev.SetBacklight(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetDisplay(true); err != nil {
+		t.Fatal(err)
+	}
+	if w := bus.Ops[0].W; !bytes.Equal(w, []byte{0x00}) {
+		t.Fatalf("%#v", w)
+	}
+	// The backlight bit is now cleared.
+	if w := bus.Ops[1].W; !bytes.Equal(w, []byte{0x04, 0x00, 0xF4, 0xF0}) {
+		t.Fatalf("%#v", w)
+	}
+}
+
+func TestNewPCF8574_fail(t *testing.T) {
+	if _, err := NewPCF8574(&i2ctest.Record{}, 0x30, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewPCF8574(&i2ctest.Record{}, 0x3F, &Opts{Cols: 40, Rows: 4}); err == nil {
+		t.Fatal("invalid size")
+	}
+	if _, err := NewPCF8574(&i2ctest.Playback{DontPanic: true}, 0x27, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	dev, err := NewPCF8574(&i2ctest.Record{}, 0x27, &Opts{Cols: 20, Rows: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(20, 0); err == nil {
+		t.Fatal("invalid column")
+	}
+	if err := dev.MoveTo(0, 4); err == nil {
+		t.Fatal("invalid row")
+	}
+	if err := dev.SetCursor(4); err == nil {
+		t.Fatal("invalid cursor")
+	}
+	if err := dev.DefineChar(8, [8]byte{}); err == nil {
+		t.Fatal("invalid character")
+	}
+}
+
+func TestNewGPIO(t *testing.T) {
+	l := &pinLog{}
+	rs := &logPin{Pin: gpiotest.Pin{N: "RS"}, log: l}
+	e := &logPin{Pin: gpiotest.Pin{N: "E"}, log: l}
+	var d [4]*logPin
+	for i := range d {
+		d[i] = &logPin{Pin: gpiotest.Pin{N: fmt.Sprintf("D%d", i+4)}, log: l}
+	}
+	l.rs, l.d = rs, d
+	bl := &gpiotest.Pin{N: "BL"}
+	dev, err := NewGPIO(rs, e, d[0], d[1], d[2], d[3], &Opts{Cols: 20, Rows: 4, Backlight: bl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "HD44780{rs:RS(0), e:E(0), d4:D4(0), d5:D5(0), d6:D6(0), d7:D7(0)}" {
+		t.Fatal(s)
+	}
+	if bl.L != gpio.High {
+		t.Fatal("backlight must be on")
+	}
+	if !bytes.Equal(l.nibbles[:4], []byte{3, 3, 3, 2}) {
+		t.Fatalf("%#v", l.nibbles[:4])
+	}
+	expected := []cmd{{0x28, false}, {0x0C, false}, {0x01, false}, {0x06, false}}
+	checkCmds(t, l.cmds(4), expected)
+
+	l.nibbles, l.rsLevels = nil, nil
+	if err := dev.MoveTo(3, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(0, 3); err != nil {
+		t.Fatal(err)
+	}
+	expected = []cmd{{0x80 | 23, false}, {'a', true}, {0x80 | 0x54, false}}
+	checkCmds(t, l.cmds(0), expected)
+
+	if err := dev.SetBacklight(false); err != nil {
+		t.Fatal(err)
+	}
+	if bl.L != gpio.Low {
+		t.Fatal("backlight must be off")
+	}
+}
+
+func TestNewGPIO_fail(t *testing.T) {
+	p := &gpiotest.Pin{}
+	if _, err := NewGPIO(p, p, p, p, p, nil, nil); err == nil {
+		t.Fatal("missing pin")
+	}
+	f := &failPin{}
+	if _, err := NewGPIO(f, f, f, f, f, f, nil); err == nil {
+		t.Fatal("pin failure")
+	}
+	dev, err := NewGPIO(p, p, p, p, p, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetBacklight(true); err == nil {
+		t.Fatal("no backlight pin")
+	}
+}
+
+//
+
+type cmd struct {
+	v  byte
+	rs bool
+}
+
+func checkCmds(t *testing.T, actual, expected []cmd) {
+	if len(actual) != len(expected) {
+		t.Fatalf("%v != %v", actual, expected)
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Fatalf("#%d: %v != %v", i, actual[i], expected[i])
+		}
+	}
+}
+
+// decodeI2C decodes the bytes written to the PCF8574.
+func decodeI2C(t *testing.T, ops []i2ctest.IO) []cmd {
+	var out []cmd
+	for i, op := range ops {
+		w := op.W
+		if len(w) != 4 || w[0]&0x04 == 0 || w[1]&0x04 != 0 || w[2]&0x04 == 0 || w[3]&0x04 != 0 {
+			t.Fatalf("#%d: invalid transaction %#v", i, w)
+		}
+		out = append(out, cmd{w[0]&0xF0 | w[2]>>4, w[0]&0x01 != 0})
+	}
+	return out
+}
+
+// pinLog records the nibbles latched on the falling edge of E.
+type pinLog struct {
+	rs       *logPin
+	d        [4]*logPin
+	nibbles  []byte
+	rsLevels []bool
+}
+
+func (l *pinLog) cmds(skip int) []cmd {
+	var out []cmd
+	for i := skip; i+1 < len(l.nibbles); i += 2 {
+		out = append(out, cmd{l.nibbles[i]<<4 | l.nibbles[i+1], l.rsLevels[i]})
+	}
+	return out
+}
+
+type logPin struct {
+	gpiotest.Pin
+	log *pinLog
+}
+
+func (p *logPin) Out(l gpio.Level) error {
+	if p.N == "E" && p.L == gpio.High && l == gpio.Low {
+		var n byte
+		for i, d := range p.log.d {
+			if d.L {
+				n |= 1 << uint(i)
+			}
+		}
+		p.log.nibbles = append(p.log.nibbles, n)
+		p.log.rsLevels = append(p.log.rsLevels, bool(p.log.rs.L))
+	}
+	return p.Pin.Out(l)
+}
+
+type failPin struct {
+	gpiotest.Pin
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	return errors.New("injected")
+}
@@ -0,0 +1,296 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package hx711 controls an Avia Semiconductor HX711 24 bits ADC for load
+// cells over two GPIO pins.
+//
+// The device signals a conversion is ready by pulling DOUT low. The 24 bits
+// are then clocked out on PD_SCK, followed by 1 to 3 pulses that select the
+// channel and gain of the next conversion. PD_SCK must not stay high for more
+// than 50µs during a read, otherwise the device powers down; the host must
+// be able to toggle the pin fast enough.
+//
+// The raw counts are converted to a mass after taring the empty scale and
+// calibrating with a known mass.
+//
+// Datasheet
+//
+// https://cdn.sparkfun.com/datasheets/Sensors/ForceFlex/hx711_english.pdf
+package hx711
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/devices"
+)
+
+// Gain is the channel and gain selection.
+type Gain uint8
+
+// Possible gains.
+const (
+	A128 Gain = 0 // Channel A, gain 128
+	B32  Gain = 1 // Channel B, gain 32
+	A64  Gain = 2 // Channel A, gain 64
+)
+
+const gainName = "A128B32A64"
+
+var gainIndex = [...]uint8{0, 4, 7, 10}
+
+func (g Gain) String() string {
+	if g >= Gain(len(gainIndex)-1) {
+		return fmt.Sprintf("Gain(%d)", g)
+	}
+	return gainName[gainIndex[g]:gainIndex[g+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Gain Gain
+	// Samples is the number of conversions done per measurement; the median
+	// value is used to reject noise spikes. Defaults to 1.
+	Samples int
+	// Timeout is the maximum time to wait for a conversion. Defaults to
+	// 500ms, which is enough at 10 samples per second.
+	Timeout time.Duration
+}
+
+// Dev is a handle to a HX711.
+type Dev struct {
+	clk  gpio.PinOut
+	data gpio.PinIn
+	opts Opts
+
+	mu     sync.Mutex
+	offset int32
+	scale  float64 // Counts per gram; 0 when not calibrated
+}
+
+// New returns an object that communicates over two pins to a HX711.
+//
+// A first conversion is done to select the gain.
+func New(clk gpio.PinOut, data gpio.PinIn, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.Gain > A64 {
+		return nil, fmt.Errorf("hx711: invalid gain %d", o.Gain)
+	}
+	if o.Samples == 0 {
+		o.Samples = defaults.Samples
+	}
+	if o.Samples < 0 {
+		return nil, fmt.Errorf("hx711: invalid samples %d", o.Samples)
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaults.Timeout
+	}
+	if err := data.In(gpio.Float, gpio.NoEdge); err != nil {
+		return nil, fmt.Errorf("hx711: %v", err)
+	}
+	// Also wakes the device up.
+	if err := clk.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("hx711: %v", err)
+	}
+	d := &Dev{clk: clk, data: data, opts: o}
+	if _, err := d.read(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("HX711{%s, %s}", d.clk, d.data)
+}
+
+// SetGain changes the channel and gain.
+//
+// A conversion is done as the new gain only applies to the next one.
+func (d *Dev) SetGain(g Gain) error {
+	if g > A64 {
+		return fmt.Errorf("hx711: invalid gain %d", g)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts.Gain = g
+	_, err := d.read()
+	return err
+}
+
+// ReadRaw returns the median of the configured number of conversions, without
+// the tare offset.
+func (d *Dev) ReadRaw() (int32, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.median(d.opts.Samples)
+}
+
+// Tare sets the current reading as zero. samples conversions are done; use a
+// larger value than for normal measurements to get a stable reference.
+func (d *Dev) Tare(samples int) error {
+	if samples <= 0 {
+		return fmt.Errorf("hx711: invalid samples %d", samples)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, err := d.median(samples)
+	if err != nil {
+		return err
+	}
+	d.offset = v
+	return nil
+}
+
+// Calibrate sets the scale from a known mass placed on the scale. Tare() must
+// have been called first with the scale empty.
+func (d *Dev) Calibrate(known devices.Mass, samples int) error {
+	if known <= 0 {
+		return errors.New("hx711: known mass must be positive")
+	}
+	if samples <= 0 {
+		return fmt.Errorf("hx711: invalid samples %d", samples)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, err := d.median(samples)
+	if err != nil {
+		return err
+	}
+	if v == d.offset {
+		return errors.New("hx711: no difference with the tare")
+	}
+	d.scale = float64(v-d.offset) / known.Float64()
+	return nil
+}
+
+// SetCalibration sets a previously determined offset and scale in counts per
+// gram, to skip taring and calibrating.
+func (d *Dev) SetCalibration(offset int32, scale float64) error {
+	if scale == 0 {
+		return errors.New("hx711: invalid scale")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.offset = offset
+	d.scale = scale
+	return nil
+}
+
+// Calibration returns the offset and scale in counts per gram.
+func (d *Dev) Calibration() (int32, float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.offset, d.scale
+}
+
+// SenseMass measures the mass on the scale.
+func (d *Dev) SenseMass(m *devices.Mass) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.scale == 0 {
+		return errors.New("hx711: not calibrated")
+	}
+	v, err := d.median(d.opts.Samples)
+	if err != nil {
+		return err
+	}
+	*m = devices.Mass(math.Floor(float64(v-d.offset)/d.scale*1000 + 0.5))
+	return nil
+}
+
+// SenseForce measures the force applied on the load cell, calibrated with a
+// mass under standard gravity.
+func (d *Dev) SenseForce(f *devices.Newton) error {
+	var m devices.Mass
+	if err := d.SenseMass(&m); err != nil {
+		return err
+	}
+	*f = devices.Newton(math.Floor(float64(m)*standardGravity/1000 + 0.5))
+	return nil
+}
+
+// Halt powers down the device. It is woken up on the next read.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.clk.Out(gpio.High); err != nil {
+		return fmt.Errorf("hx711: %v", err)
+	}
+	return nil
+}
+
+//
+
+// standardGravity is in m/s².
+const standardGravity = 9.80665
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// median returns the median of n conversions.
+func (d *Dev) median(n int) (int32, error) {
+	v := make([]int, n)
+	for i := range v {
+		r, err := d.read()
+		if err != nil {
+			return 0, err
+		}
+		v[i] = int(r)
+	}
+	sort.Ints(v)
+	return int32(v[n/2]), nil
+}
+
+// read waits for a conversion and reads it.
+func (d *Dev) read() (int32, error) {
+	// Wake up the device in case it was halted.
+	if err := d.clk.Out(gpio.Low); err != nil {
+		return 0, fmt.Errorf("hx711: %v", err)
+	}
+	for start := time.Now(); d.data.Read() != gpio.Low; {
+		if time.Since(start) > d.opts.Timeout {
+			return 0, errors.New("hx711: timed out waiting for conversion")
+		}
+		sleep(time.Millisecond)
+	}
+	// Keep the pulses as short as possible.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var v uint32
+	for i := 0; i < 24+int(d.opts.Gain)+1; i++ {
+		if err := d.clk.Out(gpio.High); err != nil {
+			return 0, fmt.Errorf("hx711: %v", err)
+		}
+		if err := d.clk.Out(gpio.Low); err != nil {
+			return 0, fmt.Errorf("hx711: %v", err)
+		}
+		if i < 24 {
+			v <<= 1
+			if d.data.Read() == gpio.High {
+				v |= 1
+			}
+		}
+	}
+	// Sign extend the 24 bits two's complement value.
+	return int32(v<<8) >> 8, nil
+}
+
+var defaults = Opts{
+	Gain:    A128,
+	Samples: 1,
+	Timeout: 500 * time.Millisecond,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
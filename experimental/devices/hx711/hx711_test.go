@@ -0,0 +1,242 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hx711
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	dev, err := New(gpioreg.ByName("GPIO5"), gpioreg.ByName("GPIO6"), &Opts{Samples: 5})
+	if err != nil {
+		log.Fatalf("failed to initialize hx711: %v", err)
+	}
+	defer dev.Halt()
+	if err := dev.Tare(20); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Put a 100g mass on the scale")
+	time.Sleep(5 * time.Second)
+	if err := dev.Calibrate(100000, 20); err != nil {
+		log.Fatal(err)
+	}
+	var m devices.Mass
+	if err := dev.SenseMass(&m); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", m)
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNew_Sense(t *testing.T) {
+	f := newFake(0, 1000, 1000, 1000, 5000, 5000, 5000, 3000, 9000, 3000, -2000, -2000, -2000)
+	d, err := New(&f.clk, &f.data, &Opts{Samples: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "HX711{CLK(0), DATA(0)}" {
+		t.Fatal(s)
+	}
+	var m devices.Mass
+	if err := d.SenseMass(&m); err == nil {
+		t.Fatal("not calibrated")
+	}
+	if err := d.Tare(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Calibrate(100000, 3); err != nil {
+		t.Fatal(err)
+	}
+	if o, s := d.Calibration(); o != 1000 || s != 40 {
+		t.Fatal(o, s)
+	}
+	// The median filter rejects the 9000 spike.
+	if err := d.SenseMass(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m != 50000 {
+		t.Fatal(m)
+	}
+	if v, err := d.ReadRaw(); err != nil || v != -2000 {
+		t.Fatal(v, err)
+	}
+	// A128 uses 25 pulses.
+	if f.pulses != 13*25 {
+		t.Fatal(f.pulses)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if f.clk.L != gpio.High {
+		t.Fatal("not powered down")
+	}
+}
+
+func TestSenseForce_Gain(t *testing.T) {
+	f := newFake(0, 0, 0x7FFFFF, -0x800000, 981)
+	d, err := New(&f.clk, &f.data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetGain(A64); err != nil {
+		t.Fatal(err)
+	}
+	if f.pulses != 25+27 {
+		t.Fatal(f.pulses)
+	}
+	if v, err := d.ReadRaw(); err != nil || v != 0x7FFFFF {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadRaw(); err != nil || v != -0x800000 {
+		t.Fatal(v, err)
+	}
+	if f.pulses != 25+27+2*27 {
+		t.Fatal(f.pulses)
+	}
+	if err := d.SetCalibration(0, 9.81); err != nil {
+		t.Fatal(err)
+	}
+	var n devices.Newton
+	if err := d.SenseForce(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 981 {
+		t.Fatal(n)
+	}
+	if err := d.SetGain(3); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if err := d.SetCalibration(0, 0); err == nil {
+		t.Fatal("invalid scale")
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	f := newFake()
+	if _, err := New(&f.clk, &f.data, &Opts{Gain: 3}); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if _, err := New(&f.clk, &f.data, &Opts{Samples: -1}); err == nil {
+		t.Fatal("invalid samples")
+	}
+	if _, err := New(&failPin{}, &f.data, nil); err == nil {
+		t.Fatal("clk failed")
+	}
+	// No conversion.
+	if _, err := New(&f.clk, &f.data, &Opts{Timeout: time.Millisecond}); err == nil {
+		t.Fatal("timeout")
+	}
+	f = newFake(0, 0)
+	d, err := New(&f.clk, &f.data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Tare(0); err == nil {
+		t.Fatal("invalid samples")
+	}
+	if err := d.Calibrate(0, 1); err == nil {
+		t.Fatal("invalid mass")
+	}
+	if err := d.Calibrate(1000, 0); err == nil {
+		t.Fatal("invalid samples")
+	}
+	if err := d.Calibrate(1000, 1); err == nil {
+		t.Fatal("no difference")
+	}
+	if s := Gain(3).String(); s != "Gain(3)" {
+		t.Fatal(s)
+	}
+	if s := B32.String(); s != "B32" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+// fake emulates a HX711 that has the specified conversions ready.
+type fake struct {
+	clk    clkPin
+	data   dataPin
+	values []int32
+	pulses int
+	// bit is the number of data bits clocked out of the current conversion.
+	bit int
+	// done is set once the 24 bits of a conversion were clocked out.
+	done bool
+}
+
+func newFake(values ...int32) *fake {
+	f := &fake{values: values}
+	f.clk = clkPin{Pin: gpiotest.Pin{N: "CLK"}, f: f}
+	f.data = dataPin{Pin: gpiotest.Pin{N: "DATA"}, f: f}
+	return f
+}
+
+type clkPin struct {
+	gpiotest.Pin
+	f *fake
+}
+
+func (c *clkPin) Out(l gpio.Level) error {
+	f := c.f
+	if l == gpio.High && c.L == gpio.Low {
+		f.pulses++
+		if !f.done && len(f.values) != 0 {
+			if f.bit++; f.bit == 25 {
+				f.values = f.values[1:]
+				f.bit = 0
+				f.done = true
+			}
+		}
+	}
+	return c.Pin.Out(l)
+}
+
+type dataPin struct {
+	gpiotest.Pin
+	f *fake
+}
+
+func (d *dataPin) Read() gpio.Level {
+	f := d.f
+	if f.done {
+		// The next conversion becomes ready.
+		f.done = false
+		return gpio.High
+	}
+	if len(f.values) == 0 {
+		return gpio.High
+	}
+	if f.bit == 0 {
+		return gpio.Low
+	}
+	return gpio.Level(uint32(f.values[0])>>uint(24-f.bit)&1 != 0)
+}
+
+type failPin struct {
+	gpiotest.Pin
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	return errors.New("injected")
+}
@@ -0,0 +1,455 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ili9341 controls a 240x320 color TFT display driven by an Ilitek
+// ILI9341 controller over 4-wire SPI.
+//
+// The driver keeps a copy of the display content and does differential
+// updates: Draw() only sends the smallest rectangle containing modified
+// pixels. A full frame is 150KiB, which takes 30ms at 40MHz, so only
+// updating the dirty rectangle is what makes interactive frame rates
+// possible.
+//
+// The pixels are sent in chunks of the SPI port's maximum transaction size.
+// When the port has no limit, a window is sent as a single transaction so a
+// DMA capable driver can send it without CPU intervention.
+//
+// The touch controller found on many modules is a separate device and is not
+// handled by this package.
+//
+// Datasheet
+//
+// https://cdn-shop.adafruit.com/datasheets/ILI9341.pdf
+package ili9341
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+)
+
+// Rotation is the display orientation, clockwise. Rotate90 and Rotate270
+// result in a 320x240 landscape display.
+type Rotation uint8
+
+// Possible rotations.
+const (
+	Rotate0   Rotation = 0
+	Rotate90  Rotation = 1
+	Rotate180 Rotation = 2
+	Rotate270 Rotation = 3
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Rotation Rotation
+	// RGB must be set for panels with the red and blue subpixels in RGB
+	// order. Most modules are BGR.
+	RGB bool
+	// MaxHz is the SPI clock speed. It defaults to 40MHz. The write cycle of
+	// the controller is rated at 10MHz but most panels work well faster.
+	MaxHz int64
+}
+
+// Dev is an open handle to the display controller.
+type Dev struct {
+	c     conn.Conn
+	dc    gpio.PinOut
+	opts  Opts
+	rect  image.Rectangle
+	chunk int
+
+	// buffer is the display content as RGB565, in logical coordinates.
+	buffer []byte
+	// tx is the scratch buffer for windows narrower than the display.
+	tx     []byte
+	halted bool
+	err    error
+}
+
+// NewSPI returns a Dev object that communicates over SPI to an ILI9341
+// display controller.
+//
+// dc is the data/command select pin. rst is the optional reset pin; pass nil
+// if it is not connected and a software reset is done instead.
+//
+// The display is cleared to black.
+func NewSPI(p spi.Port, dc, rst gpio.PinOut, opts *Opts) (*Dev, error) {
+	if dc == nil || dc == gpio.INVALID {
+		return nil, errors.New("ili9341: dc pin is required")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.Rotation > Rotate270 {
+		return nil, fmt.Errorf("ili9341: invalid rotation %d", o.Rotation)
+	}
+	if o.MaxHz == 0 {
+		o.MaxHz = defaults.MaxHz
+	}
+	if err := dc.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("ili9341: %v", err)
+	}
+	if rst != nil {
+		if err := rst.Out(gpio.Low); err != nil {
+			return nil, fmt.Errorf("ili9341: %v", err)
+		}
+		sleep(10 * time.Microsecond)
+		if err := rst.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("ili9341: %v", err)
+		}
+		sleep(120 * time.Millisecond)
+	}
+	c, err := p.Connect(o.MaxHz, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("ili9341: %v", err)
+	}
+	d := &Dev{c: c, dc: dc, opts: o, buffer: make([]byte, 2*width*height)}
+	if l, ok := c.(conn.Limits); ok {
+		d.chunk = l.MaxTxSize() &^ 1
+	}
+	d.setRotation(o.Rotation)
+	if err := d.init(rst == nil); err != nil {
+		return nil, err
+	}
+	if err := d.Refresh(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("ILI9341{%s, %s, %s}", d.c, d.dc, d.rect.Max)
+}
+
+// ColorModel implements devices.Display.
+//
+// The pixels are converted to RGB565 when sent.
+func (d *Dev) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+// Bounds implements devices.Display. Min is guaranteed to be {0, 0}. The
+// size depends on the rotation.
+func (d *Dev) Bounds() image.Rectangle {
+	return d.rect
+}
+
+// Draw implements devices.Display.
+//
+// Only the smallest rectangle containing the pixels that changed within r is
+// sent. It draws synchronously, once this function returns, the display is
+// updated.
+//
+// It discards any failure; use Err() to retrieve it.
+func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
+	clip := r.Intersect(d.rect)
+	if clip.Empty() {
+		return
+	}
+	sp = sp.Add(clip.Min.Sub(r.Min))
+	r = clip
+	dirty := image.Rectangle{}
+	w := d.rect.Dx()
+	rgba, _ := src.(*image.RGBA)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := sp.Y + y - r.Min.Y
+		o := 2 * (y*w + r.Min.X)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx := sp.X + x - r.Min.X
+			var hi, lo byte
+			if rgba != nil {
+				// Fast path.
+				i := rgba.PixOffset(sx, sy)
+				hi, lo = rgb565(rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2])
+			} else {
+				c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+				hi, lo = rgb565(c.R, c.G, c.B)
+			}
+			if d.buffer[o] != hi || d.buffer[o+1] != lo {
+				d.buffer[o], d.buffer[o+1] = hi, lo
+				dirty = dirty.Union(image.Rect(x, y, x+1, y+1))
+			}
+			o += 2
+		}
+	}
+	if dirty.Empty() {
+		d.err = nil
+		return
+	}
+	d.err = d.sendRect(dirty)
+}
+
+// Err returns the last error that occurred in Draw().
+func (d *Dev) Err() error {
+	return d.err
+}
+
+// Write writes a buffer of pixels to the whole display.
+//
+// Each pixel is 16 bits RGB565 in big endian, starting at the top left and
+// going row by row.
+func (d *Dev) Write(pixels []byte) (int, error) {
+	if len(pixels) != len(d.buffer) {
+		return 0, fmt.Errorf("ili9341: invalid pixel stream length; expected %d bytes, got %d bytes", len(d.buffer), len(pixels))
+	}
+	copy(d.buffer, pixels)
+	if err := d.Refresh(); err != nil {
+		return 0, err
+	}
+	return len(pixels), nil
+}
+
+// Refresh sends the whole content of the display, even if it didn't change.
+//
+// This is useful if the display content was lost, for example when the
+// panel power was cycled.
+func (d *Dev) Refresh() error {
+	return d.sendRect(d.rect)
+}
+
+// Dirty returns the smallest rectangle covering the pixels of img that differ
+// from the display content, in display coordinates. It returns an empty
+// rectangle if img matches the display.
+//
+// It can be used to decide whether a Draw() call is worth doing.
+func (d *Dev) Dirty(img *image.RGBA) image.Rectangle {
+	r := img.Bounds().Intersect(d.rect)
+	dirty := image.Rectangle{}
+	w := d.rect.Dx()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			hi, lo := rgb565(img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+			if o := 2 * (y*w + x); d.buffer[o] != hi || d.buffer[o+1] != lo {
+				dirty = dirty.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return dirty
+}
+
+// SetRotation changes the display orientation. The display content is
+// cleared to black.
+func (d *Dev) SetRotation(r Rotation) error {
+	if r > Rotate270 {
+		return fmt.Errorf("ili9341: invalid rotation %d", r)
+	}
+	d.setRotation(r)
+	if err := d.command(cmdMADCTL, d.madctl()); err != nil {
+		return err
+	}
+	for i := range d.buffer {
+		d.buffer[i] = 0
+	}
+	return d.Refresh()
+}
+
+// Invert inverts the colors of the display.
+func (d *Dev) Invert(invert bool) error {
+	if invert {
+		return d.command(cmdINVON)
+	}
+	return d.command(cmdINVOFF)
+}
+
+// Halt turns off the display and puts the controller to sleep.
+//
+// Sending any pixel afterward reenables the display.
+func (d *Dev) Halt() error {
+	if err := d.command(cmdDISPOFF); err != nil {
+		return err
+	}
+	if err := d.command(cmdSLPIN); err != nil {
+		return err
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	width  = 240
+	height = 320
+
+	cmdSWRESET = 0x01
+	cmdSLPIN   = 0x10
+	cmdSLPOUT  = 0x11
+	cmdINVOFF  = 0x20
+	cmdINVON   = 0x21
+	cmdDISPOFF = 0x28
+	cmdDISPON  = 0x29
+	cmdCASET   = 0x2A
+	cmdPASET   = 0x2B
+	cmdRAMWR   = 0x2C
+	cmdMADCTL  = 0x36
+	cmdPIXFMT  = 0x3A
+
+	madctlMY  = 0x80
+	madctlMX  = 0x40
+	madctlMV  = 0x20
+	madctlBGR = 0x08
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// madctlRotation is indexed by Rotation.
+var madctlRotation = [...]byte{madctlMX, madctlMV, madctlMY, madctlMX | madctlMY | madctlMV}
+
+// initCmds is the power, timing and gamma configuration recommended by the
+// panel vendors.
+var initCmds = [][]byte{
+	{0xCF, 0x00, 0xC1, 0x30},
+	{0xED, 0x64, 0x03, 0x12, 0x81},
+	{0xE8, 0x85, 0x00, 0x78},
+	{0xCB, 0x39, 0x2C, 0x00, 0x34, 0x02},
+	{0xF7, 0x20},
+	{0xEA, 0x00, 0x00},
+	{0xC0, 0x23},       // Power control 1
+	{0xC1, 0x10},       // Power control 2
+	{0xC5, 0x3E, 0x28}, // VCOM control 1
+	{0xC7, 0x86},       // VCOM control 2
+	{cmdPIXFMT, 0x55},  // 16 bits per pixel
+	{0xB1, 0x00, 0x18}, // Frame rate, 79Hz
+	{0xB6, 0x08, 0x82, 0x27},
+	{0xF2, 0x00}, // 3 gamma off
+	{0x26, 0x01}, // Gamma curve 1
+	{0xE0, 0x0F, 0x31, 0x2B, 0x0C, 0x0E, 0x08, 0x4E, 0xF1, 0x37, 0x07, 0x10, 0x03, 0x0E, 0x09, 0x00},
+	{0xE1, 0x00, 0x0E, 0x14, 0x03, 0x11, 0x07, 0x31, 0xC1, 0x48, 0x08, 0x0F, 0x0C, 0x31, 0x36, 0x0F},
+}
+
+func (d *Dev) init(reset bool) error {
+	if reset {
+		if err := d.command(cmdSWRESET); err != nil {
+			return err
+		}
+		sleep(5 * time.Millisecond)
+	}
+	for _, c := range initCmds {
+		if err := d.command(c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	if err := d.command(cmdMADCTL, d.madctl()); err != nil {
+		return err
+	}
+	if err := d.command(cmdSLPOUT); err != nil {
+		return err
+	}
+	sleep(120 * time.Millisecond)
+	return d.command(cmdDISPON)
+}
+
+func (d *Dev) setRotation(r Rotation) {
+	d.opts.Rotation = r
+	if r == Rotate90 || r == Rotate270 {
+		d.rect = image.Rect(0, 0, height, width)
+	} else {
+		d.rect = image.Rect(0, 0, width, height)
+	}
+}
+
+func (d *Dev) madctl() byte {
+	v := madctlRotation[d.opts.Rotation]
+	if !d.opts.RGB {
+		v |= madctlBGR
+	}
+	return v
+}
+
+// sendRect sends the pixels of the rectangle r from the buffer.
+func (d *Dev) sendRect(r image.Rectangle) error {
+	if d.halted {
+		// Transparently enable the display.
+		if err := d.command(cmdSLPOUT); err != nil {
+			return err
+		}
+		sleep(120 * time.Millisecond)
+		if err := d.command(cmdDISPON); err != nil {
+			return err
+		}
+		d.halted = false
+	}
+	if err := d.command(cmdCASET, byte(r.Min.X>>8), byte(r.Min.X), byte((r.Max.X-1)>>8), byte(r.Max.X-1)); err != nil {
+		return err
+	}
+	if err := d.command(cmdPASET, byte(r.Min.Y>>8), byte(r.Min.Y), byte((r.Max.Y-1)>>8), byte(r.Max.Y-1)); err != nil {
+		return err
+	}
+	if err := d.command(cmdRAMWR); err != nil {
+		return err
+	}
+	w := d.rect.Dx()
+	if r.Dx() == w {
+		// Full rows are contiguous in the buffer.
+		return d.data(d.buffer[2*r.Min.Y*w : 2*r.Max.Y*w])
+	}
+	n := 2 * r.Dx() * r.Dy()
+	if cap(d.tx) < n {
+		d.tx = make([]byte, n)
+	}
+	b := d.tx[:0]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		b = append(b, d.buffer[2*(y*w+r.Min.X):2*(y*w+r.Max.X)]...)
+	}
+	return d.data(b)
+}
+
+// command sends a command byte and its parameters.
+func (d *Dev) command(c byte, params ...byte) error {
+	if err := d.dc.Out(gpio.Low); err != nil {
+		return fmt.Errorf("ili9341: %v", err)
+	}
+	if err := d.c.Tx([]byte{c}, nil); err != nil {
+		return fmt.Errorf("ili9341: %v", err)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return d.data(params)
+}
+
+// data sends data bytes, in chunks not larger than the maximum transaction
+// size of the SPI port, if any.
+func (d *Dev) data(b []byte) error {
+	if err := d.dc.Out(gpio.High); err != nil {
+		return fmt.Errorf("ili9341: %v", err)
+	}
+	for len(b) != 0 {
+		n := len(b)
+		if d.chunk > 0 && n > d.chunk {
+			n = d.chunk
+		}
+		if err := d.c.Tx(b[:n], nil); err != nil {
+			return fmt.Errorf("ili9341: %v", err)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// rgb565 converts 8 bits per channel to the big endian 16 bits
+// representation.
+func rgb565(r, g, b byte) (byte, byte) {
+	return r&0xF8 | g>>5, (g&0x1C)<<3 | b>>3
+}
+
+var defaults = Opts{
+	Rotation: Rotate90,
+	MaxHz:    40000000,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.Display = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,315 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ili9341
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dev, err := NewSPI(p, gpioreg.ByName("GPIO25"), gpioreg.ByName("GPIO24"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize ili9341: %v", err)
+	}
+	defer dev.Halt()
+	img := image.NewRGBA(dev.Bounds())
+	for i := 0; i < 100; i++ {
+		// Move a square; only the modified pixels are sent.
+		draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+		draw.Draw(img, image.Rect(i, 100, i+40, 140), &image.Uniform{color.NRGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
+		dev.Draw(img.Bounds(), img, image.Point{})
+		if err := dev.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewSPI(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	rst := &gpiotest.Pin{N: "RST"}
+	p := &fakePort{dc: dc, maxTxSize: 4096}
+	d, err := NewSPI(p, dc, rst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rst.L != gpio.High {
+		t.Fatal("reset")
+	}
+	if p.maxHz != 40000000 {
+		t.Fatal(p.maxHz)
+	}
+	// Init commands, MADCTL, SLPOUT, DISPON, window and the full frame.
+	frame := (2*240*320 + 4095) / 4096
+	if n := 2*len(initCmds) + 4 + 5 + frame; len(p.ops) != n {
+		t.Fatal(len(p.ops), n)
+	}
+	for _, o := range p.ops[len(p.ops)-frame:] {
+		if o.cmd || len(o.w) > 4096 {
+			t.Fatal(o)
+		}
+	}
+	w := p.ops[2*len(initCmds):]
+	p.ops = w[:4]
+	p.check(t, []op{
+		{cmd: true, w: []byte{0x36}},
+		{w: []byte{0x28}},
+		{cmd: true, w: []byte{0x11}},
+		{cmd: true, w: []byte{0x29}},
+	})
+	p.ops = w[4:9]
+	p.check(t, window(0, 0, 319, 239))
+	if s := d.String(); s != "ILI9341{fake, DC(0), (320,240)}" {
+		t.Fatal(s)
+	}
+	if m := d.ColorModel(); m != color.NRGBAModel {
+		t.Fatal(m)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 320, 240) {
+		t.Fatal(r)
+	}
+
+	// Only the dirty rectangle is sent.
+	img := image.NewRGBA(d.Bounds())
+	img.Set(10, 20, color.RGBA{255, 0, 0, 255})
+	img.Set(12, 21, color.RGBA{0, 0, 255, 255})
+	if r := d.Dirty(img); r != image.Rect(10, 20, 13, 22) {
+		t.Fatal(r)
+	}
+	d.Draw(d.Bounds(), img, image.Point{})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, append(window(10, 20, 12, 21), op{w: []byte{0xF8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x1F}}))
+	if r := d.Dirty(img); !r.Empty() {
+		t.Fatal(r)
+	}
+
+	// Nothing changed.
+	d.Draw(d.Bounds(), img, image.Point{})
+	p.check(t, nil)
+
+	// Non-RGBA source, clipped.
+	n := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	n.Set(0, 0, color.NRGBA{0, 255, 0, 255})
+	n.Set(1, 0, color.NRGBA{0, 255, 0, 255})
+	d.Draw(image.Rect(-1, 0, 1, 1), n, image.Point{})
+	p.check(t, append(window(0, 0, 0, 0), op{w: []byte{0x07, 0xE0}}))
+
+	// Outside the display.
+	d.Draw(image.Rect(400, 0, 410, 10), n, image.Point{})
+	p.check(t, nil)
+
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x28}}, {cmd: true, w: []byte{0x10}}})
+	if err := d.Invert(true); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x21}}})
+	if err := d.Invert(false); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x20}}})
+	if _, err := d.Write([]byte{0}); err == nil {
+		t.Fatal("invalid length")
+	}
+	if _, err := d.Write(make([]byte, 2*240*320)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.ops) != 2+5+frame {
+		t.Fatal(len(p.ops))
+	}
+	p.ops = p.ops[:2]
+	p.check(t, []op{{cmd: true, w: []byte{0x11}}, {cmd: true, w: []byte{0x29}}})
+}
+
+func TestSetRotation_nolimit(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, nil, &Opts{RGB: true, MaxHz: 10000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.maxHz != 10000000 {
+		t.Fatal(p.maxHz)
+	}
+	if !p.ops[0].cmd || !bytes.Equal(p.ops[0].w, []byte{0x01}) {
+		t.Fatal(p.ops[0])
+	}
+	// Without limit, the frame is sent in one transaction.
+	if n := 1 + 2*len(initCmds) + 4 + 5 + 1; len(p.ops) != n {
+		t.Fatal(len(p.ops), n)
+	}
+	if o := p.ops[len(p.ops)-1]; len(o.w) != 2*240*320 {
+		t.Fatal(len(o.w))
+	}
+	if o := p.ops[len(p.ops)-9]; !bytes.Equal(o.w, []byte{0x40}) {
+		t.Fatal(o)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 240, 320) {
+		t.Fatal(r)
+	}
+	p.ops = nil
+	if err := d.SetRotation(Rotate270); err != nil {
+		t.Fatal(err)
+	}
+	p.ops = p.ops[:2+5]
+	p.check(t, append([]op{{cmd: true, w: []byte{0x36}}, {w: []byte{0xE0}}}, window(0, 0, 319, 239)...))
+	if err := d.SetRotation(4); err == nil {
+		t.Fatal("invalid rotation")
+	}
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	if _, err := NewSPI(&fakePort{dc: dc}, nil, nil, nil); err == nil {
+		t.Fatal("dc is required")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, gpio.INVALID, nil, nil); err == nil {
+		t.Fatal("dc is required")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, nil, &Opts{Rotation: 4}); err == nil {
+		t.Fatal("invalid rotation")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, &failPin{}, nil, nil); err == nil {
+		t.Fatal("dc failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &failPin{}, nil); err == nil {
+		t.Fatal("rst failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc, connectErr: true}, dc, nil, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc, txErr: true}, dc, nil, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+}
+
+func TestRGB565(t *testing.T) {
+	data := []struct {
+		r, g, b byte
+		hi, lo  byte
+	}{
+		{0, 0, 0, 0, 0},
+		{255, 255, 255, 0xFF, 0xFF},
+		{255, 0, 0, 0xF8, 0x00},
+		{0, 255, 0, 0x07, 0xE0},
+		{0, 0, 255, 0x00, 0x1F},
+	}
+	for i, line := range data {
+		if hi, lo := rgb565(line.r, line.g, line.b); hi != line.hi || lo != line.lo {
+			t.Fatalf("#%d: %#x %#x", i, hi, lo)
+		}
+	}
+}
+
+//
+
+func window(x0, y0, x1, y1 int) []op {
+	return []op{
+		{cmd: true, w: []byte{0x2A}},
+		{w: []byte{byte(x0 >> 8), byte(x0), byte(x1 >> 8), byte(x1)}},
+		{cmd: true, w: []byte{0x2B}},
+		{w: []byte{byte(y0 >> 8), byte(y0), byte(y1 >> 8), byte(y1)}},
+		{cmd: true, w: []byte{0x2C}},
+	}
+}
+
+type op struct {
+	cmd bool
+	w   []byte
+}
+
+// fakePort records the transactions along the state of the DC pin.
+type fakePort struct {
+	dc         *gpiotest.Pin
+	maxTxSize  int
+	connectErr bool
+	txErr      bool
+	maxHz      int64
+	ops        []op
+}
+
+func (f *fakePort) String() string {
+	return "fake"
+}
+
+func (f *fakePort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	if f.connectErr {
+		return nil, errors.New("injected")
+	}
+	f.maxHz = maxHz
+	return f, nil
+}
+
+func (f *fakePort) Tx(w, r []byte) error {
+	if f.txErr {
+		return errors.New("injected")
+	}
+	f.ops = append(f.ops, op{cmd: f.dc.Read() == gpio.Low, w: append([]byte{}, w...)})
+	return nil
+}
+
+func (f *fakePort) TxPackets(p []spi.Packet) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakePort) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+func (f *fakePort) MaxTxSize() int {
+	return f.maxTxSize
+}
+
+func (f *fakePort) check(t *testing.T, expected []op) {
+	if len(f.ops) != len(expected) {
+		t.Fatalf("%d ops, expected %d", len(f.ops), len(expected))
+	}
+	for i := range expected {
+		if f.ops[i].cmd != expected[i].cmd || !bytes.Equal(f.ops[i].w, expected[i].w) {
+			t.Fatalf("#%d: %v != %v", i, f.ops[i], expected[i])
+		}
+	}
+	f.ops = nil
+}
+
+type failPin struct {
+	gpiotest.Pin
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	return errors.New("injected")
+}
@@ -0,0 +1,307 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ina2xx controls a Texas Instruments INA219 or INA260 current and
+// power monitor over I²C.
+//
+// The INA219 uses an external shunt resistor which value must be provided so
+// the device can be calibrated. The INA260 has an integrated 2mΩ shunt and
+// needs no calibration.
+//
+// Datasheet
+//
+// INA219: http://www.ti.com/lit/ds/symlink/ina219.pdf
+//
+// INA260: http://www.ti.com/lit/ds/symlink/ina260.pdf
+package ina2xx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+	"periph.io/x/periph/devices"
+)
+
+// PowerMonitor is a measurement of the monitored power rail.
+type PowerMonitor struct {
+	Voltage devices.Volt
+	Current devices.Ampere
+	Power   devices.Watt
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Addr is the I²C address, between 0x40 and 0x4F depending on the A0 and A1
+	// pins.
+	Addr uint16
+	// ShuntResistor is the value of the shunt resistor in mΩ. Only used by the
+	// INA219.
+	ShuntResistor int
+	// MaxCurrent is the maximum expected current, used to select the current
+	// resolution. It is limited by the ±320mV range over the shunt resistor.
+	// Only used by the INA219.
+	MaxCurrent devices.Ampere
+}
+
+// Dev is a handle to an INA219 or INA260.
+type Dev struct {
+	m      mmr.Dev8
+	name   string
+	config uint16
+	// currentLSB is the resolution of the current register in nA.
+	currentLSB int64
+	// powerLSB is the resolution of the power register in nW.
+	powerLSB int64
+	// voltageLSB is the resolution of the bus voltage in µV.
+	voltageLSB int64
+	isINA260   bool
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewINA219 returns an object that communicates over I²C to an INA219.
+//
+// The device is reset and calibrated for the shunt resistor and maximum
+// current specified in opts. It is configured for the 32V bus range with
+// 12 bits conversions in continuous mode.
+func NewINA219(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.ShuntResistor <= 0 {
+		return nil, errors.New("ina2xx: ShuntResistor must be specified")
+	}
+	if opts.MaxCurrent <= 0 {
+		return nil, errors.New("ina2xx: MaxCurrent must be specified")
+	}
+	// The shunt voltage can't exceed 320mV.
+	if int64(opts.MaxCurrent)*int64(opts.ShuntResistor) > 320000 {
+		return nil, errors.New("ina2xx: MaxCurrent exceeds the measurable range for this ShuntResistor")
+	}
+	d, err := newDev(b, opts, "INA219")
+	if err != nil {
+		return nil, err
+	}
+	// Current resolution is MaxCurrent/2^15, rounded up to the nA.
+	d.currentLSB = (int64(opts.MaxCurrent)*1000000 + 32767) / 32768
+	d.powerLSB = 20 * d.currentLSB
+	d.voltageLSB = 4000
+	// Equation 1 on page 12: cal = 0.04096 / (currentLSB * Rshunt).
+	cal := 40960000000 / (d.currentLSB * int64(opts.ShuntResistor))
+	if cal > 0xFFFE {
+		return nil, errors.New("ina2xx: MaxCurrent too small for this ShuntResistor")
+	}
+	d.config = ina219Config
+	if err := d.reset(); err != nil {
+		return nil, err
+	}
+	if err := d.m.WriteUint16(ina219Calibration, uint16(cal)&0xFFFE); err != nil {
+		return nil, fmt.Errorf("ina2xx: %v", err)
+	}
+	return d, nil
+}
+
+// NewINA260 returns an object that communicates over I²C to an INA260.
+//
+// The device is reset and configured for 1.1ms conversions in continuous mode.
+func NewINA260(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	d, err := newDev(b, opts, "INA260")
+	if err != nil {
+		return nil, err
+	}
+	d.isINA260 = true
+	d.currentLSB = 1250000
+	d.powerLSB = 10000000
+	d.voltageLSB = 1250
+	d.config = ina260Config
+	id, err := d.m.ReadUint16(ina260ManufacturerID)
+	if err != nil {
+		return nil, fmt.Errorf("ina2xx: %v", err)
+	}
+	if id != 0x5449 {
+		return nil, fmt.Errorf("ina2xx: unexpected manufacturer ID %#04x; is this an INA260?", id)
+	}
+	if err := d.reset(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.name, d.m.Conn)
+}
+
+// Sense reads the bus voltage, current and power.
+func (d *Dev) Sense(p *PowerMonitor) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("ina2xx: already sensing continuously")
+	}
+	return d.sense(p)
+}
+
+// SenseContinuous returns measurements on a continuous basis.
+//
+// The device samples continuously; this function reads the latest values at
+// the specified interval.
+//
+// The application must call Halt() to stop the sensing when done and close
+// the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan PowerMonitor, error) {
+	if interval <= 0 {
+		return nil, errors.New("ina2xx: invalid interval")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan PowerMonitor)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Halt stops the continuous sensing as initiated by SenseContinuous().
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+// Registers.
+const (
+	regConfig  = 0x00
+	regVoltage = 0x02
+	regPower   = 0x03
+
+	ina219Current     = 0x04
+	ina219Calibration = 0x05
+
+	ina260Current        = 0x01
+	ina260ManufacturerID = 0xFE
+)
+
+const (
+	// configReset is the RST bit, common to both devices.
+	configReset = 0x8000
+	// ina219Config is BRNG=32V, PG=/8, BADC=SADC=12 bits, continuous mode.
+	ina219Config = 0x399F
+	// ina260Config is AVG=1, VBUSCT=ISHCT=1.1ms, continuous mode.
+	ina260Config = 0x6127
+)
+
+func newDev(b i2c.Bus, opts *Opts, name string) (*Dev, error) {
+	addr := opts.Addr
+	if addr == 0 {
+		addr = defaults.Addr
+	}
+	if addr < 0x40 || addr > 0x4F {
+		return nil, errors.New("ina2xx: given address not supported by device")
+	}
+	return &Dev{
+		m:    mmr.Dev8{Conn: &i2c.Dev{Bus: b, Addr: addr}, Order: binary.BigEndian},
+		name: name,
+	}, nil
+}
+
+func (d *Dev) reset() error {
+	if err := d.m.WriteUint16(regConfig, configReset); err != nil {
+		return fmt.Errorf("ina2xx: %v", err)
+	}
+	if err := d.m.WriteUint16(regConfig, d.config); err != nil {
+		return fmt.Errorf("ina2xx: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- PowerMonitor, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var p PowerMonitor
+		d.mu.Lock()
+		err := d.sense(&p)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- p:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sense(p *PowerMonitor) error {
+	v, err := d.m.ReadUint16(regVoltage)
+	if err != nil {
+		return fmt.Errorf("ina2xx: %v", err)
+	}
+	regCurrent := uint8(ina260Current)
+	if !d.isINA260 {
+		// The INA219 bus voltage is in bits 15:3, bit 0 is the math overflow flag.
+		if v&1 != 0 {
+			return errors.New("ina2xx: current or power calculation overflowed")
+		}
+		v >>= 3
+		regCurrent = ina219Current
+	}
+	c, err := d.m.ReadUint16(regCurrent)
+	if err != nil {
+		return fmt.Errorf("ina2xx: %v", err)
+	}
+	w, err := d.m.ReadUint16(regPower)
+	if err != nil {
+		return fmt.Errorf("ina2xx: %v", err)
+	}
+	p.Voltage = devices.Volt(int64(v) * d.voltageLSB / 1000)
+	p.Current = devices.Ampere(int64(int16(c)) * d.currentLSB / 1000000)
+	p.Power = devices.Watt(int64(w) * d.powerLSB / 1000000)
+	return nil
+}
+
+var defaults = Opts{
+	Addr:          0x40,
+	ShuntResistor: 100,
+	MaxCurrent:    3200,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
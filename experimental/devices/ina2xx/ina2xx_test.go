@@ -0,0 +1,191 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ina2xx
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	// 0.1Ω shunt resistor, up to 2A.
+	dev, err := NewINA219(bus, &Opts{Addr: 0x40, ShuntResistor: 100, MaxCurrent: 2000})
+	if err != nil {
+		log.Fatalf("failed to initialize ina219: %v", err)
+	}
+	var p PowerMonitor
+	if err := dev.Sense(&p); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s %s %s\n", p.Voltage, p.Current, p.Power)
+}
+
+//
+
+var ina219Init = []i2ctest.IO{
+	{Addr: 0x40, W: []byte{0x00, 0x80, 0x00}},
+	{Addr: 0x40, W: []byte{0x00, 0x39, 0x9F}},
+	{Addr: 0x40, W: []byte{0x05, 0x10, 0x62}},
+}
+
+func TestNewINA219_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(ina219Init,
+			// 12V with CNVR set, 1000 and 50.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x02}, R: []byte{0x5D, 0xC2}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x04}, R: []byte{0x03, 0xE8}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x03}, R: []byte{0x00, 0x32}},
+			// Math overflow.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x02}, R: []byte{0x5D, 0xC3}},
+		),
+	}
+	dev, err := NewINA219(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "INA219{playback(64)}" {
+		t.Fatal(s)
+	}
+	var p PowerMonitor
+	if err := dev.Sense(&p); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (PowerMonitor{Voltage: 12000, Current: 97, Power: 97}); p != expected {
+		t.Fatalf("%#v != %#v", p, expected)
+	}
+	if err := dev.Sense(&p); err == nil {
+		t.Fatal("overflow")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewINA219_fail(t *testing.T) {
+	data := []Opts{
+		{Addr: 0x40},
+		{Addr: 0x40, ShuntResistor: 100},
+		{Addr: 0x40, ShuntResistor: 100, MaxCurrent: 4000},
+		{Addr: 0x40, ShuntResistor: 100, MaxCurrent: 1},
+		{Addr: 0x50, ShuntResistor: 100, MaxCurrent: 1000},
+	}
+	for i, opts := range data {
+		if _, err := NewINA219(&i2ctest.Playback{}, &opts); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	bus := i2ctest.Playback{DontPanic: true}
+	if _, err := NewINA219(&bus, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	bus = i2ctest.Playback{Ops: ina219Init[:2], DontPanic: true}
+	if _, err := NewINA219(&bus, nil); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestNewINA260_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x41, W: []byte{0xFE}, R: []byte{0x54, 0x49}},
+			{Addr: 0x41, W: []byte{0x00, 0x80, 0x00}},
+			{Addr: 0x41, W: []byte{0x00, 0x61, 0x27}},
+			// 12V, -250mA, 3W.
+			{Addr: 0x41, W: []byte{0x02}, R: []byte{0x25, 0x80}},
+			{Addr: 0x41, W: []byte{0x01}, R: []byte{0xFF, 0x38}},
+			{Addr: 0x41, W: []byte{0x03}, R: []byte{0x01, 0x2C}},
+		},
+	}
+	dev, err := NewINA260(&bus, &Opts{Addr: 0x41})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "INA260{playback(65)}" {
+		t.Fatal(s)
+	}
+	var p PowerMonitor
+	if err := dev.Sense(&p); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (PowerMonitor{Voltage: 12000, Current: -250, Power: 3000}); p != expected {
+		t.Fatalf("%#v != %#v", p, expected)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewINA260_fail(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{{Addr: 0x40, W: []byte{0xFE}, R: []byte{0x00, 0x00}}},
+	}
+	if _, err := NewINA260(&bus, nil); err == nil {
+		t.Fatal("bad manufacturer ID")
+	}
+	bus = i2ctest.Playback{DontPanic: true}
+	if _, err := NewINA260(&bus, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := NewINA260(&bus, &Opts{Addr: 0x20}); err == nil {
+		t.Fatal("bad addr")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	ops := append([]i2ctest.IO{}, ina219Init...)
+	for i := 0; i < 2; i++ {
+		ops = append(ops,
+			i2ctest.IO{Addr: 0x40, W: []byte{0x02}, R: []byte{0x5D, 0xC2}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x04}, R: []byte{0x03, 0xE8}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x03}, R: []byte{0x00, 0x32}})
+	}
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewINA219(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(0); err == nil {
+		t.Fatal("invalid interval")
+	}
+	c, err := dev.SenseContinuous(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if p := <-c; p.Voltage != 12000 {
+			t.Fatal(p)
+		}
+	}
+	var p PowerMonitor
+	if err := dev.Sense(&p); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
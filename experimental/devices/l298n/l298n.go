@@ -0,0 +1,156 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package l298n controls a brushed DC motor through one of the two H-bridges
+// of a STMicroelectronics L298N.
+//
+// The speed is controlled with a PWM on the enable input, ENA or ENB. When
+// the enable pin doesn't implement gpio.PinPWM, e.g. when the jumper is left
+// on the board and a regular GPIO is used, the motor only runs at full speed.
+//
+// Dev implements motor.DC.
+//
+// Datasheet
+//
+// https://www.st.com/resource/en/datasheet/l298.pdf
+package l298n
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/devices/motor"
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Period is the PWM period. 0 uses the optimal value of the pin.
+	//
+	// The L298N switches up to 25kHz but a lower frequency, like 1kHz,
+	// provides more torque at low speed.
+	Period time.Duration
+}
+
+// Dev is a handle to a DC motor driven by a L298N H-bridge.
+type Dev struct {
+	en   gpio.PinOut
+	in1  gpio.PinOut
+	in2  gpio.PinOut
+	opts Opts
+
+	mu sync.Mutex
+}
+
+// New returns an object that drives a DC motor through an H-bridge of a
+// L298N, with en connected to ENA and in1, in2 to IN1 and IN2, or
+// respectively ENB, IN3 and IN4 for the second bridge.
+//
+// The motor is initially stopped, coasting.
+func New(en, in1, in2 gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Period < 0 {
+		return nil, errors.New("l298n: invalid period")
+	}
+	for _, p := range []gpio.PinOut{en, in1, in2} {
+		if p == nil || p == gpio.INVALID {
+			return nil, errors.New("l298n: en, in1 and in2 are required")
+		}
+	}
+	d := &Dev{en: en, in1: in1, in2: in2, opts: *opts}
+	if err := d.Halt(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("L298N{%s, %s, %s}", d.en, d.in1, d.in2)
+}
+
+// Run drives the motor in the direction at the speed, specified as the duty
+// cycle of the PWM on the enable pin.
+func (d *Dev) Run(dir motor.Direction, speed gpio.Duty) error {
+	if dir != motor.Forward && dir != motor.Backward {
+		return fmt.Errorf("l298n: invalid direction %s", dir)
+	}
+	if !speed.Valid() {
+		return fmt.Errorf("l298n: invalid speed %s", speed)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Disable the bridge while switching the direction, to not short the
+	// motor with the inputs momentarily at the same level.
+	if err := d.enable(0); err != nil {
+		return err
+	}
+	if err := d.inputs(dir == motor.Forward, dir == motor.Backward); err != nil {
+		return err
+	}
+	return d.enable(speed)
+}
+
+// Brake stops the motor quickly by shorting its terminals through the
+// H-bridge.
+func (d *Dev) Brake() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.inputs(gpio.Low, gpio.Low); err != nil {
+		return err
+	}
+	return d.enable(gpio.DutyMax)
+}
+
+// Halt disables the H-bridge so the motor coasts to a stop.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.enable(0); err != nil {
+		return err
+	}
+	return d.inputs(gpio.Low, gpio.Low)
+}
+
+//
+
+var defaults = Opts{
+	Period: time.Millisecond,
+}
+
+// enable sets the duty cycle of the enable pin.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) enable(duty gpio.Duty) error {
+	var err error
+	if p, ok := d.en.(gpio.PinPWM); ok {
+		err = p.PWM(duty, d.opts.Period)
+	} else if duty == 0 || duty == gpio.DutyMax {
+		err = d.en.Out(duty == gpio.DutyMax)
+	} else {
+		return fmt.Errorf("l298n: %s doesn't support PWM; speed must be 0 or gpio.DutyMax", d.en)
+	}
+	if err != nil {
+		return fmt.Errorf("l298n: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) inputs(l1, l2 gpio.Level) error {
+	if err := d.in1.Out(l1); err != nil {
+		return fmt.Errorf("l298n: %v", err)
+	}
+	if err := d.in2.Out(l2); err != nil {
+		return fmt.Errorf("l298n: %v", err)
+	}
+	return nil
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ motor.DC = &Dev{}
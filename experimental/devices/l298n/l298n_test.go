@@ -0,0 +1,197 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package l298n
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/experimental/devices/motor"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	dev, err := New(gpioreg.ByName("GPIO18"), gpioreg.ByName("GPIO23"), gpioreg.ByName("GPIO24"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize l298n: %v", err)
+	}
+	defer dev.Halt()
+	if err := dev.Run(motor.Forward, gpio.DutyHalf); err != nil {
+		log.Fatal(err)
+	}
+	time.Sleep(2 * time.Second)
+	if err := dev.Brake(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNew_PWM(t *testing.T) {
+	var l []string
+	en := &pwmPin{recordPin{Pin: gpiotest.Pin{N: "en"}, log: &l}}
+	in1 := &recordPin{Pin: gpiotest.Pin{N: "in1"}, log: &l}
+	in2 := &recordPin{Pin: gpiotest.Pin{N: "in2"}, log: &l}
+	dev, err := New(en, in1, in2, &Opts{Period: 50 * time.Microsecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "L298N{en(0), in1(0), in2(0)}" {
+		t.Fatal(s)
+	}
+	check(t, &l, []string{"en=PWM(0%, 50µs)", "in1=Low", "in2=Low"})
+	if err := dev.Run(motor.Forward, gpio.DutyHalf); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"en=PWM(0%, 50µs)", "in1=High", "in2=Low", "en=PWM(50%, 50µs)"})
+	if err := dev.Run(motor.Backward, gpio.DutyMax/4); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"en=PWM(0%, 50µs)", "in1=Low", "in2=High", "en=PWM(25%, 50µs)"})
+	if err := dev.Brake(); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"in1=Low", "in2=Low", "en=PWM(100%, 50µs)"})
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"en=PWM(0%, 50µs)", "in1=Low", "in2=Low"})
+}
+
+func TestNew_noPWM(t *testing.T) {
+	var l []string
+	en := &recordPin{Pin: gpiotest.Pin{N: "en"}, log: &l}
+	in1 := &recordPin{Pin: gpiotest.Pin{N: "in1"}, log: &l}
+	in2 := &recordPin{Pin: gpiotest.Pin{N: "in2"}, log: &l}
+	dev, err := New(en, in1, in2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"en=Low", "in1=Low", "in2=Low"})
+	if err := dev.Run(motor.Backward, gpio.DutyMax); err != nil {
+		t.Fatal(err)
+	}
+	check(t, &l, []string{"en=Low", "in1=Low", "in2=High", "en=High"})
+	if err := dev.Run(motor.Forward, gpio.DutyHalf); err == nil {
+		t.Fatal("PWM not supported")
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	p := &gpiotest.Pin{}
+	if _, err := New(p, p, p, &Opts{Period: -1}); err == nil {
+		t.Fatal("invalid period")
+	}
+	if _, err := New(p, nil, p, nil); err == nil {
+		t.Fatal("missing pin")
+	}
+	if _, err := New(p, p, gpio.INVALID, nil); err == nil {
+		t.Fatal("invalid pin")
+	}
+	if _, err := New(&failPin{}, p, p, nil); err == nil {
+		t.Fatal("en failed")
+	}
+	if _, err := New(p, &failPin{}, p, nil); err == nil {
+		t.Fatal("in1 failed")
+	}
+	if _, err := New(p, p, &failPin{}, nil); err == nil {
+		t.Fatal("in2 failed")
+	}
+	dev, err := New(p, p, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Run(2, gpio.DutyMax); err == nil {
+		t.Fatal("invalid direction")
+	}
+	if err := dev.Run(motor.Forward, gpio.DutyMax+1); err == nil {
+		t.Fatal("invalid speed")
+	}
+	// New() writes en, in1 and in2 then Run() writes en, in1, in2 and en
+	// again.
+	data := [][2]int{{1, 2}, {2, 1}, {2, 2}}
+	for n, line := range data {
+		dev, err := New(&failPin{after: line[0]}, &failPin{after: line[1]}, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.Run(motor.Forward, gpio.DutyMax); err == nil {
+			t.Fatalf("#%d: write failed", n)
+		}
+	}
+	in1 := &failPin{after: 1}
+	if dev, err = New(p, in1, p, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Brake(); err == nil {
+		t.Fatal("in1 failed")
+	}
+	en := &failPin{after: 1}
+	if dev, err = New(en, p, p, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Brake(); err == nil {
+		t.Fatal("en failed")
+	}
+}
+
+//
+
+func check(t *testing.T, l *[]string, expected []string) {
+	w := *l
+	*l = nil
+	if len(w) != len(expected) {
+		t.Fatalf("%s != %s", w, expected)
+	}
+	for i := range w {
+		if w[i] != expected[i] {
+			t.Fatalf("%s != %s", w, expected)
+		}
+	}
+}
+
+// recordPin logs the pin writes.
+type recordPin struct {
+	gpiotest.Pin
+	log *[]string
+}
+
+func (r *recordPin) Out(l gpio.Level) error {
+	*r.log = append(*r.log, fmt.Sprintf("%s=%s", r.N, l))
+	return nil
+}
+
+// pwmPin is a recordPin implementing gpio.PinPWM.
+type pwmPin struct {
+	recordPin
+}
+
+func (p *pwmPin) PWM(duty gpio.Duty, period time.Duration) error {
+	*p.log = append(*p.log, fmt.Sprintf("%s=PWM(%s, %s)", p.N, duty, period))
+	return nil
+}
+
+// failPin fails after a number of successful writes.
+type failPin struct {
+	gpiotest.Pin
+	after int
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	if f.after == 0 {
+		return errors.New("injected error")
+	}
+	f.after--
+	return nil
+}
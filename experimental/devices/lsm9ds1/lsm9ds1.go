@@ -0,0 +1,578 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package lsm9ds1 controls the accelerometer and magnetometer of a
+// STMicroelectronics LSM9DS1 or LSM303AGR over I²C.
+//
+// Both devices expose the accelerometer and the magnetometer at two different
+// I²C addresses. The gyroscope of the LSM9DS1 is not supported.
+//
+// The magnetometer is corrected for hard-iron and soft-iron distortions with
+// a Calibration, which can be saved and restored by the application. Heading()
+// calculates a tilt compensated compass heading from a Sample.
+//
+// Datasheet
+//
+// https://www.st.com/resource/en/datasheet/lsm9ds1.pdf
+//
+// https://www.st.com/resource/en/datasheet/lsm303agr.pdf
+package lsm9ds1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Acceleration is an acceleration at a precision of 0.001m/s².
+type Acceleration devices.Milli
+
+// String returns the acceleration formatted as a string in m/s².
+func (a Acceleration) String() string {
+	return devices.Milli(a).String() + "m/s²"
+}
+
+// MagneticField is a magnetic flux density at a precision of 0.001µT.
+type MagneticField devices.Milli
+
+// String returns the magnetic flux density formatted as a string in µT.
+func (m MagneticField) String() string {
+	return devices.Milli(m).String() + "µT"
+}
+
+// Sample is one measurement of the accelerometer and the magnetometer.
+//
+// On the LSM9DS1, the axes of the magnetometer are not aligned with the ones
+// of the accelerometer; see figure 1 of the datasheet.
+type Sample struct {
+	Accel [3]Acceleration
+	Mag   [3]MagneticField // Corrected with the Calibration
+}
+
+func (s *Sample) String() string {
+	return fmt.Sprintf("accel=%s mag=%s", s.Accel, s.Mag)
+}
+
+// Model is the device model.
+type Model uint8
+
+// Supported models.
+const (
+	LSM9DS1   Model = 0
+	LSM303AGR Model = 1
+)
+
+const modelName = "LSM9DS1LSM303AGR"
+
+var modelIndex = [...]uint8{0, 7, 16}
+
+func (m Model) String() string {
+	if m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// AccelRange is the full scale range of the accelerometer.
+type AccelRange uint8
+
+// Possible accelerometer ranges.
+const (
+	Accel2G  AccelRange = 0
+	Accel4G  AccelRange = 1
+	Accel8G  AccelRange = 2
+	Accel16G AccelRange = 3
+)
+
+const accelRangeName = "Accel2GAccel4GAccel8GAccel16G"
+
+var accelRangeIndex = [...]uint8{0, 7, 14, 21, 29}
+
+func (a AccelRange) String() string {
+	if a >= AccelRange(len(accelRangeIndex)-1) {
+		return fmt.Sprintf("AccelRange(%d)", a)
+	}
+	return accelRangeName[accelRangeIndex[a]:accelRangeIndex[a+1]]
+}
+
+// MagRange is the full scale range of the magnetometer.
+//
+// It is only supported by the LSM9DS1; the LSM303AGR range is fixed at
+// ±50 gauss.
+type MagRange uint8
+
+// Possible magnetometer ranges.
+const (
+	Mag4Gauss  MagRange = 0
+	Mag8Gauss  MagRange = 1
+	Mag12Gauss MagRange = 2
+	Mag16Gauss MagRange = 3
+)
+
+const magRangeName = "Mag4GaussMag8GaussMag12GaussMag16Gauss"
+
+var magRangeIndex = [...]uint8{0, 9, 18, 28, 38}
+
+func (m MagRange) String() string {
+	if m >= MagRange(len(magRangeIndex)-1) {
+		return fmt.Sprintf("MagRange(%d)", m)
+	}
+	return magRangeName[magRangeIndex[m]:magRangeIndex[m+1]]
+}
+
+// ODR is the output data rate of both the accelerometer and the
+// magnetometer.
+//
+// The closest rate supported by each sensor is used: on the LSM9DS1, ODR50Hz
+// runs the magnetometer at 40Hz and ODR100Hz runs the accelerometer at 119Hz
+// and the magnetometer at 80Hz.
+type ODR uint8
+
+// Possible output data rates.
+const (
+	ODR10Hz  ODR = 0
+	ODR50Hz  ODR = 1
+	ODR100Hz ODR = 2
+)
+
+const odrName = "ODR10HzODR50HzODR100Hz"
+
+var odrIndex = [...]uint8{0, 7, 14, 22}
+
+func (o ODR) String() string {
+	if o >= ODR(len(odrIndex)-1) {
+		return fmt.Sprintf("ODR(%d)", o)
+	}
+	return odrName[odrIndex[o]:odrIndex[o+1]]
+}
+
+// Calibration is the correction applied to the magnetometer readings:
+//
+//	corrected = SoftIron × (raw - HardIron)
+//
+// HardIron is the offset caused by magnetized materials close to the sensor.
+// SoftIron corrects the distortion of the field caused by nearby ferromagnetic
+// materials; it is the identity matrix when there is no distortion.
+//
+// The fields are exported so the calibration can be stored, e.g. as JSON, and
+// restored with SetCalibration().
+type Calibration struct {
+	HardIron [3]MagneticField
+	SoftIron [3][3]float64
+}
+
+// CalibrationFromExtremes returns the calibration for the minimum and maximum
+// values measured on each axis while rotating the device in all directions.
+//
+// Soft-iron distortion is only corrected along the axes.
+func CalibrationFromExtremes(min, max [3]MagneticField) (Calibration, error) {
+	var c Calibration
+	var radius [3]float64
+	avg := 0.
+	for i := range c.HardIron {
+		if max[i] <= min[i] {
+			return c, fmt.Errorf("lsm9ds1: invalid range on axis %d", i)
+		}
+		c.HardIron[i] = (max[i] + min[i]) / 2
+		radius[i] = float64(max[i]-min[i]) / 2
+		avg += radius[i] / 3
+	}
+	for i := range radius {
+		c.SoftIron[i][i] = avg / radius[i]
+	}
+	return c, nil
+}
+
+// Heading returns the compass heading in degrees from 0 to 360 of the X axis,
+// compensated for the tilt of the device as measured by the accelerometer.
+//
+// The magnetometer axes must be aligned with the accelerometer axes. The
+// heading is relative to the magnetic north.
+func Heading(s *Sample) float64 {
+	ax, ay, az := float64(s.Accel[0]), float64(s.Accel[1]), float64(s.Accel[2])
+	mx, my, mz := float64(s.Mag[0]), float64(s.Mag[1]), float64(s.Mag[2])
+	roll := math.Atan2(ay, az)
+	pitch := math.Atan2(-ax, ay*math.Sin(roll)+az*math.Cos(roll))
+	// Project the magnetic field on the horizontal plane.
+	bx := mx*math.Cos(pitch) + my*math.Sin(pitch)*math.Sin(roll) + mz*math.Sin(pitch)*math.Cos(roll)
+	by := my*math.Cos(roll) - mz*math.Sin(roll)
+	h := math.Atan2(-by, bx) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Model Model
+	// AccelAddr and MagAddr default to 0x6B and 0x1E for the LSM9DS1, and
+	// 0x19 and 0x1E for the LSM303AGR.
+	AccelAddr  uint16
+	MagAddr    uint16
+	AccelRange AccelRange
+	MagRange   MagRange
+	ODR        ODR
+}
+
+// Dev is a handle to a LSM9DS1 or LSM303AGR.
+type Dev struct {
+	accel conn.Conn
+	mag   conn.Conn
+
+	mu     sync.Mutex
+	opts   Opts
+	cal    Calibration
+	halted bool
+}
+
+// NewI2C returns an object that communicates over I²C to a LSM9DS1 or
+// LSM303AGR.
+//
+// The device is reset and configured according to opts. The magnetometer is
+// not calibrated.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.Model > LSM303AGR {
+		return nil, fmt.Errorf("lsm9ds1: invalid model %d", o.Model)
+	}
+	if err := checkOpts(o.AccelRange, o.MagRange, o.ODR); err != nil {
+		return nil, err
+	}
+	if o.AccelAddr == 0 {
+		o.AccelAddr = defaultAddr[o.Model][0]
+	}
+	if o.MagAddr == 0 {
+		o.MagAddr = defaultAddr[o.Model][1]
+	}
+	d := &Dev{
+		accel: &i2c.Dev{Bus: b, Addr: o.AccelAddr},
+		mag:   &i2c.Dev{Bus: b, Addr: o.MagAddr},
+		opts:  o,
+		cal:   noCalibration,
+	}
+	ids := whoAmI[o.Model]
+	for i, c := range []conn.Conn{d.accel, d.mag} {
+		var id [1]byte
+		if err := c.Tx([]byte{ids[i][0]}, id[:]); err != nil {
+			return nil, fmt.Errorf("lsm9ds1: %v", err)
+		}
+		if id[0] != ids[i][1] {
+			return nil, fmt.Errorf("lsm9ds1: unexpected id %#x on %s", id[0], c)
+		}
+	}
+	var reset [2][2]byte
+	if o.Model == LSM9DS1 {
+		// SW_RESET and IF_ADD_INC; SOFT_RST.
+		reset = [2][2]byte{{regCtrl8, 0x05}, {regCtrl2M, 0x04}}
+	} else {
+		// BOOT; SOFT_RST.
+		reset = [2][2]byte{{regCtrl5A, 0x80}, {regCfgAM, 0x20}}
+	}
+	if err := d.accel.Tx(reset[0][:], nil); err != nil {
+		return nil, fmt.Errorf("lsm9ds1: %v", err)
+	}
+	if err := d.mag.Tx(reset[1][:], nil); err != nil {
+		return nil, fmt.Errorf("lsm9ds1: %v", err)
+	}
+	sleep(10 * time.Millisecond)
+	if err := d.configure(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s, %s}", d.opts.Model, d.accel, d.mag)
+}
+
+// Sense reads the latest sample.
+func (d *Dev) Sense(s *Sample) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.halted {
+		if err := d.configure(); err != nil {
+			return err
+		}
+	}
+	var a, m [6]byte
+	if err := d.accel.Tx([]byte{d.accelOut()}, a[:]); err != nil {
+		return fmt.Errorf("lsm9ds1: %v", err)
+	}
+	if err := d.mag.Tx([]byte{d.magOut()}, m[:]); err != nil {
+		return fmt.Errorf("lsm9ds1: %v", err)
+	}
+	for i := range s.Accel {
+		raw := int64(int16(uint16(a[2*i+1])<<8 | uint16(a[2*i])))
+		var ug int64
+		if d.opts.Model == LSM9DS1 {
+			ug = raw * lsm9ds1AccelLSB[d.opts.AccelRange]
+		} else {
+			// 12 bits left justified in high resolution mode.
+			ug = (raw >> 4) * lsm303AccelLSB[d.opts.AccelRange]
+		}
+		// 9.80665m/s² per g.
+		s.Accel[i] = Acceleration(ug * 980665 / 100000000)
+	}
+	var raw [3]float64
+	for i := range raw {
+		v := int64(int16(uint16(m[2*i+1])<<8 | uint16(m[2*i])))
+		if d.opts.Model == LSM9DS1 {
+			v *= lsm9ds1MagLSB[d.opts.MagRange]
+		} else {
+			v *= lsm303MagLSB
+		}
+		raw[i] = float64(v - int64(d.cal.HardIron[i]))
+	}
+	for i := range s.Mag {
+		v := 0.
+		for j := range raw {
+			v += d.cal.SoftIron[i][j] * raw[j]
+		}
+		s.Mag[i] = MagneticField(math.Floor(v + 0.5))
+	}
+	return nil
+}
+
+// SetCalibration sets the magnetometer correction.
+func (d *Dev) SetCalibration(c Calibration) error {
+	if c.SoftIron == ([3][3]float64{}) {
+		return errors.New("lsm9ds1: soft-iron matrix must not be zero")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cal = c
+	return nil
+}
+
+// Calibration returns the current magnetometer correction.
+func (d *Dev) Calibration() Calibration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cal
+}
+
+// SetODR changes the output data rate of both sensors.
+func (d *Dev) SetODR(o ODR) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := checkOpts(d.opts.AccelRange, d.opts.MagRange, o); err != nil {
+		return err
+	}
+	d.opts.ODR = o
+	return d.configure()
+}
+
+// SetRanges changes the full scale ranges of the accelerometer and the
+// magnetometer.
+//
+// The calibration is not adjusted; it is expressed in µT and stays valid.
+func (d *Dev) SetRanges(a AccelRange, m MagRange) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := checkOpts(a, m, d.opts.ODR); err != nil {
+		return err
+	}
+	d.opts.AccelRange = a
+	d.opts.MagRange = m
+	return d.configure()
+}
+
+// Halt powers down both sensors.
+//
+// They are powered up again on the next call to Sense().
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var w [2][2]byte
+	if d.opts.Model == LSM9DS1 {
+		w = [2][2]byte{{regCtrl6XL, 0x00}, {regCtrl3M, 0x03}}
+	} else {
+		w = [2][2]byte{{regCtrl1A, 0x00}, {regCfgAM, 0x03}}
+	}
+	if err := d.accel.Tx(w[0][:], nil); err != nil {
+		return fmt.Errorf("lsm9ds1: %v", err)
+	}
+	if err := d.mag.Tx(w[1][:], nil); err != nil {
+		return fmt.Errorf("lsm9ds1: %v", err)
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	regWhoAmI = 0x0F
+
+	// LSM9DS1 accelerometer and gyroscope.
+	regCtrl6XL  = 0x20
+	regCtrl8    = 0x22
+	regOutXLXL  = 0x28
+	lsm9ds1AGID = 0x68
+
+	// LSM9DS1 magnetometer.
+	regCtrl1M  = 0x20
+	regCtrl2M  = 0x21
+	regCtrl3M  = 0x22
+	regCtrl4M  = 0x23
+	regCtrl5M  = 0x24
+	regOutXLM  = 0x28
+	lsm9ds1MID = 0x3D
+
+	// LSM303AGR accelerometer.
+	regCtrl1A  = 0x20
+	regCtrl4A  = 0x23
+	regCtrl5A  = 0x24
+	regOutXLA  = 0x28
+	lsm303AID  = 0x33
+	regWhoAmIM = 0x4F
+
+	// LSM303AGR magnetometer.
+	regCfgAM  = 0x60
+	regCfgBM  = 0x61
+	regCfgCM  = 0x62
+	regOutXM  = 0x68
+	lsm303MID = 0x40
+
+	// autoInc sets the register address auto increment on multiple bytes
+	// reads, when it is not enabled by default.
+	autoInc = 0x80
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// defaultAddr is the accelerometer and magnetometer addresses, indexed by
+// Model.
+var defaultAddr = [...][2]uint16{{0x6B, 0x1E}, {0x19, 0x1E}}
+
+// whoAmI is the identification register and value of the accelerometer and
+// the magnetometer, indexed by Model.
+var whoAmI = [...][2][2]byte{
+	{{regWhoAmI, lsm9ds1AGID}, {regWhoAmI, lsm9ds1MID}},
+	{{regWhoAmI, lsm303AID}, {regWhoAmIM, lsm303MID}},
+}
+
+// lsm9ds1AccelLSB is the sensitivity in µg per LSB, indexed by AccelRange.
+var lsm9ds1AccelLSB = [...]int64{61, 122, 244, 732}
+
+// lsm9ds1AccelFS is the FS_XL value, indexed by AccelRange.
+var lsm9ds1AccelFS = [...]byte{0, 2, 3, 1}
+
+// lsm9ds1AccelODR and lsm9ds1MagODR are the ODR_XL and DO values, indexed by
+// ODR.
+var lsm9ds1AccelODR = [...]byte{1, 2, 3}
+var lsm9ds1MagODR = [...]byte{4, 6, 7}
+
+// lsm9ds1MagLSB is the sensitivity in 0.001µT per LSB, indexed by MagRange.
+var lsm9ds1MagLSB = [...]int64{14, 29, 43, 58}
+
+// lsm303AccelLSB is the sensitivity in µg per LSB in high resolution mode,
+// indexed by AccelRange.
+var lsm303AccelLSB = [...]int64{980, 1950, 3900, 11720}
+
+// lsm303AccelODR and lsm303MagODR are the ODR values, indexed by ODR.
+var lsm303AccelODR = [...]byte{2, 4, 5}
+var lsm303MagODR = [...]byte{0, 2, 3}
+
+// lsm303MagLSB is the sensitivity in 0.001µT per LSB.
+const lsm303MagLSB = 150
+
+var noCalibration = Calibration{SoftIron: [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}}
+
+func checkOpts(a AccelRange, m MagRange, o ODR) error {
+	if a > Accel16G {
+		return fmt.Errorf("lsm9ds1: invalid accelerometer range %d", a)
+	}
+	if m > Mag16Gauss {
+		return fmt.Errorf("lsm9ds1: invalid magnetometer range %d", m)
+	}
+	if o > ODR100Hz {
+		return fmt.Errorf("lsm9ds1: invalid output data rate %d", o)
+	}
+	return nil
+}
+
+// configure writes the configuration of both sensors and powers them up.
+func (d *Dev) configure() error {
+	var a, m [][2]byte
+	if d.opts.Model == LSM9DS1 {
+		a = [][2]byte{
+			// BDU and IF_ADD_INC.
+			{regCtrl8, 0x44},
+			{regCtrl6XL, lsm9ds1AccelODR[d.opts.ODR]<<5 | lsm9ds1AccelFS[d.opts.AccelRange]<<3},
+		}
+		m = [][2]byte{
+			// Temperature compensation, X and Y ultra-high performance.
+			{regCtrl1M, 0x80 | 0x60 | lsm9ds1MagODR[d.opts.ODR]<<2},
+			{regCtrl2M, byte(d.opts.MagRange) << 5},
+			// Z ultra-high performance and BDU.
+			{regCtrl4M, 0x0C},
+			{regCtrl5M, 0x40},
+			// Continuous conversion.
+			{regCtrl3M, 0x00},
+		}
+	} else {
+		a = [][2]byte{
+			// BDU and high resolution.
+			{regCtrl4A, 0x88 | byte(d.opts.AccelRange)<<4},
+			{regCtrl1A, lsm303AccelODR[d.opts.ODR]<<4 | 0x07},
+		}
+		m = [][2]byte{
+			// Offset cancellation and BDU.
+			{regCfgBM, 0x02},
+			{regCfgCM, 0x10},
+			// Temperature compensation and continuous conversion.
+			{regCfgAM, 0x80 | lsm303MagODR[d.opts.ODR]<<2},
+		}
+	}
+	for _, r := range a {
+		if err := d.accel.Tx(r[:], nil); err != nil {
+			return fmt.Errorf("lsm9ds1: %v", err)
+		}
+	}
+	for _, r := range m {
+		if err := d.mag.Tx(r[:], nil); err != nil {
+			return fmt.Errorf("lsm9ds1: %v", err)
+		}
+	}
+	d.halted = false
+	return nil
+}
+
+// accelOut returns the address to read the 3 accelerometer axes.
+func (d *Dev) accelOut() byte {
+	if d.opts.Model == LSM9DS1 {
+		return regOutXLXL
+	}
+	return regOutXLA | autoInc
+}
+
+// magOut returns the address to read the 3 magnetometer axes.
+func (d *Dev) magOut() byte {
+	if d.opts.Model == LSM9DS1 {
+		return regOutXLM | autoInc
+	}
+	return regOutXM
+}
+
+var defaults = Opts{
+	Model:      LSM9DS1,
+	AccelRange: Accel2G,
+	MagRange:   Mag4Gauss,
+	ODR:        ODR10Hz,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,339 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package lsm9ds1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, &Opts{Model: LSM303AGR, ODR: ODR50Hz})
+	if err != nil {
+		log.Fatalf("failed to initialize lsm9ds1: %v", err)
+	}
+	defer dev.Halt()
+	// Restore a calibration saved by a previous run.
+	var c Calibration
+	if err := json.Unmarshal([]byte(`{"HardIron":[1200,-3400,560],"SoftIron":[[1.02,0,0],[0,0.97,0],[0,0,1.01]]}`), &c); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.SetCalibration(c); err != nil {
+		log.Fatal(err)
+	}
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s heading=%.1f°\n", &s, Heading(&s))
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewI2C_LSM9DS1(t *testing.T) {
+	f := newFakeBus(LSM9DS1)
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "LSM9DS1{fake(107), fake(30)}" {
+		t.Fatal(s)
+	}
+	expected := map[uint16]map[byte]byte{
+		0x6B: {0x20: 0x20, 0x22: 0x44},
+		0x1E: {0x20: 0xF0, 0x21: 0x00, 0x22: 0x00, 0x23: 0x0C, 0x24: 0x40},
+	}
+	f.check(t, expected)
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Accel != [3]Acceleration{0, -4900, 9801} {
+		t.Fatal(s.Accel)
+	}
+	if s.Mag != [3]MagneticField{14000, -28000, 7000} {
+		t.Fatal(s.Mag)
+	}
+	if str := s.String(); str != "accel=[0.000m/s² -4.900m/s² 9.801m/s²] mag=[14.000µT -28.000µT 7.000µT]" {
+		t.Fatal(str)
+	}
+	if err := dev.SetRanges(Accel8G, Mag16Gauss); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetODR(ODR100Hz); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[uint16]map[byte]byte{
+		0x6B: {0x20: 0x78},
+		0x1E: {0x20: 0xFC, 0x21: 0x60},
+	}
+	f.check(t, expected)
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Mag != [3]MagneticField{58000, -116000, 29000} {
+		t.Fatal(s.Mag)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[uint16]map[byte]byte{0x6B: {0x20: 0x00}, 0x1E: {0x22: 0x03}})
+	// Powered up again.
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[uint16]map[byte]byte{0x6B: {0x20: 0x78}, 0x1E: {0x22: 0x00}})
+}
+
+func TestNewI2C_LSM303AGR(t *testing.T) {
+	f := newFakeBus(LSM303AGR)
+	dev, err := NewI2C(f, &Opts{Model: LSM303AGR, AccelRange: Accel4G, ODR: ODR50Hz})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "LSM303AGR{fake(25), fake(30)}" {
+		t.Fatal(s)
+	}
+	expected := map[uint16]map[byte]byte{
+		0x19: {0x20: 0x47, 0x23: 0x98},
+		0x1E: {0x60: 0x88, 0x61: 0x02, 0x62: 0x10},
+	}
+	f.check(t, expected)
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Accel != [3]Acceleration{0, -9790, 19581} {
+		t.Fatal(s.Accel)
+	}
+	if s.Mag != [3]MagneticField{150000, -300000, 75000} {
+		t.Fatal(s.Mag)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, map[uint16]map[byte]byte{0x19: {0x20: 0x00}, 0x1E: {0x60: 0x03}})
+}
+
+func TestCalibration(t *testing.T) {
+	f := newFakeBus(LSM9DS1)
+	dev, err := NewI2C(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := dev.Calibration(); c != noCalibration {
+		t.Fatal(c)
+	}
+	c, err := CalibrationFromExtremes([3]MagneticField{-6000, -48000, -3000}, [3]MagneticField{34000, 12000, 17000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Radii are 20, 30 and 10µT.
+	expected := Calibration{
+		HardIron: [3]MagneticField{14000, -18000, 7000},
+		SoftIron: [3][3]float64{{1, 0, 0}, {0, 2. / 3, 0}, {0, 0, 2}},
+	}
+	if c.HardIron != expected.HardIron {
+		t.Fatal(c)
+	}
+	for i := range c.SoftIron {
+		for j := range c.SoftIron[i] {
+			if math.Abs(c.SoftIron[i][j]-expected.SoftIron[i][j]) > 1e-9 {
+				t.Fatal(c)
+			}
+		}
+	}
+	if err := dev.SetCalibration(c); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Calibration() != c {
+		t.Fatal("calibration not stored")
+	}
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Mag != [3]MagneticField{0, -6667, 0} {
+		t.Fatal(s.Mag)
+	}
+	if _, err := CalibrationFromExtremes([3]MagneticField{}, [3]MagneticField{}); err == nil {
+		t.Fatal("invalid extremes")
+	}
+	if err := dev.SetCalibration(Calibration{}); err == nil {
+		t.Fatal("zero soft-iron matrix")
+	}
+}
+
+func TestHeading(t *testing.T) {
+	data := []struct {
+		s Sample
+		h float64
+	}{
+		// Flat, pointing north.
+		{Sample{Accel: [3]Acceleration{0, 0, 9806}, Mag: [3]MagneticField{20000, 0, -40000}}, 0},
+		// Flat, pointing west.
+		{Sample{Accel: [3]Acceleration{0, 0, 9806}, Mag: [3]MagneticField{0, 20000, -40000}}, 270},
+		// Flat, pointing east.
+		{Sample{Accel: [3]Acceleration{0, 0, 9806}, Mag: [3]MagneticField{0, -20000, -40000}}, 90},
+		// Rolled 90° on the side, pointing east.
+		{Sample{Accel: [3]Acceleration{0, 9806, 0}, Mag: [3]MagneticField{0, -40000, 20000}}, 90},
+	}
+	for i, line := range data {
+		if h := Heading(&line.s); math.Abs(h-line.h) > 0.01 {
+			t.Fatalf("#%d: %f != %f", i, h, line.h)
+		}
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	data := []Opts{
+		{Model: 2},
+		{AccelRange: 4},
+		{MagRange: 4},
+		{ODR: 3},
+	}
+	for i, opts := range data {
+		if _, err := NewI2C(newFakeBus(LSM9DS1), &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	// Wrong model.
+	if _, err := NewI2C(newFakeBus(LSM303AGR), &Opts{AccelAddr: 0x19}); err == nil {
+		t.Fatal("invalid id")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	dev, err := NewI2C(newFakeBus(LSM9DS1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetODR(3); err == nil {
+		t.Fatal("invalid odr")
+	}
+	if err := dev.SetRanges(0, 4); err == nil {
+		t.Fatal("invalid range")
+	}
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+	if s := LSM303AGR.String(); s != "LSM303AGR" {
+		t.Fatal(s)
+	}
+	if s := Accel16G.String(); s != "Accel16G" {
+		t.Fatal(s)
+	}
+	if s := AccelRange(4).String(); s != "AccelRange(4)" {
+		t.Fatal(s)
+	}
+	if s := Mag12Gauss.String(); s != "Mag12Gauss" {
+		t.Fatal(s)
+	}
+	if s := MagRange(4).String(); s != "MagRange(4)" {
+		t.Fatal(s)
+	}
+	if s := ODR100Hz.String(); s != "ODR100Hz" {
+		t.Fatal(s)
+	}
+	if s := ODR(3).String(); s != "ODR(3)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+// fakeBus emulates the register maps of both sensors, including the register
+// address auto increment.
+type fakeBus struct {
+	regs map[uint16]map[byte]byte
+	// msbInc is set for the addresses where the MSB of the register address
+	// enables auto increment.
+	msbInc map[uint16]bool
+}
+
+func newFakeBus(m Model) *fakeBus {
+	// Accel: 0, -0.5, 1g on ±2g. Mag: 1000, -2000, 500.
+	accel := []byte{0x00, 0x00, 0x00, 0xE0, 0x00, 0x40}
+	mag := []byte{0xE8, 0x03, 0x30, 0xF8, 0xF4, 0x01}
+	f := &fakeBus{regs: map[uint16]map[byte]byte{}, msbInc: map[uint16]bool{}}
+	if m == LSM9DS1 {
+		f.regs[0x6B] = map[byte]byte{0x0F: 0x68}
+		f.regs[0x1E] = map[byte]byte{0x0F: 0x3D}
+		f.msbInc[0x1E] = true
+		for i := range accel {
+			f.regs[0x6B][0x28+byte(i)] = accel[i]
+			f.regs[0x1E][0x28+byte(i)] = mag[i]
+		}
+	} else {
+		f.regs[0x19] = map[byte]byte{0x0F: 0x33}
+		f.regs[0x1E] = map[byte]byte{0x4F: 0x40}
+		f.msbInc[0x19] = true
+		for i := range accel {
+			f.regs[0x19][0x28+byte(i)] = accel[i]
+			f.regs[0x1E][0x68+byte(i)] = mag[i]
+		}
+	}
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	regs := f.regs[addr]
+	if regs == nil {
+		return fmt.Errorf("no device at %#x", addr)
+	}
+	reg := w[0]
+	inc := byte(1)
+	if f.msbInc[addr] {
+		if reg&0x80 == 0 {
+			inc = 0
+		}
+		reg &^= 0x80
+	}
+	for i, v := range w[1:] {
+		regs[reg+inc*byte(i)] = v
+	}
+	for i := range r {
+		r[i] = regs[reg+inc*byte(i)]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) check(t *testing.T, expected map[uint16]map[byte]byte) {
+	for addr, regs := range expected {
+		for reg, v := range regs {
+			if r := f.regs[addr][reg]; r != v {
+				t.Fatalf("%#x %#x: %#x != %#x", addr, reg, r, v)
+			}
+		}
+	}
+}
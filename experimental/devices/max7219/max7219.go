@@ -0,0 +1,311 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package max7219 controls one or multiple cascaded Maxim MAX7219 or MAX7221
+// LED display drivers over SPI.
+//
+// Each device drives either 8 digits of a 7-segment display or an 8x8 LED
+// matrix. Devices are cascaded by connecting DOUT of one to DIN of the next
+// one; they then share the same CS line.
+//
+// Dev implements devices.Display for chains of 8x8 matrices, with the first
+// device in the chain, the one connected to MOSI, being the leftmost one.
+//
+// Datasheet
+//
+// https://datasheets.maximintegrated.com/en/ds/MAX7219-MAX7221.pdf
+package max7219
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/ssd1306/image1bit"
+)
+
+// Code B font values, to use with SetDigits() when decoding is enabled.
+//
+// Digits 0 to 9 are represented by their value. Add DP to light the decimal
+// point.
+const (
+	Minus byte = 0x0A
+	E     byte = 0x0B
+	H     byte = 0x0C
+	L     byte = 0x0D
+	P     byte = 0x0E
+	Blank byte = 0x0F
+	DP    byte = 0x80
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Cascaded is the number of devices in the chain. Defaults to 1.
+	Cascaded int
+	// Digits is the number of digits or rows scanned per device, from 1 to 8.
+	// Defaults to 8.
+	Digits int
+	// Decode enables Code B decoding for 7-segment displays.
+	Decode bool
+	// Intensity is the brightness from 0 to 15.
+	Intensity uint8
+}
+
+// Dev is a handle to a chain of MAX7219.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+	rect image.Rectangle
+
+	// buffer is the content of the digit registers, 8 bytes per device.
+	buffer []byte
+	halted bool
+	err    error
+}
+
+// NewSPI returns an object that communicates over SPI to one or multiple
+// cascaded MAX7219.
+//
+// The displays are cleared.
+func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.Cascaded == 0 {
+		o.Cascaded = 1
+	}
+	if o.Digits == 0 {
+		o.Digits = 8
+	}
+	if o.Cascaded < 0 || o.Digits < 0 || o.Digits > 8 {
+		return nil, fmt.Errorf("max7219: invalid options %d devices, %d digits", o.Cascaded, o.Digits)
+	}
+	if o.Intensity > 15 {
+		return nil, fmt.Errorf("max7219: invalid intensity %d", o.Intensity)
+	}
+	// The MAX7221 is rated at 10MHz.
+	c, err := p.Connect(10000000, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("max7219: %v", err)
+	}
+	d := &Dev{
+		c:      c,
+		opts:   o,
+		rect:   image.Rect(0, 0, 8*o.Cascaded, 8),
+		buffer: make([]byte, 8*o.Cascaded),
+	}
+	decode := byte(0)
+	if o.Decode {
+		decode = 0xFF
+	}
+	for _, r := range [][2]byte{
+		{regDisplayTest, 0},
+		{regScanLimit, byte(o.Digits - 1)},
+		{regDecode, decode},
+		{regIntensity, o.Intensity},
+	} {
+		if err := d.sendAll(r[0], r[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.flush(0, 8); err != nil {
+		return nil, err
+	}
+	if err := d.sendAll(regShutdown, 1); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MAX7219{%s, %d}", d.c, d.opts.Cascaded)
+}
+
+// SetIntensity changes the brightness of all devices, from 0 to 15.
+func (d *Dev) SetIntensity(i uint8) error {
+	if i > 15 {
+		return fmt.Errorf("max7219: invalid intensity %d", i)
+	}
+	d.opts.Intensity = i
+	return d.sendAll(regIntensity, i)
+}
+
+// SetDecode enables or disables Code B decoding on all devices.
+//
+// The digits are not updated; call SetDigits() afterward.
+func (d *Dev) SetDecode(decode bool) error {
+	d.opts.Decode = decode
+	v := byte(0)
+	if decode {
+		v = 0xFF
+	}
+	return d.sendAll(regDecode, v)
+}
+
+// SetDigits sets the digits of one device in the chain.
+//
+// digits[0] is for digit 0, which is the rightmost one on most modules. When
+// decoding is enabled, each value is a Code B font value. Otherwise each value
+// is the raw segments encoded as PABCDEFG:
+//
+//     -A-
+//    F   B
+//     -G-
+//    E   C
+//     -D-   P
+func (d *Dev) SetDigits(device int, digits []byte) error {
+	if device < 0 || device >= d.opts.Cascaded {
+		return fmt.Errorf("max7219: invalid device %d", device)
+	}
+	if len(digits) > 8 {
+		return errors.New("max7219: too many digits")
+	}
+	copy(d.buffer[8*device:], digits)
+	return d.flush(0, len(digits))
+}
+
+// ColorModel implements devices.Display.
+//
+// It is a one bit color model, as implemented by image1bit.Bit.
+func (d *Dev) ColorModel() color.Model {
+	return image1bit.BitModel
+}
+
+// Bounds implements devices.Display. Min is guaranteed to be {0, 0}. The
+// width is 8 pixels per device.
+func (d *Dev) Bounds() image.Rectangle {
+	return d.rect
+}
+
+// Draw implements devices.Display for 8x8 matrices.
+//
+// Row 0 is driven by digit 0 and the leftmost column by segment P. Only the
+// rows that changed are sent.
+//
+// It discards any failure; use Err() to retrieve it.
+func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
+	clip := r.Intersect(d.rect)
+	if clip.Empty() {
+		return
+	}
+	sp = sp.Add(clip.Min.Sub(r.Min))
+	r = clip
+	start, end := 8, 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := image1bit.BitModel.Convert(src.At(sp.X+x-r.Min.X, sp.Y+y-r.Min.Y)).(image1bit.Bit)
+			i := 8*(x/8) + y
+			mask := byte(0x80) >> uint(x%8)
+			v := d.buffer[i] &^ mask
+			if c {
+				v |= mask
+			}
+			if v != d.buffer[i] {
+				d.buffer[i] = v
+				if y < start {
+					start = y
+				}
+				if y >= end {
+					end = y + 1
+				}
+			}
+		}
+	}
+	d.err = nil
+	if start < end {
+		d.err = d.flush(start, end)
+	}
+}
+
+// Err returns the last error that occurred in Draw().
+func (d *Dev) Err() error {
+	return d.err
+}
+
+// Write writes the raw digit registers of all devices.
+//
+// The buffer must be 8 bytes per device, with the first device first.
+func (d *Dev) Write(b []byte) (int, error) {
+	if len(b) != len(d.buffer) {
+		return 0, fmt.Errorf("max7219: invalid buffer length; expected %d bytes, got %d bytes", len(d.buffer), len(b))
+	}
+	copy(d.buffer, b)
+	if err := d.flush(0, 8); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Halt puts all devices in shutdown mode, turning the LEDs off.
+//
+// Sending any digit afterward reenables the display.
+func (d *Dev) Halt() error {
+	if err := d.sendAll(regShutdown, 0); err != nil {
+		return err
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	regDigit0      = 0x01
+	regDecode      = 0x09
+	regIntensity   = 0x0A
+	regScanLimit   = 0x0B
+	regShutdown    = 0x0C
+	regDisplayTest = 0x0F
+)
+
+// flush sends the digit registers from start to end of all devices.
+func (d *Dev) flush(start, end int) error {
+	if d.halted {
+		if err := d.sendAll(regShutdown, 1); err != nil {
+			return err
+		}
+		d.halted = false
+	}
+	n := d.opts.Cascaded
+	w := make([]byte, 2*n)
+	for digit := start; digit < end; digit++ {
+		// The first bytes shifted in end up in the last device of the chain.
+		for i := 0; i < n; i++ {
+			w[2*i] = byte(regDigit0 + digit)
+			w[2*i+1] = d.buffer[8*(n-1-i)+digit]
+		}
+		if err := d.c.Tx(w, nil); err != nil {
+			return fmt.Errorf("max7219: %v", err)
+		}
+	}
+	return nil
+}
+
+// sendAll writes the same value to the register of all devices.
+func (d *Dev) sendAll(reg, v byte) error {
+	w := make([]byte, 2*d.opts.Cascaded)
+	for i := 0; i < len(w); i += 2 {
+		w[i] = reg
+		w[i+1] = v
+	}
+	if err := d.c.Tx(w, nil); err != nil {
+		return fmt.Errorf("max7219: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	Cascaded:  1,
+	Digits:    8,
+	Intensity: 7,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.Display = &Dev{}
+var _ fmt.Stringer = &Dev{}
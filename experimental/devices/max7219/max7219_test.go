@@ -0,0 +1,244 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package max7219
+
+import (
+	"errors"
+	"image"
+	"log"
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/devices/ssd1306/image1bit"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dev, err := NewSPI(p, &Opts{Decode: true, Intensity: 8})
+	if err != nil {
+		log.Fatalf("failed to initialize max7219: %v", err)
+	}
+	// Displays "-12.5" right aligned on a 8 digits display.
+	if err := dev.SetDigits(0, []byte{5, 2 | DP, 1, Minus, Blank, Blank, Blank, Blank}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Example_matrix() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	// Four 8x8 matrices.
+	dev, err := NewSPI(p, &Opts{Cascaded: 4})
+	if err != nil {
+		log.Fatalf("failed to initialize max7219: %v", err)
+	}
+	img := image1bit.NewVerticalLSB(dev.Bounds())
+	for x := 0; x < 32; x++ {
+		img.SetBit(x, x%8, image1bit.On)
+	}
+	dev.Draw(dev.Bounds(), img, image.Point{})
+	if err := dev.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNewSPI_SetDigits(t *testing.T) {
+	p := spitest.Record{}
+	d, err := NewSPI(&p, &Opts{Digits: 4, Decode: true, Intensity: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "MAX7219{record, 1}" {
+		t.Fatal(s)
+	}
+	if err := d.SetDigits(0, []byte{1, 2 | DP}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetIntensity(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetDecode(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetDigits(0, []byte{0x7E}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []conntest.IO{
+		{W: []byte{0x0F, 0x00}},
+		{W: []byte{0x0B, 0x03}},
+		{W: []byte{0x09, 0xFF}},
+		{W: []byte{0x0A, 0x0F}},
+		{W: []byte{0x01, 0x00}},
+		{W: []byte{0x02, 0x00}},
+		{W: []byte{0x03, 0x00}},
+		{W: []byte{0x04, 0x00}},
+		{W: []byte{0x05, 0x00}},
+		{W: []byte{0x06, 0x00}},
+		{W: []byte{0x07, 0x00}},
+		{W: []byte{0x08, 0x00}},
+		{W: []byte{0x0C, 0x01}},
+		{W: []byte{0x01, 0x01}},
+		{W: []byte{0x02, 0x82}},
+		{W: []byte{0x0A, 0x03}},
+		{W: []byte{0x09, 0x00}},
+		{W: []byte{0x0C, 0x00}},
+		{W: []byte{0x0C, 0x01}},
+		{W: []byte{0x01, 0x7E}},
+	}
+	if !reflect.DeepEqual(expected, p.Ops) {
+		t.Fatalf("%#v", p.Ops)
+	}
+	if err := d.SetDigits(1, nil); err == nil {
+		t.Fatal("invalid device")
+	}
+	if err := d.SetDigits(0, make([]byte, 9)); err == nil {
+		t.Fatal("too many digits")
+	}
+	if err := d.SetIntensity(16); err == nil {
+		t.Fatal("invalid intensity")
+	}
+}
+
+func TestNewSPI_Draw_cascaded(t *testing.T) {
+	p := spitest.Record{}
+	d, err := NewSPI(&p, &Opts{Cascaded: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Ops) != 4+8+1 {
+		t.Fatal(len(p.Ops))
+	}
+	if !reflect.DeepEqual(p.Ops[0], conntest.IO{W: []byte{0x0F, 0x00, 0x0F, 0x00}}) {
+		t.Fatal(p.Ops[0])
+	}
+	p.Ops = nil
+	if m := d.ColorModel(); m != image1bit.BitModel {
+		t.Fatal(m)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 16, 8) {
+		t.Fatal(r)
+	}
+	img := image1bit.NewVerticalLSB(image.Rect(0, 0, 16, 8))
+	img.SetBit(0, 1, image1bit.On)
+	img.SetBit(15, 1, image1bit.On)
+	img.SetBit(9, 3, image1bit.On)
+	d.Draw(d.Bounds(), img, image.Point{})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Row 1 and 3 are sent; the last device of the chain is sent first.
+	expected := []conntest.IO{
+		{W: []byte{0x02, 0x01, 0x02, 0x80}},
+		{W: []byte{0x03, 0x00, 0x03, 0x00}},
+		{W: []byte{0x04, 0x40, 0x04, 0x00}},
+	}
+	if !reflect.DeepEqual(expected, p.Ops) {
+		t.Fatalf("%#v", p.Ops)
+	}
+	p.Ops = nil
+	// Nothing changed.
+	d.Draw(d.Bounds(), img, image.Point{})
+	// Outside the display.
+	d.Draw(image.Rect(16, 0, 24, 8), img, image.Point{})
+	if len(p.Ops) != 0 {
+		t.Fatal(p.Ops)
+	}
+	if _, err := d.Write(make([]byte, 3)); err == nil {
+		t.Fatal("invalid length")
+	}
+	if _, err := d.Write(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Ops) != 8 {
+		t.Fatal(len(p.Ops))
+	}
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	if _, err := NewSPI(&spitest.Record{}, &Opts{Digits: 9}); err == nil {
+		t.Fatal("invalid digits")
+	}
+	if _, err := NewSPI(&spitest.Record{}, &Opts{Intensity: 16}); err == nil {
+		t.Fatal("invalid intensity")
+	}
+	if _, err := NewSPI(&spitest.Record{Initialized: true}, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+	if _, err := NewSPI(&failPort{}, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+	d, err := NewSPI(&failPort{after: 4}, nil)
+	if d != nil || err == nil {
+		t.Fatal("flush failed")
+	}
+	d, err = NewSPI(&failPort{after: 13}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image1bit.NewVerticalLSB(d.Bounds())
+	img.SetBit(0, 0, image1bit.On)
+	d.Draw(d.Bounds(), img, image.Point{})
+	if d.Err() == nil {
+		t.Fatal("draw failed")
+	}
+	if err := d.Halt(); err == nil {
+		t.Fatal("halt failed")
+	}
+}
+
+//
+
+// failPort fails all transactions after a number of successful ones.
+type failPort struct {
+	after int
+}
+
+func (f *failPort) String() string {
+	return "fail"
+}
+
+func (f *failPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	return f, nil
+}
+
+func (f *failPort) Tx(w, r []byte) error {
+	if f.after == 0 {
+		return errors.New("injected")
+	}
+	f.after--
+	return nil
+}
+
+func (f *failPort) TxPackets(p []spi.Packet) error {
+	return errors.New("injected")
+}
+
+func (f *failPort) Duplex() conn.Duplex {
+	return conn.Half
+}
@@ -0,0 +1,212 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mcp4725 controls a Microchip MCP4725 12 bits digital-to-analog
+// converter over I²C.
+//
+// The output value and power-down mode loaded at power on are stored in the
+// device's EEPROM and can be changed with SetPowerOnValue().
+//
+// Datasheet
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/22039d.pdf
+package mcp4725
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Max is the maximum raw output value.
+const Max = 4095
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Vref is the supply voltage, which is used as the reference voltage.
+	// Defaults to 3.3V.
+	Vref devices.Volt
+}
+
+// Dev is a handle to a MCP4725.
+type Dev struct {
+	c    conn.Conn
+	vref devices.Volt
+
+	mu    sync.Mutex
+	value uint16
+}
+
+// NewI2C returns an object that communicates over I²C to a MCP4725.
+//
+// The address is between 0x60 and 0x67; the bits 2 and 1 are set at the
+// factory and the bit 0 is selected with the A0 pin.
+//
+// The current output value is read back from the device.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if addr < 0x60 || addr > 0x67 {
+		return nil, errors.New("mcp4725: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Vref <= 0 {
+		return nil, errors.New("mcp4725: Vref must be positive")
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, vref: opts.Vref}
+	var r [5]byte
+	if err := d.read(r[:]); err != nil {
+		return nil, err
+	}
+	d.value = uint16(r[1])<<4 | uint16(r[2])>>4
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MCP4725{%s}", d.c)
+}
+
+// Range implements devices.AnalogOutput.
+func (d *Dev) Range() (devices.Volt, devices.Volt) {
+	return 0, toVolt(Max, d.vref)
+}
+
+// Out implements devices.AnalogOutput.
+func (d *Dev) Out(v devices.Volt) error {
+	raw, err := fromVolt(v, d.vref)
+	if err != nil {
+		return err
+	}
+	return d.Set(raw)
+}
+
+// Set sets the raw output value, between 0 and Max.
+func (d *Dev) Set(raw uint16) error {
+	if raw > Max {
+		return fmt.Errorf("mcp4725: value %d is out of range [0, %d]", raw, Max)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.fastWrite(pdNormal, raw); err != nil {
+		return err
+	}
+	d.value = raw
+	return nil
+}
+
+// SetPowerOnValue sets the output value and stores it in EEPROM so it is
+// restored at the next power on.
+//
+// The EEPROM supports a limited number of write cycles, typically 1 million.
+func (d *Dev) SetPowerOnValue(v devices.Volt) error {
+	raw, err := fromVolt(v, d.vref)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.write(cmdWriteDACEEPROM, pdNormal, raw); err != nil {
+		return err
+	}
+	d.value = raw
+	// The EEPROM write takes up to 50ms; page 10.
+	var r [1]byte
+	for i := 0; i < 10; i++ {
+		sleep(10 * time.Millisecond)
+		if err := d.read(r[:]); err != nil {
+			return err
+		}
+		if r[0]&0x80 != 0 {
+			return nil
+		}
+	}
+	return errors.New("mcp4725: timeout waiting for EEPROM write")
+}
+
+// PowerOnValue returns the output voltage stored in EEPROM and whether the
+// device powers on powered down.
+func (d *Dev) PowerOnValue() (devices.Volt, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var r [5]byte
+	if err := d.read(r[:]); err != nil {
+		return 0, false, err
+	}
+	raw := uint16(r[3]&0x0F)<<8 | uint16(r[4])
+	return toVolt(raw, d.vref), r[3]&0x60 != 0, nil
+}
+
+// Halt powers the output down, which is then pulled to ground with a 1kΩ
+// resistor.
+//
+// The next call to Out() or Set() powers the output up again.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.fastWrite(pd1k, d.value)
+}
+
+//
+
+const (
+	cmdWriteDACEEPROM = 0x60
+
+	// Power down modes.
+	pdNormal = 0
+	pd1k     = 1
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// fastWrite updates the DAC register with the 2 bytes fast mode command.
+func (d *Dev) fastWrite(pd byte, raw uint16) error {
+	if err := d.c.Tx([]byte{pd<<4 | byte(raw>>8), byte(raw)}, nil); err != nil {
+		return fmt.Errorf("mcp4725: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) write(cmd, pd byte, raw uint16) error {
+	if err := d.c.Tx([]byte{cmd | pd<<1, byte(raw >> 4), byte(raw << 4)}, nil); err != nil {
+		return fmt.Errorf("mcp4725: %v", err)
+	}
+	return nil
+}
+
+// read reads the status byte, the DAC register then the EEPROM.
+func (d *Dev) read(b []byte) error {
+	if err := d.c.Tx(nil, b); err != nil {
+		return fmt.Errorf("mcp4725: %v", err)
+	}
+	return nil
+}
+
+func toVolt(raw uint16, vref devices.Volt) devices.Volt {
+	return devices.Volt((int64(raw)*int64(vref) + 2048) / 4096)
+}
+
+func fromVolt(v, vref devices.Volt) (uint16, error) {
+	if v < 0 || v > vref {
+		return 0, fmt.Errorf("mcp4725: %s is out of range [0, %s]", v, vref)
+	}
+	raw := (int64(v)*4096 + int64(vref)/2) / int64(vref)
+	if raw > Max {
+		raw = Max
+	}
+	return uint16(raw), nil
+}
+
+var defaults = Opts{
+	Vref: 3300,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.AnalogOutput = &Dev{}
+var _ fmt.Stringer = &Dev{}
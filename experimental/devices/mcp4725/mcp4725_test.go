@@ -0,0 +1,167 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mcp4725
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x62, &Opts{Vref: 5000})
+	if err != nil {
+		log.Fatalf("failed to initialize mcp4725: %v", err)
+	}
+	// Output 1.25V now and at every power on.
+	if err := dev.SetPowerOnValue(1250); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNewI2C_Out(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x60, R: []byte{0xC0, 0x80, 0x00, 0x08, 0x00}},
+			{Addr: 0x60, W: []byte{0x08, 0x00}},
+			{Addr: 0x60, W: []byte{0x0F, 0xFF}},
+			// Halt.
+			{Addr: 0x60, W: []byte{0x1F, 0xFF}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x60, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MCP4725{playback(96)}" {
+		t.Fatal(s)
+	}
+	if min, max := dev.Range(); min != 0 || max != 3299 {
+		t.Fatal(min, max)
+	}
+	if err := dev.Out(1650); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Set(Max); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Out(-1); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.Out(3301); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.Set(Max + 1); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPowerOnValue(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x61, R: []byte{0xC0, 0x00, 0x00, 0x00, 0x00}},
+			{Addr: 0x61, W: []byte{0x60, 0x40, 0x00}},
+			// EEPROM write in progress.
+			{Addr: 0x61, R: []byte{0x40}},
+			{Addr: 0x61, R: []byte{0xC0}},
+			{Addr: 0x61, R: []byte{0xC0, 0x40, 0x00, 0x04, 0x00}},
+			// Powered down at power on.
+			{Addr: 0x61, R: []byte{0xC2, 0x40, 0x00, 0x24, 0x00}},
+			// Halt.
+			{Addr: 0x61, W: []byte{0x14, 0x00}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x61, &Opts{Vref: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetPowerOnValue(-1); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.SetPowerOnValue(1250); err != nil {
+		t.Fatal(err)
+	}
+	if v, pd, err := dev.PowerOnValue(); v != 1250 || pd || err != nil {
+		t.Fatal(v, pd, err)
+	}
+	if v, pd, err := dev.PowerOnValue(); v != 1250 || !pd || err != nil {
+		t.Fatal(v, pd, err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x68, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x60, &Opts{}); err == nil {
+		t.Fatal("bad Vref")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x60, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	ops := []i2ctest.IO{{Addr: 0x60, R: []byte{0xC0, 0, 0, 0, 0}}, {Addr: 0x60, W: []byte{0x60, 0x80, 0x00}}}
+	for i := 0; i < 10; i++ {
+		ops = append(ops, i2ctest.IO{Addr: 0x60, R: []byte{0x40}})
+	}
+	bus := i2ctest.Playback{Ops: ops, DontPanic: true}
+	dev, err := NewI2C(&bus, 0x60, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetPowerOnValue(1650); err == nil {
+		t.Fatal("timeout")
+	}
+	if err := dev.SetPowerOnValue(1650); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.Set(0); err == nil {
+		t.Fatal("write failed")
+	}
+	if _, _, err := dev.PowerOnValue(); err == nil {
+		t.Fatal("read failed")
+	}
+	bus = i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x60, R: []byte{0xC0, 0, 0, 0, 0}}, {Addr: 0x60, W: []byte{0x60, 0x80, 0x00}}},
+		DontPanic: true,
+	}
+	if dev, err = NewI2C(&bus, 0x60, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetPowerOnValue(1650); err == nil {
+		t.Fatal("read failed")
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mcp4922 controls a Microchip MCP4922 dual 12 bits digital-to-analog
+// converter over SPI.
+//
+// Each of the two outputs is exposed as a Channel that implements
+// devices.AnalogOutput. When the LDAC pin is connected, the values written are
+// only output on the next call to Update() so both channels can be changed
+// simultaneously.
+//
+// Datasheet
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/22250A.pdf
+package mcp4922
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+)
+
+// Max is the maximum raw output value.
+const Max = 4095
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Vref is the voltage applied on both VREFA and VREFB pins. Defaults to
+	// 3.3V.
+	Vref devices.Volt
+	// Buffered enables the reference input buffer, which is needed when the
+	// reference has a high impedance, e.g. a resistor divider.
+	Buffered bool
+	// Gain2x doubles the output range. The output is still limited by the
+	// supply voltage.
+	Gain2x bool
+}
+
+// Dev is a handle to a MCP4922.
+type Dev struct {
+	c        conn.Conn
+	ldac     gpio.PinOut
+	opts     Opts
+	channels [2]Channel
+
+	mu sync.Mutex
+}
+
+// NewSPI returns an object that communicates over SPI to a MCP4922.
+//
+// ldac is optional and can be nil when the LDAC pin is tied to ground, in
+// which case each value written is output immediately.
+func NewSPI(p spi.Port, ldac gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Vref <= 0 {
+		return nil, errors.New("mcp4922: Vref must be positive")
+	}
+	c, err := p.Connect(20000000, spi.Mode0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("mcp4922: %v", err)
+	}
+	if ldac == gpio.INVALID {
+		ldac = nil
+	}
+	d := &Dev{c: c, ldac: ldac, opts: *opts}
+	for i := range d.channels {
+		d.channels[i] = Channel{d: d, n: i}
+	}
+	if d.ldac != nil {
+		if err := d.ldac.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("mcp4922: %v", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MCP4922{%s}", d.c)
+}
+
+// Channel returns the output A for 0 and B for 1.
+//
+// Returns nil if the channel is invalid.
+func (d *Dev) Channel(n int) *Channel {
+	if n < 0 || n >= len(d.channels) {
+		return nil
+	}
+	return &d.channels[n]
+}
+
+// Update outputs the values written to both channels since the last call.
+//
+// It is a no-op when the LDAC pin is not connected.
+func (d *Dev) Update() error {
+	if d.ldac == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// The minimum pulse width is 100ns, which is shorter than a GPIO write.
+	if err := d.ldac.Out(gpio.Low); err != nil {
+		return fmt.Errorf("mcp4922: %v", err)
+	}
+	if err := d.ldac.Out(gpio.High); err != nil {
+		return fmt.Errorf("mcp4922: %v", err)
+	}
+	return nil
+}
+
+// Halt powers down both outputs.
+func (d *Dev) Halt() error {
+	for i := range d.channels {
+		if err := d.channels[i].Halt(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Channel is one output of the MCP4922.
+type Channel struct {
+	d *Dev
+	n int
+}
+
+func (c *Channel) String() string {
+	return fmt.Sprintf("%s.%c", c.d, 'A'+c.n)
+}
+
+// Range implements devices.AnalogOutput.
+func (c *Channel) Range() (devices.Volt, devices.Volt) {
+	return 0, devices.Volt((Max*int64(c.d.fullScale()) + 2048) / 4096)
+}
+
+// Out implements devices.AnalogOutput.
+func (c *Channel) Out(v devices.Volt) error {
+	fs := c.d.fullScale()
+	if v < 0 || v > fs {
+		return fmt.Errorf("mcp4922: %s is out of range [0, %s]", v, fs)
+	}
+	raw := (int64(v)*4096 + int64(fs)/2) / int64(fs)
+	if raw > Max {
+		raw = Max
+	}
+	return c.Set(uint16(raw))
+}
+
+// Set sets the raw output value, between 0 and Max.
+func (c *Channel) Set(raw uint16) error {
+	if raw > Max {
+		return fmt.Errorf("mcp4922: value %d is out of range [0, %d]", raw, Max)
+	}
+	return c.d.write(c.n, true, raw)
+}
+
+// Halt powers the output down; it is then pulled to ground with a 500kΩ
+// resistor.
+//
+// The next call to Out() or Set() powers the output up again.
+func (c *Channel) Halt() error {
+	return c.d.write(c.n, false, 0)
+}
+
+//
+
+const (
+	bitB    = 0x8000
+	bitBuf  = 0x4000
+	bitGain = 0x2000 // 1x when set.
+	bitOn   = 0x1000
+)
+
+func (d *Dev) fullScale() devices.Volt {
+	if d.opts.Gain2x {
+		return 2 * d.opts.Vref
+	}
+	return d.opts.Vref
+}
+
+func (d *Dev) write(n int, on bool, raw uint16) error {
+	w := raw
+	if n == 1 {
+		w |= bitB
+	}
+	if d.opts.Buffered {
+		w |= bitBuf
+	}
+	if !d.opts.Gain2x {
+		w |= bitGain
+	}
+	if on {
+		w |= bitOn
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.c.Tx([]byte{byte(w >> 8), byte(w)}, nil); err != nil {
+		return fmt.Errorf("mcp4922: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	Vref: 3300,
+}
+
+var _ conn.Resource = &Dev{}
+var _ conn.Resource = &Channel{}
+var _ devices.AnalogOutput = &Channel{}
+var _ fmt.Stringer = &Dev{}
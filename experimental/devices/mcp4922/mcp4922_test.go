@@ -0,0 +1,189 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mcp4922
+
+import (
+	"errors"
+	"log"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dev, err := NewSPI(p, gpioreg.ByName("GPIO25"), &Opts{Vref: 2048, Gain2x: true})
+	if err != nil {
+		log.Fatalf("failed to initialize mcp4922: %v", err)
+	}
+	defer dev.Halt()
+	// Change both outputs at the same time.
+	if err := dev.Channel(0).Out(1000); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Channel(1).Out(3000); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Update(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNewSPI(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{0x38, 0x00}},
+				{W: []byte{0xBF, 0xFF}},
+				// Halt.
+				{W: []byte{0x20, 0x00}},
+				{W: []byte{0xA0, 0x00}},
+			},
+		},
+	}
+	dev, err := NewSPI(&s, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MCP4922{playback}" {
+		t.Fatal(s)
+	}
+	if s := dev.Channel(1).String(); s != "MCP4922{playback}.B" {
+		t.Fatal(s)
+	}
+	if dev.Channel(2) != nil || dev.Channel(-1) != nil {
+		t.Fatal("invalid channel")
+	}
+	if min, max := dev.Channel(0).Range(); min != 0 || max != 3299 {
+		t.Fatal(min, max)
+	}
+	if err := dev.Channel(0).Out(1650); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Channel(1).Out(3300); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Channel(0).Out(3301); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.Channel(0).Set(Max + 1); err == nil {
+		t.Fatal("out of range")
+	}
+	// No-op.
+	if err := dev.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI_LDAC(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{0x57, 0xD0}},
+				{W: []byte{0xD5, 0xDC}},
+			},
+		},
+	}
+	ldac := &recordPin{}
+	dev, err := NewSPI(&s, ldac, &Opts{Vref: 2048, Buffered: true, Gain2x: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := dev.Channel(1).Range(); min != 0 || max != 4095 {
+		t.Fatal(min, max)
+	}
+	if err := dev.Channel(0).Out(2000); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Channel(1).Set(1500); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Update(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []gpio.Level{gpio.High, gpio.Low, gpio.High}
+	if len(ldac.levels) != len(expected) {
+		t.Fatal(ldac.levels)
+	}
+	for i := range expected {
+		if ldac.levels[i] != expected[i] {
+			t.Fatal(ldac.levels)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	if _, err := NewSPI(&spitest.Playback{}, nil, &Opts{}); err == nil {
+		t.Fatal("bad Vref")
+	}
+	if _, err := NewSPI(&spitest.Playback{Playback: conntest.Playback{DontPanic: true}}, gpio.INVALID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSPI(&spitest.Playback{}, &recordPin{err: errors.New("oops")}, nil); err == nil {
+		t.Fatal("ldac failed")
+	}
+	ldac := &recordPin{}
+	dev, err := NewSPI(&spitest.Playback{Playback: conntest.Playback{DontPanic: true}}, ldac, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Channel(0).Set(0); err == nil {
+		t.Fatal("write failed")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("write failed")
+	}
+	ldac.err = errors.New("oops")
+	if err := dev.Update(); err == nil {
+		t.Fatal("ldac failed")
+	}
+	ldac.err = nil
+	ldac.failHigh = true
+	if err := dev.Update(); err == nil {
+		t.Fatal("ldac failed")
+	}
+}
+
+//
+
+// recordPin records the levels written to it.
+type recordPin struct {
+	gpiotest.Pin
+	levels   []gpio.Level
+	err      error
+	failHigh bool
+}
+
+func (r *recordPin) Out(l gpio.Level) error {
+	if r.err != nil || (r.failHigh && l == gpio.High) {
+		return errors.New("oops")
+	}
+	r.levels = append(r.levels, l)
+	return nil
+}
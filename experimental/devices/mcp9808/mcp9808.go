@@ -0,0 +1,477 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mcp9808 controls a Microchip MCP9808 digital temperature sensor
+// over I²C.
+//
+// The device compares the temperature against a programmable window and a
+// critical limit and drives its ALERT pin accordingly, so the host can wait
+// for an interrupt instead of polling.
+//
+// Datasheet
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/25095A.pdf
+package mcp9808
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+	"periph.io/x/periph/devices"
+)
+
+// Resolution is the measurement resolution. Higher resolutions take longer.
+type Resolution uint8
+
+// Possible resolutions.
+const (
+	Res0_5C    Resolution = 0 // 0.5°C, 30ms
+	Res0_25C   Resolution = 1 // 0.25°C, 65ms
+	Res0_125C  Resolution = 2 // 0.125°C, 130ms
+	Res0_0625C Resolution = 3 // 0.0625°C, 250ms
+)
+
+const resolutionName = "Res0_5CRes0_25CRes0_125CRes0_0625C"
+
+var resolutionIndex = [...]uint8{0, 7, 15, 24, 34}
+
+func (r Resolution) String() string {
+	if r >= Resolution(len(resolutionIndex)-1) {
+		return fmt.Sprintf("Resolution(%d)", r)
+	}
+	return resolutionName[resolutionIndex[r]:resolutionIndex[r+1]]
+}
+
+// Hysteresis is applied on the limits when the temperature goes back inside
+// the window.
+type Hysteresis uint8
+
+// Possible hysteresis values.
+const (
+	Hyst0C   Hysteresis = 0
+	Hyst1_5C Hysteresis = 1
+	Hyst3C   Hysteresis = 2
+	Hyst6C   Hysteresis = 3
+)
+
+const hysteresisName = "Hyst0CHyst1_5CHyst3CHyst6C"
+
+var hysteresisIndex = [...]uint8{0, 6, 14, 20, 26}
+
+func (h Hysteresis) String() string {
+	if h >= Hysteresis(len(hysteresisIndex)-1) {
+		return fmt.Sprintf("Hysteresis(%d)", h)
+	}
+	return hysteresisName[hysteresisIndex[h]:hysteresisIndex[h+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Resolution Resolution
+	Hysteresis Hysteresis
+	// AlertPin is the pin connected to the ALERT output, required to use
+	// Alerts().
+	AlertPin gpio.PinIn
+}
+
+// Limits are the alert thresholds.
+//
+// The values are stored with a 0.25°C resolution.
+type Limits struct {
+	Lower    devices.Celsius
+	Upper    devices.Celsius
+	Critical devices.Celsius
+}
+
+// Alert is the state of the alert output.
+type Alert struct {
+	Temperature devices.Celsius
+	Active      bool // The ALERT pin is asserted.
+	Critical    bool // Temperature >= Critical.
+	Upper       bool // Temperature > Upper.
+	Lower       bool // Temperature < Lower.
+}
+
+func (a Alert) String() string {
+	s := "inactive"
+	if a.Active {
+		s = "active"
+	}
+	if a.Critical {
+		s += " critical"
+	}
+	if a.Upper {
+		s += " upper"
+	}
+	if a.Lower {
+		s += " lower"
+	}
+	return fmt.Sprintf("%s %s", a.Temperature, s)
+}
+
+// Dev is a handle to a MCP9808.
+type Dev struct {
+	m        mmr.Dev8
+	opts     Opts
+	config   uint16
+	shutdown bool
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a MCP9808.
+//
+// The address is between 0x18 and 0x1F depending on the A0-A2 pins.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if addr < 0x18 || addr > 0x1F {
+		return nil, errors.New("mcp9808: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Resolution > Res0_0625C {
+		return nil, fmt.Errorf("mcp9808: invalid resolution %d", opts.Resolution)
+	}
+	if opts.Hysteresis > Hyst6C {
+		return nil, fmt.Errorf("mcp9808: invalid hysteresis %d", opts.Hysteresis)
+	}
+	d := &Dev{
+		m:      mmr.Dev8{Conn: &i2c.Dev{Bus: b, Addr: addr}, Order: binary.BigEndian},
+		opts:   *opts,
+		config: uint16(opts.Hysteresis) << 9,
+	}
+	if id, err := d.m.ReadUint16(regManufacturerID); err != nil {
+		return nil, fmt.Errorf("mcp9808: %v", err)
+	} else if id != manufacturerID {
+		return nil, fmt.Errorf("mcp9808: unexpected manufacturer id %#x; is this a MCP9808?", id)
+	}
+	if id, err := d.m.ReadUint16(regDeviceID); err != nil {
+		return nil, fmt.Errorf("mcp9808: %v", err)
+	} else if id>>8 != deviceID {
+		return nil, fmt.Errorf("mcp9808: unexpected device id %#x; is this a MCP9808?", id)
+	}
+	if err := d.writeConfig(d.config); err != nil {
+		return nil, err
+	}
+	if err := d.m.WriteUint8(regResolution, byte(opts.Resolution)); err != nil {
+		return nil, fmt.Errorf("mcp9808: %v", err)
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MCP9808{%s}", d.m.Conn)
+}
+
+// Sense reads the temperature. Only env.Temperature is modified.
+//
+// The device converts continuously; if it was halted, it is woken up and the
+// call waits for a conversion.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wakeUp(); err != nil {
+		return err
+	}
+	return d.sense(env)
+}
+
+// SenseContinuous returns the temperature at the requested interval.
+//
+// It is important to call Halt() once done, which will close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < convTime[d.opts.Resolution] {
+		return nil, errors.New("mcp9808: interval is shorter than the conversion time")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wakeUp(); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Limits returns the programmed alert thresholds.
+func (d *Dev) Limits() (Limits, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var l Limits
+	for i, p := range []*devices.Celsius{&l.Upper, &l.Lower, &l.Critical} {
+		v, err := d.m.ReadUint16(regUpper + uint8(i))
+		if err != nil {
+			return l, fmt.Errorf("mcp9808: %v", err)
+		}
+		*p = rawToTemp(v & 0x1FFC)
+	}
+	return l, nil
+}
+
+// SetLimits programs the alert thresholds.
+//
+// The alert window is between l.Lower and l.Upper; l.Critical must be above
+// l.Upper.
+func (d *Dev) SetLimits(l *Limits) error {
+	if l.Lower >= l.Upper || l.Upper >= l.Critical {
+		return errors.New("mcp9808: limits must be Lower < Upper < Critical")
+	}
+	var raw [3]uint16
+	for i, t := range []devices.Celsius{l.Upper, l.Lower, l.Critical} {
+		v, err := limitToRaw(t)
+		if err != nil {
+			return err
+		}
+		raw[i] = v
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, v := range raw {
+		if err := d.m.WriteUint16(regUpper+uint8(i), v); err != nil {
+			return fmt.Errorf("mcp9808: %v", err)
+		}
+	}
+	return nil
+}
+
+// Alerts enables the ALERT output and returns a channel on which the alert
+// state is sent each time it changes, as signaled by Opts.AlertPin.
+//
+// The output is in comparator mode; it is asserted while the temperature is
+// outside the window set with SetLimits() or above the critical limit, and
+// is deasserted when the temperature gets back inside, with hysteresis. The
+// current state is sent first.
+//
+// It is important to call Halt() once done, which will close the channel.
+func (d *Dev) Alerts() (<-chan Alert, error) {
+	if d.opts.AlertPin == nil {
+		return nil, errors.New("mcp9808: Alerts() requires Opts.AlertPin")
+	}
+	d.stopSensing()
+	if err := d.opts.AlertPin.In(gpio.PullUp, gpio.BothEdges); err != nil {
+		return nil, fmt.Errorf("mcp9808: %v", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.wakeUp(); err != nil {
+		return nil, err
+	}
+	// Active low, comparator mode, on all the limits.
+	if err := d.writeConfig(d.config | configAlertCtrl); err != nil {
+		return nil, err
+	}
+	c := make(chan Alert, 1)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(c)
+		d.sensingAlerts(c, stop)
+	}(d.stop)
+	return c, nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature
+}
+
+// Halt stops the continuous sensing or alerts and puts the device in low
+// power shutdown mode.
+//
+// The next call to Sense(), SenseContinuous() or Alerts() wakes it up.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeConfig(d.config | configShutdown); err != nil {
+		return err
+	}
+	d.shutdown = true
+	return nil
+}
+
+//
+
+const (
+	regConfig         = 0x01
+	regUpper          = 0x02
+	regLower          = 0x03
+	regCritical       = 0x04
+	regAmbient        = 0x05
+	regManufacturerID = 0x06
+	regDeviceID       = 0x07
+	regResolution     = 0x08
+
+	manufacturerID = 0x0054
+	deviceID       = 0x04
+
+	configShutdown  = 1 << 8
+	configAlertCtrl = 1 << 3
+
+	ambientCritical = 1 << 15
+	ambientUpper    = 1 << 14
+	ambientLower    = 1 << 13
+)
+
+// convTime is indexed by Resolution; page 3, typical values.
+var convTime = [...]time.Duration{30 * time.Millisecond, 65 * time.Millisecond, 130 * time.Millisecond, 250 * time.Millisecond}
+
+var defaults = Opts{
+	Resolution: Res0_0625C,
+}
+
+var sleep = time.Sleep
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.sense(&e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sensingAlerts(c chan<- Alert, stop <-chan struct{}) {
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Wake up regularly to check for stop.
+			if !d.opts.AlertPin.WaitForEdge(100 * time.Millisecond) {
+				continue
+			}
+		}
+		a := Alert{Active: d.opts.AlertPin.Read() == gpio.Low}
+		d.mu.Lock()
+		raw, err := d.readTemp(&a.Temperature)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		a.Critical = raw&ambientCritical != 0
+		a.Upper = raw&ambientUpper != 0
+		a.Lower = raw&ambientLower != 0
+		select {
+		case c <- a:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// wakeUp leaves shutdown mode if needed.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) wakeUp() error {
+	if !d.shutdown {
+		return nil
+	}
+	if err := d.writeConfig(d.config); err != nil {
+		return err
+	}
+	d.shutdown = false
+	sleep(convTime[d.opts.Resolution])
+	return nil
+}
+
+func (d *Dev) writeConfig(v uint16) error {
+	if err := d.m.WriteUint16(regConfig, v); err != nil {
+		return fmt.Errorf("mcp9808: %v", err)
+	}
+	return nil
+}
+
+// sense reads the ambient temperature and records when it was read.
+func (d *Dev) sense(e *devices.Environment) error {
+	if _, err := d.readTemp(&e.Temperature); err != nil {
+		return err
+	}
+	e.Time = time.Now()
+	return nil
+}
+
+// readTemp reads the ambient temperature register and returns it raw.
+func (d *Dev) readTemp(t *devices.Celsius) (uint16, error) {
+	v, err := d.m.ReadUint16(regAmbient)
+	if err != nil {
+		return 0, fmt.Errorf("mcp9808: %v", err)
+	}
+	*t = rawToTemp(v & 0x1FFF)
+	return v, nil
+}
+
+// rawToTemp converts a 13 bits two's complement value in 1/16°C.
+func rawToTemp(v uint16) devices.Celsius {
+	s := int64(v&0x0FFF) - int64(v&0x1000)
+	return devices.Celsius(s * 1000 / 16)
+}
+
+// limitToRaw converts a temperature to a limit register value, rounded to
+// 0.25°C.
+func limitToRaw(t devices.Celsius) (uint16, error) {
+	q := int64(t) / 250
+	if r := int64(t) % 250; r >= 125 {
+		q++
+	} else if r <= -125 {
+		q--
+	}
+	if q < -1024 || q > 1023 {
+		return 0, fmt.Errorf("mcp9808: limit %s out of range", t)
+	}
+	return uint16(q<<2) & 0x1FFC, nil
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ fmt.Stringer = &Alert{}
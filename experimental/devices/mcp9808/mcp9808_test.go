@@ -0,0 +1,495 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mcp9808
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+	b, err := i2creg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	p := gpioreg.ByName("GPIO17")
+	if p == nil {
+		log.Fatal("failed to find GPIO17")
+	}
+	d, err := NewI2C(b, 0x18, &Opts{Resolution: Res0_0625C, Hysteresis: Hyst1_5C, AlertPin: p})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Halt()
+	l := Limits{Lower: 18000, Upper: 26000, Critical: 40000}
+	if err := d.SetLimits(&l); err != nil {
+		log.Fatal(err)
+	}
+	alerts, err := d.Alerts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for a := range alerts {
+		fmt.Printf("%s\n", a)
+	}
+}
+
+//
+
+func TestResolution_String(t *testing.T) {
+	data := []struct {
+		r        Resolution
+		expected string
+	}{
+		{Res0_5C, "Res0_5C"},
+		{Res0_25C, "Res0_25C"},
+		{Res0_125C, "Res0_125C"},
+		{Res0_0625C, "Res0_0625C"},
+		{Resolution(4), "Resolution(4)"},
+	}
+	for i, line := range data {
+		if s := line.r.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestHysteresis_String(t *testing.T) {
+	data := []struct {
+		h        Hysteresis
+		expected string
+	}{
+		{Hyst0C, "Hyst0C"},
+		{Hyst1_5C, "Hyst1_5C"},
+		{Hyst3C, "Hyst3C"},
+		{Hyst6C, "Hyst6C"},
+		{Hysteresis(4), "Hysteresis(4)"},
+	}
+	for i, line := range data {
+		if s := line.h.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestAlert_String(t *testing.T) {
+	a := Alert{Temperature: 30500, Active: true, Upper: true}
+	if s := a.String(); s != "30.500°C active upper" {
+		t.Fatal(s)
+	}
+	a = Alert{Temperature: -1000, Active: true, Critical: true, Lower: true}
+	if s := a.String(); s != "-1.000°C active critical lower" {
+		t.Fatal(s)
+	}
+	if s := (Alert{Temperature: 20000}).String(); s != "20.000°C inactive" {
+		t.Fatal(s)
+	}
+}
+
+func TestNewI2C(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, &Opts{Resolution: Res0_25C, Hysteresis: Hyst3C})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "MCP9808{fakeBus(24)}" {
+		t.Fatal(s)
+	}
+	if b.regs[regConfig] != 2<<9 || b.res != 1 {
+		t.Fatalf("%#x %d", b.regs[regConfig], b.res)
+	}
+	if _, err := NewI2C(b, 0x18, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.res != 3 {
+		t.Fatal(b.res)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(newFakeBus(), 0x40, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(newFakeBus(), 0x18, &Opts{Resolution: 4}); err == nil {
+		t.Fatal("invalid resolution")
+	}
+	if _, err := NewI2C(newFakeBus(), 0x18, &Opts{Hysteresis: 4}); err == nil {
+		t.Fatal("invalid hysteresis")
+	}
+	b := newFakeBus()
+	b.regs[regManufacturerID] = 0x1234
+	if _, err := NewI2C(b, 0x18, nil); err == nil {
+		t.Fatal("invalid manufacturer id")
+	}
+	b = newFakeBus()
+	b.regs[regDeviceID] = 0x0200
+	if _, err := NewI2C(b, 0x18, nil); err == nil {
+		t.Fatal("invalid device id")
+	}
+	for i := 0; i < 4; i++ {
+		b = newFakeBus()
+		b.failAfter = i
+		if _, err := NewI2C(b, 0x18, nil); err == nil {
+			t.Fatalf("#%d: I²C failure", i)
+		}
+	}
+}
+
+func TestSense(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []struct {
+		raw      uint16
+		expected devices.Celsius
+	}{
+		{0x0194, 25250},
+		{0x01FF, 31937},
+		// The alert flags are ignored.
+		{0xE194, 25250},
+		{0x1FFC, -250},
+		{0x1E70, -25000},
+	}
+	for i, line := range data {
+		b.setAmbient(line.raw)
+		e := devices.Environment{}
+		if err := d.Sense(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Temperature != line.expected {
+			t.Fatalf("#%d: %s != %s", i, e.Temperature, line.expected)
+		}
+	}
+	b.failAfter = 0
+	if err := d.Sense(&devices.Environment{}); err == nil {
+		t.Fatal("I²C failure")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	b := newFakeBus()
+	b.setAmbient(0x0194)
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := d.SenseContinuous(250 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-c; e.Temperature != 25250 {
+		t.Fatal(e.Temperature)
+	}
+	// Restarting stops the previous one.
+	c2, err := d.SenseContinuous(250 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("expected closed channel")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c2; ok {
+		t.Fatal("expected closed channel")
+	}
+	// I²C failure closes the channel.
+	c, err = d.SenseContinuous(250 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.mu.Lock()
+	b.failAfter = 0
+	b.mu.Unlock()
+	if _, ok := <-c; ok {
+		t.Fatal("expected closed channel")
+	}
+}
+
+func TestHalt(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, &Opts{Hysteresis: Hyst1_5C})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if b.regs[regConfig] != 1<<9|configShutdown {
+		t.Fatalf("%#x", b.regs[regConfig])
+	}
+	// Sense wakes the device up.
+	if err := d.Sense(&devices.Environment{}); err != nil {
+		t.Fatal(err)
+	}
+	if b.regs[regConfig] != 1<<9 {
+		t.Fatalf("%#x", b.regs[regConfig])
+	}
+	b.failAfter = 0
+	if err := d.Halt(); err == nil {
+		t.Fatal("I²C failure")
+	}
+	b.failAfter = 1
+	if err := d.Sense(&devices.Environment{}); err != nil {
+		t.Fatal("not in shutdown, no wake up needed")
+	}
+}
+
+func TestHalt_wakeUp_fail(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	b.failAfter = 0
+	if err := d.Sense(&devices.Environment{}); err == nil {
+		t.Fatal("I²C failure")
+	}
+	if _, err := d.SenseContinuous(time.Second); err == nil {
+		t.Fatal("I²C failure")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Limits{Lower: -10100, Upper: 26130, Critical: 80000}
+	if err := d.SetLimits(&l); err != nil {
+		t.Fatal(err)
+	}
+	if b.regs[regUpper] != 0x01A4 || b.regs[regLower] != 0x1F60 || b.regs[regCritical] != 0x0500 {
+		t.Fatalf("%#x %#x %#x", b.regs[regUpper], b.regs[regLower], b.regs[regCritical])
+	}
+	// Unused bits are ignored.
+	b.regs[regUpper] |= 0xE003
+	got, err := d.Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Limits{Lower: -10000, Upper: 26250, Critical: 80000}); got != expected {
+		t.Fatal(got)
+	}
+}
+
+func TestLimits_fail(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range []Limits{
+		{Lower: 30000, Upper: 20000, Critical: 40000},
+		{Lower: 10000, Upper: 20000, Critical: 20000},
+		{Lower: -300000, Upper: 20000, Critical: 40000},
+		{Lower: 10000, Upper: 20000, Critical: 256000},
+	} {
+		if err := d.SetLimits(&l); err == nil {
+			t.Fatalf("#%d: invalid limits", i)
+		}
+	}
+	b.failAfter = 0
+	if err := d.SetLimits(&Limits{Lower: 10000, Upper: 20000, Critical: 40000}); err == nil {
+		t.Fatal("I²C failure")
+	}
+	if _, err := d.Limits(); err == nil {
+		t.Fatal("I²C failure")
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	b := newFakeBus()
+	p := &gpiotest.Pin{N: "ALERT", EdgesChan: make(chan gpio.Level, 1)}
+	d, err := NewI2C(b, 0x18, &Opts{Hysteresis: Hyst1_5C, AlertPin: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	b.setAmbient(0x0140)
+	c, err := d.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.mu.Lock()
+	if b.regs[regConfig] != 1<<9|configAlertCtrl {
+		t.Fatalf("%#x", b.regs[regConfig])
+	}
+	b.mu.Unlock()
+	if p.P != gpio.PullUp {
+		t.Fatal(p.P)
+	}
+	// Initial state.
+	if a := <-c; a != (Alert{Temperature: 20000}) {
+		t.Fatal(a)
+	}
+	// Above the window.
+	b.setAmbient(0x4200)
+	p.EdgesChan <- gpio.Low
+	if a := <-c; a != (Alert{Temperature: 32000, Active: true, Upper: true}) {
+		t.Fatal(a)
+	}
+	// Above critical.
+	b.setAmbient(0xC500)
+	p.EdgesChan <- gpio.Low
+	if a := <-c; a != (Alert{Temperature: 80000, Active: true, Critical: true, Upper: true}) {
+		t.Fatal(a)
+	}
+	// Back in the window.
+	b.setAmbient(0x0160)
+	p.EdgesChan <- gpio.High
+	if a := <-c; a != (Alert{Temperature: 22000}) {
+		t.Fatal(a)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("expected closed channel")
+	}
+}
+
+func TestAlerts_fail(t *testing.T) {
+	b := newFakeBus()
+	d, err := NewI2C(b, 0x18, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Alerts(); err == nil {
+		t.Fatal("AlertPin is required")
+	}
+	d.opts.AlertPin = &gpiotest.Pin{N: "ALERT"}
+	if _, err := d.Alerts(); err == nil {
+		t.Fatal("edge detection failure")
+	}
+	p := &gpiotest.Pin{N: "ALERT", EdgesChan: make(chan gpio.Level, 1)}
+	d.opts.AlertPin = p
+	b.failAfter = 0
+	if _, err := d.Alerts(); err == nil {
+		t.Fatal("I²C failure")
+	}
+	if err := d.Halt(); err == nil {
+		t.Fatal("I²C failure")
+	}
+	// Shutdown is tracked, so the wake up fails too.
+	b.failAfter = 1
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Alerts(); err == nil {
+		t.Fatal("I²C failure")
+	}
+	// Failure while reading the state closes the channel.
+	b.failAfter = -1
+	c, err := d.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-c
+	b.mu.Lock()
+	b.failAfter = 0
+	b.mu.Unlock()
+	p.EdgesChan <- gpio.Low
+	if _, ok := <-c; ok {
+		t.Fatal("expected closed channel")
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+type fakeBus struct {
+	mu        sync.Mutex
+	regs      [9]uint16
+	res       byte
+	failAfter int
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{failAfter: -1}
+	f.regs[regManufacturerID] = manufacturerID
+	f.regs[regDeviceID] = deviceID<<8 | 0x00
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fakeBus"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failAfter == 0 {
+		return errors.New("injected")
+	}
+	if f.failAfter > 0 {
+		f.failAfter--
+	}
+	if len(w) == 0 || int(w[0]) >= len(f.regs) {
+		return errors.New("invalid register")
+	}
+	reg := w[0]
+	switch {
+	case reg == regResolution && len(w) == 2:
+		f.res = w[1]
+	case len(w) == 3:
+		f.regs[reg] = uint16(w[1])<<8 | uint16(w[2])
+	case reg == regResolution && len(r) == 1:
+		r[0] = f.res
+	case len(r) == 2:
+		r[0] = byte(f.regs[reg] >> 8)
+		r[1] = byte(f.regs[reg])
+	default:
+		return fmt.Errorf("unexpected Tx(%#x, %v, %d)", addr, w, len(r))
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) Close() error {
+	return nil
+}
+
+func (f *fakeBus) setAmbient(v uint16) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[regAmbient] = v
+}
+
+var _ i2c.Bus = &fakeBus{}
@@ -0,0 +1,611 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mfrc522 controls a NXP MFRC522 (RC522) RFID reader over SPI.
+//
+// It supports detecting ISO/IEC 14443 type A cards, including the
+// anticollision loop to select one of multiple cards in the field, and reading
+// and writing Mifare Classic cards after a CRYPTO1 authentication.
+//
+// The MFRC522 cannot detect a card by itself; the driver sends a request and
+// waits for an answer. When the IRQ pin is connected, the driver waits for
+// the interrupts instead of polling the device.
+//
+// Datasheet
+//
+// https://www.nxp.com/docs/en/data-sheet/MFRC522.pdf
+package mfrc522
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Card is an ISO/IEC 14443 type A card selected by the reader.
+type Card struct {
+	UID  []byte
+	ATQA uint16
+	SAK  byte
+}
+
+func (c *Card) String() string {
+	return fmt.Sprintf("Card{UID:%s, ATQA:%#04x, SAK:%#02x}", hex.EncodeToString(c.UID), c.ATQA, c.SAK)
+}
+
+// Key is a Mifare Classic sector key.
+type Key [6]byte
+
+// DefaultKey is the factory default key of Mifare Classic cards.
+var DefaultKey = Key{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// RxGain is the receiver gain, from 0 to 7: 18, 23, 18, 23, 33, 38, 43
+	// and 48dB. The default is 4 when opts is nil.
+	RxGain uint8
+}
+
+// Dev is a handle to a MFRC522.
+type Dev struct {
+	c   conn.Conn
+	irq gpio.PinIn
+
+	mu      sync.Mutex
+	version byte
+	halted  bool
+}
+
+// NewSPI returns an object that communicates over SPI to a MFRC522.
+//
+// rst and irq are optional. Without rst, a soft reset is done.
+func NewSPI(p spi.Port, rst gpio.PinOut, irq gpio.PinIn, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.RxGain > 7 {
+		return nil, fmt.Errorf("mfrc522: invalid gain %d", opts.RxGain)
+	}
+	c, err := p.Connect(10000000, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("mfrc522: %v", err)
+	}
+	d := &Dev{c: c, irq: irq}
+	if rst != nil {
+		if err := rst.Out(gpio.Low); err != nil {
+			return nil, fmt.Errorf("mfrc522: %v", err)
+		}
+		sleep(time.Microsecond)
+		if err := rst.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("mfrc522: %v", err)
+		}
+	} else if err := d.write(regCommand, cmdSoftReset); err != nil {
+		return nil, err
+	}
+	// Wait for the oscillator to start.
+	sleep(50 * time.Millisecond)
+	if d.version, err = d.read(regVersion); err != nil {
+		return nil, err
+	}
+	if d.version == 0x00 || d.version == 0xFF {
+		return nil, errors.New("mfrc522: device not found")
+	}
+	for _, r := range [][2]byte{
+		// The timer bounds the wait for an answer to 25ms: 13.56MHz/(2*169+1)
+		// is 40kHz, reloaded at 1000.
+		{regTMode, 0x80},
+		{regTPrescaler, 0xA9},
+		{regTReloadH, 0x03},
+		{regTReloadL, 0xE8},
+		// 100% ASK modulation.
+		{regTxASK, 0x40},
+		// CRC preset of ISO/IEC 14443 type A.
+		{regMode, 0x3D},
+		{regRFCfg, opts.RxGain << 4},
+	} {
+		if err := d.write(r[0], r[1]); err != nil {
+			return nil, err
+		}
+	}
+	if irq != nil {
+		if err := irq.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+			return nil, fmt.Errorf("mfrc522: %v", err)
+		}
+		// Active low interrupts on receive, command completion and timer.
+		if err := d.write(regComIEn, 0x80|irqRx|irqIdle|irqTimer); err != nil {
+			return nil, err
+		}
+		if err := d.write(regDivIEn, 0x80); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.setBits(regTxControl, 0x03); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MFRC522{%s}", d.c)
+}
+
+// Version returns the content of the version register; 0x91 for version 1.0
+// and 0x92 for version 2.0.
+func (d *Dev) Version() byte {
+	return d.version
+}
+
+// ReadCard looks once for a card and selects it. It returns nil if no card
+// answered.
+//
+// A card that was already selected doesn't answer until it leaves the field or
+// is put in the idle state with HaltCard().
+func (d *Dev) ReadCard() (*Card, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readCard()
+}
+
+// WaitForCard looks for a card until one answers or timeout expires.
+func (d *Dev) WaitForCard(timeout time.Duration) (*Card, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for start := time.Now(); ; {
+		c, err := d.readCard()
+		if c != nil || err != nil {
+			return c, err
+		}
+		if time.Since(start) > timeout {
+			return nil, errors.New("mfrc522: no card detected")
+		}
+	}
+}
+
+// HaltCard puts the selected card in the halt state and ends the
+// authenticated session.
+func (d *Dev) HaltCard() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.clearBits(regStatus2, status2Crypto1On); err != nil {
+		return err
+	}
+	// The card doesn't answer a HLTA.
+	if _, _, err := d.communicate(cmdTransceive, appendCRC([]byte{piccHLTA, 0}), 0, irqRx); err != errTimeout {
+		if err == nil {
+			err = errors.New("mfrc522: unexpected answer to halt")
+		}
+		return err
+	}
+	return nil
+}
+
+// MifareAuthenticate authenticates a block of a Mifare Classic card with a key
+// A or B. uid is the card UID as returned by ReadCard().
+//
+// Authentication is valid for the whole sector containing the block, until
+// another card is selected or HaltCard() is called.
+func (d *Dev) MifareAuthenticate(block byte, keyB bool, key Key, uid []byte) error {
+	if len(uid) < 4 {
+		return errors.New("mfrc522: invalid uid")
+	}
+	cmd := byte(piccAuthA)
+	if keyB {
+		cmd = piccAuthB
+	}
+	w := append([]byte{cmd, block}, key[:]...)
+	// The last 4 bytes of the UID are used.
+	w = append(w, uid[len(uid)-4:]...)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, _, err := d.communicate(cmdMFAuthent, w, 0, irqIdle); err != nil {
+		if err == errTimeout {
+			err = errors.New("mfrc522: authentication failed")
+		}
+		return err
+	}
+	s, err := d.read(regStatus2)
+	if err != nil {
+		return err
+	}
+	if s&status2Crypto1On == 0 {
+		return errors.New("mfrc522: authentication failed")
+	}
+	return nil
+}
+
+// MifareRead reads a 16 bytes block of an authenticated sector.
+func (d *Dev) MifareRead(block byte) ([16]byte, error) {
+	var b [16]byte
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r, _, err := d.communicate(cmdTransceive, appendCRC([]byte{piccRead, block}), 0, irqRx)
+	if err != nil {
+		return b, err
+	}
+	if len(r) != 18 || !checkCRC(r) {
+		return b, fmt.Errorf("mfrc522: invalid answer to read block %d", block)
+	}
+	copy(b[:], r)
+	return b, nil
+}
+
+// MifareWrite writes a 16 bytes block of an authenticated sector.
+//
+// Writing the last block of a sector changes the keys and access bits; an
+// invalid value permanently locks the sector.
+func (d *Dev) MifareWrite(block byte, data [16]byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.transceiveACK(appendCRC([]byte{piccWrite, block})); err != nil {
+		return err
+	}
+	return d.transceiveACK(appendCRC(data[:]))
+}
+
+// Halt ends the authenticated session and turns the antenna off.
+//
+// The antenna is turned back on by the next call to ReadCard().
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.clearBits(regStatus2, status2Crypto1On); err != nil {
+		return err
+	}
+	if err := d.clearBits(regTxControl, 0x03); err != nil {
+		return err
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+// Registers.
+const (
+	regCommand    = 0x01
+	regComIEn     = 0x02
+	regDivIEn     = 0x03
+	regComIrq     = 0x04
+	regError      = 0x06
+	regStatus2    = 0x08
+	regFIFOData   = 0x09
+	regFIFOLevel  = 0x0A
+	regControl    = 0x0C
+	regBitFraming = 0x0D
+	regColl       = 0x0E
+	regMode       = 0x11
+	regTxControl  = 0x14
+	regTxASK      = 0x15
+	regRFCfg      = 0x26
+	regTMode      = 0x2A
+	regTPrescaler = 0x2B
+	regTReloadH   = 0x2C
+	regTReloadL   = 0x2D
+	regVersion    = 0x37
+)
+
+// Commands.
+const (
+	cmdIdle       = 0x00
+	cmdTransceive = 0x0C
+	cmdMFAuthent  = 0x0E
+	cmdSoftReset  = 0x0F
+)
+
+// ComIrqReg and ComIEnReg bits.
+const (
+	irqRx    = 0x20
+	irqIdle  = 0x10
+	irqTimer = 0x01
+)
+
+// ErrorReg bits.
+const (
+	errProtocol = 0x01
+	errParity   = 0x02
+	errColl     = 0x08
+	errBufOvfl  = 0x10
+)
+
+const status2Crypto1On = 0x08
+
+// Card commands.
+const (
+	piccREQA   = 0x26
+	piccSelCL1 = 0x93
+	piccSelCL2 = 0x95
+	piccSelCL3 = 0x97
+	piccHLTA   = 0x50
+	piccAuthA  = 0x60
+	piccAuthB  = 0x61
+	piccRead   = 0x30
+	piccWrite  = 0xA0
+	piccACK    = 0x0A
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// errTimeout is returned by communicate() when the card didn't answer.
+var errTimeout = errors.New("mfrc522: no answer")
+
+// errCollision is returned by communicate() when multiple cards answered.
+var errCollision = errors.New("mfrc522: collision")
+
+func (d *Dev) readCard() (*Card, error) {
+	if d.halted {
+		if err := d.setBits(regTxControl, 0x03); err != nil {
+			return nil, err
+		}
+		d.halted = false
+	}
+	// Values received after a collision are cleared.
+	if err := d.clearBits(regColl, 0x80); err != nil {
+		return nil, err
+	}
+	// REQA is a 7 bits short frame.
+	r, _, err := d.communicate(cmdTransceive, []byte{piccREQA}, 0x07, irqRx)
+	if err == errTimeout {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(r) != 2 {
+		return nil, errors.New("mfrc522: invalid ATQA")
+	}
+	c := &Card{ATQA: uint16(r[1])<<8 | uint16(r[0])}
+	// The UID is 4, 7 or 10 bytes long, selected over up to 3 cascade levels.
+	for _, sel := range []byte{piccSelCL1, piccSelCL2, piccSelCL3} {
+		b, sak, err := d.selectLevel(sel)
+		if err != nil {
+			return nil, err
+		}
+		if sak&0x04 == 0 {
+			c.UID = append(c.UID, b[:4]...)
+			c.SAK = sak
+			return c, nil
+		}
+		// b[0] is the cascade tag.
+		c.UID = append(c.UID, b[1:4]...)
+	}
+	return nil, errors.New("mfrc522: invalid UID size")
+}
+
+// selectLevel runs the anticollision loop of a cascade level and selects the
+// card. It returns the 4 UID bytes and BCC, and the SAK.
+//
+// On collision, the card with a 1 at the collision position is selected.
+func (d *Dev) selectLevel(sel byte) ([5]byte, byte, error) {
+	var b [5]byte
+	for known := 0; ; {
+		n := known / 8
+		last := byte(known % 8)
+		w := []byte{sel, byte(2+n)<<4 | last}
+		if last != 0 {
+			w = append(w, b[:n+1]...)
+		} else {
+			w = append(w, b[:n]...)
+		}
+		// The answer is aligned to complete the partial byte sent.
+		r, _, err := d.communicate(cmdTransceive, w, last<<4|last, irqRx)
+		if err != nil && err != errCollision {
+			return b, 0, err
+		}
+		if len(r) != 0 && last != 0 {
+			mask := byte(0xFF) << last
+			r[0] = b[n]&^mask | r[0]&mask
+		}
+		copy(b[n:], r)
+		if err == errCollision {
+			c, err := d.read(regColl)
+			if err != nil {
+				return b, 0, err
+			}
+			if c&0x20 != 0 {
+				return b, 0, errors.New("mfrc522: invalid collision position")
+			}
+			pos := int(c & 0x1F)
+			if pos == 0 {
+				pos = 32
+			}
+			if pos <= known {
+				return b, 0, errors.New("mfrc522: anticollision failed")
+			}
+			known = pos
+			b[(pos-1)/8] |= 1 << uint((pos-1)%8)
+			continue
+		}
+		if n+len(r) != 5 || b[0]^b[1]^b[2]^b[3] != b[4] {
+			return b, 0, errors.New("mfrc522: invalid UID")
+		}
+		break
+	}
+	r, _, err := d.communicate(cmdTransceive, appendCRC(append([]byte{sel, 0x70}, b[:]...)), 0, irqRx)
+	if err != nil {
+		return b, 0, err
+	}
+	if len(r) != 3 || !checkCRC(r) {
+		return b, 0, errors.New("mfrc522: invalid SAK")
+	}
+	return b, r[0], nil
+}
+
+// transceiveACK sends w and expects the 4 bits ACK.
+func (d *Dev) transceiveACK(w []byte) error {
+	r, bits, err := d.communicate(cmdTransceive, w, 0, irqRx)
+	if err != nil {
+		return err
+	}
+	if len(r) != 1 || bits != 4 || r[0]&0x0F != piccACK {
+		return fmt.Errorf("mfrc522: card refused the command %#x", w[0])
+	}
+	return nil
+}
+
+// communicate runs a command with w in the FIFO and waits for one of the irqs.
+//
+// It returns the content of the FIFO and the number of valid bits in the last
+// byte, 0 meaning the whole byte.
+func (d *Dev) communicate(cmd byte, w []byte, framing, irqs byte) ([]byte, byte, error) {
+	if err := d.write(regCommand, cmdIdle); err != nil {
+		return nil, 0, err
+	}
+	if err := d.write(regComIrq, 0x7F); err != nil {
+		return nil, 0, err
+	}
+	if err := d.write(regFIFOLevel, 0x80); err != nil {
+		return nil, 0, err
+	}
+	if err := d.write(regFIFOData, w...); err != nil {
+		return nil, 0, err
+	}
+	if err := d.write(regBitFraming, framing); err != nil {
+		return nil, 0, err
+	}
+	if err := d.write(regCommand, cmd); err != nil {
+		return nil, 0, err
+	}
+	if cmd == cmdTransceive {
+		// StartSend.
+		if err := d.write(regBitFraming, 0x80|framing); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := d.wait(irqs); err != nil {
+		return nil, 0, err
+	}
+	e, err := d.read(regError)
+	if err != nil {
+		return nil, 0, err
+	}
+	if e&(errBufOvfl|errParity|errProtocol) != 0 {
+		return nil, 0, fmt.Errorf("mfrc522: communication error %#02x", e)
+	}
+	n, err := d.read(regFIFOLevel)
+	if err != nil {
+		return nil, 0, err
+	}
+	r, err := d.readFIFO(int(n & 0x7F))
+	if err != nil {
+		return nil, 0, err
+	}
+	bits, err := d.read(regControl)
+	if err != nil {
+		return nil, 0, err
+	}
+	if e&errColl != 0 {
+		return r, bits & 0x07, errCollision
+	}
+	return r, bits & 0x07, nil
+}
+
+// wait waits for one of the irqs or the timer.
+func (d *Dev) wait(irqs byte) error {
+	for start := time.Now(); ; {
+		v, err := d.read(regComIrq)
+		if err != nil {
+			return err
+		}
+		if v&irqs != 0 {
+			return nil
+		}
+		if v&irqTimer != 0 {
+			return errTimeout
+		}
+		// The timer normally expires way before.
+		if time.Since(start) > 100*time.Millisecond {
+			return errors.New("mfrc522: timed out")
+		}
+		if d.irq != nil {
+			d.irq.WaitForEdge(25 * time.Millisecond)
+		} else {
+			sleep(time.Millisecond)
+		}
+	}
+}
+
+// write writes v to a register; multiple values are only useful for the FIFO.
+func (d *Dev) write(reg byte, v ...byte) error {
+	if err := d.c.Tx(append([]byte{reg << 1}, v...), nil); err != nil {
+		return fmt.Errorf("mfrc522: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) read(reg byte) (byte, error) {
+	var r [2]byte
+	if err := d.c.Tx([]byte{0x80 | reg<<1, 0}, r[:]); err != nil {
+		return 0, fmt.Errorf("mfrc522: %v", err)
+	}
+	return r[1], nil
+}
+
+// readFIFO reads n bytes from the FIFO in a single transaction.
+func (d *Dev) readFIFO(n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	w := make([]byte, n+1)
+	for i := 0; i < n; i++ {
+		w[i] = 0x80 | regFIFOData<<1
+	}
+	r := make([]byte, n+1)
+	if err := d.c.Tx(w, r); err != nil {
+		return nil, fmt.Errorf("mfrc522: %v", err)
+	}
+	return r[1:], nil
+}
+
+func (d *Dev) setBits(reg, mask byte) error {
+	v, err := d.read(reg)
+	if err != nil {
+		return err
+	}
+	return d.write(reg, v|mask)
+}
+
+func (d *Dev) clearBits(reg, mask byte) error {
+	v, err := d.read(reg)
+	if err != nil {
+		return err
+	}
+	return d.write(reg, v&^mask)
+}
+
+// crcA returns the ISO/IEC 14443 type A CRC of b.
+func crcA(b []byte) uint16 {
+	crc := uint16(0x6363)
+	for _, c := range b {
+		c ^= byte(crc)
+		c ^= c << 4
+		crc = crc>>8 ^ uint16(c)<<8 ^ uint16(c)<<3 ^ uint16(c)>>4
+	}
+	return crc
+}
+
+// appendCRC appends the CRC to b, LSB first.
+func appendCRC(b []byte) []byte {
+	c := crcA(b)
+	return append(b, byte(c), byte(c>>8))
+}
+
+// checkCRC returns true if the last 2 bytes of b are its valid CRC.
+func checkCRC(b []byte) bool {
+	c := crcA(b[:len(b)-2])
+	return b[len(b)-2] == byte(c) && b[len(b)-1] == byte(c>>8)
+}
+
+var defaults = Opts{
+	RxGain: 4,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
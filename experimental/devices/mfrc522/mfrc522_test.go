@@ -0,0 +1,483 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mfrc522
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dev, err := NewSPI(p, gpioreg.ByName("GPIO25"), gpioreg.ByName("GPIO24"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize mfrc522: %v", err)
+	}
+	defer dev.Halt()
+	c, err := dev.WaitForCard(10 * time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", c)
+	if err := dev.MifareAuthenticate(4, false, DefaultKey, c.UID); err != nil {
+		log.Fatal(err)
+	}
+	data, err := dev.MifareRead(4)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("block 4: %x\n", data)
+	if err := dev.HaltCard(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewSPI_Mifare(t *testing.T) {
+	card := &fakeCard{uid: []byte{0xDE, 0xAD, 0xBE, 0xEF}, atqa: 0x0004, sak: 0x08, key: Key{1, 2, 3, 4, 5, 6}}
+	f := newFakeChip(card)
+	rst := gpiotest.Pin{N: "RST"}
+	d, err := NewSPI(f, &rst, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rst.L != gpio.High {
+		t.Fatal("not reset")
+	}
+	if s := d.String(); s != "MFRC522{fake}" {
+		t.Fatal(s)
+	}
+	if v := d.Version(); v != 0x92 {
+		t.Fatal(v)
+	}
+	if f.regs[regRFCfg] != 0x40 || f.regs[regTxControl]&0x03 != 0x03 {
+		t.Fatal(f.regs[regRFCfg], f.regs[regTxControl])
+	}
+	c, err := d.ReadCard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "Card{UID:deadbeef, ATQA:0x0004, SAK:0x08}" {
+		t.Fatal(s)
+	}
+	if err := d.MifareAuthenticate(4, false, DefaultKey, c.UID); err == nil {
+		t.Fatal("wrong key")
+	}
+	if err := d.MifareAuthenticate(4, true, card.key, c.UID); err != nil {
+		t.Fatal(err)
+	}
+	var data [16]byte
+	copy(data[:], "periph.io rocks!")
+	if err := d.MifareWrite(5, data); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := d.MifareRead(5); err != nil || b != data {
+		t.Fatal(b, err)
+	}
+	if err := d.MifareAuthenticate(4, false, DefaultKey, nil); err == nil {
+		t.Fatal("invalid uid")
+	}
+	if err := d.HaltCard(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.MifareRead(5); err == nil {
+		t.Fatal("no card selected")
+	}
+	// The card is halted.
+	if c, err := d.ReadCard(); c != nil || err != nil {
+		t.Fatal(c, err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if f.regs[regTxControl]&0x03 != 0 {
+		t.Fatal("antenna still on")
+	}
+	card.halted = false
+	if c, err := d.ReadCard(); c == nil || err != nil {
+		t.Fatal(c, err)
+	}
+}
+
+func TestReadCard_collision(t *testing.T) {
+	a := &fakeCard{uid: []byte{0x12, 0x34, 0x56, 0x78}, atqa: 0x0004, sak: 0x08}
+	b := &fakeCard{uid: []byte{0x12, 0x34, 0x57, 0x78}, atqa: 0x0004, sak: 0x08}
+	f := newFakeChip(a, b)
+	irq := gpiotest.Pin{N: "IRQ", EdgesChan: make(chan gpio.Level, 1)}
+	f.edges = irq.EdgesChan
+	d, err := NewSPI(f, nil, &irq, &Opts{RxGain: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.regs[regComIEn] != 0xB1 || f.regs[regDivIEn] != 0x80 || f.regs[regRFCfg] != 0x70 {
+		t.Fatal(f.regs[regComIEn], f.regs[regDivIEn], f.regs[regRFCfg])
+	}
+	// The card with a 1 at the collision position wins.
+	for _, e := range []*fakeCard{b, a} {
+		c, err := d.ReadCard()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(c.UID, e.uid) {
+			t.Fatal(c)
+		}
+		if err := d.HaltCard(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.WaitForCard(0); err == nil {
+		t.Fatal("no card")
+	}
+}
+
+func TestReadCard_cascade(t *testing.T) {
+	card := &fakeCard{uid: []byte{0x04, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, atqa: 0x0044, sak: 0x00}
+	d, err := NewSPI(newFakeChip(card), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.WaitForCard(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "Card{UID:04112233445566, ATQA:0x0044, SAK:0x00}" {
+		t.Fatal(s)
+	}
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	if _, err := NewSPI(newFakeChip(), nil, nil, &Opts{RxGain: 8}); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if _, err := NewSPI(&spitest.Record{Initialized: true}, nil, nil, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+	// Reads zeros.
+	if _, err := NewSPI(&spitest.Record{}, nil, nil, nil); err == nil {
+		t.Fatal("not found")
+	}
+	if _, err := NewSPI(&failPort{}, nil, nil, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+	if _, err := NewSPI(newFakeChip(), nil, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("irq failed")
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// Examples from ISO/IEC 14443-3 annex B.
+	if b := appendCRC([]byte{0x00, 0x00}); !bytes.Equal(b, []byte{0x00, 0x00, 0xA0, 0x1E}) {
+		t.Fatal(b)
+	}
+	if b := appendCRC([]byte{0x12, 0x34}); !bytes.Equal(b, []byte{0x12, 0x34, 0x26, 0xCF}) || !checkCRC(b) {
+		t.Fatal(b)
+	}
+	if checkCRC([]byte{0x12, 0x34, 0x26, 0xCE}) {
+		t.Fatal("invalid crc")
+	}
+}
+
+//
+
+type fakeCard struct {
+	uid    []byte
+	atqa   uint16
+	sak    byte
+	key    Key
+	blocks map[byte][16]byte
+	halted bool
+}
+
+// level returns the UID bytes and BCC sent at a cascade level.
+func (c *fakeCard) level(l int) []byte {
+	var b []byte
+	if l == (len(c.uid)-4)/3 {
+		b = append(b, c.uid[3*l:]...)
+	} else {
+		b = append([]byte{0x88}, c.uid[3*l:3*l+3]...)
+	}
+	return append(b, b[0]^b[1]^b[2]^b[3])
+}
+
+// fakeChip emulates a MFRC522 with cards in its field.
+type fakeChip struct {
+	regs     [64]byte
+	fifo     []byte
+	cards    []*fakeCard
+	selected *fakeCard
+	level    int
+	write    int
+	// pending is the irq raised on the next read of ComIrqReg, to exercise
+	// waiting.
+	pending byte
+	edges   chan gpio.Level
+}
+
+func newFakeChip(cards ...*fakeCard) *fakeChip {
+	f := &fakeChip{cards: cards, write: -1}
+	f.regs[regVersion] = 0x92
+	return f
+}
+
+func (f *fakeChip) String() string {
+	return "fake"
+}
+
+func (f *fakeChip) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	return f, nil
+}
+
+func (f *fakeChip) Tx(w, r []byte) error {
+	if w[0]&0x80 != 0 {
+		for i := 0; i < len(w)-1; i++ {
+			r[i+1] = f.readReg(w[i] >> 1 & 0x3F)
+		}
+		return nil
+	}
+	for _, v := range w[1:] {
+		f.writeReg(w[0]>>1, v)
+	}
+	return nil
+}
+
+func (f *fakeChip) TxPackets(p []spi.Packet) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeChip) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeChip) readReg(reg byte) byte {
+	switch reg {
+	case regFIFOData:
+		v := f.fifo[0]
+		f.fifo = f.fifo[1:]
+		return v
+	case regFIFOLevel:
+		return byte(len(f.fifo))
+	case regComIrq:
+		if f.pending != 0 {
+			f.regs[regComIrq] |= f.pending
+			f.pending = 0
+			if f.edges != nil {
+				f.edges <- gpio.Low
+			}
+			return 0
+		}
+	}
+	return f.regs[reg]
+}
+
+func (f *fakeChip) writeReg(reg, v byte) {
+	switch reg {
+	case regFIFOData:
+		f.fifo = append(f.fifo, v)
+	case regFIFOLevel:
+		if v&0x80 != 0 {
+			f.fifo = nil
+		}
+	case regComIrq:
+		if v&0x80 != 0 {
+			f.regs[reg] |= v & 0x7F
+		} else {
+			f.regs[reg] &^= v
+		}
+	case regCommand:
+		f.regs[reg] = v
+		if v == cmdMFAuthent {
+			f.auth()
+		}
+	case regBitFraming:
+		f.regs[reg] = v
+		if v&0x80 != 0 && f.regs[regCommand] == cmdTransceive {
+			f.transceive()
+		}
+	default:
+		f.regs[reg] = v
+	}
+}
+
+func (f *fakeChip) finish(irq byte, resp []byte, bits, e byte) {
+	f.fifo = resp
+	f.regs[regControl] = bits
+	f.regs[regError] = e
+	f.pending = irq
+}
+
+func (f *fakeChip) auth() {
+	w := f.fifo
+	if f.selected == nil || len(w) != 12 {
+		f.finish(irqTimer, nil, 0, 0)
+		return
+	}
+	if bytes.Equal(w[2:8], f.selected.key[:]) && bytes.Equal(w[8:], f.selected.uid[len(f.selected.uid)-4:]) {
+		f.regs[regStatus2] |= status2Crypto1On
+	}
+	f.finish(irqIdle, nil, 0, 0)
+}
+
+func (f *fakeChip) transceive() {
+	w := f.fifo
+	last := int(f.regs[regBitFraming] & 0x07)
+	if f.regs[regTxControl]&0x03 == 0 {
+		f.finish(irqTimer, nil, 0, 0)
+		return
+	}
+	switch {
+	case len(w) == 1 && last == 7 && w[0] == piccREQA:
+		f.selected = nil
+		f.level = 0
+		f.regs[regStatus2] &^= status2Crypto1On
+		for _, c := range f.cards {
+			if !c.halted {
+				f.finish(irqRx, []byte{byte(c.atqa), byte(c.atqa >> 8)}, 0, 0)
+				return
+			}
+		}
+	case len(w) == 9 && (w[0] == piccSelCL1 || w[0] == piccSelCL2 || w[0] == piccSelCL3) && w[1] == 0x70 && checkCRC(w):
+		for _, c := range f.candidates(int(w[0]-piccSelCL1) / 2) {
+			if bytes.Equal(c.level(f.level), w[2:7]) {
+				f.selected = c
+				sak := byte(0x04)
+				if f.level == (len(c.uid)-4)/3 {
+					sak = c.sak
+				}
+				f.level++
+				f.finish(irqRx, appendCRC([]byte{sak}), 0, 0)
+				return
+			}
+		}
+	case len(w) >= 2 && (w[0] == piccSelCL1 || w[0] == piccSelCL2 || w[0] == piccSelCL3):
+		f.anticollision(w, last)
+		return
+	case len(w) == 4 && w[0] == piccRead && checkCRC(w) && f.regs[regStatus2]&status2Crypto1On != 0:
+		b := f.selected.blocks[w[1]]
+		f.finish(irqRx, appendCRC(b[:]), 0, 0)
+		return
+	case len(w) == 4 && w[0] == piccWrite && checkCRC(w) && f.regs[regStatus2]&status2Crypto1On != 0:
+		f.write = int(w[1])
+		f.finish(irqRx, []byte{piccACK}, 4, 0)
+		return
+	case len(w) == 18 && f.write != -1 && checkCRC(w):
+		if f.selected.blocks == nil {
+			f.selected.blocks = map[byte][16]byte{}
+		}
+		var b [16]byte
+		copy(b[:], w)
+		f.selected.blocks[byte(f.write)] = b
+		f.write = -1
+		f.finish(irqRx, []byte{piccACK}, 4, 0)
+		return
+	case len(w) == 4 && w[0] == piccHLTA && checkCRC(w):
+		if f.selected != nil {
+			f.selected.halted = true
+			f.selected = nil
+		}
+	}
+	f.finish(irqTimer, nil, 0, 0)
+}
+
+// candidates returns the cards taking part in a cascade level.
+func (f *fakeChip) candidates(l int) []*fakeCard {
+	if l != f.level {
+		return nil
+	}
+	if l != 0 {
+		return []*fakeCard{f.selected}
+	}
+	var out []*fakeCard
+	for _, c := range f.cards {
+		if !c.halted {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (f *fakeChip) anticollision(w []byte, last int) {
+	known := int(w[1]>>4-2)*8 + int(w[1]&0x0F)
+	bit := func(b []byte, i int) byte {
+		return b[i/8] >> uint(i%8) & 1
+	}
+	var match []*fakeCard
+	for _, c := range f.candidates(int(w[0]-piccSelCL1) / 2) {
+		l := c.level(f.level)
+		ok := true
+		for i := 0; i < known; i++ {
+			if bit(l, i) != bit(w[2:], i) {
+				ok = false
+			}
+		}
+		if ok {
+			match = append(match, c)
+		}
+	}
+	if len(match) == 0 {
+		f.finish(irqTimer, nil, 0, 0)
+		return
+	}
+	l := match[0].level(f.level)
+	r := append([]byte{}, l[known/8:]...)
+	r[0] &= byte(0xFF) << uint(last)
+	for i := known; i < 40; i++ {
+		for _, c := range match[1:] {
+			if bit(c.level(f.level), i) != bit(l, i) {
+				f.regs[regColl] = byte(i+1) & 0x1F
+				f.finish(irqRx, r, 0, errColl)
+				return
+			}
+		}
+	}
+	f.finish(irqRx, r, 0, 0)
+}
+
+// failPort fails all transactions.
+type failPort struct{}
+
+func (f *failPort) String() string {
+	return "fail"
+}
+
+func (f *failPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	return f, nil
+}
+
+func (f *failPort) Tx(w, r []byte) error {
+	return errors.New("injected")
+}
+
+func (f *failPort) TxPackets(p []spi.Packet) error {
+	return errors.New("injected")
+}
+
+func (f *failPort) Duplex() conn.Duplex {
+	return conn.Half
+}
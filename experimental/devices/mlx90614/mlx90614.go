@@ -0,0 +1,197 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mlx90614 controls a Melexis MLX90614 infrared thermometer over
+// SMBus.
+//
+// All the transfers are verified with the SMBus packet error code (PEC).
+//
+// Datasheet
+//
+// https://www.melexis.com/-/media/files/documents/datasheets/mlx90614-datasheet-melexis.pdf
+package mlx90614
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Dev is a handle to a MLX90614.
+type Dev struct {
+	c    i2c.Dev
+	mu   sync.Mutex
+	dual bool
+}
+
+// NewI2C returns an object that communicates over SMBus to a MLX90614.
+//
+// The factory default address is 0x5A.
+func NewI2C(b i2c.Bus, addr uint16) (*Dev, error) {
+	if addr == 0 || addr > 0x7F {
+		return nil, errors.New("mlx90614: given address not supported by device")
+	}
+	d := &Dev{c: i2c.Dev{Bus: b, Addr: addr}}
+	// Verifies the device is present and whether it has two thermopiles.
+	v, err := d.readWord(eepromConfig)
+	if err != nil {
+		return nil, err
+	}
+	d.dual = v&configDual != 0
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MLX90614{%s}", &d.c)
+}
+
+// Ambient returns the temperature of the device itself.
+func (d *Dev) Ambient() (devices.Celsius, error) {
+	return d.readTemp(ramTa)
+}
+
+// Object returns the temperature of the object in the field of view.
+//
+// The value depends on the emissivity of the object.
+func (d *Dev) Object() (devices.Celsius, error) {
+	return d.readTemp(ramTobj1)
+}
+
+// Object2 returns the temperature measured by the second thermopile of dual
+// zone devices, e.g. the MLX90614xCx.
+func (d *Dev) Object2() (devices.Celsius, error) {
+	if !d.dual {
+		return 0, errors.New("mlx90614: device has a single thermopile")
+	}
+	return d.readTemp(ramTobj2)
+}
+
+// Emissivity returns the emissivity used to calculate the object
+// temperature, between 0.1 and 1.
+func (d *Dev) Emissivity() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, err := d.readWord(eepromEmissivity)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 65535, nil
+}
+
+// SetEmissivity stores the emissivity in EEPROM.
+//
+// The default is 1. The EEPROM supports a limited number of write cycles,
+// typically 100000.
+func (d *Dev) SetEmissivity(e float64) error {
+	if e < 0.1 || e > 1 {
+		return fmt.Errorf("mlx90614: emissivity %g is out of range [0.1, 1]", e)
+	}
+	v := uint16(math.Floor(e*65535 + 0.5))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// An EEPROM cell must be erased before being written; page 16.
+	if err := d.writeWord(eepromEmissivity, 0); err != nil {
+		return err
+	}
+	sleep(10 * time.Millisecond)
+	if err := d.writeWord(eepromEmissivity, v); err != nil {
+		return err
+	}
+	sleep(10 * time.Millisecond)
+	r, err := d.readWord(eepromEmissivity)
+	if err != nil {
+		return err
+	}
+	if r != v {
+		return errors.New("mlx90614: failed to write EEPROM")
+	}
+	return nil
+}
+
+// Halt is a no-op; the device measures continuously.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+//
+
+const (
+	ramTa    = 0x06
+	ramTobj1 = 0x07
+	ramTobj2 = 0x08
+
+	eepromEmissivity = 0x24
+	eepromConfig     = 0x25
+
+	configDual = 0x40
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+func (d *Dev) readTemp(cmd byte) (devices.Celsius, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	v, err := d.readWord(cmd)
+	if err != nil {
+		return 0, err
+	}
+	if v&0x8000 != 0 {
+		return 0, errors.New("mlx90614: measurement error")
+	}
+	// 0.02°K per LSB.
+	return devices.Celsius(int32(v)*20 - 273150), nil
+}
+
+// readWord reads a SMBus word and verifies its PEC.
+//
+// It must be called with d.mu lock held, except in the constructor.
+func (d *Dev) readWord(cmd byte) (uint16, error) {
+	var r [3]byte
+	if err := d.c.Tx([]byte{cmd}, r[:]); err != nil {
+		return 0, fmt.Errorf("mlx90614: %v", err)
+	}
+	a := byte(d.c.Addr << 1)
+	if pec([]byte{a, cmd, a | 1, r[0], r[1]}) != r[2] {
+		return 0, errors.New("mlx90614: invalid PEC")
+	}
+	return uint16(r[0]) | uint16(r[1])<<8, nil
+}
+
+// writeWord writes a SMBus word followed by its PEC.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) writeWord(cmd byte, v uint16) error {
+	w := []byte{cmd, byte(v), byte(v >> 8), 0}
+	w[3] = pec(append([]byte{byte(d.c.Addr << 1)}, w[:3]...))
+	if err := d.c.Tx(w, nil); err != nil {
+		return fmt.Errorf("mlx90614: %v", err)
+	}
+	return nil
+}
+
+// pec calculates the SMBus packet error code, a CRC with polynomial 0x07.
+func pec(b []byte) byte {
+	crc := byte(0)
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,183 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mlx90614
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x5A)
+	if err != nil {
+		log.Fatalf("failed to initialize mlx90614: %v", err)
+	}
+	a, err := dev.Ambient()
+	if err != nil {
+		log.Fatal(err)
+	}
+	o, err := dev.Object()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("ambient: %s object: %s\n", a, o)
+}
+
+//
+
+func TestNewI2C(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x5A, W: []byte{0x25}, R: []byte{0xB4, 0x9F, 0x2B}},
+			{Addr: 0x5A, W: []byte{0x06}, R: []byte{0x3E, 0x3A, 0x99}},
+			{Addr: 0x5A, W: []byte{0x07}, R: []byte{0x8C, 0x3C, 0xF8}},
+			{Addr: 0x5A, W: []byte{0x07}, R: []byte{0x00, 0x80, 0x8F}},
+			{Addr: 0x5A, W: []byte{0x24}, R: []byte{0xFF, 0xFF, 0xD6}},
+			// Invalid PEC.
+			{Addr: 0x5A, W: []byte{0x24}, R: []byte{0xFF, 0xFF, 0x00}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x5A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MLX90614{playback(90)}" {
+		t.Fatal(s)
+	}
+	if c, err := dev.Ambient(); c != 25050 || err != nil {
+		t.Fatal(c, err)
+	}
+	if c, err := dev.Object(); c != 36850 || err != nil {
+		t.Fatal(c, err)
+	}
+	if _, err := dev.Object(); err == nil {
+		t.Fatal("error flag")
+	}
+	if _, err := dev.Object2(); err == nil {
+		t.Fatal("single thermopile")
+	}
+	if e, err := dev.Emissivity(); e != 1 || err != nil {
+		t.Fatal(e, err)
+	}
+	if _, err := dev.Emissivity(); err == nil {
+		t.Fatal("invalid PEC")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObject2(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x5A, W: []byte{0x25}, R: []byte{0xF4, 0x9F, 0x70}},
+			{Addr: 0x5A, W: []byte{0x08}, R: []byte{0xC8, 0x32, 0x0F}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x5A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := dev.Object2(); c != -13150 || err != nil {
+		t.Fatal(c, err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetEmissivity(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x5A, W: []byte{0x25}, R: []byte{0xB4, 0x9F, 0x2B}},
+			{Addr: 0x5A, W: []byte{0x24, 0x00, 0x00, 0x28}},
+			{Addr: 0x5A, W: []byte{0x24, 0x32, 0xF3, 0x2C}},
+			{Addr: 0x5A, W: []byte{0x24}, R: []byte{0x32, 0xF3, 0xF6}},
+			// Write failed.
+			{Addr: 0x5A, W: []byte{0x24, 0x00, 0x00, 0x28}},
+			{Addr: 0x5A, W: []byte{0x24, 0x32, 0xF3, 0x2C}},
+			{Addr: 0x5A, W: []byte{0x24}, R: []byte{0x00, 0x00, 0x00}},
+		},
+	}
+	dev, err := NewI2C(&bus, 0x5A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetEmissivity(0.95); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetEmissivity(0.95); err == nil {
+		t.Fatal("EEPROM not written")
+	}
+	if err := dev.SetEmissivity(0); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := dev.SetEmissivity(1.1); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x80); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x5A); err == nil {
+		t.Fatal("read failed")
+	}
+	for n := 1; n < 4; n++ {
+		ops := []i2ctest.IO{
+			{Addr: 0x5A, W: []byte{0x25}, R: []byte{0xB4, 0x9F, 0x2B}},
+			{Addr: 0x5A, W: []byte{0x24, 0x00, 0x00, 0x28}},
+			{Addr: 0x5A, W: []byte{0x24, 0x32, 0xF3, 0x2C}},
+		}
+		bus := i2ctest.Playback{Ops: ops[:n], DontPanic: true}
+		dev, err := NewI2C(&bus, 0x5A)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.SetEmissivity(0.95); err == nil {
+			t.Fatalf("#%d: write failed", n)
+		}
+		if _, err := dev.Emissivity(); err == nil {
+			t.Fatalf("#%d: read failed", n)
+		}
+		if _, err := dev.Ambient(); err == nil {
+			t.Fatalf("#%d: read failed", n)
+		}
+	}
+}
+
+func TestPEC(t *testing.T) {
+	// Example from the datasheet, page 21.
+	if p := pec([]byte{0xB4, 0x07, 0xB5, 0xD2, 0x3A}); p != 0x30 {
+		t.Fatalf("%#x", p)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
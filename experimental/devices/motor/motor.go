@@ -0,0 +1,106 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package motor defines the interfaces implemented by motor drivers, so an
+// application can drive motors independently of the driver chip used.
+package motor
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Direction is the direction of rotation of a DC motor.
+type Direction int
+
+// Valid Direction values.
+const (
+	Forward  Direction = 0
+	Backward Direction = 1
+)
+
+const directionName = "ForwardBackward"
+
+var directionIndex = [...]uint8{0, 7, 15}
+
+func (d Direction) String() string {
+	if d < 0 || d >= Direction(len(directionIndex)-1) {
+		return fmt.Sprintf("Direction(%d)", d)
+	}
+	return directionName[directionIndex[d]:directionIndex[d+1]]
+}
+
+// Stepper represents a stepper motor.
+//
+// The position is tracked in software, as steps relative to the position at
+// initialization. A step is a microstep when the driver is configured for
+// microstepping.
+type Stepper interface {
+	conn.Resource
+
+	// Move starts moving the motor by the number of steps relative to the
+	// current position. Negative values turn the motor backward.
+	//
+	// It returns immediately. The current motion, if any, is stopped first.
+	Move(steps int) error
+	// MoveTo starts moving the motor to the absolute position pos, in steps.
+	//
+	// It returns immediately. The current motion, if any, is stopped first.
+	MoveTo(pos int) error
+	// Wait blocks until the current motion completes or is stopped.
+	Wait() error
+	// Position returns the current position, in steps.
+	Position() int
+	// Stop stops the current motion immediately and holds the position.
+	//
+	// Halt() stops the motion and releases the motor.
+	Stop() error
+}
+
+// DC represents a brushed DC motor driven by a H-bridge.
+type DC interface {
+	conn.Resource
+
+	// Run drives the motor in the direction at the speed, specified as the
+	// duty cycle of the PWM applied to the motor.
+	Run(dir Direction, speed gpio.Duty) error
+	// Brake shorts the motor terminals to stop it quickly.
+	//
+	// Halt() lets the motor coast to a stop instead.
+	Brake() error
+}
+
+// Profile is a trapezoidal motion profile: the motor accelerates up to Speed,
+// then decelerates to stop on the target.
+type Profile struct {
+	// Speed is the maximum speed in steps per second.
+	Speed int
+	// Acceleration is in steps per second², used both to accelerate and to
+	// decelerate. 0 disables the ramps, the motor then steps at Speed right
+	// away.
+	Acceleration int
+}
+
+// Delay returns the delay after the step i of a motion of n steps.
+//
+// The speed is limited by the acceleration from the start of the motion and
+// by the deceleration needed to stop at its end.
+func (p Profile) Delay(i, n int) time.Duration {
+	v := float64(p.Speed)
+	if p.Acceleration != 0 {
+		m := i + 1
+		if n-i < m {
+			m = n - i
+		}
+		// v² = 2·a·distance
+		if r := math.Sqrt(2 * float64(p.Acceleration) * float64(m)); r < v {
+			v = r
+		}
+	}
+	return time.Duration(float64(time.Second) / v)
+}
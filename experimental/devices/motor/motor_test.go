@@ -0,0 +1,48 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package motor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	p := Profile{Speed: 100, Acceleration: 200}
+	// The speed reaches 100 steps/s after 25 steps.
+	data := []struct {
+		i, n     int
+		expected time.Duration
+	}{
+		{0, 100, 50 * time.Millisecond},
+		{3, 100, 25 * time.Millisecond},
+		{24, 100, 10 * time.Millisecond},
+		{50, 100, 10 * time.Millisecond},
+		{96, 100, 25 * time.Millisecond},
+		{99, 100, 50 * time.Millisecond},
+		// Never reaches the maximum speed.
+		{3, 8, 25 * time.Millisecond},
+		{4, 8, 25 * time.Millisecond},
+		{0, 1, 50 * time.Millisecond},
+	}
+	for i, line := range data {
+		if d := p.Delay(line.i, line.n); d != line.expected {
+			t.Fatalf("#%d: %s != %s", i, d, line.expected)
+		}
+	}
+	p.Acceleration = 0
+	if d := p.Delay(0, 100); d != 10*time.Millisecond {
+		t.Fatal(d)
+	}
+}
+
+func TestDirection_String(t *testing.T) {
+	if s := Backward.String(); s != "Backward" {
+		t.Fatal(s)
+	}
+	if s := Direction(2).String(); s != "Direction(2)" {
+		t.Fatal(s)
+	}
+}
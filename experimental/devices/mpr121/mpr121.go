@@ -0,0 +1,392 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mpr121 controls a NXP MPR121 12 electrodes capacitive touch sensor
+// over I²C.
+//
+// The charge current and time of each electrode are configured automatically
+// by the device, based on the supply voltage.
+//
+// Datasheet
+//
+// https://www.nxp.com/docs/en/data-sheet/MPR121.pdf
+package mpr121
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Event is a change of the touch state of an electrode.
+type Event struct {
+	Electrode int
+	Touched   bool
+}
+
+func (e Event) String() string {
+	if e.Touched {
+		return fmt.Sprintf("E%d touched", e.Electrode)
+	}
+	return fmt.Sprintf("E%d released", e.Electrode)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Electrodes is the number of electrodes enabled, starting at ELE0. 0
+	// enables all 12.
+	Electrodes int
+	// TouchThreshold and ReleaseThreshold are the changes of the filtered data
+	// from the baseline to detect a touch and a release. The touch threshold
+	// is generally between 4 and 16 and the release threshold is lower.
+	TouchThreshold   uint8
+	ReleaseThreshold uint8
+	// TouchDebounce and ReleaseDebounce are the number of additional
+	// consecutive samples required to detect a touch and a release, between 0
+	// and 7.
+	TouchDebounce   int
+	ReleaseDebounce int
+	// AutoConfig enables the automatic configuration of the charge current
+	// and time of each electrode. When disabled, the global configuration of
+	// 16µA during 0.5µs is used.
+	AutoConfig bool
+	// Vdd is the supply voltage, between 1.71V and 3.6V, used to calculate
+	// the auto-configuration limits.
+	Vdd devices.Volt
+}
+
+// Dev is a handle to a MPR121.
+type Dev struct {
+	c    conn.Conn
+	intr gpio.PinIn
+	opts Opts
+	ecr  byte
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a MPR121.
+//
+// The address is 0x5A to 0x5D, depending on the connection of the ADDR pin.
+//
+// intr is optional and is connected to the IRQ pin, which is asserted low
+// when the touch state of an electrode changes. Without it, Events() polls
+// the device.
+func NewI2C(b i2c.Bus, addr uint16, intr gpio.PinIn, opts *Opts) (*Dev, error) {
+	if addr < 0x5A || addr > 0x5D {
+		return nil, errors.New("mpr121: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	n := opts.Electrodes
+	if n == 0 {
+		n = 12
+	}
+	if n < 1 || n > 12 {
+		return nil, fmt.Errorf("mpr121: invalid number of electrodes %d", opts.Electrodes)
+	}
+	if opts.TouchDebounce < 0 || opts.TouchDebounce > 7 || opts.ReleaseDebounce < 0 || opts.ReleaseDebounce > 7 {
+		return nil, errors.New("mpr121: debounce must be between 0 and 7")
+	}
+	if opts.AutoConfig && (opts.Vdd < 1710 || opts.Vdd > 3600) {
+		return nil, fmt.Errorf("mpr121: invalid Vdd %s", opts.Vdd)
+	}
+	if intr == gpio.INVALID {
+		intr = nil
+	}
+	d := &Dev{
+		c:    &i2c.Dev{Bus: b, Addr: addr},
+		intr: intr,
+		opts: *opts,
+		// Load the 5 MSB of the first sample as the baseline.
+		ecr: 0x80 | byte(n),
+	}
+	if err := d.c.Tx([]byte{regSoftReset, 0x63}, nil); err != nil {
+		return nil, fmt.Errorf("mpr121: %v", err)
+	}
+	sleep(time.Millisecond)
+	// The device is in stop mode after reset, and the AFE configuration 2 is
+	// the only register not reset to 0.
+	var v [1]byte
+	if err := d.readReg(regConfig2, v[:]); err != nil {
+		return nil, err
+	}
+	if v[0] != 0x24 {
+		return nil, fmt.Errorf("mpr121: unexpected configuration %#x after reset", v[0])
+	}
+	if err := d.writeThresholds(opts.TouchThreshold, opts.ReleaseThreshold); err != nil {
+		return nil, err
+	}
+	// Baseline filters for rising, falling and touched data; page 12.
+	if err := d.write(regMHDR, 0x01, 0x01, 0x0E, 0x00, 0x01, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00); err != nil {
+		return nil, err
+	}
+	if err := d.write(regDebounce, byte(opts.ReleaseDebounce<<4|opts.TouchDebounce)); err != nil {
+		return nil, err
+	}
+	// 6 samples for the first filter, 16µA; 0.5µs, 4 samples for the second
+	// filter, 1ms sampling period.
+	if err := d.write(regConfig1, 0x10, 0x20); err != nil {
+		return nil, err
+	}
+	if opts.AutoConfig {
+		// Limits for the charge level as recommended in AN3889.
+		usl := byte((opts.Vdd - 700) * 256 / opts.Vdd)
+		lsl := byte(uint16(usl) * 65 / 100)
+		tl := byte(uint16(usl) * 90 / 100)
+		if err := d.write(regUSL, usl, lsl, tl); err != nil {
+			return nil, err
+		}
+		// Baseline value adjustment, retry disabled, auto-reconfiguration
+		// and auto-configuration enabled.
+		if err := d.write(regAutoConfig0, 0x0B); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.write(regECR, d.ecr); err != nil {
+		return nil, err
+	}
+	if d.intr != nil {
+		if err := d.intr.In(gpio.PullUp, gpio.FallingEdge); err != nil {
+			return nil, fmt.Errorf("mpr121: %v", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("MPR121{%s}", d.c)
+}
+
+// Touched returns the touch state of the electrodes as a bitmap, bit 0 being
+// ELE0.
+//
+// Reading the touch state deasserts the IRQ pin.
+func (d *Dev) Touched() (uint16, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.touched()
+}
+
+// Events returns a channel on which the touch and release events are sent.
+//
+// It is important to call Halt() once done, which will close the channel.
+func (d *Dev) Events() (<-chan Event, error) {
+	d.stopEvents()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, err := d.touched()
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan Event, 12)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(c)
+		d.sensingEvents(t, c, stop)
+	}(d.stop)
+	return c, nil
+}
+
+// SetThresholds changes the touch and release thresholds of all the
+// electrodes.
+func (d *Dev) SetThresholds(touch, release uint8) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// The registers can only be written in stop mode.
+	if err := d.write(regECR, 0); err != nil {
+		return err
+	}
+	if err := d.writeThresholds(touch, release); err != nil {
+		return err
+	}
+	return d.write(regECR, d.ecr)
+}
+
+// Filtered returns the filtered data of the electrode n, between 0 and 12,
+// 12 being the proximity detection electrode.
+//
+// Use it with Baseline() to tune the thresholds; the electrode is touched when
+// the filtered data is lower than the baseline by TouchThreshold.
+func (d *Dev) Filtered(n int) (uint16, error) {
+	if n < 0 || n > 12 {
+		return 0, fmt.Errorf("mpr121: invalid electrode %d", n)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [2]byte
+	if err := d.readReg(regFiltered+byte(2*n), b[:]); err != nil {
+		return 0, err
+	}
+	return (uint16(b[0]) | uint16(b[1])<<8) & 0x3FF, nil
+}
+
+// Baseline returns the baseline of the electrode n, between 0 and 12.
+//
+// Only the 8 MSB of the 10 bits baseline are available.
+func (d *Dev) Baseline(n int) (uint16, error) {
+	if n < 0 || n > 12 {
+		return 0, fmt.Errorf("mpr121: invalid electrode %d", n)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var b [1]byte
+	if err := d.readReg(regBaseline+byte(n), b[:]); err != nil {
+		return 0, err
+	}
+	return uint16(b[0]) << 2, nil
+}
+
+// Halt stops the events and puts the device in stop mode.
+//
+// The next call to Events() or SetThresholds() starts it again.
+func (d *Dev) Halt() error {
+	d.stopEvents()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.write(regECR, 0)
+}
+
+//
+
+const (
+	regTouchStatus = 0x00
+	regFiltered    = 0x04
+	regBaseline    = 0x1E
+	regMHDR        = 0x2B
+	regThresholds  = 0x41
+	regDebounce    = 0x5B
+	regConfig1     = 0x5C
+	regConfig2     = 0x5D
+	regECR         = 0x5E
+	regAutoConfig0 = 0x7B
+	regUSL         = 0x7D
+	regSoftReset   = 0x80
+
+	statusOverCurrent = 0x8000
+
+	// pollInterval is used when there is no interrupt pin.
+	pollInterval = 10 * time.Millisecond
+)
+
+var defaults = Opts{
+	TouchThreshold:   12,
+	ReleaseThreshold: 6,
+	AutoConfig:       true,
+	Vdd:              3300,
+}
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+func (d *Dev) stopEvents() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingEvents(last uint16, c chan<- Event, stop <-chan struct{}) {
+	var t *time.Ticker
+	if d.intr == nil {
+		t = time.NewTicker(pollInterval)
+		defer t.Stop()
+	}
+	for {
+		if t != nil {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+		} else {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Wake up regularly to check for stop.
+			if d.intr.Read() == gpio.High && !d.intr.WaitForEdge(100*time.Millisecond) {
+				continue
+			}
+		}
+		d.mu.Lock()
+		s, err := d.touched()
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		for i := 0; i < 12; i++ {
+			if m := uint16(1) << uint(i); (s^last)&m != 0 {
+				select {
+				case c <- Event{Electrode: i, Touched: s&m != 0}:
+				case <-stop:
+					return
+				}
+			}
+		}
+		last = s
+	}
+}
+
+// touched reads the touch status.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) touched() (uint16, error) {
+	var b [2]byte
+	if err := d.readReg(regTouchStatus, b[:]); err != nil {
+		return 0, err
+	}
+	s := uint16(b[0]) | uint16(b[1])<<8
+	if s&statusOverCurrent != 0 {
+		return 0, errors.New("mpr121: over current detected on REXT pin")
+	}
+	return s & 0xFFF, nil
+}
+
+// writeThresholds sets the thresholds of the 12 electrodes and the proximity
+// detection electrode, in stop mode.
+func (d *Dev) writeThresholds(touch, release uint8) error {
+	var b [26]byte
+	for i := 0; i < len(b); i += 2 {
+		b[i] = touch
+		b[i+1] = release
+	}
+	return d.write(regThresholds, b[:]...)
+}
+
+func (d *Dev) readReg(reg byte, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("mpr121: %v", err)
+	}
+	return nil
+}
+
+// write writes the values to consecutive registers starting at reg.
+func (d *Dev) write(reg byte, v ...byte) error {
+	if err := d.c.Tx(append([]byte{reg}, v...), nil); err != nil {
+		return fmt.Errorf("mpr121: %v", err)
+	}
+	return nil
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,348 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mpr121
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x5A, gpioreg.ByName("GPIO4"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize mpr121: %v", err)
+	}
+	defer dev.Halt()
+	events, err := dev.Events()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for e := range events {
+		fmt.Printf("%s\n", e)
+	}
+}
+
+//
+
+func TestNewI2C(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x5B, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MPR121{fake(91)}" {
+		t.Fatal(s)
+	}
+	expected := [][2]byte{{0x80, 0x63}}
+	expected = append(expected, thresholds(12, 6)...)
+	expected = append(expected,
+		[2]byte{0x2B, 0x01}, [2]byte{0x2C, 0x01}, [2]byte{0x2D, 0x0E}, [2]byte{0x2E, 0x00},
+		[2]byte{0x2F, 0x01}, [2]byte{0x30, 0x05}, [2]byte{0x31, 0x01}, [2]byte{0x32, 0x00},
+		[2]byte{0x33, 0x00}, [2]byte{0x34, 0x00}, [2]byte{0x35, 0x00},
+		[2]byte{0x5B, 0x00},
+		[2]byte{0x5C, 0x10}, [2]byte{0x5D, 0x20},
+		[2]byte{0x7D, 201}, [2]byte{0x7E, 130}, [2]byte{0x7F, 180},
+		[2]byte{0x7B, 0x0B},
+		[2]byte{0x5E, 0x8C},
+	)
+	f.checkWrites(t, expected)
+	f.regs[0x00] = 0x05
+	f.regs[0x01] = 0x08
+	if s, err := dev.Touched(); s != 0x805 || err != nil {
+		t.Fatal(s, err)
+	}
+	f.regs[0x01] = 0x80
+	if _, err := dev.Touched(); err == nil {
+		t.Fatal("over current")
+	}
+	// ELE3: 0x2A5; proximity: 0x3FF.
+	f.regs[0x0A] = 0xA5
+	f.regs[0x0B] = 0xFE
+	f.regs[0x1C] = 0xFF
+	f.regs[0x1D] = 0x03
+	if v, err := dev.Filtered(3); v != 0x2A5 || err != nil {
+		t.Fatal(v, err)
+	}
+	if v, err := dev.Filtered(12); v != 0x3FF || err != nil {
+		t.Fatal(v, err)
+	}
+	f.regs[0x21] = 0xAA
+	if v, err := dev.Baseline(3); v != 0x2A8 || err != nil {
+		t.Fatal(v, err)
+	}
+	for _, n := range []int{-1, 13} {
+		if _, err := dev.Filtered(n); err == nil {
+			t.Fatal("invalid electrode")
+		}
+		if _, err := dev.Baseline(n); err == nil {
+			t.Fatal("invalid electrode")
+		}
+	}
+	if err := dev.SetThresholds(20, 10); err != nil {
+		t.Fatal(err)
+	}
+	expected = [][2]byte{{0x5E, 0x00}}
+	expected = append(expected, thresholds(20, 10)...)
+	expected = append(expected, [2]byte{0x5E, 0x8C})
+	f.checkWrites(t, expected)
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	f.checkWrites(t, [][2]byte{{0x5E, 0x00}})
+}
+
+func TestNewI2C_opts(t *testing.T) {
+	f := newFakeBus()
+	opts := Opts{Electrodes: 4, TouchThreshold: 8, ReleaseThreshold: 4, TouchDebounce: 2, ReleaseDebounce: 7}
+	if _, err := NewI2C(f, 0x5A, gpio.INVALID, &opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]byte{{0x80, 0x63}}
+	expected = append(expected, thresholds(8, 4)...)
+	expected = append(expected,
+		[2]byte{0x2B, 0x01}, [2]byte{0x2C, 0x01}, [2]byte{0x2D, 0x0E}, [2]byte{0x2E, 0x00},
+		[2]byte{0x2F, 0x01}, [2]byte{0x30, 0x05}, [2]byte{0x31, 0x01}, [2]byte{0x32, 0x00},
+		[2]byte{0x33, 0x00}, [2]byte{0x34, 0x00}, [2]byte{0x35, 0x00},
+		[2]byte{0x5B, 0x72},
+		[2]byte{0x5C, 0x10}, [2]byte{0x5D, 0x20},
+		[2]byte{0x5E, 0x84},
+	)
+	f.checkWrites(t, expected)
+}
+
+func TestEvents_poll(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x5A, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setStatus(0x0001)
+	c, err := dev.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setStatus(0x0802)
+	expected := []Event{{0, false}, {1, true}, {11, true}}
+	for _, e := range expected {
+		if g := <-c; g != e {
+			t.Fatalf("%s != %s", g, e)
+		}
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+}
+
+func TestEvents_interrupt(t *testing.T) {
+	f := newFakeBus()
+	// Buffered so the edges never block; the driver may already be reading the
+	// status while the pin is low.
+	p := &gpiotest.Pin{EdgesChan: make(chan gpio.Level, 3)}
+	dev, err := NewI2C(f, 0x5A, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dev.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setStatus(0x0010)
+	p.EdgesChan <- gpio.Low
+	if e := <-c; e != (Event{4, true}) {
+		t.Fatal(e)
+	}
+	p.Out(gpio.High)
+	f.setStatus(0x0000)
+	p.EdgesChan <- gpio.Low
+	if e := <-c; e != (Event{4, false}) {
+		t.Fatal(e)
+	}
+	p.Out(gpio.High)
+	// Over current stops the events.
+	f.setStatus(0x8000)
+	p.EdgesChan <- gpio.Low
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x59, nil, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	data := []Opts{
+		{Electrodes: 13},
+		{Electrodes: -1},
+		{TouchDebounce: 8},
+		{ReleaseDebounce: -1},
+		{AutoConfig: true, Vdd: 5000},
+	}
+	for i, opts := range data {
+		if _, err := NewI2C(newFakeBus(), 0x5A, nil, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := NewI2C(newFakeBus(), 0x5A, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("no edge detection")
+	}
+	f := newFakeBus()
+	f.regs[0x5D] = 0
+	if _, err := NewI2C(f, 0x5A, nil, nil); err == nil {
+		t.Fatal("not a MPR121")
+	}
+	// Fail at each successive transaction of the initialization.
+	for n := 0; n < 9; n++ {
+		f := newFakeBus()
+		f.failAfter = n
+		if _, err := NewI2C(f, 0x5A, nil, nil); err == nil {
+			t.Fatalf("#%d: I²C failed", n)
+		}
+	}
+	f = newFakeBus()
+	dev, err := NewI2C(f, 0x5A, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.failAfter = 0
+	if _, err := dev.Touched(); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Events(); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Filtered(0); err == nil {
+		t.Fatal("read failed")
+	}
+	if _, err := dev.Baseline(0); err == nil {
+		t.Fatal("read failed")
+	}
+	for n := 0; n < 3; n++ {
+		f.failAfter = n
+		if err := dev.SetThresholds(1, 1); err == nil {
+			t.Fatalf("#%d: write failed", n)
+		}
+	}
+	f.failAfter = 0
+	if err := dev.Halt(); err == nil {
+		t.Fatal("write failed")
+	}
+}
+
+func TestEvent_String(t *testing.T) {
+	if s := (Event{3, true}).String(); s != "E3 touched" {
+		t.Fatal(s)
+	}
+	if s := (Event{11, false}).String(); s != "E11 released" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func thresholds(touch, release byte) [][2]byte {
+	var out [][2]byte
+	for i := 0; i < 13; i++ {
+		out = append(out, [2]byte{byte(0x41 + 2*i), touch}, [2]byte{byte(0x42 + 2*i), release})
+	}
+	return out
+}
+
+// fakeBus emulates the register map with the register address auto increment.
+//
+// Each register written is logged in writes.
+type fakeBus struct {
+	mu     sync.Mutex
+	regs   [256]byte
+	writes [][2]byte
+	// failAfter fails the transactions after this number of successful ones,
+	// when not -1.
+	failAfter int
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{failAfter: -1}
+	f.regs[0x5D] = 0x24
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failAfter == 0 {
+		return errors.New("failed")
+	}
+	if f.failAfter > 0 {
+		f.failAfter--
+	}
+	reg := int(w[0])
+	for i, v := range w[1:] {
+		f.regs[reg+i] = v
+		f.writes = append(f.writes, [2]byte{byte(reg + i), v})
+	}
+	for i := range r {
+		r[i] = f.regs[reg+i]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) setStatus(s uint16) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[0] = byte(s)
+	f.regs[1] = byte(s >> 8)
+}
+
+// checkWrites verifies the registers written since the last call.
+func (f *fakeBus) checkWrites(t *testing.T, expected [][2]byte) {
+	f.mu.Lock()
+	w := f.writes
+	f.writes = nil
+	f.mu.Unlock()
+	if len(w) != len(expected) {
+		t.Fatalf("%x != %x", w, expected)
+	}
+	for i := range w {
+		if w[i] != expected[i] {
+			t.Fatalf("%x != %x", w, expected)
+		}
+	}
+}
@@ -0,0 +1,633 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mpu6050 controls an InvenSense MPU-6050, MPU-6500 or MPU-9250
+// inertial measurement unit over I²C.
+//
+// The MPU-6050 and MPU-6500 provide a 3 axis accelerometer and a 3 axis
+// gyroscope. The MPU-9250 adds an AK8963 3 axis magnetometer which is accessed
+// through the auxiliary I²C bus in bypass mode.
+//
+// Samples can be read one at a time with Sense(), in batch from the internal
+// FIFO with ReadFIFO() or continuously with SenseContinuous(). When a GPIO pin
+// is connected to the INT pin, SenseContinuous() is driven by the data ready
+// interrupt instead of polling.
+//
+// Datasheet
+//
+// https://www.invensense.com/wp-content/uploads/2015/02/MPU-6000-Register-Map1.pdf
+//
+// https://www.invensense.com/wp-content/uploads/2015/02/RM-MPU-9250A-00-v1.6.pdf
+package mpu6050
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Acceleration is an acceleration at a precision of 0.001m/s².
+type Acceleration devices.Milli
+
+// String returns the acceleration formatted as a string in m/s².
+func (a Acceleration) String() string {
+	return devices.Milli(a).String() + "m/s²"
+}
+
+// AngularVelocity is an angular velocity at a precision of 0.001°/s.
+type AngularVelocity devices.Milli
+
+// String returns the angular velocity formatted as a string in °/s.
+func (a AngularVelocity) String() string {
+	return devices.Milli(a).String() + "°/s"
+}
+
+// MagneticField is a magnetic flux density at a precision of 0.001µT.
+type MagneticField devices.Milli
+
+// String returns the magnetic flux density formatted as a string in µT.
+func (m MagneticField) String() string {
+	return devices.Milli(m).String() + "µT"
+}
+
+// Sample is one measurement of all the sensors.
+//
+// The axes of the magnetometer are not aligned with the ones of the
+// accelerometer and gyroscope; see the MPU-9250 datasheet section 9.1.
+type Sample struct {
+	Accel       [3]Acceleration
+	Gyro        [3]AngularVelocity
+	Mag         [3]MagneticField // Only set on the MPU-9250
+	Temperature devices.Celsius  // Not set on samples read from the FIFO
+}
+
+func (s *Sample) String() string {
+	return fmt.Sprintf("accel=%s gyro=%s mag=%s %s", s.Accel, s.Gyro, s.Mag, s.Temperature)
+}
+
+// AccelRange is the full scale range of the accelerometer.
+type AccelRange uint8
+
+// Possible accelerometer ranges.
+const (
+	Accel2G  AccelRange = 0
+	Accel4G  AccelRange = 1
+	Accel8G  AccelRange = 2
+	Accel16G AccelRange = 3
+)
+
+const accelRangeName = "Accel2GAccel4GAccel8GAccel16G"
+
+var accelRangeIndex = [...]uint8{0, 7, 14, 21, 29}
+
+func (a AccelRange) String() string {
+	if a >= AccelRange(len(accelRangeIndex)-1) {
+		return fmt.Sprintf("AccelRange(%d)", a)
+	}
+	return accelRangeName[accelRangeIndex[a]:accelRangeIndex[a+1]]
+}
+
+// GyroRange is the full scale range of the gyroscope.
+type GyroRange uint8
+
+// Possible gyroscope ranges, in degrees per second.
+const (
+	Gyro250DPS  GyroRange = 0
+	Gyro500DPS  GyroRange = 1
+	Gyro1000DPS GyroRange = 2
+	Gyro2000DPS GyroRange = 3
+)
+
+const gyroRangeName = "Gyro250DPSGyro500DPSGyro1000DPSGyro2000DPS"
+
+var gyroRangeIndex = [...]uint8{0, 10, 20, 31, 42}
+
+func (g GyroRange) String() string {
+	if g >= GyroRange(len(gyroRangeIndex)-1) {
+		return fmt.Sprintf("GyroRange(%d)", g)
+	}
+	return gyroRangeName[gyroRangeIndex[g]:gyroRangeIndex[g+1]]
+}
+
+// DLPF is the digital low pass filter setting applied to both the
+// accelerometer and the gyroscope.
+//
+// The names are the approximate gyroscope bandwidth. When DLPF260Hz is used,
+// the gyroscope output rate is 8kHz instead of 1kHz.
+type DLPF uint8
+
+// Possible low pass filter settings.
+const (
+	DLPF260Hz DLPF = 0
+	DLPF184Hz DLPF = 1
+	DLPF94Hz  DLPF = 2
+	DLPF44Hz  DLPF = 3
+	DLPF21Hz  DLPF = 4
+	DLPF10Hz  DLPF = 5
+	DLPF5Hz   DLPF = 6
+)
+
+const dlpfName = "DLPF260HzDLPF184HzDLPF94HzDLPF44HzDLPF21HzDLPF10HzDLPF5Hz"
+
+var dlpfIndex = [...]uint8{0, 9, 18, 26, 34, 42, 50, 57}
+
+func (d DLPF) String() string {
+	if d >= DLPF(len(dlpfIndex)-1) {
+		return fmt.Sprintf("DLPF(%d)", d)
+	}
+	return dlpfName[dlpfIndex[d]:dlpfIndex[d+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	AccelRange AccelRange
+	GyroRange  GyroRange
+	DLPF       DLPF
+	// SampleRateDivider divides the gyroscope output rate to determine the
+	// rate at which samples are produced: rate = 1kHz / (1 + divider), or
+	// 8kHz / (1 + divider) with DLPF260Hz.
+	SampleRateDivider uint8
+	// FIFO enables buffering of the accelerometer and gyroscope samples in the
+	// device's FIFO, to be retrieved with ReadFIFO().
+	FIFO bool
+	// Interrupt is the GPIO pin connected to the INT pin. When set, the data
+	// ready interrupt is enabled.
+	Interrupt gpio.PinIn
+}
+
+// Dev is a handle to a MPU-6050, MPU-6500 or MPU-9250 device.
+type Dev struct {
+	c      conn.Conn
+	mag    conn.Conn // nil when there is no magnetometer
+	name   string
+	opts   Opts
+	asa    [3]int32 // Magnetometer sensitivity adjustment
+	lastM  [3]MagneticField
+	divSet bool // The sample rate divider was changed by SenseContinuous()
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a MPU-6050,
+// MPU-6500 or MPU-9250.
+//
+// The address is 0x68 or 0x69 depending on the AD0 pin. The device is reset
+// and configured according to opts.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x68, 0x69:
+	default:
+		return nil, errors.New("mpu6050: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.AccelRange > Accel16G {
+		return nil, fmt.Errorf("mpu6050: invalid accelerometer range %d", opts.AccelRange)
+	}
+	if opts.GyroRange > Gyro2000DPS {
+		return nil, fmt.Errorf("mpu6050: invalid gyroscope range %d", opts.GyroRange)
+	}
+	if opts.DLPF > DLPF5Hz {
+		return nil, fmt.Errorf("mpu6050: invalid low pass filter %d", opts.DLPF)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	var id [1]byte
+	if err := d.readReg(regWhoAmI, id[:]); err != nil {
+		return nil, err
+	}
+	switch id[0] {
+	case 0x68:
+		d.name = "MPU6050"
+	case 0x70:
+		d.name = "MPU6500"
+	case 0x71, 0x73:
+		d.name = "MPU9250"
+		d.mag = &i2c.Dev{Bus: b, Addr: magAddr}
+	default:
+		return nil, fmt.Errorf("mpu6050: unexpected device id %#x", id[0])
+	}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.name, d.c)
+}
+
+// Sense reads the latest sample.
+func (d *Dev) Sense(s *Sample) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("mpu6050: already sensing continuously")
+	}
+	return d.sense(s)
+}
+
+// SenseContinuous returns samples on a continuous basis.
+//
+// When an interrupt pin was specified, the sample rate divider is set to
+// produce a sample every interval, rounded to the nearest supported period,
+// and each sample is read as the data ready interrupt fires. Otherwise the
+// device is polled at the requested interval.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan Sample, error) {
+	if interval <= 0 {
+		return nil, errors.New("mpu6050: invalid interval")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.opts.Interrupt != nil {
+		rate := d.gyroRate()
+		div := (int64(interval)*rate+int64(time.Second)/2)/int64(time.Second) - 1
+		if div < 0 || div > 255 {
+			return nil, fmt.Errorf("mpu6050: interval must be between %s and %s", time.Second/time.Duration(rate), 256*time.Second/time.Duration(rate))
+		}
+		if err := d.writeReg(regSmplrtDiv, byte(div)); err != nil {
+			return nil, err
+		}
+		d.divSet = true
+	}
+	sensing := make(chan Sample)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// ReadFIFO returns all the accelerometer and gyroscope samples currently
+// buffered in the FIFO.
+//
+// Opts.FIFO must have been set. The temperature and magnetometer are not
+// buffered and are left to zero. If the FIFO overflowed, it is reset and an
+// error is returned; samples were lost.
+func (d *Dev) ReadFIFO() ([]Sample, error) {
+	if !d.opts.FIFO {
+		return nil, errors.New("mpu6050: FIFO is not enabled")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return nil, errors.New("mpu6050: already sensing continuously")
+	}
+	var status [1]byte
+	if err := d.readReg(regIntStatus, status[:]); err != nil {
+		return nil, err
+	}
+	if status[0]&intFIFOOverflow != 0 {
+		if err := d.resetFIFO(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("mpu6050: FIFO overflow")
+	}
+	var count [2]byte
+	if err := d.readReg(regFIFOCount, count[:]); err != nil {
+		return nil, err
+	}
+	n := (int(count[0])<<8 | int(count[1])) / fifoFrameSize
+	out := make([]Sample, n)
+	buf := make([]byte, fifoChunk*fifoFrameSize)
+	for i := 0; i < n; i += fifoChunk {
+		frames := n - i
+		if frames > fifoChunk {
+			frames = fifoChunk
+		}
+		b := buf[:frames*fifoFrameSize]
+		if err := d.readReg(regFIFORW, b); err != nil {
+			return nil, err
+		}
+		for j := 0; j < frames; j++ {
+			f := b[j*fifoFrameSize:]
+			d.toAccel(f[0:6], &out[i+j])
+			d.toGyro(f[6:12], &out[i+j])
+		}
+	}
+	return out, nil
+}
+
+// Halt stops the sensing as initiated by SenseContinuous() and restores the
+// sample rate divider.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.divSet {
+		return nil
+	}
+	d.divSet = false
+	return d.writeReg(regSmplrtDiv, d.opts.SampleRateDivider)
+}
+
+//
+
+const (
+	regSmplrtDiv    = 0x19
+	regConfig       = 0x1A
+	regGyroConfig   = 0x1B
+	regAccelConfig  = 0x1C
+	regAccelConfig2 = 0x1D // MPU-6500 and MPU-9250 only
+	regFIFOEn       = 0x23
+	regIntPinCfg    = 0x37
+	regIntEnable    = 0x38
+	regIntStatus    = 0x3A
+	regAccelOut     = 0x3B
+	regUserCtrl     = 0x6A
+	regPwrMgmt1     = 0x6B
+	regFIFOCount    = 0x72
+	regFIFORW       = 0x74
+	regWhoAmI       = 0x75
+
+	intDataReady    = 0x01
+	intFIFOOverflow = 0x10
+
+	// INT_PIN_CFG bits; the pin is active high and push-pull.
+	pinLatch     = 0x20
+	pinAnyRead   = 0x10
+	pinI2CBypass = 0x02
+
+	userFIFOEn    = 0x40
+	userFIFOReset = 0x04
+
+	// Gyroscope X, Y, Z and accelerometer.
+	fifoSensors   = 0x78
+	fifoFrameSize = 12
+	// fifoChunk is the number of frames read per I²C transaction.
+	fifoChunk = 20
+)
+
+// AK8963 magnetometer.
+const (
+	magAddr      = 0x0C
+	magWIA       = 0x00
+	magST1       = 0x02
+	magCNTL1     = 0x0A
+	magASAX      = 0x10
+	magID        = 0x48
+	magPowerDown = 0x00
+	magFuseROM   = 0x0F
+	// 16 bits output, continuous measurement mode 2 (100Hz).
+	magContinuous = 0x16
+)
+
+// accelLSB is the number of LSB per g, indexed by AccelRange.
+var accelLSB = [...]int64{16384, 8192, 4096, 2048}
+
+// gyroLSB is the number of LSB per 10°/s, indexed by GyroRange.
+var gyroLSB = [...]int64{1310, 655, 328, 164}
+
+func (d *Dev) init() error {
+	if err := d.writeReg(regPwrMgmt1, 0x80); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	// Wake up and use the X axis gyroscope PLL as the clock source.
+	if err := d.writeReg(regPwrMgmt1, 0x01); err != nil {
+		return err
+	}
+	w := [][2]byte{
+		{regConfig, byte(d.opts.DLPF)},
+		{regSmplrtDiv, d.opts.SampleRateDivider},
+		{regGyroConfig, byte(d.opts.GyroRange) << 3},
+		{regAccelConfig, byte(d.opts.AccelRange) << 3},
+	}
+	if d.name != "MPU6050" {
+		w = append(w, [2]byte{regAccelConfig2, byte(d.opts.DLPF)})
+	}
+	var pinCfg, intEn byte
+	if d.mag != nil {
+		pinCfg |= pinI2CBypass
+	}
+	if d.opts.Interrupt != nil {
+		pinCfg |= pinLatch | pinAnyRead
+		intEn |= intDataReady
+	}
+	w = append(w, [2]byte{regIntPinCfg, pinCfg}, [2]byte{regIntEnable, intEn})
+	for _, r := range w {
+		if err := d.writeReg(r[0], r[1]); err != nil {
+			return err
+		}
+	}
+	if d.opts.FIFO {
+		if err := d.writeReg(regFIFOEn, fifoSensors); err != nil {
+			return err
+		}
+		if err := d.resetFIFO(); err != nil {
+			return err
+		}
+	}
+	if d.opts.Interrupt != nil {
+		if err := d.opts.Interrupt.In(gpio.PullDown, gpio.RisingEdge); err != nil {
+			return fmt.Errorf("mpu6050: %v", err)
+		}
+	}
+	if d.mag != nil {
+		return d.initMag()
+	}
+	return nil
+}
+
+// initMag reads the factory sensitivity adjustment values then starts the
+// continuous measurement of the AK8963.
+func (d *Dev) initMag() error {
+	var id [1]byte
+	if err := d.mag.Tx([]byte{magWIA}, id[:]); err != nil {
+		return fmt.Errorf("mpu6050: %v", err)
+	}
+	if id[0] != magID {
+		return fmt.Errorf("mpu6050: unexpected magnetometer id %#x", id[0])
+	}
+	var asa [3]byte
+	ops := []struct {
+		w, r []byte
+	}{
+		{[]byte{magCNTL1, magPowerDown}, nil},
+		{[]byte{magCNTL1, magFuseROM}, nil},
+		{[]byte{magASAX}, asa[:]},
+		{[]byte{magCNTL1, magPowerDown}, nil},
+		{[]byte{magCNTL1, magContinuous}, nil},
+	}
+	for _, op := range ops {
+		if err := d.mag.Tx(op.w, op.r); err != nil {
+			return fmt.Errorf("mpu6050: %v", err)
+		}
+		// Mode changes require 100µs.
+		time.Sleep(time.Millisecond)
+	}
+	for i, v := range asa {
+		d.asa[i] = int32(v) + 128
+	}
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- Sample, stop <-chan struct{}) {
+	var tick <-chan time.Time
+	if d.opts.Interrupt == nil {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	} else {
+		// Clear the latched interrupt, otherwise no edge would ever be seen.
+		var status [1]byte
+		d.mu.Lock()
+		err := d.readReg(regIntStatus, status[:])
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+	}
+	for {
+		if tick != nil {
+			select {
+			case <-stop:
+				return
+			case <-tick:
+			}
+		} else {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// The timeout permits to check the stop channel regularly.
+			if !d.opts.Interrupt.WaitForEdge(2 * interval) {
+				continue
+			}
+		}
+		var s Sample
+		d.mu.Lock()
+		err := d.sense(&s)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- s:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sense(s *Sample) error {
+	var b [14]byte
+	if err := d.readReg(regAccelOut, b[:]); err != nil {
+		return err
+	}
+	d.toAccel(b[0:6], s)
+	raw := int64(int16(uint16(b[6])<<8 | uint16(b[7])))
+	if d.name == "MPU6050" {
+		s.Temperature = devices.Celsius(raw*1000/340 + 36530)
+	} else {
+		s.Temperature = devices.Celsius(raw*100000/33387 + 21000)
+	}
+	d.toGyro(b[8:14], s)
+	if d.mag != nil {
+		// ST1, 6 bytes of data in little endian, then ST2. Reading ST2 is
+		// necessary to release the data registers.
+		var m [8]byte
+		if err := d.mag.Tx([]byte{magST1}, m[:]); err != nil {
+			return fmt.Errorf("mpu6050: %v", err)
+		}
+		// Keep the previous value when there is no new data or on magnetic
+		// sensor overflow.
+		if m[0]&0x01 != 0 && m[7]&0x08 == 0 {
+			for i := range d.lastM {
+				v := int32(int16(uint16(m[2*i+2])<<8 | uint16(m[2*i+1])))
+				// 0.15µT per LSB adjusted by (ASA+128)/256.
+				d.lastM[i] = MagneticField(v * 150 * d.asa[i] / 256)
+			}
+		}
+		s.Mag = d.lastM
+	}
+	return nil
+}
+
+func (d *Dev) toAccel(b []byte, s *Sample) {
+	lsb := accelLSB[d.opts.AccelRange]
+	for i := range s.Accel {
+		raw := int64(int16(uint16(b[2*i])<<8 | uint16(b[2*i+1])))
+		// 9.80665m/s² per g.
+		s.Accel[i] = Acceleration(raw * 980665 / (100 * lsb))
+	}
+}
+
+func (d *Dev) toGyro(b []byte, s *Sample) {
+	lsb := gyroLSB[d.opts.GyroRange]
+	for i := range s.Gyro {
+		raw := int64(int16(uint16(b[2*i])<<8 | uint16(b[2*i+1])))
+		s.Gyro[i] = AngularVelocity(raw * 10000 / lsb)
+	}
+}
+
+// gyroRate returns the gyroscope output rate in Hz, before the sample rate
+// divider is applied.
+func (d *Dev) gyroRate() int64 {
+	if d.opts.DLPF == DLPF260Hz {
+		return 8000
+	}
+	return 1000
+}
+
+func (d *Dev) resetFIFO() error {
+	if err := d.writeReg(regUserCtrl, userFIFOReset); err != nil {
+		return err
+	}
+	return d.writeReg(regUserCtrl, userFIFOEn)
+}
+
+func (d *Dev) readReg(reg byte, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("mpu6050: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) writeReg(reg, v byte) error {
+	if err := d.c.Tx([]byte{reg, v}, nil); err != nil {
+		return fmt.Errorf("mpu6050: %v", err)
+	}
+	return nil
+}
+
+var defaults = Opts{
+	AccelRange:        Accel2G,
+	GyroRange:         Gyro250DPS,
+	DLPF:              DLPF44Hz,
+	SampleRateDivider: 9,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
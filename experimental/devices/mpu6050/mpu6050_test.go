@@ -0,0 +1,391 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mpu6050
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	opts := Opts{
+		AccelRange: Accel4G,
+		GyroRange:  Gyro500DPS,
+		DLPF:       DLPF44Hz,
+		Interrupt:  gpioreg.ByName("GPIO17"),
+	}
+	dev, err := NewI2C(bus, 0x68, &opts)
+	if err != nil {
+		log.Fatalf("failed to initialize mpu6050: %v", err)
+	}
+	c, err := dev.SenseContinuous(10 * time.Millisecond)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dev.Halt()
+	for i := 0; i < 100; i++ {
+		s := <-c
+		fmt.Printf("%s\n", &s)
+	}
+}
+
+//
+
+func TestNewI2C_Sense_MPU6050(t *testing.T) {
+	f := newFakeBus(0x68)
+	dev, err := NewI2C(f, 0x68, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MPU6050{fake(104)}" {
+		t.Fatal(s)
+	}
+	expected := map[byte]byte{0x1A: 3, 0x19: 9, 0x1B: 0, 0x1C: 0, 0x37: 0, 0x38: 0, 0x6B: 0x01}
+	for reg, v := range expected {
+		if r := f.get(0x68, reg); r != v {
+			t.Fatalf("%#x: %#x != %#x", reg, r, v)
+		}
+	}
+	if _, ok := f.regs[0x68][0x1D]; ok {
+		t.Fatal("ACCEL_CONFIG2 doesn't exist on MPU-6050")
+	}
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Accel != [3]Acceleration{0, -4903, 9806} {
+		t.Fatal(s.Accel)
+	}
+	if s.Gyro != [3]AngularVelocity{1000, -1000, 0} {
+		t.Fatal(s.Gyro)
+	}
+	if s.Mag != [3]MagneticField{} {
+		t.Fatal(s.Mag)
+	}
+	// 340/340 + 36.53
+	if s.Temperature != 37530 {
+		t.Fatal(s.Temperature)
+	}
+	if str := s.String(); str != "accel=[0.000m/s² -4.903m/s² 9.806m/s²] gyro=[1.000°/s -1.000°/s 0.000°/s] mag=[0.000µT 0.000µT 0.000µT] 37.530°C" {
+		t.Fatal(str)
+	}
+	if _, err := dev.ReadFIFO(); err == nil {
+		t.Fatal("FIFO not enabled")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_Sense_MPU9250(t *testing.T) {
+	f := newFakeBus(0x71)
+	dev, err := NewI2C(f, 0x69, &Opts{AccelRange: Accel16G, GyroRange: Gyro2000DPS, DLPF: DLPF10Hz})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "MPU9250{fake(105)}" {
+		t.Fatal(s)
+	}
+	expected := map[byte]byte{0x1A: 5, 0x1D: 5, 0x1B: 0x18, 0x1C: 0x18, 0x37: 0x02}
+	for reg, v := range expected {
+		if r := f.get(0x69, reg); r != v {
+			t.Fatalf("%#x: %#x != %#x", reg, r, v)
+		}
+	}
+	if v := f.get(0x0C, 0x0A); v != 0x16 {
+		t.Fatalf("magnetometer mode: %#x", v)
+	}
+	var s Sample
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Accel != [3]Acceleration{0, -39226, 78453} {
+		t.Fatal(s.Accel)
+	}
+	if s.Gyro != [3]AngularVelocity{7987, -7987, 0} {
+		t.Fatal(s.Gyro)
+	}
+	// 100 * 0.15 * (0+128)/256, 100 * 0.15 * (128+128)/256, -100 * 0.15 * (64+128)/256
+	if s.Mag != [3]MagneticField{7500, 15000, -11250} {
+		t.Fatal(s.Mag)
+	}
+	// 340/333.87 + 21
+	if s.Temperature != 22018 {
+		t.Fatal(s.Temperature)
+	}
+	// Overflow, the previous value is kept.
+	f.set(0x0C, 0x09, 0x08)
+	f.set(0x0C, 0x03, 0)
+	if err := dev.Sense(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Mag != [3]MagneticField{7500, 15000, -11250} {
+		t.Fatal(s.Mag)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(newFakeBus(0x68), 0x20, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	data := []Opts{
+		{AccelRange: 4},
+		{GyroRange: 4},
+		{DLPF: 7},
+	}
+	for i, opts := range data {
+		if _, err := NewI2C(newFakeBus(0x68), 0x68, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := NewI2C(newFakeBus(0x12), 0x68, nil); err == nil {
+		t.Fatal("invalid device id")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x68, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	f := newFakeBus(0x71)
+	f.set(0x0C, 0x00, 0)
+	if _, err := NewI2C(f, 0x68, nil); err == nil {
+		t.Fatal("invalid magnetometer id")
+	}
+	p := &gpiotest.Pin{N: "INT"}
+	if _, err := NewI2C(newFakeBus(0x68), 0x68, &Opts{Interrupt: p}); err == nil {
+		t.Fatal("edge detection not supported")
+	}
+}
+
+func TestReadFIFO(t *testing.T) {
+	f := newFakeBus(0x68)
+	dev, err := NewI2C(f, 0x68, &Opts{DLPF: DLPF184Hz, FIFO: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.get(0x68, 0x23); v != 0x78 {
+		t.Fatalf("FIFO_EN: %#x", v)
+	}
+	if v := f.get(0x68, 0x6A); v != 0x40 {
+		t.Fatalf("USER_CTRL: %#x", v)
+	}
+	frame := []byte{0x40, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0x7D}
+	f.mu.Lock()
+	for i := 0; i < 25; i++ {
+		f.fifo = append(f.fifo, frame...)
+	}
+	// Incomplete frame.
+	f.fifo = append(f.fifo, 1, 2)
+	f.mu.Unlock()
+	s, err := dev.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 25 {
+		t.Fatal(len(s))
+	}
+	for i := range s {
+		if s[i].Accel != [3]Acceleration{9806, 0, 0} || s[i].Gyro != [3]AngularVelocity{0, 0, -1000} {
+			t.Fatalf("#%d: %s", i, &s[i])
+		}
+	}
+	if len(f.fifo) != 2 {
+		t.Fatal(len(f.fifo))
+	}
+	f.set(0x68, 0x3A, 0x10)
+	if _, err := dev.ReadFIFO(); err == nil {
+		t.Fatal("overflow")
+	}
+	if len(f.fifo) != 0 {
+		t.Fatal("FIFO must be reset")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	f := newFakeBus(0x68)
+	dev, err := NewI2C(f, 0x68, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(0); err == nil {
+		t.Fatal("invalid interval")
+	}
+	c, err := dev.SenseContinuous(time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if s := <-c; s.Accel[2] != 9806 {
+			t.Fatal(s.Accel)
+		}
+	}
+	var s Sample
+	if err := dev.Sense(&s); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+}
+
+func TestSenseContinuous_Interrupt(t *testing.T) {
+	f := newFakeBus(0x68)
+	p := &gpiotest.Pin{N: "INT", EdgesChan: make(chan gpio.Level)}
+	dev, err := NewI2C(f, 0x68, &Opts{DLPF: DLPF44Hz, SampleRateDivider: 4, Interrupt: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.P != gpio.PullDown {
+		t.Fatal(p.P)
+	}
+	if v := f.get(0x68, 0x37); v != 0x30 {
+		t.Fatalf("INT_PIN_CFG: %#x", v)
+	}
+	if v := f.get(0x68, 0x38); v != 0x01 {
+		t.Fatalf("INT_ENABLE: %#x", v)
+	}
+	if _, err := dev.SenseContinuous(time.Second); err == nil {
+		t.Fatal("interval too long for the divider")
+	}
+	c, err := dev.SenseContinuous(20 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.get(0x68, 0x19); v != 19 {
+		t.Fatalf("SMPLRT_DIV: %d", v)
+	}
+	for i := 0; i < 2; i++ {
+		p.EdgesChan <- gpio.High
+		if s := <-c; s.Accel[2] != 9806 {
+			t.Fatal(s.Accel)
+		}
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if v := f.get(0x68, 0x19); v != 4 {
+		t.Fatalf("SMPLRT_DIV not restored: %d", v)
+	}
+}
+
+func TestString(t *testing.T) {
+	data := []struct {
+		s        fmt.Stringer
+		expected string
+	}{
+		{Accel8G, "Accel8G"},
+		{AccelRange(4), "AccelRange(4)"},
+		{Gyro1000DPS, "Gyro1000DPS"},
+		{GyroRange(4), "GyroRange(4)"},
+		{DLPF94Hz, "DLPF94Hz"},
+		{DLPF(7), "DLPF(7)"},
+		{Acceleration(-10), "-0.010m/s²"},
+		{AngularVelocity(1500), "1.500°/s"},
+		{MagneticField(42000), "42.000µT"},
+	}
+	for i, line := range data {
+		if s := line.s.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+//
+
+// fakeBus simulates the MPU and AK8963 registers.
+type fakeBus struct {
+	mu   sync.Mutex
+	regs map[uint16]map[byte]byte
+	fifo []byte
+}
+
+func newFakeBus(id byte) *fakeBus {
+	f := &fakeBus{regs: map[uint16]map[byte]byte{0x68: {}, 0x69: {}, 0x0C: {}}}
+	// Accel: 0, -0.5, 1; temperature 340; gyro: 131, -131, 0.
+	data := []byte{0x00, 0x00, 0xE0, 0x00, 0x40, 0x00, 0x01, 0x54, 0x00, 0x83, 0xFF, 0x7D, 0x00, 0x00}
+	for _, addr := range []uint16{0x68, 0x69} {
+		f.regs[addr][0x75] = id
+		for i, v := range data {
+			f.regs[addr][0x3B+byte(i)] = v
+		}
+	}
+	// Magnetometer: ID, data ready, 100, 100, -100, sensitivity adjustment.
+	mag := map[byte]byte{0x00: 0x48, 0x02: 0x01, 0x03: 100, 0x05: 100, 0x07: 0x9C, 0x08: 0xFF, 0x10: 0, 0x11: 128, 0x12: 64}
+	for k, v := range mag {
+		f.regs[0x0C][k] = v
+	}
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	regs := f.regs[addr]
+	reg := w[0]
+	for i, v := range w[1:] {
+		regs[reg+byte(i)] = v
+	}
+	if addr != 0x0C && reg == 0x6A && len(w) == 2 && w[1]&0x04 != 0 {
+		f.fifo = nil
+	}
+	if addr != 0x0C && reg == 0x74 {
+		copy(r, f.fifo)
+		f.fifo = f.fifo[len(r):]
+		return nil
+	}
+	if addr != 0x0C && reg == 0x72 {
+		regs[0x72] = byte(len(f.fifo) >> 8)
+		regs[0x73] = byte(len(f.fifo))
+	}
+	for i := range r {
+		r[i] = regs[reg+byte(i)]
+	}
+	if addr != 0x0C && reg == 0x3A {
+		regs[0x3A] = 0
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) get(addr uint16, reg byte) byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.regs[addr][reg]
+}
+
+func (f *fakeBus) set(addr uint16, reg, v byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[addr][reg] = v
+}
@@ -6,6 +6,13 @@
 // devices like sk6812 and ucs1903 that uses a single wire NRZ encoded
 // communication protocol.
 //
+// The stream can either be generated by a pin supporting gpiostream or by
+// the MOSI line of a SPI port, each NRZ bit then being encoded as 3 SPI bits.
+//
+// On a Raspberry Pi, the bcm283x pins implement gpiostream.PinOut with DMA
+// pacing. Using GPIO18 or another PWM pin gives a jitter-free output without
+// any kernel driver.
+//
 // Note that some ICs are 7 bits with the least significant bit ignored, others
 // are using a real 8 bits PWM. The PWM frequency varies across ICs.
 //
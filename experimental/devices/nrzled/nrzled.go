@@ -13,6 +13,7 @@ import (
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio/gpiostream"
+	"periph.io/x/periph/conn/spi"
 	"periph.io/x/periph/devices"
 )
 
@@ -41,16 +42,59 @@ func NRZ(b byte) uint32 {
 	return out
 }
 
+// ColorOrder is the order in which the color channels are sent to the LEDs.
+//
+// The white channel of RGBW LEDs is always sent last.
+type ColorOrder uint8
+
+// Possible color orders. GRB is used by the ws2812 and sk6812.
+const (
+	GRB ColorOrder = 0
+	RGB ColorOrder = 1
+	BRG ColorOrder = 2
+	RBG ColorOrder = 3
+	GBR ColorOrder = 4
+	BGR ColorOrder = 5
+)
+
+const colorOrderName = "GRBRGBBRGRBGGBRBGR"
+
+func (c ColorOrder) String() string {
+	if c > BGR {
+		return fmt.Sprintf("ColorOrder(%d)", c)
+	}
+	return colorOrderName[3*c : 3*c+3]
+}
+
+// Opts is optional options to pass to the constructors.
+type Opts struct {
+	// NumPixels is the number of pixels on the strip.
+	NumPixels int
+	// Channels is either 3 (RGB) or 4 (RGBW). The default is 3.
+	Channels int
+	// Freq is the bit rate in Hz; either 800000 for fast ICs or 400000 for the
+	// slow ones. The default is 800000.
+	Freq int
+	// Order is the order of the color channels. The default is GRB.
+	Order ColorOrder
+}
+
 // Dev is a handle to the LED strip.
 type Dev struct {
-	p         gpiostream.PinOut
+	p         gpiostream.PinOut // nil when using SPI
+	s         spi.Conn          // nil when using a gpiostream.PinOut
 	numPixels int
 	channels  int                     // Number of channels per pixel
+	order     [3]int                  // Index of the R, G, B input channel for each output channel
 	b         gpiostream.BitStreamMSB // NRZ encoded bits; cached to reduce heap fragmentation
+	raw       []byte                  // b.Bits followed by the SPI latch; only used with SPI
 	buf       []byte                  // Double buffer of RGB/RGBW pixels; enables partial Draw()
 }
 
 func (d *Dev) String() string {
+	if d.s != nil {
+		return fmt.Sprintf("nrzled{%s}", d.s)
+	}
 	return fmt.Sprintf("nrzled{%s}", d.p)
 }
 
@@ -67,10 +111,7 @@ func (d *Dev) Halt() error {
 		d.b.Bits[3*i+1] = b
 		d.b.Bits[3*i+2] = c
 	}
-	if err := d.p.StreamOut(&d.b); err != nil {
-		return fmt.Errorf("nrzled: %v", err)
-	}
-	return nil
+	return d.send()
 }
 
 // ColorModel implements devices.Display.
@@ -111,30 +152,23 @@ func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
 	if img, ok := src.(*image.NRGBA); ok {
 		// Fast path for image.NRGBA.
 		base := srcR.Min.Y * img.Stride
-		raster(d.b.Bits, img.Pix[base+4*srcR.Min.X:base+4*srcR.Max.X], d.channels, 4)
+		raster(d.b.Bits, img.Pix[base+4*srcR.Min.X:base+4*srcR.Max.X], d.channels, 4, d.order)
 	} else {
 		// Generic version.
 		m := srcR.Max.X - srcR.Min.X
-		if d.channels == 3 {
-			for i := 0; i < m; i++ {
-				c := color.NRGBAModel.Convert(src.At(srcR.Min.X+i, srcR.Min.Y)).(color.NRGBA)
-				j := 3 * i
-				put(d.b.Bits[3*(j+0):], c.G)
-				put(d.b.Bits[3*(j+1):], c.R)
-				put(d.b.Bits[3*(j+2):], c.B)
-			}
-		} else {
-			for i := 0; i < m; i++ {
-				c := color.NRGBAModel.Convert(src.At(srcR.Min.X+i, srcR.Min.Y)).(color.NRGBA)
-				j := 4 * i
-				put(d.b.Bits[3*(j+0):], c.G)
-				put(d.b.Bits[3*(j+1):], c.R)
-				put(d.b.Bits[3*(j+2):], c.B)
+		for i := 0; i < m; i++ {
+			c := color.NRGBAModel.Convert(src.At(srcR.Min.X+i, srcR.Min.Y)).(color.NRGBA)
+			v := [4]byte{c.R, c.G, c.B, c.A}
+			j := d.channels * i
+			put(d.b.Bits[3*(j+0):], v[d.order[0]])
+			put(d.b.Bits[3*(j+1):], v[d.order[1]])
+			put(d.b.Bits[3*(j+2):], v[d.order[2]])
+			if d.channels == 4 {
 				put(d.b.Bits[3*(j+3):], c.A)
 			}
 		}
 	}
-	_ = d.p.StreamOut(&d.b)
+	_ = d.send()
 }
 
 // Write accepts a stream of raw RGB/RGBW pixels and sends it as NRZ encoded
@@ -145,9 +179,9 @@ func (d *Dev) Write(pixels []byte) (int, error) {
 	if len(pixels)%d.channels != 0 || len(pixels) > d.numPixels*d.channels {
 		return 0, errors.New("nrzled: invalid RGB stream length")
 	}
-	raster(d.b.Bits, pixels, d.channels, d.channels)
-	if err := d.p.StreamOut(&d.b); err != nil {
-		return 0, fmt.Errorf("nrzled: %v", err)
+	raster(d.b.Bits, pixels, d.channels, d.channels, d.order)
+	if err := d.send(); err != nil {
+		return 0, err
 	}
 	return len(pixels), nil
 }
@@ -160,25 +194,121 @@ func (d *Dev) Write(pixels []byte) (int, error) {
 // channels should be either 1 (White only), 3 (RGB) or 4 (RGBW). For RGB and
 // RGBW, the encoding is respectively GRB and GRBW.
 func New(p gpiostream.PinOut, numPixels, hz int, channels int) (*Dev, error) {
-	if hz <= 0 || hz > 1000000000 {
+	if hz <= 0 {
 		return nil, errors.New("nrzled: specify valid speed in hz")
 	}
 	if channels != 3 && channels != 4 {
 		return nil, errors.New("nrzled: specify valid number of channels (3 or 4)")
 	}
+	return NewStream(p, &Opts{NumPixels: numPixels, Channels: channels, Freq: hz})
+}
+
+// NewStream opens a handle to a compatible LED strip connected to a pin
+// supporting gpiostream.
+func NewStream(p gpiostream.PinOut, opts *Opts) (*Dev, error) {
+	d, err := newDev(opts)
+	if err != nil {
+		return nil, err
+	}
+	d.p = p
+	d.b.Bits = make(gpiostream.BitsMSB, d.numPixels*3*d.channels)
+	return d, nil
+}
+
+// NewSPI opens a handle to a compatible LED strip connected to the MOSI pin of
+// a SPI port.
+//
+// Each NRZ bit is encoded as 3 SPI bits, so the SPI clock is set to 3 times
+// the bit rate; 2.4MHz for 800kHz LEDs. The SPI driver must be able to
+// provide this clock speed accurately and MOSI must idle low. The latch
+// period is sent as trailing zero bits after each frame.
+//
+// The frame is sent as a single transaction since a pause would latch the
+// LEDs, so it must fit in conn.Limits.MaxTxSize(). The spidev default of 4096
+// bytes fits 445 RGB or 333 RGBW LEDs at 800kHz; increase the spidev module
+// parameter bufsiz for longer strips.
+func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
+	d, err := newDev(opts)
+	if err != nil {
+		return nil, err
+	}
+	if d.s, err = p.Connect(int64(3*d.freq()), spi.Mode0, 8); err != nil {
+		return nil, fmt.Errorf("nrzled: %v", err)
+	}
+	n := d.numPixels * 3 * d.channels
+	// The latch requires the line to be low for at least 300µs for the more
+	// recent ICs.
+	latch := (3*d.freq()*300/1000000 + 7) / 8
+	if l, ok := d.s.(conn.Limits); ok {
+		if s := l.MaxTxSize(); s > 0 && n+latch > s {
+			return nil, fmt.Errorf("nrzled: %d pixels need %d bytes per SPI transaction but the port is limited to %d bytes", d.numPixels, n+latch, s)
+		}
+	}
+	d.raw = make([]byte, n+latch)
+	d.b.Bits = d.raw[:n]
+	return d, nil
+}
+
+//
+
+// colorOrders is indexed by ColorOrder and contains the index of the input
+// channel for each output channel.
+var colorOrders = [...][3]int{
+	{1, 0, 2},
+	{0, 1, 2},
+	{2, 0, 1},
+	{0, 2, 1},
+	{1, 2, 0},
+	{2, 1, 0},
+}
+
+func newDev(opts *Opts) (*Dev, error) {
+	o := Opts{Channels: 3, Freq: 800000}
+	if opts != nil {
+		o.NumPixels = opts.NumPixels
+		o.Order = opts.Order
+		if opts.Channels != 0 {
+			o.Channels = opts.Channels
+		}
+		if opts.Freq != 0 {
+			o.Freq = opts.Freq
+		}
+	}
+	if o.Freq <= 0 || o.Freq > 1000000000 {
+		return nil, errors.New("nrzled: specify valid speed in hz")
+	}
+	if o.Channels != 3 && o.Channels != 4 {
+		return nil, errors.New("nrzled: specify valid number of channels (3 or 4)")
+	}
+	if o.Order > BGR {
+		return nil, fmt.Errorf("nrzled: invalid color order %d", o.Order)
+	}
 	return &Dev{
-		p:         p,
-		numPixels: numPixels,
-		channels:  channels,
-		b: gpiostream.BitStreamMSB{
-			Res: time.Second / time.Duration(hz),
-			// Each bit is encoded on 3 bits.
-			Bits: make(gpiostream.BitsMSB, numPixels*3*channels),
-		},
+		numPixels: o.NumPixels,
+		channels:  o.Channels,
+		order:     colorOrders[o.Order],
+		b:         gpiostream.BitStreamMSB{Res: time.Second / time.Duration(o.Freq)},
 	}, nil
 }
 
-//
+// freq returns the bit rate in Hz.
+func (d *Dev) freq() int {
+	return int(time.Second / d.b.Res)
+}
+
+// send sends the NRZ encoded bits.
+func (d *Dev) send() error {
+	var err error
+	if d.s != nil {
+		err = d.s.Tx(d.raw, nil)
+	} else {
+		err = d.p.StreamOut(&d.b)
+	}
+	if err != nil {
+		return fmt.Errorf("nrzled: %v", err)
+	}
+	return nil
+}
 
 // raster converts a RGB/RGBW input stream into a MSB binary output stream as it
 // must be sent over the GPIO pin.
@@ -186,24 +316,17 @@ func New(p gpiostream.PinOut, numPixels, hz int, channels int) (*Dev, error) {
 // `in` is RGB 24 bits or RGBW 32 bits. Each bit is encoded over 3 bits so the
 // length of `out` must be 3x as large as `in`.
 //
-// Encoded output format is GRB as 72 bits (24 * 3) or 96 bits (32 * 3).
-func raster(out, in []byte, outChannels, inChannels int) {
+// Encoded output format is as specified by order, e.g. GRB, as 72 bits (24 *
+// 3) or 96 bits (32 * 3).
+func raster(out, in []byte, outChannels, inChannels int, order [3]int) {
 	pixels := len(in) / inChannels
-	if outChannels == 3 {
-		for i := 0; i < pixels; i++ {
-			j := i * inChannels
-			k := 3 * i
-			put(out[3*(k+0):], in[j+1])
-			put(out[3*(k+1):], in[j+0])
-			put(out[3*(k+2):], in[j+2])
-		}
-	} else {
-		for i := 0; i < pixels; i++ {
-			j := i * inChannels
-			k := 4 * i
-			put(out[3*(k+0):], in[j+1])
-			put(out[3*(k+1):], in[j+0])
-			put(out[3*(k+2):], in[j+2])
+	for i := 0; i < pixels; i++ {
+		j := i * inChannels
+		k := outChannels * i
+		put(out[3*(k+0):], in[j+order[0]])
+		put(out[3*(k+1):], in[j+order[1]])
+		put(out[3*(k+2):], in[j+order[2]])
+		if outChannels == 4 {
 			put(out[3*(k+3):], in[j+3])
 		}
 	}
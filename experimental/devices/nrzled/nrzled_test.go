@@ -14,6 +14,8 @@ import (
 
 	"periph.io/x/periph/conn/gpio/gpiostream"
 	"periph.io/x/periph/conn/gpio/gpiostream/gpiostreamtest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spitest"
 )
 
 func TestNRZ(t *testing.T) {
@@ -255,7 +257,7 @@ func TestRaster_3_3(t *testing.T) {
 		0xdb, 0x6d, 0xb4, 0xdb, 0x6d, 0xa6, 0xdb, 0x6d, 0xb6,
 	}
 	actual := make([]byte, len(expected))
-	raster(actual, data, 3, 3)
+	raster(actual, data, 3, 3, colorOrders[GRB])
 	if !bytes.Equal(expected, actual) {
 		t.Fatalf("\nexpected %#v\n  actual %#v", expected, actual)
 	}
@@ -273,12 +275,93 @@ func TestRaster_4_4(t *testing.T) {
 		0xdb, 0x6d, 0xa6, 0xdb, 0x6d, 0xa4, 0xdb, 0x6d, 0xb4, 0xdb, 0x6d, 0xb6,
 	}
 	actual := make([]byte, len(expected))
-	raster(actual, data, 4, 4)
+	raster(actual, data, 4, 4, colorOrders[GRB])
 	if !bytes.Equal(expected, actual) {
 		t.Fatalf("\nexpected %#v\n  actual %#v", expected, actual)
 	}
 }
 
+func TestRaster_order(t *testing.T) {
+	data := []byte{0, 1, 2, 3}
+	expected := []byte{
+		// BRGW
+		0x92, 0x49, 0x34, 0x92, 0x49, 0x24, 0x92, 0x49, 0x26, 0x92, 0x49, 0x36,
+	}
+	actual := make([]byte, len(expected))
+	raster(actual, data, 4, 4, colorOrders[BRG])
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("\nexpected %#v\n  actual %#v", expected, actual)
+	}
+}
+
+func TestNewSPI(t *testing.T) {
+	s := spitest.Record{}
+	d, err := NewSPI(&s, &Opts{NumPixels: 2, Channels: 4, Order: RGB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str := d.String(); str != "nrzled{record}" {
+		t.Fatal(str)
+	}
+	if _, err := d.Write([]byte{0, 1, 2, 3, 0xFC, 0xFD, 0xFE, 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x92, 0x49, 0x24, 0x92, 0x49, 0x26, 0x92, 0x49, 0x34, 0x92, 0x49, 0x36,
+		0xdb, 0x6d, 0xa4, 0xdb, 0x6d, 0xa6, 0xdb, 0x6d, 0xb4, 0xdb, 0x6d, 0xb6,
+	}
+	// 300µs of latch at 2.4MHz.
+	expected = append(expected, make([]byte, 90)...)
+	if len(s.Ops) != 1 || !bytes.Equal(s.Ops[0].W, expected) {
+		t.Fatalf("%#v", s.Ops)
+	}
+	// Draw generic path must match the NRGBA fast path.
+	img := image.NewNRGBA(d.Bounds())
+	copy(img.Pix, []byte{0, 1, 2, 3, 0xFC, 0xFD, 0xFE, 0xFF})
+	d.Draw(d.Bounds(), img, image.Point{})
+	d.Draw(d.Bounds(), &genericImage{img}, image.Point{})
+	if len(s.Ops) != 3 || !bytes.Equal(s.Ops[1].W, expected) || !bytes.Equal(s.Ops[2].W, expected) {
+		t.Fatalf("%#v", s.Ops)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 24; i += 3 {
+		if !bytes.Equal(s.Ops[3].W[i:i+3], []byte{0x92, 0x49, 0x24}) {
+			t.Fatalf("%#v", s.Ops[3].W)
+		}
+	}
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	s := spitest.Record{Initialized: true}
+	if _, err := NewSPI(&s, nil); err == nil {
+		t.Fatal("Connect failed")
+	}
+	if _, err := NewSPI(&spitest.Record{}, &Opts{Order: 6}); err == nil {
+		t.Fatal("invalid order")
+	}
+	if _, err := NewSPI(&spitest.Record{}, &Opts{Freq: -1}); err == nil {
+		t.Fatal("invalid frequency")
+	}
+	// 150 RGB LEDs at 800kHz need 1350+90 bytes.
+	if _, err := NewSPI(&limitedPort{max: 1439}, &Opts{NumPixels: 150}); err == nil {
+		t.Fatal("frame too large")
+	}
+	if _, err := NewSPI(&limitedPort{max: 1440}, &Opts{NumPixels: 150}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColorOrder_String(t *testing.T) {
+	if s := GBR.String(); s != "GBR" {
+		t.Fatal(s)
+	}
+	if s := ColorOrder(6).String(); s != "ColorOrder(6)" {
+		t.Fatal(s)
+	}
+}
+
 //
 
 func BenchmarkNRZ(b *testing.B) {
@@ -320,3 +403,31 @@ func getRGBW() []byte {
 		6, 7, 8, 9,
 	}
 }
+
+// genericImage hides the concrete image type to exercise the generic path.
+type genericImage struct {
+	image.Image
+}
+
+// limitedPort is a spitest.Record with a maximum transaction size.
+type limitedPort struct {
+	spitest.Record
+	max int
+}
+
+func (l *limitedPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	c, err := l.Record.Connect(maxHz, mode, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedConn{c, l.max}, nil
+}
+
+type limitedConn struct {
+	spi.Conn
+	max int
+}
+
+func (l *limitedConn) MaxTxSize() int {
+	return l.max
+}
@@ -0,0 +1,471 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package pca9685 controls a NXP PCA9685 16 channels 12 bits PWM controller
+// over I²C.
+//
+// Each channel is exposed as a gpio.PinIO that supports gpio.PinPWM and as a
+// pwm.SharedPeriod. All the channels share the same prescaler so they all run
+// at the same period. The period can only be changed while no other channel is
+// active.
+//
+// Datasheet
+//
+// https://www.nxp.com/docs/en/data-sheet/PCA9685.pdf
+package pca9685
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/pwm"
+)
+
+// NumChannels is the number of PWM channels.
+const NumChannels = 16
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Addr is the I²C address, between 0x40 and 0x7F depending on the A0~A5
+	// pins. 0x70 is the default ALLCALL address and should be avoided.
+	Addr uint16
+	// Period is the initial PWM period, between 655µs and 41.9ms. The default
+	// of 20ms is suitable for servos.
+	Period time.Duration
+	// OpenDrain configures the outputs as open drain instead of totem pole.
+	OpenDrain bool
+}
+
+// Dev is a handle to a PCA9685.
+type Dev struct {
+	c conn.Conn
+
+	mu         sync.Mutex
+	prescale   uint8
+	pins       [NumChannels]Pin
+	registered bool // RegisterPins() was called
+}
+
+// NewI2C returns an object that communicates over I²C to a PCA9685.
+//
+// All channels are turned off.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	addr := opts.Addr
+	if addr == 0 {
+		addr = defaults.Addr
+	}
+	if addr < 0x40 || addr > 0x7F {
+		return nil, errors.New("pca9685: given address not supported by device")
+	}
+	period := opts.Period
+	if period == 0 {
+		period = defaults.Period
+	}
+	prescale, err := periodToPrescale(period)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}}
+	for i := range d.pins {
+		d.pins[i] = Pin{d: d, channel: i, number: -1}
+	}
+	mode2 := byte(mode2OutDrv)
+	if opts.OpenDrain {
+		mode2 = 0
+	}
+	if err := d.write(regMode2, mode2); err != nil {
+		return nil, err
+	}
+	if err := d.write(regAllLED, 0, 0, 0, fullBit); err != nil {
+		return nil, err
+	}
+	if err := d.setPrescale(prescale); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("PCA9685{%s}", d.c)
+}
+
+// Halt turns all the channels off.
+//
+// The channels are reset to the Normal polarity.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.pins {
+		d.pins[i].duty = 0
+		d.pins[i].s = pwm.State{}
+	}
+	return d.write(regAllLED, 0, 0, 0, fullBit)
+}
+
+// Pin returns the pin for the channel, between 0 and 15.
+//
+// Returns nil if the channel is invalid.
+func (d *Dev) Pin(channel int) *Pin {
+	if channel < 0 || channel >= NumChannels {
+		return nil
+	}
+	return &d.pins[channel]
+}
+
+// Period returns the current PWM period shared by all channels.
+func (d *Dev) Period() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return prescaleToPeriod(d.prescale)
+}
+
+// RegisterPins registers the 16 channels in gpioreg.
+//
+// The pins are named "PCA9685_<addr>_<channel>", with addr in hexadecimal,
+// and are numbered base to base+15. base must not collide with the numbers of
+// the pins already registered.
+//
+// On failure, none of the pins is left registered. Use UnregisterPins() to
+// unregister them.
+func (d *Dev) RegisterPins(base int) error {
+	if base < 0 {
+		return errors.New("pca9685: invalid base pin number")
+	}
+	d.mu.Lock()
+	r := d.registered
+	d.registered = true
+	d.mu.Unlock()
+	if r {
+		return errors.New("pca9685: pins already registered")
+	}
+	addr := uint16(0)
+	if i, ok := d.c.(*i2c.Dev); ok {
+		addr = i.Addr
+	}
+	// d.mu is not held while registering, as gpioreg may call back into the
+	// pins.
+	for i := range d.pins {
+		p := &d.pins[i]
+		p.name = fmt.Sprintf("PCA9685_%X_%d", addr, i)
+		p.number = base + i
+		if err := gpioreg.Register(p, true); err != nil {
+			d.unregisterPins(i)
+			return fmt.Errorf("pca9685: %v", err)
+		}
+	}
+	return nil
+}
+
+// UnregisterPins unregisters the pins registered by RegisterPins().
+func (d *Dev) UnregisterPins() error {
+	d.mu.Lock()
+	r := d.registered
+	d.mu.Unlock()
+	if !r {
+		return errors.New("pca9685: pins not registered")
+	}
+	if err := d.unregisterPins(len(d.pins)); err != nil {
+		return fmt.Errorf("pca9685: %v", err)
+	}
+	return nil
+}
+
+// Pin is a PWM channel of the PCA9685.
+//
+// It implements gpio.PinIO, gpio.PinPWM and pwm.SharedPeriod. Input is not
+// supported.
+type Pin struct {
+	d       *Dev
+	channel int
+	name    string
+	number  int
+	duty    gpio.Duty // Output duty cycle; protected by d.mu
+	s       pwm.State // Protected by d.mu; Period is unused
+}
+
+func (p *Pin) String() string {
+	return p.Name()
+}
+
+// Name implements pin.Pin.
+func (p *Pin) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return fmt.Sprintf("PCA9685_%d", p.channel)
+}
+
+// Number implements pin.Pin.
+//
+// Returns -1 unless RegisterPins() was called.
+func (p *Pin) Number() int {
+	return p.number
+}
+
+// Halt implements conn.Resource.
+//
+// It disables the channel and keeps the polarity.
+func (p *Pin) Halt() error {
+	return p.Apply(pwm.State{Polarity: p.State().Polarity})
+}
+
+// Function implements pin.Pin.
+func (p *Pin) Function() string {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	switch p.duty {
+	case 0:
+		return "Out/Low"
+	case gpio.DutyMax:
+		return "Out/High"
+	default:
+		return "PWM"
+	}
+}
+
+// In implements gpio.PinIn.
+//
+// It always fails, since the PCA9685 only has outputs.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	return errors.New("pca9685: input is not supported")
+}
+
+// Read implements gpio.PinIn.
+//
+// It returns the output level, High if the duty cycle is not 0.
+func (p *Pin) Read() gpio.Level {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	return p.duty != 0
+}
+
+// WaitForEdge implements gpio.PinIn.
+//
+// It always returns false.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	return false
+}
+
+// Pull implements gpio.PinIn.
+func (p *Pin) Pull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out implements gpio.PinOut.
+func (p *Pin) Out(l gpio.Level) error {
+	d := gpio.Duty(0)
+	if l {
+		d = gpio.DutyMax
+	}
+	return p.PWM(d, 0)
+}
+
+// PWM implements gpio.PinPWM.
+//
+// The period is shared by all the channels. Using 0 as period keeps the
+// current one. Changing the period fails if any other channel is active.
+//
+// The channel is reset to the Normal polarity.
+func (p *Pin) PWM(duty gpio.Duty, period time.Duration) error {
+	if !duty.Valid() {
+		return fmt.Errorf("pca9685: invalid duty %d", duty)
+	}
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	on, off := dutyToRegs(duty)
+	if err := p.set(duty, on, off, period); err != nil {
+		return err
+	}
+	p.s = pwm.State{Duty: duty, Enabled: true}
+	return nil
+}
+
+// Apply implements pwm.Channel.
+//
+// The same rules as PWM() apply to the period. The Inversed polarity is
+// generated by swapping the on and off times within the period.
+func (p *Pin) Apply(s pwm.State) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	d := s.Duty
+	if !s.Enabled {
+		d = 0
+	}
+	on, off := dutyToRegs(d)
+	if s.Polarity == pwm.Inversed {
+		on, off = off, on
+		d = gpio.DutyMax - d
+	}
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	if err := p.set(d, on, off, s.Period); err != nil {
+		return err
+	}
+	s.Period = 0
+	p.s = s
+	return nil
+}
+
+// State implements pwm.Channel.
+func (p *Pin) State() pwm.State {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	s := p.s
+	s.Period = prescaleToPeriod(p.d.prescale)
+	return s
+}
+
+// Siblings implements pwm.SharedPeriod.
+//
+// It returns all the channels of the device.
+func (p *Pin) Siblings() []pwm.Channel {
+	out := make([]pwm.Channel, len(p.d.pins))
+	for i := range p.d.pins {
+		out[i] = &p.d.pins[i]
+	}
+	return out
+}
+
+//
+
+// set changes the period if needed, then writes the LEDn_ON and LEDn_OFF
+// registers. duty is the resulting output duty cycle.
+//
+// d.mu must be held.
+func (p *Pin) set(duty gpio.Duty, on, off uint16, period time.Duration) error {
+	if period != 0 {
+		prescale, err := periodToPrescale(period)
+		if err != nil {
+			return err
+		}
+		if prescale != p.d.prescale {
+			for i := range p.d.pins {
+				if o := &p.d.pins[i]; o != p && o.duty != 0 {
+					return fmt.Errorf("pca9685: can't change period to %s while %s is active with period %s", period, o, prescaleToPeriod(p.d.prescale))
+				}
+			}
+			if err := p.d.setPrescale(prescale); err != nil {
+				return err
+			}
+		}
+	}
+	if err := p.d.write(regLED0+4*byte(p.channel), byte(on), byte(on>>8), byte(off), byte(off>>8)); err != nil {
+		return err
+	}
+	p.duty = duty
+	return nil
+}
+
+// Registers.
+const (
+	regMode1    = 0x00
+	regMode2    = 0x01
+	regLED0     = 0x06
+	regAllLED   = 0xFA
+	regPrescale = 0xFE
+)
+
+const (
+	mode1AI     = 0x20 // Register auto-increment
+	mode1Sleep  = 0x10 // Low power mode, oscillator off
+	mode2OutDrv = 0x04 // Totem pole outputs
+	// fullBit is bit 4 of LEDn_ON_H and LEDn_OFF_H, to turn the channel fully
+	// on or off.
+	fullBit = 0x10
+)
+
+// oscillator is the internal oscillator frequency in Hz.
+const oscillator = 25000000
+
+// periodToPrescale returns the prescaler value for the period; equation 1 on
+// page 25.
+func periodToPrescale(period time.Duration) (uint8, error) {
+	p := (int64(period)*oscillator/4096+500000000)/1000000000 - 1
+	if p < 3 || p > 255 {
+		return 0, fmt.Errorf("pca9685: period %s is out of range", period)
+	}
+	return uint8(p), nil
+}
+
+func prescaleToPeriod(prescale uint8) time.Duration {
+	return time.Duration(int64(prescale)+1) * 4096 * time.Second / oscillator
+}
+
+// dutyToRegs returns the LEDn_ON and LEDn_OFF values.
+func dutyToRegs(duty gpio.Duty) (uint16, uint16) {
+	switch duty {
+	case 0:
+		return 0, fullBit << 8
+	case gpio.DutyMax:
+		return fullBit << 8, 0
+	default:
+		return 0, uint16((int64(duty)*4096 + int64(gpio.DutyMax)/2) / int64(gpio.DutyMax))
+	}
+}
+
+// setPrescale changes the prescaler; it can only be written while the
+// oscillator is off.
+func (d *Dev) setPrescale(prescale uint8) error {
+	if err := d.write(regMode1, mode1AI|mode1Sleep); err != nil {
+		return err
+	}
+	if err := d.write(regPrescale, prescale); err != nil {
+		return err
+	}
+	if err := d.write(regMode1, mode1AI); err != nil {
+		return err
+	}
+	// Oscillator stabilization time.
+	time.Sleep(500 * time.Microsecond)
+	d.prescale = prescale
+	return nil
+}
+
+func (d *Dev) write(reg byte, b ...byte) error {
+	if err := d.c.Tx(append([]byte{reg}, b...), nil); err != nil {
+		return fmt.Errorf("pca9685: %v", err)
+	}
+	return nil
+}
+
+// unregisterPins unregisters the first n pins and resets the names and
+// numbers of all the pins.
+func (d *Dev) unregisterPins(n int) error {
+	var err error
+	for i := range d.pins {
+		p := &d.pins[i]
+		if i < n {
+			if err2 := gpioreg.Unregister(p.name); err == nil {
+				err = err2
+			}
+		}
+		p.name = ""
+		p.number = -1
+	}
+	d.mu.Lock()
+	d.registered = false
+	d.mu.Unlock()
+	return err
+}
+
+var defaults = Opts{
+	Addr:   0x40,
+	Period: 20 * time.Millisecond,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinPWM = &Pin{}
+var _ pwm.SharedPeriod = &Pin{}
@@ -0,0 +1,300 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pca9685
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/pwm"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize pca9685: %v", err)
+	}
+	defer dev.Halt()
+	s, err := NewServo(dev.Pin(0), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, angle := range []int{0, 90, 180} {
+		if err := s.SetAngle(angle); err != nil {
+			log.Fatal(err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+//
+
+var initOps = []i2ctest.IO{
+	{Addr: 0x40, W: []byte{0x01, 0x04}},
+	{Addr: 0x40, W: []byte{0xFA, 0x00, 0x00, 0x00, 0x10}},
+	{Addr: 0x40, W: []byte{0x00, 0x30}},
+	{Addr: 0x40, W: []byte{0xFE, 121}},
+	{Addr: 0x40, W: []byte{0x00, 0x20}},
+}
+
+func TestNewI2C_PWM(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps,
+			i2ctest.IO{Addr: 0x40, W: []byte{0x06, 0x00, 0x00, 0x00, 0x08}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0A, 0x00, 0x00, 0x33, 0x01}},
+			// Halt.
+			i2ctest.IO{Addr: 0x40, W: []byte{0xFA, 0x00, 0x00, 0x00, 0x10}},
+			// Period change.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x00, 0x30}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0xFE, 5}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x00, 0x20}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0E, 0x00, 0x10, 0x00, 0x00}},
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0E, 0x00, 0x00, 0x00, 0x10}},
+		),
+	}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "PCA9685{playback(64)}" {
+		t.Fatal(s)
+	}
+	if p := dev.Period(); p != 19988480*time.Nanosecond {
+		t.Fatal(p)
+	}
+	p0 := dev.Pin(0)
+	if err := p0.PWM(gpio.DutyHalf, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if s := p0.Function(); s != "PWM" {
+		t.Fatal(s)
+	}
+	if l := p0.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	s, err := NewServo(dev.Pin(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAngle(90); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAngle(181); err == nil {
+		t.Fatal("angle out of range")
+	}
+	p2 := dev.Pin(2)
+	if err := p2.PWM(gpio.DutyMax, time.Millisecond); err == nil {
+		t.Fatal("other channels are active")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p2.PWM(gpio.DutyMax, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if s := p2.Function(); s != "Out/High" {
+		t.Fatal(s)
+	}
+	if err := p2.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if s := p2.Function(); s != "Out/Low" {
+		t.Fatal(s)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPin_Apply(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps,
+			// 25% Inversed; the on and off times are swapped.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0A, 0x00, 0x04, 0x00, 0x00}},
+			// Disabled Inversed is fully on.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0A, 0x00, 0x10, 0x00, 0x00}},
+			// Disabled Normal is fully off.
+			i2ctest.IO{Addr: 0x40, W: []byte{0x0A, 0x00, 0x00, 0x00, 0x10}},
+		),
+	}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := dev.Pin(1)
+	s := pwm.State{Duty: gpio.DutyMax / 4, Polarity: pwm.Inversed, Enabled: true}
+	if err := p.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	s.Period = 19988480 * time.Nanosecond
+	if st := p.State(); st != s {
+		t.Fatal(st)
+	}
+	if s := p.Function(); s != "PWM" {
+		t.Fatal(s)
+	}
+	if err := dev.Pin(2).Apply(pwm.State{Period: time.Millisecond, Enabled: true}); err == nil {
+		t.Fatal("other channels are active")
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Function(); s != "Out/High" {
+		t.Fatal(s)
+	}
+	if st := p.State(); st.Enabled || st.Polarity != pwm.Inversed {
+		t.Fatal(st)
+	}
+	if err := p.Apply(pwm.State{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Apply(pwm.State{Polarity: 2}); err == nil {
+		t.Fatal("invalid polarity")
+	}
+	if l := len(p.Siblings()); l != NumChannels {
+		t.Fatal(l)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, &Opts{Addr: 0x20}); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, &Opts{Period: time.Second}); err == nil {
+		t.Fatal("bad period")
+	}
+	for i := 0; i < len(initOps); i++ {
+		bus := i2ctest.Playback{Ops: initOps[:i], DontPanic: true}
+		if _, err := NewI2C(&bus, nil); err == nil {
+			t.Fatalf("#%d: write failed", i)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps,
+			i2ctest.IO{Addr: 0x42, W: []byte{0x01, 0x00}},
+			i2ctest.IO{Addr: 0x42, W: []byte{0xFA, 0x00, 0x00, 0x00, 0x10}},
+			i2ctest.IO{Addr: 0x42, W: []byte{0x00, 0x30}},
+			i2ctest.IO{Addr: 0x42, W: []byte{0xFE, 121}},
+			i2ctest.IO{Addr: 0x42, W: []byte{0x00, 0x20}},
+		),
+	}
+	other, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := NewI2C(&bus, &Opts{Addr: 0x42, OpenDrain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Pin(16); p != nil {
+		t.Fatal("invalid channel")
+	}
+	p := dev.Pin(3)
+	if s := p.String(); s != "PCA9685_3" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != -1 {
+		t.Fatal(n)
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err == nil {
+		t.Fatal("input is not supported")
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("no edge")
+	}
+	if pull := p.Pull(); pull != gpio.PullNoChange {
+		t.Fatal(pull)
+	}
+	if err := p.PWM(-1, 0); err == nil {
+		t.Fatal("invalid duty")
+	}
+	if err := p.PWM(gpio.DutyHalf, time.Second); err == nil {
+		t.Fatal("invalid period")
+	}
+	if err := dev.RegisterPins(-1); err == nil {
+		t.Fatal("invalid base")
+	}
+	if err := dev.UnregisterPins(); err == nil {
+		t.Fatal("not registered")
+	}
+	if err := dev.RegisterPins(1000); err != nil {
+		t.Fatal(err)
+	}
+	defer dev.UnregisterPins()
+	if r := gpioreg.ByName("PCA9685_42_3"); r != p {
+		t.Fatal(r)
+	}
+	if r := gpioreg.ByName("1003"); r != p {
+		t.Fatal(r)
+	}
+	if err := dev.RegisterPins(1000); err == nil {
+		t.Fatal("already registered")
+	}
+	if r := gpioreg.ByName("1003"); r != p || p.Number() != 1003 {
+		t.Fatal(r, p.Number())
+	}
+	// The 11th pin collides; the first 10 are unregistered.
+	if err := other.RegisterPins(990); err == nil {
+		t.Fatal("pin number collision")
+	}
+	if r := gpioreg.ByName("PCA9685_40_0"); r != nil {
+		t.Fatal(r)
+	}
+	if n := other.Pin(0).Number(); n != -1 {
+		t.Fatal(n)
+	}
+	if err := other.RegisterPins(2000); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.UnregisterPins(); err != nil {
+		t.Fatal(err)
+	}
+	if r := gpioreg.ByName("2000"); r != nil {
+		t.Fatal(r)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewServo_fail(t *testing.T) {
+	data := []ServoOpts{
+		{},
+		{Period: time.Millisecond, MinPulse: time.Millisecond, MaxPulse: 2 * time.Millisecond, MaxAngle: 180},
+		{Period: 20 * time.Millisecond, MinPulse: time.Millisecond, MaxPulse: 2 * time.Millisecond},
+	}
+	for i, opts := range data {
+		if _, err := NewServo(&Pin{}, &opts); err == nil {
+			t.Fatalf("#%d: expected failure", i)
+		}
+	}
+	s, err := NewServo(&Pin{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPulse(time.Second); err == nil {
+		t.Fatal("pulse out of range")
+	}
+}
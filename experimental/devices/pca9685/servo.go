@@ -0,0 +1,81 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pca9685
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// ServoOpts describes the pulse width range of a servo motor.
+type ServoOpts struct {
+	// Period is the PWM period, usually 20ms.
+	Period time.Duration
+	// MinPulse is the pulse width at angle 0, usually 1ms.
+	MinPulse time.Duration
+	// MaxPulse is the pulse width at MaxAngle, usually 2ms.
+	MaxPulse time.Duration
+	// MaxAngle is the angle in degrees reached at MaxPulse.
+	MaxAngle int
+}
+
+// Servo controls a hobby servo motor connected to a PWM pin.
+//
+// It works with any gpio.PinPWM, not only a PCA9685 channel.
+type Servo struct {
+	p    gpio.PinPWM
+	opts ServoOpts
+}
+
+// NewServo returns a Servo that drives the pin p.
+func NewServo(p gpio.PinPWM, opts *ServoOpts) (*Servo, error) {
+	if opts == nil {
+		opts = &servoDefaults
+	}
+	if opts.Period <= 0 || opts.MinPulse <= 0 || opts.MaxPulse <= opts.MinPulse || opts.MaxPulse > opts.Period {
+		return nil, errors.New("pca9685: invalid servo pulse range")
+	}
+	if opts.MaxAngle <= 0 {
+		return nil, errors.New("pca9685: invalid servo MaxAngle")
+	}
+	return &Servo{p: p, opts: *opts}, nil
+}
+
+func (s *Servo) String() string {
+	return fmt.Sprintf("Servo{%s}", s.p)
+}
+
+// SetAngle moves the servo to the angle in degrees, between 0 and MaxAngle.
+func (s *Servo) SetAngle(deg int) error {
+	if deg < 0 || deg > s.opts.MaxAngle {
+		return fmt.Errorf("pca9685: angle %d is out of range [0, %d]", deg, s.opts.MaxAngle)
+	}
+	r := s.opts.MaxPulse - s.opts.MinPulse
+	return s.SetPulse(s.opts.MinPulse + r*time.Duration(deg)/time.Duration(s.opts.MaxAngle))
+}
+
+// SetPulse sets the pulse width directly.
+func (s *Servo) SetPulse(d time.Duration) error {
+	if d < 0 || d > s.opts.Period {
+		return fmt.Errorf("pca9685: pulse %s is out of range", d)
+	}
+	duty := gpio.Duty((int64(d)*int64(gpio.DutyMax) + int64(s.opts.Period)/2) / int64(s.opts.Period))
+	return s.p.PWM(duty, s.opts.Period)
+}
+
+// Halt stops sending pulses so the servo stops holding its position.
+func (s *Servo) Halt() error {
+	return s.p.PWM(0, s.opts.Period)
+}
+
+var servoDefaults = ServoOpts{
+	Period:   20 * time.Millisecond,
+	MinPulse: time.Millisecond,
+	MaxPulse: 2 * time.Millisecond,
+	MaxAngle: 180,
+}
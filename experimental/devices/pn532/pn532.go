@@ -0,0 +1,538 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package pn532 controls a NXP PN532 NFC controller over I²C, SPI or UART.
+//
+// It supports polling for ISO/IEC 14443 type A tags and reading and writing
+// Mifare Classic tags.
+//
+// The interface is selected with the I0 and I1 pins (or the switches on most
+// breakout boards): both low for UART (HSU), I0 high for I²C and I1 high for
+// SPI.
+//
+// Datasheet
+//
+// https://www.nxp.com/docs/en/nxp/data-sheets/PN532_C1.pdf
+//
+// https://www.nxp.com/docs/en/user-guide/141520.pdf
+package pn532
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/experimental/conn/uart"
+)
+
+// Tag is an ISO/IEC 14443 type A tag found in the field.
+type Tag struct {
+	UID  []byte
+	ATQA uint16 // SENS_RES
+	SAK  byte   // SEL_RES
+}
+
+func (t *Tag) String() string {
+	return fmt.Sprintf("Tag{UID:%s, ATQA:%#04x, SAK:%#02x}", hex.EncodeToString(t.UID), t.ATQA, t.SAK)
+}
+
+// Key is a Mifare Classic sector key.
+type Key [6]byte
+
+// DefaultKey is the factory default key of Mifare Classic tags.
+var DefaultKey = Key{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Timeout is the time to wait for a response. Defaults to 1s.
+	Timeout time.Duration
+}
+
+// Dev is a handle to a PN532.
+type Dev struct {
+	t    transport
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a PN532 at its fixed
+// address 0x24.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	return newDev(&i2cTransport{c: &i2c.Dev{Bus: b, Addr: 0x24}}, opts)
+}
+
+// NewSPI returns an object that communicates over SPI to a PN532.
+//
+// The PN532 uses LSB first bit order; the bits are reversed by the driver so
+// the SPI port doesn't need to support spi.LSBFirst.
+func NewSPI(p spi.Port, opts *Opts) (*Dev, error) {
+	c, err := p.Connect(5000000, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	return newDev(&spiTransport{c: c}, opts)
+}
+
+// NewUART returns an object that communicates over an UART to a PN532.
+//
+// The UART is set to 115200 bauds, the PN532 default.
+func NewUART(c uart.Conn, opts *Opts) (*Dev, error) {
+	if err := c.Speed(115200); err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	// Wake up the device from power down; page 99 of the user manual.
+	if err := c.Tx([]byte{0x55, 0x55, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, nil); err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	return newDev(&uartTransport{c: c}, opts)
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("PN532{%s}", d.t)
+}
+
+// FirmwareVersion returns the IC version, the firmware version and revision.
+func (d *Dev) FirmwareVersion() (ic, ver, rev byte, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r, err := d.exchange(cmdGetFirmwareVersion, nil, 4)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return r[0], r[1], r[2], nil
+}
+
+// ReadTag polls once for an ISO/IEC 14443 type A tag. It returns nil if no tag
+// is in the field.
+//
+// The tag found becomes the target of the Mifare operations.
+func (d *Dev) ReadTag() (*Tag, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readTag()
+}
+
+// WatchTags polls for tags at the specified interval and returns a tag each
+// time one enters the field.
+//
+// The application must call Halt() to stop the polling and close the channel.
+func (d *Dev) WatchTags(interval time.Duration) (<-chan Tag, error) {
+	d.stopWatching()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tags := make(chan Tag)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(tags)
+		d.watchTags(interval, tags, stop)
+	}(d.stop)
+	return tags, nil
+}
+
+// MifareAuthenticate authenticates a block of a Mifare Classic tag with a key
+// A or B. uid is the tag UID as returned by ReadTag().
+//
+// Authentication is valid for the whole sector containing the block.
+func (d *Dev) MifareAuthenticate(block byte, keyB bool, key Key, uid []byte) error {
+	if len(uid) < 4 {
+		return errors.New("pn532: invalid uid")
+	}
+	cmd := byte(mifareAuthA)
+	if keyB {
+		cmd = mifareAuthB
+	}
+	p := append([]byte{cmd, block}, key[:]...)
+	// The last 4 bytes of the UID are used.
+	p = append(p, uid[len(uid)-4:]...)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err := d.dataExchange(p, 0)
+	return err
+}
+
+// MifareRead reads a 16 bytes block of an authenticated sector.
+func (d *Dev) MifareRead(block byte) ([16]byte, error) {
+	var b [16]byte
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r, err := d.dataExchange([]byte{mifareRead, block}, 16)
+	if err != nil {
+		return b, err
+	}
+	copy(b[:], r)
+	return b, nil
+}
+
+// MifareWrite writes a 16 bytes block of an authenticated sector.
+//
+// Writing the last block of a sector changes the keys and access bits; an
+// invalid value permanently locks the sector.
+func (d *Dev) MifareWrite(block byte, data [16]byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err := d.dataExchange(append([]byte{mifareWrite, block}, data[:]...), 0)
+	return err
+}
+
+// Halt stops the polling initiated by WatchTags().
+func (d *Dev) Halt() error {
+	d.stopWatching()
+	return nil
+}
+
+//
+
+const (
+	cmdGetFirmwareVersion  = 0x02
+	cmdSAMConfiguration    = 0x14
+	cmdRFConfiguration     = 0x32
+	cmdInDataExchange      = 0x40
+	cmdInListPassiveTarget = 0x4A
+
+	hostToPN532 = 0xD4 // TFI of frames sent
+	pn532ToHost = 0xD5 // TFI of frames received
+
+	mifareAuthA = 0x60
+	mifareAuthB = 0x61
+	mifareRead  = 0x30
+	mifareWrite = 0xA0
+
+	brTy106kbpsTypeA = 0x00 // ISO/IEC 14443 type A at 106 kbps
+	maxFrame         = 64   // Largest frame read at once
+	statusReady      = 0x01
+
+	spiDataWrite  = 0x01
+	spiStatusRead = 0x02
+	spiDataRead   = 0x03
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// transport is the interface specific part of the protocol.
+type transport interface {
+	String() string
+	// write sends a frame.
+	write(frame []byte) error
+	// read waits for a frame and returns the bytes starting with it. There may
+	// be trailing bytes.
+	read(timeout time.Duration) ([]byte, error)
+}
+
+func newDev(t transport, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	d := &Dev{t: t, opts: *opts}
+	if d.opts.Timeout == 0 {
+		d.opts.Timeout = defaults.Timeout
+	}
+	// Normal mode, no virtual card, use the IRQ pin.
+	if _, err := d.exchange(cmdSAMConfiguration, []byte{0x01, 0x14, 0x01}, 0); err != nil {
+		return nil, err
+	}
+	// Do not wait forever for a tag; MxRtyATR, MxRtyPSL, MxRtyPassiveActivation.
+	if _, err := d.exchange(cmdRFConfiguration, []byte{0x05, 0xFF, 0x01, 0x01}, 0); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) stopWatching() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) watchTags(interval time.Duration, tags chan<- Tag, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var last []byte
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		d.mu.Lock()
+		tag, err := d.readTag()
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to poll: %v", d, err)
+			return
+		}
+		if tag == nil {
+			last = nil
+			continue
+		}
+		if bytes.Equal(tag.UID, last) {
+			continue
+		}
+		last = tag.UID
+		select {
+		case tags <- *tag:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) readTag() (*Tag, error) {
+	r, err := d.exchange(cmdInListPassiveTarget, []byte{1, brTy106kbpsTypeA}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if r[0] == 0 {
+		return nil, nil
+	}
+	// Tg, SENS_RES, SEL_RES, NFCIDLength, NFCID.
+	if len(r) < 6 || len(r) < 6+int(r[5]) {
+		return nil, errors.New("pn532: invalid target data")
+	}
+	return &Tag{
+		UID:  append([]byte{}, r[6:6+r[5]]...),
+		ATQA: uint16(r[2])<<8 | uint16(r[3]),
+		SAK:  r[4],
+	}, nil
+}
+
+// dataExchange sends data to the target found by readTag() and returns at
+// least min bytes of response.
+func (d *Dev) dataExchange(data []byte, min int) ([]byte, error) {
+	r, err := d.exchange(cmdInDataExchange, append([]byte{1}, data...), 1+min)
+	if err != nil {
+		return nil, err
+	}
+	if s := r[0] & 0x3F; s != 0 {
+		return nil, fmt.Errorf("pn532: tag error %#x", s)
+	}
+	return r[1:], nil
+}
+
+// exchange sends a command and returns the response parameters, which must be
+// at least min bytes long.
+func (d *Dev) exchange(cmd byte, params []byte, min int) ([]byte, error) {
+	if err := d.t.write(buildFrame(append([]byte{hostToPN532, cmd}, params...))); err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	b, err := d.t.read(d.opts.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	if _, ack, err := parseFrame(b); err != nil {
+		return nil, err
+	} else if !ack {
+		return nil, errors.New("pn532: expected ACK")
+	}
+	if b, err = d.t.read(d.opts.Timeout); err != nil {
+		return nil, fmt.Errorf("pn532: %v", err)
+	}
+	data, ack, err := parseFrame(b)
+	if err != nil {
+		return nil, err
+	}
+	if ack || len(data) < 2 || data[0] != pn532ToHost || data[1] != cmd+1 {
+		return nil, fmt.Errorf("pn532: unexpected response to command %#x", cmd)
+	}
+	if len(data)-2 < min {
+		return nil, fmt.Errorf("pn532: short response to command %#x", cmd)
+	}
+	return data[2:], nil
+}
+
+// buildFrame returns a normal information frame for data, which starts with
+// the TFI.
+func buildFrame(data []byte) []byte {
+	f := make([]byte, 0, len(data)+7)
+	f = append(f, 0x00, 0x00, 0xFF, byte(len(data)), byte(-len(data)))
+	sum := byte(0)
+	for _, b := range data {
+		sum += b
+	}
+	f = append(f, data...)
+	return append(f, -sum, 0x00)
+}
+
+// parseFrame returns the data of a frame, starting with the TFI, or true if it
+// is an ACK frame.
+func parseFrame(b []byte) ([]byte, bool, error) {
+	i := bytes.Index(b, []byte{0x00, 0xFF})
+	if i == -1 || len(b) < i+4 {
+		return nil, false, errors.New("pn532: no frame found")
+	}
+	l, lcs := b[i+2], b[i+3]
+	if l == 0 && lcs == 0xFF {
+		return nil, true, nil
+	}
+	if l+lcs != 0 {
+		return nil, false, errors.New("pn532: invalid frame length checksum")
+	}
+	if len(b) < i+5+int(l) {
+		return nil, false, errors.New("pn532: truncated frame")
+	}
+	data := b[i+4 : i+4+int(l)]
+	sum := b[i+4+int(l)]
+	for _, c := range data {
+		sum += c
+	}
+	if sum != 0 {
+		return nil, false, errors.New("pn532: invalid frame data checksum")
+	}
+	if l == 1 && data[0] == 0x7F {
+		return nil, false, errors.New("pn532: syntax error frame")
+	}
+	return data, false, nil
+}
+
+// i2cTransport polls the status byte that precedes all reads.
+type i2cTransport struct {
+	c conn.Conn
+}
+
+func (i *i2cTransport) String() string {
+	return fmt.Sprintf("%s", i.c)
+}
+
+func (i *i2cTransport) write(frame []byte) error {
+	return i.c.Tx(frame, nil)
+}
+
+func (i *i2cTransport) read(timeout time.Duration) ([]byte, error) {
+	var s [1]byte
+	for start := time.Now(); ; sleep(time.Millisecond) {
+		if err := i.c.Tx(nil, s[:]); err != nil {
+			return nil, err
+		}
+		if s[0]&statusReady != 0 {
+			break
+		}
+		if time.Since(start) > timeout {
+			return nil, errors.New("timed out")
+		}
+	}
+	b := make([]byte, 1+maxFrame)
+	if err := i.c.Tx(nil, b); err != nil {
+		return nil, err
+	}
+	return b[1:], nil
+}
+
+// spiTransport prefixes each transaction with the operation and reverses the
+// bits.
+type spiTransport struct {
+	c conn.Conn
+}
+
+func (s *spiTransport) String() string {
+	return fmt.Sprintf("%s", s.c)
+}
+
+func (s *spiTransport) write(frame []byte) error {
+	return s.c.Tx(reverse(append([]byte{spiDataWrite}, frame...)), nil)
+}
+
+func (s *spiTransport) read(timeout time.Duration) ([]byte, error) {
+	w := reverse([]byte{spiStatusRead, 0})
+	r := make([]byte, 2)
+	for start := time.Now(); ; sleep(time.Millisecond) {
+		if err := s.c.Tx(w, r); err != nil {
+			return nil, err
+		}
+		if reverse(r)[1]&statusReady != 0 {
+			break
+		}
+		if time.Since(start) > timeout {
+			return nil, errors.New("timed out")
+		}
+	}
+	w = make([]byte, 1+maxFrame)
+	w[0] = spiDataRead
+	r = make([]byte, len(w))
+	if err := s.c.Tx(reverse(w), r); err != nil {
+		return nil, err
+	}
+	return reverse(r)[1:], nil
+}
+
+// reverse reverses the bit order of each byte in place.
+func reverse(b []byte) []byte {
+	for i, v := range b {
+		r := byte(0)
+		for j := uint(0); j < 8; j++ {
+			r |= (v >> j & 1) << (7 - j)
+		}
+		b[i] = r
+	}
+	return b
+}
+
+// uartTransport reads frames byte by byte as there is no status byte.
+type uartTransport struct {
+	c uart.Conn
+}
+
+func (u *uartTransport) String() string {
+	return fmt.Sprintf("%s", u.c)
+}
+
+func (u *uartTransport) write(frame []byte) error {
+	return u.c.Tx(frame, nil)
+}
+
+func (u *uartTransport) read(timeout time.Duration) ([]byte, error) {
+	// The UART is expected to have its own read timeout.
+	var c [1]byte
+	prev := byte(0xFF)
+	for i := 0; ; i++ {
+		if i == maxFrame {
+			return nil, errors.New("no start code")
+		}
+		if err := u.c.Tx(nil, c[:]); err != nil {
+			return nil, err
+		}
+		if prev == 0x00 && c[0] == 0xFF {
+			break
+		}
+		prev = c[0]
+	}
+	var h [2]byte
+	if err := u.c.Tx(nil, h[:]); err != nil {
+		return nil, err
+	}
+	// ACK has only the postamble left, otherwise data, DCS and postamble.
+	n := 1
+	if h[0] != 0 || h[1] != 0xFF {
+		n = int(h[0]) + 2
+	}
+	b := make([]byte, 4+n)
+	b[0], b[1], b[2], b[3] = 0x00, 0xFF, h[0], h[1]
+	if err := u.c.Tx(nil, b[4:]); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+var defaults = Opts{
+	Timeout: time.Second,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
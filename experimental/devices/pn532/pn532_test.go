@@ -0,0 +1,330 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pn532
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/experimental/conn/uart/uarttest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	b, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer b.Close()
+	dev, err := NewI2C(b, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize pn532: %v", err)
+	}
+	tags, err := dev.WatchTags(200 * time.Millisecond)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dev.Halt()
+	for t := range tags {
+		fmt.Printf("%s\n", &t)
+		if err := dev.MifareAuthenticate(4, false, DefaultKey, t.UID); err != nil {
+			log.Printf("authentication failed: %v", err)
+			continue
+		}
+		data, err := dev.MifareRead(4)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("block 4: %x\n", data)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewI2C(t *testing.T) {
+	var ops []i2ctest.IO
+	ops = append(ops, i2cCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)...)
+	ops = append(ops, i2cCmd(0x32, []byte{0x05, 0xFF, 0x01, 0x01}, nil)...)
+	ops = append(ops, i2cCmd(0x02, nil, []byte{0x32, 0x01, 0x06, 0x07})...)
+	// Not ready yet.
+	ops = append(ops[:len(ops)-2], append([]i2ctest.IO{{Addr: 0x24, R: []byte{0}}}, ops[len(ops)-2:]...)...)
+	ops = append(ops, i2cCmd(0x4A, []byte{1, 0}, []byte{0})...)
+	ops = append(ops, i2cCmd(0x4A, []byte{1, 0}, []byte{1, 1, 0x00, 0x04, 0x08, 4, 0xDE, 0xAD, 0xBE, 0xEF})...)
+	ops = append(ops, i2cCmd(0x40, []byte{1, 0x60, 4, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xDE, 0xAD, 0xBE, 0xEF}, []byte{0})...)
+	ops = append(ops, i2cCmd(0x40, []byte{1, 0x30, 4}, append([]byte{0}, seq(16)...))...)
+	ops = append(ops, i2cCmd(0x40, append([]byte{1, 0xA0, 5}, seq(16)...), []byte{0})...)
+	ops = append(ops, i2cCmd(0x40, []byte{1, 0x61, 8, 1, 2, 3, 4, 5, 6, 0xDE, 0xAD, 0xBE, 0xEF}, []byte{0x14})...)
+	bus := i2ctest.Playback{Ops: ops}
+	d, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "PN532{playback(36)}" {
+		t.Fatal(s)
+	}
+	if ic, ver, rev, err := d.FirmwareVersion(); err != nil || ic != 0x32 || ver != 1 || rev != 6 {
+		t.Fatal(ic, ver, rev, err)
+	}
+	if tag, err := d.ReadTag(); tag != nil || err != nil {
+		t.Fatal(tag, err)
+	}
+	tag, err := d.ReadTag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := tag.String(); s != "Tag{UID:deadbeef, ATQA:0x0004, SAK:0x08}" {
+		t.Fatal(s)
+	}
+	if err := d.MifareAuthenticate(4, false, DefaultKey, tag.UID); err != nil {
+		t.Fatal(err)
+	}
+	b, err := d.MifareRead(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:], seq(16)) {
+		t.Fatal(b)
+	}
+	if err := d.MifareWrite(5, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.MifareAuthenticate(8, true, Key{1, 2, 3, 4, 5, 6}, tag.UID); err == nil {
+		t.Fatal("authentication error")
+	}
+	if err := d.MifareAuthenticate(8, true, DefaultKey, nil); err == nil {
+		t.Fatal("invalid uid")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+	// Never ready.
+	ops := []i2ctest.IO{{Addr: 0x24, W: frame(0xD4, 0x14, 0x01, 0x14, 0x01)}}
+	for i := 0; i < 10; i++ {
+		ops = append(ops, i2ctest.IO{Addr: 0x24, R: []byte{0}})
+	}
+	bus := i2ctest.Playback{Ops: ops, DontPanic: true}
+	if _, err := NewI2C(&bus, &Opts{Timeout: time.Nanosecond}); err == nil {
+		t.Fatal("timeout")
+	}
+	// No ACK.
+	ops = i2cCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)[:3]
+	ops[2].R = i2cRead(frame(0xD5, 0x15))
+	if _, err := NewI2C(&i2ctest.Playback{Ops: ops}, nil); err == nil {
+		t.Fatal("expected ACK")
+	}
+	// Error frame.
+	ops = i2cCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)
+	ops[4].R = i2cRead(frame(0x7F))
+	if _, err := NewI2C(&i2ctest.Playback{Ops: ops}, nil); err == nil {
+		t.Fatal("error frame")
+	}
+	// Wrong response.
+	ops = i2cCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)
+	ops[4].R = i2cRead(frame(0xD5, 0x03))
+	if _, err := NewI2C(&i2ctest.Playback{Ops: ops}, nil); err == nil {
+		t.Fatal("unexpected response")
+	}
+}
+
+func TestWatchTags(t *testing.T) {
+	var ops []i2ctest.IO
+	ops = append(ops, i2cCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)...)
+	ops = append(ops, i2cCmd(0x32, []byte{0x05, 0xFF, 0x01, 0x01}, nil)...)
+	for _, uid := range [][]byte{{1, 2, 3, 4}, {1, 2, 3, 4}, nil, {1, 2, 3, 4}, {5, 6, 7, 8}} {
+		r := []byte{0}
+		if uid != nil {
+			r = append([]byte{1, 1, 0x00, 0x04, 0x08, 4}, uid...)
+		}
+		ops = append(ops, i2cCmd(0x4A, []byte{1, 0}, r)...)
+	}
+	bus := i2ctest.Playback{Ops: ops, DontPanic: true}
+	d, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.WatchTags(time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tag is reported again once it left the field.
+	for _, e := range []byte{1, 1, 5} {
+		tag := <-c
+		if tag.UID[0] != e {
+			t.Fatal(tag)
+		}
+	}
+	// The playback is exhausted and fails, which stops the polling.
+	if _, ok := <-c; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI(t *testing.T) {
+	var ops []conntest.IO
+	ops = append(ops, spiCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)...)
+	ops = append(ops, spiCmd(0x32, []byte{0x05, 0xFF, 0x01, 0x01}, nil)...)
+	ops = append(ops, spiCmd(0x02, nil, []byte{0x32, 0x01, 0x06, 0x07})...)
+	p := spitest.Playback{Playback: conntest.Playback{Ops: ops}}
+	d, err := NewSPI(&p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic, _, _, err := d.FirmwareVersion(); err != nil || ic != 0x32 {
+		t.Fatal(ic, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSPI(&spitest.Playback{Initialized: true}, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+}
+
+func TestNewUART(t *testing.T) {
+	var ops []uarttest.IO
+	ops = append(ops, uarttest.IO{W: append([]byte{0x55, 0x55}, make([]byte, 14)...)})
+	ops = append(ops, uartCmd(0x14, []byte{0x01, 0x14, 0x01}, nil)...)
+	ops = append(ops, uartCmd(0x32, []byte{0x05, 0xFF, 0x01, 0x01}, nil)...)
+	ops = append(ops, uartCmd(0x02, nil, []byte{0x32, 0x01, 0x06, 0x07})...)
+	p := uarttest.Playback{Ops: ops}
+	d, err := NewUART(&p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Baud != 115200 {
+		t.Fatal(p.Baud)
+	}
+	if ic, _, _, err := d.FirmwareVersion(); err != nil || ic != 0x32 {
+		t.Fatal(ic, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewUART(&uarttest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	data := []struct {
+		b   []byte
+		ack bool
+		ok  bool
+	}{
+		{[]byte{0x00, 0x00, 0xFF, 0x00, 0xFF, 0x00}, true, true},
+		{frame(0xD5, 0x03), false, true},
+		{[]byte{0x01, 0x02}, false, false},
+		{[]byte{0x00, 0x00, 0xFF, 0x02, 0xFF, 0xD5, 0x03, 0x28}, false, false},
+		{[]byte{0x00, 0x00, 0xFF, 0x02, 0xFE, 0xD5}, false, false},
+		{[]byte{0x00, 0x00, 0xFF, 0x02, 0xFE, 0xD5, 0x03, 0x00}, false, false},
+	}
+	for i, line := range data {
+		_, ack, err := parseFrame(line.b)
+		if ack != line.ack || (err == nil) != line.ok {
+			t.Fatal(i, ack, err)
+		}
+	}
+}
+
+//
+
+// frame returns a frame containing data.
+func frame(data ...byte) []byte {
+	return buildFrame(data)
+}
+
+var ack = []byte{0x00, 0x00, 0xFF, 0x00, 0xFF, 0x00}
+
+func pad(b []byte) []byte {
+	return append(b, make([]byte, maxFrame-len(b))...)
+}
+
+func seq(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+// i2cCmd returns the I/O of a command; the read buffers are prefixed with the
+// status byte.
+func i2cCmd(cmd byte, params, resp []byte) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: 0x24, W: frame(append([]byte{0xD4, cmd}, params...)...)},
+		{Addr: 0x24, R: []byte{1}},
+		{Addr: 0x24, R: i2cRead(append([]byte{}, ack...))},
+		{Addr: 0x24, R: []byte{1}},
+		{Addr: 0x24, R: i2cRead(frame(append([]byte{0xD5, cmd + 1}, resp...)...))},
+	}
+}
+
+// i2cRead returns a read buffer containing a frame b.
+func i2cRead(b []byte) []byte {
+	return append([]byte{1}, pad(b)...)
+}
+
+// spiCmd returns the I/O of a command, with bits reversed as sent on the wire.
+func spiCmd(cmd byte, params, resp []byte) []conntest.IO {
+	status := func() conntest.IO {
+		return conntest.IO{W: reverse([]byte{0x02, 0}), R: reverse([]byte{0, 1})}
+	}
+	read := func(b []byte) conntest.IO {
+		w := make([]byte, 1+maxFrame)
+		w[0] = 0x03
+		return conntest.IO{W: reverse(w), R: reverse(append([]byte{0}, pad(b)...))}
+	}
+	return []conntest.IO{
+		{W: reverse(append([]byte{0x01}, frame(append([]byte{0xD4, cmd}, params...)...)...))},
+		status(),
+		read(append([]byte{}, ack...)),
+		status(),
+		read(frame(append([]byte{0xD5, cmd + 1}, resp...)...)),
+	}
+}
+
+// uartCmd returns the I/O of a command, as read by uartTransport.
+func uartCmd(cmd byte, params, resp []byte) []uarttest.IO {
+	f := frame(append([]byte{0xD5, cmd + 1}, resp...)...)
+	return []uarttest.IO{
+		{W: frame(append([]byte{0xD4, cmd}, params...)...)},
+		{R: []byte{0x00}},
+		{R: []byte{0x00}},
+		{R: []byte{0xFF}},
+		{R: []byte{0x00, 0xFF}},
+		{R: []byte{0x00}},
+		{R: []byte{0x00}},
+		{R: []byte{0x00}},
+		{R: []byte{0xFF}},
+		{R: f[3:5]},
+		{R: f[5:]},
+	}
+}
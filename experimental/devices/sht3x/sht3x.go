@@ -0,0 +1,448 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package sht3x controls a Sensirion SHT30/SHT31/SHT35 humidity and
+// temperature sensor over I²C.
+//
+// Both single shot and periodic acquisition modes are supported. All the data
+// read from the device is CRC checked.
+//
+// Datasheet
+//
+// https://www.sensirion.com/fileadmin/user_upload/customers/sensirion/Dokumente/2_Humidity_Sensors/Datasheets/Sensirion_Humidity_Sensors_SHT3x_Datasheet_digital.pdf
+package sht3x
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Repeatability determines the measurement duration and noise level.
+type Repeatability uint8
+
+// Possible repeatability values.
+const (
+	High   Repeatability = 0
+	Medium Repeatability = 1
+	Low    Repeatability = 2
+)
+
+const repeatabilityName = "HighMediumLow"
+
+var repeatabilityIndex = [...]uint8{0, 4, 10, 13}
+
+func (r Repeatability) String() string {
+	if r >= Repeatability(len(repeatabilityIndex)-1) {
+		return fmt.Sprintf("Repeatability(%d)", r)
+	}
+	return repeatabilityName[repeatabilityIndex[r]:repeatabilityIndex[r+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Repeatability Repeatability
+}
+
+// Status is the content of the status register.
+type Status uint16
+
+// Status bits.
+const (
+	AlertPending      Status = 1 << 15
+	HeaterOn          Status = 1 << 13
+	HumidityAlert     Status = 1 << 11
+	TemperatureAlert  Status = 1 << 10
+	ResetDetected     Status = 1 << 4
+	CommandFailed     Status = 1 << 1
+	WriteChecksumFail Status = 1 << 0
+)
+
+// Limit is a temperature and humidity pair used for the alert thresholds.
+//
+// Only the 9 most significant bits of the temperature and the 7 most
+// significant bits of the humidity are stored in the device, so the values
+// read back are rounded.
+type Limit struct {
+	Temperature devices.Celsius
+	Humidity    devices.RelativeHumidity
+}
+
+// Alert are the thresholds that drive the ALERT pin.
+//
+// The alert is raised when the temperature or humidity goes above HighSet or
+// below LowSet and is cleared when it gets back between LowClear and
+// HighClear.
+type Alert struct {
+	HighSet   Limit
+	HighClear Limit
+	LowClear  Limit
+	LowSet    Limit
+}
+
+// Dev is a handle to a SHT3x device.
+type Dev struct {
+	c    conn.Conn
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a SHT3x.
+//
+// The address must be 0x44 or 0x45 depending on the ADDR pin.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x44, 0x45:
+	default:
+		return nil, errors.New("sht3x: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Repeatability > Low {
+		return nil, fmt.Errorf("sht3x: invalid repeatability %d", opts.Repeatability)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	// Make sure the device is not in periodic mode, then verify it responds.
+	if err := d.command(cmdBreak); err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := d.Status(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("SHT3x{%s}", d.c)
+}
+
+// Sense requests a one time measurement as °C and % of relative humidity.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("sht3x: already sensing continuously")
+	}
+	if err := d.command(cmdSingleShot[d.opts.Repeatability]); err != nil {
+		return err
+	}
+	time.Sleep(measDuration[d.opts.Repeatability])
+	return d.readMeasurement(env)
+}
+
+// SenseContinuous returns measurements as °C and % of relative humidity on a
+// continuous basis.
+//
+// The device is put in periodic mode at the closest rate supported by the
+// device that is at least as fast as the interval: 0.5, 1, 2, 4 or 10
+// measurements per second.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval <= 0 {
+		return nil, errors.New("sht3x: invalid interval")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.command(cmdPeriodic[chooseRate(interval)][d.opts.Repeatability]); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature | devices.MetricHumidity
+}
+
+// Halt stops the SHT3x from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	if !d.stopSensing() {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.command(cmdBreak)
+}
+
+// SetHeater turns the internal heater on or off.
+//
+// The heater can be used to verify the sensor's plausibility or to evaporate
+// condensation. It increases the temperature by a few degrees.
+func (d *Dev) SetHeater(on bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if on {
+		return d.command(cmdHeaterEnable)
+	}
+	return d.command(cmdHeaterDisable)
+}
+
+// Status returns the status register.
+func (d *Dev) Status() (Status, error) {
+	var b [3]byte
+	if err := d.read(cmdReadStatus, b[:]); err != nil {
+		return 0, err
+	}
+	return Status(uint16(b[0])<<8 | uint16(b[1])), nil
+}
+
+// ClearStatus clears the alert and reset flags of the status register.
+func (d *Dev) ClearStatus() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.command(cmdClearStatus)
+}
+
+// Alert returns the currently programmed alert thresholds.
+func (d *Dev) Alert() (Alert, error) {
+	var a Alert
+	limits := []*Limit{&a.HighSet, &a.HighClear, &a.LowClear, &a.LowSet}
+	for i, cmd := range cmdReadAlert {
+		var b [3]byte
+		if err := d.read(cmd, b[:]); err != nil {
+			return a, err
+		}
+		*limits[i] = decodeLimit(uint16(b[0])<<8 | uint16(b[1]))
+	}
+	return a, nil
+}
+
+// SetAlert programs the alert thresholds.
+//
+// The values are not persisted across power cycles.
+func (d *Dev) SetAlert(a *Alert) error {
+	limits := []Limit{a.HighSet, a.HighClear, a.LowClear, a.LowSet}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, cmd := range cmdWriteAlert {
+		v := encodeLimit(limits[i])
+		b := [5]byte{byte(cmd >> 8), byte(cmd), byte(v >> 8), byte(v)}
+		b[4] = crc8(b[2:4])
+		if err := d.c.Tx(b[:], nil); err != nil {
+			return fmt.Errorf("sht3x: %v", err)
+		}
+	}
+	return nil
+}
+
+//
+
+// Commands.
+const (
+	cmdBreak         = 0x3093
+	cmdFetch         = 0xE000
+	cmdHeaterEnable  = 0x306D
+	cmdHeaterDisable = 0x3066
+	cmdReadStatus    = 0xF32D
+	cmdClearStatus   = 0x3041
+)
+
+// cmdSingleShot is indexed by Repeatability; clock stretching disabled.
+var cmdSingleShot = [...]uint16{0x2400, 0x240B, 0x2416}
+
+// cmdPeriodic is indexed by rate then Repeatability.
+var cmdPeriodic = [...][3]uint16{
+	{0x2032, 0x2024, 0x202F}, // 0.5 mps
+	{0x2130, 0x2126, 0x212D}, // 1 mps
+	{0x2236, 0x2220, 0x222B}, // 2 mps
+	{0x2334, 0x2322, 0x2329}, // 4 mps
+	{0x2737, 0x2721, 0x272A}, // 10 mps
+}
+
+// cmdReadAlert and cmdWriteAlert are in the order high set, high clear, low
+// clear, low set.
+var cmdReadAlert = [...]uint16{0xE11F, 0xE114, 0xE109, 0xE102}
+var cmdWriteAlert = [...]uint16{0x611D, 0x6116, 0x610B, 0x6100}
+
+// measDuration is indexed by Repeatability; page 7, max values.
+var measDuration = [...]time.Duration{
+	15500 * time.Microsecond,
+	6500 * time.Microsecond,
+	4500 * time.Microsecond,
+}
+
+// chooseRate returns the index in cmdPeriodic.
+func chooseRate(interval time.Duration) int {
+	switch {
+	case interval >= 2*time.Second:
+		return 0
+	case interval >= time.Second:
+		return 1
+	case interval >= 500*time.Millisecond:
+		return 2
+	case interval >= 250*time.Millisecond:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func (d *Dev) stopSensing() bool {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop == nil {
+		return false
+	}
+	close(stop)
+	d.wg.Wait()
+	return true
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.command(cmdFetch)
+		if err == nil {
+			err = d.readMeasurement(&e)
+		}
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) command(cmd uint16) error {
+	if err := d.c.Tx([]byte{byte(cmd >> 8), byte(cmd)}, nil); err != nil {
+		return fmt.Errorf("sht3x: %v", err)
+	}
+	return nil
+}
+
+// read sends a command and reads the reply, checking the CRC of each word.
+func (d *Dev) read(cmd uint16, b []byte) error {
+	if err := d.command(cmd); err != nil {
+		return err
+	}
+	if err := d.c.Tx(nil, b); err != nil {
+		return fmt.Errorf("sht3x: %v", err)
+	}
+	for i := 0; i < len(b); i += 3 {
+		if crc8(b[i:i+2]) != b[i+2] {
+			return errors.New("sht3x: invalid CRC")
+		}
+	}
+	return nil
+}
+
+func (d *Dev) readMeasurement(env *devices.Environment) error {
+	var b [6]byte
+	if err := d.c.Tx(nil, b[:]); err != nil {
+		return fmt.Errorf("sht3x: %v", err)
+	}
+	if crc8(b[0:2]) != b[2] || crc8(b[3:5]) != b[5] {
+		return errors.New("sht3x: invalid CRC")
+	}
+	env.Temperature = rawToTemp(uint16(b[0])<<8 | uint16(b[1]))
+	env.Humidity = rawToHumidity(uint16(b[3])<<8 | uint16(b[4]))
+	env.Time = time.Now()
+	return nil
+}
+
+// rawToTemp converts a raw value to °C; page 14.
+func rawToTemp(raw uint16) devices.Celsius {
+	return devices.Celsius(-45000 + (175000*int64(raw)+32767)/65535)
+}
+
+// rawToHumidity converts a raw value to %rH; page 14.
+func rawToHumidity(raw uint16) devices.RelativeHumidity {
+	return devices.RelativeHumidity((10000*int64(raw) + 32767) / 65535)
+}
+
+func tempToRaw(t devices.Celsius) uint16 {
+	v := (int64(t) + 45000) * 65535 / 175000
+	if v < 0 {
+		return 0
+	}
+	if v > 65535 {
+		return 65535
+	}
+	return uint16(v)
+}
+
+func humidityToRaw(h devices.RelativeHumidity) uint16 {
+	v := int64(h) * 65535 / 10000
+	if v < 0 {
+		return 0
+	}
+	if v > 65535 {
+		return 65535
+	}
+	return uint16(v)
+}
+
+// encodeLimit packs the 7 MSB of humidity and the 9 MSB of temperature.
+func encodeLimit(l Limit) uint16 {
+	return humidityToRaw(l.Humidity)&0xFE00 | tempToRaw(l.Temperature)>>7
+}
+
+func decodeLimit(v uint16) Limit {
+	return Limit{
+		Temperature: rawToTemp((v & 0x1FF) << 7),
+		Humidity:    rawToHumidity(v & 0xFE00),
+	}
+}
+
+// crc8 calculates the CRC with polynomial 0x31 and initialization 0xFF.
+func crc8(b []byte) byte {
+	crc := byte(0xFF)
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+var defaults = Opts{
+	Repeatability: High,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
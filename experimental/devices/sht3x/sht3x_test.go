@@ -0,0 +1,276 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sht3x
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x44, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize sht3x: %v", err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%8s %9s\n", env.Temperature, env.Humidity)
+}
+
+//
+
+// 25°C, 50%rH.
+var measurement = []byte{0x66, 0x66, 0x93, 0x80, 0x00, 0xA2}
+
+func initOps(addr uint16) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: addr, W: []byte{0x30, 0x93}},
+		{Addr: addr, W: []byte{0xF3, 0x2D}},
+		{Addr: addr, R: []byte{0x80, 0x10, 0xE1}},
+	}
+}
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x45),
+			i2ctest.IO{Addr: 0x45, W: []byte{0x24, 0x16}},
+			i2ctest.IO{Addr: 0x45, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x45, &Opts{Repeatability: Low})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "SHT3x{playback(69)}" {
+		t.Fatal(s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 25000, Humidity: 5000, Time: env.Time}); env != expected {
+		t.Fatalf("%#v != %#v", env, expected)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x40, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x44, &Opts{Repeatability: 3}); err == nil {
+		t.Fatal("bad repeatability")
+	}
+	bus := i2ctest.Playback{DontPanic: true}
+	if _, err := NewI2C(&bus, 0x44, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	bus = i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x44, W: []byte{0x30, 0x93}},
+			{Addr: 0x44, W: []byte{0xF3, 0x2D}},
+			{Addr: 0x44, R: []byte{0x80, 0x10, 0x00}},
+		},
+	}
+	if _, err := NewI2C(&bus, 0x44, nil); err == nil {
+		t.Fatal("bad CRC")
+	}
+}
+
+func TestSense_badCRC(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0x24, 0x00}},
+			i2ctest.IO{Addr: 0x44, R: []byte{0x66, 0x66, 0x93, 0x80, 0x00, 0x00}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("bad CRC")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0x27, 0x21}},
+			i2ctest.IO{Addr: 0x44, W: []byte{0xE0, 0x00}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+			i2ctest.IO{Addr: 0x44, W: []byte{0xE0, 0x00}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+			// Halt.
+			i2ctest.IO{Addr: 0x44, W: []byte{0x30, 0x93}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, &Opts{Repeatability: Medium})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(0); err == nil {
+		t.Fatal("invalid interval")
+	}
+	c, err := dev.SenseContinuous(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if env := <-c; env.Temperature != 25000 || env.Humidity != 5000 {
+			t.Fatal(env)
+		}
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeater_Status(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0x30, 0x6D}},
+			i2ctest.IO{Addr: 0x44, W: []byte{0xF3, 0x2D}},
+			i2ctest.IO{Addr: 0x44, R: []byte{0x80, 0x10, 0xE1}},
+			i2ctest.IO{Addr: 0x44, W: []byte{0x30, 0x66}},
+			i2ctest.IO{Addr: 0x44, W: []byte{0x30, 0x41}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetHeater(true); err != nil {
+		t.Fatal(err)
+	}
+	s, err := dev.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != AlertPending|ResetDetected {
+		t.Fatalf("%#x", s)
+	}
+	if err := dev.SetHeater(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.ClearStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAlert(t *testing.T) {
+	l := Limit{Temperature: 25000, Humidity: 5000}
+	ops := initOps(0x44)
+	for _, cmd := range cmdWriteAlert {
+		ops = append(ops, i2ctest.IO{Addr: 0x44, W: []byte{byte(cmd >> 8), byte(cmd), 0x7E, 0xCC, crc8([]byte{0x7E, 0xCC})}})
+	}
+	for _, cmd := range cmdReadAlert {
+		ops = append(ops,
+			i2ctest.IO{Addr: 0x44, W: []byte{byte(cmd >> 8), byte(cmd)}},
+			i2ctest.IO{Addr: 0x44, R: []byte{0x7E, 0xCC, crc8([]byte{0x7E, 0xCC})}})
+	}
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetAlert(&Alert{HighSet: l, HighClear: l, LowClear: l, LowSet: l}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := dev.Alert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The values are truncated by the device.
+	r := Limit{Temperature: 24728, Humidity: 4922}
+	if expected := (Alert{HighSet: r, HighClear: r, LowClear: r, LowSet: r}); a != expected {
+		t.Fatalf("%#v != %#v", a, expected)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncodeLimit(t *testing.T) {
+	if v := encodeLimit(Limit{Temperature: -50000, Humidity: -1}); v != 0 {
+		t.Fatalf("%#x", v)
+	}
+	if v := encodeLimit(Limit{Temperature: 200000, Humidity: 20000}); v != 0xFFFF {
+		t.Fatalf("%#x", v)
+	}
+}
+
+func TestCRC8(t *testing.T) {
+	// Example from the datasheet.
+	if c := crc8([]byte{0xBE, 0xEF}); c != 0x92 {
+		t.Fatalf("%#x", c)
+	}
+}
+
+func TestChooseRate(t *testing.T) {
+	data := []struct {
+		d        time.Duration
+		expected int
+	}{
+		{5 * time.Second, 0},
+		{time.Second, 1},
+		{500 * time.Millisecond, 2},
+		{300 * time.Millisecond, 3},
+		{100 * time.Millisecond, 4},
+	}
+	for i, line := range data {
+		if v := chooseRate(line.d); v != line.expected {
+			t.Fatalf("#%d: %d != %d", i, v, line.expected)
+		}
+	}
+}
+
+func TestRepeatability(t *testing.T) {
+	if s := Medium.String(); s != "Medium" {
+		t.Fatal(s)
+	}
+	if s := Repeatability(3).String(); s != "Repeatability(3)" {
+		t.Fatal(s)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package sht4x controls a Sensirion SHT40/SHT41/SHT45 humidity and
+// temperature sensor over I²C.
+//
+// The SHT4x only supports single shot measurements; SenseContinuous() polls the
+// device at the requested interval. All the data read from the device is CRC
+// checked.
+//
+// Datasheet
+//
+// https://www.sensirion.com/fileadmin/user_upload/customers/sensirion/Dokumente/2_Humidity_Sensors/Datasheets/Sensirion_Humidity_Sensors_SHT4x_Datasheet.pdf
+package sht4x
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Precision determines the measurement duration and noise level.
+type Precision uint8
+
+// Possible precision values.
+const (
+	High   Precision = 0
+	Medium Precision = 1
+	Low    Precision = 2
+)
+
+const precisionName = "HighMediumLow"
+
+var precisionIndex = [...]uint8{0, 4, 10, 13}
+
+func (p Precision) String() string {
+	if p >= Precision(len(precisionIndex)-1) {
+		return fmt.Sprintf("Precision(%d)", p)
+	}
+	return precisionName[precisionIndex[p]:precisionIndex[p+1]]
+}
+
+// Heater is a heater power and duration setting.
+//
+// The heater is turned off automatically by the device at the end of the
+// pulse and a high precision measurement is then returned.
+type Heater uint8
+
+// Possible heater settings.
+const (
+	H200mW1s    Heater = 0x39
+	H200mW100ms Heater = 0x32
+	H110mW1s    Heater = 0x2F
+	H110mW100ms Heater = 0x24
+	H20mW1s     Heater = 0x1E
+	H20mW100ms  Heater = 0x15
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Precision Precision
+}
+
+// Dev is a handle to a SHT4x device.
+type Dev struct {
+	c      conn.Conn
+	opts   Opts
+	serial uint32
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a SHT4x.
+//
+// The address is 0x44 for the SHT4x-A variants, 0x45 for the SHT4x-B and 0x46
+// for the SHT4x-C.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x44, 0x45, 0x46:
+	default:
+		return nil, errors.New("sht4x: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Precision > Low {
+		return nil, fmt.Errorf("sht4x: invalid precision %d", opts.Precision)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}, opts: *opts}
+	// Reading the serial number verifies the device is present.
+	var r [6]byte
+	if err := d.read(cmdSerial, time.Millisecond, r[:]); err != nil {
+		return nil, err
+	}
+	d.serial = uint32(r[0])<<24 | uint32(r[1])<<16 | uint32(r[3])<<8 | uint32(r[4])
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("SHT4x{%s}", d.c)
+}
+
+// SerialNumber returns the unique serial number of the device.
+func (d *Dev) SerialNumber() uint32 {
+	return d.serial
+}
+
+// Sense requests a one time measurement as °C and % of relative humidity.
+func (d *Dev) Sense(env *devices.Environment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("sht4x: already sensing continuously")
+	}
+	return d.measure(cmdMeasure[d.opts.Precision], measDuration[d.opts.Precision], env)
+}
+
+// SenseContinuous returns measurements as °C and % of relative humidity on a
+// continuous basis.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Environment, error) {
+	if interval < measDuration[d.opts.Precision] {
+		return nil, errors.New("sht4x: interval is shorter than measurement duration")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Environment)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Heat activates the internal heater for a pulse then returns the high
+// precision measurement taken right before the heater is turned off.
+//
+// The heater can be used to evaporate condensation or to verify the sensor's
+// plausibility. It must not be used for more than 10% of the time.
+func (d *Dev) Heat(h Heater, env *devices.Environment) error {
+	var duration time.Duration
+	switch h {
+	case H200mW1s, H110mW1s, H20mW1s:
+		duration = 1100 * time.Millisecond
+	case H200mW100ms, H110mW100ms, H20mW100ms:
+		duration = 110 * time.Millisecond
+	default:
+		return fmt.Errorf("sht4x: invalid heater setting %#x", uint8(h))
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("sht4x: already sensing continuously")
+	}
+	return d.measure(byte(h), duration, env)
+}
+
+// Metrics implements devices.EnvironmentalMetrics.
+func (d *Dev) Metrics() devices.Metric {
+	return devices.MetricTemperature | devices.MetricHumidity
+}
+
+// Halt stops the SHT4x from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+const cmdSerial = 0x89
+
+// cmdMeasure is indexed by Precision.
+var cmdMeasure = [...]byte{0xFD, 0xF6, 0xE0}
+
+// measDuration is indexed by Precision; page 9, max values.
+var measDuration = [...]time.Duration{
+	8300 * time.Microsecond,
+	4500 * time.Microsecond,
+	1700 * time.Microsecond,
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Environment, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var e devices.Environment
+		d.mu.Lock()
+		err := d.measure(cmdMeasure[d.opts.Precision], measDuration[d.opts.Precision], &e)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- e:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) measure(cmd byte, wait time.Duration, env *devices.Environment) error {
+	var b [6]byte
+	if err := d.read(cmd, wait, b[:]); err != nil {
+		return err
+	}
+	env.Temperature = rawToTemp(uint16(b[0])<<8 | uint16(b[1]))
+	env.Humidity = rawToHumidity(uint16(b[3])<<8 | uint16(b[4]))
+	env.Time = time.Now()
+	env.Quality = 0
+	if env.Humidity == 0 || env.Humidity == 10000 {
+		// The humidity was clamped.
+		env.Quality = devices.QualitySaturated
+	}
+	return nil
+}
+
+// read sends a command, waits for its completion then reads the reply,
+// checking the CRC of each word.
+func (d *Dev) read(cmd byte, wait time.Duration, b []byte) error {
+	if err := d.c.Tx([]byte{cmd}, nil); err != nil {
+		return fmt.Errorf("sht4x: %v", err)
+	}
+	time.Sleep(wait)
+	if err := d.c.Tx(nil, b); err != nil {
+		return fmt.Errorf("sht4x: %v", err)
+	}
+	for i := 0; i < len(b); i += 3 {
+		if crc8(b[i:i+2]) != b[i+2] {
+			return errors.New("sht4x: invalid CRC")
+		}
+	}
+	return nil
+}
+
+// rawToTemp converts a raw value to °C; page 12.
+func rawToTemp(raw uint16) devices.Celsius {
+	return devices.Celsius(-45000 + (175000*int64(raw)+32767)/65535)
+}
+
+// rawToHumidity converts a raw value to %rH; page 12.
+//
+// The formula can return values outside of 0~100% which are clamped.
+func rawToHumidity(raw uint16) devices.RelativeHumidity {
+	h := -600 + (12500*int64(raw)+32767)/65535
+	if h < 0 {
+		return 0
+	}
+	if h > 10000 {
+		return 10000
+	}
+	return devices.RelativeHumidity(h)
+}
+
+// crc8 calculates the CRC with polynomial 0x31 and initialization 0xFF.
+func crc8(b []byte) byte {
+	crc := byte(0xFF)
+	for _, v := range b {
+		crc ^= v
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+var defaults = Opts{
+	Precision: High,
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.EnvironmentalMetrics = &Dev{}
+var _ fmt.Stringer = &Dev{}
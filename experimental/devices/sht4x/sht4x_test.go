@@ -0,0 +1,226 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sht4x
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x44, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize sht4x: %v", err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%8s %9s\n", env.Temperature, env.Humidity)
+}
+
+//
+
+// 25°C, 56.5%rH.
+var measurement = []byte{0x66, 0x66, 0x93, 0x80, 0x00, 0xA2}
+
+func initOps(addr uint16) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: addr, W: []byte{0x89}},
+		{Addr: addr, R: []byte{0x12, 0x34, 0x37, 0x56, 0x78, 0x7D}},
+	}
+}
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x46),
+			i2ctest.IO{Addr: 0x46, W: []byte{0xE0}},
+			i2ctest.IO{Addr: 0x46, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x46, &Opts{Precision: Low})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "SHT4x{playback(70)}" {
+		t.Fatal(s)
+	}
+	if s := dev.SerialNumber(); s != 0x12345678 {
+		t.Fatalf("%#x", s)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Time.IsZero() {
+		t.Fatal("missing capture time")
+	}
+	if expected := (devices.Environment{Temperature: 25000, Humidity: 5650, Time: env.Time}); env != expected {
+		t.Fatalf("%#v != %#v", env, expected)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSense_saturated(t *testing.T) {
+	saturated := []byte{0x66, 0x66, 0x93, 0xFF, 0xFF, crc8([]byte{0xFF, 0xFF})}
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0xFD}},
+			i2ctest.IO{Addr: 0x44, R: saturated},
+			i2ctest.IO{Addr: 0x44, W: []byte{0xFD}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err != nil || env.Humidity != 10000 || env.Quality != devices.QualitySaturated {
+		t.Fatal(env, err)
+	}
+	if err := dev.Sense(&env); err != nil || env.Quality != 0 {
+		t.Fatal(env, err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x40, nil); err == nil {
+		t.Fatal("bad addr")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x44, &Opts{Precision: 3}); err == nil {
+		t.Fatal("bad precision")
+	}
+	bus := i2ctest.Playback{DontPanic: true}
+	if _, err := NewI2C(&bus, 0x44, nil); err == nil {
+		t.Fatal("write failed")
+	}
+	bus = i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x44, W: []byte{0x89}},
+			{Addr: 0x44, R: []byte{0x12, 0x34, 0x37, 0x56, 0x78, 0x00}},
+		},
+	}
+	if _, err := NewI2C(&bus, 0x44, nil); err == nil {
+		t.Fatal("bad CRC")
+	}
+	bus = i2ctest.Playback{
+		Ops:       []i2ctest.IO{{Addr: 0x44, W: []byte{0x89}}},
+		DontPanic: true,
+	}
+	if _, err := NewI2C(&bus, 0x44, nil); err == nil {
+		t.Fatal("read failed")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0xFD}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+			i2ctest.IO{Addr: 0x44, W: []byte{0xFD}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if env := <-c; env.Temperature != 25000 || env.Humidity != 5650 {
+			t.Fatal(env)
+		}
+	}
+	var env devices.Environment
+	if err := dev.Sense(&env); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Heat(H20mW100ms, &env); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeat(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(initOps(0x44),
+			i2ctest.IO{Addr: 0x44, W: []byte{0x32}},
+			i2ctest.IO{Addr: 0x44, R: measurement},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x44, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env devices.Environment
+	if err := dev.Heat(Heater(0), &env); err == nil {
+		t.Fatal("invalid heater")
+	}
+	if err := dev.Heat(H200mW100ms, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Temperature != 25000 {
+		t.Fatal(env)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRawToHumidity(t *testing.T) {
+	if h := rawToHumidity(0); h != 0 {
+		t.Fatal(h)
+	}
+	if h := rawToHumidity(0xFFFF); h != 10000 {
+		t.Fatal(h)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	if s := Low.String(); s != "Low" {
+		t.Fatal(s)
+	}
+	if s := Precision(3).String(); s != "Precision(3)" {
+		t.Fatal(s)
+	}
+}
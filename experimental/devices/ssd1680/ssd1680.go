@@ -0,0 +1,532 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ssd1680 controls a black and white e-paper panel driven by a Solomon
+// Systech SSD1680 or an UltraChip UC8151 controller over 4-wire SPI, like the
+// Waveshare and GoodDisplay 2.13" and 2.9" modules.
+//
+// A full refresh flashes the panel to remove any ghosting and takes a few
+// seconds. A partial refresh is faster and doesn't flash but leaves some
+// ghosting; alternate with a full refresh regularly.
+//
+// Datasheet
+//
+// https://cdn-learn.adafruit.com/assets/assets/000/097/631/original/SSD1680_Datasheet.pdf
+//
+// https://www.orientdisplay.com/wp-content/uploads/2022/09/UC8151C.pdf
+package ssd1680
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/devices/ssd1306/image1bit"
+)
+
+// Model is the display controller.
+type Model uint8
+
+// Supported controllers.
+const (
+	SSD1680 Model = 0
+	UC8151  Model = 1
+)
+
+const modelName = "SSD1680UC8151"
+
+var modelIndex = [...]uint8{0, 7, 13}
+
+func (m Model) String() string {
+	if m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// Refresh is the waveform used to update the panel.
+type Refresh uint8
+
+// Valid Refresh values.
+const (
+	// Full flashes the panel and removes any ghosting.
+	Full Refresh = 0
+	// Partial updates the panel without flashing.
+	//
+	// On the UC8151, only the area drawn is refreshed.
+	Partial Refresh = 1
+)
+
+const refreshName = "FullPartial"
+
+var refreshIndex = [...]uint8{0, 4, 11}
+
+func (r Refresh) String() string {
+	if r >= Refresh(len(refreshIndex)-1) {
+		return fmt.Sprintf("Refresh(%d)", r)
+	}
+	return refreshName[refreshIndex[r]:refreshIndex[r+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Model Model
+	// Width and Height are the size of the panel in its native portrait
+	// orientation. They default to 122x250 for the SSD1680 and 128x296 for
+	// the UC8151.
+	Width, Height int
+	// Invert must be set for panels that show a negative image.
+	Invert bool
+	// PartialLUT is the optional 153 bytes waveform used for partial refresh
+	// on the SSD1680, as provided by the panel manufacturer. Without it, the
+	// display mode 2 waveform stored in the OTP of the panel is used.
+	PartialLUT []byte
+}
+
+// Dev is an open handle to the display controller.
+type Dev struct {
+	c      conn.Conn
+	dc     gpio.PinOut
+	rst    gpio.PinOut
+	busy   gpio.PinIn
+	opts   Opts
+	rect   image.Rectangle
+	stride int
+	chunk  int
+
+	buf     []byte // 1 bit per pixel, horizontal MSB first, 1 is white.
+	refresh Refresh
+	halted  bool
+	err     error
+}
+
+// NewSPI returns a Dev object that communicates over SPI to a SSD1680 or
+// UC8151 e-paper display controller.
+//
+// dc is the data/command select pin, rst the reset pin and busy the busy
+// output of the controller. The reset pin is needed to wake up the
+// controller from deep sleep.
+//
+// The panel isn't cleared; draw on it to update it.
+func NewSPI(p spi.Port, dc, rst gpio.PinOut, busy gpio.PinIn, opts *Opts) (*Dev, error) {
+	if dc == nil || dc == gpio.INVALID || rst == nil || rst == gpio.INVALID {
+		return nil, errors.New("ssd1680: dc and rst pins are required")
+	}
+	if busy == nil || busy == gpio.INVALID {
+		return nil, errors.New("ssd1680: busy pin is required")
+	}
+	if opts == nil {
+		opts = &Opts{}
+	}
+	o := *opts
+	if o.Model > UC8151 {
+		return nil, fmt.Errorf("ssd1680: invalid model %d", o.Model)
+	}
+	if o.Width == 0 && o.Height == 0 {
+		o.Width, o.Height = defaultSize[o.Model].X, defaultSize[o.Model].Y
+	}
+	ram := ramSize[o.Model]
+	if o.Width <= 0 || o.Height <= 0 || o.Width > ram.X || o.Height > ram.Y {
+		return nil, fmt.Errorf("ssd1680: invalid size %dx%d", o.Width, o.Height)
+	}
+	if len(o.PartialLUT) != 0 {
+		if o.Model != SSD1680 {
+			return nil, fmt.Errorf("ssd1680: custom LUT is not supported on %s", o.Model)
+		}
+		if len(o.PartialLUT) != lutSize {
+			return nil, fmt.Errorf("ssd1680: invalid LUT length %d; expected %d", len(o.PartialLUT), lutSize)
+		}
+	}
+	if err := busy.In(gpio.Float, gpio.NoEdge); err != nil {
+		return nil, fmt.Errorf("ssd1680: %v", err)
+	}
+	c, err := p.Connect(4000000, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("ssd1680: %v", err)
+	}
+	d := &Dev{
+		c:      c,
+		dc:     dc,
+		rst:    rst,
+		busy:   busy,
+		opts:   o,
+		rect:   image.Rect(0, 0, o.Width, o.Height),
+		stride: (o.Width + 7) / 8,
+		chunk:  4096,
+	}
+	if l, ok := c.(conn.Limits); ok {
+		if s := l.MaxTxSize(); s > 0 && s < d.chunk {
+			d.chunk = s
+		}
+	}
+	d.buf = make([]byte, d.stride*o.Height)
+	for i := range d.buf {
+		d.buf[i] = 0xFF
+	}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s, %s, %s}", d.opts.Model, d.c, d.dc, d.rect.Max)
+}
+
+// ColorModel implements devices.Display.
+//
+// It is a 1 bit color model; colors are converted to black or white.
+func (d *Dev) ColorModel() color.Model {
+	return image1bit.BitModel
+}
+
+// Bounds implements devices.Display. Min is guaranteed to be {0, 0}.
+func (d *Dev) Bounds() image.Rectangle {
+	return d.rect
+}
+
+// Draw implements devices.Display.
+//
+// It draws synchronously with the current refresh mode; once this function
+// returns, the panel is updated.
+//
+// It discards any failure; use Err() to retrieve it.
+func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
+	clip := r.Intersect(d.rect)
+	if clip.Empty() {
+		return
+	}
+	sp = sp.Add(clip.Min.Sub(r.Min))
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		for x := clip.Min.X; x < clip.Max.X; x++ {
+			c := image1bit.BitModel.Convert(src.At(sp.X+x-clip.Min.X, sp.Y+y-clip.Min.Y)).(image1bit.Bit)
+			d.set(x, y, c)
+		}
+	}
+	d.err = d.update(clip)
+}
+
+// Err returns the last error that occurred in Draw().
+func (d *Dev) Err() error {
+	return d.err
+}
+
+// Write writes a buffer of pixels to the whole display.
+//
+// Each pixel is 1 bit, 1 being white, packed horizontally MSB first. Each row
+// is padded to a whole byte.
+func (d *Dev) Write(pixels []byte) (int, error) {
+	if len(pixels) != len(d.buf) {
+		return 0, fmt.Errorf("ssd1680: invalid pixel stream length; expected %d bytes, got %d bytes", len(d.buf), len(pixels))
+	}
+	copy(d.buf, pixels)
+	if err := d.update(d.rect); err != nil {
+		return 0, err
+	}
+	return len(pixels), nil
+}
+
+// Fill sets the whole display to black or white.
+func (d *Dev) Fill(c color.Color) error {
+	v := byte(0)
+	if image1bit.BitModel.Convert(c).(image1bit.Bit) {
+		v = 0xFF
+	}
+	for i := range d.buf {
+		d.buf[i] = v
+	}
+	return d.update(d.rect)
+}
+
+// SetRefresh selects the waveform used by the next updates.
+func (d *Dev) SetRefresh(r Refresh) error {
+	if r > Partial {
+		return fmt.Errorf("ssd1680: invalid refresh %d", r)
+	}
+	d.refresh = r
+	return nil
+}
+
+// Halt puts the controller in deep sleep. The panel keeps its image without
+// power.
+//
+// Sending any pixel afterward resets the controller to wake it up.
+func (d *Dev) Halt() error {
+	if d.halted {
+		return nil
+	}
+	if d.opts.Model == SSD1680 {
+		if err := d.command(0x10, 0x01); err != nil {
+			return err
+		}
+	} else {
+		// Floating border, power off then deep sleep.
+		if err := d.command(0x50, 0xF7); err != nil {
+			return err
+		}
+		if err := d.command(0x02); err != nil {
+			return err
+		}
+		if err := d.waitBusy(); err != nil {
+			return err
+		}
+		if err := d.command(0x07, 0xA5); err != nil {
+			return err
+		}
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	// lutSize is the size of the SSD1680 waveform LUT.
+	lutSize = 153
+	// busyPoll and busyTimeout are the polling interval and the maximum wait
+	// time of the busy pin. A full refresh takes up to 4s at low
+	// temperature.
+	busyPoll    = 10 * time.Millisecond
+	busyTimeout = 20 * time.Second
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+var ramSize = [...]image.Point{{176, 296}, {160, 296}}
+var defaultSize = [...]image.Point{{122, 250}, {128, 296}}
+
+// busyLevel is the level of the busy pin while the controller is busy.
+var busyLevel = [...]gpio.Level{gpio.High, gpio.Low}
+
+func (d *Dev) init() error {
+	if err := d.rst.Out(gpio.Low); err != nil {
+		return fmt.Errorf("ssd1680: %v", err)
+	}
+	sleep(10 * time.Millisecond)
+	if err := d.rst.Out(gpio.High); err != nil {
+		return fmt.Errorf("ssd1680: %v", err)
+	}
+	sleep(10 * time.Millisecond)
+	if err := d.waitBusy(); err != nil {
+		return err
+	}
+	if d.opts.Model == SSD1680 {
+		return d.initSSD1680()
+	}
+	return d.initUC8151()
+}
+
+func (d *Dev) initSSD1680() error {
+	// Software reset.
+	if err := d.command(0x12); err != nil {
+		return err
+	}
+	if err := d.waitBusy(); err != nil {
+		return err
+	}
+	h := d.opts.Height - 1
+	invert := byte(0)
+	if d.opts.Invert {
+		invert = 0x08
+	}
+	cmds := [][]byte{
+		{0x01, byte(h), byte(h >> 8), 0x00},       // Driver output control.
+		{0x11, 0x03},                              // Data entry mode: X then Y increment.
+		{0x44, 0x00, byte(d.stride - 1)},          // RAM X window, in bytes.
+		{0x45, 0x00, 0x00, byte(h), byte(h >> 8)}, // RAM Y window.
+		{0x3C, 0x05},                              // Border waveform.
+		{0x21, invert, 0x80},                      // Display update control 1.
+		{0x18, 0x80},                              // Internal temperature sensor.
+	}
+	for _, c := range cmds {
+		if err := d.command(c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	return d.waitBusy()
+}
+
+func (d *Dev) initUC8151() error {
+	cdi := byte(0x97)
+	if d.opts.Invert {
+		cdi = 0x87
+	}
+	cmds := [][]byte{
+		{0x06, 0x17, 0x17, 0x17}, // Booster soft start.
+		{0x00, 0x1F},             // Panel setting: OTP LUT, black and white.
+		{0x61, byte(d.opts.Width), byte(d.opts.Height >> 8), byte(d.opts.Height)}, // Resolution.
+		{0x50, cdi}, // VCOM and data interval, white border.
+		{0x04},      // Power on.
+	}
+	for _, c := range cmds {
+		if err := d.command(c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	return d.waitBusy()
+}
+
+// update sends the frame buffer and refreshes the panel.
+//
+// r is the area that changed.
+func (d *Dev) update(r image.Rectangle) error {
+	if d.halted {
+		// Transparently wake up the controller.
+		if err := d.init(); err != nil {
+			return err
+		}
+		d.halted = false
+	}
+	if d.opts.Model == SSD1680 {
+		return d.updateSSD1680()
+	}
+	return d.updateUC8151(r)
+}
+
+func (d *Dev) updateSSD1680() error {
+	// The new image goes in the black and white RAM.
+	if err := d.writeRAM(0x24); err != nil {
+		return err
+	}
+	ctl := byte(0xF7)
+	if d.refresh == Partial {
+		if len(d.opts.PartialLUT) != 0 {
+			if err := d.command(0x32, d.opts.PartialLUT...); err != nil {
+				return err
+			}
+			// Keep the LUT loaded.
+			ctl = 0xC7
+		} else {
+			// Display mode 2.
+			ctl = 0xFF
+		}
+	}
+	if err := d.command(0x22, ctl); err != nil {
+		return err
+	}
+	if err := d.command(0x20); err != nil {
+		return err
+	}
+	if err := d.waitBusy(); err != nil {
+		return err
+	}
+	// The partial refresh waveform uses the red RAM as the previous image.
+	return d.writeRAM(0x26)
+}
+
+func (d *Dev) writeRAM(cmd byte) error {
+	if err := d.command(0x4E, 0x00); err != nil {
+		return err
+	}
+	if err := d.command(0x4F, 0x00, 0x00); err != nil {
+		return err
+	}
+	return d.command(cmd, d.buf...)
+}
+
+func (d *Dev) updateUC8151(r image.Rectangle) error {
+	if d.refresh == Full {
+		if err := d.command(0x13, d.buf...); err != nil {
+			return err
+		}
+		return d.refreshUC8151()
+	}
+	// The partial window is aligned on bytes horizontally.
+	x0, x1 := r.Min.X&^7, (r.Max.X-1)|7
+	y0, y1 := r.Min.Y, r.Max.Y-1
+	if err := d.command(0x91); err != nil {
+		return err
+	}
+	if err := d.command(0x90, byte(x0), byte(x1), byte(y0>>8), byte(y0), byte(y1>>8), byte(y1), 0x01); err != nil {
+		return err
+	}
+	var b []byte
+	for y := y0; y <= y1; y++ {
+		o := y * d.stride
+		b = append(b, d.buf[o+x0/8:o+x1/8+1]...)
+	}
+	if err := d.command(0x13, b...); err != nil {
+		return err
+	}
+	if err := d.refreshUC8151(); err != nil {
+		return err
+	}
+	return d.command(0x92)
+}
+
+func (d *Dev) refreshUC8151() error {
+	if err := d.command(0x12); err != nil {
+		return err
+	}
+	// The busy pin only goes low after a short while.
+	sleep(time.Millisecond)
+	return d.waitBusy()
+}
+
+// waitBusy polls the busy pin until the controller is ready.
+func (d *Dev) waitBusy() error {
+	l := busyLevel[d.opts.Model]
+	for i := time.Duration(0); d.busy.Read() == l; i += busyPoll {
+		if i >= busyTimeout {
+			return errors.New("ssd1680: timed out waiting for the controller")
+		}
+		sleep(busyPoll)
+	}
+	return nil
+}
+
+// set sets a pixel in the frame buffer.
+func (d *Dev) set(x, y int, c image1bit.Bit) {
+	i := y*d.stride + x/8
+	m := byte(0x80) >> uint(x&7)
+	if c {
+		d.buf[i] |= m
+	} else {
+		d.buf[i] &^= m
+	}
+}
+
+// command sends a command byte and its parameters.
+func (d *Dev) command(c byte, params ...byte) error {
+	if err := d.dc.Out(gpio.Low); err != nil {
+		return fmt.Errorf("ssd1680: %v", err)
+	}
+	if err := d.c.Tx([]byte{c}, nil); err != nil {
+		return fmt.Errorf("ssd1680: %v", err)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return d.data(params)
+}
+
+// data sends data bytes, in chunks not larger than the maximum transaction
+// size of the SPI port.
+func (d *Dev) data(b []byte) error {
+	if err := d.dc.Out(gpio.High); err != nil {
+		return fmt.Errorf("ssd1680: %v", err)
+	}
+	for len(b) != 0 {
+		n := len(b)
+		if n > d.chunk {
+			n = d.chunk
+		}
+		if err := d.c.Tx(b[:n], nil); err != nil {
+			return fmt.Errorf("ssd1680: %v", err)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.Display = &Dev{}
+var _ fmt.Stringer = &Dev{}
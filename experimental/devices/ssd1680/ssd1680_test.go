@@ -0,0 +1,565 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ssd1680
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/devices/ssd1306/image1bit"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dc := gpioreg.ByName("GPIO25")
+	rst := gpioreg.ByName("GPIO17")
+	busy := gpioreg.ByName("GPIO24")
+	dev, err := NewSPI(p, dc, rst, busy, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize ssd1680: %v", err)
+	}
+	defer dev.Halt()
+	img := image1bit.NewVerticalLSB(dev.Bounds())
+	draw.Draw(img, image.Rect(10, 10, 60, 60), &image.Uniform{image1bit.On}, image.Point{}, draw.Src)
+	dev.Draw(img.Bounds(), img, image.Point{})
+	if err := dev.Err(); err != nil {
+		log.Fatal(err)
+	}
+	// Update the panel faster, without flashing.
+	if err := dev.SetRefresh(Partial); err != nil {
+		log.Fatal(err)
+	}
+	draw.Draw(img, image.Rect(20, 20, 50, 50), &image.Uniform{image1bit.Off}, image.Point{}, draw.Src)
+	dev.Draw(img.Bounds(), img, image.Point{})
+	if err := dev.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewSPI_SSD1680(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	rst := &gpiotest.Pin{N: "RST"}
+	busy := &busyPin{busy: gpio.High, n: 3}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, rst, busy, &Opts{Width: 12, Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "SSD1680{fake, DC(0), (12,3)}" {
+		t.Fatal(s)
+	}
+	if rst.L != gpio.High {
+		t.Fatal("reset")
+	}
+	if busy.n != 0 {
+		t.Fatal("busy wait")
+	}
+	if p.maxHz != 4000000 {
+		t.Fatal(p.maxHz)
+	}
+	if d.ColorModel() != image1bit.BitModel {
+		t.Fatal("color model")
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 12, 3) {
+		t.Fatal(r)
+	}
+	p.check(t, ssd1680Init(12, 3, 0x00))
+
+	// Full refresh.
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	img.Set(1, 0, color.Black)
+	d.Draw(image.Rect(8, 1, 20, 3), img, image.Point{})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	frame := []byte{0xFF, 0xFF, 0xFF, 0xBF, 0xFF, 0xFF}
+	p.check(t, ssd1680Update(frame, 0xF7))
+
+	// Partial refresh with the OTP waveform.
+	if err := d.SetRefresh(Partial); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Fill(color.Black); err != nil {
+		t.Fatal(err)
+	}
+	frame = make([]byte, 6)
+	p.check(t, ssd1680Update(frame, 0xFF))
+
+	frame = []byte{1, 2, 3, 4, 5, 6}
+	if n, err := d.Write(frame); n != 6 || err != nil {
+		t.Fatal(n, err)
+	}
+	p.check(t, ssd1680Update(frame, 0xFF))
+
+	// Deep sleep, then wake up on the next update.
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, cmd(0x10, 0x01))
+	if err := d.Fill(color.White); err != nil {
+		t.Fatal(err)
+	}
+	frame = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	p.check(t, append(ssd1680Init(12, 3, 0x00), ssd1680Update(frame, 0xFF)...))
+}
+
+func TestNewSPI_SSD1680_LUT(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	p := &fakePort{dc: dc, maxTxSize: 100}
+	lut := make([]byte, 153)
+	for i := range lut {
+		lut[i] = byte(i)
+	}
+	d, err := NewSPI(p, dc, &gpiotest.Pin{}, &gpiotest.Pin{}, &Opts{Width: 12, Height: 3, Invert: true, PartialLUT: lut})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, ssd1680Init(12, 3, 0x08))
+	if err := d.SetRefresh(Partial); err != nil {
+		t.Fatal(err)
+	}
+	d.Draw(d.Bounds(), &image.Uniform{color.White}, image.Point{})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// The LUT is sent in chunks and kept loaded for the refresh.
+	frame := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	expected := ssd1680Update(frame, 0xC7)
+	expected = append(expected[:6], append([]op{{cmd: true, w: []byte{0x32}}, {w: lut[:100]}, {w: lut[100:]}}, expected[6:]...)...)
+	p.check(t, expected)
+}
+
+func TestNewSPI_SSD1680_default(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, &gpiotest.Pin{}, &gpiotest.Pin{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 122, 250) {
+		t.Fatal(r)
+	}
+	p.check(t, ssd1680Init(122, 250, 0x00))
+}
+
+func TestNewSPI_UC8151(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	rst := &gpiotest.Pin{N: "RST"}
+	busy := &busyPin{busy: gpio.Low, n: 2}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, rst, busy, &Opts{Model: UC8151, Width: 16, Height: 260})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "UC8151{fake, DC(0), (16,260)}" {
+		t.Fatal(s)
+	}
+	p.check(t, uc8151Init(16, 260, 0x97))
+
+	// Full refresh.
+	frame := make([]byte, 2*260)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+	if _, err := d.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	expected := cmd(0x13, frame...)
+	expected = append(expected, cmd(0x12)...)
+	p.check(t, expected)
+
+	// Partial refresh, aligned on bytes horizontally.
+	if err := d.SetRefresh(Partial); err != nil {
+		t.Fatal(err)
+	}
+	d.Draw(image.Rect(9, 257, 11, 259), &image.Uniform{color.Black}, image.Point{})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected = cmd(0x91)
+	expected = append(expected, cmd(0x90, 8, 15, 0x01, 0x01, 0x01, 0x02, 0x01)...)
+	expected = append(expected, cmd(0x13, frame[2*257+1]&^0x60, frame[2*258+1]&^0x60)...)
+	expected = append(expected, cmd(0x12)...)
+	expected = append(expected, cmd(0x92)...)
+	p.check(t, expected)
+
+	// Deep sleep.
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	expected = cmd(0x50, 0xF7)
+	expected = append(expected, cmd(0x02)...)
+	expected = append(expected, cmd(0x07, 0xA5)...)
+	p.check(t, expected)
+	if err := d.SetRefresh(Full); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Fill(color.White); err != nil {
+		t.Fatal(err)
+	}
+	expected = uc8151Init(16, 260, 0x97)
+	expected = append(expected, cmd(0x13, bytes.Repeat([]byte{0xFF}, 2*260)...)...)
+	expected = append(expected, cmd(0x12)...)
+	p.check(t, expected)
+}
+
+func TestNewSPI_UC8151_invert(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	busy := &gpiotest.Pin{L: gpio.High}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, &gpiotest.Pin{}, busy, &Opts{Model: UC8151, Invert: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 128, 296) {
+		t.Fatal(r)
+	}
+	p.check(t, uc8151Init(128, 296, 0x87))
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	p := &fakePort{dc: dc}
+	if _, err := NewSPI(p, nil, &gpiotest.Pin{}, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("dc is required")
+	}
+	if _, err := NewSPI(p, dc, gpio.INVALID, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("rst is required")
+	}
+	if _, err := NewSPI(p, dc, &gpiotest.Pin{}, nil, nil); err == nil {
+		t.Fatal("busy is required")
+	}
+	data := []Opts{
+		{Model: 2},
+		{Width: 177, Height: 296},
+		{Model: UC8151, Width: 176, Height: 296},
+		{Width: 10},
+		{Width: -1, Height: 10},
+		{PartialLUT: make([]byte, 10)},
+		{Model: UC8151, PartialLUT: make([]byte, 153)},
+	}
+	for i, opts := range data {
+		if _, err := NewSPI(&fakePort{dc: dc}, dc, &gpiotest.Pin{}, &gpiotest.Pin{}, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := NewSPI(&fakePort{dc: dc, connectErr: true}, dc, &gpiotest.Pin{}, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &failPin{}, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("rst failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &failPin{after: 1}, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("rst failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, &failPin{}, &gpiotest.Pin{}, &gpiotest.Pin{}, nil); err == nil {
+		t.Fatal("dc failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &gpiotest.Pin{}, &gpiotest.Pin{L: gpio.High}, nil); err == nil {
+		t.Fatal("busy timeout")
+	}
+	// Fail at each successive transaction of the initialization.
+	for _, m := range []Model{SSD1680, UC8151} {
+		busy := &gpiotest.Pin{}
+		if m == UC8151 {
+			busy.L = gpio.High
+		}
+		n := len(ssd1680Init(122, 250, 0))
+		if m == UC8151 {
+			n = len(uc8151Init(128, 296, 0))
+		}
+		for i := 0; i < n; i++ {
+			if _, err := NewSPI(&fakePort{dc: dc, txErrAfter: i + 1}, dc, &gpiotest.Pin{}, busy, &Opts{Model: m}); err == nil {
+				t.Fatalf("%s #%d: tx failed", m, i)
+			}
+		}
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &gpiotest.Pin{}, &failPin{}, nil); err == nil {
+		t.Fatal("busy failed")
+	}
+}
+
+func TestDraw_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	for _, m := range []Model{SSD1680, UC8151} {
+		for _, r := range []Refresh{Full, Partial} {
+			for i := 0; ; i++ {
+				busy := &gpiotest.Pin{}
+				if m == UC8151 {
+					busy.L = gpio.High
+				}
+				p := &fakePort{dc: dc}
+				opts := Opts{Model: m, Width: 8, Height: 2}
+				if m == SSD1680 && r == Partial {
+					opts.PartialLUT = make([]byte, 153)
+				}
+				d, err := NewSPI(p, dc, &gpiotest.Pin{}, busy, &opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := d.SetRefresh(r); err != nil {
+					t.Fatal(err)
+				}
+				p.txErrAfter = i + 1
+				// Stop once all the transactions of the update succeed.
+				if d.Draw(d.Bounds(), &image.Uniform{color.Black}, image.Point{}); d.Err() == nil {
+					if i == 0 {
+						t.Fatalf("%s %s: tx failed", m, r)
+					}
+					break
+				}
+			}
+		}
+	}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, &gpiotest.Pin{}, &gpiotest.Pin{}, &Opts{Width: 8, Height: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Write(make([]byte, 3)); err == nil {
+		t.Fatal("invalid length")
+	}
+	p.txErrAfter = 1
+	if _, err := d.Write(make([]byte, 2)); err == nil {
+		t.Fatal("tx failed")
+	}
+	if err := d.SetRefresh(2); err == nil {
+		t.Fatal("invalid refresh")
+	}
+	// Nothing to draw.
+	p.ops = nil
+	d.Draw(image.Rect(8, 0, 10, 2), &image.Uniform{color.Black}, image.Point{})
+	if len(p.ops) != 0 {
+		t.Fatal(p.ops)
+	}
+	p.txErrAfter = 1
+	if err := d.Fill(color.White); err == nil {
+		t.Fatal("tx failed")
+	}
+	p.txErrAfter = 1
+	if err := d.Halt(); err == nil {
+		t.Fatal("tx failed")
+	}
+	// The busy pin stays busy after the refresh.
+	d.busy = &gpiotest.Pin{L: gpio.High}
+	if err := d.Fill(color.White); err == nil {
+		t.Fatal("busy timeout")
+	}
+	// Waking up fails.
+	d.halted = true
+	d.rst = &failPin{}
+	if err := d.Fill(color.White); err == nil {
+		t.Fatal("rst failed")
+	}
+}
+
+func TestHalt_UC8151_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	for i := 0; i < 4; i++ {
+		busy := &gpiotest.Pin{L: gpio.High}
+		p := &fakePort{dc: dc}
+		d, err := NewSPI(p, dc, &gpiotest.Pin{}, busy, &Opts{Model: UC8151})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 {
+			// Power off never completes.
+			busy.L = gpio.Low
+		} else {
+			p.txErrAfter = 2*i + 1
+		}
+		if err := d.Halt(); err == nil {
+			t.Fatalf("#%d: halt failed", i)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	if s := UC8151.String(); s != "UC8151" {
+		t.Fatal(s)
+	}
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+	if s := Partial.String(); s != "Partial" {
+		t.Fatal(s)
+	}
+	if s := Refresh(2).String(); s != "Refresh(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+// cmd returns the operations for a command and its parameters.
+func cmd(c byte, params ...byte) []op {
+	out := []op{{cmd: true, w: []byte{c}}}
+	if len(params) != 0 {
+		out = append(out, op{w: params})
+	}
+	return out
+}
+
+func ssd1680Init(w, h int, invert byte) []op {
+	var out []op
+	out = append(out, cmd(0x12)...)
+	out = append(out, cmd(0x01, byte(h-1), byte((h-1)>>8), 0x00)...)
+	out = append(out, cmd(0x11, 0x03)...)
+	out = append(out, cmd(0x44, 0x00, byte((w+7)/8-1))...)
+	out = append(out, cmd(0x45, 0x00, 0x00, byte(h-1), byte((h-1)>>8))...)
+	out = append(out, cmd(0x3C, 0x05)...)
+	out = append(out, cmd(0x21, invert, 0x80)...)
+	out = append(out, cmd(0x18, 0x80)...)
+	return out
+}
+
+func ssd1680Update(frame []byte, ctl byte) []op {
+	var out []op
+	out = append(out, cmd(0x4E, 0x00)...)
+	out = append(out, cmd(0x4F, 0x00, 0x00)...)
+	out = append(out, cmd(0x24, frame...)...)
+	out = append(out, cmd(0x22, ctl)...)
+	out = append(out, cmd(0x20)...)
+	out = append(out, cmd(0x4E, 0x00)...)
+	out = append(out, cmd(0x4F, 0x00, 0x00)...)
+	out = append(out, cmd(0x26, frame...)...)
+	return out
+}
+
+func uc8151Init(w, h int, cdi byte) []op {
+	var out []op
+	out = append(out, cmd(0x06, 0x17, 0x17, 0x17)...)
+	out = append(out, cmd(0x00, 0x1F)...)
+	out = append(out, cmd(0x61, byte(w), byte(h>>8), byte(h))...)
+	out = append(out, cmd(0x50, cdi)...)
+	out = append(out, cmd(0x04)...)
+	return out
+}
+
+type op struct {
+	cmd bool
+	w   []byte
+}
+
+// fakePort records the transactions along the state of the DC pin.
+type fakePort struct {
+	dc         *gpiotest.Pin
+	maxTxSize  int
+	connectErr bool
+	// txErrAfter fails the transaction number txErrAfter, counting from 1,
+	// when not 0.
+	txErrAfter int
+	maxHz      int64
+	ops        []op
+}
+
+func (f *fakePort) String() string {
+	return "fake"
+}
+
+func (f *fakePort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	if f.connectErr {
+		return nil, errors.New("injected")
+	}
+	f.maxHz = maxHz
+	return f, nil
+}
+
+func (f *fakePort) Tx(w, r []byte) error {
+	if f.txErrAfter != 0 {
+		if f.txErrAfter--; f.txErrAfter == 0 {
+			return errors.New("injected")
+		}
+	}
+	f.ops = append(f.ops, op{cmd: f.dc.Read() == gpio.Low, w: append([]byte{}, w...)})
+	return nil
+}
+
+func (f *fakePort) TxPackets(p []spi.Packet) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakePort) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+func (f *fakePort) MaxTxSize() int {
+	return f.maxTxSize
+}
+
+func (f *fakePort) check(t *testing.T, expected []op) {
+	if len(f.ops) != len(expected) {
+		t.Fatalf("%d ops, expected %d", len(f.ops), len(expected))
+	}
+	for i := range expected {
+		if f.ops[i].cmd != expected[i].cmd || !bytes.Equal(f.ops[i].w, expected[i].w) {
+			t.Fatalf("#%d: %v != %v", i, f.ops[i], expected[i])
+		}
+	}
+	f.ops = nil
+}
+
+// busyPin reads as busy n times before returning to its idle level.
+type busyPin struct {
+	gpiotest.Pin
+	busy gpio.Level
+	n    int
+}
+
+func (b *busyPin) Read() gpio.Level {
+	if b.n != 0 {
+		b.n--
+		return b.busy
+	}
+	return !b.busy
+}
+
+// failPin fails after a number of successful writes.
+type failPin struct {
+	gpiotest.Pin
+	after int
+}
+
+func (f *failPin) In(pull gpio.Pull, edge gpio.Edge) error {
+	return errors.New("injected")
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	if f.after == 0 {
+		return errors.New("injected")
+	}
+	f.after--
+	return nil
+}
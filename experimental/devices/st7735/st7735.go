@@ -0,0 +1,464 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package st7735 controls a color TFT display driven by a Sitronix ST7735 or
+// ST7789 controller over 4-wire SPI.
+//
+// The pixels are sent as 16 bits RGB565. Draw() only updates the window
+// covered by the destination rectangle, so small updates like a status bar
+// are fast even at the relatively modest SPI speeds.
+//
+// Datasheet
+//
+// https://www.displayfuture.com/Display/datasheet/controller/ST7735.pdf
+//
+// https://www.newhavendisplay.com/appnotes/datasheets/LCDs/ST7789V.pdf
+package st7735
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/devices"
+)
+
+// Model is the display controller.
+type Model uint8
+
+// Supported controllers.
+const (
+	ST7735 Model = 0 // 132x162 RAM
+	ST7789 Model = 1 // 240x320 RAM
+)
+
+const modelName = "ST7735ST7789"
+
+var modelIndex = [...]uint8{0, 6, 12}
+
+func (m Model) String() string {
+	if m >= Model(len(modelIndex)-1) {
+		return fmt.Sprintf("Model(%d)", m)
+	}
+	return modelName[modelIndex[m]:modelIndex[m+1]]
+}
+
+// Rotation is the display orientation, clockwise.
+type Rotation uint8
+
+// Possible rotations.
+const (
+	Rotate0   Rotation = 0
+	Rotate90  Rotation = 1
+	Rotate180 Rotation = 2
+	Rotate270 Rotation = 3
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Model Model
+	// Width and Height are the size of the panel in its native portrait
+	// orientation. They default to 128x160 for the ST7735 and 240x240 for the
+	// ST7789.
+	Width, Height int
+	// OffsetX and OffsetY are the position of the panel in the controller RAM,
+	// in the native orientation. Panels smaller than the RAM are often not
+	// aligned to the top left corner.
+	OffsetX, OffsetY int
+	Rotation         Rotation
+	// BGR must be set for panels with blue and red swapped.
+	BGR bool
+	// Invert must be set for panels that show a negative image. This is
+	// common with ST7789 based panels.
+	Invert bool
+}
+
+// Dev is an open handle to the display controller.
+type Dev struct {
+	c     conn.Conn
+	dc    gpio.PinOut
+	opts  Opts
+	rect  image.Rectangle
+	offX  int
+	offY  int
+	chunk int
+
+	buf    []byte
+	halted bool
+	err    error
+}
+
+// NewSPI returns a Dev object that communicates over SPI to a ST7735 or
+// ST7789 display controller.
+//
+// dc is the data/command select pin. rst is the optional reset pin; pass nil
+// if it is not connected and a software reset is done instead.
+func NewSPI(p spi.Port, dc, rst gpio.PinOut, opts *Opts) (*Dev, error) {
+	if dc == nil || dc == gpio.INVALID {
+		return nil, errors.New("st7735: dc pin is required")
+	}
+	if opts == nil {
+		opts = &Opts{}
+	}
+	o := *opts
+	if o.Model > ST7789 {
+		return nil, fmt.Errorf("st7735: invalid model %d", o.Model)
+	}
+	if o.Rotation > Rotate270 {
+		return nil, fmt.Errorf("st7735: invalid rotation %d", o.Rotation)
+	}
+	if o.Width == 0 && o.Height == 0 {
+		o.Width, o.Height = defaultSize[o.Model].X, defaultSize[o.Model].Y
+	}
+	ram := ramSize[o.Model]
+	if o.Width <= 0 || o.Height <= 0 || o.OffsetX < 0 || o.OffsetY < 0 || o.OffsetX+o.Width > ram.X || o.OffsetY+o.Height > ram.Y {
+		return nil, fmt.Errorf("st7735: invalid size %dx%d at %d,%d", o.Width, o.Height, o.OffsetX, o.OffsetY)
+	}
+	if err := dc.Out(gpio.Low); err != nil {
+		return nil, fmt.Errorf("st7735: %v", err)
+	}
+	if rst != nil {
+		if err := rst.Out(gpio.Low); err != nil {
+			return nil, fmt.Errorf("st7735: %v", err)
+		}
+		sleep(10 * time.Microsecond)
+		if err := rst.Out(gpio.High); err != nil {
+			return nil, fmt.Errorf("st7735: %v", err)
+		}
+		sleep(120 * time.Millisecond)
+	}
+	c, err := p.Connect(maxHz[o.Model], spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("st7735: %v", err)
+	}
+	d := &Dev{c: c, dc: dc, opts: o, chunk: 4096}
+	if l, ok := c.(conn.Limits); ok {
+		if s := l.MaxTxSize(); s > 0 && s < d.chunk {
+			d.chunk = s &^ 1
+		}
+	}
+	d.setRotation(o.Rotation)
+	if err := d.init(rst == nil); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s, %s, %s}", d.opts.Model, d.c, d.dc, d.rect.Max)
+}
+
+// ColorModel implements devices.Display.
+//
+// The pixels are converted to RGB565 when sent.
+func (d *Dev) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+// Bounds implements devices.Display. Min is guaranteed to be {0, 0}. The
+// size depends on the rotation.
+func (d *Dev) Bounds() image.Rectangle {
+	return d.rect
+}
+
+// Draw implements devices.Display.
+//
+// Only the pixels within r are sent to the display. It draws synchronously,
+// once this function returns, the display is updated.
+//
+// It discards any failure; use Err() to retrieve it.
+func (d *Dev) Draw(r image.Rectangle, src image.Image, sp image.Point) {
+	clip := r.Intersect(d.rect)
+	if clip.Empty() {
+		return
+	}
+	sp = sp.Add(clip.Min.Sub(r.Min))
+	r = clip
+	n := 2 * r.Dx() * r.Dy()
+	if len(d.buf) < n {
+		d.buf = make([]byte, n)
+	}
+	b := d.buf[:n]
+	i := 0
+	if img, ok := src.(*image.RGBA); ok {
+		// Fast path.
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			o := img.PixOffset(sp.X, sp.Y+y-r.Min.Y)
+			for x := r.Min.X; x < r.Max.X; x++ {
+				p := img.Pix[o : o+3 : o+3]
+				b[i], b[i+1] = rgb565(p[0], p[1], p[2])
+				i += 2
+				o += 4
+			}
+		}
+	} else {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := color.NRGBAModel.Convert(src.At(sp.X+x-r.Min.X, sp.Y+y-r.Min.Y)).(color.NRGBA)
+				b[i], b[i+1] = rgb565(c.R, c.G, c.B)
+				i += 2
+			}
+		}
+	}
+	d.err = d.drawWindow(r, b)
+}
+
+// Err returns the last error that occurred in Draw().
+func (d *Dev) Err() error {
+	return d.err
+}
+
+// Write writes a buffer of pixels to the whole display.
+//
+// Each pixel is 16 bits RGB565 in big endian, starting at the top left and
+// going row by row.
+func (d *Dev) Write(pixels []byte) (int, error) {
+	if n := 2 * d.rect.Dx() * d.rect.Dy(); len(pixels) != n {
+		return 0, fmt.Errorf("st7735: invalid pixel stream length; expected %d bytes, got %d bytes", n, len(pixels))
+	}
+	if err := d.drawWindow(d.rect, pixels); err != nil {
+		return 0, err
+	}
+	return len(pixels), nil
+}
+
+// Fill sets the whole display to a single color.
+func (d *Dev) Fill(c color.Color) error {
+	n := 2 * d.rect.Dx() * d.rect.Dy()
+	if len(d.buf) < n {
+		d.buf = make([]byte, n)
+	}
+	b := d.buf[:n]
+	v := color.NRGBAModel.Convert(c).(color.NRGBA)
+	hi, lo := rgb565(v.R, v.G, v.B)
+	for i := 0; i < n; i += 2 {
+		b[i], b[i+1] = hi, lo
+	}
+	return d.drawWindow(d.rect, b)
+}
+
+// SetRotation changes the display orientation. The content of the display is
+// not redrawn.
+func (d *Dev) SetRotation(r Rotation) error {
+	if r > Rotate270 {
+		return fmt.Errorf("st7735: invalid rotation %d", r)
+	}
+	d.setRotation(r)
+	return d.command(cmdMADCTL, d.madctl())
+}
+
+// Invert inverts the colors of the display.
+func (d *Dev) Invert(invert bool) error {
+	d.opts.Invert = invert
+	if invert {
+		return d.command(cmdINVON)
+	}
+	return d.command(cmdINVOFF)
+}
+
+// Halt turns off the display and puts the controller to sleep.
+//
+// Sending any pixel afterward reenables the display.
+func (d *Dev) Halt() error {
+	if err := d.command(cmdDISPOFF); err != nil {
+		return err
+	}
+	if err := d.command(cmdSLPIN); err != nil {
+		return err
+	}
+	d.halted = true
+	return nil
+}
+
+//
+
+const (
+	cmdSWRESET = 0x01
+	cmdSLPIN   = 0x10
+	cmdSLPOUT  = 0x11
+	cmdNORON   = 0x13
+	cmdINVOFF  = 0x20
+	cmdINVON   = 0x21
+	cmdDISPOFF = 0x28
+	cmdDISPON  = 0x29
+	cmdCASET   = 0x2A
+	cmdRASET   = 0x2B
+	cmdRAMWR   = 0x2C
+	cmdMADCTL  = 0x36
+	cmdCOLMOD  = 0x3A
+
+	madctlMY  = 0x80
+	madctlMX  = 0x40
+	madctlMV  = 0x20
+	madctlBGR = 0x08
+)
+
+// sleep is overridden in unit tests.
+var sleep = time.Sleep
+
+// ramSize, defaultSize, maxHz and colmod are indexed by Model.
+var ramSize = [...]image.Point{{132, 162}, {240, 320}}
+var defaultSize = [...]image.Point{{128, 160}, {240, 240}}
+var maxHz = [...]int64{15000000, 62500000}
+var colmod = [...]byte{0x05, 0x55}
+
+// madctlRotation is indexed by Rotation.
+var madctlRotation = [...]byte{0, madctlMX | madctlMV, madctlMX | madctlMY, madctlMY | madctlMV}
+
+// st7735Init is the panel configuration for the ST7735R: frame rate, power
+// control and VCOM, as recommended by the vendor. The ST7789 defaults are
+// fine.
+var st7735Init = [][]byte{
+	{0xB1, 0x01, 0x2C, 0x2D},
+	{0xB2, 0x01, 0x2C, 0x2D},
+	{0xB3, 0x01, 0x2C, 0x2D, 0x01, 0x2C, 0x2D},
+	{0xB4, 0x07},
+	{0xC0, 0xA2, 0x02, 0x84},
+	{0xC1, 0xC5},
+	{0xC2, 0x0A, 0x00},
+	{0xC3, 0x8A, 0x2A},
+	{0xC4, 0x8A, 0xEE},
+	{0xC5, 0x0E},
+}
+
+func (d *Dev) init(reset bool) error {
+	if reset {
+		if err := d.command(cmdSWRESET); err != nil {
+			return err
+		}
+		sleep(150 * time.Millisecond)
+	}
+	if err := d.command(cmdSLPOUT); err != nil {
+		return err
+	}
+	sleep(120 * time.Millisecond)
+	if d.opts.Model == ST7735 {
+		for _, c := range st7735Init {
+			if err := d.command(c[0], c[1:]...); err != nil {
+				return err
+			}
+		}
+	}
+	// 16 bits per pixel.
+	if err := d.command(cmdCOLMOD, colmod[d.opts.Model]); err != nil {
+		return err
+	}
+	if err := d.command(cmdMADCTL, d.madctl()); err != nil {
+		return err
+	}
+	inv := byte(cmdINVOFF)
+	if d.opts.Invert {
+		inv = cmdINVON
+	}
+	if err := d.command(inv); err != nil {
+		return err
+	}
+	if err := d.command(cmdNORON); err != nil {
+		return err
+	}
+	return d.command(cmdDISPON)
+}
+
+func (d *Dev) setRotation(r Rotation) {
+	d.opts.Rotation = r
+	o := d.opts
+	ram := ramSize[o.Model]
+	switch r {
+	case Rotate0:
+		d.rect = image.Rect(0, 0, o.Width, o.Height)
+		d.offX, d.offY = o.OffsetX, o.OffsetY
+	case Rotate90:
+		d.rect = image.Rect(0, 0, o.Height, o.Width)
+		d.offX, d.offY = o.OffsetY, o.OffsetX
+	case Rotate180:
+		d.rect = image.Rect(0, 0, o.Width, o.Height)
+		d.offX, d.offY = ram.X-o.Width-o.OffsetX, ram.Y-o.Height-o.OffsetY
+	case Rotate270:
+		d.rect = image.Rect(0, 0, o.Height, o.Width)
+		d.offX, d.offY = ram.Y-o.Height-o.OffsetY, ram.X-o.Width-o.OffsetX
+	}
+}
+
+func (d *Dev) madctl() byte {
+	v := madctlRotation[d.opts.Rotation]
+	if d.opts.BGR {
+		v |= madctlBGR
+	}
+	return v
+}
+
+// drawWindow sends the RGB565 pixels for the rectangle r.
+func (d *Dev) drawWindow(r image.Rectangle, pixels []byte) error {
+	if d.halted {
+		// Transparently enable the display.
+		if err := d.command(cmdSLPOUT); err != nil {
+			return err
+		}
+		sleep(120 * time.Millisecond)
+		if err := d.command(cmdDISPON); err != nil {
+			return err
+		}
+		d.halted = false
+	}
+	x0, x1 := r.Min.X+d.offX, r.Max.X-1+d.offX
+	y0, y1 := r.Min.Y+d.offY, r.Max.Y-1+d.offY
+	if err := d.command(cmdCASET, byte(x0>>8), byte(x0), byte(x1>>8), byte(x1)); err != nil {
+		return err
+	}
+	if err := d.command(cmdRASET, byte(y0>>8), byte(y0), byte(y1>>8), byte(y1)); err != nil {
+		return err
+	}
+	if err := d.command(cmdRAMWR); err != nil {
+		return err
+	}
+	return d.data(pixels)
+}
+
+// command sends a command byte and its parameters.
+func (d *Dev) command(c byte, params ...byte) error {
+	if err := d.dc.Out(gpio.Low); err != nil {
+		return fmt.Errorf("st7735: %v", err)
+	}
+	if err := d.c.Tx([]byte{c}, nil); err != nil {
+		return fmt.Errorf("st7735: %v", err)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return d.data(params)
+}
+
+// data sends data bytes, in chunks not larger than the maximum transaction
+// size of the SPI port.
+func (d *Dev) data(b []byte) error {
+	if err := d.dc.Out(gpio.High); err != nil {
+		return fmt.Errorf("st7735: %v", err)
+	}
+	for len(b) != 0 {
+		n := len(b)
+		if n > d.chunk {
+			n = d.chunk
+		}
+		if err := d.c.Tx(b[:n], nil); err != nil {
+			return fmt.Errorf("st7735: %v", err)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// rgb565 converts 8 bits per channel to the big endian 16 bits
+// representation.
+func rgb565(r, g, b byte) (byte, byte) {
+	return r&0xF8 | g>>5, (g&0x1C)<<3 | b>>3
+}
+
+var _ conn.Resource = &Dev{}
+var _ devices.Display = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,325 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package st7735
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dc := gpioreg.ByName("GPIO25")
+	rst := gpioreg.ByName("GPIO27")
+	dev, err := NewSPI(p, dc, rst, &Opts{Model: ST7789, Invert: true, Rotation: Rotate90})
+	if err != nil {
+		log.Fatalf("failed to initialize st7735: %v", err)
+	}
+	defer dev.Halt()
+	img := image.NewRGBA(dev.Bounds())
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.NRGBA{0, 0, 255, 255}}, image.Point{}, draw.Src)
+	dev.Draw(img.Bounds(), img, image.Point{})
+	if err := dev.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewSPI_ST7789(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	rst := &gpiotest.Pin{N: "RST"}
+	p := &fakePort{dc: dc}
+	d, err := NewSPI(p, dc, rst, &Opts{Model: ST7789, Invert: true, BGR: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rst.L != gpio.High {
+		t.Fatal("reset")
+	}
+	if p.maxHz != 62500000 {
+		t.Fatal(p.maxHz)
+	}
+	expected := []op{
+		{cmd: true, w: []byte{0x11}},
+		{cmd: true, w: []byte{0x3A}},
+		{w: []byte{0x55}},
+		{cmd: true, w: []byte{0x36}},
+		{w: []byte{0x08}},
+		{cmd: true, w: []byte{0x21}},
+		{cmd: true, w: []byte{0x13}},
+		{cmd: true, w: []byte{0x29}},
+	}
+	p.check(t, expected)
+	if s := d.String(); s != "ST7789{fake, DC(0), (240,240)}" {
+		t.Fatal(s)
+	}
+	if m := d.ColorModel(); m != color.NRGBAModel {
+		t.Fatal(m)
+	}
+	if r := d.Bounds(); r != image.Rect(0, 0, 240, 240) {
+		t.Fatal(r)
+	}
+
+	// Partial update of a 2x1 window, clipped at the right edge.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 2, color.RGBA{255, 0, 0, 255})
+	img.Set(2, 2, color.RGBA{0, 0, 255, 255})
+	d.Draw(image.Rect(238, 10, 242, 11), img, image.Point{1, 2})
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{
+		{cmd: true, w: []byte{0x2A}},
+		{w: []byte{0x00, 238, 0x00, 239}},
+		{cmd: true, w: []byte{0x2B}},
+		{w: []byte{0x00, 10, 0x00, 10}},
+		{cmd: true, w: []byte{0x2C}},
+		{w: []byte{0xF8, 0x00, 0x00, 0x1F}},
+	})
+
+	// Same with a non-RGBA image.
+	n := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	n.Set(0, 0, color.NRGBA{0, 255, 0, 255})
+	d.Draw(image.Rect(0, 0, 1, 1), n, image.Point{})
+	p.check(t, []op{
+		{cmd: true, w: []byte{0x2A}},
+		{w: []byte{0, 0, 0, 0}},
+		{cmd: true, w: []byte{0x2B}},
+		{w: []byte{0, 0, 0, 0}},
+		{cmd: true, w: []byte{0x2C}},
+		{w: []byte{0x07, 0xE0}},
+	})
+
+	// Outside the display.
+	d.Draw(image.Rect(300, 300, 310, 310), n, image.Point{})
+	p.check(t, nil)
+
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x28}}, {cmd: true, w: []byte{0x10}}})
+	if err := d.SetRotation(Rotate180); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x36}}, {w: []byte{0xC8}}})
+	if err := d.SetRotation(4); err == nil {
+		t.Fatal("invalid rotation")
+	}
+	// The panel is at the bottom of the RAM when rotated by 180°.
+	if _, err := d.Write(make([]byte, 2*240*240)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.ops) != 7+(2*240*240+4095)/4096 {
+		t.Fatal(len(p.ops))
+	}
+	p.ops = p.ops[:6]
+	p.check(t, []op{
+		{cmd: true, w: []byte{0x11}},
+		{cmd: true, w: []byte{0x29}},
+		{cmd: true, w: []byte{0x2A}},
+		{w: []byte{0, 0, 0, 239}},
+		{cmd: true, w: []byte{0x2B}},
+		{w: []byte{0, 80, 0x01, 0x3F}},
+	})
+	if _, err := d.Write([]byte{0}); err == nil {
+		t.Fatal("invalid length")
+	}
+	if err := d.Invert(false); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x20}}})
+}
+
+func TestNewSPI_ST7735(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	p := &fakePort{dc: dc, maxTxSize: 1001}
+	d, err := NewSPI(p, dc, nil, &Opts{Width: 128, Height: 128, OffsetX: 2, OffsetY: 1, Rotation: Rotate90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.maxHz != 15000000 {
+		t.Fatal(p.maxHz)
+	}
+	if len(p.ops) != 2+2*len(st7735Init)+7 {
+		t.Fatal(len(p.ops))
+	}
+	if !p.ops[0].cmd || !bytes.Equal(p.ops[0].w, []byte{0x01}) {
+		t.Fatal(p.ops[0])
+	}
+	p.ops = nil
+	if err := d.Invert(true); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, []op{{cmd: true, w: []byte{0x21}}})
+	if err := d.Fill(color.White); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.ops) != 5+(2*128*128+999)/1000 {
+		t.Fatal(len(p.ops))
+	}
+	for _, o := range p.ops[5:] {
+		if o.cmd || o.w[0] != 0xFF || len(o.w) > 1000 {
+			t.Fatal(o)
+		}
+	}
+	p.ops = p.ops[:4]
+	p.check(t, []op{
+		{cmd: true, w: []byte{0x2A}},
+		{w: []byte{0, 1, 0, 128}},
+		{cmd: true, w: []byte{0x2B}},
+		{w: []byte{0, 2, 0, 129}},
+	})
+}
+
+func TestNewSPI_fail(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	if _, err := NewSPI(&fakePort{dc: dc}, nil, nil, nil); err == nil {
+		t.Fatal("dc is required")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, gpio.INVALID, nil, nil); err == nil {
+		t.Fatal("dc is required")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, nil, &Opts{Model: 2}); err == nil {
+		t.Fatal("invalid model")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, nil, &Opts{Rotation: 4}); err == nil {
+		t.Fatal("invalid rotation")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, nil, &Opts{Width: 128, Height: 160, OffsetX: 5}); err == nil {
+		t.Fatal("invalid size")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, &failPin{}, nil, nil); err == nil {
+		t.Fatal("dc failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc}, dc, &failPin{}, nil); err == nil {
+		t.Fatal("rst failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc, connectErr: true}, dc, nil, nil); err == nil {
+		t.Fatal("connect failed")
+	}
+	if _, err := NewSPI(&fakePort{dc: dc, txErr: true}, dc, nil, nil); err == nil {
+		t.Fatal("tx failed")
+	}
+}
+
+func TestRGB565(t *testing.T) {
+	data := []struct {
+		r, g, b byte
+		hi, lo  byte
+	}{
+		{0, 0, 0, 0, 0},
+		{255, 255, 255, 0xFF, 0xFF},
+		{255, 0, 0, 0xF8, 0x00},
+		{0, 255, 0, 0x07, 0xE0},
+		{0, 0, 255, 0x00, 0x1F},
+		{0x12, 0x34, 0x56, 0x11, 0xAA},
+	}
+	for i, line := range data {
+		if hi, lo := rgb565(line.r, line.g, line.b); hi != line.hi || lo != line.lo {
+			t.Fatalf("#%d: %#x %#x", i, hi, lo)
+		}
+	}
+	if s := ST7789.String(); s != "ST7789" {
+		t.Fatal(s)
+	}
+	if s := Model(2).String(); s != "Model(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+type op struct {
+	cmd bool
+	w   []byte
+}
+
+// fakePort records the transactions along the state of the DC pin.
+type fakePort struct {
+	dc         *gpiotest.Pin
+	maxTxSize  int
+	connectErr bool
+	txErr      bool
+	maxHz      int64
+	ops        []op
+}
+
+func (f *fakePort) String() string {
+	return "fake"
+}
+
+func (f *fakePort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	if f.connectErr {
+		return nil, errors.New("injected")
+	}
+	f.maxHz = maxHz
+	return f, nil
+}
+
+func (f *fakePort) Tx(w, r []byte) error {
+	if f.txErr {
+		return errors.New("injected")
+	}
+	f.ops = append(f.ops, op{cmd: f.dc.Read() == gpio.Low, w: append([]byte{}, w...)})
+	return nil
+}
+
+func (f *fakePort) TxPackets(p []spi.Packet) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakePort) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+func (f *fakePort) MaxTxSize() int {
+	return f.maxTxSize
+}
+
+func (f *fakePort) check(t *testing.T, expected []op) {
+	if len(f.ops) != len(expected) {
+		t.Fatalf("%d ops, expected %d", len(f.ops), len(expected))
+	}
+	for i := range expected {
+		if f.ops[i].cmd != expected[i].cmd || !bytes.Equal(f.ops[i].w, expected[i].w) {
+			t.Fatalf("#%d: %v != %v", i, f.ops[i], expected[i])
+		}
+	}
+	f.ops = nil
+}
+
+type failPin struct {
+	gpiotest.Pin
+}
+
+func (f *failPin) Out(l gpio.Level) error {
+	return errors.New("injected")
+}
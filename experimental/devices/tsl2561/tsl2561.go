@@ -0,0 +1,341 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package tsl2561 controls a TAOS TSL2561 ambient light sensor over I²C.
+//
+// The device has a broadband photodiode (visible and infrared) and an
+// infrared only photodiode. The infrared channel is used to compensate the
+// broadband channel to approximate the human eye response when calculating
+// the illuminance.
+//
+// Datasheet
+//
+// https://cdn-shop.adafruit.com/datasheets/TSL2561.pdf
+package tsl2561
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+	"periph.io/x/periph/devices"
+)
+
+// Gain is the analog gain.
+type Gain uint8
+
+// Possible gain values.
+const (
+	Gain1x  Gain = 0
+	Gain16x Gain = 1
+)
+
+const gainName = "Gain1xGain16x"
+
+var gainIndex = [...]uint8{0, 6, 13}
+
+func (g Gain) String() string {
+	if g >= Gain(len(gainIndex)-1) {
+		return fmt.Sprintf("Gain(%d)", g)
+	}
+	return gainName[gainIndex[g]:gainIndex[g+1]]
+}
+
+// IntegrationTime is the ADC integration time. Longer integration times
+// increase the resolution.
+type IntegrationTime uint8
+
+// Possible integration times.
+const (
+	Integration13ms  IntegrationTime = 0 // 13.7ms
+	Integration101ms IntegrationTime = 1
+	Integration402ms IntegrationTime = 2
+)
+
+const integrationTimeName = "Integration13msIntegration101msIntegration402ms"
+
+var integrationTimeIndex = [...]uint8{0, 15, 31, 47}
+
+func (i IntegrationTime) String() string {
+	if i >= IntegrationTime(len(integrationTimeIndex)-1) {
+		return fmt.Sprintf("IntegrationTime(%d)", i)
+	}
+	return integrationTimeName[integrationTimeIndex[i]:integrationTimeIndex[i+1]]
+}
+
+// Duration returns the integration time as a time.Duration.
+func (i IntegrationTime) Duration() time.Duration {
+	if i > Integration402ms {
+		return 0
+	}
+	return integrationDuration[i]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Gain            Gain
+	IntegrationTime IntegrationTime
+}
+
+// Dev is a handle to a TSL2561.
+type Dev struct {
+	m    mmr.Dev8
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a TSL2561.
+//
+// The address is 0x29, 0x39 or 0x49 depending on the ADDR SEL pin.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	switch addr {
+	case 0x29, 0x39, 0x49:
+	default:
+		return nil, errors.New("tsl2561: given address not supported by device")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	d := &Dev{m: mmr.Dev8{Conn: &i2c.Dev{Bus: b, Addr: addr}, Order: binary.LittleEndian}}
+	id, err := d.m.ReadUint8(cmd | regID)
+	if err != nil {
+		return nil, fmt.Errorf("tsl2561: %v", err)
+	}
+	// The part number is 0x0 or 0x1 for the CS package, 0x4 or 0x5 for the
+	// others.
+	if p := id >> 4; p != 0x1 && p != 0x5 {
+		return nil, fmt.Errorf("tsl2561: unexpected part number %#x", id)
+	}
+	if err := d.setConfig(opts.Gain, opts.IntegrationTime); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("TSL2561{%s}", d.m.Conn)
+}
+
+// Sense does a one time measurement of the illuminance.
+//
+// It returns an error if one of the channels is saturated; reduce the gain or
+// the integration time in this case.
+func (d *Dev) Sense(l *devices.Lux) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("tsl2561: already sensing continuously")
+	}
+	return d.sense(l)
+}
+
+// SenseRaw does a one time measurement and returns the raw counts of the
+// broadband and infrared channels.
+func (d *Dev) SenseRaw() (broadband, ir uint16, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return 0, 0, errors.New("tsl2561: already sensing continuously")
+	}
+	return d.measure()
+}
+
+// SenseContinuous returns measurements of the illuminance on a continuous
+// basis.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Lux, error) {
+	if interval < d.opts.IntegrationTime.Duration() {
+		return nil, errors.New("tsl2561: interval is shorter than the integration time")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Lux)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// SetGain changes the analog gain.
+func (d *Dev) SetGain(g Gain) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setConfig(g, d.opts.IntegrationTime)
+}
+
+// SetIntegrationTime changes the ADC integration time.
+func (d *Dev) SetIntegrationTime(i IntegrationTime) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setConfig(d.opts.Gain, i)
+}
+
+// Halt stops the sensing as initiated by SenseContinuous(). The device is
+// powered down between measurements.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+const (
+	cmd     = 0x80
+	cmdWord = 0x20
+
+	regControl = 0x00
+	regTiming  = 0x01
+	regID      = 0x0A
+	regData0   = 0x0C
+
+	powerOn  = 0x03
+	powerOff = 0x00
+)
+
+// integrationDuration is indexed by IntegrationTime.
+var integrationDuration = [...]time.Duration{
+	13700 * time.Microsecond,
+	101 * time.Millisecond,
+	402 * time.Millisecond,
+}
+
+// saturation is the maximum count, indexed by IntegrationTime; page 4.
+var saturation = [...]uint16{5047, 37177, 65535}
+
+func (d *Dev) setConfig(g Gain, i IntegrationTime) error {
+	if g > Gain16x {
+		return fmt.Errorf("tsl2561: invalid gain %d", g)
+	}
+	if i > Integration402ms {
+		return fmt.Errorf("tsl2561: invalid integration time %d", i)
+	}
+	if d.stop != nil {
+		return errors.New("tsl2561: already sensing continuously")
+	}
+	if err := d.m.WriteUint8(cmd|regTiming, byte(g)<<4|byte(i)); err != nil {
+		return fmt.Errorf("tsl2561: %v", err)
+	}
+	d.opts.Gain = g
+	d.opts.IntegrationTime = i
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Lux, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var l devices.Lux
+		d.mu.Lock()
+		err := d.sense(&l)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- l:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sense(l *devices.Lux) error {
+	ch0, ch1, err := d.measure()
+	if err != nil {
+		return err
+	}
+	if s := saturation[d.opts.IntegrationTime]; ch0 >= s || ch1 >= s {
+		return errors.New("tsl2561: sensor saturated")
+	}
+	*l = calculateLux(ch0, ch1, d.opts.Gain, d.opts.IntegrationTime)
+	return nil
+}
+
+// measure powers the device up for one integration cycle.
+func (d *Dev) measure() (uint16, uint16, error) {
+	if err := d.m.WriteUint8(cmd|regControl, powerOn); err != nil {
+		return 0, 0, fmt.Errorf("tsl2561: %v", err)
+	}
+	// The ADC doesn't signal completion; wait for a bit more than one
+	// integration cycle.
+	time.Sleep(d.opts.IntegrationTime.Duration() + 2*time.Millisecond)
+	v, err := d.m.ReadUint32(cmd | cmdWord | regData0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tsl2561: %v", err)
+	}
+	if err := d.m.WriteUint8(cmd|regControl, powerOff); err != nil {
+		return 0, 0, fmt.Errorf("tsl2561: %v", err)
+	}
+	return uint16(v), uint16(v >> 16), nil
+}
+
+// calculateLux converts the raw counts of the broadband and infrared channels
+// to lux, using the empirical formula for the T, FN and CL packages; page 23.
+func calculateLux(ch0, ch1 uint16, g Gain, i IntegrationTime) devices.Lux {
+	if ch0 == 0 {
+		return 0
+	}
+	// Normalize to 16x gain and 402ms.
+	scale := float64(integrationDuration[Integration402ms]) / float64(integrationDuration[i])
+	if g == Gain1x {
+		scale *= 16
+	}
+	c0 := float64(ch0) * scale
+	c1 := float64(ch1) * scale
+	r := c1 / c0
+	var lux float64
+	switch {
+	case r <= 0.50:
+		lux = 0.0304*c0 - 0.062*c0*math.Pow(r, 1.4)
+	case r <= 0.61:
+		lux = 0.0224*c0 - 0.031*c1
+	case r <= 0.80:
+		lux = 0.0128*c0 - 0.0153*c1
+	case r <= 1.30:
+		lux = 0.00146*c0 - 0.00112*c1
+	}
+	return devices.Lux(math.Floor(lux*1000 + 0.5))
+}
+
+var defaults = Opts{
+	Gain:            Gain1x,
+	IntegrationTime: Integration402ms,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,200 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package tsl2561
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x39, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize tsl2561: %v", err)
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", l)
+}
+
+//
+
+var initOps = []i2ctest.IO{
+	{Addr: 0x39, W: []byte{0x8A}, R: []byte{0x50}},
+	{Addr: 0x39, W: []byte{0x81, 0x10}},
+}
+
+var measureOps = []i2ctest.IO{
+	{Addr: 0x39, W: []byte{0x80, 0x03}},
+	{Addr: 0x39, W: []byte{0xAC}, R: []byte{0xE8, 0x03, 0xC8, 0x00}},
+	{Addr: 0x39, W: []byte{0x80, 0x00}},
+}
+
+func TestNewI2C_Sense(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(append(append([]i2ctest.IO{}, initOps...), measureOps...),
+			i2ctest.IO{Addr: 0x39, W: []byte{0x80, 0x03}},
+			i2ctest.IO{Addr: 0x39, W: []byte{0xAC}, R: []byte{0xC5, 0x13, 0x00, 0x00}},
+			i2ctest.IO{Addr: 0x39, W: []byte{0x80, 0x00}},
+			i2ctest.IO{Addr: 0x39, W: []byte{0x81, 0x00}},
+			i2ctest.IO{Addr: 0x39, W: []byte{0x81, 0x01}},
+		),
+	}
+	dev, err := NewI2C(&bus, 0x39, &Opts{Gain: Gain16x, IntegrationTime: Integration13ms})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "TSL2561{playback(57)}" {
+		t.Fatal(s)
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err != nil {
+		t.Fatal(err)
+	}
+	if l != 700895 {
+		t.Fatal(l)
+	}
+	if err := dev.Sense(&l); err == nil {
+		t.Fatal("saturated")
+	}
+	if err := dev.SetGain(Gain1x); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetIntegrationTime(Integration101ms); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetGain(2); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if err := dev.SetIntegrationTime(3); err == nil {
+		t.Fatal("invalid integration time")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{}, 0x40, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x39, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	bus := i2ctest.Playback{Ops: []i2ctest.IO{{Addr: 0x39, W: []byte{0x8A}, R: []byte{0x20}}}}
+	if _, err := NewI2C(&bus, 0x39, nil); err == nil {
+		t.Fatal("invalid part number")
+	}
+	bus = i2ctest.Playback{Ops: initOps[:1], DontPanic: true}
+	if _, err := NewI2C(&bus, 0x39, &Opts{Gain: 2}); err == nil {
+		t.Fatal("invalid gain")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	bus := i2ctest.Playback{
+		Ops: append(append(append(append([]i2ctest.IO{}, initOps...), measureOps...), measureOps...), measureOps...),
+	}
+	dev, err := NewI2C(&bus, 0x39, &Opts{Gain: Gain16x, IntegrationTime: Integration13ms})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(20 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if l := <-c; l != 700895 {
+			t.Fatal(l)
+		}
+	}
+	if err := dev.SetGain(Gain1x); err == nil {
+		t.Fatal("already sensing")
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err == nil {
+		t.Fatal("already sensing")
+	}
+	if _, _, err := dev.SenseRaw(); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	// The third measurement may or may not have happened.
+	bus.Ops = measureOps
+	bus.Count = 0
+	if ch0, ch1, err := dev.SenseRaw(); err != nil || ch0 != 1000 || ch1 != 200 {
+		t.Fatal(ch0, ch1, err)
+	}
+}
+
+func TestCalculateLux(t *testing.T) {
+	data := []struct {
+		ch0, ch1 uint16
+		g        Gain
+		i        IntegrationTime
+		expected devices.Lux
+	}{
+		{0, 0, Gain1x, Integration402ms, 0},
+		{1000, 0, Gain16x, Integration402ms, 30400},
+		{1000, 0, Gain1x, Integration402ms, 486400},
+		{1000, 550, Gain16x, Integration402ms, 5350},
+		{1000, 700, Gain16x, Integration402ms, 2090},
+		{1000, 1000, Gain16x, Integration402ms, 340},
+		{1000, 2000, Gain16x, Integration402ms, 0},
+	}
+	for i, line := range data {
+		if l := calculateLux(line.ch0, line.ch1, line.g, line.i); l != line.expected {
+			t.Fatalf("#%d: %d != %d", i, l, line.expected)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	data := []struct {
+		s        fmt.Stringer
+		expected string
+	}{
+		{Gain16x, "Gain16x"},
+		{Gain(2), "Gain(2)"},
+		{Integration101ms, "Integration101ms"},
+		{IntegrationTime(3), "IntegrationTime(3)"},
+	}
+	for i, line := range data {
+		if s := line.s.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+	if d := IntegrationTime(3).Duration(); d != 0 {
+		t.Fatal(d)
+	}
+}
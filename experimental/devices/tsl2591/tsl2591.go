@@ -0,0 +1,335 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package tsl2591 controls an AMS TSL2591 ambient light sensor over I²C.
+//
+// The device has a full spectrum photodiode (visible and infrared) and an
+// infrared only photodiode. The infrared channel is used to compensate the
+// full spectrum channel when calculating the illuminance. Its dynamic range
+// is much larger than the TSL2561.
+//
+// Datasheet
+//
+// https://ams.com/documents/20143/36005/TSL2591_DS000338_6-00.pdf
+package tsl2591
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+	"periph.io/x/periph/devices"
+)
+
+// Gain is the analog gain.
+type Gain uint8
+
+// Possible gain values.
+const (
+	GainLow    Gain = 0 // 1x
+	GainMedium Gain = 1 // 25x
+	GainHigh   Gain = 2 // 428x
+	GainMax    Gain = 3 // 9876x
+)
+
+const gainName = "GainLowGainMediumGainHighGainMax"
+
+var gainIndex = [...]uint8{0, 7, 17, 25, 32}
+
+func (g Gain) String() string {
+	if g >= Gain(len(gainIndex)-1) {
+		return fmt.Sprintf("Gain(%d)", g)
+	}
+	return gainName[gainIndex[g]:gainIndex[g+1]]
+}
+
+// IntegrationTime is the ADC integration time. Longer integration times
+// increase the resolution.
+type IntegrationTime uint8
+
+// Possible integration times.
+const (
+	Integration100ms IntegrationTime = 0
+	Integration200ms IntegrationTime = 1
+	Integration300ms IntegrationTime = 2
+	Integration400ms IntegrationTime = 3
+	Integration500ms IntegrationTime = 4
+	Integration600ms IntegrationTime = 5
+)
+
+// Duration returns the integration time as a time.Duration.
+func (i IntegrationTime) Duration() time.Duration {
+	if i > Integration600ms {
+		return 0
+	}
+	return time.Duration(i+1) * 100 * time.Millisecond
+}
+
+func (i IntegrationTime) String() string {
+	if i > Integration600ms {
+		return fmt.Sprintf("IntegrationTime(%d)", i)
+	}
+	return fmt.Sprintf("Integration%dms", (int(i)+1)*100)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	Gain            Gain
+	IntegrationTime IntegrationTime
+}
+
+// Dev is a handle to a TSL2591.
+type Dev struct {
+	m    mmr.Dev8
+	opts Opts
+
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a TSL2591.
+//
+// The device has a fixed address of 0x29.
+func NewI2C(b i2c.Bus, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	d := &Dev{m: mmr.Dev8{Conn: &i2c.Dev{Bus: b, Addr: 0x29}, Order: binary.LittleEndian}}
+	id, err := d.m.ReadUint8(cmd | regID)
+	if err != nil {
+		return nil, fmt.Errorf("tsl2591: %v", err)
+	}
+	if id != 0x50 {
+		return nil, fmt.Errorf("tsl2591: unexpected device id %#x", id)
+	}
+	if err := d.setConfig(opts.Gain, opts.IntegrationTime); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("TSL2591{%s}", d.m.Conn)
+}
+
+// Sense does a one time measurement of the illuminance.
+//
+// It returns an error if one of the channels is saturated; reduce the gain or
+// the integration time in this case.
+func (d *Dev) Sense(l *devices.Lux) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("tsl2591: already sensing continuously")
+	}
+	return d.sense(l)
+}
+
+// SenseRaw does a one time measurement and returns the raw counts of the
+// full spectrum and infrared channels.
+func (d *Dev) SenseRaw() (full, ir uint16, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return 0, 0, errors.New("tsl2591: already sensing continuously")
+	}
+	return d.measure()
+}
+
+// SenseContinuous returns measurements of the illuminance on a continuous
+// basis.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Lux, error) {
+	if interval < d.opts.IntegrationTime.Duration() {
+		return nil, errors.New("tsl2591: interval is shorter than the integration time")
+	}
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sensing := make(chan devices.Lux)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// SetGain changes the analog gain.
+func (d *Dev) SetGain(g Gain) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setConfig(g, d.opts.IntegrationTime)
+}
+
+// SetIntegrationTime changes the ADC integration time.
+func (d *Dev) SetIntegrationTime(i IntegrationTime) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setConfig(d.opts.Gain, i)
+}
+
+// Halt stops the sensing as initiated by SenseContinuous(). The device is
+// powered down between measurements.
+func (d *Dev) Halt() error {
+	d.stopSensing()
+	return nil
+}
+
+//
+
+const (
+	// Command bit and normal operation.
+	cmd = 0xA0
+
+	regEnable = 0x00
+	regConfig = 0x01
+	regID     = 0x12
+	regStatus = 0x13
+	regC0Data = 0x14
+
+	enablePowerOn = 0x01
+	enableALS     = 0x02
+	statusValid   = 0x01
+
+	// luxDF is the device factor; from the vendor's application note.
+	luxDF = 408.
+)
+
+// gainFactor is indexed by Gain.
+var gainFactor = [...]float64{1, 25, 428, 9876}
+
+func (d *Dev) setConfig(g Gain, i IntegrationTime) error {
+	if g > GainMax {
+		return fmt.Errorf("tsl2591: invalid gain %d", g)
+	}
+	if i > Integration600ms {
+		return fmt.Errorf("tsl2591: invalid integration time %d", i)
+	}
+	if d.stop != nil {
+		return errors.New("tsl2591: already sensing continuously")
+	}
+	if err := d.m.WriteUint8(cmd|regConfig, byte(g)<<4|byte(i)); err != nil {
+		return fmt.Errorf("tsl2591: %v", err)
+	}
+	d.opts.Gain = g
+	d.opts.IntegrationTime = i
+	return nil
+}
+
+func (d *Dev) stopSensing() {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		d.wg.Wait()
+	}
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Lux, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		var l devices.Lux
+		d.mu.Lock()
+		err := d.sense(&l)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- l:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dev) sense(l *devices.Lux) error {
+	ch0, ch1, err := d.measure()
+	if err != nil {
+		return err
+	}
+	// The counter saturates at 36863 with the 100ms integration time.
+	s := uint16(0xFFFF)
+	if d.opts.IntegrationTime == Integration100ms {
+		s = 36863
+	}
+	if ch0 >= s || ch1 >= s {
+		return errors.New("tsl2591: sensor saturated")
+	}
+	*l = calculateLux(ch0, ch1, d.opts.Gain, d.opts.IntegrationTime)
+	return nil
+}
+
+// measure powers the device up until one integration cycle completed.
+func (d *Dev) measure() (uint16, uint16, error) {
+	if err := d.m.WriteUint8(cmd|regEnable, enablePowerOn|enableALS); err != nil {
+		return 0, 0, fmt.Errorf("tsl2591: %v", err)
+	}
+	time.Sleep(d.opts.IntegrationTime.Duration())
+	for i := 0; ; i++ {
+		s, err := d.m.ReadUint8(cmd | regStatus)
+		if err != nil {
+			return 0, 0, fmt.Errorf("tsl2591: %v", err)
+		}
+		if s&statusValid != 0 {
+			break
+		}
+		if i == 10 {
+			return 0, 0, errors.New("tsl2591: timed out waiting for measurement")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	v, err := d.m.ReadUint32(cmd | regC0Data)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tsl2591: %v", err)
+	}
+	if err := d.m.WriteUint8(cmd|regEnable, 0); err != nil {
+		return 0, 0, fmt.Errorf("tsl2591: %v", err)
+	}
+	return uint16(v), uint16(v >> 16), nil
+}
+
+// calculateLux converts the raw counts of the full spectrum and infrared
+// channels to lux.
+func calculateLux(ch0, ch1 uint16, g Gain, i IntegrationTime) devices.Lux {
+	if ch0 == 0 || ch1 >= ch0 {
+		return 0
+	}
+	// Counts per lux.
+	cpl := float64(i.Duration()/time.Millisecond) * gainFactor[g] / luxDF
+	c0 := float64(ch0)
+	c1 := float64(ch1)
+	lux := (c0 - c1) * (1 - c1/c0) / cpl
+	return devices.Lux(math.Floor(lux*1000 + 0.5))
+}
+
+var defaults = Opts{
+	Gain:            GainMedium,
+	IntegrationTime: Integration100ms,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
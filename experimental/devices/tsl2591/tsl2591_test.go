@@ -0,0 +1,196 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package tsl2591
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, &Opts{Gain: GainHigh, IntegrationTime: Integration300ms})
+	if err != nil {
+		log.Fatalf("failed to initialize tsl2591: %v", err)
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", l)
+}
+
+//
+
+var initOps = []i2ctest.IO{
+	{Addr: 0x29, W: []byte{0xB2}, R: []byte{0x50}},
+	{Addr: 0x29, W: []byte{0xA1, 0x10}},
+}
+
+func measureOps(ch0, ch1 uint16) []i2ctest.IO {
+	return []i2ctest.IO{
+		{Addr: 0x29, W: []byte{0xA0, 0x03}},
+		{Addr: 0x29, W: []byte{0xB3}, R: []byte{0x00}},
+		{Addr: 0x29, W: []byte{0xB3}, R: []byte{0x01}},
+		{Addr: 0x29, W: []byte{0xB4}, R: []byte{byte(ch0), byte(ch0 >> 8), byte(ch1), byte(ch1 >> 8)}},
+		{Addr: 0x29, W: []byte{0xA0, 0x00}},
+	}
+}
+
+func TestNewI2C_Sense(t *testing.T) {
+	ops := append([]i2ctest.IO{}, initOps...)
+	ops = append(ops, measureOps(1000, 200)...)
+	ops = append(ops, measureOps(36863, 200)...)
+	ops = append(ops, i2ctest.IO{Addr: 0x29, W: []byte{0xA1, 0x30}}, i2ctest.IO{Addr: 0x29, W: []byte{0xA1, 0x35}})
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "TSL2591{playback(41)}" {
+		t.Fatal(s)
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err != nil {
+		t.Fatal(err)
+	}
+	if l != 104448 {
+		t.Fatal(l)
+	}
+	if err := dev.Sense(&l); err == nil {
+		t.Fatal("saturated")
+	}
+	if err := dev.SetGain(GainMax); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetIntegrationTime(Integration600ms); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetGain(4); err == nil {
+		t.Fatal("invalid gain")
+	}
+	if err := dev.SetIntegrationTime(6); err == nil {
+		t.Fatal("invalid integration time")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	bus := i2ctest.Playback{Ops: []i2ctest.IO{{Addr: 0x29, W: []byte{0xB2}, R: []byte{0x20}}}}
+	if _, err := NewI2C(&bus, nil); err == nil {
+		t.Fatal("invalid device id")
+	}
+	bus = i2ctest.Playback{Ops: initOps[:1]}
+	if _, err := NewI2C(&bus, &Opts{IntegrationTime: 6}); err == nil {
+		t.Fatal("invalid integration time")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	ops := append([]i2ctest.IO{}, initOps...)
+	for i := 0; i < 3; i++ {
+		ops = append(ops, measureOps(1000, 200)...)
+	}
+	bus := i2ctest.Playback{Ops: ops}
+	dev, err := NewI2C(&bus, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval too short")
+	}
+	c, err := dev.SenseContinuous(150 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if l := <-c; l != 104448 {
+			t.Fatal(l)
+		}
+	}
+	if err := dev.SetIntegrationTime(Integration200ms); err == nil {
+		t.Fatal("already sensing")
+	}
+	var l devices.Lux
+	if err := dev.Sense(&l); err == nil {
+		t.Fatal("already sensing")
+	}
+	if _, _, err := dev.SenseRaw(); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	// The third measurement may or may not have happened.
+	bus.Ops = measureOps(1000, 200)
+	bus.Count = 0
+	if ch0, ch1, err := dev.SenseRaw(); err != nil || ch0 != 1000 || ch1 != 200 {
+		t.Fatal(ch0, ch1, err)
+	}
+}
+
+func TestCalculateLux(t *testing.T) {
+	data := []struct {
+		ch0, ch1 uint16
+		g        Gain
+		i        IntegrationTime
+		expected devices.Lux
+	}{
+		{0, 0, GainLow, Integration100ms, 0},
+		{1000, 1000, GainLow, Integration100ms, 0},
+		{1000, 0, GainLow, Integration100ms, 4080000},
+		{1000, 500, GainHigh, Integration600ms, 397},
+	}
+	for i, line := range data {
+		if l := calculateLux(line.ch0, line.ch1, line.g, line.i); l != line.expected {
+			t.Fatalf("#%d: %d != %d", i, l, line.expected)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	data := []struct {
+		s        fmt.Stringer
+		expected string
+	}{
+		{GainHigh, "GainHigh"},
+		{Gain(4), "Gain(4)"},
+		{Integration300ms, "Integration300ms"},
+		{IntegrationTime(6), "IntegrationTime(6)"},
+	}
+	for i, line := range data {
+		if s := line.s.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+	if d := IntegrationTime(6).Duration(); d != 0 {
+		t.Fatal(d)
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package uln2003 controls a 4-phase unipolar stepper motor, like the
+// 28BYJ-48, through a ULN2003 darlington array driver.
+//
+// The motion follows a trapezoidal profile: the motor accelerates up to the
+// maximum speed, then decelerates to stop on the target position. The
+// position is tracked in software, as steps relative to the position at
+// initialization.
+//
+// Dev implements motor.Stepper.
+//
+// Datasheet
+//
+// http://www.ti.com/lit/ds/symlink/uln2003a.pdf
+//
+// http://robocraft.ru/files/datasheet/28BYJ-48.pdf
+package uln2003
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/devices/motor"
+)
+
+// Steps28BYJ48 is the number of full steps per revolution of the output shaft
+// of a 28BYJ-48, including its 1/64 gear reduction.
+//
+// Double it in HalfStep mode.
+const Steps28BYJ48 = 2048
+
+// Mode is the stepping mode.
+type Mode int
+
+// Valid Mode values.
+const (
+	// FullStep energizes two phases at a time, providing the most torque.
+	FullStep Mode = 0
+	// HalfStep alternates between one and two phases energized, doubling the
+	// resolution.
+	HalfStep Mode = 1
+)
+
+const modeName = "FullStepHalfStep"
+
+var modeIndex = [...]uint8{0, 8, 16}
+
+func (m Mode) String() string {
+	if m < 0 || m >= Mode(len(modeIndex)-1) {
+		return fmt.Sprintf("Mode(%d)", m)
+	}
+	return modeName[modeIndex[m]:modeIndex[m+1]]
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Mode is the stepping mode.
+	Mode Mode
+	// Speed is the maximum speed in steps per second.
+	Speed int
+	// Acceleration is in steps per second², used both to accelerate and to
+	// decelerate. 0 disables the ramps, the motor then steps at Speed right
+	// away.
+	Acceleration int
+}
+
+// Dev is a handle to a stepper motor driven by a ULN2003.
+type Dev struct {
+	pins [4]gpio.PinOut
+	opts Opts
+	seq  []byte
+
+	mu     sync.Mutex
+	pos    int
+	offset int           // Phase offset set by SetPosition.
+	err    error         // Error of the last motion.
+	stop   chan struct{} // Closed to abort the current motion.
+	done   chan struct{} // Closed when the current motion ended.
+}
+
+// New returns an object that drives a stepper motor through the four inputs
+// of a ULN2003.
+//
+// The pins must be in the order of the phases of the motor; for a 28BYJ-48
+// on the common ULN2003 boards, this is IN1 to IN4.
+func New(in1, in2, in3, in4 gpio.PinOut, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.Mode != FullStep && opts.Mode != HalfStep {
+		return nil, errors.New("uln2003: invalid mode")
+	}
+	if opts.Speed <= 0 {
+		return nil, errors.New("uln2003: speed must be positive")
+	}
+	if opts.Acceleration < 0 {
+		return nil, errors.New("uln2003: acceleration must not be negative")
+	}
+	d := &Dev{pins: [4]gpio.PinOut{in1, in2, in3, in4}, opts: *opts, seq: fullStep}
+	if opts.Mode == HalfStep {
+		d.seq = halfStep
+	}
+	for i, p := range d.pins {
+		if p == nil || p == gpio.INVALID {
+			return nil, fmt.Errorf("uln2003: in%d is required", i+1)
+		}
+	}
+	if err := d.release(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("ULN2003{%s, %s, %s, %s}", d.pins[0], d.pins[1], d.pins[2], d.pins[3])
+}
+
+// MoveTo starts moving the motor to the absolute position pos, in steps.
+//
+// It returns immediately; use Wait() to wait for the motion to complete. The
+// current motion, if any, is stopped first.
+func (d *Dev) MoveTo(pos int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.start(pos)
+	return nil
+}
+
+// Move starts moving the motor by the number of steps relative to the current
+// position. Negative values turn the motor backward.
+//
+// It returns immediately; use Wait() to wait for the motion to complete. The
+// current motion, if any, is stopped first.
+func (d *Dev) Move(steps int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.start(d.pos + steps)
+	return nil
+}
+
+// Wait blocks until the current motion completes or is stopped.
+//
+// It returns the error that aborted the motion, if any.
+func (d *Dev) Wait() error {
+	d.mu.Lock()
+	done := d.done
+	d.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Moving returns true if a motion is in progress.
+func (d *Dev) Moving() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done == nil {
+		return false
+	}
+	select {
+	case <-d.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Position returns the current position, in steps.
+func (d *Dev) Position() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pos
+}
+
+// SetPosition stops the current motion, if any, and defines the current
+// position as pos, e.g. after homing the motor against a limit switch.
+func (d *Dev) SetPosition(pos int) error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Keep the same phases energized.
+	d.offset = mod(d.phase()-pos, len(d.seq))
+	d.pos = pos
+	return nil
+}
+
+// Stop stops the current motion immediately, without deceleration.
+//
+// The coils stay energized to hold the position.
+func (d *Dev) Stop() error {
+	d.stopMoving()
+	return nil
+}
+
+// Halt stops the current motion and de-energizes the coils, so the motor
+// doesn't draw current. The motor doesn't hold its position anymore.
+func (d *Dev) Halt() error {
+	d.stopMoving()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.release()
+}
+
+//
+
+var defaults = Opts{
+	Mode:         HalfStep,
+	Speed:        500,
+	Acceleration: 1000,
+}
+
+// Phases energized for each step, bit 0 is in1.
+var (
+	fullStep = []byte{0x3, 0x6, 0xC, 0x9}
+	halfStep = []byte{0x1, 0x3, 0x2, 0x6, 0x4, 0xC, 0x8, 0x9}
+)
+
+// after is overridden in unit tests.
+var after = time.After
+
+// start starts the motion to the target position.
+//
+// It must be called with d.mu lock held.
+func (d *Dev) start(target int) {
+	d.err = nil
+	n := target - d.pos
+	if n == 0 {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		d.moving(n, stop)
+	}(d.stop, d.done)
+}
+
+func (d *Dev) stopMoving() {
+	d.mu.Lock()
+	stop := d.stop
+	done := d.done
+	d.stop = nil
+	d.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (d *Dev) moving(n int, stop <-chan struct{}) {
+	dir := 1
+	if n < 0 {
+		dir = -1
+		n = -n
+	}
+	for i := 0; i < n; i++ {
+		d.mu.Lock()
+		d.pos += dir
+		err := d.energize(d.seq[d.phase()])
+		if err != nil {
+			d.err = err
+		}
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to step: %v", d, err)
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-after(d.stepDelay(i, n)):
+		}
+	}
+}
+
+// stepDelay returns the delay after the step i of a motion of n steps.
+func (d *Dev) stepDelay(i, n int) time.Duration {
+	return motor.Profile{Speed: d.opts.Speed, Acceleration: d.opts.Acceleration}.Delay(i, n)
+}
+
+// phase returns the index in d.seq of the current position.
+func (d *Dev) phase() int {
+	return mod(d.pos+d.offset, len(d.seq))
+}
+
+func (d *Dev) energize(phases byte) error {
+	for i, p := range d.pins {
+		if err := p.Out(phases&(1<<uint(i)) != 0); err != nil {
+			return fmt.Errorf("uln2003: %v", err)
+		}
+	}
+	return nil
+}
+
+func (d *Dev) release() error {
+	return d.energize(0)
+}
+
+func mod(a, b int) int {
+	return (a%b + b) % b
+}
+
+var _ conn.Resource = &Dev{}
+var _ motor.Stepper = &Dev{}
+var _ fmt.Stringer = &Dev{}
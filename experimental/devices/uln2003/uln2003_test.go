@@ -0,0 +1,312 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package uln2003
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	dev, err := New(gpioreg.ByName("GPIO17"), gpioreg.ByName("GPIO18"), gpioreg.ByName("GPIO27"), gpioreg.ByName("GPIO22"), nil)
+	if err != nil {
+		log.Fatalf("failed to initialize uln2003: %v", err)
+	}
+	defer dev.Halt()
+	// One revolution forward in half step mode.
+	if err := dev.MoveTo(2 * Steps28BYJ48); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		log.Fatal(err)
+	}
+	// Back to the initial position.
+	if err := dev.MoveTo(0); err != nil {
+		log.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNew_HalfStep(t *testing.T) {
+	r := &recorder{}
+	dev, err := New(r.pin(0), r.pin(1), r.pin(2), r.pin(3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "ULN2003{in1(0), in2(0), in3(0), in4(0)}" {
+		t.Fatal(s)
+	}
+	r.check(t, []byte{0x0})
+	if err := dev.MoveTo(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != 3 {
+		t.Fatal(p)
+	}
+	r.check(t, []byte{0x3, 0x2, 0x6})
+	if err := dev.Move(-5); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != -2 {
+		t.Fatal(p)
+	}
+	r.check(t, []byte{0x2, 0x3, 0x1, 0x9, 0x8})
+	// No-op.
+	if err := dev.MoveTo(-2); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Moving() {
+		t.Fatal("not moving")
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, nil)
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []byte{0x0})
+}
+
+func TestNew_FullStep(t *testing.T) {
+	r := &recorder{}
+	dev, err := New(r.pin(0), r.pin(1), r.pin(2), r.pin(3), &Opts{Mode: FullStep, Speed: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Move(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	r.check(t, []byte{0x0, 0x6, 0xC, 0x9, 0x3, 0x6})
+	// The phases are kept when redefining the position.
+	if err := dev.SetPosition(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Move(-2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != 98 {
+		t.Fatal(p)
+	}
+	r.check(t, []byte{0x3, 0x9})
+}
+
+func TestStop(t *testing.T) {
+	defer func() {
+		after = afterNow
+	}()
+	tick := make(chan time.Time)
+	after = func(time.Duration) <-chan time.Time {
+		return tick
+	}
+	r := &recorder{}
+	dev, err := New(r.pin(0), r.pin(1), r.pin(2), r.pin(3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(100); err != nil {
+		t.Fatal(err)
+	}
+	tick <- time.Time{}
+	if !dev.Moving() {
+		t.Fatal("moving")
+	}
+	if err := dev.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if dev.Moving() {
+		t.Fatal("stopped")
+	}
+	if p := dev.Position(); p != 2 {
+		t.Fatal(p)
+	}
+	// Starting a new motion aborts the current one.
+	if err := dev.MoveTo(-100); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.MoveTo(3); err != nil {
+		t.Fatal(err)
+	}
+	tick <- time.Time{}
+	tick <- time.Time{}
+	if err := dev.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if p := dev.Position(); p != 3 {
+		t.Fatal(p)
+	}
+	r.check(t, []byte{0x0, 0x3, 0x2, 0x3, 0x2, 0x6})
+}
+
+func TestStepDelay(t *testing.T) {
+	dev := Dev{opts: Opts{Speed: 100, Acceleration: 200}}
+	// The speed reaches 100 steps/s after 25 steps.
+	data := []struct {
+		i, n     int
+		expected time.Duration
+	}{
+		{0, 100, 50 * time.Millisecond},
+		{3, 100, 25 * time.Millisecond},
+		{24, 100, 10 * time.Millisecond},
+		{50, 100, 10 * time.Millisecond},
+		{96, 100, 25 * time.Millisecond},
+		{99, 100, 50 * time.Millisecond},
+		// Never reaches the maximum speed.
+		{3, 8, 25 * time.Millisecond},
+		{4, 8, 25 * time.Millisecond},
+		{0, 1, 50 * time.Millisecond},
+	}
+	for i, line := range data {
+		if d := dev.stepDelay(line.i, line.n); d != line.expected {
+			t.Fatalf("#%d: %s != %s", i, d, line.expected)
+		}
+	}
+	dev.opts.Acceleration = 0
+	if d := dev.stepDelay(0, 100); d != 10*time.Millisecond {
+		t.Fatal(d)
+	}
+}
+
+func TestNew_fail(t *testing.T) {
+	p := &gpiotest.Pin{}
+	data := []Opts{
+		{Mode: 2, Speed: 100},
+		{Speed: 0},
+		{Speed: 100, Acceleration: -1},
+	}
+	for i, opts := range data {
+		if _, err := New(p, p, p, p, &opts); err == nil {
+			t.Fatalf("#%d: invalid options", i)
+		}
+	}
+	if _, err := New(p, p, nil, p, nil); err == nil {
+		t.Fatal("missing pin")
+	}
+	if _, err := New(p, p, p, gpio.INVALID, nil); err == nil {
+		t.Fatal("invalid pin")
+	}
+	r := &recorder{}
+	r.fail = true
+	if _, err := New(r.pin(0), r.pin(1), r.pin(2), r.pin(3), nil); err == nil {
+		t.Fatal("failed to write")
+	}
+	r.fail = false
+	dev, err := New(r.pin(0), r.pin(1), r.pin(2), r.pin(3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	r.fail = true
+	r.mu.Unlock()
+	if err := dev.Move(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Wait(); err == nil {
+		t.Fatal("failed to write")
+	}
+	if err := dev.Halt(); err == nil {
+		t.Fatal("failed to write")
+	}
+}
+
+func TestMode_String(t *testing.T) {
+	if s := HalfStep.String(); s != "HalfStep" {
+		t.Fatal(s)
+	}
+	if s := Mode(2).String(); s != "Mode(2)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func init() {
+	after = afterNow
+}
+
+func afterNow(time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	c <- time.Time{}
+	return c
+}
+
+// recorder logs the phases energized, each time the four pins are written.
+type recorder struct {
+	mu     sync.Mutex
+	levels byte
+	phases []byte
+	fail   bool
+}
+
+func (r *recorder) pin(i int) *recordPin {
+	return &recordPin{Pin: gpiotest.Pin{N: fmt.Sprintf("in%d", i+1)}, r: r, i: uint(i)}
+}
+
+// check verifies the phases energized since the last call.
+func (r *recorder) check(t *testing.T, expected []byte) {
+	r.mu.Lock()
+	p := r.phases
+	r.phases = nil
+	r.mu.Unlock()
+	if len(p) != len(expected) {
+		t.Fatalf("%x != %x", p, expected)
+	}
+	for i := range p {
+		if p[i] != expected[i] {
+			t.Fatalf("%x != %x", p, expected)
+		}
+	}
+}
+
+type recordPin struct {
+	gpiotest.Pin
+	r *recorder
+	i uint
+}
+
+func (p *recordPin) Out(l gpio.Level) error {
+	p.r.mu.Lock()
+	defer p.r.mu.Unlock()
+	if p.r.fail {
+		return errors.New("injected error")
+	}
+	if l {
+		p.r.levels |= 1 << p.i
+	} else {
+		p.r.levels &^= 1 << p.i
+	}
+	if p.i == 3 {
+		p.r.phases = append(p.r.phases, p.r.levels)
+	}
+	return nil
+}
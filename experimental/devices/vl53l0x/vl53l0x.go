@@ -0,0 +1,665 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package vl53l0x controls a ST VL53L0X time-of-flight ranging sensor over
+// I²C.
+//
+// The initialization sequence and the undocumented tuning settings come from
+// the ST API, as the datasheet doesn't describe the registers.
+//
+// Datasheet
+//
+// http://www.st.com/resource/en/datasheet/vl53l0x.pdf
+package vl53l0x
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// TimingBudget is the time allowed for one measurement, between 20ms and
+	// 1s. A longer budget improves accuracy. 0 keeps the device default of
+	// about 33ms.
+	TimingBudget time.Duration
+	// IO2V8 configures the I/O pads for 2.8V instead of 1.8V. Most breakout
+	// boards need it.
+	IO2V8 bool
+}
+
+// Dev is a handle to a VL53L0X.
+type Dev struct {
+	c conn.Conn
+
+	mu           sync.Mutex
+	stopVariable byte
+	budget       uint32 // µs
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a VL53L0X.
+//
+// The default address is 0x29.
+//
+// The device is initialized and calibrated, which takes a few dozens of
+// milliseconds.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if addr == 0 || addr > 0x7F {
+		return nil, errors.New("vl53l0x: invalid address")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	if opts.TimingBudget != 0 && (opts.TimingBudget < minBudget || opts.TimingBudget > maxBudget) {
+		return nil, fmt.Errorf("vl53l0x: invalid timing budget %s", opts.TimingBudget)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}}
+	if err := d.init(opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("VL53L0X{%s}", d.c)
+}
+
+// Sense does a single ranging measurement.
+//
+// When no target is detected, the distance returned is 8.190m or more.
+func (d *Dev) Sense(dist *devices.Distance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("vl53l0x: already sensing continuously")
+	}
+	if err := d.restoreStopVariable(); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSysRangeStart, 0x01); err != nil {
+		return err
+	}
+	if err := d.poll(regSysRangeStart, timeout, func(v byte) bool { return v&0x01 == 0 }); err != nil {
+		return err
+	}
+	return d.readRange(dist, timeout)
+}
+
+// SenseContinuous returns measurements on a continuous basis.
+//
+// The device is put in timed continuous mode, where it does a measurement
+// every interval. The interval must be at least the timing budget.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Distance, error) {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if interval < time.Duration(d.budget)*time.Microsecond {
+		return nil, fmt.Errorf("vl53l0x: interval %s is shorter than timing budget %s", interval, time.Duration(d.budget)*time.Microsecond)
+	}
+	if err := d.restoreStopVariable(); err != nil {
+		return nil, err
+	}
+	period := uint32(interval / time.Millisecond)
+	osc, err := d.readReg16(regOscCalibrateVal)
+	if err != nil {
+		return nil, err
+	}
+	if osc != 0 {
+		period *= uint32(osc)
+	}
+	if err := d.write(regSystemIntermeasurementPeriod, byte(period>>24), byte(period>>16), byte(period>>8), byte(period)); err != nil {
+		return nil, err
+	}
+	if err := d.writeReg(regSysRangeStart, 0x04); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Distance)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Halt stops the VL53L0X from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	if !d.stopSensing() {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeRegs([]regVal{
+		{regSysRangeStart, 0x01}, {0xFF, 0x01}, {0x00, 0x00}, {0x91, 0x00}, {0x00, 0x01}, {0xFF, 0x00},
+	})
+}
+
+// TimingBudget returns the current measurement timing budget.
+func (d *Dev) TimingBudget() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return time.Duration(d.budget) * time.Microsecond
+}
+
+// SetTimingBudget changes the measurement timing budget, between 20ms and 1s.
+func (d *Dev) SetTimingBudget(budget time.Duration) error {
+	if budget < minBudget || budget > maxBudget {
+		return fmt.Errorf("vl53l0x: invalid timing budget %s", budget)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("vl53l0x: can't change timing budget while sensing continuously")
+	}
+	return d.setTimingBudget(uint32(budget / time.Microsecond))
+}
+
+//
+
+// Registers.
+const (
+	regSysRangeStart                 = 0x00
+	regSystemSequenceConfig          = 0x01
+	regSystemIntermeasurementPeriod  = 0x04
+	regSystemInterruptConfigGPIO     = 0x0A
+	regSystemInterruptClear          = 0x0B
+	regResultInterruptStatus         = 0x13
+	regResultRange                   = 0x1E
+	regFinalRangeMinCountRateRtn     = 0x44
+	regMsrcConfigTimeoutMacrop       = 0x46
+	regPreRangeConfigVcselPeriod     = 0x50
+	regPreRangeConfigTimeoutMacrop   = 0x51
+	regMsrcConfigControl             = 0x60
+	regFinalRangeConfigVcselPeriod   = 0x70
+	regFinalRangeConfigTimeoutMacrop = 0x71
+	regGPIOHVMuxActiveHigh           = 0x84
+	regVHVConfigPadSCLSDA            = 0x89
+	regGlobalConfigSpadEnablesRef0   = 0xB0
+	regGlobalConfigRefEnStartSelect  = 0xB6
+	regModelID                       = 0xC0
+	regOscCalibrateVal               = 0xF8
+)
+
+const (
+	minBudget = 20 * time.Millisecond
+	maxBudget = time.Second
+	timeout   = 500 * time.Millisecond
+)
+
+type regVal struct {
+	reg, val byte
+}
+
+// tuning is the default tuning settings from the ST API,
+// vl53l0x_tuning.h.
+var tuning = []regVal{
+	{0xFF, 0x01}, {0x00, 0x00},
+	{0xFF, 0x00}, {0x09, 0x00}, {0x10, 0x00}, {0x11, 0x00},
+	{0x24, 0x01}, {0x25, 0xFF}, {0x75, 0x00},
+	{0xFF, 0x01}, {0x4E, 0x2C}, {0x48, 0x00}, {0x30, 0x20},
+	{0xFF, 0x00}, {0x30, 0x09}, {0x54, 0x00}, {0x31, 0x04},
+	{0x32, 0x03}, {0x40, 0x83}, {0x46, 0x25}, {0x60, 0x00},
+	{0x27, 0x00}, {0x50, 0x06}, {0x51, 0x00}, {0x52, 0x96},
+	{0x56, 0x08}, {0x57, 0x30}, {0x61, 0x00}, {0x62, 0x00},
+	{0x64, 0x00}, {0x65, 0x00}, {0x66, 0xA0},
+	{0xFF, 0x01}, {0x22, 0x32}, {0x47, 0x14}, {0x49, 0xFF}, {0x4A, 0x00},
+	{0xFF, 0x00}, {0x7A, 0x0A}, {0x7B, 0x00}, {0x78, 0x21},
+	{0xFF, 0x01}, {0x23, 0x34}, {0x42, 0x00}, {0x44, 0xFF},
+	{0x45, 0x26}, {0x46, 0x05}, {0x40, 0x40}, {0x0E, 0x06},
+	{0x20, 0x1A}, {0x43, 0x40},
+	{0xFF, 0x00}, {0x34, 0x03}, {0x35, 0x44},
+	{0xFF, 0x01}, {0x31, 0x04}, {0x4B, 0x09}, {0x4C, 0x05}, {0x4D, 0x04},
+	{0xFF, 0x00}, {0x44, 0x00}, {0x45, 0x20}, {0x47, 0x08},
+	{0x48, 0x28}, {0x67, 0x00}, {0x70, 0x04}, {0x71, 0x01},
+	{0x72, 0xFE}, {0x76, 0x00}, {0x77, 0x00},
+	{0xFF, 0x01}, {0x0D, 0x01},
+	{0xFF, 0x00}, {0x80, 0x01}, {0x01, 0xF8},
+	{0xFF, 0x01}, {0x8E, 0x01}, {0x00, 0x01},
+	{0xFF, 0x00}, {0x80, 0x00},
+}
+
+func (d *Dev) init(opts *Opts) error {
+	id, err := d.readReg(regModelID)
+	if err != nil {
+		return err
+	}
+	if id != 0xEE {
+		return fmt.Errorf("vl53l0x: unexpected model ID %#02x", id)
+	}
+	if opts.IO2V8 {
+		if err := d.setBits(regVHVConfigPadSCLSDA, 0x01, 0); err != nil {
+			return err
+		}
+	}
+	// Set I²C standard mode and read the stop variable.
+	if err := d.writeRegs([]regVal{{0x88, 0x00}, {0x80, 0x01}, {0xFF, 0x01}, {0x00, 0x00}}); err != nil {
+		return err
+	}
+	if d.stopVariable, err = d.readReg(0x91); err != nil {
+		return err
+	}
+	if err := d.writeRegs([]regVal{{0x00, 0x01}, {0xFF, 0x00}, {0x80, 0x00}}); err != nil {
+		return err
+	}
+	// Disable SIGNAL_RATE_MSRC and SIGNAL_RATE_PRE_RANGE limit checks.
+	if err := d.setBits(regMsrcConfigControl, 0x12, 0); err != nil {
+		return err
+	}
+	// Signal rate limit of 0.25 MCPS in 9.7 fixed point.
+	if err := d.write(regFinalRangeMinCountRateRtn, 0, 32); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemSequenceConfig, 0xFF); err != nil {
+		return err
+	}
+	if err := d.initSpads(); err != nil {
+		return err
+	}
+	if err := d.writeRegs(tuning); err != nil {
+		return err
+	}
+	// Interrupt on new sample ready, active low.
+	if err := d.writeReg(regSystemInterruptConfigGPIO, 0x04); err != nil {
+		return err
+	}
+	if err := d.setBits(regGPIOHVMuxActiveHigh, 0, 0x10); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemInterruptClear, 0x01); err != nil {
+		return err
+	}
+	budget, err := d.timingBudget()
+	if err != nil {
+		return err
+	}
+	// Disable MSRC and TCC by default.
+	if err := d.writeReg(regSystemSequenceConfig, 0xE8); err != nil {
+		return err
+	}
+	if opts.TimingBudget != 0 {
+		budget = uint32(opts.TimingBudget / time.Microsecond)
+	}
+	if err := d.setTimingBudget(budget); err != nil {
+		return err
+	}
+	// VHV and phase calibrations.
+	if err := d.writeReg(regSystemSequenceConfig, 0x01); err != nil {
+		return err
+	}
+	if err := d.refCalibration(0x40); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemSequenceConfig, 0x02); err != nil {
+		return err
+	}
+	if err := d.refCalibration(0x00); err != nil {
+		return err
+	}
+	return d.writeReg(regSystemSequenceConfig, 0xE8)
+}
+
+// initSpads enables the reference SPADs as reported by the device.
+func (d *Dev) initSpads() error {
+	if err := d.writeRegs([]regVal{{0x80, 0x01}, {0xFF, 0x01}, {0x00, 0x00}, {0xFF, 0x06}}); err != nil {
+		return err
+	}
+	if err := d.setBits(0x83, 0x04, 0); err != nil {
+		return err
+	}
+	if err := d.writeRegs([]regVal{{0xFF, 0x07}, {0x81, 0x01}, {0x80, 0x01}, {0x94, 0x6B}, {0x83, 0x00}}); err != nil {
+		return err
+	}
+	if err := d.poll(0x83, timeout, func(v byte) bool { return v != 0 }); err != nil {
+		return err
+	}
+	if err := d.writeReg(0x83, 0x01); err != nil {
+		return err
+	}
+	info, err := d.readReg(0x92)
+	if err != nil {
+		return err
+	}
+	count := int(info & 0x7F)
+	first := 0
+	if info&0x80 != 0 {
+		// Aperture SPADs start at 12.
+		first = 12
+	}
+	if err := d.writeRegs([]regVal{{0x81, 0x00}, {0xFF, 0x06}}); err != nil {
+		return err
+	}
+	if err := d.setBits(0x83, 0, 0x04); err != nil {
+		return err
+	}
+	if err := d.writeRegs([]regVal{{0xFF, 0x01}, {0x00, 0x01}, {0xFF, 0x00}, {0x80, 0x00}}); err != nil {
+		return err
+	}
+
+	var m [6]byte
+	if err := d.read(regGlobalConfigSpadEnablesRef0, m[:]); err != nil {
+		return err
+	}
+	if err := d.writeRegs([]regVal{{0xFF, 0x01}, {0x4F, 0x00}, {0x4E, 0x2C}, {0xFF, 0x00}, {regGlobalConfigRefEnStartSelect, 0xB4}}); err != nil {
+		return err
+	}
+	enabled := 0
+	for i := 0; i < 48; i++ {
+		if i < first || enabled == count {
+			m[i/8] &^= 1 << uint(i%8)
+		} else if m[i/8]&(1<<uint(i%8)) != 0 {
+			enabled++
+		}
+	}
+	return d.write(regGlobalConfigSpadEnablesRef0, m[:]...)
+}
+
+func (d *Dev) refCalibration(vhvInit byte) error {
+	if err := d.writeReg(regSysRangeStart, 0x01|vhvInit); err != nil {
+		return err
+	}
+	if err := d.poll(regResultInterruptStatus, timeout, func(v byte) bool { return v&0x07 != 0 }); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemInterruptClear, 0x01); err != nil {
+		return err
+	}
+	return d.writeReg(regSysRangeStart, 0x00)
+}
+
+func (d *Dev) restoreStopVariable() error {
+	return d.writeRegs([]regVal{
+		{0x80, 0x01}, {0xFF, 0x01}, {0x00, 0x00}, {0x91, d.stopVariable}, {0x00, 0x01}, {0xFF, 0x00}, {0x80, 0x00},
+	})
+}
+
+func (d *Dev) readRange(dist *devices.Distance, wait time.Duration) error {
+	if err := d.poll(regResultInterruptStatus, wait, func(v byte) bool { return v&0x07 != 0 }); err != nil {
+		return err
+	}
+	r, err := d.readReg16(regResultRange)
+	if err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemInterruptClear, 0x01); err != nil {
+		return err
+	}
+	*dist = devices.Distance(r)
+	return nil
+}
+
+func (d *Dev) stopSensing() bool {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop == nil {
+		return false
+	}
+	close(stop)
+	d.wg.Wait()
+	return true
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Distance, stop <-chan struct{}) {
+	for {
+		var dist devices.Distance
+		d.mu.Lock()
+		err := d.readRange(&dist, interval+timeout)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		select {
+		case sensing <- dist:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Timing budget management.
+
+type sequenceSteps struct {
+	tcc, dss, msrc, preRange, finalRange bool
+}
+
+type sequenceTimeouts struct {
+	preRangeVcsel, finalRangeVcsel uint32 // PCLKs
+	msrcDssTccUs                   uint32
+	preRangeMclks, preRangeUs      uint32
+	finalRangeUs                   uint32
+}
+
+// Overheads in µs, from the ST API.
+const (
+	startOverhead      = 1910
+	endOverhead        = 960
+	msrcOverhead       = 660
+	tccOverhead        = 590
+	dssOverhead        = 690
+	preRangeOverhead   = 660
+	finalRangeOverhead = 550
+)
+
+func (d *Dev) sequence() (sequenceSteps, sequenceTimeouts, error) {
+	var s sequenceSteps
+	var t sequenceTimeouts
+	c, err := d.readReg(regSystemSequenceConfig)
+	if err != nil {
+		return s, t, err
+	}
+	s.tcc = c&0x10 != 0
+	s.dss = c&0x08 != 0
+	s.msrc = c&0x04 != 0
+	s.preRange = c&0x40 != 0
+	s.finalRange = c&0x80 != 0
+
+	v, err := d.readReg(regPreRangeConfigVcselPeriod)
+	if err != nil {
+		return s, t, err
+	}
+	t.preRangeVcsel = (uint32(v) + 1) << 1
+	if v, err = d.readReg(regMsrcConfigTimeoutMacrop); err != nil {
+		return s, t, err
+	}
+	t.msrcDssTccUs = mclksToMicroseconds(uint32(v)+1, t.preRangeVcsel)
+	r, err := d.readReg16(regPreRangeConfigTimeoutMacrop)
+	if err != nil {
+		return s, t, err
+	}
+	t.preRangeMclks = decodeTimeout(r)
+	t.preRangeUs = mclksToMicroseconds(t.preRangeMclks, t.preRangeVcsel)
+	if v, err = d.readReg(regFinalRangeConfigVcselPeriod); err != nil {
+		return s, t, err
+	}
+	t.finalRangeVcsel = (uint32(v) + 1) << 1
+	if r, err = d.readReg16(regFinalRangeConfigTimeoutMacrop); err != nil {
+		return s, t, err
+	}
+	finalRangeMclks := decodeTimeout(r)
+	if s.preRange {
+		finalRangeMclks -= t.preRangeMclks
+	}
+	t.finalRangeUs = mclksToMicroseconds(finalRangeMclks, t.finalRangeVcsel)
+	return s, t, nil
+}
+
+// usedBudget returns the time used by all the enabled steps except the final
+// range.
+func usedBudget(s sequenceSteps, t sequenceTimeouts) uint32 {
+	used := uint32(startOverhead + endOverhead)
+	if s.tcc {
+		used += t.msrcDssTccUs + tccOverhead
+	}
+	if s.dss {
+		used += 2 * (t.msrcDssTccUs + dssOverhead)
+	} else if s.msrc {
+		used += t.msrcDssTccUs + msrcOverhead
+	}
+	if s.preRange {
+		used += t.preRangeUs + preRangeOverhead
+	}
+	return used
+}
+
+// timingBudget returns the current timing budget in µs.
+func (d *Dev) timingBudget() (uint32, error) {
+	s, t, err := d.sequence()
+	if err != nil {
+		return 0, err
+	}
+	b := usedBudget(s, t)
+	if s.finalRange {
+		b += t.finalRangeUs + finalRangeOverhead
+	}
+	return b, nil
+}
+
+// setTimingBudget sets the timing budget in µs by adjusting the final range
+// timeout.
+func (d *Dev) setTimingBudget(budget uint32) error {
+	s, t, err := d.sequence()
+	if err != nil {
+		return err
+	}
+	if s.finalRange {
+		used := usedBudget(s, t) + finalRangeOverhead
+		if used > budget {
+			return fmt.Errorf("vl53l0x: timing budget %s is too short", time.Duration(budget)*time.Microsecond)
+		}
+		mclks := microsecondsToMclks(budget-used, t.finalRangeVcsel)
+		if s.preRange {
+			mclks += t.preRangeMclks
+		}
+		v := encodeTimeout(mclks)
+		if err := d.write(regFinalRangeConfigTimeoutMacrop, byte(v>>8), byte(v)); err != nil {
+			return err
+		}
+	}
+	d.budget = budget
+	return nil
+}
+
+// macroPeriod returns the macro period in ns for the VCSEL period in PCLKs.
+func macroPeriod(vcsel uint32) uint32 {
+	return (2304*vcsel*1655 + 500) / 1000
+}
+
+func mclksToMicroseconds(mclks, vcsel uint32) uint32 {
+	p := macroPeriod(vcsel)
+	return (mclks*p + 500) / 1000
+}
+
+func microsecondsToMclks(us, vcsel uint32) uint32 {
+	p := macroPeriod(vcsel)
+	return (us*1000 + p/2) / p
+}
+
+// decodeTimeout decodes the "(LSByte * 2^MSByte) + 1" timeout format.
+func decodeTimeout(v uint16) uint32 {
+	return uint32(v&0xFF)<<(v>>8) + 1
+}
+
+func encodeTimeout(mclks uint32) uint16 {
+	if mclks == 0 {
+		return 0
+	}
+	ls := mclks - 1
+	ms := uint16(0)
+	for ls&0xFFFFFF00 != 0 {
+		ls >>= 1
+		ms++
+	}
+	return ms<<8 | uint16(ls&0xFF)
+}
+
+// Register access.
+
+func (d *Dev) poll(reg byte, wait time.Duration, done func(v byte) bool) error {
+	deadline := time.Now().Add(wait)
+	for {
+		v, err := d.readReg(reg)
+		if err != nil {
+			return err
+		}
+		if done(v) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("vl53l0x: timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// setBits reads reg, sets the bits in set and clears the bits in clear.
+func (d *Dev) setBits(reg, set, clear byte) error {
+	v, err := d.readReg(reg)
+	if err != nil {
+		return err
+	}
+	return d.writeReg(reg, v&^clear|set)
+}
+
+func (d *Dev) read(reg byte, b []byte) error {
+	if err := d.c.Tx([]byte{reg}, b); err != nil {
+		return fmt.Errorf("vl53l0x: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) readReg(reg byte) (byte, error) {
+	var b [1]byte
+	err := d.read(reg, b[:])
+	return b[0], err
+}
+
+func (d *Dev) readReg16(reg byte) (uint16, error) {
+	var b [2]byte
+	err := d.read(reg, b[:])
+	return uint16(b[0])<<8 | uint16(b[1]), err
+}
+
+func (d *Dev) write(reg byte, b ...byte) error {
+	if err := d.c.Tx(append([]byte{reg}, b...), nil); err != nil {
+		return fmt.Errorf("vl53l0x: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) writeReg(reg, v byte) error {
+	return d.write(reg, v)
+}
+
+func (d *Dev) writeRegs(regs []regVal) error {
+	for _, r := range regs {
+		if err := d.write(r.reg, r.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var defaults = Opts{
+	IO2V8: true,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,276 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package vl53l0x
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x29, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize vl53l0x: %v", err)
+	}
+	var d devices.Distance
+	if err := dev.Sense(&d); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", d)
+}
+
+//
+
+func TestNewI2C_Sense(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "VL53L0X{fake(41)}" {
+		t.Fatal(s)
+	}
+	if b := dev.TimingBudget(); b != 33849*time.Microsecond {
+		t.Fatal(b)
+	}
+	if v := f.get(0, 0x89); v != 0x01 {
+		t.Fatalf("2V8 mode not set: %#x", v)
+	}
+	if v := f.get(0, 0x84); v != 0x01 {
+		t.Fatalf("interrupt polarity: %#x", v)
+	}
+	spads := []byte{f.get(0, 0xB0), f.get(0, 0xB1), f.get(0, 0xB2), f.get(0, 0xB3), f.get(0, 0xB4), f.get(0, 0xB5)}
+	if !bytes.Equal(spads, []byte{0x00, 0xF0, 0x01, 0x00, 0x00, 0x00}) {
+		t.Fatalf("%#v", spads)
+	}
+	if r := [2]byte{f.get(0, 0x71), f.get(0, 0x72)}; r != [2]byte{0x02, 0x90} {
+		t.Fatalf("final range timeout: %#v", r)
+	}
+	if v := f.get(0, 0x01); v != 0xE8 {
+		t.Fatalf("sequence: %#x", v)
+	}
+	var d devices.Distance
+	if err := dev.Sense(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d != 300 {
+		t.Fatal(d)
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(newFakeBus(), 0, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(newFakeBus(), 0x29, &Opts{TimingBudget: time.Millisecond}); err == nil {
+		t.Fatal("invalid budget")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x29, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	f := newFakeBus()
+	f.set(0xC0, 0xEA)
+	if _, err := NewI2C(f, 0x29, nil); err == nil {
+		t.Fatal("invalid model")
+	}
+}
+
+func TestTimingBudget(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, &Opts{TimingBudget: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := dev.TimingBudget(); b != 200*time.Millisecond {
+		t.Fatal(b)
+	}
+	if v := f.get(0, 0x89); v != 0 {
+		t.Fatalf("2V8 mode set: %#x", v)
+	}
+	if err := dev.SetTimingBudget(time.Millisecond); err == nil {
+		t.Fatal("invalid budget")
+	}
+	if err := dev.SetTimingBudget(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := dev.timingBudget(); err != nil || b < 19900 || b > 20100 {
+		t.Fatal(b, err)
+	}
+	// Enable all the steps, the overhead is then larger than the budget.
+	f.set(0x01, 0xFF)
+	if err := dev.SetTimingBudget(20 * time.Millisecond); err == nil {
+		t.Fatal("budget too short")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(time.Millisecond); err == nil {
+		t.Fatal("interval shorter than budget")
+	}
+	c, err := dev.SenseContinuous(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := [4]byte{f.get(0, 0x04), f.get(0, 0x05), f.get(0, 0x06), f.get(0, 0x07)}; p != [4]byte{0, 0, 0x06, 0x40} {
+		t.Fatalf("%#v", p)
+	}
+	for i := 0; i < 2; i++ {
+		if d := <-c; d != 300 {
+			t.Fatal(d)
+		}
+	}
+	var d devices.Distance
+	if err := dev.Sense(&d); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.SetTimingBudget(50 * time.Millisecond); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	if v := f.get(1, 0x91); v != 0 {
+		t.Fatalf("stop variable: %#x", v)
+	}
+}
+
+func TestSense_timeout(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.hold = true
+	f.mu.Unlock()
+	var d devices.Distance
+	if err := dev.Sense(&d); err == nil {
+		t.Fatal("timed out")
+	}
+}
+
+func TestTimeoutEncoding(t *testing.T) {
+	for _, v := range []uint32{1, 2, 255, 256, 577, 65536} {
+		e := encodeTimeout(v)
+		if d := decodeTimeout(e); d > v || d < v-v/128 {
+			t.Fatalf("%d -> %#x -> %d", v, e, d)
+		}
+	}
+	if e := encodeTimeout(0); e != 0 {
+		t.Fatal(e)
+	}
+}
+
+//
+
+// fakeBus simulates the VL53L0X registers, including the register pages
+// selected by writing to 0xFF.
+type fakeBus struct {
+	mu         sync.Mutex
+	page       byte
+	regs       map[[2]byte]byte
+	continuous bool
+	hold       bool // Never signal measurement ready
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{regs: map[[2]byte]byte{}}
+	f.set(0xC0, 0xEE)
+	f.set(0x84, 0x11)
+	// Range of 300mm.
+	f.set(0x1E, 0x01)
+	f.set(0x1F, 0x2C)
+	// Oscillator calibration.
+	f.set(0xF9, 0x10)
+	for i := byte(0); i < 6; i++ {
+		f.set(0xB0+i, 0xFF)
+	}
+	f.regs[[2]byte{1, 0x91}] = 0x3C
+	// 5 aperture SPADs.
+	f.regs[[2]byte{7, 0x92}] = 0x85
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reg := w[0]
+	for i, v := range w[1:] {
+		f.set(reg+byte(i), v)
+	}
+	for i := range r {
+		r[i] = f.regs[[2]byte{f.page, reg + byte(i)}]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) get(page, reg byte) byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.regs[[2]byte{page, reg}]
+}
+
+func (f *fakeBus) set(reg, v byte) {
+	if reg == 0xFF {
+		f.page = v
+		return
+	}
+	f.regs[[2]byte{f.page, reg}] = v
+	switch {
+	case f.page == 7 && reg == 0x83 && v == 0:
+		// SPAD info ready.
+		f.regs[[2]byte{7, 0x83}] = 0x10
+	case f.page == 0 && reg == 0x00:
+		f.continuous = v&0x04 != 0
+		if v&0x05 == 0 {
+			return
+		}
+		f.regs[[2]byte{0, 0x00}] = 0
+		if !f.hold {
+			f.regs[[2]byte{0, 0x13}] = 0x07
+		}
+	case f.page == 0 && reg == 0x0B:
+		f.regs[[2]byte{0, 0x13}] = 0
+		if f.continuous {
+			f.regs[[2]byte{0, 0x13}] = 0x07
+		}
+	}
+}
@@ -0,0 +1,792 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package vl53l1x controls a ST VL53L1X long distance time-of-flight ranging
+// sensor over I²C.
+//
+// The configuration sequence comes from the ST API, as the datasheet doesn't
+// describe the registers. The device is used in the low power autonomous mode
+// with the VHV, phase calibration, DSS1 and range sequence steps.
+//
+// Datasheet
+//
+// http://www.st.com/resource/en/datasheet/vl53l1x.pdf
+package vl53l1x
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/devices"
+)
+
+// DistanceMode trades maximum distance for ambient light immunity.
+type DistanceMode uint8
+
+// Possible distance modes.
+const (
+	Long   DistanceMode = 0 // Up to 4m in the dark
+	Medium DistanceMode = 1 // Up to 3m in the dark
+	Short  DistanceMode = 2 // Up to 1.3m, better ambient immunity
+)
+
+const distanceModeName = "LongMediumShort"
+
+var distanceModeIndex = [...]uint8{0, 4, 10, 15}
+
+func (m DistanceMode) String() string {
+	if m >= DistanceMode(len(distanceModeIndex)-1) {
+		return fmt.Sprintf("DistanceMode(%d)", m)
+	}
+	return distanceModeName[distanceModeIndex[m]:distanceModeIndex[m+1]]
+}
+
+// RangeStatus is the validity of a measurement.
+type RangeStatus uint8
+
+// Possible range status.
+const (
+	RangeValid                RangeStatus = 0
+	SigmaFail                 RangeStatus = 1 // Too much noise
+	SignalFail                RangeStatus = 2 // Return signal too low
+	MinRangeFail              RangeStatus = 3
+	OutOfBoundsFail           RangeStatus = 4 // Target beyond the range of the distance mode
+	HardwareFail              RangeStatus = 5
+	RangeValidNoWrapCheckFail RangeStatus = 6 // First measurement after start
+	WrapTargetFail            RangeStatus = 7
+	XtalkSignalFail           RangeStatus = 9
+	SynchronizationInt        RangeStatus = 10
+	MinRangeClipped           RangeStatus = 13 // Valid but the distance is clipped
+	NoUpdate                  RangeStatus = 255
+)
+
+func (r RangeStatus) String() string {
+	switch r {
+	case RangeValid:
+		return "RangeValid"
+	case SigmaFail:
+		return "SigmaFail"
+	case SignalFail:
+		return "SignalFail"
+	case MinRangeFail:
+		return "MinRangeFail"
+	case OutOfBoundsFail:
+		return "OutOfBoundsFail"
+	case HardwareFail:
+		return "HardwareFail"
+	case RangeValidNoWrapCheckFail:
+		return "RangeValidNoWrapCheckFail"
+	case WrapTargetFail:
+		return "WrapTargetFail"
+	case XtalkSignalFail:
+		return "XtalkSignalFail"
+	case SynchronizationInt:
+		return "SynchronizationInt"
+	case MinRangeClipped:
+		return "MinRangeClipped"
+	case NoUpdate:
+		return "NoUpdate"
+	default:
+		return fmt.Sprintf("RangeStatus(%d)", r)
+	}
+}
+
+// Valid returns true if the distance measured can be trusted.
+func (r RangeStatus) Valid() bool {
+	return r == RangeValid || r == RangeValidNoWrapCheckFail || r == MinRangeClipped
+}
+
+// Range is a complete ranging measurement.
+type Range struct {
+	Distance devices.Distance
+	Status   RangeStatus
+	// PeakSignal and Ambient are the return signal and ambient rates in MCPS
+	// in 9.7 fixed point.
+	PeakSignal uint16
+	Ambient    uint16
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	DistanceMode DistanceMode
+	// TimingBudget is the time allowed for one measurement, between 20ms and
+	// 1s. A budget of at least 33ms is needed in Long mode. A longer budget
+	// improves accuracy. Defaults to 50ms.
+	TimingBudget time.Duration
+	// IO2V8 configures the I/O pads for 2.8V instead of 1.8V. Most breakout
+	// boards need it.
+	IO2V8 bool
+}
+
+// Dev is a handle to a VL53L1X.
+type Dev struct {
+	c conn.Conn
+
+	mu              sync.Mutex
+	fastOscFreq     uint16
+	oscCalibrateVal uint16
+	calibrated      bool
+	savedVHVInit    byte
+	savedVHVTimeout byte
+	stop            chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewI2C returns an object that communicates over I²C to a VL53L1X.
+//
+// The default address is 0x29.
+func NewI2C(b i2c.Bus, addr uint16, opts *Opts) (*Dev, error) {
+	if addr == 0 || addr > 0x7F {
+		return nil, errors.New("vl53l1x: invalid address")
+	}
+	if opts == nil {
+		opts = &defaults
+	}
+	o := *opts
+	if o.TimingBudget == 0 {
+		o.TimingBudget = defaults.TimingBudget
+	}
+	if o.DistanceMode > Short {
+		return nil, fmt.Errorf("vl53l1x: invalid distance mode %d", o.DistanceMode)
+	}
+	if o.TimingBudget < minBudget || o.TimingBudget > maxBudget {
+		return nil, fmt.Errorf("vl53l1x: invalid timing budget %s", o.TimingBudget)
+	}
+	d := &Dev{c: &i2c.Dev{Bus: b, Addr: addr}}
+	if err := d.init(&o); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("VL53L1X{%s}", d.c)
+}
+
+// Sense does a single ranging measurement.
+//
+// Returns an error if the measurement is not valid, for example because no
+// target was detected.
+func (d *Dev) Sense(dist *devices.Distance) error {
+	var r Range
+	if err := d.SenseRange(&r); err != nil {
+		return err
+	}
+	if !r.Status.Valid() {
+		return fmt.Errorf("vl53l1x: invalid measurement: %s", r.Status)
+	}
+	*dist = r.Distance
+	return nil
+}
+
+// SenseRange does a single ranging measurement and returns all the details
+// about the measurement.
+func (d *Dev) SenseRange(r *Range) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("vl53l1x: already sensing continuously")
+	}
+	if err := d.writeReg(regSystemInterruptClear, 0x01); err != nil {
+		return err
+	}
+	if err := d.writeReg(regSystemModeStart, 0x10); err != nil {
+		return err
+	}
+	return d.read(r, timeout)
+}
+
+// SenseContinuous returns measurements on a continuous basis.
+//
+// The device does a measurement every interval, which must be at least the
+// timing budget. Invalid measurements are skipped.
+//
+// The application must call Halt() to stop the sensing when done to stop the
+// sensor and close the channel.
+func (d *Dev) SenseContinuous(interval time.Duration) (<-chan devices.Distance, error) {
+	d.stopSensing()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	budget, err := d.timingBudget()
+	if err != nil {
+		return nil, err
+	}
+	if interval < budget {
+		return nil, fmt.Errorf("vl53l1x: interval %s is shorter than timing budget %s", interval, budget)
+	}
+	period := uint32(interval/time.Millisecond) * uint32(d.oscCalibrateVal)
+	if err := d.writeReg32(regSystemIntermeasurementPeriod, period); err != nil {
+		return nil, err
+	}
+	if err := d.writeReg(regSystemInterruptClear, 0x01); err != nil {
+		return nil, err
+	}
+	if err := d.writeReg(regSystemModeStart, 0x40); err != nil {
+		return nil, err
+	}
+	sensing := make(chan devices.Distance)
+	d.stop = make(chan struct{})
+	d.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer d.wg.Done()
+		defer close(sensing)
+		d.sensingContinuous(interval, sensing, stop)
+	}(d.stop)
+	return sensing, nil
+}
+
+// Halt stops the VL53L1X from acquiring measurements as initiated by
+// SenseContinuous().
+func (d *Dev) Halt() error {
+	if !d.stopSensing() {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeReg(regSystemModeStart, 0x80); err != nil {
+		return err
+	}
+	// Restore the VHV configuration and remove the phase calibration override.
+	d.calibrated = false
+	if d.savedVHVInit != 0 {
+		if err := d.writeReg(regVHVConfigInit, d.savedVHVInit); err != nil {
+			return err
+		}
+	}
+	if d.savedVHVTimeout != 0 {
+		if err := d.writeReg(regVHVConfigTimeoutMacropLoopBound, d.savedVHVTimeout); err != nil {
+			return err
+		}
+	}
+	return d.writeReg(regPhasecalConfigOverride, 0x00)
+}
+
+// SetDistanceMode changes the distance mode, keeping the timing budget.
+func (d *Dev) SetDistanceMode(m DistanceMode) error {
+	if m > Short {
+		return fmt.Errorf("vl53l1x: invalid distance mode %d", m)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("vl53l1x: can't change distance mode while sensing continuously")
+	}
+	return d.setDistanceMode(m)
+}
+
+// TimingBudget returns the current measurement timing budget.
+func (d *Dev) TimingBudget() (time.Duration, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.timingBudget()
+}
+
+// SetTimingBudget changes the measurement timing budget, between 20ms and 1s.
+func (d *Dev) SetTimingBudget(budget time.Duration) error {
+	if budget < minBudget || budget > maxBudget {
+		return fmt.Errorf("vl53l1x: invalid timing budget %s", budget)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return errors.New("vl53l1x: can't change timing budget while sensing continuously")
+	}
+	return d.setTimingBudget(budget)
+}
+
+//
+
+// Registers.
+const (
+	regSoftReset                        = 0x0000
+	regOscMeasuredFastOscFrequency      = 0x0006
+	regVHVConfigTimeoutMacropLoopBound  = 0x0008
+	regVHVConfigInit                    = 0x000B
+	regAlgoPartToPartRangeOffsetMM      = 0x001E
+	regMMConfigOuterOffsetMM            = 0x0022
+	regDSSConfigTargetTotalRateMCPS     = 0x0024
+	regPadI2CHVExtsupConfig             = 0x002E
+	regGPIOTioHVStatus                  = 0x0031
+	regCalConfigVcselStart              = 0x0047
+	regPhasecalConfigTimeoutMacrop      = 0x004B
+	regPhasecalConfigOverride           = 0x004D
+	regDSSConfigManualEffectiveSpadsSel = 0x0054
+	regMMConfigTimeoutMacropA           = 0x005A
+	regMMConfigTimeoutMacropB           = 0x005C
+	regRangeConfigTimeoutMacropA        = 0x005E
+	regRangeConfigVcselPeriodA          = 0x0060
+	regRangeConfigTimeoutMacropB        = 0x0061
+	regRangeConfigVcselPeriodB          = 0x0063
+	regRangeConfigValidPhaseHigh        = 0x0069
+	regSystemIntermeasurementPeriod     = 0x006C
+	regSDConfigWOISD0                   = 0x0078
+	regSDConfigWOISD1                   = 0x0079
+	regSDConfigInitialPhaseSD0          = 0x007A
+	regSDConfigInitialPhaseSD1          = 0x007B
+	regSystemInterruptClear             = 0x0086
+	regSystemModeStart                  = 0x0087
+	regResultRangeStatus                = 0x0089
+	regPhasecalResultVcselStart         = 0x00D8
+	regResultOscCalibrateVal            = 0x00DE
+	regFirmwareSystemStatus             = 0x00E5
+	regIdentificationModelID            = 0x010F
+)
+
+const (
+	minBudget = 20 * time.Millisecond
+	maxBudget = time.Second
+	timeout   = 500 * time.Millisecond
+	// timingGuard is the fixed overhead of a measurement in µs.
+	timingGuard = 4528
+	// targetRate is the DSS target total rate in MCPS, 9.7 fixed point.
+	targetRate = 0x0A00
+)
+
+type regVal struct {
+	reg uint16
+	val byte
+}
+
+// staticInit is the static configuration from the ST API for the low power
+// autonomous preset mode.
+var staticInit = []regVal{
+	{regDSSConfigTargetTotalRateMCPS, targetRate >> 8},
+	{regDSSConfigTargetTotalRateMCPS + 1, targetRate & 0xFF},
+	{regGPIOTioHVStatus, 0x02},
+	{0x0036, 8},    // SIGMA_ESTIMATOR__EFFECTIVE_PULSE_WIDTH_NS
+	{0x0037, 16},   // SIGMA_ESTIMATOR__EFFECTIVE_AMBIENT_WIDTH_NS
+	{0x0039, 0x01}, // ALGO__CROSSTALK_COMPENSATION_VALID_HEIGHT_MM
+	{0x003E, 0xFF}, // ALGO__RANGE_IGNORE_VALID_HEIGHT_MM
+	{0x003F, 0},    // ALGO__RANGE_MIN_CLIP
+	{0x0040, 2},    // ALGO__CONSISTENCY_CHECK__TOLERANCE
+	{0x0050, 0x00}, // SYSTEM__THRESH_RATE_HIGH
+	{0x0051, 0x00},
+	{0x0052, 0x00}, // SYSTEM__THRESH_RATE_LOW
+	{0x0053, 0x00},
+	{0x0057, 0x38}, // DSS_CONFIG__APERTURE_ATTENUATION
+	{0x0064, 0x01}, // RANGE_CONFIG__SIGMA_THRESH = 360
+	{0x0065, 0x68},
+	{0x0066, 0x00}, // RANGE_CONFIG__MIN_COUNT_RATE_RTN_LIMIT_MCPS = 192
+	{0x0067, 0xC0},
+	{0x0071, 0x01}, // SYSTEM__GROUPED_PARAMETER_HOLD_0
+	{0x007C, 0x01}, // SYSTEM__GROUPED_PARAMETER_HOLD_1
+	{0x007E, 2},    // SD_CONFIG__QUANTIFIER
+	{0x0082, 0x00}, // SYSTEM__GROUPED_PARAMETER_HOLD
+	{0x0077, 1},    // SYSTEM__SEED_CONFIG
+	{0x0081, 0x8B}, // SYSTEM__SEQUENCE_CONFIG: VHV, PHASECAL, DSS1, RANGE
+	{regDSSConfigManualEffectiveSpadsSel, 200},
+	{regDSSConfigManualEffectiveSpadsSel + 1, 0},
+	{0x004F, 2}, // DSS_CONFIG__ROI_MODE_CONTROL: requested effective SPADs
+}
+
+// distanceModes is indexed by DistanceMode and contains the values for
+// VCSEL_PERIOD_A, VCSEL_PERIOD_B, VALID_PHASE_HIGH, WOI_SD0, WOI_SD1,
+// INITIAL_PHASE_SD0 and INITIAL_PHASE_SD1.
+var distanceModes = [...][7]byte{
+	{0x0F, 0x0D, 0xB8, 0x0F, 0x0D, 14, 14},
+	{0x0B, 0x09, 0x78, 0x0B, 0x09, 10, 10},
+	{0x07, 0x05, 0x38, 0x07, 0x05, 6, 6},
+}
+
+func (d *Dev) init(opts *Opts) error {
+	id, err := d.readReg16(regIdentificationModelID)
+	if err != nil {
+		return err
+	}
+	if id != 0xEACC {
+		return fmt.Errorf("vl53l1x: unexpected model ID %#04x", id)
+	}
+	if err := d.writeReg(regSoftReset, 0x00); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Microsecond)
+	if err := d.writeReg(regSoftReset, 0x01); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond)
+	if err := d.poll(regFirmwareSystemStatus, func(v byte) bool { return v&0x01 != 0 }); err != nil {
+		return err
+	}
+	if opts.IO2V8 {
+		v, err := d.readReg(regPadI2CHVExtsupConfig)
+		if err != nil {
+			return err
+		}
+		if err := d.writeReg(regPadI2CHVExtsupConfig, v|0x01); err != nil {
+			return err
+		}
+	}
+	if d.fastOscFreq, err = d.readReg16(regOscMeasuredFastOscFrequency); err != nil {
+		return err
+	}
+	if d.fastOscFreq == 0 {
+		return errors.New("vl53l1x: invalid oscillator frequency")
+	}
+	if d.oscCalibrateVal, err = d.readReg16(regResultOscCalibrateVal); err != nil {
+		return err
+	}
+	for _, r := range staticInit {
+		if err := d.writeReg(r.reg, r.val); err != nil {
+			return err
+		}
+	}
+	if err := d.applyDistanceMode(opts.DistanceMode); err != nil {
+		return err
+	}
+	if err := d.setTimingBudget(opts.TimingBudget); err != nil {
+		return err
+	}
+	// Assumes MM1 and MM2 are disabled.
+	offset, err := d.readReg16(regMMConfigOuterOffsetMM)
+	if err != nil {
+		return err
+	}
+	return d.writeReg16(regAlgoPartToPartRangeOffsetMM, offset*4)
+}
+
+func (d *Dev) setDistanceMode(m DistanceMode) error {
+	budget, err := d.timingBudget()
+	if err != nil {
+		return err
+	}
+	if err := d.applyDistanceMode(m); err != nil {
+		return err
+	}
+	// The timeouts depend on the VCSEL periods.
+	return d.setTimingBudget(budget)
+}
+
+func (d *Dev) applyDistanceMode(m DistanceMode) error {
+	v := distanceModes[m]
+	regs := [...]uint16{
+		regRangeConfigVcselPeriodA, regRangeConfigVcselPeriodB, regRangeConfigValidPhaseHigh,
+		regSDConfigWOISD0, regSDConfigWOISD1, regSDConfigInitialPhaseSD0, regSDConfigInitialPhaseSD1,
+	}
+	for i, r := range regs {
+		if err := d.writeReg(r, v[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timingBudget returns the timing budget; the range timeout is used twice,
+// for the A and B VCSEL periods.
+func (d *Dev) timingBudget() (time.Duration, error) {
+	p, err := d.readReg(regRangeConfigVcselPeriodA)
+	if err != nil {
+		return 0, err
+	}
+	t, err := d.readReg16(regRangeConfigTimeoutMacropA)
+	if err != nil {
+		return 0, err
+	}
+	us := mclksToMicroseconds(decodeTimeout(t), d.macroPeriod(p))
+	return time.Duration(2*us+timingGuard) * time.Microsecond, nil
+}
+
+func (d *Dev) setTimingBudget(budget time.Duration) error {
+	us := uint32(budget / time.Microsecond)
+	if us <= timingGuard {
+		return fmt.Errorf("vl53l1x: timing budget %s is too short", budget)
+	}
+	rangeTimeout := (us - timingGuard) / 2
+
+	p, err := d.readReg(regRangeConfigVcselPeriodA)
+	if err != nil {
+		return err
+	}
+	mp := d.macroPeriod(p)
+	// The phase calibration timeout is 1ms by default.
+	phasecal := microsecondsToMclks(1000, mp)
+	if phasecal > 0xFF {
+		phasecal = 0xFF
+	}
+	if err := d.writeReg(regPhasecalConfigTimeoutMacrop, byte(phasecal)); err != nil {
+		return err
+	}
+	if err := d.writeReg16(regMMConfigTimeoutMacropA, encodeTimeout(microsecondsToMclks(1, mp))); err != nil {
+		return err
+	}
+	if err := d.writeReg16(regRangeConfigTimeoutMacropA, encodeTimeout(microsecondsToMclks(rangeTimeout, mp))); err != nil {
+		return err
+	}
+
+	if p, err = d.readReg(regRangeConfigVcselPeriodB); err != nil {
+		return err
+	}
+	mp = d.macroPeriod(p)
+	if err := d.writeReg16(regMMConfigTimeoutMacropB, encodeTimeout(microsecondsToMclks(1, mp))); err != nil {
+		return err
+	}
+	return d.writeReg16(regRangeConfigTimeoutMacropB, encodeTimeout(microsecondsToMclks(rangeTimeout, mp)))
+}
+
+// macroPeriod returns the macro period in µs in 12.12 fixed point for the
+// encoded VCSEL period.
+func (d *Dev) macroPeriod(vcsel byte) uint32 {
+	// PLL period in µs in 0.24 fixed point; fastOscFreq is in 4.12 format.
+	pll := uint32(1<<30) / uint32(d.fastOscFreq)
+	pclks := (uint32(vcsel) + 1) << 1
+	mp := 2304 * pll
+	mp >>= 6
+	mp *= pclks
+	mp >>= 6
+	return mp
+}
+
+func mclksToMicroseconds(mclks, macroPeriod uint32) uint32 {
+	return uint32((uint64(mclks)*uint64(macroPeriod) + 0x800) >> 12)
+}
+
+func microsecondsToMclks(us, macroPeriod uint32) uint32 {
+	return (us<<12 + macroPeriod>>1) / macroPeriod
+}
+
+// decodeTimeout decodes the "(LSByte * 2^MSByte) + 1" timeout format.
+func decodeTimeout(v uint16) uint32 {
+	return uint32(v&0xFF)<<(v>>8) + 1
+}
+
+func encodeTimeout(mclks uint32) uint16 {
+	if mclks == 0 {
+		return 0
+	}
+	ls := mclks - 1
+	ms := uint16(0)
+	for ls&0xFFFFFF00 != 0 {
+		ls >>= 1
+		ms++
+	}
+	return ms<<8 | uint16(ls&0xFF)
+}
+
+func (d *Dev) stopSensing() bool {
+	d.mu.Lock()
+	stop := d.stop
+	d.stop = nil
+	d.mu.Unlock()
+	if stop == nil {
+		return false
+	}
+	close(stop)
+	d.wg.Wait()
+	return true
+}
+
+func (d *Dev) sensingContinuous(interval time.Duration, sensing chan<- devices.Distance, stop <-chan struct{}) {
+	for {
+		var r Range
+		d.mu.Lock()
+		err := d.read(&r, interval+timeout)
+		d.mu.Unlock()
+		if err != nil {
+			log.Printf("%s: failed to sense: %v", d, err)
+			return
+		}
+		if !r.Status.Valid() {
+			select {
+			case <-stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case sensing <- r.Distance:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// read waits for a measurement, reads it and clears the interrupt.
+func (d *Dev) read(r *Range, wait time.Duration) error {
+	// GPIO__TIO_HV_STATUS bit 0 is cleared when data is ready; the interrupt
+	// polarity is active low by default.
+	deadline := time.Now().Add(wait)
+	for {
+		v, err := d.readReg(regGPIOTioHVStatus)
+		if err != nil {
+			return err
+		}
+		if v&0x01 == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.New("vl53l1x: timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var b [17]byte
+	if err := d.readRegs(regResultRangeStatus, b[:]); err != nil {
+		return err
+	}
+	if !d.calibrated {
+		if err := d.setupManualCalibration(); err != nil {
+			return err
+		}
+		d.calibrated = true
+	}
+	spads := uint16(b[3])<<8 | uint16(b[4])
+	ambient := uint16(b[7])<<8 | uint16(b[8])
+	rng := uint16(b[13])<<8 | uint16(b[14])
+	peak := uint16(b[15])<<8 | uint16(b[16])
+	if err := d.updateDSS(spads, ambient, peak); err != nil {
+		return err
+	}
+	r.Distance = devices.Distance((uint32(rng)*2011 + 0x400) / 0x800)
+	r.Status = decodeStatus(b[0]&0x1F, b[2])
+	r.PeakSignal = peak
+	r.Ambient = ambient
+	return d.writeReg(regSystemInterruptClear, 0x01)
+}
+
+// setupManualCalibration disables the VHV calibration and forces the phase
+// calibration found on the first measurement.
+func (d *Dev) setupManualCalibration() error {
+	var err error
+	if d.savedVHVInit, err = d.readReg(regVHVConfigInit); err != nil {
+		return err
+	}
+	if d.savedVHVTimeout, err = d.readReg(regVHVConfigTimeoutMacropLoopBound); err != nil {
+		return err
+	}
+	if err := d.writeReg(regVHVConfigInit, d.savedVHVInit&0x7F); err != nil {
+		return err
+	}
+	if err := d.writeReg(regVHVConfigTimeoutMacropLoopBound, d.savedVHVTimeout&0x03+3<<2); err != nil {
+		return err
+	}
+	if err := d.writeReg(regPhasecalConfigOverride, 0x01); err != nil {
+		return err
+	}
+	v, err := d.readReg(regPhasecalResultVcselStart)
+	if err != nil {
+		return err
+	}
+	return d.writeReg(regCalConfigVcselStart, v)
+}
+
+// updateDSS adjusts the number of SPADs used for the next measurement based
+// on the signal rates.
+func (d *Dev) updateDSS(spads, ambient, peak uint16) error {
+	required := uint32(0x8000)
+	if spads != 0 {
+		total := uint32(peak) + uint32(ambient)
+		if total > 0xFFFF {
+			total = 0xFFFF
+		}
+		total = (total << 16) / uint32(spads)
+		if total != 0 {
+			required = (uint32(targetRate) << 16) / total
+			if required > 0xFFFF {
+				required = 0xFFFF
+			}
+		}
+	}
+	return d.writeReg16(regDSSConfigManualEffectiveSpadsSel, uint16(required))
+}
+
+// decodeStatus converts the device range status to a RangeStatus.
+func decodeStatus(status, streamCount byte) RangeStatus {
+	switch status {
+	case 1, 2, 3, 17:
+		return HardwareFail
+	case 13:
+		return MinRangeFail
+	case 18:
+		return SynchronizationInt
+	case 5:
+		return OutOfBoundsFail
+	case 4:
+		return SignalFail
+	case 6:
+		return SigmaFail
+	case 7:
+		return WrapTargetFail
+	case 12:
+		return XtalkSignalFail
+	case 8:
+		return MinRangeClipped
+	case 9:
+		if streamCount == 0 {
+			return RangeValidNoWrapCheckFail
+		}
+		return RangeValid
+	default:
+		return NoUpdate
+	}
+}
+
+// Register access.
+
+func (d *Dev) poll(reg uint16, done func(v byte) bool) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		v, err := d.readReg(reg)
+		if err != nil {
+			return err
+		}
+		if done(v) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("vl53l1x: timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (d *Dev) readRegs(reg uint16, b []byte) error {
+	if err := d.c.Tx([]byte{byte(reg >> 8), byte(reg)}, b); err != nil {
+		return fmt.Errorf("vl53l1x: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) readReg(reg uint16) (byte, error) {
+	var b [1]byte
+	err := d.readRegs(reg, b[:])
+	return b[0], err
+}
+
+func (d *Dev) readReg16(reg uint16) (uint16, error) {
+	var b [2]byte
+	err := d.readRegs(reg, b[:])
+	return uint16(b[0])<<8 | uint16(b[1]), err
+}
+
+func (d *Dev) write(reg uint16, b ...byte) error {
+	if err := d.c.Tx(append([]byte{byte(reg >> 8), byte(reg)}, b...), nil); err != nil {
+		return fmt.Errorf("vl53l1x: %v", err)
+	}
+	return nil
+}
+
+func (d *Dev) writeReg(reg uint16, v byte) error {
+	return d.write(reg, v)
+}
+
+func (d *Dev) writeReg16(reg uint16, v uint16) error {
+	return d.write(reg, byte(v>>8), byte(v))
+}
+
+func (d *Dev) writeReg32(reg uint16, v uint32) error {
+	return d.write(reg, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+var defaults = Opts{
+	DistanceMode: Long,
+	TimingBudget: 50 * time.Millisecond,
+	IO2V8:        true,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
@@ -0,0 +1,362 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package vl53l1x
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/devices"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+	dev, err := NewI2C(bus, 0x29, &Opts{DistanceMode: Short, TimingBudget: 20 * time.Millisecond, IO2V8: true})
+	if err != nil {
+		log.Fatalf("failed to initialize vl53l1x: %v", err)
+	}
+	c, err := dev.SenseContinuous(50 * time.Millisecond)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dev.Halt()
+	for i := 0; i < 10; i++ {
+		fmt.Printf("%s\n", <-c)
+	}
+}
+
+//
+
+func TestNewI2C_Sense(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := dev.String(); s != "VL53L1X{fake(41)}" {
+		t.Fatal(s)
+	}
+	if v := f.get(0x002E); v != 0x01 {
+		t.Fatalf("2V8 mode not set: %#x", v)
+	}
+	if v := f.get(0x0060); v != 0x0F {
+		t.Fatalf("long mode not set: %#x", v)
+	}
+	if v := f.get16(0x001E); v != 20 {
+		t.Fatalf("offset: %d", v)
+	}
+	checkBudget(t, dev, 50*time.Millisecond)
+	var d devices.Distance
+	if err := dev.Sense(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d != 300 {
+		t.Fatal(d)
+	}
+	// Manual calibration.
+	if v := f.get(0x000B); v != 0x29 {
+		t.Fatalf("vhv init: %#x", v)
+	}
+	if v := f.get(0x0008); v != 0x0D {
+		t.Fatalf("vhv timeout: %#x", v)
+	}
+	if v := f.get(0x0047); v != 0x0C {
+		t.Fatalf("vcsel start: %#x", v)
+	}
+	// DSS: (0x0A00<<16) / (((0x100+0x80)<<16)/0x100).
+	if v := f.get16(0x0054); v != 0x6AA {
+		t.Fatalf("spads: %#x", v)
+	}
+	// No target.
+	f.set(0x0089, 4)
+	var r Range
+	if err := dev.SenseRange(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != SignalFail || r.PeakSignal != 0x100 || r.Ambient != 0x80 {
+		t.Fatalf("%#v", r)
+	}
+	if err := dev.Sense(&d); err == nil {
+		t.Fatal("invalid measurement")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewI2C_fail(t *testing.T) {
+	if _, err := NewI2C(newFakeBus(), 0x80, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if _, err := NewI2C(newFakeBus(), 0x29, &Opts{DistanceMode: 3}); err == nil {
+		t.Fatal("invalid distance mode")
+	}
+	if _, err := NewI2C(newFakeBus(), 0x29, &Opts{TimingBudget: 2 * time.Second}); err == nil {
+		t.Fatal("invalid budget")
+	}
+	if _, err := NewI2C(&i2ctest.Playback{DontPanic: true}, 0x29, nil); err == nil {
+		t.Fatal("read failed")
+	}
+	f := newFakeBus()
+	f.set(0x0110, 0xCD)
+	if _, err := NewI2C(f, 0x29, nil); err == nil {
+		t.Fatal("invalid model")
+	}
+	f = newFakeBus()
+	f.set(0x0006, 0)
+	f.set(0x0007, 0)
+	if _, err := NewI2C(f, 0x29, nil); err == nil {
+		t.Fatal("invalid oscillator")
+	}
+}
+
+func TestDistanceMode_TimingBudget(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, &Opts{DistanceMode: Medium, TimingBudget: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.get(0x002E); v != 0 {
+		t.Fatalf("2V8 mode set: %#x", v)
+	}
+	if v := f.get(0x0060); v != 0x0B {
+		t.Fatalf("medium mode not set: %#x", v)
+	}
+	checkBudget(t, dev, 100*time.Millisecond)
+	if err := dev.SetDistanceMode(Short); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.get(0x0063); v != 0x05 {
+		t.Fatalf("short mode not set: %#x", v)
+	}
+	checkBudget(t, dev, 100*time.Millisecond)
+	if err := dev.SetDistanceMode(4); err == nil {
+		t.Fatal("invalid mode")
+	}
+	if err := dev.SetTimingBudget(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	checkBudget(t, dev, 20*time.Millisecond)
+	if err := dev.SetTimingBudget(time.Millisecond); err == nil {
+		t.Fatal("invalid budget")
+	}
+}
+
+func TestSenseContinuous(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.SenseContinuous(10 * time.Millisecond); err == nil {
+		t.Fatal("interval shorter than budget")
+	}
+	c, err := dev.SenseContinuous(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.get32(0x006C); v != 1600 {
+		t.Fatalf("period: %d", v)
+	}
+	for i := 0; i < 2; i++ {
+		if d := <-c; d != 300 {
+			t.Fatal(d)
+		}
+	}
+	var d devices.Distance
+	if err := dev.Sense(&d); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.SetDistanceMode(Short); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.SetTimingBudget(50 * time.Millisecond); err == nil {
+		t.Fatal("already sensing")
+	}
+	if err := dev.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel must be closed")
+	}
+	// VHV configuration restored.
+	if v := f.get(0x000B); v != 0xA9 {
+		t.Fatalf("vhv init: %#x", v)
+	}
+	if v := f.get(0x004D); v != 0 {
+		t.Fatalf("phasecal override: %#x", v)
+	}
+}
+
+func TestSense_timeout(t *testing.T) {
+	f := newFakeBus()
+	dev, err := NewI2C(f, 0x29, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.hold = true
+	f.mu.Unlock()
+	var d devices.Distance
+	if err := dev.Sense(&d); err == nil {
+		t.Fatal("timed out")
+	}
+}
+
+func TestDecodeStatus(t *testing.T) {
+	data := []struct {
+		status, stream byte
+		expected       RangeStatus
+	}{
+		{9, 1, RangeValid},
+		{9, 0, RangeValidNoWrapCheckFail},
+		{1, 0, HardwareFail},
+		{13, 0, MinRangeFail},
+		{18, 0, SynchronizationInt},
+		{5, 0, OutOfBoundsFail},
+		{6, 0, SigmaFail},
+		{7, 0, WrapTargetFail},
+		{12, 0, XtalkSignalFail},
+		{8, 0, MinRangeClipped},
+		{0, 0, NoUpdate},
+	}
+	for i, line := range data {
+		if s := decodeStatus(line.status, line.stream); s != line.expected {
+			t.Fatalf("#%d: %s != %s", i, s, line.expected)
+		}
+		if s := line.expected.String(); strings.HasPrefix(s, "RangeStatus(") {
+			t.Fatalf("#%d: %q", i, s)
+		}
+	}
+	if s := RangeStatus(8).String(); s != "RangeStatus(8)" {
+		t.Fatal(s)
+	}
+	if !MinRangeClipped.Valid() || SignalFail.Valid() {
+		t.Fatal("Valid()")
+	}
+}
+
+func TestDistanceMode_String(t *testing.T) {
+	if s := Medium.String(); s != "Medium" {
+		t.Fatal(s)
+	}
+	if s := DistanceMode(3).String(); s != "DistanceMode(3)" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func checkBudget(t *testing.T, dev *Dev, expected time.Duration) {
+	b, err := dev.TimingBudget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b < expected-expected/100 || b > expected+expected/100 {
+		t.Fatalf("%s != %s", b, expected)
+	}
+}
+
+// fakeBus simulates the VL53L1X registers.
+type fakeBus struct {
+	mu         sync.Mutex
+	regs       map[uint16]byte
+	continuous bool
+	hold       bool // Never signal data ready
+}
+
+func newFakeBus() *fakeBus {
+	f := &fakeBus{regs: map[uint16]byte{}}
+	f.set(0x010F, 0xEA)
+	f.set(0x0110, 0xCC)
+	f.set(0x00E5, 0x01)
+	// Fast oscillator frequency, 4.12 fixed point.
+	f.set(0x0006, 0xBE)
+	f.set(0x0007, 0x00)
+	f.set(0x00DF, 0x10)
+	f.set(0x0023, 5)
+	f.set(0x000B, 0xA9)
+	f.set(0x0008, 0x09)
+	f.set(0x00D8, 0x0C)
+	// Results: range complete, stream count 1, 0x100 SPADs, ambient 0x80,
+	// raw range 306 and peak signal 0x100.
+	res := []byte{9, 0, 1, 0x01, 0x00, 0, 0, 0x00, 0x80, 0, 0, 0, 0, 0x01, 0x32, 0x01, 0x00}
+	for i, v := range res {
+		f.set(0x0089+uint16(i), v)
+	}
+	return f
+}
+
+func (f *fakeBus) String() string {
+	return "fake"
+}
+
+func (f *fakeBus) Tx(addr uint16, w, r []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reg := uint16(w[0])<<8 | uint16(w[1])
+	for i, v := range w[2:] {
+		f.write(reg+uint16(i), v)
+	}
+	for i := range r {
+		r[i] = f.regs[reg+uint16(i)]
+	}
+	return nil
+}
+
+func (f *fakeBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+func (f *fakeBus) set(reg uint16, v byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regs[reg] = v
+}
+
+func (f *fakeBus) get(reg uint16) byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.regs[reg]
+}
+
+func (f *fakeBus) get16(reg uint16) uint16 {
+	return uint16(f.get(reg))<<8 | uint16(f.get(reg+1))
+}
+
+func (f *fakeBus) get32(reg uint16) uint32 {
+	return uint32(f.get16(reg))<<16 | uint32(f.get16(reg+2))
+}
+
+func (f *fakeBus) write(reg uint16, v byte) {
+	f.regs[reg] = v
+	switch reg {
+	case 0x0086:
+		// Interrupt clear.
+		f.regs[0x0031] = 0x03
+		if f.continuous && !f.hold {
+			f.regs[0x0031] = 0x02
+		}
+	case 0x0087:
+		f.continuous = v == 0x40
+		if v&0x50 != 0 && !f.hold {
+			f.regs[0x0031] = 0x02
+		}
+	}
+}
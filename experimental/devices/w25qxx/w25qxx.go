@@ -0,0 +1,333 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package w25qxx controls a Winbond W25Qxx SPI NOR flash memory, like the
+// W25Q32, W25Q64 or W25Q128.
+//
+// Most SPI NOR flash memories from other vendors, e.g. the GD25Q or MX25L
+// series, use the same commands and work too.
+//
+// The memory is read with ReadAt() and programmed with WriteAt(). Programming
+// can only clear bits, so the memory must be erased beforehand with Erase(),
+// which sets all the bits of whole sectors.
+//
+// Datasheet
+//
+// https://www.winbond.com/resource-files/w25q128jv%20revf%2003272018%20plus.pdf
+package w25qxx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/spi"
+)
+
+const (
+	// PageSize is the maximum number of bytes programmed by a single
+	// command.
+	PageSize = 256
+	// SectorSize is the smallest erasable unit.
+	SectorSize = 4096
+	// BlockSize is the largest erasable unit, besides the whole chip.
+	BlockSize = 65536
+)
+
+// ID is the JEDEC identification of a chip.
+type ID struct {
+	Manufacturer byte // 0xEF for Winbond
+	MemoryType   byte
+	Capacity     byte // The size is 2^Capacity bytes
+}
+
+// Size returns the size of the memory in bytes.
+func (i ID) Size() int64 {
+	return 1 << i.Capacity
+}
+
+func (i ID) String() string {
+	name := "unknown"
+	if i.Manufacturer == 0xEF {
+		// The part number is the size in Mbit.
+		name = fmt.Sprintf("W25Q%d", i.Size()>>17)
+	}
+	return fmt.Sprintf("%s (0x%02X%02X%02X, %dKiB)", name, i.Manufacturer, i.MemoryType, i.Capacity, i.Size()>>10)
+}
+
+// Dev is a handle to a SPI NOR flash memory.
+type Dev struct {
+	c     conn.Conn
+	id    ID
+	chunk int // Maximum transaction size; 0 when unlimited
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewSPI returns an object that communicates over SPI to a W25Qxx.
+//
+// maxHz is the maximum clock speed; use 0 for the maximum speed of the read
+// command. The chip is identified and an error is returned if no chip
+// responds.
+func NewSPI(p spi.Port, maxHz int64) (*Dev, error) {
+	if maxHz == 0 || maxHz > 50000000 {
+		maxHz = 50000000
+	}
+	c, err := p.Connect(maxHz, spi.Mode0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("w25qxx: %v", err)
+	}
+	d := &Dev{c: c}
+	if l, ok := c.(conn.Limits); ok {
+		d.chunk = l.MaxTxSize()
+	}
+	// Wake the chip up in case it was powered down.
+	if err := d.c.Tx([]byte{cmdReleasePowerDown}, nil); err != nil {
+		return nil, fmt.Errorf("w25qxx: %v", err)
+	}
+	sleep(3 * time.Microsecond)
+	r := [4]byte{}
+	if err := d.c.Tx([]byte{cmdJEDECID, 0, 0, 0}, r[:]); err != nil {
+		return nil, fmt.Errorf("w25qxx: %v", err)
+	}
+	d.id = ID{r[1], r[2], r[3]}
+	if d.id.Manufacturer == 0 || d.id.Manufacturer == 0xFF || d.id.Capacity < 16 || d.id.Capacity > 32 {
+		return nil, fmt.Errorf("w25qxx: no chip found, got ID 0x%02X%02X%02X", r[1], r[2], r[3])
+	}
+	if d.id.Capacity > 24 {
+		return nil, fmt.Errorf("w25qxx: 4 bytes addressing is not supported; %s", d.id)
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("W25Qxx{%s}", d.c)
+}
+
+// ID returns the JEDEC identification of the chip.
+func (d *Dev) ID() ID {
+	return d.id
+}
+
+// Size returns the size of the memory in bytes.
+func (d *Dev) Size() int64 {
+	return d.id.Size()
+}
+
+// UniqueID returns the factory programmed 64 bits unique ID of the chip.
+func (d *Dev) UniqueID() ([8]byte, error) {
+	var out [8]byte
+	r := [13]byte{}
+	if err := d.c.Tx([]byte{cmdUniqueID, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, r[:]); err != nil {
+		return out, fmt.Errorf("w25qxx: %v", err)
+	}
+	copy(out[:], r[5:])
+	return out, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (d *Dev) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 || off > d.Size() {
+		return 0, errors.New("w25qxx: invalid offset")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for n < len(b) && off < d.Size() {
+		l := d.chunkSize(len(b) - n)
+		if left := d.Size() - off; int64(l) > left {
+			l = int(left)
+		}
+		w := d.cmd(cmdRead, off, l)
+		r := make([]byte, len(w))
+		if err := d.c.Tx(w, r); err != nil {
+			return n, fmt.Errorf("w25qxx: %v", err)
+		}
+		copy(b[n:], r[4:])
+		n += l
+		off += int64(l)
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt.
+//
+// It programs the memory, which only clears bits. The area must have been
+// erased beforehand for the memory to contain b.
+func (d *Dev) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(b)) > d.Size() {
+		return 0, errors.New("w25qxx: write out of range")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for n < len(b) {
+		// A program command must not cross a page boundary.
+		l := PageSize - int(off%PageSize)
+		if l > len(b)-n {
+			l = len(b) - n
+		}
+		l = d.chunkSize(l)
+		if err := d.writeEnable(); err != nil {
+			return n, err
+		}
+		w := d.cmd(cmdPageProgram, off, l)
+		copy(w[4:], b[n:n+l])
+		if err := d.c.Tx(w, nil); err != nil {
+			return n, fmt.Errorf("w25qxx: %v", err)
+		}
+		if err := d.wait(10*time.Microsecond, 3*time.Millisecond); err != nil {
+			return n, err
+		}
+		n += l
+		off += int64(l)
+	}
+	return n, nil
+}
+
+// Erase sets all the bits of the area.
+//
+// off and size must be multiples of SectorSize. The blocks fully contained in
+// the area are erased at once, which is faster.
+func (d *Dev) Erase(off, size int64) error {
+	if off < 0 || size < 0 || off+size > d.Size() {
+		return errors.New("w25qxx: erase out of range")
+	}
+	if off%SectorSize != 0 || size%SectorSize != 0 {
+		return fmt.Errorf("w25qxx: erase must be aligned on %d bytes", SectorSize)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for end := off + size; off < end; {
+		c, l, timeout := byte(cmdSectorErase), int64(SectorSize), 400*time.Millisecond
+		if off%BlockSize == 0 && end-off >= BlockSize {
+			c, l, timeout = cmdBlockErase, BlockSize, 2*time.Second
+		}
+		if err := d.writeEnable(); err != nil {
+			return err
+		}
+		if err := d.c.Tx(d.cmd(c, off, 0), nil); err != nil {
+			return fmt.Errorf("w25qxx: %v", err)
+		}
+		if err := d.wait(time.Millisecond, timeout); err != nil {
+			return err
+		}
+		off += l
+	}
+	return nil
+}
+
+// EraseChip sets all the bits of the memory.
+//
+// It takes from a few seconds up to several minutes on large chips.
+func (d *Dev) EraseChip() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.writeEnable(); err != nil {
+		return err
+	}
+	if err := d.c.Tx([]byte{cmdChipErase}, nil); err != nil {
+		return fmt.Errorf("w25qxx: %v", err)
+	}
+	return d.wait(100*time.Millisecond, 400*time.Second)
+}
+
+// Halt implements conn.Resource.
+//
+// It is a no-op; the operations are synchronous.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+//
+
+const (
+	cmdWriteEnable      = 0x06
+	cmdReadStatus1      = 0x05
+	cmdRead             = 0x03
+	cmdPageProgram      = 0x02
+	cmdSectorErase      = 0x20
+	cmdBlockErase       = 0xD8
+	cmdChipErase        = 0xC7
+	cmdJEDECID          = 0x9F
+	cmdUniqueID         = 0x4B
+	cmdReleasePowerDown = 0xAB
+
+	statusBusy = 0x01
+	statusWEL  = 0x02
+)
+
+var sleep = time.Sleep
+
+// chunkSize returns the number of data bytes that can be sent with a command
+// in a single transaction, up to l.
+func (d *Dev) chunkSize(l int) int {
+	if d.chunk != 0 && l > d.chunk-4 {
+		return d.chunk - 4
+	}
+	return l
+}
+
+// cmd returns a command with a 24 bits address followed by l zero bytes.
+func (d *Dev) cmd(c byte, addr int64, l int) []byte {
+	if cap(d.buf) < 4+l {
+		d.buf = make([]byte, 4+l)
+	}
+	w := d.buf[:4+l]
+	w[0], w[1], w[2], w[3] = c, byte(addr>>16), byte(addr>>8), byte(addr)
+	for i := 4; i < len(w); i++ {
+		w[i] = 0
+	}
+	return w
+}
+
+func (d *Dev) status() (byte, error) {
+	r := [2]byte{}
+	if err := d.c.Tx([]byte{cmdReadStatus1, 0}, r[:]); err != nil {
+		return 0, fmt.Errorf("w25qxx: %v", err)
+	}
+	return r[1], nil
+}
+
+func (d *Dev) writeEnable() error {
+	if err := d.c.Tx([]byte{cmdWriteEnable}, nil); err != nil {
+		return fmt.Errorf("w25qxx: %v", err)
+	}
+	s, err := d.status()
+	if err != nil {
+		return err
+	}
+	if s&statusWEL == 0 {
+		return errors.New("w25qxx: write enable failed; is the chip write protected?")
+	}
+	return nil
+}
+
+// wait polls the status every interval until the chip is not busy anymore.
+func (d *Dev) wait(interval, timeout time.Duration) error {
+	for t := time.Duration(0); ; t += interval {
+		s, err := d.status()
+		if err != nil {
+			return err
+		}
+		if s&statusBusy == 0 {
+			return nil
+		}
+		if t >= timeout {
+			return errors.New("w25qxx: timed out waiting for the chip")
+		}
+		sleep(interval)
+	}
+}
+
+var _ conn.Resource = &Dev{}
+var _ io.ReaderAt = &Dev{}
+var _ io.WriterAt = &Dev{}
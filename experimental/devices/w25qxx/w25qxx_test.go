@@ -0,0 +1,273 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package w25qxx
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open SPI: %v", err)
+	}
+	defer p.Close()
+	dev, err := NewSPI(p, 0)
+	if err != nil {
+		log.Fatalf("failed to initialize w25qxx: %v", err)
+	}
+	// Replace the first sector.
+	if err := dev.Erase(0, SectorSize); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := dev.WriteAt([]byte("hello"), 0); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func init() {
+	sleep = func(time.Duration) {}
+}
+
+func TestNewSPI(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				conntest.IO{
+					W: []byte{cmdUniqueID, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+					R: []byte{0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8},
+				},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "W25Qxx{playback}" {
+		t.Fatal(s)
+	}
+	if s := d.ID().String(); s != "W25Q32 (0xEF4016, 4096KiB)" {
+		t.Fatal(s)
+	}
+	if n := d.Size(); n != 4*1024*1024 {
+		t.Fatal(n)
+	}
+	id, err := d.UniqueID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+		t.Fatal(id)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSPI_no_chip(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{cmdReleasePowerDown}},
+				{W: []byte{cmdJEDECID, 0, 0, 0}, R: []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+			},
+		},
+	}
+	if _, err := NewSPI(&s, 0); err == nil {
+		t.Fatal("no chip")
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				conntest.IO{
+					W: []byte{cmdRead, 0x3F, 0xFF, 0xFE, 0, 0},
+					R: []byte{0, 0, 0, 0, 1, 2},
+				},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The read is truncated at the end of the memory.
+	b := make([]byte, 4)
+	if n, err := d.ReadAt(b, d.Size()-2); n != 2 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(b, []byte{1, 2, 0, 0}) {
+		t.Fatal(b)
+	}
+	if _, err := d.ReadAt(b, -1); err == nil {
+		t.Fatal("invalid offset")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAt_chunk(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				conntest.IO{W: []byte{cmdRead, 0, 0, 0x10, 0, 0}, R: []byte{0, 0, 0, 0, 1, 2}},
+				conntest.IO{W: []byte{cmdRead, 0, 0, 0x12, 0}, R: []byte{0, 0, 0, 0, 3}},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.chunk = 6
+	b := make([]byte, 3)
+	if n, err := d.ReadAt(b, 0x10); n != 3 || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatal(b)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteAt(t *testing.T) {
+	// The write crosses a page boundary.
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				conntest.IO{W: []byte{cmdWriteEnable}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL}},
+				conntest.IO{W: []byte{cmdPageProgram, 0, 0, 0xFF, 1}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL | statusBusy}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, 0}},
+				conntest.IO{W: []byte{cmdWriteEnable}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL}},
+				conntest.IO{W: []byte{cmdPageProgram, 0, 1, 0, 2, 3}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, 0}},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.WriteAt([]byte{1, 2, 3}, 0xFF); n != 3 || err != nil {
+		t.Fatal(n, err)
+	}
+	if _, err := d.WriteAt([]byte{1}, d.Size()); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteAt_protected(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				conntest.IO{W: []byte{cmdWriteEnable}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, 0}},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.WriteAt([]byte{1}, 0); n != 0 || err == nil {
+		t.Fatal("write protected")
+	}
+}
+
+func TestErase(t *testing.T) {
+	s := spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: append(initOps(),
+				// The first sector is erased alone, then the block.
+				conntest.IO{W: []byte{cmdWriteEnable}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL}},
+				conntest.IO{W: []byte{cmdSectorErase, 0, 0xF0, 0}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, 0}},
+				conntest.IO{W: []byte{cmdWriteEnable}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL}},
+				conntest.IO{W: []byte{cmdBlockErase, 1, 0, 0}},
+				conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, 0}},
+			),
+		},
+	}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Erase(0xF000, SectorSize+BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Erase(1, SectorSize); err == nil {
+		t.Fatal("unaligned")
+	}
+	if err := d.Erase(0, d.Size()+SectorSize); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEraseChip_timeout(t *testing.T) {
+	ops := append(initOps(),
+		conntest.IO{W: []byte{cmdWriteEnable}},
+		conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusWEL}},
+		conntest.IO{W: []byte{cmdChipErase}},
+	)
+	// The chip stays busy for longer than the maximum chip erase time.
+	for i := 0; i <= 4000; i++ {
+		ops = append(ops, conntest.IO{W: []byte{cmdReadStatus1, 0}, R: []byte{0, statusBusy}})
+	}
+	s := spitest.Playback{Playback: conntest.Playback{Ops: ops}}
+	d, err := NewSPI(&s, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.EraseChip(); err == nil {
+		t.Fatal("timeout")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//
+
+// initOps returns the transactions done by NewSPI() for a W25Q32.
+func initOps() []conntest.IO {
+	return []conntest.IO{
+		{W: []byte{cmdReleasePowerDown}},
+		{W: []byte{cmdJEDECID, 0, 0, 0}, R: []byte{0, 0xEF, 0x40, 0x16}},
+	}
+}
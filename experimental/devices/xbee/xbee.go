@@ -0,0 +1,537 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package xbee controls a Digi XBee 802.15.4 or Zigbee module over an UART
+// using the API frame protocol.
+//
+// The module must be configured in API mode, either AP=1 (unescaped) or AP=2
+// (escaped), with Opts.Escaped set accordingly.
+//
+// Datasheet
+//
+// https://www.digi.com/resources/documentation/digidocs/pdfs/90000976.pdf
+//
+// https://www.digi.com/resources/documentation/Digidocs/90001500/
+package xbee
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/experimental/conn/uart"
+)
+
+// Broadcast is the 64 bits broadcast address.
+const Broadcast uint64 = 0xFFFF
+
+// Unknown16 is the 16 bits address to use when the destination network
+// address is unknown.
+const Unknown16 uint16 = 0xFFFE
+
+// Packet is a packet received from a remote node.
+type Packet struct {
+	Src64   uint64
+	Src16   uint16
+	Options byte
+	Data    []byte
+}
+
+// Node is a remote node found by Discover().
+type Node struct {
+	Addr16     uint16
+	Addr64     uint64
+	ID         string // Node identifier as set with the NI command.
+	Parent16   uint16 // Zigbee only.
+	DeviceType byte   // Zigbee only: 0 coordinator, 1 router, 2 end device.
+}
+
+func (n *Node) String() string {
+	return fmt.Sprintf("%016X(%04X) %q", n.Addr64, n.Addr16, n.ID)
+}
+
+// Opts is optional options to pass to the constructor.
+type Opts struct {
+	// Escaped must be set when the module is configured with AP=2.
+	Escaped bool
+	// Timeout is the time to wait for the response of a local command or for
+	// the transmit status. Defaults to 2s.
+	Timeout time.Duration
+}
+
+// Dev is a handle to a XBee module.
+//
+// A goroutine continuously reads frames from the UART. Received packets are
+// delivered on the channel returned by Receive().
+type Dev struct {
+	c    uart.Conn
+	opts Opts
+	rx   chan Packet
+	stop chan struct{}
+
+	wmu sync.Mutex // Serializes writes.
+
+	mu      sync.Mutex
+	nextID  byte
+	pending map[byte]chan []byte
+	err     error // Set when the read loop terminated.
+}
+
+// New opens a handle to a XBee module in API mode.
+//
+// It is recommended to call Halt() when done with the device. The UART should
+// then be closed so the read loop terminates.
+func New(c uart.Conn, opts *Opts) (*Dev, error) {
+	if opts == nil {
+		opts = &defaults
+	}
+	d := &Dev{
+		c:       c,
+		opts:    *opts,
+		rx:      make(chan Packet, 16),
+		stop:    make(chan struct{}),
+		pending: map[byte]chan []byte{},
+	}
+	if d.opts.Timeout == 0 {
+		d.opts.Timeout = defaults.Timeout
+	}
+	go d.readLoop()
+	// Ensure the module is responding in API mode.
+	if _, err := d.AT("AP", nil); err != nil {
+		d.Halt()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("xbee{%s}", d.c)
+}
+
+// Halt stops the delivery of received packets.
+//
+// The read loop terminates on the next read from the UART, which is
+// generally when the UART is closed.
+func (d *Dev) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+	return nil
+}
+
+// Receive returns the channel on which received packets are delivered.
+//
+// The channel must be drained, otherwise the read loop blocks and command
+// responses are not processed anymore. It is closed when the read loop
+// terminates.
+func (d *Dev) Receive() <-chan Packet {
+	return d.rx
+}
+
+// AT runs a local AT command and returns the response data.
+//
+// cmd must be two characters, like "NI". When param is nil, the current value
+// is queried. Otherwise the value is set; use "WR" to persist it.
+func (d *Dev) AT(cmd string, param []byte) ([]byte, error) {
+	if len(cmd) != 2 {
+		return nil, fmt.Errorf("xbee: invalid AT command %q", cmd)
+	}
+	id, ch, err := d.register()
+	if err != nil {
+		return nil, err
+	}
+	defer d.unregister(id)
+	f := make([]byte, 0, 4+len(param))
+	f = append(f, frameATCommand, id, cmd[0], cmd[1])
+	f = append(f, param...)
+	if err := d.writeFrame(f); err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-ch:
+		return parseATResponse(cmd, r)
+	case <-time.After(d.opts.Timeout):
+		return nil, fmt.Errorf("xbee: timeout waiting for %s response", cmd)
+	}
+}
+
+// Send sends data to a remote node and waits for the delivery status.
+//
+// Use Broadcast as dst64 to broadcast. Use Unknown16 as dst16 when the 16
+// bits network address of the destination is not known.
+func (d *Dev) Send(dst64 uint64, dst16 uint16, data []byte) error {
+	id, ch, err := d.register()
+	if err != nil {
+		return err
+	}
+	defer d.unregister(id)
+	f := make([]byte, 14, 14+len(data))
+	f[0] = frameTransmitRequest
+	f[1] = id
+	binary.BigEndian.PutUint64(f[2:], dst64)
+	binary.BigEndian.PutUint16(f[10:], dst16)
+	// f[12] is the broadcast radius, f[13] the options; leave both to default.
+	f = append(f, data...)
+	if err := d.writeFrame(f); err != nil {
+		return err
+	}
+	select {
+	case r := <-ch:
+		// Frame type, id, 16 bits address, retry count, delivery status,
+		// discovery status.
+		if len(r) < 6 {
+			return errors.New("xbee: short transmit status")
+		}
+		if s := r[5]; s != 0 {
+			return fmt.Errorf("xbee: delivery failed with status 0x%02X", s)
+		}
+		return nil
+	case <-time.After(d.opts.Timeout):
+		return errors.New("xbee: timeout waiting for transmit status")
+	}
+}
+
+// Discover runs a network discovery and returns the nodes that replied within
+// the timeout.
+//
+// The module's NT setting determines how long the remote nodes wait before
+// replying; the timeout should be larger than NT.
+func (d *Dev) Discover(timeout time.Duration) ([]Node, error) {
+	id, ch, err := d.register()
+	if err != nil {
+		return nil, err
+	}
+	defer d.unregister(id)
+	if err := d.writeFrame([]byte{frameATCommand, id, 'N', 'D'}); err != nil {
+		return nil, err
+	}
+	var out []Node
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		select {
+		case r := <-ch:
+			b, err := parseATResponse("ND", r)
+			if err != nil {
+				return out, err
+			}
+			if len(b) == 0 {
+				// Some firmwares send an empty response to mark the end.
+				return out, nil
+			}
+			n, err := parseNode(b)
+			if err != nil {
+				return out, err
+			}
+			out = append(out, n)
+		case <-t.C:
+			return out, nil
+		}
+	}
+}
+
+//
+
+// API frame types.
+const (
+	frameATCommand       = 0x08
+	frameTransmitRequest = 0x10
+	frameRx64            = 0x80
+	frameRx16            = 0x81
+	frameATResponse      = 0x88
+	frameModemStatus     = 0x8A
+	frameTransmitStatus  = 0x8B
+	frameReceivePacket   = 0x90
+)
+
+const (
+	startDelimiter      = 0x7E
+	escape              = 0x7D
+	escapeXOR      byte = 0x20
+	maxFrameLength      = 0xFFFF
+)
+
+// register allocates a frame id to wait for a response.
+func (d *Dev) register() (byte, <-chan []byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	for i := 0; i < 255; i++ {
+		d.nextID++
+		if d.nextID == 0 {
+			// 0 means no response is requested.
+			d.nextID = 1
+		}
+		if _, ok := d.pending[d.nextID]; !ok {
+			ch := make(chan []byte, 16)
+			d.pending[d.nextID] = ch
+			return d.nextID, ch, nil
+		}
+	}
+	return 0, nil, errors.New("xbee: too many pending requests")
+}
+
+func (d *Dev) unregister(id byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, id)
+}
+
+// writeFrame sends an API frame.
+func (d *Dev) writeFrame(data []byte) error {
+	if len(data) > maxFrameLength {
+		return errors.New("xbee: frame too large")
+	}
+	b := make([]byte, 0, len(data)+4)
+	b = append(b, startDelimiter, byte(len(data)>>8), byte(len(data)))
+	b = append(b, data...)
+	b = append(b, checksum(data))
+	if d.opts.Escaped {
+		b = escapeFrame(b)
+	}
+	d.wmu.Lock()
+	defer d.wmu.Unlock()
+	if err := d.c.Tx(b, nil); err != nil {
+		return fmt.Errorf("xbee: %v", err)
+	}
+	return nil
+}
+
+// readLoop reads frames until the UART fails or Halt() is called.
+func (d *Dev) readLoop() {
+	defer close(d.rx)
+	for {
+		f, err := d.readFrame()
+		select {
+		case <-d.stop:
+			err = errors.New("xbee: halted")
+		default:
+		}
+		if err != nil {
+			d.mu.Lock()
+			d.err = err
+			d.mu.Unlock()
+			return
+		}
+		if f == nil {
+			// Invalid frame, ignore.
+			continue
+		}
+		if !d.dispatch(f) {
+			return
+		}
+	}
+}
+
+// dispatch routes a received frame. It returns false when halted.
+func (d *Dev) dispatch(f []byte) bool {
+	switch f[0] {
+	case frameATResponse, frameTransmitStatus:
+		if len(f) < 2 {
+			return true
+		}
+		d.mu.Lock()
+		ch := d.pending[f[1]]
+		d.mu.Unlock()
+		if ch != nil {
+			select {
+			case ch <- f:
+			default:
+				log.Printf("xbee: dropped response for frame %d", f[1])
+			}
+		}
+	case frameReceivePacket, frameRx64, frameRx16:
+		p, err := parsePacket(f)
+		if err != nil {
+			log.Printf("xbee: %v", err)
+			return true
+		}
+		select {
+		case d.rx <- p:
+		case <-d.stop:
+			return false
+		}
+	case frameModemStatus:
+	default:
+	}
+	return true
+}
+
+// readFrame reads one API frame and returns the frame data without the
+// header and checksum.
+//
+// Returns nil, nil when the checksum is invalid.
+func (d *Dev) readFrame() ([]byte, error) {
+	for {
+		b, err := d.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		if b == startDelimiter {
+			break
+		}
+	}
+	var l [2]byte
+	for i := range l {
+		b, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		l[i] = b
+	}
+	n := int(binary.BigEndian.Uint16(l[:]))
+	f := make([]byte, n+1)
+	for i := range f {
+		b, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		f[i] = b
+	}
+	if n == 0 || checksum(f[:n]) != f[n] {
+		return nil, nil
+	}
+	return f[:n], nil
+}
+
+// readByte reads one byte, handling escaping when enabled.
+func (d *Dev) readByte() (byte, error) {
+	b, err := d.readRaw()
+	if err != nil || !d.opts.Escaped || b != escape {
+		return b, err
+	}
+	b, err = d.readRaw()
+	return b ^ escapeXOR, err
+}
+
+func (d *Dev) readRaw() (byte, error) {
+	var b [1]byte
+	if err := d.c.Tx(nil, b[:]); err != nil {
+		return 0, fmt.Errorf("xbee: %v", err)
+	}
+	return b[0], nil
+}
+
+func checksum(data []byte) byte {
+	s := byte(0)
+	for _, b := range data {
+		s += b
+	}
+	return 0xFF - s
+}
+
+// escapeFrame escapes all bytes except the start delimiter.
+func escapeFrame(b []byte) []byte {
+	out := make([]byte, 1, 2*len(b))
+	out[0] = b[0]
+	for _, c := range b[1:] {
+		switch c {
+		case startDelimiter, escape, 0x11, 0x13:
+			out = append(out, escape, c^escapeXOR)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func parseATResponse(cmd string, r []byte) ([]byte, error) {
+	// Frame type, id, command, status.
+	if len(r) < 5 {
+		return nil, errors.New("xbee: short AT response")
+	}
+	if string(r[2:4]) != cmd {
+		return nil, fmt.Errorf("xbee: unexpected response for %s: %q", cmd, r[2:4])
+	}
+	switch r[4] {
+	case 0:
+		return r[5:], nil
+	case 1:
+		return nil, fmt.Errorf("xbee: %s failed", cmd)
+	case 2:
+		return nil, fmt.Errorf("xbee: invalid command %s", cmd)
+	case 3:
+		return nil, fmt.Errorf("xbee: invalid parameter for %s", cmd)
+	default:
+		return nil, fmt.Errorf("xbee: %s failed with status 0x%02X", cmd, r[4])
+	}
+}
+
+func parsePacket(f []byte) (Packet, error) {
+	switch f[0] {
+	case frameReceivePacket:
+		// Type, 64 bits, 16 bits, options, data.
+		if len(f) < 12 {
+			return Packet{}, errors.New("short receive packet")
+		}
+		return Packet{
+			Src64:   binary.BigEndian.Uint64(f[1:]),
+			Src16:   binary.BigEndian.Uint16(f[9:]),
+			Options: f[11],
+			Data:    f[12:],
+		}, nil
+	case frameRx64:
+		// Type, 64 bits, RSSI, options, data.
+		if len(f) < 11 {
+			return Packet{}, errors.New("short 64 bits receive packet")
+		}
+		return Packet{
+			Src64:   binary.BigEndian.Uint64(f[1:]),
+			Src16:   Unknown16,
+			Options: f[10],
+			Data:    f[11:],
+		}, nil
+	default:
+		// Type, 16 bits, RSSI, options, data.
+		if len(f) < 5 {
+			return Packet{}, errors.New("short 16 bits receive packet")
+		}
+		return Packet{
+			Src16:   binary.BigEndian.Uint16(f[1:]),
+			Options: f[4],
+			Data:    f[5:],
+		}, nil
+	}
+}
+
+// parseNode parses a ND response.
+//
+// The format is MY, SH, SL, NI as a NUL terminated string, then on Zigbee
+// PARENT_NETWORK ADDRESS, DEVICE_TYPE and more fields that are ignored.
+func parseNode(b []byte) (Node, error) {
+	if len(b) < 10 {
+		return Node{}, errors.New("xbee: short node discovery response")
+	}
+	n := Node{
+		Addr16: binary.BigEndian.Uint16(b),
+		Addr64: binary.BigEndian.Uint64(b[2:]),
+	}
+	b = b[10:]
+	i := 0
+	for ; i < len(b) && b[i] != 0; i++ {
+	}
+	n.ID = string(b[:i])
+	if i+4 <= len(b) {
+		n.Parent16 = binary.BigEndian.Uint16(b[i+1:])
+		n.DeviceType = b[i+3]
+	}
+	return n, nil
+}
+
+var defaults = Opts{
+	Timeout: 2 * time.Second,
+}
+
+var _ conn.Resource = &Dev{}
+var _ fmt.Stringer = &Dev{}
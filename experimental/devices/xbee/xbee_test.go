@@ -0,0 +1,293 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package xbee
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	if _, err := host.Init(); err != nil {
+		log.Fatalf("failed to initialize periph: %v", err)
+	}
+	p, err := uartreg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open UART: %v", err)
+	}
+	defer p.Close()
+	d, err := New(p, nil)
+	if err != nil {
+		log.Fatalf("failed to initialize xbee: %v", err)
+	}
+	defer d.Halt()
+	nodes, err := d.Discover(10 * time.Second)
+	if err != nil {
+		log.Fatalf("failed to discover: %v", err)
+	}
+	for _, n := range nodes {
+		fmt.Printf("%s\n", &n)
+		if err := d.Send(n.Addr64, n.Addr16, []byte("hello")); err != nil {
+			log.Fatalf("failed to send: %v", err)
+		}
+	}
+	for p := range d.Receive() {
+		fmt.Printf("%016X: %q\n", p.Src64, p.Data)
+	}
+}
+
+//
+
+func TestNew(t *testing.T) {
+	f := newFakeUART(false)
+	defer f.Close()
+	go f.respond(t, []byte{0x88, 1, 'A', 'P', 0, 1})
+	d, err := New(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "xbee{fake}" {
+		t.Fatal(s)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew_escaped(t *testing.T) {
+	f := newFakeUART(true)
+	defer f.Close()
+	// 0x11 must be escaped.
+	go f.respond(t, []byte{0x88, 1, 'A', 'P', 0, 0x11})
+	d, err := New(f, &Opts{Escaped: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go f.respond(t, []byte{0x88, 2, 'N', 'I', 0, 0x7E, 0x7D, 0x13})
+	b, err := d.AT("NI", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, []byte{0x7E, 0x7D, 0x13}) {
+		t.Fatalf("%#v", b)
+	}
+}
+
+func TestNew_timeout(t *testing.T) {
+	f := newFakeUART(false)
+	defer f.Close()
+	go func() {
+		<-f.w
+	}()
+	if _, err := New(f, &Opts{Timeout: time.Millisecond}); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
+
+func TestAT(t *testing.T) {
+	d, f := newDev(t)
+	defer f.Close()
+	if _, err := d.AT("X", nil); err == nil {
+		t.Fatal("invalid command")
+	}
+	go f.respond(t, []byte{0x88, 2, 'I', 'D', 3})
+	if _, err := d.AT("ID", []byte{1}); err == nil {
+		t.Fatal("invalid parameter")
+	}
+	if !bytes.Equal(f.last, []byte{0x08, 2, 'I', 'D', 1}) {
+		t.Fatalf("%#v", f.last)
+	}
+}
+
+func TestSend(t *testing.T) {
+	d, f := newDev(t)
+	defer f.Close()
+	go f.respond(t, []byte{0x8B, 2, 0xFF, 0xFE, 0, 0, 0})
+	if err := d.Send(0x0013A20040A1B2C3, Unknown16, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x10, 2, 0, 0x13, 0xA2, 0, 0x40, 0xA1, 0xB2, 0xC3, 0xFF, 0xFE, 0, 0, 'h', 'i'}
+	if !bytes.Equal(f.last, expected) {
+		t.Fatalf("%#v != %#v", f.last, expected)
+	}
+	go f.respond(t, []byte{0x8B, 3, 0xFF, 0xFE, 0, 0x24, 0})
+	if err := d.Send(Broadcast, Unknown16, []byte("hi")); err == nil {
+		t.Fatal("delivery failed")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	d, f := newDev(t)
+	defer f.Close()
+	f.send([]byte{0x90, 0, 0x13, 0xA2, 0, 0x40, 0xA1, 0xB2, 0xC3, 0x12, 0x34, 1, 'y', 'o'})
+	// Invalid checksum is ignored.
+	f.r <- []byte{0x7E, 0, 1, 0x90, 0}
+	f.send([]byte{0x81, 0x56, 0x78, 0x28, 0, 'a'})
+	f.send([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 1, 0x28, 2, 'b'})
+	expected := []Packet{
+		{Src64: 0x0013A20040A1B2C3, Src16: 0x1234, Options: 1, Data: []byte("yo")},
+		{Src16: 0x5678, Data: []byte("a")},
+		{Src64: 1, Src16: Unknown16, Options: 2, Data: []byte("b")},
+	}
+	for i, e := range expected {
+		p := <-d.Receive()
+		if fmt.Sprintf("%#v", p) != fmt.Sprintf("%#v", e) {
+			t.Fatalf("#%d: %#v != %#v", i, p, e)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	d, f := newDev(t)
+	defer f.Close()
+	go func() {
+		<-f.w
+		f.send([]byte{0x88, 2, 'N', 'D', 0, 0x12, 0x34, 0, 0x13, 0xA2, 0, 0x40, 0xA1, 0xB2, 0xC3, 'n', '1', 0, 0xFF, 0xFE, 1, 0, 0xC1, 0x05, 0x10, 0x1E})
+		f.send([]byte{0x88, 2, 'N', 'D', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 'n', '2', 0})
+		f.send([]byte{0x88, 2, 'N', 'D', 0})
+	}()
+	nodes, err := d.Discover(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Node{
+		{Addr16: 0x1234, Addr64: 0x0013A20040A1B2C3, ID: "n1", Parent16: 0xFFFE, DeviceType: 1},
+		{Addr64: 1, ID: "n2"},
+	}
+	if fmt.Sprintf("%#v", nodes) != fmt.Sprintf("%#v", expected) {
+		t.Fatalf("%#v != %#v", nodes, expected)
+	}
+	if s := nodes[0].String(); s != "0013A20040A1B2C3(1234) \"n1\"" {
+		t.Fatal(s)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	d, f := newDev(t)
+	f.Close()
+	if _, ok := <-d.Receive(); ok {
+		t.Fatal("channel must be closed")
+	}
+	if _, err := d.AT("NI", nil); err == nil {
+		t.Fatal("read loop terminated")
+	}
+}
+
+//
+
+func newDev(t *testing.T) (*Dev, *fakeUART) {
+	f := newFakeUART(false)
+	go f.respond(t, []byte{0x88, 1, 'A', 'P', 0, 1})
+	d, err := New(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, f
+}
+
+// fakeUART is a fake XBee module. Writes are sent to w, reads are served from
+// r.
+type fakeUART struct {
+	escaped bool
+	w       chan []byte
+	r       chan []byte
+	buf     []byte
+	last    []byte // Last frame data received, updated by respond().
+	closed  chan struct{}
+}
+
+func newFakeUART(escaped bool) *fakeUART {
+	return &fakeUART{
+		escaped: escaped,
+		w:       make(chan []byte, 16),
+		r:       make(chan []byte, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (f *fakeUART) String() string {
+	return "fake"
+}
+
+func (f *fakeUART) Close() error {
+	close(f.closed)
+	return nil
+}
+
+func (f *fakeUART) Tx(w, r []byte) error {
+	if len(w) != 0 {
+		f.w <- append([]byte(nil), w...)
+	}
+	for i := range r {
+		for len(f.buf) == 0 {
+			select {
+			case f.buf = <-f.r:
+			case <-f.closed:
+				return errors.New("closed")
+			}
+		}
+		r[i] = f.buf[0]
+		f.buf = f.buf[1:]
+	}
+	return nil
+}
+
+func (f *fakeUART) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeUART) Speed(baud int) error {
+	return nil
+}
+
+func (f *fakeUART) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	return nil
+}
+
+// respond waits for one frame to be written and sends back the frame data
+// resp.
+func (f *fakeUART) respond(t *testing.T, resp []byte) {
+	w := <-f.w
+	if f.escaped {
+		w = unescape(w)
+	}
+	n := int(w[1])<<8 | int(w[2])
+	if len(w) != n+4 || checksum(w[3:3+n]) != w[3+n] {
+		t.Errorf("invalid frame %#v", w)
+	}
+	f.last = w[3 : 3+n]
+	f.send(resp)
+}
+
+func (f *fakeUART) send(data []byte) {
+	b := append([]byte{startDelimiter, byte(len(data) >> 8), byte(len(data))}, data...)
+	b = append(b, checksum(data))
+	if f.escaped {
+		b = escapeFrame(b)
+	}
+	f.r <- b
+}
+
+func unescape(b []byte) []byte {
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == escape {
+			i++
+			out = append(out, b[i]^escapeXOR)
+		} else {
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
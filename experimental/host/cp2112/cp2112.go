@@ -0,0 +1,521 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package cp2112
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+)
+
+// Dev is an open CP2112 device.
+type Dev struct {
+	GPIO0 gpio.PinIO
+	GPIO1 gpio.PinIO
+	GPIO2 gpio.PinIO
+	GPIO3 gpio.PinIO
+	GPIO4 gpio.PinIO
+	GPIO5 gpio.PinIO
+	GPIO6 gpio.PinIO
+	GPIO7 gpio.PinIO
+
+	// Immutable.
+	name string
+	d    hidDev
+
+	// Mutable.
+	mu  sync.Mutex
+	dir byte // Set bits are outputs.
+}
+
+func (d *Dev) String() string {
+	return d.name
+}
+
+// Close closes the device.
+func (d *Dev) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.d.Close()
+}
+
+// Pins returns all the GPIOs of the device.
+func (d *Dev) Pins() []gpio.PinIO {
+	return []gpio.PinIO{d.GPIO0, d.GPIO1, d.GPIO2, d.GPIO3, d.GPIO4, d.GPIO5, d.GPIO6, d.GPIO7}
+}
+
+// I2C returns the I²C bus of the device.
+//
+// The bus is always available; SCL and SDA are dedicated pins.
+func (d *Dev) I2C() (i2c.BusCloser, error) {
+	return &i2cBus{d: d}, nil
+}
+
+// All returns all the CP2112 devices found when the driver was initialized.
+func All() []*Dev {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]*Dev, len(all))
+	copy(out, all)
+	return out
+}
+
+//
+
+// Report IDs, from AN495.
+const (
+	reportGPIOConfig     = 0x02 // Feature: direction, push-pull, special.
+	reportGetGPIO        = 0x03 // Feature: latch value.
+	reportSetGPIO        = 0x04 // Feature: value and mask.
+	reportSMBusConfig    = 0x06 // Feature.
+	reportReadRequest    = 0x10 // Out: read.
+	reportWriteRead      = 0x11 // Out: write then repeated start read.
+	reportReadForce      = 0x12 // Out: retrieve the data read.
+	reportReadResponse   = 0x13 // In: data read.
+	reportWrite          = 0x14 // Out: write.
+	reportStatusRequest  = 0x15 // Out.
+	reportStatusResponse = 0x16 // In.
+)
+
+// Transfer status, byte 0 of the status response.
+const (
+	statusIdle     = 0
+	statusBusy     = 1
+	statusComplete = 2
+	statusError    = 3
+)
+
+// Limits of a single transfer.
+const (
+	maxWrite     = 61
+	maxWriteRead = 16
+	maxRead      = 512
+)
+
+// transferTimeout is the maximum duration of a transfer.
+const transferTimeout = time.Second
+
+// newDev initializes the n-th CP2112 found.
+func newDev(n int, h hidDev) (*Dev, error) {
+	d := &Dev{name: fmt.Sprintf("CP2112%d", n), d: h}
+	var b [5]byte
+	b[0] = reportGPIOConfig
+	if _, err := h.GetFeature(b[:]); err != nil {
+		return nil, err
+	}
+	d.dir = b[1]
+	if err := d.setSpeed(100000); err != nil {
+		return nil, err
+	}
+	pins := make([]*pin, 8)
+	for i := range pins {
+		pins[i] = &pin{d: d, name: fmt.Sprintf("%s.GPIO%d", d.name, i), num: 8*n + i, mask: 1 << uint(i)}
+	}
+	d.GPIO0, d.GPIO1, d.GPIO2, d.GPIO3 = pins[0], pins[1], pins[2], pins[3]
+	d.GPIO4, d.GPIO5, d.GPIO6, d.GPIO7 = pins[4], pins[5], pins[6], pins[7]
+	return d, nil
+}
+
+// setSpeed sets the SMBus configuration.
+//
+// The timeouts are in ms and the CP2112 retries transfers that were not
+// acknowledged up to 3 times.
+func (d *Dev) setSpeed(hz int64) error {
+	b := []byte{
+		reportSMBusConfig,
+		byte(hz >> 24), byte(hz >> 16), byte(hz >> 8), byte(hz),
+		0x02,       // Own address.
+		0,          // Auto send read.
+		0x03, 0xE8, // Write timeout.
+		0x03, 0xE8, // Read timeout.
+		1,       // SCL low timeout.
+		0, 0x03, // Retries.
+	}
+	_, err := d.d.SendFeature(b)
+	return err
+}
+
+// write sends an output report.
+//
+// d.mu must be held.
+func (d *Dev) write(b []byte) error {
+	_, err := d.d.Write(b)
+	return err
+}
+
+// read waits for an input report of the type id.
+//
+// d.mu must be held.
+func (d *Dev) read(id byte) ([]byte, error) {
+	var b [64]byte
+	for {
+		n, err := d.d.Read(b[:])
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, errors.New("cp2112: read timeout")
+		}
+		if b[0] == id {
+			return b[1:n], nil
+		}
+	}
+}
+
+// wait polls the transfer status until it is completed.
+//
+// d.mu must be held.
+func (d *Dev) wait() error {
+	for start := time.Now(); time.Since(start) < transferTimeout; {
+		if err := d.write([]byte{reportStatusRequest, 0x01}); err != nil {
+			return err
+		}
+		s, err := d.read(reportStatusResponse)
+		if err != nil {
+			return err
+		}
+		if len(s) < 2 {
+			return errors.New("cp2112: invalid transfer status")
+		}
+		switch s[0] {
+		case statusComplete:
+			return nil
+		case statusError:
+			return fmt.Errorf("cp2112: transfer failed with status %d", s[1])
+		case statusIdle:
+			if s[1] == 0 {
+				return nil
+			}
+			return fmt.Errorf("cp2112: transfer failed with status %d", s[1])
+		}
+	}
+	return errors.New("cp2112: transfer timeout")
+}
+
+// i2cBus is the I²C bus of a CP2112.
+type i2cBus struct {
+	d *Dev
+}
+
+func (i *i2cBus) String() string {
+	return i.d.name
+}
+
+// Close implements i2c.BusCloser.
+func (i *i2cBus) Close() error {
+	return nil
+}
+
+// Tx implements i2c.Bus.
+//
+// Up to 61 bytes can be written, or up to 16 bytes when followed by a read.
+// Up to 512 bytes can be read.
+func (i *i2cBus) Tx(addr uint16, w, r []byte) error {
+	if addr >= 0x80 {
+		return fmt.Errorf("cp2112: invalid I²C address %#x; 10 bits addresses are not supported", addr)
+	}
+	if len(w) > maxWrite || (len(r) != 0 && len(w) > maxWriteRead) {
+		return fmt.Errorf("cp2112: write of %d bytes is too large", len(w))
+	}
+	if len(r) > maxRead {
+		return fmt.Errorf("cp2112: read of %d bytes is too large", len(r))
+	}
+	if len(w) == 0 && len(r) == 0 {
+		return nil
+	}
+	a := byte(addr << 1)
+	var cmd []byte
+	switch {
+	case len(r) == 0:
+		cmd = append([]byte{reportWrite, a, byte(len(w))}, w...)
+	case len(w) == 0:
+		cmd = []byte{reportReadRequest, a, byte(len(r) >> 8), byte(len(r))}
+	default:
+		cmd = append([]byte{reportWriteRead, a, byte(len(r) >> 8), byte(len(r)), byte(len(w))}, w...)
+	}
+	i.d.mu.Lock()
+	defer i.d.mu.Unlock()
+	if err := i.d.write(cmd); err != nil {
+		return err
+	}
+	if err := i.d.wait(); err != nil {
+		return err
+	}
+	for j := 0; j < len(r); {
+		if err := i.d.write([]byte{reportReadForce, byte((len(r) - j) >> 8), byte(len(r) - j)}); err != nil {
+			return err
+		}
+		b, err := i.d.read(reportReadResponse)
+		if err != nil {
+			return err
+		}
+		if len(b) < 2 || b[0] == statusError {
+			return errors.New("cp2112: read failed")
+		}
+		n := int(b[1])
+		if n > len(b)-2 {
+			n = len(b) - 2
+		}
+		j += copy(r[j:], b[2:2+n])
+	}
+	return nil
+}
+
+// SetSpeed implements i2c.Bus.
+func (i *i2cBus) SetSpeed(hz int64) error {
+	if hz < 10000 || hz > 400000 {
+		return fmt.Errorf("cp2112: invalid I²C speed %dHz", hz)
+	}
+	i.d.mu.Lock()
+	defer i.d.mu.Unlock()
+	return i.d.setSpeed(hz)
+}
+
+// pin is a GPIO of a CP2112.
+type pin struct {
+	d    *Dev
+	name string
+	num  int
+	mask byte
+}
+
+func (p *pin) String() string {
+	return p.name
+}
+
+// Name implements pin.Pin.
+func (p *pin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+//
+// The GPIOs are 0 to 7 on the first device, 8 to 15 on the second device,
+// etc.
+func (p *pin) Number() int {
+	return p.num
+}
+
+// Function implements pin.Pin.
+func (p *pin) Function() string {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	l, err := p.read()
+	if err != nil {
+		return "<Unknown>"
+	}
+	if p.d.dir&p.mask != 0 {
+		return "Out/" + l.String()
+	}
+	return "In/" + l.String()
+}
+
+// Halt implements conn.Resource.
+func (p *pin) Halt() error {
+	return nil
+}
+
+// In implements gpio.PinIn.
+//
+// The CP2112 has no configurable pull resistor and no edge detection.
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull != gpio.PullNoChange && pull != gpio.Float {
+		return fmt.Errorf("cp2112 (%s): pull resistor is not supported", p)
+	}
+	if edge != gpio.NoEdge {
+		return fmt.Errorf("cp2112 (%s): edge detection is not supported", p)
+	}
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	return p.setDir(p.d.dir &^ p.mask)
+}
+
+// Read implements gpio.PinIn.
+func (p *pin) Read() gpio.Level {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	l, _ := p.read()
+	return l
+}
+
+// WaitForEdge implements gpio.PinIn.
+//
+// Edge detection is not supported so it always returns false.
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	return false
+}
+
+// Pull implements gpio.PinIn.
+func (p *pin) Pull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out implements gpio.PinOut.
+//
+// The output is push-pull.
+func (p *pin) Out(l gpio.Level) error {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	// Set the level before the direction to not create any glitch.
+	v := byte(0)
+	if l {
+		v = p.mask
+	}
+	if _, err := p.d.d.SendFeature([]byte{reportSetGPIO, v, p.mask}); err != nil {
+		return p.wrap(err)
+	}
+	if p.d.dir&p.mask != 0 {
+		return nil
+	}
+	return p.setDir(p.d.dir | p.mask)
+}
+
+// setDir sets the direction of all the GPIOs.
+//
+// d.mu must be held.
+func (p *pin) setDir(dir byte) error {
+	if _, err := p.d.d.SendFeature([]byte{reportGPIOConfig, dir, dir, 0, 0}); err != nil {
+		return p.wrap(err)
+	}
+	p.d.dir = dir
+	return nil
+}
+
+// read returns the level of the pin.
+//
+// d.mu must be held.
+func (p *pin) read() (gpio.Level, error) {
+	var b [2]byte
+	b[0] = reportGetGPIO
+	if _, err := p.d.d.GetFeature(b[:]); err != nil {
+		return gpio.Low, p.wrap(err)
+	}
+	return gpio.Level(b[1]&p.mask != 0), nil
+}
+
+func (p *pin) wrap(err error) error {
+	return fmt.Errorf("cp2112 (%s): %v", p, err)
+}
+
+var (
+	mu  sync.Mutex
+	all []*Dev
+)
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "cp2112"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	paths, err := hidEnumerate(vendorID, productID)
+	if err != nil {
+		return false, err
+	}
+	if len(paths) == 0 {
+		return false, errors.New("cp2112: no device found")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range paths {
+		h, err := hidOpen(path)
+		if err != nil {
+			return true, err
+		}
+		d, err := newDev(len(all), h)
+		if err != nil {
+			h.Close()
+			return true, err
+		}
+		if err := register(d); err != nil {
+			d.Close()
+			return true, err
+		}
+		all = append(all, d)
+	}
+	return true, nil
+}
+
+// Unload implements periph.Unloader.
+//
+// It unregisters the GPIOs and the I²C buses and closes the devices.
+func (d *driver) Unload() error {
+	mu.Lock()
+	defer mu.Unlock()
+	var err error
+	for _, dev := range all {
+		if err2 := unregister(dev); err == nil {
+			err = err2
+		}
+		if err2 := dev.Close(); err == nil {
+			err = err2
+		}
+	}
+	all = nil
+	return err
+}
+
+// register registers the GPIOs and the I²C bus of a device.
+//
+// On failure, what was registered is unregistered.
+func register(d *Dev) error {
+	for i, p := range d.Pins() {
+		if err := gpioreg.Register(p, true); err != nil {
+			unregisterPins(d.Pins()[:i])
+			return err
+		}
+	}
+	info := conn.Info{Driver: "cp2112", MaxSpeed: 400000}
+	if err := i2creg.RegisterWithInfo(d.name, nil, -1, info, func() (i2c.BusCloser, error) { return d.I2C() }); err != nil {
+		unregisterPins(d.Pins())
+		return err
+	}
+	return nil
+}
+
+// unregister unregisters the GPIOs and the I²C bus of a device.
+func unregister(d *Dev) error {
+	err := unregisterPins(d.Pins())
+	if err2 := i2creg.Unregister(d.name); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func unregisterPins(pins []gpio.PinIO) error {
+	var err error
+	for _, p := range pins {
+		if err2 := gpioreg.Unregister(p.Name()); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func init() {
+	if hasHID {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ i2c.BusCloser = &i2cBus{}
+var _ gpio.PinIO = &pin{}
+var _ periph.Driver = &driver{}
+var _ periph.Unloader = &driver{}
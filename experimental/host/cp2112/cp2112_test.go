@@ -0,0 +1,363 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package cp2112
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+)
+
+func TestNewDev(t *testing.T) {
+	h := &fakeHID{gpioConfig: 0x05}
+	d, err := newDev(1, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "CP21121" {
+		t.Fatal(s)
+	}
+	if d.dir != 0x05 {
+		t.Fatal(d.dir)
+	}
+	expected := []byte{0x06, 0, 1, 0x86, 0xA0, 0x02, 0, 0x03, 0xE8, 0x03, 0xE8, 1, 0, 0x03}
+	if len(h.features) != 1 || !bytes.Equal(h.features[0], expected) {
+		t.Fatalf("%#v", h.features)
+	}
+	pins := d.Pins()
+	if len(pins) != 8 {
+		t.Fatal(len(pins))
+	}
+	if s := pins[3].Name(); s != "CP21121.GPIO3" {
+		t.Fatal(s)
+	}
+	if n := pins[3].Number(); n != 11 {
+		t.Fatal(n)
+	}
+	if err := d.Close(); err != nil || !h.closed {
+		t.Fatal(err)
+	}
+}
+
+func TestNewDev_fail(t *testing.T) {
+	if _, err := newDev(0, &fakeHID{err: errors.New("oops")}); err == nil {
+		t.Fatal("feature failed")
+	}
+}
+
+func TestPin(t *testing.T) {
+	d, h := newFake(t)
+	if err := d.GPIO2.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.features) != 2 || !bytes.Equal(h.features[0], []byte{0x04, 0x04, 0x04}) || !bytes.Equal(h.features[1], []byte{0x02, 0x04, 0x04, 0, 0}) {
+		t.Fatalf("%#v", h.features)
+	}
+	h.features = nil
+	if err := d.GPIO2.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.features) != 1 || !bytes.Equal(h.features[0], []byte{0x04, 0x00, 0x04}) {
+		t.Fatalf("%#v", h.features)
+	}
+	h.latch = 0x04
+	if s := d.GPIO2.Function(); s != "Out/High" {
+		t.Fatal(s)
+	}
+	if l := d.GPIO2.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	h.features = nil
+	if err := d.GPIO2.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.features) != 1 || !bytes.Equal(h.features[0], []byte{0x02, 0, 0, 0, 0}) {
+		t.Fatalf("%#v", h.features)
+	}
+	if s := d.GPIO2.Function(); s != "In/High" {
+		t.Fatal(s)
+	}
+	if d.GPIO2.In(gpio.PullUp, gpio.NoEdge) == nil {
+		t.Fatal("pull is not supported")
+	}
+	if d.GPIO2.In(gpio.Float, gpio.RisingEdge) == nil {
+		t.Fatal("edge is not supported")
+	}
+	if d.GPIO2.WaitForEdge(-1) {
+		t.Fatal("edge is not supported")
+	}
+	if p := d.GPIO2.Pull(); p != gpio.PullNoChange {
+		t.Fatal(p)
+	}
+	h.err = errors.New("oops")
+	if s := d.GPIO2.Function(); s != "<Unknown>" {
+		t.Fatal(s)
+	}
+	if d.GPIO2.Out(gpio.High) == nil {
+		t.Fatal("feature failed")
+	}
+}
+
+func TestI2C_write(t *testing.T) {
+	d, h := newFake(t)
+	b, err := d.I2C()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.r = [][]byte{{0x16, statusComplete, 0}}
+	if err := b.Tx(0x21, []byte{1, 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{{0x14, 0x42, 2, 1, 2}, {0x15, 0x01}}
+	if !equal(h.w, expected) {
+		t.Fatalf("%#v", h.w)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestI2C_writeRead(t *testing.T) {
+	d, h := newFake(t)
+	b, _ := d.I2C()
+	h.r = [][]byte{
+		{0x16, statusBusy, 0},
+		{0x16, statusComplete, 0},
+		{0x13, statusComplete, 2, 0xA, 0xB},
+		{0x13, statusComplete, 1, 0xC},
+	}
+	r := make([]byte, 3)
+	if err := b.Tx(0x21, []byte{1}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0xA, 0xB, 0xC}) {
+		t.Fatalf("%#v", r)
+	}
+	expected := [][]byte{
+		{0x11, 0x42, 0, 3, 1, 1},
+		{0x15, 0x01},
+		{0x15, 0x01},
+		{0x12, 0, 3},
+		{0x12, 0, 1},
+	}
+	if !equal(h.w, expected) {
+		t.Fatalf("%#v", h.w)
+	}
+}
+
+func TestI2C_read(t *testing.T) {
+	d, h := newFake(t)
+	b, _ := d.I2C()
+	h.r = [][]byte{{0x16, statusComplete, 0}, {0x13, statusComplete, 1, 0xA}}
+	r := make([]byte, 1)
+	if err := b.Tx(0x21, nil, r); err != nil {
+		t.Fatal(err)
+	}
+	if !equal(h.w, [][]byte{{0x10, 0x42, 0, 1}, {0x15, 0x01}, {0x12, 0, 1}}) {
+		t.Fatalf("%#v", h.w)
+	}
+}
+
+func TestI2C_fail(t *testing.T) {
+	d, h := newFake(t)
+	b, _ := d.I2C()
+	if b.Tx(0x80, []byte{1}, nil) == nil {
+		t.Fatal("invalid address")
+	}
+	if b.Tx(0x21, make([]byte, 62), nil) == nil {
+		t.Fatal("write too large")
+	}
+	if b.Tx(0x21, make([]byte, 17), make([]byte, 1)) == nil {
+		t.Fatal("write too large")
+	}
+	if b.Tx(0x21, nil, make([]byte, 513)) == nil {
+		t.Fatal("read too large")
+	}
+	if err := b.Tx(0x21, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	h.r = [][]byte{{0x16, statusError, 2}}
+	if b.Tx(0x21, []byte{1}, nil) == nil {
+		t.Fatal("not acknowledged")
+	}
+	h.r = nil
+	if b.Tx(0x21, []byte{1}, nil) == nil {
+		t.Fatal("read timeout")
+	}
+	if b.SetSpeed(1000000) == nil {
+		t.Fatal("invalid speed")
+	}
+	if err := b.SetSpeed(400000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriver_reload(t *testing.T) {
+	defer func() {
+		hidEnumerate = hidEnumerateDefault
+		hidOpen = hidOpenDefault
+	}()
+	var handles []*fakeHID
+	hidEnumerate = func(vid, pid uint16) ([]string, error) {
+		return []string{"/dev/hidraw0"}, nil
+	}
+	hidOpen = func(path string) (hidDev, error) {
+		h := &fakeHID{}
+		handles = append(handles, h)
+		return h, nil
+	}
+	d := driver{}
+	// Load, unload then load again, as done by periph.Reload().
+	for i := 0; i < 2; i++ {
+		if ok, err := d.Init(); !ok || err != nil {
+			t.Fatal(ok, err)
+		}
+		if len(All()) != 1 || gpioreg.ByName("CP21120.GPIO3") == nil {
+			t.Fatal("device must be registered")
+		}
+		b, err := i2creg.Open("CP21120")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Close()
+		if err := d.Unload(); err != nil {
+			t.Fatal(err)
+		}
+		if len(All()) != 0 || gpioreg.ByName("CP21120.GPIO3") != nil || len(i2creg.All()) != 0 {
+			t.Fatal("device must be unregistered")
+		}
+		if !handles[i].closed {
+			t.Fatal("device must be closed")
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	defer func() {
+		hidEnumerate = hidEnumerateDefault
+		hidOpen = hidOpenDefault
+	}()
+	var handles []*fakeHID
+	hidEnumerate = func(vid, pid uint16) ([]string, error) {
+		return []string{"/dev/hidraw0"}, nil
+	}
+	hidOpen = func(path string) (hidDev, error) {
+		h := &fakeHID{}
+		handles = append(handles, h)
+		return h, nil
+	}
+	if _, err := periph.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		n := len(handles)
+		state, err := periph.Reload("cp2112")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(state.Loaded) != 1 || state.Loaded[0].String() != "cp2112" {
+			t.Fatal(state)
+		}
+		if len(handles) != n+1 {
+			t.Fatal("the device must have been opened again")
+		}
+		if n != 0 && !handles[n-1].closed {
+			t.Fatal("the previous device must have been closed")
+		}
+		if len(All()) != 1 || gpioreg.ByName("CP21120.GPIO3") == nil || len(i2creg.All()) != 1 {
+			t.Fatal("device must be registered")
+		}
+	}
+	if err := (&driver{}).Unload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//
+
+func newFake(t *testing.T) (*Dev, *fakeHID) {
+	h := &fakeHID{}
+	d, err := newDev(0, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.features = nil
+	return d, h
+}
+
+// fakeHID implements hidDev.
+type fakeHID struct {
+	gpioConfig byte
+	latch      byte
+	features   [][]byte // Feature reports sent.
+	w          [][]byte // Output reports written.
+	r          [][]byte // Input reports to read.
+	err        error
+	closed     bool
+}
+
+func (f *fakeHID) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeHID) Read(b []byte) (int, error) {
+	if len(f.r) == 0 {
+		return 0, nil
+	}
+	n := copy(b, f.r[0])
+	f.r = f.r[1:]
+	return n, nil
+}
+
+func (f *fakeHID) Write(b []byte) (int, error) {
+	f.w = append(f.w, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (f *fakeHID) GetFeature(b []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	switch b[0] {
+	case reportGPIOConfig:
+		b[1] = f.gpioConfig
+	case reportGetGPIO:
+		b[1] = f.latch
+	}
+	return len(b), nil
+}
+
+func (f *fakeHID) SendFeature(b []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.features = append(f.features, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func equal(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	// The driver is only registered automatically when hidapi is available.
+	if !hasHID {
+		periph.MustRegister(&driver{})
+	}
+}
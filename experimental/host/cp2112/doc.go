@@ -0,0 +1,34 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package cp2112 implements support for the Silicon Labs CP2112 HID USB to
+// SMBus/I²C bridge, which exposes an I²C bus and 8 GPIOs.
+//
+// The CP2112 is a HID device so it needs no kernel driver on macOS, Windows
+// or linux. It is accessed via hidapi, which is linked with cgo. This package
+// is only functional with the build tag 'hidapi' since the library is
+// generally not installed, which would cause a go get failure.
+//
+//     go build -tags hidapi
+//
+// macOS
+//
+//     brew install hidapi
+//
+// Ubuntu
+//
+//     sudo apt install libhidapi-dev
+//
+// Each CP2112 found is registered as follow, where N is the device index:
+//
+// - the GPIOs as "CP2112N.GPIO0" to "CP2112N.GPIO7" in gpioreg.
+//
+// - the I²C bus as "CP2112N" in i2creg.
+//
+// Datasheet
+//
+// https://www.silabs.com/documents/public/data-sheets/cp2112-datasheet.pdf
+//
+// https://www.silabs.com/documents/public/application-notes/an495-cp2112-interface-specification.pdf
+package cp2112
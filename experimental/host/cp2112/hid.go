@@ -0,0 +1,33 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package cp2112
+
+// hidDev is an open HID device.
+//
+// The first byte of each buffer is the report ID.
+type hidDev interface {
+	Close() error
+	// Read reads an input report, or returns 0 after one second without
+	// report.
+	Read(b []byte) (int, error)
+	// Write writes an output report.
+	Write(b []byte) (int, error)
+	GetFeature(b []byte) (int, error)
+	SendFeature(b []byte) (int, error)
+}
+
+// USB IDs of the CP2112.
+const (
+	vendorID  = 0x10C4
+	productID = 0xEA90
+)
+
+var (
+	// hidEnumerate returns the paths of the HID devices with the IDs
+	// specified.
+	hidEnumerate = hidEnumerateDefault
+	// hidOpen opens a HID device by path.
+	hidOpen = hidOpenDefault
+)
@@ -0,0 +1,79 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build cgo,hidapi
+
+package cp2112
+
+/*
+#cgo darwin LDFLAGS: -lhidapi
+#cgo linux LDFLAGS: -lhidapi-hidraw
+#cgo windows LDFLAGS: -lhidapi
+#include <stdlib.h>
+#include <hidapi/hidapi.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+const hasHID = true
+
+// hidapiDev is an open hid_device.
+type hidapiDev struct {
+	d *C.hid_device
+}
+
+func (h *hidapiDev) Close() error {
+	C.hid_close(h.d)
+	return nil
+}
+
+func (h *hidapiDev) Read(b []byte) (int, error) {
+	return h.check(C.hid_read_timeout(h.d, (*C.uchar)(unsafe.Pointer(&b[0])), C.size_t(len(b)), 1000))
+}
+
+func (h *hidapiDev) Write(b []byte) (int, error) {
+	return h.check(C.hid_write(h.d, (*C.uchar)(unsafe.Pointer(&b[0])), C.size_t(len(b))))
+}
+
+func (h *hidapiDev) GetFeature(b []byte) (int, error) {
+	return h.check(C.hid_get_feature_report(h.d, (*C.uchar)(unsafe.Pointer(&b[0])), C.size_t(len(b))))
+}
+
+func (h *hidapiDev) SendFeature(b []byte) (int, error) {
+	return h.check(C.hid_send_feature_report(h.d, (*C.uchar)(unsafe.Pointer(&b[0])), C.size_t(len(b))))
+}
+
+func (h *hidapiDev) check(n C.int) (int, error) {
+	if n < 0 {
+		return 0, errors.New("cp2112: HID I/O failed")
+	}
+	return int(n), nil
+}
+
+func hidEnumerateDefault(vid, pid uint16) ([]string, error) {
+	if C.hid_init() != 0 {
+		return nil, errors.New("cp2112: failed to initialize hidapi")
+	}
+	devs := C.hid_enumerate(C.ushort(vid), C.ushort(pid))
+	defer C.hid_free_enumeration(devs)
+	var out []string
+	for d := devs; d != nil; d = d.next {
+		out = append(out, C.GoString(d.path))
+	}
+	return out, nil
+}
+
+func hidOpenDefault(path string) (hidDev, error) {
+	p := C.CString(path)
+	defer C.free(unsafe.Pointer(p))
+	d := C.hid_open_path(p)
+	if d == nil {
+		return nil, errors.New("cp2112: failed to open " + path)
+	}
+	return &hidapiDev{d: d}, nil
+}
@@ -0,0 +1,19 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !cgo !hidapi
+
+package cp2112
+
+import "errors"
+
+const hasHID = false
+
+func hidEnumerateDefault(vid, pid uint16) ([]string, error) {
+	return nil, errors.New("cp2112: build with the tag hidapi")
+}
+
+func hidOpenDefault(path string) (hidDev, error) {
+	return nil, errors.New("cp2112: build with the tag hidapi")
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+// handle is an open D2XX device.
+//
+// Read blocks until the buffer is filled or the read timeout set when the
+// device was opened expires.
+type handle interface {
+	Close() error
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	SetBitMode(mask, mode byte) error
+}
+
+// devInfo is the information about a device as enumerated by D2XX.
+type devInfo struct {
+	typ    uint32
+	serial string
+	desc   string
+}
+
+// typeFT232H is FT_DEVICE_232H.
+const typeFT232H = 8
+
+// Bit modes, as used by SetBitMode.
+const (
+	bitModeReset = 0x00
+	bitModeMPSSE = 0x02
+)
+
+var (
+	// d2xxDevices enumerates the devices connected to the host.
+	d2xxDevices = d2xxDevicesDefault
+	// d2xxOpen opens the device at index i, as returned by d2xxDevices.
+	d2xxOpen = d2xxOpenDefault
+)
+
+// statusNames are the names of the FT_STATUS values.
+var statusNames = []string{
+	"FT_OK",
+	"FT_INVALID_HANDLE",
+	"FT_DEVICE_NOT_FOUND",
+	"FT_DEVICE_NOT_OPENED",
+	"FT_IO_ERROR",
+	"FT_INSUFFICIENT_RESOURCES",
+	"FT_INVALID_PARAMETER",
+	"FT_INVALID_BAUD_RATE",
+	"FT_DEVICE_NOT_OPENED_FOR_ERASE",
+	"FT_DEVICE_NOT_OPENED_FOR_WRITE",
+	"FT_FAILED_TO_WRITE_DEVICE",
+	"FT_EEPROM_READ_FAILED",
+	"FT_EEPROM_WRITE_FAILED",
+	"FT_EEPROM_ERASE_FAILED",
+	"FT_EEPROM_NOT_PRESENT",
+	"FT_EEPROM_NOT_PROGRAMMED",
+	"FT_INVALID_ARGS",
+	"FT_NOT_SUPPORTED",
+	"FT_OTHER_ERROR",
+	"FT_DEVICE_LIST_NOT_READY",
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
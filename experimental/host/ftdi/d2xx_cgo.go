@@ -0,0 +1,108 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build cgo,ftd2xx,!windows
+
+package ftdi
+
+/*
+#cgo LDFLAGS: -lftd2xx
+#include <ftd2xx.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+const hasD2XX = true
+
+// d2xxHandle is a FT_HANDLE.
+type d2xxHandle struct {
+	h C.FT_HANDLE
+}
+
+func (d *d2xxHandle) Close() error {
+	return status(C.FT_Close(d.h))
+}
+
+func (d *d2xxHandle) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var n C.DWORD
+	err := status(C.FT_Read(d.h, C.LPVOID(unsafe.Pointer(&b[0])), C.DWORD(len(b)), &n))
+	return int(n), err
+}
+
+func (d *d2xxHandle) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var n C.DWORD
+	err := status(C.FT_Write(d.h, C.LPVOID(unsafe.Pointer(&b[0])), C.DWORD(len(b)), &n))
+	return int(n), err
+}
+
+func (d *d2xxHandle) SetBitMode(mask, mode byte) error {
+	return status(C.FT_SetBitMode(d.h, C.UCHAR(mask), C.UCHAR(mode)))
+}
+
+func d2xxDevicesDefault() ([]devInfo, error) {
+	var num C.DWORD
+	if err := status(C.FT_CreateDeviceInfoList(&num)); err != nil {
+		return nil, err
+	}
+	out := make([]devInfo, num)
+	for i := range out {
+		var flags, typ, id, loc C.DWORD
+		var serial [16]byte
+		var desc [64]byte
+		var h C.FT_HANDLE
+		if err := status(C.FT_GetDeviceInfoDetail(C.DWORD(i), &flags, &typ, &id, &loc, C.LPVOID(unsafe.Pointer(&serial[0])), C.LPVOID(unsafe.Pointer(&desc[0])), &h)); err != nil {
+			return nil, err
+		}
+		out[i] = devInfo{typ: uint32(typ), serial: cString(serial[:]), desc: cString(desc[:])}
+	}
+	return out, nil
+}
+
+func d2xxOpenDefault(i int) (handle, error) {
+	d := &d2xxHandle{}
+	if err := status(C.FT_Open(C.int(i), &d.h)); err != nil {
+		return nil, err
+	}
+	if err := setup(d); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// setup configures the device for low latency MPSSE transfers.
+func setup(d *d2xxHandle) error {
+	if err := status(C.FT_ResetDevice(d.h)); err != nil {
+		return err
+	}
+	if err := status(C.FT_SetUSBParameters(d.h, 65536, 65536)); err != nil {
+		return err
+	}
+	if err := status(C.FT_SetTimeouts(d.h, 1000, 1000)); err != nil {
+		return err
+	}
+	return status(C.FT_SetLatencyTimer(d.h, 1))
+}
+
+// status converts a FT_STATUS to an error.
+func status(s C.FT_STATUS) error {
+	if s == C.FT_OK {
+		return nil
+	}
+	if int(s) < len(statusNames) {
+		return errors.New("ftdi: " + statusNames[s])
+	}
+	return fmt.Errorf("ftdi: FT_STATUS %d", s)
+}
@@ -0,0 +1,19 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !windows,!cgo !windows,!ftd2xx
+
+package ftdi
+
+import "errors"
+
+const hasD2XX = false
+
+func d2xxDevicesDefault() ([]devInfo, error) {
+	return nil, errors.New("ftdi: D2XX is only supported on Windows, or with the build tag ftd2xx")
+}
+
+func d2xxOpenDefault(i int) (handle, error) {
+	return nil, errors.New("ftdi: D2XX is only supported on Windows, or with the build tag ftd2xx")
+}
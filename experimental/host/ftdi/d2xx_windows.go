@@ -0,0 +1,117 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const hasD2XX = true
+
+var (
+	d2xxDLL                  = syscall.NewLazyDLL("ftd2xx.dll")
+	procCreateDeviceInfoList = d2xxDLL.NewProc("FT_CreateDeviceInfoList")
+	procGetDeviceInfoDetail  = d2xxDLL.NewProc("FT_GetDeviceInfoDetail")
+	procOpen                 = d2xxDLL.NewProc("FT_Open")
+	procClose                = d2xxDLL.NewProc("FT_Close")
+	procResetDevice          = d2xxDLL.NewProc("FT_ResetDevice")
+	procSetUSBParameters     = d2xxDLL.NewProc("FT_SetUSBParameters")
+	procSetTimeouts          = d2xxDLL.NewProc("FT_SetTimeouts")
+	procSetLatencyTimer      = d2xxDLL.NewProc("FT_SetLatencyTimer")
+	procSetBitMode           = d2xxDLL.NewProc("FT_SetBitMode")
+	procRead                 = d2xxDLL.NewProc("FT_Read")
+	procWrite                = d2xxDLL.NewProc("FT_Write")
+)
+
+// d2xxHandle is a FT_HANDLE.
+type d2xxHandle uintptr
+
+func (h d2xxHandle) Close() error {
+	return status(procClose.Call(uintptr(h)))
+}
+
+func (h d2xxHandle) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var n uint32
+	err := status(procRead.Call(uintptr(h), uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), uintptr(unsafe.Pointer(&n))))
+	return int(n), err
+}
+
+func (h d2xxHandle) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var n uint32
+	err := status(procWrite.Call(uintptr(h), uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), uintptr(unsafe.Pointer(&n))))
+	return int(n), err
+}
+
+func (h d2xxHandle) SetBitMode(mask, mode byte) error {
+	return status(procSetBitMode.Call(uintptr(h), uintptr(mask), uintptr(mode)))
+}
+
+func d2xxDevicesDefault() ([]devInfo, error) {
+	if err := d2xxDLL.Load(); err != nil {
+		return nil, fmt.Errorf("ftdi: %v; please install the D2XX driver", err)
+	}
+	var num uint32
+	if err := status(procCreateDeviceInfoList.Call(uintptr(unsafe.Pointer(&num)))); err != nil {
+		return nil, err
+	}
+	out := make([]devInfo, num)
+	for i := range out {
+		var flags, typ, id, loc uint32
+		var serial [16]byte
+		var desc [64]byte
+		var h uintptr
+		if err := status(procGetDeviceInfoDetail.Call(uintptr(i), uintptr(unsafe.Pointer(&flags)), uintptr(unsafe.Pointer(&typ)), uintptr(unsafe.Pointer(&id)), uintptr(unsafe.Pointer(&loc)), uintptr(unsafe.Pointer(&serial[0])), uintptr(unsafe.Pointer(&desc[0])), uintptr(unsafe.Pointer(&h)))); err != nil {
+			return nil, err
+		}
+		out[i] = devInfo{typ: typ, serial: cString(serial[:]), desc: cString(desc[:])}
+	}
+	return out, nil
+}
+
+func d2xxOpenDefault(i int) (handle, error) {
+	var h d2xxHandle
+	if err := status(procOpen.Call(uintptr(i), uintptr(unsafe.Pointer(&h)))); err != nil {
+		return nil, err
+	}
+	if err := setup(h); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// setup configures the device for low latency MPSSE transfers.
+func setup(h d2xxHandle) error {
+	if err := status(procResetDevice.Call(uintptr(h))); err != nil {
+		return err
+	}
+	if err := status(procSetUSBParameters.Call(uintptr(h), 65536, 65536)); err != nil {
+		return err
+	}
+	if err := status(procSetTimeouts.Call(uintptr(h), 1000, 1000)); err != nil {
+		return err
+	}
+	return status(procSetLatencyTimer.Call(uintptr(h), 1))
+}
+
+// status converts a FT_STATUS to an error.
+func status(r uintptr, _ uintptr, _ error) error {
+	if r == 0 {
+		return nil
+	}
+	if int(r) < len(statusNames) {
+		return errors.New("ftdi: " + statusNames[r])
+	}
+	return fmt.Errorf("ftdi: FT_STATUS %d", r)
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ftdi implements support for the FTDI FT232H USB device, which
+// exposes 16 GPIOs, an I²C bus and a SPI port to the host it is plugged in.
+//
+// It is primarily meant to exercise device drivers from a Windows or macOS
+// development machine, which has no native GPIO, I²C or SPI support. It uses
+// the FTDI D2XX driver:
+//
+// - On Windows, ftd2xx.dll is loaded at runtime so cgo is not needed.
+//
+// - On other OSes, libftd2xx is linked with cgo. This is only built with the
+// build tag 'ftd2xx' since the library is generally not installed, which
+// would cause a go get failure. On macOS, the Apple FTDI serial driver must
+// be unloaded for D2XX to access the device.
+//
+//     go build -tags ftd2xx
+//
+// The driver is not registered when D2XX is not available.
+//
+// Each FT232H found is registered as follow, where N is the device index:
+//
+// - the GPIOs as "FT232HN.D0" to "FT232HN.D7" and "FT232HN.C0" to
+// "FT232HN.C7" in gpioreg.
+//
+// - the I²C bus as "FT232HN.I2C" in i2creg. D0 is SCL, D1 and D2 must be
+// wired together as SDA.
+//
+// - the SPI port as "FT232HN.SPI" in spireg. D0 is CLK, D1 is MOSI, D2 is MISO
+// and D3 is CS.
+//
+// The I²C bus and the SPI port share the MPSSE engine, so only one of them can
+// be open at a time.
+//
+// Datasheet
+//
+// http://www.ftdichip.com/Support/Documents/DataSheets/ICs/DS_FT232H.pdf
+//
+// http://www.ftdichip.com/Support/Documents/AppNotes/AN_108_Command_Processor_for_MPSSE_and_MCU_Host_Bus_Emulation_Modes.pdf
+//
+// http://www.ftdichip.com/Support/Documents/AppNotes/AN_255_USB%20to%20I2C%20Example%20using%20the%20FT232H%20and%20FT201X%20devices.pdf
+package ftdi
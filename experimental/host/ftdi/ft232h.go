@@ -0,0 +1,341 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// FT232H is an open FT232H device.
+//
+// The pins D0 to D3 are used by the I²C bus or the SPI port while they are
+// open.
+type FT232H struct {
+	D0 gpio.PinIO // CLK, SCL
+	D1 gpio.PinIO // MOSI, SDA out
+	D2 gpio.PinIO // MISO, SDA in
+	D3 gpio.PinIO // CS
+	D4 gpio.PinIO //
+	D5 gpio.PinIO //
+	D6 gpio.PinIO //
+	D7 gpio.PinIO //
+	C0 gpio.PinIO //
+	C1 gpio.PinIO //
+	C2 gpio.PinIO //
+	C3 gpio.PinIO //
+	C4 gpio.PinIO //
+	C5 gpio.PinIO //
+	C6 gpio.PinIO //
+	C7 gpio.PinIO //
+
+	// Immutable.
+	name string
+	h    handle
+
+	// Mutable.
+	mu   sync.Mutex
+	dbus bus  // ADBUS, D0 to D7.
+	cbus bus  // ACBUS, C0 to C7.
+	mode mode // Function of D0 to D3.
+}
+
+func (f *FT232H) String() string {
+	return f.name
+}
+
+// Close closes the device.
+func (f *FT232H) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.h.Close()
+}
+
+// Pins returns all the GPIOs of the device, D0 to D7 then C0 to C7.
+func (f *FT232H) Pins() []gpio.PinIO {
+	return []gpio.PinIO{
+		f.D0, f.D1, f.D2, f.D3, f.D4, f.D5, f.D6, f.D7,
+		f.C0, f.C1, f.C2, f.C3, f.C4, f.C5, f.C6, f.C7,
+	}
+}
+
+//
+
+// MPSSE commands, from AN_108.
+const (
+	cmdDataOut      = 0x10 // Clock data out on TDI (D1).
+	cmdDataIn       = 0x20 // Clock data in from TDO (D2).
+	cmdWriteFalling = 0x01 // Write on the falling edge instead of the rising edge.
+	cmdBits         = 0x02 // Length is in bits instead of bytes.
+	cmdReadFalling  = 0x04 // Read on the falling edge instead of the rising edge.
+	cmdLSBFirst     = 0x08 // Shift the LSB first instead of the MSB.
+
+	cmdSetD          = 0x80 // Set ADBUS value and direction.
+	cmdGetD          = 0x81 // Read ADBUS.
+	cmdSetC          = 0x82 // Set ACBUS value and direction.
+	cmdGetC          = 0x83 // Read ACBUS.
+	cmdLoopbackOff   = 0x85
+	cmdClockDivisor  = 0x86
+	cmdFlush         = 0x87 // Send immediate.
+	cmdClockDiv5Off  = 0x8A // Use the 60MHz clock.
+	cmdThreePhaseOn  = 0x8C
+	cmdThreePhaseOff = 0x8D
+	cmdAdaptiveOff   = 0x97
+	cmdDriveZero     = 0x9E // Open drain on the pins specified.
+	cmdBad           = 0xAA // Invalid command, used to synchronize.
+)
+
+// maxClock is the maximum MPSSE clock rate.
+const maxClock = 30000000
+
+// mode is the function of D0 to D3.
+type mode int
+
+const (
+	modeGPIO mode = iota
+	modeI2C
+	modeSPI
+)
+
+// bus is the state of 8 GPIOs.
+type bus struct {
+	value byte // Output level.
+	dir   byte // Set bits are outputs.
+}
+
+// newFT232H initializes the n-th FT232H found.
+func newFT232H(n int, h handle) (*FT232H, error) {
+	f := &FT232H{name: fmt.Sprintf("FT232H%d", n), h: h}
+	if err := f.init(); err != nil {
+		return nil, err
+	}
+	pins := make([]*pin, 16)
+	for i := range pins {
+		pins[i] = &pin{f: f, name: fmt.Sprintf("%s.D%d", f.name, i), num: 16*n + i, mask: 1 << uint(i%8), c: i >= 8}
+		if pins[i].c {
+			pins[i].name = fmt.Sprintf("%s.C%d", f.name, i-8)
+		}
+	}
+	f.D0, f.D1, f.D2, f.D3 = pins[0], pins[1], pins[2], pins[3]
+	f.D4, f.D5, f.D6, f.D7 = pins[4], pins[5], pins[6], pins[7]
+	f.C0, f.C1, f.C2, f.C3 = pins[8], pins[9], pins[10], pins[11]
+	f.C4, f.C5, f.C6, f.C7 = pins[12], pins[13], pins[14], pins[15]
+	return f, nil
+}
+
+// init switches the device to MPSSE mode with all the GPIOs as inputs.
+func (f *FT232H) init() error {
+	if err := f.h.SetBitMode(0, bitModeReset); err != nil {
+		return err
+	}
+	if err := f.h.SetBitMode(0, bitModeMPSSE); err != nil {
+		return err
+	}
+	// The MPSSE answers 0xFA followed by the invalid command, which confirms
+	// that it is synchronized.
+	var b [2]byte
+	if err := f.tx([]byte{cmdBad}, b[:]); err != nil {
+		return err
+	}
+	if b[0] != 0xFA || b[1] != cmdBad {
+		return fmt.Errorf("ftdi: failed to synchronize MPSSE: %#v", b)
+	}
+	return f.tx([]byte{
+		cmdClockDiv5Off, cmdAdaptiveOff, cmdThreePhaseOff, cmdLoopbackOff,
+		cmdSetD, 0, 0, cmdSetC, 0, 0,
+	}, nil)
+}
+
+// tx writes the MPSSE commands then reads len(r) bytes of response.
+//
+// f.mu must be held.
+func (f *FT232H) tx(w, r []byte) error {
+	if _, err := f.h.Write(w); err != nil {
+		return err
+	}
+	for i := 0; i < len(r); {
+		n, err := f.h.Read(r[i:])
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("ftdi: read timeout")
+		}
+		i += n
+	}
+	return nil
+}
+
+// clockCmd returns the commands to set the MPSSE clock to the highest rate
+// not above hz, and the rate selected.
+func clockCmd(hz int64) ([]byte, int64) {
+	div := int64(0)
+	if hz < maxClock {
+		div = (maxClock + hz - 1) / hz
+		div--
+	}
+	if div > 0xFFFF {
+		div = 0xFFFF
+	}
+	return []byte{cmdClockDivisor, byte(div), byte(div >> 8)}, maxClock / (div + 1)
+}
+
+// pin is a GPIO of a FT232H.
+type pin struct {
+	f    *FT232H
+	name string
+	num  int
+	mask byte
+	c    bool // ACBUS instead of ADBUS.
+}
+
+func (p *pin) String() string {
+	return p.name
+}
+
+// Name implements pin.Pin.
+func (p *pin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+//
+// D0 to D7 are 0 to 7 and C0 to C7 are 8 to 15 on the first device, 16 to
+// 31 on the second device, etc.
+func (p *pin) Number() int {
+	return p.num
+}
+
+// Function implements pin.Pin.
+func (p *pin) Function() string {
+	p.f.mu.Lock()
+	defer p.f.mu.Unlock()
+	if s := p.reserved(); s != "" {
+		return s
+	}
+	b := p.bus()
+	if b.dir&p.mask != 0 {
+		return "Out/" + gpio.Level(b.value&p.mask != 0).String()
+	}
+	l, err := p.read()
+	if err != nil {
+		return "In/<Unknown>"
+	}
+	return "In/" + l.String()
+}
+
+// Halt implements conn.Resource.
+func (p *pin) Halt() error {
+	return nil
+}
+
+// In implements gpio.PinIn.
+//
+// The FT232H has no configurable pull resistor and no edge detection.
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull != gpio.PullNoChange && pull != gpio.Float {
+		return fmt.Errorf("ftdi (%s): pull resistor is not supported", p)
+	}
+	if edge != gpio.NoEdge {
+		return fmt.Errorf("ftdi (%s): edge detection is not supported", p)
+	}
+	p.f.mu.Lock()
+	defer p.f.mu.Unlock()
+	if s := p.reserved(); s != "" {
+		return fmt.Errorf("ftdi (%s): pin is used as %s", p, s)
+	}
+	b := p.bus()
+	b.dir &^= p.mask
+	return p.f.tx(p.setCmd(), nil)
+}
+
+// Read implements gpio.PinIn.
+func (p *pin) Read() gpio.Level {
+	p.f.mu.Lock()
+	defer p.f.mu.Unlock()
+	l, _ := p.read()
+	return l
+}
+
+// WaitForEdge implements gpio.PinIn.
+//
+// Edge detection is not supported so it always returns false.
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	return false
+}
+
+// Pull implements gpio.PinIn.
+func (p *pin) Pull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out implements gpio.PinOut.
+func (p *pin) Out(l gpio.Level) error {
+	p.f.mu.Lock()
+	defer p.f.mu.Unlock()
+	if s := p.reserved(); s != "" {
+		return fmt.Errorf("ftdi (%s): pin is used as %s", p, s)
+	}
+	b := p.bus()
+	b.dir |= p.mask
+	if l {
+		b.value |= p.mask
+	} else {
+		b.value &^= p.mask
+	}
+	return p.f.tx(p.setCmd(), nil)
+}
+
+// reserved returns the function of the pin if it is used by the I²C bus or
+// the SPI port.
+func (p *pin) reserved() string {
+	if p.c {
+		return ""
+	}
+	switch p.f.mode {
+	case modeI2C:
+		if i := p.num % 16; i < 3 {
+			return [...]string{"I2C_SCL", "I2C_SDA", "I2C_SDA"}[i]
+		}
+	case modeSPI:
+		if i := p.num % 16; i < 4 {
+			return [...]string{"SPI_CLK", "SPI_MOSI", "SPI_MISO", "SPI_CS"}[i]
+		}
+	}
+	return ""
+}
+
+func (p *pin) bus() *bus {
+	if p.c {
+		return &p.f.cbus
+	}
+	return &p.f.dbus
+}
+
+func (p *pin) setCmd() []byte {
+	b := p.bus()
+	if p.c {
+		return []byte{cmdSetC, b.value, b.dir}
+	}
+	return []byte{cmdSetD, b.value, b.dir}
+}
+
+func (p *pin) read() (gpio.Level, error) {
+	cmd := byte(cmdGetD)
+	if p.c {
+		cmd = cmdGetC
+	}
+	var b [1]byte
+	if err := p.f.tx([]byte{cmd, cmdFlush}, b[:]); err != nil {
+		return gpio.Low, err
+	}
+	return b[0]&p.mask != 0, nil
+}
+
+var _ gpio.PinIO = &pin{}
@@ -0,0 +1,150 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"sync"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+// All returns all the FT232H devices found when the driver was initialized.
+func All() []*FT232H {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]*FT232H, len(all))
+	copy(out, all)
+	return out
+}
+
+//
+
+var (
+	mu  sync.Mutex
+	all []*FT232H
+)
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "ftdi"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	devs, err := d2xxDevices()
+	if err != nil {
+		return false, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, info := range devs {
+		if info.typ != typeFT232H {
+			continue
+		}
+		h, err := d2xxOpen(i)
+		if err != nil {
+			return true, err
+		}
+		f, err := newFT232H(len(all), h)
+		if err != nil {
+			h.Close()
+			return true, err
+		}
+		if err := register(f); err != nil {
+			f.Close()
+			return true, err
+		}
+		all = append(all, f)
+	}
+	return true, nil
+}
+
+// Unload implements periph.Unloader.
+//
+// It unregisters the GPIOs, the I²C buses and the SPI ports and closes the
+// devices.
+func (d *driver) Unload() error {
+	mu.Lock()
+	defer mu.Unlock()
+	var err error
+	for _, f := range all {
+		if err2 := unregister(f); err == nil {
+			err = err2
+		}
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+	}
+	all = nil
+	return err
+}
+
+// register registers the GPIOs, the I²C bus and the SPI port of a device.
+//
+// On failure, what was registered is unregistered.
+func register(f *FT232H) error {
+	for i, p := range f.Pins() {
+		if err := gpioreg.Register(p, true); err != nil {
+			unregisterPins(f.Pins()[:i])
+			return err
+		}
+	}
+	info := conn.Info{Driver: "ftdi", MaxSpeed: 1000000}
+	if err := i2creg.RegisterWithInfo(f.name+".I2C", nil, -1, info, func() (i2c.BusCloser, error) { return f.I2C() }); err != nil {
+		unregisterPins(f.Pins())
+		return err
+	}
+	info.MaxSpeed = maxClock
+	if err := spireg.RegisterWithInfo(f.name+".SPI", nil, -1, info, func() (spi.PortCloser, error) { return f.SPI() }); err != nil {
+		unregisterPins(f.Pins())
+		i2creg.Unregister(f.name + ".I2C")
+		return err
+	}
+	return nil
+}
+
+// unregister unregisters the GPIOs, the I²C bus and the SPI port of a device.
+func unregister(f *FT232H) error {
+	err := unregisterPins(f.Pins())
+	if err2 := i2creg.Unregister(f.name + ".I2C"); err == nil {
+		err = err2
+	}
+	if err2 := spireg.Unregister(f.name + ".SPI"); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func unregisterPins(pins []gpio.PinIO) error {
+	var err error
+	for _, p := range pins {
+		if err2 := gpioreg.Unregister(p.Name()); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func init() {
+	if hasD2XX {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
+var _ periph.Unloader = &driver{}
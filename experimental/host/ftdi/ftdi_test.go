@@ -0,0 +1,403 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+func TestNewFT232H(t *testing.T) {
+	h := &fakeHandle{r: []byte{0xFA, 0xAA}}
+	f, err := newFT232H(1, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := f.String(); s != "FT232H1" {
+		t.Fatal(s)
+	}
+	if h.modes[0] != bitModeReset || h.modes[1] != bitModeMPSSE {
+		t.Fatal(h.modes)
+	}
+	expected := []byte{0xAA, 0x8A, 0x97, 0x8D, 0x85, 0x80, 0, 0, 0x82, 0, 0}
+	if !bytes.Equal(h.w, expected) {
+		t.Fatalf("%#v", h.w)
+	}
+	pins := f.Pins()
+	if len(pins) != 16 {
+		t.Fatal(len(pins))
+	}
+	if s := pins[3].Name(); s != "FT232H1.D3" {
+		t.Fatal(s)
+	}
+	if s := pins[9].Name(); s != "FT232H1.C1" {
+		t.Fatal(s)
+	}
+	if n := pins[9].Number(); n != 25 {
+		t.Fatal(n)
+	}
+	if err := f.Close(); err != nil || !h.closed {
+		t.Fatal(err)
+	}
+}
+
+func TestNewFT232H_fail(t *testing.T) {
+	if _, err := newFT232H(0, &fakeHandle{r: []byte{0xFA, 0x00}}); err == nil {
+		t.Fatal("not synchronized")
+	}
+	if _, err := newFT232H(0, &fakeHandle{}); err == nil {
+		t.Fatal("read timeout")
+	}
+	if _, err := newFT232H(0, &fakeHandle{err: errors.New("oops")}); err == nil {
+		t.Fatal("bit mode failed")
+	}
+}
+
+func TestPin(t *testing.T) {
+	f, h := newFake(t)
+	if err := f.D4.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x80, 0x10, 0x10}) {
+		t.Fatalf("%#v", h.w)
+	}
+	if s := f.D4.Function(); s != "Out/High" {
+		t.Fatal(s)
+	}
+	h.w = nil
+	if err := f.C2.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x82, 0x00, 0x04}) {
+		t.Fatalf("%#v", h.w)
+	}
+	h.w = nil
+	if err := f.D4.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x80, 0x10, 0x00}) {
+		t.Fatalf("%#v", h.w)
+	}
+	h.w = nil
+	h.r = []byte{0x10}
+	if l := f.D4.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	if !bytes.Equal(h.w, []byte{0x81, 0x87}) {
+		t.Fatalf("%#v", h.w)
+	}
+	h.r = []byte{0x00}
+	if s := f.D4.Function(); s != "In/Low" {
+		t.Fatal(s)
+	}
+	if s := f.D4.Function(); s != "In/<Unknown>" {
+		t.Fatal(s)
+	}
+	if f.D4.WaitForEdge(-1) {
+		t.Fatal("edge detection is not supported")
+	}
+	if p := f.D4.Pull(); p != gpio.PullNoChange {
+		t.Fatal(p)
+	}
+	if err := f.D4.In(gpio.PullUp, gpio.NoEdge); err == nil {
+		t.Fatal("pull is not supported")
+	}
+	if err := f.D4.In(gpio.PullNoChange, gpio.RisingEdge); err == nil {
+		t.Fatal("edge detection is not supported")
+	}
+	if err := f.D4.(conn.Resource).Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestI2C(t *testing.T) {
+	f, h := newFake(t)
+	b, err := f.I2C()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(h.w, []byte{0x8C, 0x9E, 0x07, 0x00, 0x86, 199, 0, 0x80, 0x03, 0x03}) {
+		t.Fatalf("%#v", h.w)
+	}
+	if s := f.D0.Function(); s != "I2C_SCL" {
+		t.Fatal(s)
+	}
+	if err := f.D1.Out(gpio.Low); err == nil {
+		t.Fatal("pin is used by I²C")
+	}
+	if _, err := f.SPI(); err == nil {
+		t.Fatal("pins are used by I²C")
+	}
+
+	// Write register 0x10, read 2 bytes.
+	h.w = nil
+	h.r = []byte{0, 0, 0, 0x12, 0x34}
+	r := make([]byte, 2)
+	if err := b.Tx(0x76, []byte{0x10}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0x12, 0x34}) {
+		t.Fatalf("%#v", r)
+	}
+	// The address is sent for write then for read.
+	if !bytes.Contains(h.w, []byte{0x11, 0, 0, 0x76 << 1}) || !bytes.Contains(h.w, []byte{0x11, 0, 0, 0x76<<1 | 1}) {
+		t.Fatalf("%#v", h.w)
+	}
+	// The last byte is not acknowledged.
+	if !bytes.Contains(h.w, []byte{0x13, 0, 0x80}) || h.w[len(h.w)-1] != 0x87 {
+		t.Fatalf("%#v", h.w)
+	}
+
+	h.r = []byte{0, 1}
+	if err := b.Tx(0x76, []byte{0x10}, nil); err == nil {
+		t.Fatal("NACK")
+	}
+	if err := b.Tx(0x400, []byte{0x10}, nil); err == nil {
+		t.Fatal("10 bits address")
+	}
+	if err := b.Tx(0x76, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetSpeed(400000); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetSpeed(10000000); err == nil {
+		t.Fatal("too fast")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err == nil {
+		t.Fatal("already closed")
+	}
+	if err := b.Tx(0x76, []byte{0x10}, nil); err == nil {
+		t.Fatal("closed")
+	}
+	if err := f.D1.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSPI(t *testing.T) {
+	f, h := newFake(t)
+	p, err := f.SPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x80, 0x08, 0x0B}) {
+		t.Fatalf("%#v", h.w)
+	}
+	if s := f.D3.Function(); s != "SPI_CS" {
+		t.Fatal(s)
+	}
+	if _, err := p.Connect(1000000, spi.Mode0, 9); err == nil {
+		t.Fatal("9 bits")
+	}
+	if _, err := p.Connect(1000000, spi.HalfDuplex, 8); err == nil {
+		t.Fatal("half duplex")
+	}
+	if err := p.LimitSpeed(0); err == nil {
+		t.Fatal("invalid speed")
+	}
+	if err := p.LimitSpeed(10000000); err != nil {
+		t.Fatal(err)
+	}
+	h.w = nil
+	c, err := p.Connect(1000000, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x80, 0x09, 0x0B, 0x86, 29, 0}) {
+		t.Fatalf("%#v", h.w)
+	}
+	if _, err := p.Connect(1000000, spi.Mode3, 8); err == nil {
+		t.Fatal("connect twice")
+	}
+	if d := c.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+
+	h.w = nil
+	h.r = []byte{0xAB, 0xCD}
+	r := make([]byte, 2)
+	if err := c.Tx([]byte{1, 2}, r); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x80, 0x01, 0x0B, 0x31, 1, 0, 1, 2, 0x80, 0x09, 0x0B, 0x87}
+	if !bytes.Equal(h.w, expected) {
+		t.Fatalf("%#v", h.w)
+	}
+	if !bytes.Equal(r, []byte{0xAB, 0xCD}) {
+		t.Fatalf("%#v", r)
+	}
+
+	h.w = nil
+	if err := c.(spi.Conn).TxPackets([]spi.Packet{{W: []byte{3}, KeepCS: true}, {W: []byte{4}}}); err != nil {
+		t.Fatal(err)
+	}
+	expected = []byte{0x80, 0x01, 0x0B, 0x11, 0, 0, 3, 0x80, 0x01, 0x0B, 0x11, 0, 0, 4, 0x80, 0x09, 0x0B}
+	if !bytes.Equal(h.w, expected) {
+		t.Fatalf("%#v", h.w)
+	}
+	if err := c.Tx([]byte{1, 2}, []byte{1}); err == nil {
+		t.Fatal("buffers of different size")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("already closed")
+	}
+	if err := c.Tx([]byte{1}, nil); err == nil {
+		t.Fatal("closed")
+	}
+}
+
+func TestSPI_Mode1_LSBFirst(t *testing.T) {
+	f, h := newFake(t)
+	p, err := f.SPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Connect(0, spi.Mode1|spi.LSBFirst|spi.NoCS, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.w = nil
+	h.r = []byte{0xAB}
+	r := make([]byte, 1)
+	if err := c.Tx(nil, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h.w, []byte{0x2C, 0, 0, 0x87}) {
+		t.Fatalf("%#v", h.w)
+	}
+}
+
+func TestClockCmd(t *testing.T) {
+	data := []struct {
+		hz       int64
+		expected []byte
+		actual   int64
+	}{
+		{30000000, []byte{0x86, 0, 0}, 30000000},
+		{100000000, []byte{0x86, 0, 0}, 30000000},
+		{1000000, []byte{0x86, 29, 0}, 1000000},
+		{150000, []byte{0x86, 199, 0}, 150000},
+		{1, []byte{0x86, 0xFF, 0xFF}, 457},
+	}
+	for i, line := range data {
+		c, hz := clockCmd(line.hz)
+		if !bytes.Equal(c, line.expected) || hz != line.actual {
+			t.Fatalf("#%d: %#v %d", i, c, hz)
+		}
+	}
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "ftdi" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+}
+
+func TestCString(t *testing.T) {
+	if s := cString([]byte{'F', 'T', 0, 'x'}); s != "FT" {
+		t.Fatal(s)
+	}
+	if s := cString([]byte{'F', 'T'}); s != "FT" {
+		t.Fatal(s)
+	}
+}
+
+func TestDriver_reload(t *testing.T) {
+	defer func() {
+		d2xxDevices = d2xxDevicesDefault
+		d2xxOpen = d2xxOpenDefault
+	}()
+	var handles []*fakeHandle
+	d2xxDevices = func() ([]devInfo, error) {
+		return []devInfo{{typ: 0}, {typ: typeFT232H}}, nil
+	}
+	d2xxOpen = func(i int) (handle, error) {
+		if i != 1 {
+			t.Fatalf("unexpected device %d", i)
+		}
+		h := &fakeHandle{r: []byte{0xFA, 0xAA}}
+		handles = append(handles, h)
+		return h, nil
+	}
+	d := driver{}
+	// Load, unload then load again, as done by periph.Reload().
+	for i := 0; i < 2; i++ {
+		if ok, err := d.Init(); !ok || err != nil {
+			t.Fatal(ok, err)
+		}
+		if len(All()) != 1 || gpioreg.ByName("FT232H0.C1") == nil || len(i2creg.All()) != 1 || len(spireg.All()) != 1 {
+			t.Fatal("device must be registered")
+		}
+		if err := d.Unload(); err != nil {
+			t.Fatal(err)
+		}
+		if len(All()) != 0 || gpioreg.ByName("FT232H0.C1") != nil || len(i2creg.All()) != 0 || len(spireg.All()) != 0 {
+			t.Fatal("device must be unregistered")
+		}
+		if !handles[i].closed {
+			t.Fatal("device must be closed")
+		}
+	}
+}
+
+//
+
+func newFake(t *testing.T) (*FT232H, *fakeHandle) {
+	h := &fakeHandle{r: []byte{0xFA, 0xAA}}
+	f, err := newFT232H(0, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.w = nil
+	return f, h
+}
+
+type fakeHandle struct {
+	w      []byte // Commands written.
+	r      []byte // Data to be read.
+	modes  []byte
+	err    error
+	closed bool
+}
+
+func (f *fakeHandle) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeHandle) Read(b []byte) (int, error) {
+	n := copy(b, f.r)
+	f.r = f.r[n:]
+	return n, f.err
+}
+
+func (f *fakeHandle) Write(b []byte) (int, error) {
+	f.w = append(f.w, b...)
+	return len(b), f.err
+}
+
+func (f *fakeHandle) SetBitMode(mask, mode byte) error {
+	f.modes = append(f.modes, mode)
+	return f.err
+}
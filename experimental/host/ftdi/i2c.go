@@ -0,0 +1,194 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"errors"
+	"fmt"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+)
+
+// I2C returns the I²C bus of the device.
+//
+// D0 is SCL, D1 and D2 must be wired together as SDA. External pull up
+// resistors are required.
+func (f *FT232H) I2C() (i2c.BusCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mode != modeGPIO {
+		return nil, errors.New("ftdi: D0 to D3 are already in use")
+	}
+	i := &i2cBus{f: f}
+	// Three phase clocking holds SDA for a third of a clock period after the
+	// falling edge of SCL, as required by I²C. D0 to D2 are open drain.
+	cmd := []byte{cmdThreePhaseOn, cmdDriveZero, 0x07, 0x00}
+	c, _ := clockCmd(i2cClock(100000))
+	cmd = append(cmd, c...)
+	cmd = i.set(cmd, i2cSCL|i2cSDA)
+	if err := f.tx(cmd, nil); err != nil {
+		return nil, err
+	}
+	f.mode = modeI2C
+	return i, nil
+}
+
+//
+
+// Pins used for I²C.
+const (
+	i2cSCL = 0x01 // D0
+	i2cSDA = 0x02 // D1
+)
+
+// i2cBus is the I²C bus of a FT232H.
+type i2cBus struct {
+	f *FT232H
+}
+
+func (i *i2cBus) String() string {
+	return i.f.name + ".I2C"
+}
+
+// Close releases the pins D0 to D3.
+func (i *i2cBus) Close() error {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	if i.f.mode != modeI2C {
+		return errors.New("ftdi: I²C bus already closed")
+	}
+	i.f.mode = modeGPIO
+	i.f.dbus.dir &^= 0x0F
+	return i.f.tx([]byte{cmdThreePhaseOff, cmdDriveZero, 0x00, 0x00, cmdSetD, i.f.dbus.value, i.f.dbus.dir}, nil)
+}
+
+// Tx implements i2c.Bus.
+//
+// The whole transaction is sent at once and the acknowledgements are checked
+// afterward.
+func (i *i2cBus) Tx(addr uint16, w, r []byte) error {
+	if addr >= 0x80 {
+		return fmt.Errorf("ftdi: invalid I²C address %#x; 10 bits addresses are not supported", addr)
+	}
+	if len(w) == 0 && len(r) == 0 {
+		return nil
+	}
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	if i.f.mode != modeI2C {
+		return errors.New("ftdi: I²C bus is closed")
+	}
+	var cmd []byte
+	acks := 0
+	if len(w) != 0 {
+		cmd = i.start(cmd)
+		cmd = i.writeByte(cmd, byte(addr<<1))
+		for _, b := range w {
+			cmd = i.writeByte(cmd, b)
+		}
+		acks += 1 + len(w)
+	}
+	if len(r) != 0 {
+		cmd = i.start(cmd)
+		cmd = i.writeByte(cmd, byte(addr<<1)|1)
+		acks++
+		for j := range r {
+			cmd = i.readByte(cmd, j == len(r)-1)
+		}
+	}
+	cmd = i.stop(cmd)
+	cmd = append(cmd, cmdFlush)
+	res := make([]byte, acks+len(r))
+	if err := i.f.tx(cmd, res); err != nil {
+		return err
+	}
+	for j := 0; j < acks; j++ {
+		if res[j]&1 != 0 {
+			return fmt.Errorf("ftdi: I²C device %#x did not acknowledge byte #%d", addr, j)
+		}
+	}
+	copy(r, res[acks:])
+	return nil
+}
+
+// SetSpeed implements i2c.Bus.
+func (i *i2cBus) SetSpeed(hz int64) error {
+	if hz < 1000 || hz > 1000000 {
+		return fmt.Errorf("ftdi: invalid I²C speed %dHz", hz)
+	}
+	c, _ := clockCmd(i2cClock(hz))
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	return i.f.tx(c, nil)
+}
+
+// SCL implements i2c.Pins.
+func (i *i2cBus) SCL() gpio.PinIO {
+	return i.f.D0
+}
+
+// SDA implements i2c.Pins.
+func (i *i2cBus) SDA() gpio.PinIO {
+	return i.f.D1
+}
+
+// set appends the command to set SCL and SDA to the levels in v.
+//
+// The other ADBUS pins are left as is.
+func (i *i2cBus) set(cmd []byte, v byte) []byte {
+	return append(cmd, cmdSetD, i.f.dbus.value&^0x07|v, i.f.dbus.dir&^0x07|i2cSCL|i2cSDA)
+}
+
+// start appends a start condition, SDA falling while SCL is high.
+//
+// Each state is repeated to hold it for at least 600ns.
+func (i *i2cBus) start(cmd []byte) []byte {
+	for _, v := range []byte{i2cSCL | i2cSDA, i2cSCL, 0} {
+		for j := 0; j < 4; j++ {
+			cmd = i.set(cmd, v)
+		}
+	}
+	return cmd
+}
+
+// stop appends a stop condition, SDA rising while SCL is high.
+func (i *i2cBus) stop(cmd []byte) []byte {
+	for _, v := range []byte{0, i2cSCL, i2cSCL | i2cSDA} {
+		for j := 0; j < 4; j++ {
+			cmd = i.set(cmd, v)
+		}
+	}
+	return cmd
+}
+
+// writeByte appends the commands to write a byte and read the acknowledge
+// bit, which is returned as bit 0 of one byte of response.
+func (i *i2cBus) writeByte(cmd []byte, b byte) []byte {
+	cmd = append(cmd, cmdDataOut|cmdWriteFalling, 0, 0, b)
+	cmd = i.set(cmd, i2cSDA)
+	return append(cmd, cmdDataIn|cmdBits, 0)
+}
+
+// readByte appends the commands to read a byte, which is returned as one byte
+// of response, then acknowledge it unless it is the last one.
+func (i *i2cBus) readByte(cmd []byte, last bool) []byte {
+	cmd = append(cmd, cmdDataIn, 0, 0)
+	ack := byte(0x00)
+	if last {
+		ack = 0x80
+	}
+	cmd = append(cmd, cmdDataOut|cmdWriteFalling|cmdBits, 0, ack)
+	return i.set(cmd, i2cSDA)
+}
+
+// i2cClock returns the MPSSE clock for an I²C speed; three phase clocking
+// uses 3 MPSSE clock phases per 2 I²C clock phases.
+func i2cClock(hz int64) int64 {
+	return hz * 3 / 2
+}
+
+var _ i2c.BusCloser = &i2cBus{}
+var _ i2c.Pins = &i2cBus{}
@@ -0,0 +1,272 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ftdi
+
+import (
+	"errors"
+	"fmt"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/spi"
+)
+
+// SPI returns the SPI port of the device.
+//
+// D0 is CLK, D1 is MOSI, D2 is MISO and D3 is CS, which is active low.
+func (f *FT232H) SPI() (spi.PortCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mode != modeGPIO {
+		return nil, errors.New("ftdi: D0 to D3 are already in use")
+	}
+	// Idle with CS high.
+	f.dbus.value = f.dbus.value&^0x0F | spiCS
+	f.dbus.dir = f.dbus.dir&^0x0F | spiCLK | spiMOSI | spiCS
+	if err := f.tx([]byte{cmdSetD, f.dbus.value, f.dbus.dir}, nil); err != nil {
+		return nil, err
+	}
+	f.mode = modeSPI
+	return &spiPort{f: f, maxHzPort: maxClock}, nil
+}
+
+//
+
+// Pins used for SPI.
+const (
+	spiCLK  = 0x01 // D0
+	spiMOSI = 0x02 // D1
+	spiCS   = 0x08 // D3
+)
+
+// spiMaxTxSize is the maximum length of a single MPSSE data command.
+const spiMaxTxSize = 65536
+
+// spiPort is the SPI port of a FT232H.
+type spiPort struct {
+	f *FT232H
+
+	// Protected by f.mu.
+	maxHzPort int64
+	c         *spiConn
+}
+
+func (s *spiPort) String() string {
+	return s.f.name + ".SPI"
+}
+
+// Close releases the pins D0 to D3.
+func (s *spiPort) Close() error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	if s.f.mode != modeSPI {
+		return errors.New("ftdi: SPI port already closed")
+	}
+	s.f.mode = modeGPIO
+	s.f.dbus.dir &^= 0x0F
+	return s.f.tx([]byte{cmdSetD, s.f.dbus.value, s.f.dbus.dir}, nil)
+}
+
+// LimitSpeed implements spi.PortCloser.
+func (s *spiPort) LimitSpeed(maxHz int64) error {
+	if maxHz <= 0 {
+		return fmt.Errorf("ftdi: invalid speed %d", maxHz)
+	}
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.maxHzPort = maxHz
+	if s.c != nil {
+		return s.c.setClock()
+	}
+	return nil
+}
+
+// Connect implements spi.Port.
+//
+// Only 8 bits words are supported, and HalfDuplex is not supported.
+func (s *spiPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	if maxHz < 0 {
+		return nil, fmt.Errorf("ftdi: invalid speed %d", maxHz)
+	}
+	if mode&^(spi.Mode3|spi.NoCS|spi.LSBFirst) != 0 {
+		return nil, fmt.Errorf("ftdi: invalid mode %s", mode)
+	}
+	if bits != 8 {
+		return nil, fmt.Errorf("ftdi: invalid bits %d; only 8 is supported", bits)
+	}
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	if s.c != nil {
+		return nil, errors.New("ftdi: Connect() can only be called exactly once")
+	}
+	c := &spiConn{p: s, maxHzDev: maxHz, noCS: mode&spi.NoCS != 0}
+	// Mode0 and Mode3 write on the falling edge and read on the rising edge,
+	// Mode1 and Mode2 the opposite.
+	if mode&spi.Mode3 == spi.Mode0 || mode&spi.Mode3 == spi.Mode3 {
+		c.op = cmdWriteFalling
+	} else {
+		c.op = cmdReadFalling
+	}
+	if mode&spi.LSBFirst != 0 {
+		c.op |= cmdLSBFirst
+	}
+	// CPOL sets the idle clock level.
+	if mode&spi.Mode2 != 0 {
+		s.f.dbus.value |= spiCLK
+	} else {
+		s.f.dbus.value &^= spiCLK
+	}
+	if err := s.f.tx([]byte{cmdSetD, s.f.dbus.value, s.f.dbus.dir}, nil); err != nil {
+		return nil, err
+	}
+	if err := c.setClock(); err != nil {
+		return nil, err
+	}
+	s.c = c
+	return c, nil
+}
+
+// spiConn is a connection to a device on the SPI port.
+type spiConn struct {
+	// Immutable.
+	p        *spiPort
+	maxHzDev int64
+	noCS     bool
+	op       byte // Edges and bit order of the MPSSE data commands.
+}
+
+func (c *spiConn) String() string {
+	return c.p.String()
+}
+
+// Tx implements conn.Conn.
+func (c *spiConn) Tx(w, r []byte) error {
+	return c.TxPackets([]spi.Packet{{W: w, R: r}})
+}
+
+// Write implements io.Writer.
+func (c *spiConn) Write(b []byte) (int, error) {
+	if err := c.Tx(b, nil); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// TxPackets implements spi.Conn.
+func (c *spiConn) TxPackets(p []spi.Packet) error {
+	var cmd []byte
+	total := 0
+	for _, pkt := range p {
+		if pkt.BitsPerWord != 0 && pkt.BitsPerWord != 8 {
+			return fmt.Errorf("ftdi: invalid bits %d; only 8 is supported", pkt.BitsPerWord)
+		}
+		if len(pkt.W) != 0 && len(pkt.R) != 0 && len(pkt.W) != len(pkt.R) {
+			return errors.New("ftdi: both buffers must have the same size")
+		}
+		total += len(pkt.R)
+	}
+	c.p.f.mu.Lock()
+	defer c.p.f.mu.Unlock()
+	if c.p.f.mode != modeSPI {
+		return errors.New("ftdi: SPI port is closed")
+	}
+	v := c.p.f.dbus.value
+	dir := c.p.f.dbus.dir
+	for _, pkt := range p {
+		if !c.noCS {
+			cmd = append(cmd, cmdSetD, v&^spiCS, dir)
+		}
+		cmd = c.data(cmd, pkt.W, len(pkt.R))
+		if !c.noCS && !pkt.KeepCS {
+			cmd = append(cmd, cmdSetD, v, dir)
+		}
+	}
+	var res []byte
+	if total != 0 {
+		cmd = append(cmd, cmdFlush)
+		res = make([]byte, total)
+	}
+	if err := c.p.f.tx(cmd, res); err != nil {
+		return err
+	}
+	for _, pkt := range p {
+		res = res[copy(pkt.R, res):]
+	}
+	return nil
+}
+
+// Duplex implements conn.Conn.
+func (c *spiConn) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+// MaxTxSize implements conn.Limits.
+func (c *spiConn) MaxTxSize() int {
+	return spiMaxTxSize
+}
+
+// CLK implements spi.Pins.
+func (c *spiConn) CLK() gpio.PinOut {
+	return c.p.f.D0
+}
+
+// MOSI implements spi.Pins.
+func (c *spiConn) MOSI() gpio.PinOut {
+	return c.p.f.D1
+}
+
+// MISO implements spi.Pins.
+func (c *spiConn) MISO() gpio.PinIn {
+	return c.p.f.D2
+}
+
+// CS implements spi.Pins.
+func (c *spiConn) CS() gpio.PinOut {
+	return c.p.f.D3
+}
+
+// setClock sets the clock to the lowest of the port and the device speed.
+//
+// c.p.f.mu must be held.
+func (c *spiConn) setClock() error {
+	hz := c.p.maxHzPort
+	if c.maxHzDev != 0 && c.maxHzDev < hz {
+		hz = c.maxHzDev
+	}
+	cmd, _ := clockCmd(hz)
+	return c.p.f.tx(cmd, nil)
+}
+
+// data appends the commands to write w and read n bytes, in chunks of
+// spiMaxTxSize.
+func (c *spiConn) data(cmd, w []byte, n int) []byte {
+	l := len(w)
+	if n > l {
+		l = n
+	}
+	for off := 0; off < l; off += spiMaxTxSize {
+		end := off + spiMaxTxSize
+		if end > l {
+			end = l
+		}
+		op := c.op
+		if len(w) != 0 {
+			op |= cmdDataOut
+		}
+		if n != 0 {
+			op |= cmdDataIn
+		}
+		size := end - off - 1
+		cmd = append(cmd, op, byte(size), byte(size>>8))
+		if len(w) != 0 {
+			cmd = append(cmd, w[off:end]...)
+		}
+	}
+	return cmd
+}
+
+var _ spi.PortCloser = &spiPort{}
+var _ spi.Conn = &spiConn{}
+var _ spi.Pins = &spiConn{}
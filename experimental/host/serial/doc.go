@@ -0,0 +1,13 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package serial implements UART ports over the Windows COM ports.
+//
+// This includes USB to serial adapters like the FT232R, the CP2102 or the
+// CH340, so that device drivers using an UART can be exercised from a
+// Windows development machine.
+//
+// Each COM port found is registered in uartreg as "COMn" with the number n.
+// The driver is not registered on other OSes.
+package serial
@@ -0,0 +1,224 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+// Open opens a COM port, e.g. "COM3".
+//
+// The port is initially configured at 9600 bauds, 8 bits, no parity and one
+// stop bit.
+func Open(name string) (*Port, error) {
+	f, err := portOpen(name)
+	if err != nil {
+		return nil, fmt.Errorf("serial: %v", err)
+	}
+	p := &Port{name: name, f: f, baud: 9600, stop: uart.One, parity: uart.None, bits: 8}
+	if err := p.configure(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Port is an open COM port.
+//
+// It implements uart.ConnCloser.
+type Port struct {
+	// Immutable.
+	name string
+	f    portFile
+
+	// Mutable.
+	mu     sync.Mutex
+	baud   int
+	stop   uart.Stop
+	parity uart.Parity
+	bits   int
+}
+
+func (p *Port) String() string {
+	return p.name
+}
+
+// Close implements uart.ConnCloser.
+func (p *Port) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.f.Close(); err != nil {
+		return fmt.Errorf("serial: %v", err)
+	}
+	return nil
+}
+
+// Speed implements uart.Conn.
+func (p *Port) Speed(baud int) error {
+	if baud <= 0 {
+		return fmt.Errorf("serial: invalid speed %d", baud)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.baud = baud
+	return p.configure()
+}
+
+// Configure implements uart.Conn.
+func (p *Port) Configure(stopBit uart.Stop, parity uart.Parity, bits int) error {
+	if stopBit != uart.One && stopBit != uart.OneHalf && stopBit != uart.Two {
+		return fmt.Errorf("serial: invalid stop bit %d", stopBit)
+	}
+	switch parity {
+	case uart.None, uart.Odd, uart.Even, uart.Mark, uart.Space:
+	default:
+		return fmt.Errorf("serial: invalid parity %q", parity)
+	}
+	if bits < 5 || bits > 8 {
+		return fmt.Errorf("serial: invalid bits %d", bits)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop = stopBit
+	p.parity = parity
+	p.bits = bits
+	return p.configure()
+}
+
+// Read implements io.Reader.
+//
+// It returns as soon as data is available, or io.EOF after one second
+// without data.
+func (p *Port) Read(b []byte) (int, error) {
+	return p.f.Read(b)
+}
+
+// Write implements io.Writer.
+func (p *Port) Write(b []byte) (int, error) {
+	return p.f.Write(b)
+}
+
+// Tx implements conn.Conn.
+//
+// It writes w then reads exactly len(r) bytes.
+func (p *Port) Tx(w, r []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(w) != 0 {
+		if _, err := p.f.Write(w); err != nil {
+			return fmt.Errorf("serial: %v", err)
+		}
+	}
+	if len(r) != 0 {
+		if _, err := io.ReadFull(p.f, r); err != nil {
+			return fmt.Errorf("serial: %v", err)
+		}
+	}
+	return nil
+}
+
+// Duplex implements conn.Conn.
+func (p *Port) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+// RX implements uart.Pins.
+func (p *Port) RX() gpio.PinIn {
+	return gpio.INVALID
+}
+
+// TX implements uart.Pins.
+func (p *Port) TX() gpio.PinOut {
+	return gpio.INVALID
+}
+
+// RTS implements uart.Pins.
+func (p *Port) RTS() gpio.PinIO {
+	return gpio.INVALID
+}
+
+// CTS implements uart.Pins.
+func (p *Port) CTS() gpio.PinIO {
+	return gpio.INVALID
+}
+
+//
+
+// portFile is an open COM port handle.
+type portFile interface {
+	io.ReadWriteCloser
+	configure(baud int, stop uart.Stop, parity uart.Parity, bits int) error
+}
+
+var (
+	// portOpen opens a COM port by name.
+	portOpen = portOpenDefault
+	// enumerate returns the COM port numbers present.
+	enumerate = enumerateDefault
+)
+
+// configure applies the current settings.
+//
+// p.mu must be held.
+func (p *Port) configure() error {
+	if err := p.f.configure(p.baud, p.stop, p.parity, p.bits); err != nil {
+		return fmt.Errorf("serial: %v", err)
+	}
+	return nil
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "serial"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	ports, err := enumerate()
+	if err != nil {
+		return true, err
+	}
+	if len(ports) == 0 {
+		return false, errors.New("serial: no COM port found")
+	}
+	for _, n := range ports {
+		name := fmt.Sprintf("COM%d", n)
+		if err := uartreg.RegisterWithInfo(name, nil, n, conn.Info{Driver: d.String(), Path: name}, opener(name)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func opener(name string) uartreg.Opener {
+	return func() (uart.ConnCloser, error) {
+		return Open(name)
+	}
+}
+
+func init() {
+	if isWindows {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
+var _ uart.ConnCloser = &Port{}
+var _ uart.Pins = &Port{}
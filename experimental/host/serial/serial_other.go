@@ -0,0 +1,19 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !windows
+
+package serial
+
+import "errors"
+
+const isWindows = false
+
+func portOpenDefault(name string) (portFile, error) {
+	return nil, errors.New("COM ports are only supported on Windows")
+}
+
+func enumerateDefault() ([]int, error) {
+	return nil, nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/experimental/conn/uart"
+	"periph.io/x/periph/experimental/conn/uart/uartreg"
+)
+
+func TestOpen(t *testing.T) {
+	f := &fakePort{}
+	defer setOpen(f, nil)()
+	p, err := Open("COM3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.String(); s != "COM3" {
+		t.Fatal(s)
+	}
+	if f.baud != 9600 || f.stop != uart.One || f.parity != uart.None || f.bits != 8 {
+		t.Fatal(f)
+	}
+	if err := p.Speed(115200); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Configure(uart.Two, uart.Even, 7); err != nil {
+		t.Fatal(err)
+	}
+	if f.baud != 115200 || f.stop != uart.Two || f.parity != uart.Even || f.bits != 7 {
+		t.Fatal(f)
+	}
+	if d := p.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if p.RX() != gpio.INVALID || p.TX() != gpio.INVALID || p.RTS() != gpio.INVALID || p.CTS() != gpio.INVALID {
+		t.Fatal("unexpected pins")
+	}
+	if err := p.Close(); err != nil || !f.closed {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen_fail(t *testing.T) {
+	defer setOpen(nil, errors.New("oops"))()
+	if _, err := Open("COM3"); err == nil {
+		t.Fatal("open failed")
+	}
+	f := &fakePort{err: errors.New("oops")}
+	defer setOpen(f, nil)()
+	if _, err := Open("COM3"); err == nil || !f.closed {
+		t.Fatal("configure failed")
+	}
+}
+
+func TestConfigure_fail(t *testing.T) {
+	defer setOpen(&fakePort{}, nil)()
+	p, err := Open("COM3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Speed(0); err == nil {
+		t.Fatal("invalid speed")
+	}
+	if err := p.Configure(uart.Stop(3), uart.None, 8); err == nil {
+		t.Fatal("invalid stop bit")
+	}
+	if err := p.Configure(uart.One, uart.Parity('X'), 8); err == nil {
+		t.Fatal("invalid parity")
+	}
+	if err := p.Configure(uart.One, uart.None, 9); err == nil {
+		t.Fatal("invalid bits")
+	}
+}
+
+func TestTx(t *testing.T) {
+	f := &fakePort{}
+	defer setOpen(f, nil)()
+	p, err := Open("COM3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.r.WriteString("OK\r\n")
+	r := make([]byte, 4)
+	if err := p.Tx([]byte("AT\r\n"), r); err != nil {
+		t.Fatal(err)
+	}
+	if f.w.String() != "AT\r\n" || string(r) != "OK\r\n" {
+		t.Fatalf("%q %q", f.w.String(), r)
+	}
+	if err := p.Tx(nil, r); err == nil {
+		t.Fatal("nothing to read")
+	}
+	if n, err := p.Write([]byte("A")); n != 1 || err != nil {
+		t.Fatal(n, err)
+	}
+	f.r.WriteString("B")
+	if n, err := p.Read(r); n != 1 || err != nil || r[0] != 'B' {
+		t.Fatal(n, err)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	defer setOpen(&fakePort{}, nil)()
+	defer func(e func() ([]int, error)) { enumerate = e }(enumerate)
+	d := driver{}
+	if s := d.String(); s != "serial" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+	enumerate = func() ([]int, error) { return nil, nil }
+	if ok, err := d.Init(); ok || err == nil {
+		t.Fatal("no port")
+	}
+	enumerate = func() ([]int, error) { return nil, errors.New("oops") }
+	if ok, err := d.Init(); !ok || err == nil {
+		t.Fatal("enumerate failed")
+	}
+	enumerate = func() ([]int, error) { return []int{4}, nil }
+	if ok, err := d.Init(); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	defer uartreg.Unregister("COM4")
+	c, err := uartreg.Open("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.(*Port).String(); s != "COM4" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func setOpen(f *fakePort, err error) func() {
+	old := portOpen
+	portOpen = func(name string) (portFile, error) {
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return func() { portOpen = old }
+}
+
+type fakePort struct {
+	r, w   bytes.Buffer
+	err    error
+	closed bool
+	baud   int
+	stop   uart.Stop
+	parity uart.Parity
+	bits   int
+}
+
+func (f *fakePort) Read(b []byte) (int, error) {
+	if f.r.Len() == 0 {
+		return 0, io.EOF
+	}
+	return f.r.Read(b)
+}
+
+func (f *fakePort) Write(b []byte) (int, error) {
+	return f.w.Write(b)
+}
+
+func (f *fakePort) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakePort) configure(baud int, stop uart.Stop, parity uart.Parity, bits int) error {
+	f.baud, f.stop, f.parity, f.bits = baud, stop, parity, bits
+	return f.err
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package serial
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"periph.io/x/periph/experimental/conn/uart"
+)
+
+const isWindows = true
+
+var (
+	kernel32            = syscall.NewLazyDLL("kernel32.dll")
+	procGetCommState    = kernel32.NewProc("GetCommState")
+	procSetCommState    = kernel32.NewProc("SetCommState")
+	procSetCommTimeouts = kernel32.NewProc("SetCommTimeouts")
+	procQueryDosDevice  = kernel32.NewProc("QueryDosDeviceW")
+)
+
+// dcb is DCB from winbase.h.
+type dcb struct {
+	DCBlength  uint32
+	BaudRate   uint32
+	Flags      uint32
+	wReserved  uint16
+	XonLim     uint16
+	XoffLim    uint16
+	ByteSize   byte
+	Parity     byte
+	StopBits   byte
+	XonChar    byte
+	XoffChar   byte
+	ErrorChar  byte
+	EofChar    byte
+	EvtChar    byte
+	wReserved1 uint16
+}
+
+const dcbBinary = 0x0001 // fBinary
+
+// commTimeouts is COMMTIMEOUTS from winbase.h.
+type commTimeouts struct {
+	ReadIntervalTimeout         uint32
+	ReadTotalTimeoutMultiplier  uint32
+	ReadTotalTimeoutConstant    uint32
+	WriteTotalTimeoutMultiplier uint32
+	WriteTotalTimeoutConstant   uint32
+}
+
+// comPort is an open COM port.
+type comPort struct {
+	*os.File
+}
+
+func (c *comPort) configure(baud int, stop uart.Stop, parity uart.Parity, bits int) error {
+	d := dcb{DCBlength: uint32(unsafe.Sizeof(dcb{}))}
+	if r, _, err := procGetCommState.Call(c.Fd(), uintptr(unsafe.Pointer(&d))); r == 0 {
+		return err
+	}
+	d.BaudRate = uint32(baud)
+	// No flow control.
+	d.Flags = dcbBinary
+	d.ByteSize = byte(bits)
+	d.Parity = byte(strings.IndexByte("NOEMS", byte(parity)))
+	d.StopBits = byte(stop)
+	if parity != uart.None {
+		d.Flags |= 0x0002 // fParity
+	}
+	if r, _, err := procSetCommState.Call(c.Fd(), uintptr(unsafe.Pointer(&d))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func portOpenDefault(name string) (portFile, error) {
+	p, err := syscall.UTF16PtrFromString(`\\.\` + name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	// Return as soon as any data is available, or after one second.
+	t := commTimeouts{
+		ReadIntervalTimeout:        0xFFFFFFFF,
+		ReadTotalTimeoutMultiplier: 0xFFFFFFFF,
+		ReadTotalTimeoutConstant:   1000,
+	}
+	if r, _, err := procSetCommTimeouts.Call(uintptr(h), uintptr(unsafe.Pointer(&t))); r == 0 {
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	return &comPort{os.NewFile(uintptr(h), name)}, nil
+}
+
+// enumerateDefault lists the COM ports as found in the DOS device names.
+func enumerateDefault() ([]int, error) {
+	buf := make([]uint16, 16384)
+	for {
+		r, _, err := procQueryDosDevice.Call(0, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if r != 0 {
+			buf = buf[:r]
+			break
+		}
+		if err != syscall.ERROR_INSUFFICIENT_BUFFER || len(buf) >= 1<<22 {
+			return nil, err
+		}
+		buf = make([]uint16, 2*len(buf))
+	}
+	// The names are separated by NUL characters.
+	var out []int
+	start := 0
+	for i, c := range buf {
+		if c != 0 {
+			continue
+		}
+		if s := syscall.UTF16ToString(buf[start:i]); strings.HasPrefix(s, "COM") {
+			if n, err := strconv.Atoi(s[3:]); err == nil {
+				out = append(out, n)
+			}
+		}
+		start = i + 1
+	}
+	sort.Ints(out)
+	return out, nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package usbhost initializes the USB adapters supported by periph, so
+// programs can be developed on a host without native GPIO, I²C or SPI, like
+// macOS or Windows.
+//
+// It loads the FTDI FT232H and the Silicon Labs CP2112 drivers in addition
+// to the drivers loaded by host.Init(). See the packages ftdi and cp2112 for
+// the build tags required on each OS.
+//
+// When no I²C bus or no SPI port is found, a fallback that records all the
+// transactions and reads zeros is registered as "fallback", so the program
+// still runs. The transactions can be inspected via I2C and SPI.
+package usbhost
@@ -0,0 +1,149 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package usbhost
+
+import (
+	"sync"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+	"periph.io/x/periph/host"
+
+	// Make sure the USB adapter drivers are registered.
+	_ "periph.io/x/periph/experimental/host/cp2112"
+	_ "periph.io/x/periph/experimental/host/ftdi"
+)
+
+// Fallbacks registered by Init when no real I²C bus or SPI port is found.
+//
+// All the transactions are recorded and all reads return zeros.
+var (
+	I2C = &i2ctest.Record{Bus: &discardBus{}}
+	SPI = &spitest.Record{Port: &discardPort{}}
+)
+
+// Init calls host.Init() then registers the fallbacks if needed.
+func Init() (*periph.State, error) {
+	state, err := host.Init()
+	if err != nil {
+		return state, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if registered {
+		return state, nil
+	}
+	if err := registerFallbacks(); err != nil {
+		return state, err
+	}
+	registered = true
+	return state, nil
+}
+
+//
+
+// fallbackName is the name of the fallback I²C bus and SPI port.
+const fallbackName = "fallback"
+
+var (
+	mu         sync.Mutex
+	registered bool
+)
+
+// registerFallbacks registers I2C and SPI if no I²C bus or no SPI port is
+// registered.
+func registerFallbacks() error {
+	if len(i2creg.All()) == 0 {
+		if err := i2creg.Register(fallbackName, nil, -1, func() (i2c.BusCloser, error) { return &i2cCloser{I2C}, nil }); err != nil {
+			return err
+		}
+	}
+	if len(spireg.All()) == 0 {
+		if err := spireg.Register(fallbackName, nil, -1, func() (spi.PortCloser, error) { return &spiCloser{SPI}, nil }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// i2cCloser implements i2c.BusCloser on top of i2ctest.Record.
+type i2cCloser struct {
+	*i2ctest.Record
+}
+
+func (i *i2cCloser) Close() error {
+	return nil
+}
+
+// spiCloser makes spitest.Record reusable across Open calls.
+type spiCloser struct {
+	*spitest.Record
+}
+
+// Close allows the port to be connected again.
+func (s *spiCloser) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	s.Initialized = false
+	return nil
+}
+
+// discardBus implements i2c.Bus; it discards all writes and reads zeros.
+type discardBus struct {
+	conntest.Discard
+}
+
+func (d *discardBus) Tx(addr uint16, w, r []byte) error {
+	return d.Discard.Tx(w, r)
+}
+
+func (d *discardBus) SetSpeed(hz int64) error {
+	return nil
+}
+
+// discardPort implements spi.PortCloser; its connection discards all writes
+// and reads zeros.
+type discardPort struct {
+}
+
+func (d *discardPort) String() string {
+	return "discard"
+}
+
+func (d *discardPort) Close() error {
+	return nil
+}
+
+func (d *discardPort) LimitSpeed(maxHz int64) error {
+	return nil
+}
+
+func (d *discardPort) Connect(maxHz int64, mode spi.Mode, bits int) (spi.Conn, error) {
+	return &discardConn{}, nil
+}
+
+// discardConn implements spi.Conn.
+type discardConn struct {
+	conntest.Discard
+}
+
+func (d *discardConn) TxPackets(p []spi.Packet) error {
+	for _, x := range p {
+		if err := d.Tx(x.W, x.R); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ i2c.BusCloser = &i2cCloser{}
+var _ spi.PortCloser = &spiCloser{}
+var _ spi.Conn = &discardConn{}
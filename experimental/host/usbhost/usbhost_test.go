@@ -0,0 +1,75 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package usbhost
+
+import (
+	"bytes"
+	"testing"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func TestFallbacks(t *testing.T) {
+	if len(i2creg.All()) != 0 || len(spireg.All()) != 0 {
+		t.Skip("buses are already registered")
+	}
+	oldI2C, oldSPI := I2C, SPI
+	I2C = &i2ctest.Record{Bus: &discardBus{}}
+	SPI = &spitest.Record{Port: &discardPort{}}
+	defer func() {
+		i2creg.Unregister(fallbackName)
+		spireg.Unregister(fallbackName)
+		I2C, SPI = oldI2C, oldSPI
+	}()
+	if err := registerFallbacks(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := i2creg.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := []byte{0xFF}
+	if err := b.Tx(0x21, []byte{1}, r); err != nil {
+		t.Fatal(err)
+	}
+	if r[0] != 0 {
+		t.Fatal(r)
+	}
+	if len(I2C.Ops) != 1 || I2C.Ops[0].Addr != 0x21 || !bytes.Equal(I2C.Ops[0].W, []byte{1}) {
+		t.Fatalf("%#v", I2C.Ops)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		p, err := spireg.Open("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := p.Connect(1000000, spi.Mode0, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := []byte{0xFF}
+		if err := c.Tx([]byte{2}, r); err != nil {
+			t.Fatal(err)
+		}
+		if r[0] != 0 {
+			t.Fatal(r)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(SPI.Ops) != 2 || !bytes.Equal(SPI.Ops[1].W, []byte{2}) {
+		t.Fatalf("%#v", SPI.Ops)
+	}
+}
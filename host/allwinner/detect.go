@@ -35,6 +35,31 @@ func IsA64() bool {
 	return detection.isA64
 }
 
+// IsH3 detects whether the host CPU is an Allwinner H3 or H2+ CPU.
+//
+// It looks for the string "sun8i-h3" or "sun8i-h2-plus" in
+// /proc/device-tree/compatible.
+func IsH3() bool {
+	detection.do()
+	return detection.isH3
+}
+
+// IsH5 detects whether the host CPU is an Allwinner H5 CPU.
+//
+// It looks for the string "sun50i-h5" in /proc/device-tree/compatible.
+func IsH5() bool {
+	detection.do()
+	return detection.isH5
+}
+
+// IsH6 detects whether the host CPU is an Allwinner H6 CPU.
+//
+// It looks for the string "sun50i-h6" in /proc/device-tree/compatible.
+func IsH6() bool {
+	detection.do()
+	return detection.isH6
+}
+
 //
 
 type detectionS struct {
@@ -43,6 +68,9 @@ type detectionS struct {
 	isAllwinner bool
 	isR8        bool
 	isA64       bool
+	isH3        bool
+	isH5        bool
+	isH6        bool
 }
 
 var detection detectionS
@@ -65,8 +93,18 @@ func (d *detectionS) do() {
 				if strings.Contains(c, "sun5i-r8") {
 					d.isR8 = true
 				}
+				// The H2+ is a H3 without gigabit ethernet nor 4K HDMI.
+				if strings.Contains(c, "sun8i-h3") || strings.Contains(c, "sun8i-h2-plus") {
+					d.isH3 = true
+				}
+				if strings.Contains(c, "sun50i-h5") {
+					d.isH5 = true
+				}
+				if strings.Contains(c, "sun50i-h6") {
+					d.isH6 = true
+				}
 			}
-			d.isAllwinner = d.isA64 || d.isR8
+			d.isAllwinner = d.isA64 || d.isR8 || d.isH3 || d.isH5 || d.isH6
 		}
 	}
 }
@@ -17,6 +17,10 @@
 //
 // H3: http://dl.linux-sunxi.org/H3/Allwinner_H3_Datasheet_V1.0.pdf
 //
+// H5: http://linux-sunxi.org/images/d/de/Allwinner_H5_Manual_v1.0.pdf
+//
+// H6: http://linux-sunxi.org/images/5/5c/Allwinner_H6_V200_User_Manual_V1.1.pdf
+//
 // R8: https://github.com/NextThingCo/CHIP-Hardware/raw/master/CHIP%5Bv1_0%5D/CHIPv1_0-BOM-Datasheets/Allwinner%20R8%20User%20Manual%20V1.1.pdf
 //
 // Physical overview: http://files.pine64.org/doc/datasheet/pine64/A64_Datasheet_V1.1.pdf
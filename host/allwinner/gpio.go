@@ -20,6 +20,7 @@ import (
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/soc"
 	"periph.io/x/periph/host/sysfs"
 )
 
@@ -29,10 +30,12 @@ import (
 // the R8 has the LCD-DE signal on gpio PD25 but the A64 has it on PD19.
 //
 // The availability of each gpio differs between CPUs. For example the R8 has
-// 19 pins in the group PB but the A64 only has 10.
+// 19 pins in the group PB but the A64 only has 10, and only the H3 and H5 have
+// the group PA.
 //
 // So make sure to read the datasheet for the exact right CPU.
 var (
+	PA0, PA1, PA2, PA3, PA4, PA5, PA6, PA7, PA8, PA9, PA10, PA11, PA12, PA13, PA14, PA15, PA16, PA17, PA18, PA19, PA20, PA21                                     *Pin
 	PB0, PB1, PB2, PB3, PB4, PB5, PB6, PB7, PB8, PB9, PB10, PB11, PB12, PB13, PB14, PB15, PB16, PB17, PB18                                                       *Pin
 	PC0, PC1, PC2, PC3, PC4, PC5, PC6, PC7, PC8, PC9, PC10, PC11, PC12, PC13, PC14, PC15, PC16, PC17, PC18, PC19                                                 *Pin
 	PD0, PD1, PD2, PD3, PD4, PD5, PD6, PD7, PD8, PD9, PD10, PD11, PD12, PD13, PD14, PD15, PD16, PD17, PD18, PD19, PD20, PD21, PD22, PD23, PD24, PD25, PD26, PD27 *Pin
@@ -55,6 +58,7 @@ type Pin struct {
 	// Mutable.
 	edge        *sysfs.Pin // Set once, then never set back to nil.
 	usingEdge   bool       // Set when edge detection is enabled.
+	usingPWM    bool       // Set when PWM0 is enabled on this pin.
 	available   bool       // Set when the pin is available on this CPU architecture.
 	supportEdge bool       // Set when the pin supports interrupt based edge detection.
 }
@@ -118,8 +122,12 @@ func (p *Pin) Function() string {
 
 // Halt implements conn.Resource.
 //
-// It stops edge detection if enabled.
+// It stops edge detection and PWM if enabled.
 func (p *Pin) Halt() error {
+	if p.usingPWM {
+		pwmMemory.stop()
+		p.usingPWM = false
+	}
 	if p.usingEdge {
 		if err := p.edge.Halt(); err != nil {
 			return p.wrap(err)
@@ -245,8 +253,14 @@ func (p *Pin) Out(l gpio.Level) error {
 // to reach Mhz scale bit banging.
 func (p *Pin) FastOut(l gpio.Level) {
 	bit := uint32(1 << p.offset)
-	// Pn_DAT  n*0x24+0x10  Port n Data Register (n from 1(B) to 7(H))
+	// Pn_DAT  n*0x24+0x10  Port n Data Register (n from 0(A) to 7(H))
 	switch p.group {
+	case 0:
+		if l {
+			gpioMemory.groups[0].data |= bit
+		} else {
+			gpioMemory.groups[0].data &^= bit
+		}
 	case 1:
 		if l {
 			gpioMemory.groups[1].data |= bit
@@ -292,6 +306,57 @@ func (p *Pin) FastOut(l gpio.Level) {
 	}
 }
 
+// PWM outputs a periodic signal on supported pins.
+//
+// PWM0 is exposed on PA5 on the H3 and H5, which is the UART0 RX pin used by
+// the serial console on most boards; the console must be disabled first. The
+// A64 and R8 are not supported yet.
+//
+// The period must be between 84ns and 196s. The longer the period, the
+// lower the duty cycle resolution; it is at best 1/65536.
+//
+// It requires the driver "allwinner-pwm" to be loaded, which requires root
+// level access.
+func (p *Pin) PWM(duty gpio.Duty, period time.Duration) error {
+	if duty == 0 {
+		return p.Out(gpio.Low)
+	} else if duty == gpio.DutyMax {
+		return p.Out(gpio.High)
+	}
+	if !duty.Valid() {
+		return p.wrap(fmt.Errorf("invalid duty %d", duty))
+	}
+	f := disabled
+	for i, s := range p.altFunc {
+		if s == "PWM0" {
+			f = alt1 + function(i)
+		}
+	}
+	if f == disabled || !p.available {
+		return p.wrap(errors.New("pwm is not supported on this pin"))
+	}
+	if gpioMemory == nil {
+		return p.wrap(errors.New("subsystem not initialized"))
+	}
+	if pwmMemory == nil {
+		return p.wrap(errors.New("allwinner-pwm not initialized; try again as root?"))
+	}
+	scaler, total, err := getBestPrescale(period)
+	if err != nil {
+		return p.wrap(err)
+	}
+	active := uint16(uint64(total) * uint64(duty) / uint64(gpio.DutyMax))
+	if err := p.Halt(); err != nil {
+		return err
+	}
+	if err := pwmMemory.set(scaler, total, active); err != nil {
+		return p.wrap(err)
+	}
+	p.usingPWM = true
+	p.setFunction(f)
+	return nil
+}
+
 // DefaultPull returns the default pull for the pin.
 func (p *Pin) DefaultPull() gpio.Pull {
 	return p.defaultPull
@@ -356,6 +421,28 @@ var (
 // list 100% correct on all platforms seems futile, hence periph errs on the
 // side of caution.
 var cpupins = map[string]*Pin{
+	"PA0":  {group: 0, offset: 0, name: "PA0", defaultPull: gpio.Float},
+	"PA1":  {group: 0, offset: 1, name: "PA1", defaultPull: gpio.Float},
+	"PA2":  {group: 0, offset: 2, name: "PA2", defaultPull: gpio.Float},
+	"PA3":  {group: 0, offset: 3, name: "PA3", defaultPull: gpio.Float},
+	"PA4":  {group: 0, offset: 4, name: "PA4", defaultPull: gpio.Float},
+	"PA5":  {group: 0, offset: 5, name: "PA5", defaultPull: gpio.Float},
+	"PA6":  {group: 0, offset: 6, name: "PA6", defaultPull: gpio.Float},
+	"PA7":  {group: 0, offset: 7, name: "PA7", defaultPull: gpio.Float},
+	"PA8":  {group: 0, offset: 8, name: "PA8", defaultPull: gpio.Float},
+	"PA9":  {group: 0, offset: 9, name: "PA9", defaultPull: gpio.Float},
+	"PA10": {group: 0, offset: 10, name: "PA10", defaultPull: gpio.Float},
+	"PA11": {group: 0, offset: 11, name: "PA11", defaultPull: gpio.Float},
+	"PA12": {group: 0, offset: 12, name: "PA12", defaultPull: gpio.Float},
+	"PA13": {group: 0, offset: 13, name: "PA13", defaultPull: gpio.Float},
+	"PA14": {group: 0, offset: 14, name: "PA14", defaultPull: gpio.Float},
+	"PA15": {group: 0, offset: 15, name: "PA15", defaultPull: gpio.Float},
+	"PA16": {group: 0, offset: 16, name: "PA16", defaultPull: gpio.Float},
+	"PA17": {group: 0, offset: 17, name: "PA17", defaultPull: gpio.Float},
+	"PA18": {group: 0, offset: 18, name: "PA18", defaultPull: gpio.Float},
+	"PA19": {group: 0, offset: 19, name: "PA19", defaultPull: gpio.Float},
+	"PA20": {group: 0, offset: 20, name: "PA20", defaultPull: gpio.Float},
+	"PA21": {group: 0, offset: 21, name: "PA21", defaultPull: gpio.Float},
 	"PB0":  {group: 1, offset: 0, name: "PB0", defaultPull: gpio.Float},
 	"PB1":  {group: 1, offset: 1, name: "PB1", defaultPull: gpio.Float},
 	"PB2":  {group: 1, offset: 2, name: "PB2", defaultPull: gpio.Float},
@@ -477,6 +564,28 @@ var cpupins = map[string]*Pin{
 }
 
 func init() {
+	PA0 = cpupins["PA0"]
+	PA1 = cpupins["PA1"]
+	PA2 = cpupins["PA2"]
+	PA3 = cpupins["PA3"]
+	PA4 = cpupins["PA4"]
+	PA5 = cpupins["PA5"]
+	PA6 = cpupins["PA6"]
+	PA7 = cpupins["PA7"]
+	PA8 = cpupins["PA8"]
+	PA9 = cpupins["PA9"]
+	PA10 = cpupins["PA10"]
+	PA11 = cpupins["PA11"]
+	PA12 = cpupins["PA12"]
+	PA13 = cpupins["PA13"]
+	PA14 = cpupins["PA14"]
+	PA15 = cpupins["PA15"]
+	PA16 = cpupins["PA16"]
+	PA17 = cpupins["PA17"]
+	PA18 = cpupins["PA18"]
+	PA19 = cpupins["PA19"]
+	PA20 = cpupins["PA20"]
+	PA21 = cpupins["PA21"]
 	PB0 = cpupins["PB0"]
 	PB1 = cpupins["PB1"]
 	PB2 = cpupins["PB2"]
@@ -646,7 +755,7 @@ type gpioGroup struct {
 
 // gpioMap memory-maps all the gpio pin groups.
 type gpioMap struct {
-	// PB to PH. The first group is unused.
+	// PA to PH. The first group is only used on the H3 and H5.
 	groups [8]gpioGroup
 }
 
@@ -677,18 +786,40 @@ func (d *driverGPIO) Init() (bool, error) {
 		return true, err
 	}
 
+	var model string
 	switch {
 	case IsA64():
+		model = "A64"
 		if err := mapA64Pins(); err != nil {
 			return true, err
 		}
 	case IsR8():
+		model = "R8"
 		if err := mapR8Pins(); err != nil {
 			return true, err
 		}
+	case IsH3(), IsH5():
+		model = "H3"
+		if IsH5() {
+			model = "H5"
+		}
+		if err := mapH3Pins(); err != nil {
+			return true, err
+		}
+	case IsH6():
+		model = "H6"
+		if err := mapH6Pins(); err != nil {
+			return true, err
+		}
 	default:
 		return false, errors.New("unknown Allwinner CPU model")
 	}
+	if err := soc.Register(soc.Info{
+		Model:       model,
+		Peripherals: []soc.Peripheral{{Name: "GPIO", Base: uint64(gpioBaseAddr)}},
+	}); err != nil {
+		return true, err
+	}
 
 	return true, initPins()
 }
@@ -700,12 +831,15 @@ func init() {
 }
 
 // getBaseAddress queries the virtual file system to retrieve the base address
-// of the GPIO registers for GPIO pins in groups PB to PH.
+// of the GPIO registers for GPIO pins in groups PA to PH.
 //
 // Defaults to 0x01C20800 as per datasheet if it could not query the file
-// system.
+// system, or 0x0300B000 on the H6.
 func getBaseAddress() uint64 {
 	base := uint64(0x01C20800)
+	if IsH6() {
+		base = 0x0300B000
+	}
 	link, err := os.Readlink("/sys/bus/platform/drivers/sun50i-pinctrl/driver")
 	if err != nil {
 		return base
@@ -725,5 +859,6 @@ func getBaseAddress() uint64 {
 
 var _ gpio.PinDefaultPull = &Pin{}
 var _ gpio.PinIO = &Pin{}
+var _ gpio.PinPWM = &Pin{}
 var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
@@ -17,6 +17,7 @@ import (
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/soc"
 	"periph.io/x/periph/host/sysfs"
 )
 
@@ -321,9 +322,13 @@ func init() {
 // getBaseAddressPL queries the virtual file system to retrieve the base address
 // of the GPIO registers for GPIO pins in group PL.
 //
-// Defaults to 0x01F02C00 as per datasheet if could query the file system.
+// Defaults to 0x01F02C00 as per datasheet if could query the file system, or
+// 0x07022000 on the H6.
 func getBaseAddressPL() uint64 {
 	base := uint64(0x01F02C00)
+	if IsH6() {
+		base = 0x07022000
+	}
 	link, err := os.Readlink("/sys/bus/platform/drivers/sun50i-r-pinctrl/driver")
 	if err != nil {
 		return base
@@ -352,11 +357,11 @@ func (d *driverGPIOPL) Prerequisites() []string {
 }
 
 func (d *driverGPIOPL) Init() (bool, error) {
-	// BUG(maruel): H3 supports group PL too.
-	if !IsA64() {
-		return false, errors.New("A64 CPU not detected")
+	if !IsA64() && !IsH3() && !IsH5() && !IsH6() {
+		return false, errors.New("A64, H3, H5 or H6 CPU not detected")
 	}
-	m, err := pmem.Map(getBaseAddressPL(), 4096)
+	base := getBaseAddressPL()
+	m, err := pmem.Map(base, 4096)
 	if err != nil {
 		if os.IsPermission(err) {
 			return true, fmt.Errorf("need more access, try as root: %v", err)
@@ -366,6 +371,9 @@ func (d *driverGPIOPL) Init() (bool, error) {
 	if err := m.AsPOD(&gpioMemoryPL); err != nil {
 		return true, err
 	}
+	if err := soc.Register(soc.Info{Peripherals: []soc.Peripheral{{Name: "GPIO_PL", Base: base}}}); err != nil {
+		return true, err
+	}
 
 	for i := range cpuPinsPL {
 		p := &cpuPinsPL[i]
@@ -0,0 +1,90 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package allwinner
+
+import (
+	"fmt"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestPA(t *testing.T) {
+	pins := []*Pin{
+		PA0, PA1, PA2, PA3, PA4, PA5, PA6, PA7, PA8, PA9, PA10, PA11, PA12, PA13,
+		PA14, PA15, PA16, PA17, PA18, PA19, PA20, PA21,
+	}
+	for i, p := range pins {
+		if p == nil {
+			t.Fatalf("PA%d is nil", i)
+		}
+		if s := fmt.Sprintf("PA%d", i); p.Name() != s || p.group != 0 || int(p.offset) != i {
+			t.Fatalf("%s: %s %d %d", s, p.Name(), p.group, p.offset)
+		}
+	}
+}
+
+func TestMappings(t *testing.T) {
+	for cpu, m := range map[string]map[string][5]string{"A64": mappingA64, "H3": mappingH3, "H6": mappingH6} {
+		for name := range m {
+			if cpupins[name] == nil {
+				t.Fatalf("%s: unknown pin %s", cpu, name)
+			}
+		}
+	}
+}
+
+// Only the H3 mapping is tested here since the SPI aliases it registers can't
+// be unregistered and conflict with the ones of the other CPUs.
+func TestMapH3Pins(t *testing.T) {
+	defer restorePins(savePins())
+	if err := mapH3Pins(); err != nil {
+		t.Fatal(err)
+	}
+	if !PA12.available || !PA12.supportEdge || PA12.altFunc[0] != "I2C0_SDA" {
+		t.Fatal(PA12.available, PA12.supportEdge, PA12.altFunc)
+	}
+	if !PC3.available || PC3.supportEdge {
+		t.Fatal(PC3.available, PC3.supportEdge)
+	}
+	// PB is not available on the H3.
+	if PB0.available {
+		t.Fatal("PB0 is not on the H3")
+	}
+}
+
+func TestFastOut_PA(t *testing.T) {
+	defer func(m *gpioMap) { gpioMemory = m }(gpioMemory)
+	gpioMemory = &gpioMap{}
+	PA5.FastOut(gpio.High)
+	if d := gpioMemory.groups[0].data; d != 1<<5 {
+		t.Fatal(d)
+	}
+	if d := gpioMemory.groups[1].data; d != 0 {
+		t.Fatal(d)
+	}
+	PA5.FastOut(gpio.Low)
+	if d := gpioMemory.groups[0].data; d != 0 {
+		t.Fatal(d)
+	}
+}
+
+//
+
+// savePins returns a copy of the state of all the pins, since the pins mapping
+// functions mutate them.
+func savePins() map[string]Pin {
+	s := make(map[string]Pin, len(cpupins))
+	for name, p := range cpupins {
+		s[name] = *p
+	}
+	return s
+}
+
+func restorePins(s map[string]Pin) {
+	for name, p := range cpupins {
+		*p = s[name]
+	}
+}
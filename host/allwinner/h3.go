@@ -0,0 +1,125 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// This file contains pin mapping information that is specific to the Allwinner
+// H3 model.
+
+package allwinner
+
+import (
+	"strings"
+
+	"periph.io/x/periph/conn/gpio/gpioreg"
+)
+
+// mappingH3 describes the mapping of the H3 processor gpios to their
+// alternate functions. The H2+ and H5 use the same mapping.
+//
+// It omits the in & out functions which are available on all gpio.
+//
+// The mapping comes from the datasheet page 74:
+// http://dl.linux-sunxi.org/H3/Allwinner_H3_Datasheet_V1.0.pdf
+//
+// - The datasheet uses TWI instead of I2C but it is renamed here for
+//   consistency.
+// - Only the groups PA and PG support interrupt based edge detection.
+var mappingH3 = map[string][5]string{
+	"PA0":  {"UART2_TX", "JTAG_MS", "", "", "PA_EINT0"},
+	"PA1":  {"UART2_RX", "JTAG_CK", "", "", "PA_EINT1"},
+	"PA2":  {"UART2_RTS", "JTAG_DO", "", "", "PA_EINT2"},
+	"PA3":  {"UART2_CTS", "JTAG_DI", "", "", "PA_EINT3"},
+	"PA4":  {"UART0_TX", "", "", "", "PA_EINT4"},
+	"PA5":  {"UART0_RX", "PWM0", "", "", "PA_EINT5"},
+	"PA6":  {"SIM_PWREN", "PWM1", "", "", "PA_EINT6"},
+	"PA7":  {"SIM_CLK", "", "", "", "PA_EINT7"},
+	"PA8":  {"SIM_DATA", "", "", "", "PA_EINT8"},
+	"PA9":  {"SIM_RST", "", "", "", "PA_EINT9"},
+	"PA10": {"SIM_DET", "", "", "", "PA_EINT10"},
+	"PA11": {"I2C0_SCL", "DI_TX", "", "", "PA_EINT11"},
+	"PA12": {"I2C0_SDA", "DI_RX", "", "", "PA_EINT12"},
+	"PA13": {"SPI1_CS0", "UART3_TX", "", "", "PA_EINT13"},
+	"PA14": {"SPI1_CLK", "UART3_RX", "", "", "PA_EINT14"},
+	"PA15": {"SPI1_MOSI", "UART3_RTS", "", "", "PA_EINT15"},
+	"PA16": {"SPI1_MISO", "UART3_CTS", "", "", "PA_EINT16"},
+	"PA17": {"OWA_OUT", "", "", "", "PA_EINT17"},
+	"PA18": {"PCM0_SYNC", "I2C1_SCL", "", "", "PA_EINT18"},
+	"PA19": {"PCM0_CLK", "I2C1_SDA", "", "", "PA_EINT19"},
+	"PA20": {"PCM0_DOUT", "SIM_VPPEN", "", "", "PA_EINT20"},
+	"PA21": {"PCM0_DIN", "SIM_VPPPP", "", "", "PA_EINT21"},
+	"PC0":  {"NAND_WE", "SPI0_MOSI"},
+	"PC1":  {"NAND_ALE", "SPI0_MISO"},
+	"PC2":  {"NAND_CLE", "SPI0_CLK"},
+	"PC3":  {"NAND_CE1", "SPI0_CS0"},
+	"PC4":  {"NAND_CE0"},
+	"PC5":  {"NAND_RE", "SDC2_CLK"},
+	"PC6":  {"NAND_RB0", "SDC2_CMD"},
+	"PC7":  {"NAND_RB1"},
+	"PC8":  {"NAND_DQ0", "SDC2_D0"},
+	"PC9":  {"NAND_DQ1", "SDC2_D1"},
+	"PC10": {"NAND_DQ2", "SDC2_D2"},
+	"PC11": {"NAND_DQ3", "SDC2_D3"},
+	"PC12": {"NAND_DQ4", "SDC2_D4"},
+	"PC13": {"NAND_DQ5", "SDC2_D5"},
+	"PC14": {"NAND_DQ6", "SDC2_D6"},
+	"PC15": {"NAND_DQ7", "SDC2_D7"},
+	"PC16": {"NAND_DQS", "SDC2_RST"},
+	"PD0":  {"RGMII_RXD3", "MII_RXD3", "RMII_NULL"},
+	"PD1":  {"RGMII_RXD2", "MII_RXD2", "RMII_NULL"},
+	"PD2":  {"RGMII_RXD1", "MII_RXD1", "RMII_RXD1"},
+	"PD3":  {"RGMII_RXD0", "MII_RXD0", "RMII_RXD0"},
+	"PD4":  {"RGMII_RXCK", "MII_RXCK", "RMII_NULL"},
+	"PD5":  {"RGMII_RXCTL", "MII_RXDV", "RMII_CRS_DV"},
+	"PD6":  {"RGMII_NULL", "MII_RXERR", "RMII_RXER"},
+	"PD7":  {"RGMII_TXD3", "MII_TXD3", "RMII_NULL"},
+	"PD8":  {"RGMII_TXD2", "MII_TXD2", "RMII_NULL"},
+	"PD9":  {"RGMII_TXD1", "MII_TXD1", "RMII_TXD1"},
+	"PD10": {"RGMII_TXD0", "MII_TXD0", "RMII_TXD0"},
+	"PD11": {"RGMII_NULL", "MII_CRS", "RMII_NULL"},
+	"PD12": {"RGMII_TXCK", "MII_TXCK", "RMII_TXCK"},
+	"PD13": {"RGMII_TXCTL", "MII_TXEN", "RMII_TXEN"},
+	"PD14": {"RGMII_NULL", "MII_TXERR", "RMII_NULL"},
+	"PD15": {"RGMII_CLKIN", "MII_COL", "RMII_NULL"},
+	"PD16": {"MDC"},
+	"PD17": {"MDIO"},
+	"PG0":  {"SDC1_CLK", "", "", "", "PG_EINT0"},
+	"PG1":  {"SDC1_CMD", "", "", "", "PG_EINT1"},
+	"PG2":  {"SDC1_D0", "", "", "", "PG_EINT2"},
+	"PG3":  {"SDC1_D1", "", "", "", "PG_EINT3"},
+	"PG4":  {"SDC1_D2", "", "", "", "PG_EINT4"},
+	"PG5":  {"SDC1_D3", "", "", "", "PG_EINT5"},
+	"PG6":  {"UART1_TX", "", "", "", "PG_EINT6"},
+	"PG7":  {"UART1_RX", "", "", "", "PG_EINT7"},
+	"PG8":  {"UART1_RTS", "", "", "", "PG_EINT8"},
+	"PG9":  {"UART1_CTS", "", "", "", "PG_EINT9"},
+	"PG10": {"PCM1_SYNC", "", "", "", "PG_EINT10"},
+	"PG11": {"PCM1_CLK", "", "", "", "PG_EINT11"},
+	"PG12": {"PCM1_DOUT", "", "", "", "PG_EINT12"},
+	"PG13": {"PCM1_DIN", "", "", "", "PG_EINT13"},
+}
+
+// mapH3Pins uses mappingH3 to actually set the altFunc fields of all gpio
+// and mark them as available.
+//
+// It is called by the generic allwinner processor code if a H3 or H5 is
+// detected.
+func mapH3Pins() error {
+	for name, altFuncs := range mappingH3 {
+		pin := cpupins[name]
+		pin.altFunc = altFuncs
+		pin.available = true
+		if strings.Contains(altFuncs[4], "EINT") {
+			pin.supportEdge = true
+		}
+		// Manually map the CS line as an alias because otherwise it never gets
+		// registered.
+		for _, s := range altFuncs {
+			if strings.HasPrefix(s, "SPI") && strings.HasSuffix(s, "_CS0") {
+				if err := gpioreg.RegisterAlias(s, pin.Name()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// This file contains pin mapping information that is specific to the Allwinner
+// H6 model.
+
+package allwinner
+
+import (
+	"strings"
+
+	"periph.io/x/periph/conn/gpio/gpioreg"
+)
+
+// mappingH6 describes the mapping of the H6 processor gpios to their
+// alternate functions.
+//
+// It omits the in & out functions which are available on all gpio.
+//
+// The H6 has a lot more functions than this, mostly for the LCD and the
+// transport stream interfaces; only the pins routed to the extension headers
+// of the supported boards and their commonly used functions are listed.
+//
+// The mapping comes from the user manual page 376:
+// http://linux-sunxi.org/images/5/5c/Allwinner_H6_V200_User_Manual_V1.1.pdf
+//
+// - The datasheet uses TWI instead of I2C but it is renamed here for
+//   consistency.
+// - Only the groups PF, PG and PH support interrupt based edge detection.
+var mappingH6 = map[string][5]string{
+	"PC0":  {"NAND_WE", "SDC2_DS", "SPI0_CLK"},
+	"PC2":  {"NAND_CLE", "", "SPI0_MOSI"},
+	"PC3":  {"NAND_CE1", "", "SPI0_CS0"},
+	"PC4":  {"NAND_CE0", "", "SPI0_MISO"},
+	"PD15": {"LCD0_D21", "TS1_DVLD", "DMIC_DATA3", "CSI_D9"},
+	"PD16": {"LCD0_D22", "TS1_D0", "DMIC_DATA2"},
+	"PD18": {"LCD0_CLK", "TS2_ERR", "DMIC_DATA0"},
+	"PD21": {"LCD0_VSYNC", "TS2_D0", "UART2_CTS"},
+	"PD22": {"PWM0", "TS3_CLK", "OWA_OUT"},
+	"PD23": {"I2C2_SCL", "TS3_ERR", "UART3_TX", "CCIR_CLK"},
+	"PD24": {"I2C2_SDA", "TS3_SYNC", "UART3_RX", "CCIR_DE"},
+	"PD25": {"I2C0_SCL", "TS3_DVLD", "UART3_RTS", "CCIR_HSYNC"},
+	"PD26": {"I2C0_SDA", "TS3_D0", "UART3_CTS", "CCIR_VSYNC"},
+	"PG6":  {"UART1_TX", "", "JTAG_MS", "", "PG_EINT6"},
+	"PG7":  {"UART1_RX", "", "JTAG_CK", "", "PG_EINT7"},
+	"PG8":  {"UART1_RTS", "", "JTAG_DO", "", "PG_EINT8"},
+	"PG9":  {"UART1_CTS", "", "JTAG_DI", "", "PG_EINT9"},
+	"PH0":  {"UART0_TX", "", "PWM1", "", "PH_EINT0"},
+	"PH1":  {"UART0_RX", "", "PWM1", "", "PH_EINT1"},
+	"PH3":  {"CIR_TX", "SPI1_CS0", "", "", "PH_EINT3"},
+	"PH4":  {"", "SPI1_CLK", "", "", "PH_EINT4"},
+	"PH5":  {"", "SPI1_MOSI", "", "", "PH_EINT5"},
+	"PH6":  {"", "SPI1_MISO", "", "", "PH_EINT6"},
+}
+
+// mapH6Pins uses mappingH6 to actually set the altFunc fields of all gpio
+// and mark them as available.
+//
+// It is called by the generic allwinner processor code if a H6 is detected.
+func mapH6Pins() error {
+	for name, altFuncs := range mappingH6 {
+		pin := cpupins[name]
+		pin.altFunc = altFuncs
+		pin.available = true
+		if strings.Contains(altFuncs[4], "EINT") {
+			pin.supportEdge = true
+		}
+		// Manually map the CS line as an alias because otherwise it never gets
+		// registered.
+		for _, s := range altFuncs {
+			if strings.HasPrefix(s, "SPI") && strings.HasSuffix(s, "_CS0") {
+				if err := gpioreg.RegisterAlias(s, pin.Name()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -5,9 +5,15 @@
 package allwinner
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/soc"
 )
 
 var (
@@ -172,21 +178,23 @@ func toPeriod(total uint32, active uint16) pwmPeriod {
 	return pwmPeriod(total-1)<<16 | pwmPeriod(active)
 }
 
-// getBestPrescale finds the best prescaler.
+// getBestPrescale finds the smallest prescaler that can generate period,
+// which gives the best duty cycle resolution, and the number of cycles of the
+// period.
 //
-// Cycles must be between 2 and 0x10000/2.
-func getBestPrescale(period time.Duration) pwmPrescale {
-	// TODO(maruel): Rewrite this function, it is incorrect.
+// The period must be between 2 cycles at 24MHz, 84ns, and 0x10000 cycles at
+// 333Hz, 196s.
+func getBestPrescale(period time.Duration) (pwmPrescale, uint32, error) {
 	for _, v := range prescalers {
-		p := time.Second / time.Duration(v.freq)
-		smallest := (period / pwmMaxPeriod)
-		largest := (period / 2)
-		if p > smallest && p < largest {
-			return v.scaler
+		cycles := uint64(period) * uint64(v.freq) / uint64(time.Second)
+		if cycles < 2 {
+			return 0, 0, fmt.Errorf("period %s is too short", period)
+		}
+		if cycles <= pwmMaxPeriod {
+			return v.scaler, uint32(cycles), nil
 		}
 	}
-	// Period is longer than 196s.
-	return pwmPrescale72000
+	return 0, 0, fmt.Errorf("period %s is too long", period)
 }
 
 // pwmMap represents the PWM memory mapped CPU registers.
@@ -202,3 +210,65 @@ type pwmMap struct {
 func (p *pwmMap) String() string {
 	return fmt.Sprintf("pwmMap{%s, %v}", p.ctl, p.period)
 }
+
+// set starts PWM0 with the prescaler and number of cycles specified.
+func (p *pwmMap) set(scaler pwmPrescale, total uint32, active uint16) error {
+	ctl := p.ctl &^ pwm0Mask
+	// The period register can only be written once the clock is running and
+	// the previous write was applied.
+	p.ctl = ctl | pwm0SCLK | pwmCtl(scaler)
+	for start := time.Now(); p.ctl&pwmBusy != 0; {
+		if time.Since(start) > time.Millisecond {
+			return errors.New("PWM controller is busy")
+		}
+	}
+	p.period = toPeriod(total, active)
+	p.ctl = ctl | pwm0SCLK | pwm0Polarity | pwm0Enable | pwmCtl(scaler)
+	return nil
+}
+
+// stop stops PWM0.
+func (p *pwmMap) stop() {
+	p.ctl &^= pwm0Mask
+}
+
+// driverPWM implements periph.Driver.
+//
+// It maps the PWM controller registers. Only the H3 and H5 are supported for
+// now.
+type driverPWM struct {
+}
+
+func (d *driverPWM) String() string {
+	return "allwinner-pwm"
+}
+
+func (d *driverPWM) Prerequisites() []string {
+	return []string{"allwinner-gpio"}
+}
+
+func (d *driverPWM) Init() (bool, error) {
+	if !IsH3() && !IsH5() {
+		return false, errors.New("PWM is only supported on the H3 and H5")
+	}
+	// H3: Page 187. H5: Page 210.
+	pwmBaseAddr = 0x1C21400
+	if err := pmem.MapAsPOD(uint64(pwmBaseAddr), &pwmMemory); err != nil {
+		if os.IsPermission(err) {
+			return true, fmt.Errorf("need more access, try as root: %v", err)
+		}
+		return true, err
+	}
+	return true, soc.Register(soc.Info{
+		Peripherals: []soc.Peripheral{{Name: "PWM", Base: uint64(pwmBaseAddr)}},
+		PWM:         []string{"PA5"},
+	})
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driverPWM{})
+	}
+}
+
+var _ periph.Driver = &driverPWM{}
@@ -0,0 +1,104 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package allwinner
+
+import (
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestGetBestPrescale(t *testing.T) {
+	data := []struct {
+		period time.Duration
+		scaler pwmPrescale
+		cycles uint32
+	}{
+		{84 * time.Nanosecond, pwmPrescale1, 2},
+		{time.Millisecond, pwmPrescale1, 24000},
+		{10 * time.Millisecond, pwmPrescale120, 2000},
+		{time.Second, pwmPrescale480, 50000},
+		{100 * time.Second, pwmPrescale48000, 50000},
+		{150 * time.Second, pwmPrescale72000, 49950},
+	}
+	for i, line := range data {
+		s, c, err := getBestPrescale(line.period)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if s != line.scaler || c != line.cycles {
+			t.Fatalf("#%d: %s %d", i, s, c)
+		}
+	}
+	if _, _, err := getBestPrescale(80 * time.Nanosecond); err == nil {
+		t.Fatal("too short")
+	}
+	if _, _, err := getBestPrescale(200 * time.Second); err == nil {
+		t.Fatal("too long")
+	}
+}
+
+func TestPWMMap(t *testing.T) {
+	// PWM1 bits must be kept as is.
+	p := pwmMap{ctl: 1 << 15}
+	if err := p.set(pwmPrescale120, 2000, 500); err != nil {
+		t.Fatal(err)
+	}
+	if p.ctl != 1<<15|pwm0SCLK|pwm0Polarity|pwm0Enable|pwm0Prescale120 {
+		t.Fatal(p.ctl)
+	}
+	if p.period != 1999<<16|500 {
+		t.Fatal(p.period)
+	}
+	p.stop()
+	if p.ctl != 1<<15 {
+		t.Fatal(p.ctl)
+	}
+}
+
+func TestPin_PWM(t *testing.T) {
+	defer func() {
+		gpioMemory = nil
+		pwmMemory = nil
+	}()
+	p := &Pin{group: 0, offset: 5, name: "PA5", altFunc: mappingH3["PA5"], available: true}
+	if err := p.PWM(gpio.DutyHalf, time.Millisecond); err == nil {
+		t.Fatal("gpio not initialized")
+	}
+	gpioMemory = &gpioMap{}
+	if err := p.PWM(gpio.DutyHalf, time.Millisecond); err == nil {
+		t.Fatal("pwm not initialized")
+	}
+	pwmMemory = &pwmMap{}
+	if err := p.PWM(gpio.DutyHalf, time.Nanosecond); err == nil {
+		t.Fatal("period too short")
+	}
+	if err := p.PWM(gpio.DutyHalf, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if p.function() != alt2 || !p.usingPWM {
+		t.Fatal(p.function())
+	}
+	if pwmMemory.period != 23999<<16|11999 {
+		t.Fatal(pwmMemory.period)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if pwmMemory.ctl&pwm0Enable != 0 || p.usingPWM {
+		t.Fatal(pwmMemory.ctl)
+	}
+	if err := p.PWM(0, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if p.function() != out {
+		t.Fatal(p.function())
+	}
+	q := &Pin{group: 0, offset: 6, name: "PA6", altFunc: mappingH3["PA7"], available: true}
+	if err := q.PWM(gpio.DutyHalf, time.Millisecond); err == nil {
+		t.Fatal("pwm is not supported on this pin")
+	}
+}
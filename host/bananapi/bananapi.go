@@ -0,0 +1,127 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bananapi
+
+import (
+	"errors"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/allwinner"
+	"periph.io/x/periph/host/distro"
+)
+
+// Present returns true if running on a Banana Pi M64.
+//
+// It looks for the board in /proc/device-tree/compatible.
+//
+// http://www.banana-pi.org/m64.html
+func Present() bool {
+	if isArm {
+		return dtIsCompatible("sinovoip,bananapi-m64")
+	}
+	return false
+}
+
+// All the individual pins on the Raspberry Pi 2 compatible header.
+var (
+	P1_1  = pin.V3_3       //
+	P1_2  = pin.V5         //
+	P1_3  = allwinner.PH3  // I2C1_SDA
+	P1_4  = pin.V5         //
+	P1_5  = allwinner.PH2  // I2C1_SCL
+	P1_6  = pin.GROUND     //
+	P1_7  = allwinner.PH6  //
+	P1_8  = allwinner.PB0  // UART2_TX
+	P1_9  = pin.GROUND     //
+	P1_10 = allwinner.PB1  // UART2_RX
+	P1_11 = allwinner.PH7  //
+	P1_12 = allwinner.PB5  // PCM0_BCLK
+	P1_13 = allwinner.PH8  //
+	P1_14 = pin.GROUND     //
+	P1_15 = allwinner.PH9  //
+	P1_16 = allwinner.PD5  //
+	P1_17 = pin.V3_3       //
+	P1_18 = allwinner.PD6  //
+	P1_19 = allwinner.PD2  // SPI1_MOSI
+	P1_20 = pin.GROUND     //
+	P1_21 = allwinner.PD3  // SPI1_MISO
+	P1_22 = allwinner.PD7  //
+	P1_23 = allwinner.PD1  // SPI1_CLK
+	P1_24 = allwinner.PD0  // SPI1_CS0
+	P1_25 = pin.GROUND     //
+	P1_26 = allwinner.PH10 //
+	P1_27 = allwinner.PL9  // S_I2C_SDA
+	P1_28 = allwinner.PL8  // S_I2C_SCL
+	P1_29 = allwinner.PH4  //
+	P1_30 = pin.GROUND     //
+	P1_31 = allwinner.PH5  //
+	P1_32 = allwinner.PL10 // S_PWM
+	P1_33 = allwinner.PB2  //
+	P1_34 = pin.GROUND     //
+	P1_35 = allwinner.PB4  // PCM0_SYNC
+	P1_36 = allwinner.PB3  //
+	P1_37 = allwinner.PD4  //
+	P1_38 = allwinner.PB7  // PCM0_DIN
+	P1_39 = pin.GROUND     //
+	P1_40 = allwinner.PB6  // PCM0_DOUT
+)
+
+//
+
+// dtIsCompatible is distro.DTIsCompatible, overridden in tests.
+var dtIsCompatible = distro.DTIsCompatible
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "bananapi"
+}
+
+func (d *driver) Prerequisites() []string {
+	return []string{"allwinner-gpio-pl"}
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("Banana Pi M64 board not detected")
+	}
+	if err := pinreg.Register("P1", [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bananapi
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/host/allwinner"
+)
+
+func TestPresent(t *testing.T) {
+	defer func(f func(...string) bool) { dtIsCompatible = f }(dtIsCompatible)
+	var compatible []string
+	dtIsCompatible = func(c ...string) bool {
+		compatible = c
+		return true
+	}
+	if p := Present(); p != isArm {
+		t.Fatal(p)
+	}
+	if isArm && (len(compatible) != 1 || compatible[0] != "sinovoip,bananapi-m64") {
+		t.Fatal(compatible)
+	}
+	dtIsCompatible = func(...string) bool { return false }
+	if Present() {
+		t.Fatal("Banana Pi is not present")
+	}
+}
+
+func TestDriver(t *testing.T) {
+	defer func(f func(...string) bool) { dtIsCompatible = f }(dtIsCompatible)
+	dtIsCompatible = func(...string) bool { return false }
+	d := driver{}
+	if s := d.String(); s != "bananapi" {
+		t.Fatal(s)
+	}
+	if p := d.Prerequisites(); len(p) != 1 || p[0] != "allwinner-gpio-pl" {
+		t.Fatal(p)
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("Banana Pi is not present")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header := []pin.Pin{
+		P1_1, P1_2, P1_3, P1_4, P1_5, P1_6, P1_7, P1_8, P1_9, P1_10,
+		P1_11, P1_12, P1_13, P1_14, P1_15, P1_16, P1_17, P1_18, P1_19, P1_20,
+		P1_21, P1_22, P1_23, P1_24, P1_25, P1_26, P1_27, P1_28, P1_29, P1_30,
+		P1_31, P1_32, P1_33, P1_34, P1_35, P1_36, P1_37, P1_38, P1_39, P1_40,
+	}
+	seen := map[string]bool{}
+	for i, p := range header {
+		if p == nil || p.Name() == "" {
+			t.Fatalf("P1_%d is not set", i+1)
+		}
+		if _, ok := p.(gpio.PinIO); !ok {
+			continue
+		}
+		if seen[p.Name()] {
+			t.Fatalf("P1_%d: %s is connected twice", i+1, p)
+		}
+		seen[p.Name()] = true
+	}
+	if len(seen) != 28 {
+		t.Fatal(len(seen))
+	}
+	if P1_3 != allwinner.PH3 || P1_27 != allwinner.PL9 || P1_40 != allwinner.PB6 {
+		t.Fatal(P1_3, P1_27, P1_40)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package bananapi contains the header definitions of the Banana Pi M64. It is
+// intrinsically related to package allwinner.
+//
+// The Banana Pi M64 uses the same Allwinner A64 CPU as the Pine64 and exposes
+// a Raspberry Pi 2 compatible 40 pins header as P1. The board is detected from
+// the device tree.
+//
+// Physical
+//
+// http://wiki.banana-pi.org/Banana_Pi_BPI-M64
+package bananapi
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"periph.io/x/periph"
 )
 
 var clockMemory *clockMap
@@ -17,6 +19,17 @@ var clockMemory *clockMap
 const (
 	clk19dot2MHz = 19200000
 	clk500MHz    = 500000000
+	// BCM2711.
+	clk54MHz  = 54000000
+	clk750MHz = 750000000
+)
+
+// Frequency in hertz of the oscillator and PLLD clock sources.
+//
+// They are updated by driverGPIO.Init() on the BCM2711.
+var (
+	clkOscHz  uint64 = clk19dot2MHz
+	clkPLLDHz uint64 = clk500MHz
 )
 
 const (
@@ -189,7 +202,7 @@ func findDivisorExact(srcHz, desiredHz uint64, maxWaitCycles int) (int, int) {
 // oversampling at 10x in the 1Mhz range becomes unreasonable in term of
 // memory usage.
 func findDivisorOversampled(srcHz, desiredHz uint64, maxWaitCycles int) (int, int, uint64) {
-	//log.Printf("findDivisorOversampled(%d, %d, %d)", srcHz, desiredHz, maxWaitCycles)
+	periph.Log().Debug("bcm283x-clock: findDivisorOversampled", "srcHz", srcHz, "desiredHz", desiredHz, "maxWaitCycles", maxWaitCycles)
 	// There are 2 reasons:
 	// - desiredHz is so low it is not possible to lower srcHz to this frequency
 	// - not a multiple, there's a need for a prime number
@@ -220,12 +233,12 @@ func calcSource(hz uint64, maxWaitCycles int) (clockCtl, int, int, uint64, error
 	}
 	// http://elinux.org/BCM2835_datasheet_errata states that clockSrc19dot2MHz
 	// is the cleanest clock source so try it first.
-	div, wait := findDivisorExact(clk19dot2MHz, hz, maxWaitCycles)
+	div, wait := findDivisorExact(clkOscHz, hz, maxWaitCycles)
 	if div != 0 {
 		return clockSrc19dot2MHz, div, wait, hz, nil
 	}
 	// Try 500Mhz.
-	div, wait = findDivisorExact(clk500MHz, hz, maxWaitCycles)
+	div, wait = findDivisorExact(clkPLLDHz, hz, maxWaitCycles)
 	if div != 0 {
 		return clockSrcPLLD, div, wait, hz, nil
 	}
@@ -233,8 +246,8 @@ func calcSource(hz uint64, maxWaitCycles int) (clockCtl, int, int, uint64, error
 	// Try with up to 10x oversampling. This is generally useful for lower
 	// frequencies, below 10kHz. Prefer the one with less oversampling. Only for
 	// non-aliased matches.
-	div19, wait19, hz19 := findDivisorOversampled(clk19dot2MHz, hz, maxWaitCycles)
-	div500, wait500, hz500 := findDivisorOversampled(clk500MHz, hz, maxWaitCycles)
+	div19, wait19, hz19 := findDivisorOversampled(clkOscHz, hz, maxWaitCycles)
+	div500, wait500, hz500 := findDivisorOversampled(clkPLLDHz, hz, maxWaitCycles)
 	if div19 != 0 && (div500 == 0 || hz19 < hz500) {
 		return clockSrc19dot2MHz, div19, wait19, hz19, nil
 	}
@@ -244,6 +257,26 @@ func calcSource(hz uint64, maxWaitCycles int) (clockCtl, int, int, uint64, error
 	return 0, 0, 0, 0, errors.New("failed to find a good clock")
 }
 
+// calcSourceFrac calculates the 12.12 fixed point divisor to derive the
+// desired clock from PLLD with MASH filtering.
+//
+// Returns the clock control, the divisor and the actual average frequency.
+func calcSourceFrac(hz uint64) (clockCtl, clockDiv, uint64, error) {
+	if hz < 1 {
+		return 0, 0, 0, fmt.Errorf("bcm283x-clock: desired frequency %dHz must be >1hz", hz)
+	}
+	// The MASH filter requires a minimum integer divisor of 2.
+	if hz > clkPLLDHz/2 || hz > 25*1000*1000 {
+		return 0, 0, 0, fmt.Errorf("bcm283x-clock: desired frequency %dHz is too high", hz)
+	}
+	div := ((clkPLLDHz << clockDiviShift) + hz/2) / hz
+	if div > uint64(clockDiviMask|clockDivfMask) {
+		return 0, 0, 0, fmt.Errorf("bcm283x-clock: desired frequency %dHz is too low", hz)
+	}
+	actual := ((clkPLLDHz << clockDiviShift) + div/2) / div
+	return clockSrcPLLD | clockMash1, clockDiv(div), actual, nil
+}
+
 // set changes the clock frequency to the desired value or the closest one
 // otherwise.
 //
@@ -276,13 +309,34 @@ func (c *clock) setRaw(ctl clockCtl, div int) error {
 	if ctl != clockSrc19dot2MHz && ctl != clockSrcPLLD {
 		return errors.New("invalid clock control")
 	}
+	return c.apply(ctl, clockDiv(div<<clockDiviShift))
+}
+
+// setFrac changes the clock frequency to the desired value by using the
+// fractional divider.
+//
+// The MASH noise shaping spreads the error over the cycles so the average
+// frequency is exact within a few ppm, at the cost of jitter. This is
+// acceptable for audio, where the sample rates are rarely an integer divisor
+// of the clock sources.
+//
+// Returns the actual clock used.
+func (c *clock) setFrac(hz uint64) (uint64, error) {
+	ctl, d, actual, err := calcSourceFrac(hz)
+	if err != nil {
+		return 0, err
+	}
+	return actual, c.apply(ctl, d)
+}
+
+// apply stops the clock, then restarts it with the new source and divisor.
+func (c *clock) apply(ctl clockCtl, d clockDiv) error {
 	// Stop the clock.
 	// TODO(maruel): Do not stop the clock if the current clock rate is the one
 	// desired.
 	for c.ctl&clockBusy != 0 {
 		c.ctl = clockPasswdCtl | clockKill
 	}
-	d := clockDiv(div << clockDiviShift)
 	c.div = clockPasswdDiv | d
 	Nanospin(10 * time.Nanosecond)
 	// Page 107
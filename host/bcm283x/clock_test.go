@@ -252,6 +252,33 @@ func TestCalcSource_inexact(t *testing.T) {
 	}
 }
 
+func TestCalcSource_2711(t *testing.T) {
+	defer func() {
+		clkOscHz = clk19dot2MHz
+		clkPLLDHz = clk500MHz
+	}()
+	clkOscHz = clk54MHz
+	clkPLLDHz = clk750MHz
+	data := []struct {
+		desiredHz     uint64
+		src           clockCtl
+		clkDiv, waits int
+	}{
+		{1000000, clockSrc19dot2MHz, 54, 1},
+		// The PWM base clock.
+		{25000000, clockSrcPLLD, 30, 1},
+	}
+	for i, line := range data {
+		src, clkDiv, waits, hz, err := calcSource(line.desiredHz, 1)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if src != line.src || clkDiv != line.clkDiv || waits != line.waits || hz != line.desiredHz {
+			t.Fatalf("#%d: %s %d %d %d", i, src, clkDiv, waits, hz)
+		}
+	}
+}
+
 func TestCalcSource_err(t *testing.T) {
 	if _, _, _, _, err := calcSource(0, dmaWaitcyclesMax+1); err == nil {
 		t.Fatal("0 hz")
@@ -261,6 +288,39 @@ func TestCalcSource_err(t *testing.T) {
 	}
 }
 
+func TestCalcSourceFrac(t *testing.T) {
+	data := []struct {
+		desiredHz uint64
+		div       clockDiv
+		hz        uint64
+	}{
+		{3072000, 162<<clockDiviShift | 3115, 3071998}, // 48kHz * 64
+		{2822400, 177<<clockDiviShift | 632, 2822398},  // 44.1kHz * 64
+		{512000, 976<<clockDiviShift | 2304, 512000},   // 8kHz * 64
+	}
+	for i, line := range data {
+		ctl, div, hz, err := calcSourceFrac(line.desiredHz)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if ctl != clockSrcPLLD|clockMash1 || div != line.div || hz != line.hz {
+			t.Fatalf("#%d: %s / %s = %dHz", i, ctl, div, hz)
+		}
+	}
+	for _, hz := range []uint64{0, 10000, 25000001} {
+		if _, _, _, err := calcSourceFrac(hz); err == nil {
+			t.Fatalf("%dHz", hz)
+		}
+	}
+	c := clock{}
+	if _, err := c.setFrac(0); err == nil {
+		t.Fatal("0 hz")
+	}
+	if _, err := c.setFrac(3072000); err == nil {
+		t.Fatal("it's not a register so it doesn't react")
+	}
+}
+
 func TestClock(t *testing.T) {
 	c := clock{}
 	if _, _, err := c.set(0, dmaWaitcyclesMax+1); err != nil {
@@ -45,9 +45,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiostream"
 	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/soc"
 	"periph.io/x/periph/host/videocore"
 )
 
@@ -472,8 +476,13 @@ func (c *controlBlock) initBlock(srcAddr, dstAddr, l uint32, srcIO, dstIO, srcIn
 		}
 	}
 	if dreq != dmaFire {
-		// dmaSrcDReq |
-		t |= dmaDstDReq | dreq | dmaTransferInfo(waits<<dmaWaitCyclesShift)
+		// The peripheral paces the reads when it is the source, e.g. a RX FIFO.
+		if srcIO {
+			t |= dmaSrcDReq
+		} else {
+			t |= dmaDstDReq
+		}
+		t |= dreq | dmaTransferInfo(waits<<dmaWaitCyclesShift)
 	}
 	c.transferInfo = t
 	// In bytes.
@@ -622,8 +631,7 @@ func pickChannel(blacklist ...int) (int, *dmaChannel) {
 		skip:
 		}
 	}
-	// Uncomment to understand the state of the DMA channels.
-	//fmt.Printf("%#v\n", dmaMemory)
+	periph.Log().Debug("bcm283x-dma: no channel available", "state", dmaMemory)
 	return -1, nil
 }
 
@@ -691,6 +699,226 @@ func startPWMbyDMA(p *Pin, rng, data uint32) (*dmaChannel, *videocore.Mem, error
 	return ch, buf, nil
 }
 
+// dmaWriteStreamPWM streams s on a PWM pin by feeding the PWM serialiser
+// FIFO with DMA.
+//
+// The serialiser is clocked at the stream resolution, so the output has no
+// jitter. It uses one bit of memory per sample. f is the alternate function
+// to select the PWM output on this pin.
+func dmaWriteStreamPWM(p *Pin, s gpiostream.Stream, f function) error {
+	pwm := p.pwmController()
+	hz := uint64(time.Second / s.Resolution())
+	actual, _, err := clockMemory.pwm.set(hz, 1)
+	if err != nil {
+		return err
+	}
+	if actual%hz != 0 {
+		return fmt.Errorf("can't attain %dHz exactly, got %dHz", hz, actual)
+	}
+	w, _, err := rasterBits(s, int(actual/hz))
+	if err != nil {
+		return err
+	}
+	// The control block is followed by the data.
+	cb, buf, err := allocateCB(32 + 4*len(w))
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+	copy(buf.Uint32()[8:], w)
+	physBuf := uint32(buf.PhysAddr())
+	if err := cb[0].initBlock(physBuf+32, pwmFIFOAddr(), uint32(4*len(w)), false, true, true, false, dmaPWM, 0); err != nil {
+		return err
+	}
+
+	// Bit shift for PWM0 and PWM1.
+	shift := uint((p.number & 1) * 8)
+	ctl := pwm1Serialiser | pwm1UseFIFO | pwm1Enable
+	last := w[len(w)-1]&1 != 0
+	if last {
+		// Idle at the level of the last sample.
+		ctl |= pwm1SilenceHigh
+	}
+	pwm.ctl &^= pwm1Mask << shift
+	pwm.ctl |= pwmClearFIFO
+	Nanospin(10 * time.Microsecond)
+	pwm.status = pwmBusErr | pwmGapo1 | pwmGapo2 | pwmGapo3 | pwmGapo4 | pwmRerr1 | pwmWerr1
+	if shift == 0 {
+		pwm.rng1 = 32
+	} else {
+		pwm.rng2 = 32
+	}
+	pwm.dmaCfg = pwmDMAEnable | pwmDMACfg(7<<pwmPanicShift|7)
+	Nanospin(10 * time.Microsecond)
+	pwm.ctl |= ctl << shift
+	p.setFunction(f)
+	err = runIO(buf, 4*len(w) <= maxLite)
+	if err == nil {
+		// Wait for the FIFO to drain, then for the last word to be shifted out.
+		for pwm.status&pwmEmpt1 == 0 {
+		}
+		Nanospin(32 * time.Second / time.Duration(actual))
+	}
+	pwm.dmaCfg = 0
+	// Hand the pin back to the GPIO block at the same level.
+	p.FastOut(gpio.Level(last))
+	p.setFunction(out)
+	pwm.ctl &^= pwm1Mask << shift
+	return err
+}
+
+// dmaWriteStreamEdges streams s on any pin by writing to the GPIO set and
+// clear registers with DMA.
+//
+// Each level change uses two control blocks: one unpaced write to the GPIO
+// register followed by one write per sample to the PWM FIFO, which paces the
+// transfer. This is very memory efficient for streams with long runs, like
+// servo control or DMX512, but it uses the PWM0 channel.
+func dmaWriteStreamEdges(p *Pin, s gpiostream.Stream) error {
+	hz := uint64(time.Second / s.Resolution())
+	actual, _, err := setPWMClockSource(hz, 1)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		pwmMemory.dmaCfg = 0
+		pwmMemory.ctl &^= pwm1Mask
+	}()
+	if actual%hz != 0 {
+		return fmt.Errorf("can't attain %dHz exactly, got %dHz", hz, actual)
+	}
+	first, runs, err := streamRuns(s, int(actual/hz))
+	if err != nil {
+		return err
+	}
+	// The control blocks are followed by the pin mask and a dummy word for
+	// pacing.
+	cbBytes := uint32(32)
+	maskOff := uint32(2*len(runs)) * cbBytes
+	cb, buf, err := allocateCB(int(maskOff) + 8)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+	u := buf.Uint32()
+	u[maskOff/4] = uint32(1) << uint(p.number&31)
+	u[maskOff/4+1] = 0
+	physBuf := uint32(buf.PhysAddr())
+	dest := [2]uint32{
+		gpioBaseAddr + 0x28 + 4*uint32(p.number/32), // clear
+		gpioBaseAddr + 0x1C + 4*uint32(p.number/32), // set
+	}
+	liteOk := true
+	l := first
+	for i, r := range runs {
+		d := dest[0]
+		if l {
+			d = dest[1]
+		}
+		if err := cb[2*i].initBlock(physBuf+maskOff, d, 4, false, true, false, false, dmaFire, 0); err != nil {
+			return err
+		}
+		cb[2*i].nextCB = physBuf + uint32(2*i+1)*cbBytes
+		if err := cb[2*i+1].initBlock(physBuf+maskOff+4, pwmFIFOAddr(), uint32(4*r), false, true, false, false, dmaPWM, 0); err != nil {
+			return err
+		}
+		if i != len(runs)-1 {
+			cb[2*i+1].nextCB = physBuf + uint32(2*i+2)*cbBytes
+		}
+		if 4*r > maxLite {
+			liteOk = false
+		}
+		l = !l
+	}
+	p.FastOut(gpio.Level(first))
+	p.setFunction(out)
+	return runIO(buf, liteOk)
+}
+
+// pwmFIFOAddr returns the physical address of the PWM FIFO register.
+func pwmFIFOAddr() uint32 {
+	return baseAddr + 0x20C000 + 0x18
+}
+
+// streamSamples returns the number of samples in s and a function to read
+// each sample.
+func streamSamples(s gpiostream.Stream) (int, func(i int) bool, error) {
+	switch x := s.(type) {
+	case *gpiostream.BitStreamMSB:
+		return 8 * len(x.Bits), func(i int) bool { return x.Bits[i/8]&(0x80>>uint(i&7)) != 0 }, nil
+	case *gpiostream.BitStreamLSB:
+		return 8 * len(x.Bits), func(i int) bool { return x.Bits[i/8]&(1<<uint(i&7)) != 0 }, nil
+	case *gpiostream.BitStream:
+		return 8 * len(x.Bits), func(i int) bool { return x.Bits[i/8]&(1<<uint(i&7)) != 0 }, nil
+	case *gpiostream.EdgeStream:
+		var levels []bool
+		l := true
+		for _, e := range x.Edges {
+			for n := (e + x.Res/2) / x.Res; n > 0; n-- {
+				levels = append(levels, l)
+			}
+			l = !l
+		}
+		return len(levels), func(i int) bool { return levels[i] }, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported stream type %T", s)
+	}
+}
+
+// rasterBits converts s into MSB-first 32 bits words, as expected by the PWM
+// serialiser.
+//
+// Each sample is repeated over times to compensate for an oversampled clock.
+// The last word is padded with the level of the last sample. Returns the
+// words and the number of samples, including oversampling.
+func rasterBits(s gpiostream.Stream, over int) ([]uint32, int, error) {
+	n, get, err := streamSamples(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n == 0 {
+		return nil, 0, errors.New("empty stream")
+	}
+	total := n * over
+	w := make([]uint32, (total+31)/32)
+	last := get(n - 1)
+	for i := 0; i < 32*len(w); i++ {
+		l := last
+		if i < total {
+			l = get(i / over)
+		}
+		if l {
+			w[i/32] |= 0x80000000 >> uint(i&31)
+		}
+	}
+	return w, total, nil
+}
+
+// streamRuns converts s into runs of identical levels.
+//
+// Each sample is repeated over times to compensate for an oversampled clock.
+// Returns the level of the first run and the length of each run in samples.
+func streamRuns(s gpiostream.Stream, over int) (bool, []int, error) {
+	n, get, err := streamSamples(s)
+	if err != nil {
+		return false, nil, err
+	}
+	if n == 0 {
+		return false, nil, errors.New("empty stream")
+	}
+	first := get(0)
+	runs := []int{0}
+	l := first
+	for i := 0; i < n; i++ {
+		if v := get(i); v != l {
+			runs = append(runs, 0)
+			l = v
+		}
+		runs[len(runs)-1] += over
+	}
+	return first, runs, nil
+}
+
 // physToUncachedPhys returns the uncached physical memory address backing a
 // physical memory address.
 //
@@ -798,12 +1026,30 @@ func (d *driverDMA) Init() (bool, error) {
 	if err := pmem.MapAsPOD(uint64(baseAddr+0x20C000), &pwmMemory); err != nil {
 		return true, err
 	}
+	if is2711 {
+		if err := pmem.MapAsPOD(uint64(baseAddr+0x20C800), &pwm1Memory); err != nil {
+			return true, err
+		}
+	}
 	if err := pmem.MapAsPOD(uint64(baseAddr+0x101000), &clockMemory); err != nil {
 		return true, err
 	}
 	if err := pmem.MapAsPOD(uint64(baseAddr+0x3000), &timerMemory); err != nil {
 		return true, err
 	}
+	p := []soc.Peripheral{
+		{Name: "DMA", Base: uint64(baseAddr + 0x7000)},
+		{Name: "PCM", Base: uint64(baseAddr + 0x203000)},
+		{Name: "PWM0", Base: uint64(baseAddr + 0x20C000)},
+		{Name: "CLK", Base: uint64(baseAddr + 0x101000)},
+		{Name: "TIMER", Base: uint64(baseAddr + 0x3000)},
+	}
+	if is2711 {
+		p = append(p, soc.Peripheral{Name: "PWM1", Base: uint64(baseAddr + 0x20C800)})
+	}
+	if err := soc.Register(soc.Info{Peripherals: p}); err != nil {
+		return true, err
+	}
 	return true, smokeTest()
 }
 
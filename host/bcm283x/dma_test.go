@@ -7,6 +7,9 @@ package bcm283x
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio/gpiostream"
 )
 
 func TestDmaStatus_String(t *testing.T) {
@@ -149,3 +152,69 @@ func TestStructSizes(t *testing.T) {
 	}
 
 }
+
+func TestRasterBits(t *testing.T) {
+	data := []struct {
+		s    gpiostream.Stream
+		over int
+		w    []uint32
+		n    int
+	}{
+		{&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0x80, 0x01}, Res: time.Microsecond}, 1, []uint32{0x8001FFFF}, 16},
+		{&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0xF0, 0x0F, 0xAA, 0x55}, Res: time.Microsecond}, 1, []uint32{0xF00FAA55}, 32},
+		// Padded with the last level.
+		{&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0x01}, Res: time.Microsecond}, 1, []uint32{0x01FFFFFF}, 8},
+		{&gpiostream.BitStreamLSB{Bits: gpiostream.BitsLSB{0x01, 0x02}, Res: time.Microsecond}, 1, []uint32{0x80400000}, 16},
+		{&gpiostream.BitStream{Bits: gpiostream.Bits{0x01}, Res: time.Microsecond}, 1, []uint32{0x80000000}, 8},
+		{&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0xA0, 0x00}, Res: time.Microsecond}, 3, []uint32{0xE3800000, 0}, 48},
+		{&gpiostream.EdgeStream{Edges: []time.Duration{2 * time.Microsecond, 3 * time.Microsecond, time.Microsecond}, Res: time.Microsecond}, 1, []uint32{0xC7FFFFFF}, 6},
+	}
+	for i, line := range data {
+		w, n, err := rasterBits(line.s, line.over)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(line.w, w) {
+			t.Fatalf("#%d: %#x != %#x", i, line.w, w)
+		}
+		if n != line.n {
+			t.Fatalf("#%d: %d != %d", i, line.n, n)
+		}
+	}
+}
+
+func TestRasterBits_fail(t *testing.T) {
+	if _, _, err := rasterBits(&gpiostream.BitStreamMSB{Res: time.Microsecond}, 1); err == nil {
+		t.Fatal("empty stream")
+	}
+	if _, _, err := rasterBits(&gpiostream.Program{}, 1); err == nil {
+		t.Fatal("unsupported stream")
+	}
+}
+
+func TestStreamRuns(t *testing.T) {
+	first, runs, err := streamRuns(&gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0x0F, 0xF1}, Res: time.Microsecond}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first {
+		t.Fatal("expected low")
+	}
+	if expected := []int{8, 16, 6, 2}; !reflect.DeepEqual(expected, runs) {
+		t.Fatalf("%v != %v", expected, runs)
+	}
+	// An EdgeStream starting with 0 starts low.
+	first, runs, err = streamRuns(&gpiostream.EdgeStream{Edges: []time.Duration{0, 2 * time.Millisecond, time.Millisecond}, Res: time.Millisecond}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first {
+		t.Fatal("expected low")
+	}
+	if expected := []int{2, 1}; !reflect.DeepEqual(expected, runs) {
+		t.Fatalf("%v != %v", expected, runs)
+	}
+	if _, _, err := streamRuns(&gpiostream.EdgeStream{Res: time.Millisecond}, 1); err == nil {
+		t.Fatal("empty stream")
+	}
+}
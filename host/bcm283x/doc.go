@@ -19,6 +19,9 @@
 // BCM2836:
 // https://www.raspberrypi.org/documentation/hardware/raspberrypi/bcm2836/QA7_rev3.4.pdf
 //
+// BCM2711, as found on the Raspberry Pi 4:
+// https://datasheets.raspberrypi.org/bcm2711/bcm2711-peripherals.pdf
+//
 // Another doc about PCM and PWM:
 // https://scribd.com/doc/127599939/BCM2835-Audio-clocks
 package bcm283x
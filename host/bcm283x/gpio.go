@@ -14,8 +14,10 @@ import (
 	"periph.io/x/periph"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiostream"
 	"periph.io/x/periph/host/distro"
 	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/soc"
 	"periph.io/x/periph/host/sysfs"
 )
 
@@ -75,13 +77,18 @@ var (
 // Present returns true if running on a Broadcom bcm283x based CPU.
 func Present() bool {
 	if isArm {
+		// The BCM2712 on the Raspberry Pi 5 has its GPIOs on the RP1 I/O
+		// controller, which is handled by host/rp1.
+		if distro.DTIsCompatible("brcm,bcm2712") {
+			return false
+		}
 		hardware, ok := distro.CPUInfo()["Hardware"]
 		return ok && strings.HasPrefix(hardware, "BCM")
 	}
 	return false
 }
 
-// Pin is a GPIO number (GPIOnn) on BCM238(5|6|7).
+// Pin is a GPIO number (GPIOnn) on BCM238(5|6|7) and BCM2711.
 //
 // Pin implements gpio.PinIO.
 type Pin struct {
@@ -206,7 +213,9 @@ func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
 		return err
 	}
 	p.setFunction(in)
-	if pull != gpio.PullNoChange {
+	if pull != gpio.PullNoChange && is2711 {
+		p.setPull2711(pull)
+	} else if pull != gpio.PullNoChange {
 		// Changing pull resistor requires a specific dance as described at
 		// https://www.raspberrypi.org/wp-content/uploads/2012/02/BCM2835-ARM-Peripherals.pdf
 		// page 101.
@@ -272,8 +281,21 @@ func (p *Pin) WaitForEdge(timeout time.Duration) bool {
 
 // Pull implemented gpio.PinIn.
 //
-// bcm283x doesn't support querying the pull resistor of any GPIO pin.
+// bcm283x doesn't support querying the pull resistor of any GPIO pin. The
+// BCM2711 does.
 func (p *Pin) Pull() gpio.Pull {
+	if is2711 && gpioMemory != nil {
+		off := p.number / 16
+		shift := uint(p.number%16) * 2
+		switch (gpioMemory.pullRegister[off] >> shift) & 3 {
+		case pull2711Up:
+			return gpio.PullUp
+		case pull2711Down:
+			return gpio.PullDown
+		case pull2711None:
+			return gpio.Float
+		}
+	}
 	// TODO(maruel): The best that could be added is to cache the last set value
 	// and return it.
 	return gpio.PullNoChange
@@ -332,6 +354,9 @@ func (p *Pin) FastOut(l gpio.Level) {
 // PWM0 and PWM1 share the same 25Mhz clock source. The period must be a
 // divisor of 25Mhz.
 //
+// On the BCM2711, pins 40 and 41 are driven by the second PWM controller,
+// which shares the same clock source.
+//
 // Clock pins
 //
 // Clock GPCLK0 is exposed on pins 4, 20 32 and 34.
@@ -392,7 +417,8 @@ func (p *Pin) PWM(duty gpio.Duty, period time.Duration) error {
 	if gpioMemory == nil {
 		return p.wrap(errors.New("subsystem not initialized"))
 	}
-	if pwmMemory == nil || clockMemory == nil {
+	pwm := p.pwmController()
+	if pwm == nil || clockMemory == nil {
 		return p.wrap(errors.New("bcm283x-dma not initialized; try again as root?"))
 	}
 
@@ -423,31 +449,64 @@ func (p *Pin) PWM(duty gpio.Duty, period time.Duration) error {
 		return nil
 	}
 
-	// TODO(maruel): Leverage oversampling.
-	base_freq := uint64(25 * 1000 * 1000) // 25MHz
-	// Total cycles in the period
-	rng := base_freq * uint64(period) / uint64(time.Second)
-	// Pulse width cycles
-	dat := uint32(rng * uint64(duty) / uint64(gpio.DutyMax))
-	if _, _, err := clockMemory.pwm.set(base_freq, 1); err != nil {
-		return p.wrap(err)
+	return p.setPWM(pwm, f, duty, period, 0)
+}
+
+// StreamOut streams a bit pattern on the pin and implements
+// gpiostream.PinOut.
+//
+// The samples are paced by the DMA controller, so the output is not affected
+// by the Linux scheduler and no kernel driver is needed. This makes it usable
+// for timing sensitive protocols like WS2812 or DMX512.
+//
+// The supported streams are gpiostream.BitStreamMSB, gpiostream.BitStreamLSB,
+// gpiostream.BitStream and gpiostream.EdgeStream. gpiostream.Program is not
+// supported.
+//
+// On the PWM0 and PWM1 pins, the PWM serialiser is fed by DMA, which is
+// jitter-free and uses one bit of memory per sample. On the other pins, the
+// GPIO registers are written by DMA paced by the PWM0 FIFO, which uses 64
+// bytes per level change. In both cases, PWM() must not be used at the same
+// time on the PWM channel used.
+//
+// The resolution must be attainable exactly by the PWM clock, possibly
+// oversampled. For example 2.4MHz (WS2812 at 800kHz) and 250kHz (DMX512)
+// are. The pin is left as an output at the level of the last sample.
+//
+// This can only be used if the driver "bcm283x-dma" was loaded. It can only
+// be loaded if the process has root level access.
+func (p *Pin) StreamOut(s gpiostream.Stream) error {
+	if gpioMemory == nil {
+		return p.wrap(errors.New("subsystem not initialized"))
 	}
-	p.usingClock = true
-	// Bit shift for PWM0 and PWM1
-	shift := uint((p.number & 1) * 8)
-	if shift == 0 {
-		pwmMemory.rng1 = uint32(rng)
-		Nanospin(10 * time.Nanosecond)
-		pwmMemory.dat1 = uint32(dat)
-	} else {
-		pwmMemory.rng2 = uint32(rng)
-		Nanospin(10 * time.Nanosecond)
-		pwmMemory.dat2 = uint32(dat)
+	if dmaMemory == nil || pwmMemory == nil || clockMemory == nil {
+		return p.wrap(errors.New("bcm283x-dma not initialized; try again as root?"))
+	}
+	if res := s.Resolution(); res <= 0 || res > time.Second {
+		return p.wrap(fmt.Errorf("invalid stream resolution %s", res))
+	}
+	if err := p.Halt(); err != nil {
+		return err
+	}
+	var err error
+	switch p.number {
+	case 12, 13, 45:
+		err = dmaWriteStreamPWM(p, s, alt0)
+	case 40, 41:
+		if is2711 {
+			// Driven by the second PWM controller, which uses a different DREQ.
+			err = dmaWriteStreamEdges(p, s)
+		} else {
+			err = dmaWriteStreamPWM(p, s, alt0)
+		}
+	case 18, 19:
+		err = dmaWriteStreamPWM(p, s, alt5)
+	default:
+		err = dmaWriteStreamEdges(p, s)
+	}
+	if err != nil {
+		return p.wrap(err)
 	}
-	Nanospin(10 * time.Nanosecond)
-	old := pwmMemory.ctl
-	pwmMemory.ctl = (old & ^(0xff << shift)) | ((pwm1Enable | pwm1MS) << shift)
-	p.setFunction(f)
 	return nil
 }
 
@@ -484,13 +543,13 @@ func (p *Pin) haltClock() error {
 		return nil
 
 	// PWMx
-	case 12, 13, 18, 19, 40, 45:
+	case 12, 13, 18, 19, 40, 41, 45:
 		if _, _, err := clockMemory.pwm.set(0, 0); err != nil {
 			return p.wrap(err)
 		}
 		// Bit shift for PWM0 and PWM1.
 		shift := uint((p.number & 1) * 8)
-		pwmMemory.ctl &= ^(0xff << shift)
+		p.pwmController().ctl &= ^(0xff << shift)
 		return nil
 
 	default:
@@ -500,6 +559,62 @@ func (p *Pin) haltClock() error {
 	}
 }
 
+// pwmController returns the PWM controller driving the pin.
+func (p *Pin) pwmController() *pwmMap {
+	if is2711 && (p.number == 40 || p.number == 41) {
+		return pwm1Memory
+	}
+	return pwmMemory
+}
+
+// setPWM sets the PWM controller channel of the pin and selects the function
+// f on the pin.
+//
+// pol is pwm1Polarity to invert the output, 0 otherwise.
+func (p *Pin) setPWM(pwm *pwmMap, f function, duty gpio.Duty, period time.Duration, pol pwmControl) error {
+	// TODO(maruel): Leverage oversampling.
+	base_freq := uint64(25 * 1000 * 1000) // 25MHz
+	// Total cycles in the period
+	rng := base_freq * uint64(period) / uint64(time.Second)
+	// Pulse width cycles
+	dat := uint32(rng * uint64(duty) / uint64(gpio.DutyMax))
+	if _, _, err := clockMemory.pwm.set(base_freq, 1); err != nil {
+		return p.wrap(err)
+	}
+	p.usingClock = true
+	// Bit shift for PWM0 and PWM1
+	shift := uint((p.number & 1) * 8)
+	if shift == 0 {
+		pwm.rng1 = uint32(rng)
+		Nanospin(10 * time.Nanosecond)
+		pwm.dat1 = uint32(dat)
+	} else {
+		pwm.rng2 = uint32(rng)
+		Nanospin(10 * time.Nanosecond)
+		pwm.dat2 = uint32(dat)
+	}
+	Nanospin(10 * time.Nanosecond)
+	old := pwm.ctl
+	pwm.ctl = (old & ^(0xff << shift)) | ((pwm1Enable | pwm1MS | pol) << shift)
+	p.setFunction(f)
+	return nil
+}
+
+// setPull2711 sets the pull resistor on the BCM2711, which doesn't need the
+// GPPUD dance.
+func (p *Pin) setPull2711(pull gpio.Pull) {
+	v := pull2711None
+	switch pull {
+	case gpio.PullDown:
+		v = pull2711Down
+	case gpio.PullUp:
+		v = pull2711Up
+	}
+	off := p.number / 16
+	shift := uint(p.number%16) * 2
+	gpioMemory.pullRegister[off] = (gpioMemory.pullRegister[off] &^ (3 << shift)) | (v << shift)
+}
+
 // function returns the current GPIO pin function.
 func (p *Pin) function() function {
 	if gpioMemory == nil {
@@ -533,6 +648,13 @@ const (
 	alt5 function = 2
 )
 
+// Values of the BCM2711 GPIO_PUP_PDN_CNTRL_REGx registers, 2 bits per pin.
+const (
+	pull2711None uint32 = 0
+	pull2711Up   uint32 = 1
+	pull2711Down uint32 = 2
+)
+
 var (
 	// baseAddr is the base for all the CPU registers.
 	//
@@ -545,6 +667,10 @@ var (
 	gpioMemory *gpioMap
 	// gpioBaseAddr is needed for DMA transfers.
 	gpioBaseAddr uint32
+	// is2711 is true when running on a BCM2711, as found on the Raspberry Pi 4.
+	//
+	// It is initialized by driverGPIO.Init().
+	is2711 bool
 )
 
 // cpuPins is all the pins as supported by the CPU. There is no guarantee that
@@ -599,8 +725,14 @@ var cpuPins = []Pin{
 	{number: 46, name: "GPIO46", defaultPull: gpio.PullUp},
 }
 
-// This excludes the functions in and out.
-var mapping = [][6]string{
+// mapping is the alternate functions of the CPU in use.
+//
+// It is set to mapping2711 by driverGPIO.Init() on the BCM2711.
+var mapping = mapping2835
+
+// mapping2835 is the alternate functions on BCM283x. This excludes the
+// functions in and out.
+var mapping2835 = [][6]string{
 	{"I2C0_SDA"}, // 0
 	{"I2C0_SCL"},
 	{"I2C1_SDA"},
@@ -651,6 +783,62 @@ var mapping = [][6]string{
 	{""},
 }
 
+// mapping2711 is the alternate functions on the BCM2711, which adds the I²C3
+// to I²C6, SPI3 to SPI6 and UART2 to UART5 instances. SPI2 is gone and the
+// pins 40 and 41 are driven by the second PWM controller.
+//
+// https://datasheets.raspberrypi.org/bcm2711/bcm2711-peripherals.pdf
+// pages 77-81.
+var mapping2711 = [][6]string{
+	{"I2C0_SDA", "", "", "SPI3_CS0", "UART2_TXD", "I2C6_SDA"}, // 0
+	{"I2C0_SCL", "", "", "SPI3_MISO", "UART2_RXD", "I2C6_SCL"},
+	{"I2C1_SDA", "", "", "SPI3_MOSI", "UART2_CTS", "I2C3_SDA"},
+	{"I2C1_SCL", "", "", "SPI3_CLK", "UART2_RTS", "I2C3_SCL"},
+	{"GPCLK0", "", "", "SPI4_CS0", "UART3_TXD", "I2C3_SDA"},
+	{"GPCLK1", "", "", "SPI4_MISO", "UART3_RXD", "I2C3_SCL"}, // 5
+	{"GPCLK2", "", "", "SPI4_MOSI", "UART3_CTS", "I2C4_SDA"},
+	{"SPI0_CS1", "", "", "SPI4_CLK", "UART3_RTS", "I2C4_SCL"},
+	{"SPI0_CS0", "", "", "", "UART4_TXD", "I2C4_SDA"},
+	{"SPI0_MISO", "", "", "", "UART4_RXD", "I2C4_SCL"},
+	{"SPI0_MOSI", "", "", "", "UART4_CTS", "I2C5_SDA"}, // 10
+	{"SPI0_CLK", "", "", "", "UART4_RTS", "I2C5_SCL"},
+	{"PWM0_OUT", "", "", "SPI5_CS0", "UART5_TXD", "I2C5_SDA"},
+	{"PWM1_OUT", "", "", "SPI5_MISO", "UART5_RXD", "I2C5_SCL"},
+	{"UART0_TXD", "", "", "SPI5_MOSI", "UART5_CTS", "UART1_TXD"},
+	{"UART0_RXD", "", "", "SPI5_CLK", "UART5_RTS", "UART1_RXD"}, // 15
+	{"", "", "", "UART0_CTS", "SPI1_CS2", "UART1_CTS"},
+	{"", "", "", "UART0_RTS", "SPI1_CS1", "UART1_RTS"},
+	{"PCM_CLK", "", "", "SPI6_CS0", "SPI1_CS0", "PWM0_OUT"},
+	{"PCM_FS", "", "", "SPI6_MISO", "SPI1_MISO", "PWM1_OUT"},
+	{"PCM_DIN", "", "", "SPI6_MOSI", "SPI1_MOSI", "GPCLK0"}, // 20
+	{"PCM_DOUT", "", "", "SPI6_CLK", "SPI1_CLK", "GPCLK1"},
+	{"", "", "", "", "", "I2C6_SDA"},
+	{"", "", "", "", "", "I2C6_SCL"},
+	{"", "", "", "", "", "SPI3_CS1"},
+	{"", "", "", "", "", "SPI4_CS1"}, // 25
+	{"", "", "", "", "", "SPI5_CS1"},
+	{"", "", "", "", "", "SPI6_CS1"},
+	{"I2C0_SDA", "", "PCM_CLK", "", "", ""},
+	{"I2C0_SCL", "", "PCM_FS", "", "", ""},
+	{"", "", "PCM_DIN", "UART0_CTS", "", "UART1_CTS"}, // 30
+	{"", "", "PCM_DOUT", "UART0_RTS", "", "UART1_RTS"},
+	{"GPCLK0", "", "", "UART0_TXD", "", "UART1_TXD"},
+	{"", "", "", "UART0_RXD", "", "UART1_RXD"},
+	{"GPCLK0"},
+	{"SPI0_CS1"}, // 35
+	{"SPI0_CS0", "", "UART0_TXD", "", "", ""},
+	{"SPI0_MISO", "", "UART0_RXD", "", "", ""},
+	{"SPI0_MOSI", "", "UART0_RTS", "", "", ""},
+	{"SPI0_CLK", "", "UART0_CTS", "", "", ""},
+	{"PWM0_OUT", "", "", "", "SPI0_MISO", "UART1_TXD"}, // 40
+	{"PWM1_OUT", "", "", "", "SPI0_MOSI", "UART1_RXD"},
+	{"GPCLK1", "", "", "", "SPI0_CLK", "UART1_RTS"},
+	{"GPCLK2", "", "", "", "SPI0_CS0", "UART1_CTS"},
+	{"GPCLK1", "I2C0_SDA", "I2C1_SDA", "", "SPI0_CS1", ""},
+	{"PWM1_OUT", "I2C0_SCL", "I2C1_SCL", "", "SPI0_CS2", ""}, // 45
+	{""},
+}
+
 // function specifies the active functionality of a pin. The alternative
 // function is GPIO pin dependent.
 type function uint8
@@ -725,7 +913,15 @@ type gpioMap struct {
 	pullEnableClock [2]uint32 // GPPUDCLK0-GPPUDCLK1
 	// 0xA0    -    Reserved
 	dummy uint32
-	// 0xB0    -    Test (byte)
+	// 0xA4    -    Reserved up to 0xE0; 0xB0 is Test (byte)
+	dummy11 [16]uint32
+	// BCM2711 only, bcm2711-peripherals.pdf page 84; GPPUD and GPPUDCLKx are
+	// not functional on this chip.
+	// 0xE4    RW   GPIO Pull-up / Pull-down Register 0 (GPIO0-15)
+	// 0xE8    RW   GPIO Pull-up / Pull-down Register 1 (GPIO16-31)
+	// 0xEC    RW   GPIO Pull-up / Pull-down Register 2 (GPIO32-47)
+	// 0xF0    RW   GPIO Pull-up / Pull-down Register 3 (GPIO48-57)
+	pullRegister [4]uint32 // GPIO_PUP_PDN_CNTRL_REG0-GPIO_PUP_PDN_CNTRL_REG3
 }
 
 func init() {
@@ -812,13 +1008,26 @@ func (d *driverGPIO) Init() (bool, error) {
 		return false, errors.New("bcm283x CPU not detected")
 	}
 	model := distro.CPUInfo()["model name"]
-	if strings.Contains(model, "ARMv6") {
+	socModel := "BCM2836"
+	if is2711 = detect2711(); is2711 {
+		// RPi4; bcm2711-peripherals.pdf page 5, in "Low Peripheral" mode.
+		baseAddr = 0xFE000000
+		dramBus = 0xC0000000
+		clkOscHz = clk54MHz
+		clkPLLDHz = clk750MHz
+		mapping = mapping2711
+		socModel = "BCM2711"
+	} else if strings.Contains(model, "ARMv6") {
 		baseAddr = 0x20000000
 		dramBus = 0x40000000
+		socModel = "BCM2835"
 	} else {
 		// RPi2+
 		baseAddr = 0x3F000000
 		dramBus = 0xC0000000
+		if distro.DTIsCompatible("brcm,bcm2837") {
+			socModel = "BCM2837"
+		}
 	}
 	// Page 6.
 	// Virtual addresses in kernel mode will range between 0xC0000000 and
@@ -855,6 +1064,13 @@ func (d *driverGPIO) Init() (bool, error) {
 	if err := m.AsPOD(&gpioMemory); err != nil {
 		return true, err
 	}
+	if err := soc.Register(soc.Info{
+		Model:       socModel,
+		Peripherals: []soc.Peripheral{{Name: "GPIO", Base: uint64(gpioBaseAddr)}},
+		PWM:         []string{"GPIO12", "GPIO13", "GPIO18", "GPIO19", "GPIO40", "GPIO41", "GPIO45"},
+	}); err != nil {
+		return true, err
+	}
 
 	functions := map[string]struct{}{}
 	for i := range cpuPins {
@@ -883,9 +1099,20 @@ func (d *driverGPIO) Init() (bool, error) {
 		"SPI1_CS0": 18,
 		"SPI1_CS1": 17,
 		"SPI1_CS2": 16,
-		"SPI2_CS0": 43,
-		"SPI2_CS1": 44,
-		"SPI2_CS2": 45,
+	}
+	if is2711 {
+		csMap["SPI3_CS0"] = 0
+		csMap["SPI3_CS1"] = 24
+		csMap["SPI4_CS0"] = 4
+		csMap["SPI4_CS1"] = 25
+		csMap["SPI5_CS0"] = 12
+		csMap["SPI5_CS1"] = 26
+		csMap["SPI6_CS0"] = 18
+		csMap["SPI6_CS1"] = 27
+	} else {
+		csMap["SPI2_CS0"] = 43
+		csMap["SPI2_CS1"] = 44
+		csMap["SPI2_CS2"] = 45
 	}
 	if GPIO11.Function() == "SPI0_CLK" {
 		csMap["SPI0_CS1"] = 7
@@ -902,6 +1129,14 @@ func (d *driverGPIO) Init() (bool, error) {
 	return true, sysfs.SetSpeedHook(setSpeed)
 }
 
+// detect2711 returns true if the device tree declares a BCM2711.
+//
+// /proc/cpuinfo is not reliable since the kernel reports BCM2835 for all the
+// Raspberry Pi models.
+func detect2711() bool {
+	return distro.DTIsCompatible("brcm,bcm2711")
+}
+
 func setSpeed(hz int64) error {
 	// Writing to "/sys/module/i2c_bcm2708/parameters/baudrate" was confirmed to
 	// not work.
@@ -930,3 +1165,4 @@ var _ gpio.PinIO = &Pin{}
 var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
 var _ gpio.PinPWM = &Pin{}
+var _ gpiostream.PinOut = &Pin{}
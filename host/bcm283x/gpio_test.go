@@ -7,8 +7,10 @@ package bcm283x
 import (
 	"testing"
 	"time"
+	"unsafe"
 
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiostream"
 )
 
 func TestPresent(t *testing.T) {
@@ -137,6 +139,97 @@ func TestPin(t *testing.T) {
 	}
 }
 
+func TestPin_2711(t *testing.T) {
+	defer func() {
+		gpioMemory = nil
+		is2711 = false
+		mapping = mapping2835
+	}()
+	gpioMemory = &gpioMap{}
+	is2711 = true
+	mapping = mapping2711
+
+	p := Pin{name: "Foo", number: 17, defaultPull: gpio.PullDown}
+	if err := p.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.PullUp {
+		t.Fatal(d)
+	}
+	if v := gpioMemory.pullRegister[1]; v != 1<<2 {
+		t.Fatalf("%#x", v)
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.PullDown {
+		t.Fatal(d)
+	}
+	if err := p.In(gpio.Float, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.Float {
+		t.Fatal(d)
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.Float {
+		t.Fatal(d)
+	}
+	gpioMemory.pullRegister[1] = 3 << 2
+	if d := p.Pull(); d != gpio.PullNoChange {
+		t.Fatal(d)
+	}
+	if gpioMemory.pullEnable != 0 || gpioMemory.pullEnableClock[0] != 0 {
+		t.Fatal("GPPUD must not be used")
+	}
+
+	p.number = 0
+	p.setFunction(alt5)
+	if s := p.Function(); s != "I2C6_SDA" {
+		t.Fatal(s)
+	}
+	p.setFunction(alt3)
+	if s := p.Function(); s != "SPI3_CS0" {
+		t.Fatal(s)
+	}
+	p.setFunction(alt4)
+	if s := p.Function(); s != "UART2_TXD" {
+		t.Fatal(s)
+	}
+
+	pwmMemory = &pwmMap{}
+	pwm1Memory = &pwmMap{}
+	defer func() {
+		pwmMemory = nil
+		pwm1Memory = nil
+	}()
+	for _, n := range []int{12, 13, 18, 19, 45} {
+		if p := (Pin{number: n}); p.pwmController() != pwmMemory {
+			t.Fatal(n)
+		}
+	}
+	for _, n := range []int{40, 41} {
+		if p := (Pin{number: n}); p.pwmController() != pwm1Memory {
+			t.Fatal(n)
+		}
+	}
+	is2711 = false
+	if p := (Pin{number: 40}); p.pwmController() != pwmMemory {
+		t.Fatal("BCM2835 has a single PWM controller")
+	}
+}
+
+func TestGPIOMap(t *testing.T) {
+	if o := unsafe.Offsetof(gpioMap{}.pullEnable); o != 0x94 {
+		t.Fatalf("%#x", o)
+	}
+	if o := unsafe.Offsetof(gpioMap{}.pullRegister); o != 0xE4 {
+		t.Fatalf("%#x", o)
+	}
+}
+
 func TestPinPWM(t *testing.T) {
 	defer func() {
 		clockMemory = nil
@@ -165,6 +258,33 @@ func TestPinPWM(t *testing.T) {
 	}
 }
 
+func TestPinStreamOut(t *testing.T) {
+	defer func() {
+		clockMemory = nil
+		dmaMemory = nil
+		gpioMemory = nil
+		pwmMemory = nil
+	}()
+
+	p := Pin{name: "C1", number: 4, defaultPull: gpio.PullDown}
+	s := &gpiostream.BitStreamMSB{Bits: gpiostream.BitsMSB{0xAA}, Res: time.Microsecond}
+	if err := p.StreamOut(s); err == nil || err.Error() != "bcm283x-gpio (C1): subsystem not initialized" {
+		t.Fatal(err)
+	}
+
+	gpioMemory = &gpioMap{}
+	if err := p.StreamOut(s); err == nil || err.Error() != "bcm283x-gpio (C1): bcm283x-dma not initialized; try again as root?" {
+		t.Fatal(err)
+	}
+
+	clockMemory = &clockMap{}
+	dmaMemory = &dmaMap{}
+	pwmMemory = &pwmMap{}
+	if err := p.StreamOut(&gpiostream.BitStreamMSB{}); err == nil || err.Error() != "bcm283x-gpio (C1): invalid stream resolution 0s" {
+		t.Fatal(err)
+	}
+}
+
 func TestDriver(t *testing.T) {
 	d := driverGPIO{}
 	if s := d.String(); s != "bcm283x-gpio" {
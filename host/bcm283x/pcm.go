@@ -6,6 +6,141 @@
 
 package bcm283x
 
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2s"
+)
+
+// PCM is the I²S port of the PCM audio controller.
+//
+// It is an I²S master on GPIO18 (PCM_CLK), GPIO19 (PCM_FS), GPIO20 (PCM_DIN)
+// and GPIO21 (PCM_DOUT). The frames are 64 bit clocks long, 32 per channel,
+// which is supported by most DACs and MEMS microphones. The samples are
+// transferred to and from the FIFO with DMA, so the driver "bcm283x-dma" must
+// be loaded, which requires running as root.
+//
+// The sound card driver snd_bcm2835 or a device tree I²S overlay must not be
+// loaded as they use the same controller.
+type PCM struct {
+	mu        sync.Mutex
+	connected bool
+	f         i2s.Format
+	untrack   func()
+}
+
+// NewPCM returns the I²S port.
+//
+// Only one instance can be opened at a time.
+func NewPCM() (*PCM, error) {
+	if pcmMemory == nil || clockMemory == nil || dmaMemory == nil {
+		return nil, errors.New("bcm283x-pcm: subsystem PCM not initialized")
+	}
+	pcmMu.Lock()
+	defer pcmMu.Unlock()
+	if pcmOpened {
+		return nil, errors.New("bcm283x-pcm: already opened")
+	}
+	pcmOpened = true
+	p := &PCM{}
+	p.untrack = periph.Track(p)
+	return p, nil
+}
+
+func (p *PCM) String() string {
+	return "PCM"
+}
+
+// Close stops the controller and its clock and releases the pins.
+func (p *PCM) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pcmMu.Lock()
+	defer pcmMu.Unlock()
+	if !pcmOpened {
+		return nil
+	}
+	pcmOpened = false
+	if p.untrack != nil {
+		p.untrack()
+		p.untrack = nil
+	}
+	pcmMemory.controlStatus = 0
+	_, _, err := clockMemory.pcm.set(0, 1)
+	if p.connected {
+		for _, pin := range pcmPins() {
+			pin.setFunction(in)
+		}
+	}
+	return err
+}
+
+// Connect implements i2s.Port.
+//
+// The actual sample rate is within a few ppm of the requested one.
+func (p *PCM) Connect(f i2s.Format) (i2s.Conn, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.connected {
+		return nil, errors.New("bcm283x-pcm: Connect cannot be called twice")
+	}
+	mode, txc, rxc := pcmRegisters(f)
+	pcmMemory.controlStatus = 0
+	if _, err := clockMemory.pcm.setFrac(uint64(f.SampleRate) * pcmFrameBits); err != nil {
+		return nil, err
+	}
+	pcmMemory.mode = mode
+	pcmMemory.txc = txc
+	pcmMemory.rxc = rxc
+	pcmMemory.dreq = pcmDreq(0x10<<pcmDreqTXPanicShift | 0x30<<pcmDreqRXPanicShift | 0x30<<pcmDreqTXLevelShift | 0x20<<pcmDreqRXLevelShift)
+	pcmMemory.controlStatus = pcmEnable
+	// The FIFOs take 2 PCM clocks to be cleared.
+	pcmMemory.controlStatus = pcmEnable | pcmTXClear | pcmRXClear
+	Nanospin(4 * time.Second / time.Duration(uint64(f.SampleRate)*pcmFrameBits))
+	pcmMemory.controlStatus = pcmEnable | pcmDMAEnable | pcmRXSignExtend
+	for _, pin := range pcmPins() {
+		pin.setFunction(alt0)
+	}
+	p.connected = true
+	p.f = f
+	return &pcmConn{p: p}, nil
+}
+
+// CLK implements i2s.Pins.
+func (p *PCM) CLK() gpio.PinOut {
+	return GPIO18
+}
+
+// FS implements i2s.Pins.
+func (p *PCM) FS() gpio.PinOut {
+	return GPIO19
+}
+
+// DIN implements i2s.Pins.
+func (p *PCM) DIN() gpio.PinIn {
+	return GPIO20
+}
+
+// DOUT implements i2s.Pins.
+func (p *PCM) DOUT() gpio.PinOut {
+	return GPIO21
+}
+
+//
+
+var (
+	pcmMu     sync.Mutex
+	pcmOpened bool
+)
+
 var pcmMemory *pcmMap
 
 type pcmCS uint32
@@ -166,3 +301,163 @@ type pcmMap struct {
 	intstc        pcmIntStatus // INTSTC_A
 	gray          pcmGray      // GRAY
 }
+
+// pcmFrameBits is the number of bit clocks per frame; 32 per channel.
+const pcmFrameBits = 64
+
+type pcmConn struct {
+	p *PCM
+}
+
+func (c *pcmConn) String() string {
+	return c.p.String()
+}
+
+func (c *pcmConn) Format() i2s.Format {
+	return c.p.f
+}
+
+// Write plays the samples in b and blocks until they are shifted out.
+func (c *pcmConn) Write(b []byte) (int, error) {
+	if err := c.check(b); err != nil {
+		return 0, err
+	}
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	w := pcmPack(c.p.f, b)
+	// The control block is followed by the data.
+	cb, buf, err := allocateCB(32 + 4*len(w))
+	if err != nil {
+		return 0, err
+	}
+	defer buf.Close()
+	copy(buf.Uint32()[8:], w)
+	physBuf := uint32(buf.PhysAddr())
+	if err := cb[0].initBlock(physBuf+32, pcmFIFOAddr(), uint32(4*len(w)), false, true, true, false, dmaPCMTX, 0); err != nil {
+		return 0, err
+	}
+	pcmMemory.controlStatus |= pcmTXEnable
+	err = runIO(buf, 4*len(w) <= maxLite)
+	if err == nil {
+		// Wait for the FIFO to drain.
+		for pcmMemory.controlStatus&pcmTXEmpty == 0 {
+		}
+	}
+	pcmMemory.controlStatus &^= pcmTXEnable
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read records samples until b is filled.
+func (c *pcmConn) Read(b []byte) (int, error) {
+	if err := c.check(b); err != nil {
+		return 0, err
+	}
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	n := len(b) / c.p.f.SampleSize()
+	// The control block is followed by the data.
+	cb, buf, err := allocateCB(32 + 4*n)
+	if err != nil {
+		return 0, err
+	}
+	defer buf.Close()
+	physBuf := uint32(buf.PhysAddr())
+	if err := cb[0].initBlock(pcmFIFOAddr(), physBuf+32, uint32(4*n), true, false, false, true, dmaPCMRX, 0); err != nil {
+		return 0, err
+	}
+	pcmMemory.controlStatus |= pcmRXClear
+	pcmMemory.controlStatus |= pcmRXEnable
+	err = runIO(buf, 4*n <= maxLite)
+	pcmMemory.controlStatus &^= pcmRXEnable
+	if err != nil {
+		return 0, err
+	}
+	pcmUnpack(c.p.f, buf.Uint32()[8:8+n], b)
+	return len(b), nil
+}
+
+func (c *pcmConn) CLK() gpio.PinOut {
+	return c.p.CLK()
+}
+
+func (c *pcmConn) FS() gpio.PinOut {
+	return c.p.FS()
+}
+
+func (c *pcmConn) DIN() gpio.PinIn {
+	return c.p.DIN()
+}
+
+func (c *pcmConn) DOUT() gpio.PinOut {
+	return c.p.DOUT()
+}
+
+// check returns an error if b doesn't contain a whole number of frames.
+func (c *pcmConn) check(b []byte) error {
+	if s := c.p.f.FrameSize(); len(b)%s != 0 {
+		return fmt.Errorf("bcm283x-pcm: buffer of %d bytes is not a multiple of the frame size %d", len(b), s)
+	}
+	return nil
+}
+
+// pcmRegisters returns the register values to stream f as I²S.
+//
+// In I²S, the frame sync is low for the left channel and the data is delayed
+// by one clock; it changes on the falling edge of the clock and is sampled on
+// the rising edge.
+func pcmRegisters(f i2s.Format) (pcmMode, pcmTX, pcmRX) {
+	mode := pcmClockInverted | pcmFSInverted | pcmMode((pcmFrameBits-1)<<pcmFrameLengthShift) | pcmMode(pcmFrameBits/2)
+	// The width is 8 + WID + 16*WEX.
+	w := uint32(f.BitsPerSample - 8)
+	wex := w >> 4
+	wid := w & 0xF
+	const pos1, pos2 = 1, pcmFrameBits/2 + 1
+	txc := pcmTX1Enable | pcmTX(wex<<31|pos1<<pcmTX1PosShift|wid<<16)
+	rxc := pcmRX1Enable | pcmRX(wex<<31|pos1<<pcmRX1PosShift|wid<<16)
+	if f.Channels == 2 {
+		txc |= pcmTX2Enable | pcmTX(wex<<15|pos2<<pcmTX2PosShift|wid)
+		rxc |= pcmRX2Enable | pcmRX(wex<<15|pos2<<pcmRX2PosShift|wid)
+	}
+	return mode, txc, rxc
+}
+
+// pcmPack converts the little endian samples in b into FIFO words.
+func pcmPack(f i2s.Format, b []byte) []uint32 {
+	s := f.SampleSize()
+	w := make([]uint32, len(b)/s)
+	for i := range w {
+		for j := 0; j < s; j++ {
+			w[i] |= uint32(b[i*s+j]) << uint(8*j)
+		}
+	}
+	return w
+}
+
+// pcmUnpack converts the sign extended FIFO words in w into little endian
+// samples in b.
+func pcmUnpack(f i2s.Format, w []uint32, b []byte) {
+	s := f.SampleSize()
+	for i := range w {
+		for j := 0; j < s; j++ {
+			b[i*s+j] = byte(w[i] >> uint(8*j))
+		}
+	}
+}
+
+// pcmFIFOAddr returns the physical address of the PCM FIFO register.
+func pcmFIFOAddr() uint32 {
+	return baseAddr + 0x203000 + 0x4
+}
+
+// pcmPins returns the pins used by the PCM controller as an I²S master.
+func pcmPins() []*Pin {
+	return []*Pin{GPIO18, GPIO19, GPIO20, GPIO21}
+}
+
+var _ i2s.PortCloser = &PCM{}
+var _ i2s.Pins = &PCM{}
+var _ i2s.Conn = &pcmConn{}
+var _ i2s.Pins = &pcmConn{}
@@ -0,0 +1,48 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bcm283x
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn/i2s"
+)
+
+func TestNewPCM(t *testing.T) {
+	if _, err := NewPCM(); err == nil {
+		t.Fatal("subsystem not initialized")
+	}
+}
+
+func TestPCMRegisters(t *testing.T) {
+	mode, txc, rxc := pcmRegisters(i2s.Format{SampleRate: 48000, BitsPerSample: 16, Channels: 2})
+	if mode != 0x50FC20 {
+		t.Fatalf("0x%x", mode)
+	}
+	if txc != 0x40184218 || rxc != 0x40184218 {
+		t.Fatalf("0x%x 0x%x", txc, rxc)
+	}
+	_, txc, rxc = pcmRegisters(i2s.Format{SampleRate: 16000, BitsPerSample: 32, Channels: 1})
+	if txc != 0xC0180000 || rxc != 0xC0180000 {
+		t.Fatalf("0x%x 0x%x", txc, rxc)
+	}
+}
+
+func TestPCMPack(t *testing.T) {
+	f := i2s.Format{SampleRate: 48000, BitsPerSample: 24, Channels: 2}
+	b := []byte{0x01, 0x02, 0x03, 0xFF, 0xFF, 0xFF}
+	w := pcmPack(f, b)
+	if !reflect.DeepEqual(w, []uint32{0x030201, 0xFFFFFF}) {
+		t.Fatalf("%#v", w)
+	}
+	out := make([]byte, len(b))
+	// The controller sign extends the received samples.
+	pcmUnpack(f, []uint32{0x030201, 0xFFFFFFFF}, out)
+	if !bytes.Equal(out, b) {
+		t.Fatalf("%#v", out)
+	}
+}
@@ -6,7 +6,11 @@ package bcm283x
 
 import (
 	"errors"
+	"sync"
 	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pwm"
 )
 
 // Page 138
@@ -20,6 +24,104 @@ import (
 // good enough to generate a DAC.
 var pwmMemory *pwmMap
 
+// pwm1Memory is the second PWM controller, only present on the BCM2711.
+var pwm1Memory *pwmMap
+
+// PWMChannel is a channel of a PWM controller routed to a pin.
+//
+// Contrary to Pin.PWM(), it supports the Inversed polarity and never falls
+// back to a GPCLK clock.
+type PWMChannel struct {
+	p *Pin
+	f function
+
+	mu sync.Mutex
+	s  pwm.State
+}
+
+// NewPWMChannel returns the PWM controller channel routed to a pin.
+//
+// The pin must be one of GPIO12, GPIO13, GPIO18, GPIO19, GPIO40, GPIO41 or
+// GPIO45. GPIO12, GPIO18 and GPIO40 are on the channel 0, the others on the
+// channel 1. Pins on the same channel output the same signal.
+func NewPWMChannel(p *Pin) (*PWMChannel, error) {
+	f := alt0
+	switch p.number {
+	case 12, 13, 40, 41, 45:
+	case 18, 19:
+		f = alt5
+	default:
+		return nil, p.wrap(errors.New("pwm is not supported on this pin"))
+	}
+	return &PWMChannel{p: p, f: f}, nil
+}
+
+func (c *PWMChannel) String() string {
+	return c.p.name
+}
+
+// Halt implements conn.Resource.
+//
+// It disables the channel and keeps the polarity.
+func (c *PWMChannel) Halt() error {
+	return c.Apply(pwm.State{Polarity: c.State().Polarity})
+}
+
+// Apply implements pwm.Channel.
+//
+// A disabled channel is set as a GPIO output at its inactive level.
+func (c *PWMChannel) Apply(s pwm.State) error {
+	if err := s.Validate(); err != nil {
+		return c.p.wrap(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.Period == 0 {
+		s.Period = c.s.Period
+	}
+	if !s.Enabled {
+		l := gpio.Low
+		if s.Polarity == pwm.Inversed {
+			l = gpio.High
+		}
+		if err := c.p.Out(l); err != nil {
+			return err
+		}
+		c.s = s
+		return nil
+	}
+	if s.Period == 0 {
+		return c.p.wrap(errors.New("period must be specified"))
+	}
+	if s.Period < 500*time.Nanosecond {
+		// See Pin.PWM().
+		return c.p.wrap(errors.New("period must be at least 500ns"))
+	}
+	if gpioMemory == nil {
+		return c.p.wrap(errors.New("subsystem not initialized"))
+	}
+	ctl := c.p.pwmController()
+	if ctl == nil || clockMemory == nil {
+		return c.p.wrap(errors.New("bcm283x-dma not initialized; try again as root?"))
+	}
+	var pol pwmControl
+	if s.Polarity == pwm.Inversed {
+		pol = pwm1Polarity
+	}
+	if err := c.p.setPWM(ctl, c.f, s.Duty, s.Period, pol); err != nil {
+		return err
+	}
+	c.s = s
+	return nil
+}
+
+// State implements pwm.Channel.
+func (c *PWMChannel) State() pwm.State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.s
+}
+
 // PWENi is used to enable/disable the corresponding channel. Setting this bit
 // to 1 enables the channel and transmitter state machine. All registers and
 // FIFO is writable without setting this bit.
@@ -246,3 +348,5 @@ func setPWMClockSource(hz uint64, div uint32) (uint64, int, error) {
 	// Convert divisor into wait cycles.
 	return actual, divs - 1, err
 }
+
+var _ pwm.Channel = &PWMChannel{}
@@ -4,7 +4,13 @@
 
 package bcm283x
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pwm"
+)
 
 func TestPWMMap(t *testing.T) {
 	p := pwmMap{}
@@ -20,3 +26,52 @@ func TestPWMMap(t *testing.T) {
 		t.Fatal("clockMemory is nil")
 	}
 }
+
+func TestPWMChannel(t *testing.T) {
+	defer func() {
+		clockMemory = nil
+		gpioMemory = nil
+		pwmMemory = nil
+	}()
+	if _, err := NewPWMChannel(&Pin{name: "GPIO4", number: 4}); err == nil {
+		t.Fatal("GPCLK0 is not a PWM channel")
+	}
+	c, err := NewPWMChannel(&Pin{name: "GPIO18", number: 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "GPIO18" {
+		t.Fatal(s)
+	}
+	s := pwm.State{Period: time.Millisecond, Duty: gpio.DutyHalf, Enabled: true}
+	if err := c.Apply(s); err == nil || err.Error() != "bcm283x-gpio (GPIO18): subsystem not initialized" {
+		t.Fatal(err)
+	}
+	gpioMemory = &gpioMap{}
+	if err := c.Apply(s); err == nil || err.Error() != "bcm283x-gpio (GPIO18): bcm283x-dma not initialized; try again as root?" {
+		t.Fatal(err)
+	}
+	if err := c.Apply(pwm.State{Duty: gpio.DutyHalf, Enabled: true}); err == nil || err.Error() != "bcm283x-gpio (GPIO18): period must be specified" {
+		t.Fatal(err)
+	}
+	if err := c.Apply(pwm.State{Period: 499 * time.Nanosecond, Enabled: true}); err == nil {
+		t.Fatal("period too short")
+	}
+	if err := c.Apply(pwm.State{Duty: -1}); err == nil {
+		t.Fatal("invalid duty")
+	}
+
+	// A disabled Inversed channel outputs high.
+	if err := c.Apply(pwm.State{Period: time.Millisecond, Polarity: pwm.Inversed}); err != nil {
+		t.Fatal(err)
+	}
+	if gpioMemory.outputSet[0] != 1<<18 {
+		t.Fatal(gpioMemory.outputSet)
+	}
+	if err := c.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.State(); st != (pwm.State{Period: time.Millisecond, Polarity: pwm.Inversed}) {
+		t.Fatal(st)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package beagle
+
+import (
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+)
+
+// All the individual pins on the BeagleBone AI headers.
+//
+// The GPIOs are set once the driver is initialized on a BeagleBone AI.
+var (
+	P8_1  pin.Pin    = pin.GROUND   //
+	P8_2  pin.Pin    = pin.GROUND   //
+	P8_3  gpio.PinIO = gpio.INVALID // GPIO24
+	P8_4  gpio.PinIO = gpio.INVALID // GPIO25
+	P8_5  gpio.PinIO = gpio.INVALID // GPIO193
+	P8_6  gpio.PinIO = gpio.INVALID // GPIO194
+	P8_7  gpio.PinIO = gpio.INVALID // GPIO165
+	P8_8  gpio.PinIO = gpio.INVALID // GPIO166
+	P8_9  gpio.PinIO = gpio.INVALID // GPIO178
+	P8_10 gpio.PinIO = gpio.INVALID // GPIO164
+	P8_11 gpio.PinIO = gpio.INVALID // GPIO75
+	P8_12 gpio.PinIO = gpio.INVALID // GPIO74
+	P8_13 gpio.PinIO = gpio.INVALID // GPIO107
+	P8_14 gpio.PinIO = gpio.INVALID // GPIO109
+	P8_15 gpio.PinIO = gpio.INVALID // GPIO99
+	P8_16 gpio.PinIO = gpio.INVALID // GPIO125
+	P8_17 gpio.PinIO = gpio.INVALID // GPIO242
+	P8_18 gpio.PinIO = gpio.INVALID // GPIO105
+	P8_19 gpio.PinIO = gpio.INVALID // GPIO106
+	P8_20 gpio.PinIO = gpio.INVALID // GPIO190
+	P8_21 gpio.PinIO = gpio.INVALID // GPIO189
+	P8_22 gpio.PinIO = gpio.INVALID // GPIO23
+	P8_23 gpio.PinIO = gpio.INVALID // GPIO22
+	P8_24 gpio.PinIO = gpio.INVALID // GPIO192
+	P8_25 gpio.PinIO = gpio.INVALID // GPIO191
+	P8_26 gpio.PinIO = gpio.INVALID // GPIO124
+	P8_27 gpio.PinIO = gpio.INVALID // GPIO119
+	P8_28 gpio.PinIO = gpio.INVALID // GPIO115
+	P8_29 gpio.PinIO = gpio.INVALID // GPIO118
+	P8_30 gpio.PinIO = gpio.INVALID // GPIO116
+	P8_31 gpio.PinIO = gpio.INVALID // GPIO238
+	P8_32 gpio.PinIO = gpio.INVALID // GPIO239
+	P8_33 gpio.PinIO = gpio.INVALID // GPIO237
+	P8_34 gpio.PinIO = gpio.INVALID // GPIO235
+	P8_35 gpio.PinIO = gpio.INVALID // GPIO236
+	P8_36 gpio.PinIO = gpio.INVALID // GPIO234
+	P8_37 gpio.PinIO = gpio.INVALID // GPIO232
+	P8_38 gpio.PinIO = gpio.INVALID // GPIO233
+	P8_39 gpio.PinIO = gpio.INVALID // GPIO230
+	P8_40 gpio.PinIO = gpio.INVALID // GPIO231
+	P8_41 gpio.PinIO = gpio.INVALID // GPIO228
+	P8_42 gpio.PinIO = gpio.INVALID // GPIO229
+	P8_43 gpio.PinIO = gpio.INVALID // GPIO226
+	P8_44 gpio.PinIO = gpio.INVALID // GPIO227
+	P8_45 gpio.PinIO = gpio.INVALID // GPIO224
+	P8_46 gpio.PinIO = gpio.INVALID // GPIO225
+
+	P9_1  pin.Pin    = pin.GROUND   //
+	P9_2  pin.Pin    = pin.GROUND   //
+	P9_3  pin.Pin    = pin.V3_3     //
+	P9_4  pin.Pin    = pin.V3_3     //
+	P9_5  pin.Pin    = pin.V5       //
+	P9_6  pin.Pin    = pin.V5       //
+	P9_7  pin.Pin    = pin.V5       //
+	P9_8  pin.Pin    = pin.V5       //
+	P9_9  pin.Pin    = PWR_BUT      //
+	P9_10 pin.Pin    = RESET        //
+	P9_11 gpio.PinIO = gpio.INVALID // GPIO241
+	P9_12 gpio.PinIO = gpio.INVALID // GPIO128
+	P9_13 gpio.PinIO = gpio.INVALID // GPIO172
+	P9_14 gpio.PinIO = gpio.INVALID // GPIO121
+	P9_15 gpio.PinIO = gpio.INVALID // GPIO76
+	P9_16 gpio.PinIO = gpio.INVALID // GPIO122
+	P9_17 gpio.PinIO = gpio.INVALID // GPIO209
+	P9_18 gpio.PinIO = gpio.INVALID // GPIO208
+	P9_19 gpio.PinIO = gpio.INVALID // GPIO195
+	P9_20 gpio.PinIO = gpio.INVALID // GPIO196
+	P9_21 gpio.PinIO = gpio.INVALID // GPIO3
+	P9_22 gpio.PinIO = gpio.INVALID // GPIO2
+	P9_23 gpio.PinIO = gpio.INVALID // GPIO203
+	P9_24 gpio.PinIO = gpio.INVALID // GPIO175
+	P9_25 gpio.PinIO = gpio.INVALID // GPIO177
+	P9_26 gpio.PinIO = gpio.INVALID // GPIO174
+	P9_27 gpio.PinIO = gpio.INVALID // GPIO111
+	P9_28 gpio.PinIO = gpio.INVALID // GPIO113
+	P9_29 gpio.PinIO = gpio.INVALID // GPIO139
+	P9_30 gpio.PinIO = gpio.INVALID // GPIO140
+	P9_31 gpio.PinIO = gpio.INVALID // GPIO138
+	P9_32 pin.Pin    = AIN_VREFP    //
+	P9_33 pin.Pin    = AIN4         //
+	P9_34 pin.Pin    = pin.GROUND   //
+	P9_35 pin.Pin    = AIN6         //
+	P9_36 pin.Pin    = AIN5         //
+	P9_37 pin.Pin    = AIN2         //
+	P9_38 pin.Pin    = AIN3         //
+	P9_39 pin.Pin    = AIN0         //
+	P9_40 pin.Pin    = AIN1         //
+	P9_41 gpio.PinIO = gpio.INVALID // GPIO180
+	P9_42 gpio.PinIO = gpio.INVALID // GPIO114
+	P9_43 pin.Pin    = pin.GROUND   //
+	P9_44 pin.Pin    = pin.GROUND   //
+	P9_45 pin.Pin    = pin.GROUND   //
+	P9_46 pin.Pin    = pin.GROUND   //
+)
+
+//
+
+// bbaiGPIOs is the GPIO number of each header pin.
+var bbaiGPIOs = []headerGPIO{
+	{&P8_3, 24},
+	{&P8_4, 25},
+	{&P8_5, 193},
+	{&P8_6, 194},
+	{&P8_7, 165},
+	{&P8_8, 166},
+	{&P8_9, 178},
+	{&P8_10, 164},
+	{&P8_11, 75},
+	{&P8_12, 74},
+	{&P8_13, 107},
+	{&P8_14, 109},
+	{&P8_15, 99},
+	{&P8_16, 125},
+	{&P8_17, 242},
+	{&P8_18, 105},
+	{&P8_19, 106},
+	{&P8_20, 190},
+	{&P8_21, 189},
+	{&P8_22, 23},
+	{&P8_23, 22},
+	{&P8_24, 192},
+	{&P8_25, 191},
+	{&P8_26, 124},
+	{&P8_27, 119},
+	{&P8_28, 115},
+	{&P8_29, 118},
+	{&P8_30, 116},
+	{&P8_31, 238},
+	{&P8_32, 239},
+	{&P8_33, 237},
+	{&P8_34, 235},
+	{&P8_35, 236},
+	{&P8_36, 234},
+	{&P8_37, 232},
+	{&P8_38, 233},
+	{&P8_39, 230},
+	{&P8_40, 231},
+	{&P8_41, 228},
+	{&P8_42, 229},
+	{&P8_43, 226},
+	{&P8_44, 227},
+	{&P8_45, 224},
+	{&P8_46, 225},
+	{&P9_11, 241},
+	{&P9_12, 128},
+	{&P9_13, 172},
+	{&P9_14, 121},
+	{&P9_15, 76},
+	{&P9_16, 122},
+	{&P9_17, 209},
+	{&P9_18, 208},
+	{&P9_19, 195},
+	{&P9_20, 196},
+	{&P9_21, 3},
+	{&P9_22, 2},
+	{&P9_23, 203},
+	{&P9_24, 175},
+	{&P9_25, 177},
+	{&P9_26, 174},
+	{&P9_27, 111},
+	{&P9_28, 113},
+	{&P9_29, 139},
+	{&P9_30, 140},
+	{&P9_31, 138},
+	{&P9_41, 180},
+	{&P9_42, 114},
+}
+
+// bbaiHeaders returns the headers to register.
+func bbaiHeaders() []header {
+	return []header{
+		{"P8", [][]pin.Pin{
+			{P8_1, P8_2},
+			{P8_3, P8_4},
+			{P8_5, P8_6},
+			{P8_7, P8_8},
+			{P8_9, P8_10},
+			{P8_11, P8_12},
+			{P8_13, P8_14},
+			{P8_15, P8_16},
+			{P8_17, P8_18},
+			{P8_19, P8_20},
+			{P8_21, P8_22},
+			{P8_23, P8_24},
+			{P8_25, P8_26},
+			{P8_27, P8_28},
+			{P8_29, P8_30},
+			{P8_31, P8_32},
+			{P8_33, P8_34},
+			{P8_35, P8_36},
+			{P8_37, P8_38},
+			{P8_39, P8_40},
+			{P8_41, P8_42},
+			{P8_43, P8_44},
+			{P8_45, P8_46},
+		}},
+		{"P9", [][]pin.Pin{
+			{P9_1, P9_2},
+			{P9_3, P9_4},
+			{P9_5, P9_6},
+			{P9_7, P9_8},
+			{P9_9, P9_10},
+			{P9_11, P9_12},
+			{P9_13, P9_14},
+			{P9_15, P9_16},
+			{P9_17, P9_18},
+			{P9_19, P9_20},
+			{P9_21, P9_22},
+			{P9_23, P9_24},
+			{P9_25, P9_26},
+			{P9_27, P9_28},
+			{P9_29, P9_30},
+			{P9_31, P9_32},
+			{P9_33, P9_34},
+			{P9_35, P9_36},
+			{P9_37, P9_38},
+			{P9_39, P9_40},
+			{P9_41, P9_42},
+			{P9_43, P9_44},
+			{P9_45, P9_46},
+		}},
+	}
+}
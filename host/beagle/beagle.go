@@ -0,0 +1,167 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package beagle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+// Present returns true if running on a PocketBeagle or a BeagleBone AI.
+//
+// It looks for the board in /proc/device-tree/compatible.
+func Present() bool {
+	return detect() != ""
+}
+
+// The pins that are not GPIOs on the headers.
+var (
+	AIN0      = &pin.BasicPin{N: "AIN0"}
+	AIN1      = &pin.BasicPin{N: "AIN1"}
+	AIN2      = &pin.BasicPin{N: "AIN2"}
+	AIN3      = &pin.BasicPin{N: "AIN3"}
+	AIN4      = &pin.BasicPin{N: "AIN4"}
+	AIN5      = &pin.BasicPin{N: "AIN5"}
+	AIN6      = &pin.BasicPin{N: "AIN6"}
+	AIN7      = &pin.BasicPin{N: "AIN7"}
+	AIN_VREFN = &pin.BasicPin{N: "AIN_VREF-"}
+	AIN_VREFP = &pin.BasicPin{N: "AIN_VREF+"}
+	BAT_TEMP  = &pin.BasicPin{N: "BAT_TEMP"}
+	PWR_BUT   = &pin.BasicPin{N: "PWR_BUT"}
+	RESET     = &pin.BasicPin{N: "RESET"}
+
+	USB1_DN       = &pin.BasicPin{N: "USB1_DN"}
+	USB1_DP       = &pin.BasicPin{N: "USB1_DP"}
+	USB1_ID       = &pin.BasicPin{N: "USB1_ID"}
+	USB1_VBUS_IN  = &pin.BasicPin{N: "USB1_VBUS_IN"}
+	USB1_VBUS_OUT = &pin.BasicPin{N: "USB1_VBUS_OUT"}
+	USB1_VIN      = &pin.BasicPin{N: "USB1_VIN"}
+)
+
+// SetPinmux changes the function of a header pin, e.g.
+// SetPinmux("P1_08", "spi_sclk").
+//
+// It uses the pinmux helper exposed by the BeagleBoard.org kernels as
+// /sys/devices/platform/ocp/ocp:<pin>_pinmux/state, so no cape manager or
+// device tree overlay is needed. The valid states depend on the pin; "gpio",
+// "gpio_pu" and "gpio_pd" are always supported on the pins that have a
+// helper.
+func SetPinmux(name, state string) error {
+	f, err := os.OpenFile(pinmuxPath(name), os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("beagle: %s has no pinmux helper", name)
+		}
+		return fmt.Errorf("beagle: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(state); err != nil {
+		return fmt.Errorf("beagle: failed to set %s to %q: %v", name, state, err)
+	}
+	return nil
+}
+
+//
+
+// Device tree compatible strings of the supported boards.
+const (
+	boardPocketBeagle = "ti,am335x-pocketbeagle"
+	boardBBAI         = "beagle,am5729-beagleboneai"
+)
+
+// pinmuxRoot is the directory containing the pinmux helpers.
+var pinmuxRoot = "/sys/devices/platform/ocp"
+
+func pinmuxPath(name string) string {
+	return fmt.Sprintf("%s/ocp:%s_pinmux/state", pinmuxRoot, name)
+}
+
+// detect returns the device tree compatible string of the board, or "" if
+// not running on a supported board.
+func detect() string {
+	if isArm {
+		return distro.DTMatch(boardPocketBeagle, boardBBAI)
+	}
+	return ""
+}
+
+// headerGPIO is a header pin connected to a GPIO.
+//
+// The GPIOs are numbered globally like with sysfs; GPIO n is line n%32 of
+// /dev/gpiochip<n/32>.
+type headerGPIO struct {
+	p      *gpio.PinIO
+	number int
+}
+
+// header is a header to register with pinreg.
+type header struct {
+	name string
+	pins [][]pin.Pin
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "beagle"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	var gpios []headerGPIO
+	var headers func() []header
+	switch detect() {
+	case boardPocketBeagle:
+		gpios, headers = pocketBeagleGPIOs, pocketBeagleHeaders
+	case boardBBAI:
+		gpios, headers = bbaiGPIOs, bbaiHeaders
+	default:
+		return false, errors.New("PocketBeagle or BeagleBone AI board not detected")
+	}
+	chips := map[int]*gpioioctl.Chip{}
+	for _, h := range gpios {
+		c := chips[h.number/32]
+		if c == nil {
+			var err error
+			if c, err = gpioioctl.Open(fmt.Sprintf("/dev/gpiochip%d", h.number/32)); err != nil {
+				return true, err
+			}
+			chips[h.number/32] = c
+		}
+		p := c.PinNumber(h.number%32, h.number, fmt.Sprintf("GPIO%d", h.number), gpio.PullNoChange)
+		if err := gpioreg.Register(p, true); err != nil {
+			return true, err
+		}
+		*h.p = p
+	}
+	for _, h := range headers() {
+		if err := pinreg.RegisterHeader(h.name, pinreg.Header{Pins: h.pins, Voltage: 3300}); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,9 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !arm,!arm64
+
+package beagle
+
+const isArm = false
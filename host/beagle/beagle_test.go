@@ -0,0 +1,68 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package beagle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "beagle" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("board is not present")
+	}
+}
+
+func TestSetPinmux(t *testing.T) {
+	d, err := ioutil.TempDir("", "beagle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	defer func(r string) { pinmuxRoot = r }(pinmuxRoot)
+	pinmuxRoot = d
+
+	if err := SetPinmux("P1_08", "spi_sclk"); err == nil {
+		t.Fatal("no pinmux helper")
+	}
+	p := filepath.Join(d, "ocp:P1_08_pinmux")
+	if err := os.Mkdir(p, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(p, "state"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPinmux("P1_08", "spi_sclk"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(p, "state")); err != nil || string(b) != "spi_sclk" {
+		t.Fatal(string(b), err)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	for _, gpios := range [][]headerGPIO{pocketBeagleGPIOs, bbaiGPIOs} {
+		seen := map[int]bool{}
+		for _, h := range gpios {
+			if seen[h.number] {
+				t.Fatalf("GPIO%d used twice", h.number)
+			}
+			seen[h.number] = true
+		}
+	}
+}
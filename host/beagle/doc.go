@@ -0,0 +1,25 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package beagle contains the header definitions of the PocketBeagle and the
+// BeagleBone AI.
+//
+// The GPIOs are accessed through the GPIO character device via package
+// gpioioctl, so no root access is needed as long as the user has access to
+// /dev/gpiochipN. They keep the global sysfs numbering, e.g. P1_02 on the
+// PocketBeagle is GPIO87, which is line 23 of /dev/gpiochip2.
+//
+// The PocketBeagle headers are exposed as P1 and P2, the BeagleBone AI
+// headers as P8 and P9. The GPIOs of the other board stay INVALID.
+//
+// The pin functions can be changed at runtime with SetPinmux on kernels that
+// provide the pinmux helpers, without using the cape manager. The PRUs of
+// the PocketBeagle are controlled with package pru.
+//
+// Physical
+//
+// https://github.com/beagleboard/pocketbeagle/wiki/System-Reference-Manual
+//
+// https://github.com/beagleboard/beaglebone-ai/wiki/System-Reference-Manual
+package beagle
@@ -0,0 +1,185 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package beagle
+
+import (
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+)
+
+// All the individual pins on the PocketBeagle headers.
+//
+// The GPIOs are set once the driver is initialized on a PocketBeagle.
+var (
+	P1_1  pin.Pin    = pin.DC_IN     //
+	P1_2  gpio.PinIO = gpio.INVALID  // GPIO87
+	P1_3  pin.Pin    = USB1_VBUS_OUT //
+	P1_4  gpio.PinIO = gpio.INVALID  // GPIO89
+	P1_5  pin.Pin    = USB1_VBUS_IN  //
+	P1_6  gpio.PinIO = gpio.INVALID  // GPIO5, SPI0_CS0
+	P1_7  pin.Pin    = USB1_VIN      //
+	P1_8  gpio.PinIO = gpio.INVALID  // GPIO2, SPI0_CLK
+	P1_9  pin.Pin    = USB1_DN       //
+	P1_10 gpio.PinIO = gpio.INVALID  // GPIO3, SPI0_MISO
+	P1_11 pin.Pin    = USB1_DP       //
+	P1_12 gpio.PinIO = gpio.INVALID  // GPIO4, SPI0_MOSI
+	P1_13 pin.Pin    = USB1_ID       //
+	P1_14 pin.Pin    = pin.V3_3      //
+	P1_15 pin.Pin    = pin.GROUND    //
+	P1_16 pin.Pin    = pin.GROUND    //
+	P1_17 pin.Pin    = AIN_VREFN     //
+	P1_18 pin.Pin    = AIN_VREFP     //
+	P1_19 pin.Pin    = AIN0          //
+	P1_20 gpio.PinIO = gpio.INVALID  // GPIO20
+	P1_21 pin.Pin    = AIN1          //
+	P1_22 pin.Pin    = pin.GROUND    //
+	P1_23 pin.Pin    = AIN2          //
+	P1_24 pin.Pin    = pin.V5        //
+	P1_25 pin.Pin    = AIN3          //
+	P1_26 gpio.PinIO = gpio.INVALID  // GPIO12, I2C2_SDA
+	P1_27 pin.Pin    = AIN4          //
+	P1_28 gpio.PinIO = gpio.INVALID  // GPIO13, I2C2_SCL
+	P1_29 gpio.PinIO = gpio.INVALID  // GPIO117
+	P1_30 gpio.PinIO = gpio.INVALID  // GPIO43, UART0_TX
+	P1_31 gpio.PinIO = gpio.INVALID  // GPIO114
+	P1_32 gpio.PinIO = gpio.INVALID  // GPIO42, UART0_RX
+	P1_33 gpio.PinIO = gpio.INVALID  // GPIO111
+	P1_34 gpio.PinIO = gpio.INVALID  // GPIO26
+	P1_35 gpio.PinIO = gpio.INVALID  // GPIO88
+	P1_36 gpio.PinIO = gpio.INVALID  // GPIO110, PWM0A
+
+	P2_1  gpio.PinIO = gpio.INVALID // GPIO50, PWM1A
+	P2_2  gpio.PinIO = gpio.INVALID // GPIO59
+	P2_3  gpio.PinIO = gpio.INVALID // GPIO23, PWM2A
+	P2_4  gpio.PinIO = gpio.INVALID // GPIO58
+	P2_5  gpio.PinIO = gpio.INVALID // GPIO30, UART4_RX
+	P2_6  gpio.PinIO = gpio.INVALID // GPIO57
+	P2_7  gpio.PinIO = gpio.INVALID // GPIO31, UART4_TX
+	P2_8  gpio.PinIO = gpio.INVALID // GPIO60
+	P2_9  gpio.PinIO = gpio.INVALID // GPIO15, I2C1_SCL
+	P2_10 gpio.PinIO = gpio.INVALID // GPIO52
+	P2_11 gpio.PinIO = gpio.INVALID // GPIO14, I2C1_SDA
+	P2_12 pin.Pin    = PWR_BUT      //
+	P2_13 pin.Pin    = pin.V5       //
+	P2_14 pin.Pin    = pin.BAT_PLUS //
+	P2_15 pin.Pin    = pin.GROUND   //
+	P2_16 pin.Pin    = BAT_TEMP     //
+	P2_17 gpio.PinIO = gpio.INVALID // GPIO65
+	P2_18 gpio.PinIO = gpio.INVALID // GPIO47
+	P2_19 gpio.PinIO = gpio.INVALID // GPIO27
+	P2_20 gpio.PinIO = gpio.INVALID // GPIO64
+	P2_21 pin.Pin    = pin.GROUND   //
+	P2_22 gpio.PinIO = gpio.INVALID // GPIO46
+	P2_23 pin.Pin    = pin.V3_3     //
+	P2_24 gpio.PinIO = gpio.INVALID // GPIO48
+	P2_25 gpio.PinIO = gpio.INVALID // GPIO41, SPI1_MOSI
+	P2_26 pin.Pin    = RESET        //
+	P2_27 gpio.PinIO = gpio.INVALID // GPIO40, SPI1_MISO
+	P2_28 gpio.PinIO = gpio.INVALID // GPIO116
+	P2_29 gpio.PinIO = gpio.INVALID // GPIO7, SPI1_CLK
+	P2_30 gpio.PinIO = gpio.INVALID // GPIO113
+	P2_31 gpio.PinIO = gpio.INVALID // GPIO19, SPI1_CS1
+	P2_32 gpio.PinIO = gpio.INVALID // GPIO112
+	P2_33 gpio.PinIO = gpio.INVALID // GPIO45
+	P2_34 gpio.PinIO = gpio.INVALID // GPIO115
+	P2_35 gpio.PinIO = gpio.INVALID // GPIO86, AIN5
+	P2_36 pin.Pin    = AIN7         //
+)
+
+//
+
+// pocketBeagleGPIOs is the GPIO number of each header pin.
+var pocketBeagleGPIOs = []headerGPIO{
+	{&P1_2, 87},
+	{&P1_4, 89},
+	{&P1_6, 5},
+	{&P1_8, 2},
+	{&P1_10, 3},
+	{&P1_12, 4},
+	{&P1_20, 20},
+	{&P1_26, 12},
+	{&P1_28, 13},
+	{&P1_29, 117},
+	{&P1_30, 43},
+	{&P1_31, 114},
+	{&P1_32, 42},
+	{&P1_33, 111},
+	{&P1_34, 26},
+	{&P1_35, 88},
+	{&P1_36, 110},
+	{&P2_1, 50},
+	{&P2_2, 59},
+	{&P2_3, 23},
+	{&P2_4, 58},
+	{&P2_5, 30},
+	{&P2_6, 57},
+	{&P2_7, 31},
+	{&P2_8, 60},
+	{&P2_9, 15},
+	{&P2_10, 52},
+	{&P2_11, 14},
+	{&P2_17, 65},
+	{&P2_18, 47},
+	{&P2_19, 27},
+	{&P2_20, 64},
+	{&P2_22, 46},
+	{&P2_24, 48},
+	{&P2_25, 41},
+	{&P2_27, 40},
+	{&P2_28, 116},
+	{&P2_29, 7},
+	{&P2_30, 113},
+	{&P2_31, 19},
+	{&P2_32, 112},
+	{&P2_33, 45},
+	{&P2_34, 115},
+	{&P2_35, 86},
+}
+
+// pocketBeagleHeaders returns the headers to register.
+func pocketBeagleHeaders() []header {
+	return []header{
+		{"P1", [][]pin.Pin{
+			{P1_1, P1_2},
+			{P1_3, P1_4},
+			{P1_5, P1_6},
+			{P1_7, P1_8},
+			{P1_9, P1_10},
+			{P1_11, P1_12},
+			{P1_13, P1_14},
+			{P1_15, P1_16},
+			{P1_17, P1_18},
+			{P1_19, P1_20},
+			{P1_21, P1_22},
+			{P1_23, P1_24},
+			{P1_25, P1_26},
+			{P1_27, P1_28},
+			{P1_29, P1_30},
+			{P1_31, P1_32},
+			{P1_33, P1_34},
+			{P1_35, P1_36},
+		}},
+		{"P2", [][]pin.Pin{
+			{P2_1, P2_2},
+			{P2_3, P2_4},
+			{P2_5, P2_6},
+			{P2_7, P2_8},
+			{P2_9, P2_10},
+			{P2_11, P2_12},
+			{P2_13, P2_14},
+			{P2_15, P2_16},
+			{P2_17, P2_18},
+			{P2_19, P2_20},
+			{P2_21, P2_22},
+			{P2_23, P2_24},
+			{P2_25, P2_26},
+			{P2_27, P2_28},
+			{P2_29, P2_30},
+			{P2_31, P2_32},
+			{P2_33, P2_34},
+			{P2_35, P2_36},
+		}},
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package distro
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BoardRevision returns the board revision as a hexadecimal string, e.g.
+// "a02082" on a Raspberry Pi 3, and returns "<unknown>" on non-linux systems
+// or if it is not found.
+//
+// It is read from the device tree (/proc/device-tree/system/linux,revision)
+// with a fallback to the "Revision" field of /proc/cpuinfo, which is not
+// present on arm64 kernels.
+func BoardRevision() string {
+	mu.Lock()
+	r := boardRevision
+	mu.Unlock()
+	if r == "" {
+		r = "<unknown>"
+		if isLinux {
+			r = makeBoardRevisionLinux()
+		}
+		mu.Lock()
+		boardRevision = r
+		mu.Unlock()
+	}
+	return r
+}
+
+// BoardSerial returns the board serial number, e.g. "00000000b1d4a5c6", and
+// returns "<unknown>" on non-linux systems or if it is not found.
+//
+// It is read from the device tree (/proc/device-tree/serial-number) with a
+// fallback to the "Serial" field of /proc/cpuinfo.
+func BoardSerial() string {
+	mu.Lock()
+	s := boardSerial
+	mu.Unlock()
+	if s == "" {
+		s = "<unknown>"
+		if isLinux {
+			s = makeBoardSerialLinux()
+		}
+		mu.Lock()
+		boardSerial = s
+		mu.Unlock()
+	}
+	return s
+}
+
+//
+
+var (
+	boardRevision string // cached BoardRevision()
+	boardSerial   string // cached BoardSerial()
+)
+
+// makeBoardRevisionLinux must be called without mu held, as CPUInfo() locks
+// it.
+func makeBoardRevisionLinux() string {
+	if bytes, err := readFile("/proc/device-tree/system/linux,revision"); err == nil && len(bytes) == 4 {
+		return fmt.Sprintf("%x", binary.BigEndian.Uint32(bytes))
+	}
+	if r := CPUInfo()["Revision"]; r != "" {
+		return r
+	}
+	return "<unknown>"
+}
+
+// makeBoardSerialLinux must be called without mu held, as CPUInfo() locks
+// it.
+func makeBoardSerialLinux() string {
+	if bytes, err := readFile("/proc/device-tree/serial-number"); err == nil {
+		if s := splitNull(bytes); len(s) > 0 && s[0] != "" {
+			return s[0]
+		}
+	}
+	if s := CPUInfo()["Serial"]; s != "" {
+		return s
+	}
+	return "<unknown>"
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package distro
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBoardRevision_fail(t *testing.T) {
+	defer reset()
+	if r := BoardRevision(); r != "<unknown>" {
+		t.Fatal(r)
+	}
+}
+
+func TestBoardSerial_fail(t *testing.T) {
+	defer reset()
+	if s := BoardSerial(); s != "<unknown>" {
+		t.Fatal(s)
+	}
+}
+
+func TestMakeBoardRevisionLinux(t *testing.T) {
+	defer reset()
+	readFile = func(filename string) ([]byte, error) {
+		if filename != "/proc/device-tree/system/linux,revision" {
+			t.Fatal(filename)
+		}
+		return []byte{0x00, 0xa0, 0x20, 0x82}, nil
+	}
+	if r := makeBoardRevisionLinux(); r != "a02082" {
+		t.Fatal(r)
+	}
+}
+
+func TestMakeBoardRevisionLinux_cpuinfo(t *testing.T) {
+	defer reset()
+	readFile = func(filename string) ([]byte, error) {
+		if filename == "/proc/cpuinfo" {
+			return []byte("Revision\t: a22082\nSerial\t\t: 00000000b1d4a5c6\n"), nil
+		}
+		return nil, errors.New("not found")
+	}
+	if r := makeBoardRevisionLinux(); r != "a22082" {
+		t.Fatal(r)
+	}
+	if s := makeBoardSerialLinux(); s != "00000000b1d4a5c6" {
+		t.Fatal(s)
+	}
+}
+
+func TestMakeBoardSerialLinux(t *testing.T) {
+	defer reset()
+	readFile = func(filename string) ([]byte, error) {
+		if filename != "/proc/device-tree/serial-number" {
+			t.Fatal(filename)
+		}
+		return []byte("2c0a1b3d\000"), nil
+	}
+	if s := makeBoardSerialLinux(); s != "2c0a1b3d" {
+		t.Fatal(s)
+	}
+}
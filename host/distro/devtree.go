@@ -4,6 +4,8 @@
 
 package distro
 
+import "strings"
+
 // DTModel returns platform model info from the Linux device tree (/proc/device-tree/model), and
 // returns "unknown" on non-linux systems or if the file is missing.
 func DTModel() string {
@@ -35,6 +37,31 @@ func DTCompatible() []string {
 	return dtCompatible
 }
 
+// DTIsCompatible returns true if the device tree declares the board
+// compatible with any of the strings specified.
+//
+// A string ending with "*" matches any compatible string starting with it,
+// e.g. "starfive,visionfive-2*" matches all the revisions of the board.
+func DTIsCompatible(compatible ...string) bool {
+	return DTMatch(compatible...) != ""
+}
+
+// DTMatch returns the first device tree compatible string of the board that
+// matches any of the strings specified, or "" if none matches.
+//
+// It is used by board drivers supporting multiple boards to select the
+// board specific definitions, and follows the same rules as DTIsCompatible.
+func DTMatch(compatible ...string) string {
+	for _, c := range DTCompatible() {
+		for _, m := range compatible {
+			if c == m || (strings.HasSuffix(m, "*") && strings.HasPrefix(c, m[:len(m)-1])) {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
 //
 
 var (
@@ -46,3 +46,23 @@ func TestDTCompatible(t *testing.T) {
 		t.Fatal(c)
 	}
 }
+
+func TestDTMatch(t *testing.T) {
+	defer reset()
+	dtCompatible = []string{"starfive,visionfive-2-v1.3b", "starfive,jh7110"}
+	if c := DTMatch("sinovoip,bananapi-m64", "starfive,jh7110"); c != "starfive,jh7110" {
+		t.Fatal(c)
+	}
+	if c := DTMatch("starfive,visionfive-2*"); c != "starfive,visionfive-2-v1.3b" {
+		t.Fatal(c)
+	}
+	if c := DTMatch("starfive,visionfive-2"); c != "" {
+		t.Fatal(c)
+	}
+	if !DTIsCompatible("starfive,jh7110") {
+		t.Fatal("expected compatible")
+	}
+	if DTIsCompatible() {
+		t.Fatal("nothing to match")
+	}
+}
@@ -145,9 +145,13 @@ func init() {
 }
 
 func reset() {
+	boardRevision = ""
+	boardSerial = ""
 	cpuInfo = nil
 	dtCompatible = nil
 	dtModel = ""
+	dmiBoardName = ""
+	dmiBoardVendor = ""
 	osRelease = nil
 	readFile = func(filename string) ([]byte, error) {
 		return nil, errors.New("no file can be opened in unit tests")
@@ -0,0 +1,58 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package distro
+
+import "strings"
+
+// DMIBoardVendor returns the board vendor from the DMI tables
+// (/sys/class/dmi/id/board_vendor), e.g. "AAEON", and returns "<unknown>" on
+// non-linux systems or if the file is missing.
+//
+// DMI is only available on x86 and some ARM64 UEFI systems; the device tree
+// is used on the other ARM boards.
+func DMIBoardVendor() string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dmiBoardVendor == "" {
+		dmiBoardVendor = "<unknown>"
+		if isLinux {
+			dmiBoardVendor = makeDMILinux("board_vendor")
+		}
+	}
+	return dmiBoardVendor
+}
+
+// DMIBoardName returns the board name from the DMI tables
+// (/sys/class/dmi/id/board_name), e.g. "UP-CHT01", and returns "<unknown>" on
+// non-linux systems or if the file is missing.
+func DMIBoardName() string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dmiBoardName == "" {
+		dmiBoardName = "<unknown>"
+		if isLinux {
+			dmiBoardName = makeDMILinux("board_name")
+		}
+	}
+	return dmiBoardName
+}
+
+//
+
+var (
+	dmiBoardVendor string // cached /sys/class/dmi/id/board_vendor
+	dmiBoardName   string // cached /sys/class/dmi/id/board_name
+)
+
+func makeDMILinux(field string) string {
+	if bytes, err := readFile("/sys/class/dmi/id/" + field); err == nil {
+		if s := strings.TrimSpace(string(bytes)); s != "" {
+			return s
+		}
+	}
+	return "<unknown>"
+}
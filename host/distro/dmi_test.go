@@ -0,0 +1,37 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package distro
+
+import (
+	"testing"
+)
+
+func TestDMIBoardVendor_fail(t *testing.T) {
+	defer reset()
+	if v := DMIBoardVendor(); v != "<unknown>" {
+		t.Fatal(v)
+	}
+}
+
+func TestDMIBoardName_fail(t *testing.T) {
+	defer reset()
+	if n := DMIBoardName(); n != "<unknown>" {
+		t.Fatal(n)
+	}
+}
+
+func TestMakeDMILinux(t *testing.T) {
+	defer reset()
+	readFile = func(filename string) ([]byte, error) {
+		if filename != "/sys/class/dmi/id/board_name" {
+			t.Fatal(filename)
+		}
+		return []byte("UP-CHT01\n"), nil
+	}
+	DMIBoardName()
+	if n := makeDMILinux("board_name"); n != "UP-CHT01" {
+		t.Fatal(n)
+	}
+}
@@ -62,6 +62,11 @@ func (f *File) Ioctl(op uint, data uintptr) error {
 }
 
 // Event is a file system event.
+//
+// All the events are waited for by a single goroutine with a shared epoll
+// handle, which dispatches them to the Event waiting for the file descriptor.
+// This way, watching a large number of files doesn't require one blocked
+// thread or one additional file handle per file.
 type Event struct {
 	event
 }
@@ -78,15 +83,39 @@ type Event struct {
 // epoll_wait() call is running, so no edge is missed. Two edges will be
 // coallesced into one if the user mode process can't keep up. There's no
 // accumulation of edges.
+//
+// The event is triggered by urgent data, as used by sysfs to notify a change
+// of a file.
 func (e *Event) MakeEvent(fd uintptr) error {
-	return e.event.makeEvent(fd)
+	return e.event.makeEvent(fd, epollPRI)
+}
+
+// MakeReadEvent is like MakeEvent but the event is triggered when the file
+// descriptor becomes readable.
+//
+// Since the event is edge triggered, the file should be read until it returns
+// EAGAIN before waiting for the event.
+func (e *Event) MakeReadEvent(fd uintptr) error {
+	return e.event.makeEvent(fd, epollIN)
 }
 
 // Wait waits for an event or the specified amount of time.
+//
+// A negative timeout means to wait forever. It returns 1 if the event was
+// triggered, 0 on timeout. A timeout of 0 returns 1 for an event triggered
+// before the call, so it can be used to flush an accumulated event.
 func (e *Event) Wait(timeoutms int) (int, error) {
 	return e.event.wait(timeoutms)
 }
 
+// Close stops listening for the event.
+//
+// It must be called before closing the file descriptor. Pending calls to
+// Wait() return an error.
+func (e *Event) Close() error {
+	return e.event.close()
+}
+
 //
 
 var (
@@ -4,7 +4,13 @@
 
 package fs
 
-import "syscall"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
 
 const isLinux = true
 
@@ -17,6 +23,7 @@ func ioctl(f uintptr, op uint, arg uintptr) error {
 
 const (
 	epollET     = 1 << 31
+	epollIN     = 1
 	epollPRI    = 2
 	epollCTLAdd = 1
 	epollCTLDel = 2
@@ -24,34 +31,209 @@ const (
 )
 
 type event struct {
-	event   [1]syscall.EpollEvent
-	epollFd int
-	fd      int
+	fd int32
+	c  chan struct{} // Signaled by events.loop(); closed by close()
 }
 
-// makeEvent creates an epoll *edge* triggered event.
+// makeEvent registers an epoll *edge* triggered event in the shared epoll
+// handle.
 //
 // References:
 // behavior and flags: http://man7.org/linux/man-pages/man7/epoll.7.html
 // syscall.EpollCreate: http://man7.org/linux/man-pages/man2/epoll_create.2.html
 // syscall.EpollCtl: http://man7.org/linux/man-pages/man2/epoll_ctl.2.html
-func (e *event) makeEvent(fd uintptr) error {
-	epollFd, err := syscall.EpollCreate(1)
-	if err != nil {
-		return err
-	}
-	e.epollFd = epollFd
-	e.fd = int(fd)
+func (e *event) makeEvent(fd uintptr, flags uint32) error {
+	e.fd = int32(fd)
+	e.c = make(chan struct{}, 1)
 	// EPOLLWAKEUP could be used to force the system to not go do sleep while
 	// waiting for an edge. This is generally a bad idea, as we'd instead have
 	// the system to *wake up* when an edge is triggered. Achieving this is
 	// outside the scope of this interface.
-	e.event[0].Events = epollPRI | epollET
-	e.event[0].Fd = int32(e.fd)
-	return syscall.EpollCtl(e.epollFd, epollCTLAdd, e.fd, &e.event[0])
+	return events.add(e, flags|epollET)
 }
 
 func (e *event) wait(timeoutms int) (int, error) {
-	// http://man7.org/linux/man-pages/man2/epoll_wait.2.html
-	return syscall.EpollWait(e.epollFd, e.event[:], timeoutms)
+	c := e.c
+	if c == nil {
+		return 0, errors.New("fs: event not initialized")
+	}
+	var ok bool
+	switch {
+	case timeoutms < 0:
+		_, ok = <-c
+	case timeoutms == 0:
+		// Wait for the loop to dispatch the events already triggered, so a
+		// poll flushes them reliably.
+		events.sync()
+		select {
+		case _, ok = <-c:
+		default:
+			return 0, nil
+		}
+	default:
+		t := time.NewTimer(time.Duration(timeoutms) * time.Millisecond)
+		defer t.Stop()
+		select {
+		case _, ok = <-c:
+		case <-t.C:
+			return 0, nil
+		}
+	}
+	if !ok {
+		return 0, errors.New("fs: event closed")
+	}
+	return 1, nil
+}
+
+func (e *event) close() error {
+	return events.remove(e)
+}
+
+// eventsListener waits for all the events with a single epoll handle and
+// dispatches them to the corresponding event.
+//
+// sync() wakes the loop up via a pipe registered in the same epoll handle.
+type eventsListener struct {
+	mu      sync.Mutex
+	epollFd int              // -1 when not initialized
+	wakeR   int              // Read end of the pipe to wake the loop up
+	wakeW   int              // Write end of the pipe to wake the loop up
+	fds     map[int32]*event // Registered events by file descriptor
+	synced  []chan struct{}  // sync() callers, one per byte in the pipe
+}
+
+var events = eventsListener{epollFd: -1}
+
+// add registers e and starts the loop on first use.
+func (l *eventsListener) add(e *event, flags uint32) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.epollFd == -1 {
+		if err := l.init(); err != nil {
+			return err
+		}
+	}
+	if _, ok := l.fds[e.fd]; ok {
+		return fmt.Errorf("fs: file descriptor %d is already listened to", e.fd)
+	}
+	ev := syscall.EpollEvent{Events: flags, Fd: e.fd}
+	if err := syscall.EpollCtl(l.epollFd, epollCTLAdd, int(e.fd), &ev); err != nil {
+		return err
+	}
+	l.fds[e.fd] = e
+	return nil
+}
+
+// init creates the epoll handle with the wake up pipe and starts the loop.
+func (l *eventsListener) init() error {
+	epollFd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		syscall.Close(epollFd)
+		return err
+	}
+	// The pipe is level triggered, so the loop is woken up until all the bytes
+	// are read.
+	ev := syscall.EpollEvent{Events: epollIN, Fd: int32(p[0])}
+	if err := syscall.EpollCtl(epollFd, epollCTLAdd, p[0], &ev); err != nil {
+		syscall.Close(p[0])
+		syscall.Close(p[1])
+		syscall.Close(epollFd)
+		return err
+	}
+	l.epollFd = epollFd
+	l.wakeR = p[0]
+	l.wakeW = p[1]
+	l.fds = map[int32]*event{}
+	go l.loop(epollFd)
+	return nil
+}
+
+// sync returns once the loop dispatched all the events triggered before the
+// call.
+func (l *eventsListener) sync() {
+	l.mu.Lock()
+	if l.epollFd == -1 {
+		l.mu.Unlock()
+		return
+	}
+	c := make(chan struct{})
+	if _, err := syscall.Write(l.wakeW, []byte{0}); err != nil {
+		l.mu.Unlock()
+		return
+	}
+	l.synced = append(l.synced, c)
+	l.mu.Unlock()
+	<-c
+}
+
+// remove unregisters e and unblocks its waiters.
+func (l *eventsListener) remove(e *event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.c == nil || l.fds[e.fd] != e {
+		return nil
+	}
+	delete(l.fds, e.fd)
+	close(e.c)
+	// The event argument is ignored since Linux 2.6.9 but must be non-nil.
+	var ev syscall.EpollEvent
+	return syscall.EpollCtl(l.epollFd, epollCTLDel, int(e.fd), &ev)
+}
+
+// loop waits for the events and signals them.
+//
+// An event that is not waited for stays signaled, so it is not lost.
+func (l *eventsListener) loop(epollFd int) {
+	var ev [16]syscall.EpollEvent
+	var b [1]byte
+	for {
+		// http://man7.org/linux/man-pages/man2/epoll_wait.2.html
+		n, err := syscall.EpollWait(epollFd, ev[:], -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		l.mu.Lock()
+		if err != nil {
+			// Unblock all the waiters; the next add() creates a new handle.
+			for fd, e := range l.fds {
+				close(e.c)
+				delete(l.fds, fd)
+			}
+			for _, c := range l.synced {
+				close(c)
+			}
+			l.synced = nil
+			syscall.Close(l.wakeR)
+			syscall.Close(l.wakeW)
+			syscall.Close(epollFd)
+			l.epollFd = -1
+			l.mu.Unlock()
+			return
+		}
+		wake := false
+		for i := 0; i < n; i++ {
+			if ev[i].Fd == int32(l.wakeR) {
+				wake = true
+			} else if e, ok := l.fds[ev[i].Fd]; ok {
+				select {
+				case e.c <- struct{}{}:
+				default:
+				}
+			}
+		}
+		// Events triggered before a sync() byte was written are either in this
+		// batch or in a later one when the batch is full. Release one sync()
+		// caller per batch that was fetched after its byte was written.
+		if wake && n < len(ev) {
+			if n, _ := syscall.Read(l.wakeR, b[:]); n == 1 && len(l.synced) != 0 {
+				close(l.synced[0])
+				l.synced = l.synced[1:]
+			}
+		}
+		l.mu.Unlock()
+	}
 }
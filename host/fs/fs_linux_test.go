@@ -0,0 +1,91 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	var pipes [2]*os.File
+	var evs [2]Event
+	for i := range pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		defer w.Close()
+		pipes[i] = w
+		if err := evs[i].MakeReadEvent(r.Fd()); err != nil {
+			t.Fatal(err)
+		}
+		if err := evs[i].MakeReadEvent(r.Fd()); err == nil {
+			t.Fatal("already listened to")
+		}
+	}
+	if n, err := evs[0].Wait(0); n != 0 || err != nil {
+		t.Fatal(n, err)
+	}
+	if n, err := evs[0].Wait(1); n != 0 || err != nil {
+		t.Fatal(n, err)
+	}
+	// Each event is dispatched to its own Event.
+	if _, err := pipes[1].Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := evs[1].Wait(-1); n != 1 || err != nil {
+		t.Fatal(n, err)
+	}
+	if n, err := evs[0].Wait(0); n != 0 || err != nil {
+		t.Fatal(n, err)
+	}
+	if _, err := pipes[0].Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := evs[0].Wait(10000); n != 1 || err != nil {
+		t.Fatal(n, err)
+	}
+	// A poll flushes an event triggered right before it.
+	for i := 0; i < 100; i++ {
+		if _, err := pipes[0].Write([]byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := evs[0].Wait(0); n != 1 || err != nil {
+			t.Fatal(i, n, err)
+		}
+		if n, err := evs[0].Wait(0); n != 0 || err != nil {
+			t.Fatal(i, n, err)
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := evs[0].Wait(-1)
+		done <- err
+	}()
+	for i := range evs {
+		if err := evs[i].Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-done; err == nil {
+		t.Fatal("event closed")
+	}
+	if err := evs[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvent_uninitialized(t *testing.T) {
+	var e Event
+	if _, err := e.Wait(0); err == nil {
+		t.Fatal("uninitialized")
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
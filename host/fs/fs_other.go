@@ -10,16 +10,25 @@ import "errors"
 
 const isLinux = false
 
+const (
+	epollIN  = 1
+	epollPRI = 2
+)
+
 func ioctl(f uintptr, op uint, arg uintptr) error {
 	return errors.New("fs: ioctl not supported on non-linux")
 }
 
 type event struct{}
 
-func (e *event) makeEvent(f uintptr) error {
+func (e *event) makeEvent(f uintptr, flags uint32) error {
 	return errors.New("fs: unreachable code")
 }
 
 func (e *event) wait(timeoutms int) (int, error) {
 	return 0, errors.New("fs: unreachable code")
 }
+
+func (e *event) close() error {
+	return nil
+}
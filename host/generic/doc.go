@@ -0,0 +1,20 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package generic exposes the GPIO lines of Linux hosts that are not
+// otherwise supported, so periph works out of the box on unknown boards.
+//
+// When no known SoC or board is detected, every line of every GPIO character
+// device /dev/gpiochip* is registered in gpioreg as "GPIO<n>", numbered
+// globally in chip order; e.g. line 3 of the second chip with 32 lines is
+// GPIO35. The lines named by the device tree gpio-line-names property are also
+// registered as an alias with this name, e.g. "PWR_LED", when it is unique.
+//
+// The numbering differs from the legacy sysfs numbering used by package sysfs,
+// which registers its pins as non-preferred.
+//
+// The I²C buses, SPI ports and PWM controllers are not board specific and are
+// already registered by the drivers "sysfs-i2c", "sysfs-spi" and "sysfs-pwm"
+// of package sysfs.
+package generic
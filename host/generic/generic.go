@@ -0,0 +1,182 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/host/allwinner"
+	"periph.io/x/periph/host/bcm283x"
+	"periph.io/x/periph/host/beagle"
+	"periph.io/x/periph/host/gpioioctl"
+	"periph.io/x/periph/host/jetson"
+	"periph.io/x/periph/host/mt7688"
+	"periph.io/x/periph/host/odroidc1"
+	"periph.io/x/periph/host/rp1"
+	"periph.io/x/periph/host/upboard"
+	"periph.io/x/periph/host/visionfive"
+)
+
+// Present returns true if no known SoC or board was detected, so the GPIO
+// lines are to be exposed by this package.
+func Present() bool {
+	if isLinux {
+		for _, f := range known {
+			if f() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// known is the detection function of every host with its own GPIO driver.
+var known = []func() bool{
+	allwinner.Present,
+	bcm283x.Present,
+	beagle.Present,
+	jetson.Present,
+	mt7688.Present,
+	odroidc1.Present,
+	rp1.Present,
+	upboard.Present,
+	visionfive.Present,
+}
+
+// sortChips sorts the paths to the GPIO character devices by chip number, so
+// gpiochip10 is after gpiochip2.
+func sortChips(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		a := chipNumber(paths[i])
+		b := chipNumber(paths[j])
+		if a != b {
+			return a < b
+		}
+		return paths[i] < paths[j]
+	})
+}
+
+// chipNumber returns N for /dev/gpiochipN, or -1.
+func chipNumber(path string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "gpiochip"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// chip is the subset of *gpioioctl.Chip used to register the lines.
+type chip interface {
+	Lines() int
+	LineName(offset int) (string, error)
+	PinNumber(offset, number int, name string, defaultPull gpio.Pull) *gpioioctl.Pin
+}
+
+// register registers all the lines of chips, numbered globally.
+//
+// The line names are registered as aliases only when they are unique across
+// all the chips, as unused lines are commonly all named "NC" or "". On failure,
+// the pins already registered are unregistered.
+func register(chips []chip) error {
+	var pins []gpio.PinIO
+	var names []string
+	count := map[string]int{}
+	for _, c := range chips {
+		base := len(pins)
+		for i := 0; i < c.Lines(); i++ {
+			name, err := c.LineName(i)
+			if err != nil {
+				return err
+			}
+			n := base + i
+			pins = append(pins, c.PinNumber(i, n, fmt.Sprintf("GPIO%d", n), gpio.PullNoChange))
+			names = append(names, name)
+			count[name]++
+		}
+	}
+	for i, p := range pins {
+		if err := gpioreg.Register(p, true); err != nil {
+			for _, q := range pins[:i] {
+				gpioreg.Unregister(q.Name())
+			}
+			return err
+		}
+	}
+	for i, name := range names {
+		if name == "" || count[name] != 1 || gpioreg.ByName(name) != nil {
+			continue
+		}
+		if err := gpioreg.RegisterAlias(name, pins[i].Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "generic"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("a known host was detected")
+	}
+	items, err := filepath.Glob("/dev/gpiochip*")
+	if err != nil {
+		return true, err
+	}
+	if len(items) == 0 {
+		return false, errors.New("no GPIO character device found")
+	}
+	sortChips(items)
+	opened := make([]*gpioioctl.Chip, 0, len(items))
+	chips := make([]chip, 0, len(items))
+	for _, item := range items {
+		c, err := gpioioctl.Open(item)
+		if err != nil {
+			closeChips(opened)
+			return true, err
+		}
+		opened = append(opened, c)
+		chips = append(chips, c)
+	}
+	if err := register(chips); err != nil {
+		closeChips(opened)
+		return true, err
+	}
+	return true, nil
+}
+
+// closeChips closes the chips opened by Init() when it fails.
+func closeChips(chips []*gpioioctl.Chip) {
+	for _, c := range chips {
+		c.Close()
+	}
+}
+
+func init() {
+	if isLinux {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,114 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+func TestSortChips(t *testing.T) {
+	paths := []string{"/dev/gpiochip10", "/dev/gpiochip2", "/dev/gpiochip0"}
+	sortChips(paths)
+	if expected := []string{"/dev/gpiochip0", "/dev/gpiochip2", "/dev/gpiochip10"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatal(paths)
+	}
+	if n := chipNumber("/dev/gpiochipX"); n != -1 {
+		t.Fatal(n)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	chips := []chip{
+		&fakeChip{names: []string{"", "PWR_LED", "NC"}},
+		&fakeChip{names: []string{"NC", "BUTTON"}},
+	}
+	if err := register(chips); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for i := 0; i < 5; i++ {
+			if err := gpioreg.Unregister(fmt.Sprintf("GPIO%d", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}()
+	p := gpioreg.ByName("GPIO4")
+	if p == nil || p.Number() != 4 {
+		t.Fatal(p)
+	}
+	if s := p.String(); s != "GPIO4" {
+		t.Fatal(s)
+	}
+	a, ok := gpioreg.ByName("BUTTON").(gpio.RealPin)
+	if !ok || a.Real() != p {
+		t.Fatal(a)
+	}
+	if a, ok := gpioreg.ByName("PWR_LED").(gpio.RealPin); !ok || a.Real().Name() != "GPIO1" {
+		t.Fatal(a)
+	}
+	if p := gpioreg.ByName("NC"); p != nil {
+		t.Fatal("duplicate names must not be registered")
+	}
+
+	if err := register([]chip{&fakeChip{err: errors.New("ioctl")}}); err == nil {
+		t.Fatal("LineName failed")
+	}
+}
+
+func TestRegister_rollback(t *testing.T) {
+	if err := gpioreg.Register(&gpiotest.Pin{N: "GPIO2", Num: 2}, true); err != nil {
+		t.Fatal(err)
+	}
+	defer gpioreg.Unregister("GPIO2")
+	if err := register([]chip{&fakeChip{names: []string{"", "", "", ""}}}); err == nil {
+		t.Fatal("GPIO2 is already registered")
+	}
+	if p := gpioreg.ByName("GPIO1"); p != nil {
+		t.Fatal(p)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "generic" {
+		t.Fatal(s)
+	}
+	if p := d.Prerequisites(); p != nil {
+		t.Fatal(p)
+	}
+}
+
+//
+
+type fakeChip struct {
+	names []string
+	err   error
+}
+
+func (f *fakeChip) Lines() int {
+	if f.err != nil {
+		return 1
+	}
+	return len(f.names)
+}
+
+func (f *fakeChip) LineName(offset int) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.names[offset], nil
+}
+
+func (f *fakeChip) PinNumber(offset, number int, name string, defaultPull gpio.Pull) *gpioioctl.Pin {
+	return (&gpioioctl.Chip{}).PinNumber(offset, number, name, defaultPull)
+}
@@ -0,0 +1,18 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package gpioioctl exposes the GPIO lines of the Linux GPIO character
+// devices, /dev/gpiochipN, via the v2 ioctl API.
+//
+// Unlike sysfs, the lines are not exported globally and are released when the
+// process exits. Edge detection is done by the kernel, which timestamps and
+// queues the edges.
+//
+// This package is not a driver by itself; the host drivers for boards whose
+// GPIOs are not memory mapped use it to expose their pins, e.g. host/rp1.
+//
+// Reference
+//
+// https://www.kernel.org/doc/html/latest/userspace-api/gpio/chardev.html
+package gpioioctl
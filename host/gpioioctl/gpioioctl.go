@@ -0,0 +1,292 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpioioctl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/host/fs"
+)
+
+// Chip is an open GPIO character device, e.g. /dev/gpiochip0.
+type Chip struct {
+	// Immutable.
+	name  string
+	label string
+	lines int
+	f     chip
+}
+
+// Open opens a GPIO character device.
+func Open(path string) (*Chip, error) {
+	c, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("gpioioctl: %v", err)
+	}
+	return c, nil
+}
+
+// Find returns the GPIO character device with the label specified.
+//
+// The chip number is not stable across kernel versions, so the chips are
+// enumerated to find the one with the right label, e.g. "pinctrl-rp1" or
+// "tegra-gpio".
+func Find(label string) (*Chip, error) {
+	items, err := filepath.Glob("/dev/gpiochip*")
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("gpioioctl: no GPIO character device found; please upgrade the kernel")
+	}
+	return find(items, label)
+}
+
+func (c *Chip) String() string {
+	return c.name
+}
+
+// Name returns the kernel name of the chip, e.g. "gpiochip0".
+func (c *Chip) Name() string {
+	return c.name
+}
+
+// Label returns the label of the chip, which identifies the driver.
+func (c *Chip) Label() string {
+	return c.label
+}
+
+// Lines returns the number of lines of the chip.
+func (c *Chip) Lines() int {
+	return c.lines
+}
+
+// LineName returns the name of the line at offset as set by the device tree
+// gpio-line-names property, e.g. "GPIO17" or "PWR_LED".
+//
+// It returns an empty string when the line is not named.
+func (c *Chip) LineName(offset int) (string, error) {
+	info := lineInfo{offset: uint32(offset)}
+	if err := ioctl(c.f, gpioV2GetLineInfo, unsafe.Pointer(&info)); err != nil {
+		return "", fmt.Errorf("gpioioctl: %v", err)
+	}
+	return cString(info.name[:]), nil
+}
+
+// Close closes the chip.
+//
+// The lines already requested are not released.
+func (c *Chip) Close() error {
+	return c.f.Close()
+}
+
+// Pin returns a GPIO pin for the line at offset.
+//
+// The line is requested from the chip on first use. Unlike sysfs, the line is
+// released when the process exits.
+func (c *Chip) Pin(offset int, name string, defaultPull gpio.Pull) *Pin {
+	return &Pin{chip: c, offset: offset, number: offset, name: name, defaultPull: defaultPull}
+}
+
+// PinNumber is like Pin but the pin number is set to number instead of the
+// line offset.
+//
+// It is used on boards where the GPIOs are spread over multiple chips but
+// numbered globally, e.g. line 3 of gpiochip1 is GPIO35 on an AM335x.
+func (c *Chip) PinNumber(offset, number int, name string, defaultPull gpio.Pull) *Pin {
+	return &Pin{chip: c, offset: offset, number: number, name: name, defaultPull: defaultPull}
+}
+
+//
+
+// Linux GPIO character device v2 uAPI, include/uapi/linux/gpio.h.
+//
+// All the 64 bits fields are at a multiple of 8 bytes offset so the layout is
+// the same on 32 and 64 bits ARM.
+const (
+	gpioGetChipInfo    = 0x8044B401 // _IOR(0xB4, 0x01, struct gpiochip_info)
+	gpioV2GetLineInfo  = 0xC100B405 // _IOWR(0xB4, 0x05, struct gpio_v2_line_info)
+	gpioV2GetLine      = 0xC250B407 // _IOWR(0xB4, 0x07, struct gpio_v2_line_request)
+	gpioV2SetConfig    = 0xC110B40D // _IOWR(0xB4, 0x0D, struct gpio_v2_line_config)
+	gpioV2GetValues    = 0xC010B40E // _IOWR(0xB4, 0x0E, struct gpio_v2_line_values)
+	gpioV2SetValues    = 0xC010B40F // _IOWR(0xB4, 0x0F, struct gpio_v2_line_values)
+	gpioV2AttrIDValues = 2          // GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES
+)
+
+// lineFlags is GPIO_V2_LINE_FLAG_xxx.
+type lineFlags uint64
+
+const (
+	flagInput         lineFlags = 1 << 2
+	flagOutput        lineFlags = 1 << 3
+	flagEdgeRising    lineFlags = 1 << 4
+	flagEdgeFalling   lineFlags = 1 << 5
+	flagBiasPullUp    lineFlags = 1 << 8
+	flagBiasPullDown  lineFlags = 1 << 9
+	flagBiasDisabled  lineFlags = 1 << 10
+	flagDirectionMask           = flagInput | flagOutput
+	flagBiasMask                = flagBiasPullUp | flagBiasPullDown | flagBiasDisabled
+)
+
+// chipInfo is struct gpiochip_info.
+type chipInfo struct {
+	name  [32]byte
+	label [32]byte
+	lines uint32
+}
+
+// lineAttribute is struct gpio_v2_line_config_attribute.
+type lineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64
+	mask    uint64
+}
+
+// lineInfo is struct gpio_v2_line_info.
+type lineInfo struct {
+	name     [32]byte
+	consumer [32]byte
+	offset   uint32
+	numAttrs uint32
+	flags    lineFlags
+	attrs    [10][2]uint64 // struct gpio_v2_line_attribute; unused
+	padding  [4]uint32
+}
+
+// lineConfig is struct gpio_v2_line_config.
+type lineConfig struct {
+	flags    lineFlags
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [10]lineAttribute
+}
+
+// lineRequest is struct gpio_v2_line_request.
+type lineRequest struct {
+	offsets         [64]uint32
+	consumer        [32]byte
+	config          lineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+// lineValues is struct gpio_v2_line_values.
+type lineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// lineEventSize is sizeof(struct gpio_v2_line_event).
+const lineEventSize = 48
+
+// set fills the configuration for a single line.
+func (c *lineConfig) set(flags lineFlags, l bool) {
+	*c = lineConfig{flags: flags}
+	if flags&flagOutput != 0 {
+		// Set the output level at the same time as the direction to not create
+		// any glitch.
+		c.numAttrs = 1
+		c.attrs[0] = lineAttribute{id: gpioV2AttrIDValues, mask: 1}
+		if l {
+			c.attrs[0].value = 1
+		}
+	}
+}
+
+// chip is an open GPIO character device.
+type chip interface {
+	io.Closer
+	fs.Ioctler
+}
+
+// line is a GPIO line requested from a chip.
+type line interface {
+	io.Closer
+	io.Reader
+	fs.Ioctler
+	SetReadDeadline(t time.Time) error
+}
+
+var chipOpen = chipOpenDefault
+
+func chipOpenDefault(path string) (chip, error) {
+	f, err := fs.Open(path, os.O_RDWR)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+var lineOpen = lineOpenDefault
+
+// ioctl is overridden in tests, so the fakes do not have to convert data back
+// to a pointer.
+var ioctl = func(f fs.Ioctler, op uint, data unsafe.Pointer) error {
+	return f.Ioctl(op, uintptr(data))
+}
+
+func open(path string) (*Chip, error) {
+	f, err := chipOpen(path)
+	if err != nil {
+		return nil, err
+	}
+	var info chipInfo
+	if err := ioctl(f, gpioGetChipInfo, unsafe.Pointer(&info)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Chip{name: cString(info.name[:]), label: cString(info.label[:]), lines: int(info.lines), f: f}, nil
+}
+
+// find returns the chip with label among paths.
+func find(paths []string, label string) (*Chip, error) {
+	var last error
+	for _, path := range paths {
+		c, err := open(path)
+		if err != nil {
+			last = err
+			continue
+		}
+		if c.label == label {
+			return c, nil
+		}
+		c.Close()
+	}
+	if last != nil {
+		return nil, fmt.Errorf("gpioioctl: failed to find GPIO chip %q: %v", label, last)
+	}
+	return nil, fmt.Errorf("gpioioctl: failed to find GPIO chip %q", label)
+}
+
+// requestLine requests a single line from the chip.
+func requestLine(c chip, offset int, consumer string, flags lineFlags, l bool) (line, error) {
+	r := lineRequest{numLines: 1}
+	r.offsets[0] = uint32(offset)
+	copy(r.consumer[:len(r.consumer)-1], consumer)
+	r.config.set(flags, l)
+	if err := ioctl(c, gpioV2GetLine, unsafe.Pointer(&r)); err != nil {
+		return nil, err
+	}
+	return lineOpen(r.fd, consumer)
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,24 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpioioctl
+
+import (
+	"os"
+	"syscall"
+
+	"periph.io/x/periph/host/fs"
+)
+
+// lineOpenDefault wraps the file descriptor returned by gpioV2GetLine.
+//
+// It is set as non blocking so the Go poller handles it, which makes
+// SetReadDeadline() work.
+func lineOpenDefault(fd int32, name string) (line, error) {
+	if err := syscall.SetNonblock(int(fd), true); err != nil {
+		syscall.Close(int(fd))
+		return nil, err
+	}
+	return &fs.File{File: os.NewFile(uintptr(fd), name)}, nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// +build !linux
+
+package gpioioctl
+
+import "errors"
+
+func lineOpenDefault(fd int32, name string) (line, error) {
+	return nil, errors.New("gpio character device is not supported on this platform")
+}
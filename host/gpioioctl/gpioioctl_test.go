@@ -0,0 +1,463 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpioioctl
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/host/fs"
+)
+
+func TestChip(t *testing.T) {
+	f := &fakeChip{name: "gpiochip0", label: "tegra-gpio"}
+	defer setOpen(map[string]*fakeChip{"/dev/gpiochip0": f})()
+	c, err := Open("/dev/gpiochip0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "gpiochip0" {
+		t.Fatal(s)
+	}
+	if s := c.Name(); s != "gpiochip0" {
+		t.Fatal(s)
+	}
+	if s := c.Label(); s != "tegra-gpio" {
+		t.Fatal(s)
+	}
+	if n := c.Lines(); n != 54 {
+		t.Fatal(n)
+	}
+	if err := c.Close(); err != nil || !f.closed {
+		t.Fatal(err)
+	}
+	if _, err := Open("/dev/gpiochip1"); err == nil {
+		t.Fatal("open failed")
+	}
+	f.err = errors.New("ioctl")
+	if _, err := Open("/dev/gpiochip0"); err == nil {
+		t.Fatal("chip info failed")
+	}
+}
+
+func TestChip_LineName(t *testing.T) {
+	f := &fakeChip{names: map[int]string{3: "PWR_LED"}}
+	defer setChip(f)()
+	c := &Chip{f: f}
+	if s, err := c.LineName(3); s != "PWR_LED" || err != nil {
+		t.Fatal(s, err)
+	}
+	if s, err := c.LineName(4); s != "" || err != nil {
+		t.Fatal(s, err)
+	}
+	f.err = errors.New("ioctl")
+	if _, err := c.LineName(3); err == nil {
+		t.Fatal("ioctl failed")
+	}
+}
+
+func TestPin(t *testing.T) {
+	c := &Chip{f: &fakeChip{}}
+	p := c.Pin(42, "Foo", gpio.PullDown)
+	if s := p.String(); s != "Foo" {
+		t.Fatal(s)
+	}
+	if s := p.Name(); s != "Foo" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != 42 {
+		t.Fatal(n)
+	}
+	if d := p.DefaultPull(); d != gpio.PullDown {
+		t.Fatal(d)
+	}
+	if s := p.Function(); s != "<Unknown>" {
+		t.Fatal(s)
+	}
+	if d := p.Pull(); d != gpio.PullNoChange {
+		t.Fatal(d)
+	}
+	if p.WaitForEdge(-1) {
+		t.Fatal("edge not initialized")
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPinNumber(t *testing.T) {
+	c := &fakeChip{}
+	defer setChip(c)()
+	p := (&Chip{f: c}).PinNumber(3, 35, "GPIO35", gpio.PullNoChange)
+	if n := p.Number(); n != 35 {
+		t.Fatal(n)
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if c.offset != 3 {
+		t.Fatal(c.offset)
+	}
+}
+
+func TestPin_In_Out(t *testing.T) {
+	c := &fakeChip{}
+	defer setChip(c)()
+	p := (&Chip{f: c}).Pin(4, "GPIO4", gpio.PullNoChange)
+
+	if err := p.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if c.offset != 4 || c.consumer != "periph" {
+		t.Fatal(c.offset, c.consumer)
+	}
+	l := c.lines[0]
+	if l.flags != flagInput|flagBiasPullUp {
+		t.Fatalf("%#x", l.flags)
+	}
+	if d := p.Pull(); d != gpio.PullUp {
+		t.Fatal(d)
+	}
+	// The bias is kept.
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if l.flags != flagInput|flagBiasPullUp || len(c.lines) != 1 {
+		t.Fatalf("%#x", l.flags)
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.PullDown {
+		t.Fatal(d)
+	}
+	if err := p.In(gpio.Float, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Pull(); d != gpio.Float {
+		t.Fatal(d)
+	}
+	l.value = true
+	if d := p.Read(); d != gpio.High {
+		t.Fatal(d)
+	}
+	if s := p.Function(); s != "In/High" {
+		t.Fatal(s)
+	}
+
+	// The level is set along the direction.
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if l.flags != flagOutput || l.value {
+		t.Fatalf("%#x %t", l.flags, l.value)
+	}
+	if s := p.Function(); s != "Out/Low" {
+		t.Fatal(s)
+	}
+	if err := p.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if !l.value {
+		t.Fatal("expected High")
+	}
+	if d := p.Pull(); d != gpio.PullNoChange {
+		t.Fatal(d)
+	}
+
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if !l.closed {
+		t.Fatal("line must be released")
+	}
+	if s := p.Function(); s != "<Unknown>" {
+		t.Fatal(s)
+	}
+}
+
+func TestPin_Read(t *testing.T) {
+	c := &fakeChip{}
+	defer setChip(c)()
+	p := (&Chip{f: c}).Pin(4, "GPIO4", gpio.PullNoChange)
+	// The line is requested without changing its configuration.
+	if d := p.Read(); d != gpio.Low {
+		t.Fatal(d)
+	}
+	if len(c.lines) != 1 || c.lines[0].flags != 0 {
+		t.Fatal(c.lines)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPin_WaitForEdge(t *testing.T) {
+	c := &fakeChip{}
+	defer setChip(c)()
+	p := (&Chip{f: c}).Pin(4, "GPIO4", gpio.PullNoChange)
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("edge not enabled")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	l := c.lines[0]
+	if l.flags != flagInput|flagEdgeRising|flagEdgeFalling {
+		t.Fatalf("%#x", l.flags)
+	}
+	l.events = 1
+	if !p.WaitForEdge(time.Second) {
+		t.Fatal("expected edge")
+	}
+	if l.deadline.IsZero() {
+		t.Fatal("expected deadline")
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("unexpected edge")
+	}
+	l.events = 1
+	if !p.WaitForEdge(-1) {
+		t.Fatal("expected edge")
+	}
+	if !l.deadline.IsZero() {
+		t.Fatal("unexpected deadline")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if l.flags != flagInput|flagEdgeRising {
+		t.Fatalf("%#x", l.flags)
+	}
+	if err := p.In(gpio.PullNoChange, gpio.FallingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if l.flags != flagInput|flagEdgeFalling {
+		t.Fatalf("%#x", l.flags)
+	}
+}
+
+func TestPin_fail(t *testing.T) {
+	c := &fakeChip{err: errors.New("busy")}
+	defer setChip(c)()
+	p := (&Chip{f: c}).Pin(4, "GPIO4", gpio.PullNoChange)
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err == nil {
+		t.Fatal("request failed")
+	}
+	if err := p.Out(gpio.Low); err == nil {
+		t.Fatal("request failed")
+	}
+	if d := p.Read(); d != gpio.Low {
+		t.Fatal(d)
+	}
+
+	c.err = nil
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	l := c.lines[0]
+	l.err = errors.New("io")
+	if err := p.Out(gpio.High); err == nil {
+		t.Fatal("set values failed")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err == nil {
+		t.Fatal("set config failed")
+	}
+	if d := p.Read(); d != gpio.Low {
+		t.Fatal(d)
+	}
+	if err := p.Halt(); err == nil {
+		t.Fatal("close failed")
+	}
+}
+
+func TestFind(t *testing.T) {
+	chips := map[string]*fakeChip{
+		"/dev/gpiochip0": {label: "gpio-brcmstb@107d508500"},
+		"/dev/gpiochip4": {label: "pinctrl-rp1"},
+	}
+	defer setOpen(chips)()
+	c, err := find([]string{"/dev/gpiochip0", "/dev/gpiochip4"}, "pinctrl-rp1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.f != chips["/dev/gpiochip4"] {
+		t.Fatal(c)
+	}
+	if !chips["/dev/gpiochip0"].closed {
+		t.Fatal("the other chips must be closed")
+	}
+	if _, err := find([]string{"/dev/gpiochip0"}, "pinctrl-rp1"); err == nil {
+		t.Fatal("no RP1 chip")
+	}
+	if _, err := find([]string{"/dev/gpiochip1"}, "pinctrl-rp1"); err == nil {
+		t.Fatal("open failed")
+	}
+}
+
+func TestLineInfo(t *testing.T) {
+	if s := unsafe.Sizeof(lineInfo{}); s != 256 {
+		t.Fatal(s)
+	}
+}
+
+func TestCString(t *testing.T) {
+	if s := cString([]byte{'a', 'b', 0, 'c'}); s != "ab" {
+		t.Fatal(s)
+	}
+	if s := cString([]byte{'a', 'b'}); s != "ab" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+func setChip(f *fakeChip) func() {
+	lineOpen = func(fd int32, name string) (line, error) {
+		l := &fakeLine{}
+		l.apply(&f.config)
+		f.lines = append(f.lines, l)
+		return l, nil
+	}
+	ioctl = fakeIoctl
+	return func() {
+		lineOpen = lineOpenDefault
+		ioctl = ioctlDefault
+	}
+}
+
+func setOpen(chips map[string]*fakeChip) func() {
+	chipOpen = func(path string) (chip, error) {
+		if c, ok := chips[path]; ok {
+			return c, nil
+		}
+		return nil, errors.New("not found")
+	}
+	ioctl = fakeIoctl
+	return func() {
+		chipOpen = chipOpenDefault
+		ioctl = ioctlDefault
+	}
+}
+
+var ioctlDefault = ioctl
+
+// fakeIoctl lets the fakes use data as a pointer.
+func fakeIoctl(f fs.Ioctler, op uint, data unsafe.Pointer) error {
+	return f.(interface {
+		ioctl(op uint, data unsafe.Pointer) error
+	}).ioctl(op, data)
+}
+
+type fakeChip struct {
+	name     string
+	label    string
+	err      error
+	closed   bool
+	offset   int
+	consumer string
+	config   lineConfig
+	lines    []*fakeLine
+	names    map[int]string
+}
+
+func (f *fakeChip) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeChip) Ioctl(op uint, data uintptr) error {
+	return errors.New("use fakeIoctl")
+}
+
+func (f *fakeChip) ioctl(op uint, data unsafe.Pointer) error {
+	if f.err != nil {
+		return f.err
+	}
+	switch op {
+	case gpioGetChipInfo:
+		info := (*chipInfo)(data)
+		copy(info.name[:], f.name)
+		copy(info.label[:], f.label)
+		info.lines = 54
+	case gpioV2GetLineInfo:
+		info := (*lineInfo)(data)
+		copy(info.name[:], f.names[int(info.offset)])
+	case gpioV2GetLine:
+		r := (*lineRequest)(data)
+		f.offset = int(r.offsets[0])
+		f.consumer = cString(r.consumer[:])
+		f.config = r.config
+		r.fd = 3
+	default:
+		return errors.New("unexpected ioctl")
+	}
+	return nil
+}
+
+type fakeLine struct {
+	flags    lineFlags
+	value    bool
+	events   int
+	deadline time.Time
+	err      error
+	closed   bool
+}
+
+func (f *fakeLine) apply(c *lineConfig) {
+	f.flags = c.flags
+	if c.numAttrs == 1 && c.attrs[0].id == gpioV2AttrIDValues {
+		f.value = c.attrs[0].value&1 != 0
+	}
+}
+
+func (f *fakeLine) Close() error {
+	f.closed = true
+	return f.err
+}
+
+func (f *fakeLine) Read(b []byte) (int, error) {
+	if f.events == 0 {
+		return 0, errors.New("timeout")
+	}
+	f.events--
+	return len(b), nil
+}
+
+func (f *fakeLine) Ioctl(op uint, data uintptr) error {
+	return errors.New("use fakeIoctl")
+}
+
+func (f *fakeLine) ioctl(op uint, data unsafe.Pointer) error {
+	if f.err != nil {
+		return f.err
+	}
+	switch op {
+	case gpioV2SetConfig:
+		f.apply((*lineConfig)(data))
+	case gpioV2GetValues:
+		v := (*lineValues)(data)
+		if f.value {
+			v.bits = 1
+		}
+	case gpioV2SetValues:
+		v := (*lineValues)(data)
+		f.value = v.bits&1 != 0
+	default:
+		return errors.New("unexpected ioctl")
+	}
+	return nil
+}
+
+func (f *fakeLine) SetReadDeadline(t time.Time) error {
+	f.deadline = t
+	return nil
+}
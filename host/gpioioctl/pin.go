@@ -0,0 +1,243 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpioioctl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Pin is a line of a GPIO character device.
+//
+// Pin implements gpio.PinIO.
+//
+// Each pin is requested from the GPIO character device on first use. Unlike
+// sysfs, the line is released when the process exits.
+type Pin struct {
+	// Immutable.
+	chip        *Chip
+	offset      int
+	number      int
+	name        string
+	defaultPull gpio.Pull
+
+	// Mutable.
+	mu    sync.Mutex
+	line  line      // Set when the line is requested from the chip.
+	flags lineFlags // Configuration of the line.
+}
+
+// String returns the pin name, ex: "GPIO10".
+func (p *Pin) String() string {
+	return p.name
+}
+
+// Name returns the pin name, ex: "GPIO10".
+func (p *Pin) Name() string {
+	return p.name
+}
+
+// Number returns the pin number, which is the line offset on the chip unless
+// the pin was created with Chip.PinNumber.
+func (p *Pin) Number() int {
+	return p.number
+}
+
+// Function returns the current pin function, ex: "In/High".
+//
+// The alternate functions are not exposed by the GPIO character device, so
+// "<Unknown>" is returned if the pin was not used as a GPIO by this process.
+func (p *Pin) Function() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.flags & flagDirectionMask {
+	case flagInput:
+		return "In/" + p.read().String()
+	case flagOutput:
+		return "Out/" + p.read().String()
+	default:
+		return "<Unknown>"
+	}
+}
+
+// Halt implements conn.Resource.
+//
+// It releases the line, which stops the edge detection. The pin keeps its
+// current state.
+func (p *Pin) Halt() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line == nil {
+		return nil
+	}
+	err := p.line.Close()
+	p.line = nil
+	p.flags = 0
+	if err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// In setups a pin as an input and implements gpio.PinIn.
+//
+// The pull resistor is read back by Pull() once set.
+//
+// Edge detection is done by the kernel, which timestamps and queues the
+// edges, so none is lost when WaitForEdge() is not called fast enough.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f := flagInput
+	switch pull {
+	case gpio.PullDown:
+		f |= flagBiasPullDown
+	case gpio.PullUp:
+		f |= flagBiasPullUp
+	case gpio.Float:
+		f |= flagBiasDisabled
+	case gpio.PullNoChange:
+		f |= p.flags & flagBiasMask
+	}
+	switch edge {
+	case gpio.RisingEdge:
+		f |= flagEdgeRising
+	case gpio.FallingEdge:
+		f |= flagEdgeFalling
+	case gpio.BothEdges:
+		f |= flagEdgeRising | flagEdgeFalling
+	}
+	return p.configure(f, false)
+}
+
+// Read returns the current pin level and implements gpio.PinIn.
+//
+// It works even if the pin is set as output. If the pin was not used before,
+// its line is requested without changing its configuration.
+func (p *Pin) Read() gpio.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line == nil {
+		if err := p.configure(0, false); err != nil {
+			return gpio.Low
+		}
+	}
+	return p.read()
+}
+
+// WaitForEdge waits for an edge as setup via In() and implements gpio.PinIn.
+//
+// A negative timeout means to wait forever.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	p.mu.Lock()
+	l := p.line
+	edge := p.flags&(flagEdgeRising|flagEdgeFalling) != 0
+	p.mu.Unlock()
+	if l == nil || !edge {
+		return false
+	}
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := l.SetReadDeadline(deadline); err != nil {
+		return false
+	}
+	var b [lineEventSize]byte
+	n, err := l.Read(b[:])
+	return err == nil && n == len(b)
+}
+
+// Pull implements gpio.PinIn.
+//
+// It returns the pull resistor last set by In().
+func (p *Pin) Pull() gpio.Pull {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.flags & flagBiasMask {
+	case flagBiasPullDown:
+		return gpio.PullDown
+	case flagBiasPullUp:
+		return gpio.PullUp
+	case flagBiasDisabled:
+		return gpio.Float
+	default:
+		return gpio.PullNoChange
+	}
+}
+
+// DefaultPull returns the default pull for the pin.
+//
+// Implements gpio.PinDefaultPull.
+func (p *Pin) DefaultPull() gpio.Pull {
+	return p.defaultPull
+}
+
+// Out sets a pin as output and implements gpio.PinOut.
+//
+// The level is set at the same time as the direction to not create any
+// glitch.
+func (p *Pin) Out(l gpio.Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line != nil && p.flags&flagDirectionMask == flagOutput {
+		v := lineValues{mask: 1}
+		if l {
+			v.bits = 1
+		}
+		if err := ioctl(p.line, gpioV2SetValues, unsafe.Pointer(&v)); err != nil {
+			return p.wrap(err)
+		}
+		return nil
+	}
+	return p.configure(flagOutput, bool(l))
+}
+
+//
+
+// configure requests the line or changes its configuration. p.mu must be
+// held.
+func (p *Pin) configure(f lineFlags, l bool) error {
+	if p.line == nil {
+		line, err := requestLine(p.chip.f, p.offset, "periph", f, l)
+		if err != nil {
+			return p.wrap(err)
+		}
+		p.line = line
+	} else {
+		var c lineConfig
+		c.set(f, l)
+		if err := ioctl(p.line, gpioV2SetConfig, unsafe.Pointer(&c)); err != nil {
+			return p.wrap(err)
+		}
+	}
+	p.flags = f
+	return nil
+}
+
+// read returns the level of a requested line. p.mu must be held.
+func (p *Pin) read() gpio.Level {
+	if p.line == nil {
+		return gpio.Low
+	}
+	v := lineValues{mask: 1}
+	if err := ioctl(p.line, gpioV2GetValues, unsafe.Pointer(&v)); err != nil {
+		return gpio.Low
+	}
+	return gpio.Level(v.bits&1 != 0)
+}
+
+func (p *Pin) wrap(err error) error {
+	return fmt.Errorf("gpioioctl (%s): %v", p, err)
+}
+
+var _ gpio.PinDefaultPull = &Pin{}
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinIn = &Pin{}
+var _ gpio.PinOut = &Pin{}
@@ -13,3 +13,11 @@ import "periph.io/x/periph"
 func Init() (*periph.State, error) {
 	return periph.Init()
 }
+
+// InitWithOptions calls periph.InitWithOptions() and returns it as-is.
+//
+// Like Init(), it guarantees that all the drivers implemented in this library
+// are registered; opts selects which ones are initialized.
+func InitWithOptions(opts *periph.Options) (*periph.State, error) {
+	return periph.InitWithOptions(opts)
+}
@@ -7,11 +7,16 @@ package host
 import (
 	// Make sure CPU and board drivers are registered.
 	_ "periph.io/x/periph/host/allwinner"
+	_ "periph.io/x/periph/host/bananapi"
 	_ "periph.io/x/periph/host/bcm283x"
+	_ "periph.io/x/periph/host/beagle"
 	_ "periph.io/x/periph/host/chip"
+	_ "periph.io/x/periph/host/nanopi"
 	_ "periph.io/x/periph/host/odroidc1"
+	_ "periph.io/x/periph/host/orangepi"
 	// While this board is ARM64, it may run ARM 32 bits binaries so load it on
 	// 32 bits builds too.
 	_ "periph.io/x/periph/host/pine64"
+	_ "periph.io/x/periph/host/rp1"
 	_ "periph.io/x/periph/host/rpi"
 )
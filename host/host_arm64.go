@@ -7,7 +7,12 @@ package host
 import (
 	// Make sure CPU and board drivers are registered.
 	_ "periph.io/x/periph/host/allwinner"
+	_ "periph.io/x/periph/host/bananapi"
 	_ "periph.io/x/periph/host/bcm283x"
+	_ "periph.io/x/periph/host/jetson"
+	_ "periph.io/x/periph/host/nanopi"
+	_ "periph.io/x/periph/host/orangepi"
 	_ "periph.io/x/periph/host/pine64"
+	_ "periph.io/x/periph/host/rp1"
 	_ "periph.io/x/periph/host/rpi"
 )
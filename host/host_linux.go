@@ -5,6 +5,7 @@
 package host
 
 import (
-	// Make sure sysfs drivers are registered.
+	// Make sure the generic and sysfs drivers are registered.
+	_ "periph.io/x/periph/host/generic"
 	_ "periph.io/x/periph/host/sysfs"
 )
@@ -0,0 +1,11 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package host
+
+import (
+	// Make sure CPU and board drivers are registered.
+	_ "periph.io/x/periph/host/mt7688"
+	_ "periph.io/x/periph/host/omega2"
+)
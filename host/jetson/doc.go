@@ -0,0 +1,31 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package jetson contains the header definitions of the NVIDIA Jetson Nano
+// developer kit.
+//
+// The Tegra X1 GPIOs are accessed through the GPIO character device via
+// package gpioioctl, so no root access is needed as long as the user has
+// access to /dev/gpiochip0.
+//
+// The J41 header follows the Raspberry Pi layout and is exposed as P1:
+//
+// - P1_3 and P1_5 are I²C bus 1, /dev/i2c-1.
+//
+// - P1_27 and P1_28 are I²C bus 0, /dev/i2c-0.
+//
+// - P1_8 and P1_10 are UART1, /dev/ttyTHS1.
+//
+// - P1_19, P1_21, P1_23, P1_24 and P1_26 are SPI bus 0, /dev/spidev0.N.
+//
+// - P1_37, P1_22, P1_13, P1_18 and P1_16 are SPI bus 1, /dev/spidev1.N.
+//
+// The SPI buses are only available once enabled with
+// /opt/nvidia/jetson-io/jetson-io.py; the SPI aliases, e.g. "SPI0_MOSI", are
+// only registered when the corresponding spidev device is present.
+//
+// Physical
+//
+// https://developer.nvidia.com/embedded/dlc/jetson-nano-dev-kit-carrier-board-specification
+package jetson
@@ -0,0 +1,229 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package jetson
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+// Present returns true if running on a Jetson Nano developer kit.
+//
+// It looks for the board in /proc/device-tree/compatible.
+//
+// https://developer.nvidia.com/embedded/jetson-nano-developer-kit
+func Present() bool {
+	if isArm {
+		return distro.DTIsCompatible("nvidia,p3450-0000", "nvidia,jetson-nano")
+	}
+	return false
+}
+
+// Jetson Nano specific pins.
+var (
+	I2C0_SDA = &pin.BasicPin{N: "I2C0_SDA"} // /dev/i2c-0
+	I2C0_SCL = &pin.BasicPin{N: "I2C0_SCL"} //
+	I2C1_SDA = &pin.BasicPin{N: "I2C1_SDA"} // /dev/i2c-1
+	I2C1_SCL = &pin.BasicPin{N: "I2C1_SCL"} //
+	UART1_TX = &pin.BasicPin{N: "UART1_TX"} // /dev/ttyTHS1
+	UART1_RX = &pin.BasicPin{N: "UART1_RX"} //
+)
+
+// All the individual pins on the J41 header.
+//
+// The GPIOs are set once the driver is initialized.
+var (
+	P1_1  pin.Pin    = pin.V3_3     //
+	P1_2  pin.Pin    = pin.V5       //
+	P1_3  pin.Pin    = I2C1_SDA     //
+	P1_4  pin.Pin    = pin.V5       //
+	P1_5  pin.Pin    = I2C1_SCL     //
+	P1_6  pin.Pin    = pin.GROUND   //
+	P1_7  gpio.PinIO = gpio.INVALID // PBB.00, AUD_MCLK
+	P1_8  pin.Pin    = UART1_TX     //
+	P1_9  pin.Pin    = pin.GROUND   //
+	P1_10 pin.Pin    = UART1_RX     //
+	P1_11 gpio.PinIO = gpio.INVALID // PG.02, UART2_RTS
+	P1_12 gpio.PinIO = gpio.INVALID // PJ.07, I2S0_SCLK
+	P1_13 gpio.PinIO = gpio.INVALID // PB.06, SPI1_CLK
+	P1_14 pin.Pin    = pin.GROUND   //
+	P1_15 gpio.PinIO = gpio.INVALID // PY.02, LCD_TE
+	P1_16 gpio.PinIO = gpio.INVALID // PDD.00, SPI1_CS1
+	P1_17 pin.Pin    = pin.V3_3     //
+	P1_18 gpio.PinIO = gpio.INVALID // PB.07, SPI1_CS0
+	P1_19 gpio.PinIO = gpio.INVALID // PC.00, SPI0_MOSI
+	P1_20 pin.Pin    = pin.GROUND   //
+	P1_21 gpio.PinIO = gpio.INVALID // PC.01, SPI0_MISO
+	P1_22 gpio.PinIO = gpio.INVALID // PB.05, SPI1_MISO
+	P1_23 gpio.PinIO = gpio.INVALID // PC.02, SPI0_CLK
+	P1_24 gpio.PinIO = gpio.INVALID // PC.03, SPI0_CS0
+	P1_25 pin.Pin    = pin.GROUND   //
+	P1_26 gpio.PinIO = gpio.INVALID // PC.04, SPI0_CS1
+	P1_27 pin.Pin    = I2C0_SDA     //
+	P1_28 pin.Pin    = I2C0_SCL     //
+	P1_29 gpio.PinIO = gpio.INVALID // PS.05, CAM_AF_EN
+	P1_30 pin.Pin    = pin.GROUND   //
+	P1_31 gpio.PinIO = gpio.INVALID // PZ.00
+	P1_32 gpio.PinIO = gpio.INVALID // PV.00, LCD_BL_PWM
+	P1_33 gpio.PinIO = gpio.INVALID // PE.06
+	P1_34 pin.Pin    = pin.GROUND   //
+	P1_35 gpio.PinIO = gpio.INVALID // PJ.04, I2S0_FS
+	P1_36 gpio.PinIO = gpio.INVALID // PG.03, UART2_CTS
+	P1_37 gpio.PinIO = gpio.INVALID // PB.04, SPI1_MOSI
+	P1_38 gpio.PinIO = gpio.INVALID // PJ.05, I2S0_DIN
+	P1_39 pin.Pin    = pin.GROUND   //
+	P1_40 gpio.PinIO = gpio.INVALID // PJ.06, I2S0_DOUT
+)
+
+//
+
+// headerGPIO is the Tegra GPIO of each header pin, as "port.bit".
+var headerGPIO = []struct {
+	p    *gpio.PinIO
+	port string
+	bit  int
+}{
+	{&P1_7, "BB", 0},
+	{&P1_11, "G", 2},
+	{&P1_12, "J", 7},
+	{&P1_13, "B", 6},
+	{&P1_15, "Y", 2},
+	{&P1_16, "DD", 0},
+	{&P1_18, "B", 7},
+	{&P1_19, "C", 0},
+	{&P1_21, "C", 1},
+	{&P1_22, "B", 5},
+	{&P1_23, "C", 2},
+	{&P1_24, "C", 3},
+	{&P1_26, "C", 4},
+	{&P1_29, "S", 5},
+	{&P1_31, "Z", 0},
+	{&P1_32, "V", 0},
+	{&P1_33, "E", 6},
+	{&P1_35, "J", 4},
+	{&P1_36, "G", 3},
+	{&P1_37, "B", 4},
+	{&P1_38, "J", 5},
+	{&P1_40, "J", 6},
+}
+
+// spiAliases is the SPI functions of the header pins for each SPI bus.
+var spiAliases = map[int]map[string]*gpio.PinIO{
+	0: {
+		"SPI0_MOSI": &P1_19,
+		"SPI0_MISO": &P1_21,
+		"SPI0_CLK":  &P1_23,
+		"SPI0_CS0":  &P1_24,
+		"SPI0_CS1":  &P1_26,
+	},
+	1: {
+		"SPI1_MOSI": &P1_37,
+		"SPI1_MISO": &P1_22,
+		"SPI1_CLK":  &P1_13,
+		"SPI1_CS0":  &P1_18,
+		"SPI1_CS1":  &P1_16,
+	},
+}
+
+// lineOffset returns the line offset of a Tegra GPIO on the tegra-gpio chip.
+//
+// Each port has 8 lines. Ports are named A to Z, then AA, BB, CC, DD and EE.
+func lineOffset(port string, bit int) int {
+	n := int(port[0] - 'A')
+	if len(port) == 2 {
+		n += 26
+	}
+	return n*8 + bit
+}
+
+// spiBuses returns the SPI buses enabled on the header, as found in
+// /dev/spidevN.M.
+func spiBuses() []int {
+	var out []int
+	for bus := range spiAliases {
+		if items, _ := filepath.Glob(fmt.Sprintf("/dev/spidev%d.*", bus)); len(items) != 0 {
+			out = append(out, bus)
+		}
+	}
+	return out
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "jetson"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("Jetson Nano board not detected")
+	}
+	c, err := gpioioctl.Find("tegra-gpio")
+	if err != nil {
+		return true, err
+	}
+	for _, h := range headerGPIO {
+		p := c.Pin(lineOffset(h.port, h.bit), fmt.Sprintf("P%s.%02d", h.port, h.bit), gpio.PullNoChange)
+		if err := gpioreg.Register(p, true); err != nil {
+			return true, err
+		}
+		*h.p = p
+	}
+	if err := pinreg.Register("P1", [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}); err != nil {
+		return true, err
+	}
+	for _, bus := range spiBuses() {
+		for alias, p := range spiAliases[bus] {
+			if err := gpioreg.RegisterAlias(alias, (*p).Name()); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,39 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package jetson
+
+import "testing"
+
+func TestLineOffset(t *testing.T) {
+	data := []struct {
+		port     string
+		bit      int
+		expected int
+	}{
+		{"A", 0, 0},
+		{"B", 6, 14},
+		{"C", 4, 20},
+		{"G", 2, 50},
+		{"J", 7, 79},
+		{"Z", 0, 200},
+		{"BB", 0, 216},
+		{"DD", 0, 232},
+	}
+	for i, line := range data {
+		if n := lineOffset(line.port, line.bit); n != line.expected {
+			t.Fatalf("#%d: %d != %d", i, n, line.expected)
+		}
+	}
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "jetson" {
+		t.Fatal(s)
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("Jetson is not present")
+	}
+}
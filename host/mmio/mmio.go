@@ -0,0 +1,165 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mmio provides typed access to memory mapped I/O registers.
+//
+// It is meant to be used by SoC drivers, including board support packages
+// maintained outside of this repository, so they don't have to reimplement the
+// memory mapping and register access done by the host drivers like bcm283x.
+//
+// The memory is mapped via host/pmem. The registers are accessed with single
+// 32 bits loads and stores that the compiler doesn't coalesce, split, elide or
+// reorder.
+package mmio
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+
+	"periph.io/x/periph/host/pmem"
+)
+
+// Block is a window of memory mapped 32 bits registers.
+//
+// It is safe to use a Block concurrently, but read-modify-write operations on
+// the same register must be synchronized by the caller.
+type Block struct {
+	regs []uint32
+	phys uint64
+	v    *pmem.View // Set when the Block owns the mapping
+}
+
+// Map maps size bytes of physical memory starting at base via /dev/mem.
+//
+// base and size must be multiples of 4. It normally requires running as root.
+//
+// The memory is unmapped by Close() or periph.Shutdown().
+func Map(base uint64, size int) (*Block, error) {
+	if base&3 != 0 || size&3 != 0 || size <= 0 {
+		return nil, fmt.Errorf("mmio: invalid block 0x%x (size %d); both must be multiples of 4", base, size)
+	}
+	v, err := pmem.Map(base, size)
+	if err != nil {
+		return nil, fmt.Errorf("mmio: %v", err)
+	}
+	return &Block{regs: v.Uint32(), phys: v.PhysAddr(), v: v}, nil
+}
+
+// MapGPIO maps the GPIO registers via /dev/gpiomem.
+//
+// /dev/gpiomem is only present on some distributions, e.g. Raspbian. It
+// doesn't require running as root. Its physical address is unknown.
+//
+// The mapping is shared with the drivers using pmem.MapGPIO() so Close()
+// doesn't unmap it.
+func MapGPIO() (*Block, error) {
+	v, err := pmem.MapGPIO()
+	if err != nil {
+		return nil, fmt.Errorf("mmio: %v", err)
+	}
+	return &Block{regs: v.Uint32()}, nil
+}
+
+// FromBytes returns a Block over memory that is already mapped, like the
+// Bytes() of a pmem.Mem, or a buffer in unit tests.
+//
+// phys is the physical address of b, if known. The Block doesn't own the
+// memory.
+func FromBytes(b []byte, phys uint64) (*Block, error) {
+	if len(b) == 0 || len(b)&3 != 0 || uintptr(unsafe.Pointer(&b[0]))&3 != 0 {
+		return nil, errors.New("mmio: buffer must be non empty and aligned on 4 bytes")
+	}
+	s := pmem.Slice(b)
+	return &Block{regs: s.Uint32(), phys: phys}, nil
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("mmio{0x%x, %d}", b.phys, b.Len())
+}
+
+// Close unmaps the memory if it was mapped by Map().
+//
+// The Block must not be used afterward.
+func (b *Block) Close() error {
+	if b.v == nil {
+		return nil
+	}
+	err := b.v.Close()
+	b.v = nil
+	b.regs = nil
+	return err
+}
+
+// PhysAddr returns the physical address of the block, 0 if unknown.
+func (b *Block) PhysAddr() uint64 {
+	return b.phys
+}
+
+// Len returns the size of the block in bytes.
+func (b *Block) Len() int {
+	return 4 * len(b.regs)
+}
+
+// Reg32 returns the 32 bits register at offset, in bytes.
+//
+// It panics if offset is not aligned on 4 bytes or is out of the block, like
+// an out of range slice index.
+func (b *Block) Reg32(offset int) Reg32 {
+	if offset&3 != 0 {
+		panic(fmt.Sprintf("mmio: unaligned register offset 0x%x", offset))
+	}
+	return Reg32{p: &b.regs[offset/4]}
+}
+
+// Reg32 is a 32 bits register.
+//
+// The zero value is not usable; use Block.Reg32().
+type Reg32 struct {
+	p *uint32
+}
+
+// Read reads the register.
+func (r Reg32) Read() uint32 {
+	return atomic.LoadUint32(r.p)
+}
+
+// Write writes the register.
+func (r Reg32) Write(v uint32) {
+	atomic.StoreUint32(r.p, v)
+}
+
+// Set sets the bits of mask with a read-modify-write.
+func (r Reg32) Set(mask uint32) {
+	r.Write(r.Read() | mask)
+}
+
+// Clear clears the bits of mask with a read-modify-write.
+func (r Reg32) Clear(mask uint32) {
+	r.Write(r.Read() &^ mask)
+}
+
+// Modify replaces the bits of mask with the ones of v with a
+// read-modify-write.
+func (r Reg32) Modify(mask, v uint32) {
+	r.Write(r.Read()&^mask | v&mask)
+}
+
+// Barrier orders the register accesses done before it with the ones done
+// after it, as seen by the CPU.
+//
+// It is a sequentially consistent atomic operation, which is a DMB on ARM. It
+// doesn't wait for a write to reach the peripheral; read back a register of
+// the same peripheral for that.
+func Barrier() {
+	atomic.AddUint32(&barrier, 1)
+}
+
+//
+
+// barrier is the memory location used by Barrier().
+var barrier uint32
+
+var _ fmt.Stringer = &Block{}
@@ -0,0 +1,104 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mmio
+
+import (
+	"log"
+	"testing"
+	"unsafe"
+)
+
+func ExampleMap() {
+	// Map the 4096 bytes of the BCM2837 GPIO controller. This requires running
+	// as root.
+	b, err := Map(0x3F200000, 4096)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	// Set GPIO4 high then low via GPSET0 and GPCLR0.
+	b.Reg32(0x1C).Write(1 << 4)
+	Barrier()
+	b.Reg32(0x28).Write(1 << 4)
+}
+
+//
+
+func TestFromBytes(t *testing.T) {
+	buf := make([]uint32, 4)
+	b, err := FromBytes(bytesOf(buf), 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "mmio{0x1000, 16}" {
+		t.Fatal(s)
+	}
+	if p := b.PhysAddr(); p != 0x1000 {
+		t.Fatal(p)
+	}
+	r := b.Reg32(4)
+	r.Write(0xF0)
+	if buf[1] != 0xF0 {
+		t.Fatalf("0x%x", buf[1])
+	}
+	r.Set(0x3)
+	r.Clear(0x10)
+	if v := r.Read(); v != 0xE3 {
+		t.Fatalf("0x%x", v)
+	}
+	r.Modify(0xFF, 0x1234)
+	if v := r.Read(); v != 0x34 {
+		t.Fatalf("0x%x", v)
+	}
+	Barrier()
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromBytes_fail(t *testing.T) {
+	if _, err := FromBytes(nil, 0); err == nil {
+		t.Fatal("empty")
+	}
+	if _, err := FromBytes(make([]byte, 3), 0); err == nil {
+		t.Fatal("size")
+	}
+	if _, err := FromBytes(make([]byte, 32)[1:9], 0); err == nil {
+		t.Fatal("alignment")
+	}
+}
+
+func TestReg32_panic(t *testing.T) {
+	b, err := FromBytes(make([]byte, 8), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, offset := range []int{2, 8} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("0x%x: expected panic", offset)
+				}
+			}()
+			b.Reg32(offset)
+		}()
+	}
+}
+
+func TestMap_fail(t *testing.T) {
+	if _, err := Map(0x1002, 4096); err == nil {
+		t.Fatal("unaligned base")
+	}
+	if _, err := Map(0x1000, 0); err == nil {
+		t.Fatal("empty")
+	}
+}
+
+//
+
+// bytesOf returns the memory of buf as a []byte.
+func bytesOf(buf []uint32) []byte {
+	return (*[1 << 20]byte)(unsafe.Pointer(&buf[0]))[:4*len(buf)]
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mt7688
+
+import (
+	"strings"
+
+	"periph.io/x/periph/host/distro"
+)
+
+// Present returns true if running on a MediaTek MT7688 or MT7628.
+//
+// It looks for "mediatek,mt7628an-soc" in /proc/device-tree/compatible, then
+// falls back to the "system type" in /proc/cpuinfo, as OpenWrt kernels may
+// not expose the device tree.
+func Present() bool {
+	if isMIPS {
+		if distro.DTIsCompatible("mediatek,mt7628an-soc", "mediatek,mt7688*") {
+			return true
+		}
+		s := distro.CPUInfo()["system type"]
+		return strings.HasPrefix(s, "MT7688") || strings.HasPrefix(s, "MT7628")
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package mt7688 exposes the GPIO functionality of the MediaTek MT7688 and
+// MT7628 Wi-Fi SoCs, used by boards like the Onion Omega2, the LinkIt Smart
+// 7688 and the VoCore2.
+//
+// This driver implements memory-mapped GPIO pin manipulation and leverages
+// sysfs-gpio for edge detection.
+//
+// The pins are multiplexed in groups; for example GPIO4 and GPIO5 form the
+// I2C group. Using one pin of a group as a GPIO switches the whole group to
+// GPIO mode. The SPI group (GPIO7 to GPIO10) is never switched since it is
+// used by the boot flash.
+//
+// The CPU doesn't expose pull resistors control.
+//
+// Pin multiplexing
+//
+// https://github.com/torvalds/linux/blob/master/drivers/pinctrl/ralink/pinctrl-mt76x8.c
+package mt7688
@@ -0,0 +1,384 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mt7688
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/host/pmem"
+	"periph.io/x/periph/host/sysfs"
+)
+
+// All the pins supported by the CPU. GPIO30 to GPIO35 do not exist.
+var (
+	GPIO0  = &Pin{number: 0, name: "GPIO0", group: &muxI2S}      // I2S_SDI, PCM_DRX
+	GPIO1  = &Pin{number: 1, name: "GPIO1", group: &muxI2S}      // I2S_SDO, PCM_DTX
+	GPIO2  = &Pin{number: 2, name: "GPIO2", group: &muxI2S}      // I2S_WS, PCM_CLK
+	GPIO3  = &Pin{number: 3, name: "GPIO3", group: &muxI2S}      // I2S_CLK, PCM_FS
+	GPIO4  = &Pin{number: 4, name: "GPIO4", group: &muxI2C}      // I2C_SCLK
+	GPIO5  = &Pin{number: 5, name: "GPIO5", group: &muxI2C}      // I2C_SD
+	GPIO6  = &Pin{number: 6, name: "GPIO6", group: &muxSPICS1}   // SPI_CS1
+	GPIO7  = &Pin{number: 7, name: "GPIO7", group: &muxSPI}      // SPI_CLK
+	GPIO8  = &Pin{number: 8, name: "GPIO8", group: &muxSPI}      // SPI_MOSI
+	GPIO9  = &Pin{number: 9, name: "GPIO9", group: &muxSPI}      // SPI_MISO
+	GPIO10 = &Pin{number: 10, name: "GPIO10", group: &muxSPI}    // SPI_CS0
+	GPIO11 = &Pin{number: 11, name: "GPIO11", group: &muxGPIO}   //
+	GPIO12 = &Pin{number: 12, name: "GPIO12", group: &muxUART0}  // UART0_TX
+	GPIO13 = &Pin{number: 13, name: "GPIO13", group: &muxUART0}  // UART0_RX
+	GPIO14 = &Pin{number: 14, name: "GPIO14", group: &muxSPIS}   // SPIS_CS
+	GPIO15 = &Pin{number: 15, name: "GPIO15", group: &muxSPIS}   // SPIS_CLK
+	GPIO16 = &Pin{number: 16, name: "GPIO16", group: &muxSPIS}   // SPIS_MISO
+	GPIO17 = &Pin{number: 17, name: "GPIO17", group: &muxSPIS}   // SPIS_MOSI
+	GPIO18 = &Pin{number: 18, name: "GPIO18", group: &muxPWM0}   // PWM0
+	GPIO19 = &Pin{number: 19, name: "GPIO19", group: &muxPWM1}   // PWM1
+	GPIO20 = &Pin{number: 20, name: "GPIO20", group: &muxUART2}  // UART2_TX
+	GPIO21 = &Pin{number: 21, name: "GPIO21", group: &muxUART2}  // UART2_RX
+	GPIO22 = &Pin{number: 22, name: "GPIO22", group: &muxSD}     // SD_WP
+	GPIO23 = &Pin{number: 23, name: "GPIO23", group: &muxSD}     // SD_CD
+	GPIO24 = &Pin{number: 24, name: "GPIO24", group: &muxSD}     // SD_D1
+	GPIO25 = &Pin{number: 25, name: "GPIO25", group: &muxSD}     // SD_D0
+	GPIO26 = &Pin{number: 26, name: "GPIO26", group: &muxSD}     // SD_CLK
+	GPIO27 = &Pin{number: 27, name: "GPIO27", group: &muxSD}     // SD_CMD
+	GPIO28 = &Pin{number: 28, name: "GPIO28", group: &muxSD}     // SD_D3
+	GPIO29 = &Pin{number: 29, name: "GPIO29", group: &muxSD}     // SD_D2
+	GPIO36 = &Pin{number: 36, name: "GPIO36", group: &muxPERST}  // PERST_N
+	GPIO37 = &Pin{number: 37, name: "GPIO37", group: &muxREFCLK} // REF_CLKO
+	GPIO38 = &Pin{number: 38, name: "GPIO38", group: &muxWDT}    // WDT_RST_N
+	GPIO39 = &Pin{number: 39, name: "GPIO39", group: &muxP4LED}  // EPHY_LED4_N
+	GPIO40 = &Pin{number: 40, name: "GPIO40", group: &muxP3LED}  // EPHY_LED3_N
+	GPIO41 = &Pin{number: 41, name: "GPIO41", group: &muxP2LED}  // EPHY_LED2_N
+	GPIO42 = &Pin{number: 42, name: "GPIO42", group: &muxP1LED}  // EPHY_LED1_N
+	GPIO43 = &Pin{number: 43, name: "GPIO43", group: &muxP0LED}  // EPHY_LED0_N
+	GPIO44 = &Pin{number: 44, name: "GPIO44", group: &muxWLED}   // WLED_N
+	GPIO45 = &Pin{number: 45, name: "GPIO45", group: &muxUART1}  // UART1_TX
+	GPIO46 = &Pin{number: 46, name: "GPIO46", group: &muxUART1}  // UART1_RX
+)
+
+// Pin is a GPIO number (GPIOnn) on the MT7688.
+//
+// Pin implements gpio.PinIO.
+type Pin struct {
+	// Immutable.
+	number int
+	name   string
+	group  *muxGroup
+
+	// Mutable.
+	edge      *sysfs.Pin // Set once, then never set back to nil.
+	usingEdge bool       // Set when edge detection is enabled.
+}
+
+// String returns the pin name, ex: "GPIO10".
+func (p *Pin) String() string {
+	return p.name
+}
+
+// Name returns the pin name, ex: "GPIO10".
+func (p *Pin) Name() string {
+	return p.name
+}
+
+// Number returns the pin number as assigned by gpio sysfs.
+func (p *Pin) Number() int {
+	return p.number
+}
+
+// Function returns the current pin function, ex: "In/Low" or "I2C".
+//
+// The function of a pin used by a peripheral is the name of its group.
+func (p *Pin) Function() string {
+	if muxMemory == nil || gpioMemory == nil {
+		return "N/A"
+	}
+	if v := p.group.get(); v != p.group.gpio {
+		if s := p.group.funcs[v]; len(s) != 0 {
+			return s
+		}
+		return fmt.Sprintf("<Alt%d>", v)
+	}
+	if p.isOut() {
+		return "Out/" + p.Read().String()
+	}
+	return "In/" + p.Read().String()
+}
+
+// Halt implements conn.Resource.
+//
+// It stops edge detection if enabled.
+func (p *Pin) Halt() error {
+	if p.usingEdge {
+		if err := p.edge.Halt(); err != nil {
+			return p.wrap(err)
+		}
+		p.usingEdge = false
+	}
+	return nil
+}
+
+// In setups a pin as an input and implements gpio.PinIn.
+//
+// The whole pin group is switched to GPIO mode. Only gpio.PullNoChange is
+// supported as the CPU doesn't expose the pull resistors.
+//
+// Edge detection requires opening a gpio sysfs file handle. The pin will be
+// exported at /sys/class/gpio/gpio*/. Note that the pin will not be unexported
+// at shutdown.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if muxMemory == nil || gpioMemory == nil {
+		return p.wrap(errors.New("subsystem not initialized"))
+	}
+	if pull != gpio.PullNoChange {
+		return p.wrap(errors.New("pull resistor is not supported"))
+	}
+	if err := p.setGPIO(); err != nil {
+		return err
+	}
+	if p.usingEdge && edge == gpio.NoEdge {
+		if err := p.edge.Halt(); err != nil {
+			return p.wrap(err)
+		}
+		p.usingEdge = false
+	}
+	gpioMemory.ctrl[p.number/32] &^= 1 << uint(p.number%32)
+	if edge != gpio.NoEdge {
+		if p.edge == nil {
+			ok := false
+			if p.edge, ok = sysfs.Pins[p.number]; !ok {
+				return p.wrap(errors.New("pin is not exported by sysfs"))
+			}
+		}
+		// This resets pending edges.
+		if err := p.edge.In(gpio.PullNoChange, edge); err != nil {
+			return p.wrap(err)
+		}
+		p.usingEdge = true
+	}
+	return nil
+}
+
+// Read returns the current pin level and implements gpio.PinIn.
+//
+// This function is very fast. It works even if the pin is set as output.
+func (p *Pin) Read() gpio.Level {
+	if gpioMemory == nil {
+		return gpio.Low
+	}
+	return gpio.Level(gpioMemory.data[p.number/32]&(1<<uint(p.number%32)) != 0)
+}
+
+// WaitForEdge waits for an edge as previously set using In() or the expiration
+// of a timeout.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	if p.edge != nil {
+		return p.edge.WaitForEdge(timeout)
+	}
+	return false
+}
+
+// Pull implements gpio.PinIn.
+//
+// The CPU doesn't expose the pull resistors.
+func (p *Pin) Pull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// DefaultPull returns the default pull for the pin.
+//
+// Implements gpio.PinDefaultPull. The CPU doesn't expose the pull resistors.
+func (p *Pin) DefaultPull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out sets a pin as output and implements gpio.PinOut.
+//
+// The whole pin group is switched to GPIO mode.
+func (p *Pin) Out(l gpio.Level) error {
+	if muxMemory == nil || gpioMemory == nil {
+		return p.wrap(errors.New("subsystem not initialized"))
+	}
+	if err := p.Halt(); err != nil {
+		return err
+	}
+	if err := p.setGPIO(); err != nil {
+		return err
+	}
+	// Change output before changing direction to not create any glitch.
+	p.FastOut(l)
+	gpioMemory.ctrl[p.number/32] |= 1 << uint(p.number%32)
+	return nil
+}
+
+// FastOut sets a pin output level with Absolutely No error checking.
+//
+// Out() Must be called once first before calling FastOut(), otherwise the
+// behavior is undefined. Then FastOut() can be used for minimal CPU overhead
+// to reach Mhz scale bit banging.
+func (p *Pin) FastOut(l gpio.Level) {
+	mask := uint32(1) << uint(p.number%32)
+	if l == gpio.Low {
+		gpioMemory.clear[p.number/32] = mask
+	} else {
+		gpioMemory.set[p.number/32] = mask
+	}
+}
+
+//
+
+// isOut returns true if the pin is set as an output.
+func (p *Pin) isOut() bool {
+	return gpioMemory.ctrl[p.number/32]&(1<<uint(p.number%32)) != 0
+}
+
+// setGPIO switches the pin group to GPIO mode.
+func (p *Pin) setGPIO() error {
+	if p.group.get() == p.group.gpio {
+		return nil
+	}
+	if p.group.locked {
+		return p.wrap(fmt.Errorf("pin is reserved for %s", p.group.funcs[p.group.get()]))
+	}
+	p.group.set(p.group.gpio)
+	return nil
+}
+
+func (p *Pin) wrap(err error) error {
+	return fmt.Errorf("mt7688-gpio (%s): %v", p, err)
+}
+
+//
+
+// muxGroup is a group of pins sharing the same 2 bits field in the GPIO1_MODE
+// or GPIO2_MODE register.
+type muxGroup struct {
+	reg    int       // 0 for GPIO1_MODE, 1 for GPIO2_MODE
+	shift  uint      // Bit offset of the field in the register
+	gpio   uint32    // Value of the field selecting GPIO mode
+	funcs  [4]string // Function for each value; empty for GPIO or unknown
+	locked bool      // Set when the group must not be switched to GPIO
+}
+
+func (m *muxGroup) get() uint32 {
+	return (muxMemory.mode[m.reg] >> m.shift) & 3
+}
+
+func (m *muxGroup) set(v uint32) {
+	muxMemory.mode[m.reg] = (muxMemory.mode[m.reg] &^ (3 << m.shift)) | (v << m.shift)
+}
+
+// Pin groups, as described in the GPIO1_MODE and GPIO2_MODE registers.
+var (
+	muxGPIO   = muxGroup{reg: 0, shift: 0, gpio: 0, funcs: [4]string{"", "PERST", "REFCLK", "PCIE"}}
+	muxSPIS   = muxGroup{reg: 0, shift: 2, gpio: 1, funcs: [4]string{"SPIS", "", "UTIF", "PWM_UART2"}}
+	muxSPICS1 = muxGroup{reg: 0, shift: 4, gpio: 1, funcs: [4]string{"SPI_CS1", "", "REFCLK", ""}}
+	muxI2S    = muxGroup{reg: 0, shift: 6, gpio: 1, funcs: [4]string{"I2S", "", "PCM", "ANTSEL"}}
+	muxUART0  = muxGroup{reg: 0, shift: 8, gpio: 1, funcs: [4]string{"UART0", "", "", ""}}
+	muxSD     = muxGroup{reg: 0, shift: 10, gpio: 1, funcs: [4]string{"SDXC", "", "UTIF", "JTAG"}}
+	muxSPI    = muxGroup{reg: 0, shift: 12, gpio: 1, funcs: [4]string{"SPI", "", "", ""}, locked: true}
+	muxWDT    = muxGroup{reg: 0, shift: 14, gpio: 1, funcs: [4]string{"WDT", "", "", ""}}
+	muxPERST  = muxGroup{reg: 0, shift: 16, gpio: 1, funcs: [4]string{"PERST", "", "", ""}}
+	muxREFCLK = muxGroup{reg: 0, shift: 18, gpio: 1, funcs: [4]string{"REFCLK", "", "", ""}}
+	muxI2C    = muxGroup{reg: 0, shift: 20, gpio: 1, funcs: [4]string{"I2C", "", "DEBUG", ""}}
+	muxUART1  = muxGroup{reg: 0, shift: 24, gpio: 1, funcs: [4]string{"UART1", "", "PWM", "SW_R"}}
+	muxUART2  = muxGroup{reg: 0, shift: 26, gpio: 1, funcs: [4]string{"UART2", "", "PWM", "SDXC"}}
+	muxPWM0   = muxGroup{reg: 0, shift: 28, gpio: 1, funcs: [4]string{"PWM0", "", "UTIF", "SDXC"}}
+	muxPWM1   = muxGroup{reg: 0, shift: 30, gpio: 1, funcs: [4]string{"PWM1", "", "UTIF", "SDXC"}}
+	muxWLED   = muxGroup{reg: 1, shift: 0, gpio: 1, funcs: [4]string{"WLED", "", "", ""}}
+	muxP0LED  = muxGroup{reg: 1, shift: 2, gpio: 1, funcs: [4]string{"EPHY_LED", "", "", ""}}
+	muxP1LED  = muxGroup{reg: 1, shift: 4, gpio: 1, funcs: [4]string{"EPHY_LED", "", "", ""}}
+	muxP2LED  = muxGroup{reg: 1, shift: 6, gpio: 1, funcs: [4]string{"EPHY_LED", "", "", ""}}
+	muxP3LED  = muxGroup{reg: 1, shift: 8, gpio: 1, funcs: [4]string{"EPHY_LED", "", "", ""}}
+	muxP4LED  = muxGroup{reg: 1, shift: 10, gpio: 1, funcs: [4]string{"EPHY_LED", "", "", ""}}
+)
+
+// cpuPins is all the pins as supported by the CPU.
+var cpuPins = []*Pin{
+	GPIO0, GPIO1, GPIO2, GPIO3, GPIO4, GPIO5, GPIO6, GPIO7, GPIO8, GPIO9,
+	GPIO10, GPIO11, GPIO12, GPIO13, GPIO14, GPIO15, GPIO16, GPIO17, GPIO18, GPIO19,
+	GPIO20, GPIO21, GPIO22, GPIO23, GPIO24, GPIO25, GPIO26, GPIO27, GPIO28, GPIO29,
+	GPIO36, GPIO37, GPIO38, GPIO39, GPIO40, GPIO41, GPIO42, GPIO43, GPIO44, GPIO45,
+	GPIO46,
+}
+
+// Physical addresses of the registers, in the system control block.
+const (
+	muxBaseAddr  = 0x10000060
+	gpioBaseAddr = 0x10000600
+)
+
+var (
+	// muxMemory is the memory map of the pin multiplexing registers.
+	muxMemory *muxMap
+	// gpioMemory is the memory map of the GPIO registers.
+	gpioMemory *gpioMap
+)
+
+// muxMap is the pin multiplexing registers.
+type muxMap struct {
+	// 0x60 GPIO1_MODE and 0x64 GPIO2_MODE; 2 bits per group.
+	mode [2]uint32
+}
+
+// gpioMap is the GPIO registers. Each register has one bit per GPIO, with
+// GPIO0 to GPIO31 in the first word and GPIO32 to GPIO63 in the second. The
+// third word is used for GPIO64 to GPIO95 on the MT7620, which is not
+// supported.
+type gpioMap struct {
+	ctrl  [4]uint32 // 0x600 GPIO_CTRL_n; direction, 1 is output
+	pol   [4]uint32 // 0x610 GPIO_POL_n; polarity inversion
+	data  [4]uint32 // 0x620 GPIO_DATA_n
+	set   [4]uint32 // 0x630 GPIO_DSET_n; write 1 to set
+	clear [4]uint32 // 0x640 GPIO_DCLR_n; write 1 to clear
+}
+
+// driverGPIO implements periph.Driver.
+type driverGPIO struct {
+}
+
+func (d *driverGPIO) String() string {
+	return "mt7688-gpio"
+}
+
+func (d *driverGPIO) Prerequisites() []string {
+	return nil
+}
+
+func (d *driverGPIO) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("MT7688 CPU not detected")
+	}
+	if err := pmem.MapAsPOD(muxBaseAddr, &muxMemory); err != nil {
+		if os.IsPermission(err) {
+			return true, fmt.Errorf("need more access, try as root: %v", err)
+		}
+		return true, err
+	}
+	if err := pmem.MapAsPOD(gpioBaseAddr, &gpioMemory); err != nil {
+		return true, err
+	}
+	for _, p := range cpuPins {
+		if err := gpioreg.Register(p, true); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func init() {
+	if isMIPS {
+		periph.MustRegister(&driverGPIO{})
+	}
+}
+
+var _ gpio.PinDefaultPull = &Pin{}
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinIn = &Pin{}
+var _ gpio.PinOut = &Pin{}
+var _ periph.Driver = &driverGPIO{}
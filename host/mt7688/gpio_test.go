@@ -0,0 +1,136 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mt7688
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestPin(t *testing.T) {
+	defer func() {
+		muxMemory = nil
+		gpioMemory = nil
+	}()
+	p := GPIO5
+	if s := p.String(); s != "GPIO5" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != 5 {
+		t.Fatal(n)
+	}
+	if s := p.Function(); s != "N/A" {
+		t.Fatal(s)
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err == nil {
+		t.Fatal("not initialized")
+	}
+	if err := p.Out(gpio.High); err == nil {
+		t.Fatal("not initialized")
+	}
+	if l := p.Read(); l != gpio.Low {
+		t.Fatal(l)
+	}
+
+	muxMemory = &muxMap{}
+	gpioMemory = &gpioMap{}
+	// The I2C group starts in I2C mode.
+	if s := p.Function(); s != "I2C" {
+		t.Fatal(s)
+	}
+	if err := p.In(gpio.PullUp, gpio.NoEdge); err == nil {
+		t.Fatal("pull is not supported")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if v := muxMemory.mode[0]; v != 1<<20 {
+		t.Fatalf("%#x", v)
+	}
+	if s := GPIO4.Function(); s != "In/Low" {
+		t.Fatal(s)
+	}
+	if err := p.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if gpioMemory.set[0] != 1<<5 || gpioMemory.ctrl[0] != 1<<5 {
+		t.Fatalf("%#x %#x", gpioMemory.set[0], gpioMemory.ctrl[0])
+	}
+	// Fake the level being read back.
+	gpioMemory.data[0] = 1 << 5
+	if s := p.Function(); s != "Out/High" {
+		t.Fatal(s)
+	}
+	p.FastOut(gpio.Low)
+	if gpioMemory.clear[0] != 1<<5 {
+		t.Fatalf("%#x", gpioMemory.clear[0])
+	}
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if gpioMemory.ctrl[0] != 0 {
+		t.Fatalf("%#x", gpioMemory.ctrl[0])
+	}
+
+	// GPIO46 is in the second word.
+	if err := GPIO46.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if gpioMemory.clear[1] != 1<<14 || gpioMemory.ctrl[1] != 1<<14 {
+		t.Fatalf("%#x %#x", gpioMemory.clear[1], gpioMemory.ctrl[1])
+	}
+
+	// GPIO11 is in GPIO mode when its field is 0.
+	if s := GPIO11.Function(); s != "In/Low" {
+		t.Fatal(s)
+	}
+	// GPIO44 is in GPIO2_MODE.
+	muxMemory.mode[1] = 2
+	if s := GPIO44.Function(); s != "<Alt2>" {
+		t.Fatal(s)
+	}
+	if err := GPIO44.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if v := muxMemory.mode[1]; v != 1 {
+		t.Fatalf("%#x", v)
+	}
+
+	// The SPI flash must not be disconnected.
+	if err := GPIO8.Out(gpio.Low); err == nil || err.Error() != "mt7688-gpio (GPIO8): pin is reserved for SPI" {
+		t.Fatal(err)
+	}
+}
+
+func TestCPUPins(t *testing.T) {
+	seen := map[int]bool{}
+	for _, p := range cpuPins {
+		if p.number < 0 || p.number > 46 || (p.number >= 30 && p.number <= 35) || seen[p.number] {
+			t.Fatal(p)
+		}
+		seen[p.number] = true
+	}
+	if len(seen) != 41 {
+		t.Fatal(len(seen))
+	}
+}
+
+func TestDriver(t *testing.T) {
+	d := driverGPIO{}
+	if s := d.String(); s != "mt7688-gpio" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("MT7688 is not present")
+	}
+}
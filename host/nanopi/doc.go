@@ -0,0 +1,17 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package nanopi contains the header definitions of FriendlyARM NanoPi NEO
+// boards. It is intrinsically related to package allwinner.
+//
+// Supported boards are the NanoPi NEO and NEO Air (H3), and the NanoPi NEO2
+// and NEO Plus2 (H5). They all share the same 24 pins header, which is
+// documented as "GPIO1" by FriendlyARM and is exposed here as P1.
+//
+// Physical
+//
+// http://wiki.friendlyarm.com/wiki/index.php/NanoPi_NEO
+//
+// http://wiki.friendlyarm.com/wiki/index.php/NanoPi_NEO2
+package nanopi
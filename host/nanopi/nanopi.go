@@ -0,0 +1,131 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package nanopi
+
+import (
+	"errors"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/allwinner"
+	"periph.io/x/periph/host/distro"
+)
+
+// Present returns true if running on a NanoPi NEO board.
+//
+// It looks for the board in /proc/device-tree/compatible.
+//
+// http://www.nanopi.org/
+func Present() bool {
+	if isArm {
+		return dtIsCompatible(
+			"friendlyarm,nanopi-neo", "friendlyarm,nanopi-neo-air",
+			"friendlyarm,nanopi-neo2", "friendlyarm,nanopi-neo-plus2")
+	}
+	return false
+}
+
+// All the individual pins on the header.
+var (
+	P1_1  = pin.V3_3       //
+	P1_2  = pin.V5         //
+	P1_3  = allwinner.PA12 // I2C0_SDA
+	P1_4  = pin.V5         //
+	P1_5  = allwinner.PA11 // I2C0_SCL
+	P1_6  = pin.GROUND     //
+	P1_7  = allwinner.PG11 //
+	P1_8  = allwinner.PG6  // UART1_TX
+	P1_9  = pin.GROUND     //
+	P1_10 = allwinner.PG7  // UART1_RX
+	P1_11 = allwinner.PA0  // UART2_TX
+	P1_12 = allwinner.PA6  //
+	P1_13 = allwinner.PA2  // UART2_RTS
+	P1_14 = pin.GROUND     //
+	P1_15 = allwinner.PA3  // UART2_CTS
+	P1_16 = allwinner.PG8  // UART1_RTS
+	P1_17 = pin.V3_3       //
+	P1_18 = allwinner.PG9  // UART1_CTS
+	P1_19 = allwinner.PC0  // SPI0_MOSI
+	P1_20 = pin.GROUND     //
+	P1_21 = allwinner.PC1  // SPI0_MISO
+	P1_22 = allwinner.PA1  // UART2_RX
+	P1_23 = allwinner.PC2  // SPI0_CLK
+	P1_24 = allwinner.PC3  // SPI0_CS0
+)
+
+//
+
+// dtIsCompatible is distro.DTIsCompatible, overridden in tests.
+var dtIsCompatible = distro.DTIsCompatible
+
+// aliases is a list of aliases for the functions available on the header, so
+// the buses can be referred to by their documented names. The map key is the
+// alias and the value is the real pin.
+var aliases = map[string]*allwinner.Pin{
+	"I2C0_SDA":  allwinner.PA12,
+	"I2C0_SCL":  allwinner.PA11,
+	"UART1_TX":  allwinner.PG6,
+	"UART1_RX":  allwinner.PG7,
+	"UART1_RTS": allwinner.PG8,
+	"UART1_CTS": allwinner.PG9,
+	"UART2_TX":  allwinner.PA0,
+	"UART2_RX":  allwinner.PA1,
+	"UART2_RTS": allwinner.PA2,
+	"UART2_CTS": allwinner.PA3,
+	"SPI0_MOSI": allwinner.PC0,
+	"SPI0_MISO": allwinner.PC1,
+	"SPI0_CLK":  allwinner.PC2,
+	"SPI0_CS0":  allwinner.PC3,
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "nanopi"
+}
+
+func (d *driver) Prerequisites() []string {
+	return []string{"allwinner-gpio"}
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("NanoPi NEO board not detected")
+	}
+	if err := pinreg.Register("P1", [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+	}); err != nil {
+		return true, err
+	}
+	for alias, p := range aliases {
+		if err := gpioreg.RegisterAlias(alias, p.Name()); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,76 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package nanopi
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/host/allwinner"
+)
+
+func TestPresent(t *testing.T) {
+	defer func(f func(...string) bool) { dtIsCompatible = f }(dtIsCompatible)
+	var compatible []string
+	dtIsCompatible = func(c ...string) bool {
+		compatible = c
+		return true
+	}
+	if p := Present(); p != isArm {
+		t.Fatal(p)
+	}
+	if isArm && len(compatible) != 4 {
+		t.Fatal(compatible)
+	}
+	dtIsCompatible = func(...string) bool { return false }
+	if Present() {
+		t.Fatal("NanoPi is not present")
+	}
+}
+
+func TestDriver(t *testing.T) {
+	defer func(f func(...string) bool) { dtIsCompatible = f }(dtIsCompatible)
+	dtIsCompatible = func(...string) bool { return false }
+	d := driver{}
+	if s := d.String(); s != "nanopi" {
+		t.Fatal(s)
+	}
+	if p := d.Prerequisites(); len(p) != 1 || p[0] != "allwinner-gpio" {
+		t.Fatal(p)
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("NanoPi is not present")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	header := []pin.Pin{
+		P1_1, P1_2, P1_3, P1_4, P1_5, P1_6, P1_7, P1_8, P1_9, P1_10, P1_11, P1_12,
+		P1_13, P1_14, P1_15, P1_16, P1_17, P1_18, P1_19, P1_20, P1_21, P1_22, P1_23, P1_24,
+	}
+	seen := map[string]bool{}
+	for i, p := range header {
+		if p == nil || p.Name() == "" {
+			t.Fatalf("P1_%d is not set", i+1)
+		}
+		if _, ok := p.(gpio.PinIO); !ok {
+			continue
+		}
+		if seen[p.Name()] {
+			t.Fatalf("P1_%d: %s is connected twice", i+1, p)
+		}
+		seen[p.Name()] = true
+	}
+	if P1_3 != allwinner.PA12 || P1_24 != allwinner.PC3 {
+		t.Fatal(P1_3, P1_24)
+	}
+	// All the aliases point to a pin on the header.
+	for alias, p := range aliases {
+		if !seen[p.Name()] {
+			t.Fatalf("%s: %s is not on the header", alias, p)
+		}
+	}
+}
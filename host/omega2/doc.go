@@ -0,0 +1,15 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package omega2 contains the header definitions of the Onion Omega2 and
+// Omega2+, Wi-Fi modules based on the MediaTek MT7688. It is intrinsically
+// related to package mt7688.
+//
+// The module has two 16 pins headers. J1 is the left one and J2 the right one
+// when looking at the module from the top with the antenna connector up.
+//
+// The I²C bus, e.g. /dev/i2c-0 on J2_14 and J2_15, and the SPI port, e.g.
+// /dev/spidev0.1 on J1_10 to J1_13, are enumerated by package sysfs like on
+// any other linux host.
+package omega2
@@ -0,0 +1,150 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package omega2
+
+import (
+	"errors"
+	"strings"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/mt7688"
+)
+
+// Present returns true if running on an Onion Omega2 or Omega2+.
+//
+// It looks for the board in /proc/device-tree/compatible, then falls back to
+// the "machine" in /proc/cpuinfo, as OpenWrt kernels may not expose the
+// device tree.
+//
+// https://onion.io/omega2/
+func Present() bool {
+	if isMIPS {
+		if distro.DTIsCompatible("onion,omega2*") {
+			return true
+		}
+		return strings.HasPrefix(distro.CPUInfo()["machine"], "Onion Omega2")
+	}
+	return false
+}
+
+// Omega2 specific pins.
+var (
+	FW_RST   = &pin.BasicPin{N: "FW_RST"}  // Firmware reset; hold low to factory reset
+	ETH_RX_P = &pin.BasicPin{N: "ETH_RX+"} // Ethernet port 0
+	ETH_RX_N = &pin.BasicPin{N: "ETH_RX-"} //
+	ETH_TX_P = &pin.BasicPin{N: "ETH_TX+"} //
+	ETH_TX_N = &pin.BasicPin{N: "ETH_TX-"} //
+	USB_DP   = &pin.BasicPin{N: "USB_D+"}  // USB 2.0 host
+	USB_DN   = &pin.BasicPin{N: "USB_D-"}  //
+)
+
+// All the individual pins on the headers.
+var (
+	J1_1  = pin.GROUND    //
+	J1_2  = mt7688.GPIO11 //
+	J1_3  = mt7688.GPIO3  // I2S_CLK
+	J1_4  = mt7688.GPIO2  // I2S_WS
+	J1_5  = mt7688.GPIO17 // SPIS_MOSI
+	J1_6  = mt7688.GPIO16 // SPIS_MISO
+	J1_7  = mt7688.GPIO15 // SPIS_CLK
+	J1_8  = mt7688.GPIO46 // UART1_RX
+	J1_9  = mt7688.GPIO45 // UART1_TX
+	J1_10 = mt7688.GPIO9  // SPI_MISO
+	J1_11 = mt7688.GPIO8  // SPI_MOSI
+	J1_12 = mt7688.GPIO7  // SPI_CLK
+	J1_13 = mt7688.GPIO6  // SPI_CS1
+	J1_14 = mt7688.GPIO1  // I2S_SDO
+	J1_15 = mt7688.GPIO0  // I2S_SDI
+	J1_16 = FW_RST        //
+
+	J2_1  = pin.GROUND    //
+	J2_2  = pin.V3_3      // VIN
+	J2_3  = ETH_RX_N      //
+	J2_4  = ETH_RX_P      //
+	J2_5  = ETH_TX_N      //
+	J2_6  = ETH_TX_P      //
+	J2_7  = USB_DP        //
+	J2_8  = USB_DN        //
+	J2_9  = mt7688.GPIO12 // UART0_TX
+	J2_10 = mt7688.GPIO13 // UART0_RX
+	J2_11 = mt7688.GPIO14 // SPIS_CS
+	J2_12 = mt7688.GPIO18 // PWM0
+	J2_13 = mt7688.GPIO19 // PWM1
+	J2_14 = mt7688.GPIO4  // I2C_SCL
+	J2_15 = mt7688.GPIO5  // I2C_SDA
+	J2_16 = pin.GROUND    //
+)
+
+//
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "omega2"
+}
+
+func (d *driver) Prerequisites() []string {
+	return []string{"mt7688-gpio"}
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("Omega2 board not detected")
+	}
+	if err := pinreg.Register("J1", [][]pin.Pin{
+		{J1_1},
+		{J1_2},
+		{J1_3},
+		{J1_4},
+		{J1_5},
+		{J1_6},
+		{J1_7},
+		{J1_8},
+		{J1_9},
+		{J1_10},
+		{J1_11},
+		{J1_12},
+		{J1_13},
+		{J1_14},
+		{J1_15},
+		{J1_16},
+	}); err != nil {
+		return true, err
+	}
+	if err := pinreg.Register("J2", [][]pin.Pin{
+		{J2_1},
+		{J2_2},
+		{J2_3},
+		{J2_4},
+		{J2_5},
+		{J2_6},
+		{J2_7},
+		{J2_8},
+		{J2_9},
+		{J2_10},
+		{J2_11},
+		{J2_12},
+		{J2_13},
+		{J2_14},
+		{J2_15},
+		{J2_16},
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func init() {
+	if isMIPS {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
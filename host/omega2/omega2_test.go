@@ -0,0 +1,24 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package omega2
+
+import "testing"
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "omega2" {
+		t.Fatal(s)
+	}
+	if p := d.Prerequisites(); len(p) != 1 || p[0] != "mt7688-gpio" {
+		t.Fatal(p)
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("Omega2 is not present")
+	}
+}
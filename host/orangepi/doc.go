@@ -0,0 +1,19 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package orangepi contains the header definitions of common Orange Pi boards.
+// It is intrinsically related to package allwinner.
+//
+// Supported boards are the Orange Pi Zero (H2+), Orange Pi PC (H3) and Orange
+// Pi 3 LTS (H6). The board is detected from the device tree, as provided by
+// Armbian or the Orange Pi images.
+//
+// Physical
+//
+// http://www.orangepi.org/html/hardWare/computerAndMicrocontrollers/details/Orange-Pi-Zero.html
+//
+// http://www.orangepi.org/html/hardWare/computerAndMicrocontrollers/details/Orange-Pi-PC.html
+//
+// http://www.orangepi.org/html/hardWare/computerAndMicrocontrollers/details/Orange-Pi-3-LTS.html
+package orangepi
@@ -0,0 +1,215 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package orangepi
+
+import (
+	"errors"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/allwinner"
+	"periph.io/x/periph/host/distro"
+)
+
+// Present returns true if running on a supported Orange Pi board.
+//
+// It looks for the board in /proc/device-tree/compatible.
+//
+// http://www.orangepi.org/
+func Present() bool {
+	return isArm && detect() != ""
+}
+
+// All the individual pins on the header.
+//
+// The default values are set as the 40 pins header on the Orange Pi PC. The
+// Orange Pi Zero and Orange Pi 3 LTS have a 26 pins header, P1_27 to P1_40 are
+// set to INVALID on these boards.
+var (
+	P1_1  pin.Pin    = pin.V3_3       //
+	P1_2  pin.Pin    = pin.V5         //
+	P1_3  gpio.PinIO = allwinner.PA12 // I2C0_SDA
+	P1_4  pin.Pin    = pin.V5         //
+	P1_5  gpio.PinIO = allwinner.PA11 // I2C0_SCL
+	P1_6  pin.Pin    = pin.GROUND     //
+	P1_7  gpio.PinIO = allwinner.PA6  // PWM1
+	P1_8  gpio.PinIO = allwinner.PA13 // UART3_TX
+	P1_9  pin.Pin    = pin.GROUND     //
+	P1_10 gpio.PinIO = allwinner.PA14 // UART3_RX
+	P1_11 gpio.PinIO = allwinner.PA1  // UART2_RX
+	P1_12 gpio.PinIO = allwinner.PD14 //
+	P1_13 gpio.PinIO = allwinner.PA0  // UART2_TX
+	P1_14 pin.Pin    = pin.GROUND     //
+	P1_15 gpio.PinIO = allwinner.PA3  // UART2_CTS
+	P1_16 gpio.PinIO = allwinner.PC4  //
+	P1_17 pin.Pin    = pin.V3_3       //
+	P1_18 gpio.PinIO = allwinner.PC7  //
+	P1_19 gpio.PinIO = allwinner.PC0  // SPI0_MOSI
+	P1_20 pin.Pin    = pin.GROUND     //
+	P1_21 gpio.PinIO = allwinner.PC1  // SPI0_MISO
+	P1_22 gpio.PinIO = allwinner.PA2  // UART2_RTS
+	P1_23 gpio.PinIO = allwinner.PC2  // SPI0_CLK
+	P1_24 gpio.PinIO = allwinner.PC3  // SPI0_CS0
+	P1_25 pin.Pin    = pin.GROUND     //
+	P1_26 gpio.PinIO = allwinner.PA21 //
+	P1_27 gpio.PinIO = allwinner.PA19 // I2C1_SDA
+	P1_28 gpio.PinIO = allwinner.PA18 // I2C1_SCL
+	P1_29 gpio.PinIO = allwinner.PA7  //
+	P1_30 pin.Pin    = pin.GROUND     //
+	P1_31 gpio.PinIO = allwinner.PA8  //
+	P1_32 gpio.PinIO = allwinner.PG8  // UART1_RTS
+	P1_33 gpio.PinIO = allwinner.PA9  //
+	P1_34 pin.Pin    = pin.GROUND     //
+	P1_35 gpio.PinIO = allwinner.PA10 //
+	P1_36 gpio.PinIO = allwinner.PG9  // UART1_CTS
+	P1_37 gpio.PinIO = allwinner.PA20 //
+	P1_38 gpio.PinIO = allwinner.PG6  // UART1_TX
+	P1_39 pin.Pin    = pin.GROUND     //
+	P1_40 gpio.PinIO = allwinner.PG7  // UART1_RX
+)
+
+//
+
+// Device tree compatible strings of the supported boards.
+const (
+	boardZero = "xunlong,orangepi-zero"
+	boardPC   = "xunlong,orangepi-pc"
+	board3LTS = "xunlong,orangepi-3-lts"
+)
+
+// dtMatch is distro.DTMatch, overridden in tests.
+var dtMatch = distro.DTMatch
+
+// detect returns the device tree compatible string of the board, or "" if
+// not running on a supported Orange Pi board.
+func detect() string {
+	return dtMatch(boardZero, boardPC, board3LTS)
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "orangepi"
+}
+
+func (d *driver) Prerequisites() []string {
+	return []string{"allwinner-gpio-pl"}
+}
+
+func (d *driver) Init() (bool, error) {
+	has40PinP1Header := false
+	switch detect() {
+	case boardZero:
+		setZero()
+	case boardPC:
+		has40PinP1Header = true
+	case board3LTS:
+		set3LTS()
+	default:
+		return false, errors.New("Orange Pi board not detected")
+	}
+	if err := pinreg.Register("P1", header(has40PinP1Header)); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// header returns the P1 header, with only the first 26 pins unless
+// has40PinP1Header is set.
+func header(has40PinP1Header bool) [][]pin.Pin {
+	h := [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}
+	if !has40PinP1Header {
+		h = h[:13]
+	}
+	return h
+}
+
+// setZero sets the 26 pins header of the Orange Pi Zero.
+func setZero() {
+	P1_8 = allwinner.PG6   // UART1_TX
+	P1_10 = allwinner.PG7  // UART1_RX
+	P1_12 = allwinner.PA7  //
+	P1_16 = allwinner.PA19 // I2C1_SDA
+	P1_18 = allwinner.PA18 // I2C1_SCL
+	P1_19 = allwinner.PA15 // SPI1_MOSI
+	P1_21 = allwinner.PA16 // SPI1_MISO
+	P1_23 = allwinner.PA14 // SPI1_CLK
+	P1_24 = allwinner.PA13 // SPI1_CS0
+	P1_26 = allwinner.PA10 //
+	invalidate40Pins()
+}
+
+// set3LTS sets the 26 pins header of the Orange Pi 3 LTS.
+func set3LTS() {
+	P1_3 = allwinner.PD26  // I2C0_SDA
+	P1_5 = allwinner.PD25  // I2C0_SCL
+	P1_7 = allwinner.PD22  // PWM0
+	P1_8 = allwinner.PL2   // S_UART_TX
+	P1_10 = allwinner.PL3  // S_UART_RX
+	P1_11 = allwinner.PD24 // UART3_RX
+	P1_12 = allwinner.PD18 //
+	P1_13 = allwinner.PD23 // UART3_TX
+	P1_15 = allwinner.PL10 //
+	P1_16 = allwinner.PD15 //
+	P1_18 = allwinner.PD16 //
+	P1_19 = allwinner.PH5  // SPI1_MOSI
+	P1_21 = allwinner.PH6  // SPI1_MISO
+	P1_22 = allwinner.PD21 //
+	P1_23 = allwinner.PH4  // SPI1_CLK
+	P1_24 = allwinner.PH3  // SPI1_CS0
+	P1_26 = allwinner.PL8  //
+	invalidate40Pins()
+}
+
+// invalidate40Pins marks the pins only found on 40 pins headers as INVALID.
+func invalidate40Pins() {
+	P1_27 = gpio.INVALID
+	P1_28 = gpio.INVALID
+	P1_29 = gpio.INVALID
+	P1_30 = pin.INVALID
+	P1_31 = gpio.INVALID
+	P1_32 = gpio.INVALID
+	P1_33 = gpio.INVALID
+	P1_34 = pin.INVALID
+	P1_35 = gpio.INVALID
+	P1_36 = gpio.INVALID
+	P1_37 = gpio.INVALID
+	P1_38 = gpio.INVALID
+	P1_39 = pin.INVALID
+	P1_40 = gpio.INVALID
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
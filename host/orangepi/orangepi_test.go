@@ -0,0 +1,147 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package orangepi
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/host/allwinner"
+)
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestDriver(t *testing.T) {
+	defer func(f func(...string) string) { dtMatch = f }(dtMatch)
+	dtMatch = func(...string) string { return "" }
+	d := driver{}
+	if s := d.String(); s != "orangepi" {
+		t.Fatal(s)
+	}
+	if p := d.Prerequisites(); len(p) != 1 || p[0] != "allwinner-gpio-pl" {
+		t.Fatal(p)
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("Orange Pi is not present")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	defer func(f func(...string) string) { dtMatch = f }(dtMatch)
+	var compatible []string
+	board := ""
+	dtMatch = func(c ...string) string {
+		compatible = c
+		return board
+	}
+	for _, board = range []string{boardZero, boardPC, board3LTS, ""} {
+		if b := detect(); b != board {
+			t.Fatal(b)
+		}
+	}
+	if len(compatible) != 3 {
+		t.Fatal(compatible)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	defer restoreHeader(saveHeader())
+	h := header(true)
+	if len(h) != 20 {
+		t.Fatal(len(h))
+	}
+	checkHeader(t, h)
+	if P1_3 != allwinner.PA12 || P1_40 != allwinner.PG7 {
+		t.Fatal(P1_3, P1_40)
+	}
+
+	setZero()
+	h = header(false)
+	if len(h) != 13 {
+		t.Fatal(len(h))
+	}
+	checkHeader(t, h)
+	if P1_3 != allwinner.PA12 || P1_24 != allwinner.PA13 || P1_26 != allwinner.PA10 {
+		t.Fatal(P1_3, P1_24, P1_26)
+	}
+	if P1_27 != gpio.INVALID || P1_39 != pin.INVALID {
+		t.Fatal(P1_27, P1_39)
+	}
+}
+
+func TestHeader_3LTS(t *testing.T) {
+	defer restoreHeader(saveHeader())
+	set3LTS()
+	h := header(false)
+	if len(h) != 13 {
+		t.Fatal(len(h))
+	}
+	checkHeader(t, h)
+	if P1_3 != allwinner.PD26 || P1_8 != allwinner.PL2 || P1_24 != allwinner.PH3 {
+		t.Fatal(P1_3, P1_8, P1_24)
+	}
+	if P1_40 != gpio.INVALID {
+		t.Fatal(P1_40)
+	}
+}
+
+//
+
+// checkHeader verifies that every pin on the header is set and that no gpio
+// is connected twice.
+func checkHeader(t *testing.T, h [][]pin.Pin) {
+	seen := map[string]bool{}
+	for i, row := range h {
+		for j, p := range row {
+			if p == nil || p.Name() == "" {
+				t.Fatalf("P1_%d is not set", 2*i+j+1)
+			}
+			if _, ok := p.(gpio.PinIO); !ok || p.Name() == "INVALID" {
+				continue
+			}
+			if seen[p.Name()] {
+				t.Fatalf("P1_%d: %s is connected twice", 2*i+j+1, p)
+			}
+			seen[p.Name()] = true
+		}
+	}
+}
+
+// headerVars returns pointers to all the P1_x variables.
+func headerVars() []interface{} {
+	return []interface{}{
+		&P1_1, &P1_2, &P1_3, &P1_4, &P1_5, &P1_6, &P1_7, &P1_8, &P1_9, &P1_10,
+		&P1_11, &P1_12, &P1_13, &P1_14, &P1_15, &P1_16, &P1_17, &P1_18, &P1_19, &P1_20,
+		&P1_21, &P1_22, &P1_23, &P1_24, &P1_25, &P1_26, &P1_27, &P1_28, &P1_29, &P1_30,
+		&P1_31, &P1_32, &P1_33, &P1_34, &P1_35, &P1_36, &P1_37, &P1_38, &P1_39, &P1_40,
+	}
+}
+
+func saveHeader() []pin.Pin {
+	var s []pin.Pin
+	for _, v := range headerVars() {
+		switch p := v.(type) {
+		case *pin.Pin:
+			s = append(s, *p)
+		case *gpio.PinIO:
+			s = append(s, *p)
+		}
+	}
+	return s
+}
+
+func restoreHeader(s []pin.Pin) {
+	for i, v := range headerVars() {
+		switch p := v.(type) {
+		case *pin.Pin:
+			*p = s[i]
+		case *gpio.PinIO:
+			*p = s[i].(gpio.PinIO)
+		}
+	}
+}
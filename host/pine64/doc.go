@@ -5,11 +5,16 @@
 // Package pine64 contains Pine64 hardware logic. It is intrinsically
 // related to package a64.
 //
-// Requires Armbian Jessie Server.
+// The Pine A64, Pine A64+ and Pine A64-LTS share the same headers: the
+// Raspberry Pi 2 compatible P1 header, the Euler bus and the EXP connector.
+//
+// Requires Armbian Jessie Server or a kernel providing the device tree.
 //
 // Physical
 //
 // http://files.pine64.org/doc/Pine%20A64%20Schematic/Pine%20A64%20Pin%20Assignment%20160119.pdf
 //
 // http://wiki.pine64.org/images/2/2e/Pine64_Board_Connector_heatsink.png
+//
+// https://wiki.pine64.org/wiki/PINE_A64-LTS
 package pine64
@@ -12,13 +12,20 @@ import (
 	"periph.io/x/periph/conn/pin"
 	"periph.io/x/periph/conn/pin/pinreg"
 	"periph.io/x/periph/host/allwinner"
+	"periph.io/x/periph/host/distro"
 )
 
 // Present returns true if running on a Pine64 board.
 //
+// It looks for the board in /proc/device-tree/compatible, which detects the
+// Pine A64, Pine A64+ and Pine A64-LTS, then falls back to /boot/pine64.dtb.
+//
 // https://www.pine64.org/
 func Present() bool {
 	if isArm {
+		if distro.DTIsCompatible("pine64,pine64", "pine64,pine64-plus", "pine64,pine64-lts") {
+			return true
+		}
 		// This is iffy at best.
 		_, err := os.Stat("/boot/pine64.dtb")
 		return err == nil
@@ -8,6 +8,8 @@ import (
 	"io"
 	"reflect"
 	"unsafe"
+
+	"periph.io/x/periph"
 )
 
 const pageSize = 4096
@@ -59,6 +61,7 @@ type MemAlloc struct {
 
 // Close unmaps the physical memory allocation.
 func (m *MemAlloc) Close() error {
+	m.stopTracking()
 	if err := munlock(m.orig); err != nil {
 		return err
 	}
@@ -136,7 +139,9 @@ func allocLinux(size int) (*MemAlloc, error) {
 		}
 	}
 
-	return &MemAlloc{View{Slice: b, phys: pages[0], orig: b}}, nil
+	m := &MemAlloc{View{Slice: b, phys: pages[0], orig: b}}
+	m.untrack = periph.Track(m)
+	return m, nil
 }
 
 // virtToPhys returns the physical memory address backing a virtual
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"unsafe"
 
+	"periph.io/x/periph"
 	"periph.io/x/periph/host/fs"
 )
 
@@ -77,15 +78,18 @@ func (s *Slice) AsPOD(pp interface{}) error {
 // shutdown.
 type View struct {
 	Slice
-	orig []uint8 // Reference rounded to the lowest 4Kb page containing Slice.
-	phys uint64  // physical address of the base of Slice.
+	orig    []uint8 // Reference rounded to the lowest 4Kb page containing Slice.
+	phys    uint64  // physical address of the base of Slice.
+	untrack func()  // Stops tracking for periph.Shutdown()
 }
 
 // Close unmaps the memory from the user address space.
 //
 // This is done naturally by the OS on process teardown (when the process
-// exits) so this is not a hard requirement to call this function.
+// exits) so this is not a hard requirement to call this function. It is also
+// done by periph.Shutdown().
 func (v *View) Close() error {
+	v.stopTracking()
 	return munmap(v.orig)
 }
 
@@ -180,6 +184,8 @@ func mapGPIOLinux() (*View, error) {
 			defer f.Close()
 			if i, err := mmap(f.Fd(), 0, pageSize); err == nil {
 				gpioMemView = &View{Slice: i, orig: i, phys: 0}
+				// The view is shared, release it only on shutdown.
+				periph.Track(periph.CloserFunc(closeGPIOMem))
 			} else {
 				gpioMemErr = wrapf("failed to memory map in user space GPIO memory: %v", err)
 			}
@@ -202,7 +208,29 @@ func mapLinux(base uint64, size int) (*View, error) {
 	if err != nil {
 		return nil, wrapf("mapping at 0x%x failed: %v", base, err)
 	}
-	return &View{Slice: i[offset : offset+size], orig: i, phys: base + uint64(offset)}, nil
+	v := &View{Slice: i[offset : offset+size], orig: i, phys: base + uint64(offset)}
+	v.untrack = periph.Track(v)
+	return v, nil
+}
+
+// closeGPIOMem unmaps the view returned by mapGPIOLinux().
+func closeGPIOMem() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if gpioMemView == nil {
+		return nil
+	}
+	err := munmap(gpioMemView.orig)
+	gpioMemView = nil
+	return err
+}
+
+// stopTracking stops tracking the view for periph.Shutdown().
+func (v *View) stopTracking() {
+	if v.untrack != nil {
+		v.untrack()
+		v.untrack = nil
+	}
 }
 
 func openDevMemLinux() (fileIO, error) {
@@ -0,0 +1,65 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pru
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Pulse is a pulse measured by the PRU.
+type Pulse struct {
+	Level    gpio.Level
+	Duration time.Duration
+}
+
+func (p Pulse) String() string {
+	return fmt.Sprintf("%s:%s", p.Level, p.Duration)
+}
+
+// CapturePulses asks a PRU running a firmware that implements the capture
+// message to time the next n pulses on its input pin.
+//
+// The resolution is 5ns. Less than n pulses are returned if the timeout
+// expires.
+func (p *PRU) CapturePulses(n int, timeout time.Duration) ([]Pulse, error) {
+	if n <= 0 || n > 0xFFFF {
+		return nil, p.wrap(fmt.Errorf("invalid number of pulses %d", n))
+	}
+	msg := make([]byte, 7)
+	msg[0] = cmdCapture
+	binary.LittleEndian.PutUint16(msg[1:], uint16(n))
+	binary.LittleEndian.PutUint32(msg[3:], uint32(timeout/time.Millisecond))
+	if err := p.Send(msg); err != nil {
+		return nil, err
+	}
+	out := make([]Pulse, 0, n)
+	var r [MaxMessage]byte
+	for len(out) < n {
+		l, err := p.Recv(r[:])
+		if err != nil {
+			return out, err
+		}
+		if l < 2 || r[0] != cmdCapture || l < 2+4*int(r[1]) {
+			return out, errShortReply
+		}
+		if r[1] == 0 {
+			break
+		}
+		for i := 0; i < int(r[1]); i++ {
+			v := binary.LittleEndian.Uint32(r[2+4*i:])
+			out = append(out, Pulse{Level: gpio.Level(v&(1<<31) != 0), Duration: time.Duration(v&^(1<<31)) * cycle})
+		}
+	}
+	return out, nil
+}
+
+//
+
+// cycle is the duration of a PRU clock cycle at 200MHz.
+const cycle = 5 * time.Nanosecond
@@ -0,0 +1,42 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package pru controls the two PRU (Programmable Real-time Unit) cores of the
+// TI AM335x found on the BeagleBone and PocketBeagle.
+//
+// The PRUs are 200MHz microcontrollers with direct access to some pins, which
+// makes them suitable for protocols that need deterministic timing and cannot
+// be bit-banged reliably from linux, e.g. WS2812 LEDs or pulse measurement.
+//
+// The firmware is loaded via the kernel remoteproc framework and the PRU
+// communicates with the host via rpmsg, as /dev/rpmsg_pru30 for PRU0 and
+// /dev/rpmsg_pru31 for PRU1. This requires a TI kernel with the pru_rproc and
+// rpmsg_pru modules loaded, which is the default on BeagleBoard images. The
+// PRU pins must be configured for the PRU with beagle.SetPinmux, e.g. state
+// "pruout" or "pruin".
+//
+// Firmware
+//
+// This package doesn't ship firmware blobs; they are built with TI's PRU
+// code generation tools. WS2812 and CapturePulses expect the firmware to
+// implement the following messages, all integers are little endian:
+//
+// - 0x01 WS2812 data: offset uint16 followed by up to 492 bytes of GRB
+// data to store at offset in the PRU memory. No reply.
+//
+// - 0x02 WS2812 show: length uint16; the firmware sends the length bytes
+// stored then replies 0x02 once the latch delay elapsed.
+//
+// - 0x03 capture: count uint16 and timeout in ms uint32; the firmware times
+// the next count pulses on its input pin and replies with one or more
+// messages 0x03, number of pulses uint8 followed by one uint32 per pulse:
+// the bit 31 is the level and the bits 30:0 are the duration in 5ns cycles.
+// A reply with 0 pulses indicates the timeout expired.
+//
+// Datasheet
+//
+// https://www.ti.com/lit/ug/spruh73q/spruh73q.pdf
+//
+// https://beagleboard.org/pru
+package pru
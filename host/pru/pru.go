@@ -0,0 +1,208 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pru
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxMessage is the maximum size of a message exchanged with a PRU.
+const MaxMessage = 496
+
+// PRU is one of the two PRU cores.
+type PRU struct {
+	// Immutable.
+	n     int
+	rproc string // e.g. /sys/class/remoteproc/remoteproc1
+
+	// Mutable.
+	mu  sync.Mutex
+	msg io.ReadWriteCloser // rpmsg channel, opened on first use.
+}
+
+// Open returns the PRU n, which is 0 or 1.
+//
+// It doesn't change the state of the PRU; use Load to start a firmware.
+func Open(n int) (*PRU, error) {
+	if n != 0 && n != 1 {
+		return nil, fmt.Errorf("pru: invalid PRU %d", n)
+	}
+	items, err := filepath.Glob(filepath.Join(remoteprocRoot, "remoteproc*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		b, err := ioutil.ReadFile(filepath.Join(item, "name"))
+		if err == nil && strings.TrimSpace(string(b)) == rprocNames[n] {
+			return &PRU{n: n, rproc: item}, nil
+		}
+	}
+	return nil, fmt.Errorf("pru: PRU%d not found; is pru_rproc loaded?", n)
+}
+
+func (p *PRU) String() string {
+	return fmt.Sprintf("PRU%d", p.n)
+}
+
+// State returns the remoteproc state of the PRU, e.g. "offline" or
+// "running".
+func (p *PRU) State() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(p.rproc, "state"))
+	if err != nil {
+		return "", p.wrap(err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Load copies the firmware to /lib/firmware/name then starts it on the PRU.
+//
+// The PRU is stopped first if it is running. Pass a nil blob to start a
+// firmware already present in /lib/firmware.
+func (p *PRU) Load(name string, blob []byte) error {
+	if name == "" || strings.ContainsRune(name, '/') {
+		return p.wrap(fmt.Errorf("invalid firmware name %q", name))
+	}
+	if err := p.Stop(); err != nil {
+		return err
+	}
+	if blob != nil {
+		if err := ioutil.WriteFile(filepath.Join(firmwareRoot, name), blob, 0644); err != nil {
+			return p.wrap(err)
+		}
+	}
+	if err := p.write("firmware", name); err != nil {
+		return err
+	}
+	return p.write("state", "start")
+}
+
+// Stop closes the message channel and stops the PRU if it is running.
+func (p *PRU) Stop() error {
+	if err := p.Close(); err != nil {
+		return err
+	}
+	s, err := p.State()
+	if err != nil {
+		return err
+	}
+	if s != "running" {
+		return nil
+	}
+	return p.write("state", "stop")
+}
+
+// Close closes the message channel. The PRU is left running.
+func (p *PRU) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.msg == nil {
+		return nil
+	}
+	err := p.msg.Close()
+	p.msg = nil
+	if err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// Send sends a message to the firmware.
+//
+// The rpmsg channel is created by the firmware once it started, so the first
+// call waits up to one second for it.
+func (p *PRU) Send(msg []byte) error {
+	if len(msg) == 0 || len(msg) > MaxMessage {
+		return p.wrap(fmt.Errorf("invalid message size %d", len(msg)))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.open(); err != nil {
+		return err
+	}
+	if _, err := p.msg.Write(msg); err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// Recv waits for a message from the firmware and returns its size.
+//
+// msg should be MaxMessage bytes long; a larger message is truncated.
+func (p *PRU) Recv(msg []byte) (int, error) {
+	p.mu.Lock()
+	if err := p.open(); err != nil {
+		p.mu.Unlock()
+		return 0, err
+	}
+	m := p.msg
+	p.mu.Unlock()
+	// Do not hold the lock while blocked, so Close can be called.
+	n, err := m.Read(msg)
+	if err != nil {
+		return n, p.wrap(err)
+	}
+	return n, nil
+}
+
+//
+
+// rprocNames is the remoteproc name of each PRU on the AM335x.
+var rprocNames = []string{"4a334000.pru", "4a338000.pru"}
+
+var (
+	remoteprocRoot = "/sys/class/remoteproc"
+	firmwareRoot   = "/lib/firmware"
+	// rpmsgPath is the rpmsg_pru device of each PRU.
+	rpmsgPath = []string{"/dev/rpmsg_pru30", "/dev/rpmsg_pru31"}
+	rpmsgOpen = rpmsgOpenDefault
+)
+
+func rpmsgOpenDefault(path string) (io.ReadWriteCloser, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+// open opens the rpmsg channel if needed. p.mu must be held.
+func (p *PRU) open() error {
+	if p.msg != nil {
+		return nil
+	}
+	for start := time.Now(); ; {
+		f, err := rpmsgOpen(rpmsgPath[p.n])
+		if err == nil {
+			p.msg = f
+			return nil
+		}
+		if !os.IsNotExist(err) || time.Since(start) > time.Second {
+			return p.wrap(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (p *PRU) write(file, value string) error {
+	f, err := os.OpenFile(filepath.Join(p.rproc, file), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return p.wrap(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(value); err != nil {
+		return p.wrap(fmt.Errorf("failed to write %q to %s: %v", value, file, err))
+	}
+	return nil
+}
+
+func (p *PRU) wrap(err error) error {
+	return fmt.Errorf("pru (%s): %v", p, err)
+}
+
+var errShortReply = errors.New("pru: invalid reply from the firmware")
@@ -0,0 +1,219 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pru
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestOpen(t *testing.T) {
+	d := setup(t)
+	defer os.RemoveAll(d)
+	if _, err := Open(2); err == nil {
+		t.Fatal("invalid PRU")
+	}
+	if _, err := Open(0); err == nil {
+		t.Fatal("PRU0 not present")
+	}
+	p, err := Open(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.String(); s != "PRU1" {
+		t.Fatal(s)
+	}
+	if s, err := p.State(); err != nil || s != "offline" {
+		t.Fatal(s, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	d := setup(t)
+	defer os.RemoveAll(d)
+	p, err := Open(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Load("../foo", nil); err == nil {
+		t.Fatal("invalid name")
+	}
+	if err := p.Load("ws2812.out", []byte("blob")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(firmwareRoot, "ws2812.out")); err != nil || string(b) != "blob" {
+		t.Fatal(string(b), err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(p.rproc, "firmware")); string(b) != "ws2812.out" {
+		t.Fatal(string(b))
+	}
+	if s, _ := p.State(); s != "start" {
+		t.Fatal(s)
+	}
+	// Simulate the kernel.
+	if err := ioutil.WriteFile(filepath.Join(p.rproc, "state"), []byte("running\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := p.State(); s != "stop" {
+		t.Fatal(s)
+	}
+}
+
+func TestSendRecv(t *testing.T) {
+	d := setup(t)
+	defer os.RemoveAll(d)
+	p, _ := Open(1)
+	f := &fakeRPMsg{r: [][]byte{{0x42}}}
+	defer func(o func(string) (io.ReadWriteCloser, error)) { rpmsgOpen = o }(rpmsgOpen)
+	rpmsgOpen = func(path string) (io.ReadWriteCloser, error) {
+		if path != "/dev/rpmsg_pru31" {
+			t.Fatal(path)
+		}
+		return f, nil
+	}
+	if err := p.Send(nil); err == nil {
+		t.Fatal("empty message")
+	}
+	if err := p.Send(make([]byte, MaxMessage+1)); err == nil {
+		t.Fatal("message too large")
+	}
+	if err := p.Send([]byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	var b [MaxMessage]byte
+	if n, err := p.Recv(b[:]); err != nil || n != 1 || b[0] != 0x42 {
+		t.Fatal(n, err)
+	}
+	if len(f.w) != 1 || !bytes.Equal(f.w[0], []byte{1, 2}) {
+		t.Fatalf("%#v", f.w)
+	}
+	if err := p.Close(); err != nil || !f.closed {
+		t.Fatal(err)
+	}
+}
+
+func TestWS2812(t *testing.T) {
+	p, f := newFake(t, [][]byte{{cmdWS2812Show}})
+	w := NewWS2812(p)
+	if s := w.String(); s != "PRU0.WS2812" {
+		t.Fatal(s)
+	}
+	if _, err := w.Write([]byte{1, 2}); err == nil {
+		t.Fatal("not 3 bytes per LED")
+	}
+	grb := make([]byte, 600)
+	grb[599] = 0xFF
+	if n, err := w.Write(grb); err != nil || n != 600 {
+		t.Fatal(n, err)
+	}
+	if len(f.w) != 3 {
+		t.Fatal(len(f.w))
+	}
+	if len(f.w[0]) != MaxMessage-1 || f.w[0][0] != cmdWS2812Data {
+		t.Fatal(len(f.w[0]))
+	}
+	if !bytes.Equal(f.w[1][:3], []byte{cmdWS2812Data, 0xEC, 0x01}) || len(f.w[1]) != 3+108 || f.w[1][110] != 0xFF {
+		t.Fatalf("%#v", f.w[1][:3])
+	}
+	if !bytes.Equal(f.w[2], []byte{cmdWS2812Show, 0x58, 0x02}) {
+		t.Fatalf("%#v", f.w[2])
+	}
+	if _, err := w.Write(nil); err == nil {
+		t.Fatal("no reply")
+	}
+}
+
+func TestCapturePulses(t *testing.T) {
+	p, f := newFake(t, [][]byte{
+		{cmdCapture, 2, 0xC8, 0, 0, 0x80, 0x64, 0, 0, 0},
+		{cmdCapture, 0},
+	})
+	if _, err := p.CapturePulses(0, time.Second); err == nil {
+		t.Fatal("invalid count")
+	}
+	pulses, err := p.CapturePulses(3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Pulse{{gpio.High, time.Microsecond}, {gpio.Low, 500 * time.Nanosecond}}
+	if len(pulses) != 2 || pulses[0] != expected[0] || pulses[1] != expected[1] {
+		t.Fatal(pulses)
+	}
+	if s := pulses[0].String(); s != "High:1µs" {
+		t.Fatal(s)
+	}
+	if !bytes.Equal(f.w[0], []byte{cmdCapture, 3, 0, 0xE8, 0x03, 0, 0}) {
+		t.Fatalf("%#v", f.w[0])
+	}
+	f.r = [][]byte{{cmdCapture, 1}}
+	if _, err := p.CapturePulses(1, time.Second); err == nil {
+		t.Fatal("short reply")
+	}
+}
+
+//
+
+// setup creates a fake remoteproc tree with PRU1 only.
+func setup(t *testing.T) string {
+	d, err := ioutil.TempDir("", "pru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteprocRoot = filepath.Join(d, "remoteproc")
+	firmwareRoot = filepath.Join(d, "firmware")
+	for _, p := range []string{filepath.Join(remoteprocRoot, "remoteproc2"), firmwareRoot} {
+		if err := os.MkdirAll(p, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := filepath.Join(remoteprocRoot, "remoteproc2")
+	for name, v := range map[string]string{"name": "4a338000.pru\n", "state": "offline\n", "firmware": ""} {
+		if err := ioutil.WriteFile(filepath.Join(r, name), []byte(v), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+func newFake(t *testing.T, r [][]byte) (*PRU, *fakeRPMsg) {
+	f := &fakeRPMsg{r: r}
+	return &PRU{n: 0, msg: f}, f
+}
+
+// fakeRPMsg implements io.ReadWriteCloser.
+type fakeRPMsg struct {
+	w      [][]byte
+	r      [][]byte
+	closed bool
+}
+
+func (f *fakeRPMsg) Read(b []byte) (int, error) {
+	if len(f.r) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, f.r[0])
+	f.r = f.r[1:]
+	return n, nil
+}
+
+func (f *fakeRPMsg) Write(b []byte) (int, error) {
+	f.w = append(f.w, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (f *fakeRPMsg) Close() error {
+	f.closed = true
+	return nil
+}
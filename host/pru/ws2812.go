@@ -0,0 +1,72 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pru
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// WS2812 drives a strip of WS2812 LEDs from a PRU running a firmware that
+// implements the WS2812 messages.
+type WS2812 struct {
+	p *PRU
+}
+
+// NewWS2812 returns a WS2812 strip connected to the PRU.
+func NewWS2812(p *PRU) *WS2812 {
+	return &WS2812{p: p}
+}
+
+func (w *WS2812) String() string {
+	return w.p.String() + ".WS2812"
+}
+
+// Write sends the GRB data, 3 bytes per LED, and waits for the firmware to
+// latch it.
+func (w *WS2812) Write(grb []byte) (int, error) {
+	if len(grb)%3 != 0 {
+		return 0, fmt.Errorf("pru: WS2812 data must be 3 bytes per LED; got %d bytes", len(grb))
+	}
+	if len(grb) > 0xFFFF {
+		return 0, fmt.Errorf("pru: WS2812 data too large; got %d bytes", len(grb))
+	}
+	const chunk = MaxMessage - 4
+	for i := 0; i < len(grb); i += chunk {
+		end := i + chunk
+		if end > len(grb) {
+			end = len(grb)
+		}
+		msg := make([]byte, 3, 3+end-i)
+		msg[0] = cmdWS2812Data
+		binary.LittleEndian.PutUint16(msg[1:], uint16(i))
+		if err := w.p.Send(append(msg, grb[i:end]...)); err != nil {
+			return i, err
+		}
+	}
+	msg := []byte{cmdWS2812Show, 0, 0}
+	binary.LittleEndian.PutUint16(msg[1:], uint16(len(grb)))
+	if err := w.p.Send(msg); err != nil {
+		return 0, err
+	}
+	var r [MaxMessage]byte
+	n, err := w.p.Recv(r[:])
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || r[0] != cmdWS2812Show {
+		return 0, errShortReply
+	}
+	return len(grb), nil
+}
+
+//
+
+// Message types.
+const (
+	cmdWS2812Data = 0x01
+	cmdWS2812Show = 0x02
+	cmdCapture    = 0x03
+)
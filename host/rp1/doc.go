@@ -0,0 +1,16 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package rp1 exposes the GPIO functionality of the RP1 I/O controller, as
+// found on the Raspberry Pi 5.
+//
+// The RP1 is connected to the BCM2712 CPU over PCIe, so none of the bcm283x
+// memory mapped registers apply. The pins are accessed through the Linux GPIO
+// character device via package gpioioctl, which also provides the edge
+// detection.
+//
+// Datasheet
+//
+// https://datasheets.raspberrypi.com/rp1/rp1-peripherals.pdf
+package rp1
@@ -0,0 +1,157 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package rp1
+
+import (
+	"errors"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+// All the pins of the RP1 bank 0, which are connected to the 40 pins header.
+//
+// They are accessed through the GPIO character device, so they are only set
+// once the driver is initialized on a Raspberry Pi 5.
+var (
+	GPIO0  *gpioioctl.Pin // I2C0_SDA
+	GPIO1  *gpioioctl.Pin // I2C0_SCL
+	GPIO2  *gpioioctl.Pin // I2C1_SDA
+	GPIO3  *gpioioctl.Pin // I2C1_SCL
+	GPIO4  *gpioioctl.Pin // GPCLK0
+	GPIO5  *gpioioctl.Pin // GPCLK1
+	GPIO6  *gpioioctl.Pin // GPCLK2
+	GPIO7  *gpioioctl.Pin // SPI0_CS1
+	GPIO8  *gpioioctl.Pin // SPI0_CS0
+	GPIO9  *gpioioctl.Pin // SPI0_MISO
+	GPIO10 *gpioioctl.Pin // SPI0_MOSI
+	GPIO11 *gpioioctl.Pin // SPI0_CLK
+	GPIO12 *gpioioctl.Pin // PWM0_CHAN0
+	GPIO13 *gpioioctl.Pin // PWM0_CHAN1
+	GPIO14 *gpioioctl.Pin // UART0_TXD
+	GPIO15 *gpioioctl.Pin // UART0_RXD
+	GPIO16 *gpioioctl.Pin // UART0_CTS, SPI1_CS2
+	GPIO17 *gpioioctl.Pin // UART0_RTS, SPI1_CS1
+	GPIO18 *gpioioctl.Pin // SPI1_CS0, PWM0_CHAN2
+	GPIO19 *gpioioctl.Pin // SPI1_MISO, PWM0_CHAN3
+	GPIO20 *gpioioctl.Pin // SPI1_MOSI, GPCLK0
+	GPIO21 *gpioioctl.Pin // SPI1_CLK, GPCLK1
+	GPIO22 *gpioioctl.Pin //
+	GPIO23 *gpioioctl.Pin //
+	GPIO24 *gpioioctl.Pin //
+	GPIO25 *gpioioctl.Pin //
+	GPIO26 *gpioioctl.Pin //
+	GPIO27 *gpioioctl.Pin //
+)
+
+// Present returns true if running on a Raspberry Pi 5, which has a BCM2712
+// CPU connected to a RP1 I/O controller.
+func Present() bool {
+	if isArm {
+		return distro.DTIsCompatible("brcm,bcm2712")
+	}
+	return false
+}
+
+// cpuPins is all the pins of the RP1 bank 0.
+var cpuPins = []struct {
+	name        string
+	defaultPull gpio.Pull
+}{
+	{"GPIO0", gpio.PullUp},
+	{"GPIO1", gpio.PullUp},
+	{"GPIO2", gpio.PullUp},
+	{"GPIO3", gpio.PullUp},
+	{"GPIO4", gpio.PullUp},
+	{"GPIO5", gpio.PullUp},
+	{"GPIO6", gpio.PullUp},
+	{"GPIO7", gpio.PullUp},
+	{"GPIO8", gpio.PullUp},
+	{"GPIO9", gpio.PullDown},
+	{"GPIO10", gpio.PullDown},
+	{"GPIO11", gpio.PullDown},
+	{"GPIO12", gpio.PullDown},
+	{"GPIO13", gpio.PullDown},
+	{"GPIO14", gpio.PullDown},
+	{"GPIO15", gpio.PullDown},
+	{"GPIO16", gpio.PullDown},
+	{"GPIO17", gpio.PullDown},
+	{"GPIO18", gpio.PullDown},
+	{"GPIO19", gpio.PullDown},
+	{"GPIO20", gpio.PullDown},
+	{"GPIO21", gpio.PullDown},
+	{"GPIO22", gpio.PullDown},
+	{"GPIO23", gpio.PullDown},
+	{"GPIO24", gpio.PullDown},
+	{"GPIO25", gpio.PullDown},
+	{"GPIO26", gpio.PullDown},
+	{"GPIO27", gpio.PullDown},
+}
+
+// driverGPIO implements periph.Driver.
+type driverGPIO struct {
+}
+
+func (d *driverGPIO) String() string {
+	return "rp1-gpio"
+}
+
+func (d *driverGPIO) Prerequisites() []string {
+	return nil
+}
+
+func (d *driverGPIO) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("RP1 I/O controller not detected")
+	}
+	c, err := gpioioctl.Find("pinctrl-rp1")
+	if err != nil {
+		return true, err
+	}
+	pins := make([]*gpioioctl.Pin, len(cpuPins))
+	all := make([]gpio.PinIO, len(cpuPins))
+	for i, p := range cpuPins {
+		pins[i] = c.Pin(i, p.name, p.defaultPull)
+		all[i] = pins[i]
+	}
+	if err := register(all); err != nil {
+		return true, err
+	}
+	GPIO0, GPIO1, GPIO2, GPIO3 = pins[0], pins[1], pins[2], pins[3]
+	GPIO4, GPIO5, GPIO6, GPIO7 = pins[4], pins[5], pins[6], pins[7]
+	GPIO8, GPIO9, GPIO10, GPIO11 = pins[8], pins[9], pins[10], pins[11]
+	GPIO12, GPIO13, GPIO14, GPIO15 = pins[12], pins[13], pins[14], pins[15]
+	GPIO16, GPIO17, GPIO18, GPIO19 = pins[16], pins[17], pins[18], pins[19]
+	GPIO20, GPIO21, GPIO22, GPIO23 = pins[20], pins[21], pins[22], pins[23]
+	GPIO24, GPIO25, GPIO26, GPIO27 = pins[24], pins[25], pins[26], pins[27]
+	return true, nil
+}
+
+// register registers pins in gpioreg.
+//
+// On failure, the pins already registered are unregistered so the driver can
+// be initialized again.
+func register(pins []gpio.PinIO) error {
+	for i, p := range pins {
+		if err := gpioreg.Register(p, true); err != nil {
+			for _, q := range pins[:i] {
+				gpioreg.UnregisterPin(q)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	if isArm {
+		periph.MustRegister(&driverGPIO{})
+	}
+}
+
+var _ periph.Driver = &driverGPIO{}
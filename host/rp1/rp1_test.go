@@ -0,0 +1,77 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package rp1
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+)
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestDriver(t *testing.T) {
+	d := driverGPIO{}
+	if s := d.String(); s != "rp1-gpio" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("RP1 is not present")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	pins := []gpio.PinIO{
+		&gpiotest.Pin{N: "GPIO0", Num: 0},
+		&gpiotest.Pin{N: "GPIO1", Num: 1},
+		&gpiotest.Pin{N: "GPIO2", Num: 2},
+	}
+	if err := register(pins); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pins {
+		if q := gpioreg.ByName(p.Name()); q != p {
+			t.Fatal(q)
+		}
+		if err := gpioreg.UnregisterPin(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegister_rollback(t *testing.T) {
+	if err := gpioreg.Register(&gpiotest.Pin{N: "GPIO2", Num: 2}, true); err != nil {
+		t.Fatal(err)
+	}
+	defer gpioreg.Unregister("GPIO2")
+	pins := []gpio.PinIO{
+		&gpiotest.Pin{N: "GPIO0", Num: 0},
+		&gpiotest.Pin{N: "GPIO1", Num: 1},
+		&gpiotest.Pin{N: "GPIO2", Num: 2},
+	}
+	if err := register(pins); err == nil {
+		t.Fatal("GPIO2 is already registered")
+	}
+	for _, p := range pins[:2] {
+		if q := gpioreg.ByName(p.Name()); q != nil {
+			t.Fatal(q)
+		}
+	}
+	// Once the conflict is gone, registering again succeeds.
+	gpioreg.Unregister("GPIO2")
+	if err := register(pins); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pins {
+		gpioreg.UnregisterPin(p)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"periph.io/x/periph/conn/pin/pinreg"
 	"periph.io/x/periph/host/bcm283x"
 	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/rp1"
 )
 
 // Present returns true if running on a Raspberry Pi board.
@@ -38,7 +39,13 @@ func Present() bool {
 // https://www.raspberrypi.org/documentation/hardware/raspberrypi/schematics/README.md
 //
 // The actual pin mapping depends on the board revision! The default values are
-// set as the 40 pins header on Raspberry Pi 2 and Raspberry Pi 3.
+// set as the 40 pins header on Raspberry Pi 2, 3 and 4.
+//
+// The Raspberry Pi 4 BCM2711 adds I2C3~I2C6, SPI3~SPI6 and UART2~UART5 on the
+// 40 pins header, as listed in the comments with "Pi4:".
+//
+// On the Raspberry Pi 5, the header pins are the RP1 GPIOs with the same
+// numbers, e.g. P1_3 is rp1.GPIO2.
 //
 // Some header info here: http://elinux.org/RPi_Low-level_peripherals
 //
@@ -47,46 +54,46 @@ var (
 	// Raspberry Pi A and B, 26 pin header:
 	P1_1  pin.Pin    = pin.V3_3       // max 30mA
 	P1_2  pin.Pin    = pin.V5         // (filtered)
-	P1_3  gpio.PinIO = bcm283x.GPIO2  // High, I2C1_SDA
+	P1_3  gpio.PinIO = bcm283x.GPIO2  // High, I2C1_SDA; Pi4: SPI3_MOSI, UART2_CTS, I2C3_SDA
 	P1_4  pin.Pin    = pin.V5         //
-	P1_5  gpio.PinIO = bcm283x.GPIO3  // High, I2C1_SCL
+	P1_5  gpio.PinIO = bcm283x.GPIO3  // High, I2C1_SCL; Pi4: SPI3_CLK, UART2_RTS, I2C3_SCL
 	P1_6  pin.Pin    = pin.GROUND     //
-	P1_7  gpio.PinIO = bcm283x.GPIO4  // High, GPCLK0
-	P1_8  gpio.PinIO = bcm283x.GPIO14 // Low,  UART0_TXD, UART1_TXD
+	P1_7  gpio.PinIO = bcm283x.GPIO4  // High, GPCLK0; Pi4: SPI4_CE0, UART3_TXD, I2C3_SDA
+	P1_8  gpio.PinIO = bcm283x.GPIO14 // Low,  UART0_TXD, UART1_TXD; Pi4: SPI5_MOSI, UART5_CTS
 	P1_9  pin.Pin    = pin.GROUND     //
-	P1_10 gpio.PinIO = bcm283x.GPIO15 // Low,  UART0_RXD, UART1_RXD
+	P1_10 gpio.PinIO = bcm283x.GPIO15 // Low,  UART0_RXD, UART1_RXD; Pi4: SPI5_CLK, UART5_RTS
 	P1_11 gpio.PinIO = bcm283x.GPIO17 // Low,  UART0_RTS, SPI1_CE1, UART1_RTS
-	P1_12 gpio.PinIO = bcm283x.GPIO18 // Low,  PCM_CLK, SPI1_CE0, PWM0_OUT
-	P1_13 gpio.PinIO = bcm283x.GPIO27 // Low,
+	P1_12 gpio.PinIO = bcm283x.GPIO18 // Low,  PCM_CLK, SPI1_CE0, PWM0_OUT; Pi4: SPI6_CE0
+	P1_13 gpio.PinIO = bcm283x.GPIO27 // Low,  Pi4: SPI6_CE1
 	P1_14 pin.Pin    = pin.GROUND     //
-	P1_15 gpio.PinIO = bcm283x.GPIO22 // Low,
-	P1_16 gpio.PinIO = bcm283x.GPIO23 // Low,
+	P1_15 gpio.PinIO = bcm283x.GPIO22 // Low,  Pi4: I2C6_SDA
+	P1_16 gpio.PinIO = bcm283x.GPIO23 // Low,  Pi4: I2C6_SCL
 	P1_17 pin.Pin    = pin.V3_3       //
-	P1_18 gpio.PinIO = bcm283x.GPIO24 // Low,
-	P1_19 gpio.PinIO = bcm283x.GPIO10 // Low, SPI0_MOSI
+	P1_18 gpio.PinIO = bcm283x.GPIO24 // Low,  Pi4: SPI3_CE1
+	P1_19 gpio.PinIO = bcm283x.GPIO10 // Low, SPI0_MOSI; Pi4: UART4_CTS, I2C5_SDA
 	P1_20 pin.Pin    = pin.GROUND     //
-	P1_21 gpio.PinIO = bcm283x.GPIO9  // Low, SPI0_MISO
-	P1_22 gpio.PinIO = bcm283x.GPIO25 // Low,
-	P1_23 gpio.PinIO = bcm283x.GPIO11 // Low, SPI0_CLK
-	P1_24 gpio.PinIO = bcm283x.GPIO8  // High, SPI0_CE0
+	P1_21 gpio.PinIO = bcm283x.GPIO9  // Low, SPI0_MISO; Pi4: UART4_RXD, I2C4_SCL
+	P1_22 gpio.PinIO = bcm283x.GPIO25 // Low,  Pi4: SPI4_CE1
+	P1_23 gpio.PinIO = bcm283x.GPIO11 // Low, SPI0_CLK; Pi4: UART4_RTS, I2C5_SCL
+	P1_24 gpio.PinIO = bcm283x.GPIO8  // High, SPI0_CE0; Pi4: UART4_TXD, I2C4_SDA
 	P1_25 pin.Pin    = pin.GROUND     //
-	P1_26 gpio.PinIO = bcm283x.GPIO7  // High, SPI0_CE1
+	P1_26 gpio.PinIO = bcm283x.GPIO7  // High, SPI0_CE1; Pi4: SPI4_CLK, UART3_RTS, I2C4_SCL
 
 	// Raspberry Pi A+, B+, 2 and later, 40 pin header (also named J8):
 	P1_27 gpio.PinIO = bcm283x.GPIO0  // High, I2C0_SDA used to probe for HAT EEPROM, see https://github.com/raspberrypi/hats
 	P1_28 gpio.PinIO = bcm283x.GPIO1  // High, I2C0_SCL
-	P1_29 gpio.PinIO = bcm283x.GPIO5  // High, GPCLK1
+	P1_29 gpio.PinIO = bcm283x.GPIO5  // High, GPCLK1; Pi4: SPI4_MISO, UART3_RXD, I2C3_SCL
 	P1_30 pin.Pin    = pin.GROUND     //
-	P1_31 gpio.PinIO = bcm283x.GPIO6  // High, GPCLK2
-	P1_32 gpio.PinIO = bcm283x.GPIO12 // Low,  PWM0_OUT
-	P1_33 gpio.PinIO = bcm283x.GPIO13 // Low,  PWM1_OUT
+	P1_31 gpio.PinIO = bcm283x.GPIO6  // High, GPCLK2; Pi4: SPI4_MOSI, UART3_CTS, I2C4_SDA
+	P1_32 gpio.PinIO = bcm283x.GPIO12 // Low,  PWM0_OUT; Pi4: SPI5_CE0, UART5_TXD, I2C5_SDA
+	P1_33 gpio.PinIO = bcm283x.GPIO13 // Low,  PWM1_OUT; Pi4: SPI5_MISO, UART5_RXD, I2C5_SCL
 	P1_34 pin.Pin    = pin.GROUND     //
-	P1_35 gpio.PinIO = bcm283x.GPIO19 // Low,  PCM_FS, SPI1_MISO, PWM1_OUT
+	P1_35 gpio.PinIO = bcm283x.GPIO19 // Low,  PCM_FS, SPI1_MISO, PWM1_OUT; Pi4: SPI6_MISO
 	P1_36 gpio.PinIO = bcm283x.GPIO16 // Low,  UART0_CTS, SPI1_CE2, UART1_CTS
-	P1_37 gpio.PinIO = bcm283x.GPIO26 //
-	P1_38 gpio.PinIO = bcm283x.GPIO20 // Low,  PCM_DIN, SPI1_MOSI, GPCLK0
+	P1_37 gpio.PinIO = bcm283x.GPIO26 //       Pi4: SPI5_CE1
+	P1_38 gpio.PinIO = bcm283x.GPIO20 // Low,  PCM_DIN, SPI1_MOSI, GPCLK0; Pi4: SPI6_MOSI
 	P1_39 pin.Pin    = pin.GROUND     //
-	P1_40 gpio.PinIO = bcm283x.GPIO21 // Low,  PCM_DOUT, SPI1_CLK, GPCLK1
+	P1_40 gpio.PinIO = bcm283x.GPIO21 // Low,  PCM_DOUT, SPI1_CLK, GPCLK1; Pi4: SPI6_CLK
 
 	// P5 header on Raspberry Pi A and B, PCB v2:
 	P5_1 pin.Pin    = pin.V5
@@ -122,8 +129,8 @@ func (d *driver) Init() (bool, error) {
 
 	// Setup headers based on board revision.
 	//
-	// This code is not futureproof, it will error out on a Raspberry Pi 4
-	// whenever it comes out.
+	// This code is not futureproof, it will error out on boards released after
+	// the Raspberry Pi 4.
 	// Revision codes from: http://elinux.org/RPi_HardwareHistory
 	has26PinP1Header := false
 	has40PinP1Header := false
@@ -174,6 +181,21 @@ func (d *driver) Init() (bool, error) {
 			hasAudio = true
 			hasNewAudio = true
 			hasHDMI = true
+		case 0xa03111, // 4 Model B v1.1 1GB
+			0xb03111, 0xb03112, 0xb03114, // 4 Model B v1.1, v1.2, v1.4 2GB
+			0xc03111, 0xc03112, 0xc03114, // 4 Model B v1.1, v1.2, v1.4 4GB
+			0xd03114,                     // 4 Model B v1.4 8GB
+			0xb03115, 0xc03115, 0xd03115: // 4 Model B v1.5 2GB, 4GB, 8GB
+			has40PinP1Header = true
+			hasAudio = true
+			hasNewAudio = true
+			// The HDMI hotplug detection is handled by the HDMI controllers, not
+			// GPIO46.
+		case 0xc03130: // 400 v1.0
+			// The keyboard has the 40 pins header but no audio jack.
+			has40PinP1Header = true
+		case 0xa03140, 0xb03140, 0xc03140, 0xd03140: // Compute Module 4 v1.0
+			// NOTE: Could define the use of the 2x100 pins connectors here.
 		default:
 			return true, fmt.Errorf("rpi: unknown hardware version: 0x%x", i)
 		}
@@ -182,7 +204,7 @@ func (d *driver) Init() (bool, error) {
 	}
 
 	if has26PinP1Header {
-		if err := pinreg.Register("P1", [][]pin.Pin{
+		if err := pinreg.RegisterHeader("P1", pinreg.Header{Voltage: 3300, Pins: [][]pin.Pin{
 			{P1_1, P1_2},
 			{P1_3, P1_4},
 			{P1_5, P1_6},
@@ -196,7 +218,7 @@ func (d *driver) Init() (bool, error) {
 			{P1_21, P1_22},
 			{P1_23, P1_24},
 			{P1_25, P1_26},
-		}); err != nil {
+		}}); err != nil {
 			return true, err
 		}
 
@@ -218,28 +240,7 @@ func (d *driver) Init() (bool, error) {
 		P1_39 = pin.INVALID
 		P1_40 = gpio.INVALID
 	} else if has40PinP1Header {
-		if err := pinreg.Register("P1", [][]pin.Pin{
-			{P1_1, P1_2},
-			{P1_3, P1_4},
-			{P1_5, P1_6},
-			{P1_7, P1_8},
-			{P1_9, P1_10},
-			{P1_11, P1_12},
-			{P1_13, P1_14},
-			{P1_15, P1_16},
-			{P1_17, P1_18},
-			{P1_19, P1_20},
-			{P1_21, P1_22},
-			{P1_23, P1_24},
-			{P1_25, P1_26},
-			{P1_27, P1_28},
-			{P1_29, P1_30},
-			{P1_31, P1_32},
-			{P1_33, P1_34},
-			{P1_35, P1_36},
-			{P1_37, P1_38},
-			{P1_39, P1_40},
-		}); err != nil {
+		if err := register40PinP1Header(); err != nil {
 			return true, err
 		}
 	} else {
@@ -296,14 +297,7 @@ func (d *driver) Init() (bool, error) {
 			return true, err
 		}
 	} else {
-		P5_1 = pin.INVALID
-		P5_2 = pin.INVALID
-		P5_3 = gpio.INVALID
-		P5_4 = gpio.INVALID
-		P5_5 = gpio.INVALID
-		P5_6 = gpio.INVALID
-		P5_7 = pin.INVALID
-		P5_8 = pin.INVALID
+		invalidateP5Header()
 	}
 
 	if hasAudio {
@@ -326,10 +320,120 @@ func (d *driver) Init() (bool, error) {
 	return true, nil
 }
 
+// driver5 implements periph.Driver for the Raspberry Pi 5.
+//
+// The Raspberry Pi 5 GPIOs are on the RP1 I/O controller instead of the
+// BCM2712 CPU, so it depends on a different GPIO driver.
+type driver5 struct {
+}
+
+func (d *driver5) String() string {
+	return "rpi5"
+}
+
+func (d *driver5) Prerequisites() []string {
+	return []string{"rp1-gpio"}
+}
+
+func (d *driver5) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("Raspberry Pi board not detected")
+	}
+	rev := distro.CPUInfo()["Revision"]
+	i, err := strconv.ParseInt(rev, 16, 32)
+	if err != nil {
+		return true, fmt.Errorf("rpi: failed to read cpu_info: %v", err)
+	}
+	// Ignore the overclock bit.
+	i &= 0xFFFFFF
+	switch i {
+	case 0xb04170, 0xc04170, 0xd04170, // 5 Model B v1.0 2GB, 4GB, 8GB
+		0xb04171, 0xc04171, 0xd04171, 0xe04171: // 5 Model B v1.1 2GB, 4GB, 8GB, 16GB
+	default:
+		return true, fmt.Errorf("rpi: unknown hardware version: 0x%x", i)
+	}
+
+	P1_3 = rp1.GPIO2
+	P1_5 = rp1.GPIO3
+	P1_7 = rp1.GPIO4
+	P1_8 = rp1.GPIO14
+	P1_10 = rp1.GPIO15
+	P1_11 = rp1.GPIO17
+	P1_12 = rp1.GPIO18
+	P1_13 = rp1.GPIO27
+	P1_15 = rp1.GPIO22
+	P1_16 = rp1.GPIO23
+	P1_18 = rp1.GPIO24
+	P1_19 = rp1.GPIO10
+	P1_21 = rp1.GPIO9
+	P1_22 = rp1.GPIO25
+	P1_23 = rp1.GPIO11
+	P1_24 = rp1.GPIO8
+	P1_26 = rp1.GPIO7
+	P1_27 = rp1.GPIO0
+	P1_28 = rp1.GPIO1
+	P1_29 = rp1.GPIO5
+	P1_31 = rp1.GPIO6
+	P1_32 = rp1.GPIO12
+	P1_33 = rp1.GPIO13
+	P1_35 = rp1.GPIO19
+	P1_36 = rp1.GPIO16
+	P1_37 = rp1.GPIO26
+	P1_38 = rp1.GPIO20
+	P1_40 = rp1.GPIO21
+	if err := register40PinP1Header(); err != nil {
+		return true, err
+	}
+	// There is no analog audio output and the HDMI hotplug detection is not
+	// done through GPIOs.
+	invalidateP5Header()
+	return true, nil
+}
+
+// register40PinP1Header registers the 40 pins header found on the A+, B+, 2
+// and later.
+func register40PinP1Header() error {
+	return pinreg.RegisterHeader("P1", pinreg.Header{Voltage: 3300, Pins: [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}})
+}
+
+func invalidateP5Header() {
+	P5_1 = pin.INVALID
+	P5_2 = pin.INVALID
+	P5_3 = gpio.INVALID
+	P5_4 = gpio.INVALID
+	P5_5 = gpio.INVALID
+	P5_6 = gpio.INVALID
+	P5_7 = pin.INVALID
+	P5_8 = pin.INVALID
+}
+
 func init() {
 	if isArm {
 		periph.MustRegister(&driver{})
+		periph.MustRegister(&driver5{})
 	}
 }
 
 var _ periph.Driver = &driver{}
+var _ periph.Driver = &driver5{}
@@ -0,0 +1,15 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package soc reports the features of the host at runtime: the SoC model, the
+// physical base addresses of its peripherals, the hardware PWM, SPI and I²C
+// available and which Linux kernel drivers are available.
+//
+// The SoC host drivers, e.g. bcm283x and allwinner, register the SoC
+// information during their initialization, so Get() is only meaningful after
+// host.Init() was called.
+//
+// This lets applications and smoke tests adapt to the host instead of failing
+// at first use.
+package soc
@@ -0,0 +1,161 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package soc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/sysfs"
+)
+
+// Peripheral is a block of memory mapped registers of the SoC.
+type Peripheral struct {
+	Name string `json:"name"` // e.g. "GPIO" or "PWM"
+	Base uint64 `json:"base"` // Physical base address
+}
+
+func (p Peripheral) String() string {
+	return fmt.Sprintf("%s@0x%X", p.Name, p.Base)
+}
+
+// Info is the information about the SoC registered by its host drivers.
+type Info struct {
+	// Model is the SoC model, e.g. "BCM2711" or "H3".
+	Model string `json:"model,omitempty"`
+	// Peripherals is the memory mapped peripherals used by the drivers.
+	Peripherals []Peripheral `json:"peripherals,omitempty"`
+	// PWM is the pins supporting hardware PWM, e.g. "GPIO18".
+	PWM []string `json:"pwm,omitempty"`
+}
+
+// KernelDriver is a Linux kernel driver that periph can use.
+type KernelDriver struct {
+	Name      string `json:"name"`      // e.g. "i2c-dev"
+	Path      string `json:"path"`      // Device or sysfs path pattern, e.g. "/dev/i2c-*"
+	Available bool   `json:"available"` // At least one file matches Path
+}
+
+// Report is the features of the host.
+type Report struct {
+	// SoC is the SoC information, if a SoC host driver was loaded.
+	SoC Info `json:"soc"`
+	// Board is the board model as reported by the device tree or DMI, e.g.
+	// "Raspberry Pi 3 Model B Rev 1.2". It is empty when unknown.
+	Board string `json:"board,omitempty"`
+	// I2C is the name of the I²C buses registered, e.g. "I2C1".
+	I2C []string `json:"i2c,omitempty"`
+	// SPI is the name of the SPI ports registered, e.g. "SPI0.0".
+	SPI []string `json:"spi,omitempty"`
+	// PWM is the hardware PWM outputs, including the SoC pins and the channels
+	// of the PWM controllers exposed by sysfs, e.g. "pwmchip0/pwm0".
+	PWM []string `json:"pwm,omitempty"`
+	// Kernel is the kernel drivers periph can use and whether they are
+	// available.
+	Kernel []KernelDriver `json:"kernel"`
+}
+
+// Register registers the information about the SoC detected by a host driver.
+//
+// It can be called by multiple drivers of the same SoC, e.g. the GPIO driver
+// sets the model and a PWM driver adds its peripheral. The model cannot be
+// changed once set.
+func Register(i Info) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if i.Model != "" && info.Model != "" && i.Model != info.Model {
+		return fmt.Errorf("soc: can't register SoC %q; %q is already registered", i.Model, info.Model)
+	}
+	for _, p := range i.Peripherals {
+		for _, q := range info.Peripherals {
+			if p.Name == q.Name {
+				return fmt.Errorf("soc: peripheral %q was already registered", p.Name)
+			}
+		}
+	}
+	if i.Model != "" {
+		info.Model = i.Model
+	}
+	info.Peripherals = append(info.Peripherals, i.Peripherals...)
+	info.PWM = append(info.PWM, i.PWM...)
+	return nil
+}
+
+// Get returns the features of the host.
+//
+// It is meaningful only after host.Init() was called, as the drivers
+// register the SoC information and the buses during their initialization.
+func Get() Report {
+	r := Report{Board: board()}
+	func() {
+		mu.Lock()
+		defer mu.Unlock()
+		r.SoC = Info{
+			Model:       info.Model,
+			Peripherals: append([]Peripheral(nil), info.Peripherals...),
+			PWM:         append([]string(nil), info.PWM...),
+		}
+	}()
+	for _, ref := range i2creg.All() {
+		r.I2C = append(r.I2C, ref.Name)
+	}
+	for _, ref := range spireg.All() {
+		r.SPI = append(r.SPI, ref.Name)
+	}
+	r.PWM = append(r.PWM, r.SoC.PWM...)
+	for _, p := range sysfs.PWMs {
+		r.PWM = append(r.PWM, p.Name())
+	}
+	r.Kernel = make([]KernelDriver, 0, len(kernelDrivers))
+	for _, k := range kernelDrivers {
+		k.Available = exists(k.Path)
+		r.Kernel = append(r.Kernel, k)
+	}
+	return r
+}
+
+//
+
+var (
+	mu   sync.Mutex
+	info Info
+)
+
+// kernelDrivers is the kernel drivers periph can use.
+var kernelDrivers = []KernelDriver{
+	{Name: "gpio-cdev", Path: "/dev/gpiochip*"},
+	{Name: "gpio-sysfs", Path: "/sys/class/gpio/export"},
+	{Name: "gpiomem", Path: "/dev/gpiomem"},
+	{Name: "mem", Path: "/dev/mem"},
+	{Name: "i2c-dev", Path: "/dev/i2c-*"},
+	{Name: "spidev", Path: "/dev/spidev*"},
+	{Name: "pwm", Path: "/sys/class/pwm/pwmchip*"},
+	{Name: "leds", Path: "/sys/class/leds/*"},
+	{Name: "thermal", Path: "/sys/class/thermal/thermal_zone*"},
+}
+
+// exists returns true if at least one file matches pattern.
+func exists(pattern string) bool {
+	items, err := filepath.Glob(pattern)
+	return err == nil && len(items) != 0
+}
+
+// board returns the board model, or "" if unknown.
+func board() string {
+	if m := distro.DTModel(); m != "" && m != "<unknown>" {
+		return m
+	}
+	if n := distro.DMIBoardName(); n != "" && n != "<unknown>" {
+		if v := distro.DMIBoardVendor(); v != "" && v != "<unknown>" {
+			return v + " " + n
+		}
+		return n
+	}
+	return ""
+}
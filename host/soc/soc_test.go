@@ -0,0 +1,81 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package soc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	defer reset()
+	if err := Register(Info{Model: "BCM2837", Peripherals: []Peripheral{{"GPIO", 0x3F200000}}, PWM: []string{"GPIO18"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Another driver of the same SoC.
+	if err := Register(Info{Peripherals: []Peripheral{{"PWM", 0x3F20C000}}, PWM: []string{"GPIO19"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(Info{Model: "H3"}); err == nil {
+		t.Fatal("another SoC")
+	}
+	if err := Register(Info{Peripherals: []Peripheral{{"GPIO", 0}}}); err == nil {
+		t.Fatal("same peripheral")
+	}
+	r := Get()
+	expected := Info{
+		Model:       "BCM2837",
+		Peripherals: []Peripheral{{"GPIO", 0x3F200000}, {"PWM", 0x3F20C000}},
+		PWM:         []string{"GPIO18", "GPIO19"},
+	}
+	if !reflect.DeepEqual(r.SoC, expected) {
+		t.Fatal(r.SoC)
+	}
+	if !reflect.DeepEqual(r.PWM, expected.PWM) {
+		t.Fatal(r.PWM)
+	}
+	if len(r.Kernel) != len(kernelDrivers) || r.Kernel[0].Name != "gpio-cdev" {
+		t.Fatal(r.Kernel)
+	}
+	// The report is a copy.
+	r.SoC.Peripherals[0].Base = 0
+	if Get().SoC.Peripherals[0].Base != 0x3F200000 {
+		t.Fatal("not a copy")
+	}
+	if s := expected.Peripherals[1].String(); s != "PWM@0x3F20C000" {
+		t.Fatal(s)
+	}
+}
+
+func TestExists(t *testing.T) {
+	d, err := ioutil.TempDir("", "periph_soc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	if exists(filepath.Join(d, "i2c-*")) {
+		t.Fatal("empty directory")
+	}
+	if err := ioutil.WriteFile(filepath.Join(d, "i2c-1"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(filepath.Join(d, "i2c-*")) {
+		t.Fatal("i2c-1 exists")
+	}
+	if exists("[") {
+		t.Fatal("bad pattern")
+	}
+}
+
+//
+
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	info = Info{}
+}
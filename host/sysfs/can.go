@@ -0,0 +1,336 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/can"
+	"periph.io/x/periph/conn/can/canreg"
+)
+
+// CAN is an open SocketCAN network interface.
+//
+// It can be used to send and receive frames from multiple goroutines. Each
+// CAN instance receives its own copy of the frames on the bus.
+type CAN struct {
+	s    canSocket
+	name string
+
+	mu      sync.Mutex
+	fd      bool   // CAN FD frames are enabled
+	untrack func() // Stops tracking for periph.Shutdown()
+}
+
+// NewCAN opens a CAN network interface via SocketCAN as described at
+// https://www.kernel.org/doc/Documentation/networking/can.txt.
+//
+// name is the network interface name, for example "can0". The interface must
+// have been configured and brought up beforehand, for example with:
+//
+//	ip link set can0 up type can bitrate 500000
+//
+// The resulting object is safe for concurent use.
+func NewCAN(name string) (*CAN, error) {
+	if isLinux {
+		return newCAN(name)
+	}
+	return nil, errors.New("sysfs-can: is not supported on this platform")
+}
+
+func newCAN(name string) (*CAN, error) {
+	i, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("sysfs-can: %v", err)
+	}
+	s, err := canSocketOpen(i.Index)
+	if err != nil {
+		return nil, fmt.Errorf("sysfs-can: can't open %s: %v", name, err)
+	}
+	c := &CAN{s: s, name: name}
+	c.untrack = periph.Track(c)
+	return c, nil
+}
+
+// Close closes the socket.
+func (c *CAN) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.untrack != nil {
+		c.untrack()
+		c.untrack = nil
+	}
+	if err := c.s.Close(); err != nil {
+		return fmt.Errorf("sysfs-can: %v", err)
+	}
+	return nil
+}
+
+func (c *CAN) String() string {
+	return c.name
+}
+
+// Send implements can.Bus.
+func (c *CAN) Send(f *can.Frame) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	fd := c.fd
+	c.mu.Unlock()
+	if f.FD && !fd {
+		return errors.New("sysfs-can: call EnableFD() to send CAN FD frames")
+	}
+	var raw canFrame
+	b := raw.encode(f)
+	if _, err := c.s.Write(b); err != nil {
+		return fmt.Errorf("sysfs-can: %v", err)
+	}
+	return nil
+}
+
+// Receive implements can.Bus.
+func (c *CAN) Receive(f *can.Frame) error {
+	var raw canFrame
+	b := (*[canFDFrameSize]byte)(unsafe.Pointer(&raw))[:]
+	for {
+		n, err := c.s.Read(b)
+		if err != nil {
+			return fmt.Errorf("sysfs-can: %v", err)
+		}
+		if n != canFrameSize && n != canFDFrameSize {
+			return fmt.Errorf("sysfs-can: unexpected frame size %d", n)
+		}
+		if raw.id&canErrFlag == 0 {
+			raw.decode(f, n == canFDFrameSize)
+			return nil
+		}
+	}
+}
+
+// SetFilters implements can.Bus.
+func (c *CAN) SetFilters(filters []can.Filter) error {
+	if err := c.s.setsockopt(canRawFilter, canFilters(filters)); err != nil {
+		return fmt.Errorf("sysfs-can: %v", err)
+	}
+	return nil
+}
+
+// EnableFD implements can.FDBus.
+//
+// It fails if the interface MTU doesn't support CAN FD frames.
+func (c *CAN) EnableFD(enable bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var v [4]byte
+	if enable {
+		*(*int32)(unsafe.Pointer(&v[0])) = 1
+	}
+	if err := c.s.setsockopt(canRawFDFrames, v[:]); err != nil {
+		return fmt.Errorf("sysfs-can: %v", err)
+	}
+	c.fd = enable
+	return nil
+}
+
+//
+
+// canSocket is a SocketCAN raw socket.
+type canSocket interface {
+	io.ReadWriteCloser
+	// setsockopt sets a socket option at the SOL_CAN_RAW level.
+	setsockopt(opt int, b []byte) error
+}
+
+var canSocketOpen = canSocketOpenDefault
+
+// SocketCAN constants from linux/can.h and linux/can/raw.h.
+const (
+	afCAN     = 29
+	canRaw    = 1
+	solCANRaw = 101
+
+	canRawFilter   = 1
+	canRawFDFrames = 5
+
+	canEFFFlag   = 0x80000000 // Extended frame format
+	canRTRFlag   = 0x40000000 // Remote transmission request
+	canErrFlag   = 0x20000000 // Error frame
+	canInvFilter = 0x20000000 // Invert a filter
+	canEFFMask   = 0x1FFFFFFF
+	canSFFMask   = 0x7FF
+
+	canFDBRS = 0x01 // Bit rate switch
+
+	canFrameSize   = 16
+	canFDFrameSize = 72
+)
+
+// canFrame mirrors struct canfd_frame. struct can_frame is its first 16
+// bytes.
+//
+// The fields are in the host native byte order.
+type canFrame struct {
+	id    uint32
+	len   uint8
+	flags uint8
+	res0  uint8
+	res1  uint8
+	data  [can.MaxFDLen]byte
+}
+
+// encode fills c with f and returns the bytes to write.
+func (c *canFrame) encode(f *can.Frame) []byte {
+	c.id = f.ID
+	if f.Extended {
+		c.id |= canEFFFlag
+	}
+	if f.RTR {
+		c.id |= canRTRFlag
+	}
+	c.len = uint8(len(f.Data))
+	if f.BRS {
+		c.flags |= canFDBRS
+	}
+	if !f.RTR {
+		copy(c.data[:], f.Data)
+	}
+	n := canFrameSize
+	if f.FD {
+		n = canFDFrameSize
+	}
+	return (*[canFDFrameSize]byte)(unsafe.Pointer(c))[:n]
+}
+
+// decode fills f with c.
+func (c *canFrame) decode(f *can.Frame, fd bool) {
+	f.Extended = c.id&canEFFFlag != 0
+	if f.Extended {
+		f.ID = c.id & canEFFMask
+	} else {
+		f.ID = c.id & canSFFMask
+	}
+	f.RTR = c.id&canRTRFlag != 0
+	f.FD = fd
+	f.BRS = fd && c.flags&canFDBRS != 0
+	l, max := int(c.len), can.MaxLen
+	if fd {
+		max = can.MaxFDLen
+	}
+	if l > max {
+		l = max
+	}
+	f.Data = append(f.Data[:0], c.data[:l]...)
+	if f.RTR {
+		for i := range f.Data {
+			f.Data[i] = 0
+		}
+	}
+}
+
+// canFilter mirrors struct can_filter.
+type canFilter struct {
+	id   uint32
+	mask uint32
+}
+
+// canFilters returns the CAN_RAW_FILTER socket option value for filters.
+func canFilters(filters []can.Filter) []byte {
+	raw := []canFilter{{}}
+	if len(filters) != 0 {
+		raw = make([]canFilter, len(filters))
+		for i, f := range filters {
+			raw[i].id = f.ID
+			if f.Extended {
+				raw[i].id |= canEFFFlag
+			}
+			if f.Invert {
+				raw[i].id |= canInvFilter
+			}
+			// Always compare the frame format.
+			raw[i].mask = f.Mask | canEFFFlag
+		}
+	}
+	return (*[1 << 16]byte)(unsafe.Pointer(&raw[0]))[:8*len(raw)]
+}
+
+// driverCAN implements periph.Driver.
+type driverCAN struct {
+	buses []string
+}
+
+func (d *driverCAN) String() string {
+	return "sysfs-can"
+}
+
+func (d *driverCAN) Prerequisites() []string {
+	return nil
+}
+
+func (d *driverCAN) Init() (bool, error) {
+	items, err := filepath.Glob("/sys/class/net/*/type")
+	if err != nil {
+		return true, err
+	}
+	sort.Strings(items)
+	for _, item := range items {
+		// ARPHRD_CAN
+		if b, err := ioutil.ReadFile(item); err != nil || strings.TrimSpace(string(b)) != "280" {
+			continue
+		}
+		name := filepath.Base(filepath.Dir(item))
+		n := -1
+		if strings.HasPrefix(name, "can") {
+			if i, err := strconv.Atoi(name[3:]); err == nil {
+				n = i
+			}
+		}
+		d.buses = append(d.buses, name)
+		info := conn.Info{Driver: d.String(), Path: filepath.Dir(item)}
+		// CANFD_MTU
+		if b, err := ioutil.ReadFile(filepath.Join(info.Path, "mtu")); err == nil && strings.TrimSpace(string(b)) == "72" {
+			info.Features = []string{"fd"}
+		}
+		if err := canreg.RegisterWithInfo(name, nil, n, info, openerCAN(name).Open); err != nil {
+			return true, err
+		}
+	}
+	if len(d.buses) == 0 {
+		return false, errors.New("no CAN interface found")
+	}
+	return true, nil
+}
+
+type openerCAN string
+
+func (o openerCAN) Open() (can.BusCloser, error) {
+	b, err := NewCAN(string(o))
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func init() {
+	if isLinux {
+		periph.MustRegister(&driverCAN{})
+	}
+}
+
+var _ can.BusCloser = &CAN{}
+var _ can.FDBus = &CAN{}
+var _ fmt.Stringer = &CAN{}
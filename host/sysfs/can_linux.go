@@ -0,0 +1,56 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !386
+// +build !386
+
+package sysfs
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// canSocketFD is a SocketCAN raw socket bound to a network interface.
+type canSocketFD struct {
+	fd int
+}
+
+func canSocketOpenDefault(ifindex int) (canSocket, error) {
+	fd, err := syscall.Socket(afCAN, syscall.SOCK_RAW, canRaw)
+	if err != nil {
+		return nil, err
+	}
+	// struct sockaddr_can.
+	addr := struct {
+		family  uint16
+		_       uint16
+		ifindex int32
+		_       [16]byte
+	}{family: afCAN, ifindex: int32(ifindex)}
+	if _, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&addr)), unsafe.Sizeof(addr)); errno != 0 {
+		syscall.Close(fd)
+		return nil, errno
+	}
+	return &canSocketFD{fd: fd}, nil
+}
+
+func (c *canSocketFD) Read(b []byte) (int, error) {
+	return syscall.Read(c.fd, b)
+}
+
+func (c *canSocketFD) Write(b []byte) (int, error) {
+	return syscall.Write(c.fd, b)
+}
+
+func (c *canSocketFD) Close() error {
+	return syscall.Close(c.fd)
+}
+
+func (c *canSocketFD) setsockopt(opt int, b []byte) error {
+	if _, _, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT, uintptr(c.fd), solCANRaw, uintptr(opt), uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
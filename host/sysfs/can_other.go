@@ -0,0 +1,16 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !linux || 386
+// +build !linux 386
+
+package sysfs
+
+import "errors"
+
+func canSocketOpenDefault(ifindex int) (canSocket, error) {
+	// linux/386 multiplexes the socket calls through socketcall(2), which is
+	// not exposed by package syscall.
+	return nil, errors.New("SocketCAN is not supported on this platform")
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"periph.io/x/periph/conn/can"
+	"periph.io/x/periph/conn/can/canreg"
+)
+
+func ExampleNewCAN() {
+	b, err := NewCAN("can0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := b.Send(&can.Frame{ID: 0x7DF, Data: []byte{2, 1, 0x0C}}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestNewCAN(t *testing.T) {
+	if b, err := NewCAN("does not exist"); b != nil || err == nil {
+		t.Fatal("invalid interface")
+	}
+}
+
+func TestCAN_faked(t *testing.T) {
+	s := &fakeCANSocket{}
+	c := &CAN{s: s, name: "can0"}
+	if str := c.String(); str != "can0" {
+		t.Fatal(str)
+	}
+	frames := []can.Frame{
+		{ID: 0x123, Data: []byte{1, 2, 3}},
+		{ID: 0x1234567, Extended: true},
+		{ID: 0x7DF, RTR: true, Data: make([]byte, 8)},
+	}
+	for i := range frames {
+		if err := c.Send(&frames[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fd := can.Frame{ID: 1, FD: true, BRS: true, Data: make([]byte, 12)}
+	if c.Send(&fd) == nil {
+		t.Fatal("CAN FD is not enabled")
+	}
+	if err := c.EnableFD(true); err != nil {
+		t.Fatal(err)
+	}
+	fd.Data[11] = 0xFF
+	if err := c.Send(&fd); err != nil {
+		t.Fatal(err)
+	}
+	frames = append(frames, fd)
+	if c.Send(&can.Frame{ID: 0x800}) == nil {
+		t.Fatal("invalid frame")
+	}
+	if len(s.w) != 4 || len(s.w[0]) != canFrameSize || len(s.w[3]) != canFDFrameSize {
+		t.Fatal(s.w)
+	}
+
+	// Insert an error frame, which must be skipped.
+	var e canFrame
+	e.id = canErrFlag
+	s.r = append(s.r, (*[canFDFrameSize]byte)(unsafe.Pointer(&e))[:canFrameSize])
+	s.r = append(s.r, s.w...)
+	for i := range frames {
+		var f can.Frame
+		if err := c.Receive(&f); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(f, frames[i]) {
+			t.Fatalf("#%d: %s != %s", i, &f, &frames[i])
+		}
+	}
+	s.r = [][]byte{make([]byte, 3)}
+	var f can.Frame
+	if c.Receive(&f) == nil {
+		t.Fatal("invalid frame size")
+	}
+	if c.Receive(&f) == nil {
+		t.Fatal("read failed")
+	}
+
+	if err := c.SetFilters([]can.Filter{{ID: 0x7E8, Mask: 0x7F8}, {ID: 0x18DAF110, Mask: canEFFMask, Extended: true, Invert: true}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []canFilter{
+		{0x7E8, 0x7F8 | canEFFFlag},
+		{0x18DAF110 | canEFFFlag | canInvFilter, canEFFMask | canEFFFlag},
+	}
+	if got := (*[2]canFilter)(unsafe.Pointer(&s.opts[canRawFilter][0]))[:]; !reflect.DeepEqual(got, expected) {
+		t.Fatal(got)
+	}
+	if err := c.SetFilters(nil); err != nil {
+		t.Fatal(err)
+	}
+	if b := s.opts[canRawFilter]; len(b) != 8 || *(*canFilter)(unsafe.Pointer(&b[0])) != (canFilter{}) {
+		t.Fatal(b)
+	}
+
+	s.err = errors.New("oops")
+	if c.SetFilters(nil) == nil {
+		t.Fatal("setsockopt failed")
+	}
+	if c.EnableFD(false) == nil {
+		t.Fatal("setsockopt failed")
+	}
+	if c.Send(&frames[0]) == nil {
+		t.Fatal("write failed")
+	}
+	if c.Close() == nil {
+		t.Fatal("close failed")
+	}
+}
+
+func TestCANDriver_Init(t *testing.T) {
+	d := driverCAN{}
+	if _, err := d.Init(); err == nil {
+		// It will fail on hosts without a CAN interface.
+		defer func() {
+			for _, name := range d.buses {
+				if err := canreg.Unregister(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}()
+	}
+	if d.String() != "sysfs-can" {
+		t.Fatal(d.String())
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisite")
+	}
+}
+
+//
+
+// fakeCANSocket records the written frames and the socket options and returns
+// the frames in r on read.
+type fakeCANSocket struct {
+	w    [][]byte
+	r    [][]byte
+	opts map[int][]byte
+	err  error
+}
+
+func (f *fakeCANSocket) Read(b []byte) (int, error) {
+	if len(f.r) == 0 {
+		return 0, errors.New("no more frames")
+	}
+	n := copy(b, f.r[0])
+	f.r = f.r[1:]
+	return n, nil
+}
+
+func (f *fakeCANSocket) Write(b []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.w = append(f.w, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (f *fakeCANSocket) Close() error {
+	return f.err
+}
+
+func (f *fakeCANSocket) setsockopt(opt int, b []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.opts == nil {
+		f.opts = map[int][]byte{}
+	}
+	f.opts[opt] = append([]byte(nil), b...)
+	return nil
+}
@@ -26,8 +26,8 @@ import (
 // pin numbers but this is not the case for all CPU architectures, some
 // have gaps in the pin numbering.
 //
-// This global variable is initialized once at driver initialization and isn't
-// mutated afterward. Do not modify it.
+// This global variable is initialized at driver initialization and reset when
+// the driver is unloaded. Do not modify it.
 var Pins map[int]*Pin
 
 // Pin represents one GPIO pin as found by sysfs.
@@ -43,8 +43,10 @@ type Pin struct {
 	fDirection fileIO    // handle to /sys/class/gpio/gpio*/direction; never closed
 	fEdge      fileIO    // handle to /sys/class/gpio/gpio*/edge; never closed
 	fValue     fileIO    // handle to /sys/class/gpio/gpio*/value; never closed
-	event      fs.Event  // Initialized once
+	event      fs.Event  // Initialized along fEdge
 	buf        [4]byte   // scratch buffer for Function(), Read() and Out()
+	exported   bool      // If open() exported the pin
+	untrack    func()    // Stops tracking for periph.Shutdown()
 }
 
 func (p *Pin) String() string {
@@ -186,6 +188,9 @@ func (p *Pin) Read() gpio.Level {
 
 // WaitForEdge does edge detection, returns once one is detected and implements
 // gpio.PinIn.
+//
+// The edges of all the pins are waited for by a single goroutine, so waiting
+// on many pins concurrently doesn't block one thread per pin.
 func (p *Pin) WaitForEdge(timeout time.Duration) bool {
 	// Run lockless, as the normal use is to call in a busy loop.
 	var ms int
@@ -194,23 +199,12 @@ func (p *Pin) WaitForEdge(timeout time.Duration) bool {
 	} else {
 		ms = int(timeout / time.Millisecond)
 	}
-	start := time.Now()
-	for {
-		if nr, err := p.event.Wait(ms); err != nil {
-			return false
-		} else if nr == 1 {
-			// TODO(maruel): According to pigpio, the correct way to consume the
-			// interrupt is to call Seek().
-			return true
-		}
-		// A signal occurred.
-		if timeout != -1 {
-			ms = int((timeout - time.Since(start)) / time.Millisecond)
-		}
-		if ms <= 0 {
-			return false
-		}
+	if nr, err := p.event.Wait(ms); err == nil && nr == 1 {
+		// TODO(maruel): According to pigpio, the correct way to consume the
+		// interrupt is to call Seek().
+		return true
 	}
+	return false
 }
 
 // Pull returns gpio.PullNoChange since gpio sysfs has no support for input
@@ -270,6 +264,7 @@ func (p *Pin) open() error {
 	}
 	var err error
 	_, err = exportHandle.Write([]byte(strconv.Itoa(p.number)))
+	p.exported = err == nil
 	if err != nil && !isErrBusy(err) {
 		p.err = err
 		if os.IsPermission(p.err) {
@@ -303,8 +298,57 @@ func (p *Pin) open() error {
 		p.err = err
 		p.fValue.Close()
 		p.fValue = nil
+		return p.err
 	}
-	return p.err
+	p.untrack = periph.Track(periph.CloserFunc(p.release))
+	return nil
+}
+
+// release closes the handles and unexports the pin if open() exported it.
+//
+// It is called by periph.Shutdown() and when the driver is unloaded.
+func (p *Pin) release() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.untrack != nil {
+		p.untrack()
+		p.untrack = nil
+	}
+	var errs []error
+	if p.fEdge != nil {
+		if err := p.haltEdge(); err != nil {
+			errs = append(errs, err)
+		}
+		// Stop listening before closing the file descriptor.
+		errs = append(errs, p.event.Close(), p.fEdge.Close())
+		p.fEdge = nil
+	}
+	if p.fDirection != nil {
+		errs = append(errs, p.fDirection.Close())
+		p.fDirection = nil
+	}
+	if p.fValue != nil {
+		errs = append(errs, p.fValue.Close())
+		p.fValue = nil
+	}
+	p.direction = dUnknown
+	if p.exported {
+		p.exported = false
+		f, err := fileIOOpen("/sys/class/gpio/unexport", os.O_WRONLY)
+		if err == nil {
+			_, err = f.Write([]byte(strconv.Itoa(p.number)))
+			if err2 := f.Close(); err == nil {
+				err = err2
+			}
+		}
+		errs = append(errs, err)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return p.wrap(err)
+		}
+	}
+	return nil
 }
 
 // haltEdge stops any on-going edge detection.
@@ -402,14 +446,43 @@ func (d *driverGPIO) Init() (bool, error) {
 	Pins = map[int]*Pin{}
 	for _, item := range items {
 		if err := d.parseGPIOChip(item + "/"); err != nil {
+			d.Unload()
 			return true, err
 		}
 	}
-	exportHandle, err = fileIOOpen("/sys/class/gpio/export", os.O_WRONLY)
-	if os.IsPermission(err) {
-		return true, fmt.Errorf("need more access, try as root or setup udev rules: %v", err)
+	f, err := fileIOOpen("/sys/class/gpio/export", os.O_WRONLY)
+	if err != nil {
+		d.Unload()
+		if os.IsPermission(err) {
+			return true, fmt.Errorf("need more access, try as root or setup udev rules: %v", err)
+		}
+		return true, err
+	}
+	exportHandle = f
+	return true, nil
+}
+
+// Unload implements periph.Unloader.
+//
+// It releases and unregisters the pins.
+func (d *driverGPIO) Unload() error {
+	var err error
+	for _, p := range Pins {
+		if err2 := gpioreg.UnregisterPin(p); err == nil {
+			err = err2
+		}
+		if err2 := p.release(); err == nil {
+			err = err2
+		}
+	}
+	Pins = nil
+	if c, ok := exportHandle.(io.Closer); ok {
+		if err2 := c.Close(); err == nil {
+			err = err2
+		}
 	}
-	return true, err
+	exportHandle = nil
+	return err
 }
 
 func (d *driverGPIO) parseGPIOChip(path string) error {
@@ -432,10 +505,10 @@ func (d *driverGPIO) parseGPIOChip(path string) error {
 			name:   fmt.Sprintf("GPIO%d", i),
 			root:   fmt.Sprintf("/sys/class/gpio/gpio%d/", i),
 		}
-		Pins[i] = p
 		if err := gpioreg.Register(p, false); err != nil {
 			return err
 		}
+		Pins[i] = p
 		// We cannot use gpio.MapFunction() since there is no API to determine this.
 	}
 	return nil
@@ -451,3 +524,4 @@ var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
 var _ gpio.PinIO = &Pin{}
 var _ fmt.Stringer = &Pin{}
+var _ periph.Unloader = &driverGPIO{}
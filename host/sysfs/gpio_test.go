@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
 )
 
 func TestPin_String(t *testing.T) {
@@ -188,6 +189,45 @@ func TestPin_Out(t *testing.T) {
 	}
 }
 
+func TestPin_release(t *testing.T) {
+	defer reset()
+	var paths []string
+	unexport := &fakeGPIOFile{data: make([]byte, 2)}
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		paths = append(paths, path)
+		return unexport, nil
+	}
+	p := Pin{
+		number:     42,
+		name:       "foo",
+		root:       "/tmp/gpio/priv/",
+		fDirection: &fakeGPIOFile{},
+		fEdge:      &fakeGPIOFile{},
+		fValue:     &fakeGPIOFile{},
+		exported:   true,
+	}
+	untracked := false
+	p.untrack = func() { untracked = true }
+	if err := p.release(); err != nil {
+		t.Fatal(err)
+	}
+	if !untracked || p.untrack != nil {
+		t.Fatal("pin must not be tracked anymore")
+	}
+	if p.fDirection != nil || p.fEdge != nil || p.fValue != nil || p.exported {
+		t.Fatal("handles must be closed")
+	}
+	if len(paths) != 1 || paths[0] != "/sys/class/gpio/unexport" || string(unexport.data) != "42" {
+		t.Fatal(paths, unexport.data)
+	}
+	// Edge detection is stopped first.
+	p.fEdge = &fakeGPIOFile{}
+	p.edge = gpio.RisingEdge
+	if err := p.release(); err == nil {
+		t.Fatal("edge I/O failed")
+	}
+}
+
 func TestPin_readInt(t *testing.T) {
 	if _, err := readInt("/tmp/gpio/priv/invalid_file"); err == nil {
 		t.Fatal("file is not expected to exist")
@@ -200,6 +240,44 @@ func TestGPIODriver(t *testing.T) {
 	}
 }
 
+func TestGPIODriver_Unload(t *testing.T) {
+	defer reset()
+	defer func() {
+		Pins = nil
+		exportHandle = nil
+	}()
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		switch path {
+		case "/tmp/gpio/gpiochip1000/base":
+			return &fakeGPIOFile{data: []byte("1000\n")}, nil
+		case "/tmp/gpio/gpiochip1000/ngpio":
+			return &fakeGPIOFile{data: []byte("2\n")}, nil
+		}
+		return nil, errors.New("unexpected path " + path)
+	}
+	d := driverGPIO{}
+	// Load, unload then load again, as done by periph.Reload().
+	for i := 0; i < 2; i++ {
+		Pins = map[int]*Pin{}
+		if err := d.parseGPIOChip("/tmp/gpio/gpiochip1000/"); err != nil {
+			t.Fatal(err)
+		}
+		exportHandle = &fakeGPIOFile{}
+		if p := gpioreg.ByName("GPIO1001"); p != Pins[1001] {
+			t.Fatal(p)
+		}
+		if err := d.Unload(); err != nil {
+			t.Fatal(err)
+		}
+		if p := gpioreg.ByName("GPIO1001"); p != nil {
+			t.Fatal(p)
+		}
+		if Pins != nil || exportHandle != nil {
+			t.Fatal("driver state must be reset")
+		}
+	}
+}
+
 //
 
 type fakeGPIOFile struct {
@@ -5,6 +5,7 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,9 +14,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/conn/i2c"
@@ -43,10 +46,12 @@ type I2C struct {
 	f         ioctlCloser
 	busNumber int
 
-	mu  sync.Mutex // In theory the kernel probably has an internal lock but not taking any chance.
-	fn  functionality
-	scl gpio.PinIO
-	sda gpio.PinIO
+	mu      sync.Mutex // In theory the kernel probably has an internal lock but not taking any chance.
+	fn      functionality
+	timeout int // Last adapter timeout set via TxCtx in units of 10ms; 0 if never set
+	scl     gpio.PinIO
+	sda     gpio.PinIO
+	untrack func() // Stops tracking for periph.Shutdown()
 }
 
 // NewI2C opens an I²C bus via its sysfs interface as described at
@@ -87,6 +92,7 @@ func newI2C(busNumber int) (*I2C, error) {
 	if err = i.f.Ioctl(ioctlFuncs, uintptr(unsafe.Pointer(&i.fn))); err != nil {
 		return nil, fmt.Errorf("sysfs-i2c: %v", err)
 	}
+	i.untrack = periph.Track(i)
 	return i, nil
 }
 
@@ -95,6 +101,10 @@ func newI2C(busNumber int) (*I2C, error) {
 func (i *I2C) Close() error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if i.untrack != nil {
+		i.untrack()
+		i.untrack = nil
+	}
 	if err := i.f.Close(); err != nil {
 		return fmt.Errorf("sysfs-i2c: %v", err)
 	}
@@ -107,6 +117,37 @@ func (i *I2C) String() string {
 
 // Tx execute a transaction as a single operation unit.
 func (i *I2C) Tx(addr uint16, w, r []byte) error {
+	return i.tx(addr, w, r, 0)
+}
+
+// TxCtx implements i2c.BusCtx.
+//
+// The context deadline is mapped to the I²C adapter timeout, which has a 10ms
+// resolution. The transaction cannot be interrupted once started, so a context
+// canceled without a deadline is only checked before the transaction.
+//
+// The kernel doesn't expose the adapter timeout, so it can't be restored
+// afterward; it is shared by all the users of the adapter and is left as set
+// by the last transaction with a deadline.
+func (i *I2C) TxCtx(ctx context.Context, addr uint16, w, r []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timeout := 0
+	if d, ok := ctx.Deadline(); ok {
+		left := time.Until(d)
+		if left <= 0 {
+			return context.DeadlineExceeded
+		}
+		// Round up, in units of 10ms.
+		timeout = int((left + 10*time.Millisecond - 1) / (10 * time.Millisecond))
+	}
+	return i.tx(addr, w, r, timeout)
+}
+
+// tx executes a transaction with the adapter timeout set to timeout in units
+// of 10ms, or left unchanged if 0.
+func (i *I2C) tx(addr uint16, w, r []byte, timeout int) error {
 	if addr >= 0x400 || (addr >= 0x80 && i.fn&func10BitAddr == 0) {
 		return errors.New("sysfs-i2c: invalid address")
 	}
@@ -138,6 +179,12 @@ func (i *I2C) Tx(addr uint16, w, r []byte) error {
 	pp := uintptr(unsafe.Pointer(&p))
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if timeout != 0 && timeout != i.timeout {
+		if err := i.f.Ioctl(ioctlTimeout, uintptr(timeout)); err != nil {
+			return fmt.Errorf("sysfs-i2c: %v", err)
+		}
+		i.timeout = timeout
+	}
 	if err := i.f.Ioctl(ioctlRdwr, pp); err != nil {
 		return fmt.Errorf("sysfs-i2c: %v", err)
 	}
@@ -190,7 +237,7 @@ func (i *I2C) initPins() {
 // /usr/include/linux/i2c-dev.h and /usr/include/linux/i2c.h.
 const (
 	ioctlRetries = 0x701 // TODO(maruel): Expose this
-	ioctlTimeout = 0x702 // TODO(maruel): Expose this; in units of 10ms
+	ioctlTimeout = 0x702 // In units of 10ms; set via TxCtx
 	ioctlSlave   = 0x703
 	ioctlTenBits = 0x704 // TODO(maruel): Expose this but the header says it's broken (!?)
 	ioctlFuncs   = 0x705
@@ -343,15 +390,30 @@ func (d *driverI2C) Init() (bool, error) {
 			continue
 		}
 		name := fmt.Sprintf("/dev/i2c-%d", bus)
-		d.buses = append(d.buses, name)
 		aliases := []string{fmt.Sprintf("I2C%d", bus)}
-		if err := i2creg.Register(name, aliases, bus, openerI2C(bus).Open); err != nil {
+		if err := i2creg.RegisterWithInfo(name, aliases, bus, conn.Info{Driver: d.String(), Path: name}, openerI2C(bus).Open); err != nil {
+			d.Unload()
 			return true, err
 		}
+		d.buses = append(d.buses, name)
 	}
 	return true, nil
 }
 
+// Unload implements periph.Unloader.
+//
+// It unregisters the buses. The buses already opened are not closed.
+func (d *driverI2C) Unload() error {
+	var err error
+	for _, name := range d.buses {
+		if err2 := i2creg.Unregister(name); err == nil {
+			err = err2
+		}
+	}
+	d.buses = nil
+	return err
+}
+
 type openerI2C int
 
 func (o openerI2C) Open() (i2c.BusCloser, error) {
@@ -369,4 +431,6 @@ func init() {
 }
 
 var _ i2c.Bus = &I2C{}
+var _ i2c.BusCtx = &I2C{}
 var _ fmt.Stringer = &I2C{}
+var _ periph.Unloader = &driverI2C{}
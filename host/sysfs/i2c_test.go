@@ -5,8 +5,11 @@
 package sysfs
 
 import (
+	"context"
+	"errors"
 	"log"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn/i2c/i2creg"
 )
@@ -52,6 +55,58 @@ func TestI2C_faked(t *testing.T) {
 	}
 }
 
+func TestI2C_TxCtx(t *testing.T) {
+	f := &fakeI2CIoctl{}
+	bus := I2C{f: f, busNumber: 24}
+	ctx, cancel := context.WithTimeout(context.Background(), 95*time.Millisecond)
+	defer cancel()
+	if err := bus.TxCtx(ctx, 1, []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The deadline is rounded up to 10ms.
+	if len(f.ops) != 2 || f.ops[0] != ioctlTimeout || f.ops[1] != ioctlRdwr || f.timeout == 0 || f.timeout > 10 {
+		t.Fatal(f.ops, f.timeout)
+	}
+	// The timeout is left alone without a deadline.
+	if err := bus.Tx(1, []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.TxCtx(context.Background(), 1, []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.ops) != 4 || f.ops[2] != ioctlRdwr || f.ops[3] != ioctlRdwr {
+		t.Fatal(f.ops)
+	}
+	// A new deadline sets it again.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if err := bus.TxCtx(ctx2, 1, []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.ops) != 6 || f.ops[4] != ioctlTimeout || f.timeout < 90 || f.timeout > 100 {
+		t.Fatal(f.ops, f.timeout)
+	}
+
+	cancel()
+	if err := bus.TxCtx(ctx, 1, []byte{0}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if err := bus.TxCtx(ctx, 1, []byte{0}, nil); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	if len(f.ops) != 6 {
+		t.Fatal(f.ops)
+	}
+	f.err = errors.New("ioctl")
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := bus.TxCtx(ctx, 1, []byte{0}, nil); err == nil {
+		t.Fatal("ioctl failed")
+	}
+}
+
 func TestI2C_functionality(t *testing.T) {
 	expected := "I2C|10BIT_ADDR|PROTOCOL_MANGLING|SMBUS_PEC|NOSTART|SMBUS_BLOCK_PROC_CALL|SMBUS_QUICK|SMBUS_READ_BYTE|SMBUS_WRITE_BYTE|SMBUS_READ_BYTE_DATA|SMBUS_WRITE_BYTE_DATA|SMBUS_READ_WORD_DATA|SMBUS_WRITE_WORD_DATA|SMBUS_PROC_CALL|SMBUS_READ_BLOCK_DATA|SMBUS_WRITE_BLOCK_DATA|SMBUS_READ_I2C_BLOCK|SMBUS_WRITE_I2C_BLOCK"
 	if s := functionality(0xFFFFFFFF).String(); s != expected {
@@ -64,10 +119,11 @@ func TestDriver_Init(t *testing.T) {
 	if _, err := d.Init(); err == nil {
 		// It will fail on non-linux.
 		defer func() {
-			for _, name := range d.buses {
-				if err := i2creg.Unregister(name); err != nil {
-					t.Fatal(err)
-				}
+			if err := d.Unload(); err != nil {
+				t.Fatal(err)
+			}
+			if d.buses != nil {
+				t.Fatal(d.buses)
 			}
 		}()
 		if len(d.buses) != 0 {
@@ -99,3 +155,23 @@ func TestDriver_Init(t *testing.T) {
 		t.Fatal("second SetSpeedHook must fail")
 	}
 }
+
+//
+
+type fakeI2CIoctl struct {
+	ioctlClose
+	ops     []uint
+	timeout uintptr
+	err     error
+}
+
+func (f *fakeI2CIoctl) Ioctl(op uint, data uintptr) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.ops = append(f.ops, op)
+	if op == ioctlTimeout {
+		f.timeout = data
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pwm"
+)
+
+// PWMs is all the PWM channels discovered on this host via sysfs.
+var PWMs []*PWM
+
+// PWMByName returns a *PWM for the channel name, e.g. "pwmchip0/pwm1", if
+// any.
+func PWMByName(name string) (*PWM, error) {
+	for _, p := range PWMs {
+		if p.name == name {
+			return p, nil
+		}
+	}
+	return nil, errors.New("sysfs-pwm: invalid PWM name")
+}
+
+// PWM represents one channel of a PWM controller exposed via sysfs.
+//
+// For all practical purpose, it is considered an output-only gpio.PinOut that
+// supports gpio.PinPWM. It also implements pwm.Channel to control the
+// polarity.
+type PWM struct {
+	number  int
+	name    string
+	root    string // /sys/class/pwm/pwmchip*/
+	channel int
+
+	mu       sync.Mutex
+	err      error         // If open() failed
+	fPeriod  fileIO        // handle to /sys/class/pwm/pwmchip*/pwm*/period; never closed
+	fDuty    fileIO        // handle to /sys/class/pwm/pwmchip*/pwm*/duty_cycle; never closed
+	fEnable  fileIO        // handle to /sys/class/pwm/pwmchip*/pwm*/enable; never closed
+	period   time.Duration // Last period set
+	duty     gpio.Duty     // Last duty set
+	polarity pwm.Polarity
+	enabled  bool
+}
+
+// Name returns the channel name, e.g. "pwmchip0/pwm1".
+func (p *PWM) Name() string {
+	return p.name
+}
+
+// String returns the name(number).
+func (p *PWM) String() string {
+	return fmt.Sprintf("%s(%d)", p.name, p.number)
+}
+
+// Number returns the index of the channel in PWMs.
+func (p *PWM) Number() int {
+	return p.number
+}
+
+// Function returns "PWM" when the channel is enabled, "PWM/Off" otherwise.
+func (p *PWM) Function() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled {
+		return "PWM"
+	}
+	return "PWM/Off"
+}
+
+// Halt implements conn.Resource.
+//
+// It disables the channel if it was enabled.
+func (p *PWM) Halt() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled {
+		return nil
+	}
+	if err := seekWrite(p.fEnable, []byte("0")); err != nil {
+		return p.wrap(err)
+	}
+	p.enabled = false
+	return nil
+}
+
+// Out implements gpio.PinOut.
+//
+// The channel is kept enabled with a duty cycle of 0% or 100%.
+func (p *PWM) Out(l gpio.Level) error {
+	if l == gpio.Low {
+		return p.PWM(0, 0)
+	}
+	return p.PWM(gpio.DutyMax, 0)
+}
+
+// PWM implements gpio.PinPWM.
+//
+// Using 0 as period reuses the last period set, or 1ms if none was set. The
+// period must be at least 1ns; the driver may round or reject it depending on
+// the controller capabilities.
+func (p *PWM) PWM(duty gpio.Duty, period time.Duration) error {
+	if !duty.Valid() {
+		return p.wrap(fmt.Errorf("invalid duty %d", duty))
+	}
+	if period < 0 {
+		return p.wrap(errors.New("period must be positive"))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.apply(pwm.State{Period: period, Duty: duty, Polarity: p.polarity, Enabled: true})
+}
+
+// Apply implements pwm.Channel.
+//
+// Using 0 as period reuses the last period set, or 1ms if none was set. The
+// kernel only accepts a polarity change while the channel is disabled, so the
+// channel is briefly disabled when the polarity changes. It fails if the
+// controller doesn't support setting the polarity.
+func (p *PWM) Apply(s pwm.State) error {
+	if err := s.Validate(); err != nil {
+		return p.wrap(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.apply(s)
+}
+
+// State implements pwm.Channel.
+func (p *PWM) State() pwm.State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return pwm.State{Period: p.period, Duty: p.duty, Polarity: p.polarity, Enabled: p.enabled}
+}
+
+//
+
+// open exports the channel and opens its sysfs handles.
+//
+// lock must be held.
+func (p *PWM) open() error {
+	if p.fEnable != nil || p.err != nil {
+		return p.err
+	}
+	f, err := fileIOOpen(p.root+"export", os.O_WRONLY)
+	if err != nil {
+		p.err = err
+		if os.IsPermission(err) {
+			p.err = fmt.Errorf("need more access, try as root or setup udev rules: %v", err)
+		}
+		return p.err
+	}
+	_, err = f.Write([]byte(strconv.Itoa(p.channel)))
+	f.Close()
+	if err != nil && !isErrBusy(err) {
+		p.err = err
+		return p.err
+	}
+	dir := fmt.Sprintf("%spwm%d/", p.root, p.channel)
+	// Like GPIOs, udev may still be running the rule to make the files
+	// accessible to the current user right after the export.
+	timeout := 5 * time.Second
+	for start := time.Now(); time.Since(start) < timeout; {
+		p.fPeriod, err = fileIOOpen(dir+"period", os.O_RDWR)
+		if err == nil || !os.IsPermission(err) {
+			break
+		}
+	}
+	if err != nil {
+		p.err = err
+		return p.err
+	}
+	if p.fDuty, err = fileIOOpen(dir+"duty_cycle", os.O_RDWR); err != nil {
+		p.fPeriod.Close()
+		p.fPeriod = nil
+		p.err = err
+		return p.err
+	}
+	if p.fEnable, err = fileIOOpen(dir+"enable", os.O_RDWR); err != nil {
+		p.fPeriod.Close()
+		p.fPeriod = nil
+		p.fDuty.Close()
+		p.fDuty = nil
+		p.err = err
+		return p.err
+	}
+	return nil
+}
+
+// apply sets the channel state.
+//
+// lock must be held.
+func (p *PWM) apply(s pwm.State) error {
+	if err := p.open(); err != nil {
+		return p.wrap(err)
+	}
+	if s.Period == 0 {
+		if s.Period = p.period; s.Period == 0 {
+			s.Period = time.Millisecond
+		}
+	}
+	if s.Polarity != p.polarity {
+		if err := p.setPolarity(s.Polarity); err != nil {
+			return p.wrap(err)
+		}
+	}
+	active := time.Duration(uint64(s.Period) * uint64(s.Duty) / uint64(gpio.DutyMax))
+	// The kernel refuses a duty cycle larger than the period, so reset it first
+	// when the period changes.
+	if s.Period != p.period {
+		if err := seekWrite(p.fDuty, []byte("0")); err != nil {
+			return p.wrap(err)
+		}
+		if err := seekWrite(p.fPeriod, []byte(strconv.FormatInt(int64(s.Period), 10))); err != nil {
+			return p.wrap(err)
+		}
+		p.period = s.Period
+	}
+	if err := seekWrite(p.fDuty, []byte(strconv.FormatInt(int64(active), 10))); err != nil {
+		return p.wrap(err)
+	}
+	p.duty = s.Duty
+	if s.Enabled != p.enabled {
+		v := []byte("0")
+		if s.Enabled {
+			v = []byte("1")
+		}
+		if err := seekWrite(p.fEnable, v); err != nil {
+			return p.wrap(err)
+		}
+		p.enabled = s.Enabled
+	}
+	return nil
+}
+
+// setPolarity disables the channel and changes its polarity.
+//
+// lock must be held.
+func (p *PWM) setPolarity(pol pwm.Polarity) error {
+	if p.enabled {
+		if err := seekWrite(p.fEnable, []byte("0")); err != nil {
+			return err
+		}
+		p.enabled = false
+	}
+	f, err := fileIOOpen(fmt.Sprintf("%spwm%d/polarity", p.root, p.channel), os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	v := "normal"
+	if pol == pwm.Inversed {
+		v = "inversed"
+	}
+	if _, err := f.Write([]byte(v)); err != nil {
+		return err
+	}
+	p.polarity = pol
+	return nil
+}
+
+func (p *PWM) wrap(err error) error {
+	return fmt.Errorf("sysfs-pwm (%s): %v", p, err)
+}
+
+// driverPWM implements periph.Driver.
+type driverPWM struct {
+}
+
+func (d *driverPWM) String() string {
+	return "sysfs-pwm"
+}
+
+func (d *driverPWM) Prerequisites() []string {
+	return nil
+}
+
+// Init initializes PWM sysfs handling code.
+//
+// Uses pwm sysfs as described at
+// https://www.kernel.org/doc/Documentation/pwm.txt
+func (d *driverPWM) Init() (bool, error) {
+	items, err := filepath.Glob("/sys/class/pwm/pwmchip*")
+	if err != nil {
+		return true, err
+	}
+	if len(items) == 0 {
+		return false, errors.New("no PWM controller found")
+	}
+	// This make the PWMs in deterministic order.
+	sort.Strings(items)
+	for _, item := range items {
+		if err := d.parsePWMChip(item + "/"); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (d *driverPWM) parsePWMChip(path string) error {
+	n, err := readInt(path + "npwm")
+	if err != nil {
+		return err
+	}
+	chip := filepath.Base(path)
+	for i := 0; i < n; i++ {
+		PWMs = append(PWMs, &PWM{
+			number:  len(PWMs),
+			name:    fmt.Sprintf("%s/pwm%d", chip, i),
+			root:    path,
+			channel: i,
+		})
+	}
+	return nil
+}
+
+func init() {
+	if isLinux {
+		periph.MustRegister(&driverPWM{})
+	}
+}
+
+var _ gpio.PinOut = &PWM{}
+var _ gpio.PinPWM = &PWM{}
+var _ pwm.Channel = &PWM{}
+var _ fmt.Stringer = &PWM{}
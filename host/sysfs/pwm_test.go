@@ -0,0 +1,265 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/pwm"
+)
+
+func TestPWMByName(t *testing.T) {
+	defer resetPWM()
+	PWMs = []*PWM{{number: 0, name: "pwmchip0/pwm0"}}
+	if _, err := PWMByName("FOO"); err == nil {
+		t.Fatal("invalid name")
+	}
+	if p, err := PWMByName("pwmchip0/pwm0"); p != PWMs[0] || err != nil {
+		t.Fatal(p, err)
+	}
+}
+
+func TestPWM(t *testing.T) {
+	p := PWM{number: 2, name: "pwmchip0/pwm1", root: "/tmp/pwm/priv/", channel: 1}
+	if s := p.String(); s != "pwmchip0/pwm1(2)" {
+		t.Fatal(s)
+	}
+	if s := p.Name(); s != "pwmchip0/pwm1" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != 2 {
+		t.Fatal(n)
+	}
+	if s := p.Function(); s != "PWM/Off" {
+		t.Fatal(s)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPWM_PWM(t *testing.T) {
+	defer resetPWM()
+	files := setPWMFiles(t, "/sys/class/pwm/pwmchip0/")
+	p := PWM{number: 1, name: "pwmchip0/pwm1", root: "/sys/class/pwm/pwmchip0/", channel: 1}
+	if err := p.PWM(gpio.DutyHalf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["export"].data); s != "1" {
+		t.Fatal(s)
+	}
+	if s := string(files["pwm1/period"].data); s != "1000000" {
+		t.Fatal(s)
+	}
+	if s := string(files["pwm1/duty_cycle"].data); s != "499992" {
+		t.Fatal(s)
+	}
+	if s := string(files["pwm1/enable"].data); s != "1" {
+		t.Fatal(s)
+	}
+	if s := p.Function(); s != "PWM" {
+		t.Fatal(s)
+	}
+
+	// The duty cycle is reset before changing the period.
+	if err := p.PWM(gpio.DutyMax, 10*time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/period"].data); s != "10000" {
+		t.Fatal(s)
+	}
+	if w := files["pwm1/duty_cycle"].writes; len(w) != 4 || w[2] != "0" || w[3] != "10000" {
+		t.Fatal(w)
+	}
+
+	// The last period is reused.
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/duty_cycle"].data); s != "0" {
+		t.Fatal(s)
+	}
+	if err := p.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/duty_cycle"].data); s != "10000" {
+		t.Fatal(s)
+	}
+	if w := files["pwm1/period"].writes; len(w) != 2 {
+		t.Fatal(w)
+	}
+
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/enable"].data); s != "0" {
+		t.Fatal(s)
+	}
+	if s := p.Function(); s != "PWM/Off" {
+		t.Fatal(s)
+	}
+}
+
+func TestPWM_Apply(t *testing.T) {
+	defer resetPWM()
+	files := setPWMFiles(t, "/sys/class/pwm/pwmchip0/")
+	files["pwm1/polarity"] = &fakePWMFile{}
+	p := PWM{number: 1, name: "pwmchip0/pwm1", root: "/sys/class/pwm/pwmchip0/", channel: 1}
+	s := pwm.State{Period: 100 * time.Microsecond, Duty: gpio.DutyMax / 4, Enabled: true}
+	if err := p.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	if p.State() != s {
+		t.Fatal(p.State())
+	}
+	if s := string(files["pwm1/duty_cycle"].data); s != "24998" {
+		t.Fatal(s)
+	}
+	if w := files["pwm1/polarity"].writes; len(w) != 0 {
+		t.Fatal(w)
+	}
+
+	// The channel is disabled to change the polarity, then enabled again.
+	s.Polarity = pwm.Inversed
+	if err := p.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/polarity"].data); s != "inversed" {
+		t.Fatal(s)
+	}
+	if w := files["pwm1/enable"].writes; len(w) != 3 || w[1] != "0" || w[2] != "1" {
+		t.Fatal(w)
+	}
+	// PWM() keeps the polarity.
+	if err := p.PWM(gpio.DutyHalf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if st := p.State(); st.Polarity != pwm.Inversed || st.Duty != gpio.DutyHalf || st.Period != 100*time.Microsecond {
+		t.Fatal(st)
+	}
+
+	if err := p.Apply(pwm.State{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(files["pwm1/polarity"].data); s != "normal" {
+		t.Fatal(s)
+	}
+	if s := string(files["pwm1/enable"].data); s != "0" {
+		t.Fatal(s)
+	}
+	if p.State().Enabled {
+		t.Fatal(p.State())
+	}
+	if p.Apply(pwm.State{Polarity: 3}) == nil {
+		t.Fatal("invalid polarity")
+	}
+}
+
+func TestPWM_Apply_polarity_unsupported(t *testing.T) {
+	defer resetPWM()
+	setPWMFiles(t, "/sys/class/pwm/pwmchip0/")
+	root := "/sys/class/pwm/pwmchip0/"
+	open := fileIOOpen
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		if path == root+"pwm1/polarity" {
+			return nil, os.ErrNotExist
+		}
+		return open(path, flag)
+	}
+	p := PWM{number: 1, name: "pwmchip0/pwm1", root: root, channel: 1}
+	if p.Apply(pwm.State{Polarity: pwm.Inversed, Enabled: true}) == nil {
+		t.Fatal("polarity not supported")
+	}
+}
+
+func TestPWM_PWM_fail(t *testing.T) {
+	defer resetPWM()
+	p := PWM{number: 0, name: "pwmchip0/pwm0", root: "/tmp/pwm/priv/"}
+	if err := p.PWM(-1, 0); err == nil {
+		t.Fatal("invalid duty")
+	}
+	if err := p.PWM(gpio.DutyHalf, -time.Second); err == nil {
+		t.Fatal("invalid period")
+	}
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		return nil, errors.New("not found")
+	}
+	if err := p.PWM(gpio.DutyHalf, 0); err == nil {
+		t.Fatal("export failed")
+	}
+	// The error is sticky.
+	setPWMFiles(t, "/tmp/pwm/priv/")
+	if err := p.PWM(gpio.DutyHalf, 0); err == nil {
+		t.Fatal("open failed")
+	}
+}
+
+func TestPWMDriver(t *testing.T) {
+	defer resetPWM()
+	if len((&driverPWM{}).Prerequisites()) != 0 {
+		t.Fatal("unexpected PWM prerequisites")
+	}
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		if path != "/sys/class/pwm/pwmchip4/npwm" || flag != os.O_RDONLY {
+			t.Fatal(path, flag)
+		}
+		return &fileRead{t: t, ops: [][]byte{[]byte("2\n")}}, nil
+	}
+	if err := (&driverPWM{}).parsePWMChip("/sys/class/pwm/pwmchip4/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(PWMs) != 2 {
+		t.Fatal(PWMs)
+	}
+	if s := PWMs[1].String(); s != "pwmchip4/pwm1(1)" {
+		t.Fatal(s)
+	}
+	if PWMs[1].root != "/sys/class/pwm/pwmchip4/" || PWMs[1].channel != 1 {
+		t.Fatal(PWMs[1])
+	}
+}
+
+//
+
+func resetPWM() {
+	PWMs = nil
+	reset()
+}
+
+// setPWMFiles fakes the sysfs files of a PWM controller rooted at root.
+func setPWMFiles(t *testing.T, root string) map[string]*fakePWMFile {
+	files := map[string]*fakePWMFile{
+		"export":          {},
+		"pwm1/period":     {},
+		"pwm1/duty_cycle": {},
+		"pwm1/enable":     {},
+	}
+	fileIOOpen = func(path string, flag int) (fileIO, error) {
+		if len(path) > len(root) && path[:len(root)] == root {
+			if f, ok := files[path[len(root):]]; ok {
+				return f, nil
+			}
+		}
+		t.Fatalf("unknown %q", path)
+		return nil, errors.New("unknown file")
+	}
+	return files
+}
+
+type fakePWMFile struct {
+	file
+	data   []byte
+	writes []string
+}
+
+func (f *fakePWMFile) Write(b []byte) (int, error) {
+	f.data = append(f.data[:0], b...)
+	f.writes = append(f.writes, string(b))
+	return len(b), nil
+}
@@ -5,6 +5,7 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"periph.io/x/periph"
@@ -59,6 +61,7 @@ type SPI struct {
 	mosi        gpio.PinOut
 	miso        gpio.PinIn
 	cs          gpio.PinOut
+	untrack     func() // Stops tracking for periph.Shutdown()
 }
 
 func newSPI(busNumber, chipSelect int) (*SPI, error) {
@@ -73,7 +76,9 @@ func newSPI(busNumber, chipSelect int) (*SPI, error) {
 	if err != nil {
 		return nil, fmt.Errorf("sysfs-spi: %v", err)
 	}
-	return &SPI{f: f, busNumber: busNumber, chipSelect: chipSelect}, nil
+	s := &SPI{f: f, busNumber: busNumber, chipSelect: chipSelect}
+	s.untrack = periph.Track(s)
+	return s, nil
 }
 
 // Close closes the handle to the SPI driver. It is not a requirement to close
@@ -81,6 +86,10 @@ func newSPI(busNumber, chipSelect int) (*SPI, error) {
 func (s *SPI) Close() error {
 	s.Lock()
 	defer s.Unlock()
+	if s.untrack != nil {
+		s.untrack()
+		s.untrack = nil
+	}
 	if err := s.f.Close(); err != nil {
 		return fmt.Errorf("sysfs-spi: %v", err)
 	}
@@ -205,12 +214,9 @@ func (s *SPI) txInternal(w, r []byte) (int, error) {
 		return 0, errors.New("sysfs-spi: can only specify one of w or r when in half duplex")
 	}
 	m := spiIOCTransfer{
-		speedHz:     s.maxHzPort,
+		speedHz:     s.speedHz(),
 		bitsPerWord: s.bitsPerWord,
 	}
-	if s.maxHzDev != 0 && (s.maxHzPort == 0 || s.maxHzDev < s.maxHzPort) {
-		m.speedHz = s.maxHzDev
-	}
 	if l := len(w); l != 0 {
 		m.tx = uint64(uintptr(unsafe.Pointer(&w[0])))
 		m.length = uint32(l)
@@ -254,10 +260,7 @@ func (s *SPI) txPackets(p []spi.Packet) error {
 		return errors.New("sysfs-spi: Connect wasn't called")
 	}
 	// Convert the packets.
-	speed := s.maxHzPort
-	if s.maxHzDev != 0 && (s.maxHzPort == 0 || s.maxHzDev < s.maxHzPort) {
-		speed = s.maxHzDev
-	}
+	speed := s.speedHz()
 	m := make([]spiIOCTransfer, len(p))
 	for i := range m {
 		m[i].speedHz = speed
@@ -287,6 +290,44 @@ func (s *SPI) txPackets(p []spi.Packet) error {
 	return nil
 }
 
+// speedHz returns the lowest speed between the port speed and the device
+// speed, or 0 if none is set.
+//
+// lock must be held.
+func (s *SPI) speedHz() uint32 {
+	if s.maxHzDev != 0 && (s.maxHzPort == 0 || s.maxHzDev < s.maxHzPort) {
+		return s.maxHzDev
+	}
+	return s.maxHzPort
+}
+
+// checkCtx returns an error if ctx is done or if transferring n bytes at the
+// configured speed cannot complete before the context deadline.
+//
+// spidev transfers cannot be interrupted once started, so it is better to not
+// start a transfer that would exceed the deadline.
+func (s *SPI) checkCtx(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	left := time.Until(d)
+	if left <= 0 {
+		return context.DeadlineExceeded
+	}
+	s.Lock()
+	hz := s.speedHz()
+	s.Unlock()
+	// Each byte takes at least 8 clock cycles.
+	if hz != 0 && time.Duration(int64(n)*8*int64(time.Second)/int64(hz)) > left {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
 func (s *SPI) setFlag(op uint, arg uint64) error {
 	if err := s.f.Ioctl(op|0x40000000, uintptr(unsafe.Pointer(&arg))); err != nil {
 		return err
@@ -394,6 +435,40 @@ func (s *spiConn) TxPackets(p []spi.Packet) error {
 	return s.s.txPackets(p)
 }
 
+// TxCtx implements conn.ConnCtx.
+//
+// The transfer cannot be interrupted once started, so ctx is checked before
+// and a transfer that cannot complete before the context deadline at the
+// configured speed is not started.
+func (s *spiConn) TxCtx(ctx context.Context, w, r []byte) error {
+	l := len(w)
+	if l == 0 {
+		l = len(r)
+	}
+	if err := s.s.checkCtx(ctx, l); err != nil {
+		return err
+	}
+	return s.Tx(w, r)
+}
+
+// TxPacketsCtx implements spi.ConnCtx.
+//
+// It has the same limitations as TxCtx.
+func (s *spiConn) TxPacketsCtx(ctx context.Context, p []spi.Packet) error {
+	total := 0
+	for i := range p {
+		l := len(p[i].W)
+		if l == 0 {
+			l = len(p[i].R)
+		}
+		total += l
+	}
+	if err := s.s.checkCtx(ctx, total); err != nil {
+		return err
+	}
+	return s.TxPackets(p)
+}
+
 func (s *spiConn) Duplex() conn.Duplex {
 	return s.s.duplex()
 }
@@ -493,6 +568,7 @@ var spiBufSize = 0
 
 // driverSPI implements periph.Driver.
 type driverSPI struct {
+	ports []string
 }
 
 func (d *driverSPI) String() string {
@@ -537,9 +613,11 @@ func (d *driverSPI) Init() (bool, error) {
 		if cs != 0 {
 			n = -1
 		}
-		if err := spireg.Register(name, aliases, n, (&openerSPI{bus, cs}).Open); err != nil {
+		if err := spireg.RegisterWithInfo(name, aliases, n, conn.Info{Driver: d.String(), Path: name}, (&openerSPI{bus, cs}).Open); err != nil {
+			d.Unload()
 			return true, err
 		}
+		d.ports = append(d.ports, name)
 	}
 	f, err := fs.Open("/sys/module/spidev/parameters/bufsiz", os.O_RDONLY)
 	if err != nil {
@@ -555,6 +633,20 @@ func (d *driverSPI) Init() (bool, error) {
 	return true, err
 }
 
+// Unload implements periph.Unloader.
+//
+// It unregisters the ports. The ports already opened are not closed.
+func (d *driverSPI) Unload() error {
+	var err error
+	for _, name := range d.ports {
+		if err2 := spireg.Unregister(name); err == nil {
+			err = err2
+		}
+	}
+	d.ports = nil
+	return err
+}
+
 type openerSPI struct {
 	bus int
 	cs  int
@@ -575,7 +667,9 @@ var _ conn.Limits = &spiConn{}
 var _ io.Reader = &spiConn{}
 var _ io.Writer = &spiConn{}
 var _ spi.Conn = &spiConn{}
+var _ spi.ConnCtx = &spiConn{}
 var _ spi.Pins = &SPI{}
 var _ spi.Pins = &spiConn{}
 var _ fmt.Stringer = &SPI{}
 var _ fmt.Stringer = &spiConn{}
+var _ periph.Unloader = &driverSPI{}
@@ -5,9 +5,11 @@
 package sysfs
 
 import (
+	"context"
 	"io"
 	"log"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
@@ -107,6 +109,43 @@ func TestSPI_IO(t *testing.T) {
 	}
 }
 
+func TestSPI_TxCtx(t *testing.T) {
+	port := SPI{f: &ioctlClose{}, busNumber: 24}
+	c, err := port.Connect(1000, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := c.(spi.ConnCtx)
+	if err := cc.TxCtx(context.Background(), []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	p := []spi.Packet{{W: []byte{0}}, {R: []byte{0}}}
+	if err := cc.TxPacketsCtx(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	// At 1kHz, 8 bytes take 64ms.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cc.TxCtx(ctx, nil, make([]byte, 8)); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	if err := cc.TxPacketsCtx(ctx, []spi.Packet{{W: make([]byte, 4)}, {W: make([]byte, 4)}}); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	if err := cc.TxCtx(ctx, []byte{0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := cc.TxCtx(ctx, []byte{0}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if err := cc.TxPacketsCtx(ctx, p); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+}
+
 func TestSPI_IO_not_initialized(t *testing.T) {
 	port := SPI{f: &ioctlClose{}, busNumber: 24}
 	if _, err := port.txInternal([]byte{0}, []byte{0}); err == nil {
@@ -50,6 +50,11 @@ func (t *ThermalSensor) String() string {
 	return t.name
 }
 
+// Metrics implements devices.EnvironmentalMetrics.
+func (t *ThermalSensor) Metrics() devices.Metric {
+	return devices.MetricTemperature
+}
+
 // Halt implements conn.Resource. It is a noop.
 func (t *ThermalSensor) Halt() error {
 	return nil
@@ -102,6 +107,7 @@ func (t *ThermalSensor) Sense(env *devices.Environment) error {
 		i *= 1000
 	}
 	env.Temperature = devices.Celsius(i)
+	env.Time = time.Now()
 	return nil
 }
 
@@ -172,5 +178,5 @@ func init() {
 	}
 }
 
-var _ devices.Environmental = &ThermalSensor{}
+var _ devices.EnvironmentalMetrics = &ThermalSensor{}
 var _ fmt.Stringer = &ThermalSensor{}
@@ -0,0 +1,21 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package upboard contains the header definitions of the UP family of x86
+// boards, which have a Raspberry Pi compatible 40 pins HAT header.
+//
+// The GPIOs are accessed through the GPIO character device via package
+// gpioioctl, so it requires a kernel with the upboard-pinctrl driver, e.g.
+// the one provided by the UP community. The I²C buses and SPI ports of the
+// header are enumerated from /dev/i2c-N and /dev/spidevN.M by package sysfs
+// like on any other linux host.
+//
+// Other x86 boards, e.g. the LattePanda, do not route the SoC GPIOs to a
+// header; their ACPI GPIO controllers can still be accessed with
+// gpioioctl.Find() and their I²C and SPI buses with package sysfs.
+//
+// Physical
+//
+// https://github.com/up-board/up-community/wiki/Pinout
+package upboard
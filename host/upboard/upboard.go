@@ -0,0 +1,183 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package upboard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+// Present returns true if running on an UP board, e.g. the UP, UP Squared or
+// UP Core.
+//
+// It looks for the board in the DMI tables, as these boards have no device
+// tree.
+//
+// https://up-board.org/
+func Present() bool {
+	if isX86 {
+		return distro.DMIBoardVendor() == "AAEON" && strings.HasPrefix(distro.DMIBoardName(), "UP-")
+	}
+	return false
+}
+
+// All the individual pins on the HAT header.
+//
+// The GPIOs use the Raspberry Pi numbering, e.g. P1_3 is GPIO2. They are set
+// once the driver is initialized.
+var (
+	P1_1  pin.Pin    = pin.V3_3     //
+	P1_2  pin.Pin    = pin.V5       //
+	P1_3  gpio.PinIO = gpio.INVALID // GPIO2, I2C1_SDA
+	P1_4  pin.Pin    = pin.V5       //
+	P1_5  gpio.PinIO = gpio.INVALID // GPIO3, I2C1_SCL
+	P1_6  pin.Pin    = pin.GROUND   //
+	P1_7  gpio.PinIO = gpio.INVALID // GPIO4
+	P1_8  gpio.PinIO = gpio.INVALID // GPIO14, UART1_TX
+	P1_9  pin.Pin    = pin.GROUND   //
+	P1_10 gpio.PinIO = gpio.INVALID // GPIO15, UART1_RX
+	P1_11 gpio.PinIO = gpio.INVALID // GPIO17
+	P1_12 gpio.PinIO = gpio.INVALID // GPIO18, PWM0, I2S_CLK
+	P1_13 gpio.PinIO = gpio.INVALID // GPIO27
+	P1_14 pin.Pin    = pin.GROUND   //
+	P1_15 gpio.PinIO = gpio.INVALID // GPIO22
+	P1_16 gpio.PinIO = gpio.INVALID // GPIO23
+	P1_17 pin.Pin    = pin.V3_3     //
+	P1_18 gpio.PinIO = gpio.INVALID // GPIO24
+	P1_19 gpio.PinIO = gpio.INVALID // GPIO10, SPI2_MOSI
+	P1_20 pin.Pin    = pin.GROUND   //
+	P1_21 gpio.PinIO = gpio.INVALID // GPIO9, SPI2_MISO
+	P1_22 gpio.PinIO = gpio.INVALID // GPIO25
+	P1_23 gpio.PinIO = gpio.INVALID // GPIO11, SPI2_CLK
+	P1_24 gpio.PinIO = gpio.INVALID // GPIO8, SPI2_CS0
+	P1_25 pin.Pin    = pin.GROUND   //
+	P1_26 gpio.PinIO = gpio.INVALID // GPIO7, SPI2_CS1
+	P1_27 gpio.PinIO = gpio.INVALID // GPIO0, I2C0_SDA
+	P1_28 gpio.PinIO = gpio.INVALID // GPIO1, I2C0_SCL
+	P1_29 gpio.PinIO = gpio.INVALID // GPIO5
+	P1_30 pin.Pin    = pin.GROUND   //
+	P1_31 gpio.PinIO = gpio.INVALID // GPIO6
+	P1_32 gpio.PinIO = gpio.INVALID // GPIO12, PWM0
+	P1_33 gpio.PinIO = gpio.INVALID // GPIO13, PWM1
+	P1_34 pin.Pin    = pin.GROUND   //
+	P1_35 gpio.PinIO = gpio.INVALID // GPIO19, I2S_FS
+	P1_36 gpio.PinIO = gpio.INVALID // GPIO16
+	P1_37 gpio.PinIO = gpio.INVALID // GPIO26
+	P1_38 gpio.PinIO = gpio.INVALID // GPIO20, I2S_DIN
+	P1_39 pin.Pin    = pin.GROUND   //
+	P1_40 gpio.PinIO = gpio.INVALID // GPIO21, I2S_DOUT
+)
+
+//
+
+// headerGPIO is the line of each header pin on the upboard-pinctrl chip,
+// which is the same as the Raspberry Pi GPIO number.
+var headerGPIO = []struct {
+	p    *gpio.PinIO
+	line int
+}{
+	{&P1_3, 2},
+	{&P1_5, 3},
+	{&P1_7, 4},
+	{&P1_8, 14},
+	{&P1_10, 15},
+	{&P1_11, 17},
+	{&P1_12, 18},
+	{&P1_13, 27},
+	{&P1_15, 22},
+	{&P1_16, 23},
+	{&P1_18, 24},
+	{&P1_19, 10},
+	{&P1_21, 9},
+	{&P1_22, 25},
+	{&P1_23, 11},
+	{&P1_24, 8},
+	{&P1_26, 7},
+	{&P1_27, 0},
+	{&P1_28, 1},
+	{&P1_29, 5},
+	{&P1_31, 6},
+	{&P1_32, 12},
+	{&P1_33, 13},
+	{&P1_35, 19},
+	{&P1_36, 16},
+	{&P1_37, 26},
+	{&P1_38, 20},
+	{&P1_40, 21},
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "upboard"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("UP board not detected")
+	}
+	// The HAT pins are routed through a CPLD that is exposed by the
+	// upboard-pinctrl driver as a GPIO chip; the SoC GPIOs found via ACPI are
+	// not directly connected to the header.
+	c, err := gpioioctl.Find("upboard-pinctrl")
+	if err != nil {
+		return true, err
+	}
+	for _, h := range headerGPIO {
+		p := c.Pin(h.line, fmt.Sprintf("GPIO%d", h.line), gpio.PullNoChange)
+		if err := gpioreg.Register(p, true); err != nil {
+			return true, err
+		}
+		*h.p = p
+	}
+	if err := pinreg.Register("P1", [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func init() {
+	if isX86 {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
@@ -0,0 +1,18 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package visionfive contains the header definitions of the StarFive
+// VisionFive 2, a RISC-V board based on the JH7110 SoC with a Raspberry Pi
+// compatible 40 pins header.
+//
+// The GPIOs are accessed through the GPIO character device via package
+// gpioioctl, as there is no memory mapped driver for the JH7110. The I²C bus,
+// e.g. /dev/i2c-0 on P1_3 and P1_5, and the SPI port, e.g. /dev/spidev1.0 on
+// P1_19, P1_21, P1_23 and P1_24, are enumerated by package sysfs like on any
+// other linux host.
+//
+// Physical
+//
+// https://doc-en.rvspace.org/VisionFive2/PDF/VisionFive2_40-Pin_GPIO_Header_UG.pdf
+package visionfive
@@ -0,0 +1,179 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package visionfive
+
+import (
+	"errors"
+	"fmt"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/host/distro"
+	"periph.io/x/periph/host/gpioioctl"
+)
+
+// Present returns true if running on a VisionFive 2.
+//
+// It looks for the board in /proc/device-tree/compatible; all the board
+// revisions, e.g. "starfive,visionfive-2-v1.3b", are detected.
+//
+// https://www.starfivetech.com/en/site/boards
+func Present() bool {
+	if isRiscv {
+		return distro.DTIsCompatible("starfive,visionfive-2*")
+	}
+	return false
+}
+
+// All the individual pins on the 40 pins header.
+//
+// The GPIOs are set once the driver is initialized.
+var (
+	P1_1  pin.Pin    = pin.V3_3     //
+	P1_2  pin.Pin    = pin.V5       //
+	P1_3  gpio.PinIO = gpio.INVALID // GPIO58, I2C0_SDA
+	P1_4  pin.Pin    = pin.V5       //
+	P1_5  gpio.PinIO = gpio.INVALID // GPIO57, I2C0_SCL
+	P1_6  pin.Pin    = pin.GROUND   //
+	P1_7  gpio.PinIO = gpio.INVALID // GPIO55
+	P1_8  gpio.PinIO = gpio.INVALID // GPIO5, UART0_TX
+	P1_9  pin.Pin    = pin.GROUND   //
+	P1_10 gpio.PinIO = gpio.INVALID // GPIO6, UART0_RX
+	P1_11 gpio.PinIO = gpio.INVALID // GPIO42
+	P1_12 gpio.PinIO = gpio.INVALID // GPIO38
+	P1_13 gpio.PinIO = gpio.INVALID // GPIO43
+	P1_14 pin.Pin    = pin.GROUND   //
+	P1_15 gpio.PinIO = gpio.INVALID // GPIO47
+	P1_16 gpio.PinIO = gpio.INVALID // GPIO54
+	P1_17 pin.Pin    = pin.V3_3     //
+	P1_18 gpio.PinIO = gpio.INVALID // GPIO51
+	P1_19 gpio.PinIO = gpio.INVALID // GPIO52, SPI_MOSI
+	P1_20 pin.Pin    = pin.GROUND   //
+	P1_21 gpio.PinIO = gpio.INVALID // GPIO53, SPI_MISO
+	P1_22 gpio.PinIO = gpio.INVALID // GPIO50
+	P1_23 gpio.PinIO = gpio.INVALID // GPIO48, SPI_CLK
+	P1_24 gpio.PinIO = gpio.INVALID // GPIO49, SPI_CS0
+	P1_25 pin.Pin    = pin.GROUND   //
+	P1_26 gpio.PinIO = gpio.INVALID // GPIO56
+	P1_27 gpio.PinIO = gpio.INVALID // GPIO45
+	P1_28 gpio.PinIO = gpio.INVALID // GPIO40
+	P1_29 gpio.PinIO = gpio.INVALID // GPIO37
+	P1_30 pin.Pin    = pin.GROUND   //
+	P1_31 gpio.PinIO = gpio.INVALID // GPIO39
+	P1_32 gpio.PinIO = gpio.INVALID // GPIO46, PWM0
+	P1_33 gpio.PinIO = gpio.INVALID // GPIO59, PWM1
+	P1_34 pin.Pin    = pin.GROUND   //
+	P1_35 gpio.PinIO = gpio.INVALID // GPIO63
+	P1_36 gpio.PinIO = gpio.INVALID // GPIO36
+	P1_37 gpio.PinIO = gpio.INVALID // GPIO60
+	P1_38 gpio.PinIO = gpio.INVALID // GPIO61
+	P1_39 pin.Pin    = pin.GROUND   //
+	P1_40 gpio.PinIO = gpio.INVALID // GPIO44
+)
+
+//
+
+// headerGPIO is the line of each header pin on the JH7110 system pin
+// controller, which is the GPIO number.
+var headerGPIO = []struct {
+	p    *gpio.PinIO
+	line int
+}{
+	{&P1_3, 58},
+	{&P1_5, 57},
+	{&P1_7, 55},
+	{&P1_8, 5},
+	{&P1_10, 6},
+	{&P1_11, 42},
+	{&P1_12, 38},
+	{&P1_13, 43},
+	{&P1_15, 47},
+	{&P1_16, 54},
+	{&P1_18, 51},
+	{&P1_19, 52},
+	{&P1_21, 53},
+	{&P1_22, 50},
+	{&P1_23, 48},
+	{&P1_24, 49},
+	{&P1_26, 56},
+	{&P1_27, 45},
+	{&P1_28, 40},
+	{&P1_29, 37},
+	{&P1_31, 39},
+	{&P1_32, 46},
+	{&P1_33, 59},
+	{&P1_35, 63},
+	{&P1_36, 36},
+	{&P1_37, 60},
+	{&P1_38, 61},
+	{&P1_40, 44},
+}
+
+// driver implements periph.Driver.
+type driver struct {
+}
+
+func (d *driver) String() string {
+	return "visionfive"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	if !Present() {
+		return false, errors.New("VisionFive 2 board not detected")
+	}
+	// The system pin controller handles GPIO0 to GPIO63; the always-on one
+	// is not routed to the header.
+	c, err := gpioioctl.Find("13040000.pinctrl")
+	if err != nil {
+		return true, err
+	}
+	for _, h := range headerGPIO {
+		p := c.Pin(h.line, fmt.Sprintf("GPIO%d", h.line), gpio.PullNoChange)
+		if err := gpioreg.Register(p, true); err != nil {
+			return true, err
+		}
+		*h.p = p
+	}
+	if err := pinreg.Register("P1", [][]pin.Pin{
+		{P1_1, P1_2},
+		{P1_3, P1_4},
+		{P1_5, P1_6},
+		{P1_7, P1_8},
+		{P1_9, P1_10},
+		{P1_11, P1_12},
+		{P1_13, P1_14},
+		{P1_15, P1_16},
+		{P1_17, P1_18},
+		{P1_19, P1_20},
+		{P1_21, P1_22},
+		{P1_23, P1_24},
+		{P1_25, P1_26},
+		{P1_27, P1_28},
+		{P1_29, P1_30},
+		{P1_31, P1_32},
+		{P1_33, P1_34},
+		{P1_35, P1_36},
+		{P1_37, P1_38},
+		{P1_39, P1_40},
+	}); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func init() {
+	if isRiscv {
+		periph.MustRegister(&driver{})
+	}
+}
+
+var _ periph.Driver = &driver{}
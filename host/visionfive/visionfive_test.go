@@ -0,0 +1,37 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package visionfive
+
+import "testing"
+
+func TestPresent(t *testing.T) {
+	Present()
+}
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "visionfive" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil {
+		t.Fatal("unexpected prerequisites")
+	}
+	if ok, err := d.Init(); err == nil || ok {
+		t.Fatal("VisionFive 2 is not present")
+	}
+}
+
+func TestHeaderGPIO(t *testing.T) {
+	seen := map[int]bool{}
+	for _, h := range headerGPIO {
+		if h.line < 0 || h.line > 63 || seen[h.line] {
+			t.Fatal(h.line)
+		}
+		seen[h.line] = true
+	}
+	if len(seen) != 28 {
+		t.Fatal(len(seen))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package periph
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// Logger is the structured logger the drivers emit their diagnostics to, like
+// the driver initialization results and the bus errors.
+//
+// It is the subset of *slog.Logger used by periph, so a *slog.Logger can be
+// passed as-is to SetLogger(). args are alternating keys and values.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// SetLogger sets the logger the drivers emit to.
+//
+// Use nil to discard all the messages, which is the default.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger.Store(loggerBox{l})
+}
+
+// Log returns the logger set with SetLogger().
+//
+// It is meant to be used by the drivers and is never nil.
+func Log() Logger {
+	if l, ok := logger.Load().(loggerBox); ok {
+		return l.Logger
+	}
+	return nopLogger{}
+}
+
+// NewStdLogger returns a Logger writing to l.
+//
+// It is meant for applications not using log/slog. Each message is printed on
+// one line as "LEVEL msg key=value ...".
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+//
+
+var logger atomic.Value
+
+// loggerBox is needed as atomic.Value requires a consistent concrete type.
+type loggerBox struct {
+	Logger
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s *stdLogger) Debug(msg string, args ...interface{}) { s.print("DEBUG", msg, args) }
+func (s *stdLogger) Info(msg string, args ...interface{})  { s.print("INFO", msg, args) }
+func (s *stdLogger) Warn(msg string, args ...interface{})  { s.print("WARN", msg, args) }
+func (s *stdLogger) Error(msg string, args ...interface{}) { s.print("ERROR", msg, args) }
+
+func (s *stdLogger) print(level, msg string, args []interface{}) {
+	var b bytes.Buffer
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, " !BADKEY=%v", args[i])
+			break
+		}
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	s.l.Print(b.String())
+}
+
+var _ Logger = nopLogger{}
+var _ Logger = &stdLogger{}
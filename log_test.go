@@ -0,0 +1,106 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package periph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func ExampleSetLogger() {
+	// With Go 1.21 or later, a *slog.Logger can be used directly:
+	//   periph.SetLogger(slog.Default())
+	SetLogger(NewStdLogger(log.New(os.Stderr, "", log.LstdFlags)))
+	if _, err := Init(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//
+
+func TestLog(t *testing.T) {
+	defer SetLogger(nil)
+	if _, ok := Log().(nopLogger); !ok {
+		t.Fatal("expected no-op logger by default")
+	}
+	l := &fakeLogger{}
+	SetLogger(l)
+	if Log() != l {
+		t.Fatal("SetLogger")
+	}
+	SetLogger(nil)
+	Log().Error("discarded")
+	if len(l.lines) != 0 {
+		t.Fatal(l.lines)
+	}
+}
+
+func TestLog_Init(t *testing.T) {
+	defer reset()
+	defer SetLogger(nil)
+	l := &fakeLogger{}
+	SetLogger(l)
+	registerDrivers([]Driver{
+		&driver{name: "A", ok: true},
+		&driver{name: "B", ok: false, err: errors.New("not present")},
+		&driver{name: "C", ok: true, err: errors.New("oops")},
+		&driver{name: "D", prereqs: []string{"B"}, ok: true},
+	})
+	if _, err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	l.sort()
+	expected := []string{
+		"DEBUG periph: driver loaded [driver A]",
+		"DEBUG periph: driver skipped [driver B reason not present]",
+		"DEBUG periph: driver skipped [driver D reason dependency not loaded: \"B\"]",
+		"WARN periph: driver failed [driver C err oops]",
+	}
+	if s := strings.Join(l.lines, "\n"); s != strings.Join(expected, "\n") {
+		t.Fatal(s)
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	b := bytes.Buffer{}
+	l := NewStdLogger(log.New(&b, "", 0))
+	l.Debug("a", "k", 1)
+	l.Info("b")
+	l.Warn("c", "k", "v", "x")
+	l.Error("d", "err", errors.New("e"))
+	if s := b.String(); s != "DEBUG a k=1\nINFO b\nWARN c k=v !BADKEY=x\nERROR d err=e\n" {
+		t.Fatal(s)
+	}
+}
+
+//
+
+type fakeLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (f *fakeLogger) Debug(msg string, args ...interface{}) { f.add("DEBUG", msg, args) }
+func (f *fakeLogger) Info(msg string, args ...interface{})  { f.add("INFO", msg, args) }
+func (f *fakeLogger) Warn(msg string, args ...interface{})  { f.add("WARN", msg, args) }
+func (f *fakeLogger) Error(msg string, args ...interface{}) { f.add("ERROR", msg, args) }
+
+func (f *fakeLogger) add(level, msg string, args []interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lines = append(f.lines, fmt.Sprintf("%s %s %v", level, msg, args))
+}
+
+// sort sorts the lines as drivers are initialized concurrently.
+func (f *fakeLogger) sort() {
+	sort.Strings(f.lines)
+}
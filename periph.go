@@ -21,7 +21,9 @@
 // calling periph.MustRegister().
 //
 // The user must call periph.Init() on startup to initialize all the registered
-// drivers in the correct order all at once.
+// drivers in the correct order all at once. A long running process can call
+// periph.Shutdown() on exit to release the buses, pins and memory mappings
+// claimed by the drivers.
 //
 // → cmd/ contains executables to communicate directly with the devices or the
 // buses using raw protocols.
@@ -50,6 +52,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 )
 
 // Driver is an implementation for a protocol.
@@ -79,6 +82,56 @@ type Driver interface {
 	Init() (bool, error)
 }
 
+// Unloader is an optional interface a Driver can implement to be unloaded by
+// Reload().
+type Unloader interface {
+	// Unload releases the resources acquired by Init() and unregisters the
+	// pins and buses the driver registered, so that Init() can be called again.
+	Unload() error
+}
+
+// EventType is the type of a driver state change.
+type EventType int
+
+// Driver state changes.
+const (
+	// Loaded is sent when a driver is loaded successfully.
+	Loaded EventType = iota
+	// Skipped is sent when a driver is skipped as irrelevant.
+	Skipped
+	// Failed is sent when a driver failed to load.
+	Failed
+	// Unloaded is sent when a driver is unloaded by Reload().
+	Unloaded
+)
+
+const eventTypeName = "LoadedSkippedFailedUnloaded"
+
+var eventTypeIndex = [...]uint8{0, 6, 13, 19, 27}
+
+func (e EventType) String() string {
+	if e < 0 || e >= EventType(len(eventTypeIndex)-1) {
+		return fmt.Sprintf("EventType(%d)", e)
+	}
+	return eventTypeName[eventTypeIndex[e]:eventTypeIndex[e+1]]
+}
+
+// Event is a driver state change, sent to the functions registered with
+// Notify().
+type Event struct {
+	Type EventType
+	D    Driver
+	// Err is the reason the driver was skipped or failed.
+	Err error
+}
+
+func (e Event) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s: %v", e.D, e.Type, e.Err)
+	}
+	return fmt.Sprintf("%s %s", e.D, e.Type)
+}
+
 // DriverFailure is a driver that wasn't loaded, either because it was skipped
 // or because it failed to load.
 type DriverFailure struct {
@@ -99,6 +152,25 @@ type State struct {
 	Failed  []DriverFailure
 }
 
+// Options controls which drivers are initialized by InitWithOptions().
+//
+// Drivers are referred to by their String() value. Referring to a driver that
+// is not registered is an error.
+type Options struct {
+	// Only is the list of drivers to initialize, along with their
+	// prerequisites. All the other drivers are skipped. When empty, all the
+	// drivers are initialized.
+	Only []string
+	// Skip is the list of drivers to not initialize. The drivers depending on
+	// them are skipped too.
+	Skip []string
+	// Timeout is the maximum duration of the Init() of each driver. A driver
+	// taking longer is reported as failed and the drivers depending on it are
+	// skipped. Its Init() is left running in the background, as it cannot be
+	// interrupted. When 0, there is no limit.
+	Timeout time.Duration
+}
+
 // Init initialises all the relevant drivers.
 //
 // Drivers are started concurrently.
@@ -108,12 +180,33 @@ type State struct {
 //
 // Users will want to use host.Init(), which guarantees a baseline of included
 // host drivers.
+//
+// The result of each driver is also emitted to the Logger set with
+// SetLogger().
 func Init() (*State, error) {
+	return InitWithOptions(nil)
+}
+
+// InitWithOptions is like Init() but only initializes the drivers selected by
+// opts, so an application can avoid probing irrelevant subsystems and a
+// broken driver cannot block the startup.
+//
+// The drivers not selected are reported in State.Skipped. opts is ignored
+// on later calls, the previous state is returned.
+func InitWithOptions(opts *Options) (*State, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
 	mu.Lock()
 	defer mu.Unlock()
 	if state != nil {
 		return state, nil
 	}
+	disabled, err := opts.disabled()
+	if err != nil {
+		Log().Error("periph: initialization failed", "err", err)
+		return nil, err
+	}
 	state = &State{}
 	cD := make(chan Driver)
 	cS := make(chan DriverFailure)
@@ -143,11 +236,12 @@ func Init() (*State, error) {
 
 	stages, err := explodeStages(allDrivers)
 	if err != nil {
+		Log().Error("periph: initialization failed", "err", err)
 		return state, err
 	}
 	loaded := map[string]struct{}{}
 	for _, drvs := range stages {
-		loadStage(drvs, loaded, cD, cS, cE)
+		loadStage(drvs, loaded, disabled, opts.Timeout, cD, cS, cE)
 	}
 	close(cD)
 	close(cS)
@@ -165,6 +259,88 @@ func Init() (*State, error) {
 	return state, nil
 }
 
+// Reload unloads the driver named name and initializes it again, for example
+// after a device tree overlay is loaded or an USB adapter is plugged.
+//
+// A loaded driver must implement Unloader. A skipped or failed driver is
+// initialized again directly. Its prerequisites must be loaded. The drivers
+// depending on it are not reloaded.
+//
+// It must be called after Init(). The returned State is the one returned by
+// Init(), updated.
+func Reload(name string) (*State, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if state == nil {
+		return nil, errors.New("periph: can't call Reload() before Init()")
+	}
+	d, ok := byName[name]
+	if !ok {
+		return state, fmt.Errorf("periph: can't reload unknown driver %q", name)
+	}
+	for i, l := range state.Loaded {
+		if l != d {
+			continue
+		}
+		u, ok := d.(Unloader)
+		if !ok {
+			return state, fmt.Errorf("periph: can't reload driver %q; it doesn't implement Unloader", name)
+		}
+		if err := u.Unload(); err != nil {
+			return state, fmt.Errorf("periph: failed to unload driver %q: %v", name, err)
+		}
+		Log().Debug("periph: driver unloaded", "driver", name)
+		emit(Event{Type: Unloaded, D: d})
+		state.Loaded = append(state.Loaded[:i], state.Loaded[i+1:]...)
+		break
+	}
+	state.Skipped = removeFailure(state.Skipped, d)
+	state.Failed = removeFailure(state.Failed, d)
+
+	loaded := map[string]struct{}{}
+	for _, l := range state.Loaded {
+		loaded[l.String()] = struct{}{}
+	}
+	cD := make(chan Driver, 1)
+	cS := make(chan DriverFailure, 1)
+	cE := make(chan DriverFailure, 1)
+	loadStage([]Driver{d}, loaded, nil, 0, cD, cS, cE)
+	select {
+	case l := <-cD:
+		state.Loaded = append(state.Loaded, l)
+		sort.Sort(drivers(state.Loaded))
+	case f := <-cS:
+		state.Skipped = append(state.Skipped, f)
+		sort.Sort(failures(state.Skipped))
+	case f := <-cE:
+		state.Failed = append(state.Failed, f)
+		sort.Sort(failures(state.Failed))
+	}
+	return state, nil
+}
+
+// Notify registers f to be called each time a driver is loaded, skipped,
+// failed or unloaded, by Init() or Reload().
+//
+// f may be called concurrently and must not call Init() or Reload(). Call the
+// returned function to stop the notifications.
+func Notify(f func(e Event)) func() {
+	w := &watcher{f: f}
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	watchers = append(watchers, w)
+	return func() {
+		watchersMu.Lock()
+		defer watchersMu.Unlock()
+		for i := range watchers {
+			if watchers[i] == w {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 // Register registers a driver to be initialized automatically on Init().
 //
 // The d.String() value must be unique across all registered drivers.
@@ -202,8 +378,35 @@ var (
 	allDrivers []Driver
 	byName     = map[string]Driver{}
 	state      *State
+
+	watchersMu sync.Mutex
+	watchers   []*watcher
 )
 
+type watcher struct {
+	f func(e Event)
+}
+
+// emit calls the watchers registered with Notify().
+func emit(e Event) {
+	watchersMu.Lock()
+	w := append([]*watcher(nil), watchers...)
+	watchersMu.Unlock()
+	for _, x := range w {
+		x.f(e)
+	}
+}
+
+// removeFailure returns f without the entry for d.
+func removeFailure(f []DriverFailure, d Driver) []DriverFailure {
+	for i := range f {
+		if f[i].D == d {
+			return append(f[:i], f[i+1:]...)
+		}
+	}
+	return f
+}
+
 // explodeStages creates multiple stages if needed.
 //
 // It searches if there's any driver than has dependency on another driver from
@@ -254,12 +457,92 @@ func explodeStages(drvs []Driver) ([][]Driver, error) {
 	return stages, nil
 }
 
+// disabled returns the drivers not selected by the options with the reason.
+//
+// Must be called with mu held.
+func (o *Options) disabled() (map[string]error, error) {
+	for _, name := range o.Only {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("periph: unknown driver %q in Options.Only", name)
+		}
+	}
+	for _, name := range o.Skip {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("periph: unknown driver %q in Options.Skip", name)
+		}
+	}
+	out := map[string]error{}
+	if len(o.Only) != 0 {
+		// Select the prerequisites recursively.
+		selected := map[string]struct{}{}
+		var add func(name string)
+		add = func(name string) {
+			if _, ok := selected[name]; ok {
+				return
+			}
+			selected[name] = struct{}{}
+			if d, ok := byName[name]; ok {
+				for _, dep := range d.Prerequisites() {
+					add(dep)
+				}
+			}
+		}
+		for _, name := range o.Only {
+			add(name)
+		}
+		for _, d := range allDrivers {
+			if _, ok := selected[d.String()]; !ok {
+				out[d.String()] = errors.New("not selected in Options.Only")
+			}
+		}
+	}
+	for _, name := range o.Skip {
+		out[name] = errors.New("disabled in Options.Skip")
+	}
+	return out, nil
+}
+
+// initDriver calls d.Init(), giving up after timeout if not 0.
+func initDriver(d Driver, timeout time.Duration) (bool, error) {
+	if timeout == 0 {
+		return d.Init()
+	}
+	type result struct {
+		ok  bool
+		err error
+	}
+	c := make(chan result, 1)
+	go func() {
+		ok, err := d.Init()
+		c <- result{ok, err}
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case r := <-c:
+		return r.ok, r.err
+	case <-t.C:
+		return true, timeoutError(timeout)
+	}
+}
+
+// timeoutError is returned by initDriver() when the driver timed out.
+type timeoutError time.Duration
+
+func (t timeoutError) Error() string {
+	return fmt.Sprintf("initialization timed out after %s", time.Duration(t))
+}
+
 // loadStage loads all the drivers in this stage concurrently.
-func loadStage(drvs []Driver, loaded map[string]struct{}, cD chan<- Driver, cS chan<- DriverFailure, cE chan<- DriverFailure) {
+func loadStage(drvs []Driver, loaded map[string]struct{}, disabled map[string]error, timeout time.Duration, cD chan<- Driver, cS chan<- DriverFailure, cE chan<- DriverFailure) {
 	var wg sync.WaitGroup
 	// Use int for concurrent access.
 	skip := make([]error, len(drvs))
 	for i, d := range drvs {
+		if err := disabled[d.String()]; err != nil {
+			skip[i] = err
+			continue
+		}
 		// Load only the driver if prerequisites were loaded. They are
 		// guaranteed to be in a previous stage by explodeStages().
 		for _, dep := range d.Prerequisites() {
@@ -272,20 +555,32 @@ func loadStage(drvs []Driver, loaded map[string]struct{}, cD chan<- Driver, cS c
 
 	for i, drv := range drvs {
 		if err := skip[i]; err != nil {
+			Log().Debug("periph: driver skipped", "driver", drv.String(), "reason", err)
+			emit(Event{Type: Skipped, D: drv, Err: err})
 			cS <- DriverFailure{drv, err}
 			continue
 		}
 		wg.Add(1)
 		go func(d Driver, j int) {
 			defer wg.Done()
-			if ok, err := d.Init(); ok {
+			if ok, err := initDriver(d, timeout); ok {
 				if err == nil {
+					Log().Debug("periph: driver loaded", "driver", d.String())
+					emit(Event{Type: Loaded, D: d})
 					cD <- d
 					return
 				}
+				Log().Warn("periph: driver failed", "driver", d.String(), "err", err)
+				emit(Event{Type: Failed, D: d, Err: err})
 				cE <- DriverFailure{d, err}
+				if _, ok := err.(timeoutError); ok {
+					// The driver may be in an inconsistent state.
+					skip[j] = err
+				}
 			} else {
 				// Do not assert that err != nil, as this is hard to test thoroughly.
+				Log().Debug("periph: driver skipped", "driver", d.String(), "reason", err)
+				emit(Event{Type: Skipped, D: d, Err: err})
 				cS <- DriverFailure{d, err}
 				if err != nil {
 					err = errors.New("no reason was given")
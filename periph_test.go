@@ -10,6 +10,7 @@ import (
 	"log"
 	"sort"
 	"testing"
+	"time"
 )
 
 func ExampleInit() {
@@ -163,6 +164,142 @@ func TestDependencySkipped(t *testing.T) {
 	}
 }
 
+func TestInitWithOptions_Only(t *testing.T) {
+	defer reset()
+	registerDrivers([]Driver{
+		&driver{name: "CPU", ok: true},
+		&driver{name: "Board", prereqs: []string{"CPU"}, ok: true},
+		&driver{name: "GPU", ok: true},
+	})
+	state, err := InitWithOptions(&Options{Only: []string{"Board"}})
+	if err != nil || len(state.Loaded) != 2 || len(state.Skipped) != 1 {
+		t.Fatal(state, err)
+	}
+	if s := state.Skipped[0].String(); s != "GPU: not selected in Options.Only" {
+		t.Fatal(s)
+	}
+}
+
+func TestInitWithOptions_Skip(t *testing.T) {
+	defer reset()
+	registerDrivers([]Driver{
+		&driver{name: "CPU", ok: true},
+		&driver{name: "Board", prereqs: []string{"CPU"}, ok: true},
+		&driver{name: "GPU", ok: true},
+	})
+	state, err := InitWithOptions(&Options{Skip: []string{"CPU"}})
+	if err != nil || len(state.Loaded) != 1 || len(state.Skipped) != 2 {
+		t.Fatal(state, err)
+	}
+	if s := state.Skipped[0].String(); s != "Board: dependency not loaded: \"CPU\"" {
+		t.Fatal(s)
+	}
+	if s := state.Skipped[1].String(); s != "CPU: disabled in Options.Skip" {
+		t.Fatal(s)
+	}
+}
+
+func TestInitWithOptions_unknown(t *testing.T) {
+	defer reset()
+	registerDrivers([]Driver{&driver{name: "CPU", ok: true}})
+	if _, err := InitWithOptions(&Options{Only: []string{"CPUU"}}); err == nil {
+		t.Fatal("unknown driver in Only")
+	}
+	if _, err := InitWithOptions(&Options{Skip: []string{"CPUU"}}); err == nil {
+		t.Fatal("unknown driver in Skip")
+	}
+	// The state is not kept on error.
+	state, err := InitWithOptions(&Options{Only: []string{"CPU"}})
+	if err != nil || len(state.Loaded) != 1 {
+		t.Fatal(state, err)
+	}
+}
+
+func TestInitWithOptions_Timeout(t *testing.T) {
+	defer reset()
+	block := make(chan struct{})
+	defer close(block)
+	registerDrivers([]Driver{
+		&driver{name: "CPU", ok: true},
+		&driver{name: "Slow", ok: true, block: block},
+		&driver{name: "Board", prereqs: []string{"Slow"}, ok: true},
+	})
+	state, err := InitWithOptions(&Options{Timeout: time.Millisecond})
+	if err != nil || len(state.Loaded) != 1 || len(state.Skipped) != 1 || len(state.Failed) != 1 {
+		t.Fatal(state, err)
+	}
+	if s := state.Failed[0].String(); s != "Slow: initialization timed out after 1ms" {
+		t.Fatal(s)
+	}
+}
+
+func TestReload(t *testing.T) {
+	defer reset()
+	cpu := &unloadDriver{driver: driver{name: "CPU", ok: true}}
+	board := &driver{name: "Board", prereqs: []string{"CPU"}, ok: false, err: errors.New("no board")}
+	registerDrivers([]Driver{cpu, board})
+	if _, err := Reload("CPU"); err == nil {
+		t.Fatal("Reload() before Init()")
+	}
+	var events []string
+	stop := Notify(func(e Event) {
+		events = append(events, e.String())
+	})
+	defer stop()
+	state, err := Init()
+	if err != nil || len(state.Loaded) != 1 || len(state.Skipped) != 1 {
+		t.Fatal(state, err)
+	}
+
+	// The board was plugged in.
+	board.ok = true
+	board.err = nil
+	if state2, err := Reload("Board"); err != nil || state2 != state || len(state.Loaded) != 2 || len(state.Skipped) != 0 {
+		t.Fatal(state2, err)
+	}
+	if _, err := Reload("Board"); err == nil {
+		t.Fatal("Board doesn't implement Unloader")
+	}
+	cpu.ok = false
+	cpu.err = errors.New("unplugged")
+	if _, err := Reload("CPU"); err != nil || len(state.Loaded) != 1 || len(state.Skipped) != 1 || cpu.unloads != 1 {
+		t.Fatal(state, err)
+	}
+	cpu.ok = true
+	cpu.err = errors.New("broken")
+	if _, err := Reload("CPU"); err != nil || len(state.Failed) != 1 || len(state.Skipped) != 0 || cpu.unloads != 1 {
+		t.Fatal(state, err)
+	}
+	if _, err := Reload("Unknown"); err == nil {
+		t.Fatal("unknown driver")
+	}
+	expected := "[CPU Loaded Board Skipped: no board Board Loaded CPU Unloaded CPU Skipped: unplugged CPU Failed: broken]"
+	if s := fmt.Sprint(events); s != expected {
+		t.Fatal(s)
+	}
+}
+
+func TestReload_Unload_fail(t *testing.T) {
+	defer reset()
+	cpu := &unloadDriver{driver: driver{name: "CPU", ok: true}, unloadErr: errors.New("busy")}
+	registerDrivers([]Driver{cpu})
+	if _, err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := Reload("CPU"); err == nil || len(state.Loaded) != 1 {
+		t.Fatal(state, err)
+	}
+}
+
+func TestEventType_String(t *testing.T) {
+	if s := Unloaded.String(); s != "Unloaded" {
+		t.Fatal(s)
+	}
+	if s := EventType(-1).String(); s != "EventType(-1)" {
+		t.Fatal(s)
+	}
+}
+
 func TestRegisterLate(t *testing.T) {
 	defer reset()
 	if _, err := Init(); err != nil {
@@ -355,6 +492,7 @@ func reset() {
 	allDrivers = []Driver{}
 	byName = map[string]Driver{}
 	state = nil
+	watchers = nil
 }
 
 func registerDrivers(drivers []Driver) {
@@ -368,6 +506,7 @@ type driver struct {
 	prereqs []string
 	ok      bool
 	err     error
+	block   chan struct{} // Init() waits for it to be closed when not nil
 }
 
 func (d *driver) String() string {
@@ -379,5 +518,22 @@ func (d *driver) Prerequisites() []string {
 }
 
 func (d *driver) Init() (bool, error) {
+	if d.block != nil {
+		<-d.block
+	}
 	return d.ok, d.err
 }
+
+type unloadDriver struct {
+	driver
+	unloadErr error
+	unloads   int
+}
+
+func (u *unloadDriver) Unload() error {
+	if u.unloadErr != nil {
+		return u.unloadErr
+	}
+	u.unloads++
+	return nil
+}
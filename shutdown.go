@@ -0,0 +1,79 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package periph
+
+import (
+	"io"
+	"sync"
+)
+
+// CloserFunc adapts a function to io.Closer, to be used with Track().
+type CloserFunc func() error
+
+// Close calls f.
+func (f CloserFunc) Close() error {
+	return f()
+}
+
+// Track registers a resource to be released by Shutdown().
+//
+// Host drivers call it for the resources they claim, like opened buses,
+// exported sysfs pins and memory mapped regions. Applications can use it to
+// release their own resources in the right order.
+//
+// Call the returned function once the resource is released by other means so
+// it is not closed twice.
+func Track(c io.Closer) func() {
+	t := &tracked{c: c}
+	trackedMu.Lock()
+	defer trackedMu.Unlock()
+	trackedList = append(trackedList, t)
+	return func() {
+		trackedMu.Lock()
+		defer trackedMu.Unlock()
+		for i := range trackedList {
+			if trackedList[i] == t {
+				trackedList = append(trackedList[:i], trackedList[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Shutdown releases all the resources registered with Track(), in the reverse
+// order of registration.
+//
+// It is meant to be called once on a clean exit of a long running process.
+// All the resources are released even if one fails; the first error is
+// returned. The pins and buses must not be used afterward.
+func Shutdown() error {
+	trackedMu.Lock()
+	l := trackedList
+	trackedList = nil
+	trackedMu.Unlock()
+	var err error
+	for i := len(l) - 1; i >= 0; i-- {
+		if err2 := l[i].c.Close(); err2 != nil {
+			Log().Warn("periph: failed to release resource", "err", err2)
+			if err == nil {
+				err = err2
+			}
+		}
+	}
+	return err
+}
+
+//
+
+var (
+	trackedMu   sync.Mutex
+	trackedList []*tracked
+)
+
+// tracked is a resource registered with Track(); the pointer is used as an
+// identifier.
+type tracked struct {
+	c io.Closer
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package periph
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func ExampleShutdown() {
+	// Release a resource on shutdown. Drivers track their own resources.
+	Track(CloserFunc(func() error {
+		fmt.Println("released")
+		return nil
+	}))
+	if err := Shutdown(); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// released
+}
+
+//
+
+func TestShutdown(t *testing.T) {
+	var order []int
+	for i := 0; i < 3; i++ {
+		i := i
+		Track(CloserFunc(func() error {
+			order = append(order, i)
+			if i == 1 {
+				return errors.New("oops")
+			}
+			return nil
+		}))
+	}
+	untrack := Track(CloserFunc(func() error {
+		t.Fatal("untracked resource must not be closed")
+		return nil
+	}))
+	untrack()
+	untrack()
+	if err := Shutdown(); err == nil || err.Error() != "oops" {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(order); s != "[2 1 0]" {
+		t.Fatal(s)
+	}
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}